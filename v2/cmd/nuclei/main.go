@@ -1,19 +1,93 @@
 package main
 
 import (
+	"os"
+
 	"github.com/projectdiscovery/gologger"
 	"github.com/projectdiscovery/nuclei/v2/internal/runner"
 )
 
 func main() {
 	// Parse the command line flags and read config files
-	options := runner.ParseOptions()
+	options, err := runner.ParseOptions()
+	if err != nil {
+		gologger.Fatalf("Program exiting: %s\n", err)
+	}
 
 	runner, err := runner.New(options)
 	if err != nil {
 		gologger.Fatalf("Could not create runner: %s\n", err)
 	}
 
-	runner.RunEnumeration()
+	if options.Validate {
+		valid, err := runner.Validate()
+		runner.Close()
+		if err != nil {
+			gologger.Fatalf("Error, %s.\n", err)
+		}
+		if !valid {
+			os.Exit(1)
+		}
+		return
+	}
+
+	if options.SignTemplates != "" {
+		signed, err := runner.SignTemplates()
+		runner.Close()
+		if err != nil {
+			gologger.Fatalf("Error, %s.\n", err)
+		}
+		if !signed {
+			os.Exit(1)
+		}
+		return
+	}
+
+	if options.TemplateList {
+		err = runner.ListTemplates()
+		runner.Close()
+		if err != nil {
+			gologger.Fatalf("Error, %s.\n", err)
+		}
+		return
+	}
+
+	if options.DryRun {
+		err = runner.DryRun()
+		runner.Close()
+		if err != nil {
+			gologger.Fatalf("Error, %s.\n", err)
+		}
+		return
+	}
+
+	if options.Passive != "" {
+		err = runner.Passive()
+		runner.Close()
+		if err != nil {
+			gologger.Fatalf("Error, %s.\n", err)
+		}
+		return
+	}
+
+	if options.TestFixtures != "" {
+		passed, err := runner.TestTemplates()
+		runner.Close()
+		if err != nil {
+			gologger.Fatalf("Error, %s.\n", err)
+		}
+		if !passed {
+			os.Exit(1)
+		}
+		return
+	}
+
+	err = runner.RunEnumeration()
 	runner.Close()
+	if err != nil {
+		gologger.Fatalf("Error, %s.\n", err)
+	}
+	if code := runner.ExitCode(); code != 0 {
+		os.Exit(code)
+	}
 }
@@ -0,0 +1,81 @@
+// Command engine scans each target given with its own engine, the engines
+// running concurrently, and writes the results as json lines.
+//
+//	go run ./examples/engine -t templates/ https://example.com https://example.org
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"os"
+	"os/signal"
+	"strings"
+	"sync"
+
+	jsoniter "github.com/json-iterator/go"
+	"github.com/projectdiscovery/gologger"
+	"github.com/projectdiscovery/nuclei/v2/pkg/engine"
+)
+
+func main() {
+	templates := flag.String("t", "", "Comma separated templates to run")
+	concurrency := flag.Int("c", 10, "Number of concurrent requests of each engine")
+	flag.Parse()
+	if *templates == "" || flag.NArg() == 0 {
+		fmt.Fprintf(os.Stderr, "Usage: %s -t templates target...\n", os.Args[0])
+		os.Exit(2)
+	}
+	// the engines log through gologger, the summaries and the errors being
+	// shown without the info messages and the warnings
+	gologger.MaxLevel = gologger.Error
+
+	// the scans stop once interrupted
+	ctx, cancel := context.WithCancel(context.Background())
+	signals := make(chan os.Signal, 1)
+	signal.Notify(signals, os.Interrupt)
+	go func() {
+		<-signals
+		cancel()
+	}()
+
+	encoder := jsoniter.NewEncoder(os.Stdout)
+	var mutex sync.Mutex
+	var wg sync.WaitGroup
+	var engines []*engine.Engine
+	failed := false
+	for _, target := range flag.Args() {
+		e, err := engine.New(engine.Options{
+			Templates:   strings.Split(*templates, ","),
+			Targets:     []string{target},
+			Concurrency: *concurrency,
+		})
+		if err != nil {
+			gologger.Fatalf("Could not create the engine of %s: %s\n", target, err)
+		}
+		engines = append(engines, e)
+
+		wg.Add(1)
+		go func(target string, e *engine.Engine) {
+			defer wg.Done()
+			err := e.ExecuteWithContext(ctx, func(result *engine.Result) {
+				mutex.Lock()
+				defer mutex.Unlock()
+				encoder.Encode(result)
+			})
+			if err != nil {
+				mutex.Lock()
+				defer mutex.Unlock()
+				gologger.Errorf("Could not scan %s: %s\n", target, err)
+				failed = true
+			}
+		}(target, e)
+	}
+	wg.Wait()
+	for _, e := range engines {
+		e.Close()
+	}
+	if failed {
+		os.Exit(1)
+	}
+}
@@ -4,6 +4,9 @@ go 1.14
 
 require (
 	github.com/Knetic/govaluate v3.0.0+incompatible
+	github.com/antchfx/htmlquery v1.2.3
+	github.com/antchfx/xmlquery v1.3.1
+	github.com/antchfx/xpath v1.1.10
 	github.com/asaskevich/govalidator v0.0.0-20200428143746-21a406dcc535
 	github.com/blang/semver v3.5.1+incompatible
 	github.com/d5/tengo/v2 v2.6.0
@@ -12,14 +15,15 @@ require (
 	github.com/karrick/godirwalk v1.15.6
 	github.com/logrusorgru/aurora v0.0.0-20200102142835-e9ef32dff381
 	github.com/miekg/dns v1.1.30
+	github.com/modern-go/reflect2 v1.0.2 // indirect
 	github.com/pkg/errors v0.9.1
 	github.com/projectdiscovery/gologger v1.0.0
-	github.com/projectdiscovery/retryabledns v1.0.4
 	github.com/projectdiscovery/retryablehttp-go v1.0.1
-	github.com/stretchr/testify v1.5.1
+	github.com/segmentio/kafka-go v0.4.20
+	github.com/stretchr/testify v1.6.1
 	github.com/vbauerster/mpb/v5 v5.2.4
 	golang.org/x/crypto v0.0.0-20200709230013-948cd5f35899 // indirect
-	golang.org/x/net v0.0.0-20200707034311-ab3426394381
+	golang.org/x/net v0.0.0-20200813134508-3edf25e44fcc
 	golang.org/x/sys v0.0.0-20200724161237-0e2f3a69832c // indirect
 	gopkg.in/yaml.v2 v2.3.0
 )
@@ -3,6 +3,7 @@ package progress
 import (
 	"fmt"
 	"github.com/logrusorgru/aurora"
+	"github.com/projectdiscovery/nuclei/v2/pkg/stats"
 	"github.com/vbauerster/mpb/v5"
 	"github.com/vbauerster/mpb/v5/decor"
 	"os"
@@ -22,6 +23,13 @@ type Progress struct {
 	stdout          *strings.Builder
 	stderr          *strings.Builder
 	colorizer       aurora.Aurora
+	// stats are updated with the requests of the progress if any, the
+	// progress bar being hidden if they are streamed with -stats.
+	stats  *stats.Stats
+	hidden bool
+	// streamed is true if the targets are streamed, the total number of
+	// requests being unknown.
+	streamed bool
 }
 
 // Creates and returns a new progress tracking object.
@@ -40,12 +48,27 @@ func NewProgress(noColor bool) *Progress {
 	return p
 }
 
+// Creates and returns a progress tracking object updating the stats with
+// the payload-aware numbers of requests, without a progress bar if hidden.
+func NewStatsProgress(noColor bool, counters *stats.Stats, hidden bool) *Progress {
+	if hidden {
+		return &Progress{totalMutex: &sync.Mutex{}, stats: counters, hidden: true}
+	}
+	p := NewProgress(noColor)
+	p.stats = counters
+	return p
+}
+
 // Creates and returns a progress bar that tracks all the requests progress.
 // This is only useful when multiple templates are processed within the same run.
 func (p *Progress) InitProgressbar(hostCount int64, templateCount int, requestCount int64) {
 	if p.gbar != nil {
 		panic("A global progressbar is already present.")
 	}
+	p.stats.PlanRequests(requestCount)
+	if p.hidden {
+		return
+	}
 
 	color := p.colorizer
 
@@ -59,6 +82,43 @@ func (p *Progress) InitProgressbar(hostCount int64, templateCount int, requestCo
 	p.gbar = p.setupProgressbar("["+barName+"]", requestCount, 0)
 }
 
+// Creates and returns a progress bar that counts the requests of a scan of
+// streamed targets, whose total number of requests isn't known.
+func (p *Progress) InitStreamProgressbar(templateCount int) {
+	if p.gbar != nil {
+		panic("A global progressbar is already present.")
+	}
+	p.streamed = true
+	if p.hidden {
+		return
+	}
+
+	color := p.colorizer
+
+	barName := color.Sprintf(
+		color.Cyan("%d %s, streamed hosts"),
+		color.Bold(color.Cyan(templateCount)),
+		pluralize(int64(templateCount), "template", "templates"))
+
+	p.gbar = p.progress.AddBar(
+		0,
+		mpb.BarNoPop(),
+		mpb.BarRemoveOnComplete(),
+		mpb.PrependDecorators(
+			decor.Name("["+barName+"]", decor.WCSyncSpaceR),
+			decor.Any(func(s decor.Statistics) string {
+				return color.BrightBlue(fmt.Sprintf(" %d requests", s.Current)).String()
+			}, decor.WCSyncSpace),
+		),
+		mpb.AppendDecorators(
+			decor.AverageSpeed(0, color.BrightYellow("%.2f").Bold().String()+color.BrightYellow("r/s").String(), decor.WCSyncSpace),
+			decor.Elapsed(decor.ET_STYLE_GO, decor.WCSyncSpace),
+		),
+	)
+	// the bar is only completed at the end of the stream
+	p.gbar.SetTotal(0, false)
+}
+
 func pluralize(count int64, singular, plural string) string {
 	if count > 1 {
 		return plural
@@ -68,6 +128,13 @@ func pluralize(count int64, singular, plural string) string {
 
 // Update total progress request count
 func (p *Progress) AddToTotal(delta int64) {
+	if p.streamed {
+		return
+	}
+	p.stats.PlanRequests(delta)
+	if p.hidden {
+		return
+	}
 	p.totalMutex.Lock()
 	p.total += delta
 	p.gbar.SetTotal(p.total, false)
@@ -76,6 +143,10 @@ func (p *Progress) AddToTotal(delta int64) {
 
 // Update progress tracking information and increments the request counter by one unit.
 func (p *Progress) Update() {
+	p.stats.CompleteRequests(1)
+	if p.hidden {
+		return
+	}
 	p.gbar.Increment()
 }
 
@@ -83,13 +154,31 @@ func (p *Progress) Update() {
 // This may be the case when uncompleted requests are encountered and shouldn't be part of the total count.
 func (p *Progress) Drop(count int64) {
 	// mimic dropping by incrementing the completed requests
+	p.stats.CompleteRequests(count)
+	if p.hidden {
+		return
+	}
 	p.gbar.IncrInt64(count)
 
 }
 
+// Stop completes the progress bar of a scan stopped before sending all its
+// requests, the requests not sent being dropped.
+func (p *Progress) Stop() {
+	if p.hidden {
+		return
+	}
+	p.totalMutex.Lock()
+	p.gbar.SetTotal(p.total, true)
+	p.totalMutex.Unlock()
+}
+
 // Ensures that a progress bar's total count is up-to-date if during an enumeration there were uncompleted requests and
 // wait for all the progress bars to finish.
 func (p *Progress) Wait() {
+	if p.hidden {
+		return
+	}
 	p.totalMutex.Lock()
 	if p.total == 0 {
 		p.gbar.Abort(true)
@@ -127,12 +216,18 @@ func (p *Progress) setupProgressbar(name string, total int64, priority int) *mpb
 
 // Starts capturing stdout and stderr instead of producing visual output that may interfere with the progress bars.
 func (p *Progress) StartStdCapture() {
+	if p.hidden {
+		return
+	}
 	p.stdCaptureMutex.Lock()
 	p.captureData = startStdCapture()
 }
 
 // Stops capturing stdout and stderr and store both output to be shown later.
 func (p *Progress) StopStdCapture() {
+	if p.hidden {
+		return
+	}
 	stopStdCapture(p.captureData)
 	p.stdout.Write(p.captureData.DataStdOut.Bytes())
 	p.stderr.Write(p.captureData.DataStdErr.Bytes())
@@ -141,14 +236,14 @@ func (p *Progress) StopStdCapture() {
 
 // Writes the captured stdout data to stdout, if any.
 func (p *Progress) ShowStdOut() {
-	if p.stdout.Len() > 0 {
+	if p.stdout != nil && p.stdout.Len() > 0 {
 		fmt.Fprint(os.Stdout, p.stdout.String())
 	}
 }
 
 // Writes the captured stderr data to stderr, if any.
 func (p *Progress) ShowStdErr() {
-	if p.stderr.Len() > 0 {
+	if p.stderr != nil && p.stderr.Len() > 0 {
 		fmt.Fprint(os.Stderr, p.stderr.String())
 	}
 }
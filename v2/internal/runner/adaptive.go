@@ -0,0 +1,43 @@
+package runner
+
+import (
+	"github.com/projectdiscovery/gologger"
+	"github.com/projectdiscovery/nuclei/v2/pkg/adaptive"
+	"github.com/projectdiscovery/nuclei/v2/pkg/stats"
+)
+
+// newAdaptive returns the controller of -adaptive, bounded by -c if no
+// maximum concurrency is given, its adjustments being counted by the
+// stats and shown with -v.
+func newAdaptive(options *Options, stats *stats.Stats) *adaptive.Controller {
+	max := options.AdaptiveMaxConcurrency
+	if max == 0 {
+		max = options.Threads
+	}
+	if max < options.AdaptiveMinConcurrency {
+		max = options.AdaptiveMinConcurrency
+	}
+	stats.SetConcurrency(int64(max))
+
+	return adaptive.New(adaptive.Options{
+		MinConcurrency: options.AdaptiveMinConcurrency,
+		MaxConcurrency: max,
+		Window:         options.AdaptiveWindow,
+		BackoffRate:    options.AdaptiveBackoffRate,
+		RecoverRate:    options.AdaptiveRecoverRate,
+		MaxDelay:       options.AdaptiveMaxDelay,
+	}, func(adjustment adaptive.Adjustment) {
+		stats.ConcurrencyAdjusted(adjustment.Backoff, int64(adjustment.Global), int64(adjustment.Throttled))
+
+		action := "Recovering"
+		if adjustment.Backoff {
+			action = "Backing off"
+		}
+		// only the requests to each host are delayed
+		if adjustment.Host == "" {
+			gologger.Verbosef("%s all the hosts to %d requests in flight, with an error rate of %.2f\n", "adaptive", action, adjustment.Concurrency, adjustment.ErrorRate)
+			return
+		}
+		gologger.Verbosef("%s %s to %d requests in flight with a delay of %s, with an error rate of %.2f\n", "adaptive", action, adjustment.Host, adjustment.Concurrency, adjustment.Delay, adjustment.ErrorRate)
+	})
+}
@@ -0,0 +1,118 @@
+package runner
+
+import (
+	"fmt"
+	"net/http/cookiejar"
+	"net/url"
+
+	"github.com/projectdiscovery/nuclei/v2/pkg/auth"
+	"github.com/projectdiscovery/nuclei/v2/pkg/executer"
+	"github.com/projectdiscovery/nuclei/v2/pkg/requests"
+	"github.com/projectdiscovery/nuclei/v2/pkg/templates"
+)
+
+// newSessions creates the sessions of -auth, each host being logged in with
+// the http requests of the login template before the templates are run on
+// it. There is no session with -no-auth, for the dry run, the passive scans
+// and the listing of the templates, which send no requests.
+func (r *Runner) newSessions() (*auth.Store, error) {
+	options := r.options
+	if options.Auth == "" || options.NoAuth || options.TemplateList || options.DryRun || options.Passive != "" {
+		return nil, nil
+	}
+	config, err := auth.LoadConfig(options.Auth)
+	if err != nil {
+		return nil, err
+	}
+	file, data, err := config.LoginTemplate(templates.CheckSignature)
+	if err != nil {
+		return nil, err
+	}
+	template, err := templates.ParseData(file, data)
+	if err != nil {
+		return nil, fmt.Errorf("could not parse login template: %s", err)
+	}
+	template.SetRegexGuard(r.regexGuard)
+	if len(template.BulkRequestsHTTP) == 0 || len(template.RequestsDNS) > 0 {
+		return nil, fmt.Errorf("login template %s should only have http requests", file)
+	}
+	return auth.NewStore(config, func(rootURL string) (*auth.Session, error) {
+		return r.login(config, template, rootURL)
+	}), nil
+}
+
+// login sends the requests of the login template to the root URL of a host
+// with a new cookie jar, the values extracted by each request being
+// available to the next ones, and returns the session made of the values
+// and the cookies of the jar. The login requests write no results.
+func (r *Runner) login(config *auth.Config, template *templates.Template, rootURL string) (*auth.Session, error) {
+	jar, err := cookiejar.New(nil)
+	if err != nil {
+		return nil, err
+	}
+	targetValues, err := requests.TargetValues(rootURL)
+	if err != nil {
+		return nil, err
+	}
+	variables, err := template.EvaluateVariables(targetValues)
+	if err != nil {
+		return nil, fmt.Errorf("could not evaluate variables: %s", err)
+	}
+	values := make(map[string]interface{}, len(variables))
+	for name, value := range variables {
+		values[name] = value
+	}
+
+	for index, request := range template.BulkRequestsHTTP {
+		httpExecuter, err := executer.NewHTTPExecuter(&executer.HTTPOptions{
+			Debug:           r.options.Debug,
+			Template:        template,
+			BulkHttpRequest: request,
+			Timeout:         r.effectiveTimeout(template, request.Timeout),
+			Retries:         r.effectiveRetries(template, request.Retries),
+			Resolved:        true,
+			ProxyURL:        r.options.ProxyURL,
+			ProxySocksURL:   r.options.ProxySocksURL,
+			CustomHeaders:   r.options.CustomHeaders,
+			ForcedHeaders:   r.options.ForcedHeaders,
+			CookieJar:       jar,
+			RegexGuard:      r.regexGuard,
+			DiscardResults:  true,
+			Redactor:        r.redactor,
+			NoRedact:        r.options.NoRedact,
+			Quiet:           true,
+			RateLimiter:     r.rateLimiter,
+			Adaptive:        r.adaptive,
+			HostErrors:      r.hostErrors,
+		})
+		if err != nil {
+			return nil, err
+		}
+		// a host logging in again once its session expired starts over
+		request.DeleteGenerator(rootURL)
+		result := httpExecuter.ExecuteHTTPWithValues(nil, rootURL, values)
+		if result.Error != nil {
+			return nil, fmt.Errorf("login request %d: %s", index+1, template.Redact(result.Error.Error()))
+		}
+		for name, value := range result.Extractions {
+			values[name] = value
+		}
+	}
+
+	parsed, err := url.Parse(rootURL)
+	if err != nil {
+		return nil, err
+	}
+	return auth.NewSession(config, values, jar.Cookies(parsed))
+}
+
+// establishSession logs in to the host of an http target before the
+// templates are run on it, if it isn't yet.
+func (r *Runner) establishSession(URL string) {
+	if r.sessions == nil {
+		return
+	}
+	if parsed, err := url.Parse(URL); err == nil {
+		r.sessions.Session(parsed)
+	}
+}
@@ -0,0 +1,63 @@
+package runner
+
+import (
+	"fmt"
+	"io/ioutil"
+	"strings"
+	"time"
+
+	jsoniter "github.com/json-iterator/go"
+	"github.com/projectdiscovery/gologger"
+)
+
+// benchmarkTop is the number of the slowest templates and hosts shown by
+// the report of -benchmark, the json report having all of them.
+const benchmarkTop = 20
+
+// logBenchmark shows the report of -benchmark if any, writing it with all
+// the templates and hosts to the -benchmark-json file if any.
+func (r *Runner) logBenchmark() {
+	if r.benchmark == nil {
+		return
+	}
+	report := r.benchmark.Report(benchmarkTop)
+	gologger.Labelf("Benchmark: %d requests in %s, %.1f requests per second, %d failed\n", report.Requests, (time.Duration(report.DurationMS) * time.Millisecond).Round(time.Millisecond), report.RPS, report.Errors)
+	if len(report.Templates) > 0 {
+		gologger.Labelf("Slowest templates by the time of their runs:\n")
+		for _, template := range report.Templates {
+			gologger.Labelf("  %s: %s in %d runs, %s per run, %d requests, %s average latency, %d errors, %s matching\n", template.Name, benchmarkDuration(template.TimeMS), template.Runs, benchmarkDuration(template.AvgRunMS), template.Requests, benchmarkDuration(template.AvgLatencyMS), template.Errors, benchmarkDuration(template.MatchMS))
+		}
+	}
+	if len(report.Hosts) > 0 {
+		gologger.Labelf("Slowest hosts by the latency of their requests:\n")
+		for _, host := range report.Hosts {
+			gologger.Labelf("  %s: %s in %d requests, %s average latency, %d errors\n", host.Name, benchmarkDuration(host.TimeMS), host.Requests, benchmarkDuration(host.AvgLatencyMS), host.Errors)
+		}
+	}
+	buckets := make([]string, 0, len(report.Buckets))
+	for _, bucket := range report.Buckets {
+		buckets = append(buckets, fmt.Sprintf("%ds-%ds %.1f", bucket.StartS, bucket.EndS, bucket.RPS))
+	}
+	gologger.Labelf("Requests per second over the scan: %s\n", strings.Join(buckets, ", "))
+
+	if r.options.BenchmarkJSON == "" {
+		return
+	}
+	data, err := jsoniter.MarshalIndent(r.benchmark.Report(0), "", "  ")
+	if err == nil {
+		err = ioutil.WriteFile(r.options.BenchmarkJSON, append(data, '\n'), 0644)
+	}
+	if err != nil {
+		gologger.Errorf("Could not write the benchmark to %s: %s\n", r.options.BenchmarkJSON, err)
+	}
+}
+
+// benchmarkDuration returns milliseconds of a report as a duration rounded
+// to a readable precision.
+func benchmarkDuration(ms float64) time.Duration {
+	duration := time.Duration(ms * float64(time.Millisecond))
+	if duration >= time.Second {
+		return duration.Round(time.Millisecond)
+	}
+	return duration.Round(time.Microsecond)
+}
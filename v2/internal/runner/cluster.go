@@ -0,0 +1,221 @@
+package runner
+
+import (
+	"bufio"
+	"context"
+	"fmt"
+	"strings"
+
+	"github.com/projectdiscovery/gologger"
+	"github.com/projectdiscovery/nuclei/v2/internal/progress"
+	"github.com/projectdiscovery/nuclei/v2/pkg/executer"
+	"github.com/projectdiscovery/nuclei/v2/pkg/templates"
+)
+
+// clusterMember is a template of a cluster along with its executer and the
+// state of its run.
+type clusterMember struct {
+	template *templates.Template
+	step     string
+	executer *executer.HTTPExecuter
+	writer   *bufio.Writer
+	statuses *matcherStatuses
+	failures *templateErrors
+}
+
+// clusterTemplates groups the templates with a single http request sent
+// with the same values and settings, returning the clusters of two or more
+// templates and the indexes of the parsed templates and workflows not
+// clustered.
+func (r *Runner) clusterTemplates(parsed []interface{}) ([][]*templates.Template, []int) {
+	var keys []string
+	clustered := make(map[string][]int)
+	for i, t := range parsed {
+		key, ok := r.clusterKey(t)
+		if !ok {
+			continue
+		}
+		if _, ok := clustered[key]; !ok {
+			keys = append(keys, key)
+		}
+		clustered[key] = append(clustered[key], i)
+	}
+
+	var clusters [][]*templates.Template
+	inCluster := make(map[int]bool)
+	for _, key := range keys {
+		indexes := clustered[key]
+		if len(indexes) < 2 {
+			continue
+		}
+		cluster := make([]*templates.Template, 0, len(indexes))
+		for _, index := range indexes {
+			cluster = append(cluster, parsed[index].(*templates.Template))
+			inCluster[index] = true
+		}
+		clusters = append(clusters, cluster)
+	}
+	remaining := make([]int, 0, len(parsed)-len(inCluster))
+	for i := range parsed {
+		if !inCluster[i] {
+			remaining = append(remaining, i)
+		}
+	}
+	return clusters, remaining
+}
+
+// clusterKey returns the key of the http request of a template which can
+// be clustered, the templates with variables or several requests not being
+// clustered.
+func (r *Runner) clusterKey(t interface{}) (string, bool) {
+	template, ok := t.(*templates.Template)
+	if !ok || template.HasMultipleProtocols() || len(template.RequestsDNS) > 0 || len(template.BulkRequestsHTTP) != 1 || len(template.Variables) > 0 {
+		return "", false
+	}
+	request := template.BulkRequestsHTTP[0]
+	key, ok := request.ClusterKey()
+	if !ok {
+		return "", false
+	}
+	return fmt.Sprintf("%s\x00%d\x00%d", key, r.effectiveTimeout(template, request.Timeout), r.effectiveRetries(template, request.Retries)), true
+}
+
+// newClusterJob creates the job of the templates of a cluster, sending
+// their request once per target. nil is returned if none of their
+// executers could be created.
+func (r *Runner) newClusterJob(p *progress.Progress, cluster []*templates.Template) *scanJob {
+	var members []*clusterMember
+	threads := r.options.Threads
+	for _, template := range cluster {
+		request := template.BulkRequestsHTTP[0]
+		step := requestStep(template.ID, "http", 0)
+		logLoadedTemplate(template)
+		r.logEffectiveSettings(template, request)
+
+		member := &clusterMember{template: template, step: step, statuses: r.newMatcherStatuses(), failures: &templateErrors{}}
+		if r.output != nil {
+			member.writer = bufio.NewWriter(r.output)
+		}
+		var err error
+		member.executer, err = r.newHTTPExecuter(template, request, member.writer, nil, step)
+		if err != nil {
+			if p != nil {
+				p.Drop(request.GetRequestCount() * r.inputCount)
+			}
+			gologger.Warningf("Could not create executer for template '%s': %s\n", template.ID, err)
+			r.stats.TemplateFailed(template.ID, "could not create executer: "+err.Error())
+			r.completeSteps(step)
+			r.stats.TemplateCompleted()
+			continue
+		}
+		// the targets of the cluster are limited by the threads of each template
+		if templateThreads := r.effectiveThreads(template); templateThreads < threads {
+			threads = templateThreads
+		}
+		members = append(members, member)
+	}
+	if len(members) == 0 {
+		return nil
+	}
+	executers := make([]*executer.HTTPExecuter, len(members))
+	for i, member := range members {
+		executers[i] = member.executer
+	}
+	clusterExecuter := executer.NewClusterExecuter(executers)
+
+	ids := make([]string, len(members))
+	for i, member := range members {
+		ids[i] = member.template.ID
+	}
+	job := newScanJob("cluster:"+strings.Join(ids, ","), threads)
+	job.requests = int64(len(members))
+	job.start = func(URL string) func(ctx context.Context) {
+		var pending []int
+		for i, member := range members {
+			if r.skipStep(member.step, URL) {
+				if p != nil {
+					p.Drop(1)
+				}
+				continue
+			}
+			pending = append(pending, i)
+		}
+		if len(pending) == 0 {
+			return nil
+		}
+		return func(ctx context.Context) {
+			var results []executer.Result
+			pending := pending
+			if httpURL, err := r.resolveHTTPInput(URL); err == nil {
+				// the templates are skipped on the targets whose host runs
+				// none of their technologies with -tech-detect
+				pending = r.skipClusterTech(p, members, pending, httpURL, URL)
+				if len(pending) == 0 {
+					return
+				}
+				results = clusterExecuter.ExecuteHTTP(ctx, p, httpURL, pending)
+			} else {
+				if p != nil {
+					p.Drop(int64(len(pending)))
+				}
+				results = make([]executer.Result, len(pending))
+				for i := range results {
+					results[i].Error = err
+				}
+			}
+			// the request of the cluster failed for all its templates, whose
+			// steps complete once the run is retried
+			if ctx.Err() == nil && r.retry(job, URL, results[0].Error) {
+				gologger.Warningf("Could not execute step: %s\n", results[0].Error)
+				r.recordError(URL, results[0].Error)
+				return
+			}
+			for i, index := range pending {
+				member, result := members[index], &results[i]
+				job.results.Or(result.GotResults)
+				if r.abandoned(ctx, member.template.ID, URL, result) {
+					continue
+				}
+				if result.Error != nil && !skipped(result.Error) {
+					gologger.Warningf("Could not execute step: %s\n", result.Error)
+				}
+				r.recordError(URL, result.Error)
+				member.failures.add(result.Error)
+				member.statuses.add(URL, result)
+				r.completeStep(member.step, URL)
+			}
+		}
+	}
+	// the templates of the cluster are completed along with the job
+	job.finish = func() {
+		for _, member := range members {
+			if member.writer != nil {
+				member.writer.Flush()
+			}
+			r.recordTemplateErrors(member.template.ID, member.failures)
+			r.writeStatuses(member.template, member.statuses)
+			r.stats.TemplateCompleted()
+		}
+	}
+	return job
+}
+
+// skipClusterTech returns the pending templates of a cluster run on an http
+// target with -tech-detect, completing the steps of the ones skipped.
+func (r *Runner) skipClusterTech(p *progress.Progress, members []*clusterMember, pending []int, httpURL, URL string) []int {
+	if r.techDetector == nil {
+		return pending
+	}
+	running := make([]int, 0, len(pending))
+	for _, index := range pending {
+		if !r.skipTech(members[index].template, httpURL) {
+			running = append(running, index)
+			continue
+		}
+		if p != nil {
+			p.Drop(1)
+		}
+		r.completeStep(members[index].step, URL)
+	}
+	return running
+}
@@ -0,0 +1,141 @@
+package runner
+
+import (
+	"context"
+	"crypto/subtle"
+	"net"
+	"net/http"
+	"strings"
+	"time"
+
+	jsoniter "github.com/json-iterator/go"
+	"github.com/projectdiscovery/gologger"
+)
+
+// controlShutdownTimeout is the time the requests to the control api have
+// to complete once the scan is over.
+const controlShutdownTimeout = 5 * time.Second
+
+// startControl serves the control api of -server on its localhost address
+// until the runner is closed, each request being authenticated with the
+// token of -server-token as a bearer token.
+func (r *Runner) startControl() error {
+	listener, err := net.Listen("tcp", r.options.Server)
+	if err != nil {
+		return err
+	}
+	mux := http.NewServeMux()
+	mux.HandleFunc("/status", r.controlHandler(http.MethodGet, r.serveStatus))
+	mux.HandleFunc("/pause", r.controlHandler(http.MethodPost, func(w http.ResponseWriter, req *http.Request) {
+		if r.pause() {
+			gologger.Labelf("Paused the scan, the runs in flight completing\n")
+		}
+		r.serveStatus(w, req)
+	}))
+	mux.HandleFunc("/resume", r.controlHandler(http.MethodPost, func(w http.ResponseWriter, req *http.Request) {
+		if r.resume() {
+			gologger.Labelf("Resumed the scan\n")
+		}
+		r.serveStatus(w, req)
+	}))
+	mux.HandleFunc("/stop", r.controlHandler(http.MethodPost, func(w http.ResponseWriter, req *http.Request) {
+		// the queued runs are run to stop the scan
+		r.resume()
+		r.stopScan("by the control api")
+		r.serveStatus(w, req)
+	}))
+	mux.HandleFunc("/hosts/skipped", r.controlHandler(http.MethodGet, func(w http.ResponseWriter, req *http.Request) {
+		writeControlJSON(w, r.hostErrors.Hosts())
+	}))
+
+	r.control = &http.Server{Handler: mux}
+	go func() {
+		if err := r.control.Serve(listener); err != nil && err != http.ErrServerClosed {
+			gologger.Warningf("Could not serve the control api: %s\n", err)
+		}
+	}()
+	gologger.Labelf("Serving the control api on http://%s\n", listener.Addr())
+	return nil
+}
+
+// stopControl shuts the control api down, waiting for its requests
+func (r *Runner) stopControl() {
+	if r.control == nil {
+		return
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), controlShutdownTimeout)
+	defer cancel()
+	if err := r.control.Shutdown(ctx); err != nil {
+		gologger.Warningf("Could not stop the control api: %s\n", err)
+	}
+}
+
+// controlHandler returns a handler of the control api calling next for the
+// requests with the method and the token of -server-token.
+func (r *Runner) controlHandler(method string, next http.HandlerFunc) http.HandlerFunc {
+	token := []byte(r.options.ServerToken)
+	return func(w http.ResponseWriter, req *http.Request) {
+		given := strings.TrimPrefix(req.Header.Get("Authorization"), "Bearer ")
+		if subtle.ConstantTimeCompare([]byte(given), token) != 1 {
+			w.Header().Set("WWW-Authenticate", "Bearer")
+			http.Error(w, "invalid token", http.StatusUnauthorized)
+			return
+		}
+		if req.Method != method {
+			w.Header().Set("Allow", method)
+			http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+			return
+		}
+		next(w, req)
+	}
+}
+
+// serveStatus writes the snapshot of the stats written by -stats
+func (r *Runner) serveStatus(w http.ResponseWriter, req *http.Request) {
+	writeControlJSON(w, r.stats.Snapshot())
+}
+
+// writeControlJSON writes a value of the control api as json
+func writeControlJSON(w http.ResponseWriter, value interface{}) {
+	data, err := jsoniter.Marshal(value)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	w.Write(append(data, '\n'))
+}
+
+// pause stops the workers from taking the next runs of the templates on
+// the targets, the rate limit from handing out tokens and the deadline of
+// -max-scan-duration, returning true if the scan was not paused. The runs
+// in flight complete, the requests waiting for the rate limit waiting for
+// the resume too.
+func (r *Runner) pause() bool {
+	r.pauseMutex.Lock()
+	defer r.pauseMutex.Unlock()
+	if r.paused {
+		return false
+	}
+	r.paused = true
+	r.pool.Pause()
+	r.rateLimiter.Pause()
+	r.pauseDeadline()
+	r.stats.SetPaused(true)
+	return true
+}
+
+// resume resumes a paused scan, returning true if it was paused
+func (r *Runner) resume() bool {
+	r.pauseMutex.Lock()
+	defer r.pauseMutex.Unlock()
+	if !r.paused {
+		return false
+	}
+	r.paused = false
+	r.resumeDeadline()
+	r.rateLimiter.Resume()
+	r.pool.Resume()
+	r.stats.SetPaused(false)
+	return true
+}
@@ -0,0 +1,151 @@
+package runner
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"github.com/projectdiscovery/gologger"
+	"github.com/projectdiscovery/nuclei/v2/pkg/executer"
+)
+
+// ExitTruncated is the exit code of a scan stopped after -max-scan-duration,
+// the results being the ones found so far.
+const ExitTruncated = 3
+
+// stopGracePeriod is the time the runs in flight have to complete once the
+// scan stops, before their requests are cancelled.
+const stopGracePeriod = 10 * time.Second
+
+// errTemplateTimeout is the error of a template abandoned on a target after
+// -template-timeout
+var errTemplateTimeout = errors.New("timed out, the template ran longer than -template-timeout")
+
+// errScanStopped is the error of a run cancelled after -max-scan-duration
+// or by the control api, which is run again when resuming the scan.
+var errScanStopped = errors.New("stopped, the scan stopped before the run completed")
+
+// startDeadline stops the scan once it ran for -max-scan-duration if any,
+// the time the scan is paused not counting.
+func (r *Runner) startDeadline() {
+	if r.options.MaxScanDuration <= 0 {
+		return
+	}
+	r.pauseMutex.Lock()
+	defer r.pauseMutex.Unlock()
+	r.deadlineLeft = r.options.MaxScanDuration
+	if !r.paused {
+		r.deadlineStart = time.Now()
+		r.deadline = time.AfterFunc(r.deadlineLeft, r.stopAfterDeadline)
+	}
+}
+
+// stopDeadline stops the timer of -max-scan-duration, the scan being over
+func (r *Runner) stopDeadline() {
+	r.pauseMutex.Lock()
+	defer r.pauseMutex.Unlock()
+	if r.deadline != nil {
+		r.deadline.Stop()
+	}
+	r.deadlineLeft = 0
+}
+
+// pauseDeadline stops the timer of -max-scan-duration while the scan is
+// paused, keeping the time left. It is called with the pause mutex held.
+func (r *Runner) pauseDeadline() {
+	if r.deadline != nil && r.deadline.Stop() {
+		r.deadlineLeft -= time.Since(r.deadlineStart)
+	}
+}
+
+// resumeDeadline starts the timer of -max-scan-duration again for the time
+// left once the scan is resumed. It is called with the pause mutex held.
+func (r *Runner) resumeDeadline() {
+	if r.deadlineLeft <= 0 || r.truncated.Get() {
+		return
+	}
+	r.deadlineStart = time.Now()
+	r.deadline = time.AfterFunc(r.deadlineLeft, r.stopAfterDeadline)
+}
+
+// stopAfterDeadline stops the scan once -max-scan-duration is reached
+func (r *Runner) stopAfterDeadline() {
+	r.stopScan("after " + r.options.MaxScanDuration.String())
+}
+
+// stopScan stops enqueuing the runs of the templates on the targets, the
+// runs in flight being cancelled after the grace period. The scan is
+// stopped once, the reason being shown by the summary.
+func (r *Runner) stopScan(reason string) {
+	r.stopWithin(reason, stopGracePeriod)
+}
+
+// Stop stops the scan for a reason as stopScan, the runs in flight being
+// cancelled at once, for the programs embedding the engine.
+func (r *Runner) Stop(reason string) {
+	r.stopWithin(reason, 0)
+}
+
+// stopWithin stops the scan once, cancelling the runs in flight after a
+// grace period, at once if 0.
+func (r *Runner) stopWithin(reason string, grace time.Duration) {
+	r.stopOnce.Do(func() {
+		r.stopReason = reason
+		r.truncated.Set(true)
+		r.stats.ScanTruncated()
+		if grace > 0 {
+			gologger.Labelf("Stopping the scan %s, waiting %s for the requests in flight\n", reason, grace)
+			time.AfterFunc(grace, r.cancel)
+		} else {
+			gologger.Labelf("Stopping the scan %s\n", reason)
+			r.cancel()
+		}
+		close(r.stopped)
+	})
+}
+
+// ExitCode returns the exit code of the scan, ExitTruncated if it stopped
+// after -max-scan-duration or by the control api before running all the
+// templates on all the targets, 0 otherwise.
+func (r *Runner) ExitCode() int {
+	if r.truncated.Get() {
+		return ExitTruncated
+	}
+	return 0
+}
+
+// runContext returns the context of a run of a template on a target, done
+// when the scan is cancelled or after -template-timeout if any.
+func (r *Runner) runContext() (context.Context, context.CancelFunc) {
+	if r.options.TemplateTimeout > 0 {
+		return context.WithTimeout(r.ctx, r.options.TemplateTimeout)
+	}
+	return context.WithCancel(r.ctx)
+}
+
+// runError returns the error of a run abandoned as its context is done,
+// errScanStopped or errTemplateTimeout, the error of the run otherwise.
+func (r *Runner) runError(ctx context.Context, err error) error {
+	if err == nil || ctx.Err() == nil {
+		return err
+	}
+	if r.ctx.Err() != nil {
+		return errScanStopped
+	}
+	return errTemplateTimeout
+}
+
+// abandoned sets the error of the result of a template on a target whose
+// context is done, recording the timed out ones. It returns true if the
+// run was stopped with the scan, its step being left to run when resuming.
+func (r *Runner) abandoned(ctx context.Context, templateID, target string, result *executer.Result) bool {
+	result.Error = r.runError(ctx, result.Error)
+	switch result.Error {
+	case errScanStopped:
+		return true
+	case errTemplateTimeout:
+		gologger.Verbosef("Abandoned %s on %s after %s\n", "timeout", templateID, target, r.options.TemplateTimeout)
+		r.stats.TemplateTimedOut(templateID, target)
+	}
+	return false
+}
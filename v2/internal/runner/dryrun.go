@@ -0,0 +1,254 @@
+package runner
+
+import (
+	"fmt"
+	"strings"
+
+	jsoniter "github.com/json-iterator/go"
+	"github.com/projectdiscovery/gologger"
+	"github.com/projectdiscovery/nuclei/v2/pkg/executer"
+	"github.com/projectdiscovery/nuclei/v2/pkg/templates"
+	"github.com/projectdiscovery/nuclei/v2/pkg/workflows"
+)
+
+// disableOutputs disables the outputs, the exports, the checkpoint and the
+// cache of the project for the dry run, which writes no results.
+func (options *Options) disableOutputs() {
+	options.Output = ""
+	options.ExtractorOutput = ""
+	options.SarifExport = ""
+	options.MarkdownExport = ""
+	options.ElasticsearchExport = ""
+	options.WebhookExport = ""
+	options.WebhookCheck = false
+	options.SyslogExport = ""
+	options.KafkaExport = ""
+	options.CSV = ""
+	options.ReportConfig = ""
+	options.Dedupe = false
+	options.Stats = false
+	options.StatsFile = ""
+	options.StatsJSON = ""
+	options.Metrics = false
+	options.Resume = ""
+	options.Project = false
+}
+
+// dryRunTemplate is a template or a cluster of templates of the dry run,
+// along with the executers building its requests.
+type dryRunTemplate struct {
+	ids       []string
+	executers *templateExecuters
+}
+
+// dryRunEntry is the requests of a template to a target, written by -json
+type dryRunEntry struct {
+	Templates []string `json:"templates"`
+	Protocol  string   `json:"protocol"`
+	Target    string   `json:"target"`
+	// Probed are the schemes probed in order for a target without one
+	Probed []string `json:"probed,omitempty"`
+	*executer.Plan
+}
+
+// dryRunTotals are the numbers of requests of the dry run by protocol
+type dryRunTotals struct {
+	http, dns, headless, network, websocket, ssl, file, listed int64
+	targets                                                    int64
+}
+
+// DryRun lists the requests a scan with the same flags would send to each
+// target, the first -dry-run-limit ones of each template, along with the
+// totals, without sending them, exporting all the http ones with
+// -export-requests. The targets without a scheme are listed
+// with the scheme probed first, and the templates of the workflows are
+// not listed, running depending on the matches.
+func (r *Runner) DryRun() error {
+	paths := r.templatePaths()
+	if len(paths) == 0 {
+		return ErrNoTemplates
+	}
+	loaded, err := r.loadTemplates(paths)
+	if err != nil {
+		return err
+	}
+
+	var planned []*dryRunTemplate
+	var skippedWorkflows int
+	clusters, remaining := r.clusterTemplates(loaded.parsed)
+	for _, cluster := range clusters {
+		ids := make([]string, 0, len(cluster))
+		for _, template := range cluster {
+			ids = append(ids, template.ID)
+		}
+		// the templates of a cluster send the same request once
+		planned = append(planned, &dryRunTemplate{ids: ids, executers: r.newTemplateExecuters(nil, cluster[0], nil, 0)})
+	}
+	for _, i := range remaining {
+		switch t := loaded.parsed[i].(type) {
+		case *templates.Template:
+			planned = append(planned, &dryRunTemplate{ids: []string{t.ID}, executers: r.newTemplateExecuters(nil, t, nil, 0)})
+		case *workflows.Workflow:
+			skippedWorkflows++
+		}
+	}
+
+	totals := &dryRunTotals{}
+	r.eachInput(func(target string) {
+		totals.targets++
+		for _, t := range planned {
+			r.dryRunTemplate(t, target, totals)
+		}
+	})
+
+	gologger.Infof("Dry run of %d templates on %d targets: %d http, %d dns, %d headless, %d network, %d websocket and %d ssl requests would be sent and %d files read, %d listed\n", len(loaded.parsed)-skippedWorkflows, totals.targets, totals.http, totals.dns, totals.headless, totals.network, totals.websocket, totals.ssl, totals.file, totals.listed)
+	if skippedWorkflows > 0 {
+		gologger.Infof("The requests of %d workflows are not listed, their templates running depending on the matches\n", skippedWorkflows)
+	}
+	r.closeRequestExport()
+	return nil
+}
+
+// dryRunTemplate lists the requests of a template to a target, adding them
+// to the totals.
+func (r *Runner) dryRunTemplate(t *dryRunTemplate, target string, totals *dryRunTotals) {
+	for i, dnsExecuter := range t.executers.dns {
+		if !dnsApplies(target, t.executers.dnsRequests[i]) {
+			continue
+		}
+		plan, err := dnsExecuter.PlanDNS(target, nil, r.options.DryRunLimit)
+		if err != nil {
+			gologger.Warningf("[%s] Could not list the dns requests to %s: %s\n", strings.Join(t.ids, ","), target, err)
+			continue
+		}
+		totals.dns += plan.Total
+		totals.listed += int64(len(plan.Requests))
+		r.writeDryRunEntry(&dryRunEntry{Templates: t.ids, Protocol: "dns", Target: target, Plan: plan})
+	}
+	// the network requests are the only ones of their templates, sent to
+	// the target as is
+	for _, networkExecuter := range t.executers.network {
+		plan, err := networkExecuter.PlanNetwork(target, nil, r.options.DryRunLimit)
+		if err != nil {
+			gologger.Warningf("[%s] Could not list the network steps to %s: %s\n", strings.Join(t.ids, ","), target, err)
+			continue
+		}
+		totals.network += plan.Total
+		totals.listed += int64(len(plan.Requests))
+		r.writeDryRunEntry(&dryRunEntry{Templates: t.ids, Protocol: "network", Target: target, Plan: plan})
+	}
+	// the ssl requests are the only ones of their templates too, their
+	// handshakes being run with the target as is
+	for _, sslExecuter := range t.executers.ssl {
+		plan, err := sslExecuter.PlanSSL(target, nil, r.options.DryRunLimit)
+		if err != nil {
+			gologger.Warningf("[%s] Could not list the ssl handshakes with %s: %s\n", strings.Join(t.ids, ","), target, err)
+			continue
+		}
+		totals.ssl += plan.Total
+		totals.listed += int64(len(plan.Requests))
+		r.writeDryRunEntry(&dryRunEntry{Templates: t.ids, Protocol: "ssl", Target: target, Plan: plan})
+	}
+	// the local files of the target are walked to list the ones read
+	for _, fileExecuter := range t.executers.file {
+		plan, err := fileExecuter.PlanFile(target, r.options.DryRunLimit)
+		if err != nil {
+			gologger.Warningf("[%s] Could not list the files of %s: %s\n", strings.Join(t.ids, ","), target, err)
+			continue
+		}
+		totals.file += plan.Total
+		totals.listed += int64(len(plan.Requests))
+		r.writeDryRunEntry(&dryRunEntry{Templates: t.ids, Protocol: "file", Target: target, Plan: plan})
+	}
+
+	if len(t.executers.http)+len(t.executers.headless)+len(t.executers.websocket) == 0 {
+		return
+	}
+	URL, probed := target, []string(nil)
+	if r.prober != nil && !hasScheme(target) {
+		probed = r.prober.schemesFor(target)
+		URL = probed[0] + "://" + target
+	}
+	for _, httpExecuter := range t.executers.http {
+		plan, err := httpExecuter.PlanHTTP(URL, nil, r.options.DryRunLimit)
+		if err != nil {
+			gologger.Warningf("[%s] Could not list the http requests to %s: %s\n", strings.Join(t.ids, ","), URL, err)
+			continue
+		}
+		totals.http += plan.Total
+		totals.listed += int64(len(plan.Requests))
+		r.writeDryRunEntry(&dryRunEntry{Templates: t.ids, Protocol: "http", Target: URL, Probed: probed, Plan: plan})
+		// all the requests are exported, whatever the limit of the listing
+		if r.requestExporter != nil {
+			if _, err := httpExecuter.ExportHTTP(URL, nil); err != nil {
+				gologger.Warningf("[%s] Could not export the http requests to %s: %s\n", strings.Join(t.ids, ","), URL, err)
+			}
+		}
+	}
+	// the headless requests are the only ones of their templates
+	for _, headlessExecuter := range t.executers.headless {
+		plan, err := headlessExecuter.PlanHeadless(URL, nil)
+		if err != nil {
+			gologger.Warningf("[%s] Could not list the headless steps to %s: %s\n", strings.Join(t.ids, ","), URL, err)
+			continue
+		}
+		totals.headless += plan.Total
+		totals.listed += int64(len(plan.Requests))
+		r.writeDryRunEntry(&dryRunEntry{Templates: t.ids, Protocol: "headless", Target: URL, Probed: probed, Plan: plan})
+	}
+	// the websocket requests are the only ones of their templates
+	for _, websocketExecuter := range t.executers.websocket {
+		plan, err := websocketExecuter.PlanWebsocket(URL, nil, r.options.DryRunLimit)
+		if err != nil {
+			gologger.Warningf("[%s] Could not list the websocket requests to %s: %s\n", strings.Join(t.ids, ","), URL, err)
+			continue
+		}
+		totals.websocket += plan.Total
+		totals.listed += int64(len(plan.Requests))
+		r.writeDryRunEntry(&dryRunEntry{Templates: t.ids, Protocol: "websocket", Target: URL, Probed: probed, Plan: plan})
+	}
+}
+
+// writeDryRunEntry writes the requests of a template to a target, as a json
+// line with -json, along with the raw requests with -v otherwise.
+func (r *Runner) writeDryRunEntry(entry *dryRunEntry) {
+	if r.options.JSON {
+		data, err := jsoniter.Marshal(entry)
+		if err != nil {
+			gologger.Warningf("Could not marshal the requests to %s: %s\n", entry.Target, err)
+			return
+		}
+		// gologger formats the message once more, the urls holding % escapes
+		gologger.Silentf("%s\n", strings.Replace(string(data), "%", "%%", -1))
+		return
+	}
+
+	builder := &strings.Builder{}
+	fmt.Fprintf(builder, "[%s] [%s] %s (%d of %d requests", strings.Join(entry.Templates, ","), entry.Protocol, entry.Target, len(entry.Requests), entry.Total)
+	if len(entry.Probed) > 0 {
+		fmt.Fprintf(builder, ", probing %s", strings.Join(entry.Probed, " then "))
+	}
+	if len(entry.Resolvers) > 0 {
+		fmt.Fprintf(builder, ", to %s", strings.Join(entry.Resolvers, ", "))
+	}
+	builder.WriteString(")\n")
+	for _, request := range entry.Requests {
+		fmt.Fprintf(builder, "  %s %s\n", request.Method, request.URL)
+		for _, note := range request.Notes {
+			fmt.Fprintf(builder, "    # %s\n", note)
+		}
+		if r.options.Verbose {
+			for _, line := range strings.Split(strings.TrimRight(request.Raw, "\r\n"), "\n") {
+				fmt.Fprintf(builder, "    %s\n", strings.TrimRight(line, "\r"))
+			}
+			continue
+		}
+		for _, name := range request.SortedHeaders() {
+			fmt.Fprintf(builder, "    %s: %s\n", name, request.Headers[name])
+		}
+	}
+	if entry.Error != "" {
+		fmt.Fprintf(builder, "  %s, the next requests would be abandoned\n", entry.Error)
+	}
+	gologger.Silentf("%s", strings.Replace(builder.String(), "%", "%%", -1))
+}
@@ -0,0 +1,106 @@
+package runner
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/projectdiscovery/nuclei/v2/pkg/workflows"
+)
+
+// excludeRules are the templates excluded from a run by path or by id,
+// whatever the filters selecting the templates to run.
+type excludeRules struct {
+	// paths are the absolute patterns of the excluded files and directories
+	paths []string
+	// names are the patterns without a directory matching the file names
+	names []string
+	ids   map[string]struct{}
+}
+
+// newExcludeRules creates the rules of the comma separated paths and ids
+// to exclude, returning nil if both lists are empty. The relative paths
+// are resolved against both the current and the templates directories.
+func (r *Runner) newExcludeRules(paths, ids string) (*excludeRules, error) {
+	rules := &excludeRules{ids: make(map[string]struct{})}
+	for _, id := range strings.Split(ids, ",") {
+		if id = strings.TrimSpace(id); id != "" {
+			rules.ids[id] = struct{}{}
+		}
+	}
+
+	for _, pattern := range strings.Split(paths, ",") {
+		pattern = strings.TrimSpace(pattern)
+		if pattern == "" {
+			continue
+		}
+		if _, err := filepath.Match(pattern, ""); err != nil {
+			return nil, fmt.Errorf("invalid exclude pattern %s: %s", pattern, err)
+		}
+		if !strings.ContainsRune(filepath.ToSlash(pattern), '/') {
+			rules.names = append(rules.names, pattern)
+		}
+		if !r.isRelative(pattern) {
+			rules.paths = append(rules.paths, filepath.Clean(pattern))
+			continue
+		}
+		directory, err := os.Getwd()
+		if err != nil {
+			return nil, err
+		}
+		rules.paths = append(rules.paths, filepath.Join(directory, pattern))
+		if r.templatesConfig != nil {
+			rules.paths = append(rules.paths, filepath.Join(r.templatesConfig.TemplatesDirectory, pattern))
+		}
+	}
+
+	if len(rules.paths)+len(rules.ids) == 0 {
+		return nil, nil
+	}
+	return rules, nil
+}
+
+// match returns the name of the rule excluding a template by its path or
+// its id, or an empty string if the template is not excluded.
+func (e *excludeRules) match(path, id string) string {
+	if _, ok := e.ids[id]; ok && id != "" {
+		return "exclude-id"
+	}
+	if path == "" {
+		return ""
+	}
+	for _, name := range e.names {
+		if ok, _ := filepath.Match(name, filepath.Base(path)); ok {
+			return "exclude-templates"
+		}
+	}
+	// the patterns of directories exclude the files under them
+	for _, pattern := range e.paths {
+		for current := filepath.Clean(path); ; {
+			if ok, _ := filepath.Match(pattern, current); ok {
+				return "exclude-templates"
+			}
+			parent := filepath.Dir(current)
+			if parent == current {
+				break
+			}
+			current = parent
+		}
+	}
+	return ""
+}
+
+// checkWorkflowMembers warns about the templates of the tree of a workflow
+// which are filtered out or excluded, before running the workflow.
+func (r *Runner) checkWorkflowMembers(workflow *workflows.Workflow, workflowTemplates []*workflows.WorkflowTemplate) {
+	for _, workflowTemplate := range workflowTemplates {
+		for i, template := range workflowTemplate.Templates {
+			r.filteredMember(workflow, workflowTemplate.Paths[i], template)
+		}
+		r.checkWorkflowMembers(workflow, workflowTemplate.Subtemplates)
+		for _, matcher := range workflowTemplate.Matchers {
+			r.checkWorkflowMembers(workflow, matcher.Subtemplates)
+		}
+	}
+}
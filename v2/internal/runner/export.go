@@ -0,0 +1,172 @@
+package runner
+
+import (
+	"os"
+	"os/signal"
+	"syscall"
+
+	"github.com/projectdiscovery/gologger"
+)
+
+// closeExports writes the SARIF log, sends the pending results to
+// elasticsearch, the webhook, syslog and kafka, closes the csv file, files
+// the pending issues, writes the manifest of the exported requests and
+// saves the dedupe state, if any.
+func (r *Runner) closeExports(successful bool) {
+	r.closeSarif(successful)
+	r.closeElastic()
+	r.closeWebhook()
+	r.closeSyslog()
+	r.closeKafka()
+	r.closeCSV()
+	r.closeReporting()
+	r.closeRequestExport()
+	r.saveDedupeState()
+}
+
+// closeSarif writes the SARIF log of the results of the scan if any
+func (r *Runner) closeSarif(successful bool) {
+	if r.sarif == nil {
+		return
+	}
+	if err := r.sarif.Close(successful); err != nil {
+		gologger.Errorf("Could not write SARIF log to %s: %s\n", r.sarif.Name(), err)
+		return
+	}
+	gologger.Labelf("Wrote %d findings to the SARIF log %s\n", r.sarif.Count(), r.sarif.Name())
+}
+
+// closeElastic sends the pending results to elasticsearch if used
+func (r *Runner) closeElastic() {
+	if r.elastic == nil {
+		return
+	}
+	r.elastic.Close()
+	gologger.Labelf("Indexed %d results in elasticsearch\n", r.elastic.Indexed())
+	if dropped := r.elastic.Dropped(); dropped > 0 {
+		gologger.Labelf("Dropped %d results as the elasticsearch queue was full, use a larger queue-size\n", dropped)
+	}
+	if failed := r.elastic.Failed(); failed > 0 {
+		gologger.Labelf("Could not index %d results, they were written to %s\n", failed, r.elastic.DeadLetter())
+	}
+}
+
+// closeWebhook posts the pending results to the webhook if used
+func (r *Runner) closeWebhook() {
+	if r.webhook == nil {
+		return
+	}
+	r.webhook.Close()
+	gologger.Labelf("Posted %d results to the webhook\n", r.webhook.Sent())
+	if dropped := r.webhook.Dropped(); dropped > 0 {
+		gologger.Labelf("Dropped %d results as the webhook queue was full, use a larger queue-size\n", dropped)
+	}
+	if failed := r.webhook.Failed(); failed > 0 {
+		gologger.Labelf("Could not post %d results to the webhook\n", failed)
+	}
+}
+
+// closeSyslog sends the pending results to the syslog collector if used
+func (r *Runner) closeSyslog() {
+	if r.syslog == nil {
+		return
+	}
+	r.syslog.Close()
+	gologger.Labelf("Sent %d results to syslog\n", r.syslog.Sent())
+	if dropped := r.syslog.Dropped(); dropped > 0 {
+		gologger.Labelf("Dropped %d results as the syslog queue was full, use a larger queue-size\n", dropped)
+	}
+	if failed := r.syslog.Failed(); failed > 0 {
+		gologger.Labelf("Could not send %d results as the syslog collector could not be reached\n", failed)
+	}
+}
+
+// closeKafka publishes the pending results to kafka if used
+func (r *Runner) closeKafka() {
+	if r.kafka == nil {
+		return
+	}
+	r.kafka.Close()
+	gologger.Labelf("Published %d results to the kafka topic %s\n", r.kafka.Published(), r.kafka.Topic())
+	if dropped := r.kafka.Dropped(); dropped > 0 {
+		gologger.Labelf("Wrote %d results to %s as the kafka queue was full, use a larger queue-size or a queue-wait\n", dropped, r.kafka.DeadLetter())
+	}
+	if failed := r.kafka.Failed(); failed > 0 {
+		gologger.Labelf("Could not publish %d results, they were written to %s\n", failed, r.kafka.DeadLetter())
+	}
+}
+
+// closeCSV closes the csv file of the results if any
+func (r *Runner) closeCSV() {
+	if r.csv == nil {
+		return
+	}
+	if err := r.csv.Close(); err != nil {
+		gologger.Errorf("Could not close csv file %s: %s\n", r.csv.Name(), err)
+		return
+	}
+	gologger.Labelf("Wrote %d findings to the csv file %s\n", r.csv.Count(), r.csv.Name())
+}
+
+// closeReporting files the pending issues in the issue tracker if used
+func (r *Runner) closeReporting() {
+	if r.reporting == nil {
+		return
+	}
+	r.reporting.Close()
+	if r.options.ReportDryRun {
+		gologger.Labelf("Would have filed %d issues and commented on %d issues in %s\n", r.reporting.Created(), r.reporting.Commented(), r.reporting.Tracker())
+	} else {
+		gologger.Labelf("Filed %d issues and commented on %d issues in %s\n", r.reporting.Created(), r.reporting.Commented(), r.reporting.Tracker())
+	}
+	if dropped := r.reporting.Dropped(); dropped > 0 {
+		gologger.Labelf("Dropped %d findings as the reporting queue was full, use a larger queue-size\n", dropped)
+	}
+	if failed := r.reporting.Failed(); failed > 0 {
+		gologger.Labelf("Could not report %d findings to %s\n", failed, r.reporting.Tracker())
+	}
+}
+
+// saveDedupeState saves the fingerprints of the findings to the dedupe
+// state if any, for the next runs to report only the new findings.
+func (r *Runner) saveDedupeState() {
+	if r.deduper == nil || r.deduper.State() == "" {
+		return
+	}
+	if err := r.deduper.Save(); err != nil {
+		gologger.Errorf("Could not save dedupe state to %s: %s\n", r.deduper.State(), err)
+	}
+}
+
+// closeRequestExport writes the manifest of the requests exported with
+// -export-requests if any
+func (r *Runner) closeRequestExport() {
+	if r.requestExporter == nil {
+		return
+	}
+	if err := r.requestExporter.Close(); err != nil {
+		gologger.Errorf("Could not write the manifest of the requests exported to %s: %s\n", r.requestExporter.Directory(), err)
+		return
+	}
+	gologger.Labelf("Exported %d requests to %s\n", r.requestExporter.Count(), r.requestExporter.Directory())
+}
+
+// closeExportsOnInterrupt shows the summary of the scan and closes the
+// exports with the results so far when the scan is interrupted, exiting
+// afterwards.
+func (r *Runner) closeExportsOnInterrupt() {
+	signals := make(chan os.Signal, 1)
+	signal.Notify(signals, os.Interrupt, syscall.SIGTERM)
+	<-signals
+	gologger.Labelf("Interrupted, closing the exports of the results\n")
+	r.grouper.Flush()
+	if r.checkpoint != nil {
+		r.saveCheckpoint()
+		gologger.Labelf("Wrote the checkpoint of the scan to %s, run the scan again with the same flags to resume it\n", r.checkpoint.Path())
+	}
+	r.logSummary(true)
+	r.closeExports(false)
+	// the last snapshot counts the results published on close
+	r.stopStream()
+	os.Exit(1)
+}
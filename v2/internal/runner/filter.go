@@ -0,0 +1,55 @@
+package runner
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/projectdiscovery/gologger"
+	"github.com/projectdiscovery/nuclei/v2/pkg/templates"
+	"github.com/projectdiscovery/nuclei/v2/pkg/workflows"
+)
+
+// filterReasons returns the numbers of templates filtered out by each filter
+func filterReasons(filtered map[string]int) string {
+	if len(filtered) == 0 {
+		return ""
+	}
+	names := make([]string, 0, len(filtered))
+	for name := range filtered {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	reasons := make([]string, 0, len(names))
+	for _, name := range names {
+		reasons = append(reasons, fmt.Sprintf("%d by %s", filtered[name], name))
+	}
+	return " (" + strings.Join(reasons, ", ") + ")"
+}
+
+// filteredMember returns true if a template of a workflow is excluded or
+// filtered out, warning once about each of the skipped templates.
+func (r *Runner) filteredMember(workflow *workflows.Workflow, path string, template *templates.Template) bool {
+	if r.excludes != nil {
+		if rule := r.excludes.match(path, template.ID); rule != "" {
+			if _, warned := r.filteredMembers.LoadOrStore(workflow.ID+":"+path, struct{}{}); !warned {
+				gologger.Labelf("Template %s of workflow %s was excluded by %s\n", path, workflow.ID, rule)
+			}
+			return true
+		}
+	}
+	reason := r.protocolSkipReason(template)
+	if reason == "" && r.filter != nil {
+		if ok, filtered := r.filter.Match(&template.Info); !ok {
+			reason = filtered
+		}
+	}
+	if reason == "" {
+		return false
+	}
+	if _, warned := r.filteredMembers.LoadOrStore(workflow.ID+":"+path, struct{}{}); !warned {
+		gologger.Warningf("Template %s of workflow %s was filtered out by %s\n", path, workflow.ID, reason)
+	}
+	return true
+}
@@ -0,0 +1,195 @@
+package runner
+
+import (
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"github.com/projectdiscovery/gologger"
+	"github.com/projectdiscovery/nuclei/v2/pkg/executer"
+	"github.com/projectdiscovery/nuclei/v2/pkg/templates"
+	"gopkg.in/yaml.v2"
+)
+
+// fixtureExpectation is the expected outcome of a template on a recorded
+// response, read from the yaml file named like the fixture, i.e
+// vulnerable.yaml for vulnerable.http.
+type fixtureExpectation struct {
+	// ShouldMatch is whether the template has results on the fixture, true by default
+	ShouldMatch *bool `yaml:"should-match,omitempty"`
+	// Extracted are the values expected from the named extractors
+	Extracted map[string][]string `yaml:"extracted,omitempty"`
+	// Request is the 1-based request of the template the fixture is the
+	// response to, the internal matchers applying to some, 1 by default.
+	Request int `yaml:"request,omitempty"`
+}
+
+// TestTemplates runs the http matchers and extractors of the templates of
+// the user input on the recorded responses of the fixtures directory,
+// without any network I/O, and returns false if any fixture failed. The
+// fixtures of a template are in the subdirectory named like its id if
+// any, the fixtures directory itself otherwise.
+func (r *Runner) TestTemplates() (bool, error) {
+	paths := r.templatePaths()
+	if len(paths) == 0 {
+		return false, ErrNoTemplates
+	}
+
+	var total, failed int
+	for _, path := range paths {
+		parsed, err := r.parse(path)
+		if err != nil {
+			writeProblem(path, err)
+			failed++
+			continue
+		}
+		template, ok := parsed.(*templates.Template)
+		if !ok || len(template.BulkRequestsHTTP) == 0 {
+			gologger.Labelf("Skipping %s, only the http requests of templates can be tested\n", path)
+			continue
+		}
+
+		directory := filepath.Join(r.options.TestFixtures, template.ID)
+		if info, err := os.Stat(directory); err != nil || !info.IsDir() {
+			directory = r.options.TestFixtures
+		}
+		fixtures, err := fixtureFiles(directory)
+		if err != nil {
+			return false, fmt.Errorf("could not read fixtures: %s", err)
+		}
+		if len(fixtures) == 0 {
+			gologger.Labelf("No fixtures found for template %s in %s\n", template.ID, directory)
+			continue
+		}
+		for _, fixture := range fixtures {
+			total++
+			if err := testFixture(template, fixture); err != nil {
+				gologger.Silentf("[%s] %s: fail, %s\n", template.ID, fixture, err)
+				failed++
+				continue
+			}
+			gologger.Silentf("[%s] %s: pass\n", template.ID, fixture)
+		}
+	}
+
+	if failed > 0 {
+		gologger.Labelf("%d of %d fixtures failed\n", failed, total)
+		return false, nil
+	}
+	if total == 0 {
+		gologger.Labelf("No fixtures were tested\n")
+		return false, nil
+	}
+	gologger.Labelf("All %d fixtures passed\n", total)
+	return true, nil
+}
+
+// fixtureFiles returns the recorded responses of a directory, the files
+// not being yaml expectations.
+func fixtureFiles(directory string) ([]string, error) {
+	infos, err := ioutil.ReadDir(directory)
+	if err != nil {
+		return nil, err
+	}
+	var fixtures []string
+	for _, info := range infos {
+		extension := filepath.Ext(info.Name())
+		if info.IsDir() || extension == ".yaml" || extension == ".yml" {
+			continue
+		}
+		fixtures = append(fixtures, filepath.Join(directory, info.Name()))
+	}
+	return fixtures, nil
+}
+
+// testFixture evaluates the http requests of a template on a recorded
+// response, returning an error if the outcome isn't the expected one.
+func testFixture(template *templates.Template, fixture string) error {
+	expectation, err := readExpectation(fixture)
+	if err != nil {
+		return err
+	}
+	data, err := ioutil.ReadFile(fixture)
+	if err != nil {
+		return err
+	}
+	response, err := executer.ReadHTTPResponse(data)
+	if err != nil {
+		return fmt.Errorf("could not read response: %s", err)
+	}
+	if expectation.Request > 0 {
+		response.Position = expectation.Request - 1
+	}
+
+	var matched bool
+	extracted := make(map[string][]string)
+	for _, request := range template.BulkRequestsHTTP {
+		evaluation := executer.EvaluateHTTP(request, response, make(map[string]interface{}))
+		matched = matched || evaluation.HasResults()
+		for i, matches := range evaluation.Extractions {
+			if name := request.Extractors[i].Name; name != "" {
+				extracted[name] = append(extracted[name], matches...)
+			}
+		}
+	}
+
+	shouldMatch := expectation.ShouldMatch == nil || *expectation.ShouldMatch
+	if matched && !shouldMatch {
+		return fmt.Errorf("matched while it should not")
+	}
+	if !matched && shouldMatch {
+		return fmt.Errorf("did not match while it should")
+	}
+	for name, expected := range expectation.Extracted {
+		if !sameValues(extracted[name], expected) {
+			return fmt.Errorf("extracted %s [%s] instead of [%s]", name, strings.Join(extracted[name], ", "), strings.Join(expected, ", "))
+		}
+	}
+	return nil
+}
+
+// readExpectation reads the expectation of a fixture, the default one
+// being used if it has none.
+func readExpectation(fixture string) (*fixtureExpectation, error) {
+	expectation := &fixtureExpectation{}
+	data, err := ioutil.ReadFile(strings.TrimSuffix(fixture, filepath.Ext(fixture)) + ".yaml")
+	if os.IsNotExist(err) {
+		return expectation, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	if err := yaml.UnmarshalStrict(data, expectation); err != nil {
+		return nil, fmt.Errorf("could not parse expectation: %s", err)
+	}
+	return expectation, nil
+}
+
+// sameValues returns true if two lists have the same distinct values
+func sameValues(values, expected []string) bool {
+	distinct := func(list []string) []string {
+		set := make(map[string]struct{})
+		var result []string
+		for _, value := range list {
+			if _, ok := set[value]; !ok {
+				set[value] = struct{}{}
+				result = append(result, value)
+			}
+		}
+		sort.Strings(result)
+		return result
+	}
+	a, b := distinct(values), distinct(expected)
+	if len(a) != len(b) {
+		return false
+	}
+	for i := range a {
+		if a[i] != b[i] {
+			return false
+		}
+	}
+	return true
+}
@@ -0,0 +1,72 @@
+package runner
+
+import (
+	"time"
+
+	"github.com/projectdiscovery/gologger"
+	"github.com/projectdiscovery/nuclei/v2/pkg/headless"
+	"github.com/projectdiscovery/nuclei/v2/pkg/templates"
+)
+
+// protocolSkipReason returns the reason a template is skipped for the
+// headless browser or the interactsh server it depends on, empty if it runs.
+func (r *Runner) protocolSkipReason(template *templates.Template) string {
+	if reason := r.headlessSkipReason(template); reason != "" {
+		return reason
+	}
+	return r.interactshSkipReason(template)
+}
+
+// headlessSkipReason returns the reason a template with headless requests
+// is skipped, empty if it runs. The browser is launched once the first
+// template using it is loaded for a scan with -headless, the templates
+// using it being skipped if it can't be launched.
+func (r *Runner) headlessSkipReason(template *templates.Template) string {
+	if len(template.RequestsHeadless) == 0 {
+		return ""
+	}
+	if !r.options.Headless {
+		return "headless disabled"
+	}
+	// the listings and the dry runs launch no browser, the passive runs
+	// skipping the templates with headless requests
+	if r.options.TemplateList || r.options.DryRun || r.options.Passive != "" {
+		return ""
+	}
+	r.browserOnce.Do(func() {
+		path := r.options.HeadlessBrowser
+		if path == "" {
+			var err error
+			if path, err = headless.FindBrowser(); err != nil {
+				gologger.Errorf("Could not launch the headless browser, skipping the templates using it: %s\n", err)
+				return
+			}
+		}
+		proxy := r.options.ProxyURL
+		if proxy == "" {
+			proxy = r.options.ProxySocksURL
+		}
+		// the forced headers override the custom ones, as for http
+		headers := r.options.CustomHeaders.Headers()
+		for name, value := range r.options.ForcedHeaders.Headers() {
+			headers[name] = value
+		}
+		pool := headless.NewPool(&headless.Options{
+			Browser:     path,
+			Concurrency: r.options.HeadlessConcurrency,
+			Proxy:       proxy,
+			Headers:     headers,
+			StepTimeout: time.Duration(r.options.Timeout) * time.Second,
+		})
+		if err := pool.Start(); err != nil {
+			gologger.Errorf("Could not launch the headless browser %s, skipping the templates using it: %s\n", path, err)
+			return
+		}
+		r.browser = pool
+		gologger.Infof("Using the headless browser %s\n", path)
+	})
+	if r.browser == nil {
+		return "headless unavailable"
+	}
+	return ""
+}
@@ -0,0 +1,36 @@
+package runner
+
+import (
+	"fmt"
+
+	"github.com/projectdiscovery/gologger"
+	"github.com/projectdiscovery/nuclei/v2/pkg/templates"
+)
+
+// indexTemplate adds a template or a workflow to the index of the ids,
+// returning false if its id is already used by another file, the later
+// file being skipped. An error is returned instead in strict mode.
+func (r *Runner) indexTemplate(id, path string) (bool, error) {
+	if err := templates.ValidateID(id); err != nil {
+		if r.options.Strict {
+			return false, fmt.Errorf("invalid template '%s': %s", path, err)
+		}
+		gologger.Warningf("Template '%s': %s\n", path, err)
+	}
+
+	if previous, ok := r.templateIDs[id]; ok {
+		if r.options.Strict {
+			return false, fmt.Errorf("template '%s' has the id %s of '%s'", path, id, previous)
+		}
+		gologger.Warningf("Skipping template '%s', its id %s is already used by '%s'\n", path, id, previous)
+		return false, nil
+	}
+	r.templateIDs[id] = path
+	return true, nil
+}
+
+// TemplatePath returns the path of a loaded template or workflow by id
+func (r *Runner) TemplatePath(id string) (string, bool) {
+	path, ok := r.templateIDs[id]
+	return path, ok
+}
@@ -0,0 +1,296 @@
+package runner
+
+import (
+	"bufio"
+	"context"
+	"net"
+	"os"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/projectdiscovery/gologger"
+	"github.com/projectdiscovery/nuclei/v2/pkg/inputs"
+)
+
+// streamsTargets returns true if the targets are streamed from stdin as
+// they arrive, neither a list of targets nor a single one being given. The
+// dry run reads them at once.
+func streamsTargets(options *Options) bool {
+	return options.Stdin && options.Targets == "" && options.Target == "" && !options.DryRun
+}
+
+// newTargetStream returns the reader of the targets streamed from stdin,
+// deduplicating the most recent ones unless -no-dedupe is used.
+func newTargetStream(options *Options) *inputs.Reader {
+	var deduper *inputs.Deduper
+	if !options.NoDedupe {
+		deduper = inputs.NewDeduper(inputs.DefaultDedupeSize)
+	}
+	return inputs.NewReader(os.Stdin, deduper)
+}
+
+// parseExcludedHosts returns the hosts of the input not to scan, the ones
+// of -exclude-hosts and of -exclude-file, resolving the hosts of the input
+// with -exclude-resolved.
+func parseExcludedHosts(options *Options) (*inputs.Exclusions, error) {
+	exclusions, err := inputs.ParseExclusions(options.ExcludeHosts)
+	if err != nil {
+		return nil, err
+	}
+	if options.ExcludeFile != "" {
+		if err := exclusions.AddFile(options.ExcludeFile); err != nil {
+			return nil, err
+		}
+	}
+	if options.ExcludeResolved {
+		timeout := time.Duration(options.Timeout) * time.Second
+		exclusions.Resolve(func(host string) ([]string, error) {
+			ctx, cancel := context.WithTimeout(context.Background(), timeout)
+			defer cancel()
+			return net.DefaultResolver.LookupHost(ctx, host)
+		})
+	}
+	return exclusions, nil
+}
+
+// excludedTargets are the numbers of the targets of the input excluded by
+// rule, counted once while counting the targets of the input.
+type excludedTargets struct {
+	rules map[string]uint64
+}
+
+// add counts a target excluded by a rule
+func (e *excludedTargets) add(target, rule string) {
+	gologger.Verbosef("Skipping %s excluded by %s\n", "exclude", target, rule)
+	e.rules[rule]++
+}
+
+// targetExcluded counts a streamed target excluded by a rule
+func (r *Runner) targetExcluded(target, rule string) {
+	gologger.Verbosef("Skipping %s excluded by %s\n", "exclude", target, rule)
+	r.stats.TargetsExcluded(rule, 1)
+}
+
+// warnAllExcluded warns that no target is scanned if all the targets of the
+// input were excluded, returning true if it did.
+func (r *Runner) warnAllExcluded(targets int64) bool {
+	summary := r.stats.Summary(0, false)
+	if targets > 0 || summary.ExcludedTargets == 0 {
+		return false
+	}
+	gologger.Labelf("All the %d targets of the input are excluded by -exclude-hosts and -exclude-file (%s), nothing is scanned\n", summary.ExcludedTargets, formatStatsCounts(summary.ExclusionRules))
+	return true
+}
+
+// streamedLine is a line of the streamed targets along with the number of
+// runs of its targets left to complete, expanding being true until all its
+// targets are tracked.
+type streamedLine struct {
+	consumed  int64
+	pending   int64
+	expanding bool
+}
+
+// streamedRun are the runs of a target of a streamed line left to complete
+type streamedRun struct {
+	line    *streamedLine
+	pending int64
+}
+
+// streamTracker tracks the lines of the streamed targets until all the
+// runs of their targets complete, the lines completed in order being
+// recorded as consumed in the checkpoint if any.
+type streamTracker struct {
+	mutex sync.Mutex
+	// steps is the number of runs of the templates on each target
+	steps int64
+	// lines are the lines not completed, in order, and targets the runs
+	// by target, of several lines with -no-dedupe.
+	lines   []*streamedLine
+	targets map[string][]*streamedRun
+	// failed are the steps which could not be executed, completed on each
+	// target once streamed.
+	failed []string
+}
+
+// eachInput calls a function with each target of the input in turn, the
+// cidr ranges and the ports of its lines being expanded as they are read.
+func (r *Runner) eachInput(fn func(target string)) {
+	scanner := bufio.NewScanner(strings.NewReader(r.input))
+	for scanner.Scan() {
+		// the lines were expanded once when counting the targets
+		_ = r.expander.Expand(scanner.Text(), fn, nil)
+	}
+}
+
+// expandedInput returns the targets of the input, one per line
+func (r *Runner) expandedInput() string {
+	var sb strings.Builder
+	r.eachInput(func(target string) {
+		sb.WriteString(target)
+		sb.WriteString("\n")
+	})
+	return sb.String()
+}
+
+// eachTarget calls a function with each target in turn, the targets of
+// the input or the ones streamed from stdin as they arrive, until the end
+// of the stream or until the scan stops.
+func (r *Runner) eachTarget(fn func(target string)) {
+	if r.targetStream == nil {
+		r.eachInput(fn)
+		return
+	}
+	// the lines are read aside, the scan stopping while a line is awaited
+	type read struct {
+		line     string
+		consumed int64
+	}
+	lines := make(chan read)
+	go func() {
+		defer close(lines)
+		var duplicates int64
+		for {
+			line, consumed, ok := r.targetStream.Next()
+			// the duplicates skipped before the line are counted as they are read
+			if skipped := r.targetStream.Duplicates(); skipped > duplicates {
+				r.stats.TargetsDuplicated(uint64(skipped - duplicates))
+				duplicates = skipped
+			}
+			if !ok {
+				return
+			}
+			select {
+			case lines <- read{line: line, consumed: consumed}:
+			case <-r.stopped:
+				return
+			}
+		}
+	}()
+	for {
+		var next read
+		var ok bool
+		select {
+		case next, ok = <-lines:
+		case <-r.stopped:
+		}
+		if !ok {
+			break
+		}
+		line := r.streamed.add(next.consumed)
+		err := r.expander.Expand(next.line, func(target string) {
+			if r.truncated.Get() {
+				return
+			}
+			r.stats.AddTarget()
+			r.grouper.Expect(target, r.streamed.steps)
+			for _, step := range r.streamed.track(line, target) {
+				r.completeStep(step, target)
+			}
+			fn(target)
+		}, r.targetExcluded)
+		if err != nil {
+			gologger.Errorf("Could not expand the streamed target: %s, use -cidr-limit to change the limit\n", err)
+		}
+		// the line is left to scan again if its targets were not all run
+		if r.truncated.Get() {
+			break
+		}
+		r.checkpoint.Consume(r.streamed.expanded(line))
+	}
+	if err := r.targetStream.Err(); err != nil && !r.truncated.Get() {
+		gologger.Errorf("Could not read the targets from stdin: %s\n", err)
+	}
+}
+
+// add tracks a streamed line whose targets are being expanded
+func (t *streamTracker) add(consumed int64) *streamedLine {
+	t.mutex.Lock()
+	defer t.mutex.Unlock()
+
+	line := &streamedLine{consumed: consumed, expanding: true}
+	t.lines = append(t.lines, line)
+	return line
+}
+
+// track tracks the runs of a target of a streamed line, returning the
+// steps which could not be executed to complete on it.
+func (t *streamTracker) track(line *streamedLine, target string) []string {
+	t.mutex.Lock()
+	defer t.mutex.Unlock()
+
+	line.pending += t.steps
+	t.targets[target] = append(t.targets[target], &streamedRun{line: line, pending: t.steps})
+	return t.failed
+}
+
+// expanded records that all the targets of a streamed line are tracked,
+// returning the number of lines consumed before the first line left to
+// complete, 0 if none completed.
+func (t *streamTracker) expanded(line *streamedLine) int64 {
+	t.mutex.Lock()
+	defer t.mutex.Unlock()
+
+	line.expanding = false
+	return t.advance()
+}
+
+// fail records a step which could not be executed on the streamed targets
+func (t *streamTracker) fail(step string) {
+	t.mutex.Lock()
+	defer t.mutex.Unlock()
+
+	t.failed = append(t.failed, step)
+}
+
+// complete counts a run of a target as completed, the one of its oldest
+// line. It returns the number of lines consumed before the first line left
+// to complete, 0 if none completed, along with true if all the runs of the
+// target completed.
+func (t *streamTracker) complete(target string) (int64, bool) {
+	t.mutex.Lock()
+	defer t.mutex.Unlock()
+
+	runs := t.targets[target]
+	if len(runs) == 0 {
+		return 0, false
+	}
+	runs[0].pending--
+	runs[0].line.pending--
+	if runs[0].pending > 0 {
+		return 0, false
+	}
+	if len(runs) > 1 {
+		t.targets[target] = runs[1:]
+		return t.advance(), false
+	}
+	delete(t.targets, target)
+	return t.advance(), true
+}
+
+// advance forgets the lines completed in order, returning the number of
+// lines consumed once the last of them was read, 0 if none completed.
+func (t *streamTracker) advance() int64 {
+	var consumed int64
+	for len(t.lines) > 0 && !t.lines[0].expanding && t.lines[0].pending <= 0 {
+		consumed = t.lines[0].consumed
+		t.lines[0] = nil
+		t.lines = t.lines[1:]
+	}
+	return consumed
+}
+
+// completeStreamedStep counts a run on a streamed target as completed,
+// recording the lines consumed in the checkpoint. The scheme probed for
+// the target and the positions of its requests are forgotten once all its
+// runs completed.
+func (r *Runner) completeStreamedStep(target string) {
+	consumed, completed := r.streamed.complete(target)
+	if consumed > 0 {
+		r.checkpoint.Consume(consumed)
+	}
+	if completed {
+		r.checkpoint.Forget(target, r.prober.forget(target))
+	}
+}
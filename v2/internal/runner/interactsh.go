@@ -0,0 +1,105 @@
+package runner
+
+import (
+	"time"
+
+	"github.com/projectdiscovery/gologger"
+	"github.com/projectdiscovery/nuclei/v2/pkg/interactsh"
+	"github.com/projectdiscovery/nuclei/v2/pkg/templates"
+)
+
+// interactshSkipReason returns the reason a template using interactsh is
+// skipped, empty if it runs. The client of the interactsh server is
+// registered once the first template using it is loaded for a scan, the
+// templates using it being skipped if the server can't be reached.
+func (r *Runner) interactshSkipReason(template *templates.Template) string {
+	if !template.UsesInteractsh() {
+		return ""
+	}
+	if r.options.NoInteractsh {
+		return "no-interactsh"
+	}
+	// the listings and the dry runs send no requests, the passive runs
+	// skipping the templates using interactsh
+	if r.options.TemplateList || r.options.DryRun || r.options.Passive != "" {
+		return ""
+	}
+	r.interactshOnce.Do(func() {
+		if r.options.OOBListen != "" {
+			r.listenInteractions()
+			return
+		}
+		client, err := interactsh.New(interactsh.Options{
+			Server:       r.options.InteractshServer,
+			Token:        r.options.InteractshToken,
+			CacheSize:    r.options.InteractionsCacheSize,
+			PollInterval: r.options.InteractionsPoll,
+			Timeout:      time.Duration(r.options.Timeout) * time.Second,
+		})
+		if err != nil {
+			gologger.Errorf("Could not register with the interactsh server %s, skipping the templates using it: %s\n", r.options.InteractshServer, err)
+			return
+		}
+		r.interactsh = client
+		gologger.Infof("Using the interactsh server %s\n", client.Server())
+	})
+	if r.interactsh == nil {
+		return "interactsh unavailable"
+	}
+	return ""
+}
+
+// listenInteractions starts the built-in listener of -oob-listen receiving
+// the interactions instead of the interactsh server, the templates using
+// interactsh being skipped if it can't listen.
+func (r *Runner) listenInteractions() {
+	client, err := interactsh.Listen(interactsh.ListenerOptions{
+		Address:    r.options.OOBListen,
+		Domain:     r.options.OOBDomain,
+		DNSAddress: r.options.OOBDNSListen,
+		Log:        r.options.OOBLog,
+		CacheSize:  r.options.InteractionsCacheSize,
+	})
+	if err != nil {
+		gologger.Errorf("Could not start the interaction listener on %s, skipping the templates using interactsh: %s\n", r.options.OOBListen, err)
+		return
+	}
+	r.interactsh = client
+	if r.options.OOBDomain != "" {
+		gologger.Infof("Listening for the interactions on %s for %s\n", r.options.OOBListen, client.Server())
+	} else {
+		gologger.Infof("Listening for the interactions on %s\n", r.options.OOBListen)
+	}
+}
+
+// closeInteractsh polls the interactions for the cooldown period once the
+// templates ran, unless the scan was stopped, then deregisters the client
+// and shows the numbers of interactions received.
+func (r *Runner) closeInteractsh() {
+	if r.interactsh == nil {
+		return
+	}
+	cooldown := r.options.InteractionsCooldown
+	if r.truncated.Get() {
+		cooldown = 0
+	}
+	if cooldown > 0 && r.interactsh.URLs() > 0 && r.interactsh.Listening() {
+		gologger.Infof("Listening for the interactions for %s after the scan\n", cooldown)
+	} else if cooldown > 0 && r.interactsh.URLs() > 0 {
+		gologger.Infof("Polling the interactions for %s after the scan\n", cooldown)
+	}
+	if err := r.interactsh.Close(cooldown); err != nil && r.interactsh.Listening() {
+		gologger.Warningf("Could not close the interaction listener on %s: %s\n", r.options.OOBListen, err)
+	} else if err != nil {
+		gologger.Warningf("Could not deregister from the interactsh server %s: %s\n", r.interactsh.Server(), err)
+	}
+	// the results of the late interactions are shown with the others
+	r.grouper.Flush()
+
+	if received := r.interactsh.Interactions(); received > 0 {
+		gologger.Labelf("Received %d interactions from %s for %d urls, %d not correlated to a request\n", received, r.interactsh.Server(), r.interactsh.URLs(), r.interactsh.Uncorrelated())
+	}
+	if evicted := r.interactsh.Evicted(); evicted > 0 {
+		gologger.Labelf("Ignored the interactions of the %d oldest interactsh urls, use -interactions-cache-size to correlate more\n", evicted)
+	}
+}
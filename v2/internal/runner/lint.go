@@ -0,0 +1,108 @@
+package runner
+
+import (
+	"fmt"
+	"io/ioutil"
+	"regexp"
+
+	"github.com/projectdiscovery/gologger"
+	"github.com/projectdiscovery/nuclei/v2/pkg/templates"
+	"github.com/projectdiscovery/nuclei/v2/pkg/workflows"
+	"gopkg.in/yaml.v2"
+)
+
+// lineRegex matches the line prefixing the yaml errors
+var lineRegex = regexp.MustCompile(`^(?:yaml: )?line ([0-9]+): `)
+
+// Validate validates the templates and workflows of the user input, writing
+// the problems of each file, and returns false if any file is invalid.
+func (r *Runner) Validate() (bool, error) {
+	paths := r.templatePaths()
+	if len(paths) == 0 {
+		return false, ErrNoTemplates
+	}
+
+	var invalid int
+	ids := make(map[string]string)
+	for _, path := range paths {
+		id, problems := r.validateFile(path)
+		// the files not signed by a trusted key are invalid in enforce mode
+		if r.trustedKeys != nil {
+			if keyID, err := templates.VerifySignature(path); err != nil && r.options.TemplateSignature == templates.SignatureEnforce {
+				problems = []error{fmt.Errorf("signature: %s", err)}
+			} else if err != nil {
+				gologger.Silentf("%s: signature: %s\n", path, err)
+			} else {
+				gologger.Silentf("%s: signed by trusted key %s\n", path, keyID)
+			}
+		}
+		if id != "" {
+			if previous, ok := ids[id]; ok {
+				problems = append(problems, fmt.Errorf("id %s is already used by %s", id, previous))
+			} else {
+				ids[id] = path
+			}
+		}
+		for _, problem := range problems {
+			writeProblem(path, problem)
+		}
+		if len(problems) > 0 {
+			invalid++
+		}
+	}
+
+	if invalid > 0 {
+		gologger.Labelf("%d of %d templates are invalid\n", invalid, len(paths))
+		return false, nil
+	}
+	gologger.Labelf("All %d templates are valid\n", len(paths))
+	return true, nil
+}
+
+// writeProblem writes a problem of a file, as file:line: message for the
+// problems of known line.
+func writeProblem(path string, problem error) {
+	message := problem.Error()
+	if match := lineRegex.FindStringSubmatch(message); match != nil {
+		gologger.Silentf("%s:%s: %s\n", path, match[1], message[len(match[0]):])
+	} else {
+		gologger.Silentf("%s: %s\n", path, message)
+	}
+}
+
+// validateFile validates a template or a workflow, the workflows being
+// the files with a logic or workflows, and returns its id along with its
+// problems, the templates of the workflows being loaded too.
+func (r *Runner) validateFile(path string) (string, []error) {
+	data, err := ioutil.ReadFile(path)
+	if err != nil {
+		return "", []error{err}
+	}
+	fields := make(map[string]interface{})
+	if err := yaml.Unmarshal(data, &fields); err != nil {
+		return "", []error{err}
+	}
+	id, _ := fields["id"].(string)
+	var problems []error
+	if err := templates.ValidateID(id); err != nil {
+		problems = append(problems, err)
+	}
+	_, logic := fields["logic"]
+	if _, ok := fields["workflows"]; ok || logic {
+		problems = append(problems, workflows.Validate(path)...)
+		if len(problems) > 0 {
+			return id, problems
+		}
+		workflow, err := workflows.Parse(path)
+		if err == nil {
+			err = workflow.Load(func(value string) (string, error) {
+				return r.resolveWorkflowPath(workflow, value)
+			})
+		}
+		if err != nil {
+			problems = append(problems, err)
+		}
+		return id, problems
+	}
+	return id, append(problems, templates.Validate(path)...)
+}
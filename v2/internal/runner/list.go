@@ -0,0 +1,158 @@
+package runner
+
+import (
+	"fmt"
+	"os"
+	"sort"
+	"strings"
+	"text/tabwriter"
+
+	jsoniter "github.com/json-iterator/go"
+	"github.com/projectdiscovery/gologger"
+	"github.com/projectdiscovery/nuclei/v2/pkg/templates"
+	"github.com/projectdiscovery/nuclei/v2/pkg/workflows"
+)
+
+// listedTemplate is a template or a workflow of the listing
+type listedTemplate struct {
+	ID       string   `json:"id"`
+	Name     string   `json:"name"`
+	Severity string   `json:"severity,omitempty"`
+	Tags     []string `json:"tags,omitempty"`
+	Protocol string   `json:"protocol"`
+	Path     string   `json:"path"`
+}
+
+// brokenTemplate is a file of the listing failing to parse
+type brokenTemplate struct {
+	Path  string `json:"path"`
+	Error string `json:"error"`
+}
+
+// templateListing is the listing of the templates, written by -json
+type templateListing struct {
+	Templates []listedTemplate `json:"templates"`
+	// Severities and Tags are the numbers of templates by severity and tag
+	Severities map[string]int   `json:"severities"`
+	Tags       map[string]int   `json:"tags"`
+	Broken     []brokenTemplate `json:"broken,omitempty"`
+}
+
+// ListTemplates lists the templates and workflows of the user input which
+// a scan with the same flags would run, along with the numbers of templates
+// by severity and tag and the files failing to parse.
+func (r *Runner) ListTemplates() error {
+	paths := r.templatePaths()
+	if len(paths) == 0 {
+		return ErrNoTemplates
+	}
+	loaded, err := r.loadTemplates(paths)
+	if err != nil {
+		return err
+	}
+
+	listing := &templateListing{
+		Templates:  []listedTemplate{},
+		Severities: make(map[string]int),
+		Tags:       make(map[string]int),
+	}
+	for i, parsed := range loaded.parsed {
+		var listed listedTemplate
+		switch t := parsed.(type) {
+		case *templates.Template:
+			listed = listedTemplate{ID: t.ID, Name: t.Info.Name, Severity: t.Info.Severity, Tags: t.Info.TagList(), Protocol: templateProtocol(t)}
+		case *workflows.Workflow:
+			listed = listedTemplate{ID: t.ID, Name: t.Info.Name, Severity: t.Info.Severity, Protocol: "workflow"}
+		}
+		listed.Path = loaded.paths[i]
+		listing.Templates = append(listing.Templates, listed)
+
+		severity := strings.ToLower(listed.Severity)
+		if severity == "" {
+			severity = "unknown"
+		}
+		listing.Severities[severity]++
+		for _, tag := range listed.Tags {
+			listing.Tags[tag]++
+		}
+	}
+	for i, path := range loaded.broken {
+		listing.Broken = append(listing.Broken, brokenTemplate{Path: path, Error: loaded.errors[i].Error()})
+	}
+
+	if r.options.JSON {
+		data, err := jsoniter.Marshal(listing)
+		if err != nil {
+			return fmt.Errorf("could not marshal the listing: %s", err)
+		}
+		gologger.Silentf("%s\n", string(data))
+		return nil
+	}
+
+	writer := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(writer, "ID\tNAME\tSEVERITY\tTAGS\tPROTOCOL\tPATH")
+	for _, listed := range listing.Templates {
+		fmt.Fprintf(writer, "%s\t%s\t%s\t%s\t%s\t%s\n", listed.ID, listed.Name, listed.Severity, strings.Join(listed.Tags, ","), listed.Protocol, listed.Path)
+	}
+	writer.Flush()
+
+	gologger.Silentf("\n%s\n", loaded.summary(r.filter != nil, r.excludes != nil))
+	gologger.Silentf("By severity: %s\n", formatCounts(listing.Severities))
+	if len(listing.Tags) > 0 {
+		gologger.Silentf("By tag: %s\n", formatCounts(listing.Tags))
+	}
+	if len(listing.Broken) > 0 {
+		gologger.Silentf("\nBroken templates:\n")
+		for _, broken := range listing.Broken {
+			gologger.Silentf("%s: %s\n", broken.Path, broken.Error)
+		}
+	}
+	return nil
+}
+
+// templateProtocol returns the protocols of the requests of a template
+func templateProtocol(template *templates.Template) string {
+	var protocols []string
+	if len(template.BulkRequestsHTTP) > 0 {
+		protocols = append(protocols, "http")
+	}
+	if len(template.RequestsDNS) > 0 {
+		protocols = append(protocols, "dns")
+	}
+	if len(template.RequestsHeadless) > 0 {
+		protocols = append(protocols, "headless")
+	}
+	if len(template.RequestsNetwork) > 0 {
+		protocols = append(protocols, "network")
+	}
+	if len(template.RequestsFile) > 0 {
+		protocols = append(protocols, "file")
+	}
+	if len(template.RequestsWebsocket) > 0 {
+		protocols = append(protocols, "websocket")
+	}
+	if len(template.RequestsSSL) > 0 {
+		protocols = append(protocols, "ssl")
+	}
+	return strings.Join(protocols, ",")
+}
+
+// formatCounts returns the counts by name, the largest first
+func formatCounts(counts map[string]int) string {
+	names := make([]string, 0, len(counts))
+	for name := range counts {
+		names = append(names, name)
+	}
+	sort.Slice(names, func(i, j int) bool {
+		if counts[names[i]] != counts[names[j]] {
+			return counts[names[i]] > counts[names[j]]
+		}
+		return names[i] < names[j]
+	})
+
+	formatted := make([]string, 0, len(names))
+	for _, name := range names {
+		formatted = append(formatted, fmt.Sprintf("%s %d", name, counts[name]))
+	}
+	return strings.Join(formatted, ", ")
+}
@@ -0,0 +1,97 @@
+package runner
+
+import (
+	"io"
+	"io/ioutil"
+	"net/http"
+	"net/url"
+	"sync"
+
+	"github.com/projectdiscovery/nuclei/v2/pkg/hosterrors"
+	"github.com/projectdiscovery/nuclei/v2/pkg/ratelimit"
+	"github.com/projectdiscovery/nuclei/v2/pkg/stats"
+)
+
+// livenessChecker checks with -probe-liveness that the host and port of
+// the http targets respond before running the templates on them, sending a
+// single request to each host and port for the whole scan.
+type livenessChecker struct {
+	client *http.Client
+	// limiter limits the rate of the checks to each host if any
+	limiter *ratelimit.Limiter
+	stats   *stats.Stats
+
+	mutex   sync.Mutex
+	results map[string]*livenessResult
+}
+
+// livenessResult is the cached result of the check of a host and port
+type livenessResult struct {
+	once  sync.Once
+	alive bool
+}
+
+// newLivenessChecker creates the liveness checker of the http targets
+func newLivenessChecker(options *Options, limiter *ratelimit.Limiter, stats *stats.Stats) (*livenessChecker, error) {
+	client, err := newProbeClient(options)
+	if err != nil {
+		return nil, err
+	}
+	return &livenessChecker{
+		client:  client,
+		limiter: limiter,
+		stats:   stats,
+		results: make(map[string]*livenessResult),
+	}, nil
+}
+
+// alive returns true if the host and port of an http target responded to
+// the check, which is only sent for the first target of a host and port.
+// The targets without a host are alive, as they are without a checker.
+func (c *livenessChecker) alive(URL string) bool {
+	if c == nil {
+		return true
+	}
+	parsed, err := url.Parse(URL)
+	if err != nil || parsed.Host == "" {
+		return true
+	}
+	hostPort := ratelimit.HostPort(parsed)
+
+	c.mutex.Lock()
+	result, ok := c.results[hostPort]
+	if !ok {
+		result = &livenessResult{}
+		c.results[hostPort] = result
+	}
+	c.mutex.Unlock()
+
+	result.once.Do(func() {
+		result.alive = c.check(parsed.Scheme+"://"+parsed.Host+"/", hostPort)
+	})
+	return result.alive
+}
+
+// check sends a HEAD request to the root of a host and port, which is
+// alive unless the request fails with a network error. The tls errors and
+// all the statuses are the ones of a responding host.
+func (c *livenessChecker) check(URL, hostPort string) bool {
+	req, err := http.NewRequest(http.MethodHead, URL, nil)
+	if err != nil {
+		return true
+	}
+	req.Header.Set("User-Agent", "Nuclei - Open-source project (github.com/projectdiscovery/nuclei)")
+
+	c.limiter.Wait(req.Context(), hostPort)
+	resp, err := c.client.Do(req)
+	if err != nil {
+		if !hosterrors.IsNetworkError(err) {
+			return true
+		}
+		c.stats.HostPreflightFailed(hostPort, err)
+		return false
+	}
+	io.Copy(ioutil.Discard, io.LimitReader(resp.Body, 4096))
+	resp.Body.Close()
+	return true
+}
@@ -0,0 +1,141 @@
+package runner
+
+import (
+	"fmt"
+
+	"github.com/projectdiscovery/nuclei/v2/pkg/templates"
+	"github.com/projectdiscovery/nuclei/v2/pkg/workflows"
+)
+
+// loadedTemplates are the templates and workflows of the user input
+// selected by the exclusions and the filters.
+type loadedTemplates struct {
+	// paths are the files of the selected templates and workflows
+	paths []string
+	// parsed are the selected templates and workflows, by path index
+	parsed []interface{}
+	// requests is the number of requests of the templates for each target
+	requests     int64
+	hasWorkflows bool
+	// broken are the files failing to parse along with their errors
+	broken []string
+	errors []error
+
+	excluded      map[string]int
+	excludedCount int
+	filtered      map[string]int
+	filteredCount int
+}
+
+// loadTemplates parses the template files of the user input, skipping the
+// excluded and filtered out ones along with the duplicate ids, returning an
+// error for the invalid or duplicate ids in strict mode.
+func (r *Runner) loadTemplates(paths []string) (*loadedTemplates, error) {
+	loaded := &loadedTemplates{
+		excluded: make(map[string]int),
+		filtered: make(map[string]int),
+	}
+	for _, match := range paths {
+		// the excluded paths are skipped before parsing them
+		if r.excludes != nil {
+			if rule := r.excludes.match(match, ""); rule != "" {
+				loaded.excluded[rule]++
+				loaded.excludedCount++
+				continue
+			}
+		}
+		t, err := r.parse(match)
+		switch t := t.(type) {
+		case *templates.Template:
+			// the exclusions take precedence over the filters
+			if r.excludes != nil {
+				if rule := r.excludes.match("", t.ID); rule != "" {
+					loaded.excluded[rule]++
+					loaded.excludedCount++
+					continue
+				}
+			}
+			if r.filter != nil {
+				if ok, reason := r.filter.Match(&t.Info); !ok {
+					loaded.filtered[reason]++
+					loaded.filteredCount++
+					continue
+				}
+			}
+			if reason := r.protocolSkipReason(t); reason != "" {
+				loaded.filtered[reason]++
+				loaded.filteredCount++
+				continue
+			}
+			ok, err := r.indexTemplate(t.ID, match)
+			if err != nil {
+				return nil, err
+			}
+			if !ok {
+				continue
+			}
+			loaded.requests += t.GetHTTPRequestCount() + t.GetDNSRequestCount() + t.GetHeadlessRequestCount() + t.GetNetworkRequestCount() + t.GetFileRequestCount() + t.GetWebsocketRequestCount() + t.GetSSLRequestCount()
+			loaded.paths = append(loaded.paths, match)
+			loaded.parsed = append(loaded.parsed, t)
+		case *workflows.Workflow:
+			if r.excludes != nil {
+				if rule := r.excludes.match("", t.ID); rule != "" {
+					loaded.excluded[rule]++
+					loaded.excludedCount++
+					continue
+				}
+			}
+			ok, err := r.indexTemplate(t.ID, match)
+			if err != nil {
+				return nil, err
+			}
+			if !ok {
+				continue
+			}
+			r.checkWorkflowMembers(t, t.Workflows)
+			// workflows will dynamically adjust the totals while running, as
+			// it can't be know in advance which requests will be called
+			loaded.paths = append(loaded.paths, match)
+			loaded.parsed = append(loaded.parsed, t)
+			loaded.hasWorkflows = true
+		default:
+			loaded.broken = append(loaded.broken, match)
+			loaded.errors = append(loaded.errors, err)
+		}
+	}
+	return loaded, nil
+}
+
+// summary returns the number of loaded templates along with the numbers of
+// the excluded and filtered out ones if the exclusions or filters are used,
+// the templates using interactsh being filtered out without a filter.
+func (l *loadedTemplates) summary(filter, exclude bool) string {
+	message := fmt.Sprintf("Loaded %d templates", len(l.paths))
+	if exclude {
+		message += fmt.Sprintf(", excluded %d%s", l.excludedCount, filterReasons(l.excluded))
+	}
+	if filter || l.filteredCount > 0 {
+		message += fmt.Sprintf(", filtered out %d%s", l.filteredCount, filterReasons(l.filtered))
+	}
+	return message
+}
+
+// steps returns the number of runs of the templates and workflows on each
+// target, each request block of the single protocol templates being run
+// separately on the targets.
+func (l *loadedTemplates) steps() int64 {
+	var steps int64
+	for _, parsed := range l.parsed {
+		switch t := parsed.(type) {
+		case *templates.Template:
+			if t.HasMultipleProtocols() {
+				steps++
+			} else {
+				steps += int64(len(t.RequestsDNS) + len(t.BulkRequestsHTTP) + len(t.RequestsHeadless) + len(t.RequestsNetwork) + len(t.RequestsFile) + len(t.RequestsWebsocket) + len(t.RequestsSSL))
+			}
+		case *workflows.Workflow:
+			steps++
+		}
+	}
+	return steps
+}
@@ -0,0 +1,193 @@
+package runner
+
+import (
+	"context"
+	"net"
+	"net/http"
+	"net/http/pprof"
+	"runtime"
+	"strconv"
+	"sync"
+	"time"
+
+	jsoniter "github.com/json-iterator/go"
+	"github.com/projectdiscovery/gologger"
+	"github.com/projectdiscovery/nuclei/v2/pkg/requests"
+	"github.com/projectdiscovery/nuclei/v2/pkg/stats"
+)
+
+// metricsShutdownTimeout is the time the requests to the metrics server
+// have to complete once the scan is over.
+const metricsShutdownTimeout = 5 * time.Second
+
+// engineMetrics tracks the internals of the engine served by -metrics. The
+// methods of nil metrics do nothing.
+type engineMetrics struct {
+	server *http.Server
+
+	mutex sync.Mutex
+	// runs are the runs in flight by job, each run being a goroutine, and
+	// generators the http requests whose payload generators are reported,
+	// by step.
+	runs       map[string]int64
+	generators map[*requests.BulkHTTPRequest]string
+}
+
+// metricsSnapshot is the json served on /metrics, its stats being the ones
+// written by -stats.
+type metricsSnapshot struct {
+	Stats      *stats.Snapshot `json:"stats"`
+	Goroutines int             `json:"goroutines"`
+	// ActiveRuns are the goroutines running a template on a target by
+	// template, request block, cluster or workflow.
+	ActiveRuns map[string]int64 `json:"active_runs"`
+	// Generators are the number of payload generators by step and state,
+	// one per target.
+	Generators map[string]map[string]int `json:"generators"`
+	// HostErrors are the hosts with network errors in the cache of
+	// -max-host-error, RateLimitedHosts the ones tracked by the rate
+	// limit of -rate-limit-per-host and AdaptiveHosts the ones tracked by
+	// -adaptive.
+	HostErrors       int `json:"host_errors"`
+	RateLimitedHosts int `json:"rate_limited_hosts"`
+	AdaptiveHosts    int `json:"adaptive_hosts"`
+	// Queues are the results waiting to be sent by exporter and
+	// GroupedBytes the size of the results buffered by -group-by-host.
+	Queues       map[string]int `json:"queues"`
+	GroupedBytes int            `json:"grouped_bytes"`
+}
+
+// startMetrics serves the pprof profiles and the metrics of the engine on
+// the localhost port of -metrics until the runner is closed.
+func (r *Runner) startMetrics() error {
+	listener, err := net.Listen("tcp", net.JoinHostPort("127.0.0.1", strconv.Itoa(r.options.MetricsPort)))
+	if err != nil {
+		return err
+	}
+	mux := http.NewServeMux()
+	mux.HandleFunc("/debug/pprof/", pprof.Index)
+	mux.HandleFunc("/debug/pprof/cmdline", pprof.Cmdline)
+	mux.HandleFunc("/debug/pprof/profile", pprof.Profile)
+	mux.HandleFunc("/debug/pprof/symbol", pprof.Symbol)
+	mux.HandleFunc("/debug/pprof/trace", pprof.Trace)
+	mux.HandleFunc("/metrics", r.serveMetrics)
+
+	r.metrics = &engineMetrics{
+		server:     &http.Server{Handler: mux},
+		runs:       make(map[string]int64),
+		generators: make(map[*requests.BulkHTTPRequest]string),
+	}
+	go func() {
+		if err := r.metrics.server.Serve(listener); err != nil && err != http.ErrServerClosed {
+			gologger.Warningf("Could not serve the metrics: %s\n", err)
+		}
+	}()
+	gologger.Labelf("Serving the metrics on http://%s/metrics and the profiles on http://%s/debug/pprof/\n", listener.Addr(), listener.Addr())
+	return nil
+}
+
+// stopMetrics shuts the metrics server down, waiting for its requests
+func (r *Runner) stopMetrics() {
+	if r.metrics == nil {
+		return
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), metricsShutdownTimeout)
+	defer cancel()
+	if err := r.metrics.server.Shutdown(ctx); err != nil {
+		gologger.Warningf("Could not stop the metrics server: %s\n", err)
+	}
+}
+
+// serveMetrics writes the snapshot of the metrics of the engine as json
+func (r *Runner) serveMetrics(w http.ResponseWriter, req *http.Request) {
+	data, err := jsoniter.Marshal(r.metricsSnapshot())
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	w.Write(data)
+}
+
+// metricsSnapshot returns the metrics of the engine so far
+func (r *Runner) metricsSnapshot() *metricsSnapshot {
+	snapshot := &metricsSnapshot{
+		Stats:            r.stats.Snapshot(),
+		Goroutines:       runtime.NumGoroutine(),
+		ActiveRuns:       make(map[string]int64),
+		Generators:       make(map[string]map[string]int),
+		HostErrors:       r.hostErrors.Len(),
+		RateLimitedHosts: r.rateLimiter.Len(),
+		AdaptiveHosts:    r.adaptive.Len(),
+		Queues:           make(map[string]int),
+		GroupedBytes:     r.grouper.Buffered(),
+	}
+
+	r.metrics.mutex.Lock()
+	for job, runs := range r.metrics.runs {
+		snapshot.ActiveRuns[job] = runs
+	}
+	generators := make(map[*requests.BulkHTTPRequest]string, len(r.metrics.generators))
+	for request, step := range r.metrics.generators {
+		generators[request] = step
+	}
+	r.metrics.mutex.Unlock()
+
+	for request, step := range generators {
+		for state, count := range request.GeneratorStates() {
+			if snapshot.Generators[step] == nil {
+				snapshot.Generators[step] = make(map[string]int)
+			}
+			snapshot.Generators[step][state.String()] += count
+		}
+	}
+
+	if r.webhook != nil {
+		snapshot.Queues["webhook"] = r.webhook.Queued()
+	}
+	if r.syslog != nil {
+		snapshot.Queues["syslog"] = r.syslog.Queued()
+	}
+	if r.elastic != nil {
+		snapshot.Queues["elasticsearch"] = r.elastic.Queued()
+	}
+	if r.kafka != nil {
+		snapshot.Queues["kafka"] = r.kafka.Queued()
+	}
+	if r.reporting != nil {
+		snapshot.Queues["reporting"] = r.reporting.Queued()
+	}
+	return snapshot
+}
+
+// runStarted counts a run of a job in flight
+func (m *engineMetrics) runStarted(job string) {
+	if m == nil {
+		return
+	}
+	m.mutex.Lock()
+	m.runs[job]++
+	m.mutex.Unlock()
+}
+
+// runFinished counts a run of a job as no longer in flight
+func (m *engineMetrics) runFinished(job string) {
+	if m == nil {
+		return
+	}
+	m.mutex.Lock()
+	if m.runs[job]--; m.runs[job] <= 0 {
+		delete(m.runs, job)
+	}
+	m.mutex.Unlock()
+}
+
+// addGenerators reports the payload generators of an http request of a step
+func (m *engineMetrics) addGenerators(step string, request *requests.BulkHTTPRequest) {
+	if m == nil {
+		return
+	}
+	m.mutex.Lock()
+	m.generators[request] = step
+	m.mutex.Unlock()
+}
@@ -2,37 +2,197 @@ package runner
 
 import (
 	"flag"
+	"fmt"
 	"os"
+	"path/filepath"
+	"strings"
+	"time"
 
 	"github.com/projectdiscovery/gologger"
+	"github.com/projectdiscovery/nuclei/v2/pkg/adaptive"
+	"github.com/projectdiscovery/nuclei/v2/pkg/csv"
+	"github.com/projectdiscovery/nuclei/v2/pkg/dedupe"
+	"github.com/projectdiscovery/nuclei/v2/pkg/export"
+	"github.com/projectdiscovery/nuclei/v2/pkg/file"
+	"github.com/projectdiscovery/nuclei/v2/pkg/grouping"
+	"github.com/projectdiscovery/nuclei/v2/pkg/headless"
+	"github.com/projectdiscovery/nuclei/v2/pkg/hosterrors"
+	"github.com/projectdiscovery/nuclei/v2/pkg/inputs"
+	"github.com/projectdiscovery/nuclei/v2/pkg/interactsh"
+	"github.com/projectdiscovery/nuclei/v2/pkg/project"
+	"github.com/projectdiscovery/nuclei/v2/pkg/redact"
+	"github.com/projectdiscovery/nuclei/v2/pkg/regexguard"
+	"github.com/projectdiscovery/nuclei/v2/pkg/replay"
 	"github.com/projectdiscovery/nuclei/v2/pkg/requests"
+	"github.com/projectdiscovery/nuclei/v2/pkg/retryqueue"
+	"github.com/projectdiscovery/nuclei/v2/pkg/signature"
+	"github.com/projectdiscovery/nuclei/v2/pkg/templates"
 )
 
 // Options contains the configuration options for tuning
 // the template requesting process.
 type Options struct {
-	Debug              bool                   // Debug mode allows debugging request/responses for the engine
-	Templates          multiStringFlag        // Signature specifies the template/templates to use
-	Target             string                 // Target is a single URL/Domain to scan usng a template
-	Targets            string                 // Targets specifies the targets to scan using templates.
-	Threads            int                    // Thread controls the number of concurrent requests to make.
-	Timeout            int                    // Timeout is the seconds to wait for a response from the server.
-	Retries            int                    // Retries is the number of times to retry the request
-	Output             string                 // Output is the file to write found subdomains to.
-	ProxyURL           string                 // ProxyURL is the URL for the proxy server
-	ProxySocksURL      string                 // ProxySocksURL is the URL for the proxy socks server
-	Silent             bool                   // Silent suppresses any extra text and only writes found URLs on screen.
-	Version            bool                   // Version specifies if we should just show version and exit
-	Verbose            bool                   // Verbose flag indicates whether to show verbose output or not
-	NoColor            bool                   // No-Color disables the colored output.
-	CustomHeaders      requests.CustomHeaders // Custom global headers
-	UpdateTemplates    bool                   // UpdateTemplates updates the templates installed at startup
-	TemplatesDirectory string                 // TemplatesDirectory is the directory to use for storing templates
-	JSON               bool                   // JSON writes json output to files
-	JSONRequests       bool                   // write requests/responses for matches in JSON output
-	DisableProgressBar bool                   // Disable progrss bar
+	Debug                  bool                   // Debug mode allows debugging request/responses for the engine
+	Templates              multiStringFlag        // Signature specifies the template/templates to use
+	Target                 string                 // Target is a single URL/Domain to scan usng a template
+	Targets                string                 // Targets specifies the targets to scan using templates.
+	Threads                int                    // Thread controls the number of concurrent requests to make.
+	HostConcurrency        int                    // HostConcurrency is the maximum number of templates running towards each host at once, no limit if 0
+	Timeout                int                    // Timeout is the seconds to wait for a response from the server.
+	Retries                int                    // Retries is the number of times to retry the request
+	Output                 string                 // Output is the file to write found subdomains to.
+	ProxyURL               string                 // ProxyURL is the URL for the proxy server
+	ProxySocksURL          string                 // ProxySocksURL is the URL for the proxy socks server
+	ProxyReplay            string                 // ProxyReplay is the URL of the proxy the requests of the results are replayed through
+	ProxyReplayRate        int                    // ProxyReplayRate is the number of requests replayed per second through the replay proxy
+	ExportRequests         string                 // ExportRequests is a directory the requests of the results, or of the dry run, are exported to as raw files Burp loads
+	ExportRequestsConcat   bool                   // ExportRequestsConcat exports all the requests to a single file too, in the format of the logs of Burp
+	ExportRequestsMarkers  bool                   // ExportRequestsMarkers delimits the payload values of the exported requests with the markers of the positions of Intruder
+	Silent                 bool                   // Silent suppresses any extra text and only writes found URLs on screen.
+	Version                bool                   // Version specifies if we should just show version and exit
+	Verbose                bool                   // Verbose flag indicates whether to show verbose output or not
+	NoColor                bool                   // No-Color disables the colored output.
+	CustomHeaders          requests.CustomHeaders // Custom global headers
+	ForcedHeaders          requests.CustomHeaders // Custom global headers overriding the template ones
+	CustomHeadersFile      string                 // CustomHeadersFile is a file containing custom global headers
+	UpdateTemplates        bool                   // UpdateTemplates updates the templates installed at startup
+	TemplatesDirectory     string                 // TemplatesDirectory is the directory to use for storing templates
+	JSON                   bool                   // JSON writes json output to files
+	JSONRequests           bool                   // write requests/responses for matches in JSON output
+	DisableProgressBar     bool                   // Disable progrss bar
+	Resolvers              string                 // Resolvers is a file containing the dns resolvers to use
+	NoProbe                bool                   // NoProbe disables the http/https probing of inputs without a scheme
+	ProbeOrder             string                 // ProbeOrder is the comma separated order of schemes to probe
+	ProbeTimeout           int                    // ProbeTimeout is the seconds to wait for a probe response
+	ProbeLiveness          bool                   // ProbeLiveness skips the http targets whose host and port don't respond to a preflight request
+	Auth                   string                 // Auth is the yaml config of the login establishing the session of each host before the templates are run on it
+	NoAuth                 bool                   // NoAuth skips the login of -auth, the templates being run without sessions
+	TechDetect             string                 // TechDetect runs only the templates of the technologies detected on each host, loose, strict or off
+	TechMap                string                 // TechMap is the yaml mapping of the technologies detected to the tags of their templates
+	PTRCIDRLimit           int                    // PTRCIDRLimit is the maximum number of addresses of a cidr input for PTR requests
+	Ports                  string                 // Ports are the comma separated ports and ranges of ports combined with each host of the input without a port
+	ExcludeHosts           string                 // ExcludeHosts are the comma separated hosts, globs of hosts, addresses and cidr ranges of the input not to scan
+	ExcludeFile            string                 // ExcludeFile is a file of the hosts, globs of hosts, addresses and cidr ranges of the input not to scan, one per line
+	ExcludeResolved        bool                   // ExcludeResolved resolves the hosts of the input to also exclude the ones resolving into the excluded addresses
+	CIDRLimit              int                    // CIDRLimit is the maximum number of addresses of a cidr range of the input, the larger ones being refused
+	IncludeRR              bool                   // IncludeRR writes the raw http requests/responses with a curl command and the dns records in JSON output
+	Exclusions             string                 // Exclusions is a file of matchers suppressing known false positives
+	ShowSuppressed         bool                   // ShowSuppressed shows the results suppressed by the exclusions
+	RegexMaxSize           int                    // RegexMaxSize is the maximum length in bytes of the inputs regexes are applied to
+	ExtractorOutput        string                 // ExtractorOutput is a file collecting the deduplicated extracted values of the scan
+	ExtractorDir           string                 // ExtractorDir is the directory the to-file extractors append their values in
+	SarifExport            string                 // SarifExport is a file to write the results of the scan in SARIF format
+	MarkdownExport         string                 // MarkdownExport is a directory to write a markdown report of the results of the scan
+	ElasticsearchExport    string                 // ElasticsearchExport is the yaml config of the elasticsearch cluster indexing the results of the scan
+	WebhookExport          string                 // WebhookExport is the yaml config of the webhook the results of the scan are posted to
+	WebhookCheck           bool                   // WebhookCheck checks the webhook can be reached before running the scan
+	SyslogExport           string                 // SyslogExport is the url of the syslog collector the results of the scan are sent to
+	KafkaExport            string                 // KafkaExport is the yaml config of the kafka topic the results of the scan are published to
+	CSV                    string                 // CSV is a file to append the results of the scan to as csv rows
+	CSVFields              string                 // CSVFields is the comma separated columns of the csv file, in order
+	ReportConfig           string                 // ReportConfig is the yaml config of the issue tracker the findings above a severity are filed in
+	ReportDryRun           bool                   // ReportDryRun prints the issues which would be filed instead of filing them
+	Dedupe                 bool                   // Dedupe suppresses the identical findings of the scan
+	DedupeKey              string                 // DedupeKey is the comma separated fields of the fingerprints of the findings
+	DedupeState            string                 // DedupeState is a file persisting the fingerprints across runs to report only the new findings
+	ShowDuplicates         bool                   // ShowDuplicates writes the suppressed findings to the json output
+	MatcherStatus          bool                   // MatcherStatus writes the matched, not-matched or errored status of each template for each target
+	StatsJSON              string                 // StatsJSON is a file to write the summary of the scan to as json
+	StatsTop               int                    // StatsTop is the number of templates with the most findings shown in the summary
+	Stats                  bool                   // Stats writes the progress of the scan as json lines instead of showing the progress bar
+	StatsInterval          int                    // StatsInterval is the number of seconds between the json lines of the progress
+	StatsFile              string                 // StatsFile is a file to write the json lines of the progress to instead of stderr
+	Metrics                bool                   // Metrics serves the pprof profiles and the metrics of the engine on localhost during the scan
+	MetricsPort            int                    // MetricsPort is the localhost port of the metrics server
+	Server                 string                 // Server is the localhost address of the control api pausing, resuming, stopping and inspecting the scan
+	ServerToken            string                 // ServerToken is the bearer token authenticating the requests to the control api
+	Benchmark              bool                   // Benchmark records the timings of the templates and the hosts and shows a report at the end of the scan
+	BenchmarkJSON          string                 // BenchmarkJSON is a file to write the report of -benchmark to as json
+	BenchmarkNoOutput      bool                   // BenchmarkNoOutput discards the results of -benchmark, only counting them
+	RedactHeaders          string                 // RedactHeaders is the comma separated headers redacted along with the default ones
+	NoRedact               bool                   // NoRedact writes the sensitive headers and the secrets of the templates as is
+	GroupByHost            bool                   // GroupByHost shows the results on screen by host once all the templates ran on the host
+	GroupMaxSize           int                    // GroupMaxSize is the maximum length of the results buffered by host in bytes
+	Resume                 string                 // Resume is a checkpoint file of the scan, resuming it if the file exists
+	MaxHostError           int                    // MaxHostError is the number of consecutive network errors after which the requests to a host are skipped
+	NoHostSkip             bool                   // NoHostSkip sends all the requests to the hosts whatever their errors
+	RetryAttempts          int                    // RetryAttempts is the number of times the runs failing with a network error are run again at the end of the scan
+	RetryConcurrency       int                    // RetryConcurrency is the number of runs retried concurrently, a quarter of the concurrency if 0
+	RetryQueueSize         int                    // RetryQueueSize is the number of runs to retry kept in memory, the next ones being spilled to a temporary file
+	ScanStrategy           string                 // ScanStrategy is the order the templates run on the targets, template-spray or host-spray
+	RateLimitPerHost       int                    // RateLimitPerHost is the maximum number of requests per second to each host, 0 for no limit
+	Adaptive               bool                   // Adaptive adapts the concurrency and the delays of the requests to the network errors of the hosts
+	AdaptiveMinConcurrency int                    // AdaptiveMinConcurrency is the minimum number of requests in flight to each host the concurrency backs off to
+	AdaptiveMaxConcurrency int                    // AdaptiveMaxConcurrency is the maximum number of requests in flight to each host and to all of them, the concurrency if 0
+	AdaptiveWindow         int                    // AdaptiveWindow is the number of the last requests the error rates are computed on
+	AdaptiveBackoffRate    float64                // AdaptiveBackoffRate is the error rate halving the concurrency and doubling the delay of a host
+	AdaptiveRecoverRate    float64                // AdaptiveRecoverRate is the error rate below which the concurrency of a host is increased and its delay halved
+	AdaptiveMaxDelay       time.Duration          // AdaptiveMaxDelay is the maximum delay between the requests to a backed off host
+	MaxScanDuration        time.Duration          // MaxScanDuration is the duration after which the scan stops, truncated, 0 for no limit
+	TemplateTimeout        time.Duration          // TemplateTimeout is the duration after which a template running on a target is abandoned, 0 for no limit
+	NoDedupe               bool                   // NoDedupe runs all the targets streamed from stdin, without skipping the recent duplicates
+	PassiveExtract         bool                   // PassiveExtract writes only the extracted values of the extractor-only templates, without colors
+	Severity               string                 // Severity is the comma separated severities of the templates to run
+	Tags                   string                 // Tags is the comma separated tags of the templates to run
+	ExcludeTags            string                 // ExcludeTags is the comma separated tags of the templates not to run
+	ExcludeTemplates       string                 // ExcludeTemplates is the comma separated files, directories or globs of the templates not to run
+	ExcludeIDs             string                 // ExcludeIDs is the comma separated ids of the templates not to run
+	Author                 string                 // Author is the comma separated authors of the templates to run
+	Validate               bool                   // Validate validates the templates instead of running them
+	StrictFields           bool                   // StrictFields makes the templates with unknown fields fail to load
+	Strict                 bool                   // Strict aborts the scan on duplicate or invalid template ids and fails the deprecated syntax instead of warning
+	UpdateRemoteTemplates  bool                   // UpdateRemoteTemplates downloads again the cached remote templates
+	NoRemoteTemplates      bool                   // NoRemoteTemplates disables the loading of templates from urls and repositories
+	AllowEnvVars           bool                   // AllowEnvVars expands the references to environment variables of the templates
+	AllowMissingEnvVars    bool                   // AllowMissingEnvVars replaces the missing environment variables with empty values
+	TemplateSignature      string                 // TemplateSignature is the verification mode of the signatures of templates, ignore, warn or enforce
+	TrustedKeys            string                 // TrustedKeys is the comma separated files of public keys verifying the signatures
+	SignTemplates          string                 // SignTemplates is a private key file to sign the templates with instead of running them
+	GenerateSigningKey     string                 // GenerateSigningKey is a file to write a new private key to, along with its public key
+	Watch                  bool                   // Watch reruns the changed templates after the scan until interrupted
+	TemplateThreads        int                    // TemplateThreads is the number of targets each template runs towards concurrently, overriding the templates
+	TestFixtures           string                 // TestFixtures is a directory of recorded responses to test the templates on instead of running them
+	Passive                string                 // Passive is a directory of stored http responses to run the templates on instead of sending requests, - for the files listed on stdin
+	TemplateList           bool                   // TemplateList lists the templates a scan would run instead of running them
+	DryRun                 bool                   // DryRun lists the requests a scan would send to the targets instead of sending them
+	DryRunLimit            int                    // DryRunLimit is the number of requests of each template listed per target by the dry run, 0 for all
+	Project                bool                   // Project caches the http responses on disk, the identical requests of the next runs reusing them
+	ProjectPath            string                 // ProjectPath is the directory of the cached responses of the project
+	ProjectTTL             time.Duration          // ProjectTTL is the duration the cached responses are reused for, 0 for ever
+	ProjectRewrite         bool                   // ProjectRewrite sends all the requests again, replacing the cached responses
+	ProjectRandom          string                 // ProjectRandom is how the requests with random values are cached, skip or template
+	InteractshServer       string                 // InteractshServer is the url of the interactsh server handing out the urls of {{interactsh-url}}
+	InteractshToken        string                 // InteractshToken is the token authenticating the client to a self-hosted interactsh server
+	InteractionsCacheSize  int                    // InteractionsCacheSize is the number of urls correlated to their requests, the oldest being forgotten
+	InteractionsPoll       time.Duration          // InteractionsPoll is the interval the interactions are polled at
+	InteractionsCooldown   time.Duration          // InteractionsCooldown is the duration the interactions are polled for after the scan
+	NoInteractsh           bool                   // NoInteractsh skips the templates using interactsh
+	OOBListen              string                 // OOBListen is the ip:port of the built-in listener receiving the interactions instead of the interactsh server
+	OOBDomain              string                 // OOBDomain is the domain delegated to the dns listener of -oob-listen, the urls being its subdomains
+	OOBDNSListen           string                 // OOBDNSListen is the address of the dns listener of -oob-domain, port 53 of the ip of -oob-listen by default
+	OOBLog                 string                 // OOBLog is the file the interactions received by -oob-listen are appended to as json lines
+	Headless               bool                   // Headless runs the headless requests of the templates in a chromium browser
+	HeadlessConcurrency    int                    // HeadlessConcurrency is the number of browser pages opened at once
+	HeadlessBrowser        string                 // HeadlessBrowser is the path of the chromium browser, looked up otherwise
+	FileConcurrency        int                    // FileConcurrency is the number of files read at once by each walk of the file requests
 
 	Stdin bool // Stdin specifies whether stdin input was given to the process
+
+	// Embedded is true for the runners of the programs embedding the engine,
+	// which neither update the templates nor handle the interrupts, the
+	// settings of the templates and of the regexes shared by the process
+	// being left to their defaults.
+	Embedded bool
+	// Inputs are the targets given by the programs embedding the engine,
+	// scanned along with the other targets.
+	Inputs []string
+	// Exporters are the exporters of the programs embedding the engine,
+	// sent the json results along with the ones of the flags.
+	Exporters []export.Exporter
+
+	// overrides are the flags given on the command line, -timeout and
+	// -retries overriding the values of the templates if given.
+	overrides map[string]bool
 }
 
 type multiStringFlag []string
@@ -46,35 +206,188 @@ func (m *multiStringFlag) Set(value string) error {
 	return nil
 }
 
-// ParseOptions parses the command line flags provided by a user
-func ParseOptions() *Options {
+// DefaultOptions returns the options of the flags left to their defaults,
+// for the programs running the engine without the command line.
+func DefaultOptions() *Options {
 	options := &Options{}
+	options.registerFlags(flag.NewFlagSet("nuclei", flag.ContinueOnError))
+	return options
+}
+
+// registerFlags registers the flags setting the options to a set
+func (options *Options) registerFlags(set *flag.FlagSet) {
+	set.StringVar(&options.Target, "target", "", "Target is a single target to scan using template")
+	set.Var(&options.Templates, "t", "Template input file/files to run on host. Can be used multiple times.")
+	set.StringVar(&options.Targets, "l", "", "List of URLs to run templates on")
+	set.StringVar(&options.Output, "o", "", "File to write output to (optional)")
+	set.StringVar(&options.ProxyURL, "proxy-url", "", "URL of the proxy server")
+	set.StringVar(&options.ProxySocksURL, "proxy-socks-url", "", "URL of the proxy socks server")
+	set.StringVar(&options.ProxyReplay, "proxy-replay", "", "URL of a proxy the requests of the results are replayed through, i.e http://127.0.0.1:8080")
+	set.IntVar(&options.ProxyReplayRate, "proxy-replay-rate", replay.DefaultRate, "Number of requests replayed per second through -proxy-replay")
+	set.StringVar(&options.ExportRequests, "export-requests", "", "Directory the http requests of the results, or all the ones of -dry-run, are exported to as raw files Burp loads, along with a manifest")
+	set.BoolVar(&options.ExportRequestsConcat, "export-requests-concat", false, "Export all the requests of -export-requests to a single file too, in the format of the logs of Burp")
+	set.BoolVar(&options.ExportRequestsMarkers, "export-requests-markers", false, "Delimit the payload values of the requests of -export-requests with §, the positions of Intruder")
+	set.BoolVar(&options.Silent, "silent", false, "Show only results in output")
+	set.BoolVar(&options.Version, "version", false, "Show version of nuclei")
+	set.BoolVar(&options.Verbose, "v", false, "Show Verbose output")
+	set.BoolVar(&options.NoColor, "nC", false, "Don't Use colors in output")
+	set.IntVar(&options.Threads, "c", 50, "Number of concurrent requests to make")
+	set.IntVar(&options.HostConcurrency, "host-concurrency", 0, "Maximum number of templates running towards each host at once within -c, no limit if 0")
+	set.IntVar(&options.Timeout, "timeout", 5, "Time to wait in seconds before timeout, overriding the timeout of the templates if given")
+	set.IntVar(&options.Retries, "retries", 1, "Number of times to retry a failed request, overriding the retries of the templates if given")
+	set.Var(&options.CustomHeaders, "H", "Custom Header.")
+	set.Var(&options.ForcedHeaders, "H!", "Custom Header overriding the template headers with the same name.")
+	set.StringVar(&options.CustomHeadersFile, "headers-file", "", "File containing custom headers, one per line")
+	set.BoolVar(&options.Debug, "debug", false, "Allow debugging of request/responses")
+	set.BoolVar(&options.UpdateTemplates, "update-templates", false, "Update Templates updates the installed templates (optional)")
+	set.StringVar(&options.TemplatesDirectory, "update-directory", "", "Directory to use for storing nuclei-templates")
+	set.BoolVar(&options.JSON, "json", false, "Write json output to files, one result per line")
+	set.BoolVar(&options.JSONRequests, "json-requests", false, "Write requests/responses for matches in JSON output")
+	set.BoolVar(&options.DisableProgressBar, "no-pbar", false, "Disable the progress bar")
+	set.StringVar(&options.Resolvers, "resolvers", "", "File containing dns resolvers (ip:port, doh:URL or dot:ip:port), one per line")
+	set.BoolVar(&options.NoProbe, "no-probe", false, "Disable http/https probing of inputs without a scheme")
+	set.StringVar(&options.ProbeOrder, "probe-order", "https,http", "Order of the schemes to probe for inputs without a scheme")
+	set.IntVar(&options.ProbeTimeout, "probe-timeout", 5, "Time to wait in seconds for a probe response")
+	set.BoolVar(&options.ProbeLiveness, "probe-liveness", false, "Skip the http targets whose host and port don't respond to a preflight request")
+	set.StringVar(&options.Auth, "auth", "", "Yaml config of the login establishing the session of each host, the http requests being sent with its cookies and headers")
+	set.BoolVar(&options.NoAuth, "no-auth", false, "Skip the login of -auth, running the templates without sessions")
+	set.StringVar(&options.TechDetect, "tech-detect", "off", "Run only the templates of the technologies detected on each host: loose runs all of them where nothing is detected, strict doesn't, off runs all the templates")
+	set.StringVar(&options.TechMap, "tech-map", "", "Yaml mapping of the technologies detected by -tech-detect to the tags of their templates, along with the built-in ones")
+	set.IntVar(&options.PTRCIDRLimit, "ptr-cidr-limit", 256, "Maximum number of addresses of a cidr input to query PTR records for")
+	set.StringVar(&options.Ports, "ports", "", "Comma separated ports and ranges of ports (i.e 80,443,8000-8100) to scan on each host of the input without a port")
+	set.StringVar(&options.ExcludeHosts, "exclude-hosts", "", "Comma separated hosts, globs of hosts, addresses and cidr ranges of the input not to scan, i.e *.internal.example.com,10.0.0.0/8")
+	set.StringVar(&options.ExcludeFile, "exclude-file", "", "File of the hosts, globs of hosts, addresses and cidr ranges of the input not to scan, one per line")
+	set.BoolVar(&options.ExcludeResolved, "exclude-resolved", false, "Resolve the hosts of the input to also skip the ones resolving into the excluded addresses and cidr ranges")
+	set.IntVar(&options.CIDRLimit, "cidr-limit", inputs.DefaultCIDRLimit, "Maximum number of addresses of a cidr range of the input, the larger ranges being refused")
+	set.BoolVar(&options.IncludeRR, "include-rr", false, "Write the raw http requests/responses with a curl command and the records of all the sections of dns responses in JSON output")
+	set.StringVar(&options.Exclusions, "exclusions", "", "File containing matchers suppressing the results of known false positives")
+	set.BoolVar(&options.ShowSuppressed, "show-suppressed", false, "Show the results suppressed by the exclusions")
+	set.IntVar(&options.RegexMaxSize, "regex-max-size", regexguard.DefaultMaxSize, "Maximum length in bytes of the responses regexes are applied to, 0 for no limit")
+	set.StringVar(&options.Resume, "resume", "", "Checkpoint file of the scan, written periodically and when interrupted, resuming the scan if it exists")
+	set.IntVar(&options.MaxHostError, "max-host-error", hosterrors.DefaultMaxErrors, "Number of consecutive network errors of a host after which its remaining requests are skipped")
+	set.BoolVar(&options.NoHostSkip, "no-host-skip", false, "Send all the requests to the hosts whatever their network errors, for flaky targets")
+	set.IntVar(&options.RetryAttempts, "retry-attempts", 1, "Number of times the templates failing on a target with a network error are run again at the end of the scan, 0 to disable")
+	set.IntVar(&options.RetryConcurrency, "retry-concurrency", 0, "Number of templates retried concurrently at the end of the scan, a quarter of -c if 0")
+	set.IntVar(&options.RetryQueueSize, "retry-queue-size", retryqueue.DefaultMaxSize, "Number of templates to retry kept in memory, the next ones being spilled to a temporary file")
+	set.IntVar(&options.RateLimitPerHost, "rate-limit-per-host", 0, "Maximum number of requests per second to each host, including the retries and the redirect hops, 0 for no limit")
+	set.BoolVar(&options.Adaptive, "adaptive", false, "Back off the concurrency and delay the requests of the hosts with rising timeout and connection error rates, recovering once they are gone")
+	set.IntVar(&options.AdaptiveMinConcurrency, "adaptive-min-concurrency", adaptive.DefaultMinConcurrency, "Minimum number of requests in flight to each host with -adaptive")
+	set.IntVar(&options.AdaptiveMaxConcurrency, "adaptive-max-concurrency", 0, "Maximum number of requests in flight to each host and to all of them with -adaptive, -c if 0")
+	set.IntVar(&options.AdaptiveWindow, "adaptive-window", adaptive.DefaultWindow, "Number of the last requests to a host the error rates of -adaptive are computed on")
+	set.Float64Var(&options.AdaptiveBackoffRate, "adaptive-backoff-rate", adaptive.DefaultBackoffRate, "Error rate of a host halving its concurrency and doubling its delay with -adaptive")
+	set.Float64Var(&options.AdaptiveRecoverRate, "adaptive-recover-rate", adaptive.DefaultRecoverRate, "Error rate of a host below which its concurrency is increased by one and its delay halved with -adaptive")
+	set.DurationVar(&options.AdaptiveMaxDelay, "adaptive-max-delay", adaptive.DefaultMaxDelay, "Maximum delay between the requests to a host backed off by -adaptive")
+	set.DurationVar(&options.MaxScanDuration, "max-scan-duration", 0, "Maximum duration of the scan (i.e 30m), after which it stops with the results so far and exits with code 3, 0 for no limit")
+	set.DurationVar(&options.TemplateTimeout, "template-timeout", 0, "Maximum duration of a template on a target (i.e 5m), after which it is abandoned, 0 for no limit")
+	set.BoolVar(&options.NoDedupe, "no-dedupe", false, "Don't skip the duplicates of the targets streamed from stdin, for unbounded streams")
+	set.StringVar(&options.ScanStrategy, "scan-strategy", templateSpray, "Order of the scan: template-spray runs each template on all the targets, host-spray runs all the templates on each target in turn")
+	set.StringVar(&options.ExtractorOutput, "extractor-output", "", "File collecting the sorted unique extracted values of the scan")
+	set.StringVar(&options.ExtractorDir, "extractor-dir", "", "Directory the to-file extractors of the templates append their values in, the working directory by default")
+	set.StringVar(&options.SarifExport, "sarif-export", "", "File to write the results of the scan in SARIF format")
+	set.StringVar(&options.MarkdownExport, "markdown-export", "", "Directory to write a markdown report of the results of the scan, a file per finding with an index")
+	set.StringVar(&options.ElasticsearchExport, "elasticsearch-export", "", "Yaml config of the elasticsearch cluster to index the results of the scan in")
+	set.StringVar(&options.WebhookExport, "webhook-export", "", "Yaml config of the webhook to post each result of the scan to")
+	set.BoolVar(&options.WebhookCheck, "webhook-check", false, "Check the webhook can be reached before running the scan")
+	set.StringVar(&options.SyslogExport, "syslog-export", "", "Url of the syslog collector to send the results of the scan to, i.e udp://host:514, tcp://host:514 or tls://host:6514")
+	set.StringVar(&options.KafkaExport, "kafka-export", "", "Yaml config of the kafka topic to publish each result of the scan to")
+	set.StringVar(&options.CSV, "csv", "", "File to append the results of the scan to as csv rows")
+	set.StringVar(&options.CSVFields, "csv-fields", "", "Comma separated columns of the csv file, in order (default "+strings.Join(csv.DefaultFields, ",")+")")
+	set.StringVar(&options.ReportConfig, "report-config", "", "Yaml config of the github, gitlab or jira issue tracker to file the findings above a severity in")
+	set.BoolVar(&options.ReportDryRun, "report-dry-run", false, "Print the issues and comments which would be filed in the issue tracker instead of filing them")
+	set.BoolVar(&options.Dedupe, "dedupe", false, "Suppress the identical findings of the scan, across the targets")
+	set.StringVar(&options.DedupeKey, "dedupe-key", "", "Comma separated fields of the fingerprints of the findings (default "+strings.Join(dedupe.DefaultKey, ",")+")")
+	set.StringVar(&options.DedupeState, "dedupe-state", "", "File persisting the fingerprints of the findings across runs to report only the new ones")
+	set.BoolVar(&options.ShowDuplicates, "show-duplicates", false, "Write the suppressed duplicate and known findings to the json output")
+	set.BoolVar(&options.MatcherStatus, "matcher-status", false, "Write the matched, not-matched or errored status of each template for each target to the json output")
+	set.StringVar(&options.StatsJSON, "stats-json", "", "File to write the summary of the scan to as json, at the end of the scan or when it is interrupted")
+	set.IntVar(&options.StatsTop, "stats-top", 10, "Number of templates with the most findings shown in the summary, 0 for all")
+	set.BoolVar(&options.Stats, "stats", false, "Write the progress of the scan to stderr as a json line at an interval instead of showing the progress bar")
+	set.IntVar(&options.StatsInterval, "stats-interval", 5, "Number of seconds between the json lines of the progress of -stats")
+	set.StringVar(&options.StatsFile, "stats-file", "", "File to write the json lines of the progress of -stats to instead of stderr")
+	set.BoolVar(&options.Metrics, "metrics", false, "Serve the pprof profiles and the metrics of the engine as json on localhost during the scan")
+	set.IntVar(&options.MetricsPort, "metrics-port", 9092, "Localhost port of the server of -metrics")
+	set.StringVar(&options.Server, "server", "", "Localhost address of a control api to pause, resume, stop and inspect the scan, i.e 127.0.0.1:9093")
+	set.StringVar(&options.ServerToken, "server-token", "", "Bearer token authenticating the requests to the control api of -server")
+	set.BoolVar(&options.Benchmark, "benchmark", false, "Record the timings of the templates and the hosts and show the slowest ones and the requests per second at the end of the scan")
+	set.StringVar(&options.BenchmarkJSON, "benchmark-json", "", "File to write the report of -benchmark to as json, with all the templates and hosts")
+	set.BoolVar(&options.BenchmarkNoOutput, "benchmark-no-output", false, "Discard the results of -benchmark, only counting them, to tune the engine")
+	set.StringVar(&options.RedactHeaders, "redact-headers", "", "Comma separated headers to redact in the output along with "+strings.Join(redact.DefaultHeaders, ", "))
+	set.BoolVar(&options.NoRedact, "no-redact", false, "Write the sensitive headers and the environment variables of the templates as is, for local debugging")
+	set.BoolVar(&options.GroupByHost, "group-by-host", false, "Show the results on screen by host, sorted by severity, once all the templates ran on the host")
+	set.IntVar(&options.GroupMaxSize, "group-max-size", grouping.DefaultMaxSize, "Maximum length in bytes of the results buffered by host, the largest blocks being shown early above it, 0 for no limit")
+	set.BoolVar(&options.PassiveExtract, "passive-extract", false, "Write only the extracted values of templates without matchers")
+	set.StringVar(&options.Severity, "severity", "", "Run only the templates with the comma separated severities")
+	set.StringVar(&options.Tags, "tags", "", "Run only the templates with one of the comma separated tags")
+	set.StringVar(&options.ExcludeTags, "exclude-tags", "", "Don't run the templates with one of the comma separated tags")
+	set.StringVar(&options.ExcludeTemplates, "exclude-templates", "", "Don't run the comma separated template files, directories or glob patterns")
+	set.StringVar(&options.ExcludeIDs, "exclude-id", "", "Don't run the templates with one of the comma separated ids")
+	set.StringVar(&options.Author, "author", "", "Run only the templates by one of the comma separated authors")
+	set.BoolVar(&options.Validate, "validate", false, "Validate the templates, exiting with an error if any is invalid")
+	set.BoolVar(&options.StrictFields, "strict-fields", false, "Fail to load the templates with unknown fields")
+	set.BoolVar(&options.Strict, "strict", false, "Abort on duplicate or invalid template ids and fail the templates using deprecated syntax instead of warning")
+	set.BoolVar(&options.UpdateRemoteTemplates, "update-remote-templates", false, "Download again the cached templates of urls and repositories")
+	set.BoolVar(&options.NoRemoteTemplates, "no-remote-templates", false, "Disable loading templates from urls and repositories")
+	set.BoolVar(&options.AllowEnvVars, "allow-env-vars", false, "Expand the environment variables referenced by the templates with {{env(\"NAME\")}}")
+	set.BoolVar(&options.AllowMissingEnvVars, "allow-missing-env-vars", false, "Use empty values with a warning for the missing environment variables of templates")
+	set.StringVar(&options.TemplateSignature, "template-signature", templates.SignatureIgnore, "Verification of the template signatures: ignore, warn or enforce")
+	set.StringVar(&options.TrustedKeys, "trusted-keys", "", "Comma separated files of public keys the templates are signed with")
+	set.StringVar(&options.SignTemplates, "sign-templates", "", "Sign the templates in place with the private key file and exit")
+	set.StringVar(&options.GenerateSigningKey, "generate-signing-key", "", "Write a new private key to the file and its public key to the file with .pub and exit")
+	set.BoolVar(&options.Watch, "watch", false, "Watch the templates after the scan, rerunning the changed ones until interrupted")
+
+	set.IntVar(&options.TemplateThreads, "template-threads", 0, "Number of targets each template runs towards concurrently, overriding the threads of the templates")
+	set.StringVar(&options.TestFixtures, "test", "", "Test the templates on the recorded http responses of the directory instead of running them")
+	set.StringVar(&options.Passive, "passive", "", "Run the http templates on the stored responses of the directory instead of sending requests, - for the files listed on stdin")
+	set.BoolVar(&options.TemplateList, "tl", false, "List the templates a scan with the same flags would run, with the counts by severity and tag")
+	set.BoolVar(&options.TemplateList, "template-list", false, "List the templates a scan with the same flags would run, with the counts by severity and tag")
+	set.BoolVar(&options.DryRun, "dry-run", false, "List the requests a scan with the same flags would send to the targets, without sending them")
+	set.IntVar(&options.DryRunLimit, "dry-run-limit", 10, "Number of requests of each template listed per target by -dry-run, 0 for all")
+	set.BoolVar(&options.Project, "project", false, "Cache the http responses on disk, the identical requests of the next runs reusing them instead of being sent")
+	set.StringVar(&options.ProjectPath, "project-path", filepath.Join(os.TempDir(), "nuclei-project"), "Directory of the responses cached by -project")
+	set.DurationVar(&options.ProjectTTL, "project-ttl", project.DefaultTTL, "Duration the responses cached by -project are reused for, 0 for ever")
+	set.BoolVar(&options.ProjectRewrite, "project-rewrite", false, "Send all the requests again with -project, replacing the cached responses")
+	set.StringVar(&options.ProjectRandom, "project-random", projectRandomSkip, "Caching of the requests with random values by -project: skip sends them on each run, template caches them by the requests of the template before the replacement of the values")
+	set.StringVar(&options.InteractshServer, "interactsh-server", interactsh.DefaultServer, "Url of the interactsh server handing out the urls of {{interactsh-url}} and polled for their interactions")
+	set.StringVar(&options.InteractshToken, "interactsh-token", "", "Token authenticating the client to a self-hosted interactsh server")
+	set.IntVar(&options.InteractionsCacheSize, "interactions-cache-size", interactsh.DefaultCacheSize, "Number of interactsh urls correlated to their requests, the interactions of the oldest ones being ignored")
+	set.DurationVar(&options.InteractionsPoll, "interactions-poll-duration", interactsh.DefaultPollInterval, "Interval the interactions are polled at from the interactsh server")
+	set.DurationVar(&options.InteractionsCooldown, "interactions-cooldown-period", 5*time.Second, "Duration the interactions are still polled for after the scan, for the late callbacks")
+	set.BoolVar(&options.NoInteractsh, "no-interactsh", false, "Skip the templates using interactsh, sending no requests to the interactsh server")
+	set.StringVar(&options.OOBListen, "oob-listen", "", "Ip:port of the built-in http listener receiving the interactions of {{interactsh-url}} instead of the interactsh server, reached by the targets at this ip")
+	set.StringVar(&options.OOBDomain, "oob-domain", "", "Domain delegated to the dns listener of -oob-listen, the urls being its subdomains instead of paths of the ip")
+	set.StringVar(&options.OOBDNSListen, "oob-dns-listen", "", "Address of the dns listener of -oob-domain, port 53 of the ip of -oob-listen by default")
+	set.StringVar(&options.OOBLog, "oob-log", "", "File the interactions received by -oob-listen are appended to as json lines for audit")
+	set.BoolVar(&options.Headless, "headless", false, "Run the headless requests of the templates in a chromium browser, the templates using them being skipped otherwise")
+	set.IntVar(&options.HeadlessConcurrency, "headless-concurrency", headless.DefaultConcurrency, "Number of browser pages opened at once by the headless requests, whatever the concurrency of the templates")
+	set.StringVar(&options.HeadlessBrowser, "headless-browser", "", "Path of the chromium browser of the headless requests, looked up in the PATH otherwise")
+	set.IntVar(&options.FileConcurrency, "file-concurrency", file.DefaultConcurrency, "Number of files read at once by each walk of a directory by the file requests")
+}
+
+// Override marks a flag as given, the values of the options overriding the
+// ones of the templates as on the command line, i.e for -timeout.
+func (options *Options) Override(name string) {
+	if options.overrides == nil {
+		options.overrides = make(map[string]bool)
+	}
+	options.overrides[name] = true
+}
 
-	flag.StringVar(&options.Target, "target", "", "Target is a single target to scan using template")
-	flag.Var(&options.Templates, "t", "Template input file/files to run on host. Can be used multiple times.")
-	flag.StringVar(&options.Targets, "l", "", "List of URLs to run templates on")
-	flag.StringVar(&options.Output, "o", "", "File to write output to (optional)")
-	flag.StringVar(&options.ProxyURL, "proxy-url", "", "URL of the proxy server")
-	flag.StringVar(&options.ProxySocksURL, "proxy-socks-url", "", "URL of the proxy socks server")
-	flag.BoolVar(&options.Silent, "silent", false, "Show only results in output")
-	flag.BoolVar(&options.Version, "version", false, "Show version of nuclei")
-	flag.BoolVar(&options.Verbose, "v", false, "Show Verbose output")
-	flag.BoolVar(&options.NoColor, "nC", false, "Don't Use colors in output")
-	flag.IntVar(&options.Threads, "c", 50, "Number of concurrent requests to make")
-	flag.IntVar(&options.Timeout, "timeout", 5, "Time to wait in seconds before timeout")
-	flag.IntVar(&options.Retries, "retries", 1, "Number of times to retry a failed request")
-	flag.Var(&options.CustomHeaders, "H", "Custom Header.")
-	flag.BoolVar(&options.Debug, "debug", false, "Allow debugging of request/responses")
-	flag.BoolVar(&options.UpdateTemplates, "update-templates", false, "Update Templates updates the installed templates (optional)")
-	flag.StringVar(&options.TemplatesDirectory, "update-directory", "", "Directory to use for storing nuclei-templates")
-	flag.BoolVar(&options.JSON, "json", false, "Write json output to files")
-	flag.BoolVar(&options.JSONRequests, "json-requests", false, "Write requests/responses for matches in JSON output")
-	flag.BoolVar(&options.DisableProgressBar, "no-pbar", false, "Disable the progress bar")
+// ParseOptions parses the command line flags provided by a user, returning
+// an error if they are invalid.
+func ParseOptions() (*Options, error) {
+	options := &Options{}
+	options.registerFlags(flag.CommandLine)
 
 	flag.Parse()
 
+	options.overrides = make(map[string]bool)
+	flag.Visit(func(f *flag.Flag) {
+		options.overrides[f.Name] = true
+	})
+
 	// Check if stdin pipe was given
-	options.Stdin = hasStdin()
+	// the stdin of the passive mode lists the stored responses
+	options.Stdin = hasStdin() && options.Passive == ""
 
 	// Read the inputs and configure the logging
 	options.configureOutput()
@@ -87,13 +400,23 @@ func ParseOptions() *Options {
 		os.Exit(0)
 	}
 
+	if options.GenerateSigningKey != "" {
+		if err := signature.GenerateKey(options.GenerateSigningKey); err != nil {
+			return nil, fmt.Errorf("could not generate signing key: %s", err)
+		}
+		gologger.Infof("Wrote the private key %s and the public key %s.pub\n", options.GenerateSigningKey, options.GenerateSigningKey)
+		os.Exit(0)
+	}
+
 	// Validate the options passed by the user and if any
 	// invalid options have been used, exit.
-	err := options.validateOptions()
-	if err != nil {
-		gologger.Fatalf("Program exiting: %s\n", err)
+	if err := options.validateOptions(); err != nil {
+		return nil, err
 	}
-	return options
+	if options.DryRun {
+		options.disableOutputs()
+	}
+	return options, nil
 }
 
 func hasStdin() bool {
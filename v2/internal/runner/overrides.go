@@ -0,0 +1,83 @@
+package runner
+
+import (
+	"github.com/projectdiscovery/gologger"
+	"github.com/projectdiscovery/nuclei/v2/pkg/requests"
+	"github.com/projectdiscovery/nuclei/v2/pkg/templates"
+)
+
+// overridden returns true if the value of a flag given on the command
+// line overrides the one of a template, unless the template keeps its own.
+func (r *Runner) overridden(template *templates.Template, name string) bool {
+	return r.options.overrides[name] && !template.NoOverride
+}
+
+// effectiveTimeout returns the timeout of a request of a template, the
+// -timeout given on the command line taking precedence over the template
+// value, itself taking precedence over the default timeout.
+func (r *Runner) effectiveTimeout(template *templates.Template, timeout int) int {
+	if timeout > 0 && !r.overridden(template, "timeout") {
+		return timeout
+	}
+	return r.options.Timeout
+}
+
+// effectiveRetries returns the retries of a request of a template, with
+// the same precedence as the timeout.
+func (r *Runner) effectiveRetries(template *templates.Template, retries int) int {
+	if retries > 0 && !r.overridden(template, "retries") {
+		return retries
+	}
+	return r.options.Retries
+}
+
+// effectiveThreads returns the number of targets a template runs towards
+// concurrently, -template-threads taking precedence over the template
+// value. It is capped by the global concurrency, which is the default.
+func (r *Runner) effectiveThreads(template *templates.Template) int {
+	threads := template.Threads
+	if r.options.TemplateThreads > 0 && (threads == 0 || !template.NoOverride) {
+		threads = r.options.TemplateThreads
+	}
+	if threads <= 0 || threads > r.options.Threads {
+		threads = r.options.Threads
+	}
+	return threads
+}
+
+// logEffectiveSettings displays the effective timeout, retries and threads
+// of the requests of a template in verbose mode.
+func (r *Runner) logEffectiveSettings(template *templates.Template, request interface{}) {
+	var protocol string
+	var timeout, retries int
+	switch value := request.(type) {
+	case *requests.DNSRequest:
+		protocol = "dns"
+		timeout = r.effectiveTimeout(template, value.Timeout)
+		retries = r.effectiveRetries(template, value.Retries)
+	case *requests.BulkHTTPRequest:
+		protocol = "http"
+		timeout = r.effectiveTimeout(template, value.Timeout)
+		retries = r.effectiveRetries(template, value.Retries)
+	case *requests.HeadlessRequest:
+		// the steps are not retried, each of them taking up to -timeout
+		protocol = "headless"
+		timeout = r.options.Timeout
+	case *requests.NetworkRequest:
+		// the connections are not retried, the steps sharing one
+		protocol = "network"
+		timeout = r.effectiveTimeout(template, value.Timeout)
+	case *requests.FileRequest:
+		// the files are read without timeout nor retries
+		protocol = "file"
+	case *requests.WebsocketRequest:
+		// the connections are not retried, the messages sharing one
+		protocol = "websocket"
+		timeout = r.effectiveTimeout(template, value.Timeout)
+	case *requests.SSLRequest:
+		// the handshakes are not retried
+		protocol = "ssl"
+		timeout = r.effectiveTimeout(template, value.Timeout)
+	}
+	gologger.Verbosef("[%s] Running %s requests with timeout %ds, retries %d and threads %d\n", "settings", template.ID, protocol, timeout, retries, r.effectiveThreads(template))
+}
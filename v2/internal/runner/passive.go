@@ -0,0 +1,369 @@
+package runner
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"sync/atomic"
+
+	"github.com/karrick/godirwalk"
+	"github.com/projectdiscovery/gologger"
+	"github.com/projectdiscovery/nuclei/v2/pkg/executer"
+	"github.com/projectdiscovery/nuclei/v2/pkg/templates"
+	"github.com/projectdiscovery/nuclei/v2/pkg/workflows"
+)
+
+// passiveStdin is the -passive value reading the stored responses listed on stdin
+const passiveStdin = "-"
+
+// passiveIndex is the file of a directory of stored responses listing them
+// along with their URL, as written by meg.
+const passiveIndex = "index"
+
+// passiveResponse is a stored response along with the target it was received from
+type passiveResponse struct {
+	path   string
+	target string
+}
+
+// passiveTotals are the counts of the stored responses of the passive mode
+type passiveTotals struct {
+	responses  int64
+	unreadable int64
+	untargeted int64
+
+	mutex   sync.Mutex
+	targets map[string]struct{}
+}
+
+// Passive runs the matchers and extractors of the http templates of the
+// user input on the stored responses of the -passive directory, or of the
+// files listed on stdin with -passive -, without any network I/O. The
+// results are written as for a scan, marked as passive in the json output.
+// The templates sending more than one request, with payloads or with
+// baseline requests are skipped, their matchers depending on the responses
+// of the requests they send.
+func (r *Runner) Passive() error {
+	paths := r.templatePaths()
+	if len(paths) == 0 {
+		return ErrNoTemplates
+	}
+	loaded, err := r.loadTemplates(paths)
+	if err != nil {
+		return err
+	}
+	for i, path := range loaded.broken {
+		gologger.Errorf("Could not parse file '%s': %s\n", path, loaded.errors[i])
+	}
+
+	var executers []*templateExecuters
+	skipped := make(map[string]int)
+	for _, parsed := range loaded.parsed {
+		reason := passiveSkipReason(parsed)
+		if reason != "" {
+			gologger.Verbosef("Skipping %s which can't run on stored responses: %s\n", "passive", templateID(parsed), reason)
+			skipped[reason]++
+			continue
+		}
+		executers = append(executers, r.newTemplateExecuters(nil, parsed.(*templates.Template), nil, 0))
+	}
+	if count := len(loaded.parsed) - len(executers); count > 0 {
+		gologger.Labelf("Skipped %d templates which can't run on stored responses (%s)\n", count, passiveReasons(skipped))
+	}
+	if len(executers) == 0 {
+		return errors.New("no templates can run on stored responses")
+	}
+
+	responses := make(chan passiveResponse)
+	go func() {
+		defer close(responses)
+		if err := r.eachStoredResponse(func(response passiveResponse) bool {
+			select {
+			case responses <- response:
+				return true
+			case <-r.ctx.Done():
+				return false
+			}
+		}); err != nil {
+			gologger.Errorf("Could not read the stored responses: %s\n", err)
+		}
+	}()
+
+	totals := &passiveTotals{targets: make(map[string]struct{})}
+	var results int32
+	var wg sync.WaitGroup
+	for i := 0; i < r.options.Threads; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for response := range responses {
+				if r.evaluateStored(executers, response, totals) {
+					atomic.StoreInt32(&results, 1)
+				}
+			}
+		}()
+	}
+	wg.Wait()
+	r.grouper.Flush()
+	for _, t := range executers {
+		t.flush()
+	}
+
+	gologger.Infof("Evaluated %d templates on %d stored responses of %d targets without sending any request\n", len(executers), totals.responses, len(totals.targets))
+	if totals.unreadable > 0 {
+		gologger.Labelf("Could not read %d stored responses, use -v to show the errors\n", totals.unreadable)
+	}
+	if totals.untargeted > 0 {
+		gologger.Labelf("Skipped %d stored responses without a target, neither in the index nor in a host directory\n", totals.untargeted)
+	}
+	r.closeOutputs()
+	r.finishResults(atomic.LoadInt32(&results) == 1)
+	return nil
+}
+
+// passiveSkipReason returns why a template or a workflow can't run on the
+// stored responses, empty if it can.
+func passiveSkipReason(parsed interface{}) string {
+	template, ok := parsed.(*templates.Template)
+	if !ok {
+		return "workflow"
+	}
+	if len(template.RequestsHeadless) > 0 {
+		return "headless requests"
+	}
+	if len(template.RequestsNetwork) > 0 {
+		return "network requests"
+	}
+	if len(template.RequestsFile) > 0 {
+		return "file requests"
+	}
+	if len(template.RequestsWebsocket) > 0 {
+		return "websocket requests"
+	}
+	if len(template.RequestsSSL) > 0 {
+		return "ssl requests"
+	}
+	if len(template.RequestsDNS) > 0 || len(template.BulkRequestsHTTP) == 0 {
+		return "dns requests"
+	}
+	if len(template.BulkRequestsHTTP) > 1 || template.BulkRequestsHTTP[0].Total() > 1 {
+		return "multiple requests"
+	}
+	request := template.BulkRequestsHTTP[0]
+	if len(request.Payloads) > 0 {
+		return "payloads"
+	}
+	if request.Baseline {
+		return "baseline requests"
+	}
+	if template.UsesInteractsh() {
+		return "interactsh"
+	}
+	for _, matcher := range request.Matchers {
+		if matcher.Baseline {
+			return "baseline requests"
+		}
+	}
+	return ""
+}
+
+// templateID returns the id of a template or of a workflow
+func templateID(parsed interface{}) string {
+	switch t := parsed.(type) {
+	case *templates.Template:
+		return t.ID
+	case *workflows.Workflow:
+		return t.ID
+	}
+	return ""
+}
+
+// passiveReasons returns the numbers of skipped templates by reason
+func passiveReasons(skipped map[string]int) string {
+	reasons := make([]string, 0, len(skipped))
+	for reason, count := range skipped {
+		reasons = append(reasons, fmt.Sprintf("%s %d", reason, count))
+	}
+	sort.Strings(reasons)
+	return strings.Join(reasons, ", ")
+}
+
+// evaluateStored evaluates the templates on a stored response, returning
+// true if it got results.
+func (r *Runner) evaluateStored(executers []*templateExecuters, stored passiveResponse, totals *passiveTotals) bool {
+	if stored.target == "" {
+		gologger.Verbosef("Skipping %s without a target\n", "passive", stored.path)
+		atomic.AddInt64(&totals.untargeted, 1)
+		return false
+	}
+	data, err := ioutil.ReadFile(stored.path)
+	if err != nil {
+		gologger.Verbosef("Could not read %s: %s\n", "passive", stored.path, err)
+		atomic.AddInt64(&totals.unreadable, 1)
+		return false
+	}
+	response, err := executer.ReadHTTPResponse(data)
+	if err != nil {
+		gologger.Verbosef("Could not read %s: %s\n", "passive", stored.path, err)
+		atomic.AddInt64(&totals.unreadable, 1)
+		return false
+	}
+	atomic.AddInt64(&totals.responses, 1)
+	totals.mutex.Lock()
+	totals.targets[stored.target] = struct{}{}
+	totals.mutex.Unlock()
+
+	var results bool
+	for _, t := range executers {
+		for _, httpExecuter := range t.http {
+			result := httpExecuter.ExecutePassive(stored.target, response)
+			if result.Error != nil {
+				gologger.Warningf("[%s] Could not evaluate %s: %s\n", t.template.ID, stored.path, result.Error)
+				continue
+			}
+			results = results || result.GotResults
+		}
+	}
+	return results
+}
+
+// errPassiveStopped stops the walk of the stored responses
+var errPassiveStopped = errors.New("stopped")
+
+// eachStoredResponse calls a function with each stored response of the
+// -passive directory, or listed on stdin with -passive -, until it returns
+// false. The target of a response is its URL in the index if any, or
+// else the [scheme/]host directory it is stored in, http being the scheme
+// if missing.
+func (r *Runner) eachStoredResponse(fn func(response passiveResponse) bool) error {
+	if r.options.Passive == passiveStdin {
+		scanner := bufio.NewScanner(os.Stdin)
+		for scanner.Scan() {
+			path, target := parseIndexLine(scanner.Text())
+			if path == "" {
+				continue
+			}
+			// the listed files are stored in their host directory
+			if target == "" {
+				directory := filepath.Dir(path)
+				relative := filepath.Base(directory)
+				if scheme := filepath.Base(filepath.Dir(directory)); scheme == "http" || scheme == "https" {
+					relative = scheme + "/" + relative
+				}
+				target = layoutTarget(relative)
+			}
+			if !fn(passiveResponse{path: path, target: target}) {
+				return nil
+			}
+		}
+		return scanner.Err()
+	}
+
+	directory := r.options.Passive
+	index, err := readIndex(directory)
+	if err != nil {
+		return err
+	}
+	indexPath := filepath.Join(directory, passiveIndex)
+	err = godirwalk.Walk(directory, &godirwalk.Options{
+		Callback: func(path string, d *godirwalk.Dirent) error {
+			if d.IsDir() || path == indexPath {
+				return nil
+			}
+			target, ok := index[absolutePath(path)]
+			if !ok {
+				relative, err := filepath.Rel(directory, filepath.Dir(path))
+				if err == nil && relative != "." {
+					target = layoutTarget(relative)
+				}
+			}
+			if !fn(passiveResponse{path: path, target: target}) {
+				return errPassiveStopped
+			}
+			return nil
+		},
+		ErrorCallback: func(path string, err error) godirwalk.ErrorAction {
+			if err == errPassiveStopped {
+				return godirwalk.Halt
+			}
+			gologger.Verbosef("Could not read %s: %s\n", "passive", path, err)
+			return godirwalk.SkipNode
+		},
+		Unsorted: true,
+	})
+	if err == errPassiveStopped {
+		return nil
+	}
+	return err
+}
+
+// absolutePath returns the absolute path of a file, the path itself if it
+// can't be made absolute.
+func absolutePath(path string) string {
+	if absolute, err := filepath.Abs(path); err == nil {
+		return absolute
+	}
+	return filepath.Clean(path)
+}
+
+// readIndex reads the index of a directory of stored responses if any, the
+// URLs of its files by path. The files of the index are relative to the
+// current directory or to the directory of the index.
+func readIndex(directory string) (map[string]string, error) {
+	index := make(map[string]string)
+	file, err := os.Open(filepath.Join(directory, passiveIndex))
+	if os.IsNotExist(err) {
+		return index, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+
+	scanner := bufio.NewScanner(file)
+	for scanner.Scan() {
+		path, target := parseIndexLine(scanner.Text())
+		if path == "" || target == "" {
+			continue
+		}
+		if _, err := os.Stat(path); err != nil && !filepath.IsAbs(path) {
+			path = filepath.Join(directory, path)
+		}
+		index[absolutePath(path)] = target
+	}
+	return index, scanner.Err()
+}
+
+// parseIndexLine returns the file and the URL of a line of an index, the
+// URL being optional and followed by anything, i.e the status of meg.
+func parseIndexLine(line string) (string, string) {
+	fields := strings.Fields(line)
+	switch len(fields) {
+	case 0:
+		return "", ""
+	case 1:
+		return fields[0], ""
+	}
+	return fields[0], fields[1]
+}
+
+// layoutTarget returns the target of the responses stored in a directory
+// relative to the stored ones, [scheme/]host/..., http being the scheme if
+// missing.
+func layoutTarget(relative string) string {
+	parts := strings.Split(filepath.ToSlash(relative), "/")
+	scheme := "http"
+	if len(parts) > 1 && (parts[0] == "http" || parts[0] == "https") {
+		scheme, parts = parts[0], parts[1:]
+	}
+	if parts[0] == "" || parts[0] == "." || parts[0] == ".." {
+		return ""
+	}
+	return scheme + "://" + parts[0]
+}
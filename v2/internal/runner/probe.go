@@ -0,0 +1,195 @@
+package runner
+
+import (
+	"crypto/tls"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net"
+	"net/http"
+	"net/url"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/projectdiscovery/nuclei/v2/pkg/ratelimit"
+	"golang.org/x/net/proxy"
+)
+
+// prober probes scheme-less inputs for a working http/https scheme.
+type prober struct {
+	client *http.Client
+	order  []string
+	// limiter limits the rate of the probes to each host if any
+	limiter *ratelimit.Limiter
+
+	mutex   *sync.Mutex
+	results map[string]*probeResult
+	failed  int64
+}
+
+// probeResult is the cached probing result for a single input
+type probeResult struct {
+	once sync.Once
+	URL  string
+	ok   bool
+}
+
+// newProber creates a new scheme prober for inputs without a scheme
+func newProber(options *Options, limiter *ratelimit.Limiter) (*prober, error) {
+	client, err := newProbeClient(options)
+	if err != nil {
+		return nil, err
+	}
+	return &prober{
+		client:  client,
+		order:   strings.Split(options.ProbeOrder, ","),
+		limiter: limiter,
+		mutex:   &sync.Mutex{},
+		results: make(map[string]*probeResult),
+	}, nil
+}
+
+// newProbeClient returns the client of the probes, which goes through the
+// proxies of the scan and does not follow redirects.
+func newProbeClient(options *Options) (*http.Client, error) {
+	transport := &http.Transport{
+		MaxIdleConnsPerHost: -1,
+		TLSClientConfig: &tls.Config{
+			Renegotiation:      tls.RenegotiateOnceAsClient,
+			InsecureSkipVerify: true,
+		},
+		DisableKeepAlives: true,
+	}
+
+	if options.ProxyURL != "" {
+		proxyURL, err := url.Parse(options.ProxyURL)
+		if err != nil {
+			return nil, err
+		}
+		transport.Proxy = http.ProxyURL(proxyURL)
+	}
+	if options.ProxySocksURL != "" {
+		socksURL, err := url.Parse(options.ProxySocksURL)
+		if err != nil {
+			return nil, err
+		}
+		proxyAuth := &proxy.Auth{}
+		proxyAuth.User = socksURL.User.Username()
+		proxyAuth.Password, _ = socksURL.User.Password()
+
+		dialer, err := proxy.SOCKS5("tcp", fmt.Sprintf("%s:%s", socksURL.Hostname(), socksURL.Port()), proxyAuth, proxy.Direct)
+		if err != nil {
+			return nil, err
+		}
+		transport.Dial = dialer.Dial
+	}
+
+	return &http.Client{
+		Transport: transport,
+		Timeout:   time.Duration(options.ProbeTimeout) * time.Second,
+		CheckRedirect: func(_ *http.Request, _ []*http.Request) error {
+			return http.ErrUseLastResponse
+		},
+	}, nil
+}
+
+// hasScheme returns true if the input already specifies a scheme
+func hasScheme(input string) bool {
+	return strings.Contains(input, "://")
+}
+
+// resolve returns the URL to use for http requests for an input.
+//
+// Inputs with a scheme are returned as is, otherwise the schemes are probed
+// in order and the first responding one is cached for the rest of the scan.
+func (p *prober) resolve(input string) (string, bool) {
+	if hasScheme(input) {
+		return input, true
+	}
+
+	p.mutex.Lock()
+	result, ok := p.results[input]
+	if !ok {
+		result = &probeResult{}
+		p.results[input] = result
+	}
+	p.mutex.Unlock()
+
+	result.once.Do(func() {
+		for _, scheme := range p.schemesFor(input) {
+			URL := scheme + "://" + input
+			if p.probe(URL) {
+				result.URL = URL
+				result.ok = true
+				return
+			}
+		}
+		p.mutex.Lock()
+		p.failed++
+		p.mutex.Unlock()
+	})
+	return result.URL, result.ok
+}
+
+// schemesFor returns the order in which the schemes are probed for an input.
+//
+// Well known ports favor their scheme, otherwise the configured order is used.
+func (p *prober) schemesFor(input string) []string {
+	_, port, err := net.SplitHostPort(input)
+	if err != nil {
+		return p.order
+	}
+
+	switch port {
+	case "443", "8443":
+		return []string{"https", "http"}
+	case "80", "8080":
+		return []string{"http", "https"}
+	}
+	return p.order
+}
+
+// probe returns true if the URL responds to a http request
+func (p *prober) probe(URL string) bool {
+	req, err := http.NewRequest(http.MethodGet, URL, nil)
+	if err != nil {
+		return false
+	}
+	req.Header.Set("User-Agent", "Nuclei - Open-source project (github.com/projectdiscovery/nuclei)")
+
+	p.limiter.Wait(req.Context(), ratelimit.HostPort(req.URL))
+	resp, err := p.client.Do(req)
+	if err != nil {
+		return false
+	}
+	io.Copy(ioutil.Discard, io.LimitReader(resp.Body, 4096))
+	resp.Body.Close()
+	return true
+}
+
+// forget forgets the probing result of an input, which is probed again if
+// resolved once more, returning the URL it resolved to if any. It does
+// nothing without a prober.
+func (p *prober) forget(input string) string {
+	if p == nil {
+		return ""
+	}
+	p.mutex.Lock()
+	defer p.mutex.Unlock()
+
+	result, ok := p.results[input]
+	if !ok {
+		return ""
+	}
+	delete(p.results, input)
+	return result.URL
+}
+
+// failedCount returns the number of inputs which did not respond to any probe
+func (p *prober) failedCount() int64 {
+	p.mutex.Lock()
+	defer p.mutex.Unlock()
+
+	return p.failed
+}
@@ -0,0 +1,11 @@
+package runner
+
+const (
+	// projectRandomSkip sends the requests with random values on each run
+	// of the project, their built requests being never the same.
+	projectRandomSkip = "skip"
+	// projectRandomTemplate caches the responses by the requests of the
+	// template before the replacement of the values, the random values
+	// being ignored.
+	projectRandomTemplate = "template"
+)
@@ -0,0 +1,233 @@
+package runner
+
+import (
+	"archive/zip"
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"net/url"
+	"os"
+	"path"
+	"path/filepath"
+	"strings"
+	"time"
+)
+
+const (
+	// githubPrefix prefixes the github repositories of the template inputs
+	githubPrefix = "github.com/"
+	// remoteTimeout is the time to wait for a remote template or repository
+	remoteTimeout = 2 * time.Minute
+)
+
+// remoteTokenVariables are the environment variables checked in order
+// for the token authenticating the requests to github.
+var remoteTokenVariables = []string{"NUCLEI_TEMPLATES_TOKEN", "GITHUB_TOKEN"}
+
+// remoteTemplate is a template file url or a github repository of the user input
+type remoteTemplate struct {
+	// fileURL is the url of a single template file if any
+	fileURL string
+
+	owner, repository string
+	// subdirectory is the directory of the repository to load if any
+	subdirectory string
+	// ref is the branch, tag or commit of the repository, the default branch if empty
+	ref string
+}
+
+// isRemoteTemplate returns true if a template input is a url or a github repository
+func isRemoteTemplate(input string) bool {
+	return strings.HasPrefix(input, "http://") || strings.HasPrefix(input, "https://") || strings.HasPrefix(input, githubPrefix)
+}
+
+// parseRemoteTemplate parses an url or a github.com/owner/repository[/subdirectory][@ref] input
+func parseRemoteTemplate(input string) (*remoteTemplate, error) {
+	if !strings.HasPrefix(input, githubPrefix) {
+		if _, err := url.Parse(input); err != nil {
+			return nil, err
+		}
+		return &remoteTemplate{fileURL: input}, nil
+	}
+
+	remote := &remoteTemplate{}
+	repository := strings.TrimPrefix(input, githubPrefix)
+	if index := strings.LastIndex(repository, "@"); index != -1 {
+		repository, remote.ref = repository[:index], repository[index+1:]
+		if remote.ref == "" {
+			return nil, errors.New("empty ref specified")
+		}
+	}
+	parts := strings.SplitN(strings.Trim(repository, "/"), "/", 3)
+	if len(parts) < 2 || parts[0] == "" || parts[1] == "" {
+		return nil, errors.New("repositories are specified as github.com/owner/repository[/subdirectory][@ref]")
+	}
+	remote.owner, remote.repository = parts[0], parts[1]
+	if len(parts) == 3 {
+		remote.subdirectory = parts[2]
+	}
+	return remote, nil
+}
+
+// cacheKey returns the name of the cache directory of the remote template
+func (t *remoteTemplate) cacheKey() string {
+	source := t.fileURL
+	if source == "" {
+		source = githubPrefix + t.owner + "/" + t.repository + "@" + t.ref
+	}
+	hash := sha256.Sum256([]byte(source))
+	return hex.EncodeToString(hash[:16])
+}
+
+// fetchRemoteTemplate returns the local path of a remote template input,
+// downloading it to the cache directory unless already cached.
+func (r *Runner) fetchRemoteTemplate(input string) (string, error) {
+	remote, err := parseRemoteTemplate(input)
+	if err != nil {
+		return "", err
+	}
+	cacheDirectory, err := os.UserCacheDir()
+	if err != nil {
+		return "", err
+	}
+	directory := filepath.Join(cacheDirectory, "nuclei", "remote-templates", remote.cacheKey())
+
+	local := directory
+	if remote.fileURL != "" {
+		name := path.Base(strings.SplitN(remote.fileURL, "?", 2)[0])
+		if !strings.HasSuffix(name, ".yaml") {
+			name = "template.yaml"
+		}
+		local = filepath.Join(directory, name)
+	}
+	if remote.subdirectory != "" {
+		local = filepath.Join(directory, filepath.FromSlash(remote.subdirectory))
+	}
+
+	if _, err := os.Stat(directory); err == nil && !r.options.UpdateRemoteTemplates {
+		if _, err := os.Stat(local); err != nil {
+			return "", err
+		}
+		return local, nil
+	}
+
+	// the templates are written to a temporary directory first, not to
+	// leave an incomplete cache behind on failures.
+	if err := os.MkdirAll(filepath.Dir(directory), os.ModePerm); err != nil {
+		return "", err
+	}
+	temporary, err := ioutil.TempDir(filepath.Dir(directory), "fetch-")
+	if err != nil {
+		return "", err
+	}
+	defer os.RemoveAll(temporary)
+
+	if remote.fileURL != "" {
+		data, err := downloadRemote(remote.fileURL)
+		if err != nil {
+			return "", err
+		}
+		if err := ioutil.WriteFile(filepath.Join(temporary, filepath.Base(local)), data, 0644); err != nil {
+			return "", err
+		}
+	} else {
+		zipballURL := fmt.Sprintf("https://api.github.com/repos/%s/%s/zipball/%s", remote.owner, remote.repository, remote.ref)
+		data, err := downloadRemote(zipballURL)
+		if err != nil {
+			return "", err
+		}
+		if err := unzipRepository(data, temporary); err != nil {
+			return "", err
+		}
+	}
+
+	if err := os.RemoveAll(directory); err != nil {
+		return "", err
+	}
+	if err := os.Rename(temporary, directory); err != nil {
+		return "", err
+	}
+	if _, err := os.Stat(local); err != nil {
+		return "", err
+	}
+	return local, nil
+}
+
+// downloadRemote downloads a remote template or repository archive, the
+// github requests being authenticated with the token of the environment if any.
+func downloadRemote(downloadURL string) ([]byte, error) {
+	req, err := http.NewRequest("GET", downloadURL, nil)
+	if err != nil {
+		return nil, err
+	}
+	if host := req.URL.Hostname(); host == "api.github.com" || host == "raw.githubusercontent.com" {
+		for _, variable := range remoteTokenVariables {
+			if token := os.Getenv(variable); token != "" {
+				req.Header.Set("Authorization", "token "+token)
+				break
+			}
+		}
+	}
+
+	client := &http.Client{Timeout: remoteTimeout}
+	res, err := client.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer res.Body.Close()
+
+	if res.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("unexpected status %d", res.StatusCode)
+	}
+	return ioutil.ReadAll(res.Body)
+}
+
+// unzipRepository extracts a github archive to a directory, without its
+// top level directory named after the commit.
+func unzipRepository(data []byte, directory string) error {
+	reader := bytes.NewReader(data)
+	z, err := zip.NewReader(reader, reader.Size())
+	if err != nil {
+		return fmt.Errorf("could not uncompress archive: %s", err)
+	}
+
+	for _, file := range z.File {
+		parts := strings.SplitN(file.Name, "/", 2)
+		if len(parts) < 2 || parts[1] == "" || strings.HasSuffix(parts[1], "/") {
+			continue
+		}
+		target := filepath.Join(directory, filepath.FromSlash(parts[1]))
+		if !strings.HasPrefix(target, directory+string(filepath.Separator)) {
+			return fmt.Errorf("invalid path in archive: %s", file.Name)
+		}
+		if err := os.MkdirAll(filepath.Dir(target), os.ModePerm); err != nil {
+			return err
+		}
+		if err := extractFile(file, target); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func extractFile(file *zip.File, target string) error {
+	reader, err := file.Open()
+	if err != nil {
+		return err
+	}
+	defer reader.Close()
+
+	f, err := os.Create(target)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+
+	_, err = io.Copy(f, reader)
+	return err
+}
@@ -0,0 +1,22 @@
+package runner
+
+import (
+	"net/http"
+	"time"
+
+	"github.com/projectdiscovery/gologger"
+	"github.com/projectdiscovery/nuclei/v2/pkg/replay"
+)
+
+// newReplayer returns the replayer of -proxy-replay, the failed replays
+// being shown with -v.
+func newReplayer(options *Options) (*replay.Replayer, error) {
+	return replay.New(replay.Options{
+		ProxyURL: options.ProxyReplay,
+		Rate:     options.ProxyReplayRate,
+		Timeout:  time.Duration(options.Timeout) * time.Second,
+		Failed: func(req *http.Request, err error) {
+			gologger.Verbosef("Could not replay %s %s: %s\n", "replay", req.Method, req.URL, err)
+		},
+	})
+}
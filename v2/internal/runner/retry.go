@@ -0,0 +1,187 @@
+package runner
+
+import (
+	"sync"
+	"sync/atomic"
+
+	"github.com/projectdiscovery/gologger"
+	"github.com/projectdiscovery/nuclei/v2/internal/progress"
+	"github.com/projectdiscovery/nuclei/v2/pkg/executer"
+	"github.com/projectdiscovery/nuclei/v2/pkg/grouping"
+	"github.com/projectdiscovery/nuclei/v2/pkg/hosterrors"
+	"github.com/projectdiscovery/nuclei/v2/pkg/retryqueue"
+)
+
+// retries are the runs of the jobs on the targets failing with a network
+// error, run again at the end of the scan with -retry-attempts.
+type retries struct {
+	queue *retryqueue.Queue
+	// round is the number of the retries being run, 0 during the scan
+	round int32
+
+	mutex sync.Mutex
+	// jobs are the jobs with runs to retry, identified by their index, and
+	// finishes the finishes of their templates deferred until the retries
+	// completed.
+	jobs     []*scanJob
+	ids      map[*scanJob]int
+	finishes []func()
+}
+
+// newRetries returns the retries of the runs of the scan, nil if they
+// are disabled.
+func newRetries(options *Options) *retries {
+	if options.RetryAttempts <= 0 {
+		return nil
+	}
+	return &retries{queue: retryqueue.New(options.RetryQueueSize), ids: make(map[*scanJob]int)}
+}
+
+// add queues a run of a job on a target to retry
+func (r *retries) add(job *scanJob, target string) error {
+	r.mutex.Lock()
+	id, ok := r.ids[job]
+	if !ok {
+		id = len(r.jobs)
+		r.ids[job] = id
+		r.jobs = append(r.jobs, job)
+	}
+	r.mutex.Unlock()
+	return r.queue.Add(retryqueue.Pair{Job: id, Target: target})
+}
+
+// job returns the job of an index
+func (r *retries) job(id int) *scanJob {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+	return r.jobs[id]
+}
+
+// deferFinish defers the finish of a template with runs to retry until
+// the retries completed.
+func (r *retries) deferFinish(finish func()) {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+	r.finishes = append(r.finishes, finish)
+}
+
+// retry queues the run of a job on a target to run it again at the end of
+// the scan if it failed with a network error, its host not being dead and
+// attempts being left, returning true if queued. The step of the run is
+// only completed once retried. The retried runs are counted in the stats.
+func (r *Runner) retry(job *scanJob, target string, err error) bool {
+	if r.retries == nil {
+		return false
+	}
+	round := int(atomic.LoadInt32(&r.retries.round))
+	network := hosterrors.IsNetworkError(err)
+	if round > 0 {
+		r.stats.RunRetried(!network)
+	}
+	if !network || round >= r.options.RetryAttempts || r.hostErrors.Dead(target) {
+		return false
+	}
+	if err := r.retries.add(job, target); err != nil {
+		gologger.Warningf("Could not queue %s on %s to retry: %s\n", job.name, target, err)
+		return false
+	}
+	job.retrying.Set(true)
+	return true
+}
+
+// finishTemplate finishes a template once its jobs completed, after the
+// retries at the end of the scan if some of its runs are retried.
+func (r *Runner) finishTemplate(jobs []*scanJob, finish func()) {
+	for _, job := range jobs {
+		if job.retrying.Get() {
+			r.retries.deferFinish(finish)
+			return
+		}
+	}
+	finish()
+}
+
+// retryConcurrency returns the number of runs retried concurrently, a
+// quarter of the global concurrency by default.
+func (r *Runner) retryConcurrency() int {
+	if r.options.RetryConcurrency > 0 {
+		return r.options.RetryConcurrency
+	}
+	if threads := r.options.Threads / 4; threads > 0 {
+		return threads
+	}
+	return 1
+}
+
+// runRetries runs the queued runs again, up to -retry-attempts times, then
+// finishes their jobs and templates. The runs are not retried once the scan
+// stopped, their steps being left to run when resuming it.
+func (r *Runner) runRetries(p *progress.Progress) {
+	if r.retries == nil {
+		return
+	}
+	for round := 1; round <= r.options.RetryAttempts && !r.truncated.Get(); round++ {
+		queued := r.retries.queue.Len()
+		if queued == 0 {
+			break
+		}
+		atomic.StoreInt32(&r.retries.round, int32(round))
+		gologger.Labelf("Retrying %d templates failing on a target with a network error (attempt %d of %d)\n", queued, round, r.options.RetryAttempts)
+
+		limiter := make(chan struct{}, r.retryConcurrency())
+		var wg sync.WaitGroup
+		err := r.retries.queue.Drain(func(pair retryqueue.Pair) {
+			r.enqueueRetry(p, r.retries.job(pair.Job), pair.Target, limiter, &wg)
+		})
+		wg.Wait()
+		if err != nil {
+			gologger.Warningf("Could not read the templates to retry: %s\n", err)
+		}
+	}
+	if err := r.retries.queue.Close(); err != nil {
+		gologger.Warningf("Could not remove the templates to retry: %s\n", err)
+	}
+
+	r.retries.mutex.Lock()
+	jobs, finishes := r.retries.jobs, r.retries.finishes
+	r.retries.jobs, r.retries.ids, r.retries.finishes = nil, make(map[*scanJob]int), nil
+	r.retries.mutex.Unlock()
+	atomic.StoreInt32(&r.retries.round, 0)
+
+	for _, job := range jobs {
+		if job.finish != nil {
+			job.finish()
+		}
+	}
+	for _, finish := range finishes {
+		finish()
+	}
+}
+
+// enqueueRetry submits the run of a job on a target again to the pool once
+// the limiter of the retries allows it, the results being marked as retried.
+func (r *Runner) enqueueRetry(p *progress.Progress, job *scanJob, target string, limiter chan struct{}, wg *sync.WaitGroup) {
+	if r.truncated.Get() {
+		return
+	}
+	run := job.start(target)
+	if run == nil {
+		return
+	}
+	limiter <- struct{}{}
+	// the requests of the run are sent again
+	if p != nil {
+		p.AddToTotal(job.requests)
+	}
+	wg.Add(1)
+
+	r.pool.Submit(grouping.Key(target), func() {
+		defer wg.Done()
+		r.metrics.runStarted(job.name)
+		ctx, cancel := r.runContext()
+		run(executer.WithRetry(ctx))
+		cancel()
+		r.metrics.runFinished(job.name)
+		<-limiter
+	})
+}
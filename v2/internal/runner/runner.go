@@ -8,29 +8,70 @@ import (
 	"github.com/logrusorgru/aurora"
 	"io"
 	"io/ioutil"
+	"net/http"
 	"net/http/cookiejar"
 	"os"
 	"path/filepath"
 	"regexp"
+	"sort"
 	"strings"
 	"sync"
+	"sync/atomic"
+	"time"
 
 	tengo "github.com/d5/tengo/v2"
 	"github.com/d5/tengo/v2/stdlib"
 	"github.com/karrick/godirwalk"
 	"github.com/projectdiscovery/gologger"
 	"github.com/projectdiscovery/nuclei/v2/internal/progress"
+	"github.com/projectdiscovery/nuclei/v2/pkg/adaptive"
 	"github.com/projectdiscovery/nuclei/v2/pkg/atomicboolean"
+	"github.com/projectdiscovery/nuclei/v2/pkg/auth"
+	"github.com/projectdiscovery/nuclei/v2/pkg/benchmark"
+	"github.com/projectdiscovery/nuclei/v2/pkg/burp"
+	"github.com/projectdiscovery/nuclei/v2/pkg/checkpoint"
+	"github.com/projectdiscovery/nuclei/v2/pkg/collector"
+	"github.com/projectdiscovery/nuclei/v2/pkg/csv"
+	"github.com/projectdiscovery/nuclei/v2/pkg/dedupe"
+	"github.com/projectdiscovery/nuclei/v2/pkg/elasticsearch"
+	"github.com/projectdiscovery/nuclei/v2/pkg/exclusions"
 	"github.com/projectdiscovery/nuclei/v2/pkg/executer"
+	"github.com/projectdiscovery/nuclei/v2/pkg/export"
+	"github.com/projectdiscovery/nuclei/v2/pkg/grouping"
+	"github.com/projectdiscovery/nuclei/v2/pkg/headless"
+	"github.com/projectdiscovery/nuclei/v2/pkg/hosterrors"
+	"github.com/projectdiscovery/nuclei/v2/pkg/inputs"
+	"github.com/projectdiscovery/nuclei/v2/pkg/interactsh"
+	"github.com/projectdiscovery/nuclei/v2/pkg/kafka"
+	"github.com/projectdiscovery/nuclei/v2/pkg/markdown"
+	"github.com/projectdiscovery/nuclei/v2/pkg/project"
+	"github.com/projectdiscovery/nuclei/v2/pkg/ratelimit"
+	"github.com/projectdiscovery/nuclei/v2/pkg/redact"
+	"github.com/projectdiscovery/nuclei/v2/pkg/regexguard"
+	"github.com/projectdiscovery/nuclei/v2/pkg/replay"
+	"github.com/projectdiscovery/nuclei/v2/pkg/reporting"
 	"github.com/projectdiscovery/nuclei/v2/pkg/requests"
+	"github.com/projectdiscovery/nuclei/v2/pkg/sarif"
+	"github.com/projectdiscovery/nuclei/v2/pkg/signature"
+	"github.com/projectdiscovery/nuclei/v2/pkg/stats"
+	"github.com/projectdiscovery/nuclei/v2/pkg/syslog"
 	"github.com/projectdiscovery/nuclei/v2/pkg/templates"
+	"github.com/projectdiscovery/nuclei/v2/pkg/webhook"
 	"github.com/projectdiscovery/nuclei/v2/pkg/workflows"
+	"github.com/projectdiscovery/nuclei/v2/pkg/workpool"
 )
 
 // Runner is a client for running the enumeration process.
 type Runner struct {
 	input      string
 	inputCount int64
+	// expander expands the cidr ranges and the ports of the lines of the
+	// input as they are scanned, inputCount being the number of targets.
+	expander *inputs.Expander
+	// targetStream reads the targets streamed from stdin as they arrive if
+	// any, the input being empty, and streamed tracks their lines.
+	targetStream *inputs.Reader
+	streamed     *streamTracker
 
 	// output is the output file to write if any
 	output      *os.File
@@ -40,11 +81,151 @@ type Runner struct {
 	templatesConfig *nucleiConfig
 	// options contains configuration options for runner
 	options *Options
-	limiter chan struct{}
+	// pool runs the templates on the targets within the concurrency of -c
+	// and -host-concurrency
+	pool *workpool.Pool
 
 	// progress tracking
 	progress *progress.Progress
 
+	// prober probes the scheme of inputs without one
+	prober *prober
+	// liveness checks the hosts of the http targets with -probe-liveness
+	liveness *livenessChecker
+	// sessions are the sessions of the hosts logged in with -auth, nil
+	// otherwise
+	sessions *auth.Store
+	// techDetector detects the technologies of the hosts with -tech-detect,
+	// nil otherwise
+	techDetector *techDetector
+
+	// resolvers is the pool of user supplied dns resolvers if any
+	resolvers *executer.ResolverPool
+	// resolverPools are the pools of the default and the template resolvers of the scan
+	resolverPools *executer.ResolverPools
+	// dnsErrors is the number of dns targets which did not get a response
+	dnsErrors int64
+	// statuses are the numbers of template and target pairs by status
+	statuses statusCounts
+	// exclusions suppress the results of known false positives if any
+	exclusions *exclusions.Exclusions
+
+	// collector collects the extracted values of the scan if any
+	collector *collector.Collector
+	// files are the files the values of the extractors are appended to
+	files *collector.Files
+	// regexGuard limits the length of the responses the regexes are applied to
+	regexGuard *regexguard.Guard
+	// sarif collects the results of the scan into a SARIF log if any
+	sarif *sarif.Exporter
+	// markdown writes the evidence of the results to a markdown report if any
+	markdown *markdown.Exporter
+	// elastic indexes the results in elasticsearch if any
+	elastic *elasticsearch.Exporter
+	// webhook posts the results to a webhook if any
+	webhook *webhook.Exporter
+	// syslog sends the results to a syslog collector if any
+	syslog *syslog.Exporter
+	// kafka publishes the results to a kafka topic if any
+	kafka *kafka.Exporter
+	// csv appends the results to a csv file if any
+	csv *csv.Writer
+	// reporting files the findings in an issue tracker if any
+	reporting *reporting.Exporter
+	// deduper suppresses the findings reported before if any
+	deduper *dedupe.Deduper
+	// exporters send the json results to external services and files, the
+	// ones above.
+	exporters []export.Exporter
+	// stats count the requests, findings and errors of the scan for its
+	// summary, shown once.
+	stats       *stats.Stats
+	summaryOnce sync.Once
+	// benchmark records the timings of the templates and the hosts for the
+	// report of -benchmark if any.
+	benchmark *benchmark.Recorder
+	// redactor redacts the default sensitive headers and the ones of the user
+	redactor *redact.Redactor
+	// grouper buffers the results shown on screen by host if any
+	grouper *grouping.Grouper
+	// hostErrors skips the requests to the hosts with too many consecutive
+	// network errors, nil with -no-host-skip
+	hostErrors *hosterrors.Cache
+	// rateLimiter limits the rate of the requests to each host with
+	// -rate-limit-per-host, nil otherwise
+	rateLimiter *ratelimit.Limiter
+	// adaptive adapts the concurrency and the delays of the requests to the
+	// network errors of the hosts with -adaptive, nil otherwise
+	adaptive *adaptive.Controller
+	// project caches the http responses on disk with -project, nil
+	// otherwise
+	project *project.Cache
+	// replayer replays the requests of the results through a proxy with
+	// -proxy-replay, nil otherwise
+	replayer *replay.Replayer
+	// requestExporter exports the http requests of the results, or of the
+	// dry run, with -export-requests, nil otherwise
+	requestExporter *burp.Exporter
+	// retries are the runs failing with a network error, run again at the
+	// end of the scan, nil with a -retry-attempts of 0
+	retries *retries
+	// metrics serves the pprof profiles and the metrics of the engine with
+	// -metrics, nil otherwise
+	metrics *engineMetrics
+	// stream writes the progress of the scan with -stats, to statsFile if any
+	stream    *stats.Stream
+	statsFile *os.File
+	// checkpoint records the progress of the scan with -resume, written
+	// until checkpointStop is closed. The output files are appended to if
+	// resuming is true, the checkpoint existing.
+	checkpoint        *checkpoint.Checkpoint
+	checkpointStop    chan struct{}
+	checkpointStopped chan struct{}
+	resuming          bool
+	// ctx cancels the runs in flight once the scan stops after the grace
+	// period, the scan stopping after the deadline of -max-scan-duration
+	// if any or by the control api. truncated is true and stopped closed
+	// once it stopped, for stopReason.
+	ctx        context.Context
+	cancel     context.CancelFunc
+	truncated  atomicboolean.AtomBool
+	stopped    chan struct{}
+	stopOnce   sync.Once
+	stopReason string
+	// paused is true while the control api paused the scan, the deadline
+	// of -max-scan-duration being stopped with deadlineLeft to run from
+	// deadlineStart once resumed.
+	pauseMutex    sync.Mutex
+	paused        bool
+	deadline      *time.Timer
+	deadlineLeft  time.Duration
+	deadlineStart time.Time
+	// control serves the control api of -server, nil otherwise
+	control *http.Server
+	// interactsh hands out the urls of {{interactsh-url}} and polls their
+	// interactions, registered once the first template using it is loaded
+	// for a scan, nil otherwise.
+	interactsh     *interactsh.Client
+	interactshOnce sync.Once
+	// browser runs the headless requests in the pages of a chromium browser,
+	// launched once the first template using it is loaded for a scan with
+	// -headless, nil otherwise.
+	browser     *headless.Pool
+	browserOnce sync.Once
+
+	// filter selects the templates to run by their info if any
+	filter *templates.Filter
+	// excludes are the templates excluded from the run by path or id if any
+	excludes *excludeRules
+	// filteredMembers are the excluded or filtered out workflow templates already warned about
+	filteredMembers sync.Map
+
+	// trustedKeys are the public keys verifying the signatures of the templates if any
+	trustedKeys *signature.Keys
+
+	// templateIDs is the index of the paths of the loaded templates and workflows by id
+	templateIDs map[string]string
+
 	// output coloring
 	colorizer   aurora.Aurora
 	decolorizer *regexp.Regexp
@@ -55,13 +236,25 @@ func New(options *Options) (*Runner, error) {
 	runner := &Runner{
 		outputMutex: &sync.Mutex{},
 		options:     options,
+		templateIDs: make(map[string]string),
+		files:       collector.NewFiles(options.ExtractorDir),
+		stopped:     make(chan struct{}),
 	}
+	runner.ctx, runner.cancel = context.WithCancel(context.Background())
 
-	if err := runner.updateTemplates(); err != nil {
-		gologger.Warningf("Could not update templates: %s\n", err)
-	}
-	if (len(options.Templates) == 0 || (options.Targets == "" && !options.Stdin && options.Target == "")) && options.UpdateTemplates {
-		os.Exit(0)
+	// the options of the embedded runners are validated here, not being
+	// parsed from the command line
+	if options.Embedded {
+		if err := options.validateOptions(); err != nil {
+			return nil, err
+		}
+	} else {
+		if err := runner.updateTemplates(); err != nil {
+			gologger.Warningf("Could not update templates: %s\n", err)
+		}
+		if (len(options.Templates) == 0 || (options.Targets == "" && !options.Stdin && options.Target == "")) && options.UpdateTemplates {
+			os.Exit(0)
+		}
 	}
 
 	// output coloring
@@ -76,8 +269,16 @@ func New(options *Options) (*Runner, error) {
 		runner.decolorizer = compiled
 	}
 
+	ports, _ := inputs.ParsePorts(options.Ports)
+	excludedHosts, _ := parseExcludedHosts(options)
+	runner.expander = inputs.NewExpander(ports, excludedHosts, int64(options.CIDRLimit))
+	// Stream the targets of stdin unless they are given otherwise
+	if streamsTargets(options) {
+		runner.targetStream = newTargetStream(options)
+		runner.streamed = &streamTracker{targets: make(map[string][]*streamedRun)}
+	}
 	// If we have stdin, write it to a new file
-	if options.Stdin {
+	if options.Stdin && runner.targetStream == nil {
 		tempInput, err := ioutil.TempFile("", "stdin-input-*")
 		if err != nil {
 			return nil, err
@@ -88,13 +289,19 @@ func New(options *Options) (*Runner, error) {
 		runner.tempFile = tempInput.Name()
 		tempInput.Close()
 	}
-	// If we have single target, write it to a new file
-	if options.Target != "" {
+	// If we have single target, write it to a new file, along with the
+	// targets of the embedding program if any
+	if options.Target != "" || len(options.Inputs) > 0 {
 		tempInput, err := ioutil.TempFile("", "stdin-input-*")
 		if err != nil {
 			return nil, err
 		}
-		fmt.Fprintf(tempInput, "%s\n", options.Target)
+		if options.Target != "" {
+			fmt.Fprintf(tempInput, "%s\n", options.Target)
+		}
+		for _, target := range options.Inputs {
+			fmt.Fprintf(tempInput, "%s\n", target)
+		}
 		runner.tempFile = tempInput.Name()
 		tempInput.Close()
 	}
@@ -104,11 +311,11 @@ func New(options *Options) (*Runner, error) {
 	var input *os.File
 	if options.Targets != "" {
 		input, err = os.Open(options.Targets)
-	} else if options.Stdin || options.Target != "" {
+	} else if (options.Stdin && runner.targetStream == nil) || runner.tempFile != "" {
 		input, err = os.Open(runner.tempFile)
 	}
 	if err != nil {
-		gologger.Fatalf("Could not open targets file '%s': %s\n", options.Targets, err)
+		return nil, fmt.Errorf("could not open targets file '%s': %s", options.Targets, err)
 	}
 
 	// Sanitize input and pre-compute total number of targets
@@ -117,16 +324,23 @@ func New(options *Options) (*Runner, error) {
 	sb := strings.Builder{}
 	scanner := bufio.NewScanner(input)
 	runner.inputCount = 0
+	excluded := &excludedTargets{rules: make(map[string]uint64)}
 	for scanner.Scan() {
-		url := scanner.Text()
+		url := inputs.Trim(scanner.Text())
 		// skip empty lines
 		if len(url) == 0 {
 			continue
 		}
-		// deduplication
-		if _, ok := usedInput[url]; !ok {
-			usedInput[url] = true
-			runner.inputCount++
+		// deduplication on the normalized form, the first target being scanned
+		if key := inputs.Normalize(url); !usedInput[key] {
+			usedInput[key] = true
+			// the cidr ranges and the ports are expanded when scanned
+			count, err := runner.expander.Count(url, excluded.add)
+			if err != nil {
+				input.Close()
+				return nil, fmt.Errorf("could not expand the targets: %s, use -cidr-limit to change the limit", err)
+			}
+			runner.inputCount += count
 			sb.WriteString(url)
 			sb.WriteString("\n")
 		} else {
@@ -139,27 +353,324 @@ func New(options *Options) (*Runner, error) {
 		gologger.Labelf("Supplied input was automatically deduplicated (%d removed).", dupeCount)
 	}
 
+	// the outputs of a resumed scan are appended to the ones of the checkpoint
+	if options.Resume != "" {
+		if _, err := os.Stat(options.Resume); err == nil {
+			runner.resuming = true
+		}
+	}
+
 	// Create the output file if asked
 	if options.Output != "" {
-		output, err := os.Create(options.Output)
+		output, err := runner.createOutput(options.Output)
 		if err != nil {
-			gologger.Fatalf("Could not create output file '%s': %s\n", options.Output, err)
+			return nil, fmt.Errorf("could not create output file '%s': %s", options.Output, err)
 		}
 		runner.output = output
 	}
 
-	if !options.Silent && !options.DisableProgressBar {
-		// Creates the progress tracking object
-		runner.progress = progress.NewProgress(runner.options.NoColor)
+	runner.pool = workpool.New(options.Threads, options.HostConcurrency)
+
+	runner.resolverPools = executer.NewResolverPools()
+	if options.Resolvers != "" {
+		resolvers, err := loadResolvers(options.Resolvers, time.Duration(options.Timeout)*time.Second)
+		if err != nil {
+			return nil, err
+		}
+		runner.resolvers = resolvers
+	}
+
+	// the embedded runners apply the default limit, the option not being
+	// parsed from the command line
+	runner.regexGuard = regexguard.New(regexguard.DefaultMaxSize)
+	if !options.Embedded {
+		runner.regexGuard = regexguard.New(options.RegexMaxSize)
+	}
+
+	if options.Exclusions != "" {
+		exclusions, err := exclusions.Load(options.Exclusions)
+		if err != nil {
+			return nil, err
+		}
+		runner.exclusions = exclusions
+	}
+
+	if options.ExtractorOutput != "" {
+		newCollector := collector.New
+		if runner.resuming {
+			newCollector = collector.Append
+		}
+		collector, err := newCollector(options.ExtractorOutput)
+		if err != nil {
+			return nil, err
+		}
+		runner.collector = collector
+	}
+
+	if options.SarifExport != "" {
+		exporter, err := sarif.New(options.SarifExport, Version)
+		if err != nil {
+			return nil, err
+		}
+		runner.sarif = exporter
+	}
+
+	if options.MarkdownExport != "" {
+		exporter, err := markdown.New(options.MarkdownExport)
+		if err != nil {
+			return nil, err
+		}
+		runner.markdown = exporter
 	}
 
-	runner.limiter = make(chan struct{}, options.Threads)
+	if options.ElasticsearchExport != "" {
+		config, err := elasticsearch.LoadConfig(options.ElasticsearchExport)
+		if err != nil {
+			return nil, err
+		}
+		runner.elastic = elasticsearch.New(config)
+		runner.exporters = append(runner.exporters, runner.elastic)
+	}
+
+	if options.WebhookExport != "" {
+		config, err := webhook.LoadConfig(options.WebhookExport)
+		if err != nil {
+			return nil, err
+		}
+		runner.webhook = webhook.New(config)
+		if options.WebhookCheck {
+			if err := runner.webhook.Check(); err != nil {
+				return nil, err
+			}
+		}
+		runner.exporters = append(runner.exporters, runner.webhook)
+	}
+
+	if options.SyslogExport != "" {
+		exporter, err := syslog.New(options.SyslogExport)
+		if err != nil {
+			return nil, err
+		}
+		runner.syslog = exporter
+		runner.exporters = append(runner.exporters, runner.syslog)
+	}
+
+	if options.KafkaExport != "" {
+		config, err := kafka.LoadConfig(options.KafkaExport)
+		if err != nil {
+			return nil, err
+		}
+		runner.kafka = kafka.New(config)
+		runner.exporters = append(runner.exporters, runner.kafka)
+	}
+
+	if options.CSV != "" {
+		fields, err := csv.ParseFields(options.CSVFields)
+		if err != nil {
+			return nil, err
+		}
+		writer, err := csv.New(options.CSV, fields)
+		if err != nil {
+			return nil, err
+		}
+		runner.csv = writer
+		runner.exporters = append(runner.exporters, runner.csv)
+	}
+
+	if options.ReportConfig != "" {
+		config, err := reporting.LoadConfig(options.ReportConfig, options.ReportDryRun)
+		if err != nil {
+			return nil, err
+		}
+		runner.reporting = reporting.New(config, options.ReportDryRun)
+		runner.exporters = append(runner.exporters, runner.reporting)
+	}
+	runner.exporters = append(runner.exporters, options.Exporters...)
+
+	if options.Dedupe {
+		key, err := dedupe.ParseKey(options.DedupeKey)
+		if err != nil {
+			return nil, err
+		}
+		deduper, err := dedupe.New(key, options.DedupeState)
+		if err != nil {
+			return nil, err
+		}
+		runner.deduper = deduper
+	}
+	runner.redactor = redact.New(redact.ParseHeaders(options.RedactHeaders))
+	if options.GroupByHost {
+		runner.grouper = grouping.New(options.GroupMaxSize, runner.colorizer)
+	}
+	runner.stats = stats.New(runner.inputCount)
+	if options.Benchmark {
+		runner.benchmark = benchmark.New()
+	}
+	for rule, count := range excluded.rules {
+		runner.stats.TargetsExcluded(rule, count)
+	}
+	runner.stats.TargetsDuplicated(uint64(dupeCount))
+	if runner.kafka != nil {
+		runner.stats.AddExporter("kafka", runner.kafka)
+	}
+	if !options.NoHostSkip {
+		runner.hostErrors = hosterrors.New(options.MaxHostError, func(host string, err error) {
+			gologger.Warningf("Skipping %s after %d consecutive network errors: %s\n", host, options.MaxHostError, stats.Reason(err))
+			runner.stats.HostDead(host, err)
+		})
+	}
+	runner.retries = newRetries(options)
+	if options.RateLimitPerHost > 0 {
+		runner.rateLimiter = ratelimit.New(options.RateLimitPerHost, runner.stats.RequestDelayed)
+		runner.stats.SetRateLimitPerHost(int64(options.RateLimitPerHost))
+	}
+	if options.Adaptive {
+		runner.adaptive = newAdaptive(options, runner.stats)
+	}
+	if options.Project {
+		cache, err := project.New(options.ProjectPath, options.ProjectTTL, options.ProjectRewrite)
+		if err != nil {
+			return nil, fmt.Errorf("could not create project directory: %s", err)
+		}
+		runner.project = cache
+	}
+	if options.ProxyReplay != "" {
+		replayer, err := newReplayer(options)
+		if err != nil {
+			return nil, fmt.Errorf("could not create replay proxy: %s", err)
+		}
+		runner.replayer = replayer
+	}
+	if options.ExportRequests != "" {
+		exporter, err := burp.New(burp.Options{Directory: options.ExportRequests, Concatenated: options.ExportRequestsConcat, Markers: options.ExportRequestsMarkers})
+		if err != nil {
+			return nil, fmt.Errorf("could not create export requests directory: %s", err)
+		}
+		runner.requestExporter = exporter
+	}
+	if options.Stats {
+		writer := os.Stderr
+		if options.StatsFile != "" {
+			file, err := runner.createOutput(options.StatsFile)
+			if err != nil {
+				return nil, err
+			}
+			runner.statsFile, writer = file, file
+		}
+		runner.stream = stats.NewStream(runner.stats, writer, time.Duration(options.StatsInterval)*time.Second)
+	}
+	if options.Stats || (!options.Silent && !options.DisableProgressBar) {
+		// Creates the progress tracking object, updating the stats with the
+		// requests, the progress bar being replaced by the stats stream
+		runner.progress = progress.NewStatsProgress(runner.options.NoColor, runner.stats, options.Stats)
+	}
+	if !options.Embedded {
+		go runner.closeExportsOnInterrupt()
+
+		templates.SetStrict(options.StrictFields)
+		templates.SetStrictSyntax(options.Strict)
+		templates.SetEnvironment(options.AllowEnvVars, options.AllowMissingEnvVars)
+	}
+	if options.TrustedKeys != "" {
+		keys, err := signature.LoadPublicKeys(strings.Split(options.TrustedKeys, ",")...)
+		if err != nil {
+			return nil, err
+		}
+		runner.trustedKeys = keys
+	}
+	if !options.Embedded {
+		templates.SetSignature(options.TemplateSignature, runner.trustedKeys)
+	}
+	runner.filter = templates.NewFilter(options.Severity, options.Tags, options.ExcludeTags, options.Author)
+	excludes, err := runner.newExcludeRules(options.ExcludeTemplates, options.ExcludeIDs)
+	if err != nil {
+		return nil, err
+	}
+	runner.excludes = excludes
+
+	if !options.NoProbe {
+		prober, err := newProber(options, runner.rateLimiter)
+		if err != nil {
+			return nil, err
+		}
+		runner.prober = prober
+	}
+	if options.ProbeLiveness {
+		liveness, err := newLivenessChecker(options, runner.rateLimiter, runner.stats)
+		if err != nil {
+			return nil, err
+		}
+		runner.liveness = liveness
+	}
+	sessions, err := runner.newSessions()
+	if err != nil {
+		return nil, err
+	}
+	runner.sessions = sessions
+	techDetector, err := runner.newTechDetector()
+	if err != nil {
+		return nil, err
+	}
+	runner.techDetector = techDetector
+	if options.Metrics {
+		if err := runner.startMetrics(); err != nil {
+			return nil, err
+		}
+	}
+	if options.Server != "" {
+		if err := runner.startControl(); err != nil {
+			return nil, err
+		}
+	}
 
 	return runner, nil
 }
 
+// createOutput creates an output file, opening it in append mode if the
+// scan is resumed.
+func (r *Runner) createOutput(name string) (*os.File, error) {
+	if r.resuming {
+		return os.OpenFile(name, os.O_WRONLY|os.O_CREATE|os.O_APPEND, 0644)
+	}
+	return os.Create(name)
+}
+
+// loadResolvers reads a file of dns resolvers, one per line, and creates a pool from them
+func loadResolvers(file string, timeout time.Duration) (*executer.ResolverPool, error) {
+	f, err := os.Open(file)
+	if err != nil {
+		return nil, fmt.Errorf("could not open resolvers file: %s", err)
+	}
+	defer f.Close()
+
+	var resolvers []string
+	scanner := bufio.NewScanner(f)
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		resolvers = append(resolvers, line)
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, err
+	}
+	return executer.NewResolverPool(resolvers, timeout)
+}
+
 // Close releases all the resources and cleans up
 func (r *Runner) Close() {
+	r.cancel()
+	r.stopMetrics()
+	r.stopControl()
+	r.interactsh.Close(0)
+	r.browser.Close()
+	r.pool.Close()
+	r.replayer.Close()
+	// the runs to retry spilled by an interrupted scan are removed
+	if r.retries != nil {
+		r.retries.queue.Close()
+	}
 	r.output.Close()
 	os.Remove(r.tempFile)
 }
@@ -191,9 +702,9 @@ func isNewPath(path string, pathMap map[string]bool) bool {
 	return true
 }
 
-// RunEnumeration sets up the input layer for giving input nuclei.
-// binary and runs the actual enumeration
-func (r *Runner) RunEnumeration() {
+// templatePaths returns the unique template files of the user input,
+// walking the directories and expanding the wildcards.
+func (r *Runner) templatePaths() []string {
 	// keeps track of processed dirs and files
 	processed := make(map[string]bool)
 	allTemplates := []string{}
@@ -203,6 +714,18 @@ func (r *Runner) RunEnumeration() {
 		var absPath string
 		var err error
 
+		if isRemoteTemplate(t) {
+			if r.options.NoRemoteTemplates {
+				gologger.Errorf("Could not load remote template '%s': remote templates are disabled\n", t)
+				continue
+			}
+			source := t
+			if t, err = r.fetchRemoteTemplate(source); err != nil {
+				gologger.Errorf("Could not fetch remote template '%s': %s\n", source, err)
+				continue
+			}
+		}
+
 		if strings.Contains(t, "*") {
 			dirs := strings.Split(t, "/")
 			priorDir := strings.Join(dirs[:len(dirs)-1], "/")
@@ -289,225 +812,470 @@ func (r *Runner) RunEnumeration() {
 					gologger.Labelf("Error, no templates were found in '%s'.\n", absPath)
 					continue
 				}
+				// the walk is unsorted, sort the templates to skip the same duplicates on each run
+				sort.Strings(matches)
 
 				allTemplates = append(allTemplates, matches...)
 			}
 		}
 	}
 
+	return allTemplates
+}
+
+// ErrNoTemplates is the error of the scans finding none of the templates given
+var ErrNoTemplates = errors.New("no templates were found")
+
+// RunEnumeration sets up the input layer for giving input nuclei.
+// binary and runs the actual enumeration
+func (r *Runner) RunEnumeration() error {
+	allTemplates := r.templatePaths()
+
 	// 0 matches means no templates were found in directory
 	if len(allTemplates) == 0 {
-		gologger.Fatalf("Error, no templates were found.\n")
+		return ErrNoTemplates
 	}
 
 	// progress tracking
 	p := r.progress
 
-	// precompute total request count
-	var totalRequests int64 = 0
-	hasWorkflows := false
-	parsedTemplates := []string{}
-
-	for _, match := range allTemplates {
-		t, err := r.parse(match)
-		switch t.(type) {
-		case *templates.Template:
-			template := t.(*templates.Template)
-			totalRequests += (template.GetHTTPRequestCount() + template.GetDNSRequestCount()) * r.inputCount
-			parsedTemplates = append(parsedTemplates, match)
-		case *workflows.Workflow:
-			// workflows will dynamically adjust the totals while running, as
-			// it can't be know in advance which requests will be called
-			parsedTemplates = append(parsedTemplates, match)
-			hasWorkflows = true
-		default:
-			gologger.Errorf("Could not parse file '%s': %s\n", match, err)
-		}
+	loaded, err := r.loadTemplates(allTemplates)
+	if err != nil {
+		return err
+	}
+	for i, path := range loaded.broken {
+		gologger.Errorf("Could not parse file '%s': %s\n", path, loaded.errors[i])
+		r.stats.TemplateFailed(path, "could not parse: "+loaded.errors[i].Error())
 	}
+	totalRequests := loaded.requests * r.inputCount
+	hasWorkflows := loaded.hasWorkflows
 
 	// ensure only successfully parsed templates are processed
-	allTemplates = parsedTemplates
+	discovered := allTemplates
+	allTemplates = loaded.paths
 	templateCount := len(allTemplates)
+	if r.filter != nil || r.excludes != nil || loaded.filteredCount > 0 {
+		gologger.Labelf("%s\n", loaded.summary(r.filter != nil, r.excludes != nil))
+	}
+	if err := r.openCheckpoint(allTemplates); err != nil {
+		return err
+	}
+	steps := loaded.steps()
+	r.stats.SetTemplates(int64(templateCount), steps)
+	r.expectSteps(steps)
+	if r.stream != nil {
+		r.stream.Start()
+	}
+	if r.sarif != nil {
+		r.sarif.SetTemplates(templateCount)
+		for _, parsed := range loaded.parsed {
+			if t, ok := parsed.(*templates.Template); ok {
+				r.sarif.AddRule(t)
+			}
+		}
+	}
 
-	var (
-		wgtemplates sync.WaitGroup
-		results     atomicboolean.AtomBool
-	)
+	var results atomicboolean.AtomBool
 
-	if r.inputCount == 0 {
-		gologger.Errorf("Could not find any valid input URLs.")
-	} else if totalRequests > 0 || hasWorkflows {
+	if r.inputCount == 0 && r.targetStream == nil {
+		if !r.warnAllExcluded(r.inputCount) {
+			gologger.Errorf("Could not find any valid input URLs.")
+		}
+	} else if loaded.requests > 0 || hasWorkflows {
 
-		// track global progress
-		if p != nil {
+		// track global progress, the results of the streamed targets being
+		// shown as they are found
+		if p != nil && r.targetStream != nil {
+			p.InitStreamProgressbar(templateCount)
+		} else if p != nil {
 			p.InitProgressbar(r.inputCount, templateCount, totalRequests)
 			p.StartStdCapture()
 		}
 
-		for _, match := range allTemplates {
-			wgtemplates.Add(1)
-			go func(match string) {
-				defer wgtemplates.Done()
-				t, err := r.parse(match)
-				switch t.(type) {
-				case *templates.Template:
-					template := t.(*templates.Template)
-					for _, request := range template.RequestsDNS {
-						results.Or(r.processTemplateWithList(p, template, request))
-					}
-					for _, request := range template.BulkRequestsHTTP {
-						results.Or(r.processTemplateWithList(p, template, request))
-					}
-				case *workflows.Workflow:
-					workflow := t.(*workflows.Workflow)
-					r.ProcessWorkflowWithList(p, workflow)
-				default:
-					gologger.Errorf("Could not parse file '%s': %s\n", match, err)
-				}
-			}(match)
+		r.startDeadline()
+		clusters, remaining := r.clusterTemplates(loaded.parsed)
+		if len(clusters) > 0 {
+			clustered := 0
+			for _, cluster := range clusters {
+				clustered += len(cluster)
+			}
+			gologger.Labelf("Clustered %d templates sending the same request into %d requests\n", clustered, len(clusters))
 		}
-
-		wgtemplates.Wait()
+		// the streamed targets are run in turn as they arrive
+		if r.options.ScanStrategy == hostSpray || r.targetStream != nil {
+			parsed := make([]interface{}, 0, len(remaining))
+			for _, index := range remaining {
+				parsed = append(parsed, loaded.parsed[index])
+			}
+			results.Or(r.sprayHosts(p, clusters, parsed))
+		} else {
+			paths := make([]string, 0, len(remaining))
+			for _, index := range remaining {
+				paths = append(paths, allTemplates[index])
+			}
+			results.Or(r.sprayTemplates(p, clusters, paths))
+		}
+		r.stopDeadline()
+		r.grouper.Flush()
 
 		if p != nil {
+			// the requests of the runs not enqueued are never sent
+			if r.truncated.Get() {
+				p.Stop()
+			}
 			p.Wait()
 
-			p.StopStdCapture()
-			p.ShowStdErr()
-			p.ShowStdOut()
+			if r.targetStream == nil {
+				p.StopStdCapture()
+				p.ShowStdErr()
+				p.ShowStdOut()
+			}
 		}
 	}
+	r.closeCheckpoint()
 
-	if !results.Get() {
-		if r.output != nil {
-			outputFile := r.output.Name()
-			r.output.Close()
-			os.Remove(outputFile)
+	if r.targetStream != nil {
+		if r.targetStream.Targets() == 0 {
+			gologger.Errorf("Could not find any valid input URLs.")
+		} else {
+			r.warnAllExcluded(r.stats.Snapshot().HostsTotal)
+		}
+	}
+
+	if r.prober != nil {
+		if failed := r.prober.failedCount(); failed > 0 {
+			gologger.Labelf("Skipped %d hosts that did not respond to http/https probes\n", failed)
+		}
+	}
+	if errored := atomic.LoadInt64(&r.dnsErrors); errored > 0 {
+		gologger.Labelf("Could not get a dns response for %d targets, use -v to show the errors\n", errored)
+	}
+	if oversized := r.regexGuard.Oversized(); oversized > 0 {
+		gologger.Labelf("Applied regexes to the first %d bytes of %d larger responses, use -regex-max-size to change the limit\n", r.regexGuard.MaxSize(), oversized)
+	}
+	if r.exclusions != nil {
+		if suppressed := r.exclusions.Suppressed(); suppressed > 0 && r.options.ShowSuppressed {
+			gologger.Labelf("Suppressed %d findings matching the exclusions\n", suppressed)
+		} else if suppressed > 0 {
+			gologger.Labelf("Suppressed %d findings matching the exclusions, use -show-suppressed to show them\n", suppressed)
+		}
+	}
+	r.logStatuses()
+	if r.deduper != nil {
+		hint := ""
+		if !r.options.ShowDuplicates {
+			hint = ", use -show-duplicates to write them to the json output"
+		}
+		if duplicates := r.deduper.Duplicates(); duplicates > 0 {
+			gologger.Labelf("Suppressed %d duplicate findings%s\n", duplicates, hint)
+		}
+		if known := r.deduper.Known(); known > 0 {
+			gologger.Labelf("Suppressed %d findings known from %s%s\n", known, r.deduper.State(), hint)
+		}
+	}
+	// the changed templates are run until interrupted, appending their results
+	if r.options.Watch && !r.truncated.Get() {
+		r.watch(discovered)
+	}
+
+	// the late interactions are matched before the outputs are closed
+	r.closeInteractsh()
+	results.Or(r.interactsh != nil && r.stats.Snapshot().Matched > 0)
+	// the replayed requests are sent before the summary counts them
+	r.replayer.Close()
+	r.closeOutputs()
+	r.stopStream()
+	r.logSummary(false)
+	r.finishResults(results.Get())
+	return nil
+}
+
+// closeOutputs writes the extracted values, the exports and the markdown
+// report of the results once the templates ran.
+func (r *Runner) closeOutputs() {
+	r.files.Close()
+	if r.collector != nil {
+		if err := r.collector.Close(); err != nil {
+			gologger.Warningf("Could not write extracted values to %s: %s\n", r.collector.Name(), err)
+		} else {
+			gologger.Labelf("Wrote %d unique extracted values to %s\n", r.collector.Count(), r.collector.Name())
 		}
-		gologger.Infof("No results found. Happy hacking!")
 	}
-	return
+	r.closeExports(true)
+	if r.markdown != nil {
+		gologger.Labelf("Wrote %d findings to the markdown report %s\n", r.markdown.Count(), r.markdown.Name())
+	}
+}
+
+// finishResults removes the empty output file if there were no results,
+// the output of a resumed scan having the results of the previous runs.
+func (r *Runner) finishResults(results bool) {
+	if results {
+		return
+	}
+	if r.output != nil && !r.resuming {
+		outputFile := r.output.Name()
+		r.output.Close()
+		os.Remove(outputFile)
+	}
+	gologger.Infof("No results found. Happy hacking!")
 }
 
-// processTemplateWithList processes a template and runs the enumeration on all the targets
-func (r *Runner) processTemplateWithList(p *progress.Progress, template *templates.Template, request interface{}) bool {
-	// Display the message for the template
-	message := fmt.Sprintf("[%s] Loaded template %s (@%s)", template.ID, template.Info.Name, template.Info.Author)
-	if template.Info.Severity != "" {
-		message += " [" + template.Info.Severity + "]"
+// executeParsed executes a parsed template or workflow towards the targets,
+// returning true if it got results. The template is finished, calling done
+// if not nil, once its runs retried at the end of the scan if any completed.
+func (r *Runner) executeParsed(p *progress.Progress, t interface{}, done func()) bool {
+	jobs, finish := r.newParsedJobs(p, t)
+	var results bool
+	for _, job := range jobs {
+		results = r.runJob(job) || results
 	}
-	gologger.Infof("%s\n", message)
+	r.finishTemplate(jobs, func() {
+		finish()
+		if done != nil {
+			done()
+		}
+	})
+	return results
+}
+
+// newRequestJob creates the job of a request block of a template, adding
+// the results to the matcher statuses if any. The targets the step
+// completed on before the interruption of a resumed scan are skipped. nil
+// is returned if its executer could not be created.
+func (r *Runner) newRequestJob(p *progress.Progress, template *templates.Template, request interface{}, step string, statuses *matcherStatuses) *scanJob {
+	logLoadedTemplate(template)
+	r.logEffectiveSettings(template, request)
 
 	var writer *bufio.Writer
 	if r.output != nil {
 		writer = bufio.NewWriter(r.output)
-		defer writer.Flush()
 	}
 
 	var httpExecuter *executer.HTTPExecuter
 	var dnsExecuter *executer.DNSExecuter
+	var headlessExecuter *executer.HeadlessExecuter
+	var networkExecuter *executer.NetworkExecuter
+	var fileExecuter *executer.FileExecuter
+	var websocketExecuter *executer.WebsocketExecuter
+	var sslExecuter *executer.SSLExecuter
+	var requestCount int64
 	var err error
 
 	// Create an executer based on the request type.
 	switch value := request.(type) {
 	case *requests.DNSRequest:
-		dnsExecuter = executer.NewDNSExecuter(&executer.DNSOptions{
-			Debug:      r.options.Debug,
-			Template:   template,
-			DNSRequest: value,
-			Writer:     writer,
-			JSON:       r.options.JSON,
-			ColoredOutput:   !r.options.NoColor,
-			Colorizer:       r.colorizer,
-			Decolorizer:     r.decolorizer,
-		})
+		requestCount = value.GetRequestCount()
+		dnsExecuter, err = r.newDNSExecuter(template, value, writer, false)
 	case *requests.BulkHTTPRequest:
-		httpExecuter, err = executer.NewHTTPExecuter(&executer.HTTPOptions{
-			Debug:           r.options.Debug,
-			Template:        template,
-			BulkHttpRequest: value,
-			Writer:          writer,
-			Timeout:         r.options.Timeout,
-			Retries:         r.options.Retries,
-			ProxyURL:        r.options.ProxyURL,
-			ProxySocksURL:   r.options.ProxySocksURL,
-			CustomHeaders:   r.options.CustomHeaders,
-			JSON:            r.options.JSON,
-			JSONRequests:    r.options.JSONRequests,
-			CookieReuse:     value.CookieReuse,
-			ColoredOutput:   !r.options.NoColor,
-			Colorizer:       r.colorizer,
-			Decolorizer:     r.decolorizer,
-		})
+		requestCount = value.GetRequestCount()
+		httpExecuter, err = r.newHTTPExecuter(template, value, writer, nil, step)
+	case *requests.HeadlessRequest:
+		requestCount = value.GetRequestCount()
+		headlessExecuter, err = r.newHeadlessExecuter(template, value, writer)
+	case *requests.NetworkRequest:
+		requestCount = value.GetRequestCount()
+		networkExecuter, err = r.newNetworkExecuter(template, value, writer)
+	case *requests.FileRequest:
+		requestCount = value.GetRequestCount()
+		fileExecuter, err = r.newFileExecuter(template, value, writer)
+	case *requests.WebsocketRequest:
+		requestCount = value.GetRequestCount()
+		websocketExecuter, err = r.newWebsocketExecuter(template, value, writer)
+	case *requests.SSLRequest:
+		requestCount = value.GetRequestCount()
+		sslExecuter, err = r.newSSLExecuter(template, value, writer)
 	}
 	if err != nil {
 		if p != nil {
-			p.Drop(request.(*requests.BulkHTTPRequest).GetRequestCount())
+			p.Drop(requestCount * r.inputCount)
 		}
-		gologger.Warningf("Could not create http client: %s\n", err)
-		return false
+		gologger.Warningf("Could not create executer for template '%s': %s\n", template.ID, err)
+		r.stats.TemplateFailed(template.ID, "could not create executer: "+err.Error())
+		r.completeSteps(step)
+		return nil
 	}
 
-	var globalresult atomicboolean.AtomBool
-
-	var wg sync.WaitGroup
-
-	scanner := bufio.NewScanner(strings.NewReader(r.input))
-	for scanner.Scan() {
-		text := scanner.Text()
-
-		r.limiter <- struct{}{}
-		wg.Add(1)
-
-		go func(URL string) {
-			defer wg.Done()
+	failures := &templateErrors{}
+	// the targets of the template are limited by its own threads too
+	job := newScanJob(step, r.effectiveThreads(template))
+	job.requests = requestCount
+	job.start = func(URL string) func(ctx context.Context) {
+		if r.skipStep(step, URL) {
+			if p != nil {
+				p.Drop(requestCount)
+			}
+			return nil
+		}
+		return func(ctx context.Context) {
 			var result executer.Result
 
+			// the template is skipped on the http targets whose host runs
+			// none of its technologies with -tech-detect
+			if httpExecuter != nil || headlessExecuter != nil {
+				if httpURL, err := r.resolveHTTPInput(URL); err == nil && r.skipTech(template, httpURL) {
+					if p != nil {
+						p.Drop(requestCount)
+					}
+					r.completeStep(step, URL)
+					return
+				}
+			}
 			if httpExecuter != nil {
-				result = httpExecuter.ExecuteHTTP(p, URL)
-				globalresult.Or(result.GotResults)
+				if httpURL, err := r.resolveHTTPInput(URL); err == nil {
+					result = httpExecuter.ExecuteHTTPWithContext(ctx, p, httpURL, nil)
+					job.results.Or(result.GotResults)
+				} else {
+					if p != nil {
+						p.Drop(requestCount)
+					}
+					result.Error = err
+				}
+			}
+			if headlessExecuter != nil {
+				if headlessURL, err := r.resolveHTTPInput(URL); err == nil {
+					result = headlessExecuter.ExecuteHeadlessWithContext(ctx, p, headlessURL, nil)
+					job.results.Or(result.GotResults)
+				} else {
+					if p != nil {
+						p.Drop(requestCount)
+					}
+					result.Error = err
+				}
+			}
+			if websocketExecuter != nil {
+				if websocketURL, err := r.resolveHTTPInput(URL); err == nil {
+					result = websocketExecuter.ExecuteWebsocketWithContext(ctx, p, websocketURL, nil)
+					job.results.Or(result.GotResults)
+				} else {
+					if p != nil {
+						p.Drop(requestCount)
+					}
+					result.Error = err
+				}
 			}
 			if dnsExecuter != nil {
-				result = dnsExecuter.ExecuteDNS(p, URL)
-				globalresult.Or(result.GotResults)
+				result = dnsExecuter.ExecuteDNSWithContext(ctx, p, URL, nil)
+				job.results.Or(result.GotResults)
+			}
+			if networkExecuter != nil {
+				result = networkExecuter.ExecuteNetworkWithContext(ctx, p, URL, nil)
+				job.results.Or(result.GotResults)
+			}
+			if sslExecuter != nil {
+				result = sslExecuter.ExecuteSSLWithContext(ctx, p, URL, nil)
+				job.results.Or(result.GotResults)
+			}
+			if fileExecuter != nil {
+				result = fileExecuter.ExecuteFileWithContext(ctx, p, URL, nil)
+				job.results.Or(result.GotResults)
 			}
-			if result.Error != nil {
+			if r.abandoned(ctx, template.ID, URL, &result) {
+				return
+			}
+			if result.Error != nil && !skipped(result.Error) {
 				gologger.Warningf("Could not execute step: %s\n", result.Error)
 			}
-			<-r.limiter
-		}(text)
+			r.recordError(URL, result.Error)
+			// the step completes once the run is retried
+			if r.retry(job, URL, result.Error) {
+				return
+			}
+			if dnsExecuter != nil && result.Error != nil && !skipped(result.Error) {
+				atomic.AddInt64(&r.dnsErrors, 1)
+			}
+			failures.add(result.Error)
+			statuses.add(URL, &result)
+			r.completeStep(step, URL)
+		}
 	}
+	job.finish = func() {
+		r.recordTemplateErrors(template.ID, failures)
+		if writer != nil {
+			writer.Flush()
+		}
+	}
+	return job
+}
 
-	wg.Wait()
-
-	// See if we got any results from the executers
-	return globalresult.Get()
+// resolveHTTPInput returns the URL to use for http requests towards an
+// input, the error being errNotProbed if it did not respond to the http
+// probes or errNotAlive if it failed the liveness preflight. The inputs
+// responding to the probes are alive.
+func (r *Runner) resolveHTTPInput(input string) (string, error) {
+	if r.prober != nil && !hasScheme(input) {
+		URL, ok := r.prober.resolve(input)
+		if !ok {
+			return "", errNotProbed
+		}
+		r.establishSession(URL)
+		return URL, nil
+	}
+	if !r.liveness.alive(input) {
+		return "", errNotAlive
+	}
+	r.establishSession(input)
+	return input, nil
 }
 
 // ProcessWorkflowWithList coming from stdin or list of targets
-func (r *Runner) ProcessWorkflowWithList(p *progress.Progress, workflow *workflows.Workflow) {
-	var wg sync.WaitGroup
-	scanner := bufio.NewScanner(strings.NewReader(r.input))
-	for scanner.Scan() {
-		text := scanner.Text()
-		r.limiter <- struct{}{}
-		wg.Add(1)
-
-		go func(URL string) {
-			defer wg.Done()
+func (r *Runner) ProcessWorkflowWithList(p *progress.Progress, workflow *workflows.Workflow) bool {
+	return r.runJob(r.newWorkflowJob(p, workflow))
+}
 
-			if err := r.ProcessWorkflow(p, workflow, text); err != nil {
-				gologger.Warningf("Could not run workflow for %s: %s\n", text, err)
+// newWorkflowJob creates the job of a workflow, which is only limited by
+// the global concurrency.
+func (r *Runner) newWorkflowJob(p *progress.Progress, workflow *workflows.Workflow) *scanJob {
+	job := newScanJob(workflow.ID, 0)
+	job.start = func(URL string) func(ctx context.Context) {
+		if r.skipStep(workflow.ID, URL) {
+			return nil
+		}
+		return func(ctx context.Context) {
+			// use the probed URL if any, dns requests work with both inputs
+			input := URL
+			httpURL, err := r.resolveHTTPInput(URL)
+			if err == nil {
+				input = httpURL
+			} else if err == errNotAlive {
+				// the workflow is skipped on the targets failing the
+				// preflight, as are its http requests
+				r.completeStep(workflow.ID, URL)
+				return
+			}
+			gotResults, err := r.ProcessWorkflow(ctx, p, workflow, input)
+			job.results.Or(gotResults)
+			// the errors of the templates of the workflow are not returned
+			result := executer.Result{Error: ctx.Err()}
+			if r.abandoned(ctx, workflow.ID, URL, &result) {
+				return
 			}
-			<-r.limiter
-		}(text)
+			if err != nil && result.Error == nil {
+				gologger.Warningf("Could not run workflow for %s: %s\n", input, err)
+				r.stats.TemplateFailed(workflow.ID, err.Error())
+			}
+			// the step is the one of the input, before resolving it
+			r.completeStep(workflow.ID, URL)
+		}
 	}
-
-	wg.Wait()
+	return job
 }
 
-// ProcessWorkflow towards an URL
-func (r *Runner) ProcessWorkflow(p *progress.Progress, workflow *workflows.Workflow, URL string) error {
+// ProcessWorkflow towards an URL until the context is done, returning true
+// if any template got results
+func (r *Runner) ProcessWorkflow(ctx context.Context, p *progress.Progress, workflow *workflows.Workflow, URL string) (bool, error) {
+	if len(workflow.Workflows) > 0 {
+		run := &workflowRun{ctx: ctx, workflow: workflow, URL: URL}
+		if workflow.CookieReuse {
+			jar, err := cookiejar.New(nil)
+			if err != nil {
+				return false, err
+			}
+			run.jar = jar
+		}
+		return r.processWorkflowTemplates(p, run, workflow.Workflows, nil, nil), nil
+	}
+
 	script := tengo.NewScript([]byte(workflow.Logic))
 	script.SetImports(stdlib.GetModuleMap(stdlib.AllModuleNames()...))
 	var jar *cookiejar.Jar
@@ -515,9 +1283,10 @@ func (r *Runner) ProcessWorkflow(p *progress.Progress, workflow *workflows.Workf
 		var err error
 		jar, err = cookiejar.New(nil)
 		if err != nil {
-			return err
+			return false, err
 		}
 	}
+	var variables []*workflows.NucleiVar
 	for name, value := range workflow.Variables {
 		var writer *bufio.Writer
 		if r.output != nil {
@@ -525,53 +1294,19 @@ func (r *Runner) ProcessWorkflow(p *progress.Progress, workflow *workflows.Workf
 			defer writer.Flush()
 		}
 
-		// Check if the template is an absolute path or relative path.
-		// If the path is absolute, use it. Otherwise,
-		if r.isRelative(value) {
-			newPath, err := r.resolvePath(value)
-			if err != nil {
-				newPath, err = r.resolvePathWithBaseFolder(filepath.Dir(workflow.GetPath()), value)
-				if err != nil {
-					return err
-				}
-			}
-			value = newPath
+		value, err := r.resolveWorkflowPath(workflow, value)
+		if err != nil {
+			return false, err
 		}
 
 		// Single yaml provided
 		var templatesList []*workflows.Template
 		if strings.HasSuffix(value, ".yaml") {
-			t, err := templates.Parse(value)
+			template, err := r.newWorkflowTemplate(p, workflow, value, writer, jar)
 			if err != nil {
-				return err
-			}
-			template := &workflows.Template{Progress: p}
-			if len(t.BulkRequestsHTTP) > 0 {
-				template.HTTPOptions = &executer.HTTPOptions{
-					Debug:         r.options.Debug,
-					Writer:        writer,
-					Template:      t,
-					Timeout:       r.options.Timeout,
-					Retries:       r.options.Retries,
-					ProxyURL:      r.options.ProxyURL,
-					ProxySocksURL: r.options.ProxySocksURL,
-					CustomHeaders: r.options.CustomHeaders,
-					CookieJar:     jar,
-					ColoredOutput:   !r.options.NoColor,
-					Colorizer:       r.colorizer,
-					Decolorizer:     r.decolorizer,
-				}
-			} else if len(t.RequestsDNS) > 0 {
-				template.DNSOptions = &executer.DNSOptions{
-					Debug:    r.options.Debug,
-					Template: t,
-					Writer:   writer,
-					ColoredOutput:   !r.options.NoColor,
-					Colorizer:       r.colorizer,
-					Decolorizer:     r.decolorizer,
-				}
+				return false, err
 			}
-			if template.DNSOptions != nil || template.HTTPOptions != nil {
+			if template != nil {
 				templatesList = append(templatesList, template)
 			}
 		} else {
@@ -590,65 +1325,103 @@ func (r *Runner) ProcessWorkflow(p *progress.Progress, workflow *workflows.Workf
 				Unsorted: true,
 			})
 			if err != nil {
-				return err
+				return false, err
 			}
 			// 0 matches means no templates were found in directory
 			if len(matches) == 0 {
-				return errors.New("no match found in the directory")
+				return false, errors.New("no match found in the directory")
 			}
 
 			for _, match := range matches {
-				t, err := templates.Parse(match)
+				template, err := r.newWorkflowTemplate(p, workflow, match, writer, jar)
 				if err != nil {
-					return err
+					return false, err
 				}
-				template := &workflows.Template{Progress: p}
-				if len(t.BulkRequestsHTTP) > 0 {
-					template.HTTPOptions = &executer.HTTPOptions{
-						Debug:         r.options.Debug,
-						Writer:        writer,
-						Template:      t,
-						Timeout:       r.options.Timeout,
-						Retries:       r.options.Retries,
-						ProxyURL:      r.options.ProxyURL,
-						ProxySocksURL: r.options.ProxySocksURL,
-						CustomHeaders: r.options.CustomHeaders,
-						CookieJar:     jar,
-					}
-				} else if len(t.RequestsDNS) > 0 {
-					template.DNSOptions = &executer.DNSOptions{
-						Debug:    r.options.Debug,
-						Template: t,
-						Writer:   writer,
-					}
-				}
-				if template.DNSOptions != nil || template.HTTPOptions != nil {
+				if template != nil {
 					templatesList = append(templatesList, template)
 				}
 			}
 		}
 
-		script.Add(name, &workflows.NucleiVar{Templates: templatesList, URL: URL})
+		variable := &workflows.NucleiVar{Context: ctx, Templates: templatesList, URL: URL}
+		variables = append(variables, variable)
+		script.Add(name, variable)
 	}
 
-	_, err := script.RunContext(context.Background())
+	_, err := script.RunContext(ctx)
+	// the results before the context is done are kept
+	var gotResults bool
+	for _, variable := range variables {
+		gotResults = gotResults || variable.GotResults.Get()
+	}
 	if err != nil {
-		gologger.Errorf("Could not execute workflow '%s': %s\n", workflow.ID, err)
-		return err
+		if ctx.Err() == nil {
+			gologger.Errorf("Could not execute workflow '%s': %s\n", workflow.ID, err)
+		}
+		return gotResults, err
 	}
-	return nil
+	return gotResults, nil
+}
+
+// newWorkflowTemplate parses a template of the logic of a workflow, its
+// executers being created as the ones of the other templates. Nil is
+// returned for the templates without http or dns requests and the
+// excluded ones.
+func (r *Runner) newWorkflowTemplate(p *progress.Progress, workflow *workflows.Workflow, path string, writer *bufio.Writer, jar *cookiejar.Jar) (*workflows.Template, error) {
+	t, err := templates.Parse(path)
+	if err != nil {
+		return nil, err
+	}
+	t.SetRegexGuard(r.regexGuard)
+	template := &workflows.Template{Template: t, Progress: p}
+	if len(t.BulkRequestsHTTP) > 0 {
+		template.NewHTTPExecuter = func(request *requests.BulkHTTPRequest) (*executer.HTTPExecuter, error) {
+			return r.newHTTPExecuter(t, request, writer, jar, "")
+		}
+	} else if len(t.RequestsDNS) > 0 {
+		template.NewDNSExecuter = func(request *requests.DNSRequest) (*executer.DNSExecuter, error) {
+			return r.newDNSExecuter(t, request, writer, false)
+		}
+	} else {
+		return nil, nil
+	}
+	if r.filteredMember(workflow, path, t) {
+		return nil, nil
+	}
+	return template, nil
+}
+
+// resolveWorkflowPath resolves the path of a template of a workflow, the
+// relative paths being looked up in the current and templates directories
+// before the directory of the workflow.
+func (r *Runner) resolveWorkflowPath(workflow *workflows.Workflow, value string) (string, error) {
+	if !r.isRelative(value) {
+		return value, nil
+	}
+	path, err := r.resolvePath(value)
+	if err != nil {
+		return r.resolvePathWithBaseFolder(filepath.Dir(workflow.GetPath()), value)
+	}
+	return path, nil
 }
 
 func (r *Runner) parse(file string) (interface{}, error) {
 	// check if it's a template
 	template, errTemplate := templates.Parse(file)
 	if errTemplate == nil {
+		template.SetRegexGuard(r.regexGuard)
 		return template, nil
 	}
 
 	// check if it's a workflow
 	workflow, errWorkflow := workflows.Parse(file)
 	if errWorkflow == nil {
+		if err := workflow.Load(func(path string) (string, error) {
+			return r.resolveWorkflowPath(workflow, path)
+		}); err != nil {
+			return nil, err
+		}
+		workflow.SetRegexGuard(r.regexGuard)
 		return workflow, nil
 	}
 
@@ -0,0 +1,55 @@
+package runner
+
+import (
+	"crypto/ed25519"
+	"fmt"
+	"io/ioutil"
+	"os"
+
+	"github.com/projectdiscovery/gologger"
+	"github.com/projectdiscovery/nuclei/v2/pkg/signature"
+)
+
+// SignTemplates signs in place the templates and workflows of the user input
+// with the private key, and returns false if any file could not be signed.
+func (r *Runner) SignTemplates() (bool, error) {
+	key, err := signature.LoadPrivateKey(r.options.SignTemplates)
+	if err != nil {
+		return false, fmt.Errorf("could not load signing key: %s", err)
+	}
+	paths := r.templatePaths()
+	if len(paths) == 0 {
+		return false, ErrNoTemplates
+	}
+
+	var failed int
+	for _, path := range paths {
+		if err := signFile(path, key); err != nil {
+			gologger.Errorf("Could not sign template '%s': %s\n", path, err)
+			failed++
+			continue
+		}
+		gologger.Debugf("Signed template %s\n", path)
+	}
+
+	keyID := signature.KeyID(key.Public().(ed25519.PublicKey))
+	if failed > 0 {
+		gologger.Labelf("Signed %d of %d templates with key %s\n", len(paths)-failed, len(paths), keyID)
+		return false, nil
+	}
+	gologger.Labelf("Signed %d templates with key %s\n", len(paths), keyID)
+	return true, nil
+}
+
+// signFile replaces a file with its signed content, keeping its permissions
+func signFile(path string, key ed25519.PrivateKey) error {
+	info, err := os.Stat(path)
+	if err != nil {
+		return err
+	}
+	data, err := ioutil.ReadFile(path)
+	if err != nil {
+		return err
+	}
+	return ioutil.WriteFile(path, signature.Sign(data, key), info.Mode())
+}
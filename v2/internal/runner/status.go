@@ -0,0 +1,122 @@
+package runner
+
+import (
+	"bufio"
+	"errors"
+	"sync"
+	"sync/atomic"
+
+	"github.com/projectdiscovery/gologger"
+	"github.com/projectdiscovery/nuclei/v2/pkg/executer"
+	"github.com/projectdiscovery/nuclei/v2/pkg/hosterrors"
+	"github.com/projectdiscovery/nuclei/v2/pkg/templates"
+)
+
+// errNotProbed is the error of the targets skipped as they did not respond
+// to the http probes, which are gaps of the coverage.
+var errNotProbed = errors.New("skipped, the target did not respond to the http probes")
+
+// errNotAlive is the error of the http targets skipped as their host and
+// port failed the liveness preflight of -probe-liveness.
+var errNotAlive = errors.New("skipped, the target failed the liveness preflight")
+
+// skipped returns true if the error is the one of a target skipped, as it
+// did not respond to the http probes or to the liveness preflight or has
+// too many network errors, or abandoned after the template timeout or as
+// the scan stopped.
+func skipped(err error) bool {
+	return err == errNotProbed || err == errNotAlive || err == hosterrors.ErrSkipped || err == errTemplateTimeout || err == errScanStopped
+}
+
+// statusCounts are the numbers of template and target pairs by status
+type statusCounts struct {
+	matched    int64
+	notMatched int64
+	errored    int64
+}
+
+// matcherStatuses are the merged results of the request blocks of a
+// template for each target, recorded with -matcher-status.
+type matcherStatuses struct {
+	mutex   sync.Mutex
+	results map[string]*executer.Result
+	targets []string
+}
+
+// newMatcherStatuses returns the statuses of a template if -matcher-status
+// is used, nil otherwise.
+func (r *Runner) newMatcherStatuses() *matcherStatuses {
+	if !r.options.MatcherStatus {
+		return nil
+	}
+	return &matcherStatuses{results: make(map[string]*executer.Result)}
+}
+
+// add merges the result of a request block for a target, keeping the
+// first error.
+func (s *matcherStatuses) add(target string, result *executer.Result) {
+	if s == nil {
+		return
+	}
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	merged, ok := s.results[target]
+	if !ok {
+		merged = &executer.Result{}
+		s.results[target] = merged
+		s.targets = append(s.targets, target)
+	}
+	merged.GotResults = merged.GotResults || result.GotResults
+	keepError(merged, result)
+}
+
+// keepError sets the error of a request result on a merged result without one
+func keepError(merged, result *executer.Result) {
+	if merged.Error == nil && result.Error != nil {
+		merged.Error = result.Error
+		merged.ErrorRequest = result.ErrorRequest
+	}
+}
+
+// writeStatuses writes the status records of a template for its targets
+// to the json output, counting them. Without json output, the templates
+// not matching are only shown in verbose mode.
+func (r *Runner) writeStatuses(template *templates.Template, statuses *matcherStatuses) {
+	if statuses == nil {
+		return
+	}
+	var writer *bufio.Writer
+	if r.output != nil {
+		writer = bufio.NewWriter(r.output)
+	}
+	for _, target := range statuses.targets {
+		output := executer.NewStatusOutput(template, target, statuses.results[target])
+		switch output.Status {
+		case executer.StatusMatched:
+			atomic.AddInt64(&r.statuses.matched, 1)
+		case executer.StatusNotMatched:
+			atomic.AddInt64(&r.statuses.notMatched, 1)
+		case executer.StatusErrored:
+			atomic.AddInt64(&r.statuses.errored, 1)
+		}
+
+		if r.options.JSON {
+			executer.WriteStatus(writer, output)
+			continue
+		}
+		switch output.Status {
+		case executer.StatusNotMatched:
+			gologger.Verbosef("[%s] %s %s\n", "matcher-status", template.ID, output.Status, target)
+		case executer.StatusErrored:
+			gologger.Verbosef("[%s] %s %s: %s\n", "matcher-status", template.ID, output.Status, target, output.Error)
+		}
+	}
+}
+
+// logStatuses shows the numbers of template and target pairs by status
+func (r *Runner) logStatuses() {
+	if !r.options.MatcherStatus {
+		return
+	}
+	gologger.Labelf("Matcher status: %d matched, %d not-matched, %d errored\n", atomic.LoadInt64(&r.statuses.matched), atomic.LoadInt64(&r.statuses.notMatched), atomic.LoadInt64(&r.statuses.errored))
+}
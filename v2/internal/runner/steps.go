@@ -0,0 +1,145 @@
+package runner
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/projectdiscovery/gologger"
+	"github.com/projectdiscovery/nuclei/v2/pkg/checkpoint"
+)
+
+// checkpointInterval is the interval between the writes of the checkpoint
+// of -resume while the scan runs.
+const checkpointInterval = 10 * time.Second
+
+// requestStep returns the step of a request block of a single protocol
+// template, the multi protocol templates and the workflows being a single
+// step identified by their id.
+func requestStep(id, protocol string, index int) string {
+	return fmt.Sprintf("%s:%s:%d", id, protocol, index)
+}
+
+// expectSteps sets the number of runs of the templates on each target to
+// complete before showing its results if grouping them by host.
+func (r *Runner) expectSteps(steps int64) {
+	// the streamed targets are expected as they arrive
+	if r.streamed != nil {
+		r.streamed.steps = steps
+		return
+	}
+	if r.grouper == nil {
+		return
+	}
+	r.eachInput(func(target string) {
+		r.grouper.Expect(target, steps)
+	})
+}
+
+// completeStep counts a run of a template on a target as completed, in the
+// stats, for the results grouped by host and in the checkpoint if any.
+func (r *Runner) completeStep(step, target string) {
+	r.stats.StepCompleted(target)
+	r.grouper.Complete(target)
+	r.checkpoint.Complete(step, target)
+	if r.streamed != nil {
+		r.completeStreamedStep(target)
+	}
+}
+
+// completeSteps counts a run of a template which could not be executed on
+// each target as completed, the streamed targets once they arrive.
+func (r *Runner) completeSteps(step string) {
+	if r.streamed != nil {
+		r.streamed.fail(step)
+		return
+	}
+	r.eachInput(func(target string) {
+		r.completeStep(step, target)
+	})
+}
+
+// skipStep returns true if a step completed on a target before the
+// interruption of the resumed scan, counting it as completed again.
+func (r *Runner) skipStep(step, target string) bool {
+	if !r.checkpoint.Completed(step, target) {
+		return false
+	}
+	r.completeStep(step, target)
+	return true
+}
+
+// openCheckpoint opens the checkpoint of -resume for the loaded templates
+// and the targets, writing it at an interval until the scan completes. The
+// streamed targets are recorded by the lines consumed, the ones consumed
+// before the interruption being skipped.
+func (r *Runner) openCheckpoint(paths []string) error {
+	if r.options.Resume == "" {
+		return nil
+	}
+	targets := r.expandedInput()
+	hash, err := checkpoint.Hash(paths, targets)
+	if err != nil {
+		return fmt.Errorf("could not hash the templates of the checkpoint: %s", err)
+	}
+	opened, err := checkpoint.Open(r.options.Resume, hash, targets)
+	if err == checkpoint.ErrChanged {
+		return fmt.Errorf("could not resume the scan from %s: %s, run it with the same flags or remove the checkpoint", r.options.Resume, err)
+	}
+	if err != nil {
+		return fmt.Errorf("could not resume the scan: %s", err)
+	}
+	if opened.Resumed() {
+		gologger.Labelf("Resuming the scan from %s\n", opened.Path())
+	}
+	if consumed := opened.Consumed(); r.targetStream != nil && consumed > 0 {
+		skipped := r.targetStream.Skip(consumed)
+		gologger.Labelf("Skipped the first %d lines of stdin scanned before the interruption\n", skipped)
+	}
+	r.checkpoint = opened
+	r.checkpointStop = make(chan struct{})
+	r.checkpointStopped = make(chan struct{})
+
+	go func() {
+		defer close(r.checkpointStopped)
+		ticker := time.NewTicker(checkpointInterval)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-ticker.C:
+				r.saveCheckpoint()
+			case <-r.checkpointStop:
+				return
+			}
+		}
+	}()
+	return nil
+}
+
+// saveCheckpoint writes the checkpoint of -resume if any
+func (r *Runner) saveCheckpoint() {
+	if err := r.checkpoint.Save(); err != nil {
+		gologger.Warningf("Could not write the checkpoint %s: %s\n", r.checkpoint.Path(), err)
+	}
+}
+
+// closeCheckpoint stops writing the checkpoint of -resume and removes it,
+// the scan being completed, or writes it if the scan was truncated.
+func (r *Runner) closeCheckpoint() {
+	if r.checkpoint == nil {
+		if r.truncated.Get() && !r.options.Embedded {
+			gologger.Labelf("Stopped the scan %s, use -resume to resume the next truncated scans\n", r.stopReason)
+		}
+		return
+	}
+	// the checkpoint is not written again once removed
+	close(r.checkpointStop)
+	<-r.checkpointStopped
+	if r.truncated.Get() {
+		r.saveCheckpoint()
+		gologger.Labelf("Stopped the scan %s, wrote its checkpoint to %s, run the scan again with the same flags to resume it\n", r.stopReason, r.checkpoint.Path())
+		return
+	}
+	if err := r.checkpoint.Remove(); err != nil {
+		gologger.Warningf("Could not remove the checkpoint %s: %s\n", r.checkpoint.Path(), err)
+	}
+}
@@ -0,0 +1,238 @@
+package runner
+
+import (
+	"context"
+	"sync"
+
+	"github.com/projectdiscovery/gologger"
+	"github.com/projectdiscovery/nuclei/v2/internal/progress"
+	"github.com/projectdiscovery/nuclei/v2/pkg/atomicboolean"
+	"github.com/projectdiscovery/nuclei/v2/pkg/grouping"
+	"github.com/projectdiscovery/nuclei/v2/pkg/templates"
+	"github.com/projectdiscovery/nuclei/v2/pkg/workflows"
+)
+
+const (
+	// templateSpray runs each template on all the targets, the templates
+	// running concurrently.
+	templateSpray = "template-spray"
+	// hostSpray runs all the templates on each target in turn
+	hostSpray = "host-spray"
+)
+
+// scanJob is the run of a request block, of a multi protocol template, of
+// a cluster or of a workflow on the targets, each target being a unit of
+// work of the pool of the runner.
+type scanJob struct {
+	// name identifies the job in the metrics of -metrics
+	name string
+	// start prepares the run of the job on a target, returning nil if it
+	// is skipped. The run abandons the target once its context is done.
+	start func(target string) func(ctx context.Context)
+	// finish records the results of the job once run on all the targets
+	finish func()
+	// requests is the number of requests of a run on a target, added to the
+	// progress when the run is retried.
+	requests int64
+
+	// limiter limits the targets run concurrently by the job if not nil
+	limiter chan struct{}
+	wg      sync.WaitGroup
+	results atomicboolean.AtomBool
+	// retrying is true once a run is queued to retry, the job finishing
+	// after the retries.
+	retrying atomicboolean.AtomBool
+}
+
+// newScanJob returns a job running on a number of targets concurrently,
+// without limit other than the global concurrency if 0.
+func newScanJob(name string, threads int) *scanJob {
+	job := &scanJob{name: name}
+	if threads > 0 {
+		job.limiter = make(chan struct{}, threads)
+	}
+	return job
+}
+
+// enqueue submits the run of a job on a target to the pool once the job
+// allows it, unless the scan stopped meanwhile. The pool runs it within the
+// global concurrency and the concurrency towards its host.
+func (r *Runner) enqueue(job *scanJob, target string) {
+	if r.truncated.Get() {
+		return
+	}
+	run := job.start(target)
+	if run == nil {
+		return
+	}
+	if job.limiter != nil {
+		job.limiter <- struct{}{}
+	}
+	release := func() {
+		if job.limiter != nil {
+			<-job.limiter
+		}
+	}
+	if r.truncated.Get() {
+		release()
+		return
+	}
+	job.wg.Add(1)
+
+	r.pool.Submit(grouping.Key(target), func() {
+		defer job.wg.Done()
+		r.metrics.runStarted(job.name)
+		ctx, cancel := r.runContext()
+		run(ctx)
+		cancel()
+		r.metrics.runFinished(job.name)
+		release()
+	})
+}
+
+// wait waits for the runs of a job on the targets and finishes it unless
+// some of its runs are retried, returning true if it got results.
+func (j *scanJob) wait() bool {
+	j.wg.Wait()
+	if j.finish != nil && !j.retrying.Get() {
+		j.finish()
+	}
+	return j.results.Get()
+}
+
+// runJob runs a job on all the targets, returning true if it got results.
+// A nil job, whose executers could not be created, does nothing.
+func (r *Runner) runJob(job *scanJob) bool {
+	if job == nil {
+		return false
+	}
+	r.eachInput(func(target string) {
+		r.enqueue(job, target)
+	})
+	return job.wait()
+}
+
+// newParsedJobs creates the jobs of a parsed template or workflow along
+// with the function to call once they are done, each request block of the
+// single protocol templates being a job.
+func (r *Runner) newParsedJobs(p *progress.Progress, t interface{}) ([]*scanJob, func()) {
+	var jobs []*scanJob
+	add := func(job *scanJob) {
+		if job != nil {
+			jobs = append(jobs, job)
+		}
+	}
+	switch t := t.(type) {
+	case *templates.Template:
+		statuses := r.newMatcherStatuses()
+		if t.HasMultipleProtocols() {
+			add(r.newMultiProtocolJob(p, t, statuses))
+		} else {
+			for i, request := range t.RequestsDNS {
+				add(r.newRequestJob(p, t, request, requestStep(t.ID, "dns", i), statuses))
+			}
+			for i, request := range t.BulkRequestsHTTP {
+				add(r.newRequestJob(p, t, request, requestStep(t.ID, "http", i), statuses))
+			}
+			for i, request := range t.RequestsHeadless {
+				add(r.newRequestJob(p, t, request, requestStep(t.ID, "headless", i), statuses))
+			}
+			for i, request := range t.RequestsNetwork {
+				add(r.newRequestJob(p, t, request, requestStep(t.ID, "network", i), statuses))
+			}
+			for i, request := range t.RequestsFile {
+				add(r.newRequestJob(p, t, request, requestStep(t.ID, "file", i), statuses))
+			}
+			for i, request := range t.RequestsWebsocket {
+				add(r.newRequestJob(p, t, request, requestStep(t.ID, "websocket", i), statuses))
+			}
+			for i, request := range t.RequestsSSL {
+				add(r.newRequestJob(p, t, request, requestStep(t.ID, "ssl", i), statuses))
+			}
+		}
+		return jobs, func() { r.writeStatuses(t, statuses) }
+	case *workflows.Workflow:
+		add(r.newWorkflowJob(p, t))
+	}
+	return jobs, func() {}
+}
+
+// sprayTemplates runs the clusters and the templates and workflows of the
+// paths concurrently, each of them enqueuing its runs on all the targets,
+// then the runs to retry. It returns true if any of them got results. As
+// many templates as the workers of the pool are enqueued at once, so the
+// pool stays busy while their last runs complete.
+func (r *Runner) sprayTemplates(p *progress.Progress, clusters [][]*templates.Template, paths []string) bool {
+	spray := make(chan func() bool)
+	go func() {
+		defer close(spray)
+		for _, cluster := range clusters {
+			cluster := cluster
+			spray <- func() bool {
+				return r.runJob(r.newClusterJob(p, cluster))
+			}
+		}
+		for _, match := range paths {
+			match := match
+			spray <- func() bool {
+				t, err := r.parse(match)
+				if err != nil {
+					gologger.Errorf("Could not parse file '%s': %s\n", match, err)
+					return false
+				}
+				return r.executeParsed(p, t, r.stats.TemplateCompleted)
+			}
+		}
+	}()
+
+	var wg sync.WaitGroup
+	var results atomicboolean.AtomBool
+	for i := 0; i < r.options.Threads; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for run := range spray {
+				results.Or(run())
+			}
+		}()
+	}
+	wg.Wait()
+	r.runRetries(p)
+	return results.Get()
+}
+
+// sprayHosts runs the clusters and the parsed templates and workflows on
+// each target in turn, the jobs of a target being enqueued before the ones
+// of the next target, the streamed targets as they arrive, then the runs to
+// retry. It returns true if any of them got results.
+func (r *Runner) sprayHosts(p *progress.Progress, clusters [][]*templates.Template, parsed []interface{}) bool {
+	var jobs []*scanJob
+	var finishes []func()
+	for _, cluster := range clusters {
+		if job := r.newClusterJob(p, cluster); job != nil {
+			jobs = append(jobs, job)
+		}
+	}
+	for _, t := range parsed {
+		parsedJobs, finish := r.newParsedJobs(p, t)
+		jobs = append(jobs, parsedJobs...)
+		finishes = append(finishes, finish)
+	}
+
+	r.eachTarget(func(target string) {
+		for _, job := range jobs {
+			r.enqueue(job, target)
+		}
+	})
+
+	var results bool
+	for _, job := range jobs {
+		results = job.wait() || results
+	}
+	r.runRetries(p)
+	for _, finish := range finishes {
+		finish()
+		r.stats.TemplateCompleted()
+	}
+	return results
+}
@@ -0,0 +1,200 @@
+package runner
+
+import (
+	"fmt"
+	"io/ioutil"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	jsoniter "github.com/json-iterator/go"
+	"github.com/projectdiscovery/gologger"
+	"github.com/projectdiscovery/nuclei/v2/pkg/hosterrors"
+	"github.com/projectdiscovery/nuclei/v2/pkg/stats"
+)
+
+// maxSummaryReasons is the number of dominant error reasons of the summary
+const maxSummaryReasons = 3
+
+// recordError counts the error of a template for a target in the stats,
+// the targets not responding to the http probes being skipped ones and the
+// dead hosts and the hosts failing the preflight being recorded once when
+// marked.
+func (r *Runner) recordError(target string, err error) {
+	switch err {
+	case nil, hosterrors.ErrSkipped, errTemplateTimeout, errScanStopped, errNotAlive:
+	case errNotProbed:
+		r.stats.HostSkipped(target)
+	default:
+		r.stats.HostError(target, err)
+	}
+}
+
+// stopStream stops the stream of the progress of -stats if any, writing its
+// last snapshot.
+func (r *Runner) stopStream() {
+	if r.stream == nil {
+		return
+	}
+	r.stream.Stop()
+	if r.statsFile != nil {
+		r.statsFile.Close()
+	}
+}
+
+// templateErrors count the targets of the requests of a template failing,
+// the template failing to execute at all if all of them failed.
+type templateErrors struct {
+	targets int64
+	errored int64
+	mutex   sync.Mutex
+	first   error
+}
+
+// add counts the result of a target, the skipped targets not being errors
+func (t *templateErrors) add(err error) {
+	atomic.AddInt64(&t.targets, 1)
+	if err == nil || skipped(err) {
+		return
+	}
+	atomic.AddInt64(&t.errored, 1)
+	t.mutex.Lock()
+	defer t.mutex.Unlock()
+	if t.first == nil {
+		t.first = err
+	}
+}
+
+// recordTemplateErrors records a template as failed in the stats if all
+// its targets failed.
+func (r *Runner) recordTemplateErrors(templateID string, errors *templateErrors) {
+	if targets := atomic.LoadInt64(&errors.targets); targets == 0 || atomic.LoadInt64(&errors.errored) < targets {
+		return
+	}
+	r.stats.TemplateFailed(templateID, "all the targets failed, "+stats.Reason(errors.first))
+}
+
+// logSummary shows the summary of the scan once, writing it to the
+// -stats-json file if any, at the end of the scan or when it is interrupted.
+func (r *Runner) logSummary(interrupted bool) {
+	r.summaryOnce.Do(func() {
+		summary := r.stats.Summary(r.options.StatsTop, interrupted)
+
+		gologger.Labelf("Scanned %d targets with %d requests in %s\n", summary.Targets, summary.Requests, (time.Duration(summary.DurationMS) * time.Millisecond).Round(time.Millisecond))
+		if summary.RateLimitedRequests > 0 {
+			gologger.Labelf("Delayed %d requests by %s in total to send at most %d requests per second to each host\n", summary.RateLimitedRequests, (time.Duration(summary.RateLimitWaitMS) * time.Millisecond).Round(time.Millisecond), summary.RateLimitPerHost)
+		}
+		if summary.Backoffs > 0 {
+			gologger.Labelf("Backed off %d times on the network errors of the hosts and recovered %d times with -adaptive\n", summary.Backoffs, summary.Recoveries)
+		}
+		if summary.Retries > 0 {
+			gologger.Labelf("Retried %d templates failing on a target with a network error, %d succeeded\n", summary.Retries, summary.SucceededRetries)
+		}
+		if summary.CachedRequests > 0 {
+			gologger.Labelf("Reused %d responses cached by -project instead of sending their requests\n", summary.CachedRequests)
+		}
+		if replayed, failed := r.replayer.Replayed(), r.replayer.Failed(); replayed > 0 || failed > 0 {
+			message := fmt.Sprintf("Replayed %d requests of the results through -proxy-replay", replayed)
+			if failed > 0 {
+				message += fmt.Sprintf(", %d failed, use -v to show the errors", failed)
+			}
+			if dropped := r.replayer.Dropped(); dropped > 0 {
+				message += fmt.Sprintf(", %d dropped as the proxy was too slow", dropped)
+			}
+			gologger.Labelf("%s\n", message)
+		}
+		if summary.ClusteredRequests > 0 {
+			gologger.Labelf("Saved %d requests by clustering the templates sending the same request\n", summary.ClusteredRequests)
+		}
+		if summary.SharedRequests > 0 {
+			gologger.Labelf("Reused %d responses of the baseline requests of -tech-detect instead of sending the clustered requests\n", summary.SharedRequests)
+		}
+		if len(summary.TechHosts) > 0 {
+			hosts := make([]string, 0, len(summary.TechHosts))
+			for _, host := range summary.TechHosts {
+				technologies := "nothing detected"
+				if len(host.Technologies) > 0 {
+					technologies = strings.Join(host.Technologies, ", ")
+				}
+				hosts = append(hosts, fmt.Sprintf("%s (%s, %d templates skipped)", host.Host, technologies, host.SkippedTemplates))
+			}
+			gologger.Labelf("Detected technologies: %s\n", strings.Join(hosts, "; "))
+			gologger.Labelf("Skipped %d templates of the technologies not detected on their hosts with -tech-detect\n", summary.TechSkippedTemplates)
+		}
+		if summary.Findings > 0 {
+			severities := make(map[string]int, len(summary.Severities))
+			for severity, count := range summary.Severities {
+				severities[severity] = int(count)
+			}
+			gologger.Labelf("Found %d findings: %s\n", summary.Findings, formatCounts(severities))
+			gologger.Labelf("Top templates: %s\n", formatStatsCounts(summary.TopTemplates))
+		}
+		if summary.ErroredHosts > 0 {
+			reasons := summary.ErrorReasons
+			if len(reasons) > maxSummaryReasons {
+				reasons = reasons[:maxSummaryReasons]
+			}
+			gologger.Labelf("Errored hosts: %d, top errors: %s\n", summary.ErroredHosts, formatStatsCounts(reasons))
+		}
+		if summary.SkippedHosts > 0 {
+			gologger.Labelf("Skipped hosts: %d\n", summary.SkippedHosts)
+		}
+		if summary.ExcludedTargets > 0 {
+			gologger.Labelf("Excluded targets: %d, by rule: %s\n", summary.ExcludedTargets, formatStatsCounts(summary.ExclusionRules))
+		}
+		if summary.DuplicateTargets > 0 {
+			gologger.Labelf("Duplicate targets: %d, skipped as the same as a previous target once normalized\n", summary.DuplicateTargets)
+		}
+		if len(summary.DeadHosts) > 0 {
+			dead := make([]string, 0, len(summary.DeadHosts))
+			for _, host := range summary.DeadHosts {
+				dead = append(dead, fmt.Sprintf("%s (%s)", host.Host, host.Error))
+			}
+			gologger.Labelf("Skipped hosts, error threshold of %d consecutive network errors: %s\n", r.options.MaxHostError, strings.Join(dead, ", "))
+		}
+		if len(summary.PreflightFailed) > 0 {
+			failed := make([]string, 0, len(summary.PreflightFailed))
+			for _, host := range summary.PreflightFailed {
+				failed = append(failed, fmt.Sprintf("%s (%s)", host.Host, host.Error))
+			}
+			gologger.Labelf("Skipped hosts, preflight failed: %d, %s\n", len(failed), strings.Join(failed, ", "))
+		}
+		for _, failed := range summary.FailedTemplates {
+			gologger.Labelf("Failed template %s: %s\n", failed.Template, failed.Reason)
+		}
+		if len(summary.TimedOut) > 0 {
+			timedOut := make([]string, 0, len(summary.TimedOut))
+			for _, pair := range summary.TimedOut {
+				timedOut = append(timedOut, fmt.Sprintf("%s on %s", pair.Template, pair.Target))
+			}
+			gologger.Labelf("Abandoned %d templates running longer than %s: %s\n", len(summary.TimedOut), r.options.TemplateTimeout, strings.Join(timedOut, ", "))
+		}
+		if summary.Truncated && r.options.Embedded {
+			gologger.Labelf("Truncated the scan %s\n", r.stopReason)
+		} else if summary.Truncated {
+			gologger.Labelf("Truncated the scan %s, exiting with code %d\n", r.stopReason, ExitTruncated)
+		}
+		r.logBenchmark()
+
+		if r.options.StatsJSON == "" {
+			return
+		}
+		data, err := jsoniter.MarshalIndent(summary, "", "  ")
+		if err == nil {
+			err = ioutil.WriteFile(r.options.StatsJSON, append(data, '\n'), 0644)
+		}
+		if err != nil {
+			gologger.Errorf("Could not write the summary to %s: %s\n", r.options.StatsJSON, err)
+		}
+	})
+}
+
+// formatStatsCounts returns counts as a list of names with their count
+func formatStatsCounts(counts []stats.Count) string {
+	formatted := make([]string, 0, len(counts))
+	for _, count := range counts {
+		formatted = append(formatted, fmt.Sprintf("%s %d", count.Name, count.Count))
+	}
+	return strings.Join(formatted, ", ")
+}
@@ -0,0 +1,184 @@
+package runner
+
+import (
+	"context"
+	"fmt"
+	"net/url"
+	"strings"
+	"sync"
+
+	"github.com/projectdiscovery/gologger"
+	"github.com/projectdiscovery/nuclei/v2/pkg/executer"
+	"github.com/projectdiscovery/nuclei/v2/pkg/ratelimit"
+	"github.com/projectdiscovery/nuclei/v2/pkg/stats"
+	"github.com/projectdiscovery/nuclei/v2/pkg/techdetect"
+	"github.com/projectdiscovery/nuclei/v2/pkg/templates"
+)
+
+// baselineTemplate is the template of the baseline requests of -tech-detect
+const baselineTemplate = `id: tech-baseline
+info:
+  name: Baseline of the technology detection
+  author: nuclei
+requests:
+  - method: GET
+    path:
+      - "{{BaseURL}}"
+    # the responses are fingerprinted, never matched
+    matchers:
+      - type: status
+        status: [200]
+`
+
+// techDetector detects the technologies of the hosts of the http targets
+// with -tech-detect from the responses to the baseline requests of their
+// first target and of their root, sent once by host and port for the whole
+// scan. The responses are shared with the clusters sending the same request.
+type techDetector struct {
+	mapping  *techdetect.Mapping
+	filter   *techdetect.Filter
+	executer *executer.HTTPExecuter
+	shared   *executer.SharedResponses
+	stats    *stats.Stats
+
+	mutex   sync.Mutex
+	results map[string]*techResult
+}
+
+// techResult is the cached detection of a host and port
+type techResult struct {
+	once      sync.Once
+	detection *techdetect.Detection
+}
+
+// newTechDetector creates the technology detector of -tech-detect, nil if
+// it is off and for the dry run, the passive scans and the listing of the
+// templates, which send no requests.
+func (r *Runner) newTechDetector() (*techDetector, error) {
+	options := r.options
+	mode, err := techdetect.ParseMode(options.TechDetect)
+	if err != nil {
+		return nil, err
+	}
+	if mode == techdetect.Off || options.TemplateList || options.DryRun || options.Passive != "" {
+		return nil, nil
+	}
+	mapping := techdetect.DefaultMapping()
+	if options.TechMap != "" {
+		if mapping, err = techdetect.LoadMapping(options.TechMap); err != nil {
+			return nil, err
+		}
+	}
+	template, err := templates.ParseData("tech-baseline.yaml", []byte(baselineTemplate))
+	if err != nil {
+		return nil, fmt.Errorf("could not parse baseline template: %s", err)
+	}
+	template.SetRegexGuard(r.regexGuard)
+	request := template.BulkRequestsHTTP[0]
+	shared := executer.NewSharedResponses()
+	httpExecuter, err := executer.NewHTTPExecuter(&executer.HTTPOptions{
+		Debug:           options.Debug,
+		Template:        template,
+		BulkHttpRequest: request,
+		Timeout:         r.effectiveTimeout(template, request.Timeout),
+		Retries:         r.effectiveRetries(template, request.Retries),
+		Resolved:        true,
+		ProxyURL:        options.ProxyURL,
+		ProxySocksURL:   options.ProxySocksURL,
+		CustomHeaders:   options.CustomHeaders,
+		ForcedHeaders:   options.ForcedHeaders,
+		Stats:           r.stats,
+		RegexGuard:      r.regexGuard,
+		DiscardResults:  true,
+		Redactor:        r.redactor,
+		NoRedact:        options.NoRedact,
+		Quiet:           true,
+		RateLimiter:     r.rateLimiter,
+		Adaptive:        r.adaptive,
+		Project:         r.project,
+		Sessions:        r.sessions,
+		HostErrors:      r.hostErrors,
+		SharedResponses: shared,
+	})
+	if err != nil {
+		return nil, err
+	}
+	return &techDetector{
+		mapping:  mapping,
+		filter:   techdetect.NewFilter(mapping, mode),
+		executer: httpExecuter,
+		shared:   shared,
+		stats:    r.stats,
+		results:  make(map[string]*techResult),
+	}, nil
+}
+
+// sharedResponses returns the responses shared with the clusters, nil
+// without a detector
+func (d *techDetector) sharedResponses() *executer.SharedResponses {
+	if d == nil {
+		return nil
+	}
+	return d.shared
+}
+
+// detect returns the technologies detected on the host and port of an http
+// target, which is only fingerprinted for its first target.
+func (d *techDetector) detect(URL string, parsed *url.URL) *techdetect.Detection {
+	hostPort := ratelimit.HostPort(parsed)
+
+	d.mutex.Lock()
+	result, ok := d.results[hostPort]
+	if !ok {
+		result = &techResult{}
+		d.results[hostPort] = result
+	}
+	d.mutex.Unlock()
+
+	result.once.Do(func() {
+		baselines := []string{URL}
+		if root := parsed.Scheme + "://" + parsed.Host + "/"; strings.TrimSuffix(URL, "/") != strings.TrimSuffix(root, "/") {
+			baselines = append(baselines, root)
+		}
+		var responses []*techdetect.Response
+		for _, baseline := range baselines {
+			response, err := d.executer.Fetch(context.Background(), baseline)
+			if err != nil {
+				gologger.Verbosef("Could not fetch the baseline of %s: %s\n", "tech-detect", baseline, err)
+				continue
+			}
+			responses = append(responses, &techdetect.Response{Header: response.Header, Body: string(response.Body)})
+		}
+		result.detection = d.mapping.Detect(responses)
+		if result.detection == nil {
+			return
+		}
+		d.stats.TechDetected(hostPort, result.detection.Technologies)
+		if len(result.detection.Technologies) > 0 {
+			gologger.Verbosef("Detected %s on %s\n", "tech-detect", strings.Join(result.detection.Technologies, ", "), hostPort)
+		}
+	})
+	return result.detection
+}
+
+// skipTech returns true if a template is skipped on an http target with
+// -tech-detect, its tags being the ones of technologies not detected on
+// the host of the target, counting it as skipped.
+func (r *Runner) skipTech(template *templates.Template, URL string) bool {
+	if r.techDetector == nil {
+		return false
+	}
+	tags := template.Info.TagList()
+	if !r.techDetector.filter.Filters(tags) {
+		return false
+	}
+	parsed, err := url.Parse(URL)
+	if err != nil || parsed.Host == "" {
+		return false
+	}
+	if r.techDetector.filter.Runs(tags, r.techDetector.detect(URL, parsed)) {
+		return false
+	}
+	r.stats.TechSkipped(ratelimit.HostPort(parsed))
+	return true
+}
@@ -0,0 +1,710 @@
+package runner
+
+import (
+	"bufio"
+	"context"
+	"fmt"
+	"net"
+	"net/http/cookiejar"
+	"net/url"
+	"os"
+	"strings"
+	"sync/atomic"
+
+	"github.com/projectdiscovery/gologger"
+	"github.com/projectdiscovery/nuclei/v2/internal/progress"
+	"github.com/projectdiscovery/nuclei/v2/pkg/executer"
+	"github.com/projectdiscovery/nuclei/v2/pkg/requests"
+	"github.com/projectdiscovery/nuclei/v2/pkg/templates"
+)
+
+// logLoadedTemplate displays the message for a loaded template
+func logLoadedTemplate(template *templates.Template) {
+	message := fmt.Sprintf("[%s] Loaded template %s (@%s)", template.ID, template.Info.Name, template.Info.Author)
+	if template.Info.Severity != "" {
+		message += " [" + template.Info.Severity + "]"
+	}
+	gologger.Infof("%s\n", message)
+}
+
+// newHTTPExecuter creates an executer for an http request of a template,
+// sharing the cookies of a workflow if a jar is specified. The positions of
+// the payload requests of a step are recorded in the checkpoint if any.
+func (r *Runner) newHTTPExecuter(template *templates.Template, request *requests.BulkHTTPRequest, writer *bufio.Writer, jar *cookiejar.Jar, step string) (*executer.HTTPExecuter, error) {
+	// the requests of the multi protocol templates are a single step
+	if step == "" {
+		r.metrics.addGenerators(template.ID, request)
+	} else {
+		r.metrics.addGenerators(step, request)
+	}
+	return executer.NewHTTPExecuter(&executer.HTTPOptions{
+		Debug:           r.options.Debug,
+		Template:        template,
+		BulkHttpRequest: request,
+		Writer:          writer,
+		Timeout:         r.effectiveTimeout(template, request.Timeout),
+		Retries:         r.effectiveRetries(template, request.Retries),
+		Resolved:        true,
+		ProxyURL:        r.options.ProxyURL,
+		ProxySocksURL:   r.options.ProxySocksURL,
+		CustomHeaders:   r.options.CustomHeaders,
+		ForcedHeaders:   r.options.ForcedHeaders,
+		JSON:            r.options.JSON,
+		JSONRequests:    r.options.JSONRequests,
+		IncludeRR:       r.options.IncludeRR,
+		CookieReuse:     request.CookieReuse,
+		CookieJar:       jar,
+		Exclusions:      r.exclusions,
+		ShowSuppressed:  r.options.ShowSuppressed,
+		Collector:       r.collector,
+		Files:           r.files,
+		RegexGuard:      r.regexGuard,
+		Exporter:        r.sarif,
+		Markdown:        r.markdown,
+		Exporters:       r.exporters,
+		Deduper:         r.deduper,
+		ShowDuplicates:  r.options.ShowDuplicates,
+		Stats:           r.stats,
+		Benchmark:       r.benchmark,
+		DiscardResults:  r.options.BenchmarkNoOutput,
+		Redactor:        r.redactor,
+		NoRedact:        r.options.NoRedact,
+		Grouper:         r.grouper,
+		Quiet:           r.options.Embedded,
+		RateLimiter:     r.rateLimiter,
+		Adaptive:        r.adaptive,
+		Project:         r.project,
+		TemplateKeys:    r.options.ProjectRandom == projectRandomTemplate,
+		Replayer:        r.replayer,
+		RequestExporter: r.requestExporter,
+		Interactsh:      r.interactsh,
+		Sessions:        r.sessions,
+		SharedResponses: r.techDetector.sharedResponses(),
+		Checkpoint:      r.checkpoint,
+		Step:            step,
+		HostErrors:      r.hostErrors,
+		PassiveExtract:  r.options.PassiveExtract || r.options.Silent,
+		ColoredOutput:   !r.options.NoColor,
+		Colorizer:       r.colorizer,
+		Decolorizer:     r.decolorizer,
+	})
+}
+
+// newDNSExecuter creates an executer for a dns request of a template, the
+// internal executers not writing their results.
+func (r *Runner) newDNSExecuter(template *templates.Template, request *requests.DNSRequest, writer *bufio.Writer, internal bool) (*executer.DNSExecuter, error) {
+	return executer.NewDNSExecuter(&executer.DNSOptions{
+		Debug:          r.options.Debug,
+		Template:       template,
+		DNSRequest:     request,
+		Writer:         writer,
+		JSON:           r.options.JSON,
+		JSONRequests:   r.options.JSONRequests,
+		Resolvers:      r.resolvers,
+		Pools:          r.resolverPools,
+		Timeout:        r.effectiveTimeout(template, request.Timeout),
+		Retries:        r.effectiveRetries(template, request.Retries),
+		Resolved:       true,
+		PTRCIDRLimit:   r.options.PTRCIDRLimit,
+		IncludeRR:      r.options.IncludeRR,
+		Exclusions:     r.exclusions,
+		ShowSuppressed: r.options.ShowSuppressed,
+		Collector:      r.collector,
+		Files:          r.files,
+		Exporter:       r.sarif,
+		Markdown:       r.markdown,
+		Exporters:      r.exporters,
+		Deduper:        r.deduper,
+		ShowDuplicates: r.options.ShowDuplicates,
+		Stats:          r.stats,
+		Benchmark:      r.benchmark,
+		DiscardResults: r.options.BenchmarkNoOutput,
+		Redactor:       r.redactor,
+		NoRedact:       r.options.NoRedact,
+		Grouper:        r.grouper,
+		Quiet:          r.options.Embedded,
+		RateLimiter:    r.rateLimiter,
+		Adaptive:       r.adaptive,
+		PassiveExtract: r.options.PassiveExtract || r.options.Silent,
+		Internal:       internal,
+		ColoredOutput:  !r.options.NoColor,
+		Colorizer:      r.colorizer,
+		Decolorizer:    r.decolorizer,
+	})
+}
+
+// commonOptions returns the options shared by the executers of the
+// headless, network, file, websocket and ssl requests.
+func (r *Runner) commonOptions(writer *bufio.Writer) executer.CommonOptions {
+	return executer.CommonOptions{
+		Debug:          r.options.Debug,
+		JSON:           r.options.JSON,
+		JSONRequests:   r.options.JSONRequests,
+		Writer:         writer,
+		IncludeRR:      r.options.IncludeRR,
+		Exclusions:     r.exclusions,
+		ShowSuppressed: r.options.ShowSuppressed,
+		Collector:      r.collector,
+		Files:          r.files,
+		RegexGuard:     r.regexGuard,
+		Exporter:       r.sarif,
+		Markdown:       r.markdown,
+		Exporters:      r.exporters,
+		Deduper:        r.deduper,
+		ShowDuplicates: r.options.ShowDuplicates,
+		Stats:          r.stats,
+		Benchmark:      r.benchmark,
+		DiscardResults: r.options.BenchmarkNoOutput,
+		Redactor:       r.redactor,
+		NoRedact:       r.options.NoRedact,
+		Grouper:        r.grouper,
+		Quiet:          r.options.Embedded,
+		RateLimiter:    r.rateLimiter,
+		PassiveExtract: r.options.PassiveExtract || r.options.Silent,
+		ColoredOutput:  !r.options.NoColor,
+		Colorizer:      r.colorizer,
+		Decolorizer:    r.decolorizer,
+	}
+}
+
+// newHeadlessExecuter creates an executer for a headless request of a
+// template, running its steps in the pages of the browser of -headless.
+func (r *Runner) newHeadlessExecuter(template *templates.Template, request *requests.HeadlessRequest, writer *bufio.Writer) (*executer.HeadlessExecuter, error) {
+	return executer.NewHeadlessExecuter(&executer.HeadlessOptions{
+		CommonOptions:   r.commonOptions(writer),
+		Template:        template,
+		HeadlessRequest: request,
+		Pool:            r.browser,
+	})
+}
+
+// newNetworkExecuter creates an executer for a network request of a
+// template, running its steps over raw tcp or udp connections.
+func (r *Runner) newNetworkExecuter(template *templates.Template, request *requests.NetworkRequest, writer *bufio.Writer) (*executer.NetworkExecuter, error) {
+	return executer.NewNetworkExecuter(&executer.NetworkOptions{
+		CommonOptions:  r.commonOptions(writer),
+		Template:       template,
+		NetworkRequest: request,
+		Timeout:        r.effectiveTimeout(template, request.Timeout),
+		Resolved:       true,
+	})
+}
+
+// newSSLExecuter creates an executer for a ssl request of a template,
+// running its tls handshakes with the servers of the targets.
+func (r *Runner) newSSLExecuter(template *templates.Template, request *requests.SSLRequest, writer *bufio.Writer) (*executer.SSLExecuter, error) {
+	return executer.NewSSLExecuter(&executer.SSLOptions{
+		CommonOptions: r.commonOptions(writer),
+		Template:      template,
+		SSLRequest:    request,
+		Timeout:       r.effectiveTimeout(template, request.Timeout),
+		Resolved:      true,
+	})
+}
+
+// newFileExecuter creates an executer for a file request of a template,
+// running its matchers and extractors over the local files of the targets.
+func (r *Runner) newFileExecuter(template *templates.Template, request *requests.FileRequest, writer *bufio.Writer) (*executer.FileExecuter, error) {
+	return executer.NewFileExecuter(&executer.FileOptions{
+		CommonOptions: r.commonOptions(writer),
+		Template:      template,
+		FileRequest:   request,
+		Concurrency:   r.options.FileConcurrency,
+	})
+}
+
+// newWebsocketExecuter creates an executer for a websocket request of a
+// template, sending its messages over upgraded websocket connections.
+func (r *Runner) newWebsocketExecuter(template *templates.Template, request *requests.WebsocketRequest, writer *bufio.Writer) (*executer.WebsocketExecuter, error) {
+	return executer.NewWebsocketExecuter(&executer.WebsocketOptions{
+		CommonOptions:    r.commonOptions(writer),
+		Template:         template,
+		WebsocketRequest: request,
+		Timeout:          r.effectiveTimeout(template, request.Timeout),
+		Resolved:         true,
+		ProxyURL:         r.options.ProxyURL,
+		ProxySocksURL:    r.options.ProxySocksURL,
+	})
+}
+
+// templateExecuters are the executers of the requests of a template
+type templateExecuters struct {
+	template *templates.Template
+	// writers are the buffered writers of the output file of each executer
+	writers      []*bufio.Writer
+	dns          []*executer.DNSExecuter
+	dnsRequests  []*requests.DNSRequest
+	http         []*executer.HTTPExecuter
+	httpRequests []*requests.BulkHTTPRequest
+	// headless are the executers of the headless requests, the templates
+	// with headless requests having no other requests
+	headless         []*executer.HeadlessExecuter
+	headlessRequests []*requests.HeadlessRequest
+	// network are the executers of the network requests, the templates
+	// with network requests having no other requests
+	network         []*executer.NetworkExecuter
+	networkRequests []*requests.NetworkRequest
+	// file are the executers of the file requests, the templates with file
+	// requests having no other requests
+	file         []*executer.FileExecuter
+	fileRequests []*requests.FileRequest
+	// websocket are the executers of the websocket requests, the templates
+	// with websocket requests having no other requests
+	websocket         []*executer.WebsocketExecuter
+	websocketRequests []*requests.WebsocketRequest
+	// ssl are the executers of the ssl requests, the templates with ssl
+	// requests having no other requests
+	ssl         []*executer.SSLExecuter
+	sslRequests []*requests.SSLRequest
+}
+
+// newTemplateExecuters creates the executers of the requests of a template,
+// dropping the requests to the targets of the ones failing to compile.
+func (r *Runner) newTemplateExecuters(p *progress.Progress, template *templates.Template, jar *cookiejar.Jar, targets int64) *templateExecuters {
+	executers := &templateExecuters{template: template}
+	// the results of the dns requests only gate the http ones if required
+	internal := template.RequiresAllProtocols()
+	for _, request := range template.RequestsDNS {
+		dnsExecuter, err := r.newDNSExecuter(template, request, executers.newWriter(r.output), internal)
+		if err != nil {
+			if p != nil {
+				p.Drop(request.GetRequestCount() * targets)
+			}
+			gologger.Warningf("Could not create executer for template '%s': %s\n", template.ID, err)
+			r.stats.TemplateFailed(template.ID, "could not create executer: "+err.Error())
+			continue
+		}
+		executers.dns = append(executers.dns, dnsExecuter)
+		executers.dnsRequests = append(executers.dnsRequests, request)
+	}
+	for _, request := range template.BulkRequestsHTTP {
+		httpExecuter, err := r.newHTTPExecuter(template, request, executers.newWriter(r.output), jar, "")
+		if err != nil {
+			if p != nil {
+				p.Drop(request.GetRequestCount() * targets)
+			}
+			gologger.Warningf("Could not create executer for template '%s': %s\n", template.ID, err)
+			r.stats.TemplateFailed(template.ID, "could not create executer: "+err.Error())
+			continue
+		}
+		executers.http = append(executers.http, httpExecuter)
+		executers.httpRequests = append(executers.httpRequests, request)
+	}
+	for _, request := range template.RequestsHeadless {
+		headlessExecuter, err := r.newHeadlessExecuter(template, request, executers.newWriter(r.output))
+		if err != nil {
+			if p != nil {
+				p.Drop(request.GetRequestCount() * targets)
+			}
+			gologger.Warningf("Could not create executer for template '%s': %s\n", template.ID, err)
+			r.stats.TemplateFailed(template.ID, "could not create executer: "+err.Error())
+			continue
+		}
+		executers.headless = append(executers.headless, headlessExecuter)
+		executers.headlessRequests = append(executers.headlessRequests, request)
+	}
+	for _, request := range template.RequestsNetwork {
+		networkExecuter, err := r.newNetworkExecuter(template, request, executers.newWriter(r.output))
+		if err != nil {
+			if p != nil {
+				p.Drop(request.GetRequestCount() * targets)
+			}
+			gologger.Warningf("Could not create executer for template '%s': %s\n", template.ID, err)
+			r.stats.TemplateFailed(template.ID, "could not create executer: "+err.Error())
+			continue
+		}
+		executers.network = append(executers.network, networkExecuter)
+		executers.networkRequests = append(executers.networkRequests, request)
+	}
+	for _, request := range template.RequestsFile {
+		fileExecuter, err := r.newFileExecuter(template, request, executers.newWriter(r.output))
+		if err != nil {
+			if p != nil {
+				p.Drop(request.GetRequestCount() * targets)
+			}
+			gologger.Warningf("Could not create executer for template '%s': %s\n", template.ID, err)
+			r.stats.TemplateFailed(template.ID, "could not create executer: "+err.Error())
+			continue
+		}
+		executers.file = append(executers.file, fileExecuter)
+		executers.fileRequests = append(executers.fileRequests, request)
+	}
+	for _, request := range template.RequestsWebsocket {
+		websocketExecuter, err := r.newWebsocketExecuter(template, request, executers.newWriter(r.output))
+		if err != nil {
+			if p != nil {
+				p.Drop(request.GetRequestCount() * targets)
+			}
+			gologger.Warningf("Could not create executer for template '%s': %s\n", template.ID, err)
+			r.stats.TemplateFailed(template.ID, "could not create executer: "+err.Error())
+			continue
+		}
+		executers.websocket = append(executers.websocket, websocketExecuter)
+		executers.websocketRequests = append(executers.websocketRequests, request)
+	}
+	for _, request := range template.RequestsSSL {
+		sslExecuter, err := r.newSSLExecuter(template, request, executers.newWriter(r.output))
+		if err != nil {
+			if p != nil {
+				p.Drop(request.GetRequestCount() * targets)
+			}
+			gologger.Warningf("Could not create executer for template '%s': %s\n", template.ID, err)
+			r.stats.TemplateFailed(template.ID, "could not create executer: "+err.Error())
+			continue
+		}
+		executers.ssl = append(executers.ssl, sslExecuter)
+		executers.sslRequests = append(executers.sslRequests, request)
+	}
+	return executers
+}
+
+// newWriter returns a new writer of the output file if any, the executers
+// writing concurrently to their own writer.
+func (e *templateExecuters) newWriter(output *os.File) *bufio.Writer {
+	if output == nil {
+		return nil
+	}
+	writer := bufio.NewWriter(output)
+	e.writers = append(e.writers, writer)
+	return writer
+}
+
+// flush flushes the writers of the executers
+func (e *templateExecuters) flush() {
+	for _, writer := range e.writers {
+		writer.Flush()
+	}
+}
+
+// drop drops the requests of a target from the progress
+func (e *templateExecuters) drop(p *progress.Progress) {
+	if p == nil {
+		return
+	}
+	for _, request := range e.dnsRequests {
+		p.Drop(request.GetRequestCount())
+	}
+	e.dropHTTP(p)
+	e.dropHeadless(p)
+	for _, request := range e.networkRequests {
+		p.Drop(request.GetRequestCount())
+	}
+	for _, request := range e.fileRequests {
+		p.Drop(request.GetRequestCount())
+	}
+	e.dropWebsocket(p)
+	for _, request := range e.sslRequests {
+		p.Drop(request.GetRequestCount())
+	}
+}
+
+// dropHTTP drops the http requests of a target from the progress
+func (e *templateExecuters) dropHTTP(p *progress.Progress) {
+	if p == nil {
+		return
+	}
+	for _, request := range e.httpRequests {
+		p.Drop(request.GetRequestCount())
+	}
+}
+
+// dropHeadless drops the headless requests of a target from the progress
+func (e *templateExecuters) dropHeadless(p *progress.Progress) {
+	if p == nil {
+		return
+	}
+	for _, request := range e.headlessRequests {
+		p.Drop(request.GetRequestCount())
+	}
+}
+
+// dropWebsocket drops the websocket requests of a target from the progress
+func (e *templateExecuters) dropWebsocket(p *progress.Progress) {
+	if p == nil {
+		return
+	}
+	for _, request := range e.websocketRequests {
+		p.Drop(request.GetRequestCount())
+	}
+}
+
+// executeTemplate executes the dns requests of a template towards a target, then
+// its http requests with the values of the named extractors of the dns
+// requests, and returns the merged results of the requests along with the
+// first error. The headless and the websocket requests run towards the same
+// URL as the http ones, the network, the ssl and the file requests towards
+// the target as is, i.e a bare host:port or a local directory.
+// The requests are abandoned once the context is done.
+func (r *Runner) executeTemplate(ctx context.Context, p *progress.Progress, executers *templateExecuters, input string, values map[string]interface{}) executer.Result {
+	template := executers.template
+	result := executer.Result{
+		Matches:     make(map[string]interface{}),
+		Extractions: make(map[string]interface{}),
+	}
+
+	stageValues := values
+	if len(executers.dns) > 0 {
+		var dnsResults bool
+		stageValues = make(map[string]interface{}, len(values))
+		for name, value := range values {
+			stageValues[name] = value
+		}
+		for i, dnsExecuter := range executers.dns {
+			if !dnsApplies(input, executers.dnsRequests[i]) {
+				gologger.Debugf("[%s] Skipping dns request to %s, not a domain\n", template.ID, input)
+				if p != nil {
+					p.Drop(executers.dnsRequests[i].GetRequestCount())
+				}
+				continue
+			}
+			dnsResult := dnsExecuter.ExecuteDNSWithContext(ctx, p, input, values)
+			dnsResult.Error = r.runError(ctx, dnsResult.Error)
+			if skipped(dnsResult.Error) {
+				keepError(&result, &dnsResult)
+				continue
+			}
+			if dnsResult.Error != nil {
+				atomic.AddInt64(&r.dnsErrors, 1)
+				gologger.Warningf("Could not execute step: %s\n", dnsResult.Error)
+				r.recordError(input, dnsResult.Error)
+				keepError(&result, &dnsResult)
+				continue
+			}
+			dnsResults = dnsResults || dnsResult.GotResults
+			mergeResult(&result, &dnsResult)
+			// the first value of the named extractors is available to the http requests
+			for name, value := range dnsResult.Extractions {
+				if list, ok := value.([]string); ok && name != "" && len(list) > 0 {
+					stageValues[name] = list[0]
+				}
+			}
+		}
+		if template.RequiresAllProtocols() {
+			// the results of the dns requests are only the condition of the http ones
+			result.GotResults = false
+			if !dnsResults {
+				executers.dropHTTP(p)
+				return result
+			}
+		}
+	}
+
+	if len(executers.http) > 0 {
+		URL, err := r.resolveHTTPInput(input)
+		if err != nil || !hasScheme(URL) {
+			gologger.Debugf("[%s] Skipping http requests to %s, not an http target\n", template.ID, input)
+			executers.dropHTTP(p)
+			if err != nil {
+				r.recordError(input, err)
+				keepError(&result, &executer.Result{Error: err})
+			}
+			return result
+		}
+		for _, httpExecuter := range executers.http {
+			httpResult := httpExecuter.ExecuteHTTPWithContext(ctx, p, URL, stageValues)
+			httpResult.Error = r.runError(ctx, httpResult.Error)
+			if skipped(httpResult.Error) {
+				keepError(&result, &httpResult)
+				continue
+			}
+			if httpResult.Error != nil {
+				gologger.Warningf("Could not execute step: %s\n", httpResult.Error)
+				r.recordError(input, httpResult.Error)
+				keepError(&result, &httpResult)
+				continue
+			}
+			mergeResult(&result, &httpResult)
+		}
+	}
+
+	if len(executers.headless) > 0 {
+		URL, err := r.resolveHTTPInput(input)
+		if err != nil || !hasScheme(URL) {
+			gologger.Debugf("[%s] Skipping headless requests to %s, not an http target\n", template.ID, input)
+			executers.dropHeadless(p)
+			if err != nil {
+				r.recordError(input, err)
+				keepError(&result, &executer.Result{Error: err})
+			}
+			return result
+		}
+		for _, headlessExecuter := range executers.headless {
+			headlessResult := headlessExecuter.ExecuteHeadlessWithContext(ctx, p, URL, stageValues)
+			headlessResult.Error = r.runError(ctx, headlessResult.Error)
+			if skipped(headlessResult.Error) {
+				keepError(&result, &headlessResult)
+				continue
+			}
+			if headlessResult.Error != nil {
+				gologger.Warningf("Could not execute step: %s\n", headlessResult.Error)
+				r.recordError(input, headlessResult.Error)
+				keepError(&result, &headlessResult)
+				continue
+			}
+			mergeResult(&result, &headlessResult)
+		}
+	}
+
+	for _, networkExecuter := range executers.network {
+		networkResult := networkExecuter.ExecuteNetworkWithContext(ctx, p, input, stageValues)
+		networkResult.Error = r.runError(ctx, networkResult.Error)
+		if skipped(networkResult.Error) {
+			keepError(&result, &networkResult)
+			continue
+		}
+		if networkResult.Error != nil {
+			gologger.Warningf("Could not execute step: %s\n", networkResult.Error)
+			r.recordError(input, networkResult.Error)
+			keepError(&result, &networkResult)
+			continue
+		}
+		mergeResult(&result, &networkResult)
+	}
+
+	for _, sslExecuter := range executers.ssl {
+		sslResult := sslExecuter.ExecuteSSLWithContext(ctx, p, input, stageValues)
+		sslResult.Error = r.runError(ctx, sslResult.Error)
+		if skipped(sslResult.Error) {
+			keepError(&result, &sslResult)
+			continue
+		}
+		if sslResult.Error != nil {
+			gologger.Warningf("Could not execute step: %s\n", sslResult.Error)
+			r.recordError(input, sslResult.Error)
+			keepError(&result, &sslResult)
+			continue
+		}
+		mergeResult(&result, &sslResult)
+	}
+
+	for _, fileExecuter := range executers.file {
+		fileResult := fileExecuter.ExecuteFileWithContext(ctx, p, input, stageValues)
+		fileResult.Error = r.runError(ctx, fileResult.Error)
+		if skipped(fileResult.Error) {
+			keepError(&result, &fileResult)
+			continue
+		}
+		if fileResult.Error != nil {
+			gologger.Warningf("Could not execute step: %s\n", fileResult.Error)
+			r.recordError(input, fileResult.Error)
+			keepError(&result, &fileResult)
+			continue
+		}
+		mergeResult(&result, &fileResult)
+	}
+
+	if len(executers.websocket) > 0 {
+		URL, err := r.resolveHTTPInput(input)
+		if err != nil || !hasScheme(URL) {
+			gologger.Debugf("[%s] Skipping websocket requests to %s, not an http target\n", template.ID, input)
+			executers.dropWebsocket(p)
+			if err != nil {
+				r.recordError(input, err)
+				keepError(&result, &executer.Result{Error: err})
+			}
+			return result
+		}
+		for _, websocketExecuter := range executers.websocket {
+			websocketResult := websocketExecuter.ExecuteWebsocketWithContext(ctx, p, URL, stageValues)
+			websocketResult.Error = r.runError(ctx, websocketResult.Error)
+			if skipped(websocketResult.Error) {
+				keepError(&result, &websocketResult)
+				continue
+			}
+			if websocketResult.Error != nil {
+				gologger.Warningf("Could not execute step: %s\n", websocketResult.Error)
+				r.recordError(input, websocketResult.Error)
+				keepError(&result, &websocketResult)
+				continue
+			}
+			mergeResult(&result, &websocketResult)
+		}
+	}
+	return result
+}
+
+// mergeResult adds the results of a request to the result of a template
+func mergeResult(result, requestResult *executer.Result) {
+	if !requestResult.GotResults {
+		return
+	}
+	result.GotResults = true
+	for name, value := range requestResult.Matches {
+		result.Matches[name] = value
+	}
+	for name, value := range requestResult.Extractions {
+		result.Extractions[name] = value
+	}
+}
+
+// dnsApplies returns false for the ip address targets of the dns requests
+// not being PTR requests, the question being about a domain.
+func dnsApplies(input string, request *requests.DNSRequest) bool {
+	if request.IsPTR() {
+		return true
+	}
+	host := input
+	if hasScheme(input) {
+		if u, err := url.Parse(input); err == nil {
+			host = u.Hostname()
+		}
+	} else if h, _, err := net.SplitHostPort(input); err == nil {
+		host = h
+	}
+	return net.ParseIP(strings.Trim(host, "[]")) == nil
+}
+
+// newMultiProtocolJob creates the job of a template with both dns and http
+// requests, executing them in order towards each of the targets and adding
+// the results to the matcher statuses if any. nil is returned if none of
+// its executers could be created.
+func (r *Runner) newMultiProtocolJob(p *progress.Progress, template *templates.Template, statuses *matcherStatuses) *scanJob {
+	logLoadedTemplate(template)
+	for _, request := range template.RequestsDNS {
+		r.logEffectiveSettings(template, request)
+	}
+	for _, request := range template.BulkRequestsHTTP {
+		r.logEffectiveSettings(template, request)
+	}
+
+	executers := r.newTemplateExecuters(p, template, nil, r.inputCount)
+	if len(executers.dns)+len(executers.http) == 0 {
+		r.completeSteps(template.ID)
+		return nil
+	}
+
+	failures := &templateErrors{}
+	// the targets of the template are limited by its own threads too
+	job := newScanJob(template.ID, r.effectiveThreads(template))
+	for _, request := range executers.dnsRequests {
+		job.requests += request.GetRequestCount()
+	}
+	for _, request := range executers.httpRequests {
+		job.requests += request.GetRequestCount()
+	}
+	job.start = func(input string) func(ctx context.Context) {
+		if r.skipStep(template.ID, input) {
+			executers.drop(p)
+			return nil
+		}
+		return func(ctx context.Context) {
+			result := r.executeTemplate(ctx, p, executers, input, nil)
+			job.results.Or(result.GotResults)
+			if r.abandoned(ctx, template.ID, input, &result) {
+				return
+			}
+			// the step completes once the run is retried
+			if r.retry(job, input, result.Error) {
+				return
+			}
+			failures.add(result.Error)
+			statuses.add(input, &result)
+			r.completeStep(template.ID, input)
+		}
+	}
+	job.finish = func() {
+		r.recordTemplateErrors(template.ID, failures)
+		executers.flush()
+	}
+	return job
+}
@@ -132,7 +132,8 @@ func (r *Runner) updateTemplates() error {
 
 	// Check if last checked is more than 24 hours.
 	// If not, return since we don't want to do anything now.
-	if time.Now().Sub(r.templatesConfig.LastChecked) < 24*time.Hour && !r.options.UpdateTemplates {
+	// The dry run doesn't check without -update-templates.
+	if (time.Now().Sub(r.templatesConfig.LastChecked) < 24*time.Hour || r.options.DryRun) && !r.options.UpdateTemplates {
 		return nil
 	}
 
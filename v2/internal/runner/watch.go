@@ -0,0 +1,201 @@
+package runner
+
+import (
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/karrick/godirwalk"
+	"github.com/projectdiscovery/gologger"
+	"github.com/projectdiscovery/nuclei/v2/pkg/templates"
+	"github.com/projectdiscovery/nuclei/v2/pkg/workflows"
+)
+
+const (
+	// watchInterval is the interval between the checks of the watched templates
+	watchInterval = 500 * time.Millisecond
+	// watchDebounce is the time a changed template is left unchanged before
+	// rerunning it, the successive saves of an editor being run once.
+	watchDebounce = time.Second
+)
+
+// watchedFile is the state of a watched template file
+type watchedFile struct {
+	modTime time.Time
+	size    int64
+	// id is the id of the template indexed for the file if any
+	id string
+}
+
+// watch checks the templates of the user input for changes until
+// interrupted, rerunning the new and changed templates towards the
+// targets. The files are polled, the removed files releasing their ids.
+func (r *Runner) watch(paths []string) {
+	ids := make(map[string]string, len(r.templateIDs))
+	for id, path := range r.templateIDs {
+		ids[path] = id
+	}
+	files := make(map[string]*watchedFile, len(paths))
+	for _, path := range paths {
+		if info, err := os.Stat(path); err == nil {
+			files[path] = &watchedFile{modTime: info.ModTime(), size: info.Size(), id: ids[path]}
+		}
+	}
+	gologger.Labelf("Watching %d templates for changes, press Ctrl+C to stop\n", len(files))
+
+	// pending are the changed files by the time of their last change
+	pending := make(map[string]time.Time)
+	for range time.Tick(watchInterval) {
+		current := r.watchedPaths()
+		for path, file := range files {
+			if _, ok := current[path]; ok {
+				continue
+			}
+			if file.id != "" && r.templateIDs[file.id] == path {
+				delete(r.templateIDs, file.id)
+			}
+			delete(files, path)
+			delete(pending, path)
+			gologger.Infof("Template %s was removed\n", path)
+		}
+
+		now := time.Now()
+		for path, info := range current {
+			file, ok := files[path]
+			if !ok {
+				file = &watchedFile{}
+				files[path] = file
+			} else if file.modTime.Equal(info.ModTime()) && file.size == info.Size() {
+				continue
+			}
+			file.modTime, file.size = info.ModTime(), info.Size()
+			pending[path] = now
+		}
+
+		var changed []string
+		for path, last := range pending {
+			if now.Sub(last) >= watchDebounce {
+				changed = append(changed, path)
+			}
+		}
+		sort.Strings(changed)
+		for _, path := range changed {
+			delete(pending, path)
+			r.rerunTemplate(path, files[path])
+		}
+	}
+}
+
+// watchedPaths returns the template files of the user input along with
+// their info, without logging like the initial discovery.
+func (r *Runner) watchedPaths() map[string]os.FileInfo {
+	var paths []string
+	for _, input := range r.options.Templates {
+		// the remote templates are only fetched once
+		if isRemoteTemplate(input) {
+			continue
+		}
+		if strings.Contains(input, "*") {
+			directory, pattern := filepath.Split(input)
+			resolved, err := r.resolvePathIfRelative(strings.TrimSuffix(directory, "/"))
+			if err != nil {
+				continue
+			}
+			matches, _ := filepath.Glob(filepath.Join(resolved, pattern))
+			paths = append(paths, matches...)
+			continue
+		}
+		path, err := r.resolvePathIfRelative(input)
+		if err != nil {
+			continue
+		}
+		if isFile, err := isFilePath(path); err != nil || isFile {
+			paths = append(paths, path)
+			continue
+		}
+		godirwalk.Walk(path, &godirwalk.Options{
+			Callback: func(path string, d *godirwalk.Dirent) error {
+				if !d.IsDir() && strings.HasSuffix(path, ".yaml") {
+					paths = append(paths, path)
+				}
+				return nil
+			},
+			ErrorCallback: func(path string, err error) godirwalk.ErrorAction {
+				return godirwalk.SkipNode
+			},
+			Unsorted: true,
+		})
+	}
+
+	current := make(map[string]os.FileInfo, len(paths))
+	for _, path := range paths {
+		if info, err := os.Stat(path); err == nil {
+			current[path] = info
+		}
+	}
+	return current
+}
+
+// rerunTemplate reports the problems of a changed template and runs it if it
+// loads, updating the index of the ids if the id of the template changed.
+func (r *Runner) rerunTemplate(path string, file *watchedFile) {
+	if r.excludes != nil && r.excludes.match(path, "") != "" {
+		return
+	}
+	_, problems := r.validateFile(path)
+	for _, problem := range problems {
+		writeProblem(path, problem)
+	}
+	t, err := r.parse(path)
+	if err != nil {
+		if len(problems) == 0 {
+			gologger.Errorf("Could not parse file '%s': %s\n", path, err)
+		}
+		return
+	}
+
+	var id string
+	switch t := t.(type) {
+	case *templates.Template:
+		id = t.ID
+		if r.filter != nil {
+			if ok, reason := r.filter.Match(&t.Info); !ok {
+				gologger.Infof("Template %s was filtered out by %s\n", path, reason)
+				return
+			}
+		}
+		if reason := r.protocolSkipReason(t); reason != "" {
+			gologger.Infof("Template %s was filtered out by %s\n", path, reason)
+			return
+		}
+	case *workflows.Workflow:
+		id = t.ID
+		r.checkWorkflowMembers(t, t.Workflows)
+	}
+	if r.excludes != nil {
+		if rule := r.excludes.match("", id); rule != "" {
+			gologger.Infof("Template %s was excluded by %s\n", path, rule)
+			return
+		}
+	}
+
+	if file.id != id && file.id != "" {
+		if r.templateIDs[file.id] == path {
+			delete(r.templateIDs, file.id)
+		}
+		gologger.Infof("Template %s changed its id from %s to %s\n", path, file.id, id)
+	}
+	file.id = ""
+	if previous, ok := r.templateIDs[id]; ok && previous != path {
+		gologger.Labelf("Skipping template '%s', its id %s is already used by '%s'\n", path, id, previous)
+		return
+	}
+	r.templateIDs[id] = path
+	file.id = id
+
+	gologger.Labelf("Running changed template %s\n", path)
+	r.executeParsed(nil, t, nil)
+	r.runRetries(nil)
+}
@@ -0,0 +1,106 @@
+package runner
+
+import (
+	"context"
+	"net/http/cookiejar"
+
+	"github.com/projectdiscovery/gologger"
+	"github.com/projectdiscovery/nuclei/v2/internal/progress"
+	"github.com/projectdiscovery/nuclei/v2/pkg/executer"
+	"github.com/projectdiscovery/nuclei/v2/pkg/generators"
+	"github.com/projectdiscovery/nuclei/v2/pkg/templates"
+	"github.com/projectdiscovery/nuclei/v2/pkg/workflows"
+)
+
+// workflowRun contains the state of the execution of a workflow towards a target
+type workflowRun struct {
+	// ctx abandons the run once done
+	ctx      context.Context
+	workflow *workflows.Workflow
+	URL      string
+	jar      *cookiejar.Jar
+}
+
+// processWorkflowTemplates runs workflow templates towards the target of a
+// run, running the subtemplates of the matching ones with their named
+// extracted values, and returns true if any template got results. The
+// conditions of the workflow templates are evaluated on all the values of
+// the named extractors of the parent template.
+func (r *Runner) processWorkflowTemplates(p *progress.Progress, run *workflowRun, workflowTemplates []*workflows.WorkflowTemplate, values map[string]interface{}, extractions map[string][]string) bool {
+	var gotResults bool
+	for _, workflowTemplate := range workflowTemplates {
+		if run.ctx.Err() != nil {
+			break
+		}
+		if !r.conditionHolds(run, workflowTemplate, extractions) {
+			continue
+		}
+		matched := false
+		matches := make(map[string]struct{})
+		extracted := generators.CopyMap(values)
+		lists := make(map[string][]string)
+		for i, template := range workflowTemplate.Templates {
+			if r.filteredMember(run.workflow, workflowTemplate.Paths[i], template) {
+				continue
+			}
+			result := r.executeWorkflowTemplate(p, run, template, values)
+			if !result.GotResults {
+				continue
+			}
+			matched = true
+			for name := range result.Matches {
+				matches[name] = struct{}{}
+			}
+			// the first value of the named extractors is available to the subtemplates
+			for name, value := range result.Extractions {
+				if list, ok := value.([]string); ok && name != "" && len(list) > 0 {
+					extracted[name] = list[0]
+					lists[name] = append(lists[name], list...)
+				}
+			}
+		}
+		if !matched {
+			continue
+		}
+		gotResults = true
+
+		if r.processWorkflowTemplates(p, run, workflowTemplate.Subtemplates, extracted, lists) {
+			gotResults = true
+		}
+		for _, matcher := range workflowTemplate.Matchers {
+			if _, ok := matches[matcher.Name]; !ok {
+				continue
+			}
+			if r.processWorkflowTemplates(p, run, matcher.Subtemplates, extracted, lists) {
+				gotResults = true
+			}
+		}
+	}
+	return gotResults
+}
+
+// conditionHolds returns true if the condition of a workflow template holds
+// for the values extracted by its parent, logging why it's skipped otherwise.
+func (r *Runner) conditionHolds(run *workflowRun, workflowTemplate *workflows.WorkflowTemplate, extractions map[string][]string) bool {
+	holds, missing, err := workflowTemplate.EvaluateCondition(extractions)
+	switch {
+	case err != nil:
+		gologger.Warningf("[%s] Could not evaluate condition of %s for %s: %s\n", run.workflow.ID, workflowTemplate.Template, run.URL, err)
+	case missing != "":
+		gologger.Debugf("[%s] Skipping %s for %s, %s was not extracted\n", run.workflow.ID, workflowTemplate.Template, run.URL, missing)
+	case !holds:
+		gologger.Debugf("[%s] Skipping %s for %s, its condition doesn't hold\n", run.workflow.ID, workflowTemplate.Template, run.URL)
+	}
+	return holds
+}
+
+// executeWorkflowTemplate executes the requests of a template of a workflow
+// towards the target, adding them to the progress total as they are run.
+func (r *Runner) executeWorkflowTemplate(p *progress.Progress, run *workflowRun, template *templates.Template, values map[string]interface{}) executer.Result {
+	if p != nil {
+		p.AddToTotal(template.GetHTTPRequestCount() + template.GetDNSRequestCount() + template.GetHeadlessRequestCount() + template.GetNetworkRequestCount() + template.GetFileRequestCount() + template.GetWebsocketRequestCount() + template.GetSSLRequestCount())
+	}
+	executers := r.newTemplateExecuters(p, template, run.jar, 1)
+	defer executers.flush()
+	return r.executeTemplate(run.ctx, p, executers, run.URL, values)
+}
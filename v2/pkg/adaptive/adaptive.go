@@ -0,0 +1,340 @@
+package adaptive
+
+import (
+	"context"
+	"sync"
+	"time"
+)
+
+// Default settings of the controller
+const (
+	DefaultMinConcurrency = 1
+	DefaultWindow         = 20
+	DefaultBackoffRate    = 0.2
+	DefaultRecoverRate    = 0.05
+	DefaultMaxDelay       = 2 * time.Second
+)
+
+// initialDelay is the delay between the requests to a host backed off for
+// the first time, doubled on each backoff up to the maximum delay.
+const initialDelay = 100 * time.Millisecond
+
+// Options are the settings of a controller
+type Options struct {
+	// MinConcurrency and MaxConcurrency are the bounds of the number of
+	// requests in flight to each host and to all of them.
+	MinConcurrency int
+	MaxConcurrency int
+	// Window is the number of the last requests the error rates are
+	// computed on.
+	Window int
+	// BackoffRate is the error rate halving the concurrency and doubling
+	// the delay, RecoverRate the one below which the concurrency is
+	// increased by one and the delay halved, once per window.
+	BackoffRate float64
+	RecoverRate float64
+	// MaxDelay is the maximum delay between the requests to a host
+	MaxDelay time.Duration
+}
+
+// Adjustment is a change of the concurrency of a host or of all of them
+type Adjustment struct {
+	// Host is the host adjusted, empty for the global concurrency
+	Host        string
+	Concurrency int
+	Delay       time.Duration
+	// ErrorRate is the error rate of the window triggering the adjustment
+	ErrorRate float64
+	Backoff   bool
+	// Global and Throttled are the global concurrency and the number of
+	// hosts below the maximum concurrency or delayed after the adjustment.
+	Global    int
+	Throttled int
+}
+
+// state is the concurrency of a host or of all of them along with the
+// outcomes of their last requests.
+type state struct {
+	limit    int
+	inFlight int
+	delay    time.Duration
+	// next is the time the next request to the host can start at
+	next time.Time
+
+	// outcomes is a ring of the last outcomes, true for the errors
+	outcomes []bool
+	position int
+	count    int
+	errors   int
+}
+
+// add records the outcome of a request, replacing the oldest of a full window
+func (s *state) add(failed bool) {
+	if s.count == len(s.outcomes) {
+		if s.outcomes[s.position] {
+			s.errors--
+		}
+	} else {
+		s.count++
+	}
+	s.outcomes[s.position] = failed
+	if failed {
+		s.errors++
+	}
+	s.position = (s.position + 1) % len(s.outcomes)
+}
+
+// reset forgets the outcomes, the next adjustment waiting for a full window
+func (s *state) reset() {
+	s.position, s.count, s.errors = 0, 0, 0
+}
+
+// Controller limits the requests in flight to each host and to all of them,
+// an AIMD controller adjusting the limits and the delays between the
+// requests to each host to the error rates of their last requests. The
+// hosts are tracked from their first request until they are back to the
+// maximum concurrency without errors. The methods of a nil controller do
+// nothing, the requests not being limited.
+type Controller struct {
+	options Options
+	// adjusted is called with each adjustment if not nil
+	adjusted func(adjustment Adjustment)
+
+	mutex     sync.Mutex
+	global    *state
+	hosts     map[string]*state
+	throttled int
+	// changed is closed and replaced when a request completes or a limit
+	// is adjusted, waking up the requests waiting for a slot.
+	changed chan struct{}
+}
+
+// New returns a controller with settings, calling adjusted with each
+// adjustment if not nil.
+func New(options Options, adjusted func(adjustment Adjustment)) *Controller {
+	c := &Controller{
+		options:  options,
+		adjusted: adjusted,
+		hosts:    make(map[string]*state),
+		changed:  make(chan struct{}),
+	}
+	c.global = c.newState()
+	return c
+}
+
+// newState returns the state of a host at the maximum concurrency
+func (c *Controller) newState() *state {
+	return &state{limit: c.options.MaxConcurrency, outcomes: make([]bool, c.options.Window)}
+}
+
+// Acquire waits until a request can be sent to a host, below the limits of
+// the host and of all the hosts and after the delay of the host, returning
+// the error of the context if it is done first. Each acquired request must
+// be released.
+func (c *Controller) Acquire(ctx context.Context, host string) error {
+	if c == nil {
+		return nil
+	}
+	for {
+		c.mutex.Lock()
+		h, ok := c.hosts[host]
+		if !ok {
+			h = c.newState()
+			c.hosts[host] = h
+		}
+		if h.inFlight < h.limit && c.global.inFlight < c.global.limit {
+			h.inFlight++
+			c.global.inFlight++
+			now := time.Now()
+			start := h.next
+			if start.Before(now) {
+				start = now
+			}
+			h.next = start.Add(h.delay)
+			c.mutex.Unlock()
+			return c.wait(ctx, host, start.Sub(now))
+		}
+		changed := c.changed
+		c.mutex.Unlock()
+
+		select {
+		case <-changed:
+		case <-ctx.Done():
+			return ctx.Err()
+		}
+	}
+}
+
+// wait waits for the delay of an acquired request, its slot being released
+// without an outcome if the context is done first.
+func (c *Controller) wait(ctx context.Context, host string, delay time.Duration) error {
+	if delay <= 0 {
+		return nil
+	}
+	timer := time.NewTimer(delay)
+	defer timer.Stop()
+	select {
+	case <-timer.C:
+		return nil
+	case <-ctx.Done():
+		c.mutex.Lock()
+		c.complete(host)
+		c.mutex.Unlock()
+		c.notify()
+		return ctx.Err()
+	}
+}
+
+// Release releases a request to a host, failed being true if it failed
+// with a network error, adjusting the limits on the error rates of the last
+// requests.
+func (c *Controller) Release(host string, failed bool) {
+	if c == nil {
+		return
+	}
+	var adjustments []Adjustment
+	c.mutex.Lock()
+	h := c.complete(host)
+	if h != nil {
+		if adjustment, ok := c.record(host, h, failed); ok {
+			adjustments = append(adjustments, adjustment)
+		}
+	}
+	if adjustment, ok := c.record("", c.global, failed); ok {
+		adjustments = append(adjustments, adjustment)
+	}
+	if h != nil && h.inFlight == 0 && h.errors == 0 && !c.isThrottled(h) {
+		delete(c.hosts, host)
+	}
+	c.mutex.Unlock()
+
+	c.notify()
+	if c.adjusted != nil {
+		for _, adjustment := range adjustments {
+			c.adjusted(adjustment)
+		}
+	}
+}
+
+// complete removes a request from the requests in flight to a host,
+// returning the state of the host.
+func (c *Controller) complete(host string) *state {
+	c.global.inFlight--
+	h, ok := c.hosts[host]
+	if !ok {
+		return nil
+	}
+	h.inFlight--
+	return h
+}
+
+// record records the outcome of a request to a host, or to all of them for
+// an empty host, returning the adjustment of its limit if any. The limit is
+// halved and the delay doubled once the error rate of a full window reaches
+// the backoff rate, and the limit is increased by one and the delay halved
+// once the error rate of a full window is below the recover rate.
+func (c *Controller) record(host string, s *state, failed bool) (Adjustment, bool) {
+	s.add(failed)
+	if s.count < len(s.outcomes) {
+		return Adjustment{}, false
+	}
+	rate := float64(s.errors) / float64(s.count)
+	throttled := c.isThrottled(s)
+
+	var backoff bool
+	switch {
+	case rate >= c.options.BackoffRate:
+		limit := s.limit / 2
+		if limit < c.options.MinConcurrency {
+			limit = c.options.MinConcurrency
+		}
+		// only the requests to each host are delayed
+		delay := s.delay
+		if host != "" {
+			if delay *= 2; delay == 0 {
+				delay = initialDelay
+			}
+			if delay > c.options.MaxDelay {
+				delay = c.options.MaxDelay
+			}
+		}
+		if limit == s.limit && delay == s.delay {
+			s.reset()
+			return Adjustment{}, false
+		}
+		s.limit, s.delay, backoff = limit, delay, true
+	case rate < c.options.RecoverRate && throttled:
+		if s.limit < c.options.MaxConcurrency {
+			s.limit++
+		}
+		if s.delay /= 2; s.delay < initialDelay {
+			s.delay = 0
+		}
+	default:
+		return Adjustment{}, false
+	}
+	s.reset()
+
+	if host != "" && throttled != c.isThrottled(s) {
+		if throttled {
+			c.throttled--
+		} else {
+			c.throttled++
+		}
+	}
+	return Adjustment{
+		Host:        host,
+		Concurrency: s.limit,
+		Delay:       s.delay,
+		ErrorRate:   rate,
+		Backoff:     backoff,
+		Global:      c.global.limit,
+		Throttled:   c.throttled,
+	}, true
+}
+
+// isThrottled returns true if a state is below the maximum concurrency or
+// delayed.
+func (c *Controller) isThrottled(s *state) bool {
+	return s.limit < c.options.MaxConcurrency || s.delay > 0
+}
+
+// notify wakes up the requests waiting for a slot
+func (c *Controller) notify() {
+	c.mutex.Lock()
+	close(c.changed)
+	c.changed = make(chan struct{})
+	c.mutex.Unlock()
+}
+
+// Concurrency returns the maximum number of requests in flight to all the
+// hosts, 0 for a nil controller.
+func (c *Controller) Concurrency() int {
+	if c == nil {
+		return 0
+	}
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+	return c.global.limit
+}
+
+// Throttled returns the number of hosts below the maximum concurrency or
+// delayed.
+func (c *Controller) Throttled() int {
+	if c == nil {
+		return 0
+	}
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+	return c.throttled
+}
+
+// Len returns the number of hosts tracked
+func (c *Controller) Len() int {
+	if c == nil {
+		return 0
+	}
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+	return len(c.hosts)
+}
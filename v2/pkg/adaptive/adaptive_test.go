@@ -0,0 +1,91 @@
+package adaptive
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+func newController(adjusted func(Adjustment)) *Controller {
+	return New(Options{MinConcurrency: 1, MaxConcurrency: 8, Window: 4, BackoffRate: 0.5, RecoverRate: 0.1, MaxDelay: 150 * time.Millisecond}, adjusted)
+}
+
+func TestBackoff(t *testing.T) {
+	var adjustments []Adjustment
+	c := newController(func(adjustment Adjustment) {
+		adjustments = append(adjustments, adjustment)
+	})
+	run := func(host string, failed bool) {
+		require.Nil(t, c.Acquire(context.Background(), host), "Could not acquire a request")
+		c.Release(host, failed)
+	}
+
+	for i := 0; i < 4; i++ {
+		run("a.example.com:443", i%2 == 0)
+	}
+	require.Len(t, adjustments, 2, "Could not back off the host and the global concurrency")
+	require.Equal(t, Adjustment{Host: "a.example.com:443", Concurrency: 4, Delay: 100 * time.Millisecond, ErrorRate: 0.5, Backoff: true, Global: 8, Throttled: 1}, adjustments[0], "Could not back off the host")
+	require.Equal(t, Adjustment{Concurrency: 4, ErrorRate: 0.5, Backoff: true, Global: 4, Throttled: 1}, adjustments[1], "Could not back off the global concurrency")
+	require.Equal(t, 4, c.Concurrency(), "Could not halve the global concurrency")
+
+	// the requests to the host are spaced by its delay
+	start := time.Now()
+	run("a.example.com:443", true)
+	run("a.example.com:443", true)
+	require.True(t, time.Since(start) >= 100*time.Millisecond, "Could not delay the requests to the host")
+
+	// the requests to the other hosts are not delayed
+	start = time.Now()
+	run("b.example.com:443", false)
+	run("b.example.com:443", false)
+	require.True(t, time.Since(start) < 100*time.Millisecond, "Could delay the requests to another host")
+}
+
+func TestRecover(t *testing.T) {
+	var adjustments []Adjustment
+	c := newController(func(adjustment Adjustment) {
+		if adjustment.Host != "" {
+			adjustments = append(adjustments, adjustment)
+		}
+	})
+	host := "a.example.com:443"
+	c.hosts[host] = &state{limit: 2, delay: 100 * time.Millisecond, outcomes: make([]bool, 4)}
+	c.throttled = 1
+
+	for i := 0; i < 8; i++ {
+		c.mutex.Lock()
+		c.hosts[host].inFlight++
+		c.global.inFlight++
+		c.mutex.Unlock()
+		c.Release(host, false)
+	}
+	require.Len(t, adjustments, 2, "Could not ramp up once per window")
+	require.Equal(t, 3, adjustments[0].Concurrency, "Could not increase the concurrency by one")
+	require.Zero(t, adjustments[0].Delay, "Could not halve the delay")
+	require.Equal(t, 4, adjustments[1].Concurrency, "Could not increase the concurrency again")
+	require.Equal(t, 1, c.Throttled(), "Could not keep the host throttled below the maximum")
+}
+
+func TestLimits(t *testing.T) {
+	c := New(Options{MinConcurrency: 1, MaxConcurrency: 1, Window: 4, BackoffRate: 0.5, RecoverRate: 0.1}, nil)
+	require.Nil(t, c.Acquire(context.Background(), "a.example.com:443"), "Could not acquire a request")
+
+	ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+	require.Equal(t, context.DeadlineExceeded, c.Acquire(ctx, "b.example.com:443"), "Could not wait for the global limit")
+
+	acquired := make(chan error)
+	go func() {
+		acquired <- c.Acquire(context.Background(), "b.example.com:443")
+	}()
+	c.Release("a.example.com:443", false)
+	require.Nil(t, <-acquired, "Could not acquire the released slot")
+	c.Release("b.example.com:443", false)
+	require.Zero(t, c.Len(), "Could not forget the hosts without errors")
+
+	var nilController *Controller
+	require.Nil(t, nilController.Acquire(context.Background(), "a.example.com:443"), "Could not ignore nil controller")
+	nilController.Release("a.example.com:443", true)
+}
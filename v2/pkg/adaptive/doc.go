@@ -0,0 +1,4 @@
+// Package adaptive adapts the concurrency of the requests of a scan to the
+// network errors of the hosts, backing off the hosts whose timeout and
+// connection error rates rise and ramping them back up once they subside.
+package adaptive
@@ -0,0 +1,215 @@
+package auth
+
+import (
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"path/filepath"
+	"strings"
+
+	"github.com/projectdiscovery/nuclei/v2/pkg/matchers"
+	"gopkg.in/yaml.v2"
+)
+
+// defaultMaxReauth is the default number of times the session of a host is
+// established again
+const defaultMaxReauth = 3
+
+// Config is the configuration of the sessions, read from a yaml file
+type Config struct {
+	// Template is the login template file, relative to the config, whose
+	// http requests log in to each host.
+	Template string `yaml:"template,omitempty"`
+	// Requests are the login requests in the syntax of the http requests of
+	// the templates, instead of a template.
+	Requests []interface{} `yaml:"requests,omitempty"`
+	// Variables are the values of the login requests, i.e the username and
+	// the password, overriding the variables of the template. The secrets
+	// are better read from the environment, i.e {{env("APP_PASSWORD")}},
+	// not to be written to the output.
+	Variables yaml.MapSlice `yaml:"variables,omitempty"`
+	// Headers are the headers of the session, their placeholders being
+	// replaced with the values of the named extractors of the login
+	// requests and the variables, i.e Authorization: Bearer {{token}}.
+	Headers map[string]string `yaml:"headers,omitempty"`
+	// Cookies adds the cookies set by the login responses to the session,
+	// true by default.
+	Cookies *bool `yaml:"cookies,omitempty"`
+	// Reauth detects the responses of an expired session if any
+	Reauth *Reauth `yaml:"reauth,omitempty"`
+
+	// file is the config file the template is relative to
+	file string
+}
+
+// Reauth are the matchers of the responses of an expired session, i.e a
+// redirect to /login, the session being established again and the request
+// sent again with the new one.
+type Reauth struct {
+	// MatchersCondition is the condition between the matchers, or by default
+	MatchersCondition string `yaml:"matchers-condition,omitempty"`
+	// Matchers are the matchers of the http responses of the templates
+	Matchers []*matchers.Matcher `yaml:"matchers"`
+	// MaxReauth is the number of times the session of a host is established
+	// again, 3 by default, the session being kept as is afterwards.
+	MaxReauth int `yaml:"max-reauth,omitempty"`
+
+	condition matchers.ConditionType
+}
+
+// LoadConfig loads the configuration of the sessions from a yaml file
+func LoadConfig(file string) (*Config, error) {
+	data, err := ioutil.ReadFile(file)
+	if err != nil {
+		return nil, fmt.Errorf("could not read auth config: %s", err)
+	}
+	config := &Config{file: file}
+	if err := yaml.UnmarshalStrict(data, config); err != nil {
+		return nil, fmt.Errorf("could not parse auth config %s: %s", file, err)
+	}
+	if err := config.validate(); err != nil {
+		return nil, fmt.Errorf("invalid auth config %s: %s", file, err)
+	}
+	return config, nil
+}
+
+// validate validates the configuration, compiling the matchers and setting
+// the defaults
+func (c *Config) validate() error {
+	if (c.Template == "") == (len(c.Requests) == 0) {
+		return errors.New("either a login template or login requests should be given")
+	}
+	if c.Template != "" && !filepath.IsAbs(c.Template) {
+		c.Template = filepath.Join(filepath.Dir(c.file), c.Template)
+	}
+	for name, value := range c.Headers {
+		if strings.TrimSpace(name) == "" || strings.ContainsAny(name, ":\r\n") || strings.ContainsAny(value, "\r\n") {
+			return fmt.Errorf("invalid header %q", name)
+		}
+	}
+	if c.Cookies == nil {
+		cookies := true
+		c.Cookies = &cookies
+	}
+	if !*c.Cookies && len(c.Headers) == 0 {
+		return errors.New("the session has neither cookies nor headers")
+	}
+
+	if c.Reauth == nil {
+		return nil
+	}
+	if len(c.Reauth.Matchers) == 0 {
+		return errors.New("reauth has no matchers")
+	}
+	condition, ok := matchers.ConditionTypes[c.Reauth.MatchersCondition]
+	if !ok && c.Reauth.MatchersCondition != "" {
+		return fmt.Errorf("invalid reauth matchers-condition %s", c.Reauth.MatchersCondition)
+	}
+	if !ok {
+		condition = matchers.ORCondition
+	}
+	c.Reauth.condition = condition
+	for i, matcher := range c.Reauth.Matchers {
+		if err := matcher.CompileMatchers(); err != nil {
+			return fmt.Errorf("could not compile reauth matcher %d: %s", i, err)
+		}
+		if matcher.Internal || matcher.Type == "similarity" {
+			return fmt.Errorf("reauth matcher %d can't be internal or a similarity matcher", i)
+		}
+	}
+	if err := matchers.ValidateNegative(c.Reauth.Matchers, condition); err != nil {
+		return fmt.Errorf("reauth: %s", err)
+	}
+	if c.Reauth.MaxReauth < 0 {
+		return fmt.Errorf("invalid max-reauth %d", c.Reauth.MaxReauth)
+	}
+	if c.Reauth.MaxReauth == 0 {
+		c.Reauth.MaxReauth = defaultMaxReauth
+	}
+	return nil
+}
+
+// LoginTemplate returns the file and the yaml of the login template, the
+// variables of the config overriding the ones of the template. The login
+// template file is checked as read, i.e its signature, the inline requests
+// being the ones of the config file.
+func (c *Config) LoginTemplate(check func(file string, data []byte) error) (string, []byte, error) {
+	if c.Template == "" {
+		document := yaml.MapSlice{
+			{Key: "id", Value: "auth"},
+			{Key: "info", Value: yaml.MapSlice{{Key: "name", Value: "Login of " + filepath.Base(c.file)}, {Key: "author", Value: "nuclei"}}},
+		}
+		if len(c.Variables) > 0 {
+			document = append(document, yaml.MapItem{Key: "variables", Value: c.Variables})
+		}
+		document = append(document, yaml.MapItem{Key: "requests", Value: c.Requests})
+		data, err := yaml.Marshal(document)
+		return c.file, data, err
+	}
+
+	data, err := ioutil.ReadFile(c.Template)
+	if err != nil {
+		return "", nil, fmt.Errorf("could not read login template: %s", err)
+	}
+	if check != nil {
+		if err := check(c.Template, data); err != nil {
+			return "", nil, err
+		}
+	}
+	if len(c.Variables) == 0 {
+		return c.Template, data, nil
+	}
+	var document yaml.MapSlice
+	if err := yaml.Unmarshal(data, &document); err != nil {
+		return "", nil, fmt.Errorf("could not parse login template %s: %s", c.Template, err)
+	}
+	index := -1
+	for i, item := range document {
+		if item.Key == "variables" {
+			index = i
+		}
+	}
+	if index < 0 {
+		document = append(document, yaml.MapItem{Key: "variables", Value: c.Variables})
+	} else {
+		variables, _ := document[index].Value.(yaml.MapSlice)
+		document[index].Value = mergeVariables(variables, c.Variables)
+	}
+	data, err = yaml.Marshal(document)
+	return c.Template, data, err
+}
+
+// mergeVariables returns the variables of a template with the ones of the
+// config, which override them
+func mergeVariables(variables, overrides yaml.MapSlice) yaml.MapSlice {
+	merged := make(yaml.MapSlice, 0, len(variables)+len(overrides))
+	for _, variable := range variables {
+		overridden := false
+		for _, override := range overrides {
+			overridden = overridden || override.Key == variable.Key
+		}
+		if !overridden {
+			merged = append(merged, variable)
+		}
+	}
+	return append(merged, overrides...)
+}
+
+// Expired returns true if an http response to a request of a session
+// matches the reauth matchers, the session having expired.
+func (c *Config) Expired(resp *http.Response, body, headers string) bool {
+	if c.Reauth == nil {
+		return false
+	}
+	for _, matcher := range c.Reauth.Matchers {
+		matched := matcher.Match(resp, body, headers, 0, nil, "", nil)
+		if matched && c.Reauth.condition == matchers.ORCondition {
+			return true
+		}
+		if !matched && c.Reauth.condition == matchers.ANDCondition {
+			return false
+		}
+	}
+	return c.Reauth.condition == matchers.ANDCondition
+}
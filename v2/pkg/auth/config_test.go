@@ -0,0 +1,82 @@
+package auth
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+	"gopkg.in/yaml.v2"
+)
+
+func writeConfig(t *testing.T, directory, name, content string) string {
+	file := filepath.Join(directory, name)
+	require.Nil(t, ioutil.WriteFile(file, []byte(content), 0644), "Could not write config")
+	return file
+}
+
+func TestLoadConfig(t *testing.T) {
+	directory, err := ioutil.TempDir("", "auth-*")
+	require.Nil(t, err, "Could not create temporary directory")
+	defer os.RemoveAll(directory)
+
+	config, err := LoadConfig(writeConfig(t, directory, "auth.yaml", "template: login.yaml\nheaders:\n  Authorization: Bearer {{token}}\nreauth:\n  matchers:\n    - type: status\n      status: [401]\n"))
+	require.Nil(t, err, "Could not load config")
+	require.Equal(t, filepath.Join(directory, "login.yaml"), config.Template, "Could not resolve the template from the config")
+	require.True(t, *config.Cookies, "Could not set the default cookies")
+	require.Equal(t, defaultMaxReauth, config.Reauth.MaxReauth, "Could not set the default max-reauth")
+
+	for _, invalid := range []string{
+		"headers:\n  Authorization: Bearer {{token}}\n",
+		"template: login.yaml\nrequests:\n  - path: ['{{BaseURL}}/login']\n",
+		"template: login.yaml\ncookies: false\n",
+		"template: login.yaml\nheaders:\n  'X-Token:': '{{token}}'\n",
+		"template: login.yaml\nreauth:\n  matchers: []\n",
+		"template: login.yaml\nreauth:\n  matchers-condition: xor\n  matchers:\n    - type: status\n      status: [401]\n",
+		"template: login.yaml\nreauth:\n  matchers:\n    - type: words\n",
+		"template: login.yaml\nreauth:\n  matchers:\n    - type: word\n      words: [login]\n      negative: true\n",
+		"template: login.yaml\nsession: true\n",
+	} {
+		_, err = LoadConfig(writeConfig(t, directory, "auth.yaml", invalid))
+		require.NotNil(t, err, "Could not reject invalid config %q", invalid)
+	}
+}
+
+func TestLoginTemplate(t *testing.T) {
+	directory, err := ioutil.TempDir("", "auth-*")
+	require.Nil(t, err, "Could not create temporary directory")
+	defer os.RemoveAll(directory)
+
+	// the inline requests are wrapped into a template with the variables
+	config, err := LoadConfig(writeConfig(t, directory, "inline.yaml", "requests:\n  - method: POST\n    path: ['{{BaseURL}}/login']\n    body: user={{username}}\n    extractors:\n      - type: regex\n        name: token\n        regex: ['[a-z0-9]+']\nvariables:\n  username: admin\n"))
+	require.Nil(t, err, "Could not load config")
+	file, data, err := config.LoginTemplate(nil)
+	require.Nil(t, err, "Could not get the login template")
+	require.Equal(t, filepath.Join(directory, "inline.yaml"), file, "Could not use the config as the file of the inline requests")
+	var document struct {
+		ID        string                   `yaml:"id"`
+		Variables map[string]string        `yaml:"variables"`
+		Requests  []map[string]interface{} `yaml:"requests"`
+	}
+	require.Nil(t, yaml.Unmarshal(data, &document), "Could not parse the login template")
+	require.Equal(t, "auth", document.ID, "Could not set the id of the login template")
+	require.Equal(t, map[string]string{"username": "admin"}, document.Variables, "Could not add the variables")
+	require.Equal(t, "POST", document.Requests[0]["method"], "Could not add the requests")
+
+	// the variables of the config override the ones of the template, which
+	// is checked as read
+	writeConfig(t, directory, "login.yaml", "id: login\nvariables:\n  username: guest\n  password: guest\nrequests: []\n")
+	config, err = LoadConfig(writeConfig(t, directory, "auth.yaml", "template: login.yaml\nvariables:\n  username: admin\n"))
+	require.Nil(t, err, "Could not load config")
+	var checked string
+	_, data, err = config.LoginTemplate(func(file string, data []byte) error {
+		checked = file
+		return nil
+	})
+	require.Nil(t, err, "Could not get the login template")
+	require.Equal(t, filepath.Join(directory, "login.yaml"), checked, "Could not check the login template")
+	document.Variables = nil
+	require.Nil(t, yaml.Unmarshal(data, &document), "Could not parse the login template")
+	require.Equal(t, map[string]string{"username": "admin", "password": "guest"}, document.Variables, "Could not override the variables of the template")
+}
@@ -0,0 +1,6 @@
+// Package auth establishes the sessions of the hosts before a scan, logging
+// in to each host once with the http requests of a login template and
+// storing the cookies and headers of the session, which the http requests
+// of the templates are sent with. The expired sessions are detected with
+// the matchers of the config and established again.
+package auth
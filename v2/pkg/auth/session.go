@@ -0,0 +1,127 @@
+package auth
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"regexp"
+	"strings"
+)
+
+// placeholderRegex matches the placeholders of the headers of a session
+var placeholderRegex = regexp.MustCompile(`{{([^{}]+)}}`)
+
+// errNoSession is the error of the logins setting neither a cookie nor a header
+var errNoSession = errors.New("the login set no session cookie")
+
+// Session is the headers and the cookies the requests to a host are sent
+// with once logged in
+type Session struct {
+	Headers map[string]string
+	Cookies []*http.Cookie
+
+	// secrets are the values of the session written redacted, the values
+	// of the placeholders of the headers and the cookies.
+	secrets []string
+}
+
+// NewSession returns the session of a config from the values extracted by
+// the login requests and the cookies they set, failing if a header uses a
+// value which wasn't extracted or if the session is empty.
+func NewSession(config *Config, values map[string]interface{}, cookies []*http.Cookie) (*Session, error) {
+	session := &Session{Headers: make(map[string]string, len(config.Headers))}
+	for name, value := range config.Headers {
+		var missing string
+		value = placeholderRegex.ReplaceAllStringFunc(value, func(placeholder string) string {
+			key := strings.TrimSpace(placeholder[2 : len(placeholder)-2])
+			replaced, ok := values[key]
+			if !ok || stringValue(replaced) == "" {
+				missing = key
+				return placeholder
+			}
+			session.secrets = append(session.secrets, stringValue(replaced))
+			return stringValue(replaced)
+		})
+		if missing != "" {
+			return nil, fmt.Errorf("the login requests extracted no %s for the header %s", missing, name)
+		}
+		session.Headers[strings.TrimSpace(name)] = value
+		session.secrets = append(session.secrets, value)
+	}
+	if *config.Cookies {
+		session.Cookies = cookies
+		for _, cookie := range cookies {
+			if cookie.Value != "" {
+				session.secrets = append(session.secrets, cookie.Value)
+			}
+		}
+	}
+	if len(session.Headers) == 0 && len(session.Cookies) == 0 {
+		return nil, errNoSession
+	}
+	return session, nil
+}
+
+// stringValue returns a value extracted by the login requests as a string,
+// the first one of the extractors returning several.
+func stringValue(value interface{}) string {
+	switch v := value.(type) {
+	case string:
+		return v
+	case []string:
+		if len(v) > 0 {
+			return v[0]
+		}
+	case []interface{}:
+		if len(v) > 0 {
+			if s, ok := v[0].(string); ok {
+				return s
+			}
+		}
+	}
+	return ""
+}
+
+// Apply adds the headers and the cookies of the session to a request, the
+// ones of a previous session being replaced. The headers and the cookies
+// the request already has, i.e the ones of the template, are kept.
+func (s *Session) Apply(req *http.Request, previous *Session) {
+	for name, value := range s.Headers {
+		current := req.Header.Get(name)
+		if current == "" || (previous != nil && current == previous.Headers[name]) {
+			req.Header.Set(name, value)
+		}
+	}
+	if len(s.Cookies) == 0 && (previous == nil || len(previous.Cookies) == 0) {
+		return
+	}
+
+	var cookies []*http.Cookie
+	names := make(map[string]struct{})
+	for _, cookie := range req.Cookies() {
+		if previous != nil && previous.hasCookie(cookie) {
+			continue
+		}
+		names[cookie.Name] = struct{}{}
+		cookies = append(cookies, cookie)
+	}
+	for _, cookie := range s.Cookies {
+		if _, ok := names[cookie.Name]; !ok {
+			cookies = append(cookies, &http.Cookie{Name: cookie.Name, Value: cookie.Value})
+		}
+	}
+	req.Header.Del("Cookie")
+	for _, cookie := range cookies {
+		req.AddCookie(cookie)
+	}
+}
+
+// hasCookie returns true if a cookie of a request is the one of the session
+func (s *Session) hasCookie(cookie *http.Cookie) bool {
+	for _, own := range s.Cookies {
+		if own.Name == cookie.Name && own.Value == cookie.Value {
+			return true
+		}
+	}
+	return false
+}
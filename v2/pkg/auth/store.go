@@ -0,0 +1,141 @@
+package auth
+
+import (
+	"net/http"
+	"net/url"
+	"sync"
+
+	"github.com/projectdiscovery/gologger"
+	"github.com/projectdiscovery/nuclei/v2/pkg/ratelimit"
+	"github.com/projectdiscovery/nuclei/v2/pkg/redact"
+)
+
+// minSecretLength is the length of the shortest values of the sessions
+// redacted, the shorter ones matching everywhere.
+const minSecretLength = 4
+
+// Login logs in to the root URL of a host, returning its session
+type Login func(rootURL string) (*Session, error)
+
+// Store is the sessions of the hosts of a scan, each host being logged in
+// once for its first request. The hosts failing to log in are scanned
+// without a session.
+type Store struct {
+	config *Config
+	login  Login
+
+	mutex   sync.Mutex
+	hosts   map[string]*host
+	secrets []string
+}
+
+// host is the session of a host, nil if its login failed
+type host struct {
+	mutex   sync.Mutex
+	done    bool
+	session *Session
+	reauths int
+}
+
+// NewStore creates the sessions of a config, logged in with a login function
+func NewStore(config *Config, login Login) *Store {
+	return &Store{config: config, login: login, hosts: make(map[string]*host)}
+}
+
+// Session returns the session of the host of a URL, logging in to the host
+// if it isn't yet, the other requests to the host waiting for the login.
+// It is nil without a store or if the login failed.
+func (s *Store) Session(u *url.URL) *Session {
+	if s == nil || u == nil || u.Host == "" {
+		return nil
+	}
+	h := s.host(u)
+	h.mutex.Lock()
+	defer h.mutex.Unlock()
+	if !h.done {
+		h.done = true
+		h.session = s.establish(u)
+	}
+	return h.session
+}
+
+// Renew establishes again the expired session of the host of a URL,
+// returning the new one. The session is only established again by the
+// first request with the expired session, the other requests getting the
+// new one, and the expired one is returned once the host is logged in
+// again max-reauth times.
+func (s *Store) Renew(u *url.URL, expired *Session) *Session {
+	if s == nil || expired == nil || s.config.Reauth == nil {
+		return expired
+	}
+	h := s.host(u)
+	h.mutex.Lock()
+	defer h.mutex.Unlock()
+	if h.session != expired {
+		return h.session
+	}
+	if h.reauths >= s.config.Reauth.MaxReauth {
+		if h.reauths == s.config.Reauth.MaxReauth {
+			h.reauths++
+			gologger.Warningf("The session of %s expired again after %d logins, keeping it\n", u.Host, s.config.Reauth.MaxReauth)
+		}
+		return expired
+	}
+	h.reauths++
+	gologger.Verbosef("The session of %s expired, logging in again\n", "auth", u.Host)
+	if session := s.establish(u); session != nil {
+		h.session = session
+	}
+	return h.session
+}
+
+// Expired returns true if an http response to a request of a session shows
+// the session expired.
+func (s *Store) Expired(resp *http.Response, body, headers string) bool {
+	return s != nil && s.config.Expired(resp, body, headers)
+}
+
+// Redact replaces the values of the sessions of a text, such as the tokens
+// echoed by the responses.
+func (s *Store) Redact(text string) string {
+	if s == nil {
+		return text
+	}
+	s.mutex.Lock()
+	secrets := s.secrets
+	s.mutex.Unlock()
+	return redact.Secrets(text, secrets)
+}
+
+// host returns a host of the store, adding it if needed
+func (s *Store) host(u *url.URL) *host {
+	hostPort := ratelimit.HostPort(u)
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	h, ok := s.hosts[hostPort]
+	if !ok {
+		h = &host{}
+		s.hosts[hostPort] = h
+	}
+	return h
+}
+
+// establish logs in to the host of a URL, returning nil if it fails. The
+// values of the session are added to the redacted secrets.
+func (s *Store) establish(u *url.URL) *Session {
+	session, err := s.login(u.Scheme + "://" + u.Host)
+	if err != nil {
+		gologger.Warningf("Could not log in to %s: %s\n", u.Host, err)
+		return nil
+	}
+	gologger.Verbosef("Logged in to %s\n", "auth", u.Host)
+
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	for _, secret := range session.secrets {
+		if len(secret) >= minSecretLength {
+			s.secrets = append(s.secrets, secret)
+		}
+	}
+	return session
+}
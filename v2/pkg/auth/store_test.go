@@ -0,0 +1,111 @@
+package auth
+
+import (
+	"errors"
+	"net/http"
+	"net/url"
+	"strconv"
+	"sync"
+	"sync/atomic"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+	"gopkg.in/yaml.v2"
+)
+
+func testConfig(t *testing.T, content string) *Config {
+	config := &Config{}
+	require.Nil(t, yaml.UnmarshalStrict([]byte(content), config), "Could not parse config")
+	require.Nil(t, config.validate(), "Could not validate config")
+	return config
+}
+
+func TestNewSession(t *testing.T) {
+	config := testConfig(t, "template: login.yaml\nheaders:\n  Authorization: Bearer {{token}}\n")
+	session, err := NewSession(config, map[string]interface{}{"token": []string{"secret-token", "other"}}, []*http.Cookie{{Name: "sid", Value: "secret-sid"}})
+	require.Nil(t, err, "Could not create session")
+	require.Equal(t, map[string]string{"Authorization": "Bearer secret-token"}, session.Headers, "Could not replace the extracted values")
+	require.Len(t, session.Cookies, 1, "Could not add the cookies")
+	require.ElementsMatch(t, []string{"secret-token", "Bearer secret-token", "secret-sid"}, session.secrets, "Could not collect the secrets")
+
+	_, err = NewSession(config, map[string]interface{}{}, nil)
+	require.NotNil(t, err, "Could not fail without the extracted value")
+
+	config = testConfig(t, "template: login.yaml\n")
+	_, err = NewSession(config, nil, nil)
+	require.Equal(t, errNoSession, err, "Could not fail without cookies")
+}
+
+func TestApply(t *testing.T) {
+	previous := &Session{Headers: map[string]string{"Authorization": "Bearer old"}, Cookies: []*http.Cookie{{Name: "sid", Value: "old"}}}
+	session := &Session{Headers: map[string]string{"Authorization": "Bearer new", "X-Tenant": "acme"}, Cookies: []*http.Cookie{{Name: "sid", Value: "new"}, {Name: "lang", Value: "fr"}}}
+
+	req, err := http.NewRequest(http.MethodGet, "http://example.com/", nil)
+	require.Nil(t, err, "Could not create request")
+	previous.Apply(req, nil)
+	req.AddCookie(&http.Cookie{Name: "lang", Value: "en"})
+	session.Apply(req, previous)
+	require.Equal(t, "Bearer new", req.Header.Get("Authorization"), "Could not replace the header of the previous session")
+	require.Equal(t, "acme", req.Header.Get("X-Tenant"), "Could not add the header")
+	require.Equal(t, "lang=en; sid=new", req.Header.Get("Cookie"), "Could not replace the cookies of the previous session")
+
+	// the headers of the template are kept
+	req, err = http.NewRequest(http.MethodGet, "http://example.com/", nil)
+	require.Nil(t, err, "Could not create request")
+	req.Header.Set("Authorization", "Basic template")
+	session.Apply(req, nil)
+	require.Equal(t, "Basic template", req.Header.Get("Authorization"), "Could not keep the header of the template")
+}
+
+func TestStore(t *testing.T) {
+	config := testConfig(t, "template: login.yaml\nheaders:\n  Authorization: Bearer {{token}}\nreauth:\n  max-reauth: 2\n  matchers:\n    - type: status\n      status: [401]\n")
+	var logins int32
+	store := NewStore(config, func(rootURL string) (*Session, error) {
+		login := atomic.AddInt32(&logins, 1)
+		if rootURL == "http://down.example.com" {
+			return nil, errors.New("connection refused")
+		}
+		return NewSession(config, map[string]interface{}{"token": "token-" + strconv.Itoa(int(login))}, nil)
+	})
+	up, err := url.Parse("http://up.example.com/path")
+	require.Nil(t, err, "Could not parse URL")
+
+	// the host is logged in once for its concurrent requests
+	var wg sync.WaitGroup
+	sessions := make([]*Session, 10)
+	for i := range sessions {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			sessions[i] = store.Session(up)
+		}(i)
+	}
+	wg.Wait()
+	require.Equal(t, int32(1), atomic.LoadInt32(&logins), "Could not log in once")
+	for _, session := range sessions {
+		require.Equal(t, sessions[0], session, "Could not share the session")
+	}
+	require.Equal(t, "Bearer [REDACTED:7 bytes]", store.Redact("Bearer token-1"), "Could not redact the session")
+
+	down, err := url.Parse("http://down.example.com/")
+	require.Nil(t, err, "Could not parse URL")
+	require.Nil(t, store.Session(down), "Could not fail the login")
+	require.Nil(t, store.Session(down), "Could not keep the failed login")
+	require.Equal(t, int32(2), atomic.LoadInt32(&logins), "Could not log in once to the failing host")
+
+	// the expired session is renewed once, the requests with the expired
+	// session getting the new one, until max-reauth
+	expired := sessions[0]
+	renewed := store.Renew(up, expired)
+	require.Equal(t, "Bearer token-3", renewed.Headers["Authorization"], "Could not renew the session")
+	require.Equal(t, renewed, store.Renew(up, expired), "Could not share the renewed session")
+	require.Equal(t, renewed, store.Session(up), "Could not store the renewed session")
+	last := store.Renew(up, renewed)
+	require.Equal(t, last, store.Renew(up, last), "Could not stop renewing after max-reauth")
+	require.Equal(t, int32(4), atomic.LoadInt32(&logins), "Could not limit the logins")
+
+	resp := &http.Response{StatusCode: http.StatusUnauthorized, Header: make(http.Header)}
+	require.True(t, store.Expired(resp, "", ""), "Could not detect the expired session")
+	resp.StatusCode = http.StatusOK
+	require.False(t, store.Expired(resp, "", ""), "Could not detect the valid session")
+}
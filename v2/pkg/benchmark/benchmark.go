@@ -0,0 +1,222 @@
+package benchmark
+
+import (
+	"sort"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// maxBuckets is the maximum number of time buckets of the requests per
+// second of a report, the buckets being widened to fit the scan.
+const maxBuckets = 10
+
+// Recorder records the timings of a scan, updated concurrently by the
+// executers. The methods of a nil Recorder do nothing, so the executers
+// only take the timestamps they already take without -benchmark.
+type Recorder struct {
+	start time.Time
+	// templates and hosts are the timings by template id and by host and
+	// port, seconds the numbers of requests sent by second of the scan, as
+	// *uint64.
+	templates sync.Map
+	hosts     sync.Map
+	seconds   sync.Map
+}
+
+// timing are the counters of a template or a host, in nanoseconds
+type timing struct {
+	runs      uint64
+	runNS     uint64
+	requests  uint64
+	latencyNS uint64
+	errors    uint64
+	matchNS   uint64
+}
+
+// New returns a recorder of a scan starting now
+func New() *Recorder {
+	return &Recorder{start: time.Now()}
+}
+
+// Request records a request of a template to a host and port with its
+// latency, failed if err is not nil.
+func (r *Recorder) Request(templateID, host string, latency time.Duration, err error) {
+	if r == nil {
+		return
+	}
+	for _, timing := range []*timing{load(&r.templates, templateID), load(&r.hosts, host)} {
+		atomic.AddUint64(&timing.requests, 1)
+		atomic.AddUint64(&timing.latencyNS, uint64(latency))
+		if err != nil {
+			atomic.AddUint64(&timing.errors, 1)
+		}
+	}
+	second := int64(time.Since(r.start) / time.Second)
+	counter, ok := r.seconds.Load(second)
+	if !ok {
+		counter, _ = r.seconds.LoadOrStore(second, new(uint64))
+	}
+	atomic.AddUint64(counter.(*uint64), 1)
+}
+
+// Evaluation records the time of the evaluation of the matchers and the
+// extractors of a template on a response.
+func (r *Recorder) Evaluation(templateID string, elapsed time.Duration) {
+	if r == nil {
+		return
+	}
+	atomic.AddUint64(&load(&r.templates, templateID).matchNS, uint64(elapsed))
+}
+
+// Run records a run of a template on a target with its wall time
+func (r *Recorder) Run(templateID string, elapsed time.Duration) {
+	if r == nil {
+		return
+	}
+	timing := load(&r.templates, templateID)
+	atomic.AddUint64(&timing.runs, 1)
+	atomic.AddUint64(&timing.runNS, uint64(elapsed))
+}
+
+// load returns the timing of a key of a map of timings
+func load(timings *sync.Map, key string) *timing {
+	value, ok := timings.Load(key)
+	if !ok {
+		value, _ = timings.LoadOrStore(key, &timing{})
+	}
+	return value.(*timing)
+}
+
+// Timing is the cost of a template or of a host in a report. The time of
+// a template is the total wall time of its runs, the ones of the templates
+// of a cluster sharing the run of the cluster, and the time of a host the
+// total latency of its requests.
+type Timing struct {
+	Name         string  `json:"name"`
+	Runs         uint64  `json:"runs,omitempty"`
+	TimeMS       float64 `json:"time_ms"`
+	AvgRunMS     float64 `json:"avg_run_ms,omitempty"`
+	Requests     uint64  `json:"requests"`
+	AvgLatencyMS float64 `json:"avg_latency_ms"`
+	Errors       uint64  `json:"errors"`
+	MatchMS      float64 `json:"match_ms,omitempty"`
+}
+
+// Bucket is the number of requests sent during a span of the scan
+type Bucket struct {
+	StartS   int64   `json:"start_s"`
+	EndS     int64   `json:"end_s"`
+	Requests uint64  `json:"requests"`
+	RPS      float64 `json:"rps"`
+}
+
+// Report is the report of -benchmark, written as json by -benchmark-json
+type Report struct {
+	DurationMS int64   `json:"duration_ms"`
+	Requests   uint64  `json:"requests"`
+	Errors     uint64  `json:"errors"`
+	RPS        float64 `json:"rps"`
+	// Templates are the templates by time and Hosts the hosts and ports
+	// by total latency, the slowest first.
+	Templates []Timing `json:"templates"`
+	Hosts     []Timing `json:"hosts"`
+	// Buckets are the requests per second over the scan, split in at most
+	// 10 spans of whole seconds.
+	Buckets []Bucket `json:"buckets"`
+}
+
+// Report returns the report of the scan so far with the slowest templates
+// and hosts, all of them if top is 0.
+func (r *Recorder) Report(top int) *Report {
+	elapsed := time.Since(r.start)
+	report := &Report{
+		DurationMS: elapsed.Milliseconds(),
+		Templates:  timings(&r.templates),
+		Hosts:      timings(&r.hosts),
+		Buckets:    []Bucket{},
+	}
+	for _, host := range report.Hosts {
+		report.Requests += host.Requests
+		report.Errors += host.Errors
+	}
+	if seconds := elapsed.Seconds(); seconds > 0 {
+		report.RPS = float64(report.Requests) / seconds
+	}
+	if top > 0 && len(report.Templates) > top {
+		report.Templates = report.Templates[:top]
+	}
+	if top > 0 && len(report.Hosts) > top {
+		report.Hosts = report.Hosts[:top]
+	}
+
+	// the last second is the one in progress
+	seconds := int64(elapsed/time.Second) + 1
+	width := (seconds + maxBuckets - 1) / maxBuckets
+	for start := int64(0); start < seconds; start += width {
+		end := start + width
+		if end > seconds {
+			end = seconds
+		}
+		bucket := Bucket{StartS: start, EndS: end}
+		for second := start; second < end; second++ {
+			if counter, ok := r.seconds.Load(second); ok {
+				bucket.Requests += atomic.LoadUint64(counter.(*uint64))
+			}
+		}
+		// the span of the last bucket ends with the scan, the rates being
+		// counted over a second at least.
+		span := time.Duration(end-start) * time.Second
+		if end == seconds {
+			span = elapsed - time.Duration(start)*time.Second
+		}
+		if span < time.Second {
+			span = time.Second
+		}
+		bucket.RPS = float64(bucket.Requests) / span.Seconds()
+		report.Buckets = append(report.Buckets, bucket)
+	}
+	return report
+}
+
+// timings returns the timings of a map of timings, the slowest first
+func timings(values *sync.Map) []Timing {
+	result := []Timing{}
+	values.Range(func(key, value interface{}) bool {
+		counters := value.(*timing)
+		timing := Timing{
+			Name:     key.(string),
+			Runs:     atomic.LoadUint64(&counters.runs),
+			Requests: atomic.LoadUint64(&counters.requests),
+			Errors:   atomic.LoadUint64(&counters.errors),
+			MatchMS:  milliseconds(atomic.LoadUint64(&counters.matchNS)),
+		}
+		latency := atomic.LoadUint64(&counters.latencyNS)
+		if timing.Requests > 0 {
+			timing.AvgLatencyMS = milliseconds(latency / timing.Requests)
+		}
+		// the hosts, without runs, take the time of their requests
+		if timing.Runs > 0 {
+			runNS := atomic.LoadUint64(&counters.runNS)
+			timing.TimeMS = milliseconds(runNS)
+			timing.AvgRunMS = milliseconds(runNS / timing.Runs)
+		} else {
+			timing.TimeMS = milliseconds(latency)
+		}
+		result = append(result, timing)
+		return true
+	})
+	sort.Slice(result, func(i, j int) bool {
+		if result[i].TimeMS != result[j].TimeMS {
+			return result[i].TimeMS > result[j].TimeMS
+		}
+		return result[i].Name < result[j].Name
+	})
+	return result
+}
+
+// milliseconds returns a number of nanoseconds as milliseconds, rounded
+// to the microsecond.
+func milliseconds(ns uint64) float64 {
+	return float64(ns/uint64(time.Microsecond)) / 1000
+}
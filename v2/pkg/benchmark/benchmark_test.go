@@ -0,0 +1,67 @@
+package benchmark
+
+import (
+	"errors"
+	"sync"
+	"testing"
+	"time"
+
+	jsoniter "github.com/json-iterator/go"
+	"github.com/stretchr/testify/require"
+)
+
+func TestRecorder(t *testing.T) {
+	r := New()
+	r.start = time.Now().Add(-25 * time.Second)
+
+	var wg sync.WaitGroup
+	for i := 0; i < 20; i++ {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			var err error
+			if i%10 == 0 {
+				err = errors.New("timeout")
+			}
+			r.Request("slow", "a.example.com:443", 100*time.Millisecond, err)
+			r.Evaluation("slow", time.Millisecond)
+		}(i)
+	}
+	wg.Wait()
+	r.Request("fast", "b.example.com:80", 10*time.Millisecond, nil)
+	r.Run("slow", 2*time.Second)
+	r.Run("slow", time.Second)
+	r.Run("fast", 10*time.Millisecond)
+	r.Run("cluster-follower", 10*time.Millisecond)
+
+	report := r.Report(0)
+	require.Equal(t, uint64(21), report.Requests, "Could not count the requests")
+	require.Equal(t, uint64(2), report.Errors, "Could not count the errors")
+	require.InDelta(t, 21.0/25, report.RPS, 0.1, "Could not compute the requests per second")
+
+	require.Len(t, report.Templates, 3, "Could not record the templates")
+	require.Equal(t, Timing{Name: "slow", Runs: 2, TimeMS: 3000, AvgRunMS: 1500, Requests: 20, AvgLatencyMS: 100, Errors: 2, MatchMS: 20}, report.Templates[0], "Could not record the timing of a template")
+	require.Equal(t, []string{"slow", "cluster-follower", "fast"}, []string{report.Templates[0].Name, report.Templates[1].Name, report.Templates[2].Name}, "Could not sort the templates by time")
+	require.Equal(t, Timing{Name: "a.example.com:443", TimeMS: 2000, Requests: 20, AvgLatencyMS: 100, Errors: 2}, report.Hosts[0], "Could not record the timing of a host")
+
+	require.Len(t, report.Buckets, 9, "Could not split the scan in buckets")
+	require.Equal(t, Bucket{StartS: 24, EndS: 26, Requests: 21, RPS: report.Buckets[8].RPS}, report.Buckets[8], "Could not count the requests of the last bucket")
+	require.InDelta(t, 21, report.Buckets[8].RPS, 1, "Could not compute the requests per second of the partial last bucket")
+	require.Zero(t, report.Buckets[0].Requests, "Could not keep the empty buckets")
+
+	top := r.Report(1)
+	require.Len(t, top.Templates, 1, "Could not keep the slowest templates")
+	require.Len(t, top.Hosts, 1, "Could not keep the slowest hosts")
+	require.Equal(t, uint64(21), top.Requests, "Could not count the requests of all the hosts")
+
+	data, err := jsoniter.Marshal(top)
+	require.Nil(t, err, "Could not marshal the report")
+	require.Contains(t, string(data), `"templates":[{"name":"slow","runs":2,"time_ms":3000`, "Could not marshal the timings")
+}
+
+func TestNilRecorder(t *testing.T) {
+	var r *Recorder
+	r.Request("template", "host", time.Second, nil)
+	r.Evaluation("template", time.Second)
+	r.Run("template", time.Second)
+}
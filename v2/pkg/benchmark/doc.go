@@ -0,0 +1,5 @@
+// Package benchmark records the cost of the templates and the hosts of a
+// scan run with -benchmark, the time of the runs of the templates, the
+// latency of their requests and the time of the evaluation of their
+// matchers, for the report shown and written at the end of the scan.
+package benchmark
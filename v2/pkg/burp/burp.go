@@ -0,0 +1,241 @@
+package burp
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/base64"
+	"fmt"
+	"io/ioutil"
+	"net/url"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+	"unicode/utf8"
+
+	jsoniter "github.com/json-iterator/go"
+)
+
+const (
+	// ManifestFile is the file of the directory listing the exported requests
+	ManifestFile = "manifest.json"
+	// ConcatenatedFile is the file of the directory with all the requests,
+	// in the format of the logs of Burp.
+	ConcatenatedFile = "requests.log"
+	// Marker delimits the payload values, the positions of Intruder
+	Marker = "§"
+)
+
+// logSeparator separates the requests of the concatenated file
+const logSeparator = "======================================================"
+
+// Options are the settings of an exporter
+type Options struct {
+	// Directory is the directory the requests are written to, created if
+	// it doesn't exist.
+	Directory string
+	// Concatenated writes all the requests to a single file too
+	Concatenated bool
+	// Markers delimits the payload values found in the requests with §, so
+	// the positions of Intruder are set once loaded.
+	Markers bool
+}
+
+// Request is a request of a template to export
+type Request struct {
+	TemplateID string
+	// Target is the input the request was built for
+	Target string
+	// URL is the url of the request, its scheme, host and port being the
+	// service Burp sends it to.
+	URL string
+	// Raw is the request as sent, the headers along with the body
+	Raw []byte
+	// Payloads are the payload values the request was built with
+	Payloads map[string]interface{}
+}
+
+// Entry is an exported request listed by the manifest
+type Entry struct {
+	Template string                 `json:"template"`
+	Target   string                 `json:"target"`
+	URL      string                 `json:"url"`
+	Payloads map[string]interface{} `json:"payloads,omitempty"`
+	// File is the path of the raw request, relative to the directory
+	File string `json:"file"`
+	// Request is the raw request base64 encoded if it is binary, Encoding
+	// being base64, so the manifest keeps its exact bytes too.
+	Request  string `json:"request,omitempty"`
+	Encoding string `json:"encoding,omitempty"`
+}
+
+// Exporter writes each exported request to a raw file of a directory as
+// it is exported, the manifest being written on close. The methods of a
+// nil exporter do nothing.
+type Exporter struct {
+	options Options
+
+	mutex        sync.Mutex
+	entries      []*Entry
+	concatenated *os.File
+	writer       *bufio.Writer
+}
+
+// New creates an exporter writing the requests to a directory
+func New(options Options) (*Exporter, error) {
+	if err := os.MkdirAll(options.Directory, 0755); err != nil {
+		return nil, err
+	}
+	e := &Exporter{options: options}
+	if options.Concatenated {
+		file, err := os.Create(filepath.Join(options.Directory, ConcatenatedFile))
+		if err != nil {
+			return nil, err
+		}
+		e.concatenated, e.writer = file, bufio.NewWriter(file)
+	}
+	return e, nil
+}
+
+// Export writes a request to its raw file, byte for byte, and lists it in
+// the manifest, appending it to the concatenated file if any.
+func (e *Exporter) Export(request *Request) error {
+	if e == nil {
+		return nil
+	}
+	raw := request.Raw
+	if e.options.Markers {
+		raw = annotate(raw, request.Payloads)
+	}
+
+	e.mutex.Lock()
+	defer e.mutex.Unlock()
+
+	entry := &Entry{
+		Template: request.TemplateID,
+		Target:   request.Target,
+		URL:      request.URL,
+		Payloads: request.Payloads,
+		File:     fmt.Sprintf("%06d-%s.txt", len(e.entries)+1, fileName(request.TemplateID)),
+	}
+	if !utf8.Valid(raw) || bytes.IndexByte(raw, 0) != -1 {
+		entry.Request, entry.Encoding = base64.StdEncoding.EncodeToString(raw), "base64"
+	}
+	if err := ioutil.WriteFile(filepath.Join(e.options.Directory, entry.File), raw, 0644); err != nil {
+		return err
+	}
+	if e.writer != nil {
+		writeLogItem(e.writer, request.URL, raw)
+	}
+	e.entries = append(e.entries, entry)
+	return nil
+}
+
+// Close writes the manifest and the concatenated file if any
+func (e *Exporter) Close() error {
+	if e == nil {
+		return nil
+	}
+	e.mutex.Lock()
+	defer e.mutex.Unlock()
+
+	if e.concatenated != nil {
+		err := e.writer.Flush()
+		e.concatenated.Close()
+		e.concatenated, e.writer = nil, nil
+		if err != nil {
+			return err
+		}
+	}
+	entries := e.entries
+	if entries == nil {
+		entries = []*Entry{}
+	}
+	data, err := jsoniter.MarshalIndent(entries, "", "  ")
+	if err != nil {
+		return err
+	}
+	return ioutil.WriteFile(filepath.Join(e.options.Directory, ManifestFile), append(data, '\n'), 0644)
+}
+
+// Count returns the number of requests exported
+func (e *Exporter) Count() int {
+	if e == nil {
+		return 0
+	}
+	e.mutex.Lock()
+	defer e.mutex.Unlock()
+	return len(e.entries)
+}
+
+// Directory returns the directory the requests are written to
+func (e *Exporter) Directory() string {
+	if e == nil {
+		return ""
+	}
+	return e.options.Directory
+}
+
+// annotate delimits the payload values found in a raw request with the
+// markers, the longest values first, the ones within them being left.
+func annotate(raw []byte, payloads map[string]interface{}) []byte {
+	values := make([]string, 0, len(payloads))
+	for _, value := range payloads {
+		if value := fmt.Sprint(value); value != "" {
+			values = append(values, value)
+		}
+	}
+	if len(values) == 0 {
+		return raw
+	}
+	sort.Slice(values, func(i, j int) bool {
+		if len(values[i]) != len(values[j]) {
+			return len(values[i]) > len(values[j])
+		}
+		return values[i] < values[j]
+	})
+	replacements := make([]string, 0, 2*len(values))
+	for _, value := range values {
+		replacements = append(replacements, value, Marker+value+Marker)
+	}
+	return []byte(strings.NewReplacer(replacements...).Replace(string(raw)))
+}
+
+// writeLogItem writes a request in the format of the logs of Burp, read
+// back by its extensions and by tools such as sqlmap -l.
+func writeLogItem(writer *bufio.Writer, URL string, raw []byte) {
+	writer.WriteString(logSeparator + "\n")
+	writer.WriteString(time.Now().Format("3:04:05 PM") + "  " + service(URL) + "\n")
+	writer.WriteString(logSeparator + "\n")
+	writer.Write(raw)
+	writer.WriteString("\n" + logSeparator + "\n\n\n\n")
+}
+
+// service returns the scheme, the host and the port of a url, the default
+// port of the scheme if it has none.
+func service(URL string) string {
+	parsed, err := url.Parse(URL)
+	if err != nil {
+		return URL
+	}
+	port := parsed.Port()
+	if port == "" {
+		port = "80"
+		if parsed.Scheme == "https" {
+			port = "443"
+		}
+	}
+	return parsed.Scheme + "://" + parsed.Hostname() + ":" + port
+}
+
+// unsafeCharacters are the characters of the template ids not kept in the
+// names of the files
+var unsafeCharacters = regexp.MustCompile(`[^a-zA-Z0-9._-]+`)
+
+// fileName returns a template id usable in the name of a file
+func fileName(templateID string) string {
+	return unsafeCharacters.ReplaceAllString(templateID, "_")
+}
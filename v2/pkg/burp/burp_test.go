@@ -0,0 +1,59 @@
+package burp
+
+import (
+	"encoding/base64"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	jsoniter "github.com/json-iterator/go"
+	"github.com/stretchr/testify/require"
+)
+
+func TestExporter(t *testing.T) {
+	dir, err := ioutil.TempDir("", "nuclei-burp")
+	require.Nil(t, err, "Could not create directory")
+	defer os.RemoveAll(dir)
+	directory := filepath.Join(dir, "requests")
+	exporter, err := New(Options{Directory: directory, Concatenated: true, Markers: true})
+	require.Nil(t, err, "Could not create exporter")
+
+	login := "POST /login HTTP/1.1\r\nHost: example.com\r\nContent-Length: 25\r\n\r\nuser=admin&pass=admin123"
+	err = exporter.Export(&Request{TemplateID: "default/login", Target: "https://example.com", URL: "https://example.com/login", Raw: []byte(login), Payloads: map[string]interface{}{"user": "admin", "pass": "admin123"}})
+	require.Nil(t, err, "Could not export the request")
+	binary := []byte("POST /upload HTTP/1.1\r\nHost: example.com:8080\r\n\r\n\x00\xff\xfe")
+	err = exporter.Export(&Request{TemplateID: "upload", Target: "http://example.com:8080", URL: "http://example.com:8080/upload", Raw: binary})
+	require.Nil(t, err, "Could not export the binary request")
+	require.Nil(t, exporter.Close(), "Could not close exporter")
+	require.Equal(t, 2, exporter.Count(), "Could not count the requests")
+
+	data, err := ioutil.ReadFile(filepath.Join(directory, ManifestFile))
+	require.Nil(t, err, "Could not write the manifest")
+	var entries []*Entry
+	require.Nil(t, jsoniter.Unmarshal(data, &entries), "Could not unmarshal the manifest")
+	require.Len(t, entries, 2, "Could not list the requests")
+	require.Equal(t, "000001-default_login.txt", entries[0].File, "Could not name the file after the template")
+	require.Equal(t, "admin", entries[0].Payloads["user"], "Could not list the payload values")
+	require.Empty(t, entries[0].Encoding, "Could encode a text request")
+	require.Equal(t, "base64", entries[1].Encoding, "Could not encode the binary request")
+	require.Equal(t, base64.StdEncoding.EncodeToString(binary), entries[1].Request, "Could not keep the binary request in the manifest")
+
+	written, err := ioutil.ReadFile(filepath.Join(directory, entries[0].File))
+	require.Nil(t, err, "Could not write the request")
+	require.Equal(t, "POST /login HTTP/1.1\r\nHost: example.com\r\nContent-Length: 25\r\n\r\nuser=§admin§&pass=§admin123§", string(written), "Could not mark the payload values")
+	written, err = ioutil.ReadFile(filepath.Join(directory, entries[1].File))
+	require.Nil(t, err, "Could not write the binary request")
+	require.Equal(t, binary, written, "Could not write the binary request byte for byte")
+
+	log, err := ioutil.ReadFile(filepath.Join(directory, ConcatenatedFile))
+	require.Nil(t, err, "Could not write the concatenated file")
+	require.Contains(t, string(log), "  https://example.com:443\n"+logSeparator+"\nPOST /login", "Could not log the service of the request")
+	require.Contains(t, string(log), "  http://example.com:8080\n", "Could not log the port of the request")
+	require.Equal(t, 6, strings.Count(string(log), logSeparator), "Could not separate the requests")
+
+	var nilExporter *Exporter
+	require.Nil(t, nilExporter.Export(&Request{}), "Could export to a nil exporter")
+	require.Nil(t, nilExporter.Close(), "Could close a nil exporter")
+}
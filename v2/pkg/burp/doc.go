@@ -0,0 +1,4 @@
+// Package burp exports the http requests built by the templates as raw
+// files Burp loads into Repeater and Intruder, along with a manifest of
+// their templates, targets and payload values.
+package burp
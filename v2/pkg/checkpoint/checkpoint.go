@@ -0,0 +1,325 @@
+package checkpoint
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+
+	jsoniter "github.com/json-iterator/go"
+)
+
+// version is the version of the format of the checkpoint files
+const version = 1
+
+// ErrChanged is returned when resuming a scan whose templates or targets
+// changed since the checkpoint was written.
+var ErrChanged = errors.New("the templates or the targets changed since the checkpoint")
+
+// state is the content of a checkpoint file
+type state struct {
+	Version int    `json:"version"`
+	Hash    string `json:"hash"`
+	Targets int    `json:"targets"`
+	// Completed are the bitsets of the completed targets by step, a step
+	// being a run of a template or of one of its request blocks.
+	Completed map[string][]byte `json:"completed"`
+	// Partial are the numbers of requests sent by step and target for the
+	// steps in progress.
+	Partial map[string]map[string]int `json:"partial,omitempty"`
+	// Consumed is the number of lines of the streamed targets whose runs
+	// all completed, in order, skipped when the stream is replayed.
+	Consumed int64 `json:"consumed,omitempty"`
+}
+
+// Checkpoint records the progress of a scan to a file. The methods of a
+// nil checkpoint do nothing, the scan not being resumable.
+type Checkpoint struct {
+	path    string
+	targets map[string]int
+
+	mutex   sync.Mutex
+	state   state
+	changed bool
+	resumed bool
+	// saving serializes the writes of the file
+	saving sync.Mutex
+}
+
+// Hash returns the hash of the contents of the template files and of the
+// targets of a scan, identifying the scan to resume.
+func Hash(paths []string, targets string) (string, error) {
+	hash := sha256.New()
+	for _, path := range paths {
+		data, err := ioutil.ReadFile(path)
+		if err != nil {
+			return "", err
+		}
+		fmt.Fprintf(hash, "%s\x00%d\x00", path, len(data))
+		hash.Write(data)
+	}
+	hash.Write([]byte(targets))
+	return hex.EncodeToString(hash.Sum(nil)), nil
+}
+
+// Open opens the checkpoint of a scan with a hash and a list of targets,
+// one per line, resuming it if the file exists. ErrChanged is returned if
+// the checkpoint is the one of another scan.
+func Open(path, hash, targets string) (*Checkpoint, error) {
+	c := &Checkpoint{
+		path:    path,
+		targets: make(map[string]int),
+		state: state{
+			Version:   version,
+			Hash:      hash,
+			Completed: make(map[string][]byte),
+			Partial:   make(map[string]map[string]int),
+		},
+	}
+	for _, target := range strings.Split(targets, "\n") {
+		if _, ok := c.targets[target]; target != "" && !ok {
+			c.targets[target] = len(c.targets)
+		}
+	}
+	c.state.Targets = len(c.targets)
+
+	data, err := ioutil.ReadFile(path)
+	if os.IsNotExist(err) {
+		return c, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("could not read checkpoint: %s", err)
+	}
+	var previous state
+	if err := jsoniter.Unmarshal(data, &previous); err != nil {
+		return nil, fmt.Errorf("could not parse checkpoint %s: %s", path, err)
+	}
+	if previous.Version != version {
+		return nil, fmt.Errorf("unsupported version %d of checkpoint %s", previous.Version, path)
+	}
+	if previous.Hash != hash || previous.Targets != c.state.Targets {
+		return nil, ErrChanged
+	}
+	if previous.Completed != nil {
+		c.state.Completed = previous.Completed
+	}
+	if previous.Partial != nil {
+		c.state.Partial = previous.Partial
+	}
+	c.state.Consumed = previous.Consumed
+	c.resumed = true
+	return c, nil
+}
+
+// Path returns the path of the checkpoint file
+func (c *Checkpoint) Path() string {
+	if c == nil {
+		return ""
+	}
+	return c.path
+}
+
+// Resumed returns true if the checkpoint was read from an existing file
+func (c *Checkpoint) Resumed() bool {
+	return c != nil && c.resumed
+}
+
+// Completed returns true if a step completed on a target
+func (c *Checkpoint) Completed(step, target string) bool {
+	if c == nil {
+		return false
+	}
+	index, ok := c.targets[target]
+	if !ok {
+		return false
+	}
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+
+	bits := c.state.Completed[step]
+	return index/8 < len(bits) && bits[index/8]&(1<<uint(index%8)) != 0
+}
+
+// Complete records a step as completed on a target, forgetting the
+// position of its requests. Only the position is forgotten for the
+// streamed targets, which are recorded by the lines consumed.
+func (c *Checkpoint) Complete(step, target string) {
+	if c == nil {
+		return
+	}
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+
+	if positions, ok := c.state.Partial[step]; ok {
+		if _, ok := positions[target]; ok {
+			delete(positions, target)
+			c.changed = true
+		}
+		if len(positions) == 0 {
+			delete(c.state.Partial, step)
+		}
+	}
+	index, ok := c.targets[target]
+	if !ok {
+		return
+	}
+	bits := c.state.Completed[step]
+	if bits == nil {
+		bits = make([]byte, (len(c.targets)+7)/8)
+		c.state.Completed[step] = bits
+	}
+	bits[index/8] |= 1 << uint(index%8)
+	c.changed = true
+}
+
+// Consumed returns the number of lines of the streamed targets consumed
+// before the interruption of the scan.
+func (c *Checkpoint) Consumed() int64 {
+	if c == nil {
+		return 0
+	}
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+
+	return c.state.Consumed
+}
+
+// Consume records the number of lines of the streamed targets whose runs
+// all completed.
+func (c *Checkpoint) Consume(lines int64) {
+	if c == nil {
+		return
+	}
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+
+	if lines > c.state.Consumed {
+		c.state.Consumed = lines
+		c.changed = true
+	}
+}
+
+// Forget forgets the positions of the requests of all the steps sent to
+// the streamed targets whose runs all completed.
+func (c *Checkpoint) Forget(targets ...string) {
+	if c == nil {
+		return
+	}
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+
+	for step, positions := range c.state.Partial {
+		for _, target := range targets {
+			if _, ok := positions[target]; ok {
+				delete(positions, target)
+				c.changed = true
+			}
+		}
+		if len(positions) == 0 {
+			delete(c.state.Partial, step)
+		}
+	}
+}
+
+// Position returns the number of requests of a step sent to a target
+// before the interruption of the scan, 0 if none.
+func (c *Checkpoint) Position(step, target string) int {
+	if c == nil {
+		return 0
+	}
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+
+	return c.state.Partial[step][target]
+}
+
+// Advance records the number of requests of a step sent to a target
+func (c *Checkpoint) Advance(step, target string, requests int) {
+	if c == nil {
+		return
+	}
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+
+	positions := c.state.Partial[step]
+	if positions[target] == requests {
+		return
+	}
+	if positions == nil {
+		positions = make(map[string]int)
+		c.state.Partial[step] = positions
+	}
+	positions[target] = requests
+	c.changed = true
+}
+
+// Save writes the checkpoint if it changed since it was last written,
+// replacing the file atomically so an interruption never leaves a partial
+// checkpoint.
+func (c *Checkpoint) Save() error {
+	if c == nil {
+		return nil
+	}
+	c.saving.Lock()
+	defer c.saving.Unlock()
+
+	c.mutex.Lock()
+	if !c.changed {
+		c.mutex.Unlock()
+		return nil
+	}
+	data, err := jsoniter.Marshal(&c.state)
+	c.changed = false
+	c.mutex.Unlock()
+	if err != nil {
+		return err
+	}
+	if err := c.write(data); err != nil {
+		// the changes are written by the next save
+		c.mutex.Lock()
+		c.changed = true
+		c.mutex.Unlock()
+		return err
+	}
+	return nil
+}
+
+// write replaces the checkpoint file with a temporary file of its content
+func (c *Checkpoint) write(data []byte) error {
+	file, err := ioutil.TempFile(filepath.Dir(c.path), "."+filepath.Base(c.path)+".*")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(file.Name())
+	if _, err := file.Write(data); err != nil {
+		file.Close()
+		return err
+	}
+	if err := file.Sync(); err != nil {
+		file.Close()
+		return err
+	}
+	if err := file.Close(); err != nil {
+		return err
+	}
+	if err := os.Chmod(file.Name(), 0644); err != nil {
+		return err
+	}
+	return os.Rename(file.Name(), c.path)
+}
+
+// Remove removes the checkpoint file once the scan completed
+func (c *Checkpoint) Remove() error {
+	if c == nil {
+		return nil
+	}
+	if err := os.Remove(c.path); err != nil && !os.IsNotExist(err) {
+		return err
+	}
+	return nil
+}
@@ -0,0 +1,107 @@
+package checkpoint
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestCheckpoint(t *testing.T) {
+	dir, err := ioutil.TempDir("", "checkpoint-")
+	require.Nil(t, err, "Could not create temporary directory")
+	defer os.RemoveAll(dir)
+	path := filepath.Join(dir, "scan.json")
+	targets := "https://a.example.com\nhttps://b.example.com\n"
+
+	c, err := Open(path, "hash", targets)
+	require.Nil(t, err, "Could not open new checkpoint")
+	require.False(t, c.Resumed(), "Could not start a new scan")
+
+	c.Complete("template:http:0", "https://a.example.com")
+	c.Advance("template:http:1", "https://b.example.com", 3)
+	c.Advance("template:http:1", "https://a.example.com", 2)
+	c.Complete("template:http:1", "https://a.example.com")
+	require.Nil(t, c.Save(), "Could not save checkpoint")
+
+	matches, err := filepath.Glob(filepath.Join(dir, ".*"))
+	require.Nil(t, err, "Could not list temporary files")
+	require.Empty(t, matches, "Could not remove the temporary file")
+
+	c, err = Open(path, "hash", targets)
+	require.Nil(t, err, "Could not resume checkpoint")
+	require.True(t, c.Resumed(), "Could not resume the scan")
+	require.True(t, c.Completed("template:http:0", "https://a.example.com"), "Could not record completed step")
+	require.False(t, c.Completed("template:http:0", "https://b.example.com"), "Could not skip pending step")
+	require.True(t, c.Completed("template:http:1", "https://a.example.com"), "Could not record completed step")
+	require.Equal(t, 3, c.Position("template:http:1", "https://b.example.com"), "Could not record position")
+	require.Equal(t, 0, c.Position("template:http:1", "https://a.example.com"), "Could not forget the position of a completed step")
+
+	_, err = Open(path, "other", targets)
+	require.Equal(t, ErrChanged, err, "Could not refuse changed templates")
+	_, err = Open(path, "hash", targets+"https://c.example.com\n")
+	require.Equal(t, ErrChanged, err, "Could not refuse changed targets")
+
+	require.Nil(t, c.Remove(), "Could not remove checkpoint")
+	_, err = os.Stat(path)
+	require.True(t, os.IsNotExist(err), "Could not remove checkpoint file")
+}
+
+func TestStreamedCheckpoint(t *testing.T) {
+	dir, err := ioutil.TempDir("", "checkpoint-")
+	require.Nil(t, err, "Could not create temporary directory")
+	defer os.RemoveAll(dir)
+	path := filepath.Join(dir, "scan.json")
+
+	c, err := Open(path, "hash", "")
+	require.Nil(t, err, "Could not open new checkpoint")
+	c.Advance("template:http:0", "https://a.example.com", 2)
+	c.Complete("template:http:0", "https://a.example.com")
+	require.Equal(t, 0, c.Position("template:http:0", "https://a.example.com"), "Could not forget the position of a streamed target")
+	c.Advance("template:http:0", "http://b.example.com", 1)
+	c.Advance("template:dns:0", "b.example.com", 1)
+	c.Forget("b.example.com", "http://b.example.com")
+	require.Equal(t, 0, c.Position("template:http:0", "http://b.example.com"), "Could not forget the position of a completed target")
+	require.Equal(t, 0, c.Position("template:dns:0", "b.example.com"), "Could not forget the position of a completed target")
+	c.Consume(3)
+	c.Consume(2)
+	require.Nil(t, c.Save(), "Could not save checkpoint")
+
+	c, err = Open(path, "hash", "")
+	require.Nil(t, err, "Could not resume checkpoint")
+	require.Equal(t, int64(3), c.Consumed(), "Could not record the consumed lines")
+	require.False(t, c.Completed("template:http:0", "https://a.example.com"), "Could not leave the streamed targets to the consumed lines")
+}
+
+func TestNilCheckpoint(t *testing.T) {
+	var c *Checkpoint
+	c.Complete("template", "https://a.example.com")
+	c.Advance("template", "https://a.example.com", 1)
+	require.False(t, c.Completed("template", "https://a.example.com"), "Could not ignore nil checkpoint")
+	require.Equal(t, 0, c.Position("template", "https://a.example.com"), "Could not ignore nil checkpoint")
+	c.Consume(1)
+	c.Forget("https://a.example.com")
+	require.Equal(t, int64(0), c.Consumed(), "Could not ignore nil checkpoint")
+	require.Nil(t, c.Save(), "Could not ignore nil checkpoint")
+}
+
+func TestHash(t *testing.T) {
+	f, err := ioutil.TempFile("", "template-*.yaml")
+	require.Nil(t, err, "Could not create template file")
+	defer os.Remove(f.Name())
+	f.WriteString("id: test\n")
+	f.Close()
+
+	first, err := Hash([]string{f.Name()}, "https://a.example.com\n")
+	require.Nil(t, err, "Could not hash templates")
+	second, err := Hash([]string{f.Name()}, "https://b.example.com\n")
+	require.Nil(t, err, "Could not hash templates")
+	require.NotEqual(t, first, second, "Could not hash the targets")
+
+	require.Nil(t, ioutil.WriteFile(f.Name(), []byte("id: changed\n"), 0644), "Could not change template file")
+	third, err := Hash([]string{f.Name()}, "https://a.example.com\n")
+	require.Nil(t, err, "Could not hash templates")
+	require.NotEqual(t, first, third, "Could not hash the template contents")
+}
@@ -0,0 +1,4 @@
+// Package checkpoint records the runs of the templates completed on the
+// targets of a scan and the positions of the payload requests in progress,
+// so an interrupted scan is resumed without sending its requests again.
+package checkpoint
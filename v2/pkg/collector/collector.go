@@ -0,0 +1,101 @@
+package collector
+
+import (
+	"bufio"
+	"os"
+	"sort"
+	"sync"
+)
+
+// Collector is a concurrency safe set of the extracted values of a scan.
+//
+// New values are appended to the file as they are extracted so that they
+// aren't lost if the scan is interrupted, the file is rewritten sorted on close.
+type Collector struct {
+	mutex  sync.Mutex
+	file   *os.File
+	writer *bufio.Writer
+	values map[string]struct{}
+}
+
+// New creates a collector writing the extracted values to a file
+func New(file string) (*Collector, error) {
+	output, err := os.Create(file)
+	if err != nil {
+		return nil, err
+	}
+	return &Collector{file: output, writer: bufio.NewWriter(output), values: make(map[string]struct{})}, nil
+}
+
+// Append creates a collector appending the new extracted values to the ones
+// of a file, for the resumed scans.
+func Append(file string) (*Collector, error) {
+	output, err := os.OpenFile(file, os.O_RDWR|os.O_CREATE|os.O_APPEND, 0644)
+	if err != nil {
+		return nil, err
+	}
+	values := make(map[string]struct{})
+	scanner := bufio.NewScanner(output)
+	for scanner.Scan() {
+		if value := scanner.Text(); value != "" {
+			values[value] = struct{}{}
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		output.Close()
+		return nil, err
+	}
+	return &Collector{file: output, writer: bufio.NewWriter(output), values: values}, nil
+}
+
+// Add adds an extracted value, writing it to the file if it's new
+func (c *Collector) Add(value string) error {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+
+	if _, ok := c.values[value]; ok {
+		return nil
+	}
+	c.values[value] = struct{}{}
+	c.writer.WriteString(value)
+	c.writer.WriteRune('\n')
+	return c.writer.Flush()
+}
+
+// Count returns the number of unique extracted values
+func (c *Collector) Count() int {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+	return len(c.values)
+}
+
+// Name returns the name of the file of the extracted values
+func (c *Collector) Name() string {
+	return c.file.Name()
+}
+
+// Close rewrites the file with the sorted extracted values and closes it
+func (c *Collector) Close() error {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+	defer c.file.Close()
+
+	values := make([]string, 0, len(c.values))
+	for value := range c.values {
+		values = append(values, value)
+	}
+	sort.Strings(values)
+
+	if err := c.file.Truncate(0); err != nil {
+		return err
+	}
+	if _, err := c.file.Seek(0, 0); err != nil {
+		return err
+	}
+	c.writer.Reset(c.file)
+	for _, value := range values {
+		c.writer.WriteString(value)
+		c.writer.WriteRune('\n')
+	}
+	return c.writer.Flush()
+}
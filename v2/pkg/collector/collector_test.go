@@ -0,0 +1,107 @@
+package collector
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestCollector(t *testing.T) {
+	directory, err := ioutil.TempDir("", "collector-*")
+	require.Nil(t, err, "Could not create temporary directory")
+	defer os.RemoveAll(directory)
+	file := filepath.Join(directory, "extracted.txt")
+
+	collector, err := New(file)
+	require.Nil(t, err, "Could not create collector")
+
+	values := []string{"bucket-b", "10.0.0.1", "bucket-a", "bucket-b", "10.0.0.1"}
+	errs := make(chan error, len(values))
+	var wg sync.WaitGroup
+	for _, value := range values {
+		wg.Add(1)
+		go func(value string) {
+			defer wg.Done()
+			errs <- collector.Add(value)
+		}(value)
+	}
+	wg.Wait()
+	close(errs)
+	for err := range errs {
+		require.Nil(t, err, "Could not add value")
+	}
+	require.Equal(t, 3, collector.Count(), "Could not deduplicate values")
+
+	// values are written as they are extracted
+	data, err := ioutil.ReadFile(file)
+	require.Nil(t, err, "Could not read extracted values")
+	require.Len(t, data, len("bucket-b\n10.0.0.1\nbucket-a\n"), "Could not write values incrementally")
+
+	require.Nil(t, collector.Close(), "Could not close collector")
+	data, err = ioutil.ReadFile(file)
+	require.Nil(t, err, "Could not read extracted values")
+	require.Equal(t, "10.0.0.1\nbucket-a\nbucket-b\n", string(data), "Could not sort values")
+}
+
+func TestAppendFile(t *testing.T) {
+	directory, err := ioutil.TempDir("", "collector-*")
+	require.Nil(t, err, "Could not create temporary directory")
+	defer os.RemoveAll(directory)
+	file := filepath.Join(directory, "emails.txt")
+
+	files := NewFiles(directory)
+	errs := make(chan error, 100)
+	var wg sync.WaitGroup
+	for i := 0; i < 100; i++ {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			errs <- files.Append("emails.txt", strings.Repeat("x", i%3+1), true)
+		}(i)
+	}
+	wg.Wait()
+	close(errs)
+	for err := range errs {
+		require.Nil(t, err, "Could not append value")
+	}
+	require.Nil(t, files.Append("emails.txt", "multi\nline", false), "Could not append value")
+	require.Nil(t, files.Append("emails.txt", "x", false), "Could not append value without dedupe")
+	require.NotNil(t, files.Append("../emails.txt", "x", false), "Could append value outside of the directory")
+	require.NotNil(t, files.Append(file, "x", false), "Could append value to an absolute path")
+	files.Close()
+
+	data, err := ioutil.ReadFile(file)
+	require.Nil(t, err, "Could not read appended values")
+	lines := strings.Split(strings.TrimSuffix(string(data), "\n"), "\n")
+	sort.Strings(lines[:3])
+	require.Equal(t, []string{"x", "xx", "xxx", `multi\nline`, "x"}, lines, "Could not append deduplicated values")
+}
+
+func TestCollectorAppend(t *testing.T) {
+	directory, err := ioutil.TempDir("", "collector-*")
+	require.Nil(t, err, "Could not create temporary directory")
+	defer os.RemoveAll(directory)
+	file := filepath.Join(directory, "extracted.txt")
+	require.Nil(t, ioutil.WriteFile(file, []byte("bucket-b\n10.0.0.1\n"), 0644), "Could not write extracted values")
+
+	collector, err := Append(file)
+	require.Nil(t, err, "Could not create collector")
+	require.Nil(t, collector.Add("bucket-a"), "Could not add value")
+	require.Nil(t, collector.Add("bucket-b"), "Could not add value")
+	require.Equal(t, 3, collector.Count(), "Could not deduplicate the previous values")
+
+	data, err := ioutil.ReadFile(file)
+	require.Nil(t, err, "Could not read extracted values")
+	require.Equal(t, "bucket-b\n10.0.0.1\nbucket-a\n", string(data), "Could not append values")
+
+	require.Nil(t, collector.Close(), "Could not close collector")
+	data, err = ioutil.ReadFile(file)
+	require.Nil(t, err, "Could not read extracted values")
+	require.Equal(t, "10.0.0.1\nbucket-a\nbucket-b\n", string(data), "Could not sort values")
+}
@@ -0,0 +1,4 @@
+// Package collector collects the values extracted during a scan into
+// files separate from the results output, a single deduplicated file
+// for the scan or the files of the extractors.
+package collector
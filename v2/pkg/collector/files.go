@@ -0,0 +1,97 @@
+package collector
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+)
+
+// outputFile is a file extracted values are appended to
+type outputFile struct {
+	mutex  sync.Mutex
+	file   *os.File
+	values map[string]struct{}
+}
+
+// Files are the files the extracted values of a scan are appended to, each
+// scan having its own so the scans of a program don't share their files.
+type Files struct {
+	mutex     sync.Mutex
+	directory string
+	files     map[string]*outputFile
+}
+
+// NewFiles creates the files the extracted values of a scan are appended
+// to, their paths being relative to directory, the working directory if
+// empty.
+func NewFiles(directory string) *Files {
+	return &Files{directory: directory, files: make(map[string]*outputFile)}
+}
+
+// ValidatePath returns an error if a path of a file extracted values are
+// appended to is absolute or outside of the directory of the files, so the
+// templates can't write to the other files of the user.
+func ValidatePath(path string) error {
+	if filepath.IsAbs(path) || strings.HasPrefix(path, "/") {
+		return fmt.Errorf("file %s is not relative to the extractor directory", path)
+	}
+	cleaned := filepath.Clean(path)
+	if cleaned == ".." || strings.HasPrefix(cleaned, ".."+string(filepath.Separator)) {
+		return fmt.Errorf("file %s is outside of the extractor directory", path)
+	}
+	return nil
+}
+
+// newlineEscaper escapes the newlines of the values to write one value per line
+var newlineEscaper = strings.NewReplacer("\\", "\\\\", "\n", "\\n", "\r", "\\r")
+
+// Append appends an extracted value to a file as a single line, skipping
+// the values already written to the file if dedupe is true. The path is
+// checked with ValidatePath once the placeholders are replaced.
+//
+// Files are created when the first value is written and stay open until
+// Close is called, each line being written at once so that concurrent
+// writers don't interleave.
+func (f *Files) Append(path, value string, dedupe bool) error {
+	if err := ValidatePath(path); err != nil {
+		return err
+	}
+	f.mutex.Lock()
+	output, ok := f.files[path]
+	if !ok {
+		file, err := os.OpenFile(filepath.Join(f.directory, path), os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0644)
+		if err != nil {
+			f.mutex.Unlock()
+			return err
+		}
+		output = &outputFile{file: file, values: make(map[string]struct{})}
+		f.files[path] = output
+	}
+	f.mutex.Unlock()
+
+	output.mutex.Lock()
+	defer output.mutex.Unlock()
+	if dedupe {
+		if _, ok := output.values[value]; ok {
+			return nil
+		}
+		output.values[value] = struct{}{}
+	}
+	_, err := output.file.WriteString(newlineEscaper.Replace(value) + "\n")
+	return err
+}
+
+// Close closes the files the extracted values were appended to
+func (f *Files) Close() {
+	f.mutex.Lock()
+	defer f.mutex.Unlock()
+
+	for path, output := range f.files {
+		output.mutex.Lock()
+		output.file.Close()
+		output.mutex.Unlock()
+		delete(f.files, path)
+	}
+}
@@ -0,0 +1,3 @@
+// Package csv writes the results of a scan as the rows of a csv file, one
+// per finding, for the spreadsheets and the shell pipelines.
+package csv
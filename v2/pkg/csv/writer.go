@@ -0,0 +1,175 @@
+package csv
+
+import (
+	"bufio"
+	stdcsv "encoding/csv"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+	"sync"
+
+	jsoniter "github.com/json-iterator/go"
+	"github.com/projectdiscovery/gologger"
+)
+
+// DefaultFields are the columns of the csv file by default
+var DefaultFields = []string{"timestamp", "template-id", "template-name", "severity", "host", "matched-at", "matcher-name", "extracted-values", "tags"}
+
+// result is the part of a json result written to the csv file
+type result struct {
+	Timestamp        string   `json:"timestamp"`
+	Template         string   `json:"template"`
+	Name             string   `json:"name"`
+	Severity         string   `json:"severity"`
+	Host             string   `json:"host"`
+	Matched          string   `json:"matched"`
+	MatcherName      string   `json:"matcher_name"`
+	ExtractedResults []string `json:"extracted_results"`
+	Tags             []string `json:"tags"`
+}
+
+// columns are the values of the columns of a result by field name
+var columns = map[string]func(r *result) string{
+	"timestamp":        func(r *result) string { return r.Timestamp },
+	"template-id":      func(r *result) string { return r.Template },
+	"template-name":    func(r *result) string { return r.Name },
+	"severity":         func(r *result) string { return r.Severity },
+	"host":             func(r *result) string { return r.Host },
+	"matched-at":       func(r *result) string { return r.Matched },
+	"matcher-name":     func(r *result) string { return r.MatcherName },
+	"extracted-values": func(r *result) string { return strings.Join(r.ExtractedResults, ";") },
+	"tags":             func(r *result) string { return strings.Join(r.Tags, ",") },
+}
+
+// Writer writes the json results of a scan as csv rows. The rows are
+// flushed as they are written so the file is complete when the scan is
+// interrupted.
+type Writer struct {
+	fields []string
+	name   string
+	file   *os.File
+
+	mutex  sync.Mutex
+	writer *stdcsv.Writer
+	count  int
+}
+
+// ParseFields returns the comma separated fields of the columns, failing on
+// the unknown ones. The default fields are returned if none are given.
+func ParseFields(value string) ([]string, error) {
+	if value == "" {
+		return DefaultFields, nil
+	}
+	var fields []string
+	for _, field := range strings.Split(value, ",") {
+		field = strings.ToLower(strings.TrimSpace(field))
+		if field == "" {
+			continue
+		}
+		if _, ok := columns[field]; !ok {
+			return nil, fmt.Errorf("unknown csv field %s, it should be one of %s", field, strings.Join(DefaultFields, ","))
+		}
+		fields = append(fields, field)
+	}
+	if len(fields) == 0 {
+		return nil, fmt.Errorf("no csv fields given")
+	}
+	return fields, nil
+}
+
+// New creates a writer appending the rows of the fields to a file. The
+// header is written if the file is new or empty, an existing file with
+// other columns being refused.
+func New(name string, fields []string) (*Writer, error) {
+	file, err := os.OpenFile(name, os.O_RDWR|os.O_CREATE, 0644)
+	if err != nil {
+		return nil, fmt.Errorf("could not open csv file: %s", err)
+	}
+	header, err := bufio.NewReader(file).ReadString('\n')
+	// the rows start on a new line after a header without line feed
+	unterminated := err == io.EOF && header != ""
+	if err != nil && err != io.EOF {
+		file.Close()
+		return nil, fmt.Errorf("could not read csv file %s: %s", name, err)
+	}
+	expected := strings.Join(fields, ",")
+	if header != "" && strings.TrimRight(header, "\r\n") != expected {
+		file.Close()
+		return nil, fmt.Errorf("%s has the columns %s, use the same -csv-fields or another file", name, strings.TrimRight(header, "\r\n"))
+	}
+	if _, err := file.Seek(0, io.SeekEnd); err != nil {
+		file.Close()
+		return nil, fmt.Errorf("could not open csv file %s: %s", name, err)
+	}
+
+	if unterminated {
+		if _, err := file.Write([]byte("\n")); err != nil {
+			file.Close()
+			return nil, fmt.Errorf("could not write csv file %s: %s", name, err)
+		}
+	}
+
+	w := &Writer{fields: fields, name: name, file: file, writer: stdcsv.NewWriter(file)}
+	if header == "" {
+		if err := w.write(fields); err != nil {
+			file.Close()
+			return nil, fmt.Errorf("could not write csv header to %s: %s", name, err)
+		}
+	}
+	return w, nil
+}
+
+// IncludeRR returns false as the rows don't have the raw requests and responses
+func (w *Writer) IncludeRR() bool {
+	return false
+}
+
+// Export writes a json result as a row
+func (w *Writer) Export(data []byte) {
+	r := &result{}
+	if err := jsoniter.Unmarshal(data, r); err != nil {
+		gologger.Warningf("Could not parse result for %s: %s\n", w.name, err)
+		return
+	}
+	row := make([]string, len(w.fields))
+	for i, field := range w.fields {
+		row[i] = columns[field](r)
+	}
+
+	w.mutex.Lock()
+	defer w.mutex.Unlock()
+	if err := w.write(row); err != nil {
+		gologger.Errorf("Could not write result to %s: %s\n", w.name, err)
+		return
+	}
+	w.count++
+}
+
+// write writes and flushes a row
+func (w *Writer) write(row []string) error {
+	if err := w.writer.Write(row); err != nil {
+		return err
+	}
+	w.writer.Flush()
+	return w.writer.Error()
+}
+
+// Count returns the number of rows written, without the header
+func (w *Writer) Count() int {
+	w.mutex.Lock()
+	defer w.mutex.Unlock()
+	return w.count
+}
+
+// Name returns the name of the csv file
+func (w *Writer) Name() string {
+	return w.name
+}
+
+// Close closes the csv file
+func (w *Writer) Close() error {
+	w.mutex.Lock()
+	defer w.mutex.Unlock()
+	return w.file.Close()
+}
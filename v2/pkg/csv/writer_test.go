@@ -0,0 +1,82 @@
+package csv
+
+import (
+	stdcsv "encoding/csv"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"sync"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestParseFields(t *testing.T) {
+	fields, err := ParseFields("")
+	require.Nil(t, err, "Could not parse the default fields")
+	require.Equal(t, DefaultFields, fields, "Could not use the default fields")
+
+	fields, err = ParseFields("severity, Template-ID,matched-at")
+	require.Nil(t, err, "Could not parse fields")
+	require.Equal(t, []string{"severity", "template-id", "matched-at"}, fields, "Could not keep the order of the fields")
+
+	_, err = ParseFields("severity,url")
+	require.NotNil(t, err, "Could not reject an unknown field")
+}
+
+func TestWriter(t *testing.T) {
+	directory, err := ioutil.TempDir("", "csv-*")
+	require.Nil(t, err, "Could not create temporary directory")
+	defer os.RemoveAll(directory)
+	file := filepath.Join(directory, "results.csv")
+
+	writer, err := New(file, DefaultFields)
+	require.Nil(t, err, "Could not create writer")
+	writer.Export([]byte(`{"timestamp":"2021-10-05T12:00:00Z","template":"cve-2021-1","name":"A \"quoted\", name","severity":"high","host":"https://example.com","matched":"https://example.com/a","matcher_name":"body","extracted_results":["a,b","line\nbreak"],"tags":["cve","rce"]}`))
+	require.Nil(t, writer.Close(), "Could not close writer")
+
+	// appending doesn't write the header again
+	writer, err = New(file, DefaultFields)
+	require.Nil(t, err, "Could not append to the file")
+	var wg sync.WaitGroup
+	for i := 0; i < 50; i++ {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			writer.Export([]byte(fmt.Sprintf(`{"template":"concurrent-%d","extracted_results":["%d,%d"]}`, i, i, i)))
+		}(i)
+	}
+	wg.Wait()
+	require.Equal(t, 50, writer.Count(), "Could not count the rows")
+	require.Nil(t, writer.Close(), "Could not close writer")
+
+	f, err := os.Open(file)
+	require.Nil(t, err, "Could not open the csv file")
+	defer f.Close()
+	rows, err := stdcsv.NewReader(f).ReadAll()
+	require.Nil(t, err, "Could not read the csv file back")
+	require.Len(t, rows, 52, "Could not write a single header and the rows")
+	require.Equal(t, DefaultFields, rows[0], "Could not write the header")
+	require.Equal(t, []string{"2021-10-05T12:00:00Z", "cve-2021-1", `A "quoted", name`, "high", "https://example.com", "https://example.com/a", "body", "a,b;line\nbreak", "cve,rce"}, rows[1], "Could not quote the values")
+
+	_, err = New(file, []string{"severity", "host"})
+	require.NotNil(t, err, "Could not refuse a file with other columns")
+}
+
+func TestWriterUnterminatedHeader(t *testing.T) {
+	directory, err := ioutil.TempDir("", "csv-*")
+	require.Nil(t, err, "Could not create temporary directory")
+	defer os.RemoveAll(directory)
+	file := filepath.Join(directory, "results.csv")
+	require.Nil(t, ioutil.WriteFile(file, []byte("severity,host"), 0644), "Could not write the csv file")
+
+	writer, err := New(file, []string{"severity", "host"})
+	require.Nil(t, err, "Could not append to the file")
+	writer.Export([]byte(`{"severity":"low","host":"example.com"}`))
+	require.Nil(t, writer.Close(), "Could not close writer")
+
+	data, err := ioutil.ReadFile(file)
+	require.Nil(t, err, "Could not read the csv file")
+	require.Equal(t, "severity,host\nlow,example.com\n", string(data), "Could not start the rows on a new line")
+}
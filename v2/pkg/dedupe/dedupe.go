@@ -0,0 +1,256 @@
+package dedupe
+
+import (
+	"bufio"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"io/ioutil"
+	"net"
+	"net/url"
+	"os"
+	"path"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"sync/atomic"
+)
+
+// DefaultKey are the fields of the fingerprints by default
+var DefaultKey = []string{"template-id", "matcher-name", "host", "location"}
+
+// fields are the known fields of the fingerprints
+var fields = map[string]struct{}{
+	"template-id":      {},
+	"matcher-name":     {},
+	"host":             {},
+	"location":         {},
+	"extracted-values": {},
+}
+
+// Status is the status of a finding
+type Status int
+
+const (
+	// NewFinding is a finding which wasn't seen before
+	NewFinding Status = iota
+	// DuplicateFinding is a finding already reported by the current run
+	DuplicateFinding
+	// KnownFinding is a finding reported by a previous run of the state file
+	KnownFinding
+)
+
+// String returns the name of the status as written in the json output
+func (s Status) String() string {
+	switch s {
+	case DuplicateFinding:
+		return "duplicate"
+	case KnownFinding:
+		return "known"
+	}
+	return "new"
+}
+
+// Finding is the part of a result its fingerprint is computed from
+type Finding struct {
+	TemplateID  string
+	MatcherName string
+	// Matched is the matched url or domain
+	Matched   string
+	Extracted []string
+}
+
+// Deduper suppresses the findings whose fingerprint was already seen in the
+// current run or in a previous run of the state file if any.
+type Deduper struct {
+	key   []string
+	state string
+
+	mutex    sync.Mutex
+	seen     map[string]struct{}
+	previous map[string]struct{}
+
+	duplicates uint64
+	known      uint64
+}
+
+// ParseKey returns the comma separated fields of the fingerprints, failing
+// on the unknown ones. The default key is returned if none is given.
+func ParseKey(value string) ([]string, error) {
+	if value == "" {
+		return DefaultKey, nil
+	}
+	var key []string
+	for _, field := range strings.Split(value, ",") {
+		field = strings.ToLower(strings.TrimSpace(field))
+		if field == "" {
+			continue
+		}
+		if _, ok := fields[field]; !ok {
+			return nil, fmt.Errorf("unknown dedupe field %s, it should be one of template-id,matcher-name,host,location,extracted-values", field)
+		}
+		key = append(key, field)
+	}
+	if len(key) == 0 {
+		return nil, fmt.Errorf("no dedupe fields given")
+	}
+	return key, nil
+}
+
+// New creates a deduper with the fields of a key, loading the fingerprints
+// of the previous runs from the state file if any. A missing state file is
+// created on save.
+func New(key []string, state string) (*Deduper, error) {
+	d := &Deduper{
+		key:      key,
+		state:    state,
+		seen:     make(map[string]struct{}),
+		previous: make(map[string]struct{}),
+	}
+	if state == "" {
+		return d, nil
+	}
+	file, err := os.Open(state)
+	if os.IsNotExist(err) {
+		return d, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("could not open dedupe state: %s", err)
+	}
+	defer file.Close()
+	scanner := bufio.NewScanner(file)
+	for scanner.Scan() {
+		if line := strings.TrimSpace(scanner.Text()); line != "" {
+			d.previous[line] = struct{}{}
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, fmt.Errorf("could not read dedupe state %s: %s", state, err)
+	}
+	return d, nil
+}
+
+// Check returns the status of a finding, recording its fingerprint. The
+// duplicate and known findings are counted.
+func (d *Deduper) Check(finding *Finding) Status {
+	fingerprint := Fingerprint(d.key, finding)
+
+	d.mutex.Lock()
+	defer d.mutex.Unlock()
+	if _, ok := d.seen[fingerprint]; ok {
+		atomic.AddUint64(&d.duplicates, 1)
+		return DuplicateFinding
+	}
+	d.seen[fingerprint] = struct{}{}
+	if _, ok := d.previous[fingerprint]; ok {
+		atomic.AddUint64(&d.known, 1)
+		return KnownFinding
+	}
+	return NewFinding
+}
+
+// Duplicates returns the number of findings already reported by the run
+func (d *Deduper) Duplicates() uint64 {
+	return atomic.LoadUint64(&d.duplicates)
+}
+
+// Known returns the number of findings reported by a previous run
+func (d *Deduper) Known() uint64 {
+	return atomic.LoadUint64(&d.known)
+}
+
+// State returns the name of the state file if any
+func (d *Deduper) State() string {
+	return d.state
+}
+
+// Save writes the fingerprints of the previous runs and of the current one
+// to the state file if any, replacing it atomically.
+func (d *Deduper) Save() error {
+	if d.state == "" {
+		return nil
+	}
+	d.mutex.Lock()
+	fingerprints := make([]string, 0, len(d.seen)+len(d.previous))
+	for fingerprint := range d.previous {
+		fingerprints = append(fingerprints, fingerprint)
+	}
+	for fingerprint := range d.seen {
+		if _, ok := d.previous[fingerprint]; !ok {
+			fingerprints = append(fingerprints, fingerprint)
+		}
+	}
+	d.mutex.Unlock()
+	sort.Strings(fingerprints)
+
+	file, err := ioutil.TempFile(filepath.Dir(d.state), "."+filepath.Base(d.state)+".*")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(file.Name())
+	writer := bufio.NewWriter(file)
+	for _, fingerprint := range fingerprints {
+		writer.WriteString(fingerprint)
+		writer.WriteRune('\n')
+	}
+	if err := writer.Flush(); err != nil {
+		file.Close()
+		return err
+	}
+	if err := file.Close(); err != nil {
+		return err
+	}
+	if err := os.Chmod(file.Name(), 0644); err != nil {
+		return err
+	}
+	return os.Rename(file.Name(), d.state)
+}
+
+// Fingerprint returns the hex encoded hash of the fields of a key of a finding
+func Fingerprint(key []string, finding *Finding) string {
+	host, location := normalize(finding.Matched)
+	values := make([]string, 0, len(key))
+	for _, field := range key {
+		switch field {
+		case "template-id":
+			values = append(values, finding.TemplateID)
+		case "matcher-name":
+			values = append(values, finding.MatcherName)
+		case "host":
+			values = append(values, host)
+		case "location":
+			values = append(values, location)
+		case "extracted-values":
+			extracted := append([]string{}, finding.Extracted...)
+			sort.Strings(extracted)
+			values = append(values, strings.Join(extracted, "\n"))
+		}
+	}
+	hash := sha256.Sum256([]byte(strings.Join(values, "\x00")))
+	return hex.EncodeToString(hash[:])
+}
+
+// normalize returns the host and the location of a matched url, the scheme
+// and the default ports being ignored, the path cleaned and the query
+// parameters sorted. The location of a domain is the domain itself.
+func normalize(matched string) (string, string) {
+	parsed, err := url.Parse(matched)
+	if err != nil || parsed.Host == "" {
+		domain := strings.TrimSuffix(strings.ToLower(matched), ".")
+		return domain, domain
+	}
+	host := strings.ToLower(parsed.Host)
+	if h, port, err := net.SplitHostPort(host); err == nil && (port == "80" || port == "443") {
+		host = h
+	}
+	// the duplicate slashes of the targets ending with a slash are cleaned
+	location := path.Clean("/" + parsed.EscapedPath())
+	if strings.HasSuffix(parsed.EscapedPath(), "/") && location != "/" {
+		location += "/"
+	}
+	if parsed.RawQuery != "" {
+		location += "?" + parsed.Query().Encode()
+	}
+	return host, location
+}
@@ -0,0 +1,68 @@
+package dedupe
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestParseKey(t *testing.T) {
+	key, err := ParseKey("")
+	require.Nil(t, err, "Could not parse the default key")
+	require.Equal(t, DefaultKey, key, "Could not use the default key")
+
+	key, err = ParseKey("template-id, Location")
+	require.Nil(t, err, "Could not parse key")
+	require.Equal(t, []string{"template-id", "location"}, key, "Could not parse the fields of the key")
+
+	_, err = ParseKey("template-id,url")
+	require.NotNil(t, err, "Could not reject an unknown field")
+}
+
+func TestDeduper(t *testing.T) {
+	deduper, err := New(DefaultKey, "")
+	require.Nil(t, err, "Could not create deduper")
+
+	finding := &Finding{TemplateID: "exposed-git", Matched: "https://example.com/.git/config?b=2&a=1"}
+	require.Equal(t, NewFinding, deduper.Check(finding), "Could not report a new finding")
+	require.Equal(t, DuplicateFinding, deduper.Check(&Finding{TemplateID: "exposed-git", Matched: "https://EXAMPLE.com:443/.git/config?a=1&b=2"}), "Could not normalize the matched location")
+	require.Equal(t, NewFinding, deduper.Check(&Finding{TemplateID: "exposed-git", Matched: "https://93.184.216.34/.git/config?a=1&b=2"}), "Could not report the finding of another host")
+	require.Equal(t, NewFinding, deduper.Check(&Finding{TemplateID: "exposed-git", MatcherName: "config", Matched: finding.Matched}), "Could not report the finding of another matcher")
+	require.Equal(t, uint64(1), deduper.Duplicates(), "Could not count the duplicates")
+
+	// the findings of the aliases of a host collapse without the host
+	deduper, err = New([]string{"template-id", "location"}, "")
+	require.Nil(t, err, "Could not create deduper")
+	require.Equal(t, NewFinding, deduper.Check(&Finding{TemplateID: "exposed-git", Matched: "https://example.com/.git/config"}), "Could not report a new finding")
+	require.Equal(t, DuplicateFinding, deduper.Check(&Finding{TemplateID: "exposed-git", Matched: "http://93.184.216.34/.git/config"}), "Could not ignore the host")
+	require.Equal(t, DuplicateFinding, deduper.Check(&Finding{TemplateID: "exposed-git", Matched: "https://www.example.com//.git/config"}), "Could not clean the path")
+	require.Equal(t, NewFinding, deduper.Check(&Finding{TemplateID: "exposed-git", Matched: "https://www.example.com/.git/config/"}), "Could not keep the trailing slash")
+}
+
+func TestDeduperState(t *testing.T) {
+	directory, err := ioutil.TempDir("", "dedupe-*")
+	require.Nil(t, err, "Could not create temporary directory")
+	defer os.RemoveAll(directory)
+	state := filepath.Join(directory, "state")
+
+	deduper, err := New(DefaultKey, state)
+	require.Nil(t, err, "Could not create deduper without state file")
+	require.Equal(t, NewFinding, deduper.Check(&Finding{TemplateID: "first", Matched: "example.com"}), "Could not report a new finding")
+	require.Nil(t, deduper.Save(), "Could not save state")
+
+	// a re-scan only reports the new findings
+	deduper, err = New(DefaultKey, state)
+	require.Nil(t, err, "Could not load state")
+	require.Equal(t, KnownFinding, deduper.Check(&Finding{TemplateID: "first", Matched: "example.com."}), "Could not report the known finding")
+	require.Equal(t, DuplicateFinding, deduper.Check(&Finding{TemplateID: "first", Matched: "example.com"}), "Could not report the duplicate of the known finding")
+	require.Equal(t, NewFinding, deduper.Check(&Finding{TemplateID: "second", Matched: "example.com"}), "Could not report a new finding")
+	require.Equal(t, uint64(1), deduper.Known(), "Could not count the known findings")
+	require.Nil(t, deduper.Save(), "Could not save state")
+
+	data, err := ioutil.ReadFile(state)
+	require.Nil(t, err, "Could not read state")
+	require.Len(t, data, 2*65, "Could not save the fingerprints of both runs")
+}
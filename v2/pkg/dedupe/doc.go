@@ -0,0 +1,7 @@
+// Package dedupe suppresses the identical findings of a scan, such as the
+// findings of a host scanned by its hostname and its ip, keyed by a
+// fingerprint of their template, matcher and matched location.
+//
+// The fingerprints can be persisted across runs in a state file, so that a
+// scan only reports the findings which are new since the previous runs.
+package dedupe
@@ -0,0 +1,101 @@
+package dnsrecords
+
+import (
+	"strconv"
+	"strings"
+
+	"github.com/miekg/dns"
+)
+
+// Fields returns the normalized fields of all the answers of a dns message.
+//
+// Field names are prefixed by the lowercased record type, for example a CAA
+// record produces caa_flag, caa_tag and caa_value. Multiple records of the
+// same type append to the same field.
+func Fields(msg *dns.Msg) map[string][]string {
+	fields := make(map[string][]string)
+	for _, answer := range msg.Answer {
+		for name, value := range recordFields(answer) {
+			fields[name] = append(fields[name], value)
+		}
+	}
+	return fields
+}
+
+// recordFields returns the normalized fields of a single dns record
+func recordFields(rr dns.RR) map[string]string {
+	switch record := rr.(type) {
+	case *dns.A:
+		return map[string]string{"a": record.A.String()}
+	case *dns.AAAA:
+		return map[string]string{"aaaa": record.AAAA.String()}
+	case *dns.CNAME:
+		return map[string]string{"cname": record.Target}
+	case *dns.NS:
+		return map[string]string{"ns": record.Ns}
+	case *dns.PTR:
+		return map[string]string{"ptr": record.Ptr}
+	case *dns.TXT:
+		return map[string]string{"txt": strings.Join(record.Txt, "")}
+	case *dns.MX:
+		return map[string]string{
+			"mx_preference": strconv.Itoa(int(record.Preference)),
+			"mx_host":       record.Mx,
+		}
+	case *dns.SOA:
+		return map[string]string{
+			"soa_ns":      record.Ns,
+			"soa_mbox":    record.Mbox,
+			"soa_serial":  strconv.FormatUint(uint64(record.Serial), 10),
+			"soa_refresh": strconv.FormatUint(uint64(record.Refresh), 10),
+			"soa_retry":   strconv.FormatUint(uint64(record.Retry), 10),
+			"soa_expire":  strconv.FormatUint(uint64(record.Expire), 10),
+			"soa_minttl":  strconv.FormatUint(uint64(record.Minttl), 10),
+		}
+	case *dns.CAA:
+		return map[string]string{
+			"caa_flag":  strconv.Itoa(int(record.Flag)),
+			"caa_tag":   record.Tag,
+			"caa_value": record.Value,
+		}
+	case *dns.SRV:
+		return map[string]string{
+			"srv_priority": strconv.Itoa(int(record.Priority)),
+			"srv_weight":   strconv.Itoa(int(record.Weight)),
+			"srv_port":     strconv.Itoa(int(record.Port)),
+			"srv_target":   record.Target,
+		}
+	case *dns.NAPTR:
+		return map[string]string{
+			"naptr_order":       strconv.Itoa(int(record.Order)),
+			"naptr_preference":  strconv.Itoa(int(record.Preference)),
+			"naptr_flags":       record.Flags,
+			"naptr_service":     record.Service,
+			"naptr_regexp":      record.Regexp,
+			"naptr_replacement": record.Replacement,
+		}
+	case *dns.RRSIG:
+		return map[string]string{
+			"rrsig_type_covered": dns.TypeToString[record.TypeCovered],
+			"rrsig_algorithm":    strconv.Itoa(int(record.Algorithm)),
+			"rrsig_signer":       record.SignerName,
+			"rrsig_key_tag":      strconv.Itoa(int(record.KeyTag)),
+			"rrsig_inception":    strconv.FormatUint(uint64(record.Inception), 10),
+			"rrsig_expiration":   strconv.FormatUint(uint64(record.Expiration), 10),
+		}
+	case *dns.DNSKEY:
+		return map[string]string{
+			"dnskey_flags":     strconv.Itoa(int(record.Flags)),
+			"dnskey_algorithm": strconv.Itoa(int(record.Algorithm)),
+			"dnskey_key_tag":   strconv.Itoa(int(record.KeyTag())),
+		}
+	case *dns.DS:
+		return map[string]string{
+			"ds_key_tag":     strconv.Itoa(int(record.KeyTag)),
+			"ds_algorithm":   strconv.Itoa(int(record.Algorithm)),
+			"ds_digest_type": strconv.Itoa(int(record.DigestType)),
+			"ds_digest":      record.Digest,
+		}
+	}
+	return nil
+}
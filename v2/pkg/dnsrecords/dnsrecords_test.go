@@ -0,0 +1,73 @@
+package dnsrecords
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/miekg/dns"
+	"github.com/stretchr/testify/require"
+)
+
+func TestFields(t *testing.T) {
+	msg := new(dns.Msg)
+	for _, record := range []string{
+		`example.com. 300 IN CAA 0 issue "letsencrypt.org"`,
+		`example.com. 300 IN CAA 0 iodef "mailto:security@example.com"`,
+		`_ldap._tcp.example.com. 300 IN SRV 10 5 389 ldap.example.com.`,
+	} {
+		rr, err := dns.NewRR(record)
+		require.Nil(t, err, "Could not parse record")
+		msg.Answer = append(msg.Answer, rr)
+	}
+
+	fields := Fields(msg)
+	require.Equal(t, []string{"issue", "iodef"}, fields["caa_tag"], "Could not normalize CAA tags")
+	require.Equal(t, []string{"letsencrypt.org", "mailto:security@example.com"}, fields["caa_value"], "Could not normalize CAA values")
+	require.Equal(t, []string{"389"}, fields["srv_port"], "Could not normalize SRV port")
+	require.Equal(t, []string{"ldap.example.com."}, fields["srv_target"], "Could not normalize SRV target")
+}
+
+func TestSignatureWindow(t *testing.T) {
+	msg := new(dns.Msg)
+	for _, record := range []string{
+		`example.com. 300 IN A 93.184.216.34`,
+		`example.com. 300 IN RRSIG A 13 2 300 20201020000000 20201001000000 12345 example.com. dGVzdA==`,
+		`example.com. 300 IN RRSIG A 8 2 300 20201015000000 20201005000000 54321 example.com. dGVzdA==`,
+	} {
+		rr, err := dns.NewRR(record)
+		require.Nil(t, err, "Could not parse record")
+		msg.Answer = append(msg.Answer, rr)
+	}
+
+	inception, expiration, ok := SignatureWindow(msg)
+	require.True(t, ok, "Could not find signatures")
+	require.Equal(t, int64(1601856000), inception, "Could not get latest inception")
+	require.Equal(t, int64(1602720000), expiration, "Could not get earliest expiration")
+	require.Equal(t, []string{"1603152000", "1602720000"}, Fields(msg)["rrsig_expiration"], "Could not normalize RRSIG expirations")
+	require.True(t, HasRecord(msg, dns.TypeRRSIG), "Could not find RRSIG record")
+	require.False(t, HasRecord(msg, dns.TypeDNSKEY), "Found unexpected DNSKEY record")
+}
+
+func TestSectionFields(t *testing.T) {
+	msg := new(dns.Msg)
+	for _, record := range []string{
+		`www.example.com. 300 IN CNAME example.edgekey.net.`,
+		`example.edgekey.net. 60 IN A 93.184.216.34`,
+		`example.edgekey.net. 60 IN A 93.184.216.35`,
+	} {
+		rr, err := dns.NewRR(record)
+		require.Nil(t, err, "Could not parse record")
+		msg.Answer = append(msg.Answer, rr)
+	}
+	txt := &dns.TXT{
+		Hdr: dns.RR_Header{Name: "example.com.", Rrtype: dns.TypeTXT, Class: dns.ClassINET, Ttl: 300},
+		Txt: []string{strings.Repeat("a", 255), strings.Repeat("b", 255), "c"},
+	}
+	msg.Answer = append(msg.Answer, txt)
+
+	fields := SectionFields(Sections(msg)[AnswerSection])
+	require.Equal(t, []string{"example.edgekey.net."}, fields["cname.data"], "Could not extract CNAME target")
+	require.Equal(t, []string{"93.184.216.34", "93.184.216.35"}, fields["a.data"], "Could not extract A records of the chain")
+	require.Equal(t, []string{"300", "60", "60", "300"}, fields["ttl"], "Could not extract record ttls")
+	require.Len(t, fields["txt.data"][0], 511, "Could not extract the complete TXT record")
+}
@@ -0,0 +1,59 @@
+package dnsrecords
+
+import (
+	"strings"
+
+	"github.com/miekg/dns"
+)
+
+// Signatures returns the RRSIG records of all the sections of a dns message
+func Signatures(msg *dns.Msg) []*dns.RRSIG {
+	var signatures []*dns.RRSIG
+	for _, section := range [][]dns.RR{msg.Answer, msg.Ns, msg.Extra} {
+		for _, record := range section {
+			if rrsig, ok := record.(*dns.RRSIG); ok {
+				signatures = append(signatures, rrsig)
+			}
+		}
+	}
+	return signatures
+}
+
+// SignaturesString returns the RRSIG records of a dns message, one per line
+func SignaturesString(msg *dns.Msg) string {
+	builder := &strings.Builder{}
+	for _, rrsig := range Signatures(msg) {
+		builder.WriteString(rrsig.String())
+		builder.WriteRune('\n')
+	}
+	return builder.String()
+}
+
+// SignatureWindow returns the latest inception and the earliest expiration
+// of the RRSIG records of a dns message as unix timestamps, the window in
+// which all the signatures are valid. ok is false if there are no signatures.
+func SignatureWindow(msg *dns.Msg) (inception, expiration int64, ok bool) {
+	for _, rrsig := range Signatures(msg) {
+		signatureInception, signatureExpiration := int64(rrsig.Inception), int64(rrsig.Expiration)
+		if !ok || signatureInception > inception {
+			inception = signatureInception
+		}
+		if !ok || signatureExpiration < expiration {
+			expiration = signatureExpiration
+		}
+		ok = true
+	}
+	return inception, expiration, ok
+}
+
+// HasRecord returns true if any section of a dns message contains a record of the type
+func HasRecord(msg *dns.Msg, rrtype uint16) bool {
+	for _, section := range [][]dns.RR{msg.Answer, msg.Ns, msg.Extra} {
+		for _, record := range section {
+			if record.Header().Rrtype == rrtype {
+				return true
+			}
+		}
+	}
+	return false
+}
@@ -0,0 +1,3 @@
+// Package dnsrecords normalizes dns answers into named fields
+// usable by matchers and extractors.
+package dnsrecords
@@ -0,0 +1,55 @@
+package dnsrecords
+
+import (
+	"strconv"
+
+	"github.com/miekg/dns"
+)
+
+// Response is a dns response along with the data collected by
+// the optional resolution modes of the dns requests.
+type Response struct {
+	// Msg is the final dns message received
+	Msg *dns.Msg
+	// Trace is the delegation chain if tracing was enabled
+	Trace *Trace
+	// Transfer is the result of the zone transfer for AXFR requests
+	Transfer *Transfer
+}
+
+// Transfer is the result of a zone transfer attempt
+type Transfer struct {
+	// Server is the nameserver which returned the records or the last one tried
+	Server string
+	// Success is true if the complete zone was transferred
+	Success bool
+	// Partial is true if the transfer was interrupted after receiving records
+	Partial bool
+	// Capped is true if records were dropped because of the records limit
+	Capped bool
+	// Rcode is the response code returned by the server
+	Rcode int
+	// Records are the transferred records
+	Records []dns.RR
+}
+
+// String returns the text of the response used by word and regex matchers
+func (r *Response) String() string {
+	return r.Msg.String()
+}
+
+// Fields returns the normalized fields of the answers along with
+// the fields of the trace and the zone transfer if any.
+func (r *Response) Fields() map[string][]string {
+	fields := Fields(r.Msg)
+	if r.Trace != nil {
+		for name, values := range r.Trace.Fields() {
+			fields[name] = values
+		}
+	}
+	if r.Transfer != nil {
+		fields["transfer_server"] = []string{r.Transfer.Server}
+		fields["transfer_count"] = []string{strconv.Itoa(len(r.Transfer.Records))}
+	}
+	return fields
+}
@@ -0,0 +1,92 @@
+package dnsrecords
+
+import (
+	"net"
+	"strconv"
+	"strings"
+
+	"github.com/miekg/dns"
+)
+
+// Names of the sections of a dns message
+const (
+	AnswerSection     = "answer"
+	AuthoritySection  = "authority"
+	AdditionalSection = "additional"
+)
+
+// Sections returns the records of each section of a dns message by name.
+//
+// The OPT pseudo record of the additional section is not returned.
+func Sections(msg *dns.Msg) map[string][]dns.RR {
+	var additional []dns.RR
+	for _, record := range msg.Extra {
+		if record.Header().Rrtype != dns.TypeOPT {
+			additional = append(additional, record)
+		}
+	}
+	return map[string][]dns.RR{
+		AnswerSection:     msg.Answer,
+		AuthoritySection:  msg.Ns,
+		AdditionalSection: additional,
+	}
+}
+
+// SectionString returns the records of a section, one per line
+func SectionString(records []dns.RR) string {
+	builder := &strings.Builder{}
+	for _, record := range records {
+		builder.WriteString(record.String())
+		builder.WriteRune('\n')
+	}
+	return builder.String()
+}
+
+// SectionFields returns the fields of each record of a section.
+//
+// Every record produces the name, type, ttl and data fields, and the same
+// fields prefixed by its lowercased type, i.e cname.data for the target of
+// the CNAME records only.
+func SectionFields(records []dns.RR) map[string][]string {
+	fields := make(map[string][]string)
+	for _, record := range records {
+		header := record.Header()
+		rrtype := dns.TypeToString[header.Rrtype]
+		values := map[string]string{
+			"name": header.Name,
+			"type": rrtype,
+			"ttl":  strconv.FormatUint(uint64(header.Ttl), 10),
+			"data": RecordData(record),
+		}
+		prefix := strings.ToLower(rrtype) + "."
+		for name, value := range values {
+			fields[name] = append(fields[name], value)
+			fields[prefix+name] = append(fields[prefix+name], value)
+		}
+	}
+	return fields
+}
+
+// RecordData returns the data of a record without its header.
+//
+// The character strings of TXT records are joined as a single value.
+func RecordData(record dns.RR) string {
+	if txt, ok := record.(*dns.TXT); ok {
+		return strings.Join(txt.Txt, "")
+	}
+	return strings.TrimPrefix(record.String(), record.Header().String())
+}
+
+// AnswerIPs returns the addresses of the A and AAAA records of the answer section
+func AnswerIPs(msg *dns.Msg) []net.IP {
+	var ips []net.IP
+	for _, record := range msg.Answer {
+		switch v := record.(type) {
+		case *dns.A:
+			ips = append(ips, v.A)
+		case *dns.AAAA:
+			ips = append(ips, v.AAAA)
+		}
+	}
+	return ips
+}
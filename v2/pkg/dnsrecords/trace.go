@@ -0,0 +1,63 @@
+package dnsrecords
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/miekg/dns"
+)
+
+// Trace is the delegation chain followed while resolving a question iteratively
+type Trace struct {
+	Hops []*TraceHop
+}
+
+// TraceHop is a single step of the delegation chain
+type TraceHop struct {
+	// Zone is the zone the server was queried for
+	Zone string
+	// Server is the address of the server which responded
+	Server string
+	// Rcode is the response code returned by the server
+	Rcode int
+	// Records are all the records returned by the server
+	Records []dns.RR
+}
+
+// String returns the trace in a human readable format, one hop per block
+func (t *Trace) String() string {
+	builder := &strings.Builder{}
+	for _, hop := range t.Hops {
+		fmt.Fprintf(builder, ";; %s from %s (%s)\n", hop.Zone, hop.Server, dns.RcodeToString[hop.Rcode])
+		for _, record := range hop.Records {
+			builder.WriteString(record.String())
+			builder.WriteRune('\n')
+		}
+	}
+	return builder.String()
+}
+
+// Fields returns the structured fields of the trace.
+//
+// trace_servers contains the servers consulted in order, trace_zones the zones
+// delegated to them and trace_ns the authoritative nameservers of the last hop.
+func (t *Trace) Fields() map[string][]string {
+	fields := make(map[string][]string)
+	for _, hop := range t.Hops {
+		fields["trace_servers"] = append(fields["trace_servers"], hop.Server)
+		fields["trace_zones"] = append(fields["trace_zones"], hop.Zone)
+	}
+	for i := len(t.Hops) - 1; i >= 0; i-- {
+		var names []string
+		for _, record := range t.Hops[i].Records {
+			if ns, ok := record.(*dns.NS); ok {
+				names = append(names, ns.Ns)
+			}
+		}
+		if len(names) > 0 {
+			fields["trace_ns"] = names
+			break
+		}
+	}
+	return fields
+}
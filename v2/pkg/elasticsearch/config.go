@@ -0,0 +1,111 @@
+package elasticsearch
+
+import (
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"net/url"
+	"strings"
+	"time"
+
+	"gopkg.in/yaml.v2"
+)
+
+// datePlaceholder is replaced in the index names by the date of the results
+const datePlaceholder = "{{date}}"
+
+// Config is the configuration of the exporter, read from a yaml file
+type Config struct {
+	// Host is the url of the Elasticsearch cluster, i.e https://localhost:9200
+	Host string `yaml:"host"`
+	// Index is the name of the index, {{date}} being replaced by the date of
+	// the results formatted with the date format.
+	Index string `yaml:"index"`
+	// DateFormat is the go layout of the date of the index, 2006.01.02 by default
+	DateFormat string `yaml:"date-format,omitempty"`
+	// Username and Password are the credentials of the basic authentication
+	Username string `yaml:"username,omitempty"`
+	Password string `yaml:"password,omitempty"`
+	// APIKey is the encoded api key, used instead of the basic authentication
+	APIKey string `yaml:"api-key,omitempty"`
+	// SkipVerify skips the verification of the certificate of the cluster
+	SkipVerify bool `yaml:"skip-verify,omitempty"`
+	// BatchSize is the number of documents of a bulk request, 100 by default
+	BatchSize int `yaml:"batch-size,omitempty"`
+	// BatchInterval is the longest time before sending the pending documents,
+	// 5s by default.
+	BatchInterval time.Duration `yaml:"batch-interval,omitempty"`
+	// QueueSize is the number of documents waiting to be sent beyond which
+	// the results are dropped, 10000 by default.
+	QueueSize int `yaml:"queue-size,omitempty"`
+	// Retries is the number of retries of the documents rejected with a 429
+	// or 5xx status, 3 by default.
+	Retries *int `yaml:"retries,omitempty"`
+	// IncludeRR adds the raw requests and responses to the documents, which
+	// are large and not friendly to the mappings.
+	IncludeRR bool `yaml:"include-rr,omitempty"`
+	// DeadLetter is the file the documents failing to be indexed after the
+	// retries are appended to, elasticsearch-failed.jsonl by default.
+	DeadLetter string `yaml:"dead-letter,omitempty"`
+}
+
+// LoadConfig reads and validates the configuration of a yaml file
+func LoadConfig(file string) (*Config, error) {
+	data, err := ioutil.ReadFile(file)
+	if err != nil {
+		return nil, fmt.Errorf("could not read elasticsearch config: %s", err)
+	}
+	config := &Config{}
+	if err := yaml.UnmarshalStrict(data, config); err != nil {
+		return nil, fmt.Errorf("could not parse elasticsearch config %s: %s", file, err)
+	}
+	if err := config.validate(); err != nil {
+		return nil, fmt.Errorf("invalid elasticsearch config %s: %s", file, err)
+	}
+	return config, nil
+}
+
+// validate validates the configuration, setting the defaults
+func (c *Config) validate() error {
+	if c.Host == "" {
+		return errors.New("no host given")
+	}
+	if parsed, err := url.Parse(c.Host); err != nil || (parsed.Scheme != "http" && parsed.Scheme != "https") || parsed.Host == "" {
+		return fmt.Errorf("invalid host %s, it should be like https://localhost:9200", c.Host)
+	}
+	c.Host = strings.TrimSuffix(c.Host, "/")
+	if c.Index == "" {
+		c.Index = "nuclei-" + datePlaceholder
+	}
+	if c.DateFormat == "" {
+		c.DateFormat = "2006.01.02"
+	}
+	if c.APIKey != "" && (c.Username != "" || c.Password != "") {
+		return errors.New("both an api key and a basic authentication are given")
+	}
+	if c.BatchSize < 0 || c.BatchInterval < 0 || c.QueueSize < 0 || (c.Retries != nil && *c.Retries < 0) {
+		return errors.New("the batch size and interval, queue size and retries should be 0 or more")
+	}
+	if c.BatchSize == 0 {
+		c.BatchSize = 100
+	}
+	if c.BatchInterval == 0 {
+		c.BatchInterval = 5 * time.Second
+	}
+	if c.QueueSize == 0 {
+		c.QueueSize = 10000
+	}
+	if c.Retries == nil {
+		retries := 3
+		c.Retries = &retries
+	}
+	if c.DeadLetter == "" {
+		c.DeadLetter = "elasticsearch-failed.jsonl"
+	}
+	return nil
+}
+
+// index returns the name of the index of the results of a time
+func (c *Config) index(t time.Time) string {
+	return strings.Replace(c.Index, datePlaceholder, t.UTC().Format(c.DateFormat), -1)
+}
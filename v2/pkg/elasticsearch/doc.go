@@ -0,0 +1,4 @@
+// Package elasticsearch exports the results of a scan to an Elasticsearch
+// index with the bulk api, the documents having the schema of the json
+// output.
+package elasticsearch
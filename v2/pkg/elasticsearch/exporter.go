@@ -0,0 +1,220 @@
+package elasticsearch
+
+import (
+	"bytes"
+	"crypto/tls"
+	"errors"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"os"
+	"sync/atomic"
+	"time"
+
+	jsoniter "github.com/json-iterator/go"
+	"github.com/projectdiscovery/gologger"
+	"github.com/projectdiscovery/nuclei/v2/pkg/export"
+)
+
+// document is a result waiting to be indexed
+type document struct {
+	index string
+	data  []byte
+}
+
+// Exporter indexes the results of a scan in batches from a bounded queue,
+// so that the scan is never blocked by the cluster.
+//
+// The results are dropped and counted when the queue is full. The documents
+// rejected with a 429 or 5xx status are retried with an exponential backoff
+// and the ones still failing are appended to the dead letter file.
+type Exporter struct {
+	config *Config
+	client *http.Client
+	queue  *export.Queue
+	// backoff is the delay before the first retry, doubled for each retry
+	backoff time.Duration
+
+	indexed uint64
+	failed  uint64
+}
+
+// New creates an exporter sending the results to the cluster of a config
+func New(config *Config) *Exporter {
+	transport := http.DefaultTransport.(*http.Transport).Clone()
+	transport.TLSClientConfig = &tls.Config{InsecureSkipVerify: config.SkipVerify}
+	e := &Exporter{
+		config:  config,
+		client:  &http.Client{Transport: transport, Timeout: 30 * time.Second},
+		backoff: time.Second,
+	}
+	e.queue = export.NewQueue(config.QueueSize, config.BatchSize, config.BatchInterval, e.send)
+	return e
+}
+
+// IncludeRR returns true if the documents include the raw requests and responses
+func (e *Exporter) IncludeRR() bool {
+	return e.config.IncludeRR
+}
+
+// Export queues a json result to index, dropping it if the queue is full
+func (e *Exporter) Export(data []byte) {
+	e.queue.Push(data)
+}
+
+// Close sends the queued results, waiting for the retries to complete
+func (e *Exporter) Close() {
+	e.queue.Close()
+}
+
+// Indexed returns the number of indexed results
+func (e *Exporter) Indexed() uint64 {
+	return atomic.LoadUint64(&e.indexed)
+}
+
+// Queued returns the number of results waiting in the queue
+func (e *Exporter) Queued() int {
+	return e.queue.Len()
+}
+
+// Dropped returns the number of results dropped as the queue was full
+func (e *Exporter) Dropped() uint64 {
+	return e.queue.Dropped()
+}
+
+// Failed returns the number of results written to the dead letter file
+func (e *Exporter) Failed() uint64 {
+	return atomic.LoadUint64(&e.failed)
+}
+
+// DeadLetter returns the name of the dead letter file
+func (e *Exporter) DeadLetter() string {
+	return e.config.DeadLetter
+}
+
+// send indexes a batch, retrying the rejected documents with a backoff
+func (e *Exporter) send(batch [][]byte) {
+	index := e.config.index(time.Now())
+	pending := make([]*document, 0, len(batch))
+	for _, data := range batch {
+		pending = append(pending, &document{index: index, data: data})
+	}
+	export.Retry(*e.config.Retries, e.backoff, func() (bool, error) {
+		retry, failed, err := e.bulk(pending)
+		if err != nil {
+			gologger.Warningf("Could not index %d results in elasticsearch: %s\n", len(retry)+len(failed), err)
+		}
+		e.writeDeadLetter(failed)
+		pending = retry
+		if len(retry) > 0 {
+			return true, errors.New("documents were throttled")
+		}
+		return false, nil
+	})
+	e.writeDeadLetter(pending)
+}
+
+// bulkResponse is the response of the bulk api
+type bulkResponse struct {
+	Errors bool `json:"errors"`
+	Items  []map[string]struct {
+		Status int `json:"status"`
+	} `json:"items"`
+}
+
+// bulk sends documents with the bulk api, returning the documents to retry
+// and the ones rejected for good.
+func (e *Exporter) bulk(docs []*document) ([]*document, []*document, error) {
+	body := &bytes.Buffer{}
+	for _, doc := range docs {
+		action, err := jsoniter.Marshal(map[string]map[string]string{"index": {"_index": doc.index}})
+		if err != nil {
+			return nil, docs, err
+		}
+		body.Write(action)
+		body.WriteRune('\n')
+		body.Write(doc.data)
+		body.WriteRune('\n')
+	}
+	req, err := http.NewRequest(http.MethodPost, e.config.Host+"/_bulk", body)
+	if err != nil {
+		return nil, docs, err
+	}
+	req.Header.Set("Content-Type", "application/x-ndjson")
+	if e.config.APIKey != "" {
+		req.Header.Set("Authorization", "ApiKey "+e.config.APIKey)
+	} else if e.config.Username != "" {
+		req.SetBasicAuth(e.config.Username, e.config.Password)
+	}
+
+	resp, err := e.client.Do(req)
+	if err != nil {
+		return docs, nil, err
+	}
+	defer resp.Body.Close()
+	data, err := ioutil.ReadAll(io.LimitReader(resp.Body, 10*1024*1024))
+	if err != nil {
+		return docs, nil, err
+	}
+	if resp.StatusCode == http.StatusTooManyRequests || resp.StatusCode >= 500 {
+		return docs, nil, fmt.Errorf("status %d", resp.StatusCode)
+	}
+	if resp.StatusCode >= 300 {
+		return nil, docs, fmt.Errorf("status %d: %s", resp.StatusCode, data)
+	}
+
+	var response bulkResponse
+	if err := jsoniter.Unmarshal(data, &response); err != nil {
+		return nil, docs, fmt.Errorf("could not parse bulk response: %s", err)
+	}
+	if !response.Errors {
+		atomic.AddUint64(&e.indexed, uint64(len(docs)))
+		return nil, nil, nil
+	}
+	if len(response.Items) != len(docs) {
+		return nil, docs, fmt.Errorf("got %d items for %d documents", len(response.Items), len(docs))
+	}
+	var retry, failed []*document
+	for i, item := range response.Items {
+		var status int
+		for _, result := range item {
+			status = result.Status
+		}
+		switch {
+		case status == http.StatusTooManyRequests || status >= 500:
+			retry = append(retry, docs[i])
+		case status >= 300:
+			failed = append(failed, docs[i])
+		default:
+			atomic.AddUint64(&e.indexed, 1)
+		}
+	}
+	if len(failed) > 0 {
+		return retry, failed, fmt.Errorf("%d documents were rejected", len(failed))
+	}
+	return retry, failed, nil
+}
+
+// writeDeadLetter appends the documents failing to be indexed to the dead
+// letter file, one per line.
+func (e *Exporter) writeDeadLetter(docs []*document) {
+	if len(docs) == 0 {
+		return
+	}
+	atomic.AddUint64(&e.failed, uint64(len(docs)))
+	file, err := os.OpenFile(e.config.DeadLetter, os.O_WRONLY|os.O_CREATE|os.O_APPEND, 0644)
+	if err != nil {
+		gologger.Errorf("Could not write %d results to %s: %s\n", len(docs), e.config.DeadLetter, err)
+		return
+	}
+	defer file.Close()
+	buffer := &bytes.Buffer{}
+	for _, doc := range docs {
+		buffer.Write(doc.data)
+		buffer.WriteRune('\n')
+	}
+	if _, err := file.Write(buffer.Bytes()); err != nil {
+		gologger.Errorf("Could not write %d results to %s: %s\n", len(docs), e.config.DeadLetter, err)
+	}
+}
@@ -0,0 +1,137 @@
+package elasticsearch
+
+import (
+	"bufio"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+func writeConfig(t *testing.T, directory, content string) string {
+	file := filepath.Join(directory, "elasticsearch.yaml")
+	require.Nil(t, ioutil.WriteFile(file, []byte(content), 0644), "Could not write config")
+	return file
+}
+
+func TestLoadConfig(t *testing.T) {
+	directory, err := ioutil.TempDir("", "elasticsearch-*")
+	require.Nil(t, err, "Could not create temporary directory")
+	defer os.RemoveAll(directory)
+
+	config, err := LoadConfig(writeConfig(t, directory, "host: https://localhost:9200/\napi-key: key\nbatch-interval: 2s\n"))
+	require.Nil(t, err, "Could not load config")
+	require.Equal(t, "https://localhost:9200", config.Host, "Could not trim the host")
+	require.Equal(t, 2*time.Second, config.BatchInterval, "Could not parse the batch interval")
+	require.Equal(t, 100, config.BatchSize, "Could not set the default batch size")
+	require.Equal(t, 3, *config.Retries, "Could not set the default retries")
+	require.Equal(t, "nuclei-2021.10.05", config.index(time.Date(2021, 10, 5, 12, 0, 0, 0, time.UTC)), "Could not format the date of the index")
+
+	_, err = LoadConfig(writeConfig(t, directory, "host: localhost:9200\n"))
+	require.NotNil(t, err, "Could not reject a host without scheme")
+	_, err = LoadConfig(writeConfig(t, directory, "host: https://localhost:9200\napi-key: key\nusername: elastic\n"))
+	require.NotNil(t, err, "Could not reject both authentications")
+	_, err = LoadConfig(writeConfig(t, directory, "host: https://localhost:9200\nbatch: 10\n"))
+	require.NotNil(t, err, "Could not reject an unknown field")
+}
+
+func TestExporter(t *testing.T) {
+	directory, err := ioutil.TempDir("", "elasticsearch-*")
+	require.Nil(t, err, "Could not create temporary directory")
+	defer os.RemoveAll(directory)
+
+	var mutex sync.Mutex
+	var requests int
+	indexed := make(map[string]string)
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		mutex.Lock()
+		defer mutex.Unlock()
+		requests++
+		if user, password, _ := r.BasicAuth(); r.URL.Path != "/_bulk" || user != "elastic" || password != "secret" {
+			w.WriteHeader(http.StatusUnauthorized)
+			return
+		}
+		// the first bulk request is throttled as a whole
+		if requests == 1 {
+			w.WriteHeader(http.StatusTooManyRequests)
+			return
+		}
+		var items []string
+		scanner := bufio.NewScanner(r.Body)
+		for scanner.Scan() {
+			action := scanner.Text()
+			scanner.Scan()
+			doc := scanner.Text()
+			status := 201
+			switch {
+			case strings.Contains(doc, "rejected"):
+				status = 400
+			case strings.Contains(doc, "throttled") && requests < 4:
+				status = 429
+			default:
+				indexed[doc] = action
+			}
+			items = append(items, fmt.Sprintf(`{"index":{"status":%d}}`, status))
+		}
+		fmt.Fprintf(w, `{"errors":true,"items":[%s]}`, strings.Join(items, ","))
+	}))
+	defer server.Close()
+
+	config, err := LoadConfig(writeConfig(t, directory, fmt.Sprintf("host: %s\nindex: nuclei-{{date}}\nusername: elastic\npassword: secret\nbatch-size: 2\nbatch-interval: 50ms\ndead-letter: %s\n", server.URL, filepath.Join(directory, "failed.jsonl"))))
+	require.Nil(t, err, "Could not load config")
+	exporter := New(config)
+	exporter.backoff = time.Millisecond
+
+	exporter.Export([]byte(`{"template":"indexed"}`))
+	exporter.Export([]byte(`{"template":"throttled"}`))
+	exporter.Export([]byte(`{"template":"rejected"}`))
+	exporter.Close()
+	exporter.Export([]byte(`{"template":"closed"}`))
+
+	require.Equal(t, uint64(2), exporter.Indexed(), "Could not index the results")
+	require.Equal(t, uint64(1), exporter.Failed(), "Could not count the rejected result")
+	require.Contains(t, indexed, `{"template":"throttled"}`, "Could not retry the throttled result")
+	require.Equal(t, fmt.Sprintf(`{"index":{"_index":"nuclei-%s"}}`, time.Now().UTC().Format("2006.01.02")), indexed[`{"template":"indexed"}`], "Could not index in the dated index")
+
+	data, err := ioutil.ReadFile(filepath.Join(directory, "failed.jsonl"))
+	require.Nil(t, err, "Could not read the dead letter file")
+	require.Equal(t, "{\"template\":\"rejected\"}\n", string(data), "Could not write the rejected result to the dead letter file")
+}
+
+func TestExporterOverflow(t *testing.T) {
+	directory, err := ioutil.TempDir("", "elasticsearch-*")
+	require.Nil(t, err, "Could not create temporary directory")
+	defer os.RemoveAll(directory)
+
+	release := make(chan struct{})
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		<-release
+		ioutil.ReadAll(r.Body)
+		fmt.Fprintf(w, `{"errors":false,"items":[]}`)
+	}))
+	defer server.Close()
+
+	config, err := LoadConfig(writeConfig(t, directory, fmt.Sprintf("host: %s\nbatch-size: 1\nqueue-size: 2\n", server.URL)))
+	require.Nil(t, err, "Could not load config")
+	exporter := New(config)
+
+	// the results are dropped without blocking while the cluster is stuck
+	start := time.Now()
+	for i := 0; i < 100; i++ {
+		exporter.Export([]byte(fmt.Sprintf(`{"template":"%d"}`, i)))
+	}
+	require.True(t, time.Since(start) < time.Second, "Could not export without blocking")
+	require.True(t, exporter.Dropped() > 0, "Could not drop the results of the full queue")
+
+	close(release)
+	exporter.Close()
+	require.Equal(t, uint64(100), exporter.Indexed()+exporter.Dropped(), "Could not index or drop each result")
+}
@@ -0,0 +1,5 @@
+// Package engine runs the templates of nuclei from other Go programs, the
+// results being streamed to a callback as the structured results of the
+// json output. The engines are independent, many of them running in a
+// process at once.
+package engine
@@ -0,0 +1,263 @@
+package engine
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"sync"
+	"time"
+
+	jsoniter "github.com/json-iterator/go"
+	"github.com/projectdiscovery/gologger"
+	"github.com/projectdiscovery/nuclei/v2/internal/runner"
+	"github.com/projectdiscovery/nuclei/v2/pkg/atomicboolean"
+	"github.com/projectdiscovery/nuclei/v2/pkg/executer"
+)
+
+// Result is a result of a template on a target, with the schema of the
+// json output.
+type Result = executer.ResultEvent
+
+var (
+	// ErrNoTemplates is the error of the engines finding none of their templates
+	ErrNoTemplates = runner.ErrNoTemplates
+	// ErrExecuted is the error of the engines executed more than once
+	ErrExecuted = errors.New("the engine was already executed")
+	// ErrClosed is the error of the engines executed once closed
+	ErrClosed = errors.New("the engine is closed")
+)
+
+// Options are the settings of an engine, the ones left empty being the
+// defaults of the flags of nuclei.
+type Options struct {
+	// Templates are the files, the directories, the wildcards and the urls
+	// of the templates to run, the relative ones being resolved from the
+	// working directory.
+	Templates []string
+	// Targets are the urls, the hosts and the cidr ranges to scan
+	Targets []string
+	// Tags, ExcludeTags, Severities and Authors select the templates to run
+	// by their info.
+	Tags        []string
+	ExcludeTags []string
+	Severities  []string
+	Authors     []string
+
+	// Concurrency is the number of requests sent at once, 50 by default
+	Concurrency int
+	// HostConcurrency is the maximum number of templates running towards
+	// each host at once, no limit if 0
+	HostConcurrency int
+	// RateLimit is the maximum number of requests per second to each host,
+	// no limit if 0
+	RateLimit int
+	// Timeout and Retries override the ones of the templates if not 0
+	Timeout time.Duration
+	Retries int
+	// Proxy is the url of the http or socks5 proxy the requests are sent
+	// through if any
+	Proxy string
+	// Headers are the headers added to the http requests, as Name: value
+	Headers []string
+
+	// InteractshServer is the url of the interactsh server handing out the
+	// urls of {{interactsh-url}}, the public one if empty. The templates
+	// using it are skipped with NoInteractsh.
+	InteractshServer string
+	NoInteractsh     bool
+	// Headless runs the headless requests in a chromium browser, the one of
+	// HeadlessBrowser if any, the templates using them being skipped
+	// otherwise.
+	Headless        bool
+	HeadlessBrowser string
+	// IncludeRR includes the raw requests and responses in the results,
+	// along with the curl commands.
+	IncludeRR bool
+	// ExtractorDir is the directory the to-file extractors of the templates
+	// append their values in, the working directory if empty.
+	ExtractorDir string
+}
+
+// Engine runs templates on targets once, streaming the results to the
+// callback of its execution. Its runner, the browser and the interactsh
+// client it started being released once closed.
+type Engine struct {
+	runner  *runner.Runner
+	results *resultExporter
+
+	// mutex guards the state of the execution, done being closed once it
+	// completed.
+	mutex    sync.Mutex
+	executed bool
+	closed   bool
+	done     chan struct{}
+}
+
+// New creates an engine from options, reading its targets. An error is
+// returned if the options are invalid.
+func New(options Options) (*Engine, error) {
+	results := &resultExporter{includeRR: options.IncludeRR}
+	runner, err := runner.New(options.runnerOptions(results))
+	if err != nil {
+		return nil, err
+	}
+	return &Engine{runner: runner, results: results}, nil
+}
+
+// runnerOptions returns the options of the runner of an engine, sending
+// the results to an exporter.
+func (options *Options) runnerOptions(results *resultExporter) *runner.Options {
+	runnerOptions := runner.DefaultOptions()
+	runnerOptions.Embedded = true
+	runnerOptions.Exporters = append(runnerOptions.Exporters, results)
+	runnerOptions.DisableProgressBar = true
+	runnerOptions.NoColor = true
+
+	runnerOptions.Templates = append(runnerOptions.Templates, options.Templates...)
+	runnerOptions.Inputs = options.Targets
+	runnerOptions.Tags = strings.Join(options.Tags, ",")
+	runnerOptions.ExcludeTags = strings.Join(options.ExcludeTags, ",")
+	runnerOptions.Severity = strings.Join(options.Severities, ",")
+	runnerOptions.Author = strings.Join(options.Authors, ",")
+
+	if options.Concurrency > 0 {
+		runnerOptions.Threads = options.Concurrency
+	}
+	runnerOptions.HostConcurrency = options.HostConcurrency
+	runnerOptions.RateLimitPerHost = options.RateLimit
+	if options.Timeout > 0 {
+		// the timeout of the runner is in seconds, rounded up
+		runnerOptions.Timeout = int((options.Timeout + time.Second - 1) / time.Second)
+		runnerOptions.Override("timeout")
+	}
+	if options.Retries > 0 {
+		runnerOptions.Retries = options.Retries
+		runnerOptions.Override("retries")
+	}
+	if strings.HasPrefix(options.Proxy, "socks5://") {
+		runnerOptions.ProxySocksURL = options.Proxy
+	} else {
+		runnerOptions.ProxyURL = options.Proxy
+	}
+	runnerOptions.CustomHeaders = append(runnerOptions.CustomHeaders, options.Headers...)
+
+	if options.InteractshServer != "" {
+		runnerOptions.InteractshServer = options.InteractshServer
+	}
+	runnerOptions.NoInteractsh = options.NoInteractsh
+	runnerOptions.Headless = options.Headless
+	runnerOptions.HeadlessBrowser = options.HeadlessBrowser
+	runnerOptions.ExtractorDir = options.ExtractorDir
+	return runnerOptions
+}
+
+// ExecuteWithContext runs the templates on the targets, calling callback
+// with each result as found, one at a time, the scan waiting for it to
+// return. The scan stops once the context is done, its error being
+// returned. An engine is executed once.
+func (e *Engine) ExecuteWithContext(ctx context.Context, callback func(*Result)) error {
+	e.mutex.Lock()
+	if e.closed {
+		e.mutex.Unlock()
+		return ErrClosed
+	}
+	if e.executed {
+		e.mutex.Unlock()
+		return ErrExecuted
+	}
+	e.executed = true
+	e.done = make(chan struct{})
+	e.mutex.Unlock()
+	defer close(e.done)
+
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+	e.results.start(callback)
+	defer e.results.stop()
+
+	var stopped atomicboolean.AtomBool
+	finished := make(chan struct{})
+	go func() {
+		select {
+		case <-ctx.Done():
+			stopped.Set(true)
+			e.runner.Stop("as the context is done")
+		case <-finished:
+		}
+	}()
+	err := e.runner.RunEnumeration()
+	close(finished)
+	if err != nil {
+		return err
+	}
+	if stopped.Get() {
+		return ctx.Err()
+	}
+	return nil
+}
+
+// Close stops the execution in flight if any, waiting for it to return,
+// and releases the runner of the engine.
+func (e *Engine) Close() {
+	e.mutex.Lock()
+	if e.closed {
+		e.mutex.Unlock()
+		return
+	}
+	e.closed = true
+	done := e.done
+	e.mutex.Unlock()
+
+	if done != nil {
+		select {
+		case <-done:
+		default:
+			e.runner.Stop("as the engine is closed")
+			<-done
+		}
+	}
+	e.runner.Close()
+}
+
+// resultExporter sends the json results of the scan to the callback of the
+// execution, one at a time.
+type resultExporter struct {
+	includeRR bool
+
+	mutex    sync.Mutex
+	callback func(*Result)
+}
+
+// Export calls the callback with a json result
+func (r *resultExporter) Export(data []byte) {
+	result := &Result{}
+	if err := jsoniter.Unmarshal(data, result); err != nil {
+		gologger.Warningf("Could not unmarshal the result: %s\n", err)
+		return
+	}
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+	if r.callback != nil {
+		r.callback(result)
+	}
+}
+
+// IncludeRR returns true if the results include the raw requests and responses
+func (r *resultExporter) IncludeRR() bool {
+	return r.includeRR
+}
+
+// start sends the results to a callback
+func (r *resultExporter) start(callback func(*Result)) {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+	r.callback = callback
+}
+
+// stop ignores the results sent after the execution
+func (r *resultExporter) stop() {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+	r.callback = nil
+}
@@ -0,0 +1,214 @@
+package engine
+
+import (
+	"context"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"sync"
+	"testing"
+	"time"
+
+	jsoniter "github.com/json-iterator/go"
+	"github.com/projectdiscovery/nuclei/v2/internal/runner"
+	"github.com/projectdiscovery/nuclei/v2/pkg/stats"
+	"github.com/stretchr/testify/require"
+)
+
+// writeTemplate writes a template matching a word of the body of / to a
+// directory, appending the word to a file of its own and "server" to a file
+// shared by the templates, both in the extractor directory.
+func writeTemplate(t *testing.T, dir, id, word string) string {
+	path := filepath.Join(dir, id+".yaml")
+	template := fmt.Sprintf(`id: %s
+info:
+  name: %s
+  author: test
+  severity: info
+requests:
+  - method: GET
+    path:
+      - "{{BaseURL}}/"
+    matchers:
+      - type: word
+        words:
+          - %s
+    extractors:
+      - type: regex
+        regex:
+          - "[a-z]+-server"
+        to-file: "{{template-id}}.txt"
+      - type: regex
+        regex:
+          - "server"
+        to-file: shared.txt
+        to-file-dedupe: true
+`, id, id, word)
+	require.Nil(t, ioutil.WriteFile(path, []byte(template), 0644), "Could not write template")
+	return path
+}
+
+// newEngine creates an engine writing the summary of its scan to a file
+func newEngine(t *testing.T, options Options, summary string) *Engine {
+	results := &resultExporter{includeRR: options.IncludeRR}
+	runnerOptions := options.runnerOptions(results)
+	runnerOptions.StatsJSON = summary
+	runner, err := runner.New(runnerOptions)
+	require.Nil(t, err, "Could not create engine")
+	return &Engine{runner: runner, results: results}
+}
+
+func TestEnginesConcurrently(t *testing.T) {
+	dir, err := ioutil.TempDir("", "nuclei-engine")
+	require.Nil(t, err, "Could not create directory")
+	defer os.RemoveAll(dir)
+
+	names := []string{"first", "second"}
+	engines := make([]*Engine, len(names))
+	for i, name := range names {
+		body := name + "-server"
+		server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			fmt.Fprint(w, body)
+		}))
+		defer server.Close()
+
+		engine := newEngine(t, Options{
+			Templates:    []string{writeTemplate(t, dir, name+"-template", body)},
+			Targets:      []string{server.URL},
+			Concurrency:  5,
+			NoInteractsh: true,
+			ExtractorDir: dir,
+		}, filepath.Join(dir, name+"-summary.json"))
+		defer engine.Close()
+		engines[i] = engine
+	}
+
+	results := make([][]*Result, len(engines))
+	errs := make([]error, len(engines))
+	var wg sync.WaitGroup
+	for i, engine := range engines {
+		wg.Add(1)
+		go func(i int, engine *Engine) {
+			defer wg.Done()
+			errs[i] = engine.ExecuteWithContext(context.Background(), func(result *Result) {
+				results[i] = append(results[i], result)
+			})
+		}(i, engine)
+	}
+	wg.Wait()
+
+	for i, name := range names {
+		require.Nil(t, errs[i], "Could not execute engine")
+		require.Len(t, results[i], 1, "Could not get the result of the engine")
+		require.Equal(t, name+"-template", results[i][0].Template, "Could get the result of another engine")
+		require.Equal(t, "http", results[i][0].Type, "Could not get the type of the result")
+		require.False(t, results[i][0].Timestamp.IsZero(), "Could not get the timestamp of the result")
+
+		data, err := ioutil.ReadFile(filepath.Join(dir, name+"-template.txt"))
+		require.Nil(t, err, "Could not read the extracted values of the engine")
+		require.Equal(t, name+"-server\n", string(data), "Could get the extracted values of another engine")
+
+		var summary stats.Summary
+		data, err = ioutil.ReadFile(filepath.Join(dir, name+"-summary.json"))
+		require.Nil(t, err, "Could not read the summary of the engine")
+		require.Nil(t, jsoniter.Unmarshal(data, &summary), "Could not unmarshal the summary of the engine")
+		require.Equal(t, int64(1), summary.Targets, "Could get the targets of another engine")
+		require.Equal(t, uint64(1), summary.Findings, "Could get the findings of another engine")
+		require.Equal(t, []stats.Count{{Name: name + "-template", Count: 1}}, summary.TopTemplates, "Could get the templates of another engine")
+	}
+	// each engine dedupes the values it appends to a file on its own
+	data, err := ioutil.ReadFile(filepath.Join(dir, "shared.txt"))
+	require.Nil(t, err, "Could not read the extracted values of the engines")
+	require.Equal(t, "server\nserver\n", string(data), "Could dedupe the extracted values of another engine")
+	require.Equal(t, ErrExecuted, engines[0].ExecuteWithContext(context.Background(), nil), "Could execute an engine twice")
+}
+
+func TestEngineErrors(t *testing.T) {
+	_, err := New(Options{Targets: []string{"http://127.0.0.1"}})
+	require.NotNil(t, err, "Could create an engine without templates")
+	_, err = New(Options{Templates: []string{"missing.yaml"}, Targets: []string{"http://127.0.0.1"}, Proxy: "http://[::1"})
+	require.NotNil(t, err, "Could create an engine with an invalid proxy")
+
+	engine, err := New(Options{Templates: []string{"missing.yaml"}, Targets: []string{"http://127.0.0.1"}})
+	require.Nil(t, err, "Could not create engine")
+	err = engine.ExecuteWithContext(context.Background(), nil)
+	require.Equal(t, ErrNoTemplates, err, "Could execute an engine without templates")
+	engine.Close()
+	require.Equal(t, ErrClosed, engine.ExecuteWithContext(context.Background(), nil), "Could execute a closed engine")
+
+	engine, err = New(Options{Templates: []string{"missing.yaml"}, Targets: []string{"http://127.0.0.1"}})
+	require.Nil(t, err, "Could not create engine")
+	defer engine.Close()
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	require.Equal(t, context.Canceled, engine.ExecuteWithContext(ctx, nil), "Could execute an engine with a done context")
+}
+
+func TestEngineCancel(t *testing.T) {
+	dir, err := ioutil.TempDir("", "nuclei-engine")
+	require.Nil(t, err, "Could not create directory")
+	defer os.RemoveAll(dir)
+
+	release := make(chan struct{})
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		<-release
+	}))
+	defer server.Close()
+	defer close(release)
+
+	engine, err := New(Options{Templates: []string{writeTemplate(t, dir, "slow", "slow")}, Targets: []string{server.URL}, Timeout: time.Minute, NoInteractsh: true})
+	require.Nil(t, err, "Could not create engine")
+	defer engine.Close()
+
+	ctx, cancel := context.WithTimeout(context.Background(), 200*time.Millisecond)
+	defer cancel()
+	started := time.Now()
+	require.Equal(t, context.DeadlineExceeded, engine.ExecuteWithContext(ctx, nil), "Could not stop the engine with the context")
+	require.True(t, time.Since(started) < 10*time.Second, "Could not cancel the requests in flight")
+}
+
+func TestLegacyWorkflowJSON(t *testing.T) {
+	dir, err := ioutil.TempDir("", "nuclei-engine")
+	require.Nil(t, err, "Could not create directory")
+	defer os.RemoveAll(dir)
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		fmt.Fprint(w, "legacy-server")
+	}))
+	defer server.Close()
+
+	template := writeTemplate(t, dir, "legacy-template", "legacy-server")
+	workflow := filepath.Join(dir, "legacy-workflow.yaml")
+	require.Nil(t, ioutil.WriteFile(workflow, []byte(fmt.Sprintf(`id: legacy-workflow
+info:
+  name: legacy workflow
+  author: test
+variables:
+  basic: %s
+logic: |
+  basic()
+`, template)), 0644), "Could not write workflow")
+
+	output := filepath.Join(dir, "output.json")
+	results := &resultExporter{}
+	runnerOptions := (&Options{Templates: []string{workflow}, Targets: []string{server.URL}, NoInteractsh: true, ExtractorDir: dir}).runnerOptions(results)
+	runnerOptions.Output = output
+	runnerOptions.JSON = true
+	runnerOptions.IncludeRR = true
+	runner, err := runner.New(runnerOptions)
+	require.Nil(t, err, "Could not create engine")
+	engine := &Engine{runner: runner, results: results}
+	require.Nil(t, engine.ExecuteWithContext(context.Background(), nil), "Could not execute engine")
+	engine.Close()
+
+	data, err := ioutil.ReadFile(output)
+	require.Nil(t, err, "Could not read the output of the workflow")
+	var result map[string]interface{}
+	require.Nil(t, jsoniter.Unmarshal(data, &result), "Could not write the result of the workflow as json")
+	require.Equal(t, "legacy-template", result["template"], "Could not get the template of the result")
+	require.Contains(t, result["request"], "GET / HTTP/1.1", "Could not include the request of the result")
+	require.Contains(t, result["response"], "legacy-server", "Could not include the response of the result")
+}
@@ -0,0 +1,3 @@
+// Package exclusions implements user supplied matchers suppressing
+// the results of templates matching known false positives.
+package exclusions
@@ -0,0 +1,284 @@
+package exclusions
+
+import (
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"net"
+	"net/http"
+	"net/url"
+	"path"
+	"strings"
+	"sync/atomic"
+	"time"
+
+	"github.com/projectdiscovery/nuclei/v2/pkg/dnsrecords"
+	"github.com/projectdiscovery/nuclei/v2/pkg/file"
+	"github.com/projectdiscovery/nuclei/v2/pkg/headless"
+	"github.com/projectdiscovery/nuclei/v2/pkg/matchers"
+	"github.com/projectdiscovery/nuclei/v2/pkg/network"
+	"github.com/projectdiscovery/nuclei/v2/pkg/ssl"
+	"github.com/projectdiscovery/nuclei/v2/pkg/websocket"
+	"gopkg.in/yaml.v2"
+)
+
+// Exclusion suppresses the results of the templates whose response matches its matchers.
+type Exclusion struct {
+	// Templates are the globs of the template ids the exclusion applies to, all if empty
+	Templates []string `yaml:"templates,omitempty"`
+	// Hosts are the globs of the hosts the exclusion applies to, all if empty
+	Hosts []string `yaml:"hosts,omitempty"`
+	// MatchersCondition is the condition of the matchers, or by default
+	MatchersCondition string `yaml:"matchers-condition,omitempty"`
+	// matchersCondition is internal condition for the matchers.
+	matchersCondition matchers.ConditionType
+	// Matchers are the matchers identifying the response to suppress
+	Matchers []*matchers.Matcher `yaml:"matchers"`
+}
+
+// Exclusions is the list of exclusions loaded from an exclusion file
+type Exclusions struct {
+	list       []*Exclusion
+	suppressed uint64
+}
+
+// Load reads and compiles the exclusions of a yaml file
+func Load(file string) (*Exclusions, error) {
+	data, err := ioutil.ReadFile(file)
+	if err != nil {
+		return nil, fmt.Errorf("could not read exclusions file: %s", err)
+	}
+
+	var list []*Exclusion
+	if err := yaml.UnmarshalStrict(data, &list); err != nil {
+		return nil, fmt.Errorf("could not parse exclusions file %s: %s", file, err)
+	}
+	if len(list) == 0 {
+		return nil, fmt.Errorf("no exclusions found in %s", file)
+	}
+
+	for i, exclusion := range list {
+		if err := exclusion.compile(); err != nil {
+			return nil, fmt.Errorf("could not compile exclusion %d of %s: %s", i, file, err)
+		}
+	}
+	return &Exclusions{list: list}, nil
+}
+
+// compile validates the globs and compiles the matchers of the exclusion
+func (e *Exclusion) compile() error {
+	for _, glob := range append(e.Templates, e.Hosts...) {
+		if _, err := path.Match(glob, ""); err != nil {
+			return fmt.Errorf("invalid glob %s", glob)
+		}
+	}
+
+	if len(e.Matchers) == 0 {
+		return errors.New("no matchers specified")
+	}
+	condition, ok := matchers.ConditionTypes[e.MatchersCondition]
+	if e.MatchersCondition != "" && !ok {
+		return fmt.Errorf("unknown matchers-condition specified: %s", e.MatchersCondition)
+	}
+	if !ok {
+		condition = matchers.ORCondition
+	}
+	e.matchersCondition = condition
+
+	for i, matcher := range e.Matchers {
+		if err := matcher.CompileMatchers(); err != nil {
+			return fmt.Errorf("could not compile matcher %d: %s", i, err)
+		}
+		if matcher.Baseline {
+			return fmt.Errorf("baseline is not supported by matcher %d", i)
+		}
+	}
+	return matchers.ValidateNegative(e.Matchers, e.matchersCondition)
+}
+
+// applies returns true if the exclusion is scoped to the template and the host
+func (e *Exclusion) applies(templateID, host string) bool {
+	return matchGlobs(e.Templates, templateID) && matchGlobs(e.Hosts, host)
+}
+
+// matchGlobs returns true if the value matches any of the globs, or if there are none
+func matchGlobs(globs []string, value string) bool {
+	if len(globs) == 0 {
+		return true
+	}
+	for _, glob := range globs {
+		if matched, _ := path.Match(glob, value); matched {
+			return true
+		}
+	}
+	return false
+}
+
+// combine combines the results of the matchers with the matchers condition
+func (e *Exclusion) combine(match func(matcher *matchers.Matcher) bool) bool {
+	for _, matcher := range e.Matchers {
+		matched := match(matcher)
+		if matched && e.matchersCondition == matchers.ORCondition {
+			return true
+		}
+		if !matched && e.matchersCondition == matchers.ANDCondition {
+			return false
+		}
+	}
+	return e.matchersCondition == matchers.ANDCondition
+}
+
+// MatchHTTP returns the index of the exclusion suppressing the result of a
+// template for a http response, or -1 if the result isn't suppressed.
+func (e *Exclusions) MatchHTTP(templateID, URL string, resp *http.Response, body, headers string, duration time.Duration, remoteIP string) int {
+	host := URL
+	if parsed, err := url.Parse(URL); err == nil && parsed.Hostname() != "" {
+		host = parsed.Hostname()
+	}
+
+	for i, exclusion := range e.list {
+		if !exclusion.applies(templateID, host) {
+			continue
+		}
+		if exclusion.combine(func(matcher *matchers.Matcher) bool {
+			return matcher.Match(resp, body, headers, duration, nil, remoteIP, nil)
+		}) {
+			atomic.AddUint64(&e.suppressed, 1)
+			return i
+		}
+	}
+	return -1
+}
+
+// MatchDNS returns the index of the exclusion suppressing the result of a
+// template for a dns response, or -1 if the result isn't suppressed.
+func (e *Exclusions) MatchDNS(templateID, domain string, resp *dnsrecords.Response) int {
+	host := strings.TrimSuffix(domain, ".")
+
+	for i, exclusion := range e.list {
+		if !exclusion.applies(templateID, host) {
+			continue
+		}
+		if exclusion.combine(func(matcher *matchers.Matcher) bool {
+			return matcher.MatchDNS(resp, nil)
+		}) {
+			atomic.AddUint64(&e.suppressed, 1)
+			return i
+		}
+	}
+	return -1
+}
+
+// MatchHeadless returns the index of the exclusion suppressing the result
+// of a template for a headless page, or -1 if the result isn't suppressed.
+func (e *Exclusions) MatchHeadless(templateID, URL string, resp *headless.Response) int {
+	host := URL
+	if parsed, err := url.Parse(URL); err == nil && parsed.Hostname() != "" {
+		host = parsed.Hostname()
+	}
+
+	for i, exclusion := range e.list {
+		if !exclusion.applies(templateID, host) {
+			continue
+		}
+		if exclusion.combine(func(matcher *matchers.Matcher) bool {
+			return matcher.MatchHeadless(resp, nil)
+		}) {
+			atomic.AddUint64(&e.suppressed, 1)
+			return i
+		}
+	}
+	return -1
+}
+
+// MatchNetwork returns the index of the exclusion suppressing the result of
+// a template for the bytes read from an address, or -1 if the result isn't
+// suppressed.
+func (e *Exclusions) MatchNetwork(templateID, address string, resp *network.Response) int {
+	host := address
+	if h, _, err := net.SplitHostPort(address); err == nil {
+		host = h
+	}
+
+	for i, exclusion := range e.list {
+		if !exclusion.applies(templateID, host) {
+			continue
+		}
+		if exclusion.combine(func(matcher *matchers.Matcher) bool {
+			return matcher.MatchNetwork(resp, nil)
+		}) {
+			atomic.AddUint64(&e.suppressed, 1)
+			return i
+		}
+	}
+	return -1
+}
+
+// MatchFile returns the index of the exclusion suppressing the result of a
+// template for a local file, its path being the host the exclusions are
+// scoped to, or -1 if the result isn't suppressed.
+func (e *Exclusions) MatchFile(templateID string, resp *file.Response) int {
+	for i, exclusion := range e.list {
+		if !exclusion.applies(templateID, resp.Path) {
+			continue
+		}
+		if exclusion.combine(func(matcher *matchers.Matcher) bool {
+			return matcher.MatchFile(resp, nil)
+		}) {
+			atomic.AddUint64(&e.suppressed, 1)
+			return i
+		}
+	}
+	return -1
+}
+
+// MatchWebsocket returns the index of the exclusion suppressing the result
+// of a template for the messages read from a websocket url, or -1 if the
+// result isn't suppressed.
+func (e *Exclusions) MatchWebsocket(templateID string, resp *websocket.Response) int {
+	host := resp.URL
+	if parsed, err := url.Parse(resp.URL); err == nil && parsed.Hostname() != "" {
+		host = parsed.Hostname()
+	}
+
+	for i, exclusion := range e.list {
+		if !exclusion.applies(templateID, host) {
+			continue
+		}
+		if exclusion.combine(func(matcher *matchers.Matcher) bool {
+			return matcher.MatchWebsocket(resp, nil)
+		}) {
+			atomic.AddUint64(&e.suppressed, 1)
+			return i
+		}
+	}
+	return -1
+}
+
+// MatchSSL returns the index of the exclusion suppressing the result of a
+// template for a handshake with an address, or -1 if the result isn't
+// suppressed.
+func (e *Exclusions) MatchSSL(templateID string, resp *ssl.Response) int {
+	host := resp.Address
+	if h, _, err := net.SplitHostPort(resp.Address); err == nil {
+		host = h
+	}
+
+	for i, exclusion := range e.list {
+		if !exclusion.applies(templateID, host) {
+			continue
+		}
+		if exclusion.combine(func(matcher *matchers.Matcher) bool {
+			return matcher.MatchSSL(resp, nil)
+		}) {
+			atomic.AddUint64(&e.suppressed, 1)
+			return i
+		}
+	}
+	return -1
+}
+
+// Suppressed returns the number of results suppressed by the exclusions
+func (e *Exclusions) Suppressed() uint64 {
+	return atomic.LoadUint64(&e.suppressed)
+}
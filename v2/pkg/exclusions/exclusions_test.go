@@ -0,0 +1,63 @@
+package exclusions
+
+import (
+	"io/ioutil"
+	"net/http"
+	"os"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func writeExclusions(t *testing.T, content string) string {
+	f, err := ioutil.TempFile("", "exclusions-*.yaml")
+	require.Nil(t, err, "Could not create exclusions file")
+	defer f.Close()
+
+	_, err = f.WriteString(content)
+	require.Nil(t, err, "Could not write exclusions file")
+	return f.Name()
+}
+
+func TestExclusionsMatchHTTP(t *testing.T) {
+	file := writeExclusions(t, `
+- templates: ["phpinfo-*"]
+  hosts: ["*.corp.local"]
+  matchers:
+    - type: word
+      words: ["Corporate error page"]
+- hosts: ["honeypot.example.com"]
+  matchers-condition: and
+  matchers:
+    - type: status
+      status: [200]
+`)
+	defer os.Remove(file)
+
+	exclusions, err := Load(file)
+	require.Nil(t, err, "Could not load exclusions")
+
+	resp := &http.Response{StatusCode: 200}
+	body := "<title>Corporate error page</title> phpinfo()"
+	require.Equal(t, 0, exclusions.MatchHTTP("phpinfo-files", "https://intranet.corp.local/info.php", resp, body, "", 0, ""), "Could not suppress scoped result")
+	require.Equal(t, -1, exclusions.MatchHTTP("phpinfo-files", "https://example.com/info.php", resp, body, "", 0, ""), "Could suppress result of another host")
+	require.Equal(t, -1, exclusions.MatchHTTP("git-config", "https://intranet.corp.local/info.php", resp, body, "", 0, ""), "Could suppress result of another template")
+	require.Equal(t, 1, exclusions.MatchHTTP("git-config", "http://honeypot.example.com:8080/.git/config", resp, "", "", 0, ""), "Could not suppress result of any template")
+	require.Equal(t, uint64(2), exclusions.Suppressed(), "Could not count suppressed results")
+}
+
+func TestExclusionsMalformed(t *testing.T) {
+	for _, content := range []string{
+		"",
+		"- matchers: []",
+		"- matchers:\n    - type: unknown",
+		"- unknown-field: true\n  matchers:\n    - type: status\n      status: [200]",
+		"- templates: [\"[\"]\n  matchers:\n    - type: status\n      status: [200]",
+		"- matchers:\n    - type: word\n      words: [\"a\"]\n      negative: true",
+	} {
+		file := writeExclusions(t, content)
+		_, err := Load(file)
+		os.Remove(file)
+		require.NotNil(t, err, "Could load malformed exclusions %q", content)
+	}
+}
@@ -0,0 +1,362 @@
+package executer
+
+import (
+	"bytes"
+	"context"
+	"crypto/tls"
+	"crypto/x509"
+	"fmt"
+	"io/ioutil"
+	"net"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/miekg/dns"
+	"github.com/pkg/errors"
+)
+
+// ResolverType is the transport used to query a resolver
+type ResolverType string
+
+const (
+	// PlainResolver queries the resolver over plain udp
+	PlainResolver ResolverType = "udp"
+	// DoHResolver queries the resolver with DNS-over-HTTPS
+	DoHResolver ResolverType = "doh"
+	// DoTResolver queries the resolver with DNS-over-TLS
+	DoTResolver ResolverType = "dot"
+)
+
+// maxResolverErrors is the number of consecutive errors after
+// which a resolver is moved in the cooldown pool.
+const maxResolverErrors = 3
+
+// resolverCooldown is the time a failing resolver is not queried for.
+const resolverCooldown = 30 * time.Second
+
+// Resolver is a dns server queried by the dns executer
+type Resolver struct {
+	Type    ResolverType
+	Address string
+
+	dnsClient  *dns.Client
+	httpClient *http.Client
+}
+
+// ParseResolver parses a resolver in the ip:port, doh:URL or dot:ip:port format
+func ParseResolver(value string, timeout time.Duration) (*Resolver, error) {
+	value = strings.TrimSpace(value)
+
+	switch {
+	case strings.HasPrefix(value, "doh:"):
+		address := strings.TrimPrefix(value, "doh:")
+		if !strings.HasPrefix(address, "https://") {
+			return nil, fmt.Errorf("invalid doh resolver, it should be doh:https://host/path: %s", value)
+		}
+		return &Resolver{
+			Type:    DoHResolver,
+			Address: address,
+			httpClient: &http.Client{
+				Timeout: timeout,
+				Transport: &http.Transport{
+					TLSClientConfig: &tls.Config{ClientSessionCache: tls.NewLRUClientSessionCache(0)},
+				},
+			},
+		}, nil
+	case strings.HasPrefix(value, "dot:"):
+		address := withDefaultPort(strings.TrimPrefix(value, "dot:"), "853")
+		host, port, err := net.SplitHostPort(address)
+		if err != nil || !isPort(port) {
+			return nil, fmt.Errorf("invalid dot resolver: %s", value)
+		}
+		return &Resolver{
+			Type:    DoTResolver,
+			Address: address,
+			dnsClient: &dns.Client{
+				Net:     "tcp-tls",
+				Timeout: timeout,
+				TLSConfig: &tls.Config{
+					ServerName:         host,
+					ClientSessionCache: tls.NewLRUClientSessionCache(0),
+				},
+			},
+		}, nil
+	}
+
+	address := withDefaultPort(value, "53")
+	if _, port, err := net.SplitHostPort(address); err != nil || !isPort(port) {
+		return nil, fmt.Errorf("invalid resolver: %s", value)
+	}
+	return &Resolver{
+		Type:      PlainResolver,
+		Address:   address,
+		dnsClient: &dns.Client{Net: "udp", Timeout: timeout},
+	}, nil
+}
+
+// withDefaultPort adds the port to an address if it doesn't have one
+func withDefaultPort(address, port string) string {
+	if _, _, err := net.SplitHostPort(address); err != nil {
+		return net.JoinHostPort(strings.Trim(address, "[]"), port)
+	}
+	return address
+}
+
+// String returns the resolver in the same format it was specified
+func (r *Resolver) String() string {
+	if r.Type == PlainResolver {
+		return r.Address
+	}
+	return string(r.Type) + ":" + r.Address
+}
+
+// Exchange sends a dns message to the resolver and returns the response
+// along with the transport used, waiting for the timeout if not zero.
+func (r *Resolver) Exchange(msg *dns.Msg, timeout time.Duration) (*dns.Msg, string, error) {
+	var resp *dns.Msg
+	var err error
+
+	transport := "https"
+	if r.Type == DoHResolver {
+		resp, err = r.exchangeHTTPS(msg, timeout)
+	} else {
+		transport = r.dnsClient.Net
+		resp, _, err = r.client(r.dnsClient.Net, timeout).Exchange(msg, r.Address)
+	}
+	// truncated udp responses are retried over tcp to get the full answer
+	if err == nil && resp.Truncated && r.Type == PlainResolver {
+		transport = "tcp"
+		resp, _, err = r.client("tcp", timeout).Exchange(msg, r.Address)
+	}
+	if err != nil && isTLSVerificationError(err) {
+		return nil, transport, fmt.Errorf("tls verification failed for resolver %s: %s", r, err)
+	}
+	return resp, transport, err
+}
+
+// client returns a dns client for the network, using the timeout if not zero
+func (r *Resolver) client(network string, timeout time.Duration) *dns.Client {
+	if network == r.dnsClient.Net && (timeout == 0 || timeout == r.dnsClient.Timeout) {
+		return r.dnsClient
+	}
+	if timeout == 0 {
+		timeout = r.dnsClient.Timeout
+	}
+	return &dns.Client{Net: network, Timeout: timeout, TLSConfig: r.dnsClient.TLSConfig}
+}
+
+// exchangeHTTPS sends the wireformat message with a DNS-over-HTTPS POST request
+func (r *Resolver) exchangeHTTPS(msg *dns.Msg, timeout time.Duration) (*dns.Msg, error) {
+	packed, err := msg.Pack()
+	if err != nil {
+		return nil, errors.Wrap(err, "could not pack dns message")
+	}
+
+	req, err := http.NewRequest(http.MethodPost, r.Address, bytes.NewReader(packed))
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("Content-Type", "application/dns-message")
+	req.Header.Set("Accept", "application/dns-message")
+	if timeout != 0 {
+		ctx, cancel := context.WithTimeout(req.Context(), timeout)
+		defer cancel()
+		req = req.WithContext(ctx)
+	}
+
+	resp, err := r.httpClient.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("doh resolver %s returned status %d", r.Address, resp.StatusCode)
+	}
+	data, err := ioutil.ReadAll(resp.Body)
+	if err != nil {
+		return nil, errors.Wrap(err, "could not read doh response")
+	}
+
+	answer := new(dns.Msg)
+	if err := answer.Unpack(data); err != nil {
+		return nil, errors.Wrap(err, "could not unpack doh response")
+	}
+	return answer, nil
+}
+
+// isTLSVerificationError returns true if the error is caused by an invalid certificate
+func isTLSVerificationError(err error) bool {
+	switch errors.Cause(err).(type) {
+	case x509.UnknownAuthorityError, x509.HostnameError, x509.CertificateInvalidError:
+		return true
+	}
+	// errors wrapped by net/http are not always unwrappable
+	return strings.Contains(err.Error(), "x509:")
+}
+
+// ResolverPool rotates dns queries across a list of resolvers.
+//
+// Resolvers returning errors, timeouts or SERVFAIL repeatedly are moved in
+// a cooldown pool for some time, and the system resolver is used only
+// when all the resolvers of the list are cooling down.
+type ResolverPool struct {
+	mutex     *sync.Mutex
+	resolvers []*Resolver
+	errors    []int
+	cooldown  []time.Time
+	next      int
+	system    *Resolver
+}
+
+// NewResolverPool creates a new pool of resolvers from a list of resolver strings
+func NewResolverPool(resolvers []string, timeout time.Duration) (*ResolverPool, error) {
+	pool := &ResolverPool{mutex: &sync.Mutex{}}
+	for _, value := range resolvers {
+		resolver, err := ParseResolver(value, timeout)
+		if err != nil {
+			return nil, err
+		}
+		pool.resolvers = append(pool.resolvers, resolver)
+	}
+	if len(pool.resolvers) == 0 {
+		return nil, errors.New("no resolvers specified")
+	}
+	pool.errors = make([]int, len(pool.resolvers))
+	pool.cooldown = make([]time.Time, len(pool.resolvers))
+	pool.system = systemResolver(timeout)
+	return pool, nil
+}
+
+// Resolvers returns the resolvers of the pool in the format they were
+// specified, without the system resolver used once they all cool down.
+func (p *ResolverPool) Resolvers() []string {
+	resolvers := make([]string, 0, len(p.resolvers))
+	for _, resolver := range p.resolvers {
+		resolvers = append(resolvers, resolver.String())
+	}
+	return resolvers
+}
+
+// systemResolver returns the first resolver configured on the system,
+// using the first default resolver if none could be found.
+func systemResolver(timeout time.Duration) *Resolver {
+	address := DefaultResolvers[0]
+	if config, err := dns.ClientConfigFromFile("/etc/resolv.conf"); err == nil && len(config.Servers) > 0 {
+		address = net.JoinHostPort(config.Servers[0], config.Port)
+	}
+	resolver, _ := ParseResolver(address, timeout)
+	return resolver
+}
+
+// pick returns the next available resolver in round-robin order not present
+// in tried, or -1 when the list is exhausted and the system resolver must be used.
+func (p *ResolverPool) pick(tried map[int]struct{}) int {
+	p.mutex.Lock()
+	defer p.mutex.Unlock()
+
+	now := time.Now()
+	fallback := -1
+	for i := 0; i < len(p.resolvers); i++ {
+		index := (p.next + i) % len(p.resolvers)
+		// resolvers out of cooldown get a fresh start
+		if !p.cooldown[index].IsZero() && now.After(p.cooldown[index]) {
+			p.cooldown[index] = time.Time{}
+			p.errors[index] = 0
+		}
+		if !p.cooldown[index].IsZero() {
+			continue
+		}
+		if _, ok := tried[index]; ok {
+			// prefer a different resolver but allow reusing a healthy one
+			if fallback == -1 {
+				fallback = index
+			}
+			continue
+		}
+		p.next = index + 1
+		return index
+	}
+	if fallback != -1 {
+		p.next = fallback + 1
+	}
+	return fallback
+}
+
+// report records the outcome of a query to a resolver
+func (p *ResolverPool) report(index int, failed bool) {
+	p.mutex.Lock()
+	defer p.mutex.Unlock()
+
+	if !failed {
+		p.errors[index] = 0
+		return
+	}
+	p.errors[index]++
+	if p.errors[index] >= maxResolverErrors {
+		p.cooldown[index] = time.Now().Add(resolverCooldown)
+	}
+}
+
+// Attempt is a single query sent to a resolver of the pool
+type Attempt struct {
+	// Resolver is the resolver queried
+	Resolver *Resolver
+	// Transport is the transport actually used, i.e tcp after a truncated udp response
+	Transport string
+	// Error is the error returned by the resolver if any
+	Error error
+	// Rcode is the response code returned by the resolver
+	Rcode int
+}
+
+// Do sends a dns message to the resolvers of the pool, retrying on errors
+// with a different resolver when possible. It returns the response along
+// with all the attempts made, the last one being the answering resolver.
+func (p *ResolverPool) Do(msg *dns.Msg, retries int, timeout time.Duration) (*dns.Msg, []*Attempt, error) {
+	if retries < 1 {
+		retries = 1
+	}
+
+	var (
+		err      error
+		resp     *dns.Msg
+		attempts []*Attempt
+	)
+	tried := make(map[int]struct{})
+	for i := 0; i < retries; i++ {
+		var resolver *Resolver
+		index := p.pick(tried)
+		if index == -1 {
+			resolver = p.system
+		} else {
+			resolver = p.resolvers[index]
+			tried[index] = struct{}{}
+		}
+
+		var transport string
+		resp, transport, err = resolver.Exchange(msg, timeout)
+		attempt := &Attempt{Resolver: resolver, Transport: transport, Error: err}
+		attempts = append(attempts, attempt)
+
+		serverFailure := err == nil && resp.Rcode == dns.RcodeServerFailure
+		if index != -1 {
+			p.report(index, err != nil || serverFailure)
+		}
+		if err != nil {
+			continue
+		}
+		attempt.Rcode = resp.Rcode
+		// retry on server failures, keeping the response for the last attempt
+		if serverFailure && i < retries-1 {
+			continue
+		}
+		return resp, attempts, nil
+	}
+	if err == nil && resp != nil {
+		return resp, attempts, nil
+	}
+	return nil, attempts, errors.Wrapf(err, "no response after %d attempts", len(attempts))
+}
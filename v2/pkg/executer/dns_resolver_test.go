@@ -0,0 +1,110 @@
+package executer
+
+import (
+	"net"
+	"testing"
+	"time"
+
+	"github.com/miekg/dns"
+	"github.com/stretchr/testify/require"
+)
+
+func TestParseResolver(t *testing.T) {
+	resolver, err := ParseResolver("8.8.8.8", time.Second)
+	require.Nil(t, err, "Could not parse plain resolver")
+	require.Equal(t, PlainResolver, resolver.Type, "Could not get plain resolver type")
+	require.Equal(t, "8.8.8.8:53", resolver.Address, "Could not add default plain port")
+
+	resolver, err = ParseResolver("dot:1.1.1.1", time.Second)
+	require.Nil(t, err, "Could not parse dot resolver")
+	require.Equal(t, "1.1.1.1:853", resolver.Address, "Could not add default dot port")
+
+	resolver, err = ParseResolver("doh:https://cloudflare-dns.com/dns-query", time.Second)
+	require.Nil(t, err, "Could not parse doh resolver")
+	require.Equal(t, "doh:https://cloudflare-dns.com/dns-query", resolver.String(), "Could not format doh resolver")
+
+	_, err = ParseResolver("doh:cloudflare-dns.com", time.Second)
+	require.NotNil(t, err, "Could parse invalid doh resolver")
+
+	resolver, err = ParseResolver("10.0.0.5:5353", time.Second)
+	require.Nil(t, err, "Could not parse resolver with port")
+	require.Equal(t, "10.0.0.5:5353", resolver.Address, "Could not keep resolver port")
+
+	resolver, err = ParseResolver("[2001:db8::1]:5353", time.Second)
+	require.Nil(t, err, "Could not parse ipv6 resolver with port")
+	require.Equal(t, "[2001:db8::1]:5353", resolver.Address, "Could not keep ipv6 resolver port")
+
+	resolver, err = ParseResolver("2001:db8::1", time.Second)
+	require.Nil(t, err, "Could not parse ipv6 resolver")
+	require.Equal(t, "[2001:db8::1]:53", resolver.Address, "Could not add default ipv6 port")
+
+	resolver, err = ParseResolver("dot:[2001:db8::1]", time.Second)
+	require.Nil(t, err, "Could not parse ipv6 dot resolver")
+	require.Equal(t, "[2001:db8::1]:853", resolver.Address, "Could not add default ipv6 dot port")
+
+	for _, invalid := range []string{"10.0.0.5:dns", "10.0.0.5:0", "[2001:db8::1]:70000"} {
+		_, err = ParseResolver(invalid, time.Second)
+		require.NotNil(t, err, "Could parse resolver with invalid port %s", invalid)
+	}
+}
+
+func TestResolverPoolCooldown(t *testing.T) {
+	pool, err := NewResolverPool([]string{"127.0.0.1:53", "127.0.0.2:53"}, time.Second)
+	require.Nil(t, err, "Could not create resolver pool")
+
+	for i := 0; i < maxResolverErrors; i++ {
+		pool.report(0, true)
+	}
+	for i := 0; i < 3; i++ {
+		require.Equal(t, 1, pool.pick(nil), "Could pick resolver in cooldown")
+	}
+
+	tried := map[int]struct{}{1: {}}
+	require.Equal(t, 1, pool.pick(tried), "Could not reuse the only healthy resolver")
+
+	pool.report(1, true)
+	pool.report(1, true)
+	pool.report(1, true)
+	require.Equal(t, -1, pool.pick(nil), "Could not fallback to the system resolver")
+
+	pool.cooldown[0] = time.Now().Add(-time.Second)
+	require.Equal(t, 0, pool.pick(nil), "Could not pick resolver out of cooldown")
+}
+
+func TestResolverTruncatedFallback(t *testing.T) {
+	packetConn, err := net.ListenPacket("udp", "127.0.0.1:0")
+	require.Nil(t, err, "Could not listen on udp")
+	listener, err := net.Listen("tcp", packetConn.LocalAddr().String())
+	require.Nil(t, err, "Could not listen on tcp")
+
+	// udp responses are truncated, tcp ones carry the answer
+	handler := func(truncated bool) dns.Handler {
+		return dns.HandlerFunc(func(w dns.ResponseWriter, r *dns.Msg) {
+			m := new(dns.Msg)
+			m.SetReply(r)
+			m.Truncated = truncated
+			if !truncated {
+				a, _ := dns.NewRR("example.com. 300 IN A 127.0.0.1")
+				m.Answer = append(m.Answer, a)
+			}
+			w.WriteMsg(m)
+		})
+	}
+	udpServer := &dns.Server{PacketConn: packetConn, Handler: handler(true)}
+	tcpServer := &dns.Server{Listener: listener, Handler: handler(false)}
+	go udpServer.ActivateAndServe()
+	go tcpServer.ActivateAndServe()
+	defer udpServer.Shutdown()
+	defer tcpServer.Shutdown()
+
+	resolver, err := ParseResolver(packetConn.LocalAddr().String(), time.Second)
+	require.Nil(t, err, "Could not parse resolver")
+
+	msg := new(dns.Msg)
+	msg.SetQuestion("example.com.", dns.TypeA)
+	resp, transport, err := resolver.Exchange(msg, 0)
+	require.Nil(t, err, "Could not exchange message")
+	require.False(t, resp.Truncated, "Could not retry truncated response over tcp")
+	require.Len(t, resp.Answer, 1, "Could not get answer over tcp")
+	require.Equal(t, "tcp", transport, "Could not report tcp transport")
+}
@@ -0,0 +1,200 @@
+package executer
+
+import (
+	"fmt"
+	"net"
+	"strings"
+
+	"github.com/miekg/dns"
+	"github.com/pkg/errors"
+	"github.com/projectdiscovery/nuclei/v2/pkg/dnsrecords"
+)
+
+// RootServers contains the addresses of the root nameservers
+var RootServers = []string{
+	"198.41.0.4:53",     // a.root-servers.net
+	"199.9.14.201:53",   // b.root-servers.net
+	"192.33.4.12:53",    // c.root-servers.net
+	"199.7.91.13:53",    // d.root-servers.net
+	"192.203.230.10:53", // e.root-servers.net
+	"192.5.5.241:53",    // f.root-servers.net
+	"192.112.36.4:53",   // g.root-servers.net
+	"198.97.190.53:53",  // h.root-servers.net
+	"192.36.148.17:53",  // i.root-servers.net
+	"192.58.128.30:53",  // j.root-servers.net
+	"193.0.14.129:53",   // k.root-servers.net
+	"199.7.83.42:53",    // l.root-servers.net
+	"202.12.27.33:53",   // m.root-servers.net
+}
+
+// defaultTraceMaxDepth is the maximum number of delegations followed by default
+const defaultTraceMaxDepth = 10
+
+// traceDNS resolves the question of the message iteratively starting from the
+// root servers, or from the nameservers of the configured start zone, and
+// returns the final response along with the delegation chain followed.
+func (e *DNSExecuter) traceDNS(msg *dns.Msg) (*dnsrecords.Response, error) {
+	resp, trace, err := e.iterate(msg)
+	if err != nil {
+		return nil, err
+	}
+	return &dnsrecords.Response{Msg: resp, Trace: trace}, nil
+}
+
+// iterate follows the delegation chain for the question of the message
+func (e *DNSExecuter) iterate(msg *dns.Msg) (*dns.Msg, *dnsrecords.Trace, error) {
+	client := &dns.Client{Net: "udp", Timeout: e.timeout}
+	trace := &dnsrecords.Trace{}
+
+	zone := "."
+	servers := RootServers
+	if start := e.dnsRequest.TraceStart; start != "" {
+		zone = dns.Fqdn(start)
+		var err error
+		if servers, err = e.zoneServers(zone); err != nil {
+			return nil, trace, errors.Wrap(err, "could not find start zone nameservers")
+		}
+	}
+
+	maxDepth := e.dnsRequest.TraceMaxDepth
+	if maxDepth <= 0 {
+		maxDepth = defaultTraceMaxDepth
+	}
+
+	query := msg.Copy()
+	query.RecursionDesired = false
+
+	for depth := 0; depth < maxDepth; depth++ {
+		resp, server, err := exchangeAny(client, query, servers)
+		if err != nil {
+			return nil, trace, errors.Wrapf(err, "could not query nameservers for %s", zone)
+		}
+		trace.Hops = append(trace.Hops, &dnsrecords.TraceHop{
+			Zone:    zone,
+			Server:  server,
+			Rcode:   resp.Rcode,
+			Records: append(append(append([]dns.RR{}, resp.Answer...), resp.Ns...), resp.Extra...),
+		})
+
+		// Final answer or error, the resolution is complete
+		if len(resp.Answer) > 0 || resp.Rcode != dns.RcodeSuccess || resp.Authoritative {
+			return resp, trace, nil
+		}
+
+		nextZone, nameservers := referral(resp)
+		// A referral must be for a more specific zone, otherwise it's a loop
+		if len(nameservers) == 0 || !dns.IsSubDomain(zone, nextZone) || nextZone == zone {
+			return resp, trace, nil
+		}
+
+		servers = glueServers(resp, nameservers)
+		if len(servers) == 0 {
+			for _, ns := range nameservers {
+				servers = append(servers, e.resolveNameserver(ns)...)
+			}
+		}
+		if len(servers) == 0 {
+			return resp, trace, fmt.Errorf("could not resolve nameservers for %s", nextZone)
+		}
+		zone = nextZone
+	}
+	return nil, trace, fmt.Errorf("max trace depth of %d reached", maxDepth)
+}
+
+// exchangeAny queries the servers in order until one responds
+func exchangeAny(client *dns.Client, msg *dns.Msg, servers []string) (*dns.Msg, string, error) {
+	var err error
+	for _, server := range servers {
+		var resp *dns.Msg
+		resp, _, err = client.Exchange(msg, server)
+		if err != nil {
+			continue
+		}
+		// retry over tcp if the response was truncated
+		if resp.Truncated {
+			tcpClient := &dns.Client{Net: "tcp", Timeout: client.Timeout}
+			if tcpResp, _, tcpErr := tcpClient.Exchange(msg, server); tcpErr == nil {
+				resp = tcpResp
+			}
+		}
+		return resp, server, nil
+	}
+	if err == nil {
+		err = errors.New("no servers to query")
+	}
+	return nil, "", err
+}
+
+// referral returns the delegated zone and its nameservers from a response
+func referral(resp *dns.Msg) (string, []string) {
+	var zone string
+	var nameservers []string
+	for _, record := range resp.Ns {
+		if ns, ok := record.(*dns.NS); ok {
+			zone = ns.Header().Name
+			nameservers = append(nameservers, ns.Ns)
+		}
+	}
+	return zone, nameservers
+}
+
+// glueServers returns the addresses of the nameservers from the glue records
+func glueServers(resp *dns.Msg, nameservers []string) []string {
+	names := make(map[string]struct{})
+	for _, ns := range nameservers {
+		names[strings.ToLower(ns)] = struct{}{}
+	}
+
+	var servers []string
+	for _, record := range resp.Extra {
+		if _, ok := names[strings.ToLower(record.Header().Name)]; !ok {
+			continue
+		}
+		switch glue := record.(type) {
+		case *dns.A:
+			servers = append(servers, net.JoinHostPort(glue.A.String(), "53"))
+		case *dns.AAAA:
+			servers = append(servers, net.JoinHostPort(glue.AAAA.String(), "53"))
+		}
+	}
+	return servers
+}
+
+// resolveNameserver resolves the ipv4 addresses of a nameserver with the resolvers
+func (e *DNSExecuter) resolveNameserver(name string) []string {
+	msg := new(dns.Msg)
+	msg.SetQuestion(dns.Fqdn(name), dns.TypeA)
+
+	resp, _, err := e.resolvers.Do(msg, e.retries, e.timeout)
+	if err != nil {
+		return nil
+	}
+	var servers []string
+	for _, record := range resp.Answer {
+		if a, ok := record.(*dns.A); ok {
+			servers = append(servers, net.JoinHostPort(a.A.String(), "53"))
+		}
+	}
+	return servers
+}
+
+// zoneServers returns the addresses of the nameservers of a zone
+func (e *DNSExecuter) zoneServers(zone string) ([]string, error) {
+	msg := new(dns.Msg)
+	msg.SetQuestion(zone, dns.TypeNS)
+
+	resp, _, err := e.resolvers.Do(msg, e.retries, e.timeout)
+	if err != nil {
+		return nil, err
+	}
+	var servers []string
+	for _, record := range resp.Answer {
+		if ns, ok := record.(*dns.NS); ok {
+			servers = append(servers, e.resolveNameserver(ns.Ns)...)
+		}
+	}
+	if len(servers) == 0 {
+		return nil, fmt.Errorf("no nameservers found for %s", zone)
+	}
+	return servers, nil
+}
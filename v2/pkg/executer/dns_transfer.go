@@ -0,0 +1,110 @@
+package executer
+
+import (
+	"time"
+
+	"github.com/miekg/dns"
+	"github.com/pkg/errors"
+	"github.com/projectdiscovery/nuclei/v2/pkg/dnsrecords"
+)
+
+// defaultTransferMaxRecords is the maximum number of records retained by default
+const defaultTransferMaxRecords = 10000
+
+// transferDNS attempts a zone transfer of the zone in the question against
+// each authoritative nameserver, returning on the first successful one.
+// The transfer is only attempted against the server if specified.
+func (e *DNSExecuter) transferDNS(msg *dns.Msg, server string) (*dnsrecords.Response, error) {
+	zone := msg.Question[0].Name
+
+	servers := []string{server}
+	if server == "" {
+		var err error
+		if servers, err = e.zoneServers(zone); err != nil {
+			return nil, errors.Wrap(err, "could not find zone nameservers")
+		}
+	}
+
+	maxRecords := e.dnsRequest.TransferMaxRecords
+	if maxRecords <= 0 {
+		maxRecords = defaultTransferMaxRecords
+	}
+
+	var err error
+	var transfer *dnsrecords.Transfer
+	for _, server := range servers {
+		transfer, err = e.transferFrom(msg, server, maxRecords)
+		if err != nil {
+			continue
+		}
+		if transfer.Success || transfer.Partial {
+			break
+		}
+	}
+	if transfer == nil {
+		return nil, errors.Wrap(err, "could not connect to any nameserver")
+	}
+
+	// build a message with all the records so that they can be matched as the body
+	resp := new(dns.Msg)
+	resp.SetReply(msg)
+	resp.Rcode = transfer.Rcode
+	resp.Answer = transfer.Records
+	return &dnsrecords.Response{Msg: resp, Transfer: transfer}, nil
+}
+
+// transferFrom performs a zone transfer over tcp from a nameserver.
+//
+// The transfer is complete once the closing SOA record is received, any error
+// after some records were received results in a partial transfer.
+func (e *DNSExecuter) transferFrom(msg *dns.Msg, server string, maxRecords int) (*dnsrecords.Transfer, error) {
+	conn, err := dns.DialTimeout("tcp", server, e.timeout)
+	if err != nil {
+		return nil, err
+	}
+	defer conn.Close()
+
+	query := msg.Copy()
+	query.Id = dns.Id()
+	conn.SetWriteDeadline(time.Now().Add(e.timeout))
+	if err := conn.WriteMsg(query); err != nil {
+		return nil, err
+	}
+
+	transfer := &dnsrecords.Transfer{Server: server, Rcode: dns.RcodeSuccess}
+	soaCount := 0
+	for {
+		conn.SetReadDeadline(time.Now().Add(e.timeout))
+		in, err := conn.ReadMsg()
+		if err != nil || in.Id != query.Id {
+			transfer.Partial = len(transfer.Records) > 0
+			return transfer, nil
+		}
+		// servers accepting the connection but refusing the transfer
+		if in.Rcode != dns.RcodeSuccess {
+			transfer.Rcode = in.Rcode
+			transfer.Partial = len(transfer.Records) > 0
+			return transfer, nil
+		}
+		if len(in.Answer) == 0 {
+			transfer.Partial = len(transfer.Records) > 0
+			return transfer, nil
+		}
+
+		for _, record := range in.Answer {
+			if record.Header().Rrtype == dns.TypeSOA {
+				soaCount++
+			}
+			if len(transfer.Records) < maxRecords {
+				transfer.Records = append(transfer.Records, record)
+			} else {
+				transfer.Capped = true
+			}
+		}
+		// the zone starts and ends with the SOA record
+		if soaCount >= 2 {
+			transfer.Success = true
+			return transfer, nil
+		}
+	}
+}
@@ -0,0 +1,56 @@
+package executer
+
+import (
+	"net"
+	"testing"
+	"time"
+
+	"github.com/miekg/dns"
+	"github.com/projectdiscovery/nuclei/v2/pkg/requests"
+	"github.com/stretchr/testify/require"
+)
+
+func TestTransferFrom(t *testing.T) {
+	soa, _ := dns.NewRR("example.com. 300 IN SOA ns.example.com. admin.example.com. 1 7200 3600 1209600 3600")
+	a, _ := dns.NewRR("www.example.com. 300 IN A 127.0.0.1")
+
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	require.Nil(t, err, "Could not listen")
+	server := &dns.Server{Listener: listener, Handler: dns.HandlerFunc(func(w dns.ResponseWriter, r *dns.Msg) {
+		if r.Question[0].Name == "refused.com." {
+			m := new(dns.Msg)
+			m.SetRcode(r, dns.RcodeRefused)
+			w.WriteMsg(m)
+			return
+		}
+		// send the zone in multiple messages
+		for _, records := range [][]dns.RR{{soa, a}, {a}, {soa}} {
+			m := new(dns.Msg)
+			m.SetReply(r)
+			m.Answer = records
+			w.WriteMsg(m)
+		}
+	})}
+	go server.ActivateAndServe()
+	defer server.Shutdown()
+
+	e := &DNSExecuter{timeout: time.Second, dnsRequest: &requests.DNSRequest{}}
+	msg := new(dns.Msg)
+	msg.SetAxfr("example.com.")
+
+	transfer, err := e.transferFrom(msg, listener.Addr().String(), 10)
+	require.Nil(t, err, "Could not transfer zone")
+	require.True(t, transfer.Success, "Could not get successful transfer")
+	require.Len(t, transfer.Records, 4, "Could not get all transferred records")
+
+	transfer, err = e.transferFrom(msg, listener.Addr().String(), 2)
+	require.Nil(t, err, "Could not transfer zone")
+	require.True(t, transfer.Capped, "Could not cap transferred records")
+	require.Len(t, transfer.Records, 2, "Could not cap transferred records")
+
+	msg.SetAxfr("refused.com.")
+	transfer, err = e.transferFrom(msg, listener.Addr().String(), 10)
+	require.Nil(t, err, "Could not attempt transfer")
+	require.False(t, transfer.Success, "Could transfer refused zone")
+	require.Equal(t, dns.RcodeRefused, transfer.Rcode, "Could not get refused rcode")
+}
@@ -1,7 +1,10 @@
 package executer
 
 import (
+	"net"
 	"net/url"
+	"strconv"
+	"time"
 
 	"github.com/asaskevich/govalidator"
 )
@@ -36,3 +39,50 @@ func extractDomain(URL string) string {
 func isDNS(toTest string) bool {
 	return govalidator.IsDNSName(toTest)
 }
+
+// isPort tests a string to determine if it is a valid port number
+func isPort(toTest string) bool {
+	port, err := strconv.Atoi(toTest)
+	return err == nil && port > 0 && port <= 65535
+}
+
+// isCIDR tests a string to determine if it is a cidr range
+func isCIDR(toTest string) bool {
+	_, _, err := net.ParseCIDR(toTest)
+	return err == nil
+}
+
+// expandCIDR returns the addresses of a cidr range, up to limit addresses
+func expandCIDR(cidr string, limit int) []string {
+	ip, network, err := net.ParseCIDR(cidr)
+	if err != nil {
+		return nil
+	}
+
+	var addresses []string
+	for ip = ip.Mask(network.Mask); network.Contains(ip) && len(addresses) < limit; ip = nextIP(ip) {
+		addresses = append(addresses, ip.String())
+	}
+	return addresses
+}
+
+// nextIP returns the address following ip
+func nextIP(ip net.IP) net.IP {
+	next := make(net.IP, len(ip))
+	copy(next, ip)
+	for i := len(next) - 1; i >= 0; i-- {
+		next[i]++
+		if next[i] != 0 {
+			break
+		}
+	}
+	return next
+}
+
+// timeoutOrDefault returns the timeout in seconds as duration, 5 seconds if not set
+func timeoutOrDefault(timeout int) time.Duration {
+	if timeout <= 0 {
+		timeout = 5
+	}
+	return time.Duration(timeout) * time.Second
+}
@@ -0,0 +1,164 @@
+package executer
+
+import (
+	"bufio"
+	"bytes"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"time"
+
+	"github.com/projectdiscovery/nuclei/v2/pkg/generators"
+	"github.com/projectdiscovery/nuclei/v2/pkg/matchers"
+	"github.com/projectdiscovery/nuclei/v2/pkg/requests"
+)
+
+// HTTPResponse is a response the matchers and extractors of an http request
+// are evaluated on, received by the executer or recorded beforehand.
+type HTTPResponse struct {
+	Response *http.Response
+	// Body is the decompressed body of the response
+	Body string
+	// Headers are the headers of the response, one per line
+	Headers  string
+	Duration time.Duration
+	RemoteIP string
+	// Baseline contains the baseline of the similarity and time matchers, if any
+	Baseline *matchers.Baseline
+	// Position is the 0-based position of the request of the response among
+	// the requests of the template, the internal matchers applying to some.
+	Position int
+	// Metrics are the metrics of the response, computed by EvaluateHTTP with
+	// the default limit of the regex inputs if nil.
+	Metrics *ResponseMetrics
+}
+
+// ReadHTTPResponse reads a recorded raw http response, its status line and
+// headers followed by its body. The body is the rest of the data if it is
+// shorter than its content length, the recorded bodies being often edited.
+// The data not starting with a status line is the body of a 200 response
+// without headers, the tools storing the responses often keeping only them.
+func ReadHTTPResponse(data []byte) (*HTTPResponse, error) {
+	if !bytes.HasPrefix(data, []byte("HTTP/")) {
+		resp := &http.Response{
+			Status:        "200 OK",
+			StatusCode:    http.StatusOK,
+			Proto:         "HTTP/1.1",
+			ProtoMajor:    1,
+			ProtoMinor:    1,
+			Header:        make(http.Header),
+			Body:          ioutil.NopCloser(bytes.NewReader(data)),
+			ContentLength: int64(len(data)),
+		}
+		return &HTTPResponse{Response: resp, Body: string(data)}, nil
+	}
+	resp, err := http.ReadResponse(bufio.NewReader(bytes.NewReader(data)), nil)
+	if err != nil {
+		return nil, err
+	}
+	body, err := ioutil.ReadAll(resp.Body)
+	resp.Body.Close()
+	if err != nil && err != io.ErrUnexpectedEOF {
+		return nil, err
+	}
+	resp.Body = ioutil.NopCloser(bytes.NewReader(body))
+	return &HTTPResponse{
+		Response: resp,
+		Body:     string(body),
+		Headers:  headersToString(resp.Header),
+	}, nil
+}
+
+// Evaluation is the outcome of the matchers and extractors of an http
+// request on a response.
+type Evaluation struct {
+	// InternalFailed is true if an internal matcher applying to the
+	// response failed, the remaining requests being aborted.
+	InternalFailed bool
+	// ANDFailed is true if an output matcher of the and condition failed,
+	// the extractors not being evaluated.
+	ANDFailed bool
+	// ANDMatched is true if all the output matchers of the and condition matched
+	ANDMatched bool
+	// Matched are the output matchers of the or condition which matched
+	Matched []*matchers.Matcher
+	// Extractions are the values extracted by each extractor of the request, by index
+	Extractions [][]string
+	// OutputValues are the values extracted by the non-internal extractors
+	OutputValues []string
+}
+
+// HasResults returns true if the response matched or had values extracted
+// by the output extractors, requests without matchers only having results
+// for the non-empty extractions.
+func (e *Evaluation) HasResults() bool {
+	return len(e.Matched) > 0 || len(e.OutputValues) > 0 || e.ANDMatched
+}
+
+// EvaluateHTTP evaluates the matchers and extractors of an http request on
+// a response without any network I/O. The first value of each named
+// extractor is set in the values, the next extractors and requests using it.
+// The metrics of the response are available to the dsl expressions along
+// with the values.
+func EvaluateHTTP(request *requests.BulkHTTPRequest, response *HTTPResponse, values map[string]interface{}) *Evaluation {
+	evaluation := &Evaluation{}
+	baseline := response.Baseline
+	if baseline == nil {
+		baseline = &matchers.Baseline{}
+	}
+	if response.Metrics == nil {
+		response.Metrics = NewResponseMetrics(response.Response.StatusCode, response.Body, nil)
+	}
+	variables := generators.MergeMaps(values, response.Metrics.values())
+
+	// Internal matchers of the current request gate the remaining requests
+	outputMatchers := 0
+	for _, matcher := range request.Matchers {
+		if !matcher.Internal {
+			outputMatchers++
+			continue
+		}
+		if matcher.AppliesTo(response.Position) && !matcher.Match(response.Response, response.Body, response.Headers, response.Duration, baseline, response.RemoteIP, variables) {
+			evaluation.InternalFailed = true
+			return evaluation
+		}
+	}
+
+	matcherCondition := request.GetMatchersCondition()
+	for _, matcher := range request.Matchers {
+		// Internal matchers don't produce results
+		if matcher.Internal {
+			continue
+		}
+		if !matcher.Match(response.Response, response.Body, response.Headers, response.Duration, baseline, response.RemoteIP, variables) {
+			// If the condition is AND we haven't matched, try next request.
+			if matcherCondition == matchers.ANDCondition {
+				evaluation.ANDFailed = true
+				return evaluation
+			}
+		} else if matcherCondition == matchers.ORCondition {
+			// keep the matcher to write a result for each distinct matcher
+			evaluation.Matched = append(evaluation.Matched, matcher)
+		}
+	}
+	evaluation.ANDMatched = matcherCondition == matchers.ANDCondition && outputMatchers > 0
+
+	// All matchers have successfully completed so now start with the
+	// next task which is extraction of input from matchers.
+	for _, extractor := range request.Extractors {
+		// the extracted values are kept once the body of the response,
+		// which they may share, is reused
+		matches := cloneStrings(extractor.Extract(response.Response, response.Body, response.Headers, response.Duration, response.RemoteIP, variables))
+		// the first value of a named extractor is available to the next
+		// requests to the target, replacing the value of previous responses.
+		if extractor.Name != "" && len(matches) > 0 {
+			values[extractor.Name] = matches[0]
+			variables[extractor.Name] = matches[0]
+		}
+		evaluation.Extractions = append(evaluation.Extractions, matches)
+		if !extractor.Internal {
+			evaluation.OutputValues = append(evaluation.OutputValues, matches...)
+		}
+	}
+	return evaluation
+}
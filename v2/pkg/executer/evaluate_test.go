@@ -0,0 +1,120 @@
+package executer
+
+import (
+	"bufio"
+	"bytes"
+	"testing"
+
+	"github.com/logrusorgru/aurora"
+	"github.com/stretchr/testify/require"
+)
+
+func TestEvaluateHTTP(t *testing.T) {
+	template := parseTemplate(t, `
+id: evaluate
+info:
+  name: evaluate
+  author: test
+requests:
+  - method: GET
+    path:
+      - "{{BaseURL}}"
+    matchers-condition: and
+    matchers:
+      - type: status
+        status:
+          - 200
+      - type: word
+        part: header
+        words:
+          - "Apache"
+    extractors:
+      - type: regex
+        name: version
+        part: header
+        group: 1
+        regex:
+          - "Apache/([0-9.]+)"
+`)
+	request := template.BulkRequestsHTTP[0]
+
+	response, err := ReadHTTPResponse([]byte("HTTP/1.1 200 OK\nServer: Apache/2.4.49\nContent-Length: 100\n\nedited body\n"))
+	require.Nil(t, err, "Could not read recorded response")
+	require.Equal(t, "edited body\n", response.Body, "Could not read the body shorter than its content length")
+
+	values := make(map[string]interface{})
+	evaluation := EvaluateHTTP(request, response, values)
+	require.True(t, evaluation.HasResults(), "Could not match the recorded response")
+	require.Equal(t, [][]string{{"2.4.49"}}, evaluation.Extractions, "Could not extract from the recorded response")
+	require.Equal(t, "2.4.49", values["version"], "Could not set the named extracted value")
+
+	response, err = ReadHTTPResponse([]byte("HTTP/1.1 404 Not Found\nServer: Apache/2.4.49\n\n"))
+	require.Nil(t, err, "Could not read recorded response")
+	evaluation = EvaluateHTTP(request, response, make(map[string]interface{}))
+	require.True(t, evaluation.ANDFailed, "Could not fail the and condition")
+	require.False(t, evaluation.HasResults(), "Could not skip the results of a failed and condition")
+}
+
+func TestEvaluateHTTPMetrics(t *testing.T) {
+	template := parseTemplate(t, `
+id: metrics
+info:
+  name: metrics
+  author: test
+requests:
+  - method: GET
+    path:
+      - "{{BaseURL}}"
+    matchers:
+      - type: dsl
+        dsl:
+          - "body_words == 3 && body_lines == 2 && body_length == 21 && !body_truncated && body_words == word_count(body)"
+`)
+	response, err := ReadHTTPResponse([]byte("HTTP/1.1 200 OK\n\nhéllo wörld\nsecond\n"))
+	require.Nil(t, err, "Could not read recorded response")
+
+	evaluation := EvaluateHTTP(template.BulkRequestsHTTP[0], response, make(map[string]interface{}))
+	require.True(t, evaluation.HasResults(), "Could not match the metrics of the response")
+	require.Equal(t, 3, response.Metrics.BodyWords, "Could not keep the metrics of the response")
+}
+
+func TestReadHTTPResponseBody(t *testing.T) {
+	response, err := ReadHTTPResponse([]byte("<html>only the body</html>"))
+	require.Nil(t, err, "Could not read stored body")
+	require.Equal(t, 200, response.Response.StatusCode, "Could not read the body as a 200 response")
+	require.Equal(t, "<html>only the body</html>", response.Body, "Could not read the stored body")
+}
+
+func TestExecutePassive(t *testing.T) {
+	template := parseTemplate(t, `
+id: passive
+info:
+  name: passive
+  author: test
+requests:
+  - method: GET
+    path:
+      - "{{BaseURL}}/admin"
+    matchers:
+      - type: word
+        words:
+          - "dashboard"
+`)
+	output := &bytes.Buffer{}
+	writer := bufio.NewWriter(output)
+	executer, err := NewHTTPExecuter(&HTTPOptions{Template: template, BulkHttpRequest: template.BulkRequestsHTTP[0], Writer: writer, JSON: true, Timeout: 5, Colorizer: aurora.NewAurora(false)})
+	require.Nil(t, err, "Could not create http executer")
+
+	response, err := ReadHTTPResponse([]byte("HTTP/1.1 200 OK\nServer: test\n\nthe dashboard\n"))
+	require.Nil(t, err, "Could not read stored response")
+	result := executer.ExecutePassive("http://example.com/admin", response)
+	require.Nil(t, result.Error, "Could not evaluate stored response")
+	require.True(t, result.GotResults, "Could not match the stored response")
+	writer.Flush()
+	require.Contains(t, output.String(), `"matched":"http://example.com/admin"`, "Could not write the target of the stored response")
+	require.Contains(t, output.String(), `"passive":true`, "Could not mark the result as passive")
+
+	response, err = ReadHTTPResponse([]byte("nothing here"))
+	require.Nil(t, err, "Could not read stored body")
+	require.False(t, executer.ExecutePassive("http://example.com/", response).GotResults, "Could not skip the stored response not matching")
+}
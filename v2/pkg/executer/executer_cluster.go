@@ -0,0 +1,92 @@
+package executer
+
+import (
+	"context"
+	"time"
+
+	"github.com/pkg/errors"
+	"github.com/projectdiscovery/gologger"
+	"github.com/projectdiscovery/nuclei/v2/internal/progress"
+	"github.com/projectdiscovery/nuclei/v2/pkg/hosterrors"
+)
+
+// ClusterExecuter sends the request shared by the http executers of the
+// templates of a cluster once per target, evaluating its response with the
+// matchers and the extractors of each template, which write their results
+// under their own id.
+type ClusterExecuter struct {
+	executers []*HTTPExecuter
+}
+
+// NewClusterExecuter creates an executer for the http executers of blocks
+// with the same cluster key, the request being built by the first one.
+func NewClusterExecuter(executers []*HTTPExecuter) *ClusterExecuter {
+	return &ClusterExecuter{executers: executers}
+}
+
+// ExecuteHTTP sends the request of the cluster to a target until the
+// context is done and returns the results of the executers at the indexes,
+// in the same order. Each of them counts as a request of the progress.
+func (c *ClusterExecuter) ExecuteHTTP(ctx context.Context, p *progress.Progress, URL string, indexes []int) []Result {
+	results := make([]Result, len(indexes))
+	for i := range results {
+		results[i].Matches = make(map[string]interface{})
+		results[i].Extractions = make(map[string]interface{})
+	}
+	if len(indexes) == 0 {
+		return results
+	}
+	// the templates of the cluster share the wall time of its runs
+	defer func(start time.Time) {
+		for _, index := range indexes {
+			c.executers[index].benchmark.Run(c.executers[index].template.ID, time.Since(start))
+		}
+	}(time.Now())
+	fail := func(err error) []Result {
+		for i := range results {
+			results[i].Error = err
+		}
+		if p != nil {
+			p.Drop(int64(len(indexes)))
+		}
+		return results
+	}
+
+	if err := ctx.Err(); err != nil {
+		return fail(err)
+	}
+	leader := c.executers[indexes[0]]
+	if leader.hostErrors.Dead(URL) {
+		return fail(hosterrors.ErrSkipped)
+	}
+	request, err := leader.buildRequest(URL, nil, leader.bulkHttpRequest.Path[0])
+	if err != nil {
+		return fail(errors.Wrap(err, "could not build http request"))
+	}
+	// the requests of the other templates are not sent
+	leader.stats.RequestsClustered(int64(len(indexes) - 1))
+	// the response of the same baseline request of -tech-detect is reused
+	var exchange *httpExchange
+	if shared, ok := leader.sharedResponse(request); ok {
+		leader.stats.RequestShared()
+		exchange, err = leader.cachedExchange(URL, request.Request.Request, shared)
+	} else {
+		exchange, err = leader.send(ctx, URL, request, leader.cacheKey(URL, request, nil))
+	}
+	if err != nil {
+		return fail(errors.Wrap(err, "could not handle http request"))
+	}
+	defer exchange.release()
+
+	for i, index := range indexes {
+		err := c.executers[index].handleResponse(ctx, URL, request, exchange, nil, nil, &results[i])
+		if err != nil && err != errInternalMatcher {
+			results[i].Error = errors.Wrap(err, "could not handle http request")
+		}
+		if p != nil {
+			p.Update()
+		}
+	}
+	gologger.Verbosef("Sent HTTP request to %s\n", "http-request", URL)
+	return results
+}
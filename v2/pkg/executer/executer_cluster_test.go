@@ -0,0 +1,115 @@
+package executer
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync/atomic"
+	"testing"
+
+	"github.com/logrusorgru/aurora"
+	"github.com/projectdiscovery/nuclei/v2/pkg/stats"
+	"github.com/projectdiscovery/nuclei/v2/pkg/templates"
+	"github.com/stretchr/testify/require"
+)
+
+func TestClusterExecuter(t *testing.T) {
+	var hits int64
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		atomic.AddInt64(&hits, 1)
+		fmt.Fprintf(w, "<title>Apache</title> nginx")
+	}))
+	defer server.Close()
+
+	clustered := func(id, word, headers string) string {
+		return fmt.Sprintf(`
+id: %s
+info:
+  name: %s
+  author: test
+  severity: info
+requests:
+  - method: GET
+    path:
+      - "{{BaseURL}}/"
+%s    matchers:
+      - type: word
+        words: ["%s"]
+`, id, id, headers, word)
+	}
+	apache := parseTemplate(t, clustered("apache-detect", "Apache", ""))
+	nginx := parseTemplate(t, clustered("nginx-detect", "nginx", "    headers:\n      accept: \"*/*\"\n"))
+	iis := parseTemplate(t, clustered("iis-detect", "IIS", "    headers:\n      Accept: \"*/*\"\n"))
+	random := parseTemplate(t, clustered("random-detect", "Apache", "    headers:\n      X-Token: \"{{rand_base(5)}}\"\n"))
+
+	key, ok := nginx.BulkRequestsHTTP[0].ClusterKey()
+	require.True(t, ok, "Could not cluster static request")
+	iisKey, _ := iis.BulkRequestsHTTP[0].ClusterKey()
+	require.Equal(t, key, iisKey, "Could not normalize the header names")
+	apacheKey, _ := apache.BulkRequestsHTTP[0].ClusterKey()
+	require.NotEqual(t, key, apacheKey, "Could not keep the requests with other headers apart")
+	_, ok = random.BulkRequestsHTTP[0].ClusterKey()
+	require.False(t, ok, "Could not exclude dynamic values")
+
+	output := &bytes.Buffer{}
+	writer := bufio.NewWriter(output)
+	counters := stats.New(1)
+	var executers []*HTTPExecuter
+	for _, template := range []*templates.Template{nginx, iis} {
+		executer, err := NewHTTPExecuter(&HTTPOptions{Template: template, BulkHttpRequest: template.BulkRequestsHTTP[0], Writer: writer, Timeout: 5, Stats: counters, Colorizer: aurora.NewAurora(false)})
+		require.Nil(t, err, "Could not create http executer")
+		executers = append(executers, executer)
+	}
+	results := NewClusterExecuter(executers).ExecuteHTTP(context.Background(), nil, server.URL, []int{0, 1})
+	writer.Flush()
+
+	require.Equal(t, int64(1), atomic.LoadInt64(&hits), "Could not send the request of the cluster once")
+	require.Len(t, results, 2, "Could not return the results of the templates")
+	require.Nil(t, results[0].Error, "Could not execute cluster")
+	require.True(t, results[0].GotResults, "Could not match the first template")
+	require.False(t, results[1].GotResults, "Could not evaluate the matchers of each template")
+	require.True(t, strings.HasPrefix(output.String(), "[nginx-detect] [http] "), "Could not attribute the result to its template")
+	require.Equal(t, uint64(1), counters.Summary(0, false).ClusteredRequests, "Could not count the clustered requests")
+}
+
+func TestSharedResponses(t *testing.T) {
+	var hits int64
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		atomic.AddInt64(&hits, 1)
+		w.Header().Set("Server", "nginx")
+		fmt.Fprintf(w, "welcome to %s", r.URL.Path)
+	}))
+	defer server.Close()
+
+	template := func(id, path string) *templates.Template {
+		return parseTemplate(t, fmt.Sprintf("id: %s\ninfo:\n  name: %s\n  author: test\n  severity: info\nrequests:\n  - method: GET\n    path:\n      - \"%s\"\n    matchers:\n      - type: word\n        part: header\n        words: [nginx]\n", id, id, path))
+	}
+	shared := NewSharedResponses()
+	counters := stats.New(1)
+	newExecuter := func(template *templates.Template) *HTTPExecuter {
+		executer, err := NewHTTPExecuter(&HTTPOptions{Template: template, BulkHttpRequest: template.BulkRequestsHTTP[0], Timeout: 5, Stats: counters, Quiet: true, SharedResponses: shared, Colorizer: aurora.NewAurora(false)})
+		require.Nil(t, err, "Could not create http executer")
+		return executer
+	}
+
+	// the baseline request of the root is shared with the clusters
+	// requesting the root with or without the trailing slash
+	response, err := newExecuter(template("baseline", "{{BaseURL}}")).Fetch(context.Background(), server.URL)
+	require.Nil(t, err, "Could not fetch the baseline")
+	require.Equal(t, "welcome to /", string(response.Body), "Could not return the baseline response")
+	cluster := NewClusterExecuter([]*HTTPExecuter{newExecuter(template("nginx-detect", "{{BaseURL}}/"))})
+	results := cluster.ExecuteHTTP(context.Background(), nil, server.URL, []int{0})
+	require.Nil(t, results[0].Error, "Could not execute cluster")
+	require.True(t, results[0].GotResults, "Could not match the shared response")
+	require.Equal(t, int64(1), atomic.LoadInt64(&hits), "Could not reuse the baseline response")
+	require.Equal(t, uint64(1), counters.Summary(0, false).SharedRequests, "Could not count the shared requests")
+
+	cluster = NewClusterExecuter([]*HTTPExecuter{newExecuter(template("admin-detect", "{{BaseURL}}/admin"))})
+	results = cluster.ExecuteHTTP(context.Background(), nil, server.URL, []int{0})
+	require.True(t, results[0].GotResults, "Could not execute cluster")
+	require.Equal(t, int64(2), atomic.LoadInt64(&hits), "Could not send the requests of other paths")
+}
@@ -2,19 +2,38 @@ package executer
 
 import (
 	"bufio"
+	"context"
 	"fmt"
+	"net"
 	"os"
 	"regexp"
+	"strings"
 	"sync"
+	"time"
 
 	"github.com/logrusorgru/aurora"
+	"github.com/miekg/dns"
 	"github.com/pkg/errors"
 	"github.com/projectdiscovery/gologger"
 	"github.com/projectdiscovery/nuclei/v2/internal/progress"
+	"github.com/projectdiscovery/nuclei/v2/pkg/adaptive"
+	"github.com/projectdiscovery/nuclei/v2/pkg/benchmark"
+	"github.com/projectdiscovery/nuclei/v2/pkg/collector"
+	"github.com/projectdiscovery/nuclei/v2/pkg/dedupe"
+	"github.com/projectdiscovery/nuclei/v2/pkg/dnsrecords"
+	"github.com/projectdiscovery/nuclei/v2/pkg/exclusions"
+	"github.com/projectdiscovery/nuclei/v2/pkg/export"
+	"github.com/projectdiscovery/nuclei/v2/pkg/generators"
+	"github.com/projectdiscovery/nuclei/v2/pkg/grouping"
+	"github.com/projectdiscovery/nuclei/v2/pkg/hosterrors"
+	"github.com/projectdiscovery/nuclei/v2/pkg/markdown"
 	"github.com/projectdiscovery/nuclei/v2/pkg/matchers"
+	"github.com/projectdiscovery/nuclei/v2/pkg/ratelimit"
+	"github.com/projectdiscovery/nuclei/v2/pkg/redact"
 	"github.com/projectdiscovery/nuclei/v2/pkg/requests"
+	"github.com/projectdiscovery/nuclei/v2/pkg/sarif"
+	"github.com/projectdiscovery/nuclei/v2/pkg/stats"
 	"github.com/projectdiscovery/nuclei/v2/pkg/templates"
-	retryabledns "github.com/projectdiscovery/retryabledns"
 )
 
 // DNSExecuter is a client for performing a DNS request
@@ -22,16 +41,63 @@ import (
 type DNSExecuter struct {
 	debug       bool
 	jsonOutput  bool
+	jsonRequest bool
 	Results     bool
-	dnsClient   *retryabledns.Client
+	timeout     time.Duration
+	// retries is the number of attempts of a request to the resolvers
+	retries int
+	// ptrCIDRLimit is the maximum number of addresses of a cidr range for PTR requests
+	ptrCIDRLimit int
+	includeRR    bool
+	// exclusions suppress the results matching known false positives
+	exclusions     *exclusions.Exclusions
+	showSuppressed bool
+
+	// collector collects the extracted values of the scan into a single file
+	collector *collector.Collector
+	// files are the files the values of the extractors are appended to
+	files *collector.Files
+	// exporter collects the results of the scan into a SARIF log
+	exporter *sarif.Exporter
+	// markdown writes the evidence of the results to a markdown report
+	markdown *markdown.Exporter
+	// exporters send the json results to external services
+	exporters []export.Exporter
+	// deduper suppresses the findings reported before if any
+	deduper        *dedupe.Deduper
+	showDuplicates bool
+	// stats count the requests and findings of the scan if any
+	stats *stats.Stats
+	// benchmark records the timings of the requests if any
+	benchmark *benchmark.Recorder
+	// discardResults only counts the results in the stats
+	discardResults bool
+	// redactor redacts the sensitive headers written, nil with -no-redact
+	redactor *redact.Redactor
+	// grouper buffers the results shown on screen by host if any
+	grouper *grouping.Grouper
+	// quiet shows no results on screen
+	quiet bool
+	// rateLimiter limits the rate of the requests to each host if any
+	rateLimiter *ratelimit.Limiter
+	// adaptive adapts the concurrency of the requests to each host to their
+	// network errors if any
+	adaptive *adaptive.Controller
+	// passiveExtract writes only the values of extractor-only requests
+	passiveExtract bool
+	// internal requests don't write their results, only gating the next requests
+	internal bool
+
+	resolvers   *ResolverPool
+	pools       *ResolverPools
 	template    *templates.Template
 	dnsRequest  *requests.DNSRequest
 	writer      *bufio.Writer
 	outputMutex *sync.Mutex
 
-	coloredOutput	bool
-	colorizer		aurora.Aurora
-	decolorizer		*regexp.Regexp
+	coloredOutput bool
+	colorizer     aurora.Aurora
+	decolorizer   *regexp.Regexp
 }
 
 // DefaultResolvers contains the list of resolvers known to be trusted.
@@ -42,53 +108,319 @@ var DefaultResolvers = []string{
 	"8.8.4.4:53", // Google
 }
 
+// ResolverPools holds the pool of the default resolvers and the pools
+// of the resolvers of the templates, so that the state of the resolvers
+// is kept across the executers of a scan.
+type ResolverPools struct {
+	mutex     *sync.Mutex
+	defaults  *ResolverPool
+	templates map[string]*ResolverPool
+}
+
+// NewResolverPools creates a new set of resolver pools
+func NewResolverPools() *ResolverPools {
+	return &ResolverPools{mutex: &sync.Mutex{}, templates: make(map[string]*ResolverPool)}
+}
+
+// Default returns the pool of the default resolvers
+func (p *ResolverPools) Default() *ResolverPool {
+	p.mutex.Lock()
+	defer p.mutex.Unlock()
+
+	if p.defaults == nil {
+		p.defaults, _ = NewResolverPool(DefaultResolvers, 5*time.Second)
+	}
+	return p.defaults
+}
+
+// Template returns the pool for the resolvers of a template
+func (p *ResolverPools) Template(resolvers []string, timeout time.Duration) (*ResolverPool, error) {
+	p.mutex.Lock()
+	defer p.mutex.Unlock()
+
+	key := strings.Join(resolvers, ",")
+	if pool, ok := p.templates[key]; ok {
+		return pool, nil
+	}
+	pool, err := NewResolverPool(resolvers, timeout)
+	if err != nil {
+		return nil, err
+	}
+	p.templates[key] = pool
+	return pool, nil
+}
+
+// defaultPTRCIDRLimit is the default maximum number of addresses of a cidr range for PTR requests
+const defaultPTRCIDRLimit = 256
+
 // DNSOptions contains configuration options for the DNS executer.
 type DNSOptions struct {
-	Debug      bool
-	JSON       bool
-	Template   *templates.Template
-	DNSRequest *requests.DNSRequest
-	Writer     *bufio.Writer
+	Debug        bool
+	JSON         bool
+	JSONRequests bool
+	Template     *templates.Template
+	DNSRequest   *requests.DNSRequest
+	Writer       *bufio.Writer
+	// Resolvers is the pool of resolvers to query, the default resolvers are used if nil
+	Resolvers *ResolverPool
+	// Pools are the resolver pools shared by the executers of a scan, the executer uses its own if nil
+	Pools *ResolverPools
+	// Timeout is the seconds to wait for a response from the resolvers
+	Timeout int
+	// Retries is the number of attempts of a request to the resolvers
+	Retries int
+	// Resolved uses the timeout and retries of the options even if the
+	// request has its own, the options being the effective values.
+	Resolved bool
+	// PTRCIDRLimit is the maximum number of addresses of a cidr range for
+	// PTR requests, 256 is used if not set.
+	PTRCIDRLimit int
+	// IncludeRR writes the records of all the sections in JSON output
+	IncludeRR bool
+	// Exclusions suppress the results matching known false positives
+	Exclusions *exclusions.Exclusions
+	// ShowSuppressed shows the results suppressed by the exclusions
+	ShowSuppressed bool
+	// Collector collects the extracted values of the scan if any
+	Collector *collector.Collector
+	// Files are the files the values of the extractors are appended to if any
+	Files *collector.Files
+	// Exporter collects the results of the scan into a SARIF log if any
+	Exporter *sarif.Exporter
+	// Markdown writes the evidence of the results to a markdown report if any
+	Markdown *markdown.Exporter
+	// Exporters send the json results to external services, such as
+	// elasticsearch or a webhook.
+	Exporters []export.Exporter
+	// Deduper suppresses the findings reported before by the run or by a
+	// previous run if any.
+	Deduper *dedupe.Deduper
+	// ShowDuplicates writes the suppressed duplicates to the json output
+	ShowDuplicates bool
+	// Stats count the requests and findings of the scan if any
+	Stats *stats.Stats
+	// Benchmark records the timings of the runs and the requests of the
+	// template if any.
+	Benchmark *benchmark.Recorder
+	// DiscardResults counts the results in the stats without writing,
+	// exporting or deduplicating them, for -benchmark-no-output.
+	DiscardResults bool
+	// Redactor redacts the sensitive headers of everything written, the
+	// default headers being redacted if nil.
+	Redactor *redact.Redactor
+	// NoRedact writes the sensitive headers and the secrets of the
+	// templates as is, for debugging.
+	NoRedact bool
+	// Grouper buffers the results shown on screen by host if any, the
+	// json output and the exports being written as they are found.
+	Grouper *grouping.Grouper
+	// Quiet shows no results on screen, the output file and the exports
+	// being written, for the programs embedding the engine.
+	Quiet bool
+	// RateLimiter limits the rate of the requests to each host shared by
+	// the executers if any, the dns servers of the targets with a port
+	// or the name servers of the queried domains.
+	RateLimiter *ratelimit.Limiter
+	// Adaptive limits the requests in flight to each host shared by the
+	// executers if any, the hosts being the same as the rate limiter's.
+	Adaptive *adaptive.Controller
+	// PassiveExtract writes only the extracted values of extractor-only requests
+	PassiveExtract bool
+	// Internal executes the request without writing its results, for the
+	// requests gating the execution of the next ones.
+	Internal bool
 
-	ColoredOutput	bool
-	Colorizer		aurora.Aurora
-	Decolorizer		*regexp.Regexp
+	ColoredOutput bool
+	Colorizer     aurora.Aurora
+	Decolorizer   *regexp.Regexp
 }
 
 // NewDNSExecuter creates a new DNS executer from a template
 // and a DNS request query.
-func NewDNSExecuter(options *DNSOptions) *DNSExecuter {
-	dnsClient := retryabledns.New(DefaultResolvers, options.DNSRequest.Retries)
+func NewDNSExecuter(options *DNSOptions) (*DNSExecuter, error) {
+	// the timeout and retries of the request take precedence over the global ones
+	timeout := timeoutOrDefault(options.Timeout)
+	retries := options.Retries
+	if !options.Resolved {
+		if options.DNSRequest.Timeout > 0 {
+			timeout = time.Duration(options.DNSRequest.Timeout) * time.Second
+		}
+		if options.DNSRequest.Retries > 0 {
+			retries = options.DNSRequest.Retries
+		}
+	}
+
+	pools := options.Pools
+	if pools == nil {
+		pools = NewResolverPools()
+	}
+	resolvers := options.Resolvers
+	// resolvers specified in the template take precedence
+	if len(options.DNSRequest.Resolvers) > 0 {
+		var err error
+		resolvers, err = pools.Template(options.DNSRequest.Resolvers, timeout)
+		if err != nil {
+			return nil, err
+		}
+	}
+	if resolvers == nil {
+		resolvers = pools.Default()
+	}
+	if options.PTRCIDRLimit <= 0 {
+		options.PTRCIDRLimit = defaultPTRCIDRLimit
+	}
 
 	executer := &DNSExecuter{
-		debug:       options.Debug,
-		jsonOutput:  options.JSON,
-		dnsClient:   dnsClient,
-		template:    options.Template,
-		dnsRequest:  options.DNSRequest,
-		writer:      options.Writer,
-		outputMutex: &sync.Mutex{},
-		coloredOutput:   options.ColoredOutput,
-		colorizer:       options.Colorizer,
-		decolorizer:     options.Decolorizer,
-	}
-	return executer
-}
-
-// ExecuteDNS executes the DNS request on a URL
-func (e *DNSExecuter) ExecuteDNS(p *progress.Progress, URL string) (result Result) {
+		debug:          options.Debug,
+		jsonOutput:     options.JSON,
+		jsonRequest:    options.JSONRequests,
+		timeout:        timeout,
+		retries:        retries,
+		ptrCIDRLimit:   options.PTRCIDRLimit,
+		includeRR:      options.IncludeRR,
+		exclusions:     options.Exclusions,
+		showSuppressed: options.ShowSuppressed,
+		collector:      options.Collector,
+		files:          options.Files,
+		exporter:       options.Exporter,
+		markdown:       options.Markdown,
+		deduper:        options.Deduper,
+		showDuplicates: options.ShowDuplicates,
+		stats:          options.Stats,
+		benchmark:      options.Benchmark,
+		discardResults: options.DiscardResults,
+		redactor:       newRedactor(options.Redactor, options.NoRedact),
+		grouper:        options.Grouper,
+		quiet:          options.Quiet,
+		rateLimiter:    options.RateLimiter,
+		adaptive:       options.Adaptive,
+		exporters:      options.Exporters,
+		passiveExtract: options.PassiveExtract,
+		internal:       options.Internal,
+		resolvers:      resolvers,
+		pools:          pools,
+		template:       options.Template,
+		dnsRequest:     options.DNSRequest,
+		writer:         options.Writer,
+		outputMutex:    &sync.Mutex{},
+		coloredOutput:  options.ColoredOutput,
+		colorizer:      options.Colorizer,
+		decolorizer:    options.Decolorizer,
+	}
+	return executer, nil
+}
+
+// ExecuteDNS executes the DNS request on a URL.
+//
+// PTR requests towards a cidr range are executed for each of its addresses.
+func (e *DNSExecuter) ExecuteDNS(p *progress.Progress, URL string) Result {
+	return e.ExecuteDNSWithContext(context.Background(), p, URL, nil)
+}
+
+// ExecuteDNSWithValues executes the DNS request on a URL, the values of
+// a previous template being available to the request like its variables.
+func (e *DNSExecuter) ExecuteDNSWithValues(p *progress.Progress, URL string, values map[string]interface{}) Result {
+	return e.ExecuteDNSWithContext(context.Background(), p, URL, values)
+}
+
+// ExecuteDNSWithContext executes the DNS request on a URL with values until
+// the context is done, the requests not sent yet being abandoned with the
+// error of the context.
+func (e *DNSExecuter) ExecuteDNSWithContext(ctx context.Context, p *progress.Progress, URL string, values map[string]interface{}) (result Result) {
+	defer func(start time.Time) {
+		e.benchmark.Run(e.template.ID, time.Since(start))
+	}(time.Now())
+	domain, server, err := dnsTarget(URL)
+	if err != nil {
+		result.Error = err
+		if p != nil {
+			p.Drop(1)
+		}
+		return
+	}
+
+	if !e.dnsRequest.IsPTR() || !isCIDR(domain) {
+		return e.executeDNS(ctx, p, URL, domain, server, values)
+	}
+
+	addresses := expandCIDR(domain, e.ptrCIDRLimit)
+	if p != nil {
+		p.AddToTotal(int64(len(addresses) - 1))
+	}
+	result.Matches = make(map[string]interface{})
+	result.Extractions = make(map[string]interface{})
+	for i, address := range addresses {
+		if err := ctx.Err(); err != nil {
+			result.Error = err
+			if p != nil {
+				p.Drop(int64(len(addresses) - i))
+			}
+			return
+		}
+		addressResult := e.executeDNS(ctx, p, URL, address, "", values)
+		result.GotResults = result.GotResults || addressResult.GotResults
+		for name, value := range addressResult.Matches {
+			result.Matches[name] = value
+		}
+		for name, value := range addressResult.Extractions {
+			result.Extractions[name] = value
+		}
+		if addressResult.Error != nil {
+			result.Error = addressResult.Error
+		}
+	}
+	return
+}
+
+// dnsTarget returns the domain or the ip address of the dns requests
+// towards a target, along with the dns server to send them to for the
+// targets with a port.
+func dnsTarget(URL string) (domain, server string, err error) {
 	// Parse the URL and return domain if URL.
-	var domain string
 	if isURL(URL) {
 		domain = extractDomain(URL)
 	} else {
 		domain = URL
 	}
 
+	// targets with a port are the dns server to send the request to
+	if host, port, err := net.SplitHostPort(domain); err == nil {
+		if !isPort(port) {
+			return "", "", fmt.Errorf("invalid dns port for %s: %s", URL, port)
+		}
+		domain, server = host, net.JoinHostPort(host, port)
+	}
+	return domain, server, nil
+}
+
+// buildRequest builds the DNS request towards a domain or an ip address
+// without sending it, along with the values of the template for the
+// domain.
+func (e *DNSExecuter) buildRequest(domain string, values map[string]interface{}) (map[string]interface{}, *dns.Msg, error) {
+	// The variables of the template are evaluated once per target
+	variables, err := e.template.EvaluateVariables(generators.MergeMaps(values, map[string]interface{}{"FQDN": domain}))
+	if err != nil {
+		return nil, nil, errors.Wrap(err, "could not evaluate variables")
+	}
+	variables = generators.MergeMaps(values, variables)
+
 	// Compile each request for the template based on the URL
-	compiledRequest, err := e.dnsRequest.MakeDNSRequest(domain)
+	compiledRequest, err := e.dnsRequest.MakeDNSRequest(domain, variables)
+	if err != nil {
+		return nil, nil, errors.Wrap(err, "could not make dns request")
+	}
+	return variables, compiledRequest, nil
+}
+
+// executeDNS executes the DNS request towards a domain or an ip address,
+// sending it to the server if specified instead of the resolvers.
+func (e *DNSExecuter) executeDNS(ctx context.Context, p *progress.Progress, URL, domain, server string, values map[string]interface{}) (result Result) {
+	result.Matches = make(map[string]interface{})
+	result.Extractions = make(map[string]interface{})
+
+	variables, compiledRequest, err := e.buildRequest(domain, values)
 	if err != nil {
-		result.Error = errors.Wrap(err, "could not make dns request")
+		result.Error = err
 		if p != nil {
 			p.Drop(1)
 		}
@@ -97,13 +429,72 @@ func (e *DNSExecuter) ExecuteDNS(p *progress.Progress, URL string) (result Resul
 
 	if e.debug {
 		gologger.Infof("Dumped DNS request for %s (%s)\n\n", URL, e.template.ID)
-		fmt.Fprintf(os.Stderr, "%s\n", compiledRequest.String())
+		fmt.Fprintf(os.Stderr, "%s\n", e.redact(compiledRequest.String()))
+	}
+
+	// the requests to a dns server of the target are limited by server
+	limited := server
+	if limited == "" {
+		limited = net.JoinHostPort(strings.TrimSuffix(strings.ToLower(domain), "."), "53")
+	}
+	// the request is abandoned if the context is done while waiting
+	e.rateLimiter.Wait(ctx, limited)
+	if err := ctx.Err(); err != nil {
+		result.Error = err
+		if p != nil {
+			p.Drop(1)
+		}
+		return
+	}
+	if err := e.adaptive.Acquire(ctx, limited); err != nil {
+		result.Error = err
+		if p != nil {
+			p.Drop(1)
+		}
+		return
 	}
 
-	// Send the request to the target servers
-	resp, err := e.dnsClient.Do(compiledRequest)
+	// Send the request to the target servers, following the delegation
+	// chain from the roots if a trace was requested or transferring
+	// the zone for AXFR requests.
+	start := time.Now()
+	e.stats.Request()
+	var resp *dnsrecords.Response
+	var resolver *Resolver
+	switch {
+	case e.dnsRequest.Trace:
+		resp, err = e.traceDNS(compiledRequest)
+		if resp != nil {
+			resolver = serverResolver(resp.Trace.Hops[len(resp.Trace.Hops)-1].Server)
+		}
+	case compiledRequest.Question[0].Qtype == dns.TypeAXFR:
+		resp, err = e.transferDNS(compiledRequest, server)
+		if resp != nil {
+			resolver = serverResolver(resp.Transfer.Server)
+		}
+	default:
+		var msg *dns.Msg
+		var attempts []*Attempt
+		resolvers := e.resolvers
+		if server != "" {
+			if resolvers, err = e.pools.Template([]string{server}, e.timeout); err != nil {
+				break
+			}
+		}
+		msg, attempts, err = resolvers.Do(compiledRequest, e.retries, e.timeout)
+		resp = &dnsrecords.Response{Msg: msg}
+		if len(attempts) > 0 {
+			resolver = attempts[len(attempts)-1].Resolver
+		}
+		if e.debug {
+			dumpAttempts(URL, attempts)
+		}
+	}
+	e.stats.RequestDone()
+	e.benchmark.Request(e.template.ID, limited, time.Since(start), err)
+	e.adaptive.Release(limited, err != nil && hosterrors.IsNetworkError(err))
 	if err != nil {
-		result.Error = errors.Wrap(err, "could not send dns request")
+		result.Error = errors.Wrapf(err, "could not send dns request for %s", domain)
 		if p != nil {
 			p.Drop(1)
 		}
@@ -118,23 +509,27 @@ func (e *DNSExecuter) ExecuteDNS(p *progress.Progress, URL string) (result Resul
 
 	if e.debug {
 		gologger.Infof("Dumped DNS response for %s (%s)\n\n", URL, e.template.ID)
-		fmt.Fprintf(os.Stderr, "%s\n", resp.String())
+		fmt.Fprintf(os.Stderr, "%s\n", e.redact(resp.String()))
+		if resp.Trace != nil {
+			gologger.Infof("Dumped DNS trace for %s (%s)\n\n", URL, e.template.ID)
+			fmt.Fprintf(os.Stderr, "%s\n", e.redact(resp.Trace.String()))
+		}
 	}
 
 	matcherCondition := e.dnsRequest.GetMatchersCondition()
+	var matched []*matchers.Matcher
 	for _, matcher := range e.dnsRequest.Matchers {
 		// Check if the matcher matched
-		if !matcher.MatchDNS(resp) {
+		if !matcher.MatchDNS(resp, variables) {
 			// If the condition is AND we haven't matched, return.
 			if matcherCondition == matchers.ANDCondition {
 				return
 			}
 		} else {
 			// If the matcher has matched, and its an OR
-			// write the first output then move to next matcher.
-			if matcherCondition == matchers.ORCondition && len(e.dnsRequest.Extractors) == 0 {
-				e.writeOutputDNS(domain, matcher, nil)
-				result.GotResults = true
+			// keep it to write a result for each distinct matcher.
+			if matcherCondition == matchers.ORCondition {
+				matched = append(matched, matcher)
 			}
 		}
 	}
@@ -143,25 +538,100 @@ func (e *DNSExecuter) ExecuteDNS(p *progress.Progress, URL string) (result Resul
 	// next task which is extraction of input from matchers.
 	var extractorResults []string
 	for _, extractor := range e.dnsRequest.Extractors {
-		for match := range extractor.ExtractDNS(resp) {
+		matches := extractor.ExtractDNS(resp, variables)
+		writeToFile(e.files, e.template.ID, extractor, matches)
+		for _, match := range matches {
 			if !extractor.Internal {
 				extractorResults = append(extractorResults, match)
 			}
 		}
+		if len(matches) > 0 {
+			result.Extractions[extractor.Name] = matches
+		}
+	}
+
+	// Results of responses matching an exclusion are suppressed. Requests
+	// without matchers only have a result for non-empty extractions.
+	andMatched := matcherCondition == matchers.ANDCondition && len(e.dnsRequest.Matchers) > 0
+	hasResults := len(matched) > 0 || len(extractorResults) > 0 || andMatched
+	if hasResults && e.isSuppressed(domain, resp) {
+		return
+	}
+	if e.internal {
+		for _, matcher := range matched {
+			result.Matches[matcher.Name] = nil
+		}
+		if andMatched {
+			for _, matcher := range e.dnsRequest.Matchers {
+				result.Matches[matcher.Name] = nil
+			}
+		}
+		result.GotResults = hasResults
+		return
+	}
+	collect(e.collector, extractorResults)
+
+	// Write a result for each distinct matcher of an OR condition along
+	// with the extracted values, so each finding is self-contained.
+	if len(matched) > 0 {
+		for _, matcher := range distinctMatchers(matched) {
+			result.Matches[matcher.Name] = nil
+			e.writeOutputDNS(ctx, domain, resolver, resp, matcher, extractorResults)
+		}
+		result.GotResults = true
+		return
 	}
 
 	// Write a final string of output if matcher type is
 	// AND or if we have extractors for the mechanism too.
-	if len(e.dnsRequest.Extractors) > 0 || matcherCondition == matchers.ANDCondition {
-		e.writeOutputDNS(domain, nil, extractorResults)
+	if len(extractorResults) > 0 || andMatched {
+		if andMatched {
+			for _, matcher := range e.dnsRequest.Matchers {
+				result.Matches[matcher.Name] = nil
+			}
+		}
+		e.writeOutputDNS(ctx, domain, resolver, resp, nil, extractorResults)
+		result.GotResults = true
 	}
 
 	return
 }
 
+// isSuppressed returns true if the response matches an exclusion,
+// showing the suppressed result if asked for auditing.
+func (e *DNSExecuter) isSuppressed(domain string, resp *dnsrecords.Response) bool {
+	if e.exclusions == nil {
+		return false
+	}
+	index := e.exclusions.MatchDNS(e.template.ID, domain, resp)
+	if index == -1 {
+		return false
+	}
+	if e.showSuppressed {
+		gologger.Infof("[%s] Suppressed result for %s by exclusion %d\n", e.template.ID, domain, index)
+	}
+	return true
+}
+
+// serverResolver returns a plain resolver describing the server which answered
+func serverResolver(server string) *Resolver {
+	return &Resolver{Type: PlainResolver, Address: server}
+}
+
 // Close closes the dns executer for a template.
 func (e *DNSExecuter) Close() {
 	e.outputMutex.Lock()
 	defer e.outputMutex.Unlock()
 	e.writer.Flush()
 }
+
+// dumpAttempts writes the queries sent to the resolvers for a request
+func dumpAttempts(URL string, attempts []*Attempt) {
+	for i, attempt := range attempts {
+		if attempt.Error != nil {
+			gologger.Infof("DNS attempt %d/%d for %s to %s over %s failed: %s\n", i+1, len(attempts), URL, attempt.Resolver, attempt.Transport, attempt.Error)
+			continue
+		}
+		gologger.Infof("DNS attempt %d/%d for %s to %s over %s returned %s\n", i+1, len(attempts), URL, attempt.Resolver, attempt.Transport, dns.RcodeToString[attempt.Rcode])
+	}
+}
@@ -0,0 +1,154 @@
+package executer
+
+import (
+	"context"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/pkg/errors"
+	"github.com/projectdiscovery/gologger"
+	"github.com/projectdiscovery/nuclei/v2/internal/progress"
+	"github.com/projectdiscovery/nuclei/v2/pkg/exclusions"
+	"github.com/projectdiscovery/nuclei/v2/pkg/extractors"
+	"github.com/projectdiscovery/nuclei/v2/pkg/file"
+	"github.com/projectdiscovery/nuclei/v2/pkg/generators"
+	"github.com/projectdiscovery/nuclei/v2/pkg/matchers"
+	"github.com/projectdiscovery/nuclei/v2/pkg/requests"
+	"github.com/projectdiscovery/nuclei/v2/pkg/templates"
+)
+
+// maxFileLocations is the maximum number of locations of a result in a file
+const maxFileLocations = 100
+
+// FileExecuter is a client running the matchers and the extractors of a
+// file request of a template over the local files of the targets.
+type FileExecuter struct {
+	resultSink
+	// concurrency is the number of files read at once by a walk
+	concurrency int
+	fileRequest *requests.FileRequest
+}
+
+// FileOptions contains configuration options for the file executer.
+// IncludeRR writes the content of the files matched in JSON output.
+type FileOptions struct {
+	CommonOptions
+	Template    *templates.Template
+	FileRequest *requests.FileRequest
+	// Concurrency is the number of files read at once by a walk, 10 if 0
+	Concurrency int
+}
+
+// NewFileExecuter creates a new file executer from a template and a file
+// request.
+func NewFileExecuter(options *FileOptions) (*FileExecuter, error) {
+	executer := &FileExecuter{
+		resultSink:  newResultSink("file", options.Template, options.FileRequest.Matchers, &options.CommonOptions),
+		concurrency: options.Concurrency,
+		fileRequest: options.FileRequest,
+	}
+	return executer, nil
+}
+
+// ExecuteFile runs the file request over the files of a target, a local
+// file or directory.
+func (e *FileExecuter) ExecuteFile(p *progress.Progress, target string) Result {
+	return e.ExecuteFileWithContext(context.Background(), p, target, nil)
+}
+
+// ExecuteFileWithContext runs the file request over the files of a target
+// with values until the context is done, the files of a directory being
+// read concurrently. A result is written for each file matched, along
+// with the locations of the matches in it.
+func (e *FileExecuter) ExecuteFileWithContext(ctx context.Context, p *progress.Progress, target string, values map[string]interface{}) (result Result) {
+	defer func(start time.Time) {
+		e.benchmark.Run(e.template.ID, time.Since(start))
+	}(time.Now())
+	result.Matches = make(map[string]interface{})
+	result.Extractions = make(map[string]interface{})
+	defer func() {
+		if p != nil {
+			p.Update()
+		}
+	}()
+
+	// The variables of the template are evaluated once per target
+	variables, err := e.template.EvaluateVariables(values)
+	if err != nil {
+		result.Error = errors.Wrap(err, "could not evaluate variables")
+		return
+	}
+	variables = generators.MergeMaps(values, variables)
+
+	resultMutex := &sync.Mutex{}
+	err = file.Walk(ctx, target, e.fileRequest.WalkOptions(e.concurrency), func(resp *file.Response) {
+		e.stats.Request()
+		found := e.run(ctx, target, resp, variables)
+		e.stats.RequestDone()
+		resultMutex.Lock()
+		defer resultMutex.Unlock()
+		mergeResult(&result, found)
+	})
+	if err != nil {
+		if ctx.Err() != nil {
+			result.Error = err
+		} else {
+			result.Error = errors.Wrap(err, "could not walk files")
+		}
+	}
+	return
+}
+
+// run runs the matchers and the extractors over a file, returning its result
+func (e *FileExecuter) run(ctx context.Context, target string, resp *file.Response, values map[string]interface{}) Result {
+	gologger.Verbosef("Read file %s\n", "file-request", resp.Path)
+
+	if e.debug {
+		e.dump("file", resp.Path, resp.Data)
+	}
+
+	// The matches of the matchers and the values extracted are located in
+	// the file, once the result is known to be written.
+	var offsets []int
+	var locations []file.Location
+	located := false
+	return e.evaluate(ctx, &operators{
+		condition:  e.fileRequest.GetMatchersCondition(),
+		matchers:   e.fileRequest.Matchers,
+		extractors: e.fileRequest.Extractors,
+		match: func(matcher *matchers.Matcher) bool {
+			if !matcher.MatchFile(resp, values) {
+				return false
+			}
+			// the negative matchers match nothing to locate
+			if !matcher.Negative {
+				offsets = append(offsets, matcher.Offsets(resp.Data, maxFileLocations)...)
+			}
+			return true
+		},
+		extract: func(extractor *extractors.Extractor) []string {
+			matches := extractor.ExtractFile(resp, values)
+			// the values extracted are located at their first occurrence
+			for _, match := range matches {
+				if index := strings.Index(resp.Data, match); index != -1 && match != "" {
+					offsets = append(offsets, index)
+				}
+			}
+			return matches
+		},
+		target: resp.Path,
+		exclude: func(exclusions *exclusions.Exclusions) int {
+			return exclusions.MatchFile(e.template.ID, resp)
+		},
+		result: func(matcher *matchers.Matcher, extracted []string) *protocolResult {
+			if !located {
+				locations, located = resp.Locations(offsets), true
+				if len(locations) > maxFileLocations {
+					locations = locations[:maxFileLocations]
+				}
+			}
+			return e.result(target, resp, matcher, extracted, locations)
+		},
+	})
+}
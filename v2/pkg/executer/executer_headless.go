@@ -0,0 +1,180 @@
+package executer
+
+import (
+	"context"
+	"net/url"
+	"strings"
+	"time"
+
+	"github.com/pkg/errors"
+	"github.com/projectdiscovery/gologger"
+	"github.com/projectdiscovery/nuclei/v2/internal/progress"
+	"github.com/projectdiscovery/nuclei/v2/pkg/exclusions"
+	"github.com/projectdiscovery/nuclei/v2/pkg/extractors"
+	"github.com/projectdiscovery/nuclei/v2/pkg/generators"
+	"github.com/projectdiscovery/nuclei/v2/pkg/headless"
+	"github.com/projectdiscovery/nuclei/v2/pkg/matchers"
+	"github.com/projectdiscovery/nuclei/v2/pkg/ratelimit"
+	"github.com/projectdiscovery/nuclei/v2/pkg/requests"
+	"github.com/projectdiscovery/nuclei/v2/pkg/templates"
+)
+
+// HeadlessExecuter is a client running the steps of a headless request
+// of a template in the pages of a browser.
+type HeadlessExecuter struct {
+	resultSink
+	// timeout is the time all the steps of a run can take, unlimited if zero
+	timeout time.Duration
+
+	pool            *headless.Pool
+	headlessRequest *requests.HeadlessRequest
+}
+
+// HeadlessOptions contains configuration options for the headless executer.
+// The steps, the network requests and the final dom are written in JSON
+// output with IncludeRR.
+type HeadlessOptions struct {
+	CommonOptions
+	Template        *templates.Template
+	HeadlessRequest *requests.HeadlessRequest
+	// Pool is the pool of pages of the browser shared by the executers, the
+	// runs failing without one.
+	Pool *headless.Pool
+}
+
+// NewHeadlessExecuter creates a new headless executer from a template and
+// a headless request.
+func NewHeadlessExecuter(options *HeadlessOptions) (*HeadlessExecuter, error) {
+	executer := &HeadlessExecuter{
+		resultSink:      newResultSink("headless", options.Template, options.HeadlessRequest.Matchers, &options.CommonOptions),
+		timeout:         time.Duration(options.HeadlessRequest.Timeout) * time.Second,
+		pool:            options.Pool,
+		headlessRequest: options.HeadlessRequest,
+	}
+	return executer, nil
+}
+
+// errNoBrowser is returned by the runs of the executers without a browser
+var errNoBrowser = errors.New("headless requests require -headless")
+
+// ExecuteHeadless runs the steps of the headless request towards a URL.
+func (e *HeadlessExecuter) ExecuteHeadless(p *progress.Progress, URL string) Result {
+	return e.ExecuteHeadlessWithContext(context.Background(), p, URL, nil)
+}
+
+// ExecuteHeadlessWithContext runs the steps of the headless request towards
+// a URL with values until the context is done, the page being closed with
+// the error of the context.
+func (e *HeadlessExecuter) ExecuteHeadlessWithContext(ctx context.Context, p *progress.Progress, URL string, values map[string]interface{}) (result Result) {
+	defer func(start time.Time) {
+		e.benchmark.Run(e.template.ID, time.Since(start))
+	}(time.Now())
+	result.Matches = make(map[string]interface{})
+	result.Extractions = make(map[string]interface{})
+	if e.pool == nil {
+		result.Error = errNoBrowser
+		if p != nil {
+			p.Drop(1)
+		}
+		return
+	}
+
+	variables, actions, err := e.buildActions(URL, values)
+	if err != nil {
+		result.Error = err
+		if p != nil {
+			p.Drop(1)
+		}
+		return
+	}
+
+	if e.debug {
+		e.dump("headless steps", URL, actionsString(actions))
+	}
+
+	// the runs are limited like the requests to the host of the target
+	host := URL
+	if parsed, err := url.Parse(URL); err == nil && parsed.Host != "" {
+		host = ratelimit.HostPort(parsed)
+	}
+	e.rateLimiter.Wait(ctx, host)
+	if err := ctx.Err(); err != nil {
+		result.Error = err
+		if p != nil {
+			p.Drop(1)
+		}
+		return
+	}
+
+	start := time.Now()
+	e.stats.Request()
+	resp, err := e.pool.Run(ctx, actions, e.timeout)
+	e.stats.RequestDone()
+	e.benchmark.Request(e.template.ID, host, time.Since(start), err)
+	if err != nil {
+		result.Error = errors.Wrapf(err, "could not run headless steps for %s", URL)
+		if p != nil {
+			p.Drop(1)
+		}
+		return
+	}
+
+	if p != nil {
+		p.Update()
+	}
+
+	gologger.Verbosef("Ran headless steps on %s\n", "headless-request", URL)
+
+	if e.debug {
+		e.dump("headless page", URL, resp.String())
+	}
+
+	return e.evaluate(ctx, &operators{
+		condition:  e.headlessRequest.GetMatchersCondition(),
+		matchers:   e.headlessRequest.Matchers,
+		extractors: e.headlessRequest.Extractors,
+		match: func(matcher *matchers.Matcher) bool {
+			return matcher.MatchHeadless(resp, variables)
+		},
+		extract: func(extractor *extractors.Extractor) []string {
+			return extractor.ExtractHeadless(resp, variables)
+		},
+		target: URL,
+		exclude: func(exclusions *exclusions.Exclusions) int {
+			return exclusions.MatchHeadless(e.template.ID, URL, resp)
+		},
+		result: func(matcher *matchers.Matcher, extracted []string) *protocolResult {
+			return e.result(URL, actions, resp, matcher, extracted)
+		},
+	})
+}
+
+// buildActions builds the steps of the request towards a URL, along with
+// the values of the template for the URL.
+func (e *HeadlessExecuter) buildActions(URL string, values map[string]interface{}) (map[string]interface{}, []*headless.Action, error) {
+	targetValues, err := requests.TargetValues(URL)
+	if err != nil {
+		return nil, nil, errors.Wrap(err, "could not parse url")
+	}
+	// The variables of the template are evaluated once per target
+	variables, err := e.template.EvaluateVariables(generators.MergeMaps(values, targetValues))
+	if err != nil {
+		return nil, nil, errors.Wrap(err, "could not evaluate variables")
+	}
+	variables = generators.MergeMaps(values, variables)
+
+	actions, err := e.headlessRequest.MakeActions(URL, variables)
+	if err != nil {
+		return nil, nil, errors.Wrap(err, "could not make headless steps")
+	}
+	return variables, actions, nil
+}
+
+// actionsString returns the steps of a run, one per line
+func actionsString(actions []*headless.Action) string {
+	lines := make([]string, 0, len(actions))
+	for _, action := range actions {
+		lines = append(lines, action.String())
+	}
+	return strings.Join(lines, "\n")
+}
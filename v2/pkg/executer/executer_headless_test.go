@@ -0,0 +1,133 @@
+package executer
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/logrusorgru/aurora"
+	"github.com/projectdiscovery/nuclei/v2/pkg/headless"
+	"github.com/projectdiscovery/nuclei/v2/pkg/stats"
+	"github.com/stretchr/testify/require"
+	"golang.org/x/net/websocket"
+)
+
+// newDevtoolsServer returns a devtools server whose pages write the
+// fragment of their url into their dom, alerting if it calls alert.
+func newDevtoolsServer() *httptest.Server {
+	return httptest.NewServer(websocket.Handler(func(ws *websocket.Conn) {
+		var writeMutex sync.Mutex
+		send := func(msg map[string]interface{}) {
+			data, _ := json.Marshal(msg)
+			writeMutex.Lock()
+			websocket.Message.Send(ws, string(data))
+			writeMutex.Unlock()
+		}
+		location := "about:blank"
+		for {
+			var msg struct {
+				ID        int64                  `json:"id"`
+				SessionID string                 `json:"sessionId"`
+				Method    string                 `json:"method"`
+				Params    map[string]interface{} `json:"params"`
+			}
+			if err := websocket.JSON.Receive(ws, &msg); err != nil {
+				return
+			}
+			result := map[string]interface{}{}
+			switch msg.Method {
+			case "Target.createBrowserContext":
+				result["browserContextId"] = "context"
+			case "Target.createTarget":
+				result["targetId"] = "target"
+			case "Target.attachToTarget":
+				result["sessionId"] = "session"
+			case "Page.navigate":
+				location = msg.Params["url"].(string)
+				if parsed, err := url.Parse(location); err == nil && strings.Contains(parsed.Fragment, "alert(1)") {
+					send(map[string]interface{}{"sessionId": msg.SessionID, "method": "Page.javascriptDialogOpening", "params": map[string]interface{}{"type": "alert", "message": "1"}})
+				}
+			case "Runtime.evaluate":
+				var value interface{}
+				switch expression := msg.Params["expression"].(string); {
+				case expression == "document.readyState":
+					value = "complete"
+				case expression == "location.href":
+					value = location
+				case strings.Contains(expression, "outerHTML"):
+					fragment := ""
+					if parsed, err := url.Parse(location); err == nil {
+						fragment = parsed.Fragment
+					}
+					value = "<html><body><div id=\"out\">" + fragment + "</div></body></html>"
+				}
+				result["result"] = map[string]interface{}{"type": "string", "value": value}
+			}
+			send(map[string]interface{}{"id": msg.ID, "sessionId": msg.SessionID, "result": result})
+		}
+	}))
+}
+
+func TestHeadlessExecuter(t *testing.T) {
+	server := newDevtoolsServer()
+	defer server.Close()
+	pool := headless.NewPool(&headless.Options{Endpoint: "ws" + strings.TrimPrefix(server.URL, "http")})
+	defer pool.Close()
+
+	template := parseTemplate(t, `
+id: dom-xss
+info:
+  name: dom xss
+  author: test
+  severity: high
+variables:
+  payload: "<img src=x onerror=alert(1)>"
+headless:
+  - steps:
+      - action: navigate
+        args:
+          url: "{{BaseURL}}/#{{payload}}"
+    matchers-condition: and
+    matchers:
+      - type: word
+        part: dialog
+        words:
+          - "alert: 1"
+      - type: xpath
+        xpath:
+          - "//div[@id='out']/img/@onerror"
+    extractors:
+      - type: xpath
+        xpath:
+          - "//div[@id='out']/img"
+        attribute: onerror
+`)
+	executer, err := NewHeadlessExecuter(&HeadlessOptions{Template: template, HeadlessRequest: template.RequestsHeadless[0]})
+	require.Nil(t, err, "Could not create headless executer")
+	require.Equal(t, errNoBrowser, executer.ExecuteHeadless(nil, "http://example.com").Error, "Could run the steps without browser")
+
+	output := &bytes.Buffer{}
+	writer := bufio.NewWriter(output)
+	counters := stats.New(1)
+	executer, err = NewHeadlessExecuter(&HeadlessOptions{Template: template, HeadlessRequest: template.RequestsHeadless[0], Pool: pool, CommonOptions: CommonOptions{Writer: writer, JSON: true, JSONRequests: true, IncludeRR: true, Stats: counters, Colorizer: aurora.NewAurora(false)}})
+	require.Nil(t, err, "Could not create headless executer")
+
+	result := executer.ExecuteHeadless(nil, "http://example.com")
+	require.Nil(t, result.Error, "Could not run the steps")
+	require.True(t, result.GotResults, "Could not match the page")
+	require.Equal(t, map[string]interface{}{"": []string{"alert(1)"}}, result.Extractions, "Could not extract the dom")
+	require.Equal(t, uint64(1), counters.Summary(0, false).Findings, "Could not count the finding")
+
+	var found ResultEvent
+	require.Nil(t, json.Unmarshal(bytes.TrimSpace(output.Bytes()), &found), "Could not write a single json result")
+	require.Equal(t, "headless", found.Type, "Could not write the type")
+	require.Equal(t, "http://example.com/#<img src=x onerror=alert(1)>", found.Matched, "Could not write the url of the page")
+	require.Equal(t, []string{"alert: 1"}, found.Dialogs, "Could not write the dialogs")
+	require.Equal(t, `navigate url="http://example.com/#<img src=x onerror=alert(1)>"`, found.Request, "Could not write the steps")
+	require.Contains(t, found.Response, `<img src=x onerror=alert(1)>`, "Could not write the dom")
+}
@@ -2,6 +2,8 @@ package executer
 
 import (
 	"bufio"
+	"bytes"
+	"context"
 	"crypto/tls"
 	"fmt"
 	"github.com/logrusorgru/aurora"
@@ -13,6 +15,7 @@ import (
 	"net/url"
 	"os"
 	"regexp"
+	"strconv"
 	"strings"
 	"sync"
 	"time"
@@ -20,8 +23,29 @@ import (
 	"github.com/pkg/errors"
 	"github.com/projectdiscovery/gologger"
 	"github.com/projectdiscovery/nuclei/v2/internal/progress"
+	"github.com/projectdiscovery/nuclei/v2/pkg/adaptive"
+	"github.com/projectdiscovery/nuclei/v2/pkg/auth"
+	"github.com/projectdiscovery/nuclei/v2/pkg/benchmark"
+	"github.com/projectdiscovery/nuclei/v2/pkg/burp"
+	"github.com/projectdiscovery/nuclei/v2/pkg/checkpoint"
+	"github.com/projectdiscovery/nuclei/v2/pkg/collector"
+	"github.com/projectdiscovery/nuclei/v2/pkg/dedupe"
+	"github.com/projectdiscovery/nuclei/v2/pkg/exclusions"
+	"github.com/projectdiscovery/nuclei/v2/pkg/export"
+	"github.com/projectdiscovery/nuclei/v2/pkg/generators"
+	"github.com/projectdiscovery/nuclei/v2/pkg/grouping"
+	"github.com/projectdiscovery/nuclei/v2/pkg/hosterrors"
+	"github.com/projectdiscovery/nuclei/v2/pkg/interactsh"
+	"github.com/projectdiscovery/nuclei/v2/pkg/markdown"
 	"github.com/projectdiscovery/nuclei/v2/pkg/matchers"
+	"github.com/projectdiscovery/nuclei/v2/pkg/project"
+	"github.com/projectdiscovery/nuclei/v2/pkg/ratelimit"
+	"github.com/projectdiscovery/nuclei/v2/pkg/redact"
+	"github.com/projectdiscovery/nuclei/v2/pkg/regexguard"
+	"github.com/projectdiscovery/nuclei/v2/pkg/replay"
 	"github.com/projectdiscovery/nuclei/v2/pkg/requests"
+	"github.com/projectdiscovery/nuclei/v2/pkg/sarif"
+	"github.com/projectdiscovery/nuclei/v2/pkg/stats"
 	"github.com/projectdiscovery/nuclei/v2/pkg/templates"
 	"github.com/projectdiscovery/retryablehttp-go"
 	"golang.org/x/net/proxy"
@@ -34,17 +58,93 @@ type HTTPExecuter struct {
 	Results         bool
 	jsonOutput      bool
 	jsonRequest     bool
+	includeRR       bool
 	httpClient      *retryablehttp.Client
 	template        *templates.Template
 	bulkHttpRequest *requests.BulkHTTPRequest
 	writer          *bufio.Writer
 	outputMutex     *sync.Mutex
 	customHeaders   requests.CustomHeaders
+	forcedHeaders   requests.CustomHeaders
 	CookieJar       *cookiejar.Jar
 
-	coloredOutput	bool
-	colorizer		aurora.Aurora
-	decolorizer		*regexp.Regexp
+	// baseline is true if a matcher requires the duration of a baseline request
+	baseline       bool
+	baselines      map[string]time.Duration
+	baselinesMutex *sync.Mutex
+	// responseBaselines are the responses to random paths of the targets
+	// compared with the responses by the similarity matchers
+	responseBaselines map[string]*matchers.BaselineResponse
+
+	// exclusions suppress the results matching known false positives
+	exclusions     *exclusions.Exclusions
+	showSuppressed bool
+
+	// collector collects the extracted values of the scan into a single file
+	collector *collector.Collector
+	// files are the files the values of the extractors are appended to
+	files *collector.Files
+	// regexGuard limits the length of the responses the regexes are applied to
+	regexGuard *regexguard.Guard
+	// exporter collects the results of the scan into a SARIF log
+	exporter *sarif.Exporter
+	// markdown writes the evidence of the results to a markdown report
+	markdown *markdown.Exporter
+	// exporters send the json results to external services
+	exporters []export.Exporter
+	// deduper suppresses the findings reported before if any
+	deduper        *dedupe.Deduper
+	showDuplicates bool
+	// stats count the requests and findings of the scan if any
+	stats *stats.Stats
+	// benchmark records the timings of the requests and the matchers if any
+	benchmark *benchmark.Recorder
+	// discardResults only counts the results in the stats
+	discardResults bool
+	// redactor redacts the sensitive headers written, nil with -no-redact
+	redactor *redact.Redactor
+	// grouper buffers the results shown on screen by host if any
+	grouper *grouping.Grouper
+	// quiet shows no results on screen
+	quiet bool
+	// checkpoint records the positions of the payload requests of the
+	// step to resume an interrupted scan if any
+	checkpoint *checkpoint.Checkpoint
+	step       string
+	// hostErrors skips the requests to the hosts with too many consecutive
+	// network errors if any
+	hostErrors *hosterrors.Cache
+	// adaptive adapts the concurrency of the requests to each host to their
+	// network errors if any
+	adaptive *adaptive.Controller
+	// project reuses the responses of the requests sent by the previous
+	// runs if any, cacheable being false for the requests which can't be
+	// reused and templateKeys keying them before the replacement of the
+	// values.
+	project      *project.Cache
+	cacheable    bool
+	templateKeys bool
+	// replayer replays the requests of the results through a proxy if any
+	replayer *replay.Replayer
+	// requestExporter exports the requests of the results as raw files if any
+	requestExporter *burp.Exporter
+	// interactsh hands out the urls of {{interactsh-url}} and polls their
+	// interactions if any, interactshURL being true if the requests use
+	// it and interactshMatchers if the matchers match the interactions.
+	interactsh         *interactsh.Client
+	interactshURL      bool
+	interactshMatchers bool
+	// sessions are the sessions of -auth the requests are sent with if any
+	sessions *auth.Store
+	// shared are the responses of the baseline requests of -tech-detect
+	// the clusters reuse if any
+	shared *SharedResponses
+	// passiveExtract writes only the values of extractor-only requests
+	passiveExtract bool
+
+	coloredOutput bool
+	colorizer     aurora.Aurora
+	decolorizer   *regexp.Regexp
 }
 
 // HTTPOptions contains configuration options for the HTTP executer.
@@ -60,11 +160,98 @@ type HTTPOptions struct {
 	JSON            bool
 	JSONRequests    bool
 	CustomHeaders   requests.CustomHeaders
+	ForcedHeaders   requests.CustomHeaders
 	CookieReuse     bool
 	CookieJar       *cookiejar.Jar
-	ColoredOutput	bool
-	Colorizer		aurora.Aurora
-	Decolorizer		*regexp.Regexp
+	Exclusions      *exclusions.Exclusions
+	ShowSuppressed  bool
+	Collector       *collector.Collector
+	// Files are the files the values of the extractors are appended to if any
+	Files *collector.Files
+	// RegexGuard limits the length of the responses the regexes are applied
+	// to, the default limit applying if nil.
+	RegexGuard     *regexguard.Guard
+	PassiveExtract bool
+	ColoredOutput  bool
+	Colorizer      aurora.Aurora
+	Decolorizer    *regexp.Regexp
+	// Resolved uses the timeout and retries of the options even if the
+	// request has its own, the options being the effective values.
+	Resolved bool
+	// IncludeRR writes the raw request and response of the results in JSON
+	// output along with a curl command sending the request again
+	IncludeRR bool
+	// Exporter collects the results of the scan into a SARIF log if any
+	Exporter *sarif.Exporter
+	// Markdown writes the evidence of the results to a markdown report if any
+	Markdown *markdown.Exporter
+	// Exporters send the json results to external services, such as
+	// elasticsearch or a webhook.
+	Exporters []export.Exporter
+	// Deduper suppresses the findings reported before by the run or by a
+	// previous run if any.
+	Deduper *dedupe.Deduper
+	// ShowDuplicates writes the suppressed duplicates to the json output
+	ShowDuplicates bool
+	// Stats count the requests and findings of the scan if any
+	Stats *stats.Stats
+	// Benchmark records the timings of the runs, the requests and the
+	// evaluation of the matchers of the template if any.
+	Benchmark *benchmark.Recorder
+	// DiscardResults counts the results in the stats without writing,
+	// exporting, deduplicating or replaying them, for -benchmark-no-output.
+	DiscardResults bool
+	// Redactor redacts the sensitive headers of everything written, the
+	// default headers being redacted if nil.
+	Redactor *redact.Redactor
+	// NoRedact writes the sensitive headers and the secrets of the
+	// templates as is, for debugging.
+	NoRedact bool
+	// Grouper buffers the results shown on screen by host if any, the
+	// json output and the exports being written as they are found.
+	Grouper *grouping.Grouper
+	// Quiet shows no results on screen, the output file and the exports
+	// being written, for the programs embedding the engine.
+	Quiet bool
+	// Checkpoint records the number of requests sent to each target by the
+	// step to resume an interrupted scan, the requests with payloads being
+	// fast-forwarded if they don't depend on each other.
+	Checkpoint *checkpoint.Checkpoint
+	Step       string
+	// HostErrors counts the consecutive network errors of the hosts shared
+	// by the executers, skipping the requests to the dead ones, if any.
+	HostErrors *hosterrors.Cache
+	// RateLimiter limits the rate of the requests to each host shared by
+	// the executers if any, including the retries and the redirect hops.
+	RateLimiter *ratelimit.Limiter
+	// Adaptive limits the requests in flight to each host shared by the
+	// executers if any, backing off the hosts with rising network errors.
+	Adaptive *adaptive.Controller
+	// Project caches the responses of the requests on disk shared by the
+	// executers if any, the identical requests of the next runs reusing them.
+	Project *project.Cache
+	// TemplateKeys keys the responses of the project by the requests of the
+	// template before the replacement of the values instead of the built
+	// requests, the requests with random values being cached too.
+	TemplateKeys bool
+	// Replayer replays the requests of the results through a proxy shared
+	// by the executers if any.
+	Replayer *replay.Replayer
+	// RequestExporter exports the requests of the results as raw files
+	// Burp loads, shared by the executers, if any.
+	RequestExporter *burp.Exporter
+	// Interactsh is the client of the interactsh server shared by the
+	// executers if any, the requests using {{interactsh-url}} being
+	// matched again with the interactions of their url as they arrive.
+	Interactsh *interactsh.Client
+	// Sessions are the sessions of the hosts shared by the executers if
+	// any, the requests being sent with the session of their host and sent
+	// again with a new one once it expires.
+	Sessions *auth.Store
+	// SharedResponses are the responses of the baseline requests shared by
+	// the executers if any, the clusters sending the same request reusing
+	// them instead of sending it.
+	SharedResponses *SharedResponses
 }
 
 // NewHTTPExecuter creates a new HTTP executer from a template
@@ -93,52 +280,231 @@ func NewHTTPExecuter(options *HTTPOptions) (*HTTPExecuter, error) {
 		client.HTTPClient.Jar = jar
 	}
 
+	var baseline, interactshMatchers bool
+	for _, matcher := range options.BulkHttpRequest.Matchers {
+		baseline = baseline || matcher.Baseline
+		interactshMatchers = interactshMatchers || matcher.UsesInteractsh()
+	}
+
+	var resume *checkpoint.Checkpoint
+	if options.Step != "" && options.BulkHttpRequest.Resumable() {
+		resume = options.Checkpoint
+	}
+
 	executer := &HTTPExecuter{
-		debug:           options.Debug,
-		jsonOutput:      options.JSON,
-		jsonRequest:     options.JSONRequests,
-		httpClient:      client,
-		template:        options.Template,
-		bulkHttpRequest: options.BulkHttpRequest,
-		outputMutex:     &sync.Mutex{},
-		writer:          options.Writer,
-		customHeaders:   options.CustomHeaders,
-		CookieJar:       options.CookieJar,
-		coloredOutput:   options.ColoredOutput,
-		colorizer:       options.Colorizer,
-		decolorizer:     options.Decolorizer,
+		debug:              options.Debug,
+		jsonOutput:         options.JSON,
+		jsonRequest:        options.JSONRequests,
+		includeRR:          options.IncludeRR,
+		httpClient:         client,
+		template:           options.Template,
+		bulkHttpRequest:    options.BulkHttpRequest,
+		outputMutex:        &sync.Mutex{},
+		writer:             options.Writer,
+		customHeaders:      options.CustomHeaders,
+		forcedHeaders:      options.ForcedHeaders,
+		CookieJar:          options.CookieJar,
+		baseline:           baseline,
+		baselines:          make(map[string]time.Duration),
+		baselinesMutex:     &sync.Mutex{},
+		responseBaselines:  make(map[string]*matchers.BaselineResponse),
+		exclusions:         options.Exclusions,
+		showSuppressed:     options.ShowSuppressed,
+		collector:          options.Collector,
+		files:              options.Files,
+		regexGuard:         options.RegexGuard,
+		exporter:           options.Exporter,
+		markdown:           options.Markdown,
+		deduper:            options.Deduper,
+		showDuplicates:     options.ShowDuplicates,
+		stats:              options.Stats,
+		benchmark:          options.Benchmark,
+		discardResults:     options.DiscardResults,
+		redactor:           newRedactor(options.Redactor, options.NoRedact),
+		grouper:            options.Grouper,
+		quiet:              options.Quiet,
+		checkpoint:         resume,
+		step:               options.Step,
+		hostErrors:         options.HostErrors,
+		adaptive:           options.Adaptive,
+		project:            options.Project,
+		cacheable:          cacheable(options),
+		templateKeys:       options.TemplateKeys,
+		replayer:           options.Replayer,
+		requestExporter:    options.RequestExporter,
+		interactsh:         options.Interactsh,
+		interactshURL:      options.BulkHttpRequest.UsesInteractshURL(),
+		interactshMatchers: interactshMatchers,
+		exporters:          options.Exporters,
+		sessions:           options.Sessions,
+		shared:             options.SharedResponses,
+		passiveExtract:     options.PassiveExtract,
+		coloredOutput:      options.ColoredOutput,
+		colorizer:          options.Colorizer,
+		decolorizer:        options.Decolorizer,
 	}
 
 	return executer, nil
 }
 
+// errInternalMatcher is returned when an internal matcher of a request doesn't match
+var errInternalMatcher = errors.New("internal matcher did not match")
+
 // ExecuteHTTP executes the HTTP request on a URL
-func (e *HTTPExecuter) ExecuteHTTP(p *progress.Progress, URL string) (result Result) {
+func (e *HTTPExecuter) ExecuteHTTP(p *progress.Progress, URL string) Result {
+	return e.ExecuteHTTPWithContext(context.Background(), p, URL, nil)
+}
+
+// ExecuteHTTPWithValues executes the HTTP request on a URL, the values of
+// a previous template being available to the request like its variables.
+func (e *HTTPExecuter) ExecuteHTTPWithValues(p *progress.Progress, URL string, values map[string]interface{}) Result {
+	return e.ExecuteHTTPWithContext(context.Background(), p, URL, values)
+}
+
+// ExecuteHTTPWithContext executes the HTTP request on a URL with values
+// until the context is done, the remaining requests being abandoned with
+// the error of the context and the requests in flight being cancelled.
+func (e *HTTPExecuter) ExecuteHTTPWithContext(ctx context.Context, p *progress.Progress, URL string, values map[string]interface{}) (result Result) {
 	result.Matches = make(map[string]interface{})
 	result.Extractions = make(map[string]interface{})
 	dynamicvalues := make(map[string]interface{})
+	// responses are the snapshots of the previous responses for the guards
+	var responses map[string]interface{}
+	if e.bulkHttpRequest.HasGuards() {
+		responses = make(map[string]interface{})
+	}
 
+	// a retried target starts over, its previous generator being done
+	if isRetry(ctx) {
+		e.bulkHttpRequest.DeleteGenerator(URL)
+	}
 	// verify if the URL is already being processed
 	if e.bulkHttpRequest.HasGenerator(URL) {
 		return
 	}
+	defer func(start time.Time) {
+		e.benchmark.Run(e.template.ID, time.Since(start))
+	}(time.Now())
 
 	remaining := e.bulkHttpRequest.GetRequestCount()
 
+	// The variables of the template are evaluated once per target
+	variables, err := e.variables(URL, values)
+	if err != nil {
+		result.Error = errors.Wrap(err, "could not evaluate variables")
+		if p != nil {
+			p.Drop(remaining)
+		}
+		return
+	}
+	for name, value := range values {
+		dynamicvalues[name] = value
+	}
+	for name, value := range variables {
+		dynamicvalues[name] = value
+	}
+
 	e.bulkHttpRequest.CreateGenerator(URL)
-	for e.bulkHttpRequest.Next(URL) && !result.Done {
-		httpRequest, err := e.bulkHttpRequest.MakeHTTPRequest(URL, dynamicvalues, e.bulkHttpRequest.Current(URL))
-		if err != nil {
-			result.Error = errors.Wrap(err, "could not build http request")
+	// the generator of the payloads is stopped if the requests are abandoned
+	defer e.bulkHttpRequest.StopGenerator(URL)
+	// the requests sent before the interruption of a resumed scan are
+	// skipped, the payloads being generated in the same order
+	resumed := e.checkpoint.Position(e.step, URL)
+	for sent := 0; e.bulkHttpRequest.Next(URL) && !result.Done; sent++ {
+		if err := ctx.Err(); err != nil {
+			result.Error = err
+			if p != nil {
+				p.Drop(remaining)
+			}
+			return
+		}
+		// the remaining requests to a dead host are skipped
+		if e.hostErrors.Dead(URL) {
+			result.Error = hosterrors.ErrSkipped
 			if p != nil {
 				p.Drop(remaining)
 			}
 			return
 		}
+		e.checkpoint.Advance(e.step, URL, sent)
+		if sent < resumed {
+			e.bulkHttpRequest.Skip(URL)
+			if p != nil {
+				p.Drop(1)
+			}
+			remaining--
+			continue
+		}
+		// Requests using the value of an extractor which extracted nothing are skipped
+		if name, ok := e.missingExtractorValue(e.bulkHttpRequest.Current(URL), dynamicvalues); ok {
+			gologger.Debugf("[%s] Skipping request %d to %s, extractor %s extracted no value\n", e.template.ID, e.bulkHttpRequest.Position(URL)+1, URL, name)
+			e.bulkHttpRequest.Increment(URL)
+			if p != nil {
+				p.Update()
+			}
+			remaining--
+			continue
+		}
+		// Requests whose guard is false are skipped for the target
+		if e.guardFails(URL, dynamicvalues, responses) {
+			e.bulkHttpRequest.Increment(URL)
+			if p != nil {
+				p.Update()
+			}
+			remaining--
+			continue
+		}
 
-		err = e.handleHTTP(p, URL, httpRequest, dynamicvalues, &result)
+		data := e.bulkHttpRequest.Current(URL)
+		if name, iterated := e.iteratedValues(data, &result); len(iterated) > 0 {
+			err = e.handleIterations(ctx, p, URL, data, name, iterated, dynamicvalues, responses, &result)
+		} else {
+			var httpRequest *requests.HttpRequest
+			httpRequest, err = e.buildRequest(URL, dynamicvalues, data)
+			// the request is done once its payloads are exhausted
+			if err == requests.ErrPayloadsDone {
+				e.bulkHttpRequest.Increment(URL)
+				continue
+			}
+			if err != nil {
+				result.Error = errors.Wrap(err, "could not build http request")
+				result.ErrorRequest = e.bulkHttpRequest.Position(URL)
+				if p != nil {
+					p.Drop(remaining)
+				}
+				return
+			}
+
+			err = e.handleRequest(ctx, p, URL, httpRequest, dynamicvalues, responses, &result)
+		}
+		if err == errInternalMatcher {
+			e.bulkHttpRequest.Increment(URL)
+			if p != nil {
+				p.Update()
+			}
+			remaining--
+			// skip to the next payload values or stop the requests to the target
+			if e.bulkHttpRequest.AbortsIteration() {
+				continue
+			}
+			if p != nil {
+				p.Drop(remaining)
+			}
+			return
+		}
 		if err != nil {
 			result.Error = errors.Wrap(err, "could not handle http request")
+			result.ErrorRequest = e.bulkHttpRequest.Position(URL)
+			// the failure is exposed to the guards of the next requests
+			if responses != nil {
+				snapshotResponse(responses, e.bulkHttpRequest.Position(URL), nil, err)
+				e.bulkHttpRequest.Increment(URL)
+				if p != nil {
+					p.Update()
+				}
+				remaining--
+				continue
+			}
 			if p != nil {
 				p.Drop(remaining)
 			}
@@ -157,103 +523,615 @@ func (e *HTTPExecuter) ExecuteHTTP(p *progress.Progress, URL string) (result Res
 	return
 }
 
-func (e *HTTPExecuter) handleHTTP(p *progress.Progress, URL string, request *requests.HttpRequest, dynamicvalues map[string]interface{}, result *Result) error {
+// variables returns the values of the variables of the template for a target
+func (e *HTTPExecuter) variables(URL string, values map[string]interface{}) (map[string]interface{}, error) {
+	targetValues, err := requests.TargetValues(URL)
+	if err != nil {
+		return nil, err
+	}
+	return e.template.EvaluateVariables(generators.MergeMaps(values, targetValues))
+}
+
+// guardFails returns true if the guard of the current request to a target is
+// false, evaluated with the values and the snapshots of the previous responses.
+func (e *HTTPExecuter) guardFails(URL string, dynamicvalues, responses map[string]interface{}) bool {
+	position := e.bulkHttpRequest.Position(URL)
+	guard, ok := e.bulkHttpRequest.Guard(position)
+	if !ok {
+		return false
+	}
+
+	result, err := guard.Evaluate(generators.MergeMaps(dynamicvalues, responses))
+	if err != nil {
+		gologger.Debugf("[%s] Skipping request %d to %s, could not evaluate run-if %s: %s\n", e.template.ID, position+1, URL, e.bulkHttpRequest.RunIf[position+1], err)
+		return true
+	}
+	if passed, ok := result.(bool); !ok || !passed {
+		gologger.Debugf("[%s] Skipping request %d to %s, run-if %s is false\n", e.template.ID, position+1, URL, e.bulkHttpRequest.RunIf[position+1])
+		return true
+	}
+	return false
+}
+
+// snapshotResponse adds the values of the response to the request at a 0-based
+// position to the snapshots, suffixed with the 1-based index of the request,
+// i.e status_code_1. failed_1 and error_1 expose the failure of the request.
+func snapshotResponse(responses map[string]interface{}, position int, values map[string]interface{}, err error) {
+	suffix := "_" + strconv.Itoa(position+1)
+	for name, value := range values {
+		responses[name+suffix] = value
+	}
+	responses["failed"+suffix] = err != nil
+	if err != nil {
+		responses["error"+suffix] = err.Error()
+	} else {
+		responses["error"+suffix] = ""
+	}
+}
+
+func (e *HTTPExecuter) handleHTTP(ctx context.Context, p *progress.Progress, URL string, request *requests.HttpRequest, dynamicvalues, responses map[string]interface{}, result *Result) error {
+	exchange, err := e.send(ctx, URL, request, e.cacheKey(URL, request, dynamicvalues))
+	if err != nil {
+		return err
+	}
+	defer exchange.release()
+	return e.handleResponse(ctx, URL, request, exchange, dynamicvalues, responses, result)
+}
+
+// httpExchange is the response to a request, along with its decompressed
+// body, its duration and the address of the server. The body of a received
+// response shares the pooled buffer it was read into, so what is kept once
+// the exchange is released is copied.
+type httpExchange struct {
+	buffer   *bytes.Buffer
+	resp     *http.Response
+	body     string
+	headers  string
+	duration time.Duration
+	remoteIP string
+	metrics  *ResponseMetrics
+}
+
+// release returns the buffer of the body to the pool once the response is
+// evaluated, the exchange not being used anymore.
+func (x *httpExchange) release() {
+	releaseBody(x.buffer)
+	x.buffer = nil
+}
+
+// buildRequest builds a request of the template to a target with the
+// current payloads, along with the custom headers, without sending it.
+func (e *HTTPExecuter) buildRequest(URL string, dynamicvalues map[string]interface{}, data string) (*requests.HttpRequest, error) {
+	dynamicvalues, interactshURL := e.interactshValues(dynamicvalues)
+	request, err := e.bulkHttpRequest.MakeHTTPRequest(URL, dynamicvalues, data)
+	if err != nil {
+		return nil, err
+	}
+	request.InteractshURL = interactshURL
 	e.setCustomHeaders(request)
-	req := request.Request
+	return request, nil
+}
 
+// rawRequest returns a built request as it is sent along with its body,
+// redacted.
+func (e *HTTPExecuter) rawRequest(request *requests.HttpRequest) (string, error) {
+	headers, body, err := dumpRequest(request.Request)
+	if err != nil {
+		return "", err
+	}
+	return e.redact(string(headers) + string(body)), nil
+}
+
+// send sends a built request to a target and reads its response, the
+// request being sent again once with a new session if its response shows
+// that the session it was built with expired.
+func (e *HTTPExecuter) send(ctx context.Context, URL string, request *requests.HttpRequest, key string) (*httpExchange, error) {
+	exchange, err := e.sendOnce(ctx, URL, request, key)
+	if err != nil || request.Session == nil || !e.sessions.Expired(exchange.resp, exchange.body, exchange.headers) {
+		return exchange, err
+	}
+	session := e.sessions.Renew(request.Request.URL, request.Session)
+	if session == nil || session == request.Session {
+		return exchange, nil
+	}
+	exchange.release()
+	session.Apply(request.Request.Request, request.Session)
+	request.Session = session
+	return e.sendOnce(ctx, URL, request, key)
+}
+
+// sendOnce sends a built request to a target and reads its response, the
+// request being cancelled once the context is done. The response cached by
+// the project under the key is reused instead if any, and the response is
+// cached under the key otherwise, an empty key not being cached.
+func (e *HTTPExecuter) sendOnce(ctx context.Context, URL string, request *requests.HttpRequest, key string) (*httpExchange, error) {
 	if e.debug {
-		dumpedRequest, err := httputil.DumpRequest(req.Request, true)
+		dumpedRequest, err := e.rawRequest(request)
 		if err != nil {
-			return errors.Wrap(err, "could not make http request")
+			return nil, errors.Wrap(err, "could not make http request")
 		}
 		gologger.Infof("Dumped HTTP request for %s (%s)\n\n", URL, e.template.ID)
-		fmt.Fprintf(os.Stderr, "%s", string(dumpedRequest))
+		fmt.Fprintf(os.Stderr, "%s", dumpedRequest)
 	}
+	req := request.Request.WithContext(ctx)
+	if key != "" {
+		if cached, ok := e.project.Get(key); ok {
+			e.stats.RequestCached()
+			return e.cachedExchange(URL, req.Request, cached)
+		}
+	}
+	host := ratelimit.HostPort(req.URL)
+	if err := e.adaptive.Acquire(ctx, host); err != nil {
+		return nil, err
+	}
+	req, remoteIP := traceRemoteIP(req)
+	timeStart := time.Now()
+	e.stats.Request()
 	resp, err := e.httpClient.Do(req)
+	e.stats.RequestDone()
+	e.benchmark.Request(e.template.ID, host, time.Since(timeStart), err)
+	e.adaptive.Release(host, err != nil && ctx.Err() == nil && hosterrors.IsNetworkError(err))
 	if err != nil {
 		if resp != nil {
 			resp.Body.Close()
 		}
-		return errors.Wrap(err, "Could not do request")
+		// the cancelled requests are not failures of the host
+		if ctx.Err() == nil {
+			e.hostErrors.Failed(URL, err)
+		}
+		return nil, errors.Wrap(err, "Could not do request")
 	}
+	e.hostErrors.Succeeded(URL)
 
 	if e.debug {
 		dumpedResponse, err := httputil.DumpResponse(resp, true)
 		if err != nil {
-			return errors.Wrap(err, "could not dump http response")
+			return nil, errors.Wrap(err, "could not dump http response")
 		}
 		gologger.Infof("Dumped HTTP response for %s (%s)\n\n", URL, e.template.ID)
-		fmt.Fprintf(os.Stderr, "%s\n", string(dumpedResponse))
+		fmt.Fprintf(os.Stderr, "%s\n", e.redact(string(dumpedResponse)))
 	}
 
-	data, err := ioutil.ReadAll(resp.Body)
+	buffer, err := readBody(resp.Body)
 	if err != nil {
 		io.Copy(ioutil.Discard, resp.Body)
 		resp.Body.Close()
-		return errors.Wrap(err, "could not read http body")
+		return nil, errors.Wrap(err, "could not read http body")
 	}
 	resp.Body.Close()
+	duration := time.Since(timeStart)
 
 	// net/http doesn't automatically decompress the response body if an encoding has been specified by the user in the request
 	// so in case we have to manually do it
-	data, err = requests.HandleDecompression(req, data)
+	data, err := requests.HandleDecompression(req, buffer.Bytes())
 	if err != nil {
-		return errors.Wrap(err, "could not decompress http body")
+		releaseBody(buffer)
+		return nil, errors.Wrap(err, "could not decompress http body")
+	}
+
+	if key != "" {
+		if err := e.project.Set(key, &project.Response{URL: req.URL.String(), StatusCode: resp.StatusCode, Proto: resp.Proto, Header: resp.Header, Body: data, Duration: duration, RemoteIP: remoteIP()}); err != nil {
+			gologger.Warningf("[%s] Could not cache the response of %s: %s\n", e.template.ID, URL, err)
+		}
 	}
 
-	// Convert response body from []byte to string with zero copy
+	// Convert response body from []byte to string with zero copy, the
+	// body sharing the pooled buffer until the exchange is released
 	body := unsafeToString(data)
+	return &httpExchange{
+		buffer:   buffer,
+		resp:     resp,
+		body:     body,
+		headers:  headersToString(resp.Header),
+		duration: duration,
+		remoteIP: remoteIP(),
+		metrics:  NewResponseMetrics(resp.StatusCode, body, e.regexGuard),
+	}, nil
+}
 
-	headers := headersToString(resp.Header)
-	matcherCondition := e.bulkHttpRequest.GetMatchersCondition()
-	for _, matcher := range e.bulkHttpRequest.Matchers {
-		// Check if the matcher matched
-		if !matcher.Match(resp, body, headers) {
-			// If the condition is AND we haven't matched, try next request.
-			if matcherCondition == matchers.ANDCondition {
-				return nil
-			}
-		} else {
-			// If the matcher has matched, and its an OR
-			// write the first output then move to next matcher.
-			if matcherCondition == matchers.ORCondition {
-				result.Matches[matcher.Name] = nil
-				// probably redundant but ensures we snapshot current payload values when matchers are valid
-				result.Meta = request.Meta
-				e.writeOutputHTTP(request, resp, body, matcher, nil)
-				result.GotResults = true
-			}
+// handleResponse evaluates the matchers and the extractors of the request
+// on its response, writing the results, marked as retried if the context is.
+func (e *HTTPExecuter) handleResponse(ctx context.Context, URL string, request *requests.HttpRequest, exchange *httpExchange, dynamicvalues, responses map[string]interface{}, result *Result) error {
+	resp, body, headers, duration, metrics := exchange.resp, exchange.body, exchange.headers, exchange.duration, exchange.metrics
+	remoteIP := exchange.remoteIP
+
+	var err error
+	baseline := &matchers.Baseline{}
+	if e.baseline {
+		baseline.Duration, err = e.baselineDuration(request.Request.Context(), URL, request, dynamicvalues)
+		if err != nil {
+			return errors.Wrap(err, "could not do baseline request")
 		}
 	}
-
-	// All matchers have successfully completed so now start with the
-	// next task which is extraction of input from matchers.
-	var extractorResults, outputExtractorResults []string
-	for _, extractor := range e.bulkHttpRequest.Extractors {
-		for match := range extractor.Extract(resp, body, headers) {
-			if _, ok := dynamicvalues[extractor.Name]; !ok {
-				dynamicvalues[extractor.Name] = match
-			}
-			extractorResults = append(extractorResults, match)
-			if !extractor.Internal {
-				outputExtractorResults = append(outputExtractorResults, match)
-			}
+	if e.bulkHttpRequest.Baseline {
+		baseline.Response, err = e.baselineResponse(request.Request.Context(), URL, dynamicvalues)
+		if err != nil {
+			return errors.Wrap(err, "could not do baseline request")
 		}
+	}
+
+	position := e.bulkHttpRequest.Position(URL)
+	if responses != nil {
+		values := matchers.HTTPValues(resp, body, headers, duration, remoteIP)
+		// the next requests use the body once the buffer is reused
+		values["body"] = cloneString(body)
+		snapshotResponse(responses, position, generators.MergeMaps(values, metrics.values()), nil)
+	}
+
+	evaluationStart := time.Now()
+	evaluation := EvaluateHTTP(e.bulkHttpRequest, &HTTPResponse{
+		Response: resp,
+		Body:     body,
+		Headers:  headers,
+		Duration: duration,
+		RemoteIP: remoteIP,
+		Baseline: baseline,
+		Position: position,
+		Metrics:  metrics,
+	}, dynamicvalues)
+	e.benchmark.Evaluation(e.template.ID, time.Since(evaluationStart))
+	if evaluation.InternalFailed {
+		return errInternalMatcher
+	}
+	// the interactions of the request are matched as they arrive
+	if request.InteractshURL != "" && e.interactshMatchers && !isPassive(ctx) {
+		e.awaitInteractions(ctx, URL, request, exchange, baseline, position, dynamicvalues)
+	}
+	if evaluation.ANDFailed {
+		return nil
+	}
+	matched := evaluation.Matched
+	if len(matched) > 0 {
+		// probably redundant but ensures we snapshot current payload values when matchers are valid
+		result.Meta = request.Meta
+	}
+	for i, extractor := range e.bulkHttpRequest.Extractors {
+		matches := evaluation.Extractions[i]
+		writeToFile(e.files, e.template.ID, extractor, matches)
 		// probably redundant but ensures we snapshot current payload values when extractors are valid
 		result.Meta = request.Meta
-		result.Extractions[extractor.Name] = extractorResults
+		if len(matches) > 0 {
+			result.Extractions[extractor.Name] = matches
+		}
+	}
+	outputExtractorResults := evaluation.OutputValues
+	andMatched := evaluation.ANDMatched
+
+	// Results of responses matching an exclusion are suppressed
+	if evaluation.HasResults() && e.isSuppressed(URL, resp, body, headers, duration, remoteIP) {
+		return nil
+	}
+	collect(e.collector, outputExtractorResults)
+	// the requests of the results are replayed, no request being sent for
+	// the stored responses
+	if evaluation.HasResults() && !isPassive(ctx) && !e.discardResults {
+		e.replay(request)
+	}
+	if evaluation.HasResults() && !e.discardResults {
+		if err := e.exportRequest(URL, request); err != nil {
+			gologger.Warningf("[%s] Could not export the request to %s: %s\n", e.template.ID, URL, err)
+		}
+	}
+
+	// Write a result for each distinct matcher of an OR condition along
+	// with the extracted values, so each finding is self-contained.
+	if len(matched) > 0 {
+		for _, matcher := range distinctMatchers(matched) {
+			result.Matches[matcher.Name] = nil
+			e.writeOutputHTTP(ctx, request, resp, body, duration, metrics, matcher, outputExtractorResults)
+		}
+		result.GotResults = true
+		return nil
 	}
 
 	// Write a final string of output if matcher type is
 	// AND or if we have extractors for the mechanism too.
-	if len(outputExtractorResults) > 0 || matcherCondition == matchers.ANDCondition {
-		e.writeOutputHTTP(request, resp, body, nil, outputExtractorResults)
+	if len(outputExtractorResults) > 0 || andMatched {
+		if andMatched {
+			for _, matcher := range e.bulkHttpRequest.Matchers {
+				if !matcher.Internal {
+					result.Matches[matcher.Name] = nil
+				}
+			}
+		}
+		e.writeOutputHTTP(ctx, request, resp, body, duration, metrics, nil, outputExtractorResults)
 		result.GotResults = true
 	}
 
 	return nil
 }
 
+// replay replays the request of a result through the replay proxy if any,
+// along with the cookies of the jar at the time of the match.
+func (e *HTTPExecuter) replay(request *requests.HttpRequest) {
+	if e.replayer == nil {
+		return
+	}
+	body, err := request.Request.BodyBytes()
+	if err != nil {
+		return
+	}
+	var cookies []*http.Cookie
+	if jar := e.httpClient.HTTPClient.Jar; jar != nil {
+		cookies = jar.Cookies(request.Request.URL)
+	}
+	e.replayer.Replay(e.template.ID, request.Request.Request, body, cookies)
+}
+
+// exportRequest exports a built request to a target as it is written on
+// the wire, along with its payload values, if the requests are exported.
+// The headers and the text bodies are redacted unless disabled, the binary
+// bodies being exported as is.
+func (e *HTTPExecuter) exportRequest(URL string, request *requests.HttpRequest) error {
+	if e.requestExporter == nil {
+		return nil
+	}
+	// the request is dumped from a copy, without the trace of its connection
+	content, err := request.Request.BodyBytes()
+	if err != nil {
+		return err
+	}
+	req := request.Request.Request.Clone(context.Background())
+	req.Body = nil
+	if len(content) > 0 {
+		req.Body = ioutil.NopCloser(bytes.NewReader(content))
+	}
+	dumped, err := httputil.DumpRequestOut(req, true)
+	if err != nil {
+		return err
+	}
+	headers, body := dumped, []byte(nil)
+	if end := bytes.Index(dumped, []byte("\r\n\r\n")); end != -1 {
+		headers, body = dumped[:end+4], dumped[end+4:]
+	}
+	raw := []byte(e.redact(string(headers)))
+	if _, encoding := encodeRaw(body); encoding == "" {
+		body = []byte(e.redact(string(body)))
+	}
+	return e.requestExporter.Export(&burp.Request{
+		TemplateID: e.template.ID,
+		Target:     URL,
+		URL:        request.Request.URL.String(),
+		Raw:        append(raw, body...),
+		Payloads:   request.Meta,
+	})
+}
+
+// iteratedValues returns the name and the distinct values of the named
+// extractor an iterate-all request iterates on, the extractor used by the
+// request or else the last one with values if the request uses {{value}}.
+func (e *HTTPExecuter) iteratedValues(data string, result *Result) (string, []string) {
+	if !e.bulkHttpRequest.IterateAll {
+		return "", nil
+	}
+	var name string
+	for _, extractor := range e.bulkHttpRequest.Extractors {
+		if _, ok := result.Extractions[extractor.Name]; !ok || extractor.Name == "" {
+			continue
+		}
+		if strings.Contains(data, "{{"+extractor.Name+"}}") {
+			name = extractor.Name
+			break
+		}
+		if strings.Contains(data, "{{value}}") {
+			name = extractor.Name
+		}
+	}
+	if name == "" {
+		return "", nil
+	}
+
+	extracted, _ := result.Extractions[name].([]string)
+	values := make([]string, 0, len(extracted))
+	seen := make(map[string]struct{}, len(extracted))
+	for _, value := range extracted {
+		if _, ok := seen[value]; !ok {
+			seen[value] = struct{}{}
+			values = append(values, value)
+		}
+	}
+	if max := e.bulkHttpRequest.GetMaxIterations(); len(values) > max {
+		gologger.Warningf("[%s] Iterating on the first %d of the %d values of extractor %s\n", e.template.ID, max, len(values), name)
+		values = values[:max]
+	}
+	return name, values
+}
+
+// handleIterations executes the current request of an iterate-all request
+// once per extracted value, each iteration writing its own results. The
+// request fails if all the iterations fail, with the error of the last one.
+func (e *HTTPExecuter) handleIterations(ctx context.Context, p *progress.Progress, URL, data, name string, values []string, dynamicvalues, responses map[string]interface{}, result *Result) error {
+	// the iterations are added to the request counted once by the progress
+	if p != nil {
+		p.AddToTotal(int64(len(values) - 1))
+	}
+	previous, hadPrevious := dynamicvalues[name]
+	defer func() {
+		if hadPrevious {
+			dynamicvalues[name] = previous
+		}
+	}()
+
+	var failed int
+	var lastErr error
+	for i, value := range values {
+		if result.Done {
+			if p != nil {
+				p.Drop(int64(len(values) - 1 - i))
+			}
+			break
+		}
+		dynamicvalues[name] = value
+
+		if err := e.handleIteration(ctx, p, URL, data, name, value, dynamicvalues, responses, result); err != nil {
+			failed++
+			lastErr = err
+			if err != errInternalMatcher {
+				gologger.Warningf("[%s] Could not execute iteration %s=%s towards %s: %s\n", e.template.ID, name, value, URL, err)
+			}
+		}
+		// the last iteration is counted along with the request
+		if p != nil && i < len(values)-1 {
+			p.Update()
+		}
+	}
+	if failed == len(values) {
+		return lastErr
+	}
+	return nil
+}
+
+// handleIteration executes an iteration of an iterate-all request with an
+// extracted value, the results being annotated with the value.
+func (e *HTTPExecuter) handleIteration(ctx context.Context, p *progress.Progress, URL, data, name, value string, dynamicvalues, responses map[string]interface{}, result *Result) error {
+	// {{value}} is replaced beforehand, the bare names of the values being
+	// replaced too when building the requests.
+	data = strings.Replace(data, "{{value}}", value, -1)
+	httpRequest, err := e.buildRequest(URL, dynamicvalues, data)
+	if err != nil {
+		return errors.Wrap(err, "could not build http request")
+	}
+	if httpRequest.Meta == nil {
+		httpRequest.Meta = make(map[string]interface{})
+	}
+	httpRequest.Meta[name] = value
+	httpRequest.IteratedValue = value
+	return e.handleRequest(ctx, p, URL, httpRequest, dynamicvalues, responses, result)
+}
+
+// missingExtractorValue returns the name of a named extractor used by a
+// request in a {{placeholder}} whose value hasn't been extracted yet.
+func (e *HTTPExecuter) missingExtractorValue(data string, dynamicvalues map[string]interface{}) (string, bool) {
+	for _, extractor := range e.bulkHttpRequest.Extractors {
+		if extractor.Name == "" {
+			continue
+		}
+		if _, ok := dynamicvalues[extractor.Name]; ok {
+			continue
+		}
+		if strings.Contains(data, "{{"+extractor.Name+"}}") {
+			return extractor.Name, true
+		}
+	}
+	return "", false
+}
+
+// isSuppressed returns true if the response matches an exclusion,
+// showing the suppressed result if asked for auditing.
+func (e *HTTPExecuter) isSuppressed(URL string, resp *http.Response, body, headers string, duration time.Duration, remoteIP string) bool {
+	if e.exclusions == nil {
+		return false
+	}
+	index := e.exclusions.MatchHTTP(e.template.ID, URL, resp, body, headers, duration, remoteIP)
+	if index == -1 {
+		return false
+	}
+	if e.showSuppressed {
+		gologger.Infof("[%s] Suppressed result for %s by exclusion %d\n", e.template.ID, URL, index)
+	}
+	return true
+}
+
+// baselineDuration returns the duration of the baseline request for a URL.
+//
+// The baseline request is sent once per URL with the same client and headers,
+// its response isn't matched nor counted in the progress.
+func (e *HTTPExecuter) baselineDuration(ctx context.Context, URL string, request *requests.HttpRequest, dynamicvalues map[string]interface{}) (time.Duration, error) {
+	e.baselinesMutex.Lock()
+	duration, ok := e.baselines[URL]
+	e.baselinesMutex.Unlock()
+	if ok {
+		return duration, nil
+	}
+
+	baselineRequest, err := e.bulkHttpRequest.MakeBaselineHTTPRequest(URL, dynamicvalues, e.bulkHttpRequest.Current(URL), request.Meta)
+	if err != nil {
+		return 0, err
+	}
+	e.setCustomHeaders(baselineRequest)
+
+	timeStart := time.Now()
+	e.stats.Request()
+	resp, err := e.httpClient.Do(baselineRequest.Request.WithContext(ctx))
+	e.stats.RequestDone()
+	if err != nil {
+		if resp != nil {
+			resp.Body.Close()
+		}
+		return 0, err
+	}
+	io.Copy(ioutil.Discard, resp.Body)
+	resp.Body.Close()
+	duration = time.Since(timeStart)
+
+	if e.debug {
+		gologger.Infof("Baseline HTTP request for %s (%s) took %s\n", URL, e.template.ID, duration)
+	}
+
+	e.baselinesMutex.Lock()
+	e.baselines[URL] = duration
+	e.baselinesMutex.Unlock()
+	return duration, nil
+}
+
+// baselineResponse returns the response to a random non-existent path of a URL.
+//
+// The baseline request is sent once per URL like the duration baseline, its
+// body is read up to the maximum size of the matched inputs.
+func (e *HTTPExecuter) baselineResponse(ctx context.Context, URL string, dynamicvalues map[string]interface{}) (*matchers.BaselineResponse, error) {
+	e.baselinesMutex.Lock()
+	baseline, ok := e.responseBaselines[URL]
+	e.baselinesMutex.Unlock()
+	if ok {
+		return baseline, nil
+	}
+
+	baselineRequest, err := e.bulkHttpRequest.MakeNotFoundHTTPRequest(URL, dynamicvalues)
+	if err != nil {
+		return nil, err
+	}
+	e.setCustomHeaders(baselineRequest)
+
+	e.stats.Request()
+	resp, err := e.httpClient.Do(baselineRequest.Request.WithContext(ctx))
+	e.stats.RequestDone()
+	if err != nil {
+		if resp != nil {
+			resp.Body.Close()
+		}
+		return nil, err
+	}
+	var reader io.Reader = resp.Body
+	if size := e.regexGuard.MaxSize(); size > 0 {
+		reader = io.LimitReader(resp.Body, int64(size))
+	}
+	buffer, err := readBody(reader)
+	io.Copy(ioutil.Discard, resp.Body)
+	resp.Body.Close()
+	if err != nil {
+		return nil, err
+	}
+	defer releaseBody(buffer)
+	data, err := requests.HandleDecompression(baselineRequest.Request, buffer.Bytes())
+	if err != nil {
+		return nil, err
+	}
+
+	if e.debug {
+		gologger.Infof("Baseline HTTP response for %s (%s) has status %d and length %d\n", URL, e.template.ID, resp.StatusCode, len(data))
+	}
+
+	baseline = &matchers.BaselineResponse{
+		StatusCode: resp.StatusCode,
+		Headers:    headersToString(resp.Header),
+		Body:       string(data),
+	}
+	e.baselinesMutex.Lock()
+	e.responseBaselines[URL] = baseline
+	e.baselinesMutex.Unlock()
+	return baseline, nil
+}
+
 // Close closes the http executer for a template.
 func (e *HTTPExecuter) Close() {
 	e.outputMutex.Lock()
@@ -265,7 +1143,17 @@ func (e *HTTPExecuter) Close() {
 func makeHTTPClient(proxyURL *url.URL, options *HTTPOptions) *retryablehttp.Client {
 	retryablehttpOptions := retryablehttp.DefaultOptionsSpraying
 	retryablehttpOptions.RetryWaitMax = 10 * time.Second
-	retryablehttpOptions.RetryMax = options.Retries
+	// the timeout and retries of the request take precedence over the global ones
+	timeout, retries := options.Timeout, options.Retries
+	if !options.Resolved {
+		if options.BulkHttpRequest.Timeout > 0 {
+			timeout = options.BulkHttpRequest.Timeout
+		}
+		if options.BulkHttpRequest.Retries > 0 {
+			retries = options.BulkHttpRequest.Retries
+		}
+	}
+	retryablehttpOptions.RetryMax = retries
 	followRedirects := options.BulkHttpRequest.Redirects
 	maxRedirects := options.BulkHttpRequest.MaxRedirects
 
@@ -296,17 +1184,26 @@ func makeHTTPClient(proxyURL *url.URL, options *HTTPOptions) *retryablehttp.Clie
 	if proxyURL != nil {
 		transport.Proxy = http.ProxyURL(proxyURL)
 	}
-	return retryablehttp.NewWithHTTPClient(&http.Client{
+	client := retryablehttp.NewWithHTTPClient(&http.Client{
 		Transport:     transport,
-		Timeout:       time.Duration(options.Timeout) * time.Second,
-		CheckRedirect: makeCheckRedirectFunc(followRedirects, maxRedirects),
+		Timeout:       time.Duration(timeout) * time.Second,
+		CheckRedirect: makeCheckRedirectFunc(followRedirects, maxRedirects, options.RateLimiter),
 	}, retryablehttpOptions)
+	// each attempt waits for the rate limit of its host before its timeout
+	if limiter := options.RateLimiter; limiter != nil {
+		client.RequestLogHook = func(req *http.Request, _ int) {
+			limiter.Wait(req.Context(), ratelimit.HostPort(req.URL))
+		}
+	}
+	return client
 }
 
 type checkRedirectFunc func(_ *http.Request, requests []*http.Request) error
 
-func makeCheckRedirectFunc(followRedirects bool, maxRedirects int) checkRedirectFunc {
-	return func(_ *http.Request, requests []*http.Request) error {
+// makeCheckRedirectFunc returns the redirect policy of a request, the hops
+// followed waiting for the rate limit of their host if any.
+func makeCheckRedirectFunc(followRedirects bool, maxRedirects int, limiter *ratelimit.Limiter) checkRedirectFunc {
+	return func(req *http.Request, requests []*http.Request) error {
 		if !followRedirects {
 			return http.ErrUseLastResponse
 		}
@@ -314,28 +1211,23 @@ func makeCheckRedirectFunc(followRedirects bool, maxRedirects int) checkRedirect
 			if len(requests) > 10 {
 				return http.ErrUseLastResponse
 			}
-			return nil
+			return limiter.Wait(req.Context(), ratelimit.HostPort(req.URL))
 		}
 		if len(requests) > maxRedirects {
 			return http.ErrUseLastResponse
 		}
-		return nil
+		return limiter.Wait(req.Context(), ratelimit.HostPort(req.URL))
 	}
 }
 
+// setCustomHeaders adds the custom headers to a request, along with the
+// session of its host if any.
 func (e *HTTPExecuter) setCustomHeaders(r *requests.HttpRequest) {
-	for _, customHeader := range e.customHeaders {
-		// This should be pre-computed somewhere and done only once
-		tokens := strings.Split(customHeader, ":")
-		// if it's an invalid header skip it
-		if len(tokens) < 2 {
-			continue
-		}
-
-		headerName, headerValue := tokens[0], strings.Join(tokens[1:], "")
-		headerName = strings.TrimSpace(headerName)
-		headerValue = strings.TrimSpace(headerValue)
-		r.Request.Header[headerName] = []string{headerValue}
+	r.SetCustomHeaders(e.customHeaders, false)
+	r.SetCustomHeaders(e.forcedHeaders, true)
+	if session := e.sessions.Session(r.Request.URL); session != nil {
+		session.Apply(r.Request.Request, nil)
+		r.Session = session
 	}
 }
 
@@ -345,5 +1237,24 @@ type Result struct {
 	Extractions map[string]interface{}
 	GotResults  bool
 	Error       error
-	Done        bool
+	// ErrorRequest is the index in its block of the request failing with
+	// the error if any.
+	ErrorRequest int
+	Done         bool
+}
+
+// retryKey is the key of the contexts of the runs retried at the end of the
+// scan
+type retryKey struct{}
+
+// WithRetry returns a context whose requests write their results marked as
+// retried in the json output.
+func WithRetry(ctx context.Context) context.Context {
+	return context.WithValue(ctx, retryKey{}, true)
+}
+
+// isRetry returns true if the requests with a context are retried
+func isRetry(ctx context.Context) bool {
+	retried, _ := ctx.Value(retryKey{}).(bool)
+	return retried
 }
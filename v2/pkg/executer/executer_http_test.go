@@ -0,0 +1,949 @@
+package executer
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"crypto/md5"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/logrusorgru/aurora"
+	"github.com/projectdiscovery/nuclei/v2/pkg/auth"
+	"github.com/projectdiscovery/nuclei/v2/pkg/benchmark"
+	"github.com/projectdiscovery/nuclei/v2/pkg/burp"
+	"github.com/projectdiscovery/nuclei/v2/pkg/collector"
+	"github.com/projectdiscovery/nuclei/v2/pkg/ratelimit"
+	"github.com/projectdiscovery/nuclei/v2/pkg/stats"
+	"github.com/projectdiscovery/nuclei/v2/pkg/templates"
+	"github.com/stretchr/testify/require"
+)
+
+func parseTemplate(t testing.TB, content string) *templates.Template {
+	f, err := ioutil.TempFile("", "template-*.yaml")
+	require.Nil(t, err, "Could not create template file")
+	defer os.Remove(f.Name())
+
+	_, err = f.WriteString(content)
+	f.Close()
+	require.Nil(t, err, "Could not write template file")
+
+	template, err := templates.Parse(f.Name())
+	require.Nil(t, err, "Could not parse template")
+	return template
+}
+
+func TestInternalMatchers(t *testing.T) {
+	var mutex sync.Mutex
+	var paths []string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		mutex.Lock()
+		paths = append(paths, r.URL.RequestURI())
+		mutex.Unlock()
+		fmt.Fprintf(w, "path %s", r.URL.Path)
+	}))
+	defer server.Close()
+
+	run := func(fingerprint, abort string) []string {
+		paths = nil
+		template := parseTemplate(t, fmt.Sprintf(`
+id: internal-test
+info:
+  name: internal
+  author: test
+requests:
+  - raw:
+      - |
+        GET /{{path}} HTTP/1.1
+        Host: {{Hostname}}
+    payloads:
+      path:
+        - first
+        - second
+    internal-abort: %s
+    matchers-condition: and
+    matchers:
+      - type: word
+        words: ["%s"]
+        internal: true
+        request: 1
+      - type: word
+        words: ["path"]
+`, abort, fingerprint))
+
+		executer, err := NewHTTPExecuter(&HTTPOptions{Template: template, BulkHttpRequest: template.BulkRequestsHTTP[0], Timeout: 5, Colorizer: aurora.NewAurora(false)})
+		require.Nil(t, err, "Could not create http executer")
+		executer.ExecuteHTTP(nil, server.URL)
+		return paths
+	}
+
+	require.Equal(t, []string{"/first", "/second"}, run("path", "target"), "Could not send requests after matching internal matcher")
+	require.Equal(t, []string{"/first"}, run("second", "target"), "Could not abandon target after failing internal matcher")
+	require.Equal(t, []string{"/first", "/second"}, run("second", "iteration"), "Could not skip only the failing iteration")
+}
+
+func TestInternalMatchersWithoutResults(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		fmt.Fprintf(w, "fingerprint")
+	}))
+	defer server.Close()
+
+	template := parseTemplate(t, `
+id: internal-only
+info:
+  name: internal
+  author: test
+requests:
+  - method: GET
+    path:
+      - "{{BaseURL}}/"
+    matchers-condition: and
+    matchers:
+      - type: word
+        words: ["fingerprint"]
+        internal: true
+`)
+	executer, err := NewHTTPExecuter(&HTTPOptions{Template: template, BulkHttpRequest: template.BulkRequestsHTTP[0], Timeout: 5, Colorizer: aurora.NewAurora(false)})
+	require.Nil(t, err, "Could not create http executer")
+	result := executer.ExecuteHTTP(nil, server.URL)
+	require.Nil(t, result.Error, "Could not execute http requests")
+	require.False(t, result.GotResults, "Could get results from internal matchers")
+}
+
+func TestSimilarityMatcher(t *testing.T) {
+	var mutex sync.Mutex
+	var paths []string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		mutex.Lock()
+		paths = append(paths, r.URL.Path)
+		mutex.Unlock()
+		if r.URL.Path == "/admin" {
+			fmt.Fprintf(w, "<h1>Administration</h1><p>Users settings logs backups and the server configuration</p>")
+			return
+		}
+		fmt.Fprintf(w, "<h1>Not Found</h1><p>The requested page %s could not be found on this server</p>", r.URL.Path)
+	}))
+	defer server.Close()
+
+	template := parseTemplate(t, `
+id: similarity-test
+info:
+  name: similarity
+  author: test
+requests:
+  - method: GET
+    path:
+      - "{{BaseURL}}/admin"
+      - "{{BaseURL}}/missing"
+    baseline: true
+    matchers-condition: and
+    matchers:
+      - type: similarity
+        threshold: 0.8
+      - type: dsl
+        dsl:
+          - "status_code == baseline_status"
+`)
+	executer, err := NewHTTPExecuter(&HTTPOptions{Template: template, BulkHttpRequest: template.BulkRequestsHTTP[0], Timeout: 5, Colorizer: aurora.NewAurora(false)})
+	require.Nil(t, err, "Could not create http executer")
+	result := executer.ExecuteHTTP(nil, server.URL)
+	require.Nil(t, result.Error, "Could not execute http requests")
+	require.True(t, result.GotResults, "Could not match response different from the baseline")
+
+	// the baseline is fetched a single time for the target
+	require.Len(t, paths, 3, "Could not cache the baseline response")
+	require.Equal(t, "/admin", paths[0], "Could not send the template request")
+	require.NotContains(t, paths[1:], "/admin", "Could not send the baseline request")
+}
+
+func TestJSONExtractorDynamicValues(t *testing.T) {
+	var mutex sync.Mutex
+	var paths []string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		mutex.Lock()
+		paths = append(paths, r.URL.RequestURI())
+		mutex.Unlock()
+		w.Header().Set("Content-Type", "application/json")
+		fmt.Fprintf(w, `{"session": {"token": "a1b2c3"}}`)
+	}))
+	defer server.Close()
+
+	template := parseTemplate(t, `
+id: json-extractor
+info:
+  name: json extractor
+  author: test
+requests:
+  - raw:
+      - |
+        GET /login HTTP/1.1
+        Host: {{Hostname}}
+      - |
+        GET /profile?session={{token}} HTTP/1.1
+        Host: {{Hostname}}
+    extractors:
+      - type: json
+        name: token
+        internal: true
+        json:
+          - "$.session.token"
+`)
+	executer, err := NewHTTPExecuter(&HTTPOptions{Template: template, BulkHttpRequest: template.BulkRequestsHTTP[0], Timeout: 5, Colorizer: aurora.NewAurora(false)})
+	require.Nil(t, err, "Could not create http executer")
+	executer.ExecuteHTTP(nil, server.URL)
+	require.Equal(t, []string{"/login", "/profile?session=a1b2c3"}, paths, "Could not use extracted json value in the next request")
+}
+
+func TestXPathExtractorDynamicValues(t *testing.T) {
+	var mutex sync.Mutex
+	var bodies []string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		data, _ := ioutil.ReadAll(r.Body)
+		mutex.Lock()
+		bodies = append(bodies, string(data))
+		mutex.Unlock()
+		fmt.Fprintf(w, `<html><form action="/login"><input value=" t0k3n " type="hidden" name="csrf"></form>`)
+	}))
+	defer server.Close()
+
+	template := parseTemplate(t, `
+id: xpath-extractor
+info:
+  name: xpath extractor
+  author: test
+requests:
+  - raw:
+      - |
+        GET /login HTTP/1.1
+        Host: {{Hostname}}
+      - |
+        POST /login HTTP/1.1
+        Host: {{Hostname}}
+        Content-Type: application/x-www-form-urlencoded
+
+        user=admin&token={{csrf}}
+    extractors:
+      - type: xpath
+        name: csrf
+        internal: true
+        xpath:
+          - "//input[@name='csrf']"
+        attribute: value
+`)
+	executer, err := NewHTTPExecuter(&HTTPOptions{Template: template, BulkHttpRequest: template.BulkRequestsHTTP[0], Timeout: 5, Colorizer: aurora.NewAurora(false)})
+	require.Nil(t, err, "Could not create http executer")
+	executer.ExecuteHTTP(nil, server.URL)
+	require.Len(t, bodies, 2, "Could not send the requests")
+	require.Equal(t, "user=admin&token=t0k3n", strings.TrimSpace(bodies[1]), "Could not use extracted xpath value in the next request")
+}
+
+func TestExtractorDependentRequests(t *testing.T) {
+	var mutex sync.Mutex
+	var paths []string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		mutex.Lock()
+		paths = append(paths, r.URL.RequestURI())
+		mutex.Unlock()
+		if r.URL.Path == "/tokens" {
+			fmt.Fprintf(w, "token=first token=second token=first")
+		}
+	}))
+	defer server.Close()
+
+	run := func(path string) []string {
+		paths = nil
+		template := parseTemplate(t, fmt.Sprintf(`
+id: dependent-requests
+info:
+  name: dependent requests
+  author: test
+requests:
+  - method: GET
+    path:
+      - "{{BaseURL}}/%s"
+      - "{{BaseURL}}/use?value={{session}}"
+    extractors:
+      - type: regex
+        name: session
+        internal: true
+        regex:
+          - "(first|second)"
+`, path))
+		executer, err := NewHTTPExecuter(&HTTPOptions{Template: template, BulkHttpRequest: template.BulkRequestsHTTP[0], Timeout: 5, Colorizer: aurora.NewAurora(false)})
+		require.Nil(t, err, "Could not create http executer")
+		result := executer.ExecuteHTTP(nil, server.URL)
+		require.Nil(t, result.Error, "Could not execute http requests")
+		return paths
+	}
+
+	require.Equal(t, []string{"/tokens", "/use?value=first"}, run("tokens"), "Could not use the first extracted value")
+	require.Equal(t, []string{"/empty"}, run("empty"), "Could not skip request without extracted value")
+}
+
+func TestExtractorToFile(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		fmt.Fprintf(w, "admin@example.com root@example.com admin@example.com")
+	}))
+	defer server.Close()
+
+	directory, err := ioutil.TempDir("", "to-file-*")
+	require.Nil(t, err, "Could not create temporary directory")
+	defer os.RemoveAll(directory)
+
+	template := parseTemplate(t, `
+id: emails
+info:
+  name: emails
+  author: test
+requests:
+  - method: GET
+    path:
+      - "{{BaseURL}}/"
+      - "{{BaseURL}}/other"
+    extractors:
+      - type: regex
+        name: email
+        regex:
+          - "[a-z]+@example\\.com"
+        to-file: "{{template-id}}-{{extractor-name}}.txt"
+        to-file-dedupe: true
+`)
+	files := collector.NewFiles(directory)
+	executer, err := NewHTTPExecuter(&HTTPOptions{Template: template, BulkHttpRequest: template.BulkRequestsHTTP[0], Timeout: 5, Files: files, Colorizer: aurora.NewAurora(false)})
+	require.Nil(t, err, "Could not create http executer")
+	executer.ExecuteHTTP(nil, server.URL)
+	files.Close()
+
+	data, err := ioutil.ReadFile(directory + "/emails-email.txt")
+	require.Nil(t, err, "Could not read extractor file")
+	require.Equal(t, "admin@example.com\nroot@example.com\n", string(data), "Could not write deduplicated values")
+}
+
+func TestExtractorCollector(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		fmt.Fprintf(w, "admin@example.com root@example.com admin@example.com")
+	}))
+	defer server.Close()
+
+	directory, err := ioutil.TempDir("", "collector-*")
+	require.Nil(t, err, "Could not create temporary directory")
+	defer os.RemoveAll(directory)
+	file := filepath.Join(directory, "extracted.txt")
+
+	values, err := collector.New(file)
+	require.Nil(t, err, "Could not create collector")
+
+	template := parseTemplate(t, `
+id: emails
+info:
+  name: emails
+  author: test
+requests:
+  - method: GET
+    path:
+      - "{{BaseURL}}/"
+    extractors:
+      - type: regex
+        regex:
+          - "[a-z]+@example\\.com"
+`)
+	executer, err := NewHTTPExecuter(&HTTPOptions{Template: template, BulkHttpRequest: template.BulkRequestsHTTP[0], Timeout: 5, Collector: values, Quiet: true, Colorizer: aurora.NewAurora(false)})
+	require.Nil(t, err, "Could not create http executer")
+	result := executer.ExecuteHTTP(nil, server.URL)
+	require.Nil(t, result.Error, "Could not execute http request")
+	require.Nil(t, values.Close(), "Could not close collector")
+
+	data, err := ioutil.ReadFile(file)
+	require.Nil(t, err, "Could not read collected values")
+	require.Equal(t, "admin@example.com\nroot@example.com\n", string(data), "Could not collect extracted values")
+}
+
+func TestPassiveExtract(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/" {
+			fmt.Fprintf(w, "admin@example.com root@example.com admin@example.com")
+		}
+	}))
+	defer server.Close()
+
+	template := parseTemplate(t, `
+id: emails
+info:
+  name: emails
+  author: test
+  severity: info
+requests:
+  - method: GET
+    path:
+      - "{{BaseURL}}/"
+      - "{{BaseURL}}/empty"
+    extractors:
+      - type: regex
+        regex:
+          - "[a-z]+@example\\.com"
+`)
+	output := &bytes.Buffer{}
+	writer := bufio.NewWriter(output)
+	executer, err := NewHTTPExecuter(&HTTPOptions{Template: template, BulkHttpRequest: template.BulkRequestsHTTP[0], Writer: writer, Timeout: 5, PassiveExtract: true, Colorizer: aurora.NewAurora(false)})
+	require.Nil(t, err, "Could not create http executer")
+	result := executer.ExecuteHTTP(nil, server.URL)
+	require.Nil(t, result.Error, "Could not execute http requests")
+	writer.Flush()
+
+	require.Equal(t, "admin@example.com\nroot@example.com\n", output.String(), "Could not write only the extracted values")
+}
+
+func TestTemplateVariables(t *testing.T) {
+	var token string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := ioutil.ReadAll(r.Body)
+		if r.URL.Path == "/api/login" && r.Header.Get("X-Token") == token && string(body) == "check="+token {
+			fmt.Fprintf(w, "session %s", token)
+		}
+	}))
+	defer server.Close()
+	hash := md5.Sum([]byte(strings.TrimPrefix(server.URL, "http://")))
+	token = hex.EncodeToString(hash[:])
+
+	template := parseTemplate(t, `
+id: variables
+info:
+  name: variables
+  author: test
+variables:
+  prefix: /api
+  token: "{{md5(Hostname)}}"
+requests:
+  - method: POST
+    path:
+      - "{{BaseURL}}{{prefix}}/login"
+    headers:
+      X-Token: "{{token}}"
+    body: "check={{token}}"
+    matchers:
+      - type: dsl
+        dsl:
+          - 'contains(body, "session " + token)'
+`)
+	executer, err := NewHTTPExecuter(&HTTPOptions{Template: template, BulkHttpRequest: template.BulkRequestsHTTP[0], Timeout: 5, Colorizer: aurora.NewAurora(false)})
+	require.Nil(t, err, "Could not create http executer")
+	result := executer.ExecuteHTTP(nil, server.URL)
+	require.Nil(t, result.Error, "Could not execute http requests")
+	require.True(t, result.GotResults, "Could not use the variables in the request and the matchers")
+}
+
+func TestRunIf(t *testing.T) {
+	var mutex sync.Mutex
+	var requested []string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		mutex.Lock()
+		requested = append(requested, r.URL.Path)
+		mutex.Unlock()
+		switch r.URL.Path {
+		case "/":
+			fmt.Fprintf(w, `<link href="/wp-content/style.css">`)
+		case "/wp-admin":
+			fmt.Fprintf(w, "dashboard")
+		}
+	}))
+	defer server.Close()
+
+	template := parseTemplate(t, `
+id: run-if
+info:
+  name: run-if
+  author: test
+requests:
+  - method: GET
+    path:
+      - "http://127.0.0.1:1/"
+      - "{{BaseURL}}/"
+      - "{{BaseURL}}/wp-admin"
+      - "{{BaseURL}}/never"
+    run-if:
+      2: 'failed_1 && error_1 != ""'
+      3: 'status_code_2 == 200 && contains(body_2, "wp-content")'
+      4: 'status_code_3 == 404'
+    matchers:
+      - type: word
+        words:
+          - "dashboard"
+`)
+	executer, err := NewHTTPExecuter(&HTTPOptions{Template: template, BulkHttpRequest: template.BulkRequestsHTTP[0], Timeout: 5, Colorizer: aurora.NewAurora(false)})
+	require.Nil(t, err, "Could not create http executer")
+	result := executer.ExecuteHTTP(nil, server.URL)
+	require.True(t, result.GotResults, "Could not run the requests with passing guards")
+	require.Equal(t, []string{"/", "/wp-admin"}, requested, "Could not skip the request with a failing guard")
+}
+
+func TestExecuteWithValues(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/wordpress/readme.html" {
+			fmt.Fprintf(w, "Version 5.4.2")
+		}
+	}))
+	defer server.Close()
+
+	template := parseTemplate(t, `
+id: workflow-values
+info:
+  name: workflow-values
+  author: test
+requests:
+  - method: GET
+    path:
+      - "{{BaseURL}}{{path}}/readme.html"
+    matchers:
+      - type: word
+        name: wordpress
+        words:
+          - "Version"
+    extractors:
+      - type: regex
+        name: version
+        group: 1
+        regex:
+          - "Version ([0-9.]+)"
+`)
+	executer, err := NewHTTPExecuter(&HTTPOptions{Template: template, BulkHttpRequest: template.BulkRequestsHTTP[0], Timeout: 5, Colorizer: aurora.NewAurora(false)})
+	require.Nil(t, err, "Could not create http executer")
+	result := executer.ExecuteHTTPWithValues(nil, server.URL, map[string]interface{}{"path": "/wordpress"})
+	require.Nil(t, result.Error, "Could not execute http requests")
+	require.True(t, result.GotResults, "Could not use the values in the request")
+	require.Contains(t, result.Matches, "wordpress", "Could not get the matched matcher name")
+	require.Equal(t, []string{"5.4.2"}, result.Extractions["version"], "Could not get the named extracted values")
+}
+
+func TestIterateAll(t *testing.T) {
+	var mutex sync.Mutex
+	var paths []string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		mutex.Lock()
+		paths = append(paths, r.URL.RequestURI())
+		mutex.Unlock()
+		switch {
+		case r.URL.Path == "/ids":
+			fmt.Fprintf(w, "1 2 3 2")
+		case r.URL.Query().Get("id") == "2":
+			fmt.Fprintf(w, "secret")
+		}
+	}))
+	defer server.Close()
+
+	run := func(placeholder string, maxIterations int) ([]string, string) {
+		paths = nil
+		template := parseTemplate(t, fmt.Sprintf(`
+id: iterate-all
+info:
+  name: iterate all
+  author: test
+requests:
+  - method: GET
+    iterate-all: true
+    max-iterations: %d
+    path:
+      - "{{BaseURL}}/ids"
+      - "{{BaseURL}}/item?id=%s"
+    matchers:
+      - type: word
+        words:
+          - "secret"
+    extractors:
+      - type: regex
+        name: oid
+        internal: true
+        regex:
+          - "[0-9]+"
+`, maxIterations, placeholder))
+		output := &bytes.Buffer{}
+		writer := bufio.NewWriter(output)
+		executer, err := NewHTTPExecuter(&HTTPOptions{Template: template, BulkHttpRequest: template.BulkRequestsHTTP[0], Writer: writer, JSON: true, Timeout: 5, Colorizer: aurora.NewAurora(false)})
+		require.Nil(t, err, "Could not create http executer")
+		result := executer.ExecuteHTTP(nil, server.URL)
+		require.Nil(t, result.Error, "Could not execute http requests")
+		writer.Flush()
+		return paths, output.String()
+	}
+
+	requested, output := run("{{oid}}", 0)
+	require.Equal(t, []string{"/ids", "/item?id=1", "/item?id=2", "/item?id=3"}, requested, "Could not iterate on the distinct extracted values")
+	require.Equal(t, 1, strings.Count(output, "\n"), "Could not write a result for the matching iteration")
+	require.Contains(t, output, `"iterated_value":"2"`, "Could not annotate the result with the iterated value")
+
+	requested, _ = run("{{value}}", 2)
+	require.Equal(t, []string{"/ids", "/item?id=1", "/item?id=2"}, requested, "Could not cap the iterations on {{value}}")
+}
+
+func TestFuzzing(t *testing.T) {
+	var mutex sync.Mutex
+	var queries []string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		mutex.Lock()
+		queries = append(queries, r.URL.RawQuery)
+		mutex.Unlock()
+		if strings.Contains(r.URL.Query().Get("id"), "'") {
+			fmt.Fprintf(w, "SQL syntax error")
+		}
+	}))
+	defer server.Close()
+
+	template := parseTemplate(t, `
+id: fuzzing
+info:
+  name: fuzzing
+  author: test
+requests:
+  - method: GET
+    path:
+      - "{{BaseURL}}"
+    fuzzing:
+      - part: query
+        mode: append
+        payloads:
+          injection:
+            - "'"
+            - "\""
+    matchers:
+      - type: word
+        words:
+          - "SQL syntax"
+`)
+	output := &bytes.Buffer{}
+	writer := bufio.NewWriter(output)
+	executer, err := NewHTTPExecuter(&HTTPOptions{Template: template, BulkHttpRequest: template.BulkRequestsHTTP[0], Writer: writer, JSON: true, Timeout: 5, Colorizer: aurora.NewAurora(false)})
+	require.Nil(t, err, "Could not create http executer")
+	_, ok := template.BulkRequestsHTTP[0].ClusterKey()
+	require.False(t, ok, "Could cluster a fuzzing request")
+
+	result := executer.ExecuteHTTP(nil, server.URL+"/item?id=1&sort=asc")
+	require.Nil(t, result.Error, "Could not execute http requests")
+	writer.Flush()
+	require.Equal(t, []string{"id=1%27&sort=asc", "id=1%22&sort=asc", "id=1&sort=asc%27", "id=1&sort=asc%22"}, queries, "Could not fuzz each parameter with each payload")
+	require.Equal(t, 1, strings.Count(output.String(), "\n"), "Could not write a result for the matching payload")
+	require.Contains(t, output.String(), `"fuzzing":{"part":"query","parameter":"id","position":1,"mode":"append","payload_name":"injection","payload":"'"}`, "Could not write the fuzzed parameter and payload")
+
+	plan, err := executer.PlanHTTP(server.URL+"/item?id=1", nil, 0)
+	require.Nil(t, err, "Could not plan http requests")
+	require.Equal(t, int64(2), plan.Total, "Could not count the fuzzed requests")
+	require.Len(t, plan.Requests, 2, "Could not plan the fuzzed requests")
+
+	queries = nil
+	result = executer.ExecuteHTTP(nil, server.URL+"/item")
+	require.Nil(t, result.Error, "Could not execute http requests")
+	require.Empty(t, queries, "Could send a request without parameters to fuzz")
+}
+
+func TestRequestTimeout(t *testing.T) {
+	template := parseTemplate(t, `
+id: request-timeout
+info:
+  name: request timeout
+  author: test
+requests:
+  - method: GET
+    timeout: 20
+    retries: 3
+    path:
+      - "{{BaseURL}}"
+    matchers:
+      - type: status
+        status:
+          - 200
+`)
+	request := template.BulkRequestsHTTP[0]
+	executer, err := NewHTTPExecuter(&HTTPOptions{Template: template, BulkHttpRequest: request, Timeout: 5, Colorizer: aurora.NewAurora(false)})
+	require.Nil(t, err, "Could not create http executer")
+	require.Equal(t, 20*time.Second, executer.httpClient.HTTPClient.Timeout, "Could not use the timeout of the request")
+
+	executer, err = NewHTTPExecuter(&HTTPOptions{Template: template, BulkHttpRequest: request, Timeout: 5, Resolved: true, Colorizer: aurora.NewAurora(false)})
+	require.Nil(t, err, "Could not create http executer")
+	require.Equal(t, 5*time.Second, executer.httpClient.HTTPClient.Timeout, "Could not use the resolved timeout")
+}
+
+func TestRateLimitRedirects(t *testing.T) {
+	var hits int64
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		atomic.AddInt64(&hits, 1)
+		if r.URL.Path == "/" {
+			http.Redirect(w, r, "/login", http.StatusFound)
+			return
+		}
+		fmt.Fprintf(w, "login")
+	}))
+	defer server.Close()
+
+	template := parseTemplate(t, `
+id: redirects
+info:
+  name: redirects
+  author: test
+  severity: info
+requests:
+  - method: GET
+    path:
+      - "{{BaseURL}}/"
+    redirects: true
+    matchers:
+      - type: word
+        words:
+          - login
+`)
+	var delayed int64
+	limiter := ratelimit.New(20, func(wait time.Duration) {
+		atomic.AddInt64(&delayed, 1)
+	})
+	executer, err := NewHTTPExecuter(&HTTPOptions{Template: template, BulkHttpRequest: template.BulkRequestsHTTP[0], Timeout: 5, RateLimiter: limiter, Colorizer: aurora.NewAurora(false)})
+	require.Nil(t, err, "Could not create http executer")
+	result := executer.ExecuteHTTP(nil, server.URL)
+	require.Nil(t, result.Error, "Could not execute http requests")
+	require.True(t, result.GotResults, "Could not follow the redirect")
+	require.Equal(t, int64(2), atomic.LoadInt64(&hits), "Could not send the redirect hop")
+	require.Equal(t, int64(1), atomic.LoadInt64(&delayed), "Could not limit the redirect hop to the same host")
+}
+
+func TestExecuteWithContext(t *testing.T) {
+	var hits int64
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		atomic.AddInt64(&hits, 1)
+		select {
+		case <-time.After(200 * time.Millisecond):
+		case <-r.Context().Done():
+		}
+		fmt.Fprintf(w, "hello")
+	}))
+	defer server.Close()
+
+	template := parseTemplate(t, `
+id: slow-payloads
+info:
+  name: slow payloads
+  author: test
+  severity: info
+requests:
+  - payloads:
+      word:
+        - a1
+        - a2
+        - a3
+        - a4
+        - a5
+        - a6
+        - a7
+        - a8
+        - a9
+        - a10
+    raw:
+      - |
+        GET /{{word}} HTTP/1.1
+        Host: {{Hostname}}
+
+    matchers:
+      - type: word
+        words:
+          - hello
+`)
+	executer, err := NewHTTPExecuter(&HTTPOptions{Template: template, BulkHttpRequest: template.BulkRequestsHTTP[0], Timeout: 5, Colorizer: aurora.NewAurora(false)})
+	require.Nil(t, err, "Could not create http executer")
+
+	ctx, cancel := context.WithTimeout(context.Background(), 500*time.Millisecond)
+	defer cancel()
+	start := time.Now()
+	result := executer.ExecuteHTTPWithContext(ctx, nil, server.URL, nil)
+	require.NotNil(t, result.Error, "Could not abandon the requests")
+	require.Less(t, time.Since(start).Milliseconds(), int64(1500), "Could not cancel the request in flight")
+	require.Less(t, atomic.LoadInt64(&hits), int64(10), "Could not skip the remaining requests")
+	require.False(t, executer.bulkHttpRequest.Next(server.URL), "Could not stop the generator of the payloads")
+}
+
+func TestBenchmarkDiscardResults(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		fmt.Fprintf(w, "Version 5.4.2")
+	}))
+	defer server.Close()
+
+	template := parseTemplate(t, `
+id: benchmark-discard
+info:
+  name: benchmark discard
+  author: test
+  severity: info
+requests:
+  - method: GET
+    path:
+      - "{{BaseURL}}/a"
+      - "{{BaseURL}}/b"
+    matchers:
+      - type: word
+        words:
+          - "Version"
+`)
+	output := &bytes.Buffer{}
+	writer := bufio.NewWriter(output)
+	counters := stats.New(1)
+	recorder := benchmark.New()
+	executer, err := NewHTTPExecuter(&HTTPOptions{Template: template, BulkHttpRequest: template.BulkRequestsHTTP[0], Writer: writer, Timeout: 5, Stats: counters, Benchmark: recorder, DiscardResults: true, Colorizer: aurora.NewAurora(false)})
+	require.Nil(t, err, "Could not create http executer")
+	result := executer.ExecuteHTTP(nil, server.URL)
+	writer.Flush()
+
+	require.Nil(t, result.Error, "Could not execute http requests")
+	require.True(t, result.GotResults, "Could not match the discarded results")
+	require.Empty(t, output.String(), "Could not discard the results")
+	require.Equal(t, uint64(2), counters.Summary(0, false).Findings, "Could not count the discarded results")
+
+	report := recorder.Report(0)
+	require.Len(t, report.Templates, 1, "Could not record the template")
+	require.Equal(t, "benchmark-discard", report.Templates[0].Name, "Could not record the template id")
+	require.Equal(t, uint64(1), report.Templates[0].Runs, "Could not record the run")
+	require.Equal(t, uint64(2), report.Templates[0].Requests, "Could not record the requests")
+	require.Len(t, report.Hosts, 1, "Could not record the host")
+	require.Equal(t, strings.TrimPrefix(server.URL, "http://"), report.Hosts[0].Name, "Could not record the host and port")
+}
+
+func TestExportMatchedRequests(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Query().Get("file") == "passwd" {
+			fmt.Fprintf(w, "root:x:0:0")
+		}
+	}))
+	defer server.Close()
+	dir, err := ioutil.TempDir("", "nuclei-export")
+	require.Nil(t, err, "Could not create directory")
+	defer os.RemoveAll(dir)
+	exporter, err := burp.New(burp.Options{Directory: dir})
+	require.Nil(t, err, "Could not create exporter")
+
+	template := parseTemplate(t, `
+id: exported-matches
+info:
+  name: exported matches
+  author: test
+  severity: high
+requests:
+  - raw:
+      - |
+        GET /?file={{name}} HTTP/1.1
+        Host: {{Hostname}}
+    payloads:
+      name:
+        - hosts
+        - passwd
+    matchers:
+      - type: word
+        words:
+          - "root:"
+`)
+	executer, err := NewHTTPExecuter(&HTTPOptions{Template: template, BulkHttpRequest: template.BulkRequestsHTTP[0], Writer: bufio.NewWriter(&bytes.Buffer{}), Timeout: 5, RequestExporter: exporter, Colorizer: aurora.NewAurora(false)})
+	require.Nil(t, err, "Could not create http executer")
+	result := executer.ExecuteHTTP(nil, server.URL)
+	require.Nil(t, result.Error, "Could not execute http requests")
+	require.True(t, result.GotResults, "Could not match the response")
+
+	// only the request of the result is exported
+	require.Equal(t, 1, exporter.Count(), "Could not export the matched request only")
+	data, err := ioutil.ReadFile(filepath.Join(dir, "000001-exported-matches.txt"))
+	require.Nil(t, err, "Could not write the exported request")
+	require.True(t, strings.HasPrefix(string(data), "GET /?file=passwd HTTP/1.1\r\n"), "Could not export the matched request")
+}
+
+func TestSessions(t *testing.T) {
+	var mutex sync.Mutex
+	generation := 0
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		mutex.Lock()
+		defer mutex.Unlock()
+		switch r.URL.Path {
+		case "/login":
+			generation++
+			http.SetCookie(w, &http.Cookie{Name: "sid", Value: fmt.Sprintf("session-%d", generation)})
+			fmt.Fprintf(w, `{"token":"secret-token-%d"}`, generation)
+		case "/expire":
+			generation++
+		default:
+			cookie, err := r.Cookie("sid")
+			token := fmt.Sprintf("secret-token-%d", generation)
+			if err != nil || cookie.Value != fmt.Sprintf("session-%d", generation) || r.Header.Get("Authorization") != "Bearer "+token {
+				http.Redirect(w, r, "/login", http.StatusFound)
+				return
+			}
+			fmt.Fprintf(w, "welcome %s", token)
+		}
+	}))
+	defer server.Close()
+
+	dir, err := ioutil.TempDir("", "nuclei-auth")
+	require.Nil(t, err, "Could not create directory")
+	defer os.RemoveAll(dir)
+	configFile := filepath.Join(dir, "auth.yaml")
+	require.Nil(t, ioutil.WriteFile(configFile, []byte("template: login.yaml\nheaders:\n  Authorization: Bearer {{token}}\nreauth:\n  matchers:\n    - type: status\n      status: [302]\n"), 0644), "Could not write config")
+	config, err := auth.LoadConfig(configFile)
+	require.Nil(t, err, "Could not load config")
+	var logins int32
+	sessions := auth.NewStore(config, func(rootURL string) (*auth.Session, error) {
+		atomic.AddInt32(&logins, 1)
+		resp, err := http.Get(rootURL + "/login")
+		if err != nil {
+			return nil, err
+		}
+		defer resp.Body.Close()
+		var body struct {
+			Token string `json:"token"`
+		}
+		if err := json.NewDecoder(resp.Body).Decode(&body); err != nil {
+			return nil, err
+		}
+		return auth.NewSession(config, map[string]interface{}{"token": body.Token}, resp.Cookies())
+	})
+
+	run := func() (Result, string) {
+		template := parseTemplate(t, `
+id: authenticated
+info:
+  name: authenticated
+  author: test
+requests:
+  - method: GET
+    path:
+      - "{{BaseURL}}/admin"
+    matchers:
+      - type: word
+        words:
+          - "welcome"
+`)
+		buffer := &bytes.Buffer{}
+		writer := bufio.NewWriter(buffer)
+		executer, err := NewHTTPExecuter(&HTTPOptions{Template: template, BulkHttpRequest: template.BulkRequestsHTTP[0], Writer: writer, Timeout: 5, JSON: true, IncludeRR: true, Quiet: true, Sessions: sessions, Colorizer: aurora.NewAurora(false)})
+		require.Nil(t, err, "Could not create http executer")
+		result := executer.ExecuteHTTP(nil, server.URL)
+		executer.Close()
+		return result, buffer.String()
+	}
+
+	result, output := run()
+	require.Nil(t, result.Error, "Could not execute http requests")
+	require.True(t, result.GotResults, "Could not send the request with the session")
+	require.Equal(t, int32(1), atomic.LoadInt32(&logins), "Could not log in once")
+	require.NotContains(t, output, "secret-token-1", "Could not redact the token of the session")
+	require.NotContains(t, output, "session-1", "Could not redact the cookie of the session")
+
+	// the expired session is established again and the request sent again
+	resp, err := http.Get(server.URL + "/expire")
+	require.Nil(t, err, "Could not expire the session")
+	resp.Body.Close()
+	result, output = run()
+	require.Nil(t, result.Error, "Could not execute http requests")
+	require.True(t, result.GotResults, "Could not send the request again with a new session")
+	require.Equal(t, int32(2), atomic.LoadInt32(&logins), "Could not log in again")
+	require.NotContains(t, output, "secret-token-3", "Could not redact the token of the new session")
+}
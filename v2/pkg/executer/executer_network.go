@@ -0,0 +1,160 @@
+package executer
+
+import (
+	"context"
+	"strings"
+	"time"
+
+	"github.com/pkg/errors"
+	"github.com/projectdiscovery/gologger"
+	"github.com/projectdiscovery/nuclei/v2/internal/progress"
+	"github.com/projectdiscovery/nuclei/v2/pkg/exclusions"
+	"github.com/projectdiscovery/nuclei/v2/pkg/extractors"
+	"github.com/projectdiscovery/nuclei/v2/pkg/generators"
+	"github.com/projectdiscovery/nuclei/v2/pkg/matchers"
+	"github.com/projectdiscovery/nuclei/v2/pkg/network"
+	"github.com/projectdiscovery/nuclei/v2/pkg/requests"
+	"github.com/projectdiscovery/nuclei/v2/pkg/templates"
+)
+
+// NetworkExecuter is a client running the steps of a network request of a
+// template over raw tcp or udp connections.
+type NetworkExecuter struct {
+	resultSink
+	// timeout is the time the connection and each step can take
+	timeout        time.Duration
+	networkRequest *requests.NetworkRequest
+}
+
+// NetworkOptions contains configuration options for the network executer.
+// IncludeRR writes the bytes read from the connections in JSON output.
+type NetworkOptions struct {
+	CommonOptions
+	Template       *templates.Template
+	NetworkRequest *requests.NetworkRequest
+	// Timeout is the seconds the connection and each step can take
+	Timeout int
+	// Resolved uses the timeout of the options even if the request has its
+	// own, the options being the effective values.
+	Resolved bool
+}
+
+// NewNetworkExecuter creates a new network executer from a template and a
+// network request.
+func NewNetworkExecuter(options *NetworkOptions) (*NetworkExecuter, error) {
+	executer := &NetworkExecuter{
+		resultSink:     newResultSink("network", options.Template, options.NetworkRequest.Matchers, &options.CommonOptions),
+		timeout:        requestTimeout(options.Timeout, options.Resolved, options.NetworkRequest.Timeout),
+		networkRequest: options.NetworkRequest,
+	}
+	return executer, nil
+}
+
+// ExecuteNetwork runs the steps of the network request towards a target,
+// a bare host:port or a url.
+func (e *NetworkExecuter) ExecuteNetwork(p *progress.Progress, target string) Result {
+	return e.ExecuteNetworkWithContext(context.Background(), p, target, nil)
+}
+
+// ExecuteNetworkWithContext runs the steps of the network request towards
+// a target with values until the context is done, a connection being made
+// to each address for each combination of the payloads. The errors of the
+// connections are only returned if no result was found, the matchers of
+// the error part possibly matching them.
+func (e *NetworkExecuter) ExecuteNetworkWithContext(ctx context.Context, p *progress.Progress, target string, values map[string]interface{}) (result Result) {
+	defer func(start time.Time) {
+		e.benchmark.Run(e.template.ID, time.Since(start))
+	}(time.Now())
+	remaining := e.networkRequest.GetRequestCount()
+	defer func() {
+		if p != nil && remaining > 0 {
+			p.Drop(remaining)
+		}
+	}()
+
+	variables, addresses, err := e.buildAddresses(target, values, e.networkRequest.MakeAddresses)
+	if err != nil {
+		result.Error = err
+		return
+	}
+	return runAddresses(ctx, addresses, e.networkRequest.PayloadValues, func(address string, payloadValues map[string]interface{}) (Result, error) {
+		defer func() { remaining-- }()
+		return e.run(ctx, p, target, address, generators.MergeMaps(variables, payloadValues))
+	})
+}
+
+// run runs the steps over a connection to an address, returning the result
+// of the bytes read along with the error of the connection if any.
+func (e *NetworkExecuter) run(ctx context.Context, p *progress.Progress, target, address string, values map[string]interface{}) (result Result, runErr error) {
+	result.Matches = make(map[string]interface{})
+	result.Extractions = make(map[string]interface{})
+	inputs := e.networkRequest.MakeInputs(target, values)
+
+	if e.debug {
+		e.dump("network steps", address, inputsString(inputs))
+	}
+
+	e.rateLimiter.Wait(ctx, address)
+	if err := ctx.Err(); err != nil {
+		return result, err
+	}
+
+	start := time.Now()
+	e.stats.Request()
+	resp, err := network.Run(ctx, address, inputs, &network.Options{
+		Protocol: e.networkRequest.Protocol,
+		TLS:      e.networkRequest.TLS,
+		Timeout:  e.timeout,
+		ReadSize: e.networkRequest.ReadSize,
+	})
+	e.stats.RequestDone()
+	e.benchmark.Request(e.template.ID, address, time.Since(start), err)
+	if p != nil {
+		p.Update()
+	}
+	// the errors of the connection are matched along with the bytes read,
+	// the other ones stopping the run
+	if err != nil {
+		runErr = errors.Wrapf(err, "could not run network steps for %s", address)
+		if _, ok := err.(*network.Error); !ok {
+			return result, runErr
+		}
+	}
+
+	gologger.Verbosef("Ran network steps on %s\n", "network-request", address)
+
+	if e.debug {
+		e.dump("network response", address, resp.Dump())
+	}
+
+	return e.evaluate(ctx, &operators{
+		condition:  e.networkRequest.GetMatchersCondition(),
+		matchers:   e.networkRequest.Matchers,
+		extractors: e.networkRequest.Extractors,
+		match: func(matcher *matchers.Matcher) bool {
+			return matcher.MatchNetwork(resp, values)
+		},
+		extract: func(extractor *extractors.Extractor) []string {
+			return extractor.ExtractNetwork(resp, values)
+		},
+		target: address,
+		exclude: func(exclusions *exclusions.Exclusions) int {
+			return exclusions.MatchNetwork(e.template.ID, address, resp)
+		},
+		result: func(matcher *matchers.Matcher, extracted []string) *protocolResult {
+			return e.result(target, inputs, resp, matcher, extracted)
+		},
+	}), runErr
+}
+
+// inputsString returns the data sent by the steps of a run, one per line
+func inputsString(inputs []*network.Input) string {
+	if len(inputs) == 0 {
+		return "read"
+	}
+	lines := make([]string, 0, len(inputs))
+	for _, input := range inputs {
+		lines = append(lines, input.String())
+	}
+	return strings.Join(lines, "\n")
+}
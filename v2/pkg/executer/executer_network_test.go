@@ -0,0 +1,137 @@
+package executer
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"net"
+	"strings"
+	"testing"
+
+	"github.com/logrusorgru/aurora"
+	"github.com/projectdiscovery/nuclei/v2/pkg/network"
+	"github.com/projectdiscovery/nuclei/v2/pkg/stats"
+	"github.com/stretchr/testify/require"
+)
+
+// newAuthServer returns a listener answering AUTH with the password secret
+// like a redis server, after a banner.
+func newAuthServer(t *testing.T) net.Listener {
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	require.Nil(t, err, "Could not listen")
+	go func() {
+		for {
+			conn, err := listener.Accept()
+			if err != nil {
+				return
+			}
+			go func(conn net.Conn) {
+				defer conn.Close()
+				conn.Write([]byte("+READY\r\n"))
+				reader := bufio.NewReader(conn)
+				for {
+					line, err := reader.ReadString('\n')
+					if err != nil {
+						return
+					}
+					if strings.TrimSpace(line) == "AUTH secret" {
+						conn.Write([]byte("+OK\r\n"))
+					} else {
+						conn.Write([]byte("-ERR invalid password\r\n"))
+					}
+				}
+			}(conn)
+		}
+	}()
+	return listener
+}
+
+func TestNetworkExecuter(t *testing.T) {
+	listener := newAuthServer(t)
+	defer listener.Close()
+
+	template := parseTemplate(t, `
+id: redis-default-password
+info:
+  name: redis default password
+  author: test
+  severity: high
+network:
+  - host:
+      - "{{Hostname}}"
+    inputs:
+      - read: 8
+        name: banner
+      - data: "AUTH {{password}}\r\n"
+    payloads:
+      password:
+        - admin
+        - secret
+    matchers-condition: and
+    matchers:
+      - type: word
+        words:
+          - "+OK"
+    extractors:
+      - type: kval
+        kval:
+          - banner
+`)
+	output := &bytes.Buffer{}
+	writer := bufio.NewWriter(output)
+	counters := stats.New(1)
+	executer, err := NewNetworkExecuter(&NetworkOptions{Template: template, NetworkRequest: template.RequestsNetwork[0], CommonOptions: CommonOptions{Writer: writer, JSON: true, JSONRequests: true, IncludeRR: true, Stats: counters, Colorizer: aurora.NewAurora(false)}})
+	require.Nil(t, err, "Could not create network executer")
+
+	result := executer.ExecuteNetwork(nil, listener.Addr().String())
+	require.Nil(t, result.Error, "Could not run the steps")
+	require.True(t, result.GotResults, "Could not match the answer")
+	require.Equal(t, uint64(1), counters.Summary(0, false).Findings, "Could not count the finding")
+
+	var found ResultEvent
+	require.Nil(t, json.Unmarshal(bytes.TrimSpace(output.Bytes()), &found), "Could not write a single json result")
+	require.Equal(t, "network", found.Type, "Could not write the type")
+	require.Equal(t, listener.Addr().String(), found.Matched, "Could not write the address")
+	require.Equal(t, "read\n\"AUTH secret\\r\\n\"", found.Request, "Could not write the steps")
+	require.Equal(t, "+READY\r\n+OK\r\n", found.Response, "Could not write the bytes read")
+
+	plan, err := executer.PlanNetwork("http://"+listener.Addr().String(), nil, 1)
+	require.Nil(t, err, "Could not plan the steps")
+	require.Equal(t, int64(2), plan.Total, "Could not count the combinations")
+	require.Len(t, plan.Requests, 1, "Could not limit the planned steps")
+	require.Equal(t, "TCP", plan.Requests[0].Method, "Could not plan the protocol")
+	require.Equal(t, listener.Addr().String(), plan.Requests[0].URL, "Could not plan the address")
+}
+
+func TestNetworkExecuterRefused(t *testing.T) {
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	require.Nil(t, err, "Could not listen")
+	address := listener.Addr().String()
+	listener.Close()
+
+	template := parseTemplate(t, `
+id: closed-port
+info:
+  name: closed port
+  author: test
+  severity: info
+network:
+  - host:
+      - "{{Hostname}}"
+    matchers:
+      - type: word
+        part: error
+        words:
+          - refused
+`)
+	executer, err := NewNetworkExecuter(&NetworkOptions{Template: template, NetworkRequest: template.RequestsNetwork[0], CommonOptions: CommonOptions{Colorizer: aurora.NewAurora(false)}})
+	require.Nil(t, err, "Could not create network executer")
+	result := executer.ExecuteNetwork(nil, address)
+	require.Nil(t, result.Error, "Could not match the refused connection")
+	require.True(t, result.GotResults, "Could not match the error part")
+
+	template.RequestsNetwork[0].Matchers[0].Words = []string{network.TimeoutError}
+	result = executer.ExecuteNetwork(nil, address)
+	require.NotNil(t, result.Error, "Could not return the connection error")
+	require.False(t, result.GotResults, "Could match a timeout on a refused connection")
+}
@@ -0,0 +1,73 @@
+package executer
+
+import (
+	"context"
+	"strings"
+
+	"github.com/projectdiscovery/nuclei/v2/pkg/requests"
+	"github.com/projectdiscovery/retryablehttp-go"
+)
+
+// passiveKey is the key of the contexts of the responses evaluated by the
+// passive mode
+type passiveKey struct{}
+
+// WithPassive returns a context whose requests write their results marked
+// as passive in the json output.
+func WithPassive(ctx context.Context) context.Context {
+	return context.WithValue(ctx, passiveKey{}, true)
+}
+
+// isPassive returns true if the responses of a context are stored ones
+// evaluated by the passive mode
+func isPassive(ctx context.Context) bool {
+	passive, _ := ctx.Value(passiveKey{}).(bool)
+	return passive
+}
+
+// ExecutePassive evaluates the matchers and extractors of the request on a
+// stored response of a target without any network I/O, writing the results
+// marked as passive as for a received response. The request of the results
+// is the first one of the template to the URL of the target.
+func (e *HTTPExecuter) ExecutePassive(URL string, response *HTTPResponse) *Result {
+	result := &Result{
+		Matches:     make(map[string]interface{}),
+		Extractions: make(map[string]interface{}),
+	}
+
+	method, data := e.bulkHttpRequest.Method, ""
+	if len(e.bulkHttpRequest.Raw) > 0 {
+		data = e.bulkHttpRequest.Raw[0]
+		if fields := strings.Fields(data); len(fields) > 0 {
+			method = fields[0]
+		}
+	} else if len(e.bulkHttpRequest.Path) > 0 {
+		data = e.bulkHttpRequest.Path[0]
+	}
+	if method == "" {
+		method = "GET"
+	}
+	req, err := retryablehttp.NewRequest(method, URL, nil)
+	if err != nil {
+		result.Error = err
+		return result
+	}
+	request := &requests.HttpRequest{Request: req, Data: data}
+	response.Response.Request = req.Request
+
+	if response.Metrics == nil {
+		response.Metrics = NewResponseMetrics(response.Response.StatusCode, response.Body, e.regexGuard)
+	}
+	exchange := &httpExchange{
+		resp:     response.Response,
+		body:     response.Body,
+		headers:  response.Headers,
+		duration: response.Duration,
+		remoteIP: response.RemoteIP,
+		metrics:  response.Metrics,
+	}
+	if err := e.handleResponse(WithPassive(context.Background()), URL, request, exchange, make(map[string]interface{}), nil, result); err != nil && err != errInternalMatcher {
+		result.Error = err
+	}
+	return result
+}
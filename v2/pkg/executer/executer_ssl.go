@@ -0,0 +1,148 @@
+package executer
+
+import (
+	"context"
+	"time"
+
+	"github.com/pkg/errors"
+	"github.com/projectdiscovery/gologger"
+	"github.com/projectdiscovery/nuclei/v2/internal/progress"
+	"github.com/projectdiscovery/nuclei/v2/pkg/exclusions"
+	"github.com/projectdiscovery/nuclei/v2/pkg/extractors"
+	"github.com/projectdiscovery/nuclei/v2/pkg/generators"
+	"github.com/projectdiscovery/nuclei/v2/pkg/matchers"
+	"github.com/projectdiscovery/nuclei/v2/pkg/network"
+	"github.com/projectdiscovery/nuclei/v2/pkg/requests"
+	"github.com/projectdiscovery/nuclei/v2/pkg/ssl"
+	"github.com/projectdiscovery/nuclei/v2/pkg/templates"
+)
+
+// SSLExecuter is a client running the tls handshakes of a ssl request of a
+// template with the servers of the targets.
+type SSLExecuter struct {
+	resultSink
+	// timeout is the time the connection and the handshake can take
+	timeout    time.Duration
+	sslRequest *requests.SSLRequest
+}
+
+// SSLOptions contains configuration options for the ssl executer.
+// IncludeRR writes the negotiated values and the certificates in JSON output.
+type SSLOptions struct {
+	CommonOptions
+	Template   *templates.Template
+	SSLRequest *requests.SSLRequest
+	// Timeout is the seconds the connection and the handshake can take
+	Timeout int
+	// Resolved uses the timeout of the options even if the request has its
+	// own, the options being the effective values.
+	Resolved bool
+}
+
+// NewSSLExecuter creates a new ssl executer from a template and a ssl
+// request.
+func NewSSLExecuter(options *SSLOptions) (*SSLExecuter, error) {
+	executer := &SSLExecuter{
+		resultSink: newResultSink("ssl", options.Template, options.SSLRequest.Matchers, &options.CommonOptions),
+		timeout:    requestTimeout(options.Timeout, options.Resolved, options.SSLRequest.Timeout),
+		sslRequest: options.SSLRequest,
+	}
+	return executer, nil
+}
+
+// ExecuteSSL runs the handshakes of the ssl request with a target, a bare
+// host:port or a url.
+func (e *SSLExecuter) ExecuteSSL(p *progress.Progress, target string) Result {
+	return e.ExecuteSSLWithContext(context.Background(), p, target, nil)
+}
+
+// ExecuteSSLWithContext runs the handshakes of the ssl request with a
+// target with values until the context is done, a handshake being run with
+// each address for each combination of the payloads. The errors of the
+// connections and of the handshakes are only returned if no result was
+// found, the matchers of the error part possibly matching them.
+func (e *SSLExecuter) ExecuteSSLWithContext(ctx context.Context, p *progress.Progress, target string, values map[string]interface{}) (result Result) {
+	defer func(start time.Time) {
+		e.benchmark.Run(e.template.ID, time.Since(start))
+	}(time.Now())
+	remaining := e.sslRequest.GetRequestCount()
+	defer func() {
+		if p != nil && remaining > 0 {
+			p.Drop(remaining)
+		}
+	}()
+
+	variables, addresses, err := e.buildAddresses(target, values, e.sslRequest.MakeAddresses)
+	if err != nil {
+		result.Error = err
+		return
+	}
+	return runAddresses(ctx, addresses, e.sslRequest.PayloadValues, func(address string, payloadValues map[string]interface{}) (Result, error) {
+		defer func() { remaining-- }()
+		return e.run(ctx, p, target, address, generators.MergeMaps(variables, payloadValues))
+	})
+}
+
+// run runs a handshake with an address, returning the result of what it
+// negotiated along with the error of the connection or the handshake if
+// any.
+func (e *SSLExecuter) run(ctx context.Context, p *progress.Progress, target, address string, values map[string]interface{}) (result Result, runErr error) {
+	options := e.sslRequest.MakeOptions(target, values, e.timeout)
+
+	if e.debug {
+		e.dump("ssl handshake", address, handshakeString(address, options))
+	}
+
+	e.rateLimiter.Wait(ctx, address)
+	if err := ctx.Err(); err != nil {
+		return result, err
+	}
+
+	start := time.Now()
+	e.stats.Request()
+	resp, err := ssl.Run(ctx, address, options)
+	e.stats.RequestDone()
+	e.benchmark.Request(e.template.ID, address, time.Since(start), err)
+	if p != nil {
+		p.Update()
+	}
+	// the errors of the connection and the handshake are matched, the
+	// other ones stopping the run
+	if err != nil {
+		runErr = errors.Wrapf(err, "could not run ssl handshake for %s", address)
+		if _, ok := err.(*network.Error); !ok {
+			return result, runErr
+		}
+	}
+
+	gologger.Verbosef("Ran ssl handshake with %s\n", "ssl-request", address)
+
+	if e.debug {
+		e.dump("ssl response", address, resp.String())
+	}
+
+	return e.evaluate(ctx, &operators{
+		condition:  e.sslRequest.GetMatchersCondition(),
+		matchers:   e.sslRequest.Matchers,
+		extractors: e.sslRequest.Extractors,
+		match: func(matcher *matchers.Matcher) bool {
+			return matcher.MatchSSL(resp, values)
+		},
+		extract: func(extractor *extractors.Extractor) []string {
+			return extractor.ExtractSSL(resp, values)
+		},
+		target: resp.Address,
+		exclude: func(exclusions *exclusions.Exclusions) int {
+			return exclusions.MatchSSL(e.template.ID, resp)
+		},
+		result: func(matcher *matchers.Matcher, extracted []string) *protocolResult {
+			return e.result(target, options, resp, matcher, extracted)
+		},
+	}), runErr
+}
+
+// handshakeString returns the address and the options of a handshake, one
+// per line
+func handshakeString(address string, options *ssl.Options) string {
+	return "handshake " + address + "\n" + options.String()
+}
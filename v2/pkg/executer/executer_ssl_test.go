@@ -0,0 +1,115 @@
+package executer
+
+import (
+	"bufio"
+	"bytes"
+	"crypto/tls"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/logrusorgru/aurora"
+	"github.com/projectdiscovery/nuclei/v2/pkg/ssl"
+	"github.com/projectdiscovery/nuclei/v2/pkg/stats"
+	"github.com/stretchr/testify/require"
+)
+
+// newLegacyTLSServer returns a server accepting up to tls12 with its
+// self-signed certificate
+func newLegacyTLSServer() *httptest.Server {
+	server := httptest.NewUnstartedServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}))
+	server.TLS = &tls.Config{MaxVersion: tls.VersionTLS12}
+	server.StartTLS()
+	return server
+}
+
+func TestSSLExecuter(t *testing.T) {
+	server := newLegacyTLSServer()
+	defer server.Close()
+
+	template := parseTemplate(t, `
+id: self-signed-tls12
+info:
+  name: self-signed certificate over tls12
+  author: test
+  severity: low
+ssl:
+  - host:
+      - "{{Hostname}}"
+    min-version: "{{version}}"
+    max-version: "{{version}}"
+    payloads:
+      version:
+        - tls13
+        - tls12
+    matchers:
+      - type: dsl
+        dsl:
+          - 'version == "tls12" && self_signed'
+    extractors:
+      - type: kval
+        kval:
+          - issuer_org
+`)
+	output := &bytes.Buffer{}
+	writer := bufio.NewWriter(output)
+	counters := stats.New(1)
+	executer, err := NewSSLExecuter(&SSLOptions{Template: template, SSLRequest: template.RequestsSSL[0], Timeout: 1, CommonOptions: CommonOptions{Writer: writer, JSON: true, JSONRequests: true, Stats: counters, Colorizer: aurora.NewAurora(false)}})
+	require.Nil(t, err, "Could not create ssl executer")
+
+	result := executer.ExecuteSSL(nil, server.URL)
+	require.Nil(t, result.Error, "Could not run the handshakes")
+	require.True(t, result.GotResults, "Could not match the handshake")
+	require.Equal(t, uint64(1), counters.Summary(0, false).Findings, "Could not count the finding")
+	executer.Close()
+
+	address := strings.TrimPrefix(server.URL, "https://")
+	var found ResultEvent
+	require.Nil(t, json.Unmarshal(bytes.TrimSpace(output.Bytes()), &found), "Could not write a single json result")
+	require.Equal(t, "ssl", found.Type, "Could not write the type")
+	require.Equal(t, address, found.Matched, "Could not write the address")
+	require.Equal(t, []string{"Acme Co"}, found.ExtractedResults, "Could not extract the issuer")
+	require.Equal(t, "handshake "+address+"\nmin-version: tls12\nmax-version: tls12\n", found.Request, "Could not write the options of the handshake")
+	require.Equal(t, "tls12", found.TLS.Version, "Could not write the negotiated version")
+	require.Len(t, found.TLS.Certificates, 1, "Could not write the certificates")
+
+	plan, err := executer.PlanSSL(server.URL, nil, 0)
+	require.Nil(t, err, "Could not plan the handshakes")
+	require.Equal(t, int64(2), plan.Total, "Could not count the combinations")
+	require.Equal(t, "SSL", plan.Requests[0].Method, "Could not plan the protocol")
+	require.Equal(t, address, plan.Requests[0].URL, "Could not plan the address")
+}
+
+func TestSSLExecuterRefused(t *testing.T) {
+	server := newLegacyTLSServer()
+	defer server.Close()
+
+	template := parseTemplate(t, `
+id: tls13-only
+info:
+  name: tls13 not supported
+  author: test
+  severity: info
+ssl:
+  - host:
+      - "{{Hostname}}"
+    min-version: tls13
+    matchers:
+      - type: word
+        part: error
+        words:
+          - handshake
+`)
+	executer, err := NewSSLExecuter(&SSLOptions{Template: template, SSLRequest: template.RequestsSSL[0], Timeout: 1, CommonOptions: CommonOptions{Writer: bufio.NewWriter(&bytes.Buffer{}), Colorizer: aurora.NewAurora(false)}})
+	require.Nil(t, err, "Could not create ssl executer")
+	result := executer.ExecuteSSL(nil, server.URL)
+	require.Nil(t, result.Error, "Could not match the refused handshake")
+	require.True(t, result.GotResults, "Could not match the error part")
+
+	template.RequestsSSL[0].Matchers[0].Words = []string{ssl.StartTLSError}
+	result = executer.ExecuteSSL(nil, server.URL)
+	require.NotNil(t, result.Error, "Could not return the handshake error")
+	require.False(t, result.GotResults, "Could match a refused starttls on a refused handshake")
+}
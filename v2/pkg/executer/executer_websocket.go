@@ -0,0 +1,260 @@
+package executer
+
+import (
+	"context"
+	"fmt"
+	"net/url"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/pkg/errors"
+	"github.com/projectdiscovery/gologger"
+	"github.com/projectdiscovery/nuclei/v2/internal/progress"
+	"github.com/projectdiscovery/nuclei/v2/pkg/exclusions"
+	"github.com/projectdiscovery/nuclei/v2/pkg/extractors"
+	"github.com/projectdiscovery/nuclei/v2/pkg/generators"
+	"github.com/projectdiscovery/nuclei/v2/pkg/matchers"
+	"github.com/projectdiscovery/nuclei/v2/pkg/network"
+	"github.com/projectdiscovery/nuclei/v2/pkg/ratelimit"
+	"github.com/projectdiscovery/nuclei/v2/pkg/requests"
+	"github.com/projectdiscovery/nuclei/v2/pkg/templates"
+	"github.com/projectdiscovery/nuclei/v2/pkg/websocket"
+)
+
+// WebsocketExecuter is a client running the messages of a websocket
+// request of a template over upgraded websocket connections.
+type WebsocketExecuter struct {
+	resultSink
+	// timeout is the time the connection, the upgrade and each message
+	// sent can take
+	timeout time.Duration
+	// proxyURL and proxySocksURL are the proxies the connections are made
+	// through if any
+	proxyURL         string
+	proxySocksURL    string
+	websocketRequest *requests.WebsocketRequest
+}
+
+// WebsocketOptions contains configuration options for the websocket executer.
+// IncludeRR writes the upgrade responses and the messages read in JSON output.
+type WebsocketOptions struct {
+	CommonOptions
+	Template         *templates.Template
+	WebsocketRequest *requests.WebsocketRequest
+	// Timeout is the seconds the connection, the upgrade and each message
+	// sent can take
+	Timeout int
+	// Resolved uses the timeout of the options even if the request has its
+	// own, the options being the effective values.
+	Resolved bool
+	// ProxyURL is the url of the http proxy the connections are tunnelled
+	// through if any
+	ProxyURL string
+	// ProxySocksURL is the url of the socks5 proxy the connections are made
+	// through if any
+	ProxySocksURL string
+}
+
+// NewWebsocketExecuter creates a new websocket executer from a template and
+// a websocket request.
+func NewWebsocketExecuter(options *WebsocketOptions) (*WebsocketExecuter, error) {
+	executer := &WebsocketExecuter{
+		resultSink:       newResultSink("websocket", options.Template, options.WebsocketRequest.Matchers, &options.CommonOptions),
+		timeout:          requestTimeout(options.Timeout, options.Resolved, options.WebsocketRequest.Timeout),
+		proxyURL:         options.ProxyURL,
+		proxySocksURL:    options.ProxySocksURL,
+		websocketRequest: options.WebsocketRequest,
+	}
+	return executer, nil
+}
+
+// websocketRun is a connection of a websocket request to one of its urls
+type websocketRun struct {
+	URL      string
+	Headers  map[string]string
+	Messages []*websocket.Message
+}
+
+// ExecuteWebsocket runs the messages of the websocket request towards a
+// base URL.
+func (e *WebsocketExecuter) ExecuteWebsocket(p *progress.Progress, URL string) Result {
+	return e.ExecuteWebsocketWithContext(context.Background(), p, URL, nil)
+}
+
+// ExecuteWebsocketWithContext runs the messages of the websocket request
+// towards a base URL with values until the context is done, a connection
+// being upgraded on each url for each combination of the payloads. The
+// errors of the connections, i.e a refused upgrade, are only returned if no
+// result was found, the matchers of the error part possibly matching them.
+func (e *WebsocketExecuter) ExecuteWebsocketWithContext(ctx context.Context, p *progress.Progress, URL string, values map[string]interface{}) (result Result) {
+	defer func(start time.Time) {
+		e.benchmark.Run(e.template.ID, time.Since(start))
+	}(time.Now())
+	result.Matches = make(map[string]interface{})
+	result.Extractions = make(map[string]interface{})
+	remaining := e.websocketRequest.GetRequestCount()
+	defer func() {
+		if p != nil && remaining > 0 {
+			p.Drop(remaining)
+		}
+	}()
+
+	variables, err := e.buildVariables(URL, values)
+	if err != nil {
+		result.Error = err
+		return
+	}
+
+	var connErr error
+	done := make(chan struct{})
+	defer close(done)
+	for payloadValues := range e.websocketRequest.PayloadValues(done) {
+		runValues := generators.MergeMaps(variables, payloadValues)
+		runs, err := e.buildRuns(URL, runValues)
+		if err != nil {
+			result.Error = err
+			return
+		}
+		for _, run := range runs {
+			found, err := e.run(ctx, p, URL, run, runValues)
+			remaining--
+			if err != nil && ctx.Err() != nil {
+				result.Error = err
+				return
+			}
+			if err != nil && connErr == nil {
+				connErr = err
+			}
+			mergeResult(&result, found)
+		}
+	}
+	if !result.GotResults && connErr != nil {
+		result.Error = connErr
+	}
+	return
+}
+
+// run upgrades a connection to an url and sends the messages, returning
+// the result of the messages read along with the error of the connection
+// if any.
+func (e *WebsocketExecuter) run(ctx context.Context, p *progress.Progress, baseURL string, run *websocketRun, values map[string]interface{}) (result Result, runErr error) {
+	if e.debug {
+		e.dump("websocket request", run.URL, run.String())
+	}
+
+	// the connections are limited like the requests to the host of the url
+	host := run.URL
+	if parsed, err := url.Parse(run.URL); err == nil && parsed.Host != "" {
+		host = ratelimit.HostPort(parsed)
+	}
+	e.rateLimiter.Wait(ctx, host)
+	if err := ctx.Err(); err != nil {
+		return result, err
+	}
+
+	start := time.Now()
+	e.stats.Request()
+	resp, err := websocket.Run(ctx, run.URL, run.Messages, &websocket.Options{
+		Headers:       run.Headers,
+		Timeout:       e.timeout,
+		ReadDuration:  time.Duration(e.websocketRequest.ReadDuration) * time.Second,
+		ReadCount:     e.websocketRequest.ReadCount,
+		ProxyURL:      e.proxyURL,
+		ProxySocksURL: e.proxySocksURL,
+	})
+	e.stats.RequestDone()
+	e.benchmark.Request(e.template.ID, host, time.Since(start), err)
+	if p != nil {
+		p.Update()
+	}
+	// the errors of the connection are matched along with the upgrade
+	// response and the messages read, the other ones stopping the run
+	if err != nil {
+		runErr = errors.Wrapf(err, "could not run websocket request for %s", run.URL)
+		if _, ok := err.(*network.Error); !ok {
+			return result, runErr
+		}
+	}
+
+	gologger.Verbosef("Ran websocket request on %s\n", "websocket-request", resp.URL)
+
+	if e.debug {
+		e.dump("websocket response", resp.URL, resp.Dump())
+	}
+
+	return e.evaluate(ctx, &operators{
+		condition:  e.websocketRequest.GetMatchersCondition(),
+		matchers:   e.websocketRequest.Matchers,
+		extractors: e.websocketRequest.Extractors,
+		match: func(matcher *matchers.Matcher) bool {
+			return matcher.MatchWebsocket(resp, values)
+		},
+		extract: func(extractor *extractors.Extractor) []string {
+			return extractor.ExtractWebsocket(resp, values)
+		},
+		target: resp.URL,
+		exclude: func(exclusions *exclusions.Exclusions) int {
+			return exclusions.MatchWebsocket(e.template.ID, resp)
+		},
+		result: func(matcher *matchers.Matcher, extracted []string) *protocolResult {
+			return e.result(baseURL, run, resp, matcher, extracted)
+		},
+	}), runErr
+}
+
+// buildVariables returns the values of the template for a base URL
+func (e *WebsocketExecuter) buildVariables(URL string, values map[string]interface{}) (map[string]interface{}, error) {
+	targetValues, err := requests.TargetValues(URL)
+	if err != nil {
+		return nil, errors.Wrap(err, "could not parse url")
+	}
+	// The variables of the template are evaluated once per target
+	variables, err := e.template.EvaluateVariables(generators.MergeMaps(values, targetValues))
+	if err != nil {
+		return nil, errors.Wrap(err, "could not evaluate variables")
+	}
+	return generators.MergeMaps(values, variables), nil
+}
+
+// buildRuns builds the connections of the request to each of its urls for
+// a base URL and the values of a combination of the payloads.
+func (e *WebsocketExecuter) buildRuns(URL string, values map[string]interface{}) ([]*websocketRun, error) {
+	urls, err := e.websocketRequest.MakeURLs(URL, values)
+	if err != nil {
+		return nil, errors.Wrap(err, "could not make websocket urls")
+	}
+	headers, err := e.websocketRequest.MakeHeaders(URL, values)
+	if err != nil {
+		return nil, errors.Wrap(err, "could not make websocket headers")
+	}
+	messages, err := e.websocketRequest.MakeMessages(URL, values)
+	if err != nil {
+		return nil, errors.Wrap(err, "could not make websocket messages")
+	}
+
+	runs := make([]*websocketRun, 0, len(urls))
+	for _, runURL := range urls {
+		runs = append(runs, &websocketRun{URL: runURL, Headers: headers, Messages: messages})
+	}
+	return runs, nil
+}
+
+// String returns the upgrade request of a run, its url and its headers in
+// order, followed by the messages it sends, one per line.
+func (r *websocketRun) String() string {
+	builder := &strings.Builder{}
+	builder.WriteString("GET " + r.URL + "\n")
+	names := make([]string, 0, len(r.Headers))
+	for name := range r.Headers {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		fmt.Fprintf(builder, "%s: %s\n", name, r.Headers[name])
+	}
+	for _, message := range r.Messages {
+		builder.WriteString("\n" + message.String())
+	}
+	return builder.String()
+}
@@ -0,0 +1,141 @@
+package executer
+
+import (
+	"bufio"
+	"bytes"
+	"crypto/sha1"
+	"encoding/base64"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/logrusorgru/aurora"
+	"github.com/projectdiscovery/nuclei/v2/pkg/stats"
+	"github.com/stretchr/testify/require"
+)
+
+// newSessionServer returns a server upgrading the connections whatever
+// their origin, answering whoami with the user and the origin of the
+// connection, and refusing the upgrades of /admin.
+func newSessionServer(t *testing.T) *httptest.Server {
+	return httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/admin" {
+			w.WriteHeader(http.StatusForbidden)
+			return
+		}
+		conn, rw, err := w.(http.Hijacker).Hijack()
+		require.Nil(t, err, "Could not hijack the connection")
+		defer conn.Close()
+
+		hash := sha1.Sum([]byte(r.Header.Get("Sec-WebSocket-Key") + "258EAFA5-E914-47DA-95CA-C5AB0DC85B11"))
+		rw.WriteString("HTTP/1.1 101 Switching Protocols\r\nUpgrade: websocket\r\nConnection: Upgrade\r\n")
+		rw.WriteString("Sec-WebSocket-Accept: " + base64.StdEncoding.EncodeToString(hash[:]) + "\r\n\r\n")
+		rw.Flush()
+		for {
+			// the frames of the client are short and masked
+			header := make([]byte, 6)
+			if _, err := io.ReadFull(rw, header); err != nil {
+				return
+			}
+			payload := make([]byte, header[1]&0x7f)
+			if _, err := io.ReadFull(rw, payload); err != nil {
+				return
+			}
+			for i := range payload {
+				payload[i] ^= header[2+i%4]
+			}
+			if header[0]&0x0f == 0x1 && string(payload) == "whoami" {
+				answer := `{"user":"admin","origin":"` + r.Header.Get("Origin") + `"}`
+				conn.Write(append([]byte{0x81, byte(len(answer))}, answer...))
+			}
+		}
+	}))
+}
+
+func TestWebsocketExecuter(t *testing.T) {
+	server := newSessionServer(t)
+	defer server.Close()
+
+	template := parseTemplate(t, `
+id: cswsh
+info:
+  name: cross-site websocket hijacking
+  author: test
+  severity: high
+websocket:
+  - path:
+      - "{{BaseURL}}/socket"
+    headers:
+      Origin: "{{origin}}"
+    payloads:
+      origin:
+        - https://evil.example.com
+    messages:
+      - data: whoami
+    read-count: 1
+    matchers-condition: and
+    matchers:
+      - type: status
+        status:
+          - 101
+      - type: word
+        words:
+          - '"user":"admin"'
+    extractors:
+      - type: json
+        json:
+          - origin
+`)
+	output := &bytes.Buffer{}
+	writer := bufio.NewWriter(output)
+	executer, err := NewWebsocketExecuter(&WebsocketOptions{Template: template, WebsocketRequest: template.RequestsWebsocket[0], Timeout: 1, CommonOptions: CommonOptions{Writer: writer, JSON: true, JSONRequests: true, Stats: stats.New(1), Colorizer: aurora.NewAurora(false)}})
+	require.Nil(t, err, "Could not create websocket executer")
+
+	result := executer.ExecuteWebsocket(nil, server.URL)
+	require.Nil(t, result.Error, "Could not run the websocket request")
+	require.True(t, result.GotResults, "Could not match the hijacked session")
+	executer.Close()
+
+	var found ResultEvent
+	require.Nil(t, json.Unmarshal(bytes.TrimSpace(output.Bytes()), &found), "Could not write a single json result")
+	require.Equal(t, "websocket", found.Type, "Could not write the type")
+	require.Equal(t, "ws://"+strings.TrimPrefix(server.URL, "http://")+"/socket", found.Matched, "Could not write the upgraded url")
+	require.Equal(t, []string{"https://evil.example.com"}, found.ExtractedResults, "Could not extract the origin")
+	require.Contains(t, found.Request, "Origin: https://evil.example.com\n", "Could not write the upgrade request")
+
+	plan, err := executer.PlanWebsocket(server.URL, nil, 0)
+	require.Nil(t, err, "Could not plan the websocket request")
+	require.Equal(t, int64(1), plan.Total, "Could not count the connections")
+	require.Equal(t, server.URL+"/socket", plan.Requests[0].URL, "Could not plan the url")
+	require.Equal(t, "https://evil.example.com", plan.Requests[0].Headers["Origin"], "Could not plan the headers")
+}
+
+func TestWebsocketExecuterRefused(t *testing.T) {
+	server := newSessionServer(t)
+	defer server.Close()
+
+	template := parseTemplate(t, `
+id: admin-socket
+info:
+  name: admin socket
+  author: test
+  severity: info
+websocket:
+  - path:
+      - "{{BaseURL}}/admin"
+    matchers:
+      - type: status
+        status:
+          - 101
+`)
+	executer, err := NewWebsocketExecuter(&WebsocketOptions{Template: template, WebsocketRequest: template.RequestsWebsocket[0], Timeout: 1, CommonOptions: CommonOptions{Writer: bufio.NewWriter(&bytes.Buffer{}), Colorizer: aurora.NewAurora(false)}})
+	require.Nil(t, err, "Could not create websocket executer")
+
+	result := executer.ExecuteWebsocket(nil, server.URL)
+	require.False(t, result.GotResults, "Could match the refused upgrade")
+	require.NotNil(t, result.Error, "Could not return the refused upgrade")
+	require.Contains(t, result.Error.Error(), "upgrade refused with status 403", "Could not describe the refused upgrade")
+}
@@ -0,0 +1,68 @@
+package executer
+
+import (
+	"bytes"
+	"io"
+	"strings"
+	"sync"
+
+	"github.com/projectdiscovery/nuclei/v2/pkg/regexguard"
+)
+
+// bodyBuffers are the buffers the bodies of the responses are read into,
+// reused once the responses are evaluated.
+var bodyBuffers = sync.Pool{
+	New: func() interface{} {
+		return new(bytes.Buffer)
+	},
+}
+
+// maxPooledBody is the capacity above which a buffer is not reused, the
+// default maximum length of the matched inputs, so the pool shared by the
+// scans doesn't keep the rare larger bodies in memory.
+const maxPooledBody = regexguard.DefaultMaxSize
+
+// readBody reads a body into a pooled buffer, which is released with
+// releaseBody once nothing references its bytes anymore.
+func readBody(reader io.Reader) (*bytes.Buffer, error) {
+	buffer := bodyBuffers.Get().(*bytes.Buffer)
+	if _, err := buffer.ReadFrom(reader); err != nil {
+		releaseBody(buffer)
+		return nil, err
+	}
+	return buffer, nil
+}
+
+// releaseBody returns a buffer read by readBody to the pool, doing
+// nothing for a nil buffer.
+func releaseBody(buffer *bytes.Buffer) {
+	if buffer == nil || buffer.Cap() > maxPooledBody {
+		return
+	}
+	buffer.Reset()
+	bodyBuffers.Put(buffer)
+}
+
+// cloneString returns a copy of a string, the substrings of a body read in
+// a pooled buffer being cloned to be kept once the buffer is reused.
+func cloneString(value string) string {
+	if value == "" {
+		return ""
+	}
+	var builder strings.Builder
+	builder.Grow(len(value))
+	builder.WriteString(value)
+	return builder.String()
+}
+
+// cloneStrings returns a copy of the strings, nil for nil
+func cloneStrings(values []string) []string {
+	if values == nil {
+		return nil
+	}
+	cloned := make([]string, len(values))
+	for i, value := range values {
+		cloned[i] = cloneString(value)
+	}
+	return cloned
+}
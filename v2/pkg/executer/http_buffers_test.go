@@ -0,0 +1,160 @@
+package executer
+
+import (
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/logrusorgru/aurora"
+	"github.com/stretchr/testify/require"
+)
+
+func TestPooledBodies(t *testing.T) {
+	padding := strings.Repeat("x", 4096)
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if strings.HasSuffix(r.URL.Path, "/echo") {
+			fmt.Fprintf(w, "echo=%s %s", r.URL.Query().Get("value"), padding)
+			return
+		}
+		fmt.Fprintf(w, "token=%s %s", strings.TrimPrefix(r.URL.Path, "/"), padding)
+	}))
+	defer server.Close()
+
+	template := parseTemplate(t, `
+id: pooled-bodies
+info:
+  name: pooled bodies
+  author: test
+requests:
+  - method: GET
+    path:
+      - "{{BaseURL}}"
+      - "{{BaseURL}}/echo?value={{token}}"
+    extractors:
+      - type: regex
+        name: token
+        group: 1
+        regex:
+          - "token=([0-9a-z-]+)"
+      - type: regex
+        name: echo
+        group: 1
+        regex:
+          - "echo=([0-9a-z-]+)"
+`)
+	executer, err := NewHTTPExecuter(&HTTPOptions{Template: template, BulkHttpRequest: template.BulkRequestsHTTP[0], Timeout: 5, Colorizer: aurora.NewAurora(false)})
+	require.Nil(t, err, "Could not create http executer")
+
+	var wg sync.WaitGroup
+	for i := 0; i < 50; i++ {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			token := fmt.Sprintf("token-%d", i)
+			result := executer.ExecuteHTTP(nil, server.URL+"/"+token)
+			require.Nil(t, result.Error, "Could not execute http requests")
+			require.Equal(t, []string{token}, result.Extractions["token"], "Could not keep the extracted values once the buffer is reused")
+			require.Equal(t, []string{token}, result.Extractions["echo"], "Could not use the extracted values in the next request")
+		}(i)
+	}
+	wg.Wait()
+}
+
+func TestReleaseBody(t *testing.T) {
+	buffer, err := readBody(strings.NewReader("body"))
+	require.Nil(t, err, "Could not read the body")
+	require.Equal(t, "body", buffer.String(), "Could not read the body into the buffer")
+	releaseBody(buffer)
+	require.Zero(t, buffer.Len(), "Could not reset the released buffer")
+	releaseBody(nil)
+
+	large, err := readBody(strings.NewReader(strings.Repeat("x", maxPooledBody+1)))
+	require.Nil(t, err, "Could not read the large body")
+	releaseBody(large)
+	require.NotZero(t, large.Len(), "Could not keep the large buffer out of the pool")
+}
+
+// matcherHeavyTemplate is a template of the benchmarks evaluating many
+// matchers and extractors on each response, none of them matching.
+const matcherHeavyTemplate = `
+id: matcher-heavy
+info:
+  name: matcher heavy
+  author: test
+requests:
+  - method: GET
+    path:
+      - "{{BaseURL}}"
+    matchers:
+      - type: status
+        status:
+          - 500
+      - type: word
+        words: ["phpinfo()", "wp-content", "x-powered-by: asp", "jenkins", "grafana"]
+      - type: word
+        case-insensitive: true
+        words: ["PHPMYADMIN", "Apache Tomcat", "Index of /"]
+      - type: word
+        part: header
+        case-insensitive: true
+        words: ["X-Jenkins"]
+      - type: regex
+        regex:
+          - "root:[x*]:0:0:"
+          - "(?m)^\\[core\\]$"
+          - "AKIA[0-9A-Z]{16}"
+      - type: binary
+        binary:
+          - "504b0304"
+          - "89504e47"
+      - type: dsl
+        dsl:
+          - "status_code == 500 && contains(body, 'stack trace')"
+    extractors:
+      - type: regex
+        group: 1
+        regex:
+          - "<meta name=\"generator\" content=\"([^\"]*)\""
+`
+
+// benchmarkBody is the body of the responses of the benchmarks, 64KB of html
+var benchmarkBody = "<html><head><title>benchmark</title></head><body>" + strings.Repeat("<p>Lorem ipsum dolor sit amet, consectetur adipiscing elit, sed do eiusmod.</p>\n", 800) + "</body></html>"
+
+// BenchmarkEvaluateHTTP evaluates the matchers of a matcher heavy template
+// on a response already read.
+func BenchmarkEvaluateHTTP(b *testing.B) {
+	template := parseTemplate(b, matcherHeavyTemplate)
+	response, err := ReadHTTPResponse([]byte("HTTP/1.1 200 OK\nServer: nginx\nContent-Type: text/html\n\n" + benchmarkBody))
+	require.Nil(b, err, "Could not read the response")
+
+	b.ReportAllocs()
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		EvaluateHTTP(template.BulkRequestsHTTP[0], response, make(map[string]interface{}))
+	}
+}
+
+// BenchmarkExecuteHTTP sends the request of a matcher heavy template and
+// reads and evaluates its response.
+func BenchmarkExecuteHTTP(b *testing.B) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		fmt.Fprint(w, benchmarkBody)
+	}))
+	defer server.Close()
+
+	template := parseTemplate(b, matcherHeavyTemplate)
+	executer, err := NewHTTPExecuter(&HTTPOptions{Template: template, BulkHttpRequest: template.BulkRequestsHTTP[0], Timeout: 5, Colorizer: aurora.NewAurora(false)})
+	require.Nil(b, err, "Could not create http executer")
+
+	b.ReportAllocs()
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		// the generator of a target is kept once done
+		if result := executer.ExecuteHTTP(nil, fmt.Sprintf("%s/?%d", server.URL, i)); result.Error != nil {
+			b.Fatal(result.Error)
+		}
+	}
+}
@@ -0,0 +1,143 @@
+package executer
+
+import (
+	"bytes"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"net/http/httputil"
+	"os"
+	"sort"
+	"strings"
+
+	"github.com/pkg/errors"
+	"github.com/projectdiscovery/gologger"
+	"github.com/projectdiscovery/nuclei/v2/pkg/project"
+	"github.com/projectdiscovery/nuclei/v2/pkg/requests"
+)
+
+// cacheable returns true if the responses of the requests of an executer
+// can be reused by the next runs: the requests reusing cookies depend on
+// the previous responses, the requests with random values are never the
+// same once built unless keyed by the requests of the template, and the
+// requests using {{interactsh-url}} have to reach the targets to interact.
+func cacheable(options *HTTPOptions) bool {
+	if options.Project == nil || options.CookieReuse || options.CookieJar != nil || options.BulkHttpRequest.UsesInteractshURL() {
+		return false
+	}
+	if options.TemplateKeys {
+		return true
+	}
+	return !options.BulkHttpRequest.HasRandomValues() && !options.Template.HasRandomVariables()
+}
+
+// cacheKey returns the key of the response of a request to a target in the
+// cache of the project, empty if the request isn't cached. The key is the
+// hash of the built request, its method, URL, headers and body, or of the
+// request of the template along with the values it is built with.
+func (e *HTTPExecuter) cacheKey(URL string, request *requests.HttpRequest, dynamicvalues map[string]interface{}) string {
+	if e.project == nil || !e.cacheable {
+		return ""
+	}
+	if e.templateKeys {
+		return e.templateKey(URL, request, dynamicvalues)
+	}
+
+	body, err := request.Request.BodyBytes()
+	if err != nil {
+		return ""
+	}
+	req := request.Request.Request
+	return project.Key("built", req.Method, req.URL.String(), req.Host, headerLines(req.Header), string(body))
+}
+
+// templateKey returns the key of a request before the replacement of the
+// values, the request of the template along with the values of the target
+// and the definitions of the variables, the random values of the built
+// request being ignored.
+func (e *HTTPExecuter) templateKey(URL string, request *requests.HttpRequest, dynamicvalues map[string]interface{}) string {
+	values := make(map[string]interface{}, len(dynamicvalues)+len(request.Meta))
+	for name, value := range dynamicvalues {
+		// the values of the variables are replaced with their definitions
+		if _, ok := e.template.Variables[name]; !ok {
+			values[name] = value
+		}
+	}
+	for name, value := range request.Meta {
+		values[name] = value
+	}
+	// the fuzzed requests differ by the parameter of their payload too
+	if request.Fuzzing != nil {
+		values["fuzzing"] = fmt.Sprintf("%s:%d", request.Fuzzing.Part, request.Fuzzing.Position)
+	}
+	definitions := make(map[string]interface{}, len(e.template.Variables))
+	for name, definition := range e.template.Variables {
+		definitions[name] = definition
+	}
+
+	headers := make(http.Header)
+	for name, value := range e.bulkHttpRequest.Headers {
+		headers.Set(name, value)
+	}
+	return project.Key("template", URL, request.Data, e.bulkHttpRequest.Method, headerLines(headers), e.bulkHttpRequest.Body, strings.Join(e.customHeaders, "\n"), strings.Join(e.forcedHeaders, "\n"), valueLines(definitions), valueLines(values))
+}
+
+// headerLines returns the headers as sorted lines
+func headerLines(header http.Header) string {
+	lines := make([]string, 0, len(header))
+	for name, values := range header {
+		lines = append(lines, name+": "+strings.Join(values, ", "))
+	}
+	sort.Strings(lines)
+	return strings.Join(lines, "\n")
+}
+
+// valueLines returns the values as sorted lines
+func valueLines(values map[string]interface{}) string {
+	lines := make([]string, 0, len(values))
+	for name, value := range values {
+		lines = append(lines, fmt.Sprintf("%s=%v", name, value))
+	}
+	sort.Strings(lines)
+	return strings.Join(lines, "\n")
+}
+
+// cachedExchange returns the response of a request cached by the project
+// as if it was just received.
+func (e *HTTPExecuter) cachedExchange(URL string, req *http.Request, cached *project.Response) (*httpExchange, error) {
+	resp := &http.Response{
+		Status:        fmt.Sprintf("%d %s", cached.StatusCode, http.StatusText(cached.StatusCode)),
+		StatusCode:    cached.StatusCode,
+		Proto:         cached.Proto,
+		Header:        cached.Header,
+		Body:          ioutil.NopCloser(bytes.NewReader(cached.Body)),
+		ContentLength: int64(len(cached.Body)),
+		Request:       req,
+	}
+	if resp.Header == nil {
+		resp.Header = make(http.Header)
+	}
+	var ok bool
+	if resp.ProtoMajor, resp.ProtoMinor, ok = http.ParseHTTPVersion(cached.Proto); !ok {
+		resp.Proto, resp.ProtoMajor, resp.ProtoMinor = "HTTP/1.1", 1, 1
+	}
+
+	if e.debug {
+		dumpedResponse, err := httputil.DumpResponse(resp, true)
+		if err != nil {
+			return nil, errors.Wrap(err, "could not dump http response")
+		}
+		gologger.Infof("Dumped cached HTTP response for %s (%s)\n\n", URL, e.template.ID)
+		fmt.Fprintf(os.Stderr, "%s\n", e.redact(string(dumpedResponse)))
+	}
+
+	body := string(cached.Body)
+	return &httpExchange{
+		resp:     resp,
+		body:     body,
+		headers:  headersToString(resp.Header),
+		duration: cached.Duration,
+		remoteIP: cached.RemoteIP,
+		metrics:  NewResponseMetrics(resp.StatusCode, body, e.regexGuard),
+	}, nil
+}
@@ -0,0 +1,134 @@
+package executer
+
+import (
+	"context"
+	"strings"
+
+	"github.com/projectdiscovery/gologger"
+	"github.com/projectdiscovery/nuclei/v2/internal/progress"
+	"github.com/projectdiscovery/nuclei/v2/pkg/fuzzing"
+	"github.com/projectdiscovery/nuclei/v2/pkg/requests"
+)
+
+// fuzzedParameter is a parameter of a built request fuzzed by a rule
+type fuzzedParameter struct {
+	rule      *fuzzing.Rule
+	parameter *fuzzing.Parameter
+}
+
+// handleRequest sends a built request, or the requests fuzzing it if the
+// request has fuzzing rules.
+func (e *HTTPExecuter) handleRequest(ctx context.Context, p *progress.Progress, URL string, request *requests.HttpRequest, dynamicvalues, responses map[string]interface{}, result *Result) error {
+	if len(e.bulkHttpRequest.Fuzzing) > 0 {
+		return e.handleFuzzing(ctx, p, URL, request, dynamicvalues, responses, result)
+	}
+	return e.handleHTTP(ctx, p, URL, request, dynamicvalues, responses, result)
+}
+
+// handleFuzzing sends the requests fuzzing a built request, once per
+// parameter and payload of its rules, each of them writing its own results
+// with the payload as its value. The request isn't sent without parameters
+// to fuzz, and fails if all the fuzzed requests fail, with the error of the
+// last one.
+func (e *HTTPExecuter) handleFuzzing(ctx context.Context, p *progress.Progress, URL string, request *requests.HttpRequest, dynamicvalues, responses map[string]interface{}, result *Result) error {
+	parameters, count, err := e.fuzzedParameters(request)
+	if err != nil {
+		return err
+	}
+	if count == 0 {
+		gologger.Debugf("[%s] Skipping request %d to %s, no parameter to fuzz\n", e.template.ID, e.bulkHttpRequest.Position(URL)+1, URL)
+		return nil
+	}
+	// the fuzzed requests are added to the request counted once by the progress
+	if p != nil {
+		p.AddToTotal(int64(count - 1))
+	}
+
+	var sent, failed int
+	var lastErr error
+	err = e.fuzz(request, parameters, func(fuzzed *requests.HttpRequest) bool {
+		// the requests to a dead host are skipped by the next request
+		if result.Done || ctx.Err() != nil || e.hostErrors.Dead(URL) {
+			return false
+		}
+		name := fuzzed.Fuzzing.PayloadName
+		previous, hadPrevious := dynamicvalues[name]
+		dynamicvalues[name] = fuzzed.Fuzzing.Payload
+		err := e.handleHTTP(ctx, p, URL, fuzzed, dynamicvalues, responses, result)
+		if hadPrevious {
+			dynamicvalues[name] = previous
+		} else {
+			delete(dynamicvalues, name)
+		}
+		sent++
+		if err != nil {
+			failed++
+			lastErr = err
+			if err != errInternalMatcher {
+				gologger.Warningf("[%s] Could not fuzz %s towards %s: %s\n", e.template.ID, fuzzed.Fuzzing, URL, err)
+			}
+		}
+		// the last fuzzed request is counted along with the request
+		if p != nil && sent < count {
+			p.Update()
+		}
+		return true
+	})
+	// the fuzzed requests left are dropped, the request being counted once
+	if p != nil && sent < count-1 {
+		p.Drop(int64(count - 1 - sent))
+	}
+	if err != nil {
+		return err
+	}
+	if failed > 0 && failed == sent {
+		return lastErr
+	}
+	return nil
+}
+
+// fuzzedParameters returns the parameters of a built request fuzzed by the
+// rules in order, along with the number of requests fuzzing them.
+func (e *HTTPExecuter) fuzzedParameters(request *requests.HttpRequest) ([]fuzzedParameter, int, error) {
+	body, err := request.Request.BodyBytes()
+	if err != nil {
+		return nil, 0, err
+	}
+	var parameters []fuzzedParameter
+	var count int
+	for _, rule := range e.bulkHttpRequest.Fuzzing {
+		for _, parameter := range rule.Parameters(request.Request.Request, body) {
+			parameters = append(parameters, fuzzedParameter{rule: rule, parameter: parameter})
+			count += len(rule.Values())
+		}
+	}
+	return parameters, count, nil
+}
+
+// fuzz builds the requests fuzzing the parameters of a built request in
+// turn, passing each of them to fuzzed until it returns false. The payloads
+// using {{interactsh-url}} get a new url of the interactsh server for each
+// fuzzed request, the placeholder being kept as is without a client, i.e in
+// a dry run.
+func (e *HTTPExecuter) fuzz(request *requests.HttpRequest, parameters []fuzzedParameter, fuzzed func(*requests.HttpRequest) bool) error {
+	placeholder := "{{" + requests.InteractshURLName + "}}"
+	for _, fuzzedParameter := range parameters {
+		for _, payload := range fuzzedParameter.rule.Values() {
+			var values map[string]interface{}
+			var interactshURL string
+			if e.interactsh != nil && strings.Contains(payload.Value, placeholder) {
+				interactshURL = e.interactsh.URL()
+				values = map[string]interface{}{requests.InteractshURLName: interactshURL}
+			}
+			mutated, err := request.Fuzz(fuzzedParameter.rule, fuzzedParameter.parameter, payload, values)
+			if err != nil {
+				return err
+			}
+			mutated.InteractshURL = interactshURL
+			if !fuzzed(mutated) {
+				return nil
+			}
+		}
+	}
+	return nil
+}
@@ -0,0 +1,85 @@
+package executer
+
+import (
+	"context"
+
+	"github.com/projectdiscovery/nuclei/v2/pkg/generators"
+	"github.com/projectdiscovery/nuclei/v2/pkg/interactsh"
+	"github.com/projectdiscovery/nuclei/v2/pkg/matchers"
+	"github.com/projectdiscovery/nuclei/v2/pkg/requests"
+)
+
+// interactionKey is the key of the contexts of the results matched by an
+// interaction with the interactsh server
+type interactionKey struct{}
+
+// withInteraction returns a context whose results are written along with
+// the interaction they matched.
+func withInteraction(ctx context.Context, interaction *interactsh.Interaction) context.Context {
+	return context.WithValue(ctx, interactionKey{}, interaction)
+}
+
+// interactionOf returns the interaction matched by the results of a
+// context, nil if none.
+func interactionOf(ctx context.Context) *interactsh.Interaction {
+	interaction, _ := ctx.Value(interactionKey{}).(*interactsh.Interaction)
+	return interaction
+}
+
+// interactshValues returns the values a request is built with, a new url
+// of the interactsh server as {{interactsh-url}} if the request uses it.
+// The placeholder is kept as is without a client, i.e in a dry run.
+func (e *HTTPExecuter) interactshValues(dynamicvalues map[string]interface{}) (map[string]interface{}, string) {
+	if e.interactsh == nil || !e.interactshURL {
+		return dynamicvalues, ""
+	}
+	URL := e.interactsh.URL()
+	return generators.MergeMaps(dynamicvalues, map[string]interface{}{requests.InteractshURLName: URL}), URL
+}
+
+// awaitInteractions evaluates the matchers of a request once more with
+// each interaction with its interactsh url, which may arrive well after
+// the response, until the matchers using the interactions match. The
+// response is kept until then, its body being copied out of its buffer.
+func (e *HTTPExecuter) awaitInteractions(ctx context.Context, URL string, request *requests.HttpRequest, exchange *httpExchange, baseline *matchers.Baseline, position int, dynamicvalues map[string]interface{}) {
+	response := &HTTPResponse{
+		Response: exchange.resp,
+		Body:     cloneString(exchange.body),
+		Headers:  exchange.headers,
+		Duration: exchange.duration,
+		RemoteIP: exchange.remoteIP,
+		Baseline: baseline,
+		Position: position,
+		Metrics:  exchange.metrics,
+	}
+	values := generators.MergeMaps(dynamicvalues, nil)
+	e.interactsh.Await(request.InteractshURL, func(interaction *interactsh.Interaction) bool {
+		evaluation := EvaluateHTTP(e.bulkHttpRequest, response, generators.MergeMaps(values, interaction.Values()))
+		if evaluation.InternalFailed || evaluation.ANDFailed {
+			return false
+		}
+		// the matchers not using the interactions wrote their results
+		// with the response already
+		var matched []*matchers.Matcher
+		for _, matcher := range distinctMatchers(evaluation.Matched) {
+			if matcher.UsesInteractsh() {
+				matched = append(matched, matcher)
+			}
+		}
+		if len(matched) == 0 && !evaluation.ANDMatched {
+			return false
+		}
+		if e.isSuppressed(URL, response.Response, response.Body, response.Headers, response.Duration, response.RemoteIP) {
+			return true
+		}
+
+		ctx := withInteraction(ctx, interaction)
+		for _, matcher := range matched {
+			e.writeOutputHTTP(ctx, request, response.Response, response.Body, response.Duration, response.Metrics, matcher, nil)
+		}
+		if evaluation.ANDMatched {
+			e.writeOutputHTTP(ctx, request, response.Response, response.Body, response.Duration, response.Metrics, nil, evaluation.OutputValues)
+		}
+		return true
+	})
+}
@@ -0,0 +1,145 @@
+package executer
+
+import (
+	"bufio"
+	"bytes"
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/sha256"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/json"
+	"encoding/pem"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/logrusorgru/aurora"
+	"github.com/projectdiscovery/nuclei/v2/pkg/interactsh"
+	"github.com/projectdiscovery/nuclei/v2/pkg/stats"
+	"github.com/stretchr/testify/require"
+)
+
+// interactshServer is an interactsh server whose interactions are the ones
+// queued by the tests, encrypted for the registered client.
+type interactshServer struct {
+	*httptest.Server
+	mutex   sync.Mutex
+	key     *rsa.PublicKey
+	pending [][]byte
+}
+
+func newInteractshServer(t *testing.T) *interactshServer {
+	s := &interactshServer{}
+	s.Server = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		s.mutex.Lock()
+		defer s.mutex.Unlock()
+		switch r.URL.Path {
+		case "/register":
+			var body map[string]string
+			json.NewDecoder(r.Body).Decode(&body)
+			encoded, _ := base64.StdEncoding.DecodeString(body["public-key"])
+			block, _ := pem.Decode(encoded)
+			require.NotNil(t, block, "Could not decode the public key")
+			key, err := x509.ParsePKIXPublicKey(block.Bytes)
+			require.Nil(t, err, "Could not parse the public key")
+			s.key = key.(*rsa.PublicKey)
+		case "/poll":
+			aesKey := make([]byte, 32)
+			rand.Read(aesKey)
+			encryptedKey, _ := rsa.EncryptOAEP(sha256.New(), rand.Reader, s.key, aesKey, nil)
+			block, _ := aes.NewCipher(aesKey)
+			data := []string{}
+			for _, interaction := range s.pending {
+				encrypted := make([]byte, aes.BlockSize+len(interaction))
+				rand.Read(encrypted[:aes.BlockSize])
+				cipher.NewCFBEncrypter(block, encrypted[:aes.BlockSize]).XORKeyStream(encrypted[aes.BlockSize:], interaction)
+				data = append(data, base64.StdEncoding.EncodeToString(encrypted))
+			}
+			s.pending = nil
+			json.NewEncoder(w).Encode(map[string]interface{}{"data": data, "aes_key": base64.StdEncoding.EncodeToString(encryptedKey)})
+		}
+	}))
+	return s
+}
+
+// interact queues an interaction with the host of a url of the server
+func (s *interactshServer) interact(host, protocol string) {
+	uniqueID := strings.SplitN(host, ".", 2)[0]
+	data, _ := json.Marshal(&interactsh.Interaction{Protocol: protocol, UniqueID: uniqueID, FullID: uniqueID, RawRequest: protocol + " from target", Timestamp: time.Now()})
+	s.mutex.Lock()
+	s.pending = append(s.pending, data)
+	s.mutex.Unlock()
+}
+
+func TestInteractshMatchers(t *testing.T) {
+	oob := newInteractshServer(t)
+	defer oob.Close()
+	// the target resolves the urls it is given, only fetching the ones of b
+	hosts := make(map[string]struct{})
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		callback, err := url.Parse(r.URL.Query().Get("url"))
+		require.Nil(t, err, "Could not parse the callback")
+		hosts[callback.Host] = struct{}{}
+		oob.interact(callback.Host, "dns")
+		if callback.Path == "/b" {
+			oob.interact(callback.Host, "http")
+		}
+	}))
+	defer server.Close()
+
+	template := parseTemplate(t, `
+id: blind-ssrf
+info:
+  name: blind ssrf
+  author: test
+  severity: high
+requests:
+  - raw:
+      - |
+        GET /fetch?url=http://{{interactsh-url}}/{{path}} HTTP/1.1
+        Host: {{Hostname}}
+
+    payloads:
+      path:
+        - a
+        - b
+    matchers-condition: and
+    matchers:
+      - type: word
+        part: interactsh_protocol
+        words:
+          - http
+      - type: status
+        status:
+          - 200
+`)
+	client, err := interactsh.New(interactsh.Options{Server: oob.URL, PollInterval: 10 * time.Millisecond})
+	require.Nil(t, err, "Could not register the interactsh client")
+	output := &bytes.Buffer{}
+	writer := bufio.NewWriter(output)
+	counters := stats.New(1)
+	executer, err := NewHTTPExecuter(&HTTPOptions{Template: template, BulkHttpRequest: template.BulkRequestsHTTP[0], Writer: writer, JSON: true, Timeout: 5, Stats: counters, Interactsh: client, Colorizer: aurora.NewAurora(false)})
+	require.Nil(t, err, "Could not create http executer")
+
+	result := executer.ExecuteHTTP(nil, server.URL)
+	require.False(t, result.GotResults, "Could match before the interactions")
+	require.Len(t, hosts, 2, "Could not hand out a url for each request")
+	require.Eventually(t, func() bool { return counters.Summary(0, false).Findings == 1 }, 5*time.Second, 10*time.Millisecond, "Could not match the interaction")
+	require.Nil(t, client.Close(0), "Could not close the interactsh client")
+
+	var found ResultEvent
+	require.Nil(t, json.Unmarshal(bytes.TrimSpace(output.Bytes()), &found), "Could not write a single json result")
+	require.Equal(t, "blind-ssrf", found.Template, "Could not correlate the template")
+	require.True(t, strings.HasPrefix(found.Matched, server.URL+"/fetch?url=http://"), "Could not correlate the target")
+	require.True(t, strings.HasSuffix(found.Matched, "/b"), "Could not correlate the payload values")
+	require.NotNil(t, found.Interaction, "Could not write the interaction")
+	require.Equal(t, "http", found.Interaction.Protocol, "Could not write the matched interaction")
+	require.Equal(t, uint64(3), client.Interactions(), "Could not poll all the interactions")
+}
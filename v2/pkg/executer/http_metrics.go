@@ -0,0 +1,79 @@
+package executer
+
+import (
+	"unicode"
+	"unicode/utf8"
+
+	"github.com/projectdiscovery/nuclei/v2/pkg/regexguard"
+)
+
+// ResponseMetrics are the metrics of an http response, computed once for
+// the dsl expressions and the results of the response.
+type ResponseMetrics struct {
+	// StatusCode is the status of the final response, after the redirects
+	StatusCode int `json:"status_code"`
+	// BodyLength is the length in bytes of the decompressed body
+	BodyLength int `json:"body_length"`
+	// BodyWords and BodyLines are counted on the body up to the maximum
+	// length of the regex inputs, BodyTruncated being true if it is longer.
+	BodyWords     int  `json:"body_words"`
+	BodyLines     int  `json:"body_lines"`
+	BodyTruncated bool `json:"body_truncated,omitempty"`
+}
+
+// NewResponseMetrics returns the metrics of a response with a status and a
+// decompressed body, the body being counted up to the maximum length of the
+// regex inputs of a guard, the default one if nil.
+//
+// The words are the runs of characters separated by unicode white space,
+// like wc, so the scripts without spaces between the words such as chinese
+// count as one word per run. The invalid utf-8 bytes are word characters.
+// The lines are the newline terminated lines along with the last line if
+// it is not terminated. These are the counts of the word_count and
+// line_count helpers, without scanning the body again for each expression.
+func NewResponseMetrics(statusCode int, body string, guard *regexguard.Guard) *ResponseMetrics {
+	metrics := &ResponseMetrics{StatusCode: statusCode, BodyLength: len(body)}
+	if maxSize := guard.MaxSize(); maxSize > 0 && len(body) > maxSize {
+		// the cut rune at the end of the body is ignored
+		end := maxSize
+		for end > 0 && !utf8.RuneStart(body[end]) {
+			end--
+		}
+		body = body[:end]
+		metrics.BodyTruncated = true
+	}
+
+	inWord := false
+	for i := 0; i < len(body); {
+		r, size := rune(body[i]), 1
+		if r >= utf8.RuneSelf {
+			r, size = utf8.DecodeRuneInString(body[i:])
+		}
+		i += size
+
+		if r == '\n' {
+			metrics.BodyLines++
+		}
+		if unicode.IsSpace(r) {
+			inWord = false
+		} else if !inWord {
+			inWord = true
+			metrics.BodyWords++
+		}
+	}
+	if len(body) > 0 && body[len(body)-1] != '\n' {
+		metrics.BodyLines++
+	}
+	return metrics
+}
+
+// values returns the variables of the metrics available to the dsl
+// expressions.
+func (m *ResponseMetrics) values() map[string]interface{} {
+	return map[string]interface{}{
+		"body_length":    m.BodyLength,
+		"body_words":     m.BodyWords,
+		"body_lines":     m.BodyLines,
+		"body_truncated": m.BodyTruncated,
+	}
+}
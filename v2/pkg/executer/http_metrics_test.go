@@ -0,0 +1,42 @@
+package executer
+
+import (
+	"testing"
+
+	"github.com/projectdiscovery/nuclei/v2/pkg/regexguard"
+	"github.com/stretchr/testify/require"
+)
+
+func TestResponseMetrics(t *testing.T) {
+	tests := []struct {
+		name  string
+		body  string
+		words int
+		lines int
+	}{
+		{name: "empty body", body: "", words: 0, lines: 0},
+		{name: "terminated lines", body: "hello world\nsecond line\n", words: 4, lines: 2},
+		{name: "unterminated line", body: "hello\r\nworld", words: 2, lines: 2},
+		{name: "accented words", body: "héllo wörld çà", words: 3, lines: 1},
+		{name: "no-break and ideographic spaces", body: "東京　大阪 名古屋", words: 3, lines: 1},
+		{name: "words without spaces", body: "こんにちは世界", words: 1, lines: 1},
+		{name: "emojis", body: "👋 🌍\t🚀", words: 3, lines: 1},
+		{name: "invalid utf-8", body: "\xff\xfe \x80", words: 2, lines: 1},
+		{name: "blank lines", body: "\n\n  \n", words: 0, lines: 3},
+	}
+	for _, test := range tests {
+		metrics := NewResponseMetrics(200, test.body, nil)
+		require.Equal(t, len(test.body), metrics.BodyLength, "Could not measure the length of %s", test.name)
+		require.Equal(t, test.words, metrics.BodyWords, "Could not count the words of %s", test.name)
+		require.Equal(t, test.lines, metrics.BodyLines, "Could not count the lines of %s", test.name)
+		require.False(t, metrics.BodyTruncated, "Could not count the whole body of %s", test.name)
+	}
+
+	// the cap cuts the second byte of é, which is not counted as a word
+	metrics := NewResponseMetrics(404, "one two é three\nfour", regexguard.New(9))
+	require.Equal(t, 21, metrics.BodyLength, "Could not measure the length of the whole body")
+	require.Equal(t, 2, metrics.BodyWords, "Could not count the words of the truncated body")
+	require.Equal(t, 1, metrics.BodyLines, "Could not count the lines of the truncated body")
+	require.True(t, metrics.BodyTruncated, "Could not flag the truncated body")
+	require.Equal(t, 404, metrics.StatusCode, "Could not keep the status")
+}
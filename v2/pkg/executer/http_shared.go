@@ -0,0 +1,123 @@
+package executer
+
+import (
+	"context"
+	"net/http"
+	"strconv"
+	"sync"
+
+	"github.com/pkg/errors"
+	"github.com/projectdiscovery/nuclei/v2/pkg/hosterrors"
+	"github.com/projectdiscovery/nuclei/v2/pkg/project"
+	"github.com/projectdiscovery/nuclei/v2/pkg/requests"
+)
+
+// maxSharedBytes is the total size of the bodies of the shared responses
+// kept, the next ones not being shared.
+const maxSharedBytes = 64 << 20
+
+// SharedResponses are the responses of the baseline requests of
+// -tech-detect, which the clusters sending the same request reuse instead
+// of sending it again.
+type SharedResponses struct {
+	mutex     sync.Mutex
+	responses map[string]*project.Response
+	size      int
+}
+
+// NewSharedResponses creates the store of the shared responses
+func NewSharedResponses() *SharedResponses {
+	return &SharedResponses{responses: make(map[string]*project.Response)}
+}
+
+// get returns the shared response of a key if any
+func (s *SharedResponses) get(key string) (*project.Response, bool) {
+	if s == nil {
+		return nil, false
+	}
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	response, ok := s.responses[key]
+	return response, ok
+}
+
+// set shares the response of a key if there is room for its body
+func (s *SharedResponses) set(key string, response *project.Response) {
+	if s == nil {
+		return
+	}
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	if _, ok := s.responses[key]; ok || s.size+len(response.Body) > maxSharedBytes {
+		return
+	}
+	s.responses[key] = response
+	s.size += len(response.Body)
+}
+
+// sharedKey returns the key of a built request in the shared responses,
+// its method, URL, headers and body along with the redirects it follows.
+// The empty path of the URL is the root.
+func (e *HTTPExecuter) sharedKey(request *requests.HttpRequest) string {
+	body, err := request.Request.BodyBytes()
+	if err != nil {
+		return ""
+	}
+	req := request.Request.Request
+	URL := *req.URL
+	if URL.Path == "" && URL.RawPath == "" {
+		URL.Path = "/"
+	}
+	return project.Key("shared", req.Method, URL.String(), req.Host, headerLines(req.Header), string(body), strconv.FormatBool(e.bulkHttpRequest.Redirects), strconv.Itoa(e.bulkHttpRequest.MaxRedirects))
+}
+
+// sharedResponse returns the shared response of a built request if any,
+// with its own headers.
+func (e *HTTPExecuter) sharedResponse(request *requests.HttpRequest) (*project.Response, bool) {
+	if e.shared == nil {
+		return nil, false
+	}
+	shared, ok := e.shared.get(e.sharedKey(request))
+	if !ok {
+		return nil, false
+	}
+	response := *shared
+	response.Header = shared.Header.Clone()
+	return &response, true
+}
+
+// Fetch sends the request of the executer to a target as a baseline
+// request, returning its response, which is shared with the clusters
+// sending the same request.
+func (e *HTTPExecuter) Fetch(ctx context.Context, URL string) (*project.Response, error) {
+	if e.hostErrors.Dead(URL) {
+		return nil, hosterrors.ErrSkipped
+	}
+	request, err := e.buildRequest(URL, nil, e.bulkHttpRequest.Path[0])
+	if err != nil {
+		return nil, errors.Wrap(err, "could not build http request")
+	}
+	exchange, err := e.send(ctx, URL, request, e.cacheKey(URL, request, nil))
+	if err != nil {
+		return nil, errors.Wrap(err, "could not handle http request")
+	}
+	defer exchange.release()
+
+	response := &project.Response{
+		URL:        request.Request.URL.String(),
+		StatusCode: exchange.resp.StatusCode,
+		Proto:      exchange.resp.Proto,
+		Header:     exchange.resp.Header.Clone(),
+		Body:       []byte(exchange.body),
+		Duration:   exchange.duration,
+		RemoteIP:   exchange.remoteIP,
+	}
+	if response.Header == nil {
+		response.Header = make(http.Header)
+	}
+	// the requests of a session which expired are not shared
+	if request.Session == nil || !e.sessions.Expired(exchange.resp, exchange.body, exchange.headers) {
+		e.shared.set(e.sharedKey(request), response)
+	}
+	return response, nil
+}
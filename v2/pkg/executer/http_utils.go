@@ -1,22 +1,95 @@
 package executer
 
 import (
+	"bytes"
+	"encoding/base64"
+	"net"
 	"net/http"
+	"net/http/httptrace"
+	"net/http/httputil"
+	"sort"
 	"strings"
+	"time"
+	"unicode/utf8"
 	"unsafe"
+
+	"github.com/projectdiscovery/nuclei/v2/pkg/file"
+	"github.com/projectdiscovery/nuclei/v2/pkg/fuzzing"
+	"github.com/projectdiscovery/nuclei/v2/pkg/interactsh"
+	"github.com/projectdiscovery/nuclei/v2/pkg/ssl"
+	"github.com/projectdiscovery/nuclei/v2/pkg/templates"
+	"github.com/projectdiscovery/retryablehttp-go"
 )
 
-type jsonOutput struct {
-	Template         string   `json:"template"`
-	Type             string   `json:"type"`
-	Matched          string   `json:"matched"`
-	MatcherName      string   `json:"matcher_name,omitempty"`
-	ExtractedResults []string `json:"extracted_results,omitempty"`
-	Severity         string   `json:"severity"`
-	Author           string   `json:"author"`
-	Description      string   `json:"description"`
-	Request          string   `json:"request,omitempty"`
-	Response         string   `json:"response,omitempty"`
+// ResultEvent is a result written by -json, one per line, and sent to the
+// exporters, the results of the programs embedding the engine.
+type ResultEvent struct {
+	Template         string                    `json:"template"`
+	Name             string                    `json:"name"`
+	Tags             []string                  `json:"tags,omitempty"`
+	Type             string                    `json:"type"`
+	Host             string                    `json:"host"`
+	Matched          string                    `json:"matched"`
+	Path             string                    `json:"path,omitempty"`
+	MatcherName      string                    `json:"matcher_name,omitempty"`
+	MatchedCount     int                       `json:"matched_count,omitempty"`
+	ExtractedResults []string                  `json:"extracted_results,omitempty"`
+	IteratedValue    string                    `json:"iterated_value,omitempty"`
+	Severity         string                    `json:"severity"`
+	Author           string                    `json:"author"`
+	Description      string                    `json:"description"`
+	Reference        []string                  `json:"reference,omitempty"`
+	Classification   *templates.Classification `json:"classification,omitempty"`
+	Timestamp        time.Time                 `json:"timestamp"`
+	// DurationMS is the duration of the http request in milliseconds
+	DurationMS int64 `json:"duration_ms,omitempty"`
+	// Metrics are the status, length and counts of words and lines of the
+	// http response.
+	Metrics *ResponseMetrics `json:"metrics,omitempty"`
+	// Dedupe is duplicate or known for the findings reported before by the
+	// run or by a previous run, written only with -show-duplicates.
+	Dedupe string `json:"dedupe,omitempty"`
+	// Retried is true for the results of a run retried at the end of the
+	// scan after failing with a network error.
+	Retried bool `json:"retried,omitempty"`
+	// Passive is true for the results of the stored responses evaluated by
+	// the passive mode, no request being sent.
+	Passive bool `json:"passive,omitempty"`
+	// Interaction is the interaction with the interactsh url of the request
+	// matched by the interactsh matchers, received after the response.
+	Interaction *interactsh.Interaction `json:"interaction,omitempty"`
+	// Fuzzing is the payload injected into a parameter of the http request
+	// by a fuzzing rule, if it was fuzzed.
+	Fuzzing *fuzzing.Mutation `json:"fuzzing,omitempty"`
+	// Request and Response are base64 encoded if they are binary, which is
+	// given by their encoding. Responses longer than the cap of the regexes
+	// are truncated.
+	Request           string              `json:"request,omitempty"`
+	RequestEncoding   string              `json:"request_encoding,omitempty"`
+	Response          string              `json:"response,omitempty"`
+	ResponseEncoding  string              `json:"response_encoding,omitempty"`
+	ResponseTruncated bool                `json:"response_truncated,omitempty"`
+	CurlCommand       string              `json:"curl_command,omitempty"`
+	Resolver          string              `json:"resolver,omitempty"`
+	ResolverType      string              `json:"resolver_type,omitempty"`
+	Trace             string              `json:"trace,omitempty"`
+	PTR               []string            `json:"ptr,omitempty"`
+	Records           map[string][]string `json:"records,omitempty"`
+	// Console and Dialogs are the console messages and the dialogs of a
+	// headless page, Network its network requests with -include-rr.
+	Console []string `json:"console,omitempty"`
+	Dialogs []string `json:"dialogs,omitempty"`
+	Network []string `json:"network,omitempty"`
+	// Error is the kind of the error which stopped the steps of a network
+	// request, the connection of a websocket request or the handshake of a
+	// ssl request, i.e refused or handshake, for the results matching it.
+	Error string `json:"error,omitempty"`
+	// TLS is what the handshake of a ssl request negotiated, the version,
+	// the cipher suite and the certificates.
+	TLS *ssl.Handshake `json:"tls,omitempty"`
+	// Locations are the lines and the offsets of the matches in the file
+	// of a file request, the first ones of a file matching many times.
+	Locations []file.Location `json:"locations,omitempty"`
 }
 
 // unsafeToString converts byte slice to string with zero allocations
@@ -42,3 +115,89 @@ func headersToString(headers http.Header) string {
 	}
 	return builder.String()
 }
+
+// traceRemoteIP traces the connections of a request, returning the request
+// to send and a function returning the ip of the last connection made,
+// which is the final hop of the redirect chain. The ip of the proxy is
+// returned when the requests are sent through a proxy.
+func traceRemoteIP(req *retryablehttp.Request) (*retryablehttp.Request, func() string) {
+	var remoteIP string
+	trace := &httptrace.ClientTrace{
+		GotConn: func(info httptrace.GotConnInfo) {
+			if host, _, err := net.SplitHostPort(info.Conn.RemoteAddr().String()); err == nil {
+				remoteIP = host
+			}
+		},
+	}
+	traced := req.WithContext(httptrace.WithClientTrace(req.Context(), trace))
+	return traced, func() string { return remoteIP }
+}
+
+// encodeRaw returns a raw request or response as a string, base64 encoded
+// along with its encoding if it is binary.
+func encodeRaw(raw []byte) (string, string) {
+	if utf8.Valid(raw) && bytes.IndexByte(raw, 0) == -1 {
+		return string(raw), ""
+	}
+	return base64.StdEncoding.EncodeToString(raw), "base64"
+}
+
+// dumpRequest returns the headers of an http request as sent and its body
+func dumpRequest(req *retryablehttp.Request) ([]byte, []byte, error) {
+	headers, err := httputil.DumpRequest(req.Request, false)
+	if err != nil {
+		return nil, nil, err
+	}
+	body, err := req.BodyBytes()
+	if err != nil {
+		return nil, nil, err
+	}
+	return headers, body, nil
+}
+
+// curlCommand returns a curl command line sending an http request again
+// with its headers and body, the values of the headers being redacted by
+// redactHeader. The certificates aren't verified and the path is sent as
+// is, like nuclei does. Binary bodies are piped base64 encoded.
+func curlCommand(req *http.Request, body []byte, redactHeader func(name, value string) string) string {
+	builder := &strings.Builder{}
+	encoded, encoding := encodeRaw(body)
+	if encoding != "" {
+		builder.WriteString("echo ")
+		builder.WriteString(encoded)
+		builder.WriteString(" | base64 -d | ")
+	}
+	builder.WriteString("curl -k --path-as-is -X ")
+	builder.WriteString(shellQuote(req.Method))
+
+	if req.Host != "" && req.Host != req.URL.Host {
+		builder.WriteString(" -H ")
+		builder.WriteString(shellQuote("Host: " + req.Host))
+	}
+	names := make([]string, 0, len(req.Header))
+	for name := range req.Header {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		for _, value := range req.Header[name] {
+			builder.WriteString(" -H ")
+			builder.WriteString(shellQuote(name + ": " + redactHeader(name, value)))
+		}
+	}
+
+	if encoding != "" {
+		builder.WriteString(" --data-binary @-")
+	} else if len(body) > 0 {
+		builder.WriteString(" --data-binary ")
+		builder.WriteString(shellQuote(encoded))
+	}
+	builder.WriteRune(' ')
+	builder.WriteString(shellQuote(req.URL.String()))
+	return builder.String()
+}
+
+// shellQuote quotes a value as a single argument of a posix shell
+func shellQuote(value string) string {
+	return "'" + strings.Replace(value, "'", `'\''`, -1) + "'"
+}
@@ -0,0 +1,34 @@
+package executer
+
+import (
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/projectdiscovery/retryablehttp-go"
+	"github.com/stretchr/testify/require"
+)
+
+func TestTraceRemoteIP(t *testing.T) {
+	listener, err := net.Listen("tcp", "[::1]:0")
+	if err != nil {
+		t.Skip("ipv6 loopback is not available")
+	}
+	final := httptest.NewUnstartedServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}))
+	final.Listener = listener
+	final.Start()
+	defer final.Close()
+
+	redirect := httptest.NewServer(http.RedirectHandler(final.URL, http.StatusFound))
+	defer redirect.Close()
+
+	req, err := retryablehttp.NewRequest(http.MethodGet, redirect.URL, nil)
+	require.Nil(t, err, "Could not create request")
+	req, remoteIP := traceRemoteIP(req)
+
+	resp, err := retryablehttp.NewClient(retryablehttp.DefaultOptionsSingle).Do(req)
+	require.Nil(t, err, "Could not send request")
+	resp.Body.Close()
+	require.Equal(t, "::1", remoteIP(), "Could not get the ip of the final hop")
+}
@@ -0,0 +1,219 @@
+package executer
+
+import (
+	"bufio"
+	"strings"
+	"sync"
+
+	jsoniter "github.com/json-iterator/go"
+	"github.com/logrusorgru/aurora"
+	"github.com/projectdiscovery/gologger"
+	"github.com/projectdiscovery/nuclei/v2/pkg/collector"
+	"github.com/projectdiscovery/nuclei/v2/pkg/dedupe"
+	"github.com/projectdiscovery/nuclei/v2/pkg/export"
+	"github.com/projectdiscovery/nuclei/v2/pkg/extractors"
+	"github.com/projectdiscovery/nuclei/v2/pkg/grouping"
+	"github.com/projectdiscovery/nuclei/v2/pkg/markdown"
+	"github.com/projectdiscovery/nuclei/v2/pkg/matchers"
+	"github.com/projectdiscovery/nuclei/v2/pkg/redact"
+	"github.com/projectdiscovery/nuclei/v2/pkg/sarif"
+	"github.com/projectdiscovery/nuclei/v2/pkg/templates"
+)
+
+// distinctMatchers returns the matched matchers to write a result for,
+// once per matcher name. Unnamed matchers are each kept.
+func distinctMatchers(matched []*matchers.Matcher) []*matchers.Matcher {
+	names := make(map[string]struct{})
+	distinct := make([]*matchers.Matcher, 0, len(matched))
+	for _, matcher := range matched {
+		if matcher.Name != "" {
+			if _, ok := names[matcher.Name]; ok {
+				continue
+			}
+			names[matcher.Name] = struct{}{}
+		}
+		distinct = append(distinct, matcher)
+	}
+	return distinct
+}
+
+// collect adds the extracted values of a result to the collector if any
+func collect(c *collector.Collector, values []string) {
+	if c == nil {
+		return
+	}
+	for _, value := range values {
+		if err := c.Add(value); err != nil {
+			gologger.Warningf("Could not write extracted value to %s: %s\n", c.Name(), err)
+			return
+		}
+	}
+}
+
+// exportSarif adds a result of a template to the SARIF log if any, the matched
+// url and the extracted values being redacted.
+func exportSarif(exporter *sarif.Exporter, template *templates.Template, redact func(string) string, matched string, matcher *matchers.Matcher, values []string) {
+	if exporter == nil {
+		return
+	}
+	exporter.Add(template, redact(matched), matcherName(matcher), redactValues(redact, values))
+}
+
+// checkDuplicate returns the dedupe status of a result, all the results
+// being new without deduplication.
+func checkDuplicate(deduper *dedupe.Deduper, templateID, matched string, matcher *matchers.Matcher, values []string) dedupe.Status {
+	if deduper == nil {
+		return dedupe.NewFinding
+	}
+	return deduper.Check(&dedupe.Finding{TemplateID: templateID, MatcherName: matcherName(matcher), Matched: matched, Extracted: values})
+}
+
+// exportMarkdown writes the evidence of a result to the markdown report if any
+func exportMarkdown(exporter *markdown.Exporter, finding *markdown.Finding) {
+	if exporter == nil {
+		return
+	}
+	if err := exporter.Add(finding); err != nil {
+		gologger.Warningf("Could not write finding to %s: %s\n", exporter.Name(), err)
+	}
+}
+
+// exportJSON sends a json result to the exporters if any, building it once
+// with the raw requests and responses and once without as they are asked.
+func exportJSON(exporters []export.Exporter, redact func(string) string, result func(includeRR bool) *ResultEvent) {
+	var data [2][]byte
+	for _, exporter := range exporters {
+		includeRR := exporter.IncludeRR()
+		built := &data[0]
+		if includeRR {
+			built = &data[1]
+		}
+		if *built == nil {
+			marshaled, ok := marshalResult(result(includeRR), redact)
+			if !ok {
+				continue
+			}
+			*built = marshaled
+		}
+		exporter.Export(*built)
+	}
+}
+
+// matcherName returns the name of the matcher of a result if any
+func matcherName(matcher *matchers.Matcher) string {
+	if matcher == nil {
+		return ""
+	}
+	return matcher.Name
+}
+
+// newRedactor returns the redactor of an executer, nil if not redacting
+func newRedactor(redactor *redact.Redactor, noRedact bool) *redact.Redactor {
+	if noRedact {
+		return nil
+	}
+	if redactor == nil {
+		return redact.Default
+	}
+	return redactor
+}
+
+// redactValues returns the redacted extracted values of a result
+func redactValues(redact func(string) string, values []string) []string {
+	redacted := make([]string, 0, len(values))
+	for _, value := range values {
+		redacted = append(redacted, redact(value))
+	}
+	return redacted
+}
+
+// writeToFile appends the values of an extractor to its file if any
+func writeToFile(files *collector.Files, templateID string, extractor *extractors.Extractor, values []string) {
+	if extractor.ToFile == "" || files == nil {
+		return
+	}
+	path := extractor.OutputFile(templateID)
+	for _, value := range values {
+		if err := files.Append(path, value, extractor.ToFileDedupe); err != nil {
+			gologger.Warningf("Could not write extracted value to %s: %s\n", path, err)
+			return
+		}
+	}
+}
+
+// fileMutex serializes the writes to the output file, each executer having
+// its own buffered writer of the file.
+var fileMutex sync.Mutex
+
+// writeLines writes lines to the output file if any. The writer is flushed
+// while holding the lock so the lines of concurrent results are never
+// interleaved, whatever their length.
+func writeLines(writer *bufio.Writer, lines ...string) {
+	if writer == nil {
+		return
+	}
+	fileMutex.Lock()
+	defer fileMutex.Unlock()
+	for _, line := range lines {
+		writer.WriteString(line)
+		if !strings.HasSuffix(line, "\n") {
+			writer.WriteRune('\n')
+		}
+	}
+	writer.Flush()
+}
+
+// marshalResult returns a redacted json result, false if it can't be marshaled
+func marshalResult(output *ResultEvent, redact func(string) string) ([]byte, bool) {
+	data, err := jsoniter.Marshal(output)
+	if err != nil {
+		gologger.Warningf("Could not marshal json output: %s\n", err)
+		return nil, false
+	}
+	return []byte(redact(string(data))), true
+}
+
+// writeJSON writes a json result on screen unless quiet and to the output
+// file if any. The % are escaped on screen as gologger formats the messages
+// twice.
+func writeJSON(writer *bufio.Writer, quiet bool, data []byte) {
+	if !quiet {
+		gologger.Silentf("%s\n", strings.Replace(string(data), "%", "%%", -1))
+	}
+	writeLines(writer, string(data))
+}
+
+// printResult shows a result line of a target on screen unless quiet,
+// buffering it by host if grouping.
+func printResult(grouper *grouping.Grouper, quiet bool, target, severity, message string) {
+	if quiet {
+		return
+	}
+	if grouper != nil {
+		grouper.Add(target, severity, message)
+		return
+	}
+	gologger.Silentf("%s", message)
+}
+
+// writeExtractedValues writes the values of an extractor-only result one per
+// line on screen unless quiet and to the output file if any.
+func writeExtractedValues(writer *bufio.Writer, quiet bool, values []string) {
+	if !quiet {
+		for _, value := range values {
+			gologger.Silentf("%s\n", value)
+		}
+	}
+	writeLines(writer, values...)
+}
+
+// writeCVE appends the cve ids of the classification of a template in
+// brackets to a result line, if any.
+func writeCVE(builder *strings.Builder, colorizer aurora.Aurora, classification *templates.Classification) {
+	if classification == nil || len(classification.CVEID) == 0 {
+		return
+	}
+	builder.WriteString(" [")
+	builder.WriteString(colorizer.BrightMagenta(strings.Join(classification.CVEID, ",")).String())
+	builder.WriteString("]")
+}
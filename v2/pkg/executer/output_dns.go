@@ -1,43 +1,63 @@
 package executer
 
 import (
+	"context"
 	"strings"
+	"time"
 
-	jsoniter "github.com/json-iterator/go"
-	"github.com/projectdiscovery/gologger"
+	"github.com/miekg/dns"
+	"github.com/projectdiscovery/nuclei/v2/pkg/dedupe"
+	"github.com/projectdiscovery/nuclei/v2/pkg/dnsrecords"
+	"github.com/projectdiscovery/nuclei/v2/pkg/markdown"
 	"github.com/projectdiscovery/nuclei/v2/pkg/matchers"
 )
 
 // writeOutputDNS writes dns output to streams
-func (e *DNSExecuter) writeOutputDNS(domain string, matcher *matchers.Matcher, extractorResults []string) {
-	if e.jsonOutput {
-		output := jsonOutput{
-			Template:    e.template.ID,
-			Type:        "dns",
-			Matched:     domain,
-			Severity:    e.template.Info.Severity,
-			Author:      e.template.Info.Author,
-			Description: e.template.Info.Description,
-		}
-		if matcher != nil && len(matcher.Name) > 0 {
-			output.MatcherName = matcher.Name
-		}
-		if len(extractorResults) > 0 {
-			output.ExtractedResults = extractorResults
-		}
-		data, err := jsoniter.Marshal(output)
-		if err != nil {
-			gologger.Warningf("Could not marshal json output: %s\n", err)
+func (e *DNSExecuter) writeOutputDNS(ctx context.Context, domain string, resolver *Resolver, resp *dnsrecords.Response, matcher *matchers.Matcher, extractorResults []string) {
+	retried := isRetry(ctx)
+	// The discarded results are counted without being recorded as reported
+	if e.discardResults {
+		e.stats.Finding(e.template.ID, e.template.Info.Severity)
+		return
+	}
+	// Findings reported before are only written to the json output if asked
+	if status := checkDuplicate(e.deduper, e.template.ID, domain, matcher, extractorResults); status != dedupe.NewFinding {
+		if e.showDuplicates && e.jsonOutput {
+			output := e.jsonResult(domain, resolver, resp, matcher, extractorResults, e.includeRR, e.jsonRequest)
+			output.Dedupe = status.String()
+			output.Retried = retried
+			if data, ok := marshalResult(output, e.redact); ok {
+				writeJSON(e.writer, e.quiet, data)
+			}
 		}
+		return
+	}
+	e.stats.Finding(e.template.ID, e.template.Info.Severity)
 
-		gologger.Silentf("%s", string(data))
+	exportSarif(e.exporter, e.template, e.redact, domain, matcher, extractorResults)
+	if e.markdown != nil {
+		exportMarkdown(e.markdown, e.markdownFinding(domain, resp, matcher, extractorResults))
+	}
+	exportJSON(e.exporters, e.redact, func(includeRR bool) *ResultEvent {
+		output := e.jsonResult(domain, resolver, resp, matcher, extractorResults, includeRR, includeRR)
+		output.Retried = retried
+		return output
+	})
+	if e.jsonOutput {
+		output := e.jsonResult(domain, resolver, resp, matcher, extractorResults, e.includeRR, e.jsonRequest)
+		output.Retried = retried
+		if data, ok := marshalResult(output, e.redact); ok {
+			writeJSON(e.writer, e.quiet, data)
+		}
+		return
+	}
 
-		if e.writer != nil {
-			e.outputMutex.Lock()
-			e.writer.Write(data)
-			e.writer.WriteRune('\n')
-			e.outputMutex.Unlock()
+	// Extractor-only requests write the bare values to pipe them to other tools
+	if e.passiveExtract && len(e.dnsRequest.Matchers) == 0 {
+		for i, result := range extractorResults {
+			extractorResults[i] = e.redact(result)
 		}
+		writeExtractedValues(e.writer, e.quiet, extractorResults)
 		return
 	}
 
@@ -52,10 +72,24 @@ func (e *DNSExecuter) writeOutputDNS(domain string, matcher *matchers.Matcher, e
 	}
 	builder.WriteString("] [")
 	builder.WriteString(colorizer.BrightBlue("dns").String())
+	// write the resolver transport if it's not plain dns
+	if resolver.Type != PlainResolver {
+		builder.WriteString(":")
+		builder.WriteString(colorizer.BrightBlue(string(resolver.Type)).String())
+	}
 	builder.WriteString("] ")
 
 	builder.WriteString(domain)
 
+	// Write the resolved names for reverse lookups
+	if e.dnsRequest.IsPTR() {
+		if names := ptrNames(resp); len(names) > 0 {
+			builder.WriteString(" (")
+			builder.WriteString(strings.Join(names, ","))
+			builder.WriteString(")")
+		}
+	}
+
 	// If any extractors, write the results
 	if len(extractorResults) > 0 {
 		builder.WriteString(" [")
@@ -67,18 +101,96 @@ func (e *DNSExecuter) writeOutputDNS(domain string, matcher *matchers.Matcher, e
 		}
 		builder.WriteString("]")
 	}
+	writeCVE(builder, colorizer, e.template.Info.Classification)
 	builder.WriteRune('\n')
 
 	// Write output to screen as well as any output file
-	message := builder.String()
-	gologger.Silentf("%s", message)
+	message := e.redact(builder.String())
+	printResult(e.grouper, e.quiet, domain, e.template.Info.Severity, message)
 
 	if e.writer != nil {
-		e.outputMutex.Lock()
 		if e.coloredOutput {
 			message = e.decolorizer.ReplaceAllString(message, "")
 		}
-		e.writer.WriteString(message)
-		e.outputMutex.Unlock()
+		writeLines(e.writer, message)
+	}
+}
+
+// markdownFinding returns the evidence of a result for the markdown report
+func (e *DNSExecuter) markdownFinding(domain string, resp *dnsrecords.Response, matcher *matchers.Matcher, extractorResults []string) *markdown.Finding {
+	var questions []string
+	for _, question := range resp.Msg.Question {
+		questions = append(questions, question.String())
+	}
+	return &markdown.Finding{
+		Template:    e.template,
+		Type:        "dns",
+		Host:        domain,
+		Matched:     e.redact(domain),
+		MatcherName: matcherName(matcher),
+		Extracted:   redactValues(e.redact, extractorResults),
+		Request:     strings.Join(questions, "\n"),
+		Response:    e.redact(resp.Msg.String()),
+		Timestamp:   time.Now(),
+	}
+}
+
+// jsonResult returns the json output of a result, with the records of all
+// the sections and the delegation trace if required.
+func (e *DNSExecuter) jsonResult(domain string, resolver *Resolver, resp *dnsrecords.Response, matcher *matchers.Matcher, extractorResults []string, records, trace bool) *ResultEvent {
+	output := &ResultEvent{
+		Template:       e.template.ID,
+		Name:           e.template.Info.Name,
+		Tags:           e.template.Info.TagList(),
+		Type:           "dns",
+		Host:           domain,
+		Matched:        domain,
+		MatcherName:    matcherName(matcher),
+		Severity:       e.template.Info.Severity,
+		Author:         e.template.Info.Author,
+		Description:    e.template.Info.Description,
+		Reference:      e.template.Info.Reference,
+		Classification: e.template.Info.Classification,
+		Timestamp:      time.Now(),
+		Resolver:       resolver.String(),
+		ResolverType:   string(resolver.Type),
+	}
+	if len(extractorResults) > 0 {
+		output.ExtractedResults = extractorResults
+	}
+	if e.dnsRequest.IsPTR() {
+		output.PTR = ptrNames(resp)
+	}
+	if records {
+		output.Records = make(map[string][]string)
+		for section, records := range dnsrecords.Sections(resp.Msg) {
+			for _, record := range records {
+				output.Records[section] = append(output.Records[section], record.String())
+			}
+		}
+	}
+	if trace && resp.Trace != nil {
+		output.Trace = resp.Trace.String()
+	}
+	return output
+}
+
+// redact replaces the values of the environment variables of the template
+// written to the output, unless disabled.
+func (e *DNSExecuter) redact(value string) string {
+	if e.redactor == nil {
+		return value
+	}
+	return e.redactor.Redact(e.template.Redact(value))
+}
+
+// ptrNames returns the names resolved by a reverse lookup
+func ptrNames(resp *dnsrecords.Response) []string {
+	var names []string
+	for _, record := range resp.Msg.Answer {
+		if ptr, ok := record.(*dns.PTR); ok {
+			names = append(names, ptr.Ptr)
+		}
 	}
+	return names
 }
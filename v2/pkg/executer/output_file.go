@@ -0,0 +1,73 @@
+package executer
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+
+	"github.com/projectdiscovery/nuclei/v2/pkg/file"
+	"github.com/projectdiscovery/nuclei/v2/pkg/matchers"
+)
+
+// result returns the result of a file written by the sink, the lines of
+// the matches moving along with the edits of the file, with the content of
+// the file if required.
+func (e *FileExecuter) result(target string, resp *file.Response, matcher *matchers.Matcher, extractorResults []string, locations []file.Location) *protocolResult {
+	return &protocolResult{
+		host:      target,
+		matched:   resp.Path,
+		located:   locatedPath(resp.Path, locations),
+		matcher:   matcher,
+		extracted: extractorResults,
+		json: func(output *ResultEvent, _, read bool) {
+			output.Locations = locations
+			if read {
+				data := resp.Data
+				if maxSize := e.regexGuard.MaxSize(); maxSize > 0 && len(data) > maxSize {
+					data = data[:maxSize]
+					output.ResponseTruncated = true
+				}
+				output.Response, output.ResponseEncoding = encodeRaw([]byte(data))
+			}
+		},
+		evidence: func() (string, string) {
+			return "", matchedLines(resp, locations)
+		},
+	}
+}
+
+// locatedPath returns the path of a file along with the lines of the
+// matches in it, i.e config/app.yml:3,12.
+func locatedPath(path string, locations []file.Location) string {
+	if len(locations) == 0 {
+		return path
+	}
+	lines := make([]string, 0, len(locations))
+	for i, location := range locations {
+		if i > 0 && location.Line == locations[i-1].Line {
+			continue
+		}
+		lines = append(lines, strconv.Itoa(location.Line))
+	}
+	return path + ":" + strings.Join(lines, ",")
+}
+
+// matchedLines returns the lines of the matches in a file, prefixed by
+// their number.
+func matchedLines(resp *file.Response, locations []file.Location) string {
+	builder := &strings.Builder{}
+	for i, location := range locations {
+		if i > 0 && location.Line == locations[i-1].Line {
+			continue
+		}
+		start := strings.LastIndexByte(resp.Data[:location.Offset], '\n') + 1
+		end := strings.IndexByte(resp.Data[location.Offset:], '\n')
+		if end == -1 {
+			end = len(resp.Data)
+		} else {
+			end += location.Offset
+		}
+		fmt.Fprintf(builder, "%d: %s\n", location.Line, strings.TrimSuffix(resp.Data[start:end], "\r"))
+	}
+	return builder.String()
+}
@@ -0,0 +1,32 @@
+package executer
+
+import (
+	"github.com/projectdiscovery/nuclei/v2/pkg/headless"
+	"github.com/projectdiscovery/nuclei/v2/pkg/matchers"
+)
+
+// result returns the result of a page written by the sink, with the steps
+// and the final dom and network requests of the page if required.
+func (e *HeadlessExecuter) result(URL string, actions []*headless.Action, resp *headless.Response, matcher *matchers.Matcher, extractorResults []string) *protocolResult {
+	return &protocolResult{
+		host:      URL,
+		matched:   resp.URL,
+		key:       URL,
+		matcher:   matcher,
+		extracted: extractorResults,
+		json: func(output *ResultEvent, steps, page bool) {
+			output.Console = resp.Console
+			output.Dialogs = resp.Dialogs
+			if steps {
+				output.Request = actionsString(actions)
+			}
+			if page {
+				output.Response = resp.DOM
+				output.Network = resp.Requests
+			}
+		},
+		evidence: func() (string, string) {
+			return actionsString(actions), resp.String()
+		},
+	}
+}
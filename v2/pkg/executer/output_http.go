@@ -1,66 +1,89 @@
 package executer
 
 import (
+	"context"
 	"net/http"
 	"net/http/httputil"
+	"strconv"
 	"strings"
+	"time"
 
-	jsoniter "github.com/json-iterator/go"
 	"github.com/projectdiscovery/gologger"
+	"github.com/projectdiscovery/nuclei/v2/pkg/dedupe"
+	"github.com/projectdiscovery/nuclei/v2/pkg/markdown"
 	"github.com/projectdiscovery/nuclei/v2/pkg/matchers"
 	"github.com/projectdiscovery/nuclei/v2/pkg/requests"
 )
 
 // writeOutputHTTP writes http output to streams
-func (e *HTTPExecuter) writeOutputHTTP(req *requests.HttpRequest, resp *http.Response, body string, matcher *matchers.Matcher, extractorResults []string) {
+func (e *HTTPExecuter) writeOutputHTTP(ctx context.Context, req *requests.HttpRequest, resp *http.Response, body string, duration time.Duration, metrics *ResponseMetrics, matcher *matchers.Matcher, extractorResults []string) {
 	URL := req.Request.URL.String()
+	retried, passive, interaction := isRetry(ctx), isPassive(ctx), interactionOf(ctx)
 
-	if e.jsonOutput {
-		output := jsonOutput{
-			Template:    e.template.ID,
-			Type:        "http",
-			Matched:     URL,
-			Severity:    e.template.Info.Severity,
-			Author:      e.template.Info.Author,
-			Description: e.template.Info.Description,
-		}
-		if matcher != nil && len(matcher.Name) > 0 {
-			output.MatcherName = matcher.Name
-		}
-		if len(extractorResults) > 0 {
-			output.ExtractedResults = extractorResults
-		}
-		if e.jsonRequest {
-			dumpedRequest, err := httputil.DumpRequest(req.Request.Request, true)
-			if err != nil {
-				gologger.Warningf("could not dump request: %s\n", err)
-			} else {
-				output.Request = string(dumpedRequest)
-			}
-			dumpedResponse, err := httputil.DumpResponse(resp, false)
-			if err != nil {
-				gologger.Warningf("could not dump response: %s\n", err)
-			} else {
-				output.Response = string(dumpedResponse) + body
-			}
+	// occurrences of the matched word for matchers with a words count
+	var matchedCount int
+	if matcher != nil && matcher.Count > 0 {
+		matchedCount = matcher.Occurrences(resp, body, headersToString(resp.Header))
+	}
 
+	// The discarded results are counted without being recorded as reported
+	if e.discardResults {
+		e.stats.Finding(e.template.ID, e.template.Info.Severity)
+		return
+	}
+	// Findings reported before are only written to the json output if asked,
+	// the fuzzed requests reporting each parameter and payload
+	matched := URL
+	if req.Fuzzing != nil {
+		matched += " " + req.Fuzzing.String()
+	}
+	if status := checkDuplicate(e.deduper, e.template.ID, matched, matcher, extractorResults); status != dedupe.NewFinding {
+		if e.showDuplicates && e.jsonOutput {
+			output := e.jsonResult(req, resp, body, duration, metrics, matcher, matchedCount, extractorResults, e.jsonRequest || e.includeRR, e.includeRR)
+			output.Dedupe = status.String()
+			output.Retried = retried
+			output.Passive = passive
+			output.Interaction = interaction
+			if data, ok := marshalResult(output, e.redact); ok {
+				writeJSON(e.writer, e.quiet, data)
+			}
 		}
-		data, err := jsoniter.Marshal(output)
-		if err != nil {
-			gologger.Warningf("Could not marshal json output: %s\n", err)
-		}
+		return
+	}
+	e.stats.Finding(e.template.ID, e.template.Info.Severity)
 
-		gologger.Silentf("%s", string(data))
+	exportSarif(e.exporter, e.template, e.redact, URL, matcher, extractorResults)
+	if e.markdown != nil {
+		exportMarkdown(e.markdown, e.markdownFinding(req, resp, body, matcher, extractorResults))
+	}
 
-		if e.writer != nil {
-			e.outputMutex.Lock()
-			e.writer.Write(data)
-			e.writer.WriteRune('\n')
-			e.outputMutex.Unlock()
+	exportJSON(e.exporters, e.redact, func(includeRR bool) *ResultEvent {
+		output := e.jsonResult(req, resp, body, duration, metrics, matcher, matchedCount, extractorResults, includeRR, includeRR)
+		output.Retried = retried
+		output.Passive = passive
+		output.Interaction = interaction
+		return output
+	})
+	if e.jsonOutput {
+		output := e.jsonResult(req, resp, body, duration, metrics, matcher, matchedCount, extractorResults, e.jsonRequest || e.includeRR, e.includeRR)
+		output.Retried = retried
+		output.Passive = passive
+		output.Interaction = interaction
+		if data, ok := marshalResult(output, e.redact); ok {
+			writeJSON(e.writer, e.quiet, data)
 		}
 		return
 	}
 
+	// Extractor-only requests write the bare values to pipe them to other tools
+	if e.passiveExtract && len(e.bulkHttpRequest.Matchers) == 0 {
+		for i, result := range extractorResults {
+			extractorResults[i] = e.redact(result)
+		}
+		writeExtractedValues(e.writer, e.quiet, extractorResults)
+		return
+	}
+
 	builder := &strings.Builder{}
 	colorizer := e.colorizer
 
@@ -78,6 +101,31 @@ func (e *HTTPExecuter) writeOutputHTTP(req *requests.HttpRequest, resp *http.Res
 	escapedURL := strings.Replace(URL, "%", "%%", -1)
 	builder.WriteString(escapedURL)
 
+	if matchedCount > 0 {
+		builder.WriteString(" [")
+		builder.WriteString(colorizer.BrightYellow("count").Bold().String())
+		builder.WriteString("=")
+		builder.WriteString(colorizer.BrightYellow(strconv.Itoa(matchedCount)).String())
+		builder.WriteString("]")
+	}
+
+	if interaction != nil {
+		builder.WriteString(" [")
+		builder.WriteString(colorizer.BrightYellow("interaction").Bold().String())
+		builder.WriteString("=")
+		builder.WriteString(colorizer.BrightYellow(interaction.Protocol).String())
+		builder.WriteString("]")
+	}
+
+	// the payload is written along with the meta
+	if req.Fuzzing != nil {
+		builder.WriteString(" [")
+		builder.WriteString(colorizer.BrightYellow("fuzzing").Bold().String())
+		builder.WriteString("=")
+		builder.WriteString(colorizer.BrightYellow(req.Fuzzing.Part + ":" + req.Fuzzing.Parameter).String())
+		builder.WriteString("]")
+	}
+
 	// If any extractors, write the results
 	if len(extractorResults) > 0 {
 		builder.WriteString(" [")
@@ -95,24 +143,120 @@ func (e *HTTPExecuter) writeOutputHTTP(req *requests.HttpRequest, resp *http.Res
 		builder.WriteString(" [")
 		var metas []string
 		for name, value := range req.Meta {
-			metas = append(metas, colorizer.BrightYellow(name).Bold().String()+"="+ colorizer.BrightYellow(value.(string)).String() )
+			metas = append(metas, colorizer.BrightYellow(name).Bold().String()+"="+colorizer.BrightYellow(value.(string)).String())
 		}
 		builder.WriteString(strings.Join(metas, ","))
 		builder.WriteString("]")
 	}
+	writeCVE(builder, colorizer, e.template.Info.Classification)
 
 	builder.WriteRune('\n')
 
 	// Write output to screen as well as any output file
-	message := builder.String()
-	gologger.Silentf("%s", message)
+	message := e.redact(builder.String())
+	printResult(e.grouper, e.quiet, URL, e.template.Info.Severity, message)
 
 	if e.writer != nil {
-		e.outputMutex.Lock()
 		if e.coloredOutput {
 			message = e.decolorizer.ReplaceAllString(message, "")
 		}
-		e.writer.WriteString(message)
-		e.outputMutex.Unlock()
+		writeLines(e.writer, message)
+	}
+}
+
+// jsonResult returns the json output of a result, with the raw request and
+// response if required along with the curl command sending the request.
+func (e *HTTPExecuter) jsonResult(req *requests.HttpRequest, resp *http.Response, body string, duration time.Duration, metrics *ResponseMetrics, matcher *matchers.Matcher, matchedCount int, extractorResults []string, raw, curl bool) *ResultEvent {
+	output := &ResultEvent{
+		Template:       e.template.ID,
+		Name:           e.template.Info.Name,
+		Tags:           e.template.Info.TagList(),
+		Type:           "http",
+		Host:           req.Request.URL.Host,
+		Matched:        req.Request.URL.String(),
+		Path:           req.Request.URL.RequestURI(),
+		MatcherName:    matcherName(matcher),
+		MatchedCount:   matchedCount,
+		IteratedValue:  req.IteratedValue,
+		Fuzzing:        req.Fuzzing,
+		Severity:       e.template.Info.Severity,
+		Author:         e.template.Info.Author,
+		Description:    e.template.Info.Description,
+		Reference:      e.template.Info.Reference,
+		Classification: e.template.Info.Classification,
+		Timestamp:      time.Now(),
+		DurationMS:     duration.Milliseconds(),
+		Metrics:        metrics,
+	}
+	if len(extractorResults) > 0 {
+		output.ExtractedResults = extractorResults
+	}
+	if raw {
+		e.writeRawHTTP(output, req, resp, body, curl)
+	}
+	return output
+}
+
+// writeRawHTTP adds the raw request and response of a result to its json
+// output, along with the curl command sending the request if required.
+// The response body is truncated to the length the regexes are applied to.
+func (e *HTTPExecuter) writeRawHTTP(output *ResultEvent, req *requests.HttpRequest, resp *http.Response, body string, curl bool) {
+	headers, requestBody, err := dumpRequest(req.Request)
+	if err != nil {
+		gologger.Warningf("could not dump request: %s\n", err)
+	} else {
+		output.Request, output.RequestEncoding = encodeRaw([]byte(e.redact(string(headers) + string(requestBody))))
+		if curl {
+			output.CurlCommand = e.redact(curlCommand(req.Request.Request, []byte(e.redact(string(requestBody))), e.redactHeader))
+		}
+	}
+	dumpedResponse, err := httputil.DumpResponse(resp, false)
+	if err != nil {
+		gologger.Warningf("could not dump response: %s\n", err)
+		return
+	}
+	if maxSize := e.regexGuard.MaxSize(); maxSize > 0 && len(body) > maxSize {
+		body = body[:maxSize]
+		output.ResponseTruncated = true
+	}
+	output.Response, output.ResponseEncoding = encodeRaw([]byte(e.redact(string(dumpedResponse) + body)))
+}
+
+// markdownFinding returns the evidence of a result for the markdown report
+func (e *HTTPExecuter) markdownFinding(req *requests.HttpRequest, resp *http.Response, body string, matcher *matchers.Matcher, extractorResults []string) *markdown.Finding {
+	finding := &markdown.Finding{
+		Template:    e.template,
+		Type:        "http",
+		Host:        req.Request.URL.Host,
+		Matched:     e.redact(req.Request.URL.String()),
+		MatcherName: matcherName(matcher),
+		Extracted:   redactValues(e.redact, extractorResults),
+		Timestamp:   time.Now(),
+	}
+	if headers, requestBody, err := dumpRequest(req.Request); err == nil {
+		finding.Request = e.redact(string(headers) + string(requestBody))
+	}
+	if dumpedResponse, err := httputil.DumpResponse(resp, false); err == nil {
+		finding.Response = e.redact(string(dumpedResponse) + body)
+	}
+	return finding
+}
+
+// redact replaces the values of the environment variables of the template,
+// of the sessions and of the sensitive headers written to the output,
+// unless disabled.
+func (e *HTTPExecuter) redact(value string) string {
+	if e.redactor == nil {
+		return value
+	}
+	return e.redactor.Redact(e.sessions.Redact(e.template.Redact(value)))
+}
+
+// redactHeader returns the value of a header written to the output, redacted
+// if the header is sensitive unless disabled.
+func (e *HTTPExecuter) redactHeader(name, value string) string {
+	if e.redactor == nil {
+		return value
 	}
+	return e.redactor.Header(name, e.sessions.Redact(value))
 }
@@ -0,0 +1,29 @@
+package executer
+
+import (
+	"github.com/projectdiscovery/nuclei/v2/pkg/matchers"
+	"github.com/projectdiscovery/nuclei/v2/pkg/network"
+)
+
+// result returns the result of a run written by the sink, with the data
+// sent and the bytes read if required.
+func (e *NetworkExecuter) result(target string, inputs []*network.Input, resp *network.Response, matcher *matchers.Matcher, extractorResults []string) *protocolResult {
+	return &protocolResult{
+		host:      target,
+		matched:   resp.Address,
+		matcher:   matcher,
+		extracted: extractorResults,
+		json: func(output *ResultEvent, sent, read bool) {
+			output.Error = resp.Error
+			if sent {
+				output.Request = inputsString(inputs)
+			}
+			if read {
+				output.Response, output.ResponseEncoding = encodeRaw([]byte(resp.Data))
+			}
+		},
+		evidence: func() (string, string) {
+			return inputsString(inputs), resp.Dump()
+		},
+	}
+}
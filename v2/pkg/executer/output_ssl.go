@@ -0,0 +1,31 @@
+package executer
+
+import (
+	"github.com/projectdiscovery/nuclei/v2/pkg/matchers"
+	"github.com/projectdiscovery/nuclei/v2/pkg/ssl"
+)
+
+// result returns the result of a handshake written by the sink along with
+// what the handshake negotiated, with the options of the handshake and the
+// fields of the certificates if required.
+func (e *SSLExecuter) result(target string, options *ssl.Options, resp *ssl.Response, matcher *matchers.Matcher, extractorResults []string) *protocolResult {
+	return &protocolResult{
+		host:      target,
+		matched:   resp.Address,
+		matcher:   matcher,
+		extracted: extractorResults,
+		json: func(output *ResultEvent, sent, read bool) {
+			output.Error = resp.Error
+			output.TLS = resp.Handshake()
+			if sent {
+				output.Request = handshakeString(resp.Address, options)
+			}
+			if read {
+				output.Response = resp.String()
+			}
+		},
+		evidence: func() (string, string) {
+			return handshakeString(resp.Address, options), resp.String()
+		},
+	}
+}
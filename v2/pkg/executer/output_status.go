@@ -0,0 +1,66 @@
+package executer
+
+import (
+	"bufio"
+	"time"
+
+	jsoniter "github.com/json-iterator/go"
+	"github.com/projectdiscovery/gologger"
+	"github.com/projectdiscovery/nuclei/v2/pkg/templates"
+)
+
+// The statuses of a template which ran against a target
+const (
+	StatusMatched    = "matched"
+	StatusNotMatched = "not-matched"
+	StatusErrored    = "errored"
+)
+
+// StatusOutput is the record of the status of a template for a target,
+// written to the json output with -matcher-status along with the results.
+type StatusOutput struct {
+	Template string `json:"template"`
+	Name     string `json:"name"`
+	Severity string `json:"severity"`
+	Host     string `json:"host"`
+	Status   string `json:"status"`
+	// Error and RequestIndex are the error of the first failing request and
+	// its index in its block for the errored templates.
+	Error        string    `json:"error,omitempty"`
+	RequestIndex *int      `json:"request_index,omitempty"`
+	Timestamp    time.Time `json:"timestamp"`
+}
+
+// NewStatusOutput returns the status record of a template for a target
+// from the result of its requests.
+func NewStatusOutput(template *templates.Template, host string, result *Result) *StatusOutput {
+	output := &StatusOutput{
+		Template:  template.ID,
+		Name:      template.Info.Name,
+		Severity:  template.Info.Severity,
+		Host:      host,
+		Status:    StatusNotMatched,
+		Timestamp: time.Now(),
+	}
+	switch {
+	case result.GotResults:
+		output.Status = StatusMatched
+	case result.Error != nil:
+		output.Status = StatusErrored
+		output.Error = template.Redact(result.Error.Error())
+		index := result.ErrorRequest
+		output.RequestIndex = &index
+	}
+	return output
+}
+
+// WriteStatus writes a status record on screen and to the output file if
+// any, without interleaving with the results.
+func WriteStatus(writer *bufio.Writer, output *StatusOutput) {
+	data, err := jsoniter.Marshal(output)
+	if err != nil {
+		gologger.Warningf("Could not marshal status output: %s\n", err)
+		return
+	}
+	writeJSON(writer, false, data)
+}
@@ -0,0 +1,390 @@
+package executer
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"flag"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/logrusorgru/aurora"
+	"github.com/miekg/dns"
+	"github.com/projectdiscovery/nuclei/v2/pkg/dnsrecords"
+	"github.com/projectdiscovery/nuclei/v2/pkg/export"
+	"github.com/projectdiscovery/nuclei/v2/pkg/redact"
+	"github.com/projectdiscovery/nuclei/v2/pkg/regexguard"
+	"github.com/projectdiscovery/nuclei/v2/pkg/templates"
+	"github.com/stretchr/testify/require"
+)
+
+var update = flag.Bool("update", false, "update the golden files")
+
+func TestJSONOutput(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header()["Date"] = nil
+		time.Sleep(5 * time.Millisecond)
+		if r.URL.Path == "/binary" {
+			w.Write([]byte("found\x00\xff\xfe"))
+			return
+		}
+		body, _ := ioutil.ReadAll(r.Body)
+		w.Write([]byte("found 100% of " + string(body)))
+	}))
+	defer server.Close()
+
+	template := parseTemplate(t, `
+id: json-output
+info:
+  name: json output
+  author: test
+  severity: low
+  tags: Test,output
+requests:
+  - method: POST
+    path:
+      - "{{BaseURL}}/text?q=it's"
+      - "{{BaseURL}}/binary"
+    headers:
+      X-Token: secret
+    body: "a=1"
+    matchers:
+      - type: word
+        name: found
+        words:
+          - "found"
+    extractors:
+      - type: regex
+        regex:
+          - "[0-9]+%"
+`)
+	// the responses are truncated to the length the regexes are applied to
+	guard := regexguard.New(12)
+	template.SetRegexGuard(guard)
+
+	output := &bytes.Buffer{}
+	writer := bufio.NewWriter(output)
+	executer, err := NewHTTPExecuter(&HTTPOptions{Template: template, BulkHttpRequest: template.BulkRequestsHTTP[0], Writer: writer, Timeout: 5, JSON: true, IncludeRR: true, RegexGuard: guard, Colorizer: aurora.NewAurora(false)})
+	require.Nil(t, err, "Could not create http executer")
+	result := executer.ExecuteHTTP(nil, server.URL)
+	require.Nil(t, result.Error, "Could not execute http requests")
+
+	// the address of the server, the timestamps and the durations change
+	host := strings.TrimPrefix(server.URL, "http://")
+	lines := strings.Split(strings.TrimSpace(strings.Replace(output.String(), host, "127.0.0.1:8080", -1)), "\n")
+	require.Len(t, lines, 2, "Could not write a line per result")
+	var results []map[string]interface{}
+	for _, line := range lines {
+		result := make(map[string]interface{})
+		require.Nil(t, json.Unmarshal([]byte(line), &result), "Could not unmarshal json output")
+		require.NotEmpty(t, result["timestamp"], "Could not write the timestamp")
+		require.NotZero(t, result["duration_ms"], "Could not write the duration")
+		result["timestamp"] = "2006-01-02T15:04:05Z"
+		result["duration_ms"] = 5
+		results = append(results, result)
+	}
+	got, err := json.MarshalIndent(results, "", "  ")
+	require.Nil(t, err, "Could not marshal json output")
+
+	golden := filepath.Join("testdata", "json-output.golden")
+	if *update {
+		require.Nil(t, ioutil.WriteFile(golden, got, 0644), "Could not update golden file")
+	}
+	expected, err := ioutil.ReadFile(golden)
+	require.Nil(t, err, "Could not read golden file")
+	require.Equal(t, string(expected), string(got), "Could not write the json output schema")
+}
+
+func TestRetriedOutput(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte("found"))
+	}))
+	defer server.Close()
+
+	template := parseTemplate(t, `
+id: retried-output
+info:
+  name: retried output
+  author: test
+  severity: low
+requests:
+  - method: GET
+    path:
+      - "{{BaseURL}}/"
+    matchers:
+      - type: word
+        words:
+          - "found"
+`)
+	output := &bytes.Buffer{}
+	writer := bufio.NewWriter(output)
+	executer, err := NewHTTPExecuter(&HTTPOptions{Template: template, BulkHttpRequest: template.BulkRequestsHTTP[0], Writer: writer, Timeout: 5, JSON: true, Colorizer: aurora.NewAurora(false)})
+	require.Nil(t, err, "Could not create http executer")
+
+	for _, retried := range []bool{false, true} {
+		output.Reset()
+		ctx := context.Background()
+		if retried {
+			ctx = WithRetry(ctx)
+		}
+		result := executer.ExecuteHTTPWithContext(ctx, nil, server.URL, nil)
+		require.Nil(t, result.Error, "Could not execute http requests")
+
+		var line map[string]interface{}
+		require.Nil(t, json.Unmarshal(output.Bytes(), &line), "Could not unmarshal json output")
+		if retried {
+			require.Equal(t, true, line["retried"], "Could not mark the retried result")
+		} else {
+			require.NotContains(t, line, "retried", "Could not omit the retried mark")
+		}
+	}
+}
+
+func TestWriteLinesConcurrently(t *testing.T) {
+	f, err := ioutil.TempFile("", "output-*.txt")
+	require.Nil(t, err, "Could not create output file")
+	defer os.Remove(f.Name())
+
+	var wg sync.WaitGroup
+	for _, char := range []string{"a", "b", "c"} {
+		wg.Add(1)
+		go func(line string) {
+			defer wg.Done()
+			// the lines are longer than the buffers of the writers
+			writer := bufio.NewWriterSize(f, 16)
+			for i := 0; i < 100; i++ {
+				writeLines(writer, line)
+			}
+		}(strings.Repeat(char, 1000))
+	}
+	wg.Wait()
+	f.Close()
+
+	data, err := ioutil.ReadFile(f.Name())
+	require.Nil(t, err, "Could not read output file")
+	lines := strings.Split(strings.TrimSuffix(string(data), "\n"), "\n")
+	require.Len(t, lines, 300, "Could not write all the lines")
+	for _, line := range lines {
+		require.Len(t, line, 1000, "Could not write the lines whole")
+		require.Equal(t, strings.Repeat(line[:1], 1000), line, "Could not keep the lines from interleaving")
+	}
+}
+
+func TestStatusOutput(t *testing.T) {
+	template := &templates.Template{ID: "status", Info: templates.Info{Name: "Status", Severity: "low"}}
+
+	output := NewStatusOutput(template, "https://example.com", &Result{GotResults: true, Error: errors.New("second request failed")})
+	require.Equal(t, StatusMatched, output.Status, "Could not report the matched template")
+	require.Empty(t, output.Error, "Could not ignore the error of a matched template")
+
+	output = NewStatusOutput(template, "https://example.com", &Result{})
+	require.Equal(t, StatusNotMatched, output.Status, "Could not report the template not matching")
+	require.Nil(t, output.RequestIndex, "Could not omit the request index")
+
+	output = NewStatusOutput(template, "https://example.com", &Result{Error: errors.New("connection refused"), ErrorRequest: 2})
+	require.Equal(t, StatusErrored, output.Status, "Could not report the errored template")
+	require.Equal(t, "connection refused", output.Error, "Could not write the error")
+	require.Equal(t, 2, *output.RequestIndex, "Could not write the index of the failing request")
+}
+
+// testExporter records the json results sent to an exporter
+type testExporter struct {
+	mutex sync.Mutex
+	data  []string
+}
+
+func (e *testExporter) Export(data []byte) {
+	e.mutex.Lock()
+	defer e.mutex.Unlock()
+	e.data = append(e.data, string(data))
+}
+
+func (e *testExporter) IncludeRR() bool {
+	return true
+}
+
+func TestRedactedOutput(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Set-Cookie", "session=server-secret")
+		w.Write([]byte("found"))
+	}))
+	defer server.Close()
+
+	defer templates.SetEnvironment(false, false)
+	templates.SetEnvironment(true, false)
+	os.Setenv("NUCLEI_REDACT_TEST", "env-secret")
+	defer os.Unsetenv("NUCLEI_REDACT_TEST")
+	content := `
+id: redacted-output
+info:
+  name: redacted output
+  author: test
+requests:
+  - method: GET
+    path:
+      - '{{BaseURL}}/?key={{env("NUCLEI_REDACT_TEST")}}'
+    headers:
+      Authorization: Bearer header-secret
+      Cookie: c=cookie-secret
+    matchers:
+      - type: word
+        words:
+          - "found"
+`
+	secrets := []string{"server-secret", "env-secret", "header-secret", "cookie-secret", "custom-secret"}
+
+	execute := func(noRedact bool) (string, string) {
+		template := parseTemplate(t, content)
+
+		// the debug dumps are written to stderr
+		stderr := os.Stderr
+		reader, writer, err := os.Pipe()
+		require.Nil(t, err, "Could not create pipe")
+		os.Stderr = writer
+		defer func() { os.Stderr = stderr }()
+
+		output := &bytes.Buffer{}
+		exporter := &testExporter{}
+		executer, err := NewHTTPExecuter(&HTTPOptions{
+			Template:        template,
+			BulkHttpRequest: template.BulkRequestsHTTP[0],
+			Writer:          bufio.NewWriter(output),
+			Timeout:         5,
+			Debug:           true,
+			JSON:            true,
+			IncludeRR:       true,
+			CustomHeaders:   []string{"X-Custom: custom-secret"},
+			Exporters:       []export.Exporter{exporter},
+			Redactor:        redact.New([]string{"X-Custom"}),
+			NoRedact:        noRedact,
+			Colorizer:       aurora.NewAurora(false),
+		})
+		require.Nil(t, err, "Could not create http executer")
+		result := executer.ExecuteHTTP(nil, server.URL)
+		require.Nil(t, result.Error, "Could not execute http requests")
+
+		writer.Close()
+		debug, err := ioutil.ReadAll(reader)
+		require.Nil(t, err, "Could not read the debug output")
+		require.Len(t, exporter.data, 1, "Could not export the result")
+		return output.String() + exporter.data[0], string(debug)
+	}
+
+	written, debug := execute(false)
+	for _, secret := range secrets {
+		require.NotContains(t, written, secret, "Could not redact %s from the json output and the exports", secret)
+		require.NotContains(t, debug, secret, "Could not redact %s from the debug output", secret)
+	}
+	result := &ResultEvent{}
+	require.Nil(t, json.Unmarshal([]byte(strings.SplitN(written, "\n", 2)[0]), result), "Could not unmarshal json output")
+	require.Contains(t, result.Request, "Authorization: [REDACTED:20 bytes]\r\n", "Could not redact the header of the request")
+	require.Contains(t, result.Response, "Set-Cookie: [REDACTED:21 bytes]\r\n", "Could not redact the header of the response")
+	require.Contains(t, result.CurlCommand, "-H 'X-Custom: [REDACTED:13 bytes]'", "Could not redact the header of the curl command")
+	require.Contains(t, result.Matched, "key=[REDACTED:10 bytes]", "Could not redact the environment variable")
+
+	written, debug = execute(true)
+	for _, secret := range secrets {
+		require.Contains(t, written, secret, "Could not write %s as is without redaction", secret)
+		require.Contains(t, debug, secret, "Could not dump %s as is without redaction", secret)
+	}
+}
+
+func TestDNSRecordsOutput(t *testing.T) {
+	template := parseTemplate(t, `
+id: dns-records
+info:
+  name: dns records
+  author: test
+dns:
+  - name: "{{FQDN}}"
+    type: A
+    matchers:
+      - type: word
+        words:
+          - "IN"
+`)
+	output := &bytes.Buffer{}
+	executer, err := NewDNSExecuter(&DNSOptions{
+		Template:   template,
+		DNSRequest: template.RequestsDNS[0],
+		Writer:     bufio.NewWriter(output),
+		JSON:       true,
+		IncludeRR:  true,
+		Quiet:      true,
+	})
+	require.Nil(t, err, "Could not create dns executer")
+
+	msg := new(dns.Msg)
+	for _, record := range []string{
+		`www.example.com. 300 IN CNAME example.com.`,
+		`example.com. 300 IN A 93.184.216.34`,
+	} {
+		rr, err := dns.NewRR(record)
+		require.Nil(t, err, "Could not parse record")
+		msg.Answer = append(msg.Answer, rr)
+	}
+	rr, err := dns.NewRR(`example.com. 300 IN NS a.iana-servers.net.`)
+	require.Nil(t, err, "Could not parse record")
+	msg.Ns = append(msg.Ns, rr)
+
+	resolver, err := ParseResolver("8.8.8.8", time.Second)
+	require.Nil(t, err, "Could not parse resolver")
+	executer.writeOutputDNS(context.Background(), "www.example.com", resolver, &dnsrecords.Response{Msg: msg}, nil, nil)
+
+	var result struct {
+		Records map[string][]string `json:"records"`
+	}
+	require.Nil(t, json.Unmarshal(output.Bytes(), &result), "Could not decode dns result")
+	require.Len(t, result.Records[dnsrecords.AnswerSection], 2, "Could not write the answer records")
+	require.Len(t, result.Records[dnsrecords.AuthoritySection], 1, "Could not write the authority records")
+	require.Contains(t, result.Records[dnsrecords.AnswerSection][1], "93.184.216.34", "Could not write the record data")
+}
+
+func TestRedactedEnvHeader(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte("found"))
+	}))
+	defer server.Close()
+
+	defer templates.SetEnvironment(false, false)
+	templates.SetEnvironment(true, false)
+	os.Setenv("NUCLEI_REDACT_HEADER_TEST", "env-api-key-14")
+	defer os.Unsetenv("NUCLEI_REDACT_HEADER_TEST")
+	template := parseTemplate(t, `
+id: redacted-env-header
+info:
+  name: redacted env header
+  author: test
+requests:
+  - method: GET
+    path:
+      - "{{BaseURL}}/"
+    headers:
+      X-Api-Key: '{{env("NUCLEI_REDACT_HEADER_TEST")}}'
+    matchers:
+      - type: word
+        words:
+          - "found"
+`)
+	output := &bytes.Buffer{}
+	writer := bufio.NewWriter(output)
+	executer, err := NewHTTPExecuter(&HTTPOptions{Template: template, BulkHttpRequest: template.BulkRequestsHTTP[0], Writer: writer, Timeout: 5, JSON: true, IncludeRR: true, Redactor: redact.Default, Colorizer: aurora.NewAurora(false)})
+	require.Nil(t, err, "Could not create http executer")
+	result := executer.ExecuteHTTP(nil, server.URL)
+	require.Nil(t, result.Error, "Could not execute http requests")
+	executer.Close()
+
+	event := &ResultEvent{}
+	require.Nil(t, json.Unmarshal(output.Bytes(), event), "Could not unmarshal json output")
+	require.Contains(t, event.Request, "X-Api-Key: [REDACTED:14 bytes]\r\n", "Could not redact the environment variable of the header once")
+	require.Contains(t, event.CurlCommand, "-H 'X-Api-Key: [REDACTED:14 bytes]'", "Could not redact the header of the curl command")
+}
@@ -0,0 +1,39 @@
+package executer
+
+import (
+	"github.com/projectdiscovery/nuclei/v2/pkg/matchers"
+	"github.com/projectdiscovery/nuclei/v2/pkg/websocket"
+)
+
+// result returns the result of a run written by the sink, with the upgrade
+// request and the messages sent, and the upgrade response and the messages
+// read if required.
+func (e *WebsocketExecuter) result(target string, run *websocketRun, resp *websocket.Response, matcher *matchers.Matcher, extractorResults []string) *protocolResult {
+	return &protocolResult{
+		host:      target,
+		matched:   resp.URL,
+		matcher:   matcher,
+		extracted: extractorResults,
+		json: func(output *ResultEvent, sent, read bool) {
+			output.Error = resp.Error
+			if sent {
+				output.Request = run.String()
+			}
+			if read {
+				output.Response, output.ResponseEncoding = encodeRaw([]byte(resp.Upgrade + resp.String()))
+			}
+		},
+		evidence: func() (string, string) {
+			return run.String(), resp.Dump()
+		},
+	}
+}
+
+// redactHeader redacts the value of a sensitive header of an upgrade
+// request, i.e Cookie, unless disabled.
+func (e *WebsocketExecuter) redactHeader(name, value string) string {
+	if e.redactor == nil {
+		return value
+	}
+	return e.redactor.Header(name, e.template.Redact(value))
+}
@@ -0,0 +1,425 @@
+package executer
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"strings"
+	"sync"
+
+	"github.com/miekg/dns"
+	"github.com/pkg/errors"
+	"github.com/projectdiscovery/nuclei/v2/pkg/file"
+	"github.com/projectdiscovery/nuclei/v2/pkg/generators"
+	"github.com/projectdiscovery/nuclei/v2/pkg/headless"
+	"github.com/projectdiscovery/nuclei/v2/pkg/network"
+	"github.com/projectdiscovery/nuclei/v2/pkg/requests"
+)
+
+// PlannedRequest is a request an executer would send to a target, built
+// without being sent.
+type PlannedRequest struct {
+	// Method is the method of the http requests, the question type of the
+	// dns requests, the protocol of the network requests, WEBSOCKET, SSL or
+	// FILE.
+	Method string `json:"method"`
+	// URL is the URL of the http requests, the question name of the dns
+	// requests, the address of the network and the ssl requests, the url
+	// upgraded by the websocket requests or the path of the files read.
+	URL     string            `json:"url"`
+	Headers map[string]string `json:"headers,omitempty"`
+	// Raw is the request as it would be sent, redacted
+	Raw string `json:"raw"`
+	// Notes explain when the request would be sent differently
+	Notes []string `json:"notes,omitempty"`
+}
+
+// Plan is the requests an executer would send to a target
+type Plan struct {
+	Requests []*PlannedRequest `json:"requests"`
+	// Total is the number of requests to the target, the listed requests
+	// being the first ones if limited.
+	Total int64 `json:"total"`
+	// Resolvers are the resolvers the dns requests would be sent to
+	Resolvers []string `json:"resolvers,omitempty"`
+	// Error is the error building the next request if any, the scan
+	// abandoning the requests to the target with it.
+	Error string `json:"error,omitempty"`
+}
+
+// PlanHTTP builds the requests the executer would send to a URL with the
+// values of a previous template, the first limit ones if limit is more
+// than 0, without sending them. The values extracted from the responses
+// are left as their {{placeholders}}.
+func (e *HTTPExecuter) PlanHTTP(URL string, values map[string]interface{}, limit int) (*Plan, error) {
+	dynamicvalues, err := e.planValues(URL, values)
+	if err != nil {
+		return nil, err
+	}
+
+	plan := &Plan{Total: e.bulkHttpRequest.CountRequests()}
+	var dumpErr error
+	fuzzed, err := e.planRequests(URL, dynamicvalues, limit, func(request *requests.HttpRequest, data string) bool {
+		raw, err := e.rawRequest(request)
+		if err != nil {
+			dumpErr = errors.Wrap(err, "could not dump http request")
+			return false
+		}
+
+		planned := &PlannedRequest{
+			Method:  request.Request.Method,
+			URL:     request.Request.URL.String(),
+			Headers: make(map[string]string, len(request.Request.Header)),
+			Raw:     raw,
+			Notes:   e.requestNotes(URL, data),
+		}
+		if request.Fuzzing != nil {
+			planned.Notes = append(planned.Notes, fmt.Sprintf("fuzzing %s", request.Fuzzing))
+		}
+		for name, values := range request.Request.Header {
+			planned.Headers[name] = e.redactHeader(name, strings.Join(values, ", "))
+		}
+		plan.Requests = append(plan.Requests, planned)
+		return true
+	})
+	if dumpErr != nil {
+		return nil, dumpErr
+	}
+	plan.Total += fuzzed
+	if err != nil {
+		plan.Error = err.Error()
+	}
+	return plan, nil
+}
+
+// ExportHTTP exports all the requests the executer would send to a URL
+// with the values of a previous template, without sending them, returning
+// the number of requests exported. The values extracted from the responses
+// are left as their {{placeholders}}, and the requests following one which
+// can't be built are not exported.
+func (e *HTTPExecuter) ExportHTTP(URL string, values map[string]interface{}) (int, error) {
+	dynamicvalues, err := e.planValues(URL, values)
+	if err != nil {
+		return 0, err
+	}
+
+	var exported int
+	var exportErr error
+	_, err = e.planRequests(URL, dynamicvalues, 0, func(request *requests.HttpRequest, data string) bool {
+		if exportErr = e.exportRequest(URL, request); exportErr != nil {
+			return false
+		}
+		exported++
+		return true
+	})
+	if exportErr != nil {
+		return exported, errors.Wrap(exportErr, "could not export http request")
+	}
+	return exported, err
+}
+
+// planValues returns the values the requests to a URL are planned with,
+// the values of a previous template along with the variables, the values
+// extracted from the responses being replaced with their placeholders.
+func (e *HTTPExecuter) planValues(URL string, values map[string]interface{}) (map[string]interface{}, error) {
+	variables, err := e.variables(URL, values)
+	if err != nil {
+		return nil, errors.Wrap(err, "could not evaluate variables")
+	}
+	dynamicvalues := make(map[string]interface{})
+	for name, value := range values {
+		dynamicvalues[name] = value
+	}
+	for name, value := range variables {
+		dynamicvalues[name] = value
+	}
+	// the placeholders are replaced with themselves to build the requests
+	for _, extractor := range e.bulkHttpRequest.Extractors {
+		if _, ok := dynamicvalues[extractor.Name]; !ok && extractor.Name != "" {
+			dynamicvalues[extractor.Name] = "{{" + extractor.Name + "}}"
+		}
+	}
+	if _, ok := dynamicvalues["value"]; !ok && e.bulkHttpRequest.IterateAll {
+		dynamicvalues["value"] = "{{value}}"
+	}
+	return dynamicvalues, nil
+}
+
+// planRequests builds the requests to a URL in turn, the first limit ones
+// if limit is more than 0, passing each of them to planned along with the
+// path or the raw request it was built from until it returns false. The
+// requests with fuzzing rules are planned as their fuzzed requests, the
+// number of requests they add being returned. The error building a
+// request stops the requests and is returned.
+func (e *HTTPExecuter) planRequests(URL string, dynamicvalues map[string]interface{}, limit int, planned func(request *requests.HttpRequest, data string) bool) (int64, error) {
+	// the generator of the URL is forgotten once planned
+	e.bulkHttpRequest.CreateGenerator(URL)
+	defer e.bulkHttpRequest.DeleteGenerator(URL)
+	defer e.bulkHttpRequest.StopGenerator(URL)
+	var count int
+	var added int64
+	for e.bulkHttpRequest.Next(URL) && (limit <= 0 || count < limit) {
+		data := e.bulkHttpRequest.Current(URL)
+		request, err := e.buildRequest(URL, dynamicvalues, data)
+		if err == requests.ErrPayloadsDone {
+			e.bulkHttpRequest.Increment(URL)
+			continue
+		}
+		if err != nil {
+			return added, errors.Wrap(err, "could not build http request")
+		}
+		if len(e.bulkHttpRequest.Fuzzing) == 0 {
+			if !planned(request, data) {
+				return added, nil
+			}
+			count++
+			e.bulkHttpRequest.Increment(URL)
+			continue
+		}
+
+		parameters, total, err := e.fuzzedParameters(request)
+		if err != nil {
+			return added, errors.Wrap(err, "could not fuzz http request")
+		}
+		added += int64(total - 1)
+		stopped := false
+		err = e.fuzz(request, parameters, func(fuzzed *requests.HttpRequest) bool {
+			if limit > 0 && count >= limit {
+				return false
+			}
+			if !planned(fuzzed, data) {
+				stopped = true
+				return false
+			}
+			count++
+			return true
+		})
+		if err != nil {
+			return added, errors.Wrap(err, "could not fuzz http request")
+		}
+		if stopped {
+			return added, nil
+		}
+		e.bulkHttpRequest.Increment(URL)
+	}
+	return added, nil
+}
+
+// requestNotes returns the notes of the current request to a URL, sent
+// if its guard holds and with the values extracted from the responses.
+func (e *HTTPExecuter) requestNotes(URL, data string) []string {
+	var notes []string
+	if _, ok := e.bulkHttpRequest.Guard(e.bulkHttpRequest.Position(URL)); ok {
+		notes = append(notes, "sent if its run-if guard holds")
+	}
+	for _, extractor := range e.bulkHttpRequest.Extractors {
+		if extractor.Name == "" || !strings.Contains(data, "{{"+extractor.Name+"}}") {
+			continue
+		}
+		if e.bulkHttpRequest.IterateAll {
+			notes = append(notes, fmt.Sprintf("sent once per value extracted by %s", extractor.Name))
+		} else {
+			notes = append(notes, fmt.Sprintf("sent with the value extracted by %s, skipped without", extractor.Name))
+		}
+	}
+	if e.bulkHttpRequest.IterateAll && strings.Contains(data, "{{value}}") {
+		notes = append(notes, "sent once per extracted value")
+	}
+	return notes
+}
+
+// PlanDNS builds the requests the executer would send to a target with the
+// values of a previous template, the first limit ones if limit is more
+// than 0 for the PTR requests towards a cidr range, without sending them.
+func (e *DNSExecuter) PlanDNS(URL string, values map[string]interface{}, limit int) (*Plan, error) {
+	domain, server, err := dnsTarget(URL)
+	if err != nil {
+		return nil, err
+	}
+	domains := []string{domain}
+	if e.dnsRequest.IsPTR() && isCIDR(domain) {
+		domains = expandCIDR(domain, e.ptrCIDRLimit)
+	}
+
+	plan := &Plan{Total: int64(len(domains))}
+	for _, domain := range domains {
+		if limit > 0 && len(plan.Requests) >= limit {
+			break
+		}
+		_, msg, err := e.buildRequest(domain, values)
+		if err != nil {
+			plan.Error = err.Error()
+			return plan, nil
+		}
+		question := msg.Question[0]
+		planned := &PlannedRequest{
+			Method: dns.TypeToString[question.Qtype],
+			URL:    question.Name,
+			Raw:    e.redact(msg.String()),
+		}
+		switch {
+		case e.dnsRequest.Trace:
+			planned.Notes = append(planned.Notes, "sent to the nameservers of the delegation chain from the root servers")
+		case question.Qtype == dns.TypeAXFR && server == "":
+			planned.Notes = append(planned.Notes, "sent to the nameservers of the zone, looked up with the resolvers")
+		}
+		plan.Requests = append(plan.Requests, planned)
+	}
+
+	if server != "" {
+		plan.Resolvers = []string{server}
+	} else if !e.dnsRequest.Trace {
+		plan.Resolvers = e.resolvers.Resolvers()
+	}
+	return plan, nil
+}
+
+// SortedHeaders returns the names of the headers of a planned request in
+// order.
+func (r *PlannedRequest) SortedHeaders() []string {
+	names := make([]string, 0, len(r.Headers))
+	for name := range r.Headers {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// PlanHeadless builds the steps the executer would run in a page towards a
+// URL with the values of a previous template, without running them.
+func (e *HeadlessExecuter) PlanHeadless(URL string, values map[string]interface{}) (*Plan, error) {
+	_, actions, err := e.buildActions(URL, values)
+	if err != nil {
+		return nil, err
+	}
+	planned := &PlannedRequest{Method: "HEADLESS", URL: URL, Raw: e.redact(actionsString(actions))}
+	for _, action := range actions {
+		if action.Action == headless.NavigateAction {
+			planned.URL = action.Args["url"]
+			break
+		}
+	}
+	planned.Notes = append(planned.Notes, fmt.Sprintf("run in a browser page, %d steps", len(actions)))
+	return &Plan{Requests: []*PlannedRequest{planned}, Total: 1}, nil
+}
+
+// PlanNetwork builds the steps the executer would send to each address of
+// a target with the values of a previous template, the first limit ones if
+// limit is more than 0, without sending them.
+func (e *NetworkExecuter) PlanNetwork(target string, values map[string]interface{}, limit int) (*Plan, error) {
+	variables, addresses, err := e.buildAddresses(target, values, e.networkRequest.MakeAddresses)
+	if err != nil {
+		return nil, err
+	}
+	method := strings.ToUpper(e.networkRequest.Protocol)
+	if method == "" {
+		method = strings.ToUpper(network.TCPProtocol)
+	}
+
+	plan := &Plan{Total: e.networkRequest.GetRequestCount()}
+	for _, address := range addresses {
+		done := make(chan struct{})
+		for payloadValues := range e.networkRequest.PayloadValues(done) {
+			if limit > 0 && len(plan.Requests) >= limit {
+				break
+			}
+			inputs := e.networkRequest.MakeInputs(target, generators.MergeMaps(variables, payloadValues))
+			planned := &PlannedRequest{Method: method, URL: address, Raw: e.redact(inputsString(inputs))}
+			if e.networkRequest.TLS {
+				planned.Notes = append(planned.Notes, "sent over tls")
+			}
+			plan.Requests = append(plan.Requests, planned)
+		}
+		close(done)
+	}
+	return plan, nil
+}
+
+// PlanWebsocket builds the upgrades and the messages the executer would
+// send to each url of a base URL with the values of a previous template,
+// the first limit ones if limit is more than 0, without sending them.
+func (e *WebsocketExecuter) PlanWebsocket(URL string, values map[string]interface{}, limit int) (*Plan, error) {
+	variables, err := e.buildVariables(URL, values)
+	if err != nil {
+		return nil, err
+	}
+
+	plan := &Plan{Total: e.websocketRequest.GetRequestCount()}
+	done := make(chan struct{})
+	defer close(done)
+	for payloadValues := range e.websocketRequest.PayloadValues(done) {
+		runs, err := e.buildRuns(URL, generators.MergeMaps(variables, payloadValues))
+		if err != nil {
+			return nil, err
+		}
+		for _, run := range runs {
+			if limit > 0 && len(plan.Requests) >= limit {
+				return plan, nil
+			}
+			headers := make(map[string]string, len(run.Headers))
+			for name, value := range run.Headers {
+				headers[name] = e.redactHeader(name, value)
+			}
+			planned := &PlannedRequest{Method: "WEBSOCKET", URL: run.URL, Headers: headers, Raw: e.redact(run.String())}
+			if strings.HasPrefix(run.URL, "wss://") || strings.HasPrefix(run.URL, "https://") {
+				planned.Notes = append(planned.Notes, "upgraded over tls")
+			}
+			plan.Requests = append(plan.Requests, planned)
+		}
+	}
+	return plan, nil
+}
+
+// PlanSSL builds the handshakes the executer would run with each address
+// of a target with the values of a previous template, the first limit ones
+// if limit is more than 0, without connecting.
+func (e *SSLExecuter) PlanSSL(target string, values map[string]interface{}, limit int) (*Plan, error) {
+	variables, addresses, err := e.buildAddresses(target, values, e.sslRequest.MakeAddresses)
+	if err != nil {
+		return nil, err
+	}
+
+	plan := &Plan{Total: e.sslRequest.GetRequestCount()}
+	for _, address := range addresses {
+		done := make(chan struct{})
+		for payloadValues := range e.sslRequest.PayloadValues(done) {
+			if limit > 0 && len(plan.Requests) >= limit {
+				break
+			}
+			options := e.sslRequest.MakeOptions(target, generators.MergeMaps(variables, payloadValues), e.timeout)
+			planned := &PlannedRequest{Method: "SSL", URL: address, Raw: e.redact(handshakeString(address, options))}
+			if options.StartTLS != "" {
+				planned.Notes = append(planned.Notes, "upgraded with "+options.StartTLS+" starttls")
+			}
+			plan.Requests = append(plan.Requests, planned)
+		}
+		close(done)
+	}
+	return plan, nil
+}
+
+// PlanFile lists the files of a target the executer would read, the first
+// limit ones if limit is more than 0, walking the target without running
+// the matchers. The total is the number of files read.
+func (e *FileExecuter) PlanFile(target string, limit int) (*Plan, error) {
+	var paths []string
+	mutex := &sync.Mutex{}
+	err := file.Walk(context.Background(), target, e.fileRequest.WalkOptions(e.concurrency), func(resp *file.Response) {
+		mutex.Lock()
+		paths = append(paths, resp.Path)
+		mutex.Unlock()
+	})
+	if err != nil {
+		return nil, err
+	}
+	sort.Strings(paths)
+
+	plan := &Plan{Total: int64(len(paths))}
+	for _, path := range paths {
+		if limit > 0 && len(plan.Requests) >= limit {
+			break
+		}
+		plan.Requests = append(plan.Requests, &PlannedRequest{Method: "FILE", URL: path})
+	}
+	return plan, nil
+}
@@ -0,0 +1,150 @@
+package executer
+
+import (
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync/atomic"
+	"testing"
+
+	"github.com/logrusorgru/aurora"
+	"github.com/projectdiscovery/nuclei/v2/pkg/burp"
+	"github.com/projectdiscovery/nuclei/v2/pkg/requests"
+	"github.com/stretchr/testify/require"
+)
+
+func TestPlanHTTP(t *testing.T) {
+	var received int32
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		atomic.AddInt32(&received, 1)
+	}))
+	defer server.Close()
+
+	template := parseTemplate(t, `
+id: planned-requests
+info:
+  name: planned requests
+  author: test
+requests:
+  - raw:
+      - |
+        POST /login HTTP/1.1
+        Host: {{Hostname}}
+        Content-Type: application/x-www-form-urlencoded
+
+        login={{account}}&token={{session}}
+    payloads:
+      account:
+        - admin
+        - root
+        - guest
+    extractors:
+      - type: regex
+        name: session
+        internal: true
+        regex:
+          - "token=([a-z]+)"
+`)
+	executer, err := NewHTTPExecuter(&HTTPOptions{Template: template, BulkHttpRequest: template.BulkRequestsHTTP[0], Timeout: 5, CustomHeaders: requests.CustomHeaders{"X-Scan: planned"}, Colorizer: aurora.NewAurora(false)})
+	require.Nil(t, err, "Could not create http executer")
+
+	plan, err := executer.PlanHTTP(server.URL, nil, 2)
+	require.Nil(t, err, "Could not plan http requests")
+	require.Equal(t, int64(3), plan.Total, "Could not count the requests of the payloads")
+	require.Len(t, plan.Requests, 2, "Could not limit the planned requests")
+	require.Equal(t, "POST", plan.Requests[0].Method, "Could not plan the method")
+	require.Equal(t, server.URL+"/login", plan.Requests[0].URL, "Could not plan the URL")
+	require.Equal(t, "planned", plan.Requests[0].Headers["X-Scan"], "Could not plan the custom headers")
+	require.Contains(t, plan.Requests[0].Raw, "login=admin&token={{session}}", "Could not plan the body with the payloads and the placeholders")
+	require.Contains(t, plan.Requests[1].Raw, "login=root", "Could not plan the next payloads")
+	require.Equal(t, []string{"sent with the value extracted by session, skipped without"}, plan.Requests[0].Notes, "Could not note the extracted values")
+	require.Zero(t, atomic.LoadInt32(&received), "Could not plan without sending requests")
+
+	// the requests are planned again from the first payloads
+	plan, err = executer.PlanHTTP(server.URL, nil, 0)
+	require.Nil(t, err, "Could not plan http requests again")
+	require.Contains(t, plan.Requests[0].Raw, "login=admin", "Could not plan the first payloads again")
+	require.Len(t, plan.Requests, 3, "Could not plan all the requests")
+	require.Empty(t, plan.Error, "Could fail once the payloads are exhausted")
+}
+
+func TestExportHTTP(t *testing.T) {
+	dir, err := ioutil.TempDir("", "nuclei-export")
+	require.Nil(t, err, "Could not create directory")
+	defer os.RemoveAll(dir)
+	exporter, err := burp.New(burp.Options{Directory: dir, Markers: true})
+	require.Nil(t, err, "Could not create exporter")
+
+	template := parseTemplate(t, `
+id: exported-requests
+info:
+  name: exported requests
+  author: test
+requests:
+  - raw:
+      - |
+        POST /login HTTP/1.1
+        Host: {{Hostname}}
+        Authorization: Bearer secret-token
+
+        login={{account}}
+    payloads:
+      account:
+        - admin
+        - root
+    matchers:
+      - type: status
+        status:
+          - 200
+`)
+	executer, err := NewHTTPExecuter(&HTTPOptions{Template: template, BulkHttpRequest: template.BulkRequestsHTTP[0], Timeout: 5, RequestExporter: exporter, Colorizer: aurora.NewAurora(false)})
+	require.Nil(t, err, "Could not create http executer")
+
+	// all the requests are exported whatever the limit of the plan
+	exported, err := executer.ExportHTTP("http://example.com", nil)
+	require.Nil(t, err, "Could not export http requests")
+	require.Equal(t, 2, exported, "Could not export the requests of the payloads")
+	require.Nil(t, exporter.Close(), "Could not close exporter")
+
+	data, err := ioutil.ReadFile(filepath.Join(dir, "000002-exported-requests.txt"))
+	require.Nil(t, err, "Could not write the exported request")
+	require.True(t, strings.HasPrefix(string(data), "POST /login HTTP/1.1\r\nHost: example.com\r\n"), "Could not export the request as sent")
+	require.Contains(t, string(data), "\r\nContent-Length: 12\r\n", "Could not export the headers written on the wire")
+	require.True(t, strings.HasSuffix(string(data), "\r\n\r\nlogin=§root§\n\n"), "Could not mark the payload values")
+	require.NotContains(t, string(data), "secret-token", "Could not redact the exported request")
+}
+
+func TestPlanDNS(t *testing.T) {
+	template := parseTemplate(t, `
+id: planned-queries
+info:
+  name: planned queries
+  author: test
+dns:
+  - name: "{{FQDN}}"
+    type: PTR
+    resolvers:
+      - 127.0.0.1:5353
+    matchers:
+      - type: word
+        words:
+          - "PTR"
+`)
+	executer, err := NewDNSExecuter(&DNSOptions{Template: template, DNSRequest: template.RequestsDNS[0], Timeout: 5, Colorizer: aurora.NewAurora(false)})
+	require.Nil(t, err, "Could not create dns executer")
+
+	plan, err := executer.PlanDNS("192.0.2.0/30", nil, 2)
+	require.Nil(t, err, "Could not plan dns requests")
+	require.Equal(t, int64(4), plan.Total, "Could not count the requests of the cidr range")
+	require.Len(t, plan.Requests, 2, "Could not limit the planned requests")
+	require.Equal(t, "PTR", plan.Requests[0].Method, "Could not plan the question type")
+	require.Equal(t, "0.2.0.192.in-addr.arpa.", plan.Requests[0].URL, "Could not plan the question name")
+	require.Equal(t, []string{"127.0.0.1:5353"}, plan.Resolvers, "Could not plan the resolvers of the template")
+
+	plan, err = executer.PlanDNS("192.0.2.1:53", nil, 0)
+	require.Nil(t, err, "Could not plan dns requests to a server")
+	require.Equal(t, []string{"192.0.2.1:53"}, plan.Resolvers, "Could not plan the dns server of the target")
+}
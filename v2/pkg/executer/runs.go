@@ -0,0 +1,83 @@
+package executer
+
+import (
+	"context"
+	"time"
+
+	"github.com/pkg/errors"
+	"github.com/projectdiscovery/nuclei/v2/pkg/generators"
+	"github.com/projectdiscovery/nuclei/v2/pkg/requests"
+)
+
+// requestTimeout returns the time the connections of a request can take,
+// the timeout of the request taking precedence over the global one unless
+// the options are the effective values.
+func requestTimeout(timeout int, resolved bool, requestTimeout int) time.Duration {
+	if !resolved && requestTimeout > 0 {
+		return time.Duration(requestTimeout) * time.Second
+	}
+	return timeoutOrDefault(timeout)
+}
+
+// mergeResult adds the matches and the extractions of a run to the result
+// of a request if the run found results.
+func mergeResult(result *Result, found Result) {
+	if !found.GotResults {
+		return
+	}
+	result.GotResults = true
+	for name, value := range found.Matches {
+		result.Matches[name] = value
+	}
+	for name, value := range found.Extractions {
+		result.Extractions[name] = value
+	}
+}
+
+// buildAddresses builds the addresses a request connects to for a target,
+// along with the values of the template for the target.
+func (s *resultSink) buildAddresses(target string, values map[string]interface{}, makeAddresses func(target string, values map[string]interface{}) ([]string, error)) (map[string]interface{}, []string, error) {
+	// The variables of the template are evaluated once per target
+	variables, err := s.template.EvaluateVariables(generators.MergeMaps(values, requests.NetworkTargetValues(target)))
+	if err != nil {
+		return nil, nil, errors.Wrap(err, "could not evaluate variables")
+	}
+	variables = generators.MergeMaps(values, variables)
+
+	addresses, err := makeAddresses(target, variables)
+	if err != nil {
+		return nil, nil, errors.Wrapf(err, "could not make %s addresses", s.protocol)
+	}
+	return variables, addresses, nil
+}
+
+// runAddresses runs a request with each address for each combination of
+// its payloads until the context is done. The errors of the connections
+// are only returned if no result was found, the matchers of the error part
+// possibly matching them.
+func runAddresses(ctx context.Context, addresses []string, payloads func(done <-chan struct{}) <-chan map[string]interface{}, run func(address string, payloadValues map[string]interface{}) (Result, error)) (result Result) {
+	result.Matches = make(map[string]interface{})
+	result.Extractions = make(map[string]interface{})
+
+	var connErr error
+	for _, address := range addresses {
+		done := make(chan struct{})
+		for payloadValues := range payloads(done) {
+			found, err := run(address, payloadValues)
+			if err != nil && ctx.Err() != nil {
+				close(done)
+				result.Error = err
+				return
+			}
+			if err != nil && connErr == nil {
+				connErr = err
+			}
+			mergeResult(&result, found)
+		}
+		close(done)
+	}
+	if !result.GotResults && connErr != nil {
+		result.Error = connErr
+	}
+	return
+}
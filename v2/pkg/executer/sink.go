@@ -0,0 +1,482 @@
+package executer
+
+import (
+	"bufio"
+	"context"
+	"fmt"
+	"os"
+	"regexp"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/logrusorgru/aurora"
+	"github.com/projectdiscovery/gologger"
+	"github.com/projectdiscovery/nuclei/v2/pkg/benchmark"
+	"github.com/projectdiscovery/nuclei/v2/pkg/collector"
+	"github.com/projectdiscovery/nuclei/v2/pkg/dedupe"
+	"github.com/projectdiscovery/nuclei/v2/pkg/exclusions"
+	"github.com/projectdiscovery/nuclei/v2/pkg/export"
+	"github.com/projectdiscovery/nuclei/v2/pkg/extractors"
+	"github.com/projectdiscovery/nuclei/v2/pkg/grouping"
+	"github.com/projectdiscovery/nuclei/v2/pkg/markdown"
+	"github.com/projectdiscovery/nuclei/v2/pkg/matchers"
+	"github.com/projectdiscovery/nuclei/v2/pkg/ratelimit"
+	"github.com/projectdiscovery/nuclei/v2/pkg/redact"
+	"github.com/projectdiscovery/nuclei/v2/pkg/regexguard"
+	"github.com/projectdiscovery/nuclei/v2/pkg/sarif"
+	"github.com/projectdiscovery/nuclei/v2/pkg/stats"
+	"github.com/projectdiscovery/nuclei/v2/pkg/templates"
+)
+
+// CommonOptions contains the configuration options shared by the executers
+// of the headless, network, file, websocket and ssl requests.
+type CommonOptions struct {
+	Debug        bool
+	JSON         bool
+	JSONRequests bool
+	Writer       *bufio.Writer
+	// IncludeRR writes the requests sent and the responses read in JSON
+	// output, as described by the options of each protocol.
+	IncludeRR bool
+	// Exclusions suppress the results matching known false positives
+	Exclusions *exclusions.Exclusions
+	// ShowSuppressed shows the results suppressed by the exclusions
+	ShowSuppressed bool
+	// Collector collects the extracted values of the scan if any
+	Collector *collector.Collector
+	// Files are the files the values of the extractors are appended to if any
+	Files *collector.Files
+	// RegexGuard limits the length of the responses the regexes are applied
+	// to, the default limit applying if nil.
+	RegexGuard *regexguard.Guard
+	// Exporter collects the results of the scan into a SARIF log if any
+	Exporter *sarif.Exporter
+	// Markdown writes the evidence of the results to a markdown report if any
+	Markdown *markdown.Exporter
+	// Exporters send the json results to external services, such as
+	// elasticsearch or a webhook.
+	Exporters []export.Exporter
+	// Deduper suppresses the findings reported before by the run or by a
+	// previous run if any.
+	Deduper *dedupe.Deduper
+	// ShowDuplicates writes the suppressed duplicates to the json output
+	ShowDuplicates bool
+	// Stats count the requests and findings of the scan if any
+	Stats *stats.Stats
+	// Benchmark records the timings of the runs and the requests of the
+	// template if any.
+	Benchmark *benchmark.Recorder
+	// DiscardResults counts the results in the stats without writing,
+	// exporting or deduplicating them, for -benchmark-no-output.
+	DiscardResults bool
+	// Redactor redacts the sensitive values of everything written, the
+	// default ones being redacted if nil.
+	Redactor *redact.Redactor
+	// NoRedact writes the sensitive values and the secrets of the
+	// templates as is, for debugging.
+	NoRedact bool
+	// Grouper buffers the results shown on screen by host if any, the
+	// json output and the exports being written as they are found.
+	Grouper *grouping.Grouper
+	// Quiet shows no results on screen, the output file and the exports
+	// being written, for the programs embedding the engine.
+	Quiet bool
+	// RateLimiter limits the rate of the runs towards each host shared by
+	// the executers if any.
+	RateLimiter *ratelimit.Limiter
+	// PassiveExtract writes only the extracted values of extractor-only requests
+	PassiveExtract bool
+
+	ColoredOutput bool
+	Colorizer     aurora.Aurora
+	Decolorizer   *regexp.Regexp
+}
+
+// resultSink evaluates the matchers and the extractors of a request of a
+// template and writes its results to the output file, the screen and the
+// exports. It is embedded by the executers sharing the CommonOptions.
+type resultSink struct {
+	// protocol is the type of the results, i.e network
+	protocol string
+	template *templates.Template
+
+	debug       bool
+	jsonOutput  bool
+	jsonRequest bool
+	includeRR   bool
+	// exclusions suppress the results matching known false positives
+	exclusions     *exclusions.Exclusions
+	showSuppressed bool
+
+	// collector collects the extracted values of the scan into a single file
+	collector *collector.Collector
+	// files are the files the values of the extractors are appended to
+	files *collector.Files
+	// regexGuard limits the length of the responses the regexes are applied to
+	regexGuard *regexguard.Guard
+	// exporter collects the results of the scan into a SARIF log
+	exporter *sarif.Exporter
+	// markdown writes the evidence of the results to a markdown report
+	markdown *markdown.Exporter
+	// exporters send the json results to external services
+	exporters []export.Exporter
+	// deduper suppresses the findings reported before if any
+	deduper        *dedupe.Deduper
+	showDuplicates bool
+	// stats count the requests and findings of the scan if any
+	stats *stats.Stats
+	// benchmark records the timings of the runs and the requests if any
+	benchmark *benchmark.Recorder
+	// discardResults only counts the results in the stats
+	discardResults bool
+	// redactor redacts the sensitive values written, nil with -no-redact
+	redactor *redact.Redactor
+	// grouper buffers the results shown on screen by host if any
+	grouper *grouping.Grouper
+	// quiet shows no results on screen
+	quiet bool
+	// rateLimiter limits the rate of the runs towards each host if any
+	rateLimiter *ratelimit.Limiter
+	// extractedOnly writes only the values of extractor-only requests
+	extractedOnly bool
+
+	writer      *bufio.Writer
+	outputMutex *sync.Mutex
+
+	coloredOutput bool
+	colorizer     aurora.Aurora
+	decolorizer   *regexp.Regexp
+}
+
+// newResultSink creates the sink of the results of a protocol for a
+// request of a template with matchers.
+func newResultSink(protocol string, template *templates.Template, requestMatchers []*matchers.Matcher, options *CommonOptions) resultSink {
+	return resultSink{
+		protocol:       protocol,
+		template:       template,
+		debug:          options.Debug,
+		jsonOutput:     options.JSON,
+		jsonRequest:    options.JSONRequests,
+		includeRR:      options.IncludeRR,
+		exclusions:     options.Exclusions,
+		showSuppressed: options.ShowSuppressed,
+		collector:      options.Collector,
+		files:          options.Files,
+		regexGuard:     options.RegexGuard,
+		exporter:       options.Exporter,
+		markdown:       options.Markdown,
+		exporters:      options.Exporters,
+		deduper:        options.Deduper,
+		showDuplicates: options.ShowDuplicates,
+		stats:          options.Stats,
+		benchmark:      options.Benchmark,
+		discardResults: options.DiscardResults,
+		redactor:       newRedactor(options.Redactor, options.NoRedact),
+		grouper:        options.Grouper,
+		quiet:          options.Quiet,
+		rateLimiter:    options.RateLimiter,
+		extractedOnly:  options.PassiveExtract && len(requestMatchers) == 0,
+		writer:         options.Writer,
+		outputMutex:    &sync.Mutex{},
+		coloredOutput:  options.ColoredOutput,
+		colorizer:      options.Colorizer,
+		decolorizer:    options.Decolorizer,
+	}
+}
+
+// protocolResult is a result of a request written by a resultSink
+type protocolResult struct {
+	// host is the target the request was run towards
+	host string
+	// matched is what the request matched, i.e the url of the page or the
+	// address connected to.
+	matched string
+	// located is the matched value shown and reported if it differs, i.e
+	// the path of a file along with the lines of the matches.
+	located string
+	// key is the value the result is deduplicated by if it differs
+	key string
+
+	matcher   *matchers.Matcher
+	extracted []string
+
+	// json adds the fields of the protocol to the json output, along with
+	// the request sent and the response read if asked.
+	json func(output *ResultEvent, request, response bool)
+	// evidence returns the request and the response of the markdown report
+	evidence func() (request, response string)
+}
+
+// reported returns the matched value of a result shown and reported
+func (r *protocolResult) reported() string {
+	if r.located != "" {
+		return r.located
+	}
+	return r.matched
+}
+
+// dedupeKey returns the value a result is deduplicated by
+func (r *protocolResult) dedupeKey() string {
+	if r.key != "" {
+		return r.key
+	}
+	return r.matched
+}
+
+// operators are the matchers and the extractors of a request evaluated
+// over a response by a resultSink.
+type operators struct {
+	condition  matchers.ConditionType
+	matchers   []*matchers.Matcher
+	extractors []*extractors.Extractor
+	// match and extract run a matcher and an extractor over the response
+	match   func(matcher *matchers.Matcher) bool
+	extract func(extractor *extractors.Extractor) []string
+	// target is the target shown along with the suppressed results
+	target string
+	// exclude returns the index of the exclusion matching the response,
+	// or -1 if the result isn't suppressed.
+	exclude func(exclusions *exclusions.Exclusions) int
+	// result returns the result to write for a matcher, nil for the AND
+	// condition and the extractor-only requests.
+	result func(matcher *matchers.Matcher, extracted []string) *protocolResult
+}
+
+// evaluate runs the matchers and the extractors of a request over a
+// response and writes its results. Requests without matchers only have a
+// result for non-empty extractions.
+func (s *resultSink) evaluate(ctx context.Context, ops *operators) (result Result) {
+	result.Matches = make(map[string]interface{})
+	result.Extractions = make(map[string]interface{})
+
+	var matched []*matchers.Matcher
+	for _, matcher := range ops.matchers {
+		// Check if the matcher matched
+		if !ops.match(matcher) {
+			// If the condition is AND we haven't matched, return.
+			if ops.condition == matchers.ANDCondition {
+				return
+			}
+		} else if ops.condition == matchers.ORCondition {
+			// If the matcher has matched, and its an OR
+			// keep it to write a result for each distinct matcher.
+			matched = append(matched, matcher)
+		}
+	}
+
+	// All matchers have successfully completed so now start with the
+	// next task which is extraction of input from matchers.
+	var extractorResults []string
+	for _, extractor := range ops.extractors {
+		matches := ops.extract(extractor)
+		writeToFile(s.files, s.template.ID, extractor, matches)
+		for _, match := range matches {
+			if !extractor.Internal {
+				extractorResults = append(extractorResults, match)
+			}
+		}
+		if len(matches) > 0 {
+			result.Extractions[extractor.Name] = matches
+		}
+	}
+
+	// Results of responses matching an exclusion are suppressed
+	andMatched := ops.condition == matchers.ANDCondition && len(ops.matchers) > 0
+	hasResults := len(matched) > 0 || len(extractorResults) > 0 || andMatched
+	if hasResults && s.isSuppressed(ops.target, ops.exclude) {
+		return
+	}
+	collect(s.collector, extractorResults)
+
+	// Write a result for each distinct matcher of an OR condition along
+	// with the extracted values, so each finding is self-contained.
+	if len(matched) > 0 {
+		for _, matcher := range distinctMatchers(matched) {
+			result.Matches[matcher.Name] = nil
+			s.write(ctx, ops.result(matcher, extractorResults))
+		}
+		result.GotResults = true
+		return
+	}
+
+	// Write a final string of output if matcher type is
+	// AND or if we have extractors for the mechanism too.
+	if len(extractorResults) > 0 || andMatched {
+		if andMatched {
+			for _, matcher := range ops.matchers {
+				result.Matches[matcher.Name] = nil
+			}
+		}
+		s.write(ctx, ops.result(nil, extractorResults))
+		result.GotResults = true
+	}
+	return
+}
+
+// isSuppressed returns true if a response matches an exclusion, showing
+// the suppressed result if asked for auditing.
+func (s *resultSink) isSuppressed(target string, exclude func(exclusions *exclusions.Exclusions) int) bool {
+	if s.exclusions == nil || exclude == nil {
+		return false
+	}
+	index := exclude(s.exclusions)
+	if index == -1 {
+		return false
+	}
+	if s.showSuppressed {
+		gologger.Infof("[%s] Suppressed result for %s by exclusion %d\n", s.template.ID, target, index)
+	}
+	return true
+}
+
+// write writes a result to streams
+func (s *resultSink) write(ctx context.Context, result *protocolResult) {
+	retried := isRetry(ctx)
+	// The discarded results are counted without being recorded as reported
+	if s.discardResults {
+		s.stats.Finding(s.template.ID, s.template.Info.Severity)
+		return
+	}
+	// Findings reported before are only written to the json output if asked
+	if status := checkDuplicate(s.deduper, s.template.ID, result.dedupeKey(), result.matcher, result.extracted); status != dedupe.NewFinding {
+		if s.showDuplicates && s.jsonOutput {
+			output := s.jsonResult(result, s.jsonRequest, s.includeRR)
+			output.Dedupe = status.String()
+			output.Retried = retried
+			if data, ok := marshalResult(output, s.redact); ok {
+				writeJSON(s.writer, s.quiet, data)
+			}
+		}
+		return
+	}
+	s.stats.Finding(s.template.ID, s.template.Info.Severity)
+
+	exportSarif(s.exporter, s.template, s.redact, result.reported(), result.matcher, result.extracted)
+	if s.markdown != nil {
+		exportMarkdown(s.markdown, s.markdownFinding(result))
+	}
+	exportJSON(s.exporters, s.redact, func(includeRR bool) *ResultEvent {
+		output := s.jsonResult(result, includeRR, includeRR)
+		output.Retried = retried
+		return output
+	})
+	if s.jsonOutput {
+		output := s.jsonResult(result, s.jsonRequest, s.includeRR)
+		output.Retried = retried
+		if data, ok := marshalResult(output, s.redact); ok {
+			writeJSON(s.writer, s.quiet, data)
+		}
+		return
+	}
+
+	// Extractor-only requests write the bare values to pipe them to other tools
+	if s.extractedOnly {
+		values := redactValues(s.redact, result.extracted)
+		writeExtractedValues(s.writer, s.quiet, values)
+		return
+	}
+
+	builder := &strings.Builder{}
+	colorizer := s.colorizer
+
+	builder.WriteRune('[')
+	builder.WriteString(colorizer.BrightGreen(s.template.ID).String())
+	if result.matcher != nil && len(result.matcher.Name) > 0 {
+		builder.WriteString(":")
+		builder.WriteString(colorizer.BrightGreen(result.matcher.Name).Bold().String())
+	}
+	builder.WriteString("] [")
+	builder.WriteString(colorizer.BrightBlue(s.protocol).String())
+	builder.WriteString("] ")
+
+	builder.WriteString(result.reported())
+
+	// If any extractors, write the results
+	if len(result.extracted) > 0 {
+		builder.WriteString(" [")
+		for i, value := range result.extracted {
+			builder.WriteString(colorizer.BrightCyan(value).String())
+			if i != len(result.extracted)-1 {
+				builder.WriteRune(',')
+			}
+		}
+		builder.WriteString("]")
+	}
+	writeCVE(builder, colorizer, s.template.Info.Classification)
+	builder.WriteRune('\n')
+
+	// Write output to screen as well as any output file
+	message := s.redact(builder.String())
+	printResult(s.grouper, s.quiet, result.host, s.template.Info.Severity, message)
+
+	if s.writer != nil {
+		if s.coloredOutput {
+			message = s.decolorizer.ReplaceAllString(message, "")
+		}
+		writeLines(s.writer, message)
+	}
+}
+
+// markdownFinding returns the evidence of a result for the markdown report
+func (s *resultSink) markdownFinding(result *protocolResult) *markdown.Finding {
+	request, response := result.evidence()
+	return &markdown.Finding{
+		Template:    s.template,
+		Type:        s.protocol,
+		Host:        result.host,
+		Matched:     s.redact(result.reported()),
+		MatcherName: matcherName(result.matcher),
+		Extracted:   redactValues(s.redact, result.extracted),
+		Request:     s.redact(request),
+		Response:    s.redact(response),
+		Timestamp:   time.Now(),
+	}
+}
+
+// jsonResult returns the json output of a result, with the request sent and
+// the response read if required.
+func (s *resultSink) jsonResult(result *protocolResult, request, response bool) *ResultEvent {
+	output := &ResultEvent{
+		Template:       s.template.ID,
+		Name:           s.template.Info.Name,
+		Tags:           s.template.Info.TagList(),
+		Type:           s.protocol,
+		Host:           result.host,
+		Matched:        result.matched,
+		MatcherName:    matcherName(result.matcher),
+		Severity:       s.template.Info.Severity,
+		Author:         s.template.Info.Author,
+		Description:    s.template.Info.Description,
+		Reference:      s.template.Info.Reference,
+		Classification: s.template.Info.Classification,
+		Timestamp:      time.Now(),
+	}
+	if len(result.extracted) > 0 {
+		output.ExtractedResults = result.extracted
+	}
+	result.json(output, request, response)
+	return output
+}
+
+// dump shows a request sent or a response read on stderr for debugging
+func (s *resultSink) dump(what, target, data string) {
+	gologger.Infof("Dumped %s for %s (%s)\n\n", what, target, s.template.ID)
+	fmt.Fprintf(os.Stderr, "%s\n", s.redact(data))
+}
+
+// redact replaces the values of the environment variables of the template
+// written to the output, unless disabled.
+func (s *resultSink) redact(value string) string {
+	if s.redactor == nil {
+		return value
+	}
+	return s.redactor.Redact(s.template.Redact(value))
+}
+
+// Close closes the executer of a template, flushing its output.
+func (s *resultSink) Close() {
+	s.outputMutex.Lock()
+	defer s.outputMutex.Unlock()
+	s.writer.Flush()
+}
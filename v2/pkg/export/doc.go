@@ -0,0 +1,4 @@
+// Package export contains the interface of the exporters sending the json
+// results of a scan to external services as they are found, along with the
+// bounded queue and the retries they share.
+package export
@@ -0,0 +1,155 @@
+package export
+
+import (
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// Exporter sends the json results of a scan to an external service
+type Exporter interface {
+	// Export queues a json result to send, blocking the scan at most for
+	// the wait of its queue if any.
+	Export(data []byte)
+	// IncludeRR returns true if the results include the raw requests and
+	// responses along with the curl commands.
+	IncludeRR() bool
+}
+
+// Queue is a bounded queue of results sent in batches by a worker, so that
+// the scan is never blocked by a slow service. The results are dropped and
+// counted when the queue is full, unless an overflow is set.
+type Queue struct {
+	// mutex guards the queue against the results pushed after close
+	mutex  sync.RWMutex
+	closed bool
+	items  chan []byte
+	done   chan struct{}
+
+	batchSize int
+	interval  time.Duration
+	send      func(batch [][]byte)
+	dropped   uint64
+
+	// wait is the longest time a result waits for room in the full queue,
+	// overflow being called with the results still not queued if any.
+	wait     time.Duration
+	overflow func(item []byte)
+}
+
+// NewQueue creates a queue of a size calling send with the batches of at
+// most batchSize results, the pending results being sent on each interval.
+func NewQueue(size, batchSize int, interval time.Duration, send func(batch [][]byte)) *Queue {
+	q := &Queue{
+		items:     make(chan []byte, size),
+		done:      make(chan struct{}),
+		batchSize: batchSize,
+		interval:  interval,
+		send:      send,
+	}
+	go q.run()
+	return q
+}
+
+// SetOverflow makes the results wait at most wait for room in the full
+// queue, overflow being called with the ones still not queued instead of
+// dropping them. It is set before pushing any result.
+func (q *Queue) SetOverflow(wait time.Duration, overflow func(item []byte)) {
+	q.wait = wait
+	q.overflow = overflow
+}
+
+// Push queues a result, returning false if it was dropped or given to the
+// overflow.
+func (q *Queue) Push(item []byte) bool {
+	q.mutex.RLock()
+	defer q.mutex.RUnlock()
+	if q.closed {
+		return false
+	}
+	select {
+	case q.items <- item:
+		return true
+	default:
+	}
+	if q.wait > 0 {
+		timer := time.NewTimer(q.wait)
+		defer timer.Stop()
+		select {
+		case q.items <- item:
+			return true
+		case <-timer.C:
+		}
+	}
+	atomic.AddUint64(&q.dropped, 1)
+	if q.overflow != nil {
+		q.overflow(item)
+	}
+	return false
+}
+
+// Close sends the queued results, waiting for the worker to complete. The
+// results pushed afterwards are ignored.
+func (q *Queue) Close() {
+	q.mutex.Lock()
+	if !q.closed {
+		q.closed = true
+		close(q.items)
+	}
+	q.mutex.Unlock()
+	<-q.done
+}
+
+// Len returns the number of results waiting in the queue
+func (q *Queue) Len() int {
+	return len(q.items)
+}
+
+// Dropped returns the number of results dropped or given to the overflow
+// as the queue was full
+func (q *Queue) Dropped() uint64 {
+	return atomic.LoadUint64(&q.dropped)
+}
+
+// run sends the queued results when a batch is full or on each interval
+func (q *Queue) run() {
+	defer close(q.done)
+	ticker := time.NewTicker(q.interval)
+	defer ticker.Stop()
+
+	batch := make([][]byte, 0, q.batchSize)
+	for {
+		select {
+		case item, ok := <-q.items:
+			if !ok {
+				if len(batch) > 0 {
+					q.send(batch)
+				}
+				return
+			}
+			batch = append(batch, item)
+			if len(batch) >= q.batchSize {
+				q.send(batch)
+				batch = make([][]byte, 0, q.batchSize)
+			}
+		case <-ticker.C:
+			if len(batch) > 0 {
+				q.send(batch)
+				batch = make([][]byte, 0, q.batchSize)
+			}
+		}
+	}
+}
+
+// Retry calls a function until it succeeds or its error isn't retryable,
+// retrying at most retries times with a delay doubling from backoff.
+func Retry(retries int, backoff time.Duration, call func() (retryable bool, err error)) error {
+	for attempt := 0; ; attempt++ {
+		retryable, err := call()
+		if err == nil || !retryable || attempt >= retries {
+			return err
+		}
+		time.Sleep(backoff)
+		backoff *= 2
+	}
+}
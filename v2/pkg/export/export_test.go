@@ -0,0 +1,110 @@
+package export
+
+import (
+	"errors"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestQueue(t *testing.T) {
+	var mutex sync.Mutex
+	var batches [][]string
+	release := make(chan struct{})
+	queue := NewQueue(2, 2, time.Hour, func(batch [][]byte) {
+		<-release
+		mutex.Lock()
+		defer mutex.Unlock()
+		var items []string
+		for _, item := range batch {
+			items = append(items, string(item))
+		}
+		batches = append(batches, items)
+	})
+
+	// the worker is stuck on the first batch while the queue fills up
+	pushed := 0
+	for i := 0; i < 10; i++ {
+		if queue.Push([]byte{'a' + byte(i)}) {
+			pushed++
+		}
+	}
+	require.True(t, queue.Dropped() > 0, "Could not drop the results of the full queue")
+	require.Equal(t, uint64(10-pushed), queue.Dropped(), "Could not count the dropped results")
+	require.Equal(t, 2, queue.Len(), "Could not count the queued results")
+
+	close(release)
+	queue.Close()
+	require.False(t, queue.Push([]byte("closed")), "Could not ignore the results pushed after close")
+
+	var sent int
+	for _, batch := range batches {
+		require.True(t, len(batch) <= 2, "Could not limit the size of the batches")
+		sent += len(batch)
+	}
+	require.Equal(t, pushed, sent, "Could not send the queued results on close")
+}
+
+func TestQueueOverflow(t *testing.T) {
+	release := make(chan struct{})
+	queue := NewQueue(1, 1, time.Hour, func(batch [][]byte) {
+		<-release
+	})
+	var mutex sync.Mutex
+	var overflowed []string
+	queue.SetOverflow(10*time.Millisecond, func(item []byte) {
+		mutex.Lock()
+		defer mutex.Unlock()
+		overflowed = append(overflowed, string(item))
+	})
+
+	// the worker is stuck on the first result, the second one is queued
+	pushed := 0
+	for i := 0; i < 5; i++ {
+		if queue.Push([]byte{'a' + byte(i)}) {
+			pushed++
+		}
+	}
+	require.Len(t, overflowed, 5-pushed, "Could not give the results of the full queue to the overflow")
+	require.Equal(t, uint64(5-pushed), queue.Dropped(), "Could not count the overflowing results")
+
+	close(release)
+	queue.Close()
+
+	// the results waiting for room are queued once the worker is released
+	release = make(chan struct{})
+	queue = NewQueue(1, 1, time.Hour, func(batch [][]byte) {
+		<-release
+	})
+	queue.SetOverflow(time.Minute, nil)
+	require.True(t, queue.Push([]byte("a")), "Could not queue the result")
+	go func() {
+		time.Sleep(10 * time.Millisecond)
+		close(release)
+	}()
+	for i := 0; i < 3; i++ {
+		require.True(t, queue.Push([]byte("waited")), "Could not wait for room in the queue")
+	}
+	queue.Close()
+	require.Equal(t, uint64(0), queue.Dropped(), "Could not queue the results waiting for room")
+}
+
+func TestRetry(t *testing.T) {
+	calls := 0
+	err := Retry(2, time.Millisecond, func() (bool, error) {
+		calls++
+		return true, errors.New("unavailable")
+	})
+	require.NotNil(t, err, "Could not return the last error")
+	require.Equal(t, 3, calls, "Could not retry up to the retries")
+
+	calls = 0
+	err = Retry(2, time.Millisecond, func() (bool, error) {
+		calls++
+		return false, errors.New("rejected")
+	})
+	require.NotNil(t, err, "Could not return the error")
+	require.Equal(t, 1, calls, "Could not stop on an error which isn't retryable")
+}
@@ -0,0 +1,43 @@
+package extractors
+
+import (
+	"crypto/x509"
+	"net/http"
+
+	"github.com/projectdiscovery/nuclei/v2/pkg/ssl"
+)
+
+// extractCertificateKVal extracts the fields of the leaf certificate, or of
+// all the certificates of the chain if requested. Plain http responses
+// extract nothing.
+func (e *Extractor) extractCertificateKVal(r *http.Response) []string {
+	if r.TLS == nil {
+		return newResults().values
+	}
+	return e.extractCertificatesKVal(r.TLS.PeerCertificates)
+}
+
+// extractCertificatesKVal extracts the fields of the leaf of certificates,
+// or of all of them if requested.
+func (e *Extractor) extractCertificatesKVal(chain []*x509.Certificate) []string {
+	results := newResults()
+	if len(chain) == 0 {
+		return results.values
+	}
+
+	certificates := chain[:1]
+	if e.Chain {
+		certificates = chain
+	}
+	for _, cert := range certificates {
+		fields := ssl.CertificateFields(cert)
+		for _, k := range e.KVal {
+			for _, v := range fields[k] {
+				if v != "" {
+					results.add(v)
+				}
+			}
+		}
+	}
+	return results.values
+}
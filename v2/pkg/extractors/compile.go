@@ -3,6 +3,15 @@ package extractors
 import (
 	"fmt"
 	"regexp"
+	"strconv"
+	"strings"
+
+	"github.com/Knetic/govaluate"
+	"github.com/antchfx/xpath"
+	"github.com/projectdiscovery/nuclei/v2/pkg/collector"
+	"github.com/projectdiscovery/nuclei/v2/pkg/generators"
+	"github.com/projectdiscovery/nuclei/v2/pkg/jsonpath"
+	"github.com/projectdiscovery/nuclei/v2/pkg/regexguard"
 )
 
 // CompileExtractors performs the initial setup operation on a extractor
@@ -21,10 +30,72 @@ func (e *Extractor) CompileExtractors() error {
 			return fmt.Errorf("could not compile regex: %s", regex)
 		}
 		e.regexCompiled = append(e.regexCompiled, compiled)
+
+		group, err := regexGroup(compiled, e.Group)
+		if err != nil {
+			return err
+		}
+		e.regexGroups = append(e.regexGroups, group)
+	}
+	if e.Group != "" && e.extractorType != RegexExtractor {
+		return fmt.Errorf("group is only supported by regex extractors")
+	}
+
+	// Compile the json paths
+	for _, path := range e.JSON {
+		compiled, err := jsonpath.Compile(path)
+		if err != nil {
+			return fmt.Errorf("could not compile json path: %s", path)
+		}
+		e.jsonCompiled = append(e.jsonCompiled, compiled)
+	}
+	if e.extractorType == JSONExtractor && len(e.jsonCompiled) == 0 {
+		return fmt.Errorf("no json paths specified for json extractor")
+	}
+
+	// Compile the xpath expressions
+	for _, expr := range e.XPath {
+		compiled, err := xpath.Compile(expr)
+		if err != nil {
+			return fmt.Errorf("could not compile xpath: %s", expr)
+		}
+		e.xpathCompiled = append(e.xpathCompiled, compiled)
+	}
+	if e.extractorType == XPathExtractor && len(e.xpathCompiled) == 0 {
+		return fmt.Errorf("no xpath expressions specified for xpath extractor")
+	}
+
+	// Compile the dsl expressions
+	for _, dsl := range e.DSL {
+		compiled, err := govaluate.NewEvaluableExpressionWithFunctions(dsl, generators.GuardedHelperFunctions(func() *regexguard.Guard { return e.guard }))
+		if err != nil {
+			return fmt.Errorf("could not compile dsl: %s", dsl)
+		}
+		if err := generators.ValidateExpression(dsl); err != nil {
+			return fmt.Errorf("could not compile dsl %s: %s", dsl, err)
+		}
+		e.dslCompiled = append(e.dslCompiled, compiled)
+	}
+	if e.extractorType == DSLExtractor && len(e.dslCompiled) == 0 {
+		return fmt.Errorf("no dsl expressions specified for dsl extractor")
 	}
 
 	// Setup the part of the request to match, if any.
-	if e.Part != "" {
+	if strings.HasPrefix(e.Part, headerPartPrefix) {
+		// header.<name> matches the values of a single header
+		e.part = HeaderPart
+		e.headerName = strings.TrimPrefix(e.Part, headerPartPrefix)
+		if e.headerName == "" {
+			return fmt.Errorf("no header name specified for part: %s", e.Part)
+		}
+	} else if strings.HasPrefix(e.Part, cookiePartPrefix) {
+		// cookie.<name> matches the value of a single cookie
+		e.part = CookiePart
+		e.cookieName = strings.TrimPrefix(e.Part, cookiePartPrefix)
+		if e.cookieName == "" {
+			return fmt.Errorf("no cookie name specified for part: %s", e.Part)
+		}
+	} else if e.Part != "" {
 		e.part, ok = PartTypes[e.Part]
 		if !ok {
 			return fmt.Errorf("unknown matcher part specified: %s", e.Part)
@@ -33,5 +104,39 @@ func (e *Extractor) CompileExtractors() error {
 		e.part = BodyPart
 	}
 
+	if e.part == CertificatePart && e.extractorType != KValExtractor {
+		return fmt.Errorf("certificate part is only supported by kval extractors")
+	}
+	if e.ToFile != "" {
+		if err := collector.ValidatePath(e.ToFile); err != nil {
+			return fmt.Errorf("invalid to-file: %s", err)
+		}
+	}
+	if e.ToFileDedupe && e.ToFile == "" {
+		return fmt.Errorf("to-file-dedupe requires to-file")
+	}
+	if e.Chain && e.part != CertificatePart {
+		return fmt.Errorf("chain is only supported by the certificate part")
+	}
+
 	return nil
 }
+
+// regexGroup returns the index of a capture group of a regex from its index or name
+func regexGroup(regex *regexp.Regexp, group string) (int, error) {
+	if group == "" {
+		return 0, nil
+	}
+	if index, err := strconv.Atoi(group); err == nil {
+		if index < 0 || index > regex.NumSubexp() {
+			return 0, fmt.Errorf("group %d is larger than the %d groups of regex: %s", index, regex.NumSubexp(), regex)
+		}
+		return index, nil
+	}
+	for index, name := range regex.SubexpNames() {
+		if index > 0 && name == group {
+			return index, nil
+		}
+	}
+	return 0, fmt.Errorf("no group named %s in regex: %s", group, regex)
+}
@@ -1,15 +1,40 @@
 package extractors
 
 import (
+	"fmt"
 	"net/http"
+	"strconv"
+	"strings"
+	"time"
 
-	"github.com/miekg/dns"
+	"github.com/projectdiscovery/gologger"
+	"github.com/projectdiscovery/nuclei/v2/pkg/dnsrecords"
+	"github.com/projectdiscovery/nuclei/v2/pkg/file"
+	"github.com/projectdiscovery/nuclei/v2/pkg/generators"
+	"github.com/projectdiscovery/nuclei/v2/pkg/headless"
+	"github.com/projectdiscovery/nuclei/v2/pkg/jsonpath"
+	"github.com/projectdiscovery/nuclei/v2/pkg/matchers"
+	"github.com/projectdiscovery/nuclei/v2/pkg/network"
+	"github.com/projectdiscovery/nuclei/v2/pkg/ssl"
+	"github.com/projectdiscovery/nuclei/v2/pkg/websocket"
+	"github.com/projectdiscovery/nuclei/v2/pkg/xpathquery"
 )
 
-// Extract extracts response from the parts of request using a regex
-func (e *Extractor) Extract(resp *http.Response, body, headers string) map[string]struct{} {
+// Extract extracts response from the parts of request using a regex.
+//
+// duration is the time taken by the request and remoteIP the address it
+// connected to, available to the dsl extractors like the dsl matchers along
+// with the variables, i.e the variables of the template.
+// The values are deduplicated and returned in the order of extraction.
+func (e *Extractor) Extract(resp *http.Response, body, headers string, duration time.Duration, remoteIP string, variables map[string]interface{}) []string {
 	switch e.extractorType {
 	case RegexExtractor:
+		if e.headerName != "" {
+			return e.extractRegexValues(resp.Header.Values(e.headerName))
+		}
+		if e.part == CookiePart {
+			return e.extractRegexValues(e.cookieValues(resp))
+		}
 		if e.part == BodyPart {
 			return e.extractRegex(body)
 		} else if e.part == HeaderPart {
@@ -22,8 +47,18 @@ func (e *Extractor) Extract(resp *http.Response, body, headers string) map[strin
 			return e.extractRegex(body)
 		}
 	case KValExtractor:
+		if e.headerName != "" {
+			return extractValues(resp.Header.Values(e.headerName))
+		}
+		if e.cookieName != "" {
+			return extractValues(e.cookieValues(resp))
+		}
 		if e.part == HeaderPart {
 			return e.extractKVal(resp)
+		} else if e.part == CertificatePart {
+			return e.extractCertificateKVal(resp)
+		} else if e.part == CookiePart {
+			return e.extractCookieKVal(resp, "set-cookie")
 		} else {
 			matches := e.extractKVal(resp)
 			if len(matches) > 0 {
@@ -31,54 +66,396 @@ func (e *Extractor) Extract(resp *http.Response, body, headers string) map[strin
 			}
 			return e.extractCookieKVal(resp, "set-cookie")
 		}
+	case JSONExtractor:
+		return e.extractJSON(body)
+	case XPathExtractor:
+		return e.extractXPath(body)
+	case DSLExtractor:
+		return e.extractDSL(generators.MergeMaps(variables, matchers.HTTPValues(resp, body, headers, duration, remoteIP)))
+	}
+
+	return nil
+}
+
+// ExtractDNS extracts response from dns message using a regex.
+//
+// The trace is only available for dns requests with tracing enabled and
+// the variables are available to the dsl extractors.
+func (e *Extractor) ExtractDNS(resp *dnsrecords.Response, variables map[string]interface{}) []string {
+	switch e.extractorType {
+	case RegexExtractor:
+		switch e.part {
+		case TracePart:
+			if resp.Trace == nil {
+				return nil
+			}
+			return e.extractRegex(resp.Trace.String())
+		case RRSIGPart:
+			return e.extractRegex(dnsrecords.SignaturesString(resp.Msg))
+		case AnswerPart, AuthorityPart, AdditionalPart:
+			return e.extractRegex(dnsrecords.SectionString(dnsrecords.Sections(resp.Msg)[dnsSections[e.part]]))
+		}
+		return e.extractRegex(resp.String())
+	case KValExtractor:
+		return e.extractDNSKVal(resp)
+	case DSLExtractor:
+		return e.extractDSL(generators.MergeMaps(variables, matchers.DNSValues(resp)))
+	}
+
+	return nil
+}
+
+// ExtractHeadless extracts the recordings of a headless page.
+//
+// The regexes extract from the dom by default, or the console messages, the
+// dialogs and the network requests by their parts, the kval extractors the
+// values of the named steps and the variables are available to the dsl
+// extractors.
+func (e *Extractor) ExtractHeadless(resp *headless.Response, variables map[string]interface{}) []string {
+	switch e.extractorType {
+	case RegexExtractor:
+		switch e.part {
+		case ConsolePart:
+			return e.extractRegexValues(resp.Console)
+		case DialogPart:
+			return e.extractRegexValues(resp.Dialogs)
+		case NetworkPart:
+			return e.extractRegexValues(resp.Requests)
+		case AllPart:
+			return e.extractRegex(resp.String())
+		}
+		return e.extractRegex(resp.DOM)
+	case KValExtractor:
+		results := newResults()
+		for _, k := range e.KVal {
+			if v, ok := resp.Values[k]; ok && v != "" {
+				results.add(v)
+			}
+		}
+		return results.values
+	case JSONExtractor:
+		return e.extractJSON(resp.DOM)
+	case XPathExtractor:
+		return e.extractXPath(resp.DOM)
+	case DSLExtractor:
+		return e.extractDSL(generators.MergeMaps(variables, matchers.HeadlessValues(resp)))
+	}
+
+	return nil
+}
+
+// ExtractNetwork extracts the bytes read from a network connection.
+//
+// The regexes extract from the bytes read by default or from the kind of
+// the error which stopped the steps by the error part, the kval extractors
+// the bytes read by the named steps and the variables are available to the
+// dsl extractors.
+func (e *Extractor) ExtractNetwork(resp *network.Response, variables map[string]interface{}) []string {
+	switch e.extractorType {
+	case RegexExtractor:
+		if e.part == ErrorPart {
+			return e.extractRegex(resp.Error)
+		}
+		return e.extractRegex(resp.Data)
+	case KValExtractor:
+		results := newResults()
+		for _, k := range e.KVal {
+			if v, ok := resp.Values[k]; ok && v != "" {
+				results.add(v)
+			}
+		}
+		return results.values
+	case JSONExtractor:
+		return e.extractJSON(resp.Data)
+	case DSLExtractor:
+		return e.extractDSL(generators.MergeMaps(variables, matchers.NetworkValues(resp)))
 	}
 
 	return nil
 }
 
-// ExtractDNS extracts response from dns message using a regex
-func (e *Extractor) ExtractDNS(msg *dns.Msg) map[string]struct{} {
+// ExtractWebsocket extracts the messages read from an upgraded websocket
+// connection.
+//
+// The regexes extract from the messages by default, one per line, from the
+// upgrade response by the header parts or from the kind of the error of
+// the connection by the error part, the kval extractors the headers of the
+// upgrade response, the json paths from each message and the variables are
+// available to the dsl extractors.
+func (e *Extractor) ExtractWebsocket(resp *websocket.Response, variables map[string]interface{}) []string {
 	switch e.extractorType {
 	case RegexExtractor:
-		return e.extractRegex(msg.String())
+		if e.headerName != "" {
+			return e.extractRegexValues(resp.Headers.Values(e.headerName))
+		}
+		switch e.part {
+		case HeaderPart:
+			return e.extractRegex(resp.Upgrade)
+		case AllPart:
+			return e.extractRegex(resp.Upgrade + resp.String())
+		case ErrorPart:
+			return e.extractRegex(resp.Error)
+		}
+		return e.extractRegexValues(resp.Messages)
 	case KValExtractor:
+		if e.headerName != "" {
+			return extractValues(resp.Headers.Values(e.headerName))
+		}
+		results := newResults()
+		for _, k := range e.KVal {
+			for _, v := range resp.Headers.Values(k) {
+				results.add(v)
+			}
+		}
+		return results.values
+	case JSONExtractor:
+		results := newResults()
+		for _, message := range resp.Messages {
+			for _, value := range e.extractJSON(message) {
+				results.add(value)
+			}
+		}
+		return results.values
+	case DSLExtractor:
+		return e.extractDSL(generators.MergeMaps(variables, matchers.WebsocketValues(resp)))
 	}
 
 	return nil
 }
 
-// extractRegex extracts text from a corpus and returns it
-func (e *Extractor) extractRegex(corpus string) map[string]struct{} {
-	results := make(map[string]struct{})
-	for _, regex := range e.regexCompiled {
-		matches := regex.FindAllString(corpus, -1)
-		for _, match := range matches {
-			results[match] = struct{}{}
+// ExtractSSL extracts the handshake with a tls server.
+//
+// The regexes extract from the negotiated version and cipher suite and the
+// fields of the certificates by default, one per line, or from each of them
+// by its part, the kval extractors the negotiated values and the fields of
+// the leaf certificate, the ones of the chain by the certificate part, and
+// the variables are available to the dsl extractors.
+func (e *Extractor) ExtractSSL(resp *ssl.Response, variables map[string]interface{}) []string {
+	switch e.extractorType {
+	case RegexExtractor:
+		switch e.part {
+		case VersionPart:
+			return e.extractRegex(resp.Version)
+		case CipherPart:
+			return e.extractRegex(resp.Cipher)
+		case CertificatePart:
+			return e.extractRegex(resp.CertificatesString())
+		case ErrorPart:
+			return e.extractRegex(resp.Error)
+		}
+		return e.extractRegex(resp.String())
+	case KValExtractor:
+		if e.part == CertificatePart {
+			return e.extractCertificatesKVal(resp.Certificates)
 		}
+		fields := resp.Fields()
+		results := newResults()
+		for _, k := range e.KVal {
+			for _, v := range fields[k] {
+				if v != "" {
+					results.add(v)
+				}
+			}
+		}
+		return results.values
+	case DSLExtractor:
+		return e.extractDSL(generators.MergeMaps(variables, matchers.SSLValues(resp)))
 	}
-	return results
+
+	return nil
+}
+
+// ExtractFile extracts the content of a local file.
+//
+// The regexes, the json paths and the xpath expressions extract from the
+// content of the file, and its path and the variables are available to the
+// dsl extractors.
+func (e *Extractor) ExtractFile(resp *file.Response, variables map[string]interface{}) []string {
+	switch e.extractorType {
+	case RegexExtractor:
+		return e.extractRegex(resp.Data)
+	case JSONExtractor:
+		return e.extractJSON(resp.Data)
+	case XPathExtractor:
+		return e.extractXPath(resp.Data)
+	case DSLExtractor:
+		return e.extractDSL(generators.MergeMaps(variables, matchers.FileValues(resp)))
+	}
+
+	return nil
+}
+
+// results are the deduplicated values of an extractor in extraction order
+type results struct {
+	seen   map[string]struct{}
+	values []string
+}
+
+func newResults() *results {
+	return &results{seen: make(map[string]struct{})}
+}
+
+func (r *results) add(value string) {
+	if _, ok := r.seen[value]; ok {
+		return
+	}
+	r.seen[value] = struct{}{}
+	r.values = append(r.values, value)
+}
+
+// extractRegex extracts text from a corpus and returns it, the capture
+// group of the matches if a group was specified
+func (e *Extractor) extractRegex(corpus string) []string {
+	results := newResults()
+	corpus = e.guard.Input(corpus)
+	for i, regex := range e.regexCompiled {
+		group := e.regexGroups[i]
+		if group == 0 {
+			for _, match := range regex.FindAllString(corpus, -1) {
+				results.add(match)
+			}
+			continue
+		}
+		// matches where the group didn't participate are skipped
+		for _, match := range regex.FindAllStringSubmatchIndex(corpus, -1) {
+			start, end := match[2*group], match[2*group+1]
+			if start != -1 && end > start {
+				results.add(corpus[start:end])
+			}
+		}
+	}
+	return results.values
+}
+
+// extractRegexValues extracts text from each of the values separately
+func (e *Extractor) extractRegexValues(values []string) []string {
+	results := newResults()
+	for _, value := range values {
+		for _, match := range e.extractRegex(value) {
+			results.add(match)
+		}
+	}
+	return results.values
+}
+
+// extractValues extracts the non empty values of a header or cookie
+func extractValues(values []string) []string {
+	results := newResults()
+	for _, value := range values {
+		if value != "" {
+			results.add(value)
+		}
+	}
+	return results.values
+}
+
+// cookieValues returns the values of the cookies set by the response with
+// the cookie name, without their attributes.
+func (e *Extractor) cookieValues(r *http.Response) []string {
+	var values []string
+	for _, cookie := range r.Cookies() {
+		if e.cookieName == "" || cookie.Name == e.cookieName {
+			values = append(values, cookie.Value)
+		}
+	}
+	return values
+}
+
+// extractJSON extracts the values selected by the json paths from a json document.
+//
+// Values are extracted in the same textual form compared by the json matchers,
+// the arrays being flattened into their elements. Non json bodies extract nothing.
+func (e *Extractor) extractJSON(corpus string) []string {
+	results := newResults()
+	document, err := jsonpath.Parse(corpus)
+	if err != nil {
+		return results.values
+	}
+	for _, path := range e.jsonCompiled {
+		for _, value := range jsonpath.Values(path.Evaluate(document)) {
+			results.add(value)
+		}
+	}
+	return results.values
+}
+
+// extractXPath extracts the trimmed text or attribute of the nodes selected by
+// the xpath expressions. Bodies failing to parse extract nothing.
+func (e *Extractor) extractXPath(corpus string) []string {
+	results := newResults()
+	document, err := xpathquery.Parse(corpus)
+	if err != nil {
+		return results.values
+	}
+	for _, expr := range e.xpathCompiled {
+		for _, value := range document.Select(expr, e.Attribute) {
+			if value = strings.TrimSpace(value); value != "" {
+				results.add(value)
+			}
+		}
+	}
+	return results.values
+}
+
+// extractDSL extracts the results of the dsl expressions.
+//
+// Expressions failing to evaluate, i.e referencing variables missing
+// from the response, extract nothing.
+func (e *Extractor) extractDSL(values map[string]interface{}) []string {
+	results := newResults()
+	for i, expression := range e.dslCompiled {
+		result, err := expression.Evaluate(values)
+		if err != nil {
+			gologger.Debugf("Could not evaluate dsl extractor %s: %s\n", e.DSL[i], err)
+			continue
+		}
+		switch v := result.(type) {
+		case nil:
+		case float64:
+			results.add(strconv.FormatFloat(v, 'f', -1, 64))
+		default:
+			results.add(fmt.Sprint(v))
+		}
+	}
+	return results.values
 }
 
 // extractKVal extracts text from http response
-func (e *Extractor) extractKVal(r *http.Response) map[string]struct{} {
-	results := make(map[string]struct{})
+func (e *Extractor) extractKVal(r *http.Response) []string {
+	results := newResults()
 	for _, k := range e.KVal {
 		for _, v := range r.Header.Values(k) {
-			results[v] = struct{}{}
+			results.add(v)
+		}
+	}
+	return results.values
+}
+
+// extractDNSKVal extracts the normalized fields of the dns response, or the
+// fields of the records of a section if a section part was specified.
+func (e *Extractor) extractDNSKVal(resp *dnsrecords.Response) []string {
+	results := newResults()
+	fields := resp.Fields()
+	if section, ok := dnsSections[e.part]; ok {
+		fields = dnsrecords.SectionFields(dnsrecords.Sections(resp.Msg)[section])
+	}
+	for _, k := range e.KVal {
+		for _, v := range fields[k] {
+			results.add(v)
 		}
 	}
-	return results
+	return results.values
 }
 
 // extractCookieKVal extracts text from cookies
-func (e *Extractor) extractCookieKVal(r *http.Response, key string) map[string]struct{} {
-	results := make(map[string]struct{})
+func (e *Extractor) extractCookieKVal(r *http.Response, key string) []string {
+	results := newResults()
 	for _, k := range e.KVal {
 		for _, cookie := range r.Cookies() {
 			if cookie.Name == k {
-				results[cookie.Value] = struct{}{}
+				results.add(cookie.Value)
 			}
 		}
 	}
-	return results
+	return results.values
 }
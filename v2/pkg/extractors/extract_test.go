@@ -0,0 +1,225 @@
+package extractors
+
+import (
+	"crypto/sha256"
+	"crypto/x509"
+	"encoding/hex"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/projectdiscovery/nuclei/v2/pkg/file"
+	"github.com/projectdiscovery/nuclei/v2/pkg/headless"
+	"github.com/projectdiscovery/nuclei/v2/pkg/network"
+	"github.com/projectdiscovery/nuclei/v2/pkg/ssl"
+	"github.com/projectdiscovery/nuclei/v2/pkg/websocket"
+	"github.com/stretchr/testify/require"
+	"gopkg.in/yaml.v2"
+)
+
+func TestDSLExtractor(t *testing.T) {
+	resp := &http.Response{StatusCode: 200, Header: http.Header{"Server": []string{"nginx"}}, Body: http.NoBody}
+
+	e := &Extractor{Type: "dsl", DSL: []string{"len(body)", "md5(body)", `server + ":" + status_code`, "missing_header"}}
+	err := e.CompileExtractors()
+	require.Nil(t, err, "Could not compile dsl extractor")
+
+	results := e.Extract(resp, "admin", "", time.Second, "127.0.0.1", nil)
+	require.Equal(t, []string{"5", "21232f297a57a5a743894a0e4a801fc3", "nginx:200"}, results, "Could not extract dsl results")
+
+	require.NotNil(t, (&Extractor{Type: "dsl", DSL: []string{"len(body"}}).CompileExtractors(), "Could compile invalid dsl")
+	require.NotNil(t, (&Extractor{Type: "dsl"}).CompileExtractors(), "Could compile dsl extractor without expressions")
+}
+
+func TestHeaderAndCookieParts(t *testing.T) {
+	resp := &http.Response{Header: http.Header{
+		"Set-Cookie": []string{`PHPSESSID="abc123"; Path=/; HttpOnly`, "lang=en; Secure", "PHPSESSID=def456"},
+		"X-Backend":  []string{"app-01.internal", "app-02.internal"},
+	}, Body: http.NoBody}
+
+	e := &Extractor{Type: "kval", Part: "cookie.PHPSESSID"}
+	require.Nil(t, e.CompileExtractors(), "Could not compile cookie extractor")
+	require.Equal(t, []string{"abc123", "def456"}, e.Extract(resp, "", "", 0, "", nil), "Could not extract cookie values")
+
+	e = &Extractor{Type: "regex", Part: "cookie.PHPSESSID", Regex: []string{"^abc[0-9]+"}}
+	require.Nil(t, e.CompileExtractors(), "Could not compile cookie extractor")
+	require.Equal(t, []string{"abc123"}, e.Extract(resp, "", "", 0, "", nil), "Could not extract from cookie values")
+
+	e = &Extractor{Type: "kval", Part: "header.x-backend"}
+	require.Nil(t, e.CompileExtractors(), "Could not compile header extractor")
+	require.Equal(t, []string{"app-01.internal", "app-02.internal"}, e.Extract(resp, "", "", 0, "", nil), "Could not extract header values")
+
+	e = &Extractor{Type: "regex", Part: "header.set-cookie", Regex: []string{"^[a-zA-Z]+"}}
+	require.Nil(t, e.CompileExtractors(), "Could not compile header extractor")
+	require.Equal(t, []string{"PHPSESSID", "lang"}, e.Extract(resp, "", "", 0, "", nil), "Could not extract from each header value")
+
+	e = &Extractor{Type: "kval", Part: "cookie", KVal: []string{"lang"}}
+	require.Nil(t, e.CompileExtractors(), "Could not compile cookies extractor")
+	require.Equal(t, []string{"en"}, e.Extract(resp, "", "", 0, "", nil), "Could not extract cookie kval")
+
+	require.NotNil(t, (&Extractor{Type: "kval", Part: "cookie."}).CompileExtractors(), "Could compile cookie part without name")
+}
+
+func TestCertificateExtractor(t *testing.T) {
+	server := httptest.NewTLSServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}))
+	defer server.Close()
+
+	resp, err := server.Client().Get(server.URL)
+	require.Nil(t, err, "Could not make tls request")
+	resp.Body.Close()
+	cert := resp.TLS.PeerCertificates[0]
+
+	e := &Extractor{Type: "kval", Part: "certificate", KVal: []string{"san", "issuer_org", "fingerprint_sha256", "not_after"}}
+	require.Nil(t, e.CompileExtractors(), "Could not compile certificate extractor")
+
+	sum := sha256.Sum256(cert.Raw)
+	expected := append([]string{}, cert.DNSNames...)
+	for _, ip := range cert.IPAddresses {
+		expected = append(expected, ip.String())
+	}
+	expected = append(expected, "Acme Co", hex.EncodeToString(sum[:]), cert.NotAfter.UTC().Format(time.RFC3339))
+	require.Equal(t, expected, e.Extract(resp, "", "", 0, "", nil), "Could not extract certificate fields")
+
+	plain := &http.Response{Header: http.Header{}, Body: http.NoBody}
+	require.Empty(t, e.Extract(plain, "", "", 0, "", nil), "Could extract certificate fields without tls")
+
+	require.NotNil(t, (&Extractor{Type: "regex", Part: "certificate", Regex: []string{"."}}).CompileExtractors(), "Could compile certificate part for regex extractor")
+	require.NotNil(t, (&Extractor{Type: "kval", KVal: []string{"san"}, Chain: true}).CompileExtractors(), "Could compile chain without certificate part")
+}
+
+func TestRegexGroup(t *testing.T) {
+	resp := &http.Response{Header: http.Header{}, Body: http.NoBody}
+	headers := "Server: Apache/2.4.41 (Ubuntu)\nX-Powered-By: PHP/7.4.3\nVia: Apache/2.4.41"
+
+	e := &Extractor{}
+	require.Nil(t, yaml.Unmarshal([]byte(`{type: regex, part: header, group: 1, regex: ['(?:Apache|PHP)/([0-9.]+)']}`), e), "Could not decode extractor")
+	require.Nil(t, e.CompileExtractors(), "Could not compile regex extractor")
+	require.Equal(t, []string{"2.4.41", "7.4.3"}, e.Extract(resp, "", headers, 0, "", nil), "Could not extract capture group")
+
+	e = &Extractor{Type: "regex", Part: "header", Group: "version", Regex: []string{"Server: [a-zA-Z]+/(?P<version>[0-9.]+)", "PHP(/(?P<version>[0-9.]+))?"}}
+	require.Nil(t, e.CompileExtractors(), "Could not compile regex extractor")
+	require.Equal(t, []string{"2.4.41", "7.4.3"}, e.Extract(resp, "", headers+"\nX-Generator: PHP", 0, "", nil), "Could not extract named group")
+
+	e = &Extractor{Type: "regex", Part: "header", Regex: []string{"Apache/[0-9.]+"}}
+	require.Nil(t, e.CompileExtractors(), "Could not compile regex extractor")
+	require.Equal(t, []string{"Apache/2.4.41"}, e.Extract(resp, "", headers, 0, "", nil), "Could not extract whole match")
+
+	require.NotNil(t, (&Extractor{Type: "regex", Group: "2", Regex: []string{"a(b)"}}).CompileExtractors(), "Could compile group larger than the groups")
+	require.NotNil(t, (&Extractor{Type: "regex", Group: "name", Regex: []string{"a(b)"}}).CompileExtractors(), "Could compile missing named group")
+	require.NotNil(t, (&Extractor{Type: "kval", Group: "1", KVal: []string{"server"}}).CompileExtractors(), "Could compile group for kval extractor")
+}
+
+func TestHeadlessExtractor(t *testing.T) {
+	resp := &headless.Response{
+		DOM:      `<html><body><a href="/admin">Admin</a></body></html>`,
+		Console:  []string{"log: token=abc", "log: token=def"},
+		Requests: []string{"GET http://example.com/", "GET http://example.com/api/v2/users"},
+		Values:   map[string]string{"version": "3.4.1"},
+	}
+
+	e := &Extractor{Type: "regex", Part: "console", Group: "1", Regex: []string{"token=([a-z]+)$"}}
+	require.Nil(t, e.CompileExtractors(), "Could not compile console extractor")
+	require.Equal(t, []string{"abc", "def"}, e.ExtractHeadless(resp, nil), "Could not extract each console message")
+
+	e = &Extractor{Type: "regex", Part: "network", Regex: []string{"/api/v[0-9]+/[a-z]+"}}
+	require.Nil(t, e.CompileExtractors(), "Could not compile network extractor")
+	require.Equal(t, []string{"/api/v2/users"}, e.ExtractHeadless(resp, nil), "Could not extract the network requests")
+
+	e = &Extractor{Type: "xpath", XPath: []string{"//a"}, Attribute: "href"}
+	require.Nil(t, e.CompileExtractors(), "Could not compile xpath extractor")
+	require.Equal(t, []string{"/admin"}, e.ExtractHeadless(resp, nil), "Could not extract the dom")
+
+	e = &Extractor{Type: "kval", KVal: []string{"version", "missing"}}
+	require.Nil(t, e.CompileExtractors(), "Could not compile kval extractor")
+	require.Equal(t, []string{"3.4.1"}, e.ExtractHeadless(resp, nil), "Could not extract the values of the steps")
+}
+
+func TestNetworkExtractor(t *testing.T) {
+	resp := &network.Response{
+		Data:   "+PONG\r\n$23\r\nredis_version:6.0.9\r\n\r\n",
+		Values: map[string]string{"ping": "+PONG\r\n"},
+		Error:  network.TimeoutError,
+	}
+
+	e := &Extractor{Type: "regex", Group: "1", Regex: []string{"redis_version:([0-9.]+)"}}
+	require.Nil(t, e.CompileExtractors(), "Could not compile regex extractor")
+	require.Equal(t, []string{"6.0.9"}, e.ExtractNetwork(resp, nil), "Could not extract the bytes read")
+
+	e = &Extractor{Type: "regex", Part: "error", Regex: []string{"[a-z]+"}}
+	require.Nil(t, e.CompileExtractors(), "Could not compile error extractor")
+	require.Equal(t, []string{"timeout"}, e.ExtractNetwork(resp, nil), "Could not extract the kind of the error")
+
+	e = &Extractor{Type: "kval", KVal: []string{"ping"}}
+	require.Nil(t, e.CompileExtractors(), "Could not compile kval extractor")
+	require.Equal(t, []string{"+PONG\r\n"}, e.ExtractNetwork(resp, nil), "Could not extract the named steps")
+}
+
+func TestFileExtractor(t *testing.T) {
+	resp := &file.Response{Path: "config/app.json", Data: `{"database": {"password": "hunter2"}}`}
+
+	e := &Extractor{Type: "json", JSON: []string{"database.password"}}
+	require.Nil(t, e.CompileExtractors(), "Could not compile json extractor")
+	require.Equal(t, []string{"hunter2"}, e.ExtractFile(resp, nil), "Could not extract the content")
+
+	e = &Extractor{Type: "dsl", DSL: []string{"path"}}
+	require.Nil(t, e.CompileExtractors(), "Could not compile dsl extractor")
+	require.Equal(t, []string{"config/app.json"}, e.ExtractFile(resp, nil), "Could not extract the path")
+}
+
+func TestWebsocketExtractor(t *testing.T) {
+	resp := &websocket.Response{
+		StatusCode: 101,
+		Headers:    http.Header{"X-Session": []string{"42"}},
+		Upgrade:    "HTTP/1.1 101 Switching Protocols\r\nX-Session: 42\r\n\r\n",
+		Messages:   []string{`{"token":"abc"}`, `{"token":"def"}`},
+	}
+
+	e := &Extractor{Type: "json", JSON: []string{"token"}}
+	require.Nil(t, e.CompileExtractors(), "Could not compile json extractor")
+	require.Equal(t, []string{"abc", "def"}, e.ExtractWebsocket(resp, nil), "Could not extract the messages")
+
+	e = &Extractor{Type: "kval", KVal: []string{"x-session"}}
+	require.Nil(t, e.CompileExtractors(), "Could not compile kval extractor")
+	require.Equal(t, []string{"42"}, e.ExtractWebsocket(resp, nil), "Could not extract the header of the upgrade")
+
+	e = &Extractor{Type: "regex", Part: "header", Regex: []string{"HTTP/1.1 [0-9]+"}}
+	require.Nil(t, e.CompileExtractors(), "Could not compile regex extractor")
+	require.Equal(t, []string{"HTTP/1.1 101"}, e.ExtractWebsocket(resp, nil), "Could not extract the upgrade")
+}
+
+func TestSSLExtractor(t *testing.T) {
+	server := httptest.NewTLSServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}))
+	defer server.Close()
+	resp := &ssl.Response{
+		ServerName:   "127.0.0.1",
+		Version:      "tls12",
+		Cipher:       "TLS_ECDHE_RSA_WITH_AES_128_GCM_SHA256",
+		Certificates: []*x509.Certificate{server.Certificate()},
+	}
+
+	e := &Extractor{Type: "kval", KVal: []string{"version", "issuer_org"}}
+	require.Nil(t, e.CompileExtractors(), "Could not compile kval extractor")
+	require.Equal(t, []string{"tls12", "Acme Co"}, e.ExtractSSL(resp, nil), "Could not extract the fields of the handshake")
+
+	e = &Extractor{Type: "kval", Part: "certificate", KVal: []string{"version", "san"}}
+	require.Nil(t, e.CompileExtractors(), "Could not compile certificate extractor")
+	require.Contains(t, e.ExtractSSL(resp, nil), "127.0.0.1", "Could not extract the fields of the certificate")
+
+	e = &Extractor{Type: "regex", Part: "cipher", Regex: []string{"AES_[0-9]+"}}
+	require.Nil(t, e.CompileExtractors(), "Could not compile regex extractor")
+	require.Equal(t, []string{"AES_128"}, e.ExtractSSL(resp, nil), "Could not extract the cipher suite")
+
+	e = &Extractor{Type: "dsl", DSL: []string{"self_signed"}}
+	require.Nil(t, e.CompileExtractors(), "Could not compile dsl extractor")
+	require.Equal(t, []string{"true"}, e.ExtractSSL(resp, nil), "Could not extract the values of the handshake")
+}
+
+func TestToFile(t *testing.T) {
+	for _, path := range []string{"emails.txt", "out/{{template-id}}-{{extractor-name}}.txt", "out/../emails.txt"} {
+		require.Nil(t, (&Extractor{Type: "regex", Regex: []string{"."}, ToFile: path}).CompileExtractors(), "Could not compile relative to-file %s", path)
+	}
+	for _, path := range []string{"../x", "/abs", "out/../../x", ".."} {
+		require.NotNil(t, (&Extractor{Type: "regex", Regex: []string{"."}, ToFile: path}).CompileExtractors(), "Could compile to-file %s outside of the directory", path)
+	}
+}
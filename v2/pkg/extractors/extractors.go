@@ -1,6 +1,15 @@
 package extractors
 
-import "regexp"
+import (
+	"regexp"
+	"strings"
+
+	"github.com/Knetic/govaluate"
+	"github.com/antchfx/xpath"
+	"github.com/projectdiscovery/nuclei/v2/pkg/dnsrecords"
+	"github.com/projectdiscovery/nuclei/v2/pkg/jsonpath"
+	"github.com/projectdiscovery/nuclei/v2/pkg/regexguard"
+)
 
 // Extractor is used to extract part of response using a regex.
 type Extractor struct {
@@ -15,19 +24,61 @@ type Extractor struct {
 	Regex []string `yaml:"regex"`
 	// regexCompiled is the compiled variant
 	regexCompiled []*regexp.Regexp
+	// guard limits the length of the inputs of the regexes and the regex
+	// dsl function, the default limit applying if nil.
+	guard *regexguard.Guard
+	// Group is the capture group of the regexes to extract, the index or the
+	// name of the group. Default is 0, the whole match.
+	Group string `yaml:"group,omitempty"`
+	// regexGroups are the indexes of the group in each regex
+	regexGroups []int
 
 	// KVal are the kval to be present in the response headers/cookies
 	KVal []string `yaml:"kval,omitempty"`
+	// Chain extracts the kval of all the certificates of the chain for the
+	// certificate part, only the leaf certificate by default.
+	Chain bool `yaml:"chain,omitempty"`
+
+	// JSON are the json paths to extract from the json response
+	JSON []string `yaml:"json,omitempty"`
+	// jsonCompiled is the compiled variant
+	jsonCompiled []*jsonpath.Path
+
+	// XPath are the xpath expressions to extract from the html or xml response
+	XPath []string `yaml:"xpath,omitempty"`
+	// xpathCompiled is the compiled variant
+	xpathCompiled []*xpath.Expr
+	// Attribute is the attribute of the nodes selected by the xpath
+	// expressions to extract, the text of the nodes if not specified.
+	Attribute string `yaml:"attribute,omitempty"`
+
+	// DSL are the dsl expressions whose results are extracted
+	DSL []string `yaml:"dsl,omitempty"`
+	// dslCompiled is the compiled variant
+	dslCompiled []*govaluate.EvaluableExpression
 
 	// Part is the part of the request to match
 	//
-	// By default, matching is performed in request body.
+	// By default, matching is performed in request body. header.<name>
+	// matches the values of a single header, i.e header.set-cookie, and
+	// cookie.<name> the value of a single cookie, i.e cookie.PHPSESSID.
 	Part string `yaml:"part,omitempty"`
 	// part is the part of the request to match
 	part Part
+	// headerName is the name of the header to match for header.<name> parts
+	headerName string
+	// cookieName is the name of the cookie to match for cookie.<name> parts
+	cookieName string
 
 	// Internal defines if this is used internally
 	Internal bool `yaml:"internal,omitempty"`
+
+	// ToFile is a file the extracted values are appended to, one per line,
+	// relative to the extractor directory of the scan. {{template-id}} and
+	// {{extractor-name}} are replaced in the path.
+	ToFile string `yaml:"to-file,omitempty"`
+	// ToFileDedupe skips the values already appended to the file
+	ToFileDedupe bool `yaml:"to-file-dedupe,omitempty"`
 }
 
 // ExtractorType is the type of the extractor specified
@@ -38,12 +89,21 @@ const (
 	RegexExtractor ExtractorType = iota + 1
 	// KValExtractor extracts responses with key:value
 	KValExtractor
+	// JSONExtractor extracts json responses with json paths
+	JSONExtractor
+	// XPathExtractor extracts html or xml responses with xpath expressions
+	XPathExtractor
+	// DSLExtractor extracts the results of dsl expressions
+	DSLExtractor
 )
 
 // ExtractorTypes is an table for conversion of extractor type from string.
 var ExtractorTypes = map[string]ExtractorType{
 	"regex": RegexExtractor,
 	"kval":  KValExtractor,
+	"json":  JSONExtractor,
+	"xpath": XPathExtractor,
+	"dsl":   DSLExtractor,
 }
 
 // Part is the part of the request to match
@@ -56,6 +116,39 @@ const (
 	HeaderPart
 	// AllPart matches both response body and headers of the response.
 	AllPart
+	// TracePart matches the delegation chain of a dns trace.
+	TracePart
+	// RRSIGPart matches the RRSIG records of a dns response.
+	RRSIGPart
+	// AnswerPart matches the answer section of a dns response.
+	AnswerPart
+	// AuthorityPart matches the authority section of a dns response.
+	AuthorityPart
+	// AdditionalPart matches the additional section of a dns response.
+	AdditionalPart
+	// CookiePart matches the cookies set by the response.
+	CookiePart
+	// CertificatePart matches the certificates of a tls connection.
+	CertificatePart
+	// ConsolePart matches the console messages of a headless page
+	ConsolePart
+	// DialogPart matches the dialogs opened by a headless page
+	DialogPart
+	// NetworkPart matches the network requests of a headless page
+	NetworkPart
+	// ErrorPart matches the kind of the error of a network, websocket or ssl connection
+	ErrorPart
+	// VersionPart matches the version negotiated by a tls handshake
+	VersionPart
+	// CipherPart matches the cipher suite negotiated by a tls handshake
+	CipherPart
+)
+
+const (
+	// headerPartPrefix is the prefix of the parts matching a single header
+	headerPartPrefix = "header."
+	// cookiePartPrefix is the prefix of the parts matching a single cookie
+	cookiePartPrefix = "cookie."
 )
 
 // PartTypes is an table for conversion of part type from string.
@@ -63,9 +156,49 @@ var PartTypes = map[string]Part{
 	"body":   BodyPart,
 	"header": HeaderPart,
 	"all":    AllPart,
+	"cookie": CookiePart,
+	"trace":  TracePart,
+	"rrsig":  RRSIGPart,
+	// dns message sections
+	"answer":     AnswerPart,
+	"authority":  AuthorityPart,
+	"additional": AdditionalPart,
+	// tls certificates
+	"certificate": CertificatePart,
+	// headless pages, the dom being their body
+	"dom":     BodyPart,
+	"console": ConsolePart,
+	"dialog":  DialogPart,
+	"network": NetworkPart,
+	// network connections, the bytes read being their body
+	"data":  BodyPart,
+	"error": ErrorPart,
+	// websocket connections, the messages read being their body
+	"messages": BodyPart,
+	// ssl handshakes, the negotiated values and the certificates being
+	// their body
+	"version": VersionPart,
+	"cipher":  CipherPart,
+}
+
+// dnsSections is the table of the dns message sections of the parts
+var dnsSections = map[Part]string{
+	AnswerPart:     dnsrecords.AnswerSection,
+	AuthorityPart:  dnsrecords.AuthoritySection,
+	AdditionalPart: dnsrecords.AdditionalSection,
 }
 
 // GetPart returns the part of the matcher
 func (e *Extractor) GetPart() Part {
 	return e.part
 }
+
+// SetGuard sets the guard limiting the inputs of the regexes of the extractor
+func (e *Extractor) SetGuard(guard *regexguard.Guard) {
+	e.guard = guard
+}
+
+// OutputFile returns the file the extracted values of a template are appended to
+func (e *Extractor) OutputFile(templateID string) string {
+	return strings.NewReplacer("{{template-id}}", templateID, "{{extractor-name}}", e.Name).Replace(e.ToFile)
+}
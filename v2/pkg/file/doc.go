@@ -0,0 +1,5 @@
+// Package file walks the local files and directories targeted by the file
+// requests of the templates, such as a source checkout or a log directory,
+// reading the files concurrently for their matchers and extractors along
+// with the locations of the matches in them.
+package file
@@ -0,0 +1,285 @@
+package file
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+)
+
+// DefaultMaxSize is the default maximum size of the files read, in bytes
+const DefaultMaxSize = 5 * 1024 * 1024
+
+// DefaultConcurrency is the default number of files read at once
+const DefaultConcurrency = 10
+
+// binarySniffSize is the number of leading bytes of a file searched for a
+// NUL byte to tell the binary files apart, as git does.
+const binarySniffSize = 8000
+
+// archiveExtensions are the extensions of the archives and the compressed
+// files, skipped unless read as opaque files.
+var archiveExtensions = map[string]struct{}{
+	".zip": {}, ".jar": {}, ".war": {}, ".ear": {}, ".apk": {},
+	".tar": {}, ".gz": {}, ".tgz": {}, ".bz2": {}, ".xz": {},
+	".zst": {}, ".7z": {}, ".rar": {},
+}
+
+// Options are the options of the walk of a target
+type Options struct {
+	// Extensions are the extensions of the files read, i.e .go or go, all
+	// the files being read if empty.
+	Extensions []string
+	// ExcludeExtensions are the extensions of the files skipped
+	ExcludeExtensions []string
+	// MaxSize is the maximum size of the files read in bytes, the larger
+	// ones being skipped. DefaultMaxSize if 0.
+	MaxSize int64
+	// Archives reads the archives as opaque files, without extracting them.
+	// They are skipped otherwise.
+	Archives bool
+	// Binary reads the binary files, skipped otherwise
+	Binary bool
+	// Concurrency is the number of files read at once, DefaultConcurrency
+	// if 0.
+	Concurrency int
+}
+
+// Response is a file read by the walk
+type Response struct {
+	// Path is the path of the file, under the target of the walk
+	Path string
+	// Data is the content of the file, as is
+	Data string
+}
+
+// Location is the location of a match in a file
+type Location struct {
+	// Line is the line of the match, from 1
+	Line int `json:"line"`
+	// Offset is the offset of the match in bytes, from 0
+	Offset int `json:"offset"`
+}
+
+// String returns the location as line:offset
+func (l Location) String() string {
+	return fmt.Sprintf("%d:%d", l.Line, l.Offset)
+}
+
+// Locations returns the locations of offsets of the content of the file in
+// order, without duplicates. The offsets out of the content are ignored.
+func (r *Response) Locations(offsets []int) []Location {
+	sorted := make([]int, 0, len(offsets))
+	for _, offset := range offsets {
+		if offset >= 0 && offset <= len(r.Data) {
+			sorted = append(sorted, offset)
+		}
+	}
+	sort.Ints(sorted)
+
+	var locations []Location
+	line, counted := 1, 0
+	for i, offset := range sorted {
+		if i > 0 && offset == sorted[i-1] {
+			continue
+		}
+		// the lines are counted once from the previous offset
+		line += strings.Count(r.Data[counted:offset], "\n")
+		counted = offset
+		locations = append(locations, Location{Line: line, Offset: offset})
+	}
+	return locations
+}
+
+// Walk reads the files of a target, a file or a directory walked
+// recursively, calling fn for each file read from at most Concurrency
+// goroutines at once. The symbolic links are followed, each directory and
+// file being read once whatever the links to it so the loops of links end.
+// The entries which can't be read are skipped and the walk stops once the
+// context is done, returning its error. An error is returned if the target
+// can't be read.
+func Walk(ctx context.Context, target string, options *Options, fn func(*Response)) error {
+	info, err := os.Stat(target)
+	if err != nil {
+		return fmt.Errorf("could not read %s: %w", target, err)
+	}
+	real, err := filepath.EvalSymlinks(target)
+	if err != nil {
+		return fmt.Errorf("could not read %s: %w", target, err)
+	}
+
+	w := newWalker(options)
+	concurrency := options.Concurrency
+	if concurrency <= 0 {
+		concurrency = DefaultConcurrency
+	}
+	paths := make(chan string)
+	wg := &sync.WaitGroup{}
+	for i := 0; i < concurrency; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for path := range paths {
+				if resp, ok := w.read(path); ok {
+					fn(resp)
+				}
+			}
+		}()
+	}
+	if info.IsDir() {
+		w.walk(ctx, target, real, paths)
+	} else if info.Mode().IsRegular() && w.accepts(target, info) {
+		// a file targeted directly is read whatever its extension
+		w.send(ctx, target, paths)
+	}
+	close(paths)
+	wg.Wait()
+	return ctx.Err()
+}
+
+// walker walks the directories of a target from a single goroutine
+type walker struct {
+	options    *Options
+	maxSize    int64
+	extensions map[string]struct{}
+	excluded   map[string]struct{}
+	// seen are the real paths of the directories and the files walked
+	seen map[string]struct{}
+}
+
+// newWalker returns a walker of the files matching the options
+func newWalker(options *Options) *walker {
+	w := &walker{
+		options:    options,
+		maxSize:    options.MaxSize,
+		extensions: normalizeExtensions(options.Extensions),
+		excluded:   normalizeExtensions(options.ExcludeExtensions),
+		seen:       make(map[string]struct{}),
+	}
+	if w.maxSize <= 0 {
+		w.maxSize = DefaultMaxSize
+	}
+	return w
+}
+
+// normalizeExtensions returns the lower cased extensions with their dot
+func normalizeExtensions(extensions []string) map[string]struct{} {
+	normalized := make(map[string]struct{}, len(extensions))
+	for _, extension := range extensions {
+		extension = strings.ToLower(strings.TrimSpace(extension))
+		if extension == "" {
+			continue
+		}
+		if !strings.HasPrefix(extension, ".") {
+			extension = "." + extension
+		}
+		normalized[extension] = struct{}{}
+	}
+	return normalized
+}
+
+// walk sends the files of a directory whose real path is real to be read,
+// walking its subdirectories unless seen before.
+func (w *walker) walk(ctx context.Context, dir, real string, paths chan<- string) {
+	if _, ok := w.seen[real]; ok {
+		return
+	}
+	w.seen[real] = struct{}{}
+
+	entries, err := ioutil.ReadDir(dir)
+	if err != nil {
+		return
+	}
+	for _, entry := range entries {
+		if ctx.Err() != nil {
+			return
+		}
+		path := filepath.Join(dir, entry.Name())
+		entryReal := filepath.Join(real, entry.Name())
+		if entry.Mode()&os.ModeSymlink != 0 {
+			// the links are followed, the broken ones being skipped
+			if entry, err = os.Stat(path); err != nil {
+				continue
+			}
+			if entryReal, err = filepath.EvalSymlinks(path); err != nil {
+				continue
+			}
+		}
+		switch {
+		case entry.IsDir():
+			w.walk(ctx, path, entryReal, paths)
+		case entry.Mode().IsRegular():
+			if _, ok := w.seen[entryReal]; ok || !w.accepts(path, entry) || !w.filtered(path) {
+				continue
+			}
+			w.seen[entryReal] = struct{}{}
+			w.send(ctx, path, paths)
+		}
+	}
+}
+
+// send sends a file to be read unless the context is done
+func (w *walker) send(ctx context.Context, path string, paths chan<- string) {
+	select {
+	case paths <- path:
+	case <-ctx.Done():
+	}
+}
+
+// accepts returns true if a file isn't too large and isn't an archive,
+// unless the archives are read.
+func (w *walker) accepts(path string, info os.FileInfo) bool {
+	if info.Size() > w.maxSize {
+		return false
+	}
+	if isArchive(path) {
+		return w.options.Archives
+	}
+	return true
+}
+
+// filtered returns true if the extension of a file is included and isn't
+// excluded.
+func (w *walker) filtered(path string) bool {
+	extension := strings.ToLower(filepath.Ext(path))
+	if _, ok := w.excluded[extension]; ok {
+		return false
+	}
+	if len(w.extensions) == 0 {
+		return true
+	}
+	_, ok := w.extensions[extension]
+	return ok
+}
+
+// read reads a file, returning false if it can't be read or is a binary
+// file skipped. The archives read are opaque files whatever their content.
+func (w *walker) read(path string) (*Response, bool) {
+	data, err := ioutil.ReadFile(path)
+	if err != nil {
+		return nil, false
+	}
+	if !w.options.Binary && !isArchive(path) && isBinary(data) {
+		return nil, false
+	}
+	return &Response{Path: path, Data: string(data)}, true
+}
+
+// isArchive returns true if a file is an archive or a compressed file
+func isArchive(path string) bool {
+	_, ok := archiveExtensions[strings.ToLower(filepath.Ext(path))]
+	return ok
+}
+
+// isBinary returns true if the leading bytes of a file contain a NUL byte
+func isBinary(data []byte) bool {
+	if len(data) > binarySniffSize {
+		data = data[:binarySniffSize]
+	}
+	return bytes.IndexByte(data, 0) != -1
+}
@@ -0,0 +1,96 @@
+package file
+
+import (
+	"context"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+// newCheckout returns a directory of source files, along with a binary, an
+// archive, a large file and links looping to the directory.
+func newCheckout(t *testing.T) string {
+	dir, err := ioutil.TempDir("", "nuclei-file")
+	require.Nil(t, err, "Could not create the directory")
+	files := map[string]string{
+		"main.go":         "package main\n\nconst token = \"ghp_secret\"\n",
+		"config/app.yml":  "password: hunter2\n",
+		"config/app.YML":  "password: hunter3\n",
+		"README":          "no extension\n",
+		"bin/tool":        "\x7fELF\x00\x00ghp_secret",
+		"dist/app.zip":    "PK\x03\x04\x00ghp_secret",
+		"logs/large.log":  strings.Repeat("x", 2048),
+		"logs/server.log": "GET /\nGET /admin\n",
+	}
+	for name, content := range files {
+		path := filepath.Join(dir, name)
+		require.Nil(t, os.MkdirAll(filepath.Dir(path), 0755), "Could not create the directory")
+		require.Nil(t, ioutil.WriteFile(path, []byte(content), 0644), "Could not write the file")
+	}
+	require.Nil(t, os.Symlink(dir, filepath.Join(dir, "config", "loop")), "Could not link the directory")
+	require.Nil(t, os.Symlink(filepath.Join(dir, "main.go"), filepath.Join(dir, "link.go")), "Could not link the file")
+	require.Nil(t, os.Symlink(filepath.Join(dir, "missing"), filepath.Join(dir, "broken.go")), "Could not link the missing file")
+	return dir
+}
+
+// walk returns the paths of the files read by a walk relative to the target
+func walk(t *testing.T, target string, options *Options) []string {
+	var paths []string
+	mutex := &sync.Mutex{}
+	err := Walk(context.Background(), target, options, func(resp *Response) {
+		relative, err := filepath.Rel(target, resp.Path)
+		require.Nil(t, err, "Could not read the path")
+		mutex.Lock()
+		paths = append(paths, filepath.ToSlash(relative))
+		mutex.Unlock()
+	})
+	require.Nil(t, err, "Could not walk the target")
+	sort.Strings(paths)
+	return paths
+}
+
+func TestWalk(t *testing.T) {
+	dir := newCheckout(t)
+	defer os.RemoveAll(dir)
+
+	paths := walk(t, dir, &Options{MaxSize: 1024, Concurrency: 2})
+	// main.go is read once whatever the links to it
+	require.Len(t, paths, 5, "Could not skip the binaries, the archives, the large files and the loops")
+	require.Contains(t, paths, "README", "Could not read the file without extension")
+	require.Contains(t, paths, "logs/server.log", "Could not read the nested file")
+	require.Contains(t, paths, "config/app.yml", "Could not read the nested file")
+	require.Contains(t, paths, "config/app.YML", "Could not read the nested file")
+
+	paths = walk(t, dir, &Options{Extensions: []string{"yml", ".LOG"}, ExcludeExtensions: []string{"log"}})
+	require.Equal(t, []string{"config/app.YML", "config/app.yml"}, paths, "Could not filter the extensions")
+
+	paths = walk(t, dir, &Options{Extensions: []string{".zip", "tool"}, Archives: true})
+	require.Equal(t, []string{"dist/app.zip"}, paths, "Could not read the archive as an opaque file")
+
+	paths = walk(t, filepath.Join(dir, "bin"), &Options{Binary: true})
+	require.Equal(t, []string{"tool"}, paths, "Could not read the binary file")
+
+	paths = walk(t, filepath.Join(dir, "main.go"), &Options{Extensions: []string{"yml"}})
+	require.Equal(t, []string{"."}, paths, "Could not read the file targeted directly")
+
+	err := Walk(context.Background(), filepath.Join(dir, "missing"), &Options{}, func(*Response) {})
+	require.NotNil(t, err, "Could not fail the missing target")
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	err = Walk(ctx, dir, &Options{}, func(*Response) {})
+	require.Equal(t, context.Canceled, err, "Could not return the error of the context")
+}
+
+func TestLocations(t *testing.T) {
+	resp := &Response{Data: "first\nsecond secret\nthird secret\n"}
+	locations := resp.Locations([]int{26, 13, 0, 13, 100})
+	require.Equal(t, []Location{{Line: 1, Offset: 0}, {Line: 2, Offset: 13}, {Line: 3, Offset: 26}}, locations, "Could not locate the offsets")
+	require.Equal(t, "2:13", locations[1].String(), "Could not format the location")
+}
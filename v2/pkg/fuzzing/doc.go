@@ -0,0 +1,6 @@
+// Package fuzzing injects the payloads of the fuzzing rules of the http
+// requests into each parameter of a part of the built requests, the query,
+// the headers, the path segments or the form fields of the body, replacing
+// their value, appended or prefixed to it. The parameters are parsed in
+// order and the ones not fuzzed are kept as written, with their encoding.
+package fuzzing
@@ -0,0 +1,353 @@
+package fuzzing
+
+import (
+	"errors"
+	"fmt"
+	"mime"
+	"net/http"
+	"net/url"
+	"sort"
+	"strings"
+
+	"github.com/projectdiscovery/nuclei/v2/pkg/generators"
+)
+
+// The parts of the requests fuzzed
+const (
+	// QueryPart fuzzes the parameters of the query
+	QueryPart = "query"
+	// HeaderPart fuzzes the headers
+	HeaderPart = "header"
+	// PathPart fuzzes the segments of the path
+	PathPart = "path"
+	// BodyPart fuzzes the form fields of the url-encoded bodies
+	BodyPart = "body"
+)
+
+// The modes of the injection of the payloads
+const (
+	// ReplaceMode replaces the values with the payloads
+	ReplaceMode = "replace"
+	// AppendMode appends the payloads to the values
+	AppendMode = "append"
+	// PrefixMode prefixes the values with the payloads
+	PrefixMode = "prefix"
+)
+
+// skippedHeaders are the headers not fuzzed unless they are keys, the ones
+// set by the transport.
+var skippedHeaders = map[string]struct{}{"Connection": {}, "Content-Length": {}, "Host": {}}
+
+// Rule is a fuzzing rule of an http request, each of its payloads being
+// injected into each parameter of a part of the built requests in turn, a
+// request being sent per parameter and payload.
+type Rule struct {
+	// Part is the part of the requests fuzzed, query (default), header,
+	// path or body, the form fields of the url-encoded bodies.
+	Part string `yaml:"part,omitempty"`
+	// Mode is how the payloads are injected into the values, replace
+	// (default), append or prefix.
+	Mode string `yaml:"mode,omitempty"`
+	// Keys are the names of the parameters fuzzed, all of them by default.
+	// The array parameters match with or without their brackets, a
+	// matching a[] and a[0], and the headers of the keys are added to the
+	// requests without them. The keys of the path are its segments.
+	Keys []string `yaml:"keys,omitempty"`
+	// Payloads are the values injected by name, lists or files as the
+	// payloads of the requests, their placeholders being replaced.
+	Payloads map[string]interface{} `yaml:"payloads"`
+
+	// part and mode are the compiled part and mode
+	part string
+	mode string
+	// values are the loaded values of the payloads, by name in order
+	values []Payload
+}
+
+// Payload is a value of a payload of a rule
+type Payload struct {
+	Name  string
+	Value string
+}
+
+// Compile validates the part and the mode of a rule and loads the values
+// of its payloads.
+func (r *Rule) Compile() error {
+	switch r.Part {
+	case "":
+		r.part = QueryPart
+	case QueryPart, HeaderPart, PathPart, BodyPart:
+		r.part = r.Part
+	default:
+		return fmt.Errorf("unknown fuzzing part %s (supported: query, header, path, body)", r.Part)
+	}
+	switch r.Mode {
+	case "":
+		r.mode = ReplaceMode
+	case ReplaceMode, AppendMode, PrefixMode:
+		r.mode = r.Mode
+	default:
+		return fmt.Errorf("unknown fuzzing mode %s (supported: replace, append, prefix)", r.Mode)
+	}
+	if len(r.Payloads) == 0 {
+		return errors.New("fuzzing rule has no payloads")
+	}
+
+	loaded := generators.LoadPayloads(r.Payloads)
+	names := make([]string, 0, len(loaded))
+	for name := range loaded {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	r.values = nil
+	for _, name := range names {
+		if len(loaded[name]) == 0 {
+			return fmt.Errorf("fuzzing payload %s has no values", name)
+		}
+		for _, value := range loaded[name] {
+			r.values = append(r.values, Payload{Name: name, Value: value})
+		}
+	}
+	return nil
+}
+
+// Values returns the values of the payloads of a compiled rule, by name in
+// order.
+func (r *Rule) Values() []Payload {
+	return r.values
+}
+
+// Contains returns true if a value of the payloads contains a string, i.e
+// a placeholder.
+func (r *Rule) Contains(value string) bool {
+	for _, payload := range r.values {
+		if strings.Contains(payload.Value, value) {
+			return true
+		}
+	}
+	return false
+}
+
+// Parameter is a parameter of a part of a request the payloads of a rule
+// are injected into.
+type Parameter struct {
+	// Name is the decoded name of a query parameter or a form field, the
+	// name of a header or the decoded segment of a path.
+	Name string
+	// Value is the value of the parameter as written
+	Value string
+	// Position is the 1-based position of the parameter in its part, the
+	// array parameters repeating their names.
+	Position int
+
+	// missing is true for the headers of the keys the request is without
+	missing bool
+}
+
+// Parameters returns the parameters of a request fuzzed by a rule in order,
+// along with its body. The headers are sorted by name.
+func (r *Rule) Parameters(req *http.Request, body []byte) []*Parameter {
+	var parameters []*Parameter
+	switch r.part {
+	case QueryPart:
+		parameters = r.pairParameters(req.URL.RawQuery)
+	case BodyPart:
+		if isForm(req) {
+			parameters = r.pairParameters(string(body))
+		}
+	case PathPart:
+		for i, segment := range strings.Split(req.URL.EscapedPath(), "/") {
+			if segment == "" {
+				continue
+			}
+			if name := unescape(segment, url.PathUnescape); r.fuzzes(name, false) {
+				parameters = append(parameters, &Parameter{Name: name, Value: segment, Position: i})
+			}
+		}
+	case HeaderPart:
+		parameters = r.headerParameters(req.Header)
+	}
+	return parameters
+}
+
+// pairParameters returns the parameters of a query or a url-encoded body
+func (r *Rule) pairParameters(raw string) []*Parameter {
+	if raw == "" {
+		return nil
+	}
+	var parameters []*Parameter
+	for i, pair := range strings.Split(raw, "&") {
+		if pair == "" {
+			continue
+		}
+		key, value := splitPair(pair)
+		if name := unescape(key, url.QueryUnescape); r.fuzzes(name, false) {
+			parameters = append(parameters, &Parameter{Name: name, Value: value, Position: i + 1})
+		}
+	}
+	return parameters
+}
+
+// headerParameters returns the headers fuzzed by name, the headers of the
+// keys being added if missing.
+func (r *Rule) headerParameters(header http.Header) []*Parameter {
+	names := make([]string, 0, len(header))
+	for name := range header {
+		if _, ok := skippedHeaders[http.CanonicalHeaderKey(name)]; ok && len(r.Keys) == 0 {
+			continue
+		}
+		if r.fuzzes(name, true) {
+			names = append(names, name)
+		}
+	}
+	// the headers of the request matching a key are fuzzed already
+	for _, key := range r.Keys {
+		if !hasHeader(names, key) {
+			names = append(names, http.CanonicalHeaderKey(key))
+		}
+	}
+	sort.Strings(names)
+
+	parameters := make([]*Parameter, 0, len(names))
+	for i, name := range names {
+		values, ok := header[name]
+		parameters = append(parameters, &Parameter{Name: name, Value: strings.Join(values, ", "), Position: i + 1, missing: !ok})
+	}
+	return parameters
+}
+
+// fuzzes returns true if a parameter is fuzzed by a rule, the names of the
+// headers being case-insensitive.
+func (r *Rule) fuzzes(name string, header bool) bool {
+	if len(r.Keys) == 0 {
+		return true
+	}
+	base := name
+	if index := strings.IndexByte(name, '['); index > 0 {
+		base = name[:index]
+	}
+	for _, key := range r.Keys {
+		if header && strings.EqualFold(key, name) {
+			return true
+		}
+		if !header && (key == name || key == base) {
+			return true
+		}
+	}
+	return false
+}
+
+// Mutate returns a copy of a request with a payload injected into one of
+// its parameters, along with its body, the payload being encoded as the
+// query or the path but for the headers. The other parameters are kept as
+// written.
+func (r *Rule) Mutate(req *http.Request, body []byte, parameter *Parameter, payload string) (*http.Request, []byte) {
+	mutated := req.Clone(req.Context())
+	mutatedBody := append([]byte(nil), body...)
+	switch r.part {
+	case QueryPart:
+		mutated.URL.RawQuery = r.mutatePairs(req.URL.RawQuery, parameter, url.QueryEscape(payload))
+	case BodyPart:
+		mutatedBody = []byte(r.mutatePairs(string(body), parameter, url.QueryEscape(payload)))
+	case PathPart:
+		segments := strings.Split(req.URL.EscapedPath(), "/")
+		segments[parameter.Position] = r.inject(segments[parameter.Position], url.PathEscape(payload))
+		escaped := strings.Join(segments, "/")
+		mutated.URL.RawPath = escaped
+		mutated.URL.Path = unescape(escaped, url.PathUnescape)
+	case HeaderPart:
+		// the headers of the keys are added with the payload alone
+		if parameter.missing {
+			mutated.Header[parameter.Name] = []string{payload}
+		} else {
+			mutated.Header[parameter.Name] = []string{r.inject(parameter.Value, payload)}
+		}
+	}
+	return mutated, mutatedBody
+}
+
+// mutatePairs injects an encoded payload into the value of a parameter of
+// a query or a url-encoded body.
+func (r *Rule) mutatePairs(raw string, parameter *Parameter, payload string) string {
+	pairs := strings.Split(raw, "&")
+	key, value := splitPair(pairs[parameter.Position-1])
+	pairs[parameter.Position-1] = key + "=" + r.inject(value, payload)
+	return strings.Join(pairs, "&")
+}
+
+// inject injects a payload into a value with the mode of a rule
+func (r *Rule) inject(value, payload string) string {
+	switch r.mode {
+	case AppendMode:
+		return value + payload
+	case PrefixMode:
+		return payload + value
+	}
+	return payload
+}
+
+// Mutation returns the mutation of a request with the value of a payload
+// injected into a parameter by a rule, once its placeholders are replaced.
+func (r *Rule) Mutation(parameter *Parameter, name, payload string) *Mutation {
+	return &Mutation{
+		Part:        r.part,
+		Parameter:   parameter.Name,
+		Position:    parameter.Position,
+		Mode:        r.mode,
+		PayloadName: name,
+		Payload:     payload,
+	}
+}
+
+// Mutation is the payload injected into a parameter of a fuzzed request,
+// written along with its results.
+type Mutation struct {
+	Part      string `json:"part"`
+	Parameter string `json:"parameter"`
+	// Position is the 1-based position of the parameter in its part, the
+	// segment of a path or the sorted header, telling the parameters with
+	// the same names apart.
+	Position    int    `json:"position"`
+	Mode        string `json:"mode"`
+	PayloadName string `json:"payload_name"`
+	Payload     string `json:"payload"`
+}
+
+// String returns the parameter of a mutation followed by its payload, i.e
+// query:id=1'
+func (m *Mutation) String() string {
+	return m.Part + ":" + m.Parameter + "=" + m.Payload
+}
+
+// splitPair returns the key and the value of a parameter as written
+func splitPair(pair string) (string, string) {
+	if index := strings.IndexByte(pair, '='); index != -1 {
+		return pair[:index], pair[index+1:]
+	}
+	return pair, ""
+}
+
+// unescape returns a decoded value, as written if it is malformed
+func unescape(value string, decode func(string) (string, error)) string {
+	if decoded, err := decode(value); err == nil {
+		return decoded
+	}
+	return value
+}
+
+// isForm returns true if the body of a request is url-encoded
+func isForm(req *http.Request) bool {
+	mediaType, _, err := mime.ParseMediaType(req.Header.Get("Content-Type"))
+	return err == nil && mediaType == "application/x-www-form-urlencoded"
+}
+
+// hasHeader returns true if the names of the headers contain a name,
+// case-insensitively.
+func hasHeader(names []string, name string) bool {
+	for _, key := range names {
+		if strings.EqualFold(key, name) {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,96 @@
+package fuzzing
+
+import (
+	"io/ioutil"
+	"net/http"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+// compile returns a compiled rule fuzzing a part with a payload
+func compile(t *testing.T, part, mode string, keys []string, payloads ...interface{}) *Rule {
+	rule := &Rule{Part: part, Mode: mode, Keys: keys, Payloads: map[string]interface{}{"injection": payloads}}
+	require.Nil(t, rule.Compile(), "Could not compile rule")
+	return rule
+}
+
+// mutate returns the requests of a rule injecting its first payload into
+// each parameter of a request in turn
+func mutate(t *testing.T, rule *Rule, req *http.Request, body string) ([]*Parameter, []*http.Request, []string) {
+	parameters := rule.Parameters(req, []byte(body))
+	var mutated []*http.Request
+	var bodies []string
+	for _, parameter := range parameters {
+		mutatedReq, mutatedBody := rule.Mutate(req, []byte(body), parameter, rule.Values()[0].Value)
+		mutated = append(mutated, mutatedReq)
+		bodies = append(bodies, string(mutatedBody))
+	}
+	return parameters, mutated, bodies
+}
+
+func TestQuery(t *testing.T) {
+	req, err := http.NewRequest(http.MethodGet, "http://example.com/search?q=a%20b&cat[]=1&cat%5B%5D=2&flag&z=+", nil)
+	require.Nil(t, err, "Could not create request")
+
+	parameters, mutated, _ := mutate(t, compile(t, "", "", nil, "x'y"), req, "")
+	var names []string
+	for _, parameter := range parameters {
+		names = append(names, parameter.Name)
+	}
+	require.Equal(t, []string{"q", "cat[]", "cat[]", "flag", "z"}, names, "Could not parse the parameters in order")
+	require.Equal(t, 3, parameters[2].Position, "Could not get the position of the array parameter")
+	require.Equal(t, "q=x%27y&cat[]=1&cat%5B%5D=2&flag&z=+", mutated[0].URL.RawQuery, "Could not keep the encoding of the other parameters")
+	require.Equal(t, "q=a%20b&cat[]=1&cat%5B%5D=x%27y&flag&z=+", mutated[2].URL.RawQuery, "Could not fuzz the second array parameter")
+	require.Equal(t, "q=a%20b&cat[]=1&cat%5B%5D=2&flag=x%27y&z=+", mutated[3].URL.RawQuery, "Could not fuzz the parameter without value")
+	require.Equal(t, "q=a%20b&cat[]=1&cat%5B%5D=2&flag&z=+", req.URL.RawQuery, "Could modify the fuzzed request")
+
+	parameters, mutated, _ = mutate(t, compile(t, "query", "append", []string{"cat"}, "!"), req, "")
+	require.Len(t, parameters, 2, "Could not match the array parameters by their names")
+	require.Equal(t, "q=a%20b&cat[]=1%21&cat%5B%5D=2&flag&z=+", mutated[0].URL.RawQuery, "Could not append the payload")
+
+	_, mutated, _ = mutate(t, compile(t, "query", "prefix", []string{"q"}, "-"), req, "")
+	require.Equal(t, "q=-a%20b&cat[]=1&cat%5B%5D=2&flag&z=+", mutated[0].URL.RawQuery, "Could not prefix the payload")
+}
+
+func TestPathHeaderBody(t *testing.T) {
+	req, err := http.NewRequest(http.MethodPost, "http://example.com/api/user%20s/42", strings.NewReader("name=a&id=1"))
+	require.Nil(t, err, "Could not create request")
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	req.Header.Set("User-Agent", "test")
+	req.Header.Set("Connection", "close")
+
+	parameters, mutated, _ := mutate(t, compile(t, "path", "", nil, "../x"), req, "")
+	require.Len(t, parameters, 3, "Could not parse the segments of the path")
+	require.Equal(t, "user s", parameters[1].Name, "Could not decode the segment")
+	require.Equal(t, "/api/user%20s/..%2Fx", mutated[2].URL.EscapedPath(), "Could not fuzz the segment")
+
+	parameters, mutated, _ = mutate(t, compile(t, "header", "", nil, "x"), req, "")
+	require.Len(t, parameters, 2, "Could not skip the headers of the transport")
+	require.Equal(t, "x", mutated[1].Header.Get("User-Agent"), "Could not fuzz the header")
+	require.Equal(t, "application/x-www-form-urlencoded", mutated[1].Header.Get("Content-Type"), "Could fuzz another header")
+
+	parameters, mutated, _ = mutate(t, compile(t, "header", "", []string{"user-agent", "X-Forwarded-For"}, "127.0.0.1"), req, "")
+	require.Len(t, parameters, 2, "Could not fuzz the headers of the keys")
+	require.Equal(t, "127.0.0.1", mutated[1].Header.Get("X-Forwarded-For"), "Could not add the missing header")
+
+	body, err := ioutil.ReadAll(req.Body)
+	require.Nil(t, err, "Could not read body")
+	parameters, _, bodies := mutate(t, compile(t, "body", "", nil, "<b>"), req, string(body))
+	require.Len(t, parameters, 2, "Could not parse the form fields")
+	require.Equal(t, []string{"name=%3Cb%3E&id=1", "name=a&id=%3Cb%3E"}, bodies, "Could not fuzz the form fields")
+
+	req.Header.Set("Content-Type", "application/json")
+	require.Empty(t, compile(t, "body", "", nil, "x").Parameters(req, body), "Could fuzz a body which isn't a form")
+}
+
+func TestCompile(t *testing.T) {
+	rule := &Rule{Payloads: map[string]interface{}{"b": []interface{}{"2"}, "a": []interface{}{"1", "3"}}}
+	require.Nil(t, rule.Compile(), "Could not compile rule")
+	require.Equal(t, []Payload{{Name: "a", Value: "1"}, {Name: "a", Value: "3"}, {Name: "b", Value: "2"}}, rule.Values(), "Could not load the payloads by name in order")
+
+	require.NotNil(t, (&Rule{Part: "cookie", Payloads: rule.Payloads}).Compile(), "Could compile an unknown part")
+	require.NotNil(t, (&Rule{Mode: "insert", Payloads: rule.Payloads}).Compile(), "Could compile an unknown mode")
+	require.NotNil(t, (&Rule{}).Compile(), "Could compile a rule without payloads")
+}
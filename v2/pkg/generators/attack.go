@@ -18,3 +18,36 @@ var AttackTypes = map[string]Type{
 	"pitchfork":   PitchFork,
 	"clusterbomb": ClusterBomb,
 }
+
+// Combinations returns the number of combinations of the payloads an
+// attack generates, the pitchfork attack generating none for wordlists of
+// different sizes.
+func Combinations(attack Type, payloads map[string][]string) int {
+	if len(payloads) == 0 {
+		return 0
+	}
+	switch attack {
+	case PitchFork:
+		size := -1
+		for _, wordlist := range payloads {
+			if size == -1 {
+				size = len(wordlist)
+			}
+			if len(wordlist) != size {
+				return 0
+			}
+		}
+		return size
+	case ClusterBomb:
+		combinations := 1
+		for _, wordlist := range payloads {
+			combinations *= len(wordlist)
+		}
+		return combinations
+	}
+	combinations := 0
+	for _, wordlist := range payloads {
+		combinations += len(wordlist)
+	}
+	return combinations
+}
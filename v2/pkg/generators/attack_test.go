@@ -0,0 +1,25 @@
+package generators
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestCombinations(t *testing.T) {
+	payloads := map[string][]string{"user": {"admin", "root"}, "password": {"admin", "root", "toor"}}
+	require.Equal(t, 5, Combinations(Sniper, payloads), "Could not count the sniper combinations")
+	require.Equal(t, 6, Combinations(ClusterBomb, payloads), "Could not count the clusterbomb combinations")
+	require.Equal(t, 0, Combinations(PitchFork, payloads), "Could not count the pitchfork combinations of different sizes")
+	require.Equal(t, 2, Combinations(PitchFork, map[string][]string{"user": {"admin", "root"}, "password": {"admin", "toor"}}), "Could not count the pitchfork combinations")
+	require.Equal(t, 0, Combinations(ClusterBomb, nil), "Could not count the combinations without payloads")
+
+	// the counts match the generated combinations
+	for attack, generator := range map[Type]func(map[string][]string, <-chan struct{}) chan map[string]interface{}{Sniper: SniperGenerator, ClusterBomb: ClusterbombGenerator} {
+		generated := 0
+		for range generator(payloads, nil) {
+			generated++
+		}
+		require.Equal(t, generated, Combinations(attack, payloads), "Could not count the generated combinations")
+	}
+}
@@ -1,8 +1,8 @@
 package generators
 
 // ClusterbombGenerator Attack - Generate all possible combinations from an input map with all values listed
-// as slices of the same size
-func ClusterbombGenerator(payloads map[string][]string) (out chan map[string]interface{}) {
+// as slices of the same size, until done is closed if the values are not all read
+func ClusterbombGenerator(payloads map[string][]string, done <-chan struct{}) (out chan map[string]interface{}) {
 	out = make(chan map[string]interface{})
 
 	// generator
@@ -10,9 +10,9 @@ func ClusterbombGenerator(payloads map[string][]string) (out chan map[string]int
 		defer close(out)
 		var order []string
 		var parts [][]string
-		for name, wordlist := range payloads {
+		for _, name := range payloadNames(payloads) {
 			order = append(order, name)
-			parts = append(parts, wordlist)
+			parts = append(parts, payloads[name])
 		}
 
 		var n = 1
@@ -41,7 +41,11 @@ func ClusterbombGenerator(payloads map[string][]string) (out chan map[string]int
 					item[order[i]] = ar[p]
 				}
 			}
-			out <- item
+			select {
+			case out <- item:
+			case <-done:
+				return
+			}
 			at[len(parts)-1]++
 		}
 	}()
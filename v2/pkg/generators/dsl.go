@@ -6,106 +6,498 @@ import (
 	"crypto/sha256"
 	"encoding/base64"
 	"encoding/hex"
+	"fmt"
 	"html"
+	"math"
 	"net/url"
 	"regexp"
+	"strconv"
 	"strings"
+	"sync"
+	"time"
 
 	"github.com/Knetic/govaluate"
+	"github.com/projectdiscovery/nuclei/v2/pkg/regexguard"
 )
 
-// HelperFunctions contains the dsl functions
-func HelperFunctions() (functions map[string]govaluate.ExpressionFunction) {
-	functions = make(map[string]govaluate.ExpressionFunction)
+// helperFunction is a dsl function along with the number of arguments it accepts
+type helperFunction struct {
+	// minArgs is the minimum number of arguments
+	minArgs int
+	// maxArgs is the maximum number of arguments, -1 for variadic functions
+	maxArgs int
+	// fn is the implementation receiving the validated arguments
+	fn govaluate.ExpressionFunction
+}
+
+// helpers contains the dsl functions indexed by name
+var helpers = map[string]helperFunction{
 	// strings
-	functions["len"] = func(args ...interface{}) (interface{}, error) {
-		length := len(args[0].(string))
-		return (float64)(length), nil
-	}
-	functions["toupper"] = func(args ...interface{}) (interface{}, error) {
-		return strings.ToUpper(args[0].(string)), nil
-	}
-	functions["tolower"] = func(args ...interface{}) (interface{}, error) {
-		return strings.ToLower(args[0].(string)), nil
-	}
-	functions["replace"] = func(args ...interface{}) (interface{}, error) {
-		return strings.Replace(args[0].(string), args[1].(string), args[2].(string), -1), nil
-	}
-	functions["trim"] = func(args ...interface{}) (interface{}, error) {
-		return strings.Trim(args[0].(string), args[2].(string)), nil
-	}
-	functions["trimleft"] = func(args ...interface{}) (interface{}, error) {
-		return strings.TrimLeft(args[0].(string), args[1].(string)), nil
-	}
-	functions["trimright"] = func(args ...interface{}) (interface{}, error) {
-		return strings.TrimRight(args[0].(string), args[1].(string)), nil
-	}
-	functions["trimspace"] = func(args ...interface{}) (interface{}, error) {
-		return strings.TrimSpace(args[0].(string)), nil
-	}
-	functions["trimprefix"] = func(args ...interface{}) (interface{}, error) {
-		return strings.TrimPrefix(args[0].(string), args[1].(string)), nil
-	}
-	functions["trimsuffix"] = func(args ...interface{}) (interface{}, error) {
-		return strings.TrimSuffix(args[0].(string), args[1].(string)), nil
-	}
-	functions["reverse"] = func(args ...interface{}) (interface{}, error) {
-		return reverseString(args[0].(string)), nil
-	}
+	"len": {1, 1, func(args ...interface{}) (interface{}, error) {
+		return float64(len(toString(args[0]))), nil
+	}},
+	"toupper": {1, 1, func(args ...interface{}) (interface{}, error) {
+		return strings.ToUpper(toString(args[0])), nil
+	}},
+	"tolower": {1, 1, func(args ...interface{}) (interface{}, error) {
+		return strings.ToLower(toString(args[0])), nil
+	}},
+	"replace": {3, 3, func(args ...interface{}) (interface{}, error) {
+		return strings.Replace(toString(args[0]), toString(args[1]), toString(args[2]), -1), nil
+	}},
+	"trim": {1, 2, func(args ...interface{}) (interface{}, error) {
+		// without a cutset trim removes the leading and trailing whitespaces
+		if len(args) == 1 {
+			return strings.TrimSpace(toString(args[0])), nil
+		}
+		return strings.Trim(toString(args[0]), toString(args[1])), nil
+	}},
+	"trimleft": {2, 2, func(args ...interface{}) (interface{}, error) {
+		return strings.TrimLeft(toString(args[0]), toString(args[1])), nil
+	}},
+	"trimright": {2, 2, func(args ...interface{}) (interface{}, error) {
+		return strings.TrimRight(toString(args[0]), toString(args[1])), nil
+	}},
+	"trimspace": {1, 1, func(args ...interface{}) (interface{}, error) {
+		return strings.TrimSpace(toString(args[0])), nil
+	}},
+	"trim_space": {1, 1, func(args ...interface{}) (interface{}, error) {
+		return strings.TrimSpace(toString(args[0])), nil
+	}},
+	"trimprefix": {2, 2, func(args ...interface{}) (interface{}, error) {
+		return strings.TrimPrefix(toString(args[0]), toString(args[1])), nil
+	}},
+	"trimsuffix": {2, 2, func(args ...interface{}) (interface{}, error) {
+		return strings.TrimSuffix(toString(args[0]), toString(args[1])), nil
+	}},
+	"reverse": {1, 1, func(args ...interface{}) (interface{}, error) {
+		return reverseString(toString(args[0])), nil
+	}},
+	"repeat": {2, 2, func(args ...interface{}) (interface{}, error) {
+		count, err := toCount("repeat", args[1])
+		if err != nil {
+			return nil, err
+		}
+		return strings.Repeat(toString(args[0]), count), nil
+	}},
+	"line_count": {1, 1, func(args ...interface{}) (interface{}, error) {
+		return float64(lineCount(toString(args[0]))), nil
+	}},
+	"word_count": {1, 1, func(args ...interface{}) (interface{}, error) {
+		return float64(len(strings.Fields(toString(args[0])))), nil
+	}},
 	// encoding
-	functions["base64"] = func(args ...interface{}) (interface{}, error) {
-		sEnc := base64.StdEncoding.EncodeToString([]byte(args[0].(string)))
-		return sEnc, nil
-	}
-	functions["base64_decode"] = func(args ...interface{}) (interface{}, error) {
-		sEnc := base64.StdEncoding.EncodeToString([]byte(args[0].(string)))
-		return sEnc, nil
+	"base64": {1, 1, func(args ...interface{}) (interface{}, error) {
+		return base64.StdEncoding.EncodeToString([]byte(toString(args[0]))), nil
+	}},
+	"base64_decode": {1, 1, func(args ...interface{}) (interface{}, error) {
+		decoded, err := base64.StdEncoding.DecodeString(toString(args[0]))
+		if err != nil {
+			return nil, fmt.Errorf("base64_decode: invalid base64 value: %s", err)
+		}
+		return string(decoded), nil
+	}},
+	"mmh3": {1, 1, func(args ...interface{}) (interface{}, error) {
+		return float64(MMH3([]byte(toString(args[0])))), nil
+	}},
+	"favicon_hash": {1, 1, func(args ...interface{}) (interface{}, error) {
+		return float64(FaviconHash([]byte(toString(args[0])))), nil
+	}},
+	"url_encode": {1, 1, func(args ...interface{}) (interface{}, error) {
+		return url.PathEscape(toString(args[0])), nil
+	}},
+	"url_decode": {1, 1, func(args ...interface{}) (interface{}, error) {
+		decoded, err := url.PathUnescape(toString(args[0]))
+		if err != nil {
+			return nil, fmt.Errorf("url_decode: invalid url encoded value: %s", err)
+		}
+		return decoded, nil
+	}},
+	"hex_encode": {1, 1, func(args ...interface{}) (interface{}, error) {
+		return hex.EncodeToString([]byte(toString(args[0]))), nil
+	}},
+	"hex_decode": {1, 1, func(args ...interface{}) (interface{}, error) {
+		decoded, err := hex.DecodeString(toString(args[0]))
+		if err != nil {
+			return nil, fmt.Errorf("hex_decode: invalid hex value: %s", err)
+		}
+		return string(decoded), nil
+	}},
+	"html_escape": {1, 1, func(args ...interface{}) (interface{}, error) {
+		return html.EscapeString(toString(args[0])), nil
+	}},
+	"html_unescape": {1, 1, func(args ...interface{}) (interface{}, error) {
+		return html.UnescapeString(toString(args[0])), nil
+	}},
+	// hashing
+	"md5": {1, 1, func(args ...interface{}) (interface{}, error) {
+		hash := md5.Sum([]byte(toString(args[0])))
+		return hex.EncodeToString(hash[:]), nil
+	}},
+	"sha256": {1, 1, func(args ...interface{}) (interface{}, error) {
+		h := sha256.New()
+		h.Write([]byte(toString(args[0])))
+		return hex.EncodeToString(h.Sum(nil)), nil
+	}},
+	"sha1": {1, 1, func(args ...interface{}) (interface{}, error) {
+		h := sha1.New()
+		h.Write([]byte(toString(args[0])))
+		return hex.EncodeToString(h.Sum(nil)), nil
+	}},
+	// search
+	"contains": {2, 2, func(args ...interface{}) (interface{}, error) {
+		return strings.Contains(toString(args[0]), toString(args[1])), nil
+	}},
+	"contains_any": {2, -1, func(args ...interface{}) (interface{}, error) {
+		corpus := toString(args[0])
+		for _, arg := range args[1:] {
+			if strings.Contains(corpus, toString(arg)) {
+				return true, nil
+			}
+		}
+		return false, nil
+	}},
+	"contains_all": {2, -1, func(args ...interface{}) (interface{}, error) {
+		corpus := toString(args[0])
+		for _, arg := range args[1:] {
+			if !strings.Contains(corpus, toString(arg)) {
+				return false, nil
+			}
+		}
+		return true, nil
+	}},
+	"starts_with": {2, 2, func(args ...interface{}) (interface{}, error) {
+		return strings.HasPrefix(toString(args[0]), toString(args[1])), nil
+	}},
+	"ends_with": {2, 2, func(args ...interface{}) (interface{}, error) {
+		return strings.HasSuffix(toString(args[0]), toString(args[1])), nil
+	}},
+	"regex": {2, 2, func(args ...interface{}) (interface{}, error) {
+		return matchRegex(nil, args...)
+	}},
+	// versions
+	"compare_versions": {2, -1, func(args ...interface{}) (interface{}, error) {
+		constraints := make([]string, 0, len(args)-1)
+		for _, arg := range args[1:] {
+			constraints = append(constraints, toString(arg))
+		}
+		return CompareVersions(toString(args[0]), constraints...)
+	}},
+	// similarity
+	"levenshtein": {2, 2, func(args ...interface{}) (interface{}, error) {
+		return float64(Levenshtein(toString(args[0]), toString(args[1]))), nil
+	}},
+	"simhash_diff": {2, 2, func(args ...interface{}) (interface{}, error) {
+		return float64(SimhashDiff(toString(args[0]), toString(args[1]))), nil
+	}},
+	"similarity": {2, 2, func(args ...interface{}) (interface{}, error) {
+		return Similarity(toString(args[0]), toString(args[1])), nil
+	}},
+	// random
+	"rand_base": {1, 2, func(args ...interface{}) (interface{}, error) {
+		length, err := toCount("rand_base", args[0])
+		if err != nil {
+			return nil, err
+		}
+		charset := alphanumericCharset
+		if len(args) == 2 {
+			charset = toString(args[1])
+		}
+		return randomString(charset, length)
+	}},
+	"rand_text_alpha": {1, 1, func(args ...interface{}) (interface{}, error) {
+		length, err := toCount("rand_text_alpha", args[0])
+		if err != nil {
+			return nil, err
+		}
+		return randomString(lettersCharset, length)
+	}},
+	"rand_text_numeric": {1, 1, func(args ...interface{}) (interface{}, error) {
+		length, err := toCount("rand_text_numeric", args[0])
+		if err != nil {
+			return nil, err
+		}
+		return randomString(numbersCharset, length)
+	}},
+	"rand_int": {2, 2, func(args ...interface{}) (interface{}, error) {
+		lower, err := toCount("rand_int", args[0])
+		if err != nil {
+			return nil, err
+		}
+		upper, err := toCount("rand_int", args[1])
+		if err != nil {
+			return nil, err
+		}
+		if upper < lower {
+			return nil, fmt.Errorf("rand_int expects a maximum greater than the minimum, got %d and %d", lower, upper)
+		}
+		value, err := randomInt(upper - lower + 1)
+		if err != nil {
+			return nil, err
+		}
+		return float64(lower + value), nil
+	}},
+	// time
+	"unix_time": {0, 0, func(args ...interface{}) (interface{}, error) {
+		return float64(time.Now().Unix()), nil
+	}},
+	"now": {0, 0, func(args ...interface{}) (interface{}, error) {
+		return float64(time.Now().Unix()), nil
+	}},
+	"date_time": {1, 2, func(args ...interface{}) (interface{}, error) {
+		date := time.Now()
+		if len(args) == 2 {
+			seconds, err := toCount("date_time", args[1])
+			if err != nil {
+				return nil, err
+			}
+			date = time.Unix(int64(seconds), 0)
+		}
+		return date.UTC().Format(timeLayout(toString(args[0]))), nil
+	}},
+}
+
+// HelperFunctions contains the dsl functions, the inputs of the regex
+// function having the default limit.
+func HelperFunctions() map[string]govaluate.ExpressionFunction {
+	return GuardedHelperFunctions(nil)
+}
+
+// GuardedHelperFunctions contains the dsl functions, the inputs of the
+// regex function being limited by the guard returned by guard, if any, when
+// the function is called.
+func GuardedHelperFunctions(guard func() *regexguard.Guard) (functions map[string]govaluate.ExpressionFunction) {
+	functions = make(map[string]govaluate.ExpressionFunction, len(helpers))
+	for name, helper := range helpers {
+		name, helper := name, helper
+		if name == "regex" && guard != nil {
+			helper.fn = func(args ...interface{}) (interface{}, error) {
+				return matchRegex(guard(), args...)
+			}
+		}
+		functions[name] = func(args ...interface{}) (interface{}, error) {
+			if err := helper.validate(name, len(args)); err != nil {
+				return nil, err
+			}
+			return helper.fn(args...)
+		}
 	}
-	functions["url_encode"] = func(args ...interface{}) (interface{}, error) {
-		return url.PathEscape(args[0].(string)), nil
+	return
+}
+
+// matchRegex returns true if a regex matches a corpus, limited by a guard
+func matchRegex(guard *regexguard.Guard, args ...interface{}) (interface{}, error) {
+	compiled, err := compileRegex(toString(args[0]))
+	if err != nil {
+		return nil, fmt.Errorf("regex: invalid regex %s: %s", toString(args[0]), err)
 	}
-	functions["url_decode"] = func(args ...interface{}) (interface{}, error) {
-		return url.PathUnescape(args[0].(string))
+	return compiled.MatchString(guard.Input(toString(args[1]))), nil
+}
+
+// validate returns an error naming the function if the number of arguments is invalid
+func (h helperFunction) validate(name string, count int) error {
+	switch {
+	case h.minArgs == h.maxArgs && count != h.minArgs:
+		return fmt.Errorf("%s expects %d arguments, got %d", name, h.minArgs, count)
+	case count < h.minArgs:
+		return fmt.Errorf("%s expects at least %d arguments, got %d", name, h.minArgs, count)
+	case h.maxArgs != -1 && count > h.maxArgs:
+		return fmt.Errorf("%s expects at most %d arguments, got %d", name, h.maxArgs, count)
 	}
-	functions["hex_encode"] = func(args ...interface{}) (interface{}, error) {
-		return hex.EncodeToString([]byte(args[0].(string))), nil
+	return nil
+}
+
+// ValidateExpression checks the number of arguments of the dsl functions
+// called by an expression, so templates fail to load instead of never matching.
+func ValidateExpression(expression string) error {
+	for _, call := range functionCalls(expression) {
+		helper, ok := helpers[call.name]
+		if !ok {
+			continue
+		}
+		if err := helper.validate(call.name, call.args); err != nil {
+			return err
+		}
 	}
-	functions["hex_decode"] = func(args ...interface{}) (interface{}, error) {
-		hx, _ := hex.DecodeString(args[0].(string))
-		return string(hx), nil
+	return nil
+}
+
+// functionCall is a call to a function found in an expression
+type functionCall struct {
+	name string
+	args int
+}
+
+// functionCalls returns the function calls of an expression with their arguments count
+func functionCalls(expression string) []functionCall {
+	type clause struct {
+		call  int // index of the call in calls, -1 for parenthesis
+		empty bool
 	}
-	functions["html_escape"] = func(args ...interface{}) (interface{}, error) {
-		return html.EscapeString(args[0].(string)), nil
+
+	var (
+		calls   []functionCall
+		clauses []clause
+		quote   rune
+		escaped bool
+	)
+	for i, r := range expression {
+		if quote != 0 {
+			switch {
+			case escaped:
+				escaped = false
+			case r == '\\':
+				escaped = true
+			case r == quote:
+				quote = 0
+			}
+			continue
+		}
+		if len(clauses) > 0 && r != ')' && r != ' ' {
+			clauses[len(clauses)-1].empty = false
+		}
+
+		switch r {
+		case '"', '\'':
+			quote = r
+		case '(':
+			name := functionName(expression[:i])
+			if name == "" {
+				clauses = append(clauses, clause{call: -1})
+				continue
+			}
+			calls = append(calls, functionCall{name: name, args: 1})
+			clauses = append(clauses, clause{call: len(calls) - 1, empty: true})
+		case ',':
+			if len(clauses) > 0 && clauses[len(clauses)-1].call != -1 {
+				calls[clauses[len(clauses)-1].call].args++
+			}
+		case ')':
+			if len(clauses) == 0 {
+				continue
+			}
+			last := clauses[len(clauses)-1]
+			clauses = clauses[:len(clauses)-1]
+			if last.call != -1 && last.empty {
+				calls[last.call].args = 0
+			}
+		}
 	}
-	functions["html_unescape"] = func(args ...interface{}) (interface{}, error) {
-		return html.UnescapeString(args[0].(string)), nil
+	return calls
+}
+
+// functionName returns the identifier at the end of an expression prefix
+func functionName(prefix string) string {
+	prefix = strings.TrimRight(prefix, " ")
+	start := len(prefix)
+	for start > 0 {
+		c := prefix[start-1]
+		if c != '_' && (c < 'a' || c > 'z') && (c < 'A' || c > 'Z') && (c < '0' || c > '9') {
+			break
+		}
+		start--
 	}
-	// hashing
-	functions["md5"] = func(args ...interface{}) (interface{}, error) {
-		hash := md5.Sum([]byte(args[0].(string)))
-		return hex.EncodeToString(hash[:]), nil
+	return prefix[start:]
+}
+
+// maxCachedRegexes is the maximum number of regexes of the regex function kept compiled
+const maxCachedRegexes = 1024
+
+var (
+	regexCache      = make(map[string]*regexp.Regexp)
+	regexCacheMutex = &sync.RWMutex{}
+)
+
+// compileRegex returns the compiled regex of a pattern, compiling the
+// patterns of the regex function once instead of on each evaluation.
+func compileRegex(pattern string) (*regexp.Regexp, error) {
+	regexCacheMutex.RLock()
+	compiled, ok := regexCache[pattern]
+	regexCacheMutex.RUnlock()
+	if ok {
+		return compiled, nil
 	}
-	functions["sha256"] = func(args ...interface{}) (interface{}, error) {
-		h := sha256.New()
-		h.Write([]byte(args[0].(string)))
-		return hex.EncodeToString(h.Sum(nil)), nil
+
+	compiled, err := regexp.Compile(pattern)
+	if err != nil {
+		return nil, err
 	}
-	functions["sha1"] = func(args ...interface{}) (interface{}, error) {
-		h := sha1.New()
-		h.Write([]byte(args[0].(string)))
-		return hex.EncodeToString(h.Sum(nil)), nil
+	regexCacheMutex.Lock()
+	if len(regexCache) < maxCachedRegexes {
+		regexCache[pattern] = compiled
 	}
-	// search
-	functions["contains"] = func(args ...interface{}) (interface{}, error) {
-		return strings.Contains(args[0].(string), args[1].(string)), nil
+	regexCacheMutex.Unlock()
+	return compiled, nil
+}
+
+// toString returns the textual form of a dsl value
+func toString(value interface{}) string {
+	switch v := value.(type) {
+	case string:
+		return v
+	case float64:
+		return strconv.FormatFloat(v, 'f', -1, 64)
+	case []byte:
+		return string(v)
 	}
-	functions["regex"] = func(args ...interface{}) (interface{}, error) {
-		compiled, err := regexp.Compile(args[0].(string))
+	return fmt.Sprint(value)
+}
+
+// toCount returns a dsl value as a non negative integer
+func toCount(name string, value interface{}) (int, error) {
+	var number float64
+	switch v := value.(type) {
+	case float64:
+		number = v
+	case string:
+		parsed, err := strconv.ParseFloat(v, 64)
 		if err != nil {
-			return nil, err
+			return 0, fmt.Errorf("%s expects a number, got %q", name, v)
 		}
-		return compiled.MatchString(args[1].(string)), nil
+		number = parsed
+	default:
+		return 0, fmt.Errorf("%s expects a number, got %v", name, value)
+	}
+	if number < 0 || number != math.Trunc(number) {
+		return 0, fmt.Errorf("%s expects a non negative integer, got %v", name, number)
 	}
+	return int(number), nil
+}
 
-	return
+// lineCount returns the number of lines of a string, ignoring a trailing newline
+func lineCount(value string) int {
+	if value == "" {
+		return 0
+	}
+	count := strings.Count(value, "\n")
+	if !strings.HasSuffix(value, "\n") {
+		count++
+	}
+	return count
+}
+
+// strftimeLayouts converts the strftime directives to go time layouts
+var strftimeLayouts = strings.NewReplacer(
+	"%Y", "2006",
+	"%y", "06",
+	"%m", "01",
+	"%d", "02",
+	"%H", "15",
+	"%M", "04",
+	"%S", "05",
+	"%b", "Jan",
+	"%a", "Mon",
+	"%Z", "MST",
+	"%z", "-0700",
+	"%%", "%",
+)
+
+// timeLayout returns the go time layout of a format, which can either be a
+// go layout or use strftime directives, i.e %Y-%m-%d. strftime directives are
+// preferred as the expressions turn go layouts looking like dates into numbers.
+func timeLayout(format string) string {
+	if !strings.Contains(format, "%") {
+		return format
+	}
+	return strftimeLayouts.Replace(format)
 }
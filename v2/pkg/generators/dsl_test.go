@@ -0,0 +1,154 @@
+package generators
+
+import (
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/Knetic/govaluate"
+	"github.com/stretchr/testify/require"
+)
+
+func evaluate(t *testing.T, expression string) (interface{}, error) {
+	compiled, err := govaluate.NewEvaluableExpressionWithFunctions(expression, HelperFunctions())
+	require.Nil(t, err, "Could not compile expression %s", expression)
+	return compiled.Evaluate(nil)
+}
+
+func requireResult(t *testing.T, expression string, expected interface{}) {
+	result, err := evaluate(t, expression)
+	require.Nil(t, err, "Could not evaluate expression %s", expression)
+	require.Equal(t, expected, result, "Could not get correct result for %s", expression)
+}
+
+func TestContainsAny(t *testing.T) {
+	requireResult(t, `contains_any("nginx/1.18", "apache", "nginx")`, true)
+	requireResult(t, `contains_any("nginx/1.18", "apache", "iis")`, false)
+}
+
+func TestContainsAll(t *testing.T) {
+	requireResult(t, `contains_all("admin panel login", "admin", "login")`, true)
+	requireResult(t, `contains_all("admin panel login", "admin", "logout")`, false)
+}
+
+func TestStartsEndsWith(t *testing.T) {
+	requireResult(t, `starts_with("<?xml version", "<?xml")`, true)
+	requireResult(t, `starts_with("version", "<?xml")`, false)
+	requireResult(t, `ends_with("config.php", ".php")`, true)
+	requireResult(t, `ends_with("config.php", ".asp")`, false)
+}
+
+func TestLineCount(t *testing.T) {
+	requireResult(t, `line_count("")`, float64(0))
+	requireResult(t, "line_count(\"a\nb\nc\")", float64(3))
+	requireResult(t, "line_count(\"a\nb\n\")", float64(2))
+}
+
+func TestWordCount(t *testing.T) {
+	requireResult(t, `word_count("  index of /  ")`, float64(3))
+	requireResult(t, `word_count("")`, float64(0))
+}
+
+func TestHexEncoding(t *testing.T) {
+	requireResult(t, `hex_encode("nuclei")`, "6e75636c6569")
+	requireResult(t, `hex_decode("6e75636c6569")`, "nuclei")
+
+	_, err := evaluate(t, `hex_decode("xyz")`)
+	require.NotNil(t, err, "Could not get error for invalid hex")
+}
+
+func TestURLEncoding(t *testing.T) {
+	requireResult(t, `url_encode("a b/c")`, "a%20b%2Fc")
+	requireResult(t, `url_decode("a%20b%2Fc")`, "a b/c")
+
+	_, err := evaluate(t, `url_decode("%zz")`)
+	require.NotNil(t, err, "Could not get error for invalid url encoding")
+}
+
+func TestTrim(t *testing.T) {
+	requireResult(t, "trim(\"  value \n\")", "value")
+	requireResult(t, `trim("--value--", "-")`, "value")
+	requireResult(t, "trim_space(\" \tvalue \")", "value")
+}
+
+func TestRepeat(t *testing.T) {
+	requireResult(t, `repeat("ab", 3)`, "ababab")
+
+	_, err := evaluate(t, `repeat("ab", -1)`)
+	require.NotNil(t, err, "Could not get error for negative count")
+	_, err = evaluate(t, `repeat("ab", "x")`)
+	require.NotNil(t, err, "Could not get error for invalid count")
+}
+
+func TestCompareVersions(t *testing.T) {
+	requireResult(t, `compare_versions("1.5.2", ">=1.2,<2.0")`, true)
+	requireResult(t, `compare_versions("v2.0.0", ">=1.2,<2.0")`, false)
+	requireResult(t, `compare_versions("1.2", ">=1.2.0")`, true)
+	requireResult(t, `compare_versions("1.2.3-rc1", "<1.2.3")`, true)
+	requireResult(t, `compare_versions("1.2.3-rc2", "<1.2.3-rc10")`, true)
+	requireResult(t, `compare_versions("1.2.3rc1", ">1.2.2", "<1.2.3")`, true)
+	requireResult(t, `compare_versions("1.2.3", "1.2.3")`, true)
+
+	_, err := evaluate(t, `compare_versions("latest", ">=1.0")`)
+	require.NotNil(t, err, "Could not get error for invalid version")
+	_, err = evaluate(t, `compare_versions("1.0", ">=x")`)
+	require.NotNil(t, err, "Could not get error for invalid constraint")
+}
+
+func TestDateTime(t *testing.T) {
+	requireResult(t, `date_time("%Y-%m-%d", 0)`, "1970-01-01")
+	requireResult(t, `date_time("%Y-%m-%d %H:%M", 86400)`, "1970-01-02 00:00")
+	requireResult(t, `date_time("%Y")`, time.Now().UTC().Format("2006"))
+}
+
+func TestRandom(t *testing.T) {
+	result, err := evaluate(t, `rand_base(8)`)
+	require.Nil(t, err, "Could not evaluate rand_base")
+	require.Regexp(t, "^[a-z0-9]{8}$", result, "Could not get random alphanumeric value")
+
+	requireResult(t, `rand_base(4, "a")`, "aaaa")
+	result, err = evaluate(t, `rand_text_alpha(6) + rand_text_numeric(3)`)
+	require.Nil(t, err, "Could not evaluate random text")
+	require.Regexp(t, "^[a-zA-Z]{6}[0-9]{3}$", result, "Could not get random text")
+
+	result, err = evaluate(t, `rand_int(10, 12)`)
+	require.Nil(t, err, "Could not evaluate rand_int")
+	require.Contains(t, []interface{}{float64(10), float64(11), float64(12)}, result, "Could not get random integer in range")
+
+	_, err = evaluate(t, `rand_int(5, 1)`)
+	require.NotNil(t, err, "Could not get error for invalid range")
+}
+
+func TestHasRandomValues(t *testing.T) {
+	require.True(t, HasRandomValues("/?id={{rand_base(8)}}"), "Could not detect random value")
+	require.True(t, HasRandomValues(`{{md5(unix_time())}}`), "Could not detect nested time value")
+	require.False(t, HasRandomValues("/{{md5(Hostname)}}"), "Could not ignore deterministic value")
+	require.False(t, HasRandomValues("rand_base(8)"), "Could not ignore text outside of expressions")
+}
+
+func TestArgumentsValidation(t *testing.T) {
+	_, err := evaluate(t, `starts_with("value")`)
+	require.EqualError(t, err, "starts_with expects 2 arguments, got 1", "Could not get arguments count error")
+
+	_, err = evaluate(t, `contains_any("value")`)
+	require.EqualError(t, err, "contains_any expects at least 2 arguments, got 1", "Could not get arguments count error")
+
+	require.Nil(t, ValidateExpression(`contains_any(body, "a", "b") && len(replace(body, ",", "")) > 2`), "Could not validate correct expression")
+	require.Nil(t, ValidateExpression(`unix_time() - 10 > 0 && (status_code == 200)`), "Could not validate correct expression")
+	require.EqualError(t, ValidateExpression(`status_code == 200 && starts_with(body)`), "starts_with expects 2 arguments, got 1", "Could not validate incorrect expression")
+	require.EqualError(t, ValidateExpression(`trim(body, "(", ")")`), "trim expects at most 2 arguments, got 3", "Could not validate incorrect expression")
+	require.EqualError(t, ValidateExpression(`now(1)`), "now expects 0 arguments, got 1", "Could not validate incorrect expression")
+}
+
+func BenchmarkRegexFunction(b *testing.B) {
+	compiled, err := govaluate.NewEvaluableExpressionWithFunctions(`regex("(?i)<title>[^<]*admin[^<]*</title>", body)`, HelperFunctions())
+	require.Nil(b, err, "Could not compile expression")
+	values := map[string]interface{}{"body": strings.Repeat("<p>content</p>", 1000) + "<title>Admin panel</title>"}
+
+	b.ReportAllocs()
+	for i := 0; i < b.N; i++ {
+		if _, err := compiled.Evaluate(values); err != nil {
+			b.Fatal(err)
+		}
+	}
+}
@@ -0,0 +1,81 @@
+package generators
+
+import (
+	"encoding/base64"
+	"math/bits"
+	"strings"
+)
+
+// MMH3 returns the signed 32 bits murmur3 hash of a value with a 0 seed,
+// the value returned by the python mmh3.hash function.
+func MMH3(data []byte) int32 {
+	const (
+		c1 = 0xcc9e2d51
+		c2 = 0x1b873593
+	)
+
+	var hash uint32
+	blocks := len(data) / 4
+	for i := 0; i < blocks; i++ {
+		k := uint32(data[i*4]) | uint32(data[i*4+1])<<8 | uint32(data[i*4+2])<<16 | uint32(data[i*4+3])<<24
+		k *= c1
+		k = bits.RotateLeft32(k, 15)
+		k *= c2
+
+		hash ^= k
+		hash = bits.RotateLeft32(hash, 13)
+		hash = hash*5 + 0xe6546b64
+	}
+
+	// the remaining bytes are mixed in little endian order
+	tail := data[blocks*4:]
+	var k uint32
+	switch len(tail) {
+	case 3:
+		k ^= uint32(tail[2]) << 16
+		fallthrough
+	case 2:
+		k ^= uint32(tail[1]) << 8
+		fallthrough
+	case 1:
+		k ^= uint32(tail[0])
+		k *= c1
+		k = bits.RotateLeft32(k, 15)
+		k *= c2
+		hash ^= k
+	}
+
+	hash ^= uint32(len(data))
+	hash ^= hash >> 16
+	hash *= 0x85ebca6b
+	hash ^= hash >> 13
+	hash *= 0xc2b2ae35
+	hash ^= hash >> 16
+	return int32(hash)
+}
+
+// FaviconHash returns the hash of a favicon following the shodan
+// http.favicon.hash convention, the mmh3 of the base64 of the favicon
+// with a newline every 76 characters and a final one.
+func FaviconHash(data []byte) int32 {
+	return MMH3([]byte(encodeBase64Lines(data)))
+}
+
+// encodeBase64Lines encodes data to base64 like python base64.encodebytes
+func encodeBase64Lines(data []byte) string {
+	const lineLength = 76
+
+	encoded := base64.StdEncoding.EncodeToString(data)
+	builder := &strings.Builder{}
+	builder.Grow(len(encoded) + len(encoded)/lineLength + 1)
+	for len(encoded) > lineLength {
+		builder.WriteString(encoded[:lineLength])
+		builder.WriteByte('\n')
+		encoded = encoded[lineLength:]
+	}
+	if encoded != "" {
+		builder.WriteString(encoded)
+		builder.WriteByte('\n')
+	}
+	return builder.String()
+}
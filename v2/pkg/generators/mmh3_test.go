@@ -0,0 +1,34 @@
+package generators
+
+import (
+	"encoding/hex"
+	"testing"
+
+	"github.com/Knetic/govaluate"
+	"github.com/stretchr/testify/require"
+)
+
+func TestMMH3(t *testing.T) {
+	// published vectors of the python mmh3 and smhasher implementations
+	require.Equal(t, int32(-156908512), MMH3([]byte("foo")), "Could not hash value")
+	require.Equal(t, int32(0), MMH3(nil), "Could not hash empty value")
+	require.Equal(t, int32(0x2e4ff723), MMH3([]byte("The quick brown fox jumps over the lazy dog")), "Could not hash value")
+
+	requireResult(t, `mmh3("foo")`, float64(-156908512))
+}
+
+func TestFaviconHash(t *testing.T) {
+	// an icon header followed by bytes which aren't valid utf-8
+	favicon, err := hex.DecodeString("0000010001001010000001002000000306090c0f1215181b1e2124272a2d303336393c3f4245484b4e5154575a5d606366696c6f7275787b7e8184878a8d909396999c9fa2a5a8abaeb1b4b7babdc0c3c6c9cccfd2d5d8dbdee1e4e7eaedf0f3f6f9fcfffffe80")
+	require.Nil(t, err, "Could not decode favicon")
+
+	encoded := "AAABAAEAEBAAAAEAIAAAAwYJDA8SFRgbHiEkJyotMDM2OTw/QkVIS05RVFdaXWBjZmlsb3J1eHt+\ngYSHio2Qk5aZnJ+ipairrrG0t7q9wMPGyczP0tXY297h5Ofq7fDz9vn8///+gA==\n"
+	require.Equal(t, encoded, encodeBase64Lines(favicon), "Could not encode favicon like python")
+	require.Equal(t, int32(1231271616), FaviconHash(favicon), "Could not hash favicon")
+
+	compiled, err := govaluate.NewEvaluableExpressionWithFunctions(`favicon_hash(body) == 1231271616`, HelperFunctions())
+	require.Nil(t, err, "Could not compile expression")
+	result, err := compiled.Evaluate(map[string]interface{}{"body": string(favicon)})
+	require.Nil(t, err, "Could not evaluate expression")
+	require.Equal(t, true, result, "Could not hash binary body")
+}
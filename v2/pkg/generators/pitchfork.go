@@ -1,8 +1,8 @@
 package generators
 
 // PitchforkGenerator Attack - Generate positional combinations from an input map with all values listed
-// as slices of the same size
-func PitchforkGenerator(payloads map[string][]string) (out chan map[string]interface{}) {
+// as slices of the same size, until done is closed if the values are not all read
+func PitchforkGenerator(payloads map[string][]string, done <-chan struct{}) (out chan map[string]interface{}) {
 	out = make(chan map[string]interface{})
 
 	size := 0
@@ -30,7 +30,11 @@ func PitchforkGenerator(payloads map[string][]string) (out chan map[string]inter
 				element[name] = wordlist[i]
 			}
 
-			out <- element
+			select {
+			case out <- element:
+			case <-done:
+				return
+			}
 		}
 	}()
 
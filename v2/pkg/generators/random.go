@@ -0,0 +1,51 @@
+package generators
+
+import (
+	"crypto/rand"
+	"fmt"
+	"math/big"
+	"regexp"
+)
+
+// charsets of the random dsl functions
+const (
+	lettersCharset      = "abcdefghijklmnopqrstuvwxyzABCDEFGHIJKLMNOPQRSTUVWXYZ"
+	numbersCharset      = "0123456789"
+	alphanumericCharset = "abcdefghijklmnopqrstuvwxyz0123456789"
+)
+
+// randomRegex matches the calls to the dsl functions returning a different
+// value on each evaluation between {{}}, the random and the time ones.
+var randomRegex = regexp.MustCompile(`\{\{[^}]*\b(rand_base|rand_text_alpha|rand_text_numeric|rand_int|unix_time|now|date_time)\s*\(`)
+
+// HasRandomValues returns true if a value of a template calls the dsl
+// functions returning a different value on each evaluation, such as
+// rand_base or unix_time.
+func HasRandomValues(value string) bool {
+	return randomRegex.MatchString(value)
+}
+
+// randomString returns a random string of length characters of a charset
+func randomString(charset string, length int) (string, error) {
+	if charset == "" {
+		return "", fmt.Errorf("empty charset")
+	}
+	value := make([]byte, length)
+	for i := range value {
+		index, err := randomInt(len(charset))
+		if err != nil {
+			return "", err
+		}
+		value[i] = charset[index]
+	}
+	return string(value), nil
+}
+
+// randomInt returns a random integer in [0, n)
+func randomInt(n int) (int, error) {
+	value, err := rand.Int(rand.Reader, big.NewInt(int64(n)))
+	if err != nil {
+		return 0, err
+	}
+	return int(value.Int64()), nil
+}
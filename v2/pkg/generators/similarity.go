@@ -0,0 +1,102 @@
+package generators
+
+import (
+	"hash/fnv"
+	"math/bits"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+)
+
+// maxLevenshteinLength is the maximum number of characters of the inputs
+// compared by levenshtein, the distance taking quadratic time.
+const maxLevenshteinLength = 4096
+
+// Levenshtein returns the number of single character edits between two strings.
+//
+// The inputs are truncated to their first 4096 characters.
+func Levenshtein(a, b string) int {
+	first, second := truncateRunes(a), truncateRunes(b)
+	if len(first) < len(second) {
+		first, second = second, first
+	}
+
+	// a single row of the distances matrix is kept
+	row := make([]int, len(second)+1)
+	for i := range row {
+		row[i] = i
+	}
+	for i := 1; i <= len(first); i++ {
+		previous := row[0]
+		row[0] = i
+		for j := 1; j <= len(second); j++ {
+			current := row[j]
+			cost := 1
+			if first[i-1] == second[j-1] {
+				cost = 0
+			}
+			row[j] = min(row[j]+1, min(row[j-1]+1, previous+cost))
+			previous = current
+		}
+	}
+	return row[len(second)]
+}
+
+func truncateRunes(value string) []rune {
+	if utf8.RuneCountInString(value) <= maxLevenshteinLength {
+		return []rune(value)
+	}
+	return []rune(value)[:maxLevenshteinLength]
+}
+
+func min(a, b int) int {
+	if a < b {
+		return a
+	}
+	return b
+}
+
+// Simhash returns the 64 bits simhash of the words of a string, similar
+// strings having fingerprints differing by a few bits.
+func Simhash(value string) uint64 {
+	words := strings.FieldsFunc(value, func(r rune) bool {
+		return !unicode.IsLetter(r) && !unicode.IsNumber(r)
+	})
+	if len(words) == 0 {
+		return 0
+	}
+
+	var weights [64]int
+	for _, word := range words {
+		hasher := fnv.New64a()
+		hasher.Write([]byte(word))
+		hash := hasher.Sum64()
+		for i := 0; i < 64; i++ {
+			if hash&(1<<uint(i)) != 0 {
+				weights[i]++
+			} else {
+				weights[i]--
+			}
+		}
+	}
+
+	var fingerprint uint64
+	for i, weight := range weights {
+		if weight > 0 {
+			fingerprint |= 1 << uint(i)
+		}
+	}
+	return fingerprint
+}
+
+// SimhashDiff returns the number of bits, from 0 to 64, differing
+// between the simhashes of two strings.
+func SimhashDiff(a, b string) int {
+	return bits.OnesCount64(Simhash(a) ^ Simhash(b))
+}
+
+// Similarity returns the similarity ratio of two strings based on their
+// simhashes, 1 for the same words and around 0.5 for unrelated strings.
+func Similarity(a, b string) float64 {
+	return 1 - float64(SimhashDiff(a, b))/64
+}
@@ -0,0 +1,29 @@
+package generators
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestLevenshtein(t *testing.T) {
+	require.Equal(t, 3, Levenshtein("kitten", "sitting"), "Could not get distance")
+	require.Equal(t, 0, Levenshtein("same", "same"), "Could not get distance of equal strings")
+	require.Equal(t, 4, Levenshtein("", "abcd"), "Could not get distance to empty string")
+	require.Equal(t, 1, Levenshtein("héllo", "hello"), "Could not get distance of unicode strings")
+
+	long := strings.Repeat("a", maxLevenshteinLength+100)
+	require.Equal(t, 0, Levenshtein(long, long+"b"), "Could not truncate long inputs")
+}
+
+func TestSimilarity(t *testing.T) {
+	notFound := "<h1>Not Found</h1><p>The requested page /%s could not be found on this server</p>"
+	first, second := strings.Replace(notFound, "%s", "a1b2c3", 1), strings.Replace(notFound, "%s", "admin", 1)
+	require.Greater(t, Similarity(first, second), 0.8, "Could not get similarity of reflected paths")
+	require.Equal(t, float64(1), Similarity(first, first), "Could not get similarity of equal strings")
+	require.Less(t, Similarity(first, "<h1>Administration</h1><p>Users settings logs backups and the server configuration</p>"), 0.8, "Could get similarity of different pages")
+
+	requireResult(t, `simhash_diff("index of /", "index of /")`, float64(0))
+	requireResult(t, `levenshtein("kitten", "sitting")`, float64(3))
+}
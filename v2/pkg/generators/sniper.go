@@ -1,18 +1,23 @@
 package generators
 
-// SniperGenerator Attack - Generate sequential combinations
-func SniperGenerator(payloads map[string][]string) (out chan map[string]interface{}) {
+// SniperGenerator Attack - Generate sequential combinations, until done is
+// closed if the values are not all read
+func SniperGenerator(payloads map[string][]string, done <-chan struct{}) (out chan map[string]interface{}) {
 	out = make(chan map[string]interface{})
 
 	// generator
 	go func() {
 		defer close(out)
 
-		for name, wordlist := range payloads {
-			for _, value := range wordlist {
+		for _, name := range payloadNames(payloads) {
+			for _, value := range payloads[name] {
 				element := CopyMapWithDefaultValue(payloads, "")
 				element[name] = value
-				out <- element
+				select {
+				case out <- element:
+				case <-done:
+					return
+				}
 			}
 		}
 	}()
@@ -4,9 +4,21 @@ import (
 	"bufio"
 	"fmt"
 	"os"
+	"sort"
 	"strings"
 )
 
+// payloadNames returns the sorted names of the payloads, the combinations
+// being generated in the same order on each run for the resumed scans.
+func payloadNames(payloads map[string][]string) []string {
+	names := make([]string, 0, len(payloads))
+	for name := range payloads {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
 // LoadPayloads creating proper data structure
 func LoadPayloads(payloads map[string]interface{}) map[string][]string {
 	loadedPayloads := make(map[string][]string)
@@ -0,0 +1,177 @@
+package generators
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// version is a parsed software version
+type version struct {
+	// segments are the numeric segments of the version, i.e 1.2.3
+	segments []int
+	// prerelease is the optional pre-release suffix, i.e rc1 for 1.2.3-rc1
+	prerelease string
+}
+
+// parseVersion parses a version like v1.2.3, 1.2.3-rc1 or 1.2.3rc1.
+// Build metadata after a + is ignored.
+func parseVersion(value string) (*version, error) {
+	value = strings.TrimSpace(value)
+	value = strings.TrimPrefix(strings.TrimPrefix(value, "v"), "V")
+	if index := strings.Index(value, "+"); index != -1 {
+		value = value[:index]
+	}
+
+	v := &version{}
+	segments := strings.Split(value, ".")
+	for i, segment := range segments {
+		digits := 0
+		for digits < len(segment) && segment[digits] >= '0' && segment[digits] <= '9' {
+			digits++
+		}
+		if digits == 0 {
+			return nil, fmt.Errorf("invalid version %q", value)
+		}
+		number, _ := strconv.Atoi(segment[:digits])
+		v.segments = append(v.segments, number)
+
+		// the pre-release starts with the first non numeric character
+		if digits < len(segment) {
+			rest := strings.Join(segments[i+1:], ".")
+			v.prerelease = strings.TrimLeft(segment[digits:], "-_.")
+			if rest != "" {
+				v.prerelease += "." + rest
+			}
+			break
+		}
+	}
+	return v, nil
+}
+
+// compare returns -1, 0 or 1 if the version is lower, equal or greater than other
+func (v *version) compare(other *version) int {
+	for i := 0; i < len(v.segments) || i < len(other.segments); i++ {
+		var a, b int
+		if i < len(v.segments) {
+			a = v.segments[i]
+		}
+		if i < len(other.segments) {
+			b = other.segments[i]
+		}
+		if a != b {
+			return sign(a - b)
+		}
+	}
+
+	// a pre-release is lower than the release, i.e 1.2.3-rc1 < 1.2.3
+	switch {
+	case v.prerelease == other.prerelease:
+		return 0
+	case v.prerelease == "":
+		return 1
+	case other.prerelease == "":
+		return -1
+	}
+	return naturalCompare(v.prerelease, other.prerelease)
+}
+
+// naturalCompare compares two strings comparing the runs of digits by their value,
+// so that rc2 is lower than rc10.
+func naturalCompare(a, b string) int {
+	for a != "" && b != "" {
+		chunkA, restA := nextChunk(a)
+		chunkB, restB := nextChunk(b)
+		numberA, errA := strconv.Atoi(chunkA)
+		numberB, errB := strconv.Atoi(chunkB)
+		switch {
+		case errA == nil && errB == nil:
+			if numberA != numberB {
+				return sign(numberA - numberB)
+			}
+		case chunkA != chunkB:
+			return strings.Compare(chunkA, chunkB)
+		}
+		a, b = restA, restB
+	}
+	return strings.Compare(a, b)
+}
+
+// nextChunk returns the leading run of digits or non digits of a string
+func nextChunk(value string) (string, string) {
+	isDigit := func(c byte) bool { return c >= '0' && c <= '9' }
+	end := 1
+	for end < len(value) && isDigit(value[end]) == isDigit(value[0]) {
+		end++
+	}
+	return value[:end], value[end:]
+}
+
+func sign(value int) int {
+	switch {
+	case value < 0:
+		return -1
+	case value > 0:
+		return 1
+	}
+	return 0
+}
+
+// versionOperators are the operators supported by the version constraints,
+// two characters operators are listed first to be matched before the others.
+var versionOperators = []string{">=", "<=", "==", "!=", ">", "<", "="}
+
+// CompareVersions returns true if the version satisfies all the constraints.
+//
+// Constraints are an operator followed by a version, i.e >=1.2, and can be
+// separated by commas, i.e ">=1.2,<2.0". A version without an operator is
+// compared for equality.
+func CompareVersions(value string, constraints ...string) (bool, error) {
+	current, err := parseVersion(value)
+	if err != nil {
+		return false, fmt.Errorf("compare_versions: %s", err)
+	}
+
+	for _, constraint := range constraints {
+		for _, part := range strings.Split(constraint, ",") {
+			part = strings.TrimSpace(part)
+			if part == "" {
+				continue
+			}
+
+			operator := "=="
+			for _, op := range versionOperators {
+				if strings.HasPrefix(part, op) {
+					operator = op
+					part = strings.TrimPrefix(part, op)
+					break
+				}
+			}
+			target, err := parseVersion(part)
+			if err != nil {
+				return false, fmt.Errorf("compare_versions: invalid constraint %q: %s", constraint, err)
+			}
+
+			result := current.compare(target)
+			var ok bool
+			switch operator {
+			case ">=":
+				ok = result >= 0
+			case "<=":
+				ok = result <= 0
+			case ">":
+				ok = result > 0
+			case "<":
+				ok = result < 0
+			case "!=":
+				ok = result != 0
+			default:
+				ok = result == 0
+			}
+			if !ok {
+				return false, nil
+			}
+		}
+	}
+	return true, nil
+}
@@ -0,0 +1,4 @@
+// Package grouping buffers the results shown on screen by host, showing
+// them as a block sorted by severity once all the templates ran on the
+// host, so the findings of a host are read at a glance.
+package grouping
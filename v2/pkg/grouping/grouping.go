@@ -0,0 +1,208 @@
+package grouping
+
+import (
+	"fmt"
+	"net/url"
+	"sort"
+	"strings"
+	"sync"
+
+	"github.com/logrusorgru/aurora"
+	"github.com/projectdiscovery/gologger"
+)
+
+// DefaultMaxSize is the default maximum length of the buffered results in bytes
+const DefaultMaxSize = 10 * 1024 * 1024
+
+// severities are the orders of the results of a block by severity, the
+// unknown severities being sorted after them.
+var severities = map[string]int{"critical": 0, "high": 1, "medium": 2, "low": 3, "info": 4}
+
+// Grouper buffers the results shown on screen by host until all the runs
+// of the templates on the host completed. When the buffered results exceed
+// the maximum size, the largest blocks are shown early. The methods of a
+// nil Grouper do nothing.
+type Grouper struct {
+	mutex     sync.Mutex
+	maxSize   int
+	size      int
+	groups    map[string]*group
+	colorizer aurora.Aurora
+	// print shows a block of results, on screen by default
+	print func(block string)
+}
+
+// group are the buffered results of a host
+type group struct {
+	// pending is the number of runs of the templates on the host left
+	pending int64
+	results []result
+	size    int
+	// count is the number of results of the host, shown or buffered
+	count int
+}
+
+// result is a result line shown on screen
+type result struct {
+	severity int
+	message  string
+}
+
+// New returns a grouper buffering up to a maximum size of results, without
+// limit if 0.
+func New(maxSize int, colorizer aurora.Aurora) *Grouper {
+	return &Grouper{
+		maxSize:   maxSize,
+		groups:    make(map[string]*group),
+		colorizer: colorizer,
+		print:     func(block string) { gologger.Silentf("%s", block) },
+	}
+}
+
+// Key returns the host of a target, i.e the host and port of an url or a
+// domain, the results being grouped by it.
+func Key(target string) string {
+	if strings.Contains(target, "://") {
+		if parsed, err := url.Parse(target); err == nil && parsed.Host != "" {
+			target = parsed.Host
+		}
+	} else if slash := strings.IndexByte(target, '/'); slash != -1 {
+		target = target[:slash]
+	}
+	return strings.TrimSuffix(strings.ToLower(target), ".")
+}
+
+// Expect adds a number of runs of the templates on a target to the runs
+// of its host to complete before showing its results.
+func (g *Grouper) Expect(target string, runs int64) {
+	if g == nil {
+		return
+	}
+	g.mutex.Lock()
+	defer g.mutex.Unlock()
+	g.group(Key(target)).pending += runs
+}
+
+// Add buffers a result line of a template of a severity for a target,
+// showing the largest blocks early if the results exceed the maximum size.
+func (g *Grouper) Add(target, severity, message string) {
+	if g == nil {
+		return
+	}
+	order, ok := severities[strings.ToLower(severity)]
+	if !ok {
+		order = len(severities)
+	}
+
+	g.mutex.Lock()
+	defer g.mutex.Unlock()
+	group := g.group(Key(target))
+	group.results = append(group.results, result{severity: order, message: message})
+	group.size += len(message)
+	group.count++
+	g.size += len(message)
+
+	for g.maxSize > 0 && g.size > g.maxSize {
+		var largest string
+		for host, group := range g.groups {
+			if largest == "" || group.size > g.groups[largest].size || (group.size == g.groups[largest].size && host < largest) {
+				largest = host
+			}
+		}
+		g.show(largest, true)
+	}
+}
+
+// Complete counts a run of the templates on a target as completed, showing
+// the results of its host once all its runs are.
+func (g *Grouper) Complete(target string) {
+	if g == nil {
+		return
+	}
+	g.mutex.Lock()
+	defer g.mutex.Unlock()
+	key := Key(target)
+	group := g.group(key)
+	if group.pending--; group.pending <= 0 {
+		g.show(key, false)
+		delete(g.groups, key)
+	}
+}
+
+// Buffered returns the size of the results buffered in bytes
+func (g *Grouper) Buffered() int {
+	if g == nil {
+		return 0
+	}
+	g.mutex.Lock()
+	defer g.mutex.Unlock()
+	return g.size
+}
+
+// Flush shows the buffered results of all the hosts, by host name, at the
+// end of the scan or when it is interrupted.
+func (g *Grouper) Flush() {
+	if g == nil {
+		return
+	}
+	g.mutex.Lock()
+	defer g.mutex.Unlock()
+	hosts := make([]string, 0, len(g.groups))
+	for host := range g.groups {
+		hosts = append(hosts, host)
+	}
+	sort.Strings(hosts)
+	for _, host := range hosts {
+		g.show(host, false)
+		delete(g.groups, host)
+	}
+}
+
+// group returns the group of a host, creating it if required
+func (g *Grouper) group(key string) *group {
+	existing, ok := g.groups[key]
+	if !ok {
+		existing = &group{}
+		g.groups[key] = existing
+	}
+	return existing
+}
+
+// show shows the buffered results of a host as a block with a banner,
+// sorted by severity, the block being partial if the host has pending runs.
+func (g *Grouper) show(key string, partial bool) {
+	group := g.groups[key]
+	if len(group.results) == 0 {
+		return
+	}
+	sort.SliceStable(group.results, func(i, j int) bool {
+		return group.results[i].severity < group.results[j].severity
+	})
+
+	count := fmt.Sprintf("%d %s", group.count, pluralize(group.count, "finding", "findings"))
+	if partial {
+		count += " so far"
+	}
+	builder := &strings.Builder{}
+	builder.WriteString("[")
+	builder.WriteString(g.colorizer.BrightMagenta("host").String())
+	builder.WriteString("] ")
+	builder.WriteString(g.colorizer.Bold(key).String())
+	builder.WriteString(" (")
+	builder.WriteString(count)
+	builder.WriteString(")\n")
+	for _, result := range group.results {
+		builder.WriteString(result.message)
+	}
+	g.print(builder.String())
+
+	g.size -= group.size
+	group.results, group.size = nil, 0
+}
+
+func pluralize(count int, singular, plural string) string {
+	if count > 1 {
+		return plural
+	}
+	return singular
+}
@@ -0,0 +1,76 @@
+package grouping
+
+import (
+	"testing"
+
+	"github.com/logrusorgru/aurora"
+	"github.com/stretchr/testify/require"
+)
+
+// newTestGrouper returns a grouper without colors recording its blocks
+func newTestGrouper(maxSize int) (*Grouper, *[]string) {
+	var blocks []string
+	grouper := New(maxSize, aurora.NewAurora(false))
+	grouper.print = func(block string) { blocks = append(blocks, block) }
+	return grouper, &blocks
+}
+
+func TestGrouper(t *testing.T) {
+	grouper, blocks := newTestGrouper(0)
+	grouper.Expect("example.com", 2)
+	grouper.Expect("https://other.example.com", 2)
+
+	grouper.Add("https://example.com/admin", "low", "[low-template] https://example.com/admin\n")
+	grouper.Add("https://other.example.com", "info", "[info-template] https://other.example.com\n")
+	grouper.Add("https://example.com/", "Critical", "[critical-template] https://example.com/\n")
+	grouper.Add("example.com", "", "[unknown-template] example.com\n")
+	grouper.Add("example.com", "low", "[dns-template] example.com\n")
+	grouper.Complete("example.com")
+	require.Empty(t, *blocks, "Could not buffer the results of a host with pending runs")
+	require.Equal(t, 182, grouper.Buffered(), "Could not count the size of the buffered results")
+
+	grouper.Complete("example.com")
+	require.Equal(t, []string{"[host] example.com (4 findings)\n[critical-template] https://example.com/\n[low-template] https://example.com/admin\n[dns-template] example.com\n[unknown-template] example.com\n"}, *blocks, "Could not show the results of the host sorted by severity")
+
+	grouper.Add("https://late.example.com", "high", "[late-template] https://late.example.com\n")
+	grouper.Flush()
+	require.Zero(t, grouper.Buffered(), "Could not forget the shown results")
+	require.Equal(t, []string{"[host] late.example.com (1 finding)\n[late-template] https://late.example.com\n", "[host] other.example.com (1 finding)\n[info-template] https://other.example.com\n"}, (*blocks)[1:], "Could not flush the remaining hosts by name")
+
+	var nilGrouper *Grouper
+	nilGrouper.Expect("example.com", 1)
+	nilGrouper.Add("example.com", "low", "result\n")
+	nilGrouper.Complete("example.com")
+	nilGrouper.Flush()
+	require.Zero(t, nilGrouper.Buffered(), "Could not ignore nil grouper")
+}
+
+func TestGrouperMaxSize(t *testing.T) {
+	grouper, blocks := newTestGrouper(20)
+	grouper.Expect("a.example.com", 1)
+	grouper.Expect("b.example.com", 1)
+
+	grouper.Add("a.example.com", "low", "a1 low result\n")
+	grouper.Add("b.example.com", "low", "b1\n")
+	require.Empty(t, *blocks, "Could not buffer the results under the maximum size")
+	grouper.Add("a.example.com", "high", "a2 high\n")
+	require.Equal(t, []string{"[host] a.example.com (2 findings so far)\na2 high\na1 low result\n"}, *blocks, "Could not show the largest block early")
+
+	grouper.Add("a.example.com", "medium", "a3\n")
+	grouper.Complete("a.example.com")
+	grouper.Complete("b.example.com")
+	require.Equal(t, []string{"[host] a.example.com (3 findings)\na3\n", "[host] b.example.com (1 finding)\nb1\n"}, (*blocks)[1:], "Could not show the rest of the results once completed")
+}
+
+func TestKey(t *testing.T) {
+	tests := map[string]string{
+		"https://Example.com:8443/app?q=1": "example.com:8443",
+		"http://example.com":               "example.com",
+		"example.com/path":                 "example.com",
+		"example.com.":                     "example.com",
+		"192.168.1.1:53":                   "192.168.1.1:53",
+	}
+	for target, key := range tests {
+		require.Equal(t, key, Key(target), "Could not find the host of %s", target)
+	}
+}
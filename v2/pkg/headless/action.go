@@ -0,0 +1,106 @@
+package headless
+
+import (
+	"fmt"
+	"strings"
+	"time"
+)
+
+// The actions of the steps of a headless request
+const (
+	// NavigateAction loads the url of the page, waiting for its load
+	NavigateAction = "navigate"
+	// WaitLoadAction waits for the current document of the page to load,
+	// i.e after a click navigated to another one.
+	WaitLoadAction = "waitload"
+	// ClickAction clicks the first element matching the selector, waiting
+	// for it to exist.
+	ClickAction = "click"
+	// FillAction types the value into the first element matching the
+	// selector, waiting for it to exist.
+	FillAction = "fill"
+	// ScriptAction executes the code in the page, awaiting its promise if
+	// any, its result being stored by the name of the step if any.
+	ScriptAction = "script"
+	// CaptureAction stores the dom of the page at this step by the name of
+	// the step, capture by default.
+	CaptureAction = "capture"
+	// SleepAction waits for the duration, i.e 500ms
+	SleepAction = "sleep"
+)
+
+// actionArgs are the required arguments of each action
+var actionArgs = map[string][]string{
+	NavigateAction: {"url"},
+	WaitLoadAction: nil,
+	ClickAction:    {"selector"},
+	FillAction:     {"selector", "value"},
+	ScriptAction:   {"code"},
+	CaptureAction:  nil,
+	SleepAction:    {"duration"},
+}
+
+// Action is a step of a headless request run in the page
+type Action struct {
+	// Action is the step, navigate, waitload, click, fill, script, capture
+	// or sleep.
+	Action string `yaml:"action"`
+	// Args are the arguments of the step: the url to navigate to, the css
+	// selector of the element to click or fill, the value to fill, the code
+	// to execute or the duration to sleep for.
+	Args map[string]string `yaml:"args,omitempty"`
+	// Name stores the result of a script or a capture as a value of the
+	// matchers and the extractors.
+	Name string `yaml:"name,omitempty"`
+	// Timeout is the seconds the step can take, the timeout of the scan
+	// otherwise.
+	Timeout int `yaml:"timeout,omitempty"`
+}
+
+// Validate returns an error if the action is unknown or misses one of its
+// arguments.
+func (a *Action) Validate() error {
+	required, ok := actionArgs[a.Action]
+	if !ok {
+		return fmt.Errorf("unknown action %s (supported: navigate, waitload, click, fill, script, capture, sleep)", a.Action)
+	}
+	for _, arg := range required {
+		if _, ok := a.Args[arg]; !ok {
+			return fmt.Errorf("%s action without %s arg", a.Action, arg)
+		}
+	}
+	if a.Timeout < 0 {
+		return fmt.Errorf("invalid timeout %d", a.Timeout)
+	}
+	if a.Action == SleepAction && !strings.Contains(a.Args["duration"], "{{") {
+		if _, err := time.ParseDuration(a.Args["duration"]); err != nil {
+			return fmt.Errorf("invalid sleep duration %s", a.Args["duration"])
+		}
+	}
+	return nil
+}
+
+// Arg returns an argument of the action
+func (a *Action) Arg(name string) string {
+	return a.Args[name]
+}
+
+// WithArgs returns a copy of the action with new arguments, i.e the ones
+// whose placeholders were replaced.
+func (a *Action) WithArgs(args map[string]string) *Action {
+	action := *a
+	action.Args = args
+	return &action
+}
+
+// String returns the action along with its arguments
+func (a *Action) String() string {
+	builder := &strings.Builder{}
+	builder.WriteString(a.Action)
+	for _, name := range []string{"url", "selector", "value", "code", "duration"} {
+		if value, ok := a.Args[name]; ok {
+			fmt.Fprintf(builder, " %s=%q", name, value)
+		}
+	}
+	return builder.String()
+}
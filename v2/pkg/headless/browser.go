@@ -0,0 +1,127 @@
+package headless
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"os"
+	"os/exec"
+	"strings"
+	"time"
+)
+
+// launchTimeout is the time a browser can take to start its devtools
+const launchTimeout = 30 * time.Second
+
+// browserNames are the executables of chromium looked up in the path
+var browserNames = []string{"chromium", "chromium-browser", "google-chrome", "google-chrome-stable", "chrome", "headless-shell"}
+
+// FindBrowser returns the path of a chromium executable, looked up in the
+// path and the usual install locations.
+func FindBrowser() (string, error) {
+	for _, name := range browserNames {
+		if path, err := exec.LookPath(name); err == nil {
+			return path, nil
+		}
+	}
+	for _, path := range []string{"/Applications/Google Chrome.app/Contents/MacOS/Google Chrome", "/Applications/Chromium.app/Contents/MacOS/Chromium"} {
+		if _, err := os.Stat(path); err == nil {
+			return path, nil
+		}
+	}
+	return "", errors.New("no chromium browser found, install one or specify its path with -headless-browser")
+}
+
+// browser is a headless chromium process, with its own profile
+type browser struct {
+	cmd     *exec.Cmd
+	dataDir string
+	conn    *conn
+}
+
+// launch starts a headless chromium, its requests going through the proxy
+// if any, and connects to its devtools.
+func launch(path, proxy string) (*browser, error) {
+	dataDir, err := ioutil.TempDir("", "nuclei-headless-")
+	if err != nil {
+		return nil, fmt.Errorf("could not create the browser profile: %s", err)
+	}
+	args := []string{
+		"--headless",
+		"--disable-gpu",
+		"--no-first-run",
+		"--no-default-browser-check",
+		"--disable-extensions",
+		"--mute-audio",
+		"--ignore-certificate-errors",
+		"--remote-debugging-port=0",
+		"--remote-allow-origins=*",
+		"--user-data-dir=" + dataDir,
+	}
+	if os.Geteuid() == 0 {
+		args = append(args, "--no-sandbox")
+	}
+	if proxy != "" {
+		args = append(args, "--proxy-server="+proxy)
+	}
+	args = append(args, "about:blank")
+
+	b := &browser{cmd: exec.Command(path, args...), dataDir: dataDir}
+	stderr, err := b.cmd.StderrPipe()
+	if err != nil {
+		b.close()
+		return nil, err
+	}
+	if err := b.cmd.Start(); err != nil {
+		b.close()
+		return nil, fmt.Errorf("could not start %s: %s", path, err)
+	}
+
+	endpoint := make(chan string, 1)
+	go func() {
+		reader := bufio.NewReader(stderr)
+		for {
+			line, err := reader.ReadString('\n')
+			if index := strings.Index(line, "ws://"); index != -1 && strings.HasPrefix(line, "DevTools listening on") {
+				endpoint <- strings.TrimSpace(line[index:])
+				// the rest of the output is drained so the browser never blocks
+				_, _ = io.Copy(ioutil.Discard, reader)
+				return
+			}
+			if err != nil {
+				close(endpoint)
+				return
+			}
+		}
+	}()
+
+	select {
+	case URL, ok := <-endpoint:
+		if !ok {
+			b.close()
+			return nil, fmt.Errorf("%s exited before starting its devtools", path)
+		}
+		if b.conn, err = dial(URL); err != nil {
+			b.close()
+			return nil, fmt.Errorf("could not connect to the browser: %s", err)
+		}
+		return b, nil
+	case <-time.After(launchTimeout):
+		b.close()
+		return nil, fmt.Errorf("%s did not start its devtools in %s", path, launchTimeout)
+	}
+}
+
+// close stops the browser and removes its profile
+func (b *browser) close() {
+	if b.conn != nil {
+		b.conn.close()
+	}
+	if b.cmd.Process != nil {
+		_ = b.cmd.Process.Kill()
+		_ = b.cmd.Wait()
+	}
+	os.RemoveAll(b.dataDir)
+}
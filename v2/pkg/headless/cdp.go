@@ -0,0 +1,186 @@
+package headless
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"sync"
+
+	"golang.org/x/net/websocket"
+)
+
+// errClosed is returned by the calls of a closed connection
+var errClosed = errors.New("browser connection closed")
+
+// message is a message of the devtools protocol, a call with an id and its
+// result or error, or an event of a session with a method.
+type message struct {
+	ID        int64           `json:"id,omitempty"`
+	SessionID string          `json:"sessionId,omitempty"`
+	Method    string          `json:"method,omitempty"`
+	Params    json.RawMessage `json:"params,omitempty"`
+	Result    json.RawMessage `json:"result,omitempty"`
+	Error     *protocolError  `json:"error,omitempty"`
+}
+
+// protocolError is the error of a call returned by the browser
+type protocolError struct {
+	Code    int    `json:"code"`
+	Message string `json:"message"`
+}
+
+func (e *protocolError) Error() string {
+	return fmt.Sprintf("%s (%d)", e.Message, e.Code)
+}
+
+// conn is a connection to the devtools of a browser, dispatching the
+// results of the calls by id and the events by session. The messages are
+// sent under writeMutex, the reads never waiting for the writes.
+type conn struct {
+	ws         *websocket.Conn
+	writeMutex sync.Mutex
+
+	mutex    sync.Mutex
+	next     int64
+	pending  map[int64]chan *message
+	sessions map[string]func(*message)
+
+	closed chan struct{}
+	once   sync.Once
+}
+
+// dial connects to the devtools websocket of a browser
+func dial(URL string) (*conn, error) {
+	ws, err := websocket.Dial(URL, "", "http://127.0.0.1/")
+	if err != nil {
+		return nil, err
+	}
+	// the final dom of the pages may be large
+	ws.MaxPayloadBytes = 64 << 20
+	c := &conn{
+		ws:       ws,
+		pending:  make(map[int64]chan *message),
+		sessions: make(map[string]func(*message)),
+		closed:   make(chan struct{}),
+	}
+	go c.readLoop()
+	return c, nil
+}
+
+// readLoop dispatches the messages of the browser until the connection is
+// closed. The events are handled in the loop, their handlers never waiting
+// for a call.
+func (c *conn) readLoop() {
+	defer c.close()
+	for {
+		var data []byte
+		if err := websocket.Message.Receive(c.ws, &data); err != nil {
+			return
+		}
+		msg := &message{}
+		if err := json.Unmarshal(data, msg); err != nil {
+			continue
+		}
+		c.mutex.Lock()
+		if msg.ID != 0 {
+			if result, ok := c.pending[msg.ID]; ok {
+				delete(c.pending, msg.ID)
+				result <- msg
+			}
+			c.mutex.Unlock()
+			continue
+		}
+		handler := c.sessions[msg.SessionID]
+		c.mutex.Unlock()
+		if handler != nil && msg.Method != "" {
+			handler(msg)
+		}
+	}
+}
+
+// call calls a method of the browser, or of a page with a session, and
+// decodes its result if not nil, until the context is done.
+func (c *conn) call(ctx context.Context, sessionID, method string, params, result interface{}) error {
+	if params == nil {
+		params = struct{}{}
+	}
+	encoded, err := json.Marshal(params)
+	if err != nil {
+		return err
+	}
+	response := make(chan *message, 1)
+	c.mutex.Lock()
+	select {
+	case <-c.closed:
+		c.mutex.Unlock()
+		return errClosed
+	default:
+	}
+	c.next++
+	id := c.next
+	c.pending[id] = response
+	c.mutex.Unlock()
+
+	data, err := json.Marshal(&message{ID: id, SessionID: sessionID, Method: method, Params: encoded})
+	if err == nil {
+		c.writeMutex.Lock()
+		err = websocket.Message.Send(c.ws, string(data))
+		c.writeMutex.Unlock()
+	}
+	if err != nil {
+		c.mutex.Lock()
+		delete(c.pending, id)
+		c.mutex.Unlock()
+		return err
+	}
+
+	select {
+	case msg := <-response:
+		if msg.Error != nil {
+			return fmt.Errorf("%s: %s", method, msg.Error)
+		}
+		if result == nil || len(msg.Result) == 0 {
+			return nil
+		}
+		return json.Unmarshal(msg.Result, result)
+	case <-ctx.Done():
+		c.mutex.Lock()
+		delete(c.pending, id)
+		c.mutex.Unlock()
+		return ctx.Err()
+	case <-c.closed:
+		return errClosed
+	}
+}
+
+// subscribe sets the handler of the events of a session, nil removing it
+func (c *conn) subscribe(sessionID string, handler func(*message)) {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+	if handler == nil {
+		delete(c.sessions, sessionID)
+		return
+	}
+	c.sessions[sessionID] = handler
+}
+
+// isClosed returns true once the connection is closed
+func (c *conn) isClosed() bool {
+	select {
+	case <-c.closed:
+		return true
+	default:
+		return false
+	}
+}
+
+// close closes the connection, failing the calls waiting for a result
+func (c *conn) close() {
+	c.once.Do(func() {
+		c.mutex.Lock()
+		close(c.closed)
+		c.mutex.Unlock()
+		c.ws.Close()
+	})
+}
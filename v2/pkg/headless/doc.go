@@ -0,0 +1,5 @@
+// Package headless runs the steps of the headless requests of the templates
+// in the pages of a chromium browser driven over the devtools protocol,
+// recording the final dom, the console messages, the dialogs and the
+// network requests of each page for the matchers and the extractors.
+package headless
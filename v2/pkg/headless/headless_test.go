@@ -0,0 +1,228 @@
+package headless
+
+import (
+	"context"
+	"encoding/json"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+	"golang.org/x/net/websocket"
+)
+
+// fakeBrowser is a devtools server whose pages log, alert and request the
+// urls they navigate to, the elements of their selectors existing unless
+// missing.
+type fakeBrowser struct {
+	*httptest.Server
+
+	mutex    sync.Mutex
+	headers  map[string]string
+	filled   []string
+	accepted int
+	contexts int
+	disposed int
+	open     int
+	maxOpen  int
+}
+
+func newFakeBrowser() *fakeBrowser {
+	b := &fakeBrowser{}
+	b.Server = httptest.NewServer(websocket.Handler(b.serve))
+	return b
+}
+
+func (b *fakeBrowser) endpoint() string {
+	return "ws" + strings.TrimPrefix(b.URL, "http")
+}
+
+func (b *fakeBrowser) serve(ws *websocket.Conn) {
+	var writeMutex sync.Mutex
+	send := func(msg interface{}) {
+		data, _ := json.Marshal(msg)
+		writeMutex.Lock()
+		websocket.Message.Send(ws, string(data))
+		writeMutex.Unlock()
+	}
+	event := func(sessionID, method string, params interface{}) {
+		send(map[string]interface{}{"sessionId": sessionID, "method": method, "params": params})
+	}
+	value := func(v interface{}) map[string]interface{} {
+		return map[string]interface{}{"result": map[string]interface{}{"type": "string", "value": v}}
+	}
+
+	location := "about:blank"
+	for {
+		var data []byte
+		if err := websocket.Message.Receive(ws, &data); err != nil {
+			return
+		}
+		var msg struct {
+			ID        int64                  `json:"id"`
+			SessionID string                 `json:"sessionId"`
+			Method    string                 `json:"method"`
+			Params    map[string]interface{} `json:"params"`
+		}
+		json.Unmarshal(data, &msg)
+
+		var result interface{} = map[string]interface{}{}
+		b.mutex.Lock()
+		switch msg.Method {
+		case "Target.createBrowserContext":
+			b.contexts++
+			b.open++
+			if b.open > b.maxOpen {
+				b.maxOpen = b.open
+			}
+			result = map[string]interface{}{"browserContextId": "context"}
+		case "Target.disposeBrowserContext":
+			b.disposed++
+			b.open--
+		case "Target.createTarget":
+			result = map[string]interface{}{"targetId": "target"}
+		case "Target.attachToTarget":
+			result = map[string]interface{}{"sessionId": "session"}
+		case "Network.setExtraHTTPHeaders":
+			b.headers = make(map[string]string)
+			for k, v := range msg.Params["headers"].(map[string]interface{}) {
+				b.headers[k] = v.(string)
+			}
+		case "Page.handleJavaScriptDialog":
+			b.accepted++
+		case "Page.navigate":
+			location = msg.Params["url"].(string)
+			if strings.Contains(location, "unreachable") {
+				result = map[string]interface{}{"errorText": "net::ERR_NAME_NOT_RESOLVED"}
+				break
+			}
+			event(msg.SessionID, "Network.requestWillBeSent", map[string]interface{}{"request": map[string]interface{}{"method": "GET", "url": location}})
+			event(msg.SessionID, "Runtime.consoleAPICalled", map[string]interface{}{"type": "log", "args": []interface{}{map[string]interface{}{"type": "string", "value": "loaded"}, map[string]interface{}{"type": "number", "value": 1}}})
+			if strings.Contains(location, "alert") {
+				event(msg.SessionID, "Page.javascriptDialogOpening", map[string]interface{}{"type": "alert", "message": "xss"})
+			}
+		case "Runtime.evaluate":
+			expression := msg.Params["expression"].(string)
+			switch {
+			case expression == "document.readyState":
+				result = value("complete")
+			case expression == "location.href":
+				result = value(location)
+			case strings.Contains(expression, "outerHTML"):
+				result = value("<html><body>" + location + "</body></html>")
+			case strings.Contains(expression, `querySelector("#missing")`):
+				result = map[string]interface{}{"result": map[string]interface{}{"type": "boolean", "value": false}}
+			case strings.Contains(expression, "querySelector"):
+				if strings.Contains(expression, "element.value") {
+					b.filled = append(b.filled, expression)
+				}
+				result = map[string]interface{}{"result": map[string]interface{}{"type": "boolean", "value": true}}
+			case strings.Contains(expression, "throw"):
+				result = map[string]interface{}{"result": map[string]interface{}{"type": "object"}, "exceptionDetails": map[string]interface{}{"text": "Uncaught", "exception": map[string]interface{}{"description": "Error: failed"}}}
+			default:
+				result = map[string]interface{}{"result": map[string]interface{}{"type": "object", "value": map[string]interface{}{"answer": 42}}}
+			}
+		}
+		b.mutex.Unlock()
+		send(map[string]interface{}{"id": msg.ID, "sessionId": msg.SessionID, "result": result})
+	}
+}
+
+func TestPoolRun(t *testing.T) {
+	browser := newFakeBrowser()
+	defer browser.Close()
+
+	pool := NewPool(&Options{Endpoint: browser.endpoint(), Headers: map[string]string{"X-Test": "nuclei"}, StepTimeout: time.Second})
+	defer pool.Close()
+
+	actions := []*Action{
+		{Action: NavigateAction, Args: map[string]string{"url": "http://example.com/?q=alert"}},
+		{Action: FillAction, Args: map[string]string{"selector": "#q", "value": `"test"`}},
+		{Action: ClickAction, Args: map[string]string{"selector": "#submit"}},
+		{Action: ScriptAction, Args: map[string]string{"code": "({answer: 42})"}, Name: "answer"},
+		{Action: CaptureAction},
+	}
+	for _, action := range actions {
+		require.Nil(t, action.Validate(), "Could not validate the action")
+	}
+	response, err := pool.Run(context.Background(), actions, 0)
+	require.Nil(t, err, "Could not run the steps")
+
+	require.Equal(t, "http://example.com/?q=alert", response.URL, "Could not get the url")
+	require.Equal(t, "<html><body>http://example.com/?q=alert</body></html>", response.DOM, "Could not get the dom")
+	require.Equal(t, []string{"log: loaded 1"}, response.Console, "Could not record the console")
+	require.Equal(t, []string{"alert: xss"}, response.Dialogs, "Could not record the dialogs")
+	require.Equal(t, []string{"GET http://example.com/?q=alert"}, response.Requests, "Could not record the requests")
+	require.Equal(t, `{"answer":42}`, response.Values["answer"], "Could not store the script result")
+	require.Equal(t, response.DOM, response.Values["capture"], "Could not store the capture")
+
+	browser.mutex.Lock()
+	require.Equal(t, map[string]string{"X-Test": "nuclei"}, browser.headers, "Could not send the headers")
+	require.Len(t, browser.filled, 1, "Could not fill the element")
+	require.Contains(t, browser.filled[0], `element.value = "\"test\""`, "Could not encode the value")
+	require.Equal(t, 1, browser.disposed, "Could not dispose the browser context")
+	browser.mutex.Unlock()
+	require.Eventually(t, func() bool {
+		browser.mutex.Lock()
+		defer browser.mutex.Unlock()
+		return browser.accepted == 1
+	}, time.Second, 10*time.Millisecond, "Could not accept the dialog")
+}
+
+func TestPoolRunErrors(t *testing.T) {
+	browser := newFakeBrowser()
+	defer browser.Close()
+
+	pool := NewPool(&Options{Endpoint: browser.endpoint(), StepTimeout: 300 * time.Millisecond})
+	defer pool.Close()
+
+	_, err := pool.Run(context.Background(), []*Action{{Action: NavigateAction, Args: map[string]string{"url": "http://unreachable/"}}}, 0)
+	require.EqualError(t, err, "step 1 (navigate): net::ERR_NAME_NOT_RESOLVED", "Could not fail the navigation")
+
+	_, err = pool.Run(context.Background(), []*Action{{Action: ClickAction, Args: map[string]string{"selector": "#missing"}}}, 0)
+	require.EqualError(t, err, "step 1 (click): no element matching #missing", "Could not time out the step")
+
+	_, err = pool.Run(context.Background(), []*Action{{Action: ScriptAction, Args: map[string]string{"code": "throw new Error('failed')"}}}, 0)
+	require.EqualError(t, err, "step 1 (script): Error: failed", "Could not fail the script")
+
+	start := time.Now()
+	_, err = pool.Run(context.Background(), []*Action{{Action: SleepAction, Args: map[string]string{"duration": "5s"}, Timeout: 10}}, 200*time.Millisecond)
+	require.NotNil(t, err, "Could not time out the request")
+	require.True(t, time.Since(start) < 2*time.Second, "Could not time out the request in time")
+
+	pool.Close()
+	_, err = pool.Run(context.Background(), []*Action{{Action: WaitLoadAction}}, 0)
+	require.Equal(t, errPoolClosed, err, "Could not fail the closed pool")
+}
+
+func TestPoolConcurrency(t *testing.T) {
+	browser := newFakeBrowser()
+	defer browser.Close()
+
+	pool := NewPool(&Options{Endpoint: browser.endpoint(), Concurrency: 2})
+	defer pool.Close()
+
+	var wg sync.WaitGroup
+	for i := 0; i < 6; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			_, err := pool.Run(context.Background(), []*Action{{Action: SleepAction, Args: map[string]string{"duration": "50ms"}}}, 0)
+			require.Nil(t, err, "Could not run the steps")
+		}()
+	}
+	wg.Wait()
+	browser.mutex.Lock()
+	require.Equal(t, 6, browser.contexts, "Could not open a browser context per run")
+	require.Equal(t, 2, browser.maxOpen, "Could not limit the pages opened at once")
+	browser.mutex.Unlock()
+}
+
+func TestActionValidate(t *testing.T) {
+	require.Nil(t, (&Action{Action: SleepAction, Args: map[string]string{"duration": "{{delay}}"}}).Validate(), "Could not validate the placeholder")
+	require.EqualError(t, (&Action{Action: "hover"}).Validate(), "unknown action hover (supported: navigate, waitload, click, fill, script, capture, sleep)", "Could not reject the action")
+	require.EqualError(t, (&Action{Action: FillAction, Args: map[string]string{"selector": "#q"}}).Validate(), "fill action without value arg", "Could not reject the missing arg")
+	require.EqualError(t, (&Action{Action: SleepAction, Args: map[string]string{"duration": "soon"}}).Validate(), "invalid sleep duration soon", "Could not reject the duration")
+}
@@ -0,0 +1,350 @@
+package headless
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strings"
+	"sync"
+	"time"
+)
+
+// pollInterval is the interval the elements and the loads are waited at
+const pollInterval = 100 * time.Millisecond
+
+// Page is a page of a browser opened in its own browser context, so the
+// cookies and the storage of the runs are never shared. The console
+// messages, the dialogs and the network requests of the page are recorded
+// as they are received.
+type Page struct {
+	conn      *conn
+	contextID string
+	targetID  string
+	sessionID string
+
+	mutex    sync.Mutex
+	console  []string
+	dialogs  []string
+	requests []string
+}
+
+// newPage opens a blank page in a new browser context, sending the headers
+// with all its requests.
+func newPage(ctx context.Context, c *conn, headers map[string]string) (*Page, error) {
+	page := &Page{conn: c}
+	var browserContext struct {
+		BrowserContextID string `json:"browserContextId"`
+	}
+	if err := c.call(ctx, "", "Target.createBrowserContext", map[string]interface{}{"disposeOnDetach": true}, &browserContext); err != nil {
+		return nil, err
+	}
+	page.contextID = browserContext.BrowserContextID
+
+	var target struct {
+		TargetID string `json:"targetId"`
+	}
+	if err := c.call(ctx, "", "Target.createTarget", map[string]interface{}{"url": "about:blank", "browserContextId": page.contextID}, &target); err != nil {
+		page.Close()
+		return nil, err
+	}
+	page.targetID = target.TargetID
+
+	var session struct {
+		SessionID string `json:"sessionId"`
+	}
+	if err := c.call(ctx, "", "Target.attachToTarget", map[string]interface{}{"targetId": page.targetID, "flatten": true}, &session); err != nil {
+		page.Close()
+		return nil, err
+	}
+	page.sessionID = session.SessionID
+	c.subscribe(page.sessionID, page.handleEvent)
+
+	for _, method := range []string{"Page.enable", "Runtime.enable", "Network.enable"} {
+		if err := page.call(ctx, method, nil, nil); err != nil {
+			page.Close()
+			return nil, err
+		}
+	}
+	if len(headers) > 0 {
+		if err := page.call(ctx, "Network.setExtraHTTPHeaders", map[string]interface{}{"headers": headers}, nil); err != nil {
+			page.Close()
+			return nil, err
+		}
+	}
+	return page, nil
+}
+
+// call calls a method of the page
+func (p *Page) call(ctx context.Context, method string, params, result interface{}) error {
+	return p.conn.call(ctx, p.sessionID, method, params, result)
+}
+
+// remoteObject is a value returned by the page
+type remoteObject struct {
+	Type        string          `json:"type"`
+	Value       json.RawMessage `json:"value"`
+	Description string          `json:"description"`
+}
+
+// String returns the value as a string, the strings being unquoted
+func (o *remoteObject) String() string {
+	if len(o.Value) == 0 {
+		return o.Description
+	}
+	var value string
+	if err := json.Unmarshal(o.Value, &value); err == nil {
+		return value
+	}
+	return string(o.Value)
+}
+
+// exceptionDetails is an exception thrown by the code of the page
+type exceptionDetails struct {
+	Text      string        `json:"text"`
+	Exception *remoteObject `json:"exception"`
+}
+
+// String returns the description of the exception
+func (d *exceptionDetails) String() string {
+	if d.Exception != nil && d.Exception.Description != "" {
+		return d.Exception.Description
+	}
+	return d.Text
+}
+
+// handleEvent records the console messages, the exceptions, the dialogs
+// and the network requests of the page. The dialogs are accepted so the
+// page keeps running, outside of the read loop.
+func (p *Page) handleEvent(msg *message) {
+	switch msg.Method {
+	case "Runtime.consoleAPICalled":
+		var event struct {
+			Type string          `json:"type"`
+			Args []*remoteObject `json:"args"`
+		}
+		if json.Unmarshal(msg.Params, &event) != nil {
+			return
+		}
+		values := make([]string, 0, len(event.Args))
+		for _, arg := range event.Args {
+			values = append(values, arg.String())
+		}
+		p.record(&p.console, event.Type+": "+strings.Join(values, " "))
+	case "Runtime.exceptionThrown":
+		var event struct {
+			ExceptionDetails *exceptionDetails `json:"exceptionDetails"`
+		}
+		if json.Unmarshal(msg.Params, &event) != nil || event.ExceptionDetails == nil {
+			return
+		}
+		p.record(&p.console, "exception: "+event.ExceptionDetails.String())
+	case "Page.javascriptDialogOpening":
+		var event struct {
+			Type    string `json:"type"`
+			Message string `json:"message"`
+		}
+		if json.Unmarshal(msg.Params, &event) != nil {
+			return
+		}
+		p.record(&p.dialogs, event.Type+": "+event.Message)
+		go func() {
+			ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+			defer cancel()
+			_ = p.call(ctx, "Page.handleJavaScriptDialog", map[string]interface{}{"accept": true}, nil)
+		}()
+	case "Network.requestWillBeSent":
+		var event struct {
+			Request struct {
+				Method string `json:"method"`
+				URL    string `json:"url"`
+			} `json:"request"`
+		}
+		if json.Unmarshal(msg.Params, &event) != nil {
+			return
+		}
+		p.record(&p.requests, event.Request.Method+" "+event.Request.URL)
+	}
+}
+
+// record appends an event to a list of the page
+func (p *Page) record(list *[]string, value string) {
+	p.mutex.Lock()
+	*list = append(*list, value)
+	p.mutex.Unlock()
+}
+
+// Run runs the steps in the page, each within its timeout or the default
+// one, and returns what the page recorded along with its final dom. The
+// error names the step that failed.
+func (p *Page) Run(ctx context.Context, actions []*Action, timeout time.Duration) (*Response, error) {
+	response := &Response{Values: make(map[string]string)}
+	for i, action := range actions {
+		stepTimeout := timeout
+		if action.Timeout > 0 {
+			stepTimeout = time.Duration(action.Timeout) * time.Second
+		}
+		stepCtx, cancel := context.WithTimeout(ctx, stepTimeout)
+		err := p.runAction(stepCtx, action, response)
+		cancel()
+		if err != nil {
+			return nil, fmt.Errorf("step %d (%s): %s", i+1, action.Action, err)
+		}
+	}
+
+	stepCtx, cancel := context.WithTimeout(ctx, timeout)
+	defer cancel()
+	dom, err := p.evaluate(stepCtx, "document.documentElement ? document.documentElement.outerHTML : ''")
+	if err != nil {
+		return nil, fmt.Errorf("could not get the dom: %s", err)
+	}
+	location, err := p.evaluate(stepCtx, "location.href")
+	if err != nil {
+		return nil, fmt.Errorf("could not get the url: %s", err)
+	}
+	response.DOM, response.URL = dom.String(), location.String()
+
+	p.mutex.Lock()
+	response.Console = append([]string(nil), p.console...)
+	response.Dialogs = append([]string(nil), p.dialogs...)
+	response.Requests = append([]string(nil), p.requests...)
+	p.mutex.Unlock()
+	return response, nil
+}
+
+// runAction runs a step in the page
+func (p *Page) runAction(ctx context.Context, action *Action, response *Response) error {
+	switch action.Action {
+	case NavigateAction:
+		var navigation struct {
+			ErrorText string `json:"errorText"`
+		}
+		if err := p.call(ctx, "Page.navigate", map[string]interface{}{"url": action.Arg("url")}, &navigation); err != nil {
+			return err
+		}
+		if navigation.ErrorText != "" {
+			return errors.New(navigation.ErrorText)
+		}
+		return p.waitLoad(ctx)
+	case WaitLoadAction:
+		return p.waitLoad(ctx)
+	case ClickAction:
+		return p.waitElement(ctx, action.Arg("selector"), "element.scrollIntoView(); element.click();")
+	case FillAction:
+		value, _ := json.Marshal(action.Arg("value"))
+		return p.waitElement(ctx, action.Arg("selector"), fmt.Sprintf(`element.focus();
+			element.value = %s;
+			element.dispatchEvent(new Event("input", {bubbles: true}));
+			element.dispatchEvent(new Event("change", {bubbles: true}));`, value))
+	case ScriptAction:
+		result, err := p.evaluate(ctx, action.Arg("code"))
+		if err != nil {
+			return err
+		}
+		if action.Name != "" {
+			response.Values[action.Name] = result.String()
+		}
+	case CaptureAction:
+		dom, err := p.evaluate(ctx, "document.documentElement ? document.documentElement.outerHTML : ''")
+		if err != nil {
+			return err
+		}
+		name := action.Name
+		if name == "" {
+			name = CaptureAction
+		}
+		response.Values[name] = dom.String()
+	case SleepAction:
+		duration, err := time.ParseDuration(action.Arg("duration"))
+		if err != nil {
+			return err
+		}
+		select {
+		case <-time.After(duration):
+		case <-ctx.Done():
+			return ctx.Err()
+		}
+	}
+	return nil
+}
+
+// evaluate evaluates an expression in the page, awaiting its promise if
+// any, and returns its value. The exceptions are returned as errors.
+func (p *Page) evaluate(ctx context.Context, expression string) (*remoteObject, error) {
+	var result struct {
+		Result           *remoteObject     `json:"result"`
+		ExceptionDetails *exceptionDetails `json:"exceptionDetails"`
+	}
+	params := map[string]interface{}{"expression": expression, "returnByValue": true, "awaitPromise": true}
+	if err := p.call(ctx, "Runtime.evaluate", params, &result); err != nil {
+		return nil, err
+	}
+	if result.ExceptionDetails != nil {
+		return nil, errors.New(result.ExceptionDetails.String())
+	}
+	if result.Result == nil {
+		return &remoteObject{}, nil
+	}
+	return result.Result, nil
+}
+
+// waitLoad waits for the current document of the page to load
+func (p *Page) waitLoad(ctx context.Context) error {
+	for {
+		state, err := p.evaluate(ctx, "document.readyState")
+		if err != nil {
+			return err
+		}
+		if state.String() == "complete" {
+			return nil
+		}
+		select {
+		case <-time.After(pollInterval):
+		case <-ctx.Done():
+			return ctx.Err()
+		}
+	}
+}
+
+// waitElement waits for an element matching the selector to exist and
+// runs the code with it as element.
+func (p *Page) waitElement(ctx context.Context, selector, code string) error {
+	encoded, _ := json.Marshal(selector)
+	expression := fmt.Sprintf(`(function() {
+		var element = document.querySelector(%s);
+		if (!element) {
+			return false;
+		}
+		%s
+		return true;
+	})()`, encoded, code)
+	for {
+		found, err := p.evaluate(ctx, expression)
+		if err != nil {
+			return err
+		}
+		if found.String() == "true" {
+			return nil
+		}
+		select {
+		case <-time.After(pollInterval):
+		case <-ctx.Done():
+			return fmt.Errorf("no element matching %s", selector)
+		}
+	}
+}
+
+// Close closes the page along with its browser context
+func (p *Page) Close() {
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	if p.sessionID != "" {
+		p.conn.subscribe(p.sessionID, nil)
+	}
+	if p.targetID != "" {
+		_ = p.conn.call(ctx, "", "Target.closeTarget", map[string]interface{}{"targetId": p.targetID}, nil)
+	}
+	if p.contextID != "" {
+		_ = p.conn.call(ctx, "", "Target.disposeBrowserContext", map[string]interface{}{"browserContextId": p.contextID}, nil)
+	}
+}
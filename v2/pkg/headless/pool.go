@@ -0,0 +1,149 @@
+package headless
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"time"
+)
+
+// DefaultConcurrency is the default number of pages opened at once
+const DefaultConcurrency = 5
+
+// Options are the options of a pool of pages
+type Options struct {
+	// Browser is the path of the chromium executable, looked up otherwise
+	Browser string
+	// Endpoint is the devtools websocket of a running browser, used instead
+	// of launching one.
+	Endpoint string
+	// Concurrency is the number of pages opened at once
+	Concurrency int
+	// Proxy is the proxy the requests of the browser go through
+	Proxy string
+	// Headers are sent with all the requests of the pages
+	Headers map[string]string
+	// StepTimeout is the default time a step can take
+	StepTimeout time.Duration
+}
+
+// Pool runs the steps of the requests in the pages of a single browser,
+// launched on the first run and relaunched if it exits. At most
+// Concurrency pages are opened at once, whatever the concurrency of the
+// templates.
+type Pool struct {
+	options *Options
+	slots   chan struct{}
+
+	mutex   sync.Mutex
+	browser *browser
+	conn    *conn
+	closed  bool
+}
+
+// errPoolClosed is returned by the runs of a closed pool
+var errPoolClosed = errors.New("browser pool closed")
+
+// NewPool returns a pool of pages, the browser staying dormant until the
+// first run.
+func NewPool(options *Options) *Pool {
+	if options.Concurrency <= 0 {
+		options.Concurrency = DefaultConcurrency
+	}
+	if options.StepTimeout <= 0 {
+		options.StepTimeout = 10 * time.Second
+	}
+	return &Pool{options: options, slots: make(chan struct{}, options.Concurrency)}
+}
+
+// Run runs the steps in a new page once one of the slots is free, within
+// the timeout if not zero, and returns what the page recorded.
+func (p *Pool) Run(ctx context.Context, actions []*Action, timeout time.Duration) (*Response, error) {
+	if timeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, timeout)
+		defer cancel()
+	}
+	select {
+	case p.slots <- struct{}{}:
+		defer func() { <-p.slots }()
+	case <-ctx.Done():
+		return nil, ctx.Err()
+	}
+
+	c, err := p.connect()
+	if err != nil {
+		return nil, err
+	}
+	page, err := newPage(ctx, c, p.options.Headers)
+	if err != nil {
+		return nil, err
+	}
+	defer page.Close()
+	return page.Run(ctx, actions, p.options.StepTimeout)
+}
+
+// Start launches the browser if it is not running, the runs launching it
+// otherwise.
+func (p *Pool) Start() error {
+	_, err := p.connect()
+	return err
+}
+
+// connect returns the connection to the browser, launching it if it is not
+// running.
+func (p *Pool) connect() (*conn, error) {
+	p.mutex.Lock()
+	defer p.mutex.Unlock()
+
+	if p.closed {
+		return nil, errPoolClosed
+	}
+	if p.conn != nil && !p.conn.isClosed() {
+		return p.conn, nil
+	}
+	if p.browser != nil {
+		p.browser.close()
+		p.browser = nil
+	}
+	if p.options.Endpoint != "" {
+		c, err := dial(p.options.Endpoint)
+		if err != nil {
+			return nil, err
+		}
+		p.conn = c
+		return c, nil
+	}
+
+	path := p.options.Browser
+	if path == "" {
+		var err error
+		if path, err = FindBrowser(); err != nil {
+			return nil, err
+		}
+	}
+	b, err := launch(path, p.options.Proxy)
+	if err != nil {
+		return nil, err
+	}
+	p.browser, p.conn = b, b.conn
+	return p.conn, nil
+}
+
+// Close stops the browser, failing the runs in progress
+func (p *Pool) Close() {
+	if p == nil {
+		return
+	}
+	p.mutex.Lock()
+	defer p.mutex.Unlock()
+
+	p.closed = true
+	if p.browser != nil {
+		p.browser.close()
+		p.browser = nil
+	} else if p.conn != nil {
+		p.conn.close()
+	}
+	p.conn = nil
+}
@@ -0,0 +1,51 @@
+package headless
+
+import "strings"
+
+// Response is what a page recorded while running the steps of a request
+type Response struct {
+	// URL is the url of the page once the steps ran
+	URL string
+	// DOM is the serialized dom of the page once the steps ran
+	DOM string
+	// Console are the console messages and the uncaught exceptions of the
+	// page, as their type followed by their text, i.e "log: hello".
+	Console []string
+	// Dialogs are the alert, confirm and prompt dialogs opened by the page,
+	// as their type followed by their message, i.e "alert: 1". They are
+	// accepted as they open.
+	Dialogs []string
+	// Requests are the network requests of the page, as their method
+	// followed by their url.
+	Requests []string
+	// Values are the results of the named scripts and captures
+	Values map[string]string
+}
+
+// ConsoleString returns the console messages, one per line
+func (r *Response) ConsoleString() string {
+	return strings.Join(r.Console, "\n")
+}
+
+// DialogsString returns the dialogs, one per line
+func (r *Response) DialogsString() string {
+	return strings.Join(r.Dialogs, "\n")
+}
+
+// RequestsString returns the network requests, one per line
+func (r *Response) RequestsString() string {
+	return strings.Join(r.Requests, "\n")
+}
+
+// String returns all the parts of the response, the dom last
+func (r *Response) String() string {
+	builder := &strings.Builder{}
+	for _, part := range []string{r.ConsoleString(), r.DialogsString(), r.RequestsString()} {
+		if part != "" {
+			builder.WriteString(part)
+			builder.WriteString("\n")
+		}
+	}
+	builder.WriteString(r.DOM)
+	return builder.String()
+}
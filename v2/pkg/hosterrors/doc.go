@@ -0,0 +1,4 @@
+// Package hosterrors tracks the consecutive network errors of the requests
+// to each host, skipping the remaining requests to the hosts which stopped
+// responding instead of waiting out the timeout of each of them.
+package hosterrors
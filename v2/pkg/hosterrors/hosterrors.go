@@ -0,0 +1,150 @@
+package hosterrors
+
+import (
+	"errors"
+	"net"
+	"sort"
+	"sync"
+
+	"github.com/projectdiscovery/nuclei/v2/pkg/grouping"
+)
+
+// DefaultMaxErrors is the number of consecutive network errors of a host
+// after which its remaining requests are skipped by default.
+const DefaultMaxErrors = 30
+
+// ErrSkipped is the error of the requests skipped for a dead host
+var ErrSkipped = errors.New("skipped, the host had too many consecutive network errors")
+
+// host are the consecutive network errors of a host
+type host struct {
+	errors int
+	dead   bool
+}
+
+// Cache counts the consecutive network errors of the hosts, shared by the
+// executers of the scan. The methods of a nil cache do nothing, no host
+// being skipped.
+type Cache struct {
+	max int
+	// dead is called once for each host marked as dead, with the error of
+	// its last request.
+	dead func(host string, err error)
+
+	mutex sync.Mutex
+	hosts map[string]*host
+}
+
+// New returns a cache marking the hosts as dead after a number of
+// consecutive network errors, calling dead for each of them if not nil.
+func New(max int, dead func(host string, err error)) *Cache {
+	return &Cache{max: max, dead: dead, hosts: make(map[string]*host)}
+}
+
+// Failed counts the error of a request to a target if it is a network
+// error, marking its host as dead after the maximum consecutive errors.
+func (c *Cache) Failed(target string, err error) {
+	if c == nil || !IsNetworkError(err) {
+		return
+	}
+	key := grouping.Key(target)
+	c.mutex.Lock()
+	h, ok := c.hosts[key]
+	if !ok {
+		h = &host{}
+		c.hosts[key] = h
+	}
+	h.errors++
+	marked := !h.dead && h.errors >= c.max
+	if marked {
+		h.dead = true
+	}
+	c.mutex.Unlock()
+
+	if marked && c.dead != nil {
+		c.dead(key, err)
+	}
+}
+
+// Succeeded resets the consecutive errors of the host of a target, unless
+// it is already dead.
+func (c *Cache) Succeeded(target string) {
+	if c == nil {
+		return
+	}
+	key := grouping.Key(target)
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+	if h, ok := c.hosts[key]; ok && !h.dead {
+		h.errors = 0
+	}
+}
+
+// Dead returns true if the host of a target is dead
+func (c *Cache) Dead(target string) bool {
+	if c == nil {
+		return false
+	}
+	key := grouping.Key(target)
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+	h, ok := c.hosts[key]
+	return ok && h.dead
+}
+
+// Len returns the number of hosts with network errors in the cache
+func (c *Cache) Len() int {
+	if c == nil {
+		return 0
+	}
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+	return len(c.hosts)
+}
+
+// Host is a host of the cache with its consecutive network errors, skipped
+// once dead.
+type Host struct {
+	Host   string `json:"host"`
+	Errors int    `json:"errors"`
+	Dead   bool   `json:"dead"`
+}
+
+// Hosts returns the hosts of the cache sorted by host, the dead ones first
+func (c *Cache) Hosts() []Host {
+	hosts := []Host{}
+	if c == nil {
+		return hosts
+	}
+	c.mutex.Lock()
+	for key, h := range c.hosts {
+		hosts = append(hosts, Host{Host: key, Errors: h.errors, Dead: h.dead})
+	}
+	c.mutex.Unlock()
+	sort.Slice(hosts, func(i, j int) bool {
+		if hosts[i].Dead != hosts[j].Dead {
+			return hosts[i].Dead
+		}
+		return hosts[i].Host < hosts[j].Host
+	})
+	return hosts
+}
+
+// IsNetworkError returns true if an error is a connection or a dns error or
+// a timeout, the errors of the tls handshakes and the http error statuses
+// being the ones of a responding host.
+func IsNetworkError(err error) bool {
+	if err == nil {
+		return false
+	}
+	var dnsErr *net.DNSError
+	if errors.As(err, &dnsErr) {
+		return true
+	}
+	var opErr *net.OpError
+	if errors.As(err, &opErr) && opErr.Op != "remote error" && opErr.Op != "local error" {
+		return true
+	}
+	var netErr net.Error
+	return errors.As(err, &netErr) && netErr.Timeout()
+}
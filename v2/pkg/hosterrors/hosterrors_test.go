@@ -0,0 +1,66 @@
+package hosterrors
+
+import (
+	"errors"
+	"fmt"
+	"net"
+	"net/url"
+	"syscall"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+// timeoutError is a network timeout
+type timeoutError struct{}
+
+func (timeoutError) Error() string   { return "i/o timeout" }
+func (timeoutError) Timeout() bool   { return true }
+func (timeoutError) Temporary() bool { return true }
+
+func TestCache(t *testing.T) {
+	refused := &url.Error{Op: "Get", URL: "https://example.com", Err: &net.OpError{Op: "dial", Net: "tcp", Err: syscall.ECONNREFUSED}}
+	var dead []string
+	cache := New(3, func(host string, err error) {
+		dead = append(dead, host)
+		require.Equal(t, refused, err, "Could not pass the triggering error")
+	})
+
+	cache.Failed("https://example.com/a", refused)
+	cache.Failed("https://example.com/b", refused)
+	cache.Succeeded("https://example.com/c")
+	cache.Failed("https://example.com/a", refused)
+	cache.Failed("https://example.com/b", refused)
+	require.False(t, cache.Dead("example.com"), "Could not reset the errors after a success")
+
+	cache.Failed("https://example.com/a", errors.New("could not read http body"))
+	require.False(t, cache.Dead("example.com"), "Could not ignore the errors which are not network errors")
+
+	cache.Failed("https://example.com/a", refused)
+	require.True(t, cache.Dead("https://example.com/d"), "Could not mark the host as dead")
+	require.True(t, cache.Dead("example.com"), "Could not share the host between the inputs")
+	require.False(t, cache.Dead("https://other.example.com"), "Could not keep the hosts apart")
+
+	cache.Failed("https://example.com/a", refused)
+	cache.Succeeded("https://example.com/a")
+	require.True(t, cache.Dead("example.com"), "Could not keep the host dead")
+	require.Equal(t, []string{"example.com"}, dead, "Could not report the dead host once")
+	require.Equal(t, 1, cache.Len(), "Could not count the hosts of the cache")
+	cache.Failed("https://b.example.com", refused)
+	require.Equal(t, []Host{{Host: "example.com", Errors: 4, Dead: true}, {Host: "b.example.com", Errors: 1}}, cache.Hosts(), "Could not list the hosts of the cache")
+
+	var nilCache *Cache
+	nilCache.Failed("https://example.com", refused)
+	require.False(t, nilCache.Dead("https://example.com"), "Could not ignore nil cache")
+	require.Zero(t, nilCache.Len(), "Could not ignore nil cache")
+	require.Empty(t, nilCache.Hosts(), "Could not ignore nil cache")
+}
+
+func TestIsNetworkError(t *testing.T) {
+	require.True(t, IsNetworkError(fmt.Errorf("giving up: %w", &url.Error{Op: "Get", URL: "https://example.com", Err: timeoutError{}})), "Could not detect timeout")
+	require.True(t, IsNetworkError(&net.DNSError{Err: "no such host", Name: "example.com"}), "Could not detect dns error")
+	require.True(t, IsNetworkError(&net.OpError{Op: "read", Net: "tcp", Err: syscall.ECONNRESET}), "Could not detect connection reset")
+	require.False(t, IsNetworkError(&net.OpError{Op: "remote error", Err: errors.New("tls: handshake failure")}), "Could not skip tls error")
+	require.False(t, IsNetworkError(errors.New("unexpected status")), "Could not skip other error")
+	require.False(t, IsNetworkError(nil), "Could not skip nil error")
+}
@@ -0,0 +1,6 @@
+// Package inputs reads the targets of a scan streamed one per line, such as
+// the output of a port scanner piped to nuclei, handing each target over as
+// soon as its line arrives and skipping the duplicates of the recent ones,
+// the near duplicates of a target being normalized into the same form.
+// It expands the cidr ranges and the ports of the targets as they are read.
+package inputs
@@ -0,0 +1,327 @@
+package inputs
+
+import (
+	"fmt"
+	"io/ioutil"
+	"math/big"
+	"net"
+	"net/url"
+	"path"
+	"strconv"
+	"strings"
+	"sync"
+)
+
+// DefaultCIDRLimit is the default maximum number of addresses of a cidr
+// range of the targets, the one of a /8 ipv4 range.
+const DefaultCIDRLimit = 1 << 24
+
+// ParsePorts parses comma separated ports and ranges of ports, i.e
+// 80,443,8000-8100, returning the ports in order without duplicates.
+func ParsePorts(value string) ([]string, error) {
+	var ports []string
+	seen := make(map[int]struct{})
+	for _, part := range strings.Split(value, ",") {
+		part = strings.TrimSpace(part)
+		if part == "" {
+			continue
+		}
+		first, last := part, part
+		if index := strings.Index(part, "-"); index != -1 {
+			first, last = part[:index], part[index+1:]
+		}
+		start, err := parsePort(first)
+		if err != nil {
+			return nil, err
+		}
+		end, err := parsePort(last)
+		if err != nil {
+			return nil, err
+		}
+		if start > end {
+			return nil, fmt.Errorf("invalid port range %s", part)
+		}
+		for port := start; port <= end; port++ {
+			if _, ok := seen[port]; !ok {
+				seen[port] = struct{}{}
+				ports = append(ports, strconv.Itoa(port))
+			}
+		}
+	}
+	return ports, nil
+}
+
+// parsePort parses a port between 1 and 65535
+func parsePort(value string) (int, error) {
+	port, err := strconv.Atoi(strings.TrimSpace(value))
+	if err != nil || port <= 0 || port > 65535 {
+		return 0, fmt.Errorf("invalid port %s", value)
+	}
+	return port, nil
+}
+
+// maxResolved is the maximum number of hosts whose resolved exclusion is
+// kept, the cache being emptied once full.
+const maxResolved = 10000
+
+// Exclusions are the hosts, globs of hosts, addresses and cidr ranges whose
+// targets are not scanned. The methods of nil exclusions exclude nothing.
+type Exclusions struct {
+	// hosts are the excluded hosts, lowercased, and addresses the excluded
+	// addresses
+	hosts     map[string]struct{}
+	globs     []string
+	addresses map[string]struct{}
+	networks  []*net.IPNet
+	// lookup resolves the hosts of the targets to exclude the ones resolving
+	// into the excluded addresses and cidr ranges if not nil, resolved
+	// being the rules of the hosts resolved.
+	lookup        func(host string) ([]string, error)
+	resolvedMutex sync.Mutex
+	resolved      map[string]string
+}
+
+// ParseExclusions parses comma separated hosts, globs of hosts, addresses
+// and cidr ranges, i.e admin.example.com,*.internal.example.com,10.0.0.0/8.
+func ParseExclusions(value string) (*Exclusions, error) {
+	exclusions := &Exclusions{hosts: make(map[string]struct{}), addresses: make(map[string]struct{})}
+	for _, part := range strings.Split(value, ",") {
+		if err := exclusions.Add(part); err != nil {
+			return nil, err
+		}
+	}
+	return exclusions, nil
+}
+
+// Add adds an excluded host, glob of hosts, address or cidr range, the
+// empty entries being ignored.
+func (e *Exclusions) Add(entry string) error {
+	entry = strings.ToLower(strings.TrimSpace(entry))
+	if entry == "" {
+		return nil
+	}
+	if strings.Contains(entry, "/") {
+		_, network, err := net.ParseCIDR(entry)
+		if err != nil {
+			return fmt.Errorf("invalid excluded cidr range %s", entry)
+		}
+		e.networks = append(e.networks, network)
+		return nil
+	}
+	if strings.ContainsAny(entry, "*?[") {
+		if _, err := path.Match(entry, ""); err != nil {
+			return fmt.Errorf("invalid excluded glob %s", entry)
+		}
+		e.globs = append(e.globs, entry)
+		return nil
+	}
+	if ip := net.ParseIP(strings.Trim(entry, "[]")); ip != nil {
+		e.addresses[ip.String()] = struct{}{}
+		return nil
+	}
+	e.hosts[entry] = struct{}{}
+	return nil
+}
+
+// AddFile adds the excluded entries of a file, one per line, the lines
+// starting with # being ignored.
+func (e *Exclusions) AddFile(file string) error {
+	data, err := ioutil.ReadFile(file)
+	if err != nil {
+		return err
+	}
+	for _, line := range strings.Split(string(data), "\n") {
+		if strings.HasPrefix(strings.TrimSpace(line), "#") {
+			continue
+		}
+		if err := e.Add(line); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// Resolve resolves the hosts of the targets with lookup, excluding the ones
+// resolving into the excluded addresses and cidr ranges. The hosts which
+// can't be resolved are not excluded.
+func (e *Exclusions) Resolve(lookup func(host string) ([]string, error)) {
+	e.lookup = lookup
+	e.resolved = make(map[string]string)
+}
+
+// Excluded returns true if the host of a target is excluded, the target
+// being a host, an address or an url with or without a port.
+func (e *Exclusions) Excluded(target string) bool {
+	return e.Rule(target) != ""
+}
+
+// Rule returns the entry excluding the host of a target, empty if it isn't
+// excluded.
+func (e *Exclusions) Rule(target string) string {
+	if e == nil {
+		return ""
+	}
+	host := strings.ToLower(targetHost(target))
+	ip := net.ParseIP(host)
+	if ip != nil {
+		return e.addressRule(ip)
+	}
+	if _, ok := e.hosts[host]; ok {
+		return host
+	}
+	for _, glob := range e.globs {
+		if matched, _ := path.Match(glob, host); matched {
+			return glob
+		}
+	}
+	return e.resolvedRule(host)
+}
+
+// addressRule returns the entry excluding an address, empty if none
+func (e *Exclusions) addressRule(ip net.IP) string {
+	if _, ok := e.addresses[ip.String()]; ok {
+		return ip.String()
+	}
+	for _, network := range e.networks {
+		if network.Contains(ip) {
+			return network.String()
+		}
+	}
+	return ""
+}
+
+// resolvedRule returns the entry excluding an address a host resolves to,
+// empty if none or if the hosts aren't resolved.
+func (e *Exclusions) resolvedRule(host string) string {
+	if e.lookup == nil || (len(e.networks) == 0 && len(e.addresses) == 0) {
+		return ""
+	}
+	e.resolvedMutex.Lock()
+	rule, ok := e.resolved[host]
+	e.resolvedMutex.Unlock()
+	if ok {
+		return rule
+	}
+
+	addresses, err := e.lookup(host)
+	if err == nil {
+		for _, address := range addresses {
+			if ip := net.ParseIP(address); ip != nil {
+				if rule = e.addressRule(ip); rule != "" {
+					break
+				}
+			}
+		}
+	}
+	e.resolvedMutex.Lock()
+	if len(e.resolved) >= maxResolved {
+		e.resolved = make(map[string]string)
+	}
+	e.resolved[host] = rule
+	e.resolvedMutex.Unlock()
+	return rule
+}
+
+// targetHost returns the host of a target without its scheme and port
+func targetHost(target string) string {
+	if strings.Contains(target, "://") {
+		if u, err := url.Parse(target); err == nil {
+			return u.Hostname()
+		}
+	}
+	if host, _, err := net.SplitHostPort(target); err == nil {
+		return host
+	}
+	return strings.Trim(target, "[]")
+}
+
+// Expander expands the targets of the input, the cidr ranges into their
+// addresses, and combines the hosts without a scheme or a port with each
+// of the ports if any. The excluded targets are skipped.
+type Expander struct {
+	ports      []string
+	exclusions *Exclusions
+	limit      int64
+}
+
+// NewExpander returns an expander of the targets combining them with the
+// ports, refusing the cidr ranges of more than limit addresses.
+func NewExpander(ports []string, exclusions *Exclusions, limit int64) *Expander {
+	return &Expander{ports: ports, exclusions: exclusions, limit: limit}
+}
+
+// Expand calls a function with each target of a line of the input in turn,
+// the addresses of a cidr range being generated as they are called, and
+// excluded with each excluded target along with the entry excluding it if
+// not nil. An error is returned for a cidr range larger than the limit.
+func (e *Expander) Expand(line string, fn func(target string), excluded func(target, rule string)) error {
+	line = strings.TrimSpace(line)
+	if line == "" {
+		return nil
+	}
+	ip, network, err := net.ParseCIDR(line)
+	if err != nil {
+		e.expandTarget(line, fn, excluded)
+		return nil
+	}
+	ones, bits := network.Mask.Size()
+	size := new(big.Int).Lsh(big.NewInt(1), uint(bits-ones))
+	if size.Cmp(big.NewInt(e.limit)) > 0 {
+		return fmt.Errorf("cidr range %s has %s addresses, more than the limit of %d", line, size, e.limit)
+	}
+	for ip = ip.Mask(network.Mask); network.Contains(ip); ip = nextIP(ip) {
+		e.expandTarget(ip.String(), fn, excluded)
+	}
+	return nil
+}
+
+// expandTarget calls a function with a target combined with each of the
+// ports unless it is excluded, calling excluded otherwise if not nil.
+func (e *Expander) expandTarget(target string, fn func(target string), excluded func(target, rule string)) {
+	if rule := e.exclusions.Rule(target); rule != "" {
+		if excluded != nil {
+			excluded(target, rule)
+		}
+		return
+	}
+	e.withPorts(target, fn)
+}
+
+// Count returns the number of targets of a line of the input, without
+// keeping them, calling excluded with each excluded target if not nil. An
+// error is returned for a cidr range larger than the limit.
+func (e *Expander) Count(line string, excluded func(target, rule string)) (int64, error) {
+	var count int64
+	err := e.Expand(line, func(string) { count++ }, excluded)
+	return count, err
+}
+
+// withPorts calls a function with a target combined with each of the ports,
+// the target as is if it has a scheme or a port or without ports.
+func (e *Expander) withPorts(target string, fn func(target string)) {
+	if len(e.ports) == 0 || strings.Contains(target, "://") {
+		fn(target)
+		return
+	}
+	if _, _, err := net.SplitHostPort(target); err == nil {
+		fn(target)
+		return
+	}
+	host := strings.Trim(target, "[]")
+	for _, port := range e.ports {
+		fn(net.JoinHostPort(host, port))
+	}
+}
+
+// nextIP returns the address following ip
+func nextIP(ip net.IP) net.IP {
+	next := make(net.IP, len(ip))
+	copy(next, ip)
+	for i := len(next) - 1; i >= 0; i-- {
+		next[i]++
+		if next[i] != 0 {
+			break
+		}
+	}
+	return next
+}
@@ -0,0 +1,107 @@
+package inputs
+
+import (
+	"errors"
+	"io/ioutil"
+	"os"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestParsePorts(t *testing.T) {
+	ports, err := ParsePorts("443, 80,8000-8002,80")
+	require.Nil(t, err, "Could not parse ports")
+	require.Equal(t, []string{"443", "80", "8000", "8001", "8002"}, ports, "Could not parse ports in order")
+
+	for _, value := range []string{"0", "65536", "http", "90-80", "80-"} {
+		_, err := ParsePorts(value)
+		require.NotNil(t, err, "Could not refuse invalid ports %s", value)
+	}
+}
+
+func TestExclusions(t *testing.T) {
+	exclusions, err := ParseExclusions("admin.example.com, 10.0.0.5,192.168.0.0/30,2001:db8::1")
+	require.Nil(t, err, "Could not parse exclusions")
+
+	for _, target := range []string{"ADMIN.example.com", "https://admin.example.com/login", "10.0.0.5:8080", "192.168.0.3", "[2001:db8:0::1]:443"} {
+		require.True(t, exclusions.Excluded(target), "Could not exclude %s", target)
+	}
+	for _, target := range []string{"example.com", "10.0.0.6", "192.168.0.4", "http://10.0.0.50"} {
+		require.False(t, exclusions.Excluded(target), "Could not keep %s", target)
+	}
+
+	_, err = ParseExclusions("10.0.0.0/33")
+	require.NotNil(t, err, "Could not refuse invalid cidr range")
+
+	var nilExclusions *Exclusions
+	require.False(t, nilExclusions.Excluded("example.com"), "Could not ignore nil exclusions")
+}
+
+func TestExclusionRules(t *testing.T) {
+	exclusions, err := ParseExclusions("*.internal.example.com,10.0.0.0/24")
+	require.Nil(t, err, "Could not parse exclusions")
+	file, err := ioutil.TempFile("", "exclusions-*.txt")
+	require.Nil(t, err, "Could not create exclusions file")
+	defer os.Remove(file.Name())
+	file.WriteString("# out of scope\nvpn.example.com\n\n192.168.1.1\n")
+	file.Close()
+	require.Nil(t, exclusions.AddFile(file.Name()), "Could not read exclusions file")
+
+	require.Equal(t, "*.internal.example.com", exclusions.Rule("https://db.eu.internal.example.com:8443"), "Could not exclude by glob")
+	require.Equal(t, "vpn.example.com", exclusions.Rule("VPN.example.com"), "Could not exclude host of the file")
+	require.Equal(t, "192.168.1.1", exclusions.Rule("192.168.1.1:22"), "Could not exclude address of the file")
+	require.Equal(t, "10.0.0.0/24", exclusions.Rule("10.0.0.7"), "Could not exclude by cidr range")
+	require.Empty(t, exclusions.Rule("internal.example.com"), "Could not keep the host of the glob")
+	require.Empty(t, exclusions.Rule("app.example.com"), "Could not keep the hosts without resolving them")
+
+	var lookups int
+	exclusions.Resolve(func(host string) ([]string, error) {
+		lookups++
+		if host == "app.example.com" {
+			return []string{"10.0.0.20"}, nil
+		}
+		return nil, errors.New("no such host")
+	})
+	require.Equal(t, "10.0.0.0/24", exclusions.Rule("http://app.example.com"), "Could not exclude host resolving into cidr range")
+	require.Equal(t, "10.0.0.0/24", exclusions.Rule("app.example.com:8080"), "Could not exclude host resolved before")
+	require.Empty(t, exclusions.Rule("unknown.example.com"), "Could not keep host which can't be resolved")
+	require.Equal(t, 2, lookups, "Could not cache the resolved hosts")
+
+	for _, value := range []string{"[invalid", "10.0.0.0/33"} {
+		_, err = ParseExclusions(value)
+		require.NotNil(t, err, "Could not refuse invalid exclusion %s", value)
+	}
+}
+
+func TestExpander(t *testing.T) {
+	exclusions, err := ParseExclusions("10.0.0.1")
+	require.Nil(t, err, "Could not parse exclusions")
+	e := NewExpander([]string{"80", "443"}, exclusions, 256)
+
+	var targets []string
+	require.Nil(t, e.Expand("10.0.0.0/30", func(target string) { targets = append(targets, target) }, nil), "Could not expand cidr range")
+	require.Equal(t, []string{"10.0.0.0:80", "10.0.0.0:443", "10.0.0.2:80", "10.0.0.2:443", "10.0.0.3:80", "10.0.0.3:443"}, targets, "Could not expand cidr range with ports")
+
+	excluded := make(map[string]string)
+	count, err := e.Count("10.0.0.0/31", func(target, rule string) { excluded[target] = rule })
+	require.Nil(t, err, "Could not count cidr range")
+	require.Equal(t, int64(2), count, "Could not count the targets with ports")
+	require.Equal(t, map[string]string{"10.0.0.1": "10.0.0.1"}, excluded, "Could not report the excluded targets")
+
+	targets = nil
+	for _, line := range []string{"example.com", "example.com:8080", "https://example.com", "::1", " ", "10.0.0.1"} {
+		require.Nil(t, e.Expand(line, func(target string) { targets = append(targets, target) }, nil), "Could not expand %s", line)
+	}
+	require.Equal(t, []string{"example.com:80", "example.com:443", "example.com:8080", "https://example.com", "[::1]:80", "[::1]:443"}, targets, "Could not combine hosts with ports")
+
+	count, err = e.Count("2001:db8::/120", nil)
+	require.Nil(t, err, "Could not count ipv6 cidr range")
+	require.Equal(t, int64(512), count, "Could not count ipv6 cidr range")
+
+	_, err = e.Count("2001:db8::/64", nil)
+	require.NotNil(t, err, "Could not refuse cidr range above the limit")
+	count, err = NewExpander(nil, nil, DefaultCIDRLimit).Count("10.0.0.0/16", nil)
+	require.Nil(t, err, "Could not count cidr range")
+	require.Equal(t, int64(65536), count, "Could not count cidr range")
+}
@@ -0,0 +1,122 @@
+package inputs
+
+import (
+	"bufio"
+	"io"
+	"sync"
+	"sync/atomic"
+)
+
+// DefaultDedupeSize is the number of the most recent targets of a stream
+// remembered to skip their duplicates.
+const DefaultDedupeSize = 1 << 20
+
+// Deduper remembers a bounded number of the most recent targets, forgetting
+// the oldest ones first. The methods of a nil deduper do nothing, none of
+// the targets being duplicates.
+type Deduper struct {
+	mutex  sync.Mutex
+	seen   map[string]struct{}
+	recent []string
+	next   int
+}
+
+// NewDeduper returns a deduper remembering the last size targets
+func NewDeduper(size int) *Deduper {
+	return &Deduper{seen: make(map[string]struct{}, size), recent: make([]string, 0, size)}
+}
+
+// Seen returns true if a target is one of the remembered ones, remembering
+// it otherwise.
+func (d *Deduper) Seen(target string) bool {
+	if d == nil {
+		return false
+	}
+	d.mutex.Lock()
+	defer d.mutex.Unlock()
+
+	if _, ok := d.seen[target]; ok {
+		return true
+	}
+	if len(d.recent) < cap(d.recent) {
+		d.recent = append(d.recent, target)
+	} else {
+		delete(d.seen, d.recent[d.next])
+		d.recent[d.next] = target
+		d.next = (d.next + 1) % len(d.recent)
+	}
+	d.seen[target] = struct{}{}
+	return false
+}
+
+// Reader reads the targets of a stream as its lines arrive, skipping the
+// blank lines and the duplicates of the deduper if any, the targets being
+// deduplicated on their normalized form while the first one is returned.
+type Reader struct {
+	scanner *bufio.Scanner
+	deduper *Deduper
+	// consumed are the lines read, targets the targets returned and
+	// duplicates the ones skipped as duplicates, accessed atomically.
+	consumed   int64
+	targets    int64
+	duplicates int64
+}
+
+// NewReader returns a reader of the targets of a stream
+func NewReader(reader io.Reader, deduper *Deduper) *Reader {
+	return &Reader{scanner: bufio.NewScanner(reader), deduper: deduper}
+}
+
+// Skip skips the lines consumed by a previous read of the same stream,
+// returning the number of lines skipped, less if the stream ended before.
+// Their targets are remembered by the deduper as they were by the read.
+func (r *Reader) Skip(lines int64) int64 {
+	var skipped int64
+	for ; skipped < lines && r.scanner.Scan(); skipped++ {
+		atomic.AddInt64(&r.consumed, 1)
+		if target := Trim(r.scanner.Text()); target != "" {
+			r.deduper.Seen(Normalize(target))
+		}
+	}
+	return skipped
+}
+
+// Next returns the next target of the stream along with the number of
+// lines consumed once it is read, blocking until its line arrives. false
+// is returned at the end of the stream.
+func (r *Reader) Next() (string, int64, bool) {
+	for r.scanner.Scan() {
+		consumed := atomic.AddInt64(&r.consumed, 1)
+		target := Trim(r.scanner.Text())
+		if target == "" {
+			continue
+		}
+		if r.deduper.Seen(Normalize(target)) {
+			atomic.AddInt64(&r.duplicates, 1)
+			continue
+		}
+		atomic.AddInt64(&r.targets, 1)
+		return target, consumed, true
+	}
+	return "", atomic.LoadInt64(&r.consumed), false
+}
+
+// Err returns the error of the stream if it did not end normally
+func (r *Reader) Err() error {
+	return r.scanner.Err()
+}
+
+// Consumed returns the number of lines read from the stream
+func (r *Reader) Consumed() int64 {
+	return atomic.LoadInt64(&r.consumed)
+}
+
+// Targets returns the number of targets returned by the reader
+func (r *Reader) Targets() int64 {
+	return atomic.LoadInt64(&r.targets)
+}
+
+// Duplicates returns the number of targets skipped as duplicates
+func (r *Reader) Duplicates() int64 {
+	return atomic.LoadInt64(&r.duplicates)
+}
@@ -0,0 +1,103 @@
+package inputs
+
+import (
+	"io"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestDeduper(t *testing.T) {
+	d := NewDeduper(2)
+	require.False(t, d.Seen("a.example.com"), "Could not remember new target")
+	require.False(t, d.Seen("b.example.com"), "Could not remember new target")
+	require.True(t, d.Seen("a.example.com"), "Could not skip recent duplicate")
+	require.False(t, d.Seen("c.example.com"), "Could not remember new target")
+	require.False(t, d.Seen("a.example.com"), "Could not forget the oldest target")
+	require.True(t, d.Seen("c.example.com"), "Could not skip recent duplicate")
+
+	var nilDeduper *Deduper
+	require.False(t, nilDeduper.Seen("a.example.com"), "Could not ignore nil deduper")
+	require.False(t, nilDeduper.Seen("a.example.com"), "Could not ignore nil deduper")
+}
+
+func TestReader(t *testing.T) {
+	r := NewReader(strings.NewReader("a.example.com\n\n b.example.com \na.example.com\nc.example.com\n"), NewDeduper(DefaultDedupeSize))
+	require.Equal(t, int64(1), r.Skip(1), "Could not skip consumed line")
+
+	target, consumed, ok := r.Next()
+	require.True(t, ok, "Could not read target")
+	require.Equal(t, "b.example.com", target, "Could not trim target")
+	require.Equal(t, int64(3), consumed, "Could not count the blank line")
+
+	target, consumed, ok = r.Next()
+	require.True(t, ok, "Could not read target")
+	require.Equal(t, "c.example.com", target, "Could not skip the duplicate of the skipped line")
+	require.Equal(t, int64(5), consumed, "Could not count the duplicate line")
+	require.Equal(t, int64(1), r.Duplicates(), "Could not count the duplicate")
+
+	_, _, ok = r.Next()
+	require.False(t, ok, "Could not end the stream")
+	require.Nil(t, r.Err(), "Could not end the stream normally")
+	require.Equal(t, int64(5), r.Consumed(), "Could not count the lines")
+	require.Equal(t, int64(2), r.Targets(), "Could not count the targets")
+}
+
+func TestReaderStreaming(t *testing.T) {
+	pipeReader, pipeWriter := io.Pipe()
+	r := NewReader(pipeReader, nil)
+	go func() {
+		io.WriteString(pipeWriter, "a.example.com\n")
+	}()
+	target, _, ok := r.Next()
+	require.True(t, ok, "Could not read target before the end of the stream")
+	require.Equal(t, "a.example.com", target, "Could not read target")
+
+	go func() {
+		io.WriteString(pipeWriter, "a.example.com\n")
+		pipeWriter.Close()
+	}()
+	target, _, ok = r.Next()
+	require.True(t, ok, "Could not read duplicate without deduper")
+	require.Equal(t, "a.example.com", target, "Could not read duplicate")
+	_, _, ok = r.Next()
+	require.False(t, ok, "Could not end the stream")
+}
+
+func TestNormalize(t *testing.T) {
+	for _, target := range []string{"http://example.com", "http://example.com/", "HTTP://EXAMPLE.COM", "http://example.com:80", "example.com:80", "example.com:80/", "\ufeff http://example.com\t"} {
+		require.Equal(t, "http://example.com/", Normalize(target), "Could not normalize %q", target)
+	}
+	for target, normalized := range map[string]string{
+		"https://Example.com:443":  "https://example.com/",
+		"example.com:443":          "https://example.com/",
+		"https://example.com:80":   "https://example.com:80/",
+		"http://example.com:8080":  "http://example.com:8080/",
+		"http://example.com?q=1":   "http://example.com/?q=1",
+		"http://example.com/App1/": "http://example.com/App1/",
+		"http://example.com/app1":  "http://example.com/app1",
+		"http://[2001:DB8::1]:80/": "http://[2001:db8::1]/",
+		"example.com:443/App":      "https://example.com/App",
+		"Example.com":              "example.com",
+		"Example.com/":             "example.com",
+		"Example.com:8443/App":     "example.com:8443/App",
+		"10.0.0.0/24":              "10.0.0.0/24",
+		"2001:DB8::/120":           "2001:db8::/120",
+	} {
+		require.Equal(t, normalized, Normalize(target), "Could not normalize %q", target)
+	}
+	require.NotEqual(t, Normalize("http://example.com/app1/"), Normalize("http://example.com/app2/"), "Could not keep the paths apart")
+	require.NotEqual(t, Normalize("example.com"), Normalize("http://example.com"), "Could not keep the host to probe apart")
+}
+
+func TestReaderNormalize(t *testing.T) {
+	r := NewReader(strings.NewReader("\ufeffHTTP://Example.com\nhttp://example.com/\nexample.com:80\nhttp://example.com/app/\n"), NewDeduper(DefaultDedupeSize))
+	target, _, ok := r.Next()
+	require.True(t, ok, "Could not read target")
+	require.Equal(t, "HTTP://Example.com", target, "Could not keep the first target as is")
+	target, _, ok = r.Next()
+	require.True(t, ok, "Could not read target")
+	require.Equal(t, "http://example.com/app/", target, "Could not skip the near duplicates")
+	require.Equal(t, int64(2), r.Duplicates(), "Could not count the near duplicates")
+}
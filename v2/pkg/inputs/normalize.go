@@ -0,0 +1,82 @@
+package inputs
+
+import (
+	"net"
+	"net/url"
+	"strings"
+	"unicode"
+)
+
+// Trim returns a line of the input without the white space and the byte
+// order mark around it.
+func Trim(line string) string {
+	return strings.TrimFunc(line, func(r rune) bool {
+		return unicode.IsSpace(r) || r == '\ufeff'
+	})
+}
+
+// Normalize returns the form of a target shared by its near duplicates, the
+// one the targets are deduplicated on. The scheme and the host are lower
+// cased, the default ports of http and https are removed and the root of a
+// host is the same with or without its trailing slash, the paths beyond
+// the root being kept as is. A host without a scheme on port 80 or 443 is
+// the one of the url of its scheme, while a host without a scheme and a
+// port is kept apart, its scheme being probed.
+func Normalize(target string) string {
+	target = Trim(target)
+	if !strings.Contains(target, "://") {
+		return normalizeHost(target)
+	}
+	u, err := url.Parse(target)
+	if err != nil || u.Host == "" {
+		return target
+	}
+	u.Scheme = strings.ToLower(u.Scheme)
+	u.Host = normalizeHostPort(u.Hostname(), u.Port(), u.Scheme)
+	if u.Path == "" && u.Opaque == "" {
+		u.Path = "/"
+	}
+	return u.String()
+}
+
+// normalizeHost returns the normalized form of a target without a scheme,
+// a host and a port along with an optional path.
+func normalizeHost(target string) string {
+	hostPort, path := target, ""
+	if slash := strings.Index(target, "/"); slash >= 0 {
+		hostPort, path = target[:slash], target[slash:]
+	}
+	if path == "/" {
+		path = ""
+	}
+	host, port, err := net.SplitHostPort(hostPort)
+	if err != nil {
+		return strings.ToLower(hostPort) + path
+	}
+	var scheme string
+	switch port {
+	case "80":
+		scheme = "http"
+	case "443":
+		scheme = "https"
+	default:
+		return strings.ToLower(hostPort) + path
+	}
+	if path == "" {
+		path = "/"
+	}
+	return scheme + "://" + normalizeHostPort(host, port, scheme) + path
+}
+
+// normalizeHostPort returns the lower cased host of a url along with its
+// port unless it is the default one of the scheme.
+func normalizeHostPort(host, port, scheme string) string {
+	host = strings.ToLower(host)
+	if port == "" || (scheme == "http" && port == "80") || (scheme == "https" && port == "443") {
+		if strings.Contains(host, ":") {
+			return "[" + host + "]"
+		}
+		return host
+	}
+	return net.JoinHostPort(host, port)
+}
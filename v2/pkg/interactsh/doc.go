@@ -0,0 +1,7 @@
+// Package interactsh is a client of an interactsh server, handing out the
+// unique urls the templates send to the targets with {{interactsh-url}} and
+// polling the dns, http and smtp interactions of the targets with them, so
+// the blind vulnerabilities are matched once their callback arrives. The
+// interactions are received by its own http and dns listeners instead in
+// the networks without an interactsh server.
+package interactsh
@@ -0,0 +1,552 @@
+package interactsh
+
+import (
+	"bytes"
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/sha256"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/json"
+	"encoding/pem"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"net/url"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// DefaultServer is the public interactsh server used by default
+const DefaultServer = "https://interact.sh"
+
+// DefaultCacheSize is the default number of urls correlated at once
+const DefaultCacheSize = 5000
+
+// DefaultPollInterval is the default interval between the polls
+const DefaultPollInterval = 5 * time.Second
+
+// The urls handed out are the correlation id of the client followed by a
+// nonce, the unique id of the interactions, as a subdomain of the server.
+const (
+	correlationIDLength = 20
+	nonceLength         = 13
+	uniqueIDLength      = correlationIDLength + nonceLength
+)
+
+// Options are the settings of a client
+type Options struct {
+	// Server is the url of the interactsh server
+	Server string
+	// Token authenticates the requests to a self-hosted server if any
+	Token string
+	// CacheSize is the number of urls correlated at once, the oldest ones
+	// being forgotten first.
+	CacheSize    int
+	PollInterval time.Duration
+	Timeout      time.Duration
+}
+
+// Interaction is an interaction of a target with a url of the client,
+// received by the server.
+type Interaction struct {
+	Protocol      string    `json:"protocol"`
+	UniqueID      string    `json:"unique-id"`
+	FullID        string    `json:"full-id"`
+	QType         string    `json:"q-type,omitempty"`
+	RawRequest    string    `json:"raw-request,omitempty"`
+	RawResponse   string    `json:"raw-response,omitempty"`
+	SMTPFrom      string    `json:"smtp-from,omitempty"`
+	RemoteAddress string    `json:"remote-address"`
+	Timestamp     time.Time `json:"timestamp"`
+}
+
+// Values returns the values of an interaction the matchers are evaluated
+// with, interactsh_protocol, interactsh_request and interactsh_response.
+func (i *Interaction) Values() map[string]interface{} {
+	return map[string]interface{}{
+		"interactsh_protocol": i.Protocol,
+		"interactsh_request":  i.RawRequest,
+		"interactsh_response": i.RawResponse,
+	}
+}
+
+// correlation is a url handed out, along with the function awaiting its
+// interactions if any and the interactions received before it awaited.
+type correlation struct {
+	await    func(interaction *Interaction) bool
+	received []*Interaction
+	done     bool
+}
+
+// Client hands out the urls of a server and polls their interactions in
+// the background, calling the function awaiting the interactions of each
+// url until it returns true. The methods of a nil client do nothing.
+type Client struct {
+	server        *url.URL
+	token         string
+	client        *http.Client
+	key           *rsa.PrivateKey
+	secret        string
+	correlationID string
+	interval      time.Duration
+
+	// mutex guards the correlations, a bounded number of urls by unique
+	// id, recent holding them in the order they were handed out.
+	mutex        sync.Mutex
+	correlations map[string]*correlation
+	recent       []string
+	next         int
+
+	stop    chan struct{}
+	stopped chan struct{}
+	once    sync.Once
+
+	// listener receives the interactions instead of the server if any,
+	// the urls being the ones of its host.
+	listener *listener
+
+	// the numbers of urls handed out and of interactions received, the
+	// ones not matching a url and the ones evicted before awaited.
+	urls         uint64
+	interactions uint64
+	uncorrelated uint64
+	evicted      uint64
+}
+
+// New registers a client with a server and starts polling its interactions
+func New(options Options) (*Client, error) {
+	server, err := url.Parse(options.Server)
+	if err != nil {
+		return nil, err
+	}
+	if (server.Scheme != "http" && server.Scheme != "https") || server.Hostname() == "" {
+		return nil, fmt.Errorf("invalid server %s, it should be an http or https url", options.Server)
+	}
+	if options.PollInterval <= 0 {
+		options.PollInterval = DefaultPollInterval
+	}
+	if options.Timeout <= 0 {
+		options.Timeout = 10 * time.Second
+	}
+	key, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		return nil, err
+	}
+	secret, err := randomID(32)
+	if err != nil {
+		return nil, err
+	}
+	c, err := newClient(options.CacheSize)
+	if err != nil {
+		return nil, err
+	}
+	c.server = server
+	c.token = options.Token
+	c.client = &http.Client{Timeout: options.Timeout}
+	c.key = key
+	c.secret = secret
+	c.interval = options.PollInterval
+	if err := c.register(); err != nil {
+		return nil, err
+	}
+	go c.pollLoop()
+	return c, nil
+}
+
+// newClient creates a client correlating the interactions of a number of
+// urls at once, the default cache size if 0.
+func newClient(cacheSize int) (*Client, error) {
+	if cacheSize <= 0 {
+		cacheSize = DefaultCacheSize
+	}
+	correlationID, err := randomID(correlationIDLength)
+	if err != nil {
+		return nil, err
+	}
+	return &Client{
+		correlationID: correlationID,
+		correlations:  make(map[string]*correlation, cacheSize),
+		recent:        make([]string, 0, cacheSize),
+		stop:          make(chan struct{}),
+		stopped:       make(chan struct{}),
+	}, nil
+}
+
+// register registers the public key and the correlation id of the client
+func (c *Client) register() error {
+	public, err := x509.MarshalPKIXPublicKey(&c.key.PublicKey)
+	if err != nil {
+		return err
+	}
+	encoded := pem.EncodeToMemory(&pem.Block{Type: "RSA PUBLIC KEY", Bytes: public})
+	return c.post("/register", map[string]string{
+		"public-key":     base64.StdEncoding.EncodeToString(encoded),
+		"secret-key":     c.secret,
+		"correlation-id": c.correlationID,
+	})
+}
+
+// post posts a json request to the server, returning an error unless it
+// responds with 200.
+func (c *Client) post(path string, body interface{}) error {
+	data, err := json.Marshal(body)
+	if err != nil {
+		return err
+	}
+	req, err := http.NewRequest(http.MethodPost, c.endpoint(path), bytes.NewReader(data))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	resp, err := c.do(req)
+	if err != nil {
+		return err
+	}
+	resp.Body.Close()
+	return nil
+}
+
+// do sends a request to the server with the token if any, the response
+// being an error unless its status is 200.
+func (c *Client) do(req *http.Request) (*http.Response, error) {
+	if c.token != "" {
+		req.Header.Set("Authorization", c.token)
+	}
+	resp, err := c.client.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	if resp.StatusCode != http.StatusOK {
+		message, _ := ioutil.ReadAll(io.LimitReader(resp.Body, 512))
+		resp.Body.Close()
+		return nil, fmt.Errorf("%s %s returned %s: %s", req.Method, req.URL.Path, resp.Status, strings.TrimSpace(string(message)))
+	}
+	return resp, nil
+}
+
+// endpoint returns the url of an endpoint of the server
+func (c *Client) endpoint(path string) string {
+	return strings.TrimSuffix(c.server.String(), "/") + path
+}
+
+// URL returns a new url of the server, whose interactions are kept until
+// a function awaits them or the url is evicted, so none is missed while
+// the response of its request is evaluated.
+func (c *Client) URL() string {
+	if c == nil {
+		return ""
+	}
+	nonce, err := randomID(nonceLength)
+	if err != nil {
+		// crypto/rand does not fail on the supported platforms
+		panic(err)
+	}
+	uniqueID := c.correlationID + nonce
+
+	c.mutex.Lock()
+	if len(c.recent) < cap(c.recent) {
+		c.recent = append(c.recent, uniqueID)
+	} else {
+		if evicted := c.correlations[c.recent[c.next]]; evicted != nil && !evicted.done {
+			atomic.AddUint64(&c.evicted, 1)
+		}
+		delete(c.correlations, c.recent[c.next])
+		c.recent[c.next] = uniqueID
+		c.next = (c.next + 1) % len(c.recent)
+	}
+	c.correlations[uniqueID] = &correlation{}
+	c.mutex.Unlock()
+
+	atomic.AddUint64(&c.urls, 1)
+	if c.listener != nil {
+		return c.listener.url(uniqueID)
+	}
+	return uniqueID + "." + c.server.Hostname()
+}
+
+// Await calls a function with each interaction of a url handed out, the
+// ones received before first, until it returns true. It does nothing for
+// a url evicted before or unknown to the client.
+func (c *Client) Await(URL string, fn func(interaction *Interaction) bool) {
+	if c == nil {
+		return
+	}
+	uniqueID := urlID(URL)
+
+	c.mutex.Lock()
+	entry, ok := c.correlations[uniqueID]
+	if !ok || entry.done {
+		c.mutex.Unlock()
+		return
+	}
+	received := entry.received
+	entry.received = nil
+	entry.await = fn
+	c.mutex.Unlock()
+
+	for _, interaction := range received {
+		if fn(interaction) {
+			c.complete(uniqueID)
+			return
+		}
+	}
+}
+
+// complete stops calling the function awaiting the interactions of a url
+func (c *Client) complete(uniqueID string) {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+	if entry, ok := c.correlations[uniqueID]; ok {
+		entry.done = true
+		entry.await = nil
+	}
+}
+
+// deliver hands an interaction over to the function awaiting its url, or
+// keeps it until one awaits it.
+func (c *Client) deliver(interaction *Interaction) {
+	atomic.AddUint64(&c.interactions, 1)
+	uniqueID := interactionID(interaction)
+
+	c.mutex.Lock()
+	entry, ok := c.correlations[uniqueID]
+	if !ok {
+		c.mutex.Unlock()
+		atomic.AddUint64(&c.uncorrelated, 1)
+		return
+	}
+	if entry.done {
+		c.mutex.Unlock()
+		return
+	}
+	fn := entry.await
+	if fn == nil {
+		entry.received = append(entry.received, interaction)
+		c.mutex.Unlock()
+		return
+	}
+	c.mutex.Unlock()
+
+	if fn(interaction) {
+		c.complete(uniqueID)
+	}
+}
+
+// urlID returns the unique id of a url handed out, its first label, or its
+// path for the urls of a listener reached by its ip.
+func urlID(URL string) string {
+	URL = strings.ToLower(URL)
+	if index := strings.IndexByte(URL, '/'); index != -1 {
+		return strings.SplitN(URL[index+1:], "/", 2)[0]
+	}
+	return strings.SplitN(URL, ".", 2)[0]
+}
+
+// interactionID returns the unique id of the url of an interaction, the
+// label of its full id of the length of the unique ids if it has none.
+func interactionID(interaction *Interaction) string {
+	if interaction.UniqueID != "" {
+		return strings.ToLower(interaction.UniqueID)
+	}
+	for _, label := range strings.Split(interaction.FullID, ".") {
+		if len(label) == uniqueIDLength {
+			return strings.ToLower(label)
+		}
+	}
+	return ""
+}
+
+// pollLoop polls the interactions at the interval until the client stops
+func (c *Client) pollLoop() {
+	defer close(c.stopped)
+	ticker := time.NewTicker(c.interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-c.stop:
+			return
+		case <-ticker.C:
+			// a failed poll is retried at the next interval, the server
+			// keeping the interactions until they are polled
+			_ = c.poll()
+		}
+	}
+}
+
+// pollResponse is the response of the server to a poll, its interactions
+// being encrypted with aes_key, itself encrypted with the public key.
+type pollResponse struct {
+	Data   []string `json:"data"`
+	AESKey string   `json:"aes_key"`
+}
+
+// poll fetches the interactions received since the previous poll and
+// delivers them.
+func (c *Client) poll() error {
+	query := url.Values{"id": {c.correlationID}, "secret": {c.secret}}
+	req, err := http.NewRequest(http.MethodGet, c.endpoint("/poll?"+query.Encode()), nil)
+	if err != nil {
+		return err
+	}
+	resp, err := c.do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	var polled pollResponse
+	if err := json.NewDecoder(resp.Body).Decode(&polled); err != nil {
+		return err
+	}
+	if len(polled.Data) == 0 {
+		return nil
+	}
+	key, err := c.decryptKey(polled.AESKey)
+	if err != nil {
+		return err
+	}
+	for _, data := range polled.Data {
+		interaction, err := decryptInteraction(key, data)
+		if err != nil {
+			atomic.AddUint64(&c.interactions, 1)
+			atomic.AddUint64(&c.uncorrelated, 1)
+			continue
+		}
+		c.deliver(interaction)
+	}
+	return nil
+}
+
+// decryptKey decrypts the aes key of a poll with the private key
+func (c *Client) decryptKey(encoded string) ([]byte, error) {
+	encrypted, err := base64.StdEncoding.DecodeString(encoded)
+	if err != nil {
+		return nil, err
+	}
+	return rsa.DecryptOAEP(sha256.New(), rand.Reader, c.key, encrypted, nil)
+}
+
+// decryptInteraction decrypts an interaction of a poll, encrypted with
+// aes in cfb mode after its iv.
+func decryptInteraction(key []byte, encoded string) (*Interaction, error) {
+	encrypted, err := base64.StdEncoding.DecodeString(encoded)
+	if err != nil {
+		return nil, err
+	}
+	block, err := aes.NewCipher(key)
+	if err != nil {
+		return nil, err
+	}
+	if len(encrypted) < aes.BlockSize {
+		return nil, fmt.Errorf("interaction shorter than its iv")
+	}
+	decrypted := make([]byte, len(encrypted)-aes.BlockSize)
+	cipher.NewCFBDecrypter(block, encrypted[:aes.BlockSize]).XORKeyStream(decrypted, encrypted[aes.BlockSize:])
+
+	interaction := &Interaction{}
+	if err := json.Unmarshal(decrypted, interaction); err != nil {
+		return nil, err
+	}
+	return interaction, nil
+}
+
+// Close keeps polling for the cooldown once the scan completed if urls
+// were handed out, the interactions of the last requests arriving late,
+// then polls a last time and deregisters the client from the server. The
+// listener of the client if any is shut down after the cooldown instead.
+func (c *Client) Close(cooldown time.Duration) error {
+	if c == nil {
+		return nil
+	}
+	var err error
+	c.once.Do(func() {
+		if cooldown > 0 && atomic.LoadUint64(&c.urls) > 0 {
+			time.Sleep(cooldown)
+		}
+		if c.listener != nil {
+			err = c.listener.close()
+			return
+		}
+		close(c.stop)
+		<-c.stopped
+		_ = c.poll()
+		err = c.post("/deregister", map[string]string{
+			"secret-key":     c.secret,
+			"correlation-id": c.correlationID,
+		})
+	})
+	return err
+}
+
+// Server returns the host of the server, the one of the urls of the
+// listener if any.
+func (c *Client) Server() string {
+	if c == nil {
+		return ""
+	}
+	if c.listener != nil {
+		return c.listener.host
+	}
+	return c.server.Hostname()
+}
+
+// Listening returns true if the interactions are received by the listener
+// of the client rather than polled from a server.
+func (c *Client) Listening() bool {
+	return c != nil && c.listener != nil
+}
+
+// URLs returns the number of urls handed out
+func (c *Client) URLs() uint64 {
+	if c == nil {
+		return 0
+	}
+	return atomic.LoadUint64(&c.urls)
+}
+
+// Interactions returns the number of interactions received
+func (c *Client) Interactions() uint64 {
+	if c == nil {
+		return 0
+	}
+	return atomic.LoadUint64(&c.interactions)
+}
+
+// Uncorrelated returns the number of interactions received not matching
+// any url of the client, the ones of the urls evicted included.
+func (c *Client) Uncorrelated() uint64 {
+	if c == nil {
+		return 0
+	}
+	return atomic.LoadUint64(&c.uncorrelated)
+}
+
+// Evicted returns the number of urls forgotten before their interactions
+// were awaited or matched, as more urls were handed out than the cache size.
+func (c *Client) Evicted() uint64 {
+	if c == nil {
+		return 0
+	}
+	return atomic.LoadUint64(&c.evicted)
+}
+
+// idCharset is the charset of the ids, lower case as the subdomains are
+const idCharset = "abcdefghijklmnopqrstuvwxyz0123456789"
+
+// randomID returns a random id of length characters
+func randomID(length int) (string, error) {
+	random := make([]byte, length)
+	if _, err := rand.Read(random); err != nil {
+		return "", err
+	}
+	for i, b := range random {
+		random[i] = idCharset[int(b)%len(idCharset)]
+	}
+	return string(random), nil
+}
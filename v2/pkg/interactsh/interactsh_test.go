@@ -0,0 +1,167 @@
+package interactsh
+
+import (
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/sha256"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/json"
+	"encoding/pem"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+// fakeServer is an interactsh server keeping the interactions of the
+// registered client until polled, encrypted as the server does.
+type fakeServer struct {
+	*httptest.Server
+	token string
+
+	mutex        sync.Mutex
+	key          *rsa.PublicKey
+	secret       string
+	pending      []*Interaction
+	deregistered bool
+}
+
+func newFakeServer(t *testing.T, token string) *fakeServer {
+	s := &fakeServer{token: token}
+	s.Server = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("Authorization") != s.token {
+			http.Error(w, "invalid token", http.StatusUnauthorized)
+			return
+		}
+		s.mutex.Lock()
+		defer s.mutex.Unlock()
+		var body map[string]string
+		switch r.URL.Path {
+		case "/register":
+			require.Nil(t, json.NewDecoder(r.Body).Decode(&body), "Could not decode the registration")
+			encoded, err := base64.StdEncoding.DecodeString(body["public-key"])
+			require.Nil(t, err, "Could not decode the public key")
+			block, _ := pem.Decode(encoded)
+			require.NotNil(t, block, "Could not decode the public key pem")
+			key, err := x509.ParsePKIXPublicKey(block.Bytes)
+			require.Nil(t, err, "Could not parse the public key")
+			require.Len(t, body["correlation-id"], correlationIDLength, "Could not send the correlation id")
+			s.key, s.secret = key.(*rsa.PublicKey), body["secret-key"]
+		case "/poll":
+			if r.URL.Query().Get("secret") != s.secret {
+				http.Error(w, "invalid secret", http.StatusUnauthorized)
+				return
+			}
+			json.NewEncoder(w).Encode(s.encrypt(t))
+			s.pending = nil
+		case "/deregister":
+			require.Nil(t, json.NewDecoder(r.Body).Decode(&body), "Could not decode the deregistration")
+			s.deregistered = body["secret-key"] == s.secret
+		}
+	}))
+	return s
+}
+
+// encrypt encrypts the pending interactions with a new aes key
+func (s *fakeServer) encrypt(t *testing.T) *pollResponse {
+	key := make([]byte, 32)
+	rand.Read(key)
+	encryptedKey, err := rsa.EncryptOAEP(sha256.New(), rand.Reader, s.key, key, nil)
+	require.Nil(t, err, "Could not encrypt the aes key")
+	block, err := aes.NewCipher(key)
+	require.Nil(t, err, "Could not create the cipher")
+
+	response := &pollResponse{AESKey: base64.StdEncoding.EncodeToString(encryptedKey)}
+	for _, interaction := range s.pending {
+		data, _ := json.Marshal(interaction)
+		encrypted := make([]byte, aes.BlockSize+len(data))
+		rand.Read(encrypted[:aes.BlockSize])
+		cipher.NewCFBEncrypter(block, encrypted[:aes.BlockSize]).XORKeyStream(encrypted[aes.BlockSize:], data)
+		response.Data = append(response.Data, base64.StdEncoding.EncodeToString(encrypted))
+	}
+	return response
+}
+
+// interact queues an interaction with a url, as a target would
+func (s *fakeServer) interact(URL, protocol string) {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	uniqueID := strings.SplitN(URL, ".", 2)[0]
+	s.pending = append(s.pending, &Interaction{Protocol: protocol, UniqueID: uniqueID, FullID: uniqueID, RawRequest: protocol + " request", Timestamp: time.Now()})
+}
+
+func TestClient(t *testing.T) {
+	server := newFakeServer(t, "s3cret")
+	defer server.Close()
+
+	_, err := New(Options{Server: server.URL})
+	require.NotNil(t, err, "Could not refuse the registration without the token")
+
+	client, err := New(Options{Server: server.URL, Token: "s3cret", PollInterval: 10 * time.Millisecond})
+	require.Nil(t, err, "Could not register the client")
+	URL := client.URL()
+	require.True(t, strings.HasSuffix(URL, ".127.0.0.1"), "Could not hand out a subdomain of the server")
+	require.Len(t, strings.SplitN(URL, ".", 2)[0], uniqueIDLength, "Could not hand out a unique id")
+	require.NotEqual(t, URL, client.URL(), "Could not hand out a new url")
+
+	// the interactions received before awaiting them are kept
+	server.interact(URL, "dns")
+	server.interact("unknownunknownunknownunknownunkno.127.0.0.1", "dns")
+	require.Eventually(t, func() bool { return client.Interactions() == 2 }, time.Second, 10*time.Millisecond, "Could not poll the interactions")
+	require.Equal(t, uint64(1), client.Uncorrelated(), "Could not count the uncorrelated interaction")
+
+	received := make(chan *Interaction, 4)
+	client.Await(URL, func(interaction *Interaction) bool {
+		received <- interaction
+		return interaction.Protocol == "http"
+	})
+	require.Equal(t, "dns", (<-received).Protocol, "Could not deliver the interaction received before")
+	server.interact(URL, "http")
+	interaction := <-received
+	require.Equal(t, "http", interaction.Protocol, "Could not deliver the interaction received after")
+	require.Equal(t, "http request", interaction.Values()["interactsh_request"], "Could not expose the raw request")
+
+	// the url is completed once the function returned true
+	server.interact(URL, "smtp")
+	require.Eventually(t, func() bool { return client.Interactions() == 4 }, time.Second, 10*time.Millisecond, "Could not poll the interactions")
+	require.Nil(t, client.Close(0), "Could not close the client")
+	require.Empty(t, received, "Could not stop delivering the interactions of a completed url")
+	require.True(t, server.deregistered, "Could not deregister the client")
+
+	var nilClient *Client
+	require.Empty(t, nilClient.URL(), "Could not ignore nil client")
+	nilClient.Await(URL, nil)
+	require.Nil(t, nilClient.Close(time.Second), "Could not ignore nil client")
+}
+
+func TestClientEviction(t *testing.T) {
+	server := newFakeServer(t, "")
+	defer server.Close()
+
+	client, err := New(Options{Server: server.URL, CacheSize: 1, PollInterval: time.Hour})
+	require.Nil(t, err, "Could not register the client")
+	defer client.Close(0)
+	evicted := client.URL()
+	URL := client.URL()
+	require.Equal(t, uint64(1), client.Evicted(), "Could not evict the oldest url")
+
+	server.interact(evicted, "dns")
+	server.interact(URL, "dns")
+	require.Nil(t, client.poll(), "Could not poll the interactions")
+	require.Equal(t, uint64(1), client.Uncorrelated(), "Could not forget the evicted url")
+
+	var received int
+	client.Await(evicted, func(*Interaction) bool { received++; return true })
+	client.Await(URL, func(*Interaction) bool { received++; return true })
+	require.Equal(t, 1, received, "Could not deliver the interactions of the cached url only")
+
+	_, err = New(Options{Server: "ftp://example.com"})
+	require.NotNil(t, err, "Could not refuse invalid server")
+}
@@ -0,0 +1,257 @@
+package interactsh
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net"
+	"net/http"
+	"net/http/httputil"
+	"os"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/miekg/dns"
+)
+
+// ListenerOptions are the settings of a client receiving the interactions
+// with its own http and dns listeners, without an interactsh server.
+type ListenerOptions struct {
+	// Address is the ip and the port of the http listener, the ip being
+	// the one the targets reach it at.
+	Address string
+	// Domain is the domain delegated to the dns listener if any, the urls
+	// being its subdomains. Without a domain the urls are the address of
+	// the http listener, the unique id being their path.
+	Domain string
+	// DNSAddress is the address of the dns listener of the domain, port 53
+	// of the ip of the http listener by default.
+	DNSAddress string
+	// Log is the file the interactions received are appended to as json
+	// lines if any, the ones not correlated to a url included.
+	Log string
+	// CacheSize is the number of urls correlated at once, the oldest ones
+	// being forgotten first.
+	CacheSize int
+}
+
+// listener serves the http and dns requests of the targets, the
+// interactions with the urls of a client.
+type listener struct {
+	ip     net.IP
+	port   string
+	domain string
+	// host is the host of the urls, the domain or the address
+	host string
+
+	http *http.Server
+	dns  *dns.Server
+
+	// mutex serializes the interactions delivered and logged
+	mutex   sync.Mutex
+	log     *os.File
+	deliver func(interaction *Interaction)
+}
+
+// Listen creates a client receiving the interactions with the urls it
+// hands out with its own http and dns listeners, which answer the requests
+// of the targets with the ip of the http listener.
+func Listen(options ListenerOptions) (*Client, error) {
+	host, _, err := net.SplitHostPort(options.Address)
+	if err != nil {
+		return nil, fmt.Errorf("invalid listen address %s: %s", options.Address, err)
+	}
+	ip := net.ParseIP(host)
+	if ip == nil || ip.IsUnspecified() {
+		return nil, fmt.Errorf("invalid listen address %s, it should be the ip the targets reach the listener at", options.Address)
+	}
+	c, err := newClient(options.CacheSize)
+	if err != nil {
+		return nil, err
+	}
+	l := &listener{ip: ip, domain: strings.ToLower(strings.TrimSuffix(options.Domain, ".")), deliver: c.deliver}
+
+	if options.Log != "" {
+		if l.log, err = os.OpenFile(options.Log, os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0644); err != nil {
+			return nil, err
+		}
+	}
+	httpListener, err := net.Listen("tcp", options.Address)
+	if err != nil {
+		l.close()
+		return nil, err
+	}
+	// the port of the urls is the one listened on, chosen if 0
+	_, l.port, _ = net.SplitHostPort(httpListener.Addr().String())
+	l.host = l.domain
+	if l.host == "" {
+		l.host = net.JoinHostPort(host, l.port)
+	}
+	l.http = &http.Server{Handler: http.HandlerFunc(l.serveHTTP), ReadHeaderTimeout: 10 * time.Second}
+	go l.http.Serve(httpListener)
+
+	if l.domain != "" {
+		address := options.DNSAddress
+		if address == "" {
+			address = net.JoinHostPort(host, "53")
+		}
+		conn, err := net.ListenPacket("udp", address)
+		if err != nil {
+			l.close()
+			return nil, err
+		}
+		// the server is shut down once started only
+		started := make(chan struct{})
+		l.dns = &dns.Server{PacketConn: conn, Handler: dns.HandlerFunc(l.serveDNS), NotifyStartedFunc: func() { close(started) }}
+		go l.dns.ActivateAndServe()
+		<-started
+	}
+	c.listener = l
+	return c, nil
+}
+
+// url returns the url of a unique id, a subdomain of the domain or the
+// path of the address without a domain, the port being kept unless 80.
+func (l *listener) url(uniqueID string) string {
+	address := l.ip.String()
+	if l.domain != "" {
+		address = uniqueID + "." + l.domain
+	} else if l.ip.To4() == nil {
+		address = "[" + address + "]"
+	}
+	if l.port != "80" {
+		address += ":" + l.port
+	}
+	if l.domain != "" {
+		return address
+	}
+	return address + "/" + uniqueID
+}
+
+// serveHTTP answers an http request with the reversed unique id of its
+// url, as the interactsh servers do, receiving its interaction.
+func (l *listener) serveHTTP(w http.ResponseWriter, r *http.Request) {
+	raw, _ := httputil.DumpRequest(r, true)
+	uniqueID := findID(r.Host)
+	if uniqueID == "" {
+		uniqueID = findID(r.URL.Path)
+	}
+	body := "<html><head></head><body>" + reverse(uniqueID) + "</body></html>"
+	w.Header().Set("Content-Type", "text/html; charset=utf-8")
+	w.Write([]byte(body))
+
+	l.receive(&Interaction{
+		Protocol:      "http",
+		UniqueID:      uniqueID,
+		FullID:        strings.ToLower(r.Host + r.URL.Path),
+		RawRequest:    string(raw),
+		RawResponse:   "HTTP/1.1 200 OK\r\nContent-Type: text/html; charset=utf-8\r\n\r\n" + body,
+		RemoteAddress: remoteHost(r.RemoteAddr),
+		Timestamp:     time.Now().UTC(),
+	})
+}
+
+// serveDNS answers the queries of the names of the domain with the ip of
+// the http listener, receiving their interactions. The other names are
+// refused.
+func (l *listener) serveDNS(w dns.ResponseWriter, req *dns.Msg) {
+	reply := &dns.Msg{}
+	reply.SetReply(req)
+	reply.Authoritative = true
+	if len(req.Question) == 0 {
+		w.WriteMsg(reply)
+		return
+	}
+	question := req.Question[0]
+	name := strings.ToLower(strings.TrimSuffix(question.Name, "."))
+	if name != l.domain && !strings.HasSuffix(name, "."+l.domain) {
+		reply.Rcode = dns.RcodeRefused
+	} else if ip := l.ip.To4(); ip != nil && (question.Qtype == dns.TypeA || question.Qtype == dns.TypeANY) {
+		reply.Answer = append(reply.Answer, &dns.A{Hdr: dns.RR_Header{Name: question.Name, Rrtype: dns.TypeA, Class: dns.ClassINET, Ttl: 60}, A: ip})
+	} else if ip == nil && (question.Qtype == dns.TypeAAAA || question.Qtype == dns.TypeANY) {
+		reply.Answer = append(reply.Answer, &dns.AAAA{Hdr: dns.RR_Header{Name: question.Name, Rrtype: dns.TypeAAAA, Class: dns.ClassINET, Ttl: 60}, AAAA: l.ip})
+	}
+	w.WriteMsg(reply)
+
+	l.receive(&Interaction{
+		Protocol:      "dns",
+		UniqueID:      findID(name),
+		FullID:        name,
+		QType:         dns.TypeToString[question.Qtype],
+		RawRequest:    req.String(),
+		RawResponse:   reply.String(),
+		RemoteAddress: remoteHost(w.RemoteAddr().String()),
+		Timestamp:     time.Now().UTC(),
+	})
+}
+
+// receive logs an interaction and delivers it to the client, one at a time
+// as the interactions polled.
+func (l *listener) receive(interaction *Interaction) {
+	l.mutex.Lock()
+	defer l.mutex.Unlock()
+	if l.log != nil {
+		if data, err := json.Marshal(interaction); err == nil {
+			l.log.Write(append(data, '\n'))
+		}
+	}
+	l.deliver(interaction)
+}
+
+// close shuts down the listeners and closes the log
+func (l *listener) close() error {
+	var errs []string
+	if l.http != nil {
+		if err := l.http.Close(); err != nil {
+			errs = append(errs, err.Error())
+		}
+	}
+	if l.dns != nil {
+		if err := l.dns.Shutdown(); err != nil {
+			errs = append(errs, err.Error())
+		}
+	}
+	l.mutex.Lock()
+	defer l.mutex.Unlock()
+	if l.log != nil {
+		if err := l.log.Close(); err != nil {
+			errs = append(errs, err.Error())
+		}
+		l.log = nil
+	}
+	if len(errs) > 0 {
+		return errors.New(strings.Join(errs, ", "))
+	}
+	return nil
+}
+
+// findID returns the first label or path segment of a host or a path with
+// the length of the unique ids, empty if none.
+func findID(value string) string {
+	for _, part := range strings.FieldsFunc(strings.ToLower(value), func(r rune) bool {
+		return r == '.' || r == '/' || r == ':'
+	}) {
+		if len(part) == uniqueIDLength {
+			return part
+		}
+	}
+	return ""
+}
+
+// remoteHost returns the host of a remote address
+func remoteHost(address string) string {
+	if host, _, err := net.SplitHostPort(address); err == nil {
+		return host
+	}
+	return address
+}
+
+// reverse returns a string reversed
+func reverse(value string) string {
+	reversed := []byte(value)
+	for i, j := 0, len(reversed)-1; i < j; i, j = i+1, j-1 {
+		reversed[i], reversed[j] = reversed[j], reversed[i]
+	}
+	return string(reversed)
+}
@@ -0,0 +1,119 @@
+package interactsh
+
+import (
+	"bufio"
+	"io/ioutil"
+	"net"
+	"net/http"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/miekg/dns"
+	"github.com/stretchr/testify/require"
+)
+
+// awaitInteractions returns the channel of the interactions of a url
+func awaitInteractions(c *Client, URL string) chan *Interaction {
+	interactions := make(chan *Interaction, 10)
+	c.Await(URL, func(interaction *Interaction) bool {
+		interactions <- interaction
+		return false
+	})
+	return interactions
+}
+
+// receiveInteraction returns the next interaction of a channel
+func receiveInteraction(t *testing.T, interactions chan *Interaction) *Interaction {
+	select {
+	case interaction := <-interactions:
+		return interaction
+	case <-time.After(5 * time.Second):
+		require.Fail(t, "Could not receive the interaction")
+		return nil
+	}
+}
+
+func TestListenerDomain(t *testing.T) {
+	dir, err := ioutil.TempDir("", "nuclei-listener")
+	require.Nil(t, err, "Could not create directory")
+	defer os.RemoveAll(dir)
+	log := filepath.Join(dir, "interactions.jsonl")
+
+	c, err := Listen(ListenerOptions{Address: "127.0.0.1:0", Domain: "OOB.example.", DNSAddress: "127.0.0.1:0", Log: log})
+	require.Nil(t, err, "Could not listen")
+	require.True(t, c.Listening(), "Could not listen for the interactions")
+	require.Equal(t, "oob.example", c.Server(), "Could not use the domain for the urls")
+
+	URL := c.URL()
+	require.Regexp(t, regexp.MustCompile(`^[a-z0-9]{33}\.oob\.example:[0-9]+$`), URL, "Could not hand out a subdomain")
+	interactions := awaitInteractions(c, URL)
+
+	host, port, _ := net.SplitHostPort(URL)
+	req, err := http.NewRequest(http.MethodGet, "http://127.0.0.1:"+port+"/callback", nil)
+	require.Nil(t, err, "Could not create request")
+	req.Host = host
+	resp, err := http.DefaultClient.Do(req)
+	require.Nil(t, err, "Could not send the http callback")
+	body, _ := ioutil.ReadAll(resp.Body)
+	resp.Body.Close()
+	require.Contains(t, string(body), reverse(urlID(URL)), "Could not reflect the reversed id")
+	interaction := receiveInteraction(t, interactions)
+	require.Equal(t, "http", interaction.Protocol, "Could not receive the http interaction")
+	require.Equal(t, urlID(URL), interaction.UniqueID, "Could not correlate the http interaction")
+	require.Contains(t, interaction.RawRequest, "GET /callback", "Could not record the http request")
+
+	query := &dns.Msg{}
+	query.SetQuestion(dns.Fqdn(strings.ToUpper(host)), dns.TypeA)
+	reply, err := dns.Exchange(query, c.listener.dns.PacketConn.LocalAddr().String())
+	require.Nil(t, err, "Could not send the dns callback")
+	require.Len(t, reply.Answer, 1, "Could not answer the query")
+	require.Equal(t, "127.0.0.1", reply.Answer[0].(*dns.A).A.String(), "Could not answer with the ip of the listener")
+	interaction = receiveInteraction(t, interactions)
+	require.Equal(t, "dns", interaction.Protocol, "Could not receive the dns interaction")
+	require.Equal(t, "A", interaction.QType, "Could not record the type of the query")
+
+	query.SetQuestion("other.example.", dns.TypeA)
+	reply, err = dns.Exchange(query, c.listener.dns.PacketConn.LocalAddr().String())
+	require.Nil(t, err, "Could not send the query of another domain")
+	require.Equal(t, dns.RcodeRefused, reply.Rcode, "Could answer the query of another domain")
+
+	require.Nil(t, c.Close(0), "Could not close the listener")
+	require.Equal(t, uint64(3), c.Interactions(), "Could not count the interactions")
+	require.Equal(t, uint64(1), c.Uncorrelated(), "Could not count the uncorrelated interaction")
+	_, err = http.Get("http://127.0.0.1:" + port + "/")
+	require.NotNil(t, err, "Could reach a closed listener")
+
+	file, err := os.Open(log)
+	require.Nil(t, err, "Could not write the log")
+	defer file.Close()
+	lines := 0
+	for scanner := bufio.NewScanner(file); scanner.Scan(); {
+		lines++
+	}
+	require.Equal(t, 3, lines, "Could not log all the interactions")
+}
+
+func TestListenerAddress(t *testing.T) {
+	c, err := Listen(ListenerOptions{Address: "127.0.0.1:0"})
+	require.Nil(t, err, "Could not listen")
+	defer c.Close(0)
+
+	URL := c.URL()
+	require.Regexp(t, regexp.MustCompile(`^127\.0\.0\.1:[0-9]+/[a-z0-9]{33}$`), URL, "Could not hand out a path of the address")
+	require.Equal(t, strings.SplitN(URL, "/", 2)[0], c.Server(), "Could not use the address for the urls")
+	interactions := awaitInteractions(c, URL)
+
+	resp, err := http.Get("http://" + URL + "/path?a=1")
+	require.Nil(t, err, "Could not send the http callback")
+	resp.Body.Close()
+	interaction := receiveInteraction(t, interactions)
+	require.Equal(t, "http", interaction.Protocol, "Could not receive the http interaction")
+	require.Equal(t, "127.0.0.1", interaction.RemoteAddress, "Could not record the remote address")
+
+	_, err = Listen(ListenerOptions{Address: "0.0.0.0:0"})
+	require.NotNil(t, err, "Could listen on an address the targets can't reach")
+}
@@ -0,0 +1,3 @@
+// Package jsonpath evaluates simple JSONPath expressions against decoded
+// JSON documents, shared by the json matchers and extractors.
+package jsonpath
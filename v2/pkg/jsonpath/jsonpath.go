@@ -0,0 +1,182 @@
+package jsonpath
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// wildcard selects all the elements of an array or object
+const wildcard = "*"
+
+// Path is a compiled JSONPath expression.
+//
+// The supported syntax is a subset of JSONPath with an optional leading $,
+// dot separated keys, bracketed indexes or quoted keys and * wildcards, i.e
+// $.data.users[0].role, data.users[*].name or data["user-agent"].
+type Path struct {
+	expression string
+	steps      []string
+}
+
+// Compile compiles a JSONPath expression
+func Compile(expression string) (*Path, error) {
+	path := &Path{expression: expression}
+
+	rest := strings.TrimPrefix(strings.TrimSpace(expression), "$")
+	for rest != "" {
+		switch rest[0] {
+		case '.':
+			rest = rest[1:]
+			end := strings.IndexAny(rest, ".[")
+			if end == -1 {
+				end = len(rest)
+			}
+			if end == 0 {
+				return nil, fmt.Errorf("empty key in path: %s", expression)
+			}
+			path.steps = append(path.steps, rest[:end])
+			rest = rest[end:]
+		case '[':
+			end := strings.IndexByte(rest, ']')
+			if end == -1 {
+				return nil, fmt.Errorf("unterminated bracket in path: %s", expression)
+			}
+			step := rest[1:end]
+			if unquoted, err := strconv.Unquote(step); err == nil {
+				step = unquoted
+			} else if strings.HasPrefix(step, "'") && strings.HasSuffix(step, "'") && len(step) > 1 {
+				step = step[1 : len(step)-1]
+			}
+			path.steps = append(path.steps, step)
+			rest = rest[end+1:]
+		default:
+			// a path without the leading $. is accepted
+			if len(path.steps) > 0 {
+				return nil, fmt.Errorf("invalid path: %s", expression)
+			}
+			rest = "." + rest
+		}
+	}
+	return path, nil
+}
+
+// String returns the expression of the path
+func (p *Path) String() string {
+	return p.expression
+}
+
+// Evaluate returns the values selected by the path in a decoded document
+func (p *Path) Evaluate(document interface{}) []interface{} {
+	values := []interface{}{document}
+	for _, step := range p.steps {
+		var next []interface{}
+		for _, value := range values {
+			next = append(next, selectStep(value, step)...)
+		}
+		if len(next) == 0 {
+			return nil
+		}
+		values = next
+	}
+	return values
+}
+
+// selectStep returns the values selected by a single step of a path
+func selectStep(value interface{}, step string) []interface{} {
+	switch node := value.(type) {
+	case map[string]interface{}:
+		if step == wildcard {
+			values := make([]interface{}, 0, len(node))
+			for _, child := range node {
+				values = append(values, child)
+			}
+			return values
+		}
+		if child, ok := node[step]; ok {
+			return []interface{}{child}
+		}
+	case []interface{}:
+		if step == wildcard {
+			return node
+		}
+		index, err := strconv.Atoi(step)
+		if err != nil {
+			return nil
+		}
+		// negative indexes count from the end of the array
+		if index < 0 {
+			index += len(node)
+		}
+		if index >= 0 && index < len(node) {
+			return []interface{}{node[index]}
+		}
+	}
+	return nil
+}
+
+// Parse decodes a JSON document, keeping the numbers in their textual form
+func Parse(data string) (interface{}, error) {
+	var document interface{}
+
+	decoder := json.NewDecoder(strings.NewReader(data))
+	decoder.UseNumber()
+	if err := decoder.Decode(&document); err != nil {
+		return nil, err
+	}
+	return document, nil
+}
+
+// Strings returns the textual form of the values selected by a path.
+//
+// Numbers keep their original form, booleans are true or false, null values
+// are skipped and objects are encoded as JSON. The elements of arrays are
+// returned along with the array itself so that any element can be compared.
+func Strings(values []interface{}) []string {
+	var results []string
+	for _, value := range values {
+		switch v := value.(type) {
+		case nil:
+			continue
+		case string:
+			results = append(results, v)
+		case json.Number:
+			results = append(results, v.String())
+		case bool:
+			results = append(results, strconv.FormatBool(v))
+		case []interface{}:
+			results = append(results, encode(v))
+			results = append(results, Strings(v)...)
+		default:
+			results = append(results, encode(v))
+		}
+	}
+	return results
+}
+
+// Values returns the textual form of the values selected by a path with
+// the arrays flattened into their elements, like Strings without the arrays.
+func Values(values []interface{}) []string {
+	var results []string
+	for _, value := range values {
+		if array, ok := value.([]interface{}); ok {
+			results = append(results, Values(array)...)
+			continue
+		}
+		results = append(results, Strings([]interface{}{value})...)
+	}
+	return results
+}
+
+// encode returns the JSON encoding of a value without html escaping
+func encode(value interface{}) string {
+	buffer := &bytes.Buffer{}
+	encoder := json.NewEncoder(buffer)
+	encoder.SetEscapeHTML(false)
+	if err := encoder.Encode(value); err != nil {
+		return ""
+	}
+	return strings.TrimSuffix(buffer.String(), "\n")
+}
@@ -0,0 +1,46 @@
+package jsonpath
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestEvaluate(t *testing.T) {
+	document, err := Parse(`{"data": {"user": {"role": "admin", "id": 1337, "active": true}, "groups": [{"name": "a"}, {"name": "b"}], "user-agent": "x"}}`)
+	require.Nil(t, err, "Could not parse document")
+
+	for expression, expected := range map[string][]string{
+		"$.data.user.role":     {"admin"},
+		"data.user.id":         {"1337"},
+		"data.user.active":     {"true"},
+		"data.groups[*].name":  {"a", "b"},
+		"data.groups[-1].name": {"b"},
+		`data["user-agent"]`:   {"x"},
+		"data.missing":         nil,
+	} {
+		path, err := Compile(expression)
+		require.Nil(t, err, "Could not compile path %s", expression)
+		require.Equal(t, expected, Strings(path.Evaluate(document)), "Could not evaluate path %s", expression)
+	}
+
+	path, err := Compile("data.groups")
+	require.Nil(t, err, "Could not compile path")
+	require.Equal(t, []string{`[{"name":"a"},{"name":"b"}]`, `{"name":"a"}`, `{"name":"b"}`}, Strings(path.Evaluate(document)), "Could not evaluate array path")
+
+	require.Equal(t, []string{`{"name":"a"}`, `{"name":"b"}`}, Values(path.Evaluate(document)), "Could not flatten array path")
+
+	document, err = Parse(`{"items": [{"id": 1, "tags": ["x", ["y"]]}, {"id": 2.5, "tags": null}]}`)
+	require.Nil(t, err, "Could not parse document")
+	path, err = Compile("$.items[*].id")
+	require.Nil(t, err, "Could not compile path")
+	require.Equal(t, []string{"1", "2.5"}, Values(path.Evaluate(document)), "Could not stringify numbers")
+	path, err = Compile("$.items[*].tags")
+	require.Nil(t, err, "Could not compile path")
+	require.Equal(t, []string{"x", "y"}, Values(path.Evaluate(document)), "Could not flatten nested arrays")
+
+	for _, invalid := range []string{"data..user", "data[0"} {
+		_, err = Compile(invalid)
+		require.NotNil(t, err, "Could compile invalid path %s", invalid)
+	}
+}
@@ -0,0 +1,237 @@
+package kafka
+
+import (
+	"crypto/tls"
+	"crypto/x509"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"net"
+	"strings"
+	"time"
+
+	kafkago "github.com/segmentio/kafka-go"
+	"github.com/segmentio/kafka-go/sasl"
+	"github.com/segmentio/kafka-go/sasl/plain"
+	"github.com/segmentio/kafka-go/sasl/scram"
+	"gopkg.in/yaml.v2"
+)
+
+// The sasl mechanisms of the brokers
+const (
+	// PlainMechanism sends the username and password as is, to use over tls
+	PlainMechanism = "plain"
+	// ScramSHA256Mechanism and ScramSHA512Mechanism prove the password
+	// without sending it.
+	ScramSHA256Mechanism = "scram-sha-256"
+	ScramSHA512Mechanism = "scram-sha-512"
+)
+
+// Config is the configuration of the exporter, read from a yaml file
+type Config struct {
+	// Brokers are the host:port addresses of the brokers the metadata of
+	// the topic is asked to, the first one reachable being used.
+	Brokers []string `yaml:"brokers"`
+	// Topic is the topic the results are published to
+	Topic string `yaml:"topic"`
+	// Key is the field of the json results used as the key of the records,
+	// host by default, the records of a key being published in order to the
+	// same partition. The nested fields are separated by dots, i.e
+	// info.severity, and the results without the field are spread over the
+	// partitions.
+	Key string `yaml:"key,omitempty"`
+	// ClientID is the client id of the requests, nuclei by default
+	ClientID string `yaml:"client-id,omitempty"`
+	// Acks are the acknowledgements waited for, all (default) for the
+	// in-sync replicas or leader for the leader of the partition only.
+	Acks string `yaml:"acks,omitempty"`
+	// TLS connects to the brokers over tls, the ca, cert and key being the
+	// certificate of the authority of the brokers and the client
+	// certificate and key files, if any.
+	TLS        bool   `yaml:"tls,omitempty"`
+	CA         string `yaml:"ca,omitempty"`
+	Cert       string `yaml:"cert,omitempty"`
+	CertKey    string `yaml:"cert-key,omitempty"`
+	SkipVerify bool   `yaml:"skip-verify,omitempty"`
+	// SASL is the sasl mechanism authenticating the username and password,
+	// plain, scram-sha-256 or scram-sha-512, none by default.
+	SASL     string `yaml:"sasl,omitempty"`
+	Username string `yaml:"username,omitempty"`
+	Password string `yaml:"password,omitempty"`
+	// Timeout is the timeout of the connections and the requests, the
+	// brokers waiting for the acknowledgements as long, 10s by default.
+	Timeout time.Duration `yaml:"timeout,omitempty"`
+	// BatchSize is the number of results of a produce request, 100 by default
+	BatchSize int `yaml:"batch-size,omitempty"`
+	// BatchInterval is the longest time before publishing the pending
+	// results, 1s by default.
+	BatchInterval time.Duration `yaml:"batch-interval,omitempty"`
+	// QueueSize is the number of results waiting to be published beyond
+	// which the results are appended to the dead letter file, 10000 by
+	// default.
+	QueueSize int `yaml:"queue-size,omitempty"`
+	// QueueWait is the longest time a result waits for room in the full
+	// queue before being appended to the dead letter file, none by default.
+	QueueWait time.Duration `yaml:"queue-wait,omitempty"`
+	// Retries is the number of retries of the results failing with a
+	// network error or a retriable error of the brokers, 3 by default.
+	Retries *int `yaml:"retries,omitempty"`
+	// IncludeRR adds the raw requests and responses to the results, which
+	// may go beyond the largest records accepted by the brokers, 1MB by
+	// default.
+	IncludeRR bool `yaml:"include-rr,omitempty"`
+	// DeadLetter is the file the results failing to be published after
+	// the retries and the ones overflowing the queue are appended to,
+	// kafka-failed.jsonl by default.
+	DeadLetter string `yaml:"dead-letter,omitempty"`
+
+	// keyPath is the path of the key in the json results
+	keyPath   []interface{}
+	tls       *tls.Config
+	mechanism sasl.Mechanism
+}
+
+// LoadConfig reads and validates the configuration of a yaml file
+func LoadConfig(file string) (*Config, error) {
+	data, err := ioutil.ReadFile(file)
+	if err != nil {
+		return nil, fmt.Errorf("could not read kafka config: %s", err)
+	}
+	config := &Config{}
+	if err := yaml.UnmarshalStrict(data, config); err != nil {
+		return nil, fmt.Errorf("could not parse kafka config %s: %s", file, err)
+	}
+	if err := config.validate(); err != nil {
+		return nil, fmt.Errorf("invalid kafka config %s: %s", file, err)
+	}
+	return config, nil
+}
+
+// validate validates the configuration, setting the defaults
+func (c *Config) validate() error {
+	if len(c.Brokers) == 0 {
+		return errors.New("no brokers given")
+	}
+	for _, broker := range c.Brokers {
+		if host, port, err := net.SplitHostPort(broker); err != nil || host == "" || port == "" {
+			return fmt.Errorf("invalid broker %s, it should be like localhost:9092", broker)
+		}
+	}
+	if c.Topic == "" {
+		return errors.New("no topic given")
+	}
+	if c.Key == "" {
+		c.Key = "host"
+	}
+	for _, field := range strings.Split(c.Key, ".") {
+		c.keyPath = append(c.keyPath, field)
+	}
+	if c.ClientID == "" {
+		c.ClientID = "nuclei"
+	}
+	switch c.Acks {
+	case "":
+		c.Acks = "all"
+	case "all", "leader":
+	default:
+		return fmt.Errorf("invalid acks %s, it should be all or leader", c.Acks)
+	}
+
+	switch c.SASL = strings.ToLower(c.SASL); c.SASL {
+	case "":
+		if c.Username != "" || c.Password != "" {
+			return errors.New("a username or password is given without sasl")
+		}
+	case PlainMechanism, ScramSHA256Mechanism, ScramSHA512Mechanism:
+		if c.Username == "" {
+			return fmt.Errorf("no username given for sasl %s", c.SASL)
+		}
+		mechanism, err := c.saslMechanism()
+		if err != nil {
+			return err
+		}
+		c.mechanism = mechanism
+	default:
+		return fmt.Errorf("invalid sasl %s, it should be plain, scram-sha-256 or scram-sha-512", c.SASL)
+	}
+	if c.TLS {
+		config, err := c.tlsConfig()
+		if err != nil {
+			return err
+		}
+		c.tls = config
+	} else if c.CA != "" || c.Cert != "" || c.CertKey != "" || c.SkipVerify {
+		return errors.New("tls options given without tls")
+	}
+
+	if c.Timeout < 0 || c.BatchSize < 0 || c.BatchInterval < 0 || c.QueueSize < 0 || c.QueueWait < 0 || (c.Retries != nil && *c.Retries < 0) {
+		return errors.New("the timeout, batch size and interval, queue size and wait and retries should be 0 or more")
+	}
+	if c.Timeout == 0 {
+		c.Timeout = 10 * time.Second
+	}
+	if c.BatchSize == 0 {
+		c.BatchSize = 100
+	}
+	if c.BatchInterval == 0 {
+		c.BatchInterval = time.Second
+	}
+	if c.QueueSize == 0 {
+		c.QueueSize = 10000
+	}
+	if c.Retries == nil {
+		retries := 3
+		c.Retries = &retries
+	}
+	if c.DeadLetter == "" {
+		c.DeadLetter = "kafka-failed.jsonl"
+	}
+	return nil
+}
+
+// tlsConfig returns the tls config of the connections to the brokers, their
+// server name being set when dialing each of them.
+func (c *Config) tlsConfig() (*tls.Config, error) {
+	config := &tls.Config{InsecureSkipVerify: c.SkipVerify}
+	if c.CA != "" {
+		data, err := ioutil.ReadFile(c.CA)
+		if err != nil {
+			return nil, fmt.Errorf("could not read ca: %s", err)
+		}
+		config.RootCAs = x509.NewCertPool()
+		if !config.RootCAs.AppendCertsFromPEM(data) {
+			return nil, fmt.Errorf("no certificates found in ca %s", c.CA)
+		}
+	}
+	if (c.Cert == "") != (c.CertKey == "") {
+		return nil, errors.New("both the client certificate and key should be given")
+	}
+	if c.Cert != "" {
+		certificate, err := tls.LoadX509KeyPair(c.Cert, c.CertKey)
+		if err != nil {
+			return nil, fmt.Errorf("could not load client certificate: %s", err)
+		}
+		config.Certificates = []tls.Certificate{certificate}
+	}
+	return config, nil
+}
+
+// saslMechanism returns the sasl mechanism authenticating the connections
+func (c *Config) saslMechanism() (sasl.Mechanism, error) {
+	switch c.SASL {
+	case ScramSHA256Mechanism:
+		return scram.Mechanism(scram.SHA256, c.Username, c.Password)
+	case ScramSHA512Mechanism:
+		return scram.Mechanism(scram.SHA512, c.Username, c.Password)
+	default:
+		return plain.Mechanism{Username: c.Username, Password: c.Password}, nil
+	}
+}
+
+// acks returns the acknowledgements of the produce requests
+func (c *Config) acks() kafkago.RequiredAcks {
+	if c.Acks == "leader" {
+		return kafkago.RequireOne
+	}
+	return kafkago.RequireAll
+}
@@ -0,0 +1,6 @@
+// Package kafka publishes the results of a scan to a Kafka topic, each
+// record being a json result keyed by its host, so that the results of a
+// target keep their order in its partition. The records are published by
+// the writer of segmentio/kafka-go, over tls and with the sasl plain and
+// scram authentications.
+package kafka
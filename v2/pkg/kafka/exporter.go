@@ -0,0 +1,220 @@
+package kafka
+
+import (
+	"bytes"
+	"context"
+	"os"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	jsoniter "github.com/json-iterator/go"
+	"github.com/projectdiscovery/gologger"
+	"github.com/projectdiscovery/nuclei/v2/pkg/export"
+	kafkago "github.com/segmentio/kafka-go"
+)
+
+// Exporter publishes the results of a scan in batches from a bounded queue,
+// so that the scan is never blocked by the brokers.
+//
+// The results overflowing the queue once full are appended to the dead
+// letter file and counted, waiting for room in the queue first if the
+// config gives a wait. The records failing with a retriable error of the
+// brokers are retried by the writer, the batches being retried with an
+// exponential backoff when the brokers can't be reached, and the ones
+// still failing are appended to the dead letter file. The results of a key
+// are published in order to the partition of the key, a batch being
+// published once the previous one is.
+type Exporter struct {
+	config    *Config
+	queue     *export.Queue
+	writer    *kafkago.Writer
+	transport *kafkago.Transport
+	// backoff is the delay before the first retry, doubled for each retry
+	backoff time.Duration
+
+	published uint64
+	failed    uint64
+	// deadLetterMutex serializes the writes of the worker and of the
+	// results overflowing the queue to the dead letter file.
+	deadLetterMutex sync.Mutex
+}
+
+// New creates an exporter publishing the results to the topic of a config
+func New(config *Config) *Exporter {
+	e := &Exporter{
+		config:  config,
+		backoff: time.Second,
+		transport: &kafkago.Transport{
+			DialTimeout: config.Timeout,
+			ClientID:    config.ClientID,
+			TLS:         config.tls,
+			SASL:        config.mechanism,
+		},
+	}
+	e.writer = &kafkago.Writer{
+		Addr:  kafkago.TCP(config.Brokers...),
+		Topic: config.Topic,
+		// the partitions of the keys are the ones of the java client
+		Balancer:    &kafkago.Murmur2Balancer{},
+		MaxAttempts: *config.Retries + 1,
+		BatchSize:   config.BatchSize,
+		// the results are batched by the queue, the writer sending them
+		// at once
+		BatchTimeout: time.Millisecond,
+		ReadTimeout:  config.Timeout,
+		WriteTimeout: config.Timeout,
+		RequiredAcks: config.acks(),
+		Transport:    e.transport,
+	}
+	e.queue = export.NewQueue(config.QueueSize, config.BatchSize, config.BatchInterval, e.send)
+	e.queue.SetOverflow(config.QueueWait, e.overflow)
+	return e
+}
+
+// IncludeRR returns true if the results include the raw requests and responses
+func (e *Exporter) IncludeRR() bool {
+	return e.config.IncludeRR
+}
+
+// Export queues a json result to publish, appending it to the dead letter
+// file if the queue is still full after the wait of the config.
+func (e *Exporter) Export(data []byte) {
+	e.queue.Push(data)
+}
+
+// overflow appends a result overflowing the queue to the dead letter file
+func (e *Exporter) overflow(data []byte) {
+	e.appendDeadLetter([]kafkago.Message{{Value: data}})
+}
+
+// Close publishes the queued results, waiting for the retries to complete,
+// and closes the connections to the brokers.
+func (e *Exporter) Close() {
+	e.queue.Close()
+	e.writer.Close()
+	e.transport.CloseIdleConnections()
+}
+
+// Published returns the number of results published to the topic
+func (e *Exporter) Published() uint64 {
+	return atomic.LoadUint64(&e.published)
+}
+
+// Queued returns the number of results waiting in the queue
+func (e *Exporter) Queued() int {
+	return e.queue.Len()
+}
+
+// Dropped returns the number of results written to the dead letter file
+// as the queue was full
+func (e *Exporter) Dropped() uint64 {
+	return e.queue.Dropped()
+}
+
+// Failed returns the number of results written to the dead letter file
+// as they failed to be published
+func (e *Exporter) Failed() uint64 {
+	return atomic.LoadUint64(&e.failed)
+}
+
+// DeadLetter returns the name of the dead letter file
+func (e *Exporter) DeadLetter() string {
+	return e.config.DeadLetter
+}
+
+// Topic returns the topic the results are published to
+func (e *Exporter) Topic() string {
+	return e.config.Topic
+}
+
+// send publishes a batch, the results larger than the batches of the
+// writer and the ones failing after the retries of the writer being
+// appended to the dead letter file.
+func (e *Exporter) send(batch [][]byte) {
+	pending := make([]kafkago.Message, 0, len(batch))
+	for _, data := range batch {
+		pending = append(pending, kafkago.Message{Key: e.key(data), Value: data})
+	}
+	var failed []kafkago.Message
+	export.Retry(*e.config.Retries, e.backoff, func() (bool, error) {
+		err := e.writer.WriteMessages(context.Background(), pending...)
+		// the writer refuses the whole batch for a result too large
+		for {
+			tooLarge, ok := err.(kafkago.MessageTooLargeError)
+			if !ok {
+				break
+			}
+			failed = append(failed, tooLarge.Message)
+			if pending = tooLarge.Remaining; len(pending) == 0 {
+				return false, nil
+			}
+			err = e.writer.WriteMessages(context.Background(), pending...)
+		}
+		switch errs := err.(type) {
+		case nil:
+			atomic.AddUint64(&e.published, uint64(len(pending)))
+			pending = nil
+			return false, nil
+		case kafkago.WriteErrors:
+			// the records of the partitions were already retried
+			for i, err := range errs {
+				if err != nil {
+					failed = append(failed, pending[i])
+					continue
+				}
+				atomic.AddUint64(&e.published, 1)
+			}
+			gologger.Warningf("Could not publish %d results to kafka: %s\n", errs.Count(), err)
+			pending = nil
+			return false, err
+		default:
+			// the metadata of the topic could not be fetched
+			gologger.Warningf("Could not publish %d results to kafka: %s\n", len(pending), err)
+			return true, err
+		}
+	})
+	e.writeDeadLetter(append(failed, pending...))
+}
+
+// key returns the key of the record of a json result, nil without its field
+func (e *Exporter) key(data []byte) []byte {
+	value := jsoniter.Get(data, e.config.keyPath...)
+	if value.LastError() != nil {
+		return nil
+	}
+	if key := value.ToString(); key != "" {
+		return []byte(key)
+	}
+	return nil
+}
+
+// writeDeadLetter appends the results failing to be published to the dead
+// letter file, one per line.
+func (e *Exporter) writeDeadLetter(messages []kafkago.Message) {
+	if len(messages) == 0 {
+		return
+	}
+	atomic.AddUint64(&e.failed, uint64(len(messages)))
+	e.appendDeadLetter(messages)
+}
+
+// appendDeadLetter appends the values of messages to the dead letter file
+func (e *Exporter) appendDeadLetter(messages []kafkago.Message) {
+	e.deadLetterMutex.Lock()
+	defer e.deadLetterMutex.Unlock()
+	file, err := os.OpenFile(e.config.DeadLetter, os.O_WRONLY|os.O_CREATE|os.O_APPEND, 0644)
+	if err != nil {
+		gologger.Errorf("Could not write %d results to %s: %s\n", len(messages), e.config.DeadLetter, err)
+		return
+	}
+	defer file.Close()
+	buffer := &bytes.Buffer{}
+	for _, m := range messages {
+		buffer.Write(m.Value)
+		buffer.WriteRune('\n')
+	}
+	if _, err := file.Write(buffer.Bytes()); err != nil {
+		gologger.Errorf("Could not write %d results to %s: %s\n", len(messages), e.config.DeadLetter, err)
+	}
+}
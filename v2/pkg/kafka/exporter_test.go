@@ -0,0 +1,322 @@
+package kafka
+
+import (
+	"fmt"
+	"io/ioutil"
+	"net"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	jsoniter "github.com/json-iterator/go"
+	kafkago "github.com/segmentio/kafka-go"
+	"github.com/segmentio/kafka-go/protocol"
+	"github.com/segmentio/kafka-go/protocol/apiversions"
+	"github.com/segmentio/kafka-go/protocol/metadata"
+	"github.com/segmentio/kafka-go/protocol/produce"
+	"github.com/segmentio/kafka-go/protocol/saslauthenticate"
+	"github.com/segmentio/kafka-go/protocol/saslhandshake"
+	"github.com/stretchr/testify/require"
+)
+
+func writeConfig(t *testing.T, directory, content string) string {
+	file := filepath.Join(directory, "kafka.yaml")
+	require.Nil(t, ioutil.WriteFile(file, []byte(content), 0644), "Could not write config")
+	return file
+}
+
+func TestLoadConfig(t *testing.T) {
+	directory, err := ioutil.TempDir("", "kafka-*")
+	require.Nil(t, err, "Could not create temporary directory")
+	defer os.RemoveAll(directory)
+
+	config, err := LoadConfig(writeConfig(t, directory, "brokers: [localhost:9092]\ntopic: nuclei\nsasl: SCRAM-SHA-512\nusername: nuclei\nbatch-interval: 2s\n"))
+	require.Nil(t, err, "Could not load config")
+	require.Equal(t, ScramSHA512Mechanism, config.SASL, "Could not normalize the sasl mechanism")
+	require.Equal(t, 2*time.Second, config.BatchInterval, "Could not parse the batch interval")
+	require.Equal(t, []interface{}{"host"}, config.keyPath, "Could not set the default key")
+	require.Equal(t, kafkago.RequireAll, config.acks(), "Could not set the default acks")
+	require.Equal(t, 3, *config.Retries, "Could not set the default retries")
+
+	config, err = LoadConfig(writeConfig(t, directory, "brokers: [localhost:9092]\ntopic: nuclei\nkey: info.severity\nacks: leader\n"))
+	require.Nil(t, err, "Could not load config")
+	require.Equal(t, []interface{}{"info", "severity"}, config.keyPath, "Could not split the nested key")
+	require.Equal(t, kafkago.RequireOne, config.acks(), "Could not parse the acks")
+
+	for _, invalid := range []string{
+		"topic: nuclei\n",
+		"brokers: [localhost]\ntopic: nuclei\n",
+		"brokers: [localhost:9092]\n",
+		"brokers: [localhost:9092]\ntopic: nuclei\nsasl: gssapi\nusername: nuclei\n",
+		"brokers: [localhost:9092]\ntopic: nuclei\nsasl: plain\n",
+		"brokers: [localhost:9092]\ntopic: nuclei\nusername: nuclei\n",
+		"brokers: [localhost:9092]\ntopic: nuclei\nskip-verify: true\n",
+		"brokers: [localhost:9092]\ntopic: nuclei\nacks: none\n",
+		"brokers: [localhost:9092]\ntopic: nuclei\nbatch: 10\n",
+	} {
+		_, err = LoadConfig(writeConfig(t, directory, invalid))
+		require.NotNil(t, err, "Could not reject invalid config %q", invalid)
+	}
+}
+
+// fakeBroker is a broker of a topic with two partitions led by itself,
+// authenticating with sasl plain and answering the produce requests with
+// the error codes of produce.
+type fakeBroker struct {
+	listener net.Listener
+	port     int32
+
+	mutex sync.Mutex
+	// records are the values of the records published by partition and
+	// keys their keys, produce returning the error code of a partition.
+	records  map[int32][]string
+	keys     map[string]int32
+	requests int
+	produce  func(request int, partition int32, values []string) kafkago.Error
+}
+
+func newFakeBroker(t *testing.T) *fakeBroker {
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	require.Nil(t, err, "Could not listen")
+	b := &fakeBroker{
+		listener: listener,
+		port:     int32(listener.Addr().(*net.TCPAddr).Port),
+		records:  make(map[int32][]string),
+		keys:     make(map[string]int32),
+		produce:  func(int, int32, []string) kafkago.Error { return 0 },
+	}
+	go func() {
+		for {
+			conn, err := listener.Accept()
+			if err != nil {
+				return
+			}
+			go b.serve(conn)
+		}
+	}()
+	return b
+}
+
+// serve answers the requests of a connection
+func (b *fakeBroker) serve(conn net.Conn) {
+	defer conn.Close()
+	authenticated := false
+	for {
+		version, correlationID, _, request, err := protocol.ReadRequest(conn)
+		if err != nil {
+			return
+		}
+		var response protocol.Message
+		switch request := request.(type) {
+		case *apiversions.Request:
+			versions := &apiversions.Response{}
+			for _, key := range []protocol.ApiKey{protocol.ApiVersions, protocol.SaslHandshake, protocol.SaslAuthenticate, protocol.Metadata, protocol.Produce} {
+				versions.ApiKeys = append(versions.ApiKeys, apiversions.ApiKeyResponse{ApiKey: int16(key), MinVersion: key.MinVersion(), MaxVersion: key.MaxVersion()})
+			}
+			response = versions
+		case *saslhandshake.Request:
+			handshake := &saslhandshake.Response{Mechanisms: []string{"PLAIN"}}
+			if request.Mechanism != "PLAIN" {
+				handshake.ErrorCode = int16(kafkago.UnsupportedSASLMechanism)
+			}
+			response = handshake
+		case *saslauthenticate.Request:
+			if string(request.AuthBytes) != "\x00nuclei\x00secret" {
+				response = &saslauthenticate.Response{ErrorCode: int16(kafkago.SASLAuthenticationFailed), ErrorMessage: "invalid credentials"}
+				break
+			}
+			authenticated = true
+			response = &saslauthenticate.Response{}
+		case *metadata.Request:
+			if !authenticated {
+				return
+			}
+			topic := metadata.ResponseTopic{Name: "nuclei"}
+			for index := int32(0); index < 2; index++ {
+				topic.Partitions = append(topic.Partitions, metadata.ResponsePartition{PartitionIndex: index, LeaderID: 1, ReplicaNodes: []int32{1}, IsrNodes: []int32{1}})
+			}
+			response = &metadata.Response{
+				Brokers:      []metadata.ResponseBroker{{NodeID: 1, Host: "127.0.0.1", Port: b.port}},
+				ControllerID: 1,
+				Topics:       []metadata.ResponseTopic{topic},
+			}
+		case *produce.Request:
+			if !authenticated {
+				return
+			}
+			response = b.handleProduce(request)
+		default:
+			return
+		}
+		if err := protocol.WriteResponse(conn, version, correlationID, response); err != nil {
+			return
+		}
+	}
+}
+
+// handleProduce stores the records of a produce request
+func (b *fakeBroker) handleProduce(request *produce.Request) *produce.Response {
+	b.mutex.Lock()
+	defer b.mutex.Unlock()
+	b.requests++
+
+	response := &produce.Response{}
+	for _, topic := range request.Topics {
+		produced := produce.ResponseTopic{Topic: topic.Topic}
+		for _, partition := range topic.Partitions {
+			keys, values := readRecords(partition.RecordSet)
+			code := b.produce(b.requests, partition.Partition, values)
+			if code == 0 {
+				b.records[partition.Partition] = append(b.records[partition.Partition], values...)
+				for _, key := range keys {
+					b.keys[key] = partition.Partition
+				}
+			}
+			produced.Partitions = append(produced.Partitions, produce.ResponsePartition{Partition: partition.Partition, ErrorCode: int16(code), LogAppendTime: -1})
+		}
+		response.Topics = append(response.Topics, produced)
+	}
+	return response
+}
+
+// readRecords returns the keys and values of the records of a record set
+func readRecords(records protocol.RecordSet) ([]string, []string) {
+	var keys, values []string
+	if records.Records == nil {
+		return nil, nil
+	}
+	for {
+		record, err := records.Records.ReadRecord()
+		if err != nil {
+			return keys, values
+		}
+		if record.Key != nil {
+			key, _ := protocol.ReadAll(record.Key)
+			keys = append(keys, string(key))
+		}
+		value, _ := protocol.ReadAll(record.Value)
+		values = append(values, string(value))
+	}
+}
+
+func TestExporter(t *testing.T) {
+	directory, err := ioutil.TempDir("", "kafka-*")
+	require.Nil(t, err, "Could not create temporary directory")
+	defer os.RemoveAll(directory)
+
+	broker := newFakeBroker(t)
+	defer broker.listener.Close()
+	// the first request isn't led by the broker, the rejected results are
+	// too large
+	broker.produce = func(request int, partition int32, values []string) kafkago.Error {
+		if request == 1 {
+			return kafkago.NotLeaderForPartition
+		}
+		for _, value := range values {
+			if strings.Contains(value, "rejected") {
+				return kafkago.MessageSizeTooLarge
+			}
+		}
+		return 0
+	}
+
+	config, err := LoadConfig(writeConfig(t, directory, fmt.Sprintf("brokers: [127.0.0.1:1, %s]\ntopic: nuclei\nsasl: plain\nusername: nuclei\npassword: secret\nbatch-size: 10\nbatch-interval: 50ms\ndead-letter: %s\n", broker.listener.Addr(), filepath.Join(directory, "failed.jsonl"))))
+	require.Nil(t, err, "Could not load config")
+	exporter := New(config)
+	exporter.backoff = time.Millisecond
+
+	var hosts []string
+	for i := 0; i < 6; i++ {
+		host := "https://" + strconv.Itoa(i%3) + ".example.com"
+		hosts = append(hosts, host)
+		exporter.Export([]byte(fmt.Sprintf(`{"template":"%d","host":"%s"}`, i, host)))
+	}
+	exporter.Close()
+	exporter.Export([]byte(`{"template":"closed"}`))
+
+	require.Equal(t, uint64(6), exporter.Published(), "Could not publish the results")
+	require.Equal(t, uint64(0), exporter.Failed(), "Could not retry the results")
+	for _, host := range hosts {
+		require.Equal(t, int32((&kafkago.Murmur2Balancer{}).Balance(kafkago.Message{Key: []byte(host)}, 0, 1)), broker.keys[host], "Could not publish by the partition of the host")
+	}
+	require.Len(t, broker.records, 2, "Could not publish to both partitions")
+	for index, values := range broker.records {
+		var previous int
+		for _, value := range values {
+			current := jsoniter.Get([]byte(value), "template").ToInt()
+			require.True(t, current >= previous, "Could not keep the order of partition %d", index)
+			previous = current
+		}
+	}
+
+	// the results failing for good are written to the dead letter file
+	exporter = New(config)
+	exporter.backoff = time.Millisecond
+	exporter.Export([]byte(`{"template":"rejected","host":"https://0.example.com"}`))
+	exporter.Close()
+	require.Equal(t, uint64(1), exporter.Failed(), "Could not count the rejected result")
+
+	data, err := ioutil.ReadFile(filepath.Join(directory, "failed.jsonl"))
+	require.Nil(t, err, "Could not read the dead letter file")
+	require.Equal(t, "{\"template\":\"rejected\",\"host\":\"https://0.example.com\"}\n", string(data), "Could not write the rejected result to the dead letter file")
+}
+
+func TestExporterUnreachable(t *testing.T) {
+	directory, err := ioutil.TempDir("", "kafka-*")
+	require.Nil(t, err, "Could not create temporary directory")
+	defer os.RemoveAll(directory)
+
+	broker := newFakeBroker(t)
+	broker.listener.Close()
+	config, err := LoadConfig(writeConfig(t, directory, fmt.Sprintf("brokers: [%s]\ntopic: nuclei\nretries: 2\ntimeout: 1s\ndead-letter: %s\n", broker.listener.Addr(), filepath.Join(directory, "failed.jsonl"))))
+	require.Nil(t, err, "Could not load config")
+	exporter := New(config)
+	exporter.backoff = time.Millisecond
+	exporter.Export([]byte(`{"template":"unreachable"}`))
+	exporter.Close()
+
+	require.Equal(t, uint64(1), exporter.Failed(), "Could not count the result failing after the retries")
+	data, err := ioutil.ReadFile(filepath.Join(directory, "failed.jsonl"))
+	require.Nil(t, err, "Could not read the dead letter file")
+	require.Equal(t, "{\"template\":\"unreachable\"}\n", string(data), "Could not write the result to the dead letter file")
+}
+
+func TestExporterStalled(t *testing.T) {
+	directory, err := ioutil.TempDir("", "kafka-*")
+	require.Nil(t, err, "Could not create temporary directory")
+	defer os.RemoveAll(directory)
+
+	// the broker doesn't answer the produce requests until released
+	broker := newFakeBroker(t)
+	defer broker.listener.Close()
+	release := make(chan struct{})
+	broker.produce = func(int, int32, []string) kafkago.Error {
+		<-release
+		return 0
+	}
+
+	config, err := LoadConfig(writeConfig(t, directory, fmt.Sprintf("brokers: [%s]\ntopic: nuclei\nsasl: plain\nusername: nuclei\npassword: secret\nbatch-size: 1\nqueue-size: 2\nqueue-wait: 10ms\ndead-letter: %s\n", broker.listener.Addr(), filepath.Join(directory, "failed.jsonl"))))
+	require.Nil(t, err, "Could not load config")
+	exporter := New(config)
+
+	started := time.Now()
+	for i := 0; i < 10; i++ {
+		exporter.Export([]byte(fmt.Sprintf(`{"template":"%d"}`, i)))
+	}
+	require.True(t, time.Since(started) < 5*time.Second, "Could not bound the wait for room in the queue")
+	dropped := exporter.Dropped()
+	require.True(t, dropped > 0, "Could not overflow the queue of the stalled broker")
+
+	data, err := ioutil.ReadFile(filepath.Join(directory, "failed.jsonl"))
+	require.Nil(t, err, "Could not read the dead letter file")
+	require.Equal(t, int(dropped), strings.Count(string(data), "\n"), "Could not write the overflowing results to the dead letter file")
+
+	close(release)
+	exporter.Close()
+	require.Equal(t, 10-dropped, exporter.Published(), "Could not publish the queued results once the broker recovered")
+	require.Equal(t, uint64(0), exporter.Failed(), "Could not count the overflowing results apart from the failed ones")
+}
@@ -0,0 +1,3 @@
+// Package markdown exports the findings of a scan as a markdown report, a
+// file of evidence per finding and an index of the findings by severity.
+package markdown
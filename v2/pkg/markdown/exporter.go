@@ -0,0 +1,303 @@
+package markdown
+
+import (
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/projectdiscovery/nuclei/v2/pkg/templates"
+)
+
+// indexFile is the name of the index of the findings of a report
+const indexFile = "index.md"
+
+// maxBodySize is the length of the requests and responses written to the
+// files of the findings, the longer ones being truncated.
+const maxBodySize = 64 * 1024
+
+// unsafeChars are the characters replaced in the names of the files
+var unsafeChars = regexp.MustCompile(`[^A-Za-z0-9._-]+`)
+
+// markdownEscaper escapes the characters having a meaning in markdown text
+var markdownEscaper = strings.NewReplacer(
+	`\`, `\\`, "`", "\\`", "*", `\*`, "_", `\_`, "[", `\[`, "]", `\]`, "<", `\<`, ">", `\>`, "#", `\#`,
+)
+
+// severities are the orders of the sections of the index by severity, the
+// unknown severities being sorted after them by name.
+var severities = map[string]int{"critical": 0, "high": 1, "medium": 2, "low": 3, "info": 4}
+
+// Finding is a result of a template written to its own file
+type Finding struct {
+	Template    *templates.Template
+	Type        string
+	Host        string
+	Matched     string
+	MatcherName string
+	Extracted   []string
+	// Request and Response are the raw request and response if any
+	Request   string
+	Response  string
+	Timestamp time.Time
+}
+
+// Exporter is a concurrency safe writer of a markdown report to a directory.
+//
+// The file of each finding is written as it's found and the index is
+// rewritten after each of them, so an interrupted scan keeps a complete
+// report of its findings so far.
+type Exporter struct {
+	mutex     sync.Mutex
+	directory string
+	entries   []*entry
+}
+
+// entry is a finding of the index
+type entry struct {
+	severity string
+	title    string
+	file     string
+}
+
+// New creates an exporter writing a report to a directory, created if
+// needed. Directories already having a report are refused so that reports
+// are never mixed.
+func New(directory string) (*Exporter, error) {
+	if err := os.MkdirAll(directory, 0755); err != nil {
+		return nil, err
+	}
+	if _, err := os.Stat(filepath.Join(directory, indexFile)); err == nil {
+		return nil, fmt.Errorf("%s already contains a markdown report, remove it or use another directory", directory)
+	}
+	e := &Exporter{directory: directory}
+	return e, e.writeIndex()
+}
+
+// Add writes the file of a finding and adds it to the index
+func (e *Exporter) Add(finding *Finding) error {
+	file, err := e.createFile(finding)
+	if err != nil {
+		return err
+	}
+	_, err = file.WriteString(formatFinding(finding))
+	if closeErr := file.Close(); err == nil {
+		err = closeErr
+	}
+	if err != nil {
+		return err
+	}
+
+	info := finding.Template.Info
+	severity := strings.ToLower(info.Severity)
+	if severity == "" {
+		severity = "unknown"
+	}
+	title := fmt.Sprintf("%s at %s", templateName(finding.Template), finding.Matched)
+	if finding.MatcherName != "" {
+		title += fmt.Sprintf(" (%s)", finding.MatcherName)
+	}
+
+	e.mutex.Lock()
+	defer e.mutex.Unlock()
+	e.entries = append(e.entries, &entry{severity: severity, title: title, file: filepath.Base(file.Name())})
+	return e.writeIndex()
+}
+
+// Name returns the directory of the report
+func (e *Exporter) Name() string {
+	return e.directory
+}
+
+// Count returns the number of findings of the report
+func (e *Exporter) Count() int {
+	e.mutex.Lock()
+	defer e.mutex.Unlock()
+	return len(e.entries)
+}
+
+// createFile creates the file of a finding named after its template and
+// host, a counter being appended to the names already used.
+func (e *Exporter) createFile(finding *Finding) (*os.File, error) {
+	name := unsafeChars.ReplaceAllString(finding.Template.ID+"-"+finding.Host, "_")
+	for i := 0; ; i++ {
+		path := filepath.Join(e.directory, name+".md")
+		if i > 0 {
+			path = filepath.Join(e.directory, fmt.Sprintf("%s-%d.md", name, i))
+		}
+		file, err := os.OpenFile(path, os.O_WRONLY|os.O_CREATE|os.O_EXCL, 0644)
+		if os.IsExist(err) {
+			continue
+		}
+		return file, err
+	}
+}
+
+// writeIndex rewrites the index of the findings grouped by severity. The
+// index is written to a temporary file renamed once complete.
+func (e *Exporter) writeIndex() error {
+	entries := append([]*entry{}, e.entries...)
+	sort.SliceStable(entries, func(i, j int) bool {
+		first, second := severityOrder(entries[i].severity), severityOrder(entries[j].severity)
+		if first != second {
+			return first < second
+		}
+		return entries[i].severity < entries[j].severity
+	})
+
+	builder := &strings.Builder{}
+	builder.WriteString("# Nuclei report\n\n")
+	if len(entries) == 0 {
+		builder.WriteString("No findings yet.\n")
+	}
+	for i, entry := range entries {
+		if i == 0 || entry.severity != entries[i-1].severity {
+			if i > 0 {
+				builder.WriteString("\n")
+			}
+			fmt.Fprintf(builder, "## %s\n\n", strings.ToUpper(entry.severity[:1])+entry.severity[1:])
+		}
+		fmt.Fprintf(builder, "- [%s](%s)\n", escapeText(entry.title), entry.file)
+	}
+
+	temp, err := ioutil.TempFile(e.directory, "."+indexFile+".*")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(temp.Name())
+	_, err = temp.WriteString(builder.String())
+	if closeErr := temp.Close(); err == nil {
+		err = closeErr
+	}
+	if err != nil {
+		return err
+	}
+	if err := os.Chmod(temp.Name(), 0644); err != nil {
+		return err
+	}
+	return os.Rename(temp.Name(), filepath.Join(e.directory, indexFile))
+}
+
+// formatFinding returns the markdown evidence of a finding
+func formatFinding(finding *Finding) string {
+	template := finding.Template
+	info := template.Info
+	builder := &strings.Builder{}
+
+	fmt.Fprintf(builder, "# %s\n\n", escapeText(templateName(template)))
+	builder.WriteString("| Field | Value |\n|---|---|\n")
+	row := func(name, value string) {
+		if value != "" {
+			fmt.Fprintf(builder, "| %s | %s |\n", name, strings.Replace(escapeText(value), "|", "\\|", -1))
+		}
+	}
+	row("Template", template.ID)
+	row("Severity", info.Severity)
+	row("Author", info.Author)
+	row("Tags", strings.Join(info.TagList(), ", "))
+	row("Target", finding.Host)
+	row("Matched", finding.Matched)
+	row("Matcher", finding.MatcherName)
+	row("Type", finding.Type)
+	row("Date", finding.Timestamp.UTC().Format(time.RFC3339))
+	if classification := info.Classification; classification != nil {
+		row("CVE", strings.Join(classification.CVEID, ", "))
+		row("CWE", strings.Join(classification.CWEID, ", "))
+		row("CVSS metrics", classification.CVSSMetrics)
+		if classification.CVSSScore > 0 {
+			row("CVSS score", fmt.Sprintf("%.1f", classification.CVSSScore))
+		}
+	}
+
+	if info.Description != "" {
+		fmt.Fprintf(builder, "\n## Description\n\n%s\n", strings.TrimSpace(info.Description))
+	}
+	if len(info.Reference) > 0 {
+		builder.WriteString("\n## References\n\n")
+		for _, reference := range info.Reference {
+			fmt.Fprintf(builder, "- %s\n", reference)
+		}
+	}
+	if len(finding.Extracted) > 0 {
+		builder.WriteString("\n## Extracted values\n\n")
+		for _, value := range finding.Extracted {
+			fmt.Fprintf(builder, "- %s\n", codeSpan(value))
+		}
+	}
+	writeBlock(builder, "Request", finding.Type, finding.Request)
+	writeBlock(builder, "Response", finding.Type, finding.Response)
+	return builder.String()
+}
+
+// writeBlock writes a raw request or response in a fenced code block,
+// truncated with a note if it's too long.
+func writeBlock(builder *strings.Builder, title, language, raw string) {
+	if raw == "" {
+		return
+	}
+	var note string
+	if len(raw) > maxBodySize {
+		note = fmt.Sprintf("\n_Truncated to the first %d of %d bytes._\n", maxBodySize, len(raw))
+		raw = raw[:maxBodySize]
+	}
+	// the line breaks of the http messages are written as in the markdown
+	raw = strings.Replace(strings.ToValidUTF8(raw, "\uFFFD"), "\r\n", "\n", -1)
+	fence := strings.Repeat("`", longestRun(raw, '`')+1)
+	if len(fence) < 3 {
+		fence = "```"
+	}
+	fmt.Fprintf(builder, "\n## %s\n\n%s%s\n%s\n%s\n%s", title, fence, language, strings.TrimRight(raw, "\r\n"), fence, note)
+}
+
+// codeSpan returns a value as inline code, its backticks being kept
+func codeSpan(value string) string {
+	ticks := strings.Repeat("`", longestRun(value, '`')+1)
+	if strings.HasPrefix(value, "`") || strings.HasSuffix(value, "`") {
+		return ticks + " " + value + " " + ticks
+	}
+	return ticks + value + ticks
+}
+
+// longestRun returns the length of the longest run of a character
+func longestRun(value string, char byte) int {
+	var longest, current int
+	for i := 0; i < len(value); i++ {
+		if value[i] != char {
+			current = 0
+			continue
+		}
+		current++
+		if current > longest {
+			longest = current
+		}
+	}
+	return longest
+}
+
+// escapeText escapes a value written in markdown text, replacing its line
+// breaks.
+func escapeText(value string) string {
+	value = strings.NewReplacer("\r", " ", "\n", " ").Replace(value)
+	return markdownEscaper.Replace(value)
+}
+
+// templateName returns the name of a template, or its id if it has none
+func templateName(template *templates.Template) string {
+	if template.Info.Name != "" {
+		return template.Info.Name
+	}
+	return template.ID
+}
+
+// severityOrder returns the order of the index section of a severity
+func severityOrder(severity string) int {
+	if order, ok := severities[severity]; ok {
+		return order
+	}
+	return len(severities)
+}
@@ -0,0 +1,84 @@
+package markdown
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/projectdiscovery/nuclei/v2/pkg/templates"
+	"github.com/stretchr/testify/require"
+)
+
+func TestExporter(t *testing.T) {
+	directory, err := ioutil.TempDir("", "markdown-*")
+	require.Nil(t, err, "Could not create temporary directory")
+	defer os.RemoveAll(directory)
+	report := filepath.Join(directory, "report")
+
+	exporter, err := New(report)
+	require.Nil(t, err, "Could not create exporter")
+	cve := &templates.Template{ID: "CVE-2021-41773", Info: templates.Info{
+		Name:           "Apache Path Traversal",
+		Severity:       "high",
+		Description:    "Path traversal in Apache 2.4.49",
+		Reference:      templates.StringSlice{"https://httpd.apache.org/security/vulnerabilities_24.html"},
+		Classification: &templates.Classification{CVEID: []string{"CVE-2021-41773"}, CVSSScore: 7.5},
+	}}
+	apache := &templates.Template{ID: "apache-version", Info: templates.Info{Name: "Apache Version", Severity: "info"}}
+
+	var wg sync.WaitGroup
+	errs := make(chan error, 4)
+	for i := 0; i < 3; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			errs <- exporter.Add(&Finding{Template: cve, Type: "http", Host: "example.com:8443", Matched: "https://example.com:8443/cgi-bin/.%2e/etc/passwd", Request: "GET /cgi-bin/.%2e/etc/passwd HTTP/1.1\r\nHost: example.com:8443\r\n\r\n", Response: "HTTP/1.1 200 OK\r\n\r\nroot:x:0:0:```", Timestamp: time.Now()})
+		}()
+	}
+	wg.Add(1)
+	go func() {
+		defer wg.Done()
+		errs <- exporter.Add(&Finding{Template: apache, Type: "http", Host: "example.com", Matched: "https://example.com", MatcherName: "version", Extracted: []string{"2.4.49"}, Response: "HTTP/1.1 200 OK\r\n\r\n" + strings.Repeat("a", maxBodySize)})
+	}()
+	wg.Wait()
+	close(errs)
+	for err := range errs {
+		require.Nil(t, err, "Could not add finding")
+	}
+	require.Equal(t, 4, exporter.Count(), "Could not count the findings")
+
+	for _, name := range []string{"CVE-2021-41773-example.com_8443.md", "CVE-2021-41773-example.com_8443-1.md", "CVE-2021-41773-example.com_8443-2.md", "apache-version-example.com.md"} {
+		require.FileExists(t, filepath.Join(report, name), "Could not write a file per finding")
+	}
+	files, err := ioutil.ReadDir(report)
+	require.Nil(t, err, "Could not list the report")
+	require.Len(t, files, 5, "Could not remove the temporary index files")
+
+	data, err := ioutil.ReadFile(filepath.Join(report, "CVE-2021-41773-example.com_8443.md"))
+	require.Nil(t, err, "Could not read finding")
+	finding := string(data)
+	require.Contains(t, finding, "| Severity | high |", "Could not write the severity")
+	require.Contains(t, finding, "| CVE | CVE-2021-41773 |", "Could not write the cve")
+	require.Contains(t, finding, "- https://httpd.apache.org/security/vulnerabilities_24.html", "Could not write the references")
+	require.Contains(t, finding, "````http\nHTTP/1.1 200 OK\n\nroot:x:0:0:```\n````", "Could not fence the response with backticks")
+
+	data, err = ioutil.ReadFile(filepath.Join(report, "apache-version-example.com.md"))
+	require.Nil(t, err, "Could not read finding")
+	require.Contains(t, string(data), "- `2.4.49`", "Could not write the extracted values")
+	require.Contains(t, string(data), "_Truncated to the first 65536 of 65555 bytes._", "Could not truncate the response")
+
+	data, err = ioutil.ReadFile(filepath.Join(report, indexFile))
+	require.Nil(t, err, "Could not read index")
+	index := string(data)
+	require.Contains(t, index, "## High\n\n- [Apache Path Traversal at https://example.com:8443/cgi-bin/.%2e/etc/passwd](CVE-2021-41773-example.com_8443", "Could not index the high findings")
+	require.Contains(t, index, "## Info\n\n- [Apache Version at https://example.com (version)](apache-version-example.com.md)", "Could not index the info findings")
+	require.True(t, strings.Index(index, "## High") < strings.Index(index, "## Info"), "Could not sort the index by severity")
+	require.Equal(t, 3, strings.Count(index, "](CVE-2021-41773"), "Could not index each finding once")
+
+	_, err = New(report)
+	require.NotNil(t, err, "Could not refuse a directory with a report")
+}
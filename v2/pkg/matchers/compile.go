@@ -1,13 +1,22 @@
 package matchers
 
 import (
+	"encoding/hex"
 	"fmt"
+	"net"
 	"regexp"
+	"strings"
 
 	"github.com/Knetic/govaluate"
+	"github.com/antchfx/xpath"
 	"github.com/projectdiscovery/nuclei/v2/pkg/generators"
+	"github.com/projectdiscovery/nuclei/v2/pkg/jsonpath"
+	"github.com/projectdiscovery/nuclei/v2/pkg/regexguard"
 )
 
+// defaultSimilarityThreshold is the similarity threshold of the similarity matchers
+const defaultSimilarityThreshold = 0.9
+
 // CompileMatchers performs the initial setup operation on a matcher
 func (m *Matcher) CompileMatchers() error {
 	var ok bool
@@ -18,6 +27,33 @@ func (m *Matcher) CompileMatchers() error {
 		return fmt.Errorf("unknown matcher type specified: %s", m.Type)
 	}
 
+	// Lowercase the words once for case insensitive matching
+	if m.CaseInsensitive {
+		for i, word := range m.Words {
+			m.Words[i] = strings.ToLower(word)
+		}
+	}
+
+	// Validate the request of internal matchers
+	if m.Request < 0 {
+		return fmt.Errorf("invalid request index specified: %d", m.Request)
+	}
+	if m.Request > 0 && !m.Internal {
+		return fmt.Errorf("request index is only supported by internal matchers")
+	}
+
+	// Validate the occurrences count of the words
+	if m.Count < 0 {
+		return fmt.Errorf("invalid word count specified: %d", m.Count)
+	}
+	switch m.CountCondition {
+	case "":
+		m.CountCondition = ">="
+	case ">=", "==":
+	default:
+		return fmt.Errorf("unknown count condition specified: %s", m.CountCondition)
+	}
+
 	// Compile the regexes
 	for _, regex := range m.Regex {
 		compiled, err := regexp.Compile(regex)
@@ -30,12 +66,84 @@ func (m *Matcher) CompileMatchers() error {
 
 	// Compile the dsl expressions
 	for _, dsl := range m.DSL {
-		compiled, err := govaluate.NewEvaluableExpressionWithFunctions(dsl, generators.HelperFunctions())
+		compiled, err := govaluate.NewEvaluableExpressionWithFunctions(dsl, generators.GuardedHelperFunctions(func() *regexguard.Guard { return m.guard }))
 		if err != nil {
 			return fmt.Errorf("could not compile dsl: %s", dsl)
 		}
+		if err := generators.ValidateExpression(dsl); err != nil {
+			return fmt.Errorf("could not compile dsl %s: %s", dsl, err)
+		}
 
 		m.dslCompiled = append(m.dslCompiled, compiled)
+		for _, variable := range compiled.Vars() {
+			if variable == "raw" {
+				m.dslRaw = true
+			}
+			if strings.HasPrefix(variable, "interactsh_") {
+				m.interactsh = true
+			}
+		}
+	}
+
+	// Decode the binary characters once, the invalid ones being decoded up
+	// to the first invalid byte
+	for _, binary := range m.Binary {
+		decoded, _ := hex.DecodeString(binary)
+		m.binaryCompiled = append(m.binaryCompiled, string(decoded))
+	}
+
+	if m.Baseline && m.matcherType != DSLMatcher {
+		return fmt.Errorf("baseline is only supported by dsl matchers")
+	}
+
+	// Compile the xpath expressions
+	for _, expr := range m.XPath {
+		compiled, err := xpath.Compile(expr)
+		if err != nil {
+			return fmt.Errorf("could not compile xpath: %s", expr)
+		}
+
+		m.xpathCompiled = append(m.xpathCompiled, compiled)
+	}
+	if m.matcherType == XPathMatcher && len(m.xpathCompiled) == 0 {
+		return fmt.Errorf("no xpath expressions specified for xpath matcher")
+	}
+
+	// Compile the json paths
+	for _, path := range m.JSON {
+		compiled, err := jsonpath.Compile(path)
+		if err != nil {
+			return fmt.Errorf("could not compile json path: %s", path)
+		}
+
+		m.jsonCompiled = append(m.jsonCompiled, compiled)
+	}
+	if m.matcherType == JSONMatcher && len(m.jsonCompiled) == 0 {
+		return fmt.Errorf("no json paths specified for json matcher")
+	}
+
+	// Compile the ip ranges
+	for _, cidr := range m.CIDR {
+		_, compiled, err := net.ParseCIDR(cidr)
+		if err != nil {
+			return fmt.Errorf("could not compile cidr: %s", cidr)
+		}
+
+		m.cidrCompiled = append(m.cidrCompiled, compiled)
+	}
+	if m.matcherType == CIDRMatcher && len(m.cidrCompiled) == 0 {
+		return fmt.Errorf("no cidr ranges specified for cidr matcher")
+	}
+
+	// Validate the similarity threshold
+	if m.Threshold < 0 || m.Threshold > 1 {
+		return fmt.Errorf("invalid similarity threshold specified: %v", m.Threshold)
+	}
+	if m.Threshold > 0 && m.matcherType != SimilarityMatcher {
+		return fmt.Errorf("threshold is only supported by similarity matchers")
+	}
+	if m.matcherType == SimilarityMatcher && m.Threshold == 0 {
+		m.Threshold = defaultSimilarityThreshold
 	}
 
 	// Setup the condition type, if any.
@@ -49,7 +157,14 @@ func (m *Matcher) CompileMatchers() error {
 	}
 
 	// Setup the part of the request to match, if any.
-	if m.Part != "" {
+	if strings.HasPrefix(m.Part, headerPartPrefix) {
+		// header.<name> matches the values of a single header
+		m.part = HeaderPart
+		m.headerName = strings.TrimPrefix(m.Part, headerPartPrefix)
+		if m.headerName == "" {
+			return fmt.Errorf("no header name specified for part: %s", m.Part)
+		}
+	} else if m.Part != "" {
 		m.part, ok = PartTypes[m.Part]
 		if !ok {
 			return fmt.Errorf("unknown matcher part specified: %s", m.Part)
@@ -57,5 +172,44 @@ func (m *Matcher) CompileMatchers() error {
 	} else {
 		m.part = BodyPart
 	}
+	if _, ok := interactshParts[m.part]; ok {
+		switch m.matcherType {
+		case WordsMatcher, RegexMatcher, BinaryMatcher, SizeMatcher:
+		default:
+			return fmt.Errorf("part %s is only supported by word, regex, binary and size matchers", m.Part)
+		}
+		m.interactsh = true
+	}
+	return nil
+}
+
+// ValidateNegative validates the use of negative matchers between the matchers of a request.
+//
+// Negative matchers match any response not containing their content, so they
+// can only narrow positive matchers combined with the and matchers-condition.
+// Internal matchers don't produce results and are not validated.
+func ValidateNegative(matchers []*Matcher, condition ConditionType) error {
+	var outputs, positives int
+	for _, matcher := range matchers {
+		if matcher.Internal {
+			continue
+		}
+		outputs++
+		if !matcher.Negative {
+			positives++
+		}
+	}
+
+	for i, matcher := range matchers {
+		if matcher.Internal || !matcher.Negative {
+			continue
+		}
+		if condition == ORCondition && outputs > 1 {
+			return fmt.Errorf("negative matcher %d can't be used with the or matchers-condition as it would match any response without its content, use matchers-condition: and", i)
+		}
+	}
+	if outputs > 0 && positives == 0 {
+		return fmt.Errorf("negative matchers require a positive matcher with matchers-condition: and, a lone negative matcher matches any response without its content")
+	}
 	return nil
 }
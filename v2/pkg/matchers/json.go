@@ -0,0 +1,40 @@
+package matchers
+
+import "github.com/projectdiscovery/nuclei/v2/pkg/jsonpath"
+
+// matchJSON matches json paths against a json document.
+//
+// A path matches if it selects at least one value, or if words are specified,
+// one of the selected values contains any of the words. Numbers and booleans
+// are compared in their textual form and any element of an array can match.
+func (m *Matcher) matchJSON(corpus string) bool {
+	document, err := jsonpath.Parse(corpus)
+	if err != nil {
+		return false
+	}
+
+	// Iterate over all the paths accepted as valid
+	for i, path := range m.jsonCompiled {
+		// Continue if the path doesn't match
+		if !m.matchValues(jsonpath.Strings(path.Evaluate(document))) {
+			// If we are in an AND request and a match failed,
+			// return false as the AND condition fails on any single mismatch.
+			if m.condition == ANDCondition {
+				return false
+			}
+			// Continue with the flow since its an OR Condition.
+			continue
+		}
+
+		// If the condition was an OR, return on the first match.
+		if m.condition == ORCondition {
+			return true
+		}
+
+		// If we are at the end of the paths, return with true
+		if len(m.jsonCompiled)-1 == i {
+			return true
+		}
+	}
+	return false
+}
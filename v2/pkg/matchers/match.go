@@ -1,15 +1,62 @@
 package matchers
 
 import (
-	"encoding/hex"
+	"net"
 	"net/http"
 	"strings"
+	"time"
 
-	"github.com/miekg/dns"
+	"github.com/projectdiscovery/nuclei/v2/pkg/dnsrecords"
+	"github.com/projectdiscovery/nuclei/v2/pkg/file"
+	"github.com/projectdiscovery/nuclei/v2/pkg/generators"
+	"github.com/projectdiscovery/nuclei/v2/pkg/headless"
+	"github.com/projectdiscovery/nuclei/v2/pkg/network"
+	"github.com/projectdiscovery/nuclei/v2/pkg/ssl"
+	"github.com/projectdiscovery/nuclei/v2/pkg/websocket"
 )
 
-// Match matches a http response again a given matcher
-func (m *Matcher) Match(resp *http.Response, body, headers string) bool {
+// Baseline contains the baseline of a target the responses are compared with
+type Baseline struct {
+	// Duration is the time taken by the baseline request of time based payloads
+	Duration time.Duration
+	// Response is the response to a random non-existent path of the target,
+	// only fetched for requests with a baseline.
+	Response *BaselineResponse
+}
+
+// BaselineResponse is the response to the baseline request of a target
+type BaselineResponse struct {
+	StatusCode int
+	Headers    string
+	Body       string
+}
+
+// Match matches a http response again a given matcher.
+//
+// duration is the time taken by the request and baseline the baseline of
+// the target, possibly nil if no baseline was requested.
+// remoteIP is the address the final request of a redirect chain connected to.
+// The variables, i.e the variables of the template, are available to the dsl matchers.
+func (m *Matcher) Match(resp *http.Response, body, headers string, duration time.Duration, baseline *Baseline, remoteIP string, variables map[string]interface{}) bool {
+	return m.result(m.match(resp, body, headers, duration, baseline, remoteIP, variables))
+}
+
+// match matches a http response again a given matcher, ignoring negation
+func (m *Matcher) match(resp *http.Response, body, headers string, duration time.Duration, baseline *Baseline, remoteIP string, variables map[string]interface{}) bool {
+	if baseline == nil {
+		baseline = &Baseline{}
+	}
+
+	if m.headerName != "" {
+		switch m.matcherType {
+		case SizeMatcher, WordsMatcher, RegexMatcher, BinaryMatcher:
+			return m.matchHeaderValues(resp.Header.Values(m.headerName))
+		}
+	}
+	if name, ok := interactshParts[m.part]; ok {
+		return m.matchInteraction(variables[name])
+	}
+
 	switch m.matcherType {
 	case StatusMatcher:
 		return m.matchStatusCode(resp.StatusCode)
@@ -53,33 +100,415 @@ func (m *Matcher) Match(resp *http.Response, body, headers string) bool {
 		}
 	case DSLMatcher:
 		// Match complex query
-		return m.matchDSL(httpToMap(resp, body, headers))
+		values := generators.MergeMaps(variables, httpValues(resp, body, headers, duration, remoteIP, m.dslRaw))
+		if m.Baseline {
+			values["duration_baseline"] = baseline.Duration.Seconds()
+		}
+		if baseline.Response != nil {
+			values["baseline_status"] = baseline.Response.StatusCode
+			values["baseline_length"] = len(baseline.Response.Body)
+			values["baseline_headers"] = baseline.Response.Headers
+			values["baseline_body"] = baseline.Response.Body
+		}
+		return m.matchDSL(values)
+	case XPathMatcher:
+		// Match the html or xml structure of the body
+		return m.matchXPath(body)
+	case JSONMatcher:
+		// Match the json paths of the body
+		return m.matchJSON(body)
+	case CIDRMatcher:
+		// Match the address the request connected to
+		return m.matchCIDR(net.ParseIP(remoteIP))
+	case SimilarityMatcher:
+		// Match the responses differing from the baseline of the target
+		if baseline.Response == nil {
+			return false
+		}
+		if m.part == BodyPart {
+			return m.matchSimilarity(body, baseline.Response.Body)
+		} else if m.part == HeaderPart {
+			return m.matchSimilarity(headers, baseline.Response.Headers)
+		}
+		return m.matchSimilarity(headers+body, baseline.Response.Headers+baseline.Response.Body)
 	}
 	return false
 }
 
-// MatchDNS matches a dns response against a given matcher
-func (m *Matcher) MatchDNS(msg *dns.Msg) bool {
+// MatchDNS matches a dns response against a given matcher.
+//
+// The trace is only available for dns requests with tracing enabled and
+// the variables are available to the dsl matchers.
+func (m *Matcher) MatchDNS(resp *dnsrecords.Response, variables map[string]interface{}) bool {
+	return m.result(m.matchDNS(resp, variables))
+}
+
+// matchDNS matches a dns response against a given matcher, ignoring negation
+func (m *Matcher) matchDNS(resp *dnsrecords.Response, variables map[string]interface{}) bool {
+	corpus := resp.String()
+	switch m.part {
+	case TracePart:
+		if resp.Trace == nil {
+			return false
+		}
+		corpus = resp.Trace.String()
+	case RRSIGPart:
+		corpus = dnsrecords.SignaturesString(resp.Msg)
+	case AnswerPart, AuthorityPart, AdditionalPart:
+		corpus = dnsrecords.SectionString(dnsrecords.Sections(resp.Msg)[dnsSections[m.part]])
+	}
+
 	switch m.matcherType {
 	// [WIP] add dns status code matcher
 	case SizeMatcher:
-		return m.matchSizeCode(msg.Len())
+		return m.matchSizeCode(resp.Msg.Len())
+	case WordsMatcher:
+		// Match for word check
+		return m.matchWords(corpus)
+	case RegexMatcher:
+		// Match regex check
+		return m.matchRegex(corpus)
+	case BinaryMatcher:
+		// Match binary characters check
+		return m.matchBinary(corpus)
+	case DSLMatcher:
+		// Match complex query
+		return m.matchDSL(generators.MergeMaps(variables, DNSValues(resp)))
+	case CIDRMatcher:
+		// Match any of the A/AAAA answers
+		for _, ip := range dnsrecords.AnswerIPs(resp.Msg) {
+			if m.matchCIDR(ip) {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// MatchHeadless matches the recordings of a headless page against a given
+// matcher.
+//
+// The dom is matched by default, the console messages, the dialogs and the
+// network requests by their parts, and the variables are available to the
+// dsl matchers along with the values of the named steps.
+func (m *Matcher) MatchHeadless(resp *headless.Response, variables map[string]interface{}) bool {
+	return m.result(m.matchHeadless(resp, variables))
+}
+
+// matchHeadless matches the recordings of a headless page against a given
+// matcher, ignoring negation
+func (m *Matcher) matchHeadless(resp *headless.Response, variables map[string]interface{}) bool {
+	corpus := resp.DOM
+	switch m.part {
+	case ConsolePart, DialogPart, NetworkPart:
+		corpus = headlessParts[m.part](resp)
+	case AllPart:
+		corpus = resp.String()
+	}
+
+	switch m.matcherType {
+	case SizeMatcher:
+		return m.matchSizeCode(len(corpus))
+	case WordsMatcher:
+		// Match for word check
+		return m.matchWords(corpus)
+	case RegexMatcher:
+		// Match regex check
+		return m.matchRegex(corpus)
+	case BinaryMatcher:
+		// Match binary characters check
+		return m.matchBinary(corpus)
+	case DSLMatcher:
+		// Match complex query
+		return m.matchDSL(generators.MergeMaps(variables, HeadlessValues(resp)))
+	case XPathMatcher:
+		// Match the structure of the dom
+		return m.matchXPath(resp.DOM)
+	}
+	return false
+}
+
+// MatchNetwork matches the bytes read from a network connection against a
+// given matcher.
+//
+// The bytes read are matched as is by default, the binary matchers matching
+// the raw bytes, and the kind of the error which stopped the steps by the
+// error part, i.e refused or timeout. The variables are available to the
+// dsl matchers along with the bytes read by the named steps.
+func (m *Matcher) MatchNetwork(resp *network.Response, variables map[string]interface{}) bool {
+	return m.result(m.matchNetwork(resp, variables))
+}
+
+// matchNetwork matches the bytes read from a network connection against a
+// given matcher, ignoring negation
+func (m *Matcher) matchNetwork(resp *network.Response, variables map[string]interface{}) bool {
+	corpus := resp.Data
+	if m.part == ErrorPart {
+		corpus = resp.Error
+	}
+
+	switch m.matcherType {
+	case SizeMatcher:
+		return m.matchSizeCode(len(corpus))
 	case WordsMatcher:
 		// Match for word check
-		return m.matchWords(msg.String())
+		return m.matchWords(corpus)
 	case RegexMatcher:
 		// Match regex check
-		return m.matchRegex(msg.String())
+		return m.matchRegex(corpus)
 	case BinaryMatcher:
 		// Match binary characters check
-		return m.matchBinary(msg.String())
+		return m.matchBinary(corpus)
 	case DSLMatcher:
 		// Match complex query
-		return m.matchDSL(dnsToMap(msg))
+		return m.matchDSL(generators.MergeMaps(variables, NetworkValues(resp)))
 	}
 	return false
 }
 
+// MatchFile matches the content of a local file against a given matcher.
+//
+// The content is matched as is, the binary matchers matching the raw
+// bytes. The variables are available to the dsl matchers along with the
+// path of the file.
+func (m *Matcher) MatchFile(resp *file.Response, variables map[string]interface{}) bool {
+	return m.result(m.matchFile(resp, variables))
+}
+
+// matchFile matches the content of a local file against a given matcher,
+// ignoring negation
+func (m *Matcher) matchFile(resp *file.Response, variables map[string]interface{}) bool {
+	switch m.matcherType {
+	case SizeMatcher:
+		return m.matchSizeCode(len(resp.Data))
+	case WordsMatcher:
+		// Match for word check
+		return m.matchWords(resp.Data)
+	case RegexMatcher:
+		// Match regex check
+		return m.matchRegex(resp.Data)
+	case BinaryMatcher:
+		// Match binary characters check
+		return m.matchBinary(resp.Data)
+	case DSLMatcher:
+		// Match complex query
+		return m.matchDSL(generators.MergeMaps(variables, FileValues(resp)))
+	}
+	return false
+}
+
+// MatchWebsocket matches the messages read from an upgraded websocket
+// connection against a given matcher.
+//
+// The messages are matched by default, one per line, the upgrade response
+// by the status matchers and the header parts, and the kind of the error
+// of the connection by the error part, i.e handshake or refused. The
+// variables are available to the dsl matchers.
+func (m *Matcher) MatchWebsocket(resp *websocket.Response, variables map[string]interface{}) bool {
+	return m.result(m.matchWebsocket(resp, variables))
+}
+
+// matchWebsocket matches the messages read from an upgraded websocket
+// connection against a given matcher, ignoring negation
+func (m *Matcher) matchWebsocket(resp *websocket.Response, variables map[string]interface{}) bool {
+	if m.headerName != "" {
+		switch m.matcherType {
+		case SizeMatcher, WordsMatcher, RegexMatcher, BinaryMatcher:
+			return m.matchHeaderValues(resp.Headers.Values(m.headerName))
+		}
+	}
+
+	corpus := resp.String()
+	switch m.part {
+	case HeaderPart:
+		corpus = resp.Upgrade
+	case AllPart:
+		corpus = resp.Upgrade + corpus
+	case ErrorPart:
+		corpus = resp.Error
+	}
+
+	switch m.matcherType {
+	case StatusMatcher:
+		return m.matchStatusCode(resp.StatusCode)
+	case SizeMatcher:
+		return m.matchSizeCode(len(corpus))
+	case WordsMatcher:
+		// Match for word check
+		return m.matchWords(corpus)
+	case RegexMatcher:
+		// Match regex check
+		return m.matchRegex(corpus)
+	case BinaryMatcher:
+		// Match binary characters check
+		return m.matchBinary(corpus)
+	case DSLMatcher:
+		// Match complex query
+		return m.matchDSL(generators.MergeMaps(variables, WebsocketValues(resp)))
+	case JSONMatcher:
+		// Match the json paths of any of the messages
+		for _, message := range resp.Messages {
+			if m.matchJSON(message) {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// MatchSSL matches the handshake with a tls server against a given matcher.
+//
+// The negotiated version and cipher suite and the fields of the
+// certificates are matched by default, one per line, each of them by its
+// part, and the kind of the error of the connection or the handshake by the
+// error part, i.e handshake or starttls. The variables are available to the
+// dsl matchers along with the fields of the leaf certificate.
+func (m *Matcher) MatchSSL(resp *ssl.Response, variables map[string]interface{}) bool {
+	return m.result(m.matchSSL(resp, variables))
+}
+
+// matchSSL matches the handshake with a tls server against a given
+// matcher, ignoring negation
+func (m *Matcher) matchSSL(resp *ssl.Response, variables map[string]interface{}) bool {
+	corpus := resp.String()
+	switch m.part {
+	case VersionPart:
+		corpus = resp.Version
+	case CipherPart:
+		corpus = resp.Cipher
+	case CertificatePart:
+		corpus = resp.CertificatesString()
+	case ErrorPart:
+		corpus = resp.Error
+	}
+
+	switch m.matcherType {
+	case SizeMatcher:
+		return m.matchSizeCode(len(corpus))
+	case WordsMatcher:
+		// Match for word check
+		return m.matchWords(corpus)
+	case RegexMatcher:
+		// Match regex check
+		return m.matchRegex(corpus)
+	case DSLMatcher:
+		// Match complex query
+		return m.matchDSL(generators.MergeMaps(variables, SSLValues(resp)))
+	}
+	return false
+}
+
+// Offsets returns the offsets in bytes of the words, the regexes and the
+// binary strings of a matcher found in a corpus, at most limit of them, to
+// locate its matches. The other matchers have no offsets. The offsets of
+// the case insensitive words in a corpus which isn't ascii are approximate.
+func (m *Matcher) Offsets(corpus string, limit int) []int {
+	var offsets []int
+	add := func(corpus, value string, fold bool) {
+		for start := 0; len(offsets) < limit && start <= len(corpus); {
+			var index int
+			if fold {
+				index = indexFold(corpus[start:], value)
+			} else {
+				index = strings.Index(corpus[start:], value)
+			}
+			if index == -1 {
+				return
+			}
+			offsets = append(offsets, start+index)
+			start += index + len(value)
+			if value == "" {
+				start++
+			}
+		}
+	}
+
+	switch m.matcherType {
+	case WordsMatcher:
+		corpus, fold := m.wordsCorpus(corpus)
+		for _, word := range m.Words {
+			add(corpus, word, fold)
+		}
+	case BinaryMatcher:
+		for _, binary := range m.binaryCompiled {
+			add(corpus, binary, false)
+		}
+	case RegexMatcher:
+		corpus = m.guard.Input(corpus)
+		for _, regex := range m.regexCompiled {
+			if len(offsets) >= limit {
+				break
+			}
+			for _, match := range regex.FindAllStringIndex(corpus, limit-len(offsets)) {
+				offsets = append(offsets, match[0])
+			}
+		}
+	}
+	return offsets
+}
+
+// matchHeaderValues matches the values of a single header, matching if any
+// of the values matches. A missing header is matched as an empty value.
+func (m *Matcher) matchHeaderValues(values []string) bool {
+	if len(values) == 0 {
+		values = []string{""}
+	}
+	for _, value := range values {
+		var matched bool
+		switch m.matcherType {
+		case SizeMatcher:
+			matched = m.matchSizeCode(len(value))
+		case WordsMatcher:
+			matched = m.matchWords(value)
+		case RegexMatcher:
+			matched = m.matchRegex(value)
+		case BinaryMatcher:
+			matched = m.matchBinary(value)
+		}
+		if matched {
+			return true
+		}
+	}
+	return false
+}
+
+// matchInteraction matches the value of an interaction of an interactsh
+// part, none matching before an interaction is received.
+func (m *Matcher) matchInteraction(value interface{}) bool {
+	interaction, ok := value.(string)
+	if !ok {
+		return false
+	}
+	return m.matchHeaderValues([]string{interaction})
+}
+
+// matchValues returns true if the values selected by an xpath or json path
+// match the words, or if any value was selected when there are no words.
+func (m *Matcher) matchValues(values []string) bool {
+	if len(m.Words) == 0 {
+		return len(values) > 0
+	}
+	for _, value := range values {
+		if m.CaseInsensitive {
+			value = strings.ToLower(value)
+		}
+		for _, word := range m.Words {
+			if strings.Contains(value, word) {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// result returns the result of the match, reversed for negative matchers
+func (m *Matcher) result(matched bool) bool {
+	if m.Negative {
+		return !matched
+	}
+	return matched
+}
+
 // matchStatusCode matches a status code check against an HTTP Response
 func (m *Matcher) matchStatusCode(statusCode int) bool {
 	// Iterate over all the status codes accepted as valid
@@ -114,10 +543,12 @@ func (m *Matcher) matchSizeCode(length int) bool {
 
 // matchWords matches a word check against an HTTP Response/Headers.
 func (m *Matcher) matchWords(corpus string) bool {
+	corpus, fold := m.wordsCorpus(corpus)
+
 	// Iterate over all the words accepted as valid
 	for i, word := range m.Words {
 		// Continue if the word doesn't match
-		if strings.Index(corpus, word) == -1 {
+		if !m.matchWord(corpus, word, fold) {
 			// If we are in an AND request and a match failed,
 			// return false as the AND condition fails on any single mismatch.
 			if m.condition == ANDCondition {
@@ -140,8 +571,84 @@ func (m *Matcher) matchWords(corpus string) bool {
 	return false
 }
 
+// wordsCorpus returns the corpus the words are searched in, and true if
+// they are searched ignoring the case. The words are lowercased at compile
+// time, and so is the corpus of the case insensitive matchers unless it is
+// ascii, the words being searched in it as is instead of copying it.
+func (m *Matcher) wordsCorpus(corpus string) (string, bool) {
+	if !m.CaseInsensitive {
+		return corpus, false
+	}
+	if isASCII(corpus) {
+		return corpus, true
+	}
+	return strings.ToLower(corpus), false
+}
+
+// matchWord returns true if the word is present in the corpus,
+// or if its occurrences satisfy the count when specified.
+func (m *Matcher) matchWord(corpus, word string, fold bool) bool {
+	if m.Count == 0 {
+		if fold {
+			return indexFold(corpus, word) != -1
+		}
+		return strings.Contains(corpus, word)
+	}
+	occurrences := countWord(corpus, word, fold)
+	if m.CountCondition == "==" {
+		return occurrences == m.Count
+	}
+	return occurrences >= m.Count
+}
+
+// countWord returns the number of non-overlapping occurrences of a word in
+// the corpus, ignoring the case if fold is true.
+func countWord(corpus, word string, fold bool) int {
+	if !fold {
+		return strings.Count(corpus, word)
+	}
+	if word == "" {
+		return len(corpus) + 1
+	}
+	count := 0
+	for {
+		index := indexFold(corpus, word)
+		if index == -1 {
+			return count
+		}
+		count++
+		corpus = corpus[index+len(word):]
+	}
+}
+
+// Occurrences returns the number of occurrences of the first word satisfying
+// the count in the part of the http response matched by a word matcher.
+func (m *Matcher) Occurrences(resp *http.Response, body, headers string) int {
+	var corpus string
+	switch {
+	case m.headerName != "":
+		corpus = strings.Join(resp.Header.Values(m.headerName), "\n")
+	case m.part == BodyPart:
+		corpus = body
+	case m.part == HeaderPart:
+		corpus = headers
+	default:
+		corpus = headers + body
+	}
+	corpus, fold := m.wordsCorpus(corpus)
+
+	for _, word := range m.Words {
+		if m.matchWord(corpus, word, fold) {
+			return countWord(corpus, word, fold)
+		}
+	}
+	return 0
+}
+
 // matchRegex matches a regex check against an HTTP Response/Headers.
 func (m *Matcher) matchRegex(corpus string) bool {
+	corpus = m.guard.Input(corpus)
+
 	// Iterate over all the regexes accepted as valid
 	for i, regex := range m.regexCompiled {
 		// Continue if the regex doesn't match
@@ -172,11 +679,9 @@ func (m *Matcher) matchRegex(corpus string) bool {
 func (m *Matcher) matchBinary(corpus string) bool {
 
 	// Iterate over all the words accepted as valid
-	for i, binary := range m.Binary {
+	for i, binary := range m.binaryCompiled {
 		// Continue if the word doesn't match
-
-		hexa, _ := hex.DecodeString(binary)
-		if !strings.Contains(corpus, string(hexa)) {
+		if !strings.Contains(corpus, binary) {
 			// If we are in an AND request and a match failed,
 			// return false as the AND condition fails on any single mismatch.
 			if m.condition == ANDCondition {
@@ -192,26 +697,62 @@ func (m *Matcher) matchBinary(corpus string) bool {
 		}
 
 		// If we are at the end of the words, return with true
-		if len(m.Binary)-1 == i {
+		if len(m.binaryCompiled)-1 == i {
 			return true
 		}
 	}
 	return false
 }
 
+// matchCIDR matches an ip address against the ip ranges
+func (m *Matcher) matchCIDR(ip net.IP) bool {
+	if ip == nil {
+		return false
+	}
+
+	// Iterate over all the ranges accepted as valid
+	for i, cidr := range m.cidrCompiled {
+		// Continue if the range doesn't contain the address
+		if !cidr.Contains(ip) {
+			// If we are in an AND request and a match failed,
+			// return false as the AND condition fails on any single mismatch.
+			if m.condition == ANDCondition {
+				return false
+			}
+			// Continue with the flow since its an OR Condition.
+			continue
+		}
+
+		// If the condition was an OR, return on the first match.
+		if m.condition == ORCondition {
+			return true
+		}
+
+		// If we are at the end of the ranges, return with true
+		if len(m.cidrCompiled)-1 == i {
+			return true
+		}
+	}
+	return false
+}
+
+// matchSimilarity matches a corpus less similar to the baseline than the threshold
+func (m *Matcher) matchSimilarity(corpus, baseline string) bool {
+	return generators.Similarity(corpus, baseline) < m.Threshold
+}
+
 // matchDSL matches on a generic map result
 func (m *Matcher) matchDSL(mp map[string]interface{}) bool {
 	// Iterate over all the regexes accepted as valid
 	for i, expression := range m.dslCompiled {
+		// expressions failing to evaluate, i.e referencing variables
+		// missing from the response, are handled as not matching.
 		result, err := expression.Evaluate(mp)
-		if err != nil {
-			continue
-		}
 		var bResult bool
 		bResult, ok := result.(bool)
 
 		// Continue if the regex doesn't match
-		if !ok || !bResult {
+		if err != nil || !ok || !bResult {
 			// If we are in an AND request and a match failed,
 			// return false as the AND condition fails on any single mismatch.
 			if m.condition == ANDCondition {
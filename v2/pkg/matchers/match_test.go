@@ -1,8 +1,21 @@
 package matchers
 
 import (
+	"crypto/x509"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"strings"
 	"testing"
+	"time"
 
+	"github.com/miekg/dns"
+	"github.com/projectdiscovery/nuclei/v2/pkg/dnsrecords"
+	"github.com/projectdiscovery/nuclei/v2/pkg/file"
+	"github.com/projectdiscovery/nuclei/v2/pkg/headless"
+	"github.com/projectdiscovery/nuclei/v2/pkg/network"
+	"github.com/projectdiscovery/nuclei/v2/pkg/ssl"
+	"github.com/projectdiscovery/nuclei/v2/pkg/websocket"
 	"github.com/stretchr/testify/require"
 )
 
@@ -28,3 +41,471 @@ func TestORCondition(t *testing.T) {
 	matched = m.matchWords("c")
 	require.False(t, matched, "Could match invalid OR condition")
 }
+
+func TestXPathMatcher(t *testing.T) {
+	m := &Matcher{Type: "xpath", XPath: []string{"//meta[@name='generator']"}, Attribute: "content", Words: []string{"WordPress"}}
+	err := m.CompileMatchers()
+	require.Nil(t, err, "Could not compile xpath matcher")
+
+	// unclosed tags should still be parsed
+	matched := m.matchXPath(`<html><head><meta name="generator" content="WordPress 5.5"><title>x</head><body><div>`)
+	require.True(t, matched, "Could not match attribute of malformed html")
+
+	matched = m.matchXPath(`{"generator": "WordPress"}`)
+	require.False(t, matched, "Could match non markup body")
+
+	m = &Matcher{Type: "xpath", XPath: []string{"//user[@role='admin']", "//user/name"}, Condition: "and"}
+	err = m.CompileMatchers()
+	require.Nil(t, err, "Could not compile xpath matcher")
+
+	matched = m.matchXPath(`<?xml version="1.0"?><users><user role="admin"><name>root</name></user></users>`)
+	require.True(t, matched, "Could not match valid AND condition on xml")
+
+	matched = m.matchXPath(`<?xml version="1.0"?><users><user role="guest"><name>guest</name></user></users>`)
+	require.False(t, matched, "Could match invalid AND condition on xml")
+
+	m = &Matcher{Type: "xpath", XPath: []string{"//div[@class="}}
+	require.NotNil(t, m.CompileMatchers(), "Could compile invalid xpath expression")
+}
+
+func TestJSONMatcher(t *testing.T) {
+	m := &Matcher{Type: "json", JSON: []string{"data.user.role"}, Words: []string{"admin"}}
+	err := m.CompileMatchers()
+	require.Nil(t, err, "Could not compile json matcher")
+
+	matched := m.matchJSON(`{"data": {"user": {"role": "admin"}}}`)
+	require.True(t, matched, "Could not match json path value")
+
+	matched = m.matchJSON(`<html>admin</html>`)
+	require.False(t, matched, "Could match non json body")
+
+	m = &Matcher{Type: "json", JSON: []string{"users[*].id", "debug"}, Words: []string{"1337", "true"}, Condition: "and"}
+	err = m.CompileMatchers()
+	require.Nil(t, err, "Could not compile json matcher")
+
+	matched = m.matchJSON(`{"users": [{"id": 1}, {"id": 1337}], "debug": true}`)
+	require.True(t, matched, "Could not match array element and boolean")
+
+	matched = m.matchJSON(`{"users": [{"id": 1}], "debug": true}`)
+	require.False(t, matched, "Could match invalid AND condition")
+
+	m = &Matcher{Type: "json", JSON: []string{"error"}, Negative: true}
+	err = m.CompileMatchers()
+	require.Nil(t, err, "Could not compile json matcher")
+	require.True(t, m.result(m.matchJSON(`{"status": "ok"}`)), "Could not match negative json matcher")
+}
+
+func TestHeaderNamePart(t *testing.T) {
+	resp := &http.Response{Header: http.Header{}}
+	resp.Header.Add("Via", "1.1 nginx")
+	resp.Header.Add("Server", "Apache")
+	resp.Header.Add("Server", "nginx/1.18.0")
+
+	m := &Matcher{Type: "word", Part: "header.server", Words: []string{"nginx"}}
+	err := m.CompileMatchers()
+	require.Nil(t, err, "Could not compile header matcher")
+	require.True(t, m.Match(resp, "", "", 0, nil, "", nil), "Could not match any value of multi-valued header")
+
+	m = &Matcher{Type: "word", Part: "header.SERVER", Words: []string{"Via"}}
+	err = m.CompileMatchers()
+	require.Nil(t, err, "Could not compile header matcher")
+	require.False(t, m.Match(resp, "", "Via: 1.1 nginx", 0, nil, "", nil), "Could match other headers")
+
+	m = &Matcher{Type: "size", Part: "header.x-missing", Size: []int{0}}
+	err = m.CompileMatchers()
+	require.Nil(t, err, "Could not compile header matcher")
+	require.True(t, m.Match(resp, "", "", 0, nil, "", nil), "Could not match missing header as empty")
+}
+
+func TestInteractshParts(t *testing.T) {
+	resp := &http.Response{Header: http.Header{}, Body: http.NoBody}
+	interaction := map[string]interface{}{"interactsh_protocol": "dns", "interactsh_request": "GET /x HTTP/1.1\nUser-Agent: curl"}
+
+	m := &Matcher{Type: "word", Part: "interactsh_protocol", Words: []string{"dns"}}
+	require.Nil(t, m.CompileMatchers(), "Could not compile interactsh matcher")
+	require.True(t, m.UsesInteractsh(), "Could not flag the interactsh matcher")
+	require.True(t, m.Match(resp, "dns", "", 0, nil, "", interaction), "Could not match the interaction protocol")
+	require.False(t, m.Match(resp, "dns", "", 0, nil, "", nil), "Could match before the interaction")
+
+	m = &Matcher{Type: "regex", Part: "interactsh_request", Regex: []string{"User-Agent: [a-z]+"}}
+	require.Nil(t, m.CompileMatchers(), "Could not compile interactsh matcher")
+	require.True(t, m.Match(resp, "", "", 0, nil, "", interaction), "Could not match the interaction request")
+
+	m = &Matcher{Type: "dsl", DSL: []string{"contains(interactsh_protocol, 'dns')"}}
+	require.Nil(t, m.CompileMatchers(), "Could not compile interactsh dsl matcher")
+	require.True(t, m.UsesInteractsh(), "Could not flag the interactsh dsl matcher")
+	require.True(t, m.Match(resp, "", "", 0, nil, "", interaction), "Could not match the interaction with dsl")
+
+	m = &Matcher{Type: "word", Words: []string{"dns"}}
+	require.Nil(t, m.CompileMatchers(), "Could not compile matcher")
+	require.False(t, m.UsesInteractsh(), "Could not keep the body matcher apart")
+
+	m = &Matcher{Type: "status", Part: "interactsh_protocol", Status: []int{200}}
+	require.NotNil(t, m.CompileMatchers(), "Could compile status matcher on the interactions")
+}
+
+func TestTLSDSL(t *testing.T) {
+	server := httptest.NewTLSServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}))
+	defer server.Close()
+
+	resp, err := server.Client().Get(server.URL)
+	require.Nil(t, err, "Could not make tls request")
+	resp.Body.Close()
+
+	m := &Matcher{Type: "dsl", DSL: []string{"ssl_not_after > now() + 86400*14", "contains(ssl_san, '127.0.0.1')"}, Condition: "and"}
+	err = m.CompileMatchers()
+	require.Nil(t, err, "Could not compile dsl matcher")
+	require.True(t, m.Match(resp, "", "", 0, nil, "", nil), "Could not match certificate fields")
+
+	// plain http responses don't have the tls variables
+	plain := &http.Response{Header: http.Header{}, Body: http.NoBody}
+	require.False(t, m.Match(plain, "", "", 0, nil, "", nil), "Could match tls variables without tls")
+}
+
+func TestDurationDSL(t *testing.T) {
+	resp := &http.Response{Header: http.Header{}, Body: http.NoBody}
+
+	m := &Matcher{Type: "dsl", DSL: []string{"duration > duration_baseline + 5"}, Baseline: true}
+	err := m.CompileMatchers()
+	require.Nil(t, err, "Could not compile baseline matcher")
+	require.True(t, m.Match(resp, "", "", 12*time.Second, &Baseline{Duration: 6 * time.Second}, "", nil), "Could not match delay over baseline")
+	require.False(t, m.Match(resp, "", "", 12*time.Second, &Baseline{Duration: 8 * time.Second}, "", nil), "Could match slow baseline")
+
+	m = &Matcher{Type: "word", Words: []string{"a"}, Baseline: true}
+	require.NotNil(t, m.CompileMatchers(), "Could compile baseline for word matcher")
+}
+
+func TestCaseInsensitiveWords(t *testing.T) {
+	m := &Matcher{Type: "word", Words: []string{"SQL syntax", "mysql"}, CaseInsensitive: true, Condition: "and"}
+	err := m.CompileMatchers()
+	require.Nil(t, err, "Could not compile word matcher")
+
+	require.True(t, m.matchWords("You have an error in your sql Syntax; check the MySQL manual"), "Could not match words with different case")
+	require.False(t, m.matchWords("You have an error in your sql Syntax"), "Could match invalid AND condition")
+
+	m = &Matcher{Type: "word", Words: []string{"NGINX"}, CaseInsensitive: true, Negative: true, Part: "header"}
+	err = m.CompileMatchers()
+	require.Nil(t, err, "Could not compile word matcher")
+	require.False(t, m.Match(&http.Response{}, "", "Server: nginx", 0, nil, "", nil), "Could match negative case insensitive words")
+}
+
+func TestCaseInsensitiveFold(t *testing.T) {
+	// the ascii corpus is searched without being lowercased, the others are
+	for _, corpus := range []string{"Server: NGINX/1.2 nginx Nginx", "Server: NGİNX nginx Ngınx", ""} {
+		lowered := strings.ToLower(corpus)
+		for _, word := range []string{"nginx", "server: n", "x", "", "ngİnx"} {
+			m := &Matcher{Type: "word", Words: []string{word}, CaseInsensitive: true}
+			require.Nil(t, m.CompileMatchers(), "Could not compile word matcher")
+			require.Equal(t, strings.Contains(lowered, m.Words[0]), m.matchWords(corpus), "Could not match %q in %q ignoring the case", word, corpus)
+			folded, fold := m.wordsCorpus(corpus)
+			require.Equal(t, strings.Count(lowered, m.Words[0]), countWord(folded, m.Words[0], fold), "Could not count %q in %q ignoring the case", word, corpus)
+		}
+	}
+
+	m := &Matcher{Type: "word", Words: []string{"NGINX"}, CaseInsensitive: true, Count: 2, CountCondition: "=="}
+	require.Nil(t, m.CompileMatchers(), "Could not compile word matcher")
+	require.True(t, m.matchWords("nginx, Nginx"), "Could not count the words ignoring the case")
+	require.Equal(t, 2, m.Occurrences(nil, "nginx, Nginx", ""), "Could not count the occurrences ignoring the case")
+}
+
+func TestBinaryMatcher(t *testing.T) {
+	m := &Matcher{Type: "binary", Binary: []string{"504b0304", "zz"}, Condition: "and"}
+	require.Nil(t, m.CompileMatchers(), "Could not compile binary matcher")
+	require.True(t, m.matchBinary("data PK\x03\x04 data"), "Could not match the decoded binary characters")
+	require.False(t, m.matchBinary("data"), "Could match missing binary characters")
+}
+
+func TestRawDSL(t *testing.T) {
+	m := &Matcher{Type: "dsl", DSL: []string{"contains(raw, 'HTTP/1.1 200 OK')"}}
+	require.Nil(t, m.CompileMatchers(), "Could not compile dsl matcher")
+	require.True(t, m.dslRaw, "Could not find the raw response in the dsl")
+	resp := &http.Response{StatusCode: 200, Status: "200 OK", ProtoMajor: 1, ProtoMinor: 1, Header: http.Header{}, Body: http.NoBody}
+	require.True(t, m.Match(resp, "", "", 0, nil, "", nil), "Could not match the raw response")
+
+	m = &Matcher{Type: "dsl", DSL: []string{"status_code == 200"}}
+	require.Nil(t, m.CompileMatchers(), "Could not compile dsl matcher")
+	require.False(t, m.dslRaw, "Could dump the raw response without using it")
+}
+
+func TestWordsCount(t *testing.T) {
+	m := &Matcher{Type: "word", Words: []string{"<a href=", "<li>"}, Count: 3}
+	err := m.CompileMatchers()
+	require.Nil(t, err, "Could not compile word matcher")
+
+	body := `<a href="a"><li><a href="b"><a href="c">`
+	require.True(t, m.matchWords(body), "Could not match OR condition with one word reaching the count")
+	require.Equal(t, 3, m.Occurrences(nil, body, ""), "Could not count occurrences of the matched word")
+
+	m = &Matcher{Type: "word", Words: []string{"<a href=", "<li>"}, Count: 3, Condition: "and"}
+	err = m.CompileMatchers()
+	require.Nil(t, err, "Could not compile word matcher")
+	require.False(t, m.matchWords(body), "Could match AND condition with a word below the count")
+
+	m = &Matcher{Type: "word", Words: []string{"<a href="}, Count: 2, CountCondition: "=="}
+	err = m.CompileMatchers()
+	require.Nil(t, err, "Could not compile word matcher")
+	require.False(t, m.matchWords(body), "Could match exact count with more occurrences")
+
+	m = &Matcher{Type: "word", Words: []string{"a"}, Count: 2, CountCondition: "<"}
+	require.NotNil(t, m.CompileMatchers(), "Could compile unknown count condition")
+}
+
+func TestNegativeMatchers(t *testing.T) {
+	resp := &http.Response{StatusCode: 200, Header: http.Header{"Server": []string{"login-gateway"}}}
+	headers := "Server: login-gateway"
+
+	m := &Matcher{Type: "word", Words: []string{"login"}, Negative: true, Part: "body"}
+	err := m.CompileMatchers()
+	require.Nil(t, err, "Could not compile negative matcher")
+	require.True(t, m.Match(resp, "<h1>Welcome</h1>", headers, 0, nil, "", nil), "Could not match negative body part ignoring the headers")
+	require.False(t, m.Match(resp, `<form id="login">`, headers, 0, nil, "", nil), "Could match negative body part containing the word")
+
+	m = &Matcher{Type: "word", Words: []string{"login"}, Negative: true, Part: "header"}
+	err = m.CompileMatchers()
+	require.Nil(t, err, "Could not compile negative matcher")
+	require.False(t, m.Match(resp, "<h1>Welcome</h1>", headers, 0, nil, "", nil), "Could match negative header part containing the word")
+
+	for _, m := range []*Matcher{
+		{Type: "status", Status: []int{404}, Negative: true},
+		{Type: "size", Size: []int{1}, Negative: true},
+		{Type: "regex", Regex: []string{"pass(word)?"}, Negative: true},
+		{Type: "binary", Binary: []string{"00"}, Negative: true},
+		{Type: "dsl", DSL: []string{"status_code == 404"}, Negative: true},
+	} {
+		err = m.CompileMatchers()
+		require.Nil(t, err, "Could not compile negative %s matcher", m.Type)
+		require.True(t, m.Match(resp, "<h1>Welcome</h1>", headers, 0, nil, "", nil), "Could not match negative %s matcher", m.Type)
+	}
+
+	// the negation applies to each response of a multi-request template
+	m = &Matcher{Type: "word", Words: []string{"login"}, Negative: true}
+	err = m.CompileMatchers()
+	require.Nil(t, err, "Could not compile negative matcher")
+	for body, expected := range map[string]bool{"<form id=\"login\">": false, "<h1>Dashboard</h1>": true} {
+		require.Equal(t, expected, m.Match(resp, body, headers, 0, nil, "", nil), "Could not match negative matcher for response %s", body)
+	}
+}
+
+func TestValidateNegative(t *testing.T) {
+	status := &Matcher{Type: "status", Status: []int{200}}
+	negative := &Matcher{Type: "word", Words: []string{"login"}, Negative: true}
+
+	require.Nil(t, ValidateNegative([]*Matcher{status, negative}, ANDCondition), "Could not validate negative matcher with and condition")
+	require.NotNil(t, ValidateNegative([]*Matcher{status, negative}, ORCondition), "Could validate negative matcher with or condition")
+	require.NotNil(t, ValidateNegative([]*Matcher{negative}, ORCondition), "Could validate lone negative matcher")
+	require.NotNil(t, ValidateNegative([]*Matcher{negative, negative}, ANDCondition), "Could validate only negative matchers")
+}
+
+func TestCIDRMatcher(t *testing.T) {
+	m := &Matcher{Type: "cidr", CIDR: []string{"169.254.0.0/16", "fd00:ec2::/32"}}
+	err := m.CompileMatchers()
+	require.Nil(t, err, "Could not compile cidr matcher")
+
+	resp := &http.Response{}
+	require.True(t, m.Match(resp, "", "", 0, nil, "169.254.169.254", nil), "Could not match ipv4 range")
+	require.True(t, m.Match(resp, "", "", 0, nil, "fd00:ec2::254", nil), "Could not match ipv6 range")
+	require.False(t, m.Match(resp, "", "", 0, nil, "93.184.216.34", nil), "Could match address out of the ranges")
+	require.False(t, m.Match(resp, "", "", 0, nil, "", nil), "Could match unknown address")
+
+	msg := &dns.Msg{Answer: []dns.RR{
+		&dns.A{Hdr: dns.RR_Header{Name: "example.com.", Rrtype: dns.TypeA}, A: net.ParseIP("93.184.216.34")},
+		&dns.AAAA{Hdr: dns.RR_Header{Name: "example.com.", Rrtype: dns.TypeAAAA}, AAAA: net.ParseIP("fd00:ec2::1")},
+	}}
+	require.True(t, m.MatchDNS(&dnsrecords.Response{Msg: msg}, nil), "Could not match any of the dns answers")
+	msg.Answer = msg.Answer[:1]
+	require.False(t, m.MatchDNS(&dnsrecords.Response{Msg: msg}, nil), "Could match dns answers out of the ranges")
+
+	m = &Matcher{Type: "dsl", DSL: []string{`remote_ip == "169.254.169.254"`}}
+	err = m.CompileMatchers()
+	require.Nil(t, err, "Could not compile dsl matcher")
+	require.True(t, m.Match(resp, "", "", 0, nil, "169.254.169.254", nil), "Could not match remote ip in dsl")
+
+	m = &Matcher{Type: "cidr", CIDR: []string{"169.254.0.0/33"}}
+	require.NotNil(t, m.CompileMatchers(), "Could compile invalid cidr")
+}
+
+func BenchmarkMatchRegex(b *testing.B) {
+	m := &Matcher{Type: "regex", Regex: []string{`(?i)<title>[^<]*admin[^<]*</title>`, `phpinfo\(\)`}}
+	require.Nil(b, m.CompileMatchers(), "Could not compile regex matcher")
+	body := strings.Repeat("<p>content</p>", 1000) + "<title>Admin panel</title>"
+
+	b.ReportAllocs()
+	for i := 0; i < b.N; i++ {
+		m.Match(&http.Response{}, body, "", 0, nil, "", nil)
+	}
+}
+
+func TestSimilarityMatcher(t *testing.T) {
+	resp := &http.Response{Header: http.Header{}, Body: http.NoBody}
+	baseline := &Baseline{Response: &BaselineResponse{StatusCode: 404, Body: "<h1>Not Found</h1><p>The requested page /x could not be found</p>"}}
+
+	m := &Matcher{Type: "similarity"}
+	err := m.CompileMatchers()
+	require.Nil(t, err, "Could not compile similarity matcher")
+	require.Equal(t, defaultSimilarityThreshold, m.Threshold, "Could not set default threshold")
+
+	require.True(t, m.Match(resp, "<h1>Dashboard</h1><p>Welcome back administrator, 3 new alerts</p>", "", 0, baseline, "", nil), "Could not match response different from baseline")
+	require.False(t, m.Match(resp, baseline.Response.Body, "", 0, baseline, "", nil), "Could match response similar to baseline")
+	require.False(t, m.Match(resp, "anything", "", 0, nil, "", nil), "Could match without baseline")
+
+	m = &Matcher{Type: "dsl", DSL: []string{"baseline_status == 404 && len(body) != baseline_length"}}
+	require.Nil(t, m.CompileMatchers(), "Could not compile baseline dsl matcher")
+	require.True(t, m.Match(resp, "other", "", 0, baseline, "", nil), "Could not match baseline variables")
+
+	require.NotNil(t, (&Matcher{Type: "similarity", Threshold: 1.5}).CompileMatchers(), "Could compile invalid threshold")
+	require.NotNil(t, (&Matcher{Type: "word", Words: []string{"a"}, Threshold: 0.5}).CompileMatchers(), "Could compile threshold for word matcher")
+}
+
+func TestHeadlessMatcher(t *testing.T) {
+	resp := &headless.Response{
+		URL:      "http://example.com/#<img src=x onerror=alert(1)>",
+		DOM:      `<html><body><div id="out"><img src="x" onerror="alert(1)"></div></body></html>`,
+		Console:  []string{"log: polluted", "exception: TypeError: x is undefined"},
+		Dialogs:  []string{"alert: 1"},
+		Requests: []string{"GET http://example.com/", "POST http://example.com/api/token"},
+		Values:   map[string]string{"polluted": "true"},
+	}
+
+	m := &Matcher{Type: "word", Part: "dialog", Words: []string{"alert: 1"}}
+	require.Nil(t, m.CompileMatchers(), "Could not compile dialog matcher")
+	require.True(t, m.MatchHeadless(resp, nil), "Could not match the dialogs")
+
+	m = &Matcher{Type: "regex", Part: "console", Regex: []string{"^exception: TypeError"}}
+	require.Nil(t, m.CompileMatchers(), "Could not compile console matcher")
+	require.False(t, m.MatchHeadless(resp, nil), "Could match a regex across the console messages")
+	m = &Matcher{Type: "regex", Part: "console", Regex: []string{"(?m)^exception: TypeError"}}
+	require.Nil(t, m.CompileMatchers(), "Could not compile console matcher")
+	require.True(t, m.MatchHeadless(resp, nil), "Could not match the console messages")
+
+	m = &Matcher{Type: "word", Part: "network", Words: []string{"POST http://example.com/api/token"}}
+	require.Nil(t, m.CompileMatchers(), "Could not compile network matcher")
+	require.True(t, m.MatchHeadless(resp, nil), "Could not match the network requests")
+
+	m = &Matcher{Type: "xpath", XPath: []string{"//div[@id='out']/img/@onerror"}, Words: []string{"alert"}}
+	require.Nil(t, m.CompileMatchers(), "Could not compile xpath matcher")
+	require.True(t, m.MatchHeadless(resp, nil), "Could not match the dom")
+
+	m = &Matcher{Type: "word", Part: "dom", Words: []string{"polluted"}}
+	require.Nil(t, m.CompileMatchers(), "Could not compile dom matcher")
+	require.False(t, m.MatchHeadless(resp, nil), "Could match the console messages as the dom")
+
+	m = &Matcher{Type: "dsl", DSL: []string{`polluted == "true" && contains(url, "onerror")`}}
+	require.Nil(t, m.CompileMatchers(), "Could not compile dsl matcher")
+	require.True(t, m.MatchHeadless(resp, nil), "Could not match the values of the steps")
+}
+
+func TestNetworkMatcher(t *testing.T) {
+	resp := &network.Response{
+		Address: "127.0.0.1:1099",
+		Data:    "\x4e\x00\x0b127.0.0.1\x00\x00",
+		Values:  map[string]string{"handshake": "\x4e\x00"},
+	}
+
+	m := &Matcher{Type: "binary", Binary: []string{"4e000b"}}
+	require.Nil(t, m.CompileMatchers(), "Could not compile binary matcher")
+	require.True(t, m.MatchNetwork(resp, nil), "Could not match the raw bytes")
+
+	m = &Matcher{Type: "size", Size: []int{14}}
+	require.Nil(t, m.CompileMatchers(), "Could not compile size matcher")
+	require.True(t, m.MatchNetwork(resp, nil), "Could not match the size of the bytes read")
+
+	m = &Matcher{Type: "dsl", DSL: []string{`len(handshake) == 2 && contains(address, "1099")`}}
+	require.Nil(t, m.CompileMatchers(), "Could not compile dsl matcher")
+	require.True(t, m.MatchNetwork(resp, nil), "Could not match the values of the steps")
+
+	refused := &network.Response{Address: "127.0.0.1:6379", Error: network.RefusedError}
+	m = &Matcher{Type: "word", Part: "error", Words: []string{"refused"}}
+	require.Nil(t, m.CompileMatchers(), "Could not compile error matcher")
+	require.True(t, m.MatchNetwork(refused, nil), "Could not match the kind of the error")
+	require.False(t, m.MatchNetwork(resp, nil), "Could match the kind of a missing error")
+}
+
+func TestFileMatcher(t *testing.T) {
+	resp := &file.Response{Path: "src/config.go", Data: "package config\n\nconst Token = \"ghp_abc\"\nconst Backup = \"GHP_ABC\"\n"}
+
+	m := &Matcher{Type: "regex", Regex: []string{`ghp_[a-z]+`}}
+	require.Nil(t, m.CompileMatchers(), "Could not compile regex matcher")
+	require.True(t, m.MatchFile(resp, nil), "Could not match the content")
+	require.Equal(t, []int{31}, m.Offsets(resp.Data, 10), "Could not locate the regex")
+
+	m = &Matcher{Type: "word", Words: []string{"ghp_abc"}, CaseInsensitive: true}
+	require.Nil(t, m.CompileMatchers(), "Could not compile word matcher")
+	require.Equal(t, []int{31, 56}, m.Offsets(resp.Data, 10), "Could not locate the words ignoring the case")
+	require.Equal(t, []int{31}, m.Offsets(resp.Data, 1), "Could not limit the offsets")
+
+	m = &Matcher{Type: "dsl", DSL: []string{`contains(path, "config") && contains(data, "Token")`}}
+	require.Nil(t, m.CompileMatchers(), "Could not compile dsl matcher")
+	require.True(t, m.MatchFile(resp, nil), "Could not match the path")
+	require.Empty(t, m.Offsets(resp.Data, 10), "Could locate a dsl matcher")
+}
+
+func TestWebsocketMatcher(t *testing.T) {
+	resp := &websocket.Response{
+		URL:        "wss://chat.example.com/socket",
+		StatusCode: 101,
+		Headers:    http.Header{"Sec-Websocket-Protocol": []string{"graphql-ws"}},
+		Upgrade:    "HTTP/1.1 101 Switching Protocols\r\nSec-Websocket-Protocol: graphql-ws\r\n\r\n",
+		Messages:   []string{`{"type":"connection_ack"}`, `{"type":"data","user":"admin"}`},
+	}
+
+	m := &Matcher{Type: "status", Status: []int{101}}
+	require.Nil(t, m.CompileMatchers(), "Could not compile status matcher")
+	require.True(t, m.MatchWebsocket(resp, nil), "Could not match the status of the upgrade")
+
+	m = &Matcher{Type: "word", Part: "header.Sec-WebSocket-Protocol", Words: []string{"graphql-ws"}}
+	require.Nil(t, m.CompileMatchers(), "Could not compile header matcher")
+	require.True(t, m.MatchWebsocket(resp, nil), "Could not match the header of the upgrade")
+
+	m = &Matcher{Type: "word", Words: []string{"Switching Protocols"}}
+	require.Nil(t, m.CompileMatchers(), "Could not compile word matcher")
+	require.False(t, m.MatchWebsocket(resp, nil), "Could match the upgrade in the messages")
+
+	m = &Matcher{Type: "json", JSON: []string{"user"}}
+	require.Nil(t, m.CompileMatchers(), "Could not compile json matcher")
+	require.True(t, m.MatchWebsocket(resp, nil), "Could not match a message")
+
+	m = &Matcher{Type: "dsl", DSL: []string{`status_code == 101 && sec_websocket_protocol == "graphql-ws" && contains(body, "admin")`}}
+	require.Nil(t, m.CompileMatchers(), "Could not compile dsl matcher")
+	require.True(t, m.MatchWebsocket(resp, nil), "Could not match the values of the upgrade")
+
+	refused := &websocket.Response{StatusCode: 403, Error: websocket.HandshakeError}
+	m = &Matcher{Type: "word", Part: "error", Words: []string{"handshake"}}
+	require.Nil(t, m.CompileMatchers(), "Could not compile error matcher")
+	require.True(t, m.MatchWebsocket(refused, nil), "Could not match the refused upgrade")
+	require.False(t, m.MatchWebsocket(resp, nil), "Could match the kind of a missing error")
+}
+
+func TestSSLMatcher(t *testing.T) {
+	server := httptest.NewTLSServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}))
+	defer server.Close()
+	resp := &ssl.Response{
+		Address:      "127.0.0.1:443",
+		ServerName:   "127.0.0.1",
+		Version:      "tls10",
+		Cipher:       "TLS_RSA_WITH_AES_128_CBC_SHA",
+		Certificates: []*x509.Certificate{server.Certificate()},
+		Untrusted:    true,
+	}
+
+	m := &Matcher{Type: "word", Part: "version", Words: []string{"tls10"}}
+	require.Nil(t, m.CompileMatchers(), "Could not compile version matcher")
+	require.True(t, m.MatchSSL(resp, nil), "Could not match the version")
+
+	m = &Matcher{Type: "regex", Part: "cipher", Regex: []string{"_CBC_"}}
+	require.Nil(t, m.CompileMatchers(), "Could not compile cipher matcher")
+	require.True(t, m.MatchSSL(resp, nil), "Could not match the cipher suite")
+
+	m = &Matcher{Type: "word", Part: "certificate", Words: []string{"issuer_org: Acme Co"}}
+	require.Nil(t, m.CompileMatchers(), "Could not compile certificate matcher")
+	require.True(t, m.MatchSSL(resp, nil), "Could not match the certificate")
+
+	m = &Matcher{Type: "dsl", DSL: []string{`self_signed && untrusted && !mismatched && !expired && expires_in_days > 30 && chain_length == 1 && subject_org == "Acme Co"`}}
+	require.Nil(t, m.CompileMatchers(), "Could not compile dsl matcher")
+	require.True(t, m.MatchSSL(resp, nil), "Could not match the values of the handshake")
+
+	refused := &ssl.Response{Address: "127.0.0.1:443", Error: ssl.HandshakeError}
+	m = &Matcher{Type: "word", Part: "error", Words: []string{"handshake"}}
+	require.Nil(t, m.CompileMatchers(), "Could not compile error matcher")
+	require.True(t, m.MatchSSL(refused, nil), "Could not match the refused handshake")
+	require.False(t, m.MatchSSL(resp, nil), "Could match the kind of a missing error")
+}
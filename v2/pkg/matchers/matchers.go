@@ -1,9 +1,15 @@
 package matchers
 
 import (
+	"net"
 	"regexp"
 
 	"github.com/Knetic/govaluate"
+	"github.com/antchfx/xpath"
+	"github.com/projectdiscovery/nuclei/v2/pkg/dnsrecords"
+	"github.com/projectdiscovery/nuclei/v2/pkg/headless"
+	"github.com/projectdiscovery/nuclei/v2/pkg/jsonpath"
+	"github.com/projectdiscovery/nuclei/v2/pkg/regexguard"
 )
 
 // Matcher is used to identify whether a template was successful.
@@ -21,16 +27,61 @@ type Matcher struct {
 	Size []int `yaml:"size,omitempty"`
 	// Words are the words required to be present in the response
 	Words []string `yaml:"words,omitempty"`
+	// CaseInsensitive matches the words irrespective of their case
+	CaseInsensitive bool `yaml:"case-insensitive,omitempty"`
+	// Count is the number of occurrences required for each word to match
+	Count int `yaml:"count,omitempty"`
+	// CountCondition is the comparison of the occurrences with the count,
+	// >= to require at least count occurrences (default) or == exactly count.
+	CountCondition string `yaml:"count-condition,omitempty"`
 	// Regex are the regex pattern required to be present in the response
 	Regex []string `yaml:"regex,omitempty"`
 	// regexCompiled is the compiled variant
 	regexCompiled []*regexp.Regexp
+	// guard limits the length of the inputs of the regexes and the regex
+	// dsl function, the default limit applying if nil.
+	guard *regexguard.Guard
 	// Binary are the binary characters required to be present in the response
 	Binary []string `yaml:"binary,omitempty"`
+	// binaryCompiled are the decoded binary characters
+	binaryCompiled []string
 	// DSL are the dsl queries
 	DSL []string `yaml:"dsl,omitempty"`
 	// dslCompiled is the compiled variant
 	dslCompiled []*govaluate.EvaluableExpression
+	// dslRaw is true if the dsl queries use the raw response, which is
+	// only dumped then.
+	dslRaw bool
+	// Baseline sends a baseline request without the payload delays for dsl
+	// matchers, its duration is available as duration_baseline.
+	Baseline bool `yaml:"baseline,omitempty"`
+	// XPath are the xpath expressions required to match the html or xml response
+	XPath []string `yaml:"xpath,omitempty"`
+	// xpathCompiled is the compiled variant
+	xpathCompiled []*xpath.Expr
+	// Attribute is the attribute of the nodes selected by the xpath expressions to compare with the words
+	Attribute string `yaml:"attribute,omitempty"`
+	// JSON are the json paths required to match the json response
+	JSON []string `yaml:"json,omitempty"`
+	// jsonCompiled is the compiled variant
+	jsonCompiled []*jsonpath.Path
+	// CIDR are the ip ranges required to contain the remote address
+	CIDR []string `yaml:"cidr,omitempty"`
+	// cidrCompiled is the compiled variant
+	cidrCompiled []*net.IPNet
+	// Threshold is the similarity ratio with the baseline of the target under
+	// which a response is considered different, from 0 to 1. Default is 0.9.
+	Threshold float64 `yaml:"threshold,omitempty"`
+
+	// Negative specifies if the match result should be reversed
+	Negative bool `yaml:"negative,omitempty"`
+
+	// Internal matchers gate the execution of the remaining requests of a
+	// template without producing results, stopping them when not matching.
+	Internal bool `yaml:"internal,omitempty"`
+	// Request is the 1-based index of the request an internal matcher is
+	// evaluated for, all the requests if not specified.
+	Request int `yaml:"request,omitempty"`
 
 	// Condition is the optional condition between two matcher variables
 	//
@@ -41,10 +92,16 @@ type Matcher struct {
 
 	// Part is the part of the request to match
 	//
-	// By default, matching is performed in request body.
+	// By default, matching is performed in request body. header.<name>
+	// matches the values of a single header, i.e header.server.
 	Part string `yaml:"part,omitempty"`
 	// part is the part of the request to match
 	part Part
+	// headerName is the name of the header to match for header.<name> parts
+	headerName string
+	// interactsh is true if the matcher matches the interactions of the
+	// requests with the interactsh server, by its part or its dsl queries.
+	interactsh bool
 }
 
 // MatcherType is the type of the matcher specified
@@ -63,16 +120,28 @@ const (
 	SizeMatcher
 	// DSLMatcher matches based upon dsl syntax
 	DSLMatcher
+	// XPathMatcher matches html or xml responses with xpath expressions
+	XPathMatcher
+	// JSONMatcher matches json responses with json paths
+	JSONMatcher
+	// CIDRMatcher matches the remote address with ip ranges
+	CIDRMatcher
+	// SimilarityMatcher matches responses differing from the baseline of the target
+	SimilarityMatcher
 )
 
 // MatcherTypes is an table for conversion of matcher type from string.
 var MatcherTypes = map[string]MatcherType{
-	"status": StatusMatcher,
-	"size":   SizeMatcher,
-	"word":   WordsMatcher,
-	"regex":  RegexMatcher,
-	"binary": BinaryMatcher,
-	"dsl":    DSLMatcher,
+	"status":     StatusMatcher,
+	"size":       SizeMatcher,
+	"word":       WordsMatcher,
+	"regex":      RegexMatcher,
+	"binary":     BinaryMatcher,
+	"dsl":        DSLMatcher,
+	"xpath":      XPathMatcher,
+	"json":       JSONMatcher,
+	"cidr":       CIDRMatcher,
+	"similarity": SimilarityMatcher,
 }
 
 // ConditionType is the type of condition for matcher
@@ -101,16 +170,114 @@ const (
 	HeaderPart
 	// AllPart matches both response body and headers of the response.
 	AllPart
+	// TracePart matches the delegation chain of a dns trace.
+	TracePart
+	// RRSIGPart matches the RRSIG records of a dns response.
+	RRSIGPart
+	// AnswerPart matches the answer section of a dns response.
+	AnswerPart
+	// AuthorityPart matches the authority section of a dns response.
+	AuthorityPart
+	// AdditionalPart matches the additional section of a dns response.
+	AdditionalPart
+	// InteractshProtocolPart matches the protocol of an interaction with
+	// the interactsh url of the request, i.e dns or http.
+	InteractshProtocolPart
+	// InteractshRequestPart matches the raw request of an interaction
+	InteractshRequestPart
+	// InteractshResponsePart matches the raw response of an interaction
+	InteractshResponsePart
+	// ConsolePart matches the console messages of a headless page
+	ConsolePart
+	// DialogPart matches the dialogs opened by a headless page
+	DialogPart
+	// NetworkPart matches the network requests of a headless page
+	NetworkPart
+	// ErrorPart matches the kind of the error of a network, websocket or ssl connection
+	ErrorPart
+	// VersionPart matches the version negotiated by a tls handshake
+	VersionPart
+	// CipherPart matches the cipher suite negotiated by a tls handshake
+	CipherPart
+	// CertificatePart matches the certificates of a tls handshake
+	CertificatePart
 )
 
+// headerPartPrefix is the prefix of the parts matching a single header
+const headerPartPrefix = "header."
+
 // PartTypes is an table for conversion of part type from string.
 var PartTypes = map[string]Part{
 	"body":   BodyPart,
 	"header": HeaderPart,
 	"all":    AllPart,
+	"trace":  TracePart,
+	"rrsig":  RRSIGPart,
+	// dns message sections
+	"answer":     AnswerPart,
+	"authority":  AuthorityPart,
+	"additional": AdditionalPart,
+	// interactions with the interactsh server
+	"interactsh_protocol": InteractshProtocolPart,
+	"interactsh_request":  InteractshRequestPart,
+	"interactsh_response": InteractshResponsePart,
+	// headless pages, the dom being their body
+	"dom":     BodyPart,
+	"console": ConsolePart,
+	"dialog":  DialogPart,
+	"network": NetworkPart,
+	// network connections, the bytes read being their body
+	"data":  BodyPart,
+	"error": ErrorPart,
+	// websocket connections, the messages read being their body
+	"messages": BodyPart,
+	// ssl handshakes, the negotiated values and the certificates being
+	// their body
+	"version":     VersionPart,
+	"cipher":      CipherPart,
+	"certificate": CertificatePart,
+}
+
+// interactshParts is the table of the values of the interactions matched
+// by the interactsh parts, also available to the dsl queries.
+var interactshParts = map[Part]string{
+	InteractshProtocolPart: "interactsh_protocol",
+	InteractshRequestPart:  "interactsh_request",
+	InteractshResponsePart: "interactsh_response",
+}
+
+// dnsSections is the table of the dns message sections of the parts
+var dnsSections = map[Part]string{
+	AnswerPart:     dnsrecords.AnswerSection,
+	AuthorityPart:  dnsrecords.AuthoritySection,
+	AdditionalPart: dnsrecords.AdditionalSection,
+}
+
+// headlessParts is the table of the recordings of a headless page of the parts
+var headlessParts = map[Part]func(*headless.Response) string{
+	ConsolePart: (*headless.Response).ConsoleString,
+	DialogPart:  (*headless.Response).DialogsString,
+	NetworkPart: (*headless.Response).RequestsString,
+}
+
+// AppliesTo returns true if an internal matcher is evaluated for the
+// request at the 0-based position of the template requests.
+func (m *Matcher) AppliesTo(position int) bool {
+	return m.Request == 0 || m.Request-1 == position
+}
+
+// UsesInteractsh returns true if the matcher matches the interactions with
+// the interactsh server, which are only known once they are received.
+func (m *Matcher) UsesInteractsh() bool {
+	return m.interactsh
 }
 
 // GetPart returns the part of the matcher
 func (m *Matcher) GetPart() Part {
 	return m.part
 }
+
+// SetGuard sets the guard limiting the inputs of the regexes of the matcher
+func (m *Matcher) SetGuard(guard *regexguard.Guard) {
+	m.guard = guard
+}
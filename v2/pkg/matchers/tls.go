@@ -0,0 +1,50 @@
+package matchers
+
+import (
+	"crypto/tls"
+	"strings"
+)
+
+// tlsVersions is the table of the names of the tls versions
+var tlsVersions = map[uint16]string{
+	tls.VersionSSL30: "ssl3.0",
+	tls.VersionTLS10: "tls1.0",
+	tls.VersionTLS11: "tls1.1",
+	tls.VersionTLS12: "tls1.2",
+	tls.VersionTLS13: "tls1.3",
+}
+
+// tlsToMap returns the dsl variables of a tls connection and its leaf certificate.
+//
+// ssl_not_before and ssl_not_after are unix timestamps, ssl_san contains the
+// dns names, ip addresses and emails of the certificate one per line.
+func tlsToMap(state *tls.ConnectionState) map[string]interface{} {
+	m := make(map[string]interface{})
+
+	m["ssl_version"] = tlsVersions[state.Version]
+	m["ssl_cipher"] = tls.CipherSuiteName(state.CipherSuite)
+
+	if len(state.PeerCertificates) == 0 {
+		return m
+	}
+	cert := state.PeerCertificates[0]
+
+	var san []string
+	san = append(san, cert.DNSNames...)
+	for _, ip := range cert.IPAddresses {
+		san = append(san, ip.String())
+	}
+	san = append(san, cert.EmailAddresses...)
+
+	m["ssl_subject_cn"] = cert.Subject.CommonName
+	m["ssl_subject"] = cert.Subject.String()
+	m["ssl_san"] = strings.Join(san, "\n")
+	m["ssl_issuer_cn"] = cert.Issuer.CommonName
+	m["ssl_issuer"] = cert.Issuer.String()
+	m["ssl_not_before"] = cert.NotBefore.Unix()
+	m["ssl_not_after"] = cert.NotAfter.Unix()
+	m["ssl_sig_alg"] = cert.SignatureAlgorithm.String()
+	m["ssl_serial"] = cert.SerialNumber.String()
+	m["ssl_chain_length"] = len(state.PeerCertificates)
+	return m
+}
@@ -2,14 +2,126 @@ package matchers
 
 import (
 	"fmt"
+	"math"
 	"net/http"
 	"net/http/httputil"
 	"strings"
+	"time"
+	"unicode/utf8"
 
 	"github.com/miekg/dns"
+	"github.com/projectdiscovery/nuclei/v2/pkg/dnsrecords"
+	"github.com/projectdiscovery/nuclei/v2/pkg/file"
+	"github.com/projectdiscovery/nuclei/v2/pkg/headless"
+	"github.com/projectdiscovery/nuclei/v2/pkg/network"
+	"github.com/projectdiscovery/nuclei/v2/pkg/ssl"
+	"github.com/projectdiscovery/nuclei/v2/pkg/websocket"
 )
 
-func httpToMap(resp *http.Response, body, headers string) (m map[string]interface{}) {
+// HTTPValues returns the variables of a http response available to the dsl
+// expressions, the variables of the response along with the duration of the
+// request in seconds and milliseconds and the address it connected to.
+func HTTPValues(resp *http.Response, body, headers string, duration time.Duration, remoteIP string) map[string]interface{} {
+	return httpValues(resp, body, headers, duration, remoteIP, true)
+}
+
+// httpValues returns the variables of a http response, along with the raw
+// response if required, which is dumped with its body.
+func httpValues(resp *http.Response, body, headers string, duration time.Duration, remoteIP string, raw bool) map[string]interface{} {
+	values := httpToMap(resp, body, headers, raw)
+	values["duration"] = duration.Seconds()
+	values["duration_ms"] = duration.Milliseconds()
+	values["remote_ip"] = remoteIP
+	return values
+}
+
+// DNSValues returns the variables of a dns response available to the dsl expressions
+func DNSValues(resp *dnsrecords.Response) map[string]interface{} {
+	return dnsToMap(resp)
+}
+
+// HeadlessValues returns the variables of the recordings of a headless page
+// available to the dsl expressions, the values of the named steps included.
+func HeadlessValues(resp *headless.Response) map[string]interface{} {
+	m := make(map[string]interface{}, len(resp.Values)+5)
+	for k, v := range resp.Values {
+		m[k] = v
+	}
+	m["dom"] = resp.DOM
+	m["url"] = resp.URL
+	m["console"] = resp.ConsoleString()
+	m["dialog"] = resp.DialogsString()
+	m["network"] = resp.RequestsString()
+	return m
+}
+
+// NetworkValues returns the variables of the bytes read from a network
+// connection available to the dsl expressions, the bytes read by the named
+// steps included.
+func NetworkValues(resp *network.Response) map[string]interface{} {
+	m := make(map[string]interface{}, len(resp.Values)+3)
+	for k, v := range resp.Values {
+		m[k] = v
+	}
+	m["data"] = resp.Data
+	m["error"] = resp.Error
+	m["address"] = resp.Address
+	return m
+}
+
+// FileValues returns the variables of a local file available to the dsl
+// expressions, its path and its content.
+func FileValues(resp *file.Response) map[string]interface{} {
+	return map[string]interface{}{
+		"path": resp.Path,
+		"data": resp.Data,
+	}
+}
+
+// WebsocketValues returns the variables of the messages read from an
+// upgraded websocket connection available to the dsl expressions, along
+// with the status and the headers of the upgrade response.
+func WebsocketValues(resp *websocket.Response) map[string]interface{} {
+	m := make(map[string]interface{}, len(resp.Headers)+5)
+	for k, v := range resp.Headers {
+		k = strings.ToLower(strings.TrimSpace(strings.Replace(k, "-", "_", -1)))
+		m[k] = strings.Join(v, " ")
+	}
+	m["status_code"] = resp.StatusCode
+	m["all_headers"] = resp.Upgrade
+	m["body"] = resp.String()
+	m["error"] = resp.Error
+	m["url"] = resp.URL
+	return m
+}
+
+// SSLValues returns the variables of a handshake with a tls server
+// available to the dsl expressions, the fields of the leaf certificate
+// included along with the days until it expires and whether it is expired,
+// self-signed, untrusted or not valid for the server name.
+func SSLValues(resp *ssl.Response) map[string]interface{} {
+	fields := resp.Fields()
+	m := make(map[string]interface{}, len(fields)+10)
+	for k, v := range fields {
+		m[k] = strings.Join(v, " ")
+	}
+	m["address"] = resp.Address
+	m["starttls"] = resp.StartTLS
+	m["error"] = resp.Error
+	m["certificate"] = resp.CertificatesString()
+	m["chain_length"] = len(resp.Certificates)
+	if len(resp.Certificates) > 0 {
+		leaf := resp.Certificates[0]
+		m["expires_in_days"] = int(math.Floor(time.Until(leaf.NotAfter).Hours() / 24))
+		m["expired"] = time.Now().After(leaf.NotAfter)
+		m["self_signed"] = ssl.SelfSigned(leaf)
+		m["untrusted"] = resp.Untrusted
+		m["mismatched"] = resp.Mismatched
+	}
+	return m
+}
+
+func httpToMap(resp *http.Response, body, headers string, raw bool) (m map[string]interface{}) {
 	m = make(map[string]interface{})
 
 	m["content_length"] = resp.ContentLength
@@ -21,15 +133,24 @@ func httpToMap(resp *http.Response, body, headers string) (m map[string]interfac
 	m["all_headers"] = headers
 
 	m["body"] = body
-	if r, err := httputil.DumpResponse(resp, true); err == nil {
-		m["raw"] = string(r)
+	if raw {
+		if r, err := httputil.DumpResponse(resp, true); err == nil {
+			m["raw"] = string(r)
+		}
+	}
+
+	if resp.TLS != nil {
+		for k, v := range tlsToMap(resp.TLS) {
+			m[k] = v
+		}
 	}
 
 	return m
 }
 
-func dnsToMap(msg *dns.Msg) (m map[string]interface{}) {
+func dnsToMap(resp *dnsrecords.Response) (m map[string]interface{}) {
 	m = make(map[string]interface{})
+	msg := resp.Msg
 
 	m["rcode"] = msg.Rcode
 	var qs string
@@ -58,5 +179,73 @@ func dnsToMap(msg *dns.Msg) (m map[string]interface{}) {
 
 	m["raw"] = msg.String()
 
+	// normalized fields of the answers, trace and transfer, i.e caa_tag, srv_port, etc.
+	for name, values := range resp.Fields() {
+		m[name] = strings.Join(values, "\n")
+	}
+	if resp.Trace != nil {
+		m["trace"] = resp.Trace.String()
+	}
+	if resp.Transfer != nil {
+		m["transfer_success"] = resp.Transfer.Success
+	}
+
+	// dnssec status of the response, the signature timestamps are the
+	// window in which all the signatures are valid as unix timestamps.
+	m["dnssec_validated"] = msg.AuthenticatedData
+	m["has_rrsig"] = dnsrecords.HasRecord(msg, dns.TypeRRSIG)
+	m["has_dnskey"] = dnsrecords.HasRecord(msg, dns.TypeDNSKEY)
+	m["has_ds"] = dnsrecords.HasRecord(msg, dns.TypeDS)
+	if inception, expiration, ok := dnsrecords.SignatureWindow(msg); ok {
+		m["rrsig_inception"] = inception
+		m["rrsig_expiration"] = expiration
+	}
+
 	return m
 }
+
+// isASCII returns true if a string only has ascii characters
+func isASCII(value string) bool {
+	for i := 0; i < len(value); i++ {
+		if value[i] >= utf8.RuneSelf {
+			return false
+		}
+	}
+	return true
+}
+
+// indexFold returns the index of the first occurrence of a lowercase word
+// in an ascii corpus ignoring the case, -1 if it is not present.
+func indexFold(corpus, word string) int {
+	if word == "" {
+		return 0
+	}
+	first, upper := word[0], word[0]
+	if 'a' <= first && first <= 'z' {
+		upper = first - 'a' + 'A'
+	}
+	for i := 0; i+len(word) <= len(corpus); i++ {
+		if c := corpus[i]; c != first && c != upper {
+			continue
+		}
+		if equalFoldASCII(corpus[i+1:i+len(word)], word[1:]) {
+			return i
+		}
+	}
+	return -1
+}
+
+// equalFoldASCII returns true if an ascii string is a lowercase one
+// ignoring the case.
+func equalFoldASCII(value, lower string) bool {
+	for i := 0; i < len(value); i++ {
+		c := value[i]
+		if 'A' <= c && c <= 'Z' {
+			c += 'a' - 'A'
+		}
+		if c != lower[i] {
+			return false
+		}
+	}
+	return true
+}
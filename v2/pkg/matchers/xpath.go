@@ -0,0 +1,40 @@
+package matchers
+
+import "github.com/projectdiscovery/nuclei/v2/pkg/xpathquery"
+
+// matchXPath matches xpath expressions against an html or xml document.
+//
+// An expression matches if it selects at least one node, or if words are
+// specified, one of the selected values (the attribute value if an attribute
+// is specified, the inner text otherwise) contains any of the words.
+func (m *Matcher) matchXPath(corpus string) bool {
+	document, err := xpathquery.Parse(corpus)
+	if err != nil {
+		return false
+	}
+
+	// Iterate over all the expressions accepted as valid
+	for i, expr := range m.xpathCompiled {
+		// Continue if the expression doesn't match
+		if !m.matchValues(document.Select(expr, m.Attribute)) {
+			// If we are in an AND request and a match failed,
+			// return false as the AND condition fails on any single mismatch.
+			if m.condition == ANDCondition {
+				return false
+			}
+			// Continue with the flow since its an OR Condition.
+			continue
+		}
+
+		// If the condition was an OR, return on the first match.
+		if m.condition == ORCondition {
+			return true
+		}
+
+		// If we are at the end of the expressions, return with true
+		if len(m.xpathCompiled)-1 == i {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,5 @@
+// Package network runs the steps of the network requests of the templates
+// over raw tcp or udp connections, optionally wrapped in tls, recording the
+// bytes read by each step for the matchers and the extractors along with
+// the kind of the error stopping the steps, i.e a refused connection.
+package network
@@ -0,0 +1,92 @@
+package network
+
+import (
+	"encoding/hex"
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// The encodings of the data of the inputs
+const (
+	// TextInput sends the data as is
+	TextInput = "text"
+	// HexInput sends the bytes of the hex encoded data, for the binary
+	// protocols, the spaces between the bytes being ignored.
+	HexInput = "hex"
+)
+
+// Input is a step of a network request, sending its data to the
+// connection then reading the answer.
+type Input struct {
+	// Data is the data sent, nothing being sent if empty, i.e to read the
+	// banner of the service.
+	Data string `yaml:"data,omitempty"`
+	// Type is the encoding of the data, text (default) or hex
+	Type string `yaml:"type,omitempty"`
+	// Read is the maximum number of bytes read once the data is sent, the
+	// read size of the request otherwise. -1 reads nothing.
+	Read int `yaml:"read,omitempty"`
+	// Name stores the bytes read by the step as a value of the matchers
+	// and the extractors.
+	Name string `yaml:"name,omitempty"`
+	// Timeout is the seconds the step can take, the timeout of the scan
+	// otherwise.
+	Timeout int `yaml:"timeout,omitempty"`
+}
+
+// Validate returns an error if the encoding of the input is unknown or its
+// hex data is malformed, the data with placeholders being decoded once they
+// are replaced.
+func (i *Input) Validate() error {
+	switch i.Type {
+	case "", TextInput:
+	case HexInput:
+		if !strings.Contains(i.Data, "{{") {
+			if _, err := i.Bytes(); err != nil {
+				return err
+			}
+		}
+	default:
+		return fmt.Errorf("unknown input type %s (supported: text, hex)", i.Type)
+	}
+	if i.Read < -1 {
+		return fmt.Errorf("invalid read %d", i.Read)
+	}
+	if i.Timeout < 0 {
+		return fmt.Errorf("invalid timeout %d", i.Timeout)
+	}
+	return nil
+}
+
+// Bytes returns the bytes sent by the input
+func (i *Input) Bytes() ([]byte, error) {
+	if i.Type != HexInput {
+		return []byte(i.Data), nil
+	}
+	data, err := hex.DecodeString(strings.Join(strings.Fields(i.Data), ""))
+	if err != nil {
+		return nil, fmt.Errorf("invalid hex data %s", i.Data)
+	}
+	return data, nil
+}
+
+// WithData returns a copy of the input sending other data, i.e once its
+// placeholders are replaced.
+func (i *Input) WithData(data string) *Input {
+	input := *i
+	input.Data = data
+	return &input
+}
+
+// String returns the data sent by the input, quoted or hex encoded, or
+// read for the inputs only reading.
+func (i *Input) String() string {
+	switch {
+	case i.Data == "":
+		return "read"
+	case i.Type == HexInput:
+		return "hex " + i.Data
+	}
+	return strconv.Quote(i.Data)
+}
@@ -0,0 +1,215 @@
+package network
+
+import (
+	"context"
+	"crypto/tls"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"strings"
+	"syscall"
+	"time"
+	"unicode"
+)
+
+// The protocols of the connections
+const (
+	// TCPProtocol connects over tcp, the default
+	TCPProtocol = "tcp"
+	// UDPProtocol sends the data of the inputs as udp datagrams
+	UDPProtocol = "udp"
+)
+
+// DefaultReadSize is the default number of bytes read by a step
+const DefaultReadSize = 1024
+
+// The kinds of the errors stopping the steps, matched by the error part
+const (
+	// RefusedError is a connection refused by the target, i.e a closed port
+	RefusedError = "refused"
+	// TimeoutError is a connection or a step timing out, i.e a filtered port
+	// or a service not answering.
+	TimeoutError = "timeout"
+	// ClosedError is a connection closed or reset by the target
+	ClosedError = "closed"
+	// OtherError is any other error of the connection
+	OtherError = "error"
+)
+
+// Options are the options of the connections of a request
+type Options struct {
+	// Protocol is the protocol of the connection, tcp or udp
+	Protocol string
+	// TLS wraps the tcp connection in tls, the certificate of the target
+	// not being verified.
+	TLS bool
+	// Timeout is the time the connection and each step can take
+	Timeout time.Duration
+	// ReadSize is the default number of bytes read by a step
+	ReadSize int
+}
+
+// Response is what the steps of a request read from a connection
+type Response struct {
+	// Address is the host and port the connection was made to
+	Address string
+	// Data are the bytes read by all the steps, as is
+	Data string
+	// Values are the bytes read by the named steps
+	Values map[string]string
+	// Error is the kind of the error which stopped the steps if any, i.e
+	// refused or timeout.
+	Error string
+}
+
+// String returns the bytes read by the steps
+func (r *Response) String() string {
+	return r.Data
+}
+
+// Dump returns the bytes read by the steps, hex dumped unless printable
+func (r *Response) Dump() string {
+	for _, c := range r.Data {
+		if c == unicode.ReplacementChar || (!unicode.IsPrint(c) && !unicode.IsSpace(c)) {
+			return hex.Dump([]byte(r.Data))
+		}
+	}
+	return r.Data
+}
+
+// Error is an error of the connection along with its kind
+type Error struct {
+	// Kind is the kind of the error, i.e refused
+	Kind string
+	// Err is the error of the connection
+	Err error
+}
+
+// Error returns the message of the error
+func (e *Error) Error() string {
+	return e.Err.Error()
+}
+
+// Unwrap returns the error of the connection
+func (e *Error) Unwrap() error {
+	return e.Err
+}
+
+// Run connects to an address and runs the steps in order over the same
+// connection, returning what they read along with the error which stopped
+// them. The errors of the connection are *Error, the response recording
+// their kind and the bytes read until then. Without inputs, the banner of
+// the service is read.
+func Run(ctx context.Context, address string, inputs []*Input, options *Options) (*Response, error) {
+	resp := &Response{Address: address, Values: make(map[string]string)}
+	protocol := options.Protocol
+	if protocol == "" {
+		protocol = TCPProtocol
+	}
+	readSize := options.ReadSize
+	if readSize <= 0 {
+		readSize = DefaultReadSize
+	}
+
+	dialer := &net.Dialer{Timeout: options.Timeout}
+	conn, err := dialer.DialContext(ctx, protocol, address)
+	if err != nil {
+		return resp, resp.fail(ctx, fmt.Errorf("could not connect: %w", err))
+	}
+	defer conn.Close()
+
+	// the steps in progress fail once the context is done
+	stop := make(chan struct{})
+	defer close(stop)
+	go func() {
+		select {
+		case <-ctx.Done():
+			conn.Close()
+		case <-stop:
+		}
+	}()
+
+	if options.TLS {
+		host, _, _ := net.SplitHostPort(address)
+		tlsConn := tls.Client(conn, &tls.Config{InsecureSkipVerify: true, ServerName: host})
+		tlsConn.SetDeadline(deadline(options.Timeout))
+		if err := tlsConn.Handshake(); err != nil {
+			return resp, resp.fail(ctx, fmt.Errorf("could not handshake: %w", err))
+		}
+		conn = tlsConn
+	}
+
+	if len(inputs) == 0 {
+		inputs = []*Input{{}}
+	}
+	data := &strings.Builder{}
+	defer func() { resp.Data = data.String() }()
+	for i, input := range inputs {
+		timeout := options.Timeout
+		if input.Timeout > 0 {
+			timeout = time.Duration(input.Timeout) * time.Second
+		}
+		conn.SetDeadline(deadline(timeout))
+
+		sent, err := input.Bytes()
+		if err != nil {
+			return resp, fmt.Errorf("step %d: %s", i+1, err)
+		}
+		if len(sent) > 0 {
+			if _, err := conn.Write(sent); err != nil {
+				return resp, resp.fail(ctx, fmt.Errorf("step %d: %w", i+1, err))
+			}
+		}
+		if input.Read < 0 {
+			continue
+		}
+		size := input.Read
+		if size == 0 {
+			size = readSize
+		}
+		buffer := make([]byte, size)
+		n, err := conn.Read(buffer)
+		data.Write(buffer[:n])
+		if input.Name != "" {
+			resp.Values[input.Name] = string(buffer[:n])
+		}
+		if err != nil {
+			return resp, resp.fail(ctx, fmt.Errorf("step %d: %w", i+1, err))
+		}
+	}
+	return resp, nil
+}
+
+// deadline returns the deadline of a step, none for a zero timeout
+func deadline(timeout time.Duration) time.Time {
+	if timeout <= 0 {
+		return time.Time{}
+	}
+	return time.Now().Add(timeout)
+}
+
+// fail records the kind of an error of the connection, returning the error
+// of the context instead once it is done.
+func (r *Response) fail(ctx context.Context, err error) error {
+	if ctxErr := ctx.Err(); ctxErr != nil {
+		return ctxErr
+	}
+	r.Error = ErrorKind(err)
+	return &Error{Kind: r.Error, Err: err}
+}
+
+// ErrorKind returns the kind of an error of a connection
+func ErrorKind(err error) string {
+	var netErr net.Error
+	switch {
+	case errors.As(err, &netErr) && netErr.Timeout():
+		return TimeoutError
+	case errors.Is(err, syscall.ECONNREFUSED):
+		return RefusedError
+	case errors.Is(err, io.EOF), errors.Is(err, syscall.ECONNRESET), errors.Is(err, syscall.EPIPE):
+		return ClosedError
+	}
+	return OtherError
+}
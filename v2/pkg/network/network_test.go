@@ -0,0 +1,157 @@
+package network
+
+import (
+	"bufio"
+	"context"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+// newRedisServer returns a listener answering PING and INFO like an
+// unauthenticated redis server, after a banner if any.
+func newRedisServer(t *testing.T, banner string) net.Listener {
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	require.Nil(t, err, "Could not listen")
+	go func() {
+		for {
+			conn, err := listener.Accept()
+			if err != nil {
+				return
+			}
+			go func(conn net.Conn) {
+				defer conn.Close()
+				if banner != "" {
+					conn.Write([]byte(banner))
+				}
+				reader := bufio.NewReader(conn)
+				for {
+					line, err := reader.ReadString('\n')
+					if err != nil {
+						return
+					}
+					switch strings.TrimSpace(line) {
+					case "PING":
+						conn.Write([]byte("+PONG\r\n"))
+					case "INFO":
+						conn.Write([]byte("$23\r\nredis_version:6.0.9\r\n\r\n"))
+					case "QUIT":
+						return
+					}
+				}
+			}(conn)
+		}
+	}()
+	return listener
+}
+
+func TestRun(t *testing.T) {
+	listener := newRedisServer(t, "")
+	defer listener.Close()
+
+	inputs := []*Input{
+		{Data: "PING\r\n", Name: "ping"},
+		{Data: "494e464f0d0a", Type: HexInput},
+	}
+	for _, input := range inputs {
+		require.Nil(t, input.Validate(), "Could not validate the input")
+	}
+	resp, err := Run(context.Background(), listener.Addr().String(), inputs, &Options{Timeout: time.Second})
+	require.Nil(t, err, "Could not run the steps")
+	require.Equal(t, "+PONG\r\n$23\r\nredis_version:6.0.9\r\n\r\n", resp.Data, "Could not read the answers")
+	require.Equal(t, map[string]string{"ping": "+PONG\r\n"}, resp.Values, "Could not store the named step")
+	require.Empty(t, resp.Error, "Could not run without error")
+}
+
+func TestRunBanner(t *testing.T) {
+	listener := newRedisServer(t, "220 mail.example.com ESMTP\r\n")
+	defer listener.Close()
+
+	resp, err := Run(context.Background(), listener.Addr().String(), nil, &Options{Timeout: time.Second})
+	require.Nil(t, err, "Could not read the banner")
+	require.Equal(t, "220 mail.example.com ESMTP\r\n", resp.Data, "Could not read the banner")
+
+	resp, err = Run(context.Background(), listener.Addr().String(), []*Input{{Read: 4}}, &Options{Timeout: time.Second})
+	require.Nil(t, err, "Could not read the banner")
+	require.Equal(t, "220 ", resp.Data, "Could not limit the read")
+}
+
+func TestRunErrors(t *testing.T) {
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	require.Nil(t, err, "Could not listen")
+	address := listener.Addr().String()
+	listener.Close()
+
+	resp, err := Run(context.Background(), address, nil, &Options{Timeout: time.Second})
+	require.NotNil(t, err, "Could not fail the closed port")
+	require.Equal(t, RefusedError, resp.Error, "Could not record the refused connection")
+	require.Equal(t, RefusedError, err.(*Error).Kind, "Could not return the kind")
+
+	silent := newRedisServer(t, "")
+	defer silent.Close()
+	start := time.Now()
+	resp, err = Run(context.Background(), silent.Addr().String(), []*Input{{Data: "PING\r\n"}, {Data: "UNKNOWN\r\n", Timeout: 1}}, &Options{Timeout: 5 * time.Second})
+	require.NotNil(t, err, "Could not time out the step")
+	require.Equal(t, TimeoutError, resp.Error, "Could not record the timeout")
+	require.Equal(t, "+PONG\r\n", resp.Data, "Could not keep the bytes read before the timeout")
+	require.True(t, time.Since(start) < 3*time.Second, "Could not use the timeout of the step")
+
+	resp, err = Run(context.Background(), silent.Addr().String(), []*Input{{Data: "QUIT\r\n"}}, &Options{Timeout: time.Second})
+	require.NotNil(t, err, "Could not fail the closed connection")
+	require.Equal(t, ClosedError, resp.Error, "Could not record the closed connection")
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	_, err = Run(ctx, silent.Addr().String(), nil, &Options{Timeout: time.Second})
+	require.Equal(t, context.Canceled, err, "Could not return the error of the context")
+}
+
+func TestRunTLS(t *testing.T) {
+	server := httptest.NewTLSServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte("hello over tls"))
+	}))
+	defer server.Close()
+
+	address := strings.TrimPrefix(server.URL, "https://")
+	resp, err := Run(context.Background(), address, []*Input{{Data: "GET / HTTP/1.0\r\n\r\n"}}, &Options{TLS: true, Timeout: time.Second, ReadSize: 4096})
+	require.Nil(t, err, "Could not run the steps over tls")
+	require.True(t, strings.HasPrefix(resp.Data, "HTTP/1.0 200 OK"), "Could not read the answer over tls")
+}
+
+func TestRunUDP(t *testing.T) {
+	conn, err := net.ListenPacket("udp", "127.0.0.1:0")
+	require.Nil(t, err, "Could not listen")
+	defer conn.Close()
+	go func() {
+		buffer := make([]byte, 512)
+		for {
+			n, addr, err := conn.ReadFrom(buffer)
+			if err != nil {
+				return
+			}
+			conn.WriteTo(append([]byte("echo "), buffer[:n]...), addr)
+		}
+	}()
+
+	resp, err := Run(context.Background(), conn.LocalAddr().String(), []*Input{{Data: "stats"}}, &Options{Protocol: UDPProtocol, Timeout: time.Second})
+	require.Nil(t, err, "Could not run the steps over udp")
+	require.Equal(t, "echo stats", resp.Data, "Could not read the datagram")
+}
+
+func TestInputValidate(t *testing.T) {
+	require.Nil(t, (&Input{Data: "2a31 0d0a", Type: HexInput}).Validate(), "Could not validate the spaced hex")
+	require.Nil(t, (&Input{Data: "{{payload}}", Type: HexInput}).Validate(), "Could not validate the placeholder")
+	require.EqualError(t, (&Input{Data: "zz", Type: HexInput}).Validate(), "invalid hex data zz", "Could not reject the hex")
+	require.EqualError(t, (&Input{Type: "base64"}).Validate(), "unknown input type base64 (supported: text, hex)", "Could not reject the type")
+	require.EqualError(t, (&Input{Read: -2}).Validate(), "invalid read -2", "Could not reject the read")
+}
+
+func TestResponseDump(t *testing.T) {
+	require.Equal(t, "+PONG\r\n", (&Response{Data: "+PONG\r\n"}).Dump(), "Could not dump the text")
+	require.Contains(t, (&Response{Data: "\x00\x01JRMI"}).Dump(), "00 01 4a 52 4d 49", "Could not hex dump the binary data")
+}
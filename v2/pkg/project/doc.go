@@ -0,0 +1,4 @@
+// Package project caches the responses of a scan on disk by request, so the
+// identical requests of the next runs towards the same targets reuse them
+// instead of being sent again.
+package project
@@ -0,0 +1,133 @@
+package project
+
+import (
+	"crypto/sha256"
+	"encoding/binary"
+	"encoding/hex"
+	"io/ioutil"
+	"net/http"
+	"os"
+	"path/filepath"
+	"time"
+
+	jsoniter "github.com/json-iterator/go"
+)
+
+// DefaultTTL is the default duration the responses are reused for
+const DefaultTTL = 24 * time.Hour
+
+// Response is a response stored in the cache, its body being decompressed
+type Response struct {
+	// Stored is the time the response was stored at
+	Stored     time.Time   `json:"stored"`
+	URL        string      `json:"url"`
+	StatusCode int         `json:"status_code"`
+	Proto      string      `json:"proto"`
+	Header     http.Header `json:"header"`
+	Body       []byte      `json:"body"`
+	// Duration is the time the response took, reused by the time based
+	// matchers.
+	Duration time.Duration `json:"duration"`
+	RemoteIP string        `json:"remote_ip,omitempty"`
+}
+
+// Cache stores the responses in a directory, a json file per request named
+// by the hash of the request. It is safe for concurrent use, the files
+// being written to a temporary file renamed once complete. The methods of
+// a nil cache do nothing, the requests not being cached.
+type Cache struct {
+	directory string
+	// ttl is the duration the responses are reused for, 0 for ever
+	ttl time.Duration
+	// rewrite ignores the stored responses, storing the responses again
+	rewrite bool
+}
+
+// New returns a cache of the responses in a directory, created if missing,
+// reusing them for ttl, for ever if 0, or storing them again if rewrite is
+// true.
+func New(directory string, ttl time.Duration, rewrite bool) (*Cache, error) {
+	if err := os.MkdirAll(directory, 0700); err != nil {
+		return nil, err
+	}
+	return &Cache{directory: directory, ttl: ttl, rewrite: rewrite}, nil
+}
+
+// Key returns the key of a request made of parts, the hash of the parts
+// prefixed with their length so different parts can't have the same key.
+func Key(parts ...string) string {
+	hash := sha256.New()
+	length := make([]byte, 8)
+	for _, part := range parts {
+		binary.BigEndian.PutUint64(length, uint64(len(part)))
+		hash.Write(length)
+		hash.Write([]byte(part))
+	}
+	return hex.EncodeToString(hash.Sum(nil))
+}
+
+// path returns the file of a key, in a subdirectory named by its first two
+// characters to keep the directories small.
+func (c *Cache) path(key string) string {
+	return filepath.Join(c.directory, key[:2], key+".json")
+}
+
+// Get returns the stored response of a key, false if there is none, if it
+// expired, if it can't be read or with rewrite.
+func (c *Cache) Get(key string) (*Response, bool) {
+	if c == nil || c.rewrite {
+		return nil, false
+	}
+	data, err := ioutil.ReadFile(c.path(key))
+	if err != nil {
+		return nil, false
+	}
+	response := &Response{}
+	if err := jsoniter.Unmarshal(data, response); err != nil {
+		return nil, false
+	}
+	if c.ttl > 0 && time.Since(response.Stored) > c.ttl {
+		return nil, false
+	}
+	return response, true
+}
+
+// Set stores the response of a key, replacing the previous one if any. The
+// concurrent writers of a key don't corrupt the file, the last one being
+// kept.
+func (c *Cache) Set(key string, response *Response) error {
+	if c == nil {
+		return nil
+	}
+	if response.Stored.IsZero() {
+		response.Stored = time.Now()
+	}
+	data, err := jsoniter.Marshal(response)
+	if err != nil {
+		return err
+	}
+
+	path := c.path(key)
+	directory := filepath.Dir(path)
+	if err := os.MkdirAll(directory, 0700); err != nil {
+		return err
+	}
+	file, err := ioutil.TempFile(directory, ".tmp-*")
+	if err != nil {
+		return err
+	}
+	if _, err := file.Write(data); err != nil {
+		file.Close()
+		os.Remove(file.Name())
+		return err
+	}
+	if err := file.Close(); err != nil {
+		os.Remove(file.Name())
+		return err
+	}
+	if err := os.Rename(file.Name(), path); err != nil {
+		os.Remove(file.Name())
+		return err
+	}
+	return nil
+}
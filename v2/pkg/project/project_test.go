@@ -0,0 +1,79 @@
+package project
+
+import (
+	"io/ioutil"
+	"net/http"
+	"os"
+	"strconv"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestCache(t *testing.T) {
+	directory, err := ioutil.TempDir("", "nuclei-project-test")
+	require.Nil(t, err, "Could not create temporary directory")
+	defer os.RemoveAll(directory)
+
+	cache, err := New(directory, time.Hour, false)
+	require.Nil(t, err, "Could not create cache")
+	key := Key("GET", "http://example.com/", "")
+	_, ok := cache.Get(key)
+	require.False(t, ok, "Could not miss the response not stored")
+
+	response := &Response{URL: "http://example.com/", StatusCode: 200, Proto: "HTTP/1.1", Header: http.Header{"Server": {"test"}}, Body: []byte("ok"), Duration: time.Second}
+	require.Nil(t, cache.Set(key, response), "Could not store response")
+	stored, ok := cache.Get(key)
+	require.True(t, ok, "Could not get the stored response")
+	require.Equal(t, 200, stored.StatusCode, "Could not get the status code")
+	require.Equal(t, "test", stored.Header.Get("Server"), "Could not get the headers")
+	require.Equal(t, []byte("ok"), stored.Body, "Could not get the body")
+	require.Equal(t, time.Second, stored.Duration, "Could not get the duration")
+
+	rewrite, err := New(directory, time.Hour, true)
+	require.Nil(t, err, "Could not create rewriting cache")
+	_, ok = rewrite.Get(key)
+	require.False(t, ok, "Could not ignore the stored response with rewrite")
+
+	expired := &Response{Stored: time.Now().Add(-2 * time.Hour), StatusCode: 404}
+	require.Nil(t, cache.Set(key, expired), "Could not replace response")
+	_, ok = cache.Get(key)
+	require.False(t, ok, "Could not expire the response after the ttl")
+	forever, err := New(directory, 0, false)
+	require.Nil(t, err, "Could not create cache without ttl")
+	_, ok = forever.Get(key)
+	require.True(t, ok, "Could not keep the response without ttl")
+
+	require.NotEqual(t, Key("a", "bc"), Key("ab", "c"), "Could not separate the parts of the keys")
+}
+
+func TestCacheConcurrentWriters(t *testing.T) {
+	directory, err := ioutil.TempDir("", "nuclei-project-test")
+	require.Nil(t, err, "Could not create temporary directory")
+	defer os.RemoveAll(directory)
+
+	cache, err := New(directory, 0, false)
+	require.Nil(t, err, "Could not create cache")
+	key := Key("GET", "http://example.com/")
+	wg := &sync.WaitGroup{}
+	for i := 0; i < 20; i++ {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			require.Nil(t, cache.Set(key, &Response{Body: []byte(strconv.Itoa(i))}), "Could not store response concurrently")
+		}(i)
+	}
+	wg.Wait()
+
+	stored, ok := cache.Get(key)
+	require.True(t, ok, "Could not get the response of concurrent writers")
+	_, err = strconv.Atoi(string(stored.Body))
+	require.Nil(t, err, "Could not store a complete response")
+
+	var nilCache *Cache
+	require.Nil(t, nilCache.Set(key, &Response{}), "Could not ignore the responses of a nil cache")
+	_, ok = nilCache.Get(key)
+	require.False(t, ok, "Could not miss with a nil cache")
+}
@@ -0,0 +1,4 @@
+// Package ratelimit limits the rate of the requests sent to each host of a
+// scan, so a fragile host doesn't get all the requests of the concurrency
+// of the scan at once.
+package ratelimit
@@ -0,0 +1,212 @@
+package ratelimit
+
+import (
+	"container/list"
+	"context"
+	"net"
+	"net/url"
+	"strings"
+	"sync"
+	"time"
+)
+
+// bucket is the time the next request to a host can be sent at
+type bucket struct {
+	host string
+	next time.Time
+}
+
+// Limiter spaces the requests sent to each host so they don't exceed a
+// rate, a token bucket of a single token per host being refilled at the
+// rate. The buckets are created on the first request to a host and expire
+// once full, a full bucket being the same as a new one, so the hosts which
+// are not requested anymore are not kept. The methods of a nil limiter do
+// nothing.
+type Limiter struct {
+	interval time.Duration
+	// delayed is called with the wait of each delayed request if not nil
+	delayed func(wait time.Duration)
+
+	mutex   sync.Mutex
+	buckets map[string]*list.Element
+	// used are the buckets by time of their last request, the least
+	// recently used first.
+	used *list.List
+	// resumed is closed once a paused limiter is resumed, nil if it is not
+	// paused, pausedAt being the start of the pause and shifted the total
+	// duration of the pauses so far.
+	resumed  chan struct{}
+	pausedAt time.Time
+	shifted  time.Duration
+}
+
+// New returns a limiter of a number of requests per second to each host,
+// calling delayed with the wait of the requests delayed if not nil.
+func New(rate int, delayed func(wait time.Duration)) *Limiter {
+	return &Limiter{
+		interval: time.Second / time.Duration(rate),
+		delayed:  delayed,
+		buckets:  make(map[string]*list.Element),
+		used:     list.New(),
+	}
+}
+
+// Wait waits until a request can be sent to a host, returning the error of
+// the context if it is done first. The requests waiting for a host are sent
+// in order, and no request is sent while the limiter is paused, the ones
+// waiting being delayed by the pause.
+func (l *Limiter) Wait(ctx context.Context, host string) error {
+	if l == nil {
+		return nil
+	}
+	if err := l.waitResumed(ctx); err != nil {
+		return err
+	}
+	wait, shifted := l.reserveShifted(host, time.Now())
+	if wait <= 0 {
+		return nil
+	}
+	if l.delayed != nil {
+		l.delayed(wait)
+	}
+	for wait > 0 {
+		timer := time.NewTimer(wait)
+		select {
+		case <-timer.C:
+		case <-ctx.Done():
+			timer.Stop()
+			return ctx.Err()
+		}
+		if err := l.waitResumed(ctx); err != nil {
+			return err
+		}
+		// the time of the request moved with the pauses since it waits
+		l.mutex.Lock()
+		wait, shifted = l.shifted-shifted, l.shifted
+		l.mutex.Unlock()
+	}
+	return nil
+}
+
+// waitResumed waits until the limiter is not paused, returning the error
+// of the context if it is done first.
+func (l *Limiter) waitResumed(ctx context.Context) error {
+	for {
+		l.mutex.Lock()
+		resumed := l.resumed
+		l.mutex.Unlock()
+		if resumed == nil {
+			return nil
+		}
+		select {
+		case <-resumed:
+		case <-ctx.Done():
+			return ctx.Err()
+		}
+	}
+}
+
+// Pause stops handing out the tokens of the hosts until Resume is called,
+// the requests waiting for a token waiting for the resume too.
+func (l *Limiter) Pause() {
+	if l == nil {
+		return
+	}
+	l.mutex.Lock()
+	defer l.mutex.Unlock()
+	if l.resumed == nil {
+		l.resumed = make(chan struct{})
+		l.pausedAt = time.Now()
+	}
+}
+
+// Resume hands out the tokens again, the buckets not refilled before the
+// pause being refilled as much later as the pause lasted, so the requests
+// delayed by the pause don't burst.
+func (l *Limiter) Resume() {
+	if l == nil {
+		return
+	}
+	l.mutex.Lock()
+	defer l.mutex.Unlock()
+	if l.resumed == nil {
+		return
+	}
+	pause := time.Since(l.pausedAt)
+	for element := l.used.Front(); element != nil; element = element.Next() {
+		if b := element.Value.(*bucket); b.next.After(l.pausedAt) {
+			b.next = b.next.Add(pause)
+		}
+	}
+	l.shifted += pause
+	close(l.resumed)
+	l.resumed = nil
+}
+
+// reserve takes the token of a host, returning the time to wait until it
+// is refilled if the bucket is empty.
+func (l *Limiter) reserve(host string, now time.Time) time.Duration {
+	wait, _ := l.reserveShifted(host, now)
+	return wait
+}
+
+// reserveShifted takes the token of a host like reserve, returning the
+// total duration of the pauses at the time along with the time to wait.
+func (l *Limiter) reserveShifted(host string, now time.Time) (time.Duration, time.Duration) {
+	l.mutex.Lock()
+	defer l.mutex.Unlock()
+	l.expire(now)
+
+	var b *bucket
+	if element, ok := l.buckets[host]; ok {
+		b = element.Value.(*bucket)
+		l.used.MoveToBack(element)
+	} else {
+		b = &bucket{host: host, next: now}
+		l.buckets[host] = l.used.PushBack(b)
+	}
+	at := b.next
+	if at.Before(now) {
+		at = now
+	}
+	b.next = at.Add(l.interval)
+	return at.Sub(now), l.shifted
+}
+
+// expire removes the least recently used buckets which are full
+func (l *Limiter) expire(now time.Time) {
+	for element := l.used.Front(); element != nil; element = l.used.Front() {
+		b := element.Value.(*bucket)
+		if b.next.After(now) {
+			return
+		}
+		l.used.Remove(element)
+		delete(l.buckets, b.host)
+	}
+}
+
+// Len returns the number of hosts with a bucket
+func (l *Limiter) Len() int {
+	if l == nil {
+		return 0
+	}
+	l.mutex.Lock()
+	defer l.mutex.Unlock()
+	return len(l.buckets)
+}
+
+// HostPort returns the host of the limiter for a url, the lower case
+// hostname with the port or the default port of its scheme.
+func HostPort(u *url.URL) string {
+	hostname := strings.TrimSuffix(strings.ToLower(u.Hostname()), ".")
+	port := u.Port()
+	if port == "" {
+		switch strings.ToLower(u.Scheme) {
+		case "https", "wss":
+			port = "443"
+		default:
+			port = "80"
+		}
+	}
+	return net.JoinHostPort(hostname, port)
+}
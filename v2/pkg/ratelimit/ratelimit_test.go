@@ -0,0 +1,100 @@
+package ratelimit
+
+import (
+	"context"
+	"net/url"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestLimiter(t *testing.T) {
+	var delayed int
+	limiter := New(10, func(wait time.Duration) {
+		delayed++
+	})
+	now := time.Now()
+
+	require.Equal(t, time.Duration(0), limiter.reserve("a.example.com:443", now), "Could not send the first request")
+	require.Equal(t, 100*time.Millisecond, limiter.reserve("a.example.com:443", now), "Could not delay the second request")
+	require.Equal(t, 200*time.Millisecond, limiter.reserve("a.example.com:443", now), "Could not queue the third request")
+	require.Equal(t, time.Duration(0), limiter.reserve("b.example.com:443", now), "Could not limit each host separately")
+	require.Equal(t, 2, limiter.Len(), "Could not create the buckets")
+
+	// the buckets are full again once their requests are sent
+	require.Equal(t, time.Duration(0), limiter.reserve("c.example.com:443", now.Add(time.Second)), "Could not refill the bucket")
+	require.Equal(t, 1, limiter.Len(), "Could not expire the full buckets")
+
+	start := time.Now()
+	require.Nil(t, limiter.Wait(context.Background(), "d.example.com:80"), "Could not wait for host")
+	require.Nil(t, limiter.Wait(context.Background(), "d.example.com:80"), "Could not wait for host")
+	require.True(t, time.Since(start) >= 100*time.Millisecond, "Could not space the requests")
+	require.Equal(t, 1, delayed, "Could not report the delayed request")
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	require.Equal(t, context.Canceled, limiter.Wait(ctx, "d.example.com:80"), "Could not stop waiting with the context")
+
+	var nilLimiter *Limiter
+	require.Nil(t, nilLimiter.Wait(context.Background(), "a.example.com:443"), "Could not ignore nil limiter")
+}
+
+func TestLimiterPause(t *testing.T) {
+	limiter := New(10, nil)
+	require.Nil(t, limiter.Wait(context.Background(), "a.example.com:443"), "Could not send the first request")
+
+	// the second request waits for its token and for the pause
+	limiter.Pause()
+	done := make(chan time.Time, 1)
+	start := time.Now()
+	go func() {
+		limiter.Wait(context.Background(), "a.example.com:443")
+		done <- time.Now()
+	}()
+	time.Sleep(200 * time.Millisecond)
+	select {
+	case <-done:
+		t.Fatal("Could not hold the requests of a paused limiter")
+	default:
+	}
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	require.Equal(t, context.Canceled, limiter.Wait(ctx, "b.example.com:443"), "Could not stop waiting for the resume with the context")
+
+	limiter.Resume()
+	limiter.Resume()
+	require.True(t, (<-done).Sub(start) >= 200*time.Millisecond, "Could not send the request once resumed")
+
+	// a request reserved before the pause is delayed by the pause
+	limiter = New(5, nil)
+	limiter.Wait(context.Background(), "c.example.com:443")
+	start = time.Now()
+	go func() {
+		limiter.Wait(context.Background(), "c.example.com:443")
+		done <- time.Now()
+	}()
+	time.Sleep(50 * time.Millisecond)
+	limiter.Pause()
+	time.Sleep(100 * time.Millisecond)
+	limiter.Resume()
+	require.True(t, (<-done).Sub(start) >= 300*time.Millisecond, "Could not delay the reserved request by the pause")
+
+	var nilLimiter *Limiter
+	nilLimiter.Pause()
+	nilLimiter.Resume()
+}
+
+func TestHostPort(t *testing.T) {
+	for value, expected := range map[string]string{
+		"https://Example.com/path":    "example.com:443",
+		"http://example.com./":        "example.com:80",
+		"http://example.com:8080/a":   "example.com:8080",
+		"https://[::1]:8443/":         "[::1]:8443",
+		"https://user@example.com/?a": "example.com:443",
+	} {
+		u, err := url.Parse(value)
+		require.Nil(t, err, "Could not parse url")
+		require.Equal(t, expected, HostPort(u), "Could not normalize host of %s", value)
+	}
+}
@@ -0,0 +1,5 @@
+// Package redact replaces the values of the sensitive headers and of the
+// secrets expanded in the templates in everything written by the engine,
+// such as the raw requests and responses of the results and the debug
+// output, to share the evidence safely.
+package redact
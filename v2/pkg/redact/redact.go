@@ -0,0 +1,91 @@
+package redact
+
+import (
+	"encoding/json"
+	"fmt"
+	"regexp"
+	"strings"
+)
+
+// DefaultHeaders are the names of the headers redacted by default
+var DefaultHeaders = []string{"Authorization", "Cookie", "Set-Cookie", "X-Api-Key"}
+
+// Default redacts the default headers
+var Default = New(nil)
+
+// Redactor replaces the values of the sensitive headers of the raw http
+// requests and responses, the names of the headers being case insensitive.
+type Redactor struct {
+	headers map[string]struct{}
+	lines   *regexp.Regexp
+}
+
+// New returns a redactor of the default headers along with other headers
+func New(headers []string) *Redactor {
+	redactor := &Redactor{headers: make(map[string]struct{})}
+	var names []string
+	for _, name := range append(append([]string{}, DefaultHeaders...), headers...) {
+		name = strings.ToLower(strings.TrimSpace(name))
+		if _, ok := redactor.headers[name]; ok || name == "" {
+			continue
+		}
+		redactor.headers[name] = struct{}{}
+		names = append(names, regexp.QuoteMeta(name))
+	}
+	redactor.lines = regexp.MustCompile(`(?im)^(` + strings.Join(names, "|") + `)([ \t]*:[ \t]*)([^\r\n]*)`)
+	return redactor
+}
+
+// ParseHeaders returns the names of the comma separated headers
+func ParseHeaders(value string) []string {
+	var headers []string
+	for _, name := range strings.Split(value, ",") {
+		if name = strings.TrimSpace(name); name != "" {
+			headers = append(headers, name)
+		}
+	}
+	return headers
+}
+
+// Marker returns the marker replacing a redacted value, with its length
+func Marker(value string) string {
+	return fmt.Sprintf("[REDACTED:%d bytes]", len(value))
+}
+
+// markerValue matches the values already replaced by their marker
+var markerValue = regexp.MustCompile(`^\[REDACTED:\d+ bytes\]$`)
+
+// Header returns the value of a header, redacted if the header is sensitive.
+// The values already redacted, i.e as secrets, keep their marker.
+func (r *Redactor) Header(name, value string) string {
+	if _, ok := r.headers[strings.ToLower(name)]; !ok || value == "" || markerValue.MatchString(value) {
+		return value
+	}
+	return Marker(value)
+}
+
+// Redact replaces the values of the sensitive header lines of a text, such
+// as a raw http request or response, keeping the markers of the values
+// already redacted.
+func (r *Redactor) Redact(text string) string {
+	return r.lines.ReplaceAllStringFunc(text, func(line string) string {
+		parts := r.lines.FindStringSubmatch(line)
+		if parts[3] == "" || markerValue.MatchString(parts[3]) {
+			return line
+		}
+		return parts[1] + parts[2] + Marker(parts[3])
+	})
+}
+
+// Secrets replaces the secret values of a text, in their raw and json
+// escaped forms.
+func Secrets(text string, secrets []string) string {
+	for _, secret := range secrets {
+		marker := Marker(secret)
+		text = strings.Replace(text, secret, marker, -1)
+		if escaped, err := json.Marshal(secret); err == nil {
+			text = strings.Replace(text, string(escaped[1:len(escaped)-1]), marker, -1)
+		}
+	}
+	return text
+}
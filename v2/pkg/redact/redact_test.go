@@ -0,0 +1,28 @@
+package redact
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestRedact(t *testing.T) {
+	redactor := New([]string{" X-Token ", "authorization", ""})
+
+	raw := "GET / HTTP/1.1\r\nHost: example.com\r\nauthorization: Bearer abc\r\nCookie:session=12345\r\nX-Token: t0k3n\r\nX-Other: kept\r\nX-Api-Key:\r\n\r\nAuthorization: in the body\n"
+	expected := "GET / HTTP/1.1\r\nHost: example.com\r\nauthorization: [REDACTED:10 bytes]\r\nCookie:[REDACTED:13 bytes]\r\nX-Token: [REDACTED:5 bytes]\r\nX-Other: kept\r\nX-Api-Key:\r\n\r\nAuthorization: [REDACTED:11 bytes]\n"
+	require.Equal(t, expected, redactor.Redact(raw), "Could not redact the sensitive headers")
+	require.Equal(t, `{"request":"GET / HTTP/1.1\r\nCookie: a"}`, redactor.Redact(`{"request":"GET / HTTP/1.1\r\nCookie: a"}`), "Could not leave the json escaped lines as is")
+
+	require.Equal(t, "[REDACTED:3 bytes]", redactor.Header("SET-COOKIE", "a=b"), "Could not redact a default header")
+	require.Equal(t, "kept", redactor.Header("X-Other", "kept"), "Could not keep a header")
+	require.Equal(t, "[REDACTED:14 bytes]", redactor.Header("X-Api-Key", Marker("env-api-key-14")), "Could redact a redacted header again")
+	require.Equal(t, "X-Api-Key: [REDACTED:14 bytes]\r\n", redactor.Redact("X-Api-Key: "+Marker("env-api-key-14")+"\r\n"), "Could redact a redacted header line again")
+	require.Equal(t, "Cookie: [REDACTED:3 bytes]", Default.Redact("Cookie: a=b"), "Could not redact with the default headers")
+	require.Equal(t, []string{"X-Token", "X-Auth"}, ParseHeaders(" X-Token,,X-Auth "), "Could not parse the headers")
+}
+
+func TestSecrets(t *testing.T) {
+	require.Equal(t, `{"key":"[REDACTED:6 bytes]"} [REDACTED:6 bytes]`, Secrets(`{"key":"s3cr\"t"} s3cr"t`, []string{`s3cr"t`}), "Could not redact the raw and escaped secrets")
+	require.Equal(t, "no secret", Secrets("no secret", nil), "Could not keep a text without secrets")
+}
@@ -0,0 +1,3 @@
+// Package regexguard limits the length of the inputs the regexes of
+// the matchers, extractors and dsl functions are applied to.
+package regexguard
@@ -0,0 +1,52 @@
+package regexguard
+
+import "sync/atomic"
+
+// DefaultMaxSize is the default maximum length of a regex input in bytes
+const DefaultMaxSize = 5 * 1024 * 1024
+
+// Guard limits the length of the regex inputs of a scan and counts the
+// inputs it truncated, each scan having its own. A nil guard applies the
+// default limit without counting.
+type Guard struct {
+	maxSize   int
+	oversized uint64
+}
+
+// New creates a guard limiting the regex inputs to a maximum length, 0
+// disabling the limit.
+func New(maxSize int) *Guard {
+	return &Guard{maxSize: maxSize}
+}
+
+// MaxSize returns the maximum length of a regex input
+func (g *Guard) MaxSize() int {
+	if g == nil {
+		return DefaultMaxSize
+	}
+	return g.maxSize
+}
+
+// Input returns the corpus a regex is applied to, truncated to the maximum
+// length. Truncated inputs are counted to warn about them after the scan.
+//
+// Go regexes run in linear time so they can't backtrack catastrophically,
+// but a single pattern applied to a large body can still take long.
+func (g *Guard) Input(corpus string) string {
+	size := g.MaxSize()
+	if size <= 0 || len(corpus) <= size {
+		return corpus
+	}
+	if g != nil {
+		atomic.AddUint64(&g.oversized, 1)
+	}
+	return corpus[:size]
+}
+
+// Oversized returns the number of regex inputs truncated to the maximum length
+func (g *Guard) Oversized() uint64 {
+	if g == nil {
+		return 0
+	}
+	return atomic.LoadUint64(&g.oversized)
+}
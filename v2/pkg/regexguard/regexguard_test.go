@@ -0,0 +1,21 @@
+package regexguard
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestInput(t *testing.T) {
+	guard := New(4)
+	require.Equal(t, "abc", guard.Input("abc"), "Could not keep input under the limit")
+	require.Equal(t, "abcd", guard.Input("abcdef"), "Could not truncate oversized input")
+	require.Equal(t, uint64(1), guard.Oversized(), "Could not count oversized input")
+	require.Equal(t, uint64(0), New(4).Oversized(), "Could share the count of another guard")
+
+	corpus := strings.Repeat("a", DefaultMaxSize+1)
+	require.Equal(t, corpus, New(0).Input(corpus), "Could not disable the limit")
+	var defaults *Guard
+	require.Equal(t, DefaultMaxSize, len(defaults.Input(corpus)), "Could not apply the default limit")
+}
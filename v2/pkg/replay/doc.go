@@ -0,0 +1,4 @@
+// Package replay sends the requests of the results of a scan again through
+// a proxy, so the findings land in its history for a manual follow-up
+// without proxying the whole scan.
+package replay
@@ -0,0 +1,196 @@
+package replay
+
+import (
+	"bytes"
+	"context"
+	"crypto/tls"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"net/url"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// TemplateHeader is the header of the replayed requests naming the
+// template of their result, so they can be found in the proxy history.
+const TemplateHeader = "X-Nuclei-Template"
+
+// DefaultRate is the default number of requests replayed per second
+const DefaultRate = 10
+
+// queueSize is the number of requests waiting to be replayed, the next
+// ones being dropped so the scan is never slowed down by the proxy.
+const queueSize = 1000
+
+// Options are the settings of a replayer
+type Options struct {
+	// ProxyURL is the proxy the requests are replayed through, the https
+	// requests being tunneled with CONNECT.
+	ProxyURL string
+	// Rate is the number of requests replayed per second
+	Rate    int
+	Timeout time.Duration
+	// Failed is called with the error of each failed replay if not nil
+	Failed func(req *http.Request, err error)
+}
+
+// entry is a request waiting to be replayed
+type entry struct {
+	request *http.Request
+	body    []byte
+}
+
+// Replayer sends the requests of the results again through a proxy, in
+// the background and at a rate, the failures being counted without
+// affecting the results. The methods of a nil replayer do nothing.
+type Replayer struct {
+	client   *http.Client
+	interval time.Duration
+	failed   func(req *http.Request, err error)
+
+	// mutex guards the queue, closed once the replayer is
+	mutex  sync.RWMutex
+	queue  chan *entry
+	closed bool
+	done   chan struct{}
+
+	replayedCount uint64
+	failedCount   uint64
+	droppedCount  uint64
+}
+
+// New returns a replayer through a proxy, replaying the requests as they
+// are queued until closed.
+func New(options Options) (*Replayer, error) {
+	proxyURL, err := url.Parse(options.ProxyURL)
+	if err != nil {
+		return nil, err
+	}
+	transport := &http.Transport{
+		Proxy: http.ProxyURL(proxyURL),
+		TLSClientConfig: &tls.Config{
+			Renegotiation:      tls.RenegotiateOnceAsClient,
+			InsecureSkipVerify: true,
+		},
+		DisableKeepAlives: true,
+	}
+	r := &Replayer{
+		client: &http.Client{
+			Transport: transport,
+			Timeout:   options.Timeout,
+			// the redirects of the replayed requests are followed from the proxy
+			CheckRedirect: func(*http.Request, []*http.Request) error {
+				return http.ErrUseLastResponse
+			},
+		},
+		interval: time.Second / time.Duration(options.Rate),
+		failed:   options.Failed,
+		queue:    make(chan *entry, queueSize),
+		done:     make(chan struct{}),
+	}
+	go r.run()
+	return r, nil
+}
+
+// Replay queues a request of a result of a template to be replayed along
+// with its body and the cookies it was sent with, the request being copied
+// with the template header. The request is dropped if the queue is full.
+func (r *Replayer) Replay(templateID string, req *http.Request, body []byte, cookies []*http.Cookie) {
+	if r == nil {
+		return
+	}
+	replayed := req.Clone(req.Context())
+	replayed.Header.Set(TemplateHeader, templateID)
+	if replayed.Header.Get("Cookie") == "" {
+		for _, cookie := range cookies {
+			replayed.AddCookie(cookie)
+		}
+	}
+
+	r.mutex.RLock()
+	defer r.mutex.RUnlock()
+	if r.closed {
+		atomic.AddUint64(&r.droppedCount, 1)
+		return
+	}
+	select {
+	case r.queue <- &entry{request: replayed, body: body}:
+	default:
+		atomic.AddUint64(&r.droppedCount, 1)
+	}
+}
+
+// run replays the queued requests in turn at the rate until the queue is
+// closed.
+func (r *Replayer) run() {
+	defer close(r.done)
+	var last time.Time
+	for entry := range r.queue {
+		if wait := r.interval - time.Since(last); wait > 0 {
+			time.Sleep(wait)
+		}
+		last = time.Now()
+		r.send(entry)
+	}
+}
+
+// send replays a request, discarding its response
+func (r *Replayer) send(entry *entry) {
+	req := entry.request.WithContext(context.Background())
+	req.Body = ioutil.NopCloser(bytes.NewReader(entry.body))
+	req.ContentLength = int64(len(entry.body))
+	req.RequestURI = ""
+
+	resp, err := r.client.Do(req)
+	if err != nil {
+		atomic.AddUint64(&r.failedCount, 1)
+		if r.failed != nil {
+			r.failed(req, err)
+		}
+		return
+	}
+	io.Copy(ioutil.Discard, resp.Body)
+	resp.Body.Close()
+	atomic.AddUint64(&r.replayedCount, 1)
+}
+
+// Close waits for the queued requests to be replayed, the requests queued
+// afterwards being dropped.
+func (r *Replayer) Close() {
+	if r == nil {
+		return
+	}
+	r.mutex.Lock()
+	if !r.closed {
+		r.closed = true
+		close(r.queue)
+	}
+	r.mutex.Unlock()
+	<-r.done
+}
+
+// Replayed returns the number of requests replayed
+func (r *Replayer) Replayed() uint64 {
+	if r == nil {
+		return 0
+	}
+	return atomic.LoadUint64(&r.replayedCount)
+}
+
+// Failed returns the number of requests which failed to be replayed
+func (r *Replayer) Failed() uint64 {
+	if r == nil {
+		return 0
+	}
+	return atomic.LoadUint64(&r.failedCount)
+}
+
+// Dropped returns the number of requests dropped as the queue was full
+func (r *Replayer) Dropped() uint64 {
+	if r == nil {
+		return 0
+	}
+	return atomic.LoadUint64(&r.droppedCount)
+}
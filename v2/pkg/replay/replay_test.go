@@ -0,0 +1,67 @@
+package replay
+
+import (
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestReplayer(t *testing.T) {
+	var mutex sync.Mutex
+	var received []string
+	var times []time.Time
+	proxy := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := ioutil.ReadAll(r.Body)
+		mutex.Lock()
+		received = append(received, strings.Join([]string{r.Method, r.URL.String(), r.Header.Get(TemplateHeader), r.Header.Get("Cookie"), string(body)}, " "))
+		times = append(times, time.Now())
+		mutex.Unlock()
+	}))
+	defer proxy.Close()
+
+	replayer, err := New(Options{ProxyURL: proxy.URL, Rate: 10, Timeout: 5 * time.Second})
+	require.Nil(t, err, "Could not create replayer")
+	req, err := http.NewRequest(http.MethodPost, "http://target.example.com/login", nil)
+	require.Nil(t, err, "Could not create request")
+	replayer.Replay("login-check", req, []byte("user=admin"), []*http.Cookie{{Name: "session", Value: "abc"}})
+	replayer.Replay("login-check", req, []byte("user=root"), nil)
+	replayer.Close()
+
+	require.Equal(t, []string{
+		"POST http://target.example.com/login login-check session=abc user=admin",
+		"POST http://target.example.com/login login-check  user=root",
+	}, received, "Could not replay the requests through the proxy")
+	require.True(t, times[1].Sub(times[0]) >= 90*time.Millisecond, "Could not replay the requests at the rate")
+	require.Equal(t, uint64(2), replayer.Replayed(), "Could not count the replayed requests")
+	require.Empty(t, req.Header.Get(TemplateHeader), "Could not copy the replayed request")
+
+	replayer.Replay("login-check", req, nil, nil)
+	require.Equal(t, uint64(1), replayer.Dropped(), "Could not drop the requests replayed once closed")
+
+	var nilReplayer *Replayer
+	nilReplayer.Replay("login-check", req, nil, nil)
+	nilReplayer.Close()
+}
+
+func TestReplayerFailures(t *testing.T) {
+	proxy := httptest.NewServer(http.NotFoundHandler())
+	proxy.Close()
+
+	var failures int
+	replayer, err := New(Options{ProxyURL: proxy.URL, Rate: 100, Timeout: time.Second, Failed: func(req *http.Request, err error) {
+		failures++
+	}})
+	require.Nil(t, err, "Could not create replayer")
+	req, err := http.NewRequest(http.MethodGet, "http://target.example.com/", nil)
+	require.Nil(t, err, "Could not create request")
+	replayer.Replay("down", req, nil, nil)
+	replayer.Close()
+	require.Equal(t, uint64(1), replayer.Failed(), "Could not count the failed replays")
+	require.Equal(t, 1, failures, "Could not report the failed replays")
+}
@@ -0,0 +1,122 @@
+package reporting
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net"
+	"net/http"
+	"strconv"
+	"time"
+
+	jsoniter "github.com/json-iterator/go"
+	"github.com/projectdiscovery/nuclei/v2/pkg/export"
+)
+
+// maxRetryAfter is the longest delay requested by a rate limited response
+// which is honoured before retrying.
+const maxRetryAfter = time.Minute
+
+// client sends the requests of the api of a tracker, retrying the requests
+// rate limited or failing with a 5xx status or a timeout.
+type client struct {
+	config *Config
+	http   *http.Client
+	// backoff is the delay before the first retry, doubled for each retry
+	backoff time.Duration
+	// auth sets the credentials of a request
+	auth func(req *http.Request)
+}
+
+// newClient creates the client of the api of the tracker of a config
+func newClient(config *Config) *client {
+	return &client{
+		config:  config,
+		http:    &http.Client{Timeout: config.Timeout},
+		backoff: time.Second,
+	}
+}
+
+// do sends a request to a path of the api, encoding the payload as json if
+// any and decoding the json response in result if not nil.
+func (c *client) do(method, path string, payload, result interface{}) error {
+	var body []byte
+	if payload != nil {
+		data, err := jsoniter.Marshal(payload)
+		if err != nil {
+			return err
+		}
+		body = data
+	}
+
+	return export.Retry(*c.config.Retries, c.backoff, func() (bool, error) {
+		req, err := http.NewRequest(method, c.config.URL+path, bytes.NewReader(body))
+		if err != nil {
+			return false, err
+		}
+		req.Header.Set("Accept", "application/json")
+		if payload != nil {
+			req.Header.Set("Content-Type", "application/json")
+		}
+		if c.auth != nil {
+			c.auth(req)
+		}
+
+		resp, err := c.http.Do(req)
+		if err != nil {
+			var netErr net.Error
+			return errors.As(err, &netErr) && netErr.Timeout(), err
+		}
+		defer resp.Body.Close()
+
+		if limited, delay := rateLimited(resp); limited {
+			io.Copy(ioutil.Discard, resp.Body)
+			if delay > 0 {
+				time.Sleep(delay)
+			}
+			return true, fmt.Errorf("rate limited with status %d", resp.StatusCode)
+		}
+		if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+			message, _ := ioutil.ReadAll(io.LimitReader(resp.Body, 512))
+			return resp.StatusCode >= 500, fmt.Errorf("unexpected status %d: %s", resp.StatusCode, bytes.TrimSpace(message))
+		}
+		if result == nil {
+			io.Copy(ioutil.Discard, resp.Body)
+			return false, nil
+		}
+		if err := jsoniter.NewDecoder(resp.Body).Decode(result); err != nil {
+			return false, fmt.Errorf("could not decode response: %s", err)
+		}
+		return false, nil
+	})
+}
+
+// rateLimited returns true if a response is rate limited, along with the
+// delay it requests before retrying from its Retry-After header or from the
+// reset time of the rate limits of github and gitlab.
+func rateLimited(resp *http.Response) (bool, time.Duration) {
+	switch {
+	case resp.StatusCode == http.StatusTooManyRequests:
+	case resp.StatusCode == http.StatusForbidden && resp.Header.Get("X-RateLimit-Remaining") == "0":
+	default:
+		return false, 0
+	}
+
+	var delay time.Duration
+	if seconds, err := strconv.Atoi(resp.Header.Get("Retry-After")); err == nil {
+		delay = time.Duration(seconds) * time.Second
+	} else if date, err := http.ParseTime(resp.Header.Get("Retry-After")); err == nil {
+		delay = time.Until(date)
+	} else if reset, err := strconv.ParseInt(resp.Header.Get("X-RateLimit-Reset"), 10, 64); err == nil {
+		delay = time.Until(time.Unix(reset, 0))
+	}
+	if delay < 0 {
+		delay = 0
+	}
+	if delay > maxRetryAfter {
+		delay = maxRetryAfter
+	}
+	return true, delay
+}
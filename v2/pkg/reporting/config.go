@@ -0,0 +1,171 @@
+package reporting
+
+import (
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"net/url"
+	"os"
+	"strings"
+	"time"
+
+	"gopkg.in/yaml.v2"
+)
+
+// defaultTitle is the template of the titles of the issues by default
+const defaultTitle = "[{{severity}}] {{template-id}} on {{host}}"
+
+// severityRanks are the ranks of the severities, the higher the more severe
+var severityRanks = map[string]int{
+	"info":     0,
+	"low":      1,
+	"medium":   2,
+	"high":     3,
+	"critical": 4,
+}
+
+// Config is the configuration of the issue tracker, read from a yaml file
+type Config struct {
+	// Tracker is the issue tracker, github, gitlab or jira
+	Tracker string `yaml:"tracker"`
+	// URL is the url of the api of the tracker, https://api.github.com for
+	// github and https://gitlab.com/api/v4 for gitlab by default, i.e
+	// https://company.atlassian.net for jira.
+	URL string `yaml:"url,omitempty"`
+	// Project is the owner/repo of github, the id or path of the project of
+	// gitlab or the key of the project of jira.
+	Project string `yaml:"project"`
+	// TokenEnv is the environment variable of the token of the api
+	TokenEnv string `yaml:"token-env"`
+	// Username is the user of the api token of jira cloud, the token being
+	// sent as a bearer token without it.
+	Username string `yaml:"username,omitempty"`
+	// Severity is the lowest severity of the findings to file, high by default
+	Severity string `yaml:"severity,omitempty"`
+	// Title is the template of the titles of the issues, with the
+	// {{severity}}, {{template-id}}, {{template-name}}, {{host}},
+	// {{matched}} and {{matcher-name}} placeholders.
+	Title string `yaml:"title,omitempty"`
+	// Labels are added to the issues
+	Labels []string `yaml:"labels,omitempty"`
+	// SeverityLabels are added to the issues of the findings by severity
+	SeverityLabels map[string][]string `yaml:"severity-labels,omitempty"`
+	// Components are the components of the jira issues
+	Components []string `yaml:"components,omitempty"`
+	// IssueType is the type of the jira issues, Bug by default
+	IssueType string `yaml:"issue-type,omitempty"`
+	// Retries is the number of retries of the requests rate limited or
+	// failing with a 5xx status, 3 by default.
+	Retries *int `yaml:"retries,omitempty"`
+	// QueueSize is the number of findings waiting to be filed beyond which
+	// the findings are dropped, 1000 by default.
+	QueueSize int `yaml:"queue-size,omitempty"`
+	// Timeout is the timeout of the requests, 30s by default
+	Timeout time.Duration `yaml:"timeout,omitempty"`
+
+	token string
+}
+
+// LoadConfig reads and validates the configuration of a yaml file, the
+// token being required unless the issues are only printed.
+func LoadConfig(file string, dryRun bool) (*Config, error) {
+	data, err := ioutil.ReadFile(file)
+	if err != nil {
+		return nil, fmt.Errorf("could not read reporting config: %s", err)
+	}
+	config := &Config{}
+	if err := yaml.UnmarshalStrict(data, config); err != nil {
+		return nil, fmt.Errorf("could not parse reporting config %s: %s", file, err)
+	}
+	if err := config.validate(dryRun); err != nil {
+		return nil, fmt.Errorf("invalid reporting config %s: %s", file, err)
+	}
+	return config, nil
+}
+
+// validate validates the configuration, setting the defaults
+func (c *Config) validate(dryRun bool) error {
+	switch c.Tracker {
+	case "github":
+		if c.URL == "" {
+			c.URL = "https://api.github.com"
+		}
+		if strings.Count(c.Project, "/") != 1 {
+			return fmt.Errorf("invalid github project %s, it should be like owner/repo", c.Project)
+		}
+	case "gitlab":
+		if c.URL == "" {
+			c.URL = "https://gitlab.com/api/v4"
+		}
+	case "jira":
+		if c.URL == "" {
+			return errors.New("no jira url given")
+		}
+		if c.IssueType == "" {
+			c.IssueType = "Bug"
+		}
+	case "":
+		return errors.New("no tracker given")
+	default:
+		return fmt.Errorf("invalid tracker %s, it should be github, gitlab or jira", c.Tracker)
+	}
+	if parsed, err := url.Parse(c.URL); err != nil || (parsed.Scheme != "http" && parsed.Scheme != "https") || parsed.Host == "" {
+		return fmt.Errorf("invalid url %s", c.URL)
+	}
+	c.URL = strings.TrimSuffix(c.URL, "/")
+	if c.Project == "" {
+		return errors.New("no project given")
+	}
+	if c.Tracker != "jira" && (len(c.Components) > 0 || c.IssueType != "") {
+		return errors.New("components and issue type are only used by jira")
+	}
+
+	if c.TokenEnv == "" {
+		return errors.New("no token-env given")
+	}
+	c.token = os.Getenv(c.TokenEnv)
+	if c.token == "" && !dryRun {
+		return fmt.Errorf("the token environment variable %s is not set", c.TokenEnv)
+	}
+
+	c.Severity = strings.ToLower(c.Severity)
+	if c.Severity == "" {
+		c.Severity = "high"
+	}
+	if _, ok := severityRanks[c.Severity]; !ok {
+		return fmt.Errorf("invalid severity %s, it should be info, low, medium, high or critical", c.Severity)
+	}
+	for severity := range c.SeverityLabels {
+		if _, ok := severityRanks[strings.ToLower(severity)]; !ok {
+			return fmt.Errorf("invalid severity %s of the severity labels", severity)
+		}
+	}
+	if c.Title == "" {
+		c.Title = defaultTitle
+	}
+	if c.Timeout < 0 || c.QueueSize < 0 || (c.Retries != nil && *c.Retries < 0) {
+		return errors.New("the timeout, queue size and retries should be 0 or more")
+	}
+	if c.Timeout == 0 {
+		c.Timeout = 30 * time.Second
+	}
+	if c.QueueSize == 0 {
+		c.QueueSize = 1000
+	}
+	if c.Retries == nil {
+		retries := 3
+		c.Retries = &retries
+	}
+	return nil
+}
+
+// labels returns the labels of the issues of a severity
+func (c *Config) labels(severity string) []string {
+	labels := append([]string{}, c.Labels...)
+	for name, severityLabels := range c.SeverityLabels {
+		if strings.EqualFold(name, severity) {
+			labels = append(labels, severityLabels...)
+		}
+	}
+	return labels
+}
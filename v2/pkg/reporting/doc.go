@@ -0,0 +1,4 @@
+// Package reporting files the findings of a scan above a severity threshold
+// as the issues of an issue tracker, GitHub, GitLab or Jira, commenting on
+// the open issue of a finding already filed instead of filing it again.
+package reporting
@@ -0,0 +1,64 @@
+package reporting
+
+import (
+	"fmt"
+	"net/http"
+	"net/url"
+	"strconv"
+)
+
+// github files the issues in a repository of github
+type github struct {
+	client *client
+}
+
+func newGithub(client *client) *github {
+	client.auth = func(req *http.Request) {
+		req.Header.Set("Authorization", "token "+client.config.token)
+		req.Header.Set("Accept", "application/vnd.github.v3+json")
+	}
+	return &github{client: client}
+}
+
+// Find searches the open issues of the repository with the fingerprint in
+// their body.
+func (g *github) Find(fingerprint string) (string, error) {
+	query := fmt.Sprintf("%q in:body is:issue is:open repo:%s", fingerprint, g.client.config.Project)
+	result := &struct {
+		Items []struct {
+			Number int `json:"number"`
+		} `json:"items"`
+	}{}
+	if err := g.client.do(http.MethodGet, "/search/issues?q="+url.QueryEscape(query), nil, result); err != nil {
+		return "", err
+	}
+	if len(result.Items) == 0 {
+		return "", nil
+	}
+	return strconv.Itoa(result.Items[0].Number), nil
+}
+
+// Create files an issue, returning its number
+func (g *github) Create(issue *Issue) (string, error) {
+	payload := map[string]interface{}{"title": issue.Title, "body": issue.Body}
+	if len(issue.Labels) > 0 {
+		payload["labels"] = issue.Labels
+	}
+	result := &struct {
+		Number int `json:"number"`
+	}{}
+	if err := g.client.do(http.MethodPost, "/repos/"+g.client.config.Project+"/issues", payload, result); err != nil {
+		return "", err
+	}
+	return strconv.Itoa(result.Number), nil
+}
+
+// Comment adds a comment to the issue of a number
+func (g *github) Comment(id, body string) error {
+	return g.client.do(http.MethodPost, "/repos/"+g.client.config.Project+"/issues/"+id+"/comments", map[string]string{"body": body}, nil)
+}
+
+// Jira returns false as github uses markdown
+func (g *github) Jira() bool {
+	return false
+}
@@ -0,0 +1,65 @@
+package reporting
+
+import (
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+)
+
+// gitlab files the issues in a project of gitlab
+type gitlab struct {
+	client *client
+}
+
+func newGitlab(client *client) *gitlab {
+	client.auth = func(req *http.Request) {
+		req.Header.Set("PRIVATE-TOKEN", client.config.token)
+	}
+	return &gitlab{client: client}
+}
+
+// path returns the path of the api of the project
+func (g *gitlab) path() string {
+	return "/projects/" + url.PathEscape(g.client.config.Project)
+}
+
+// Find searches the open issues of the project with the fingerprint in
+// their description.
+func (g *gitlab) Find(fingerprint string) (string, error) {
+	var result []struct {
+		IID int `json:"iid"`
+	}
+	if err := g.client.do(http.MethodGet, g.path()+"/issues?state=opened&in=description&search="+url.QueryEscape(fingerprint), nil, &result); err != nil {
+		return "", err
+	}
+	if len(result) == 0 {
+		return "", nil
+	}
+	return strconv.Itoa(result[0].IID), nil
+}
+
+// Create files an issue, returning its iid
+func (g *gitlab) Create(issue *Issue) (string, error) {
+	payload := map[string]string{"title": issue.Title, "description": issue.Body}
+	if len(issue.Labels) > 0 {
+		payload["labels"] = strings.Join(issue.Labels, ",")
+	}
+	result := &struct {
+		IID int `json:"iid"`
+	}{}
+	if err := g.client.do(http.MethodPost, g.path()+"/issues", payload, result); err != nil {
+		return "", err
+	}
+	return strconv.Itoa(result.IID), nil
+}
+
+// Comment adds a note to the issue of an iid
+func (g *gitlab) Comment(id, body string) error {
+	return g.client.do(http.MethodPost, g.path()+"/issues/"+id+"/notes", map[string]string{"body": body}, nil)
+}
+
+// Jira returns false as gitlab uses markdown
+func (g *gitlab) Jira() bool {
+	return false
+}
@@ -0,0 +1,78 @@
+package reporting
+
+import (
+	"fmt"
+	"net/http"
+	"net/url"
+)
+
+// jira files the issues in a project of jira. The fingerprint of the
+// findings is added to the labels of the issues as well as their
+// descriptions, the search of jira not matching the words of the text.
+type jira struct {
+	client *client
+}
+
+func newJira(client *client) *jira {
+	client.auth = func(req *http.Request) {
+		if client.config.Username != "" {
+			req.SetBasicAuth(client.config.Username, client.config.token)
+		} else {
+			req.Header.Set("Authorization", "Bearer "+client.config.token)
+		}
+	}
+	return &jira{client: client}
+}
+
+// Find searches the unresolved issues of the project with the fingerprint
+// in their labels.
+func (j *jira) Find(fingerprint string) (string, error) {
+	jql := fmt.Sprintf("project = %q AND labels = %q AND statusCategory != Done", j.client.config.Project, fingerprint)
+	result := &struct {
+		Issues []struct {
+			Key string `json:"key"`
+		} `json:"issues"`
+	}{}
+	if err := j.client.do(http.MethodGet, "/rest/api/2/search?maxResults=1&fields=key&jql="+url.QueryEscape(jql), nil, result); err != nil {
+		return "", err
+	}
+	if len(result.Issues) == 0 {
+		return "", nil
+	}
+	return result.Issues[0].Key, nil
+}
+
+// Create files an issue, returning its key
+func (j *jira) Create(issue *Issue) (string, error) {
+	fields := map[string]interface{}{
+		"project":     map[string]string{"key": j.client.config.Project},
+		"summary":     issue.Title,
+		"description": issue.Body,
+		"issuetype":   map[string]string{"name": j.client.config.IssueType},
+		"labels":      append(append([]string{}, issue.Labels...), issue.Fingerprint),
+	}
+	if len(j.client.config.Components) > 0 {
+		components := make([]map[string]string, 0, len(j.client.config.Components))
+		for _, component := range j.client.config.Components {
+			components = append(components, map[string]string{"name": component})
+		}
+		fields["components"] = components
+	}
+	result := &struct {
+		Key string `json:"key"`
+	}{}
+	if err := j.client.do(http.MethodPost, "/rest/api/2/issue", map[string]interface{}{"fields": fields}, result); err != nil {
+		return "", err
+	}
+	return result.Key, nil
+}
+
+// Comment adds a comment to the issue of a key
+func (j *jira) Comment(id, body string) error {
+	return j.client.do(http.MethodPost, "/rest/api/2/issue/"+id+"/comment", map[string]string{"body": body}, nil)
+}
+
+// Jira returns true as jira uses its wiki markup
+func (j *jira) Jira() bool {
+	return true
+}
@@ -0,0 +1,324 @@
+package reporting
+
+import (
+	"encoding/base64"
+	"fmt"
+	"sort"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+	"unicode/utf8"
+
+	jsoniter "github.com/json-iterator/go"
+	"github.com/projectdiscovery/gologger"
+	"github.com/projectdiscovery/nuclei/v2/pkg/dedupe"
+	"github.com/projectdiscovery/nuclei/v2/pkg/export"
+)
+
+// maxSnippetSize is the length of the request and response snippets of the
+// issues, the rest being truncated.
+const maxSnippetSize = 4096
+
+// Issue is an issue to file for a finding
+type Issue struct {
+	Title string
+	// Body is markdown, or the wiki markup for jira
+	Body   string
+	Labels []string
+	// Fingerprint identifies the finding of the issue, written to the issue
+	// to find it again.
+	Fingerprint string
+}
+
+// Tracker is an issue tracker the issues of the findings are filed in
+type Tracker interface {
+	// Find returns the id of the open issue with a fingerprint, empty if
+	// there is none.
+	Find(fingerprint string) (string, error)
+	// Create files an issue, returning its id
+	Create(issue *Issue) (string, error)
+	// Comment adds a comment to an issue
+	Comment(id, body string) error
+	// Jira returns true if the bodies are jira wiki markup instead of markdown
+	Jira() bool
+}
+
+// finding is the part of a json result written to the issues
+type finding struct {
+	Template         string   `json:"template"`
+	Name             string   `json:"name"`
+	Severity         string   `json:"severity"`
+	Host             string   `json:"host"`
+	Matched          string   `json:"matched"`
+	MatcherName      string   `json:"matcher_name"`
+	ExtractedResults []string `json:"extracted_results"`
+	Description      string   `json:"description"`
+	Reference        []string `json:"reference"`
+	Tags             []string `json:"tags"`
+	Timestamp        string   `json:"timestamp"`
+	Request          string   `json:"request"`
+	RequestEncoding  string   `json:"request_encoding"`
+	Response         string   `json:"response"`
+	ResponseEncoding string   `json:"response_encoding"`
+}
+
+// Exporter files the findings above the severity threshold from a bounded
+// queue, so that the requests to the tracker never slow the scan down.
+//
+// The open issue of a finding is searched by its fingerprint, a comment
+// being added to it instead of filing a new issue. With a dry run, the
+// issues are only printed.
+type Exporter struct {
+	config  *Config
+	tracker Tracker
+	dryRun  bool
+	queue   *export.Queue
+
+	// issues are the ids of the issues of the fingerprints filed by the run,
+	// as the search of the trackers lags behind the created issues.
+	mutex  sync.Mutex
+	issues map[string]string
+
+	created   uint64
+	commented uint64
+	failed    uint64
+}
+
+// New creates an exporter filing the issues in the tracker of a config
+func New(config *Config, dryRun bool) *Exporter {
+	e := &Exporter{config: config, dryRun: dryRun, issues: make(map[string]string)}
+	client := newClient(config)
+	switch config.Tracker {
+	case "github":
+		e.tracker = newGithub(client)
+	case "gitlab":
+		e.tracker = newGitlab(client)
+	case "jira":
+		e.tracker = newJira(client)
+	}
+	e.queue = export.NewQueue(config.QueueSize, 1, time.Second, e.send)
+	return e
+}
+
+// IncludeRR returns true as the issues have the snippets of the requests and
+// responses.
+func (e *Exporter) IncludeRR() bool {
+	return true
+}
+
+// Export queues a json result to file if its severity is above the threshold,
+// dropping it if the queue is full.
+func (e *Exporter) Export(data []byte) {
+	severity := strings.ToLower(jsoniter.Get(data, "severity").ToString())
+	if rank, ok := severityRanks[severity]; !ok || rank < severityRanks[e.config.Severity] {
+		return
+	}
+	e.queue.Push(data)
+}
+
+// Close files the queued findings, waiting for the requests to complete
+func (e *Exporter) Close() {
+	e.queue.Close()
+}
+
+// Created returns the number of issues filed, or printed with a dry run
+func (e *Exporter) Created() uint64 {
+	return atomic.LoadUint64(&e.created)
+}
+
+// Commented returns the number of comments added to the existing issues
+func (e *Exporter) Commented() uint64 {
+	return atomic.LoadUint64(&e.commented)
+}
+
+// Queued returns the number of findings waiting in the queue
+func (e *Exporter) Queued() int {
+	return e.queue.Len()
+}
+
+// Dropped returns the number of findings dropped as the queue was full
+func (e *Exporter) Dropped() uint64 {
+	return e.queue.Dropped()
+}
+
+// Failed returns the number of findings which could not be filed
+func (e *Exporter) Failed() uint64 {
+	return atomic.LoadUint64(&e.failed)
+}
+
+// Tracker returns the name of the tracker and the project of the issues
+func (e *Exporter) Tracker() string {
+	return e.config.Tracker + " " + e.config.Project
+}
+
+// send files the findings of a batch
+func (e *Exporter) send(batch [][]byte) {
+	for _, data := range batch {
+		f := &finding{}
+		if err := jsoniter.Unmarshal(data, f); err != nil {
+			atomic.AddUint64(&e.failed, 1)
+			gologger.Warningf("Could not parse result to report: %s\n", err)
+			continue
+		}
+		if err := e.report(f); err != nil {
+			atomic.AddUint64(&e.failed, 1)
+			gologger.Errorf("Could not report %s on %s to %s: %s\n", f.Template, f.Matched, e.Tracker(), err)
+		}
+	}
+}
+
+// report files the issue of a finding, or comments on its open issue
+func (e *Exporter) report(f *finding) error {
+	wiki := e.tracker.Jira()
+	issue := &Issue{
+		Title:       e.title(f),
+		Labels:      e.config.labels(f.Severity),
+		Fingerprint: "nuclei-" + dedupe.Fingerprint(dedupe.DefaultKey, &dedupe.Finding{TemplateID: f.Template, MatcherName: f.MatcherName, Matched: f.Matched, Extracted: f.ExtractedResults})[:16],
+	}
+	issue.Body = body(f, issue.Fingerprint, wiki)
+
+	e.mutex.Lock()
+	id, filed := e.issues[issue.Fingerprint]
+	e.mutex.Unlock()
+
+	if e.dryRun {
+		if filed {
+			gologger.Infof("[dry-run] Would comment on the issue of %s in %s: found again at %s\n", issue.Fingerprint, e.Tracker(), f.Matched)
+			atomic.AddUint64(&e.commented, 1)
+			return nil
+		}
+		gologger.Infof("[dry-run] Would file in %s the issue %q with the labels [%s]:\n%s\n", e.Tracker(), issue.Title, strings.Join(issue.Labels, ","), issue.Body)
+		e.remember(issue.Fingerprint, issue.Fingerprint)
+		atomic.AddUint64(&e.created, 1)
+		return nil
+	}
+
+	if !filed {
+		found, err := e.tracker.Find(issue.Fingerprint)
+		if err != nil {
+			return fmt.Errorf("could not search the issues: %s", err)
+		}
+		id = found
+	}
+	if id != "" {
+		if err := e.tracker.Comment(id, comment(f, wiki)); err != nil {
+			return fmt.Errorf("could not comment on issue %s: %s", id, err)
+		}
+		e.remember(issue.Fingerprint, id)
+		atomic.AddUint64(&e.commented, 1)
+		return nil
+	}
+	id, err := e.tracker.Create(issue)
+	if err != nil {
+		return fmt.Errorf("could not create issue: %s", err)
+	}
+	e.remember(issue.Fingerprint, id)
+	atomic.AddUint64(&e.created, 1)
+	gologger.Infof("Filed issue %s in %s for %s on %s\n", id, e.Tracker(), f.Template, f.Matched)
+	return nil
+}
+
+// remember records the issue of a fingerprint filed by the run
+func (e *Exporter) remember(fingerprint, id string) {
+	e.mutex.Lock()
+	defer e.mutex.Unlock()
+	e.issues[fingerprint] = id
+}
+
+// title returns the title of the issue of a finding
+func (e *Exporter) title(f *finding) string {
+	return strings.NewReplacer(
+		"{{severity}}", f.Severity,
+		"{{template-id}}", f.Template,
+		"{{template-name}}", f.Name,
+		"{{host}}", f.Host,
+		"{{matched}}", f.Matched,
+		"{{matcher-name}}", f.MatcherName,
+	).Replace(e.config.Title)
+}
+
+// body returns the body of the issue of a finding, in markdown or in jira
+// wiki markup, ending with the fingerprint of the finding.
+func body(f *finding, fingerprint string, wiki bool) string {
+	builder := &strings.Builder{}
+	heading, bold := "### ", "**"
+	if wiki {
+		heading, bold = "h3. ", "*"
+	}
+
+	if f.Description != "" {
+		fmt.Fprintf(builder, "%s\n\n", f.Description)
+	}
+	fmt.Fprintf(builder, "%sTemplate%s: %s (%s)\n", bold, bold, f.Template, f.Name)
+	fmt.Fprintf(builder, "%sSeverity%s: %s\n", bold, bold, f.Severity)
+	fmt.Fprintf(builder, "%sMatched at%s: %s\n", bold, bold, f.Matched)
+	if f.MatcherName != "" {
+		fmt.Fprintf(builder, "%sMatcher%s: %s\n", bold, bold, f.MatcherName)
+	}
+	if len(f.Tags) > 0 {
+		fmt.Fprintf(builder, "%sTags%s: %s\n", bold, bold, strings.Join(f.Tags, ", "))
+	}
+	if len(f.ExtractedResults) > 0 {
+		fmt.Fprintf(builder, "\n%sExtracted values\n\n", heading)
+		for _, value := range f.ExtractedResults {
+			fmt.Fprintf(builder, "* %s\n", value)
+		}
+	}
+	if len(f.Reference) > 0 {
+		fmt.Fprintf(builder, "\n%sReferences\n\n", heading)
+		references := append([]string{}, f.Reference...)
+		sort.Strings(references)
+		for _, reference := range references {
+			fmt.Fprintf(builder, "* %s\n", reference)
+		}
+	}
+	writeSnippet(builder, heading+"Request", f.Request, f.RequestEncoding, wiki)
+	writeSnippet(builder, heading+"Response", f.Response, f.ResponseEncoding, wiki)
+	fmt.Fprintf(builder, "\nFingerprint: %s\n", fingerprint)
+	return builder.String()
+}
+
+// comment returns the comment of a finding already filed
+func comment(f *finding, wiki bool) string {
+	bold := "**"
+	if wiki {
+		bold = "*"
+	}
+	return fmt.Sprintf("Found again by nuclei at %s on %s%s%s\n", f.Timestamp, bold, f.Matched, bold)
+}
+
+// writeSnippet writes the beginning of a request or response as a code block
+func writeSnippet(builder *strings.Builder, title, value, encoding string, wiki bool) {
+	if value == "" {
+		return
+	}
+	fmt.Fprintf(builder, "\n%s\n\n", title)
+	if encoding == "base64" {
+		if decoded, err := base64.StdEncoding.DecodeString(value); err == nil {
+			fmt.Fprintf(builder, "_Binary content of %d bytes omitted._\n", len(decoded))
+			return
+		}
+	}
+	snippet := strings.Replace(value, "\r\n", "\n", -1)
+	truncated := len(snippet) > maxSnippetSize
+	if truncated {
+		end := maxSnippetSize
+		for end > 0 && !utf8.RuneStart(snippet[end]) {
+			end--
+		}
+		snippet = snippet[:end]
+	}
+	if wiki {
+		fmt.Fprintf(builder, "{noformat}\n%s\n{noformat}\n", strings.Replace(snippet, "{noformat}", "{ noformat}", -1))
+	} else {
+		fence := "```"
+		for strings.Contains(snippet, fence) {
+			fence += "`"
+		}
+		fmt.Fprintf(builder, "%s\n%s\n%s\n", fence, snippet, fence)
+	}
+	if truncated {
+		fmt.Fprintf(builder, "_Truncated to the first %d bytes._\n", maxSnippetSize)
+	}
+}
@@ -0,0 +1,241 @@
+package reporting
+
+import (
+	"encoding/base64"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"testing"
+
+	jsoniter "github.com/json-iterator/go"
+	"github.com/stretchr/testify/require"
+)
+
+func writeConfig(t *testing.T, directory, content string) string {
+	file := filepath.Join(directory, "reporting.yaml")
+	require.Nil(t, ioutil.WriteFile(file, []byte(content), 0644), "Could not write config")
+	return file
+}
+
+func result(t *testing.T, severity, matched string) []byte {
+	data, err := jsoniter.Marshal(map[string]interface{}{
+		"template":          "exposed-panel",
+		"name":              "Exposed Panel",
+		"severity":          severity,
+		"host":              "https://example.com",
+		"matched":           matched,
+		"description":       "An admin panel is exposed.",
+		"reference":         []string{"https://example.com/advisory"},
+		"extracted_results": []string{"v1.2"},
+		"timestamp":         "2021-01-01T00:00:00Z",
+		"request":           "GET /admin HTTP/1.1\r\nHost: example.com\r\n\r\n",
+		"response":          base64.StdEncoding.EncodeToString([]byte{0, 1, 2}),
+		"response_encoding": "base64",
+	})
+	require.Nil(t, err, "Could not marshal result")
+	return data
+}
+
+func TestLoadConfig(t *testing.T) {
+	directory, err := ioutil.TempDir("", "reporting-*")
+	require.Nil(t, err, "Could not create temporary directory")
+	defer os.RemoveAll(directory)
+	os.Setenv("NUCLEI_TEST_TOKEN", "token")
+	defer os.Unsetenv("NUCLEI_TEST_TOKEN")
+
+	config, err := LoadConfig(writeConfig(t, directory, "tracker: github\nproject: acme/findings\ntoken-env: NUCLEI_TEST_TOKEN\n"), false)
+	require.Nil(t, err, "Could not load config")
+	require.Equal(t, "https://api.github.com", config.URL, "Could not set the default url")
+	require.Equal(t, "high", config.Severity, "Could not set the default severity")
+	require.Equal(t, "token", config.token, "Could not read the token")
+
+	config, err = LoadConfig(writeConfig(t, directory, "tracker: jira\nurl: https://acme.atlassian.net/\nproject: SEC\ntoken-env: NUCLEI_MISSING_TOKEN\n"), true)
+	require.Nil(t, err, "Could not load config without token for a dry run")
+	require.Equal(t, "Bug", config.IssueType, "Could not set the default issue type")
+	require.Equal(t, "https://acme.atlassian.net", config.URL, "Could not trim the url")
+
+	_, err = LoadConfig(writeConfig(t, directory, "tracker: jira\nurl: https://acme.atlassian.net\nproject: SEC\ntoken-env: NUCLEI_MISSING_TOKEN\n"), false)
+	require.NotNil(t, err, "Could not reject a missing token")
+	_, err = LoadConfig(writeConfig(t, directory, "tracker: github\nproject: findings\ntoken-env: NUCLEI_TEST_TOKEN\n"), false)
+	require.NotNil(t, err, "Could not reject a github project without owner")
+	_, err = LoadConfig(writeConfig(t, directory, "tracker: gitlab\nproject: acme/findings\ntoken-env: NUCLEI_TEST_TOKEN\nseverity: severe\n"), false)
+	require.NotNil(t, err, "Could not reject an invalid severity")
+	_, err = LoadConfig(writeConfig(t, directory, "tracker: trello\nproject: acme\ntoken-env: NUCLEI_TEST_TOKEN\n"), false)
+	require.NotNil(t, err, "Could not reject an unknown tracker")
+}
+
+func TestGithub(t *testing.T) {
+	var mutex sync.Mutex
+	var issues, comments []map[string]interface{}
+	var limited bool
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		mutex.Lock()
+		defer mutex.Unlock()
+		if r.Header.Get("Authorization") != "token secret" {
+			w.WriteHeader(http.StatusUnauthorized)
+			return
+		}
+		switch {
+		case r.Method == http.MethodGet && r.URL.Path == "/search/issues":
+			if !limited {
+				limited = true
+				w.Header().Set("Retry-After", "0")
+				w.WriteHeader(http.StatusTooManyRequests)
+				return
+			}
+			require.Contains(t, r.URL.Query().Get("q"), "repo:acme/findings", "Could not search the repository")
+			fmt.Fprint(w, `{"items":[]}`)
+		case r.Method == http.MethodPost && r.URL.Path == "/repos/acme/findings/issues":
+			issue := make(map[string]interface{})
+			require.Nil(t, jsoniter.NewDecoder(r.Body).Decode(&issue), "Could not decode issue")
+			issues = append(issues, issue)
+			fmt.Fprintf(w, `{"number":%d}`, len(issues))
+		case r.Method == http.MethodPost && r.URL.Path == "/repos/acme/findings/issues/1/comments":
+			comment := make(map[string]interface{})
+			require.Nil(t, jsoniter.NewDecoder(r.Body).Decode(&comment), "Could not decode comment")
+			comments = append(comments, comment)
+			fmt.Fprint(w, `{}`)
+		default:
+			w.WriteHeader(http.StatusNotFound)
+		}
+	}))
+	defer server.Close()
+
+	config := &Config{Tracker: "github", URL: server.URL, Project: "acme/findings", TokenEnv: "NUCLEI_TEST_TOKEN", Labels: []string{"security"}, SeverityLabels: map[string][]string{"critical": {"p0"}}}
+	os.Setenv("NUCLEI_TEST_TOKEN", "secret")
+	defer os.Unsetenv("NUCLEI_TEST_TOKEN")
+	require.Nil(t, config.validate(false), "Could not validate config")
+
+	exporter := New(config, false)
+	exporter.tracker.(*github).client.backoff = 0
+	exporter.Export(result(t, "critical", "https://example.com/admin"))
+	exporter.Export(result(t, "medium", "https://example.com/admin"))
+	exporter.Export(result(t, "critical", "https://example.com/admin"))
+	exporter.Close()
+
+	require.Equal(t, uint64(1), exporter.Created(), "Could not create the issue")
+	require.Equal(t, uint64(1), exporter.Commented(), "Could not comment on the filed issue")
+	require.Equal(t, uint64(0), exporter.Failed(), "Could not retry the rate limited search")
+	require.Len(t, issues, 1, "Could not skip the findings below the severity")
+	require.Len(t, comments, 1, "Could not comment on the issue")
+	require.Equal(t, "[critical] exposed-panel on https://example.com", issues[0]["title"], "Could not render the title")
+	require.Equal(t, []interface{}{"security", "p0"}, issues[0]["labels"], "Could not add the labels")
+
+	body := issues[0]["body"].(string)
+	require.Contains(t, body, "An admin panel is exposed.", "Could not add the description")
+	require.Contains(t, body, "* https://example.com/advisory", "Could not add the references")
+	require.Contains(t, body, "* v1.2", "Could not add the extracted values")
+	require.Contains(t, body, "```\nGET /admin HTTP/1.1\nHost: example.com", "Could not add the request")
+	require.Contains(t, body, "_Binary content of 3 bytes omitted._", "Could not omit the binary response")
+	require.Contains(t, body, "Fingerprint: nuclei-", "Could not add the fingerprint")
+}
+
+func TestGitlab(t *testing.T) {
+	var notes int
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("PRIVATE-TOKEN") != "secret" {
+			w.WriteHeader(http.StatusUnauthorized)
+			return
+		}
+		switch {
+		case r.Method == http.MethodGet && r.URL.EscapedPath() == "/projects/acme%2Ffindings/issues":
+			require.Equal(t, "opened", r.URL.Query().Get("state"), "Could not search the open issues")
+			require.True(t, strings.HasPrefix(r.URL.Query().Get("search"), "nuclei-"), "Could not search the fingerprint")
+			fmt.Fprint(w, `[{"iid":7}]`)
+		case r.Method == http.MethodPost && r.URL.EscapedPath() == "/projects/acme%2Ffindings/issues/7/notes":
+			notes++
+			fmt.Fprint(w, `{}`)
+		default:
+			w.WriteHeader(http.StatusNotFound)
+		}
+	}))
+	defer server.Close()
+
+	config := &Config{Tracker: "gitlab", URL: server.URL, Project: "acme/findings", TokenEnv: "NUCLEI_TEST_TOKEN", Severity: "medium"}
+	os.Setenv("NUCLEI_TEST_TOKEN", "secret")
+	defer os.Unsetenv("NUCLEI_TEST_TOKEN")
+	require.Nil(t, config.validate(false), "Could not validate config")
+
+	exporter := New(config, false)
+	exporter.Export(result(t, "medium", "https://example.com/admin"))
+	exporter.Export(result(t, "low", "https://example.com/admin"))
+	exporter.Close()
+
+	require.Equal(t, uint64(0), exporter.Created(), "Could not find the open issue")
+	require.Equal(t, uint64(1), exporter.Commented(), "Could not comment on the open issue")
+	require.Equal(t, 1, notes, "Could not add the note")
+}
+
+func TestJira(t *testing.T) {
+	var fields map[string]interface{}
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if username, password, ok := r.BasicAuth(); !ok || username != "bot@acme.com" || password != "secret" {
+			w.WriteHeader(http.StatusUnauthorized)
+			return
+		}
+		switch {
+		case r.Method == http.MethodGet && r.URL.Path == "/rest/api/2/search":
+			require.Contains(t, r.URL.Query().Get("jql"), "statusCategory != Done", "Could not search the unresolved issues")
+			fmt.Fprint(w, `{"issues":[]}`)
+		case r.Method == http.MethodPost && r.URL.Path == "/rest/api/2/issue":
+			payload := &struct {
+				Fields map[string]interface{} `json:"fields"`
+			}{}
+			require.Nil(t, jsoniter.NewDecoder(r.Body).Decode(payload), "Could not decode issue")
+			fields = payload.Fields
+			fmt.Fprint(w, `{"key":"SEC-12"}`)
+		case r.Method == http.MethodPost:
+			w.WriteHeader(http.StatusServiceUnavailable)
+		default:
+			w.WriteHeader(http.StatusNotFound)
+		}
+	}))
+	defer server.Close()
+
+	config := &Config{Tracker: "jira", URL: server.URL, Project: "SEC", TokenEnv: "NUCLEI_TEST_TOKEN", Username: "bot@acme.com", Components: []string{"web"}}
+	os.Setenv("NUCLEI_TEST_TOKEN", "secret")
+	defer os.Unsetenv("NUCLEI_TEST_TOKEN")
+	require.Nil(t, config.validate(false), "Could not validate config")
+
+	exporter := New(config, false)
+	exporter.Export(result(t, "high", "https://example.com/admin"))
+	exporter.Close()
+
+	require.Equal(t, uint64(1), exporter.Created(), "Could not create the issue")
+	require.Equal(t, map[string]interface{}{"key": "SEC"}, fields["project"], "Could not set the project")
+	require.Equal(t, map[string]interface{}{"name": "Bug"}, fields["issuetype"], "Could not set the issue type")
+	require.Equal(t, []interface{}{map[string]interface{}{"name": "web"}}, fields["components"], "Could not set the components")
+	labels := fields["labels"].([]interface{})
+	require.True(t, strings.HasPrefix(labels[len(labels)-1].(string), "nuclei-"), "Could not label the issue with the fingerprint")
+
+	description := fields["description"].(string)
+	require.Contains(t, description, "*Matched at*: https://example.com/admin", "Could not use the wiki markup")
+	require.Contains(t, description, "{noformat}\nGET /admin HTTP/1.1", "Could not add the request as wiki markup")
+}
+
+func TestDryRun(t *testing.T) {
+	config := &Config{Tracker: "github", URL: "http://127.0.0.1:1", Project: "acme/findings", TokenEnv: "NUCLEI_MISSING_TOKEN"}
+	require.Nil(t, config.validate(true), "Could not validate config")
+
+	exporter := New(config, true)
+	exporter.Export(result(t, "high", "https://example.com/admin"))
+	exporter.Export(result(t, "high", "https://example.com/admin"))
+	exporter.Export(result(t, "high", "https://example.com/login"))
+	exporter.Close()
+
+	require.Equal(t, uint64(2), exporter.Created(), "Could not print the issues")
+	require.Equal(t, uint64(1), exporter.Commented(), "Could not print the comments")
+	require.Equal(t, uint64(0), exporter.Failed(), "Could not skip the requests of the dry run")
+}
+
+func TestSnippet(t *testing.T) {
+	builder := &strings.Builder{}
+	writeSnippet(builder, "### Response", "```\n"+strings.Repeat("a", maxSnippetSize), "", false)
+	require.Contains(t, builder.String(), "````\n```\n", "Could not escape the fence of the snippet")
+	require.Contains(t, builder.String(), fmt.Sprintf("_Truncated to the first %d bytes._", maxSnippetSize), "Could not truncate the snippet")
+}
@@ -2,15 +2,23 @@ package requests
 
 import (
 	"bufio"
+	"bytes"
+	"crypto/rand"
+	"encoding/hex"
+	"errors"
 	"fmt"
 	"io/ioutil"
 	"net/http"
 	"net/url"
 	"regexp"
+	"sort"
+	"strconv"
 	"strings"
 
 	"github.com/Knetic/govaluate"
+	"github.com/projectdiscovery/nuclei/v2/pkg/auth"
 	"github.com/projectdiscovery/nuclei/v2/pkg/extractors"
+	"github.com/projectdiscovery/nuclei/v2/pkg/fuzzing"
 	"github.com/projectdiscovery/nuclei/v2/pkg/generators"
 	"github.com/projectdiscovery/nuclei/v2/pkg/matchers"
 	retryablehttp "github.com/projectdiscovery/retryablehttp-go"
@@ -51,11 +59,53 @@ type BulkHTTPRequest struct {
 	Redirects bool `yaml:"redirects,omitempty"`
 	// MaxRedirects is the maximum number of redirects that should be followed.
 	MaxRedirects int `yaml:"max-redirects,omitempty"`
+	// Timeout is the seconds to wait for a response, overriding the global timeout
+	Timeout int `yaml:"timeout,omitempty"`
+	// Retries is the number of times to retry a failed request, overriding the global retries
+	Retries int `yaml:"retries,omitempty"`
+	// InternalAbort is what the failure of an internal matcher stops,
+	// target for the remaining requests to the target (default) or
+	// iteration for the remaining requests of the current payload values.
+	InternalAbort string `yaml:"internal-abort,omitempty"`
+	// Baseline fetches a random non-existent path of each target once, its
+	// response is compared with the responses by the similarity matchers.
+	Baseline bool `yaml:"baseline,omitempty"`
+	// RunIf contains the guards of the requests by 1-based index, dsl
+	// expressions evaluated just before the request with the responses
+	// of the previous requests, i.e status_code_1. The request is skipped
+	// if its guard is false.
+	RunIf map[int]string `yaml:"run-if,omitempty"`
+	// runIf contains the compiled guards by 1-based index
+	runIf map[int]*govaluate.EvaluableExpression
+	// IterateAll executes the requests using a named extractor once per
+	// value it extracted instead of with its first value only, the paths
+	// and raw requests using {{value}} iterating on the last named
+	// extractor with values. It can't be used with payloads.
+	IterateAll bool `yaml:"iterate-all,omitempty"`
+	// MaxIterations is the maximum number of values iterated on by the
+	// requests of an iterate-all request, DefaultMaxIterations by default.
+	MaxIterations int `yaml:"max-iterations,omitempty"`
+	// Fuzzing are the rules injecting payloads into each parameter of a
+	// part of the built requests, i.e the query of the urls of the input,
+	// each request being sent once per parameter and payload instead of
+	// as is, and not at all without parameters to fuzz.
+	Fuzzing []*fuzzing.Rule `yaml:"fuzzing,omitempty"`
 	// Raw contains raw requests
 	Raw  []string `yaml:"raw,omitempty"`
 	gsfm *GeneratorFSM
 }
 
+// DefaultMaxIterations is the default maximum number of values iterated on
+const DefaultMaxIterations = 100
+
+// GetMaxIterations returns the maximum number of values iterated on by iterate-all requests
+func (r *BulkHTTPRequest) GetMaxIterations() int {
+	if r.MaxIterations > 0 {
+		return r.MaxIterations
+	}
+	return DefaultMaxIterations
+}
+
 // GetMatchersCondition returns the condition for the matcher
 func (r *BulkHTTPRequest) GetMatchersCondition() matchers.ConditionType {
 	return r.matchersCondition
@@ -66,6 +116,135 @@ func (r *BulkHTTPRequest) SetMatchersCondition(condition matchers.ConditionType)
 	r.matchersCondition = condition
 }
 
+// AbortsIteration returns true if a failing internal matcher only skips the current payload values
+func (r *BulkHTTPRequest) AbortsIteration() bool {
+	return r.InternalAbort == "iteration" && len(r.Payloads) > 0
+}
+
+// Resumable returns true if the requests with payloads can be resumed from a
+// position, none of them depending on the previous ones with extractors,
+// guards or cookies.
+func (r *BulkHTTPRequest) Resumable() bool {
+	if len(r.Payloads) == 0 || len(r.RunIf) > 0 || r.CookieReuse || r.IterateAll {
+		return false
+	}
+	for _, extractor := range r.Extractors {
+		if extractor.Name != "" {
+			return false
+		}
+	}
+	return true
+}
+
+// HasRandomValues returns true if the paths, the raw requests, the headers
+// or the body call the dsl functions returning a different value on each
+// evaluation, the requests being different on each run.
+func (r *BulkHTTPRequest) HasRandomValues() bool {
+	for _, values := range [][]string{r.Path, r.Raw} {
+		for _, value := range values {
+			if generators.HasRandomValues(value) {
+				return true
+			}
+		}
+	}
+	for _, value := range r.Headers {
+		if generators.HasRandomValues(value) {
+			return true
+		}
+	}
+	return generators.HasRandomValues(r.Body)
+}
+
+// InteractshURLName is the name of the value replaced with a unique url
+// of the interactsh server in each request, {{interactsh-url}}.
+const InteractshURLName = "interactsh-url"
+
+// UsesInteractshURL returns true if the paths, the raw requests, the
+// headers, the body or the payloads of the fuzzing rules use
+// {{interactsh-url}}.
+func (r *BulkHTTPRequest) UsesInteractshURL() bool {
+	placeholder := "{{" + InteractshURLName + "}}"
+	for _, rule := range r.Fuzzing {
+		if rule.Contains(placeholder) {
+			return true
+		}
+	}
+	return r.requestsUse(placeholder)
+}
+
+// ValidateFuzzing returns an error if the requests fuzzed by the fuzzing
+// rules use {{interactsh-url}}, the fuzzed requests sharing its url. The
+// payloads of the rules use it instead, each fuzzed request getting its
+// own url.
+func (r *BulkHTTPRequest) ValidateFuzzing() error {
+	if len(r.Fuzzing) > 0 && r.requestsUse("{{"+InteractshURLName+"}}") {
+		return fmt.Errorf("fuzzed requests can't use {{%s}}, the payloads of the fuzzing rules can", InteractshURLName)
+	}
+	return nil
+}
+
+// requestsUse returns true if the paths, the raw requests, the headers or
+// the body contain a placeholder.
+func (r *BulkHTTPRequest) requestsUse(placeholder string) bool {
+	for _, values := range [][]string{r.Path, r.Raw} {
+		for _, value := range values {
+			if strings.Contains(value, placeholder) {
+				return true
+			}
+		}
+	}
+	for _, value := range r.Headers {
+		if strings.Contains(value, placeholder) {
+			return true
+		}
+	}
+	return strings.Contains(r.Body, placeholder)
+}
+
+// UsesInteractsh returns true if the requests use {{interactsh-url}} or
+// the matchers match the interactions with the interactsh server.
+func (r *BulkHTTPRequest) UsesInteractsh() bool {
+	if r.UsesInteractshURL() {
+		return true
+	}
+	for _, matcher := range r.Matchers {
+		if matcher.UsesInteractsh() {
+			return true
+		}
+	}
+	return false
+}
+
+// CompileRunIf compiles the guards of the requests
+func (r *BulkHTTPRequest) CompileRunIf() error {
+	r.runIf = make(map[int]*govaluate.EvaluableExpression, len(r.RunIf))
+	for index, expression := range r.RunIf {
+		if index < 1 || index > len(r.Path)+len(r.Raw) {
+			return fmt.Errorf("invalid request index specified for run-if: %d", index)
+		}
+		if err := generators.ValidateExpression(expression); err != nil {
+			return fmt.Errorf("could not compile run-if of request %d: %s", index, err)
+		}
+		compiled, err := govaluate.NewEvaluableExpressionWithFunctions(expression, generators.HelperFunctions())
+		if err != nil {
+			return fmt.Errorf("could not compile run-if of request %d: %s", index, err)
+		}
+		r.runIf[index] = compiled
+	}
+	return nil
+}
+
+// HasGuards returns true if some requests have a run-if guard
+func (r *BulkHTTPRequest) HasGuards() bool {
+	return len(r.runIf) > 0
+}
+
+// Guard returns the compiled guard of the request at the 0-based position if any
+func (r *BulkHTTPRequest) Guard(position int) (*govaluate.EvaluableExpression, bool) {
+	guard, ok := r.runIf[position+1]
+	return guard, ok
+}
+
 // GetAttackType returns the attack
 func (r *BulkHTTPRequest) GetAttackType() generators.Type {
 	return r.attackType
@@ -81,26 +260,99 @@ func (r *BulkHTTPRequest) GetRequestCount() int64 {
 	return int64(len(r.Raw) | len(r.Path))
 }
 
+// CountRequests returns the number of requests sent to a target, the raw
+// requests being sent with each combination of the payloads if any.
+func (r *BulkHTTPRequest) CountRequests() int64 {
+	if len(r.Payloads) == 0 || r.gsfm == nil {
+		return r.GetRequestCount()
+	}
+	return int64(len(r.Path) + len(r.Raw)*r.gsfm.Combinations())
+}
+
 func (r *BulkHTTPRequest) MakeHTTPRequest(baseURL string, dynamicValues map[string]interface{}, data string) (*HttpRequest, error) {
-	parsed, err := url.Parse(baseURL)
+	values, err := requestValues(baseURL, dynamicValues)
 	if err != nil {
 		return nil, err
 	}
-	hostname := parsed.Host
-
-	values := generators.MergeMaps(dynamicValues, map[string]interface{}{
-		"BaseURL":  baseURL,
-		"Hostname": hostname,
-	})
 
 	// if data contains \n it's a raw request
+	var request *HttpRequest
 	if strings.Contains(data, "\n") {
-		return r.makeHTTPRequestFromRaw(baseURL, data, values)
+		request, err = r.makeHTTPRequestFromRaw(baseURL, data, values)
+	} else {
+		request, err = r.makeHTTPRequestFromModel(baseURL, data, values)
+	}
+	if err != nil {
+		return nil, err
+	}
+	request.Data = data
+	return request, nil
+}
+
+// MakeBaselineHTTPRequest creates the baseline of a request built with the
+// payload values, without advancing the payload generator. The numbers of the
+// payloads are replaced with 0 to remove the delays of time based payloads.
+func (r *BulkHTTPRequest) MakeBaselineHTTPRequest(baseURL string, dynamicValues map[string]interface{}, data string, payloads map[string]interface{}) (*HttpRequest, error) {
+	values, err := requestValues(baseURL, dynamicValues)
+	if err != nil {
+		return nil, err
+	}
+
+	if strings.Contains(data, "\n") {
+		baselinePayloads := make(map[string]interface{}, len(payloads))
+		for name, value := range payloads {
+			baselinePayloads[name] = numbersRegex.ReplaceAllString(fmt.Sprint(value), "0")
+		}
+		return r.handleRawWithPaylods(data+"\n", baseURL, values, baselinePayloads)
 	}
 
 	return r.makeHTTPRequestFromModel(baseURL, data, values)
 }
 
+// MakeNotFoundHTTPRequest creates the baseline request of the similarity
+// matchers, requesting a random non-existent path of the base URL.
+func (r *BulkHTTPRequest) MakeNotFoundHTTPRequest(baseURL string, dynamicValues map[string]interface{}) (*HttpRequest, error) {
+	values, err := requestValues(baseURL, dynamicValues)
+	if err != nil {
+		return nil, err
+	}
+
+	random := make([]byte, 12)
+	if _, err := rand.Read(random); err != nil {
+		return nil, err
+	}
+	return r.makeHTTPRequestFromModel(baseURL, "{{BaseURL}}/"+hex.EncodeToString(random), values)
+}
+
+// numbersRegex matches the numbers of the payloads replaced in baseline requests
+var numbersRegex = regexp.MustCompile(`[0-9]+`)
+
+// dynamicValuesRegex matches the potential dsl expressions between {{}}
+var dynamicValuesRegex = regexp.MustCompile(`(?m)\{\{.+}}`)
+
+// requestValues returns the placeholder values of the requests to a base URL
+func requestValues(baseURL string, dynamicValues map[string]interface{}) (map[string]interface{}, error) {
+	values, err := TargetValues(baseURL)
+	if err != nil {
+		return nil, err
+	}
+	return generators.MergeMaps(dynamicValues, values), nil
+}
+
+// TargetValues returns the placeholder values derived from a base URL
+func TargetValues(baseURL string) (map[string]interface{}, error) {
+	parsed, err := url.Parse(baseURL)
+	if err != nil {
+		return nil, err
+	}
+	hostname := parsed.Host
+
+	return map[string]interface{}{
+		"BaseURL":  baseURL,
+		"Hostname": hostname,
+	}, nil
+}
+
 // MakeHTTPRequestFromModel creates a *http.Request from a request template
 func (r *BulkHTTPRequest) makeHTTPRequestFromModel(baseURL string, data string, values map[string]interface{}) (*HttpRequest, error) {
 	replacer := newReplacer(values)
@@ -117,7 +369,7 @@ func (r *BulkHTTPRequest) makeHTTPRequestFromModel(baseURL string, data string,
 		return nil, err
 	}
 
-	return &HttpRequest{Request: request}, nil
+	return &HttpRequest{Request: request, values: values, authoredHeaders: headerNames(r.Headers, nil)}, nil
 }
 
 func (r *BulkHTTPRequest) InitGenerator() {
@@ -132,10 +384,89 @@ func (r *BulkHTTPRequest) HasGenerator(URL string) bool {
 	return r.gsfm.Has(URL)
 }
 
+// DeleteGenerator forgets the generator of the payloads of a target, its
+// requests being sent again from the first payloads.
+func (r *BulkHTTPRequest) DeleteGenerator(URL string) {
+	r.gsfm.Delete(URL)
+}
+
+// StopGenerator stops the generator of the payloads of a target whose
+// requests are abandoned.
+func (r *BulkHTTPRequest) StopGenerator(URL string) {
+	r.gsfm.Stop(URL)
+}
+
+// GeneratorStates returns the number of generators of the targets by state
+func (r *BulkHTTPRequest) GeneratorStates() map[GeneratorState]int {
+	if r.gsfm == nil {
+		return nil
+	}
+	return r.gsfm.States()
+}
+
 func (r *BulkHTTPRequest) ReadOne(URL string) {
 	r.gsfm.ReadOne(URL)
 }
 
+// targetPlaceholders removes the placeholders of the values derived from
+// the target, the same for all the requests to a target.
+var targetPlaceholders = strings.NewReplacer("{{BaseURL}}", "", "{{Hostname}}", "")
+
+// ClusterKey returns the key of the request of a block sending a single
+// request whose values only depend on the target, the blocks with the same
+// key sending the same request to a target. False is returned for the
+// blocks with payloads, cookie reuse, guards, iterations, fuzzing rules or
+// baselines.
+func (r *BulkHTTPRequest) ClusterKey() (string, bool) {
+	if len(r.Path) != 1 || len(r.Raw) > 0 || len(r.Payloads) > 0 || r.CookieReuse || len(r.RunIf) > 0 || r.IterateAll || len(r.Fuzzing) > 0 || r.Baseline {
+		return "", false
+	}
+	for _, matcher := range r.Matchers {
+		if matcher.Baseline {
+			return "", false
+		}
+	}
+
+	method := strings.ToUpper(r.Method)
+	if method == "" {
+		method = http.MethodGet
+	}
+	names := make([]string, 0, len(r.Headers))
+	headers := make(map[string]string, len(r.Headers))
+	for name, value := range r.Headers {
+		canonical := http.CanonicalHeaderKey(name)
+		names = append(names, canonical)
+		headers[canonical] = value
+	}
+	sort.Strings(names)
+
+	parts := []string{method, r.Path[0], r.Body}
+	for _, name := range names {
+		parts = append(parts, name+": "+headers[name])
+	}
+	for _, part := range parts {
+		if strings.Contains(targetPlaceholders.Replace(part), "{{") {
+			return "", false
+		}
+	}
+	parts = append(parts, strconv.FormatBool(r.Redirects), strconv.Itoa(r.MaxRedirects))
+	return strings.Join(parts, "\x00"), true
+}
+
+// Skip advances the generator of a target past its current request without
+// building it, reading the payload values of a raw request if any.
+func (r *BulkHTTPRequest) Skip(URL string) {
+	if len(r.Payloads) > 0 && strings.Contains(r.Current(URL), "\n") {
+		r.gsfm.InitOrSkip(URL)
+		r.ReadOne(URL)
+	}
+	r.Increment(URL)
+}
+
+// ErrPayloadsDone is returned building a raw request whose payloads are
+// exhausted, the request being done once incremented.
+var ErrPayloadsDone = errors.New("no more payload values")
+
 // makeHTTPRequestFromRaw creates a *http.Request from a raw request
 func (r *BulkHTTPRequest) makeHTTPRequestFromRaw(baseURL string, data string, values map[string]interface{}) (*HttpRequest, error) {
 	// Add trailing line
@@ -143,7 +474,11 @@ func (r *BulkHTTPRequest) makeHTTPRequestFromRaw(baseURL string, data string, va
 	if len(r.Payloads) > 0 {
 		r.gsfm.InitOrSkip(baseURL)
 		r.ReadOne(baseURL)
-		return r.handleRawWithPaylods(data, baseURL, values, r.gsfm.Value(baseURL))
+		payloads := r.gsfm.Value(baseURL)
+		if payloads == nil {
+			return nil, ErrPayloadsDone
+		}
+		return r.handleRawWithPaylods(data, baseURL, values, payloads)
 	}
 
 	// otherwise continue with normal flow
@@ -161,8 +496,7 @@ func (r *BulkHTTPRequest) handleRawWithPaylods(raw string, baseURL string, value
 
 	dynamicValues := make(map[string]interface{})
 	// find all potentials tokens between {{}}
-	var re = regexp.MustCompile(`(?m)\{\{.+}}`)
-	for _, match := range re.FindAllString(raw, -1) {
+	for _, match := range dynamicValuesRegex.FindAllString(raw, -1) {
 		// check if the match contains a dynamic variable
 		expr := generators.TrimDelimiters(match)
 		compiled, err := govaluate.NewEvaluableExpressionWithFunctions(expr, generators.HelperFunctions())
@@ -200,7 +534,7 @@ func (r *BulkHTTPRequest) handleRawWithPaylods(raw string, baseURL string, value
 		return nil, err
 	}
 
-	return &HttpRequest{Request: request, Meta: genValues}, nil
+	return &HttpRequest{Request: request, Meta: genValues, values: finValues, authoredHeaders: headerNames(r.Headers, compiledRequest.Headers)}, nil
 }
 
 func (r *BulkHTTPRequest) fillRequest(req *http.Request, values map[string]interface{}) (*retryablehttp.Request, error) {
@@ -210,7 +544,7 @@ func (r *BulkHTTPRequest) fillRequest(req *http.Request, values map[string]inter
 
 	// Check if the user requested a request body
 	if r.Body != "" {
-		req.Body = ioutil.NopCloser(strings.NewReader(r.Body))
+		req.Body = ioutil.NopCloser(strings.NewReader(replacer.Replace(r.Body)))
 	}
 
 	// Set the header values requested
@@ -241,6 +575,105 @@ func (r *BulkHTTPRequest) fillRequest(req *http.Request, values map[string]inter
 type HttpRequest struct {
 	Request *retryablehttp.Request
 	Meta    map[string]interface{}
+	// IteratedValue is the extracted value an iterate-all request was built with
+	IteratedValue string
+	// Data is the path or the raw request of the template the request was
+	// built from, before the replacement of the values.
+	Data string
+	// InteractshURL is the url of the interactsh server the request was
+	// built with, if it uses {{interactsh-url}}.
+	InteractshURL string
+	// Fuzzing is the payload injected into a parameter of the request by a
+	// fuzzing rule, if it was fuzzed.
+	Fuzzing *fuzzing.Mutation
+	// Session is the session of the host the request was built with, if any
+	Session *auth.Session
+
+	// values are the placeholder values used to build the request
+	values map[string]interface{}
+	// authoredHeaders contains the lowercased names of the headers set by the template
+	authoredHeaders map[string]struct{}
+}
+
+// SetCustomHeaders adds the user supplied global headers to the request.
+//
+// Placeholders in the header values are replaced with the values used to build
+// the request. Headers authored by the template take precedence unless force is true.
+func (r *HttpRequest) SetCustomHeaders(headers CustomHeaders, force bool) {
+	replacer := newReplacer(r.values)
+
+	for _, customHeader := range headers {
+		tokens := strings.SplitN(customHeader, ":", 2)
+		// if it's an invalid header skip it
+		if len(tokens) < 2 {
+			continue
+		}
+
+		headerName := strings.TrimSpace(tokens[0])
+		headerValue := strings.TrimSpace(replacer.Replace(tokens[1]))
+		if headerName == "" {
+			continue
+		}
+
+		if _, ok := r.authoredHeaders[strings.ToLower(headerName)]; ok {
+			if !force {
+				continue
+			}
+			// remove the authored header irrespective of the case used
+			for name := range r.Request.Header {
+				if strings.EqualFold(name, headerName) {
+					delete(r.Request.Header, name)
+				}
+			}
+		}
+		r.Request.Header[headerName] = []string{headerValue}
+	}
+}
+
+// Fuzz returns a copy of a built request with the value of a payload
+// injected into a parameter by a fuzzing rule, the payload being added to
+// its payload values. The placeholders of the payload are replaced with
+// the values of the request along with the values given, i.e a new
+// {{interactsh-url}}.
+func (r *HttpRequest) Fuzz(rule *fuzzing.Rule, parameter *fuzzing.Parameter, payload fuzzing.Payload, values map[string]interface{}) (*HttpRequest, error) {
+	body, err := r.Request.BodyBytes()
+	if err != nil {
+		return nil, err
+	}
+	finValues := generators.MergeMaps(r.values, values)
+	value := newPlaceholderReplacer(finValues).Replace(payload.Value)
+
+	req, body := rule.Mutate(r.Request.Request, body, parameter, value)
+	req.Body = nil
+	if len(body) > 0 {
+		req.Body = ioutil.NopCloser(bytes.NewReader(body))
+	}
+	request, err := retryablehttp.FromRequest(req)
+	if err != nil {
+		return nil, err
+	}
+	return &HttpRequest{
+		Request:         request,
+		Meta:            generators.MergeMaps(r.Meta, map[string]interface{}{payload.Name: value}),
+		IteratedValue:   r.IteratedValue,
+		Data:            r.Data,
+		InteractshURL:   r.InteractshURL,
+		Fuzzing:         rule.Mutation(parameter, payload.Name, value),
+		Session:         r.Session,
+		values:          finValues,
+		authoredHeaders: r.authoredHeaders,
+	}, nil
+}
+
+// headerNames returns the lowercased names of the headers in the maps
+func headerNames(maps ...map[string]string) map[string]struct{} {
+	names := make(map[string]struct{})
+	for _, m := range maps {
+		for name := range m {
+			names[strings.ToLower(name)] = struct{}{}
+		}
+	}
+	return names
 }
 
 // CustomHeaders valid for all requests
@@ -257,6 +690,20 @@ func (c *CustomHeaders) Set(value string) error {
 	return nil
 }
 
+// Headers returns the values of the valid headers by name, the last value
+// of a header being kept.
+func (c CustomHeaders) Headers() map[string]string {
+	headers := make(map[string]string, len(c))
+	for _, customHeader := range c {
+		tokens := strings.SplitN(customHeader, ":", 2)
+		if len(tokens) < 2 || strings.TrimSpace(tokens[0]) == "" {
+			continue
+		}
+		headers[strings.TrimSpace(tokens[0])] = strings.TrimSpace(tokens[1])
+	}
+	return headers
+}
+
 type RawRequest struct {
 	FullURL string
 	Method  string
@@ -1,10 +1,14 @@
 package requests
 
 import (
+	"fmt"
+	"net"
+	"sort"
 	"strings"
 
 	"github.com/miekg/dns"
 	"github.com/projectdiscovery/nuclei/v2/pkg/extractors"
+	"github.com/projectdiscovery/nuclei/v2/pkg/generators"
 	"github.com/projectdiscovery/nuclei/v2/pkg/matchers"
 )
 
@@ -16,6 +20,20 @@ type DNSRequest struct {
 	Type    string `yaml:"type"`
 	Class   string `yaml:"class"`
 	Retries int    `yaml:"retries"`
+	// Timeout is the seconds to wait for a response, overriding the global timeout
+	Timeout int `yaml:"timeout,omitempty"`
+	// Resolvers optionally overrides the resolvers to use for the request
+	Resolvers []string `yaml:"resolvers,omitempty"`
+	// Trace resolves the request iteratively recording the delegation chain
+	Trace bool `yaml:"trace,omitempty"`
+	// TraceStart is the zone to start the trace from instead of the roots
+	TraceStart string `yaml:"trace-start,omitempty"`
+	// TraceMaxDepth is the maximum number of delegations followed in a trace
+	TraceMaxDepth int `yaml:"trace-max-depth,omitempty"`
+	// TransferMaxRecords is the maximum number of records retained by AXFR requests
+	TransferMaxRecords int `yaml:"transfer-max-records,omitempty"`
+	// DNSSEC sets the DO bit on the request to receive the DNSSEC records
+	DNSSEC bool `yaml:"dnssec,omitempty"`
 	// Raw contains a raw request
 	Raw string `yaml:"raw,omitempty"`
 
@@ -42,23 +60,44 @@ func (r *DNSRequest) SetMatchersCondition(condition matchers.ConditionType) {
 	r.matchersCondition = condition
 }
 
+// IsPTR returns true if the request is a reverse dns lookup
+func (r *DNSRequest) IsPTR() bool {
+	return toQType(r.Type) == dns.TypePTR
+}
+
 // Returns the total number of requests the YAML rule will perform
 func (r *DNSRequest) GetRequestCount() int64 {
 	return 1
 }
 
-// MakeDNSRequest creates a *dns.Request from a request template
-func (r *DNSRequest) MakeDNSRequest(domain string) (*dns.Msg, error) {
+// MakeDNSRequest creates a *dns.Request from a request template.
+//
+// For PTR requests towards an ip address, the FQDN is the reverse
+// in-addr.arpa or ip6.arpa name of the address. values are the additional
+// placeholder values of the name, i.e the variables of the template.
+func (r *DNSRequest) MakeDNSRequest(domain string, values map[string]interface{}) (*dns.Msg, error) {
+	if toQType(r.Type) == dns.TypePTR && net.ParseIP(domain) != nil {
+		reverse, err := dns.ReverseAddr(domain)
+		if err != nil {
+			return nil, err
+		}
+		domain = reverse
+	}
 	domain = dns.Fqdn(domain)
 
 	// Build a request on the specified URL
 	req := new(dns.Msg)
 	req.Id = dns.Id()
 	req.RecursionDesired = r.Recursion
+	if r.DNSSEC {
+		// ask for the DNSSEC records and the validation status of the resolver
+		req.SetEdns0(4096, true)
+		req.AuthenticatedData = true
+	}
 
 	var q dns.Question
 
-	replacer := newReplacer(map[string]interface{}{"FQDN": domain})
+	replacer := newReplacer(generators.MergeMaps(values, map[string]interface{}{"FQDN": domain}))
 
 	q.Name = dns.Fqdn(replacer.Replace(r.Name))
 	q.Qclass = toQClass(r.Class)
@@ -69,30 +108,53 @@ func (r *DNSRequest) MakeDNSRequest(domain string) (*dns.Msg, error) {
 	return req, nil
 }
 
-func toQType(ttype string) (rtype uint16) {
+// DNSTypes is a table for conversion of dns record types from string.
+var DNSTypes = map[string]uint16{
+	"A":     dns.TypeA,
+	"NS":    dns.TypeNS,
+	"CNAME": dns.TypeCNAME,
+	"SOA":   dns.TypeSOA,
+	"PTR":   dns.TypePTR,
+	"MX":    dns.TypeMX,
+	"TXT":   dns.TypeTXT,
+	"AAAA":  dns.TypeAAAA,
+	"CAA":   dns.TypeCAA,
+	"SRV":   dns.TypeSRV,
+	"NAPTR": dns.TypeNAPTR,
+	"ANY":   dns.TypeANY,
+	"AXFR":  dns.TypeAXFR,
+}
+
+// SupportedDNSTypes returns the sorted list of supported dns record types
+func SupportedDNSTypes() []string {
+	var types []string
+	for name := range DNSTypes {
+		types = append(types, name)
+	}
+	sort.Strings(types)
+	return types
+}
+
+// ValidateType checks if the dns record type of the request is supported.
+//
+// An empty type defaults to A.
+func (r *DNSRequest) ValidateType() error {
+	if strings.TrimSpace(r.Type) == "" {
+		return nil
+	}
+	if _, ok := DNSTypes[strings.TrimSpace(strings.ToUpper(r.Type))]; !ok {
+		return fmt.Errorf("unknown dns type specified: %s (supported: %s)", r.Type, strings.Join(SupportedDNSTypes(), ", "))
+	}
+	return nil
+}
+
+func toQType(ttype string) uint16 {
 	ttype = strings.TrimSpace(strings.ToUpper(ttype))
 
-	switch ttype {
-	case "A":
-		rtype = dns.TypeA
-	case "NS":
-		rtype = dns.TypeNS
-	case "CNAME":
-		rtype = dns.TypeCNAME
-	case "SOA":
-		rtype = dns.TypeSOA
-	case "PTR":
-		rtype = dns.TypePTR
-	case "MX":
-		rtype = dns.TypeMX
-	case "TXT":
-		rtype = dns.TypeTXT
-	case "AAAA":
-		rtype = dns.TypeAAAA
-	default:
-		rtype = dns.TypeA
+	if rtype, ok := DNSTypes[ttype]; ok {
+		return rtype
 	}
-	return
+	return dns.TypeA
 }
 
 func toQClass(tclass string) (rclass uint16) {
@@ -0,0 +1,66 @@
+package requests
+
+import (
+	"github.com/projectdiscovery/nuclei/v2/pkg/extractors"
+	"github.com/projectdiscovery/nuclei/v2/pkg/file"
+	"github.com/projectdiscovery/nuclei/v2/pkg/matchers"
+)
+
+// FileRequest contains the matchers and the extractors run over the content
+// of the local files of the targets from a template
+type FileRequest struct {
+	// Extensions are the extensions of the files read, i.e .go or go, all
+	// the files being read if empty.
+	Extensions []string `yaml:"extensions,omitempty"`
+	// ExcludeExtensions are the extensions of the files skipped
+	ExcludeExtensions []string `yaml:"exclude-extensions,omitempty"`
+	// MaxSize is the maximum size of the files read in bytes, 5 MiB by
+	// default.
+	MaxSize int64 `yaml:"max-size,omitempty"`
+	// Archives reads the archives as opaque files, without extracting them.
+	// They are skipped otherwise.
+	Archives bool `yaml:"archives,omitempty"`
+	// Binary reads the binary files, skipped otherwise
+	Binary bool `yaml:"binary,omitempty"`
+
+	// Matchers contains the detection mechanism for the request to identify
+	// whether the request was successful
+	Matchers []*matchers.Matcher `yaml:"matchers,omitempty"`
+	// matchersCondition is internal condition for the matchers.
+	matchersCondition matchers.ConditionType
+	// MatchersCondition is the condition of the matchers
+	// whether to use AND or OR. Default is OR.
+	MatchersCondition string `yaml:"matchers-condition,omitempty"`
+	// Extractors contains the extraction mechanism for the request to identify
+	// and extract parts of the response.
+	Extractors []*extractors.Extractor `yaml:"extractors,omitempty"`
+}
+
+// GetMatchersCondition returns the condition for the matcher
+func (r *FileRequest) GetMatchersCondition() matchers.ConditionType {
+	return r.matchersCondition
+}
+
+// SetMatchersCondition sets the condition for the matcher
+func (r *FileRequest) SetMatchersCondition(condition matchers.ConditionType) {
+	r.matchersCondition = condition
+}
+
+// Returns the total number of requests the YAML rule will perform, a
+// single walk of each target whatever its number of files
+func (r *FileRequest) GetRequestCount() int64 {
+	return 1
+}
+
+// WalkOptions returns the options of the walk of the targets, reading at
+// most concurrency files at once.
+func (r *FileRequest) WalkOptions(concurrency int) *file.Options {
+	return &file.Options{
+		Extensions:        r.Extensions,
+		ExcludeExtensions: r.ExcludeExtensions,
+		MaxSize:           r.MaxSize,
+		Archives:          r.Archives,
+		Binary:            r.Binary,
+		Concurrency:       concurrency,
+	}
+}
@@ -15,12 +15,25 @@ const (
 	Done
 )
 
+// String returns the name of the state of a generator
+func (s GeneratorState) String() string {
+	switch s {
+	case Init:
+		return "init"
+	case Running:
+		return "running"
+	}
+	return "done"
+}
+
 type Generator struct {
 	sync.RWMutex
-	positionPath          int
-	positionRaw           int
-	currentPayloads       map[string]interface{}
-	gchan                 chan map[string]interface{}
+	positionPath    int
+	positionRaw     int
+	currentPayloads map[string]interface{}
+	gchan           chan map[string]interface{}
+	// done stops the generator of the payloads if they are not all read
+	done                  chan struct{}
 	currentGeneratorValue map[string]interface{}
 	state                 GeneratorState
 }
@@ -29,7 +42,7 @@ type GeneratorFSM struct {
 	sync.RWMutex
 	payloads     map[string]interface{}
 	basePayloads map[string][]string
-	generator    func(payloads map[string][]string) (out chan map[string]interface{})
+	generator    func(payloads map[string][]string, done <-chan struct{}) (out chan map[string]interface{})
 	Generators   map[string]*Generator
 	Type         generators.Type
 	Paths        []string
@@ -39,6 +52,7 @@ type GeneratorFSM struct {
 func NewGeneratorFSM(typ generators.Type, payloads map[string]interface{}, paths, raws []string) *GeneratorFSM {
 	var gsfm GeneratorFSM
 	gsfm.payloads = payloads
+	gsfm.Type = typ
 	gsfm.Paths = paths
 	gsfm.Raws = raws
 
@@ -133,12 +147,33 @@ func (gfsm *GeneratorFSM) InitOrSkip(key string) {
 		g.Lock()
 		defer g.Unlock()
 		if g.gchan == nil {
-			g.gchan = gfsm.generator(gfsm.basePayloads)
+			g.done = make(chan struct{})
+			g.gchan = gfsm.generator(gfsm.basePayloads, g.done)
 			g.state = Running
 		}
 	}
 }
 
+// Stop stops the generator of the payloads of a key whose requests are
+// abandoned before reading all of them.
+func (gfsm *GeneratorFSM) Stop(key string) {
+	gfsm.RLock()
+	defer gfsm.RUnlock()
+
+	g, ok := gfsm.Generators[key]
+	if !ok {
+		return
+	}
+	g.Lock()
+	defer g.Unlock()
+	if g.done != nil {
+		close(g.done)
+		g.done = nil
+	}
+	g.gchan = nil
+	g.state = Done
+}
+
 func (gfsm *GeneratorFSM) Value(key string) map[string]interface{} {
 	gfsm.RLock()
 	defer gfsm.RUnlock()
@@ -151,6 +186,12 @@ func (gfsm *GeneratorFSM) Value(key string) map[string]interface{} {
 	return g.currentGeneratorValue
 }
 
+// Combinations returns the number of combinations of the payloads each raw
+// request is sent with, 0 without payloads.
+func (gfsm *GeneratorFSM) Combinations() int {
+	return generators.Combinations(gfsm.Type, gfsm.basePayloads)
+}
+
 func (gfsm *GeneratorFSM) hasPayloads() bool {
 	return len(gfsm.basePayloads) > 0
 }
@@ -217,6 +258,21 @@ func (gfsm *GeneratorFSM) Current(key string) string {
 
 	return gfsm.Raws[g.positionRaw]
 }
+
+// States returns the number of generators by state, one by key
+func (gfsm *GeneratorFSM) States() map[GeneratorState]int {
+	gfsm.RLock()
+	defer gfsm.RUnlock()
+
+	states := make(map[GeneratorState]int)
+	for _, g := range gfsm.Generators {
+		g.RLock()
+		states[g.state]++
+		g.RUnlock()
+	}
+	return states
+}
+
 func (gfsm *GeneratorFSM) Total() int {
 	return len(gfsm.Paths) + len(gfsm.Raws)
 }
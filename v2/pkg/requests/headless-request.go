@@ -0,0 +1,64 @@
+package requests
+
+import (
+	"github.com/projectdiscovery/nuclei/v2/pkg/extractors"
+	"github.com/projectdiscovery/nuclei/v2/pkg/headless"
+	"github.com/projectdiscovery/nuclei/v2/pkg/matchers"
+)
+
+// HeadlessRequest contains the steps run in a headless browser page from a template
+type HeadlessRequest struct {
+	// Steps are the actions run in the page in order, i.e navigating to
+	// {{BaseURL}}, filling a field and clicking a button.
+	Steps []*headless.Action `yaml:"steps"`
+	// Timeout is the seconds all the steps can take, unlimited otherwise
+	Timeout int `yaml:"timeout,omitempty"`
+
+	// Matchers contains the detection mechanism for the request to identify
+	// whether the request was successful
+	Matchers []*matchers.Matcher `yaml:"matchers,omitempty"`
+	// matchersCondition is internal condition for the matchers.
+	matchersCondition matchers.ConditionType
+	// MatchersCondition is the condition of the matchers
+	// whether to use AND or OR. Default is OR.
+	MatchersCondition string `yaml:"matchers-condition,omitempty"`
+	// Extractors contains the extraction mechanism for the request to identify
+	// and extract parts of the response.
+	Extractors []*extractors.Extractor `yaml:"extractors,omitempty"`
+}
+
+// GetMatchersCondition returns the condition for the matcher
+func (r *HeadlessRequest) GetMatchersCondition() matchers.ConditionType {
+	return r.matchersCondition
+}
+
+// SetMatchersCondition sets the condition for the matcher
+func (r *HeadlessRequest) SetMatchersCondition(condition matchers.ConditionType) {
+	r.matchersCondition = condition
+}
+
+// Returns the total number of requests the YAML rule will perform
+func (r *HeadlessRequest) GetRequestCount() int64 {
+	return 1
+}
+
+// MakeActions returns the steps of the request towards a base URL, their
+// placeholders being replaced. values are the additional placeholder values,
+// i.e the variables of the template.
+func (r *HeadlessRequest) MakeActions(baseURL string, values map[string]interface{}) ([]*headless.Action, error) {
+	values, err := requestValues(baseURL, values)
+	if err != nil {
+		return nil, err
+	}
+	replacer := newReplacer(values)
+
+	actions := make([]*headless.Action, 0, len(r.Steps))
+	for _, step := range r.Steps {
+		args := make(map[string]string, len(step.Args))
+		for name, value := range step.Args {
+			args[name] = replacer.Replace(value)
+		}
+		actions = append(actions, step.WithArgs(args))
+	}
+	return actions, nil
+}
@@ -0,0 +1,184 @@
+package requests
+
+import (
+	"fmt"
+	"net"
+	"net/url"
+	"strings"
+
+	"github.com/projectdiscovery/nuclei/v2/pkg/extractors"
+	"github.com/projectdiscovery/nuclei/v2/pkg/generators"
+	"github.com/projectdiscovery/nuclei/v2/pkg/matchers"
+	"github.com/projectdiscovery/nuclei/v2/pkg/network"
+)
+
+// NetworkRequest contains the steps sent over a raw tcp or udp connection
+// from a template
+type NetworkRequest struct {
+	// Host are the addresses to connect to as host:port, i.e {{Hostname}}
+	// for the host and port of the target or {{Host}}:6379.
+	Host []string `yaml:"host"`
+	// Protocol is the protocol of the connections, tcp or udp. Default is tcp.
+	Protocol string `yaml:"protocol,omitempty"`
+	// TLS wraps the tcp connections in tls
+	TLS bool `yaml:"tls,omitempty"`
+	// Inputs are the steps sent in order over the same connection, the
+	// banner of the service being read without inputs.
+	Inputs []*network.Input `yaml:"inputs,omitempty"`
+	// ReadSize is the number of bytes read by the steps not specifying it,
+	// 1024 by default.
+	ReadSize int `yaml:"read-size,omitempty"`
+	// Timeout is the seconds the connection and each step can take,
+	// overriding the global timeout
+	Timeout int `yaml:"timeout,omitempty"`
+	// AttackType is the attack type
+	// Sniper, PitchFork and ClusterBomb. Default is Sniper
+	AttackType string `yaml:"attack,omitempty"`
+	// attackType is internal attack type
+	attackType generators.Type
+	// Payloads are the values of the placeholders of the inputs, a
+	// connection being made for each of their combinations.
+	Payloads map[string]interface{} `yaml:"payloads,omitempty"`
+	// payloads are the loaded values of the payloads
+	payloads map[string][]string
+
+	// Matchers contains the detection mechanism for the request to identify
+	// whether the request was successful
+	Matchers []*matchers.Matcher `yaml:"matchers,omitempty"`
+	// matchersCondition is internal condition for the matchers.
+	matchersCondition matchers.ConditionType
+	// MatchersCondition is the condition of the matchers
+	// whether to use AND or OR. Default is OR.
+	MatchersCondition string `yaml:"matchers-condition,omitempty"`
+	// Extractors contains the extraction mechanism for the request to identify
+	// and extract parts of the response.
+	Extractors []*extractors.Extractor `yaml:"extractors,omitempty"`
+}
+
+// GetMatchersCondition returns the condition for the matcher
+func (r *NetworkRequest) GetMatchersCondition() matchers.ConditionType {
+	return r.matchersCondition
+}
+
+// SetMatchersCondition sets the condition for the matcher
+func (r *NetworkRequest) SetMatchersCondition(condition matchers.ConditionType) {
+	r.matchersCondition = condition
+}
+
+// GetAttackType returns the attack
+func (r *NetworkRequest) GetAttackType() generators.Type {
+	return r.attackType
+}
+
+// SetAttackType sets the attack
+func (r *NetworkRequest) SetAttackType(attack generators.Type) {
+	r.attackType = attack
+}
+
+// InitPayloads loads the values of the payloads of the request
+func (r *NetworkRequest) InitPayloads() {
+	if len(r.Payloads) > 0 {
+		r.payloads = generators.LoadPayloads(r.Payloads)
+	}
+}
+
+// Returns the total number of requests the YAML rule will perform
+func (r *NetworkRequest) GetRequestCount() int64 {
+	combinations := 1
+	if len(r.payloads) > 0 {
+		combinations = generators.Combinations(r.attackType, r.payloads)
+	}
+	return int64(len(r.Host) * combinations)
+}
+
+// PayloadValues returns the combinations of the values of the payloads
+// until done is closed, a single empty combination without payloads.
+func (r *NetworkRequest) PayloadValues(done <-chan struct{}) <-chan map[string]interface{} {
+	return payloadValues(r.attackType, r.payloads, done)
+}
+
+// payloadValues returns the combinations of the values of payloads for an
+// attack type until done is closed, a single empty combination without
+// payloads.
+func payloadValues(attackType generators.Type, payloads map[string][]string, done <-chan struct{}) <-chan map[string]interface{} {
+	if len(payloads) == 0 {
+		values := make(chan map[string]interface{}, 1)
+		values <- map[string]interface{}{}
+		close(values)
+		return values
+	}
+	switch attackType {
+	case generators.PitchFork:
+		return generators.PitchforkGenerator(payloads, done)
+	case generators.ClusterBomb:
+		return generators.ClusterbombGenerator(payloads, done)
+	}
+	return generators.SniperGenerator(payloads, done)
+}
+
+// MakeAddresses returns the addresses the request connects to for a
+// target, their placeholders being replaced. values are the additional
+// placeholder values, i.e the variables of the template.
+func (r *NetworkRequest) MakeAddresses(target string, values map[string]interface{}) ([]string, error) {
+	return makeAddresses(r.Host, target, values)
+}
+
+// makeAddresses returns the addresses of hosts for a target, their
+// placeholders being replaced.
+func makeAddresses(hosts []string, target string, values map[string]interface{}) ([]string, error) {
+	replacer := newPlaceholderReplacer(generators.MergeMaps(values, NetworkTargetValues(target)))
+
+	addresses := make([]string, 0, len(hosts))
+	for _, host := range hosts {
+		address := replacer.Replace(host)
+		if _, _, err := net.SplitHostPort(address); err != nil {
+			return nil, fmt.Errorf("invalid address %s, it should be host:port", address)
+		}
+		addresses = append(addresses, address)
+	}
+	return addresses, nil
+}
+
+// MakeInputs returns the steps of the request towards a target, the
+// placeholders of their data being replaced. values are the additional
+// placeholder values, i.e the values of the payloads.
+func (r *NetworkRequest) MakeInputs(target string, values map[string]interface{}) []*network.Input {
+	replacer := newPlaceholderReplacer(generators.MergeMaps(values, NetworkTargetValues(target)))
+
+	inputs := make([]*network.Input, 0, len(r.Inputs))
+	for _, input := range r.Inputs {
+		inputs = append(inputs, input.WithData(replacer.Replace(input.Data)))
+	}
+	return inputs
+}
+
+// defaultPorts are the ports of the schemes of the url targets
+var defaultPorts = map[string]string{
+	"http":  "80",
+	"https": "443",
+}
+
+// NetworkTargetValues returns the placeholder values of a target of the
+// network requests, a bare host:port or a url: Hostname is its host and
+// port, the default port of the scheme of a url, and Host and Port their
+// parts.
+func NetworkTargetValues(target string) map[string]interface{} {
+	hostname := target
+	if strings.Contains(target, "://") {
+		if parsed, err := url.Parse(target); err == nil && parsed.Host != "" {
+			hostname = parsed.Host
+			if parsed.Port() == "" && defaultPorts[parsed.Scheme] != "" {
+				hostname = net.JoinHostPort(parsed.Hostname(), defaultPorts[parsed.Scheme])
+			}
+		}
+	}
+	host, port, err := net.SplitHostPort(hostname)
+	if err != nil {
+		host, port = strings.Trim(hostname, "[]"), ""
+	}
+	return map[string]interface{}{
+		"Hostname": hostname,
+		"Host":     host,
+		"Port":     port,
+	}
+}
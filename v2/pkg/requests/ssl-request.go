@@ -0,0 +1,126 @@
+package requests
+
+import (
+	"time"
+
+	"github.com/projectdiscovery/nuclei/v2/pkg/extractors"
+	"github.com/projectdiscovery/nuclei/v2/pkg/generators"
+	"github.com/projectdiscovery/nuclei/v2/pkg/matchers"
+	"github.com/projectdiscovery/nuclei/v2/pkg/ssl"
+)
+
+// SSLRequest contains the tls handshakes run with the servers of a template
+type SSLRequest struct {
+	// Host are the addresses to connect to as host:port, i.e {{Hostname}}
+	// for the host and port of the target or {{Host}}:587.
+	Host []string `yaml:"host"`
+	// ServerName is the server name sent, the host of the address by default
+	ServerName string `yaml:"server-name,omitempty"`
+	// MinVersion is the minimum version offered, tls10, tls11, tls12 or
+	// tls13. Default is tls10.
+	MinVersion string `yaml:"min-version,omitempty"`
+	// MaxVersion is the maximum version offered. Default is tls13, or tls12
+	// with ciphers.
+	MaxVersion string `yaml:"max-version,omitempty"`
+	// Ciphers are the names of the cipher suites offered, i.e
+	// TLS_RSA_WITH_RC4_128_SHA, all the supported ones by default.
+	Ciphers []string `yaml:"ciphers,omitempty"`
+	// StartTLS is the protocol upgraded with starttls before the handshake,
+	// smtp, imap or pop3.
+	StartTLS string `yaml:"starttls,omitempty"`
+	// Timeout is the seconds the connection and the handshake can take,
+	// overriding the global timeout
+	Timeout int `yaml:"timeout,omitempty"`
+	// AttackType is the attack type
+	// Sniper, PitchFork and ClusterBomb. Default is Sniper
+	AttackType string `yaml:"attack,omitempty"`
+	// attackType is internal attack type
+	attackType generators.Type
+	// Payloads are the values of the placeholders of the server name, the
+	// versions and the ciphers, a handshake being run for each of their
+	// combinations.
+	Payloads map[string]interface{} `yaml:"payloads,omitempty"`
+	// payloads are the loaded values of the payloads
+	payloads map[string][]string
+
+	// Matchers contains the detection mechanism for the request to identify
+	// whether the request was successful
+	Matchers []*matchers.Matcher `yaml:"matchers,omitempty"`
+	// matchersCondition is internal condition for the matchers.
+	matchersCondition matchers.ConditionType
+	// MatchersCondition is the condition of the matchers
+	// whether to use AND or OR. Default is OR.
+	MatchersCondition string `yaml:"matchers-condition,omitempty"`
+	// Extractors contains the extraction mechanism for the request to identify
+	// and extract parts of the response.
+	Extractors []*extractors.Extractor `yaml:"extractors,omitempty"`
+}
+
+// GetMatchersCondition returns the condition for the matcher
+func (r *SSLRequest) GetMatchersCondition() matchers.ConditionType {
+	return r.matchersCondition
+}
+
+// SetMatchersCondition sets the condition for the matcher
+func (r *SSLRequest) SetMatchersCondition(condition matchers.ConditionType) {
+	r.matchersCondition = condition
+}
+
+// GetAttackType returns the attack
+func (r *SSLRequest) GetAttackType() generators.Type {
+	return r.attackType
+}
+
+// SetAttackType sets the attack
+func (r *SSLRequest) SetAttackType(attack generators.Type) {
+	r.attackType = attack
+}
+
+// InitPayloads loads the values of the payloads of the request
+func (r *SSLRequest) InitPayloads() {
+	if len(r.Payloads) > 0 {
+		r.payloads = generators.LoadPayloads(r.Payloads)
+	}
+}
+
+// Returns the total number of requests the YAML rule will perform
+func (r *SSLRequest) GetRequestCount() int64 {
+	combinations := 1
+	if len(r.payloads) > 0 {
+		combinations = generators.Combinations(r.attackType, r.payloads)
+	}
+	return int64(len(r.Host) * combinations)
+}
+
+// PayloadValues returns the combinations of the values of the payloads
+// until done is closed, a single empty combination without payloads.
+func (r *SSLRequest) PayloadValues(done <-chan struct{}) <-chan map[string]interface{} {
+	return payloadValues(r.attackType, r.payloads, done)
+}
+
+// MakeAddresses returns the addresses the request connects to for a
+// target, their placeholders being replaced. values are the additional
+// placeholder values, i.e the variables of the template.
+func (r *SSLRequest) MakeAddresses(target string, values map[string]interface{}) ([]string, error) {
+	return makeAddresses(r.Host, target, values)
+}
+
+// MakeOptions returns the options of the handshakes of the request towards
+// a target, the placeholders of the server name, the versions and the
+// ciphers being replaced. values are the additional placeholder values,
+// i.e the values of the payloads.
+func (r *SSLRequest) MakeOptions(target string, values map[string]interface{}, timeout time.Duration) *ssl.Options {
+	replacer := newPlaceholderReplacer(generators.MergeMaps(values, NetworkTargetValues(target)))
+
+	options := &ssl.Options{
+		ServerName: replacer.Replace(r.ServerName),
+		MinVersion: replacer.Replace(r.MinVersion),
+		MaxVersion: replacer.Replace(r.MaxVersion),
+		StartTLS:   r.StartTLS,
+		Timeout:    timeout,
+	}
+	for _, cipher := range r.Ciphers {
+		options.Ciphers = append(options.Ciphers, replacer.Replace(cipher))
+	}
+	return options
+}
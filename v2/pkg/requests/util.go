@@ -5,6 +5,7 @@ import (
 	"compress/gzip"
 	"fmt"
 	"io/ioutil"
+	"regexp"
 	"strings"
 
 	"github.com/projectdiscovery/retryablehttp-go"
@@ -22,6 +23,17 @@ func newReplacer(values map[string]interface{}) *strings.Replacer {
 	return strings.NewReplacer(replacerItems...)
 }
 
+// newPlaceholderReplacer returns a replacer of the {{name}} placeholders of
+// the values only, the data sent by the network requests possibly holding
+// their bare names, i.e Host in a raw http request.
+func newPlaceholderReplacer(values map[string]interface{}) *strings.Replacer {
+	replacerItems := make([]string, 0, 2*len(values))
+	for k, v := range values {
+		replacerItems = append(replacerItems, fmt.Sprintf("{{%s}}", k), fmt.Sprintf("%v", v))
+	}
+	return strings.NewReplacer(replacerItems...)
+}
+
 // HandleDecompression if the user specified a custom encoding (as golang transport doesn't do this automatically)
 func HandleDecompression(r *retryablehttp.Request, bodyOrig []byte) (bodyDec []byte, err error) {
 	encodingHeader := strings.ToLower(r.Header.Get("Accept-Encoding"))
@@ -42,3 +54,16 @@ func HandleDecompression(r *retryablehttp.Request, bodyOrig []byte) (bodyDec []b
 
 	return bodyOrig, nil
 }
+
+// rawRequestLineRegex matches the request line of the raw requests, the method
+// or the path possibly being placeholders.
+var rawRequestLineRegex = regexp.MustCompile(`^([A-Z]+|\{\{[^}]+}}) (/|http|\{\{)\S* HTTP/[0-9.]+$`)
+
+// ValidateRawRequest returns an error if the request line of a raw request is malformed
+func ValidateRawRequest(raw string) error {
+	line := strings.TrimSpace(strings.SplitN(strings.TrimLeft(raw, " \t\r\n"), "\n", 2)[0])
+	if !rawRequestLineRegex.MatchString(line) {
+		return fmt.Errorf("malformed request line: %q", line)
+	}
+	return nil
+}
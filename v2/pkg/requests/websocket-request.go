@@ -0,0 +1,144 @@
+package requests
+
+import (
+	"github.com/projectdiscovery/nuclei/v2/pkg/extractors"
+	"github.com/projectdiscovery/nuclei/v2/pkg/generators"
+	"github.com/projectdiscovery/nuclei/v2/pkg/matchers"
+	"github.com/projectdiscovery/nuclei/v2/pkg/websocket"
+)
+
+// WebsocketRequest contains the messages sent over an upgraded websocket
+// connection from a template
+type WebsocketRequest struct {
+	// Path are the urls upgraded, i.e {{BaseURL}}/socket, the http and
+	// https urls being upgraded as ws and wss ones.
+	Path []string `yaml:"path"`
+	// Headers are the headers of the upgrade requests, i.e Origin for the
+	// cross-site websocket hijacking checks.
+	Headers map[string]string `yaml:"headers,omitempty"`
+	// Messages are the messages sent in order once the connection is
+	// upgraded, the messages of the server being read afterwards.
+	Messages []*websocket.Message `yaml:"messages,omitempty"`
+	// ReadDuration is the seconds the messages of the server are read for,
+	// 2 by default.
+	ReadDuration int `yaml:"read-duration,omitempty"`
+	// ReadCount stops the read once this number of messages are read
+	ReadCount int `yaml:"read-count,omitempty"`
+	// Timeout is the seconds the connection, the upgrade and each message
+	// sent can take, overriding the global timeout
+	Timeout int `yaml:"timeout,omitempty"`
+	// AttackType is the attack type
+	// Sniper, PitchFork and ClusterBomb. Default is Sniper
+	AttackType string `yaml:"attack,omitempty"`
+	// attackType is internal attack type
+	attackType generators.Type
+	// Payloads are the values of the placeholders of the urls, the headers
+	// and the messages, a connection being made for each of their
+	// combinations.
+	Payloads map[string]interface{} `yaml:"payloads,omitempty"`
+	// payloads are the loaded values of the payloads
+	payloads map[string][]string
+
+	// Matchers contains the detection mechanism for the request to identify
+	// whether the request was successful
+	Matchers []*matchers.Matcher `yaml:"matchers,omitempty"`
+	// matchersCondition is internal condition for the matchers.
+	matchersCondition matchers.ConditionType
+	// MatchersCondition is the condition of the matchers
+	// whether to use AND or OR. Default is OR.
+	MatchersCondition string `yaml:"matchers-condition,omitempty"`
+	// Extractors contains the extraction mechanism for the request to identify
+	// and extract parts of the response.
+	Extractors []*extractors.Extractor `yaml:"extractors,omitempty"`
+}
+
+// GetMatchersCondition returns the condition for the matcher
+func (r *WebsocketRequest) GetMatchersCondition() matchers.ConditionType {
+	return r.matchersCondition
+}
+
+// SetMatchersCondition sets the condition for the matcher
+func (r *WebsocketRequest) SetMatchersCondition(condition matchers.ConditionType) {
+	r.matchersCondition = condition
+}
+
+// GetAttackType returns the attack
+func (r *WebsocketRequest) GetAttackType() generators.Type {
+	return r.attackType
+}
+
+// SetAttackType sets the attack
+func (r *WebsocketRequest) SetAttackType(attack generators.Type) {
+	r.attackType = attack
+}
+
+// InitPayloads loads the values of the payloads of the request
+func (r *WebsocketRequest) InitPayloads() {
+	if len(r.Payloads) > 0 {
+		r.payloads = generators.LoadPayloads(r.Payloads)
+	}
+}
+
+// Returns the total number of requests the YAML rule will perform
+func (r *WebsocketRequest) GetRequestCount() int64 {
+	combinations := 1
+	if len(r.payloads) > 0 {
+		combinations = generators.Combinations(r.attackType, r.payloads)
+	}
+	return int64(len(r.Path) * combinations)
+}
+
+// PayloadValues returns the combinations of the values of the payloads
+// until done is closed, a single empty combination without payloads.
+func (r *WebsocketRequest) PayloadValues(done <-chan struct{}) <-chan map[string]interface{} {
+	return payloadValues(r.attackType, r.payloads, done)
+}
+
+// MakeURLs returns the urls the request upgrades for a base URL, their
+// placeholders being replaced. values are the additional placeholder
+// values, i.e the variables of the template and the values of the payloads.
+func (r *WebsocketRequest) MakeURLs(baseURL string, values map[string]interface{}) ([]string, error) {
+	values, err := requestValues(baseURL, values)
+	if err != nil {
+		return nil, err
+	}
+	replacer := newPlaceholderReplacer(values)
+
+	urls := make([]string, 0, len(r.Path))
+	for _, path := range r.Path {
+		urls = append(urls, replacer.Replace(path))
+	}
+	return urls, nil
+}
+
+// MakeHeaders returns the headers of the upgrade requests for a base URL,
+// their placeholders being replaced.
+func (r *WebsocketRequest) MakeHeaders(baseURL string, values map[string]interface{}) (map[string]string, error) {
+	values, err := requestValues(baseURL, values)
+	if err != nil {
+		return nil, err
+	}
+	replacer := newPlaceholderReplacer(values)
+
+	headers := make(map[string]string, len(r.Headers))
+	for name, value := range r.Headers {
+		headers[name] = replacer.Replace(value)
+	}
+	return headers, nil
+}
+
+// MakeMessages returns the messages of the request for a base URL, the
+// placeholders of their data being replaced.
+func (r *WebsocketRequest) MakeMessages(baseURL string, values map[string]interface{}) ([]*websocket.Message, error) {
+	values, err := requestValues(baseURL, values)
+	if err != nil {
+		return nil, err
+	}
+	replacer := newPlaceholderReplacer(values)
+
+	messages := make([]*websocket.Message, 0, len(r.Messages))
+	for _, message := range r.Messages {
+		messages = append(messages, message.WithData(replacer.Replace(message.Data)))
+	}
+	return messages, nil
+}
@@ -0,0 +1,145 @@
+package retryqueue
+
+import (
+	"bufio"
+	"io"
+	"io/ioutil"
+	"os"
+	"sync"
+
+	jsoniter "github.com/json-iterator/go"
+)
+
+// DefaultMaxSize is the default number of pairs kept in memory, the next
+// ones being spilled to a temporary file.
+const DefaultMaxSize = 10000
+
+// Pair is a run of a job on a target to retry, the job being identified by
+// its index among the jobs of the scan.
+type Pair struct {
+	Job    int    `json:"job"`
+	Target string `json:"target"`
+}
+
+// Queue queues the pairs to retry in memory up to a maximum size, the next
+// ones being spilled to a temporary file as json lines. It is safe for
+// concurrent use.
+type Queue struct {
+	max int
+
+	mutex sync.Mutex
+	pairs []Pair
+	// file is the temporary file of the spilled pairs if any
+	file    *os.File
+	writer  *bufio.Writer
+	spilled int
+}
+
+// New returns a queue keeping at most max pairs in memory
+func New(max int) *Queue {
+	return &Queue{max: max}
+}
+
+// Add queues a pair, spilling it to the temporary file if the queue is full
+func (q *Queue) Add(pair Pair) error {
+	q.mutex.Lock()
+	defer q.mutex.Unlock()
+
+	if len(q.pairs) < q.max {
+		q.pairs = append(q.pairs, pair)
+		return nil
+	}
+	if q.file == nil {
+		file, err := ioutil.TempFile("", "nuclei-retries-*.jsonl")
+		if err != nil {
+			return err
+		}
+		q.file = file
+		q.writer = bufio.NewWriter(file)
+	}
+	data, err := jsoniter.Marshal(pair)
+	if err != nil {
+		return err
+	}
+	if _, err := q.writer.Write(append(data, '\n')); err != nil {
+		return err
+	}
+	q.spilled++
+	return nil
+}
+
+// Len returns the number of pairs queued, in memory or spilled
+func (q *Queue) Len() int {
+	q.mutex.Lock()
+	defer q.mutex.Unlock()
+	return len(q.pairs) + q.spilled
+}
+
+// Spilled returns the number of pairs queued in the temporary file
+func (q *Queue) Spilled() int {
+	q.mutex.Lock()
+	defer q.mutex.Unlock()
+	return q.spilled
+}
+
+// Drain calls a function with each queued pair in the order they were
+// queued, emptying the queue. The pairs queued by the function are kept for
+// the next drain.
+func (q *Queue) Drain(fn func(pair Pair)) error {
+	q.mutex.Lock()
+	pairs, file, writer := q.pairs, q.file, q.writer
+	q.pairs, q.file, q.writer, q.spilled = nil, nil, nil, 0
+	q.mutex.Unlock()
+
+	for _, pair := range pairs {
+		fn(pair)
+	}
+	if file == nil {
+		return nil
+	}
+	defer removeFile(file)
+
+	if err := writer.Flush(); err != nil {
+		return err
+	}
+	if _, err := file.Seek(0, io.SeekStart); err != nil {
+		return err
+	}
+	reader := bufio.NewReader(file)
+	for {
+		line, err := reader.ReadBytes('\n')
+		if len(line) > 0 {
+			var pair Pair
+			if err := jsoniter.Unmarshal(line, &pair); err != nil {
+				return err
+			}
+			fn(pair)
+		}
+		if err == io.EOF {
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+	}
+}
+
+// Close empties the queue, removing its temporary file if any
+func (q *Queue) Close() error {
+	q.mutex.Lock()
+	defer q.mutex.Unlock()
+
+	q.pairs, q.spilled = nil, 0
+	if q.file == nil {
+		return nil
+	}
+	err := removeFile(q.file)
+	q.file, q.writer = nil, nil
+	return err
+}
+
+// removeFile closes and removes a temporary file
+func removeFile(file *os.File) error {
+	file.Close()
+	return os.Remove(file.Name())
+}
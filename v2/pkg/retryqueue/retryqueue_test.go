@@ -0,0 +1,44 @@
+package retryqueue
+
+import (
+	"os"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestQueue(t *testing.T) {
+	q := New(2)
+	for i, target := range []string{"a.example.com", "b.example.com", "c.example.com", "d.example.com"} {
+		require.Nil(t, q.Add(Pair{Job: i, Target: target}), "Could not queue pair")
+	}
+	require.Equal(t, 4, q.Len(), "Could not count the queued pairs")
+	require.Equal(t, 2, q.Spilled(), "Could not spill the pairs over the maximum size")
+	path := q.file.Name()
+
+	var drained []Pair
+	err := q.Drain(func(pair Pair) {
+		drained = append(drained, pair)
+		if pair.Job == 3 {
+			require.Nil(t, q.Add(Pair{Job: pair.Job, Target: pair.Target}), "Could not queue pair while draining")
+		}
+	})
+	require.Nil(t, err, "Could not drain the queue")
+	require.Equal(t, []Pair{{0, "a.example.com"}, {1, "b.example.com"}, {2, "c.example.com"}, {3, "d.example.com"}}, drained, "Could not drain the pairs in order")
+	_, err = os.Stat(path)
+	require.True(t, os.IsNotExist(err), "Could not remove the spilled pairs")
+	require.Equal(t, 1, q.Len(), "Could not keep the pair queued while draining")
+
+	require.Nil(t, q.Close(), "Could not close the queue")
+	require.Zero(t, q.Len(), "Could not empty the closed queue")
+}
+
+func TestQueueClose(t *testing.T) {
+	q := New(0)
+	require.Nil(t, q.Add(Pair{Job: 1, Target: "a.example.com"}), "Could not queue pair")
+	path := q.file.Name()
+
+	require.Nil(t, q.Close(), "Could not close the queue")
+	_, err := os.Stat(path)
+	require.True(t, os.IsNotExist(err), "Could not remove the spilled pairs")
+}
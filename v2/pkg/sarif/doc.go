@@ -0,0 +1,4 @@
+// Package sarif exports the findings of a scan as a SARIF 2.1.0 log for
+// the code scanning integrations, a rule per template and a result per
+// finding located by its matched url.
+package sarif
@@ -0,0 +1,242 @@
+package sarif
+
+import (
+	"fmt"
+	"io/ioutil"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"time"
+
+	jsoniter "github.com/json-iterator/go"
+	"github.com/projectdiscovery/nuclei/v2/pkg/templates"
+)
+
+// Exporter is a concurrency safe collector of the findings of a scan,
+// written to a SARIF log on close.
+//
+// The log is written to a temporary file of the same directory renamed to
+// the file once complete, so an interrupted scan never leaves a partial log.
+type Exporter struct {
+	mutex     sync.Mutex
+	file      string
+	version   string
+	start     time.Time
+	templates int
+	rules     []*rule
+	indexes   map[string]int
+	results   []*result
+	closed    bool
+}
+
+// New creates an exporter writing a SARIF log to a file on close, the scan
+// starting now.
+func New(file, nucleiVersion string) (*Exporter, error) {
+	info, err := os.Stat(filepath.Dir(file))
+	if err != nil {
+		return nil, err
+	}
+	if !info.IsDir() {
+		return nil, fmt.Errorf("%s is not a directory", filepath.Dir(file))
+	}
+	return &Exporter{
+		file:    file,
+		version: nucleiVersion,
+		start:   time.Now(),
+		indexes: make(map[string]int),
+	}, nil
+}
+
+// AddRule adds the rule of a template if it's not added yet
+func (e *Exporter) AddRule(template *templates.Template) {
+	e.mutex.Lock()
+	defer e.mutex.Unlock()
+	e.ruleIndex(template)
+}
+
+// SetTemplates sets the number of templates of the scan
+func (e *Exporter) SetTemplates(count int) {
+	e.mutex.Lock()
+	defer e.mutex.Unlock()
+	e.templates = count
+}
+
+// Add adds a finding of a template at a matched url or domain, along with
+// the name of the matcher if any and the extracted values.
+func (e *Exporter) Add(template *templates.Template, matched, matcherName string, extracted []string) {
+	e.mutex.Lock()
+	defer e.mutex.Unlock()
+
+	text := fmt.Sprintf("%s matched at %s", ruleName(template), matched)
+	properties := make(map[string]interface{})
+	if matcherName != "" {
+		text += fmt.Sprintf(" by the %s matcher", matcherName)
+		properties["matcher"] = matcherName
+	}
+	if len(extracted) > 0 {
+		text += fmt.Sprintf(", extracted %s", strings.Join(extracted, ", "))
+		properties["extracted"] = extracted
+	}
+	e.results = append(e.results, &result{
+		RuleID:     template.ID,
+		RuleIndex:  e.ruleIndex(template),
+		Level:      level(template.Info.Severity),
+		Message:    &message{Text: text},
+		Locations:  []*location{{PhysicalLocation: &physicalLocation{ArtifactLocation: &artifactLocation{URI: locationURI(matched)}}}},
+		Properties: properties,
+	})
+}
+
+// Count returns the number of findings
+func (e *Exporter) Count() int {
+	e.mutex.Lock()
+	defer e.mutex.Unlock()
+	return len(e.results)
+}
+
+// Name returns the name of the file of the SARIF log
+func (e *Exporter) Name() string {
+	return e.file
+}
+
+// Close writes the SARIF log, the scan ending now and being interrupted if
+// it's not successful. The findings added after the first close are ignored.
+func (e *Exporter) Close(successful bool) error {
+	e.mutex.Lock()
+	defer e.mutex.Unlock()
+	if e.closed {
+		return nil
+	}
+	e.closed = true
+
+	execution := &invocation{
+		ExecutionSuccessful: successful,
+		StartTimeUTC:        e.start.UTC().Format(time.RFC3339),
+		EndTimeUTC:          time.Now().UTC().Format(time.RFC3339),
+	}
+	if !successful {
+		execution.ExitCodeDescription = "interrupted"
+	}
+	log := &sarifLog{
+		Schema:  schemaURI,
+		Version: version,
+		Runs: []*run{{
+			Tool: &tool{Driver: &toolComponent{
+				Name:           "nuclei",
+				Version:        e.version,
+				InformationURI: "https://github.com/projectdiscovery/nuclei",
+				Rules:          append([]*rule{}, e.rules...),
+			}},
+			Invocations: []*invocation{execution},
+			Results:     append([]*result{}, e.results...),
+			Properties:  map[string]interface{}{"templates": e.templates},
+		}},
+	}
+	data, err := jsoniter.MarshalIndent(log, "", "  ")
+	if err != nil {
+		return err
+	}
+
+	temp, err := ioutil.TempFile(filepath.Dir(e.file), "."+filepath.Base(e.file)+".*")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(temp.Name())
+	_, err = temp.Write(data)
+	if closeErr := temp.Close(); err == nil {
+		err = closeErr
+	}
+	if err != nil {
+		return err
+	}
+	// the temporary files are created readable by the owner only
+	if err := os.Chmod(temp.Name(), 0644); err != nil {
+		return err
+	}
+	return os.Rename(temp.Name(), e.file)
+}
+
+// ruleIndex returns the index of the rule of a template, adding it if needed
+func (e *Exporter) ruleIndex(template *templates.Template) int {
+	if index, ok := e.indexes[template.ID]; ok {
+		return index
+	}
+	info := template.Info
+	properties := map[string]interface{}{}
+	if tags := info.TagList(); len(tags) > 0 {
+		properties["tags"] = tags
+	}
+	if info.Severity != "" {
+		properties["severity"] = strings.ToLower(info.Severity)
+	}
+	if score := securitySeverity(info.Severity); score != "" {
+		properties["security-severity"] = score
+	}
+	description := info.Description
+	if description == "" {
+		description = ruleName(template)
+	}
+	e.indexes[template.ID] = len(e.rules)
+	e.rules = append(e.rules, &rule{
+		ID:                   template.ID,
+		Name:                 info.Name,
+		ShortDescription:     &message{Text: ruleName(template)},
+		FullDescription:      &message{Text: description},
+		DefaultConfiguration: &reportingConfiguration{Level: level(info.Severity)},
+		Properties:           properties,
+	})
+	return len(e.rules) - 1
+}
+
+// ruleName returns the name of a template, or its id if it has none
+func ruleName(template *templates.Template) string {
+	if template.Info.Name != "" {
+		return template.Info.Name
+	}
+	return template.ID
+}
+
+// level returns the SARIF level of a severity, templates without severity
+// having the default warning level.
+func level(severity string) string {
+	switch strings.ToLower(severity) {
+	case "critical", "high":
+		return "error"
+	case "low", "info":
+		return "note"
+	default:
+		return "warning"
+	}
+}
+
+// securitySeverity returns the score of a severity the code scanning
+// integrations rank the security rules by, if any.
+func securitySeverity(severity string) string {
+	switch strings.ToLower(severity) {
+	case "critical":
+		return "9.5"
+	case "high":
+		return "8.0"
+	case "medium":
+		return "5.5"
+	case "low":
+		return "2.0"
+	default:
+		return ""
+	}
+}
+
+// locationURI returns the uri of a matched url, escaped if needed, or the
+// dns uri of a matched domain.
+func locationURI(matched string) string {
+	if !strings.Contains(matched, "://") {
+		return "dns:" + url.PathEscape(matched)
+	}
+	parsed, err := url.Parse(matched)
+	if err != nil {
+		return url.PathEscape(matched)
+	}
+	return parsed.String()
+}
@@ -0,0 +1,211 @@
+package sarif
+
+import (
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"net/url"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/projectdiscovery/nuclei/v2/pkg/templates"
+	"github.com/stretchr/testify/require"
+)
+
+func TestExporter(t *testing.T) {
+	directory, err := ioutil.TempDir("", "sarif-*")
+	require.Nil(t, err, "Could not create temporary directory")
+	defer os.RemoveAll(directory)
+	file := filepath.Join(directory, "results.sarif")
+
+	exporter, err := New(file, "2.0.5")
+	require.Nil(t, err, "Could not create exporter")
+	apache := &templates.Template{ID: "apache-version", Info: templates.Info{Name: "Apache Version", Severity: "info", Tags: "tech,apache"}}
+	cve := &templates.Template{ID: "CVE-2021-41773", Info: templates.Info{Name: "Apache Path Traversal", Severity: "High", Description: "Path traversal in Apache 2.4.49"}}
+	exporter.AddRule(apache)
+	exporter.SetTemplates(2)
+
+	var wg sync.WaitGroup
+	for _, host := range []string{"a.example.com", "b.example.com"} {
+		wg.Add(1)
+		go func(host string) {
+			defer wg.Done()
+			exporter.Add(cve, "https://"+host+"/cgi-bin/.%2e/.%2e/etc/passwd", "", nil)
+			exporter.Add(apache, host, "version", []string{"2.4.49"})
+		}(host)
+	}
+	wg.Wait()
+	require.Nil(t, exporter.Close(true), "Could not write SARIF log")
+	exporter.Add(cve, "https://c.example.com", "", nil)
+	require.Nil(t, exporter.Close(false), "Could not close the exporter again")
+
+	data, err := ioutil.ReadFile(file)
+	require.Nil(t, err, "Could not read SARIF log")
+	validateSchema(t, data)
+
+	var log sarifLog
+	require.Nil(t, json.Unmarshal(data, &log), "Could not unmarshal SARIF log")
+	run := log.Runs[0]
+	require.Equal(t, "2.0.5", run.Tool.Driver.Version, "Could not write the nuclei version")
+	require.Equal(t, float64(2), run.Properties["templates"], "Could not write the template count")
+	require.True(t, run.Invocations[0].ExecutionSuccessful, "Could not write the scan as successful")
+	require.Len(t, run.Tool.Driver.Rules, 2, "Could not write a rule per template")
+	require.Equal(t, "note", run.Tool.Driver.Rules[0].DefaultConfiguration.Level, "Could not map the info severity")
+	require.Equal(t, []interface{}{"tech", "apache"}, run.Tool.Driver.Rules[0].Properties["tags"], "Could not write the tags")
+	require.Equal(t, "error", run.Tool.Driver.Rules[1].DefaultConfiguration.Level, "Could not map the high severity")
+	require.Len(t, run.Results, 4, "Could not write a result per finding before close")
+
+	var uris, texts []string
+	for _, result := range run.Results {
+		require.Equal(t, result.RuleID, run.Tool.Driver.Rules[result.RuleIndex].ID, "Could not index the rule of a result")
+		uris = append(uris, result.Locations[0].PhysicalLocation.ArtifactLocation.URI)
+		texts = append(texts, result.Message.Text)
+	}
+	require.Contains(t, uris, "https://a.example.com/cgi-bin/.%2e/.%2e/etc/passwd", "Could not locate the result by its url")
+	require.Contains(t, uris, "dns:b.example.com", "Could not locate the result by its domain")
+	require.Contains(t, texts, "Apache Version matched at a.example.com by the version matcher, extracted 2.4.49", "Could not write the extracted values")
+
+	files, err := ioutil.ReadDir(directory)
+	require.Nil(t, err, "Could not list the directory")
+	require.Len(t, files, 1, "Could not remove the temporary file")
+}
+
+func TestExporterInterrupted(t *testing.T) {
+	directory, err := ioutil.TempDir("", "sarif-*")
+	require.Nil(t, err, "Could not create temporary directory")
+	defer os.RemoveAll(directory)
+	file := filepath.Join(directory, "results.sarif")
+
+	exporter, err := New(file, "2.0.5")
+	require.Nil(t, err, "Could not create exporter")
+	require.Nil(t, exporter.Close(false), "Could not write SARIF log")
+
+	data, err := ioutil.ReadFile(file)
+	require.Nil(t, err, "Could not read SARIF log")
+	validateSchema(t, data)
+	require.Contains(t, string(data), `"executionSuccessful": false`, "Could not write the scan as interrupted")
+
+	_, err = New(filepath.Join(directory, "missing", "results.sarif"), "2.0.5")
+	require.NotNil(t, err, "Could not reject a missing directory")
+}
+
+// validateSchema validates a SARIF log against the definitions of the
+// schema used by the exporter, the keywords they use being supported.
+func validateSchema(t *testing.T, data []byte) {
+	schemaData, err := ioutil.ReadFile(filepath.Join("testdata", "sarif-schema-2.1.0.json"))
+	require.Nil(t, err, "Could not read SARIF schema")
+	var schema, instance map[string]interface{}
+	require.Nil(t, json.Unmarshal(schemaData, &schema), "Could not unmarshal SARIF schema")
+	require.Nil(t, json.Unmarshal(data, &instance), "Could not unmarshal SARIF log")
+	definitions := schema["definitions"].(map[string]interface{})
+	require.Nil(t, validateValue(definitions, schema, instance, "$"), "Could not validate SARIF log")
+}
+
+func validateValue(definitions, schema map[string]interface{}, value interface{}, path string) error {
+	if ref, ok := schema["$ref"].(string); ok {
+		return validateValue(definitions, definitions[strings.TrimPrefix(ref, "#/definitions/")].(map[string]interface{}), value, path)
+	}
+	if enum, ok := schema["enum"].([]interface{}); ok {
+		var found bool
+		for _, allowed := range enum {
+			found = found || reflect.DeepEqual(allowed, value)
+		}
+		if !found {
+			return fmt.Errorf("%s: %v is not one of %v", path, value, enum)
+		}
+	}
+	if anyOf, ok := schema["anyOf"].([]interface{}); ok {
+		var errs []string
+		for _, alternative := range anyOf {
+			if err := validateValue(definitions, alternative.(map[string]interface{}), value, path); err == nil {
+				errs = nil
+				break
+			} else {
+				errs = append(errs, err.Error())
+			}
+		}
+		if len(errs) > 0 {
+			return fmt.Errorf("%s: no alternative is valid: %s", path, strings.Join(errs, "; "))
+		}
+	}
+
+	switch typed := value.(type) {
+	case map[string]interface{}:
+		if kind, ok := schema["type"]; ok && kind != "object" {
+			return fmt.Errorf("%s: object is not a %v", path, kind)
+		}
+		properties, _ := schema["properties"].(map[string]interface{})
+		for _, required := range asSlice(schema["required"]) {
+			if _, ok := typed[required.(string)]; !ok {
+				return fmt.Errorf("%s: missing required %s", path, required)
+			}
+		}
+		for name, property := range typed {
+			if propertySchema, ok := properties[name]; ok {
+				if err := validateValue(definitions, propertySchema.(map[string]interface{}), property, path+"."+name); err != nil {
+					return err
+				}
+			} else if schema["additionalProperties"] == false {
+				return fmt.Errorf("%s: unknown property %s", path, name)
+			}
+		}
+	case []interface{}:
+		if kind, ok := schema["type"]; ok && kind != "array" {
+			return fmt.Errorf("%s: array is not a %v", path, kind)
+		}
+		for i, item := range typed {
+			if schema["uniqueItems"] == true {
+				for _, previous := range typed[:i] {
+					if reflect.DeepEqual(previous, item) {
+						return fmt.Errorf("%s: duplicate item %v", path, item)
+					}
+				}
+			}
+			if items, ok := schema["items"].(map[string]interface{}); ok {
+				if err := validateValue(definitions, items, item, fmt.Sprintf("%s[%d]", path, i)); err != nil {
+					return err
+				}
+			}
+		}
+	case string:
+		if kind, ok := schema["type"]; ok && kind != "string" {
+			return fmt.Errorf("%s: string is not a %v", path, kind)
+		}
+		switch schema["format"] {
+		case "date-time":
+			if _, err := time.Parse(time.RFC3339, typed); err != nil {
+				return fmt.Errorf("%s: %s is not a date-time", path, typed)
+			}
+		case "uri":
+			if parsed, err := url.Parse(typed); err != nil || !parsed.IsAbs() {
+				return fmt.Errorf("%s: %s is not an absolute uri", path, typed)
+			}
+		case "uri-reference":
+			if _, err := url.Parse(typed); err != nil {
+				return fmt.Errorf("%s: %s is not a uri reference", path, typed)
+			}
+		}
+	case float64:
+		if kind, ok := schema["type"]; ok && kind != "number" && (kind != "integer" || typed != float64(int64(typed))) {
+			return fmt.Errorf("%s: number is not a %v", path, kind)
+		}
+		if minimum, ok := schema["minimum"].(float64); ok && typed < minimum {
+			return fmt.Errorf("%s: %v is less than %v", path, typed, minimum)
+		}
+	case bool:
+		if kind, ok := schema["type"]; ok && kind != "boolean" {
+			return fmt.Errorf("%s: boolean is not a %v", path, kind)
+		}
+	}
+	return nil
+}
+
+func asSlice(value interface{}) []interface{} {
+	slice, _ := value.([]interface{})
+	return slice
+}
@@ -0,0 +1,80 @@
+package sarif
+
+// The types of the SARIF 2.1.0 objects written by the exporter, see
+// https://docs.oasis-open.org/sarif/sarif/v2.1.0/sarif-v2.1.0.html
+
+const (
+	schemaURI = "https://json.schemastore.org/sarif-2.1.0.json"
+	version   = "2.1.0"
+)
+
+type sarifLog struct {
+	Schema  string `json:"$schema"`
+	Version string `json:"version"`
+	Runs    []*run `json:"runs"`
+}
+
+type run struct {
+	Tool        *tool                  `json:"tool"`
+	Invocations []*invocation          `json:"invocations"`
+	Results     []*result              `json:"results"`
+	Properties  map[string]interface{} `json:"properties,omitempty"`
+}
+
+type tool struct {
+	Driver *toolComponent `json:"driver"`
+}
+
+type toolComponent struct {
+	Name           string  `json:"name"`
+	Version        string  `json:"version"`
+	InformationURI string  `json:"informationUri"`
+	Rules          []*rule `json:"rules"`
+}
+
+// rule is the reportingDescriptor of a template
+type rule struct {
+	ID                   string                  `json:"id"`
+	Name                 string                  `json:"name,omitempty"`
+	ShortDescription     *message                `json:"shortDescription,omitempty"`
+	FullDescription      *message                `json:"fullDescription,omitempty"`
+	DefaultConfiguration *reportingConfiguration `json:"defaultConfiguration"`
+	Properties           map[string]interface{}  `json:"properties,omitempty"`
+}
+
+type reportingConfiguration struct {
+	Level string `json:"level"`
+}
+
+type invocation struct {
+	ExecutionSuccessful bool   `json:"executionSuccessful"`
+	StartTimeUTC        string `json:"startTimeUtc"`
+	EndTimeUTC          string `json:"endTimeUtc"`
+	ExitCodeDescription string `json:"exitCodeDescription,omitempty"`
+}
+
+// result is a finding of a template
+type result struct {
+	RuleID     string                 `json:"ruleId"`
+	RuleIndex  int                    `json:"ruleIndex"`
+	Level      string                 `json:"level"`
+	Message    *message               `json:"message"`
+	Locations  []*location            `json:"locations"`
+	Properties map[string]interface{} `json:"properties,omitempty"`
+}
+
+type message struct {
+	Text string `json:"text"`
+}
+
+type location struct {
+	PhysicalLocation *physicalLocation `json:"physicalLocation"`
+}
+
+type physicalLocation struct {
+	ArtifactLocation *artifactLocation `json:"artifactLocation"`
+}
+
+type artifactLocation struct {
+	URI string `json:"uri"`
+}
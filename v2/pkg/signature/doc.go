@@ -0,0 +1,3 @@
+// Package signature signs and verifies the templates with ed25519 keys,
+// the signature being embedded in the template as its digest field.
+package signature
@@ -0,0 +1,136 @@
+package signature
+
+import (
+	"bytes"
+	"crypto/ed25519"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"regexp"
+	"strings"
+)
+
+// digestRegex matches the top level digest lines of a template
+var digestRegex = regexp.MustCompile(`(?m)^digest:[ \t]*([^\r\n]*)(?:\r?\n|$)`)
+
+// ErrUnsigned is returned by the verification of a template without digest
+var ErrUnsigned = errors.New("template is not signed")
+
+// Keys are the trusted public keys verifying the templates, by key id
+type Keys struct {
+	keys map[string]ed25519.PublicKey
+}
+
+// KeyID returns the id of a public key written in the digests, the hex
+// encoded first 8 bytes of the sha256 of the key.
+func KeyID(key ed25519.PublicKey) string {
+	hash := sha256.Sum256(key)
+	return hex.EncodeToString(hash[:8])
+}
+
+// LoadPublicKeys reads the trusted public keys of files containing a base64
+// encoded key per line. Empty lines and lines starting with # are ignored.
+func LoadPublicKeys(files ...string) (*Keys, error) {
+	keys := &Keys{keys: make(map[string]ed25519.PublicKey)}
+	for _, file := range files {
+		data, err := ioutil.ReadFile(file)
+		if err != nil {
+			return nil, fmt.Errorf("could not read public keys file: %s", err)
+		}
+		for _, line := range strings.Split(string(data), "\n") {
+			line = strings.TrimSpace(line)
+			if line == "" || strings.HasPrefix(line, "#") {
+				continue
+			}
+			key, err := base64.StdEncoding.DecodeString(line)
+			if err != nil || len(key) != ed25519.PublicKeySize {
+				return nil, fmt.Errorf("invalid public key in %s: %s", file, line)
+			}
+			keys.keys[KeyID(key)] = key
+		}
+	}
+	if len(keys.keys) == 0 {
+		return nil, errors.New("no public keys found")
+	}
+	return keys, nil
+}
+
+// LoadPrivateKey reads a private key file containing the base64 encoded seed of the key
+func LoadPrivateKey(file string) (ed25519.PrivateKey, error) {
+	data, err := ioutil.ReadFile(file)
+	if err != nil {
+		return nil, fmt.Errorf("could not read private key file: %s", err)
+	}
+	seed, err := base64.StdEncoding.DecodeString(strings.TrimSpace(string(data)))
+	if err != nil || len(seed) != ed25519.SeedSize {
+		return nil, fmt.Errorf("invalid private key in %s", file)
+	}
+	return ed25519.NewKeyFromSeed(seed), nil
+}
+
+// GenerateKey writes a new private key to a file and its public key to
+// the file with the .pub extension.
+func GenerateKey(file string) error {
+	public, private, err := ed25519.GenerateKey(rand.Reader)
+	if err != nil {
+		return err
+	}
+	if err := ioutil.WriteFile(file, []byte(base64.StdEncoding.EncodeToString(private.Seed())+"\n"), 0600); err != nil {
+		return err
+	}
+	return ioutil.WriteFile(file+".pub", []byte(base64.StdEncoding.EncodeToString(public)+"\n"), 0644)
+}
+
+// canonical returns the signed bytes of a template, the template without
+// its digest lines, every other byte being covered by the signature.
+func canonical(data []byte) []byte {
+	return digestRegex.ReplaceAll(data, nil)
+}
+
+// Sign returns a template with its digest, replacing the previous one if any.
+// The digest is appended as the last line, "digest: <key id>:<signature>".
+func Sign(data []byte, key ed25519.PrivateKey) []byte {
+	signed := canonical(data)
+	if len(signed) > 0 && !bytes.HasSuffix(signed, []byte("\n")) {
+		signed = append(signed, '\n')
+	}
+	signature := ed25519.Sign(key, signed)
+	digest := fmt.Sprintf("digest: %s:%s\n", KeyID(key.Public().(ed25519.PublicKey)), base64.StdEncoding.EncodeToString(signature))
+	return append(signed[:len(signed):len(signed)], digest...)
+}
+
+// Verify verifies the digest of a template, returning the id of the
+// trusted key it is signed with.
+func (k *Keys) Verify(data []byte) (string, error) {
+	matches := digestRegex.FindAllSubmatch(data, -1)
+	if len(matches) == 0 {
+		return "", ErrUnsigned
+	}
+	if len(matches) > 1 {
+		return "", errors.New("template has several digests")
+	}
+	parts := strings.SplitN(strings.TrimSpace(string(matches[0][1])), ":", 2)
+	if len(parts) != 2 {
+		return "", errors.New("malformed digest, digests are <key id>:<signature>")
+	}
+	keyID := parts[0]
+	signature, err := base64.StdEncoding.DecodeString(parts[1])
+	if err != nil {
+		return "", errors.New("malformed digest, the signature is not base64 encoded")
+	}
+	var key ed25519.PublicKey
+	if k != nil {
+		key = k.keys[keyID]
+	}
+	if key == nil {
+		return "", fmt.Errorf("template is signed by the untrusted key %s", keyID)
+	}
+	if !ed25519.Verify(key, canonical(data), signature) {
+		return "", fmt.Errorf("invalid signature of key %s, the template was modified after signing", keyID)
+	}
+	return keyID, nil
+}
@@ -0,0 +1,52 @@
+package signature
+
+import (
+	"crypto/ed25519"
+	"encoding/base64"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestSignVerify(t *testing.T) {
+	directory, err := ioutil.TempDir("", "signature-")
+	require.Nil(t, err, "Could not create directory")
+	defer os.RemoveAll(directory)
+
+	file := filepath.Join(directory, "key")
+	require.Nil(t, GenerateKey(file), "Could not generate key")
+	private, err := LoadPrivateKey(file)
+	require.Nil(t, err, "Could not load private key")
+	keys, err := LoadPublicKeys(file + ".pub")
+	require.Nil(t, err, "Could not load public key")
+	keyID := KeyID(private.Public().(ed25519.PublicKey))
+
+	template := []byte("id: test\ninfo:\n  name: test\n  digest: not-top-level\nrequests: []")
+	_, err = keys.Verify(template)
+	require.Equal(t, ErrUnsigned, err, "Could not get unsigned error")
+
+	signed := Sign(template, private)
+	id, err := keys.Verify(signed)
+	require.Nil(t, err, "Could not verify signed template")
+	require.Equal(t, keyID, id, "Could not get the key id")
+	require.Equal(t, signed, Sign(signed, private), "Could not sign again the signed template")
+
+	for i := 0; i < len(template); i++ {
+		tampered := append([]byte{}, signed...)
+		tampered[i] ^= 0x01
+		_, err = keys.Verify(tampered)
+		require.NotNil(t, err, "Could not detect tampered byte %d", i)
+	}
+
+	_, other, err := ed25519.GenerateKey(nil)
+	require.Nil(t, err, "Could not generate key")
+	_, err = keys.Verify(Sign(template, other))
+	require.EqualError(t, err, "template is signed by the untrusted key "+KeyID(other.Public().(ed25519.PublicKey)), "Could not reject untrusted key")
+
+	require.Nil(t, ioutil.WriteFile(file+".pub", []byte(base64.StdEncoding.EncodeToString([]byte("short"))), 0644), "Could not write key")
+	_, err = LoadPublicKeys(file + ".pub")
+	require.NotNil(t, err, "Could not reject invalid public key")
+}
@@ -0,0 +1,112 @@
+package ssl
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"crypto/x509"
+	"encoding/hex"
+	"strings"
+	"time"
+)
+
+// certificateKeys are the fields of a certificate in the order they are written
+var certificateKeys = []string{
+	"subject_cn",
+	"subject",
+	"subject_org",
+	"issuer_cn",
+	"issuer",
+	"issuer_org",
+	"san",
+	"not_before",
+	"not_after",
+	"serial",
+	"sig_alg",
+	"fingerprint_sha256",
+}
+
+// CertificateFields returns the fields of a certificate extracted by the
+// kval extractors.
+//
+// Dates are RFC3339 timestamps in UTC, the serial and the fingerprint are
+// lowercase hex without colons. san contains one value per dns name, ip
+// address and email of the certificate.
+func CertificateFields(cert *x509.Certificate) map[string][]string {
+	fields := map[string][]string{
+		"subject_cn":         {cert.Subject.CommonName},
+		"subject":            {cert.Subject.String()},
+		"subject_org":        cert.Subject.Organization,
+		"issuer_cn":          {cert.Issuer.CommonName},
+		"issuer":             {cert.Issuer.String()},
+		"issuer_org":         cert.Issuer.Organization,
+		"not_before":         {cert.NotBefore.UTC().Format(time.RFC3339)},
+		"not_after":          {cert.NotAfter.UTC().Format(time.RFC3339)},
+		"serial":             {hex.EncodeToString(cert.SerialNumber.Bytes())},
+		"sig_alg":            {cert.SignatureAlgorithm.String()},
+		"fingerprint_sha256": {FingerprintSHA256(cert)},
+	}
+
+	san := append([]string{}, cert.DNSNames...)
+	for _, ip := range cert.IPAddresses {
+		san = append(san, ip.String())
+	}
+	san = append(san, cert.EmailAddresses...)
+	fields["san"] = san
+	return fields
+}
+
+// FingerprintSHA256 returns the sha256 fingerprint of a certificate
+func FingerprintSHA256(cert *x509.Certificate) string {
+	sum := sha256.Sum256(cert.Raw)
+	return hex.EncodeToString(sum[:])
+}
+
+// SelfSigned returns true if a certificate is issued by its own subject and
+// signed by its own key.
+func SelfSigned(cert *x509.Certificate) bool {
+	if !bytes.Equal(cert.RawIssuer, cert.RawSubject) {
+		return false
+	}
+	return cert.CheckSignature(cert.SignatureAlgorithm, cert.RawTBSCertificate, cert.Signature) == nil
+}
+
+// writeCertificate writes the fields of a certificate, one per line
+func writeCertificate(builder *strings.Builder, cert *x509.Certificate) {
+	fields := CertificateFields(cert)
+	for _, key := range certificateKeys {
+		builder.WriteString(key)
+		builder.WriteString(": ")
+		builder.WriteString(strings.Join(fields[key], ", "))
+		builder.WriteRune('\n')
+	}
+}
+
+// Certificate is the summary of a certificate of the chain written to the
+// json output.
+type Certificate struct {
+	SubjectCN         string    `json:"subject_cn"`
+	Subject           string    `json:"subject"`
+	IssuerCN          string    `json:"issuer_cn"`
+	Issuer            string    `json:"issuer"`
+	SAN               []string  `json:"san,omitempty"`
+	NotBefore         time.Time `json:"not_before"`
+	NotAfter          time.Time `json:"not_after"`
+	Serial            string    `json:"serial"`
+	FingerprintSHA256 string    `json:"fingerprint_sha256"`
+}
+
+// newCertificate returns the summary of a certificate
+func newCertificate(cert *x509.Certificate) *Certificate {
+	fields := CertificateFields(cert)
+	return &Certificate{
+		SubjectCN:         cert.Subject.CommonName,
+		Subject:           cert.Subject.String(),
+		IssuerCN:          cert.Issuer.CommonName,
+		Issuer:            cert.Issuer.String(),
+		SAN:               fields["san"],
+		NotBefore:         cert.NotBefore.UTC(),
+		NotAfter:          cert.NotAfter.UTC(),
+		Serial:            fields["serial"][0],
+		FingerprintSHA256: fields["fingerprint_sha256"][0],
+	}
+}
@@ -0,0 +1,6 @@
+// Package ssl runs the tls handshakes of the ssl requests of the templates,
+// optionally after upgrading a mail connection with starttls, recording the
+// negotiated version and cipher suite and the certificate chain of the
+// server for the matchers and the extractors along with the kind of the
+// error of the handshake, i.e a version refused by the server.
+package ssl
@@ -0,0 +1,340 @@
+package ssl
+
+import (
+	"context"
+	"crypto/tls"
+	"crypto/x509"
+	"errors"
+	"fmt"
+	"net"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/projectdiscovery/nuclei/v2/pkg/network"
+)
+
+// The kinds of the errors of the handshakes, along with the ones of the
+// connections of the network requests, matched by the error part.
+const (
+	// HandshakeError is a handshake failing, i.e a version or the cipher
+	// suites refused by the server.
+	HandshakeError = "handshake"
+	// StartTLSError is a server refusing the starttls command
+	StartTLSError = "starttls"
+)
+
+// versions are the tls versions by their name
+var versions = map[string]uint16{
+	"tls10": tls.VersionTLS10,
+	"tls11": tls.VersionTLS11,
+	"tls12": tls.VersionTLS12,
+	"tls13": tls.VersionTLS13,
+}
+
+// VersionName returns the name of a tls version, i.e tls12
+func VersionName(version uint16) string {
+	for name, value := range versions {
+		if value == version {
+			return name
+		}
+	}
+	return fmt.Sprintf("0x%04x", version)
+}
+
+// ciphers are the cipher suites by their name, the insecure ones included
+var ciphers = make(map[string]uint16)
+
+func init() {
+	for _, suite := range append(tls.CipherSuites(), tls.InsecureCipherSuites()...) {
+		ciphers[suite.Name] = suite.ID
+	}
+}
+
+// Options are the options of the handshakes of a request
+type Options struct {
+	// ServerName is the server name sent, the host of the address by default
+	ServerName string
+	// MinVersion is the minimum version offered, tls10 by default
+	MinVersion string
+	// MaxVersion is the maximum version offered, tls13 by default or tls12
+	// with ciphers, the cipher suites of tls13 not being configurable.
+	MaxVersion string
+	// Ciphers are the names of the cipher suites offered, i.e
+	// TLS_RSA_WITH_RC4_128_SHA, all the supported ones by default.
+	Ciphers []string
+	// StartTLS is the protocol upgraded with starttls before the handshake
+	// if any, i.e smtp.
+	StartTLS string
+	// Timeout is the time the connection and the handshake can take
+	Timeout time.Duration
+}
+
+// Validate returns an error if a version, a cipher suite or the starttls
+// protocol of the options is unknown, the values with placeholders being
+// validated once they are replaced.
+func (o *Options) Validate() error {
+	options := *o
+	if strings.Contains(options.MinVersion, "{{") {
+		options.MinVersion = ""
+	}
+	if strings.Contains(options.MaxVersion, "{{") {
+		options.MaxVersion = ""
+	}
+	options.Ciphers = nil
+	for _, cipher := range o.Ciphers {
+		if !strings.Contains(cipher, "{{") {
+			options.Ciphers = append(options.Ciphers, cipher)
+		}
+	}
+	_, err := options.config("")
+	return err
+}
+
+// config returns the tls configuration of a handshake with an address
+func (o *Options) config(address string) (*tls.Config, error) {
+	config := &tls.Config{
+		InsecureSkipVerify: true,
+		ServerName:         o.ServerName,
+		MinVersion:         tls.VersionTLS10,
+		MaxVersion:         tls.VersionTLS13,
+	}
+	if config.ServerName == "" {
+		config.ServerName, _, _ = net.SplitHostPort(address)
+	}
+	if o.MinVersion != "" {
+		version, ok := versions[o.MinVersion]
+		if !ok {
+			return nil, fmt.Errorf("unknown min-version %s (supported: %s)", o.MinVersion, versionNames())
+		}
+		config.MinVersion = version
+	}
+	if len(o.Ciphers) > 0 {
+		config.MaxVersion = tls.VersionTLS12
+		for _, name := range o.Ciphers {
+			id, ok := ciphers[name]
+			if !ok {
+				return nil, fmt.Errorf("unknown cipher %s", name)
+			}
+			config.CipherSuites = append(config.CipherSuites, id)
+		}
+	}
+	if o.MaxVersion != "" {
+		version, ok := versions[o.MaxVersion]
+		if !ok {
+			return nil, fmt.Errorf("unknown max-version %s (supported: %s)", o.MaxVersion, versionNames())
+		}
+		config.MaxVersion = version
+	}
+	if config.MinVersion > config.MaxVersion {
+		return nil, fmt.Errorf("min-version %s is above max-version %s", VersionName(config.MinVersion), VersionName(config.MaxVersion))
+	}
+	switch o.StartTLS {
+	case "", SMTPStartTLS, IMAPStartTLS, POP3StartTLS:
+	default:
+		return nil, fmt.Errorf("unknown starttls protocol %s (supported: smtp, imap, pop3)", o.StartTLS)
+	}
+	return config, nil
+}
+
+// versionNames returns the names of the versions, sorted
+func versionNames() string {
+	names := make([]string, 0, len(versions))
+	for name := range versions {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return strings.Join(names, ", ")
+}
+
+// String returns the options of a handshake, one per line
+func (o *Options) String() string {
+	builder := &strings.Builder{}
+	for _, option := range [][2]string{
+		{"starttls", o.StartTLS},
+		{"server-name", o.ServerName},
+		{"min-version", o.MinVersion},
+		{"max-version", o.MaxVersion},
+		{"ciphers", strings.Join(o.Ciphers, ", ")},
+	} {
+		if option[1] != "" {
+			builder.WriteString(option[0] + ": " + option[1] + "\n")
+		}
+	}
+	return builder.String()
+}
+
+// Response is what a handshake negotiated with a server
+type Response struct {
+	// Address is the host and port the connection was made to
+	Address string
+	// ServerName is the server name sent
+	ServerName string
+	// StartTLS is the protocol upgraded with starttls if any
+	StartTLS string
+	// Version is the negotiated version, i.e tls12, empty if the handshake
+	// failed.
+	Version string
+	// Cipher is the name of the negotiated cipher suite
+	Cipher string
+	// Certificates are the certificates sent by the server, the leaf first
+	Certificates []*x509.Certificate
+	// Untrusted is true if the chain isn't verified by the system roots,
+	// i.e a self-signed or an expired certificate.
+	Untrusted bool
+	// Mismatched is true if the leaf isn't valid for the server name
+	Mismatched bool
+	// Error is the kind of the error of the connection or the handshake if
+	// any, i.e handshake or refused.
+	Error string
+}
+
+// String returns the negotiated version and cipher suite along with the
+// fields of the certificates, one per line.
+func (r *Response) String() string {
+	builder := &strings.Builder{}
+	if r.Version != "" {
+		builder.WriteString("version: " + r.Version + "\n")
+		builder.WriteString("cipher: " + r.Cipher + "\n")
+	}
+	builder.WriteString(r.CertificatesString())
+	return builder.String()
+}
+
+// CertificatesString returns the fields of the certificates, one per line,
+// the certificates being separated by an empty line.
+func (r *Response) CertificatesString() string {
+	builder := &strings.Builder{}
+	for i, cert := range r.Certificates {
+		if i > 0 {
+			builder.WriteRune('\n')
+		}
+		writeCertificate(builder, cert)
+	}
+	return builder.String()
+}
+
+// Fields returns the negotiated version and cipher suite along with the
+// fields of the leaf certificate, extracted by the kval extractors.
+func (r *Response) Fields() map[string][]string {
+	fields := make(map[string][]string)
+	if len(r.Certificates) > 0 {
+		fields = CertificateFields(r.Certificates[0])
+	}
+	fields["version"] = []string{r.Version}
+	fields["cipher"] = []string{r.Cipher}
+	fields["server_name"] = []string{r.ServerName}
+	return fields
+}
+
+// Handshake is what a handshake negotiated, written to the json output
+type Handshake struct {
+	Version      string         `json:"version"`
+	Cipher       string         `json:"cipher"`
+	ServerName   string         `json:"server_name,omitempty"`
+	StartTLS     string         `json:"starttls,omitempty"`
+	Certificates []*Certificate `json:"certificates,omitempty"`
+}
+
+// Handshake returns what the handshake negotiated, nil if it failed
+func (r *Response) Handshake() *Handshake {
+	if r.Version == "" {
+		return nil
+	}
+	handshake := &Handshake{Version: r.Version, Cipher: r.Cipher, ServerName: r.ServerName, StartTLS: r.StartTLS}
+	for _, cert := range r.Certificates {
+		handshake.Certificates = append(handshake.Certificates, newCertificate(cert))
+	}
+	return handshake
+}
+
+// Run connects to an address and runs a tls handshake, after upgrading the
+// connection with starttls if required, returning what it negotiated. The
+// errors of the connection and of the handshake are *network.Error, the
+// response recording their kind, and the invalid options fail before
+// connecting.
+func Run(ctx context.Context, address string, options *Options) (*Response, error) {
+	resp := &Response{Address: address, StartTLS: options.StartTLS}
+	config, err := options.config(address)
+	if err != nil {
+		return resp, err
+	}
+	resp.ServerName = config.ServerName
+
+	dialer := &net.Dialer{Timeout: options.Timeout}
+	conn, err := dialer.DialContext(ctx, "tcp", address)
+	if err != nil {
+		return resp, resp.fail(ctx, "", fmt.Errorf("could not connect: %w", err))
+	}
+	defer conn.Close()
+
+	// the handshake in progress fails once the context is done
+	stop := make(chan struct{})
+	defer close(stop)
+	go func() {
+		select {
+		case <-ctx.Done():
+			conn.Close()
+		case <-stop:
+		}
+	}()
+
+	conn.SetDeadline(deadline(options.Timeout))
+	if options.StartTLS != "" {
+		if err := startTLS(conn, options.StartTLS); err != nil {
+			return resp, resp.fail(ctx, "", fmt.Errorf("could not starttls: %w", err))
+		}
+	}
+	tlsConn := tls.Client(conn, config)
+	if err := tlsConn.Handshake(); err != nil {
+		// the errors of the handshake which aren't the ones of the
+		// connection are the alerts and the parameters refused
+		kind := network.ErrorKind(err)
+		if kind == network.OtherError {
+			kind = HandshakeError
+		}
+		return resp, resp.fail(ctx, kind, fmt.Errorf("could not handshake: %w", err))
+	}
+
+	state := tlsConn.ConnectionState()
+	resp.Version = VersionName(state.Version)
+	resp.Cipher = tls.CipherSuiteName(state.CipherSuite)
+	resp.Certificates = state.PeerCertificates
+	if len(resp.Certificates) > 0 {
+		leaf := resp.Certificates[0]
+		intermediates := x509.NewCertPool()
+		for _, cert := range resp.Certificates[1:] {
+			intermediates.AddCert(cert)
+		}
+		_, err := leaf.Verify(x509.VerifyOptions{Intermediates: intermediates})
+		resp.Untrusted = err != nil
+		resp.Mismatched = leaf.VerifyHostname(resp.ServerName) != nil
+	}
+	return resp, nil
+}
+
+// deadline returns the deadline of a handshake, none for a zero timeout
+func deadline(timeout time.Duration) time.Time {
+	if timeout <= 0 {
+		return time.Time{}
+	}
+	return time.Now().Add(timeout)
+}
+
+// fail records the kind of an error of the connection or the handshake,
+// the one of the connection if kind is empty, returning the error of the
+// context instead once it is done.
+func (r *Response) fail(ctx context.Context, kind string, err error) error {
+	if ctxErr := ctx.Err(); ctxErr != nil {
+		return ctxErr
+	}
+	switch {
+	case kind != "":
+	case errors.Is(err, errStartTLSRefused):
+		kind = StartTLSError
+	default:
+		kind = network.ErrorKind(err)
+	}
+	r.Error = kind
+	return &network.Error{Kind: kind, Err: err}
+}
@@ -0,0 +1,123 @@
+package ssl
+
+import (
+	"bufio"
+	"context"
+	"crypto/tls"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/projectdiscovery/nuclei/v2/pkg/network"
+	"github.com/stretchr/testify/require"
+)
+
+// newTLSServer returns a server accepting up to tls12
+func newTLSServer() *httptest.Server {
+	server := httptest.NewUnstartedServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}))
+	server.TLS = &tls.Config{MaxVersion: tls.VersionTLS12}
+	server.StartTLS()
+	return server
+}
+
+// newMailServer returns a listener answering the starttls command of a
+// mail protocol with greeting, then running the handshake with the
+// certificate of server if answer is positive.
+func newMailServer(t *testing.T, server *httptest.Server, greeting string, answers ...string) net.Listener {
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	require.Nil(t, err, "Could not listen")
+	go func() {
+		for {
+			conn, err := listener.Accept()
+			if err != nil {
+				return
+			}
+			go func(conn net.Conn) {
+				defer conn.Close()
+				reader := bufio.NewReader(conn)
+				conn.Write([]byte(greeting))
+				for _, answer := range answers {
+					if _, err := reader.ReadString('\n'); err != nil {
+						return
+					}
+					conn.Write([]byte(answer))
+				}
+				tls.Server(conn, &tls.Config{Certificates: server.TLS.Certificates}).Handshake()
+			}(conn)
+		}
+	}()
+	return listener
+}
+
+func TestRun(t *testing.T) {
+	server := newTLSServer()
+	defer server.Close()
+	address := strings.TrimPrefix(server.URL, "https://")
+
+	resp, err := Run(context.Background(), address, &Options{Timeout: time.Second})
+	require.Nil(t, err, "Could not run the handshake")
+	require.Equal(t, "tls12", resp.Version, "Could not negotiate the highest version")
+	require.NotEmpty(t, resp.Cipher, "Could not record the cipher suite")
+	require.Equal(t, "127.0.0.1", resp.ServerName, "Could not send the host as server name")
+	require.Len(t, resp.Certificates, 1, "Could not record the certificates")
+	require.True(t, SelfSigned(resp.Certificates[0]), "Could not detect the self-signed certificate")
+	require.True(t, resp.Untrusted, "Could not detect the untrusted chain")
+	require.False(t, resp.Mismatched, "Could mismatch the ip address of the certificate")
+	require.Contains(t, resp.String(), "version: tls12\n", "Could not write the version")
+	require.Equal(t, "tls12", resp.Handshake().Version, "Could not summarize the handshake")
+
+	resp, err = Run(context.Background(), address, &Options{Ciphers: []string{"TLS_ECDHE_RSA_WITH_AES_256_GCM_SHA384"}, ServerName: "evil.example.org", Timeout: time.Second})
+	require.Nil(t, err, "Could not run the handshake with a cipher suite")
+	require.Equal(t, "TLS_ECDHE_RSA_WITH_AES_256_GCM_SHA384", resp.Cipher, "Could not negotiate the cipher suite")
+	require.True(t, resp.Mismatched, "Could not detect the mismatched server name")
+
+	resp, err = Run(context.Background(), address, &Options{MinVersion: "tls13", Timeout: time.Second})
+	require.NotNil(t, err, "Could not refuse the version")
+	require.IsType(t, &network.Error{}, err, "Could not return a handshake error")
+	require.Equal(t, HandshakeError, resp.Error, "Could not record the refused version")
+	require.Empty(t, resp.Version, "Could negotiate a refused version")
+	require.Nil(t, resp.Handshake(), "Could summarize a failed handshake")
+}
+
+func TestRunStartTLS(t *testing.T) {
+	server := newTLSServer()
+	defer server.Close()
+
+	smtp := newMailServer(t, server, "220 mail ESMTP\r\n", "250-mail\r\n250 STARTTLS\r\n", "220 ready\r\n")
+	defer smtp.Close()
+	resp, err := Run(context.Background(), smtp.Addr().String(), &Options{StartTLS: SMTPStartTLS, Timeout: time.Second})
+	require.Nil(t, err, "Could not run the handshake after starttls")
+	require.Equal(t, "tls13", resp.Version, "Could not negotiate the version after starttls")
+	require.Equal(t, SMTPStartTLS, resp.Handshake().StartTLS, "Could not summarize the starttls protocol")
+
+	imap := newMailServer(t, server, "* OK ready\r\n", "* BYE\r\na1 BAD disabled\r\n")
+	defer imap.Close()
+	resp, err = Run(context.Background(), imap.Addr().String(), &Options{StartTLS: IMAPStartTLS, Timeout: time.Second})
+	require.NotNil(t, err, "Could not fail the refused starttls")
+	require.Equal(t, StartTLSError, resp.Error, "Could not record the refused starttls")
+}
+
+func TestRunErrors(t *testing.T) {
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	require.Nil(t, err, "Could not listen")
+	address := listener.Addr().String()
+	listener.Close()
+	resp, err := Run(context.Background(), address, &Options{Timeout: time.Second})
+	require.NotNil(t, err, "Could not fail the closed port")
+	require.Equal(t, network.RefusedError, resp.Error, "Could not record the refused connection")
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	_, err = Run(ctx, address, &Options{})
+	require.Equal(t, context.Canceled, err, "Could not return the error of the context")
+
+	require.Nil(t, (&Options{MinVersion: "tls10", MaxVersion: "tls10"}).Validate(), "Could not validate the versions")
+	require.NotNil(t, (&Options{MinVersion: "ssl30"}).Validate(), "Could not reject the unknown version")
+	require.NotNil(t, (&Options{MinVersion: "tls13", MaxVersion: "tls12"}).Validate(), "Could not reject the inverted versions")
+	require.NotNil(t, (&Options{Ciphers: []string{"TLS_NONE"}}).Validate(), "Could not reject the unknown cipher suite")
+	require.NotNil(t, (&Options{StartTLS: "ftp"}).Validate(), "Could not reject the unknown starttls protocol")
+	require.Nil(t, (&Options{MinVersion: "{{version}}", Ciphers: []string{"{{cipher}}"}}).Validate(), "Could not skip the placeholders")
+}
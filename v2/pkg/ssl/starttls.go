@@ -0,0 +1,105 @@
+package ssl
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"net/textproto"
+	"strings"
+)
+
+// The protocols upgraded with starttls before the handshake
+const (
+	// SMTPStartTLS upgrades a smtp connection, i.e on the ports 25 and 587
+	SMTPStartTLS = "smtp"
+	// IMAPStartTLS upgrades an imap connection, i.e on the port 143
+	IMAPStartTLS = "imap"
+	// POP3StartTLS upgrades a pop3 connection, i.e on the port 110
+	POP3StartTLS = "pop3"
+)
+
+// errStartTLSRefused is the error of a server refusing to upgrade
+var errStartTLSRefused = errors.New("starttls refused")
+
+// startTLS upgrades a connection with the starttls command of a protocol,
+// the handshake being run over it afterwards.
+func startTLS(conn net.Conn, protocol string) error {
+	reader := bufio.NewReader(conn)
+	switch protocol {
+	case SMTPStartTLS:
+		text := textproto.NewReader(reader)
+		if _, _, err := text.ReadResponse(220); err != nil {
+			return smtpError(err)
+		}
+		if _, err := io.WriteString(conn, "EHLO nuclei\r\n"); err != nil {
+			return err
+		}
+		if _, _, err := text.ReadResponse(250); err != nil {
+			return smtpError(err)
+		}
+		if _, err := io.WriteString(conn, "STARTTLS\r\n"); err != nil {
+			return err
+		}
+		if _, _, err := text.ReadResponse(220); err != nil {
+			return smtpError(err)
+		}
+	case IMAPStartTLS:
+		if err := expectLine(reader, "", "* OK"); err != nil {
+			return err
+		}
+		if _, err := io.WriteString(conn, "a1 STARTTLS\r\n"); err != nil {
+			return err
+		}
+		// the untagged lines before the answer of the command are skipped
+		if err := expectLine(reader, "a1 ", "a1 OK"); err != nil {
+			return err
+		}
+	case POP3StartTLS:
+		if err := expectLine(reader, "", "+OK"); err != nil {
+			return err
+		}
+		if _, err := io.WriteString(conn, "STLS\r\n"); err != nil {
+			return err
+		}
+		if err := expectLine(reader, "", "+OK"); err != nil {
+			return err
+		}
+	default:
+		return fmt.Errorf("unknown starttls protocol %s (supported: smtp, imap, pop3)", protocol)
+	}
+	if reader.Buffered() > 0 {
+		return fmt.Errorf("%w, data sent before the handshake", errStartTLSRefused)
+	}
+	return nil
+}
+
+// expectLine reads the lines of a server until one starts with prefix,
+// failing unless it starts with expected.
+func expectLine(reader *bufio.Reader, prefix, expected string) error {
+	for {
+		line, err := reader.ReadString('\n')
+		if err != nil {
+			return err
+		}
+		line = strings.TrimRight(line, "\r\n")
+		if !strings.HasPrefix(line, prefix) {
+			continue
+		}
+		if !strings.HasPrefix(line, expected) {
+			return fmt.Errorf("%w: %s", errStartTLSRefused, line)
+		}
+		return nil
+	}
+}
+
+// smtpError returns the error of an unexpected answer of a smtp server as
+// a refused upgrade, the errors of the connection as is.
+func smtpError(err error) error {
+	var protoErr *textproto.Error
+	if errors.As(err, &protoErr) {
+		return fmt.Errorf("%w: %d %s", errStartTLSRefused, protoErr.Code, protoErr.Msg)
+	}
+	return err
+}
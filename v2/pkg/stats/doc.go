@@ -0,0 +1,5 @@
+// Package stats counts the requests, findings and errors of a scan as it
+// goes, for the summary shown at the end of the scan or when it is
+// interrupted, whatever the outputs of the results are. The same counters
+// are written periodically as json lines by a stream while scanning.
+package stats
@@ -0,0 +1,789 @@
+package stats
+
+import (
+	"errors"
+	"net"
+	"net/url"
+	"sort"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// maxReasonLength is the length of the error messages used as the reasons of
+// the errors which are not classified, the rest being truncated.
+const maxReasonLength = 80
+
+// Stats are the counters of a scan, updated concurrently by the executers
+// and the runner. The methods of a nil Stats do nothing.
+type Stats struct {
+	start   time.Time
+	targets int64
+
+	requests uint64
+	findings uint64
+	errors   uint64
+	// inFlight is the number of requests sent awaiting their response
+	inFlight int64
+	// clustered is the number of requests not sent as their templates
+	// share the request of another template.
+	clustered uint64
+	// cached is the number of requests not sent as their response was
+	// stored by a previous run of the project.
+	cached uint64
+	// shared is the number of requests not sent as their response was the
+	// one of a baseline request of -tech-detect.
+	shared uint64
+	// retried is the number of runs of the templates on the targets run
+	// again after failing with a network error, retrySucceeded the ones
+	// which did not fail again.
+	retried        uint64
+	retrySucceeded uint64
+	// rateLimit is the maximum number of requests per second to each host,
+	// delayed and delayedNS the number and the total wait of the requests
+	// delayed to stay below it.
+	rateLimit int64
+	delayed   uint64
+	delayedNS uint64
+	// concurrency is the global concurrency of the adaptive mode, throttled
+	// the number of hosts it throttles, backoffs and recoveries the numbers
+	// of its adjustments.
+	concurrency int64
+	throttled   int64
+	backoffs    uint64
+	recoveries  uint64
+	// plannedRequests and completedRequests are the payload-aware numbers
+	// of requests of the scan, the skipped requests being completed.
+	plannedRequests   int64
+	completedRequests int64
+
+	// totalTemplates is the number of templates and workflows of the scan
+	// and steps the number of their runs on each target, each request block
+	// of the single protocol templates being run separately on the targets.
+	totalTemplates     int64
+	steps              int64
+	completedTemplates uint64
+	completedHosts     uint64
+	targetSteps        sync.Map
+	// severities and templates are the numbers of findings by severity and
+	// template id, reasons the numbers of errors by reason, as *uint64.
+	severities sync.Map
+	templates  sync.Map
+	reasons    sync.Map
+
+	// errored and skipped are the targets with an error and the targets
+	// skipped as they did not respond to the http probes.
+	errored      sync.Map
+	erroredCount uint64
+	skipped      sync.Map
+	skippedCount uint64
+	// excluded are the numbers of targets of the input not scanned by
+	// exclusion rule, as *uint64.
+	excluded      sync.Map
+	excludedCount uint64
+	// duplicates are the targets of the input not scanned as they are near
+	// duplicates of a previous one.
+	duplicates uint64
+	// dead are the reasons of the errors of the hosts skipped after too
+	// many consecutive network errors, by host.
+	dead sync.Map
+	// preflight are the reasons of the errors of the hosts and ports
+	// skipped as they failed the liveness preflight, by host and port.
+	preflight sync.Map
+	// technologies are the technologies detected on the hosts and ports by
+	// -tech-detect, techSkipped the numbers of templates skipped on them as
+	// *uint64.
+	technologies     sync.Map
+	techSkipped      sync.Map
+	techSkippedCount uint64
+
+	// failed are the reasons of the templates which could not be executed,
+	// timedOut the template and target pairs abandoned after -template-timeout.
+	mutex    sync.Mutex
+	failed   map[string]string
+	timedOut []TimedOutTemplate
+	// truncated is 1 if the scan stopped after -max-scan-duration or by
+	// the control api, paused 1 while the control api paused it.
+	truncated uint32
+	paused    uint32
+	// exporters are the exporters whose results are counted by name
+	exporters map[string]ExportCounter
+}
+
+// ExportCounter counts the results of an exporter publishing them to an
+// external service, i.e kafka.
+type ExportCounter interface {
+	// Published returns the number of results published
+	Published() uint64
+	// Failed returns the number of results failing to be published
+	Failed() uint64
+	// Dropped returns the number of results dropped as the queue was full
+	Dropped() uint64
+}
+
+// New returns the stats of a scan of a number of targets starting now
+func New(targets int64) *Stats {
+	return &Stats{start: time.Now(), targets: targets, failed: make(map[string]string), exporters: make(map[string]ExportCounter)}
+}
+
+// AddExporter adds the counters of an exporter to the snapshots
+func (s *Stats) AddExporter(name string, counter ExportCounter) {
+	if s == nil {
+		return
+	}
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	s.exporters[name] = counter
+}
+
+// SetTemplates sets the number of templates and workflows of the scan and
+// the number of their runs on each target.
+func (s *Stats) SetTemplates(templates, steps int64) {
+	if s == nil {
+		return
+	}
+	atomic.StoreInt64(&s.totalTemplates, templates)
+	atomic.StoreInt64(&s.steps, steps)
+}
+
+// PlanRequests adds a number of requests to the requests to send
+func (s *Stats) PlanRequests(count int64) {
+	if s == nil {
+		return
+	}
+	atomic.AddInt64(&s.plannedRequests, count)
+}
+
+// CompleteRequests counts a number of requests sent or skipped
+func (s *Stats) CompleteRequests(count int64) {
+	if s == nil {
+		return
+	}
+	atomic.AddInt64(&s.completedRequests, count)
+}
+
+// StepCompleted counts a run on a target, the target being completed once
+// all the runs of the templates are.
+func (s *Stats) StepCompleted(target string) {
+	if s == nil {
+		return
+	}
+	counter, ok := s.targetSteps.Load(target)
+	if !ok {
+		counter, _ = s.targetSteps.LoadOrStore(target, new(int64))
+	}
+	if atomic.AddInt64(counter.(*int64), 1) == atomic.LoadInt64(&s.steps) {
+		atomic.AddUint64(&s.completedHosts, 1)
+		// the completed targets are forgotten for the streams of targets
+		s.targetSteps.Delete(target)
+	}
+}
+
+// AddTarget counts a target streamed to the scan, whose number of targets
+// isn't known beforehand.
+func (s *Stats) AddTarget() {
+	if s == nil {
+		return
+	}
+	atomic.AddInt64(&s.targets, 1)
+}
+
+// TemplateCompleted counts a template or a workflow run on all the targets
+func (s *Stats) TemplateCompleted() {
+	if s == nil {
+		return
+	}
+	atomic.AddUint64(&s.completedTemplates, 1)
+}
+
+// Request counts a request sent to a target, in flight until RequestDone
+// is called once its response arrived or it failed.
+func (s *Stats) Request() {
+	if s == nil {
+		return
+	}
+	atomic.AddUint64(&s.requests, 1)
+	atomic.AddInt64(&s.inFlight, 1)
+}
+
+// RequestDone counts a request of Request as no longer in flight
+func (s *Stats) RequestDone() {
+	if s == nil {
+		return
+	}
+	atomic.AddInt64(&s.inFlight, -1)
+}
+
+// RequestsClustered counts the requests of the templates of a cluster not
+// sent to a target, the request of the cluster being sent once.
+func (s *Stats) RequestsClustered(count int64) {
+	if s == nil || count <= 0 {
+		return
+	}
+	atomic.AddUint64(&s.clustered, uint64(count))
+}
+
+// RequestCached counts a request not sent, its response being reused from
+// the cache of the project
+func (s *Stats) RequestCached() {
+	if s == nil {
+		return
+	}
+	atomic.AddUint64(&s.cached, 1)
+}
+
+// RequestShared counts a request not sent, its response being the one of
+// the same baseline request of -tech-detect
+func (s *Stats) RequestShared() {
+	if s == nil {
+		return
+	}
+	atomic.AddUint64(&s.shared, 1)
+}
+
+// RunRetried counts a run of a template on a target run again after failing
+// with a network error, succeeded being true if it did not fail again.
+func (s *Stats) RunRetried(succeeded bool) {
+	if s == nil {
+		return
+	}
+	atomic.AddUint64(&s.retried, 1)
+	if succeeded {
+		atomic.AddUint64(&s.retrySucceeded, 1)
+	}
+}
+
+// SetRateLimitPerHost sets the maximum number of requests per second to
+// each host, 0 for no limit.
+func (s *Stats) SetRateLimitPerHost(rate int64) {
+	if s == nil {
+		return
+	}
+	atomic.StoreInt64(&s.rateLimit, rate)
+}
+
+// RequestDelayed counts a request delayed by the rate limit of its host
+func (s *Stats) RequestDelayed(wait time.Duration) {
+	if s == nil {
+		return
+	}
+	atomic.AddUint64(&s.delayed, 1)
+	atomic.AddUint64(&s.delayedNS, uint64(wait))
+}
+
+// SetConcurrency sets the global concurrency of the adaptive mode
+func (s *Stats) SetConcurrency(concurrency int64) {
+	if s == nil {
+		return
+	}
+	atomic.StoreInt64(&s.concurrency, concurrency)
+}
+
+// ConcurrencyAdjusted counts an adjustment of the adaptive mode, backing
+// off or recovering, setting the global concurrency and the number of
+// throttled hosts after it.
+func (s *Stats) ConcurrencyAdjusted(backoff bool, concurrency, throttled int64) {
+	if s == nil {
+		return
+	}
+	if backoff {
+		atomic.AddUint64(&s.backoffs, 1)
+	} else {
+		atomic.AddUint64(&s.recoveries, 1)
+	}
+	atomic.StoreInt64(&s.concurrency, concurrency)
+	atomic.StoreInt64(&s.throttled, throttled)
+}
+
+// Finding counts a finding of a template with a severity
+func (s *Stats) Finding(templateID, severity string) {
+	if s == nil {
+		return
+	}
+	severity = strings.ToLower(severity)
+	if severity == "" {
+		severity = "unknown"
+	}
+	atomic.AddUint64(&s.findings, 1)
+	increment(&s.severities, severity)
+	increment(&s.templates, templateID)
+}
+
+// HostError counts an error of a template for a target, the target being
+// counted once whatever its number of errors.
+func (s *Stats) HostError(target string, err error) {
+	if s == nil || err == nil {
+		return
+	}
+	atomic.AddUint64(&s.errors, 1)
+	if _, loaded := s.errored.LoadOrStore(target, struct{}{}); !loaded {
+		atomic.AddUint64(&s.erroredCount, 1)
+	}
+	increment(&s.reasons, Reason(err))
+}
+
+// TargetsExcluded counts the targets of the input not scanned as they are
+// excluded by a rule
+func (s *Stats) TargetsExcluded(rule string, count uint64) {
+	if s == nil || count == 0 {
+		return
+	}
+	atomic.AddUint64(&s.excludedCount, count)
+	counter, ok := s.excluded.Load(rule)
+	if !ok {
+		counter, _ = s.excluded.LoadOrStore(rule, new(uint64))
+	}
+	atomic.AddUint64(counter.(*uint64), count)
+}
+
+// TargetsDuplicated counts the targets of the input not scanned as they
+// are near duplicates of a previous one
+func (s *Stats) TargetsDuplicated(count uint64) {
+	if s == nil || count == 0 {
+		return
+	}
+	atomic.AddUint64(&s.duplicates, count)
+}
+
+// HostSkipped counts a target skipped as it did not respond to the probes
+func (s *Stats) HostSkipped(target string) {
+	if s == nil {
+		return
+	}
+	if _, loaded := s.skipped.LoadOrStore(target, struct{}{}); !loaded {
+		atomic.AddUint64(&s.skippedCount, 1)
+	}
+}
+
+// HostDead records a host skipped after too many consecutive network
+// errors with the reason of its last error.
+func (s *Stats) HostDead(host string, err error) {
+	if s == nil {
+		return
+	}
+	s.dead.LoadOrStore(host, Reason(err))
+}
+
+// HostPreflightFailed records a host and port skipped as it failed the
+// liveness preflight with the reason of its error.
+func (s *Stats) HostPreflightFailed(hostPort string, err error) {
+	if s == nil {
+		return
+	}
+	s.preflight.LoadOrStore(hostPort, Reason(err))
+}
+
+// TechDetected records the technologies detected on a host and port
+func (s *Stats) TechDetected(hostPort string, technologies []string) {
+	if s == nil {
+		return
+	}
+	s.technologies.LoadOrStore(hostPort, technologies)
+}
+
+// TechSkipped counts a template skipped on a host and port as it is the one
+// of a technology not detected on it
+func (s *Stats) TechSkipped(hostPort string) {
+	if s == nil {
+		return
+	}
+	increment(&s.techSkipped, hostPort)
+	atomic.AddUint64(&s.techSkippedCount, 1)
+}
+
+// TemplateFailed records a template which could not be executed at all,
+// keeping the first reason of a template.
+func (s *Stats) TemplateFailed(templateID, reason string) {
+	if s == nil {
+		return
+	}
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	if _, ok := s.failed[templateID]; !ok {
+		s.failed[templateID] = reason
+	}
+}
+
+// TemplateTimedOut records a template abandoned on a target as it ran
+// longer than the template timeout.
+func (s *Stats) TemplateTimedOut(templateID, target string) {
+	if s == nil {
+		return
+	}
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	s.timedOut = append(s.timedOut, TimedOutTemplate{Template: templateID, Target: target})
+}
+
+// ScanTruncated records the scan as stopped before running all the
+// templates, its maximum duration being reached.
+func (s *Stats) ScanTruncated() {
+	if s == nil {
+		return
+	}
+	atomic.StoreUint32(&s.truncated, 1)
+}
+
+// SetPaused records the scan as paused or resumed by the control api
+func (s *Stats) SetPaused(paused bool) {
+	if s == nil {
+		return
+	}
+	var value uint32
+	if paused {
+		value = 1
+	}
+	atomic.StoreUint32(&s.paused, value)
+}
+
+// increment increments the counter of a key of a map of counters
+func increment(counters *sync.Map, key string) {
+	counter, ok := counters.Load(key)
+	if !ok {
+		counter, _ = counters.LoadOrStore(key, new(uint64))
+	}
+	atomic.AddUint64(counter.(*uint64), 1)
+}
+
+// Reason returns the reason of an error of a request, i.e timeout or
+// connection refused, or the cause of the error without the url of the
+// request if it is not a known network error.
+func Reason(err error) string {
+	var netErr net.Error
+	if errors.As(err, &netErr) && netErr.Timeout() {
+		return "timeout"
+	}
+	message := strings.ToLower(err.Error())
+	switch {
+	case strings.Contains(message, "timeout"), strings.Contains(message, "deadline exceeded"):
+		return "timeout"
+	case strings.Contains(message, "connection refused"):
+		return "connection refused"
+	case strings.Contains(message, "connection reset"):
+		return "connection reset"
+	case strings.Contains(message, "no such host"):
+		return "no such host"
+	case strings.Contains(message, "x509"), strings.Contains(message, "tls"):
+		return "tls error"
+	case strings.Contains(message, "eof"):
+		return "connection closed"
+	}
+
+	var urlErr *url.Error
+	if errors.As(err, &urlErr) {
+		err = urlErr.Err
+	} else {
+		for unwrapped := errors.Unwrap(err); unwrapped != nil; unwrapped = errors.Unwrap(err) {
+			err = unwrapped
+		}
+	}
+	reason := err.Error()
+	if len(reason) > maxReasonLength {
+		reason = reason[:maxReasonLength] + "..."
+	}
+	return reason
+}
+
+// Count is a number of findings or errors of a name
+type Count struct {
+	Name  string `json:"name"`
+	Count uint64 `json:"count"`
+}
+
+// FailedTemplate is a template which could not be executed at all
+type FailedTemplate struct {
+	Template string `json:"template"`
+	Reason   string `json:"reason"`
+}
+
+// TimedOutTemplate is a template abandoned on a target after the template
+// timeout
+type TimedOutTemplate struct {
+	Template string `json:"template"`
+	Target   string `json:"target"`
+}
+
+// DeadHost is a host skipped after too many consecutive network errors, or
+// a host and port skipped as it failed the liveness preflight.
+type DeadHost struct {
+	Host  string `json:"host"`
+	Error string `json:"error"`
+}
+
+// TechHost are the technologies detected on a host and port and the number
+// of templates skipped on it by -tech-detect
+type TechHost struct {
+	Host             string   `json:"host"`
+	Technologies     []string `json:"technologies"`
+	SkippedTemplates uint64   `json:"skipped_templates"`
+}
+
+// Snapshot are the counters of a scan at a point in time, written by -stats
+type Snapshot struct {
+	ElapsedMS          int64  `json:"elapsed_ms"`
+	HostsCompleted     uint64 `json:"hosts_completed"`
+	HostsTotal         int64  `json:"hosts_total"`
+	TemplatesCompleted uint64 `json:"templates_completed"`
+	TemplatesTotal     int64  `json:"templates_total"`
+	Requests           uint64 `json:"requests"`
+	// RPS is the average number of requests per second of the scan, or
+	// their number per second since the previous snapshot of a stream.
+	RPS     float64 `json:"rps"`
+	Matched uint64  `json:"matched"`
+	Errored uint64  `json:"errored"`
+	// Percent and ETAMS are the completion of the payload-aware number of
+	// requests of the scan and the time left at the average rate, if known.
+	Percent float64 `json:"percent"`
+	ETAMS   int64   `json:"eta_ms,omitempty"`
+	// RateLimitPerHost is the maximum number of requests per second to
+	// each host, RateLimited the number of requests delayed to stay below.
+	RateLimitPerHost int64  `json:"rate_limit_per_host,omitempty"`
+	RateLimited      uint64 `json:"rate_limited,omitempty"`
+	RateLimitWaitMS  int64  `json:"rate_limit_wait_ms,omitempty"`
+	// RequestsInFlight are the requests sent awaiting their response
+	RequestsInFlight int64 `json:"requests_in_flight"`
+	// CachedRequests are the requests whose response was reused from the
+	// cache of the project instead of being sent
+	CachedRequests uint64 `json:"cached_requests,omitempty"`
+	// Concurrency is the global concurrency of the adaptive mode,
+	// ThrottledHosts the hosts it throttles and Backoffs and Recoveries the
+	// numbers of its adjustments.
+	Concurrency    int64  `json:"concurrency,omitempty"`
+	ThrottledHosts int64  `json:"throttled_hosts,omitempty"`
+	Backoffs       uint64 `json:"backoffs,omitempty"`
+	Recoveries     uint64 `json:"recoveries,omitempty"`
+	// Paused is true while the control api paused the scan
+	Paused bool `json:"paused,omitempty"`
+	// Exports are the counters of the results published by exporter
+	Exports map[string]ExportCount `json:"exports,omitempty"`
+}
+
+// ExportCount are the counters of the results of an exporter
+type ExportCount struct {
+	Published uint64 `json:"published"`
+	Failed    uint64 `json:"failed"`
+	Dropped   uint64 `json:"dropped"`
+}
+
+// Snapshot returns the counters of the scan so far
+func (s *Stats) Snapshot() *Snapshot {
+	elapsed := time.Since(s.start)
+	snapshot := &Snapshot{
+		ElapsedMS:          elapsed.Milliseconds(),
+		HostsCompleted:     atomic.LoadUint64(&s.completedHosts),
+		HostsTotal:         atomic.LoadInt64(&s.targets),
+		TemplatesCompleted: atomic.LoadUint64(&s.completedTemplates),
+		TemplatesTotal:     atomic.LoadInt64(&s.totalTemplates),
+		Requests:           atomic.LoadUint64(&s.requests),
+		Matched:            atomic.LoadUint64(&s.findings),
+		Errored:            atomic.LoadUint64(&s.errors),
+		RateLimitPerHost:   atomic.LoadInt64(&s.rateLimit),
+		RateLimited:        atomic.LoadUint64(&s.delayed),
+		RateLimitWaitMS:    time.Duration(atomic.LoadUint64(&s.delayedNS)).Milliseconds(),
+		RequestsInFlight:   atomic.LoadInt64(&s.inFlight),
+		CachedRequests:     atomic.LoadUint64(&s.cached),
+		Concurrency:        atomic.LoadInt64(&s.concurrency),
+		ThrottledHosts:     atomic.LoadInt64(&s.throttled),
+		Backoffs:           atomic.LoadUint64(&s.backoffs),
+		Recoveries:         atomic.LoadUint64(&s.recoveries),
+		Paused:             atomic.LoadUint32(&s.paused) == 1,
+	}
+	if seconds := elapsed.Seconds(); seconds > 0 {
+		snapshot.RPS = float64(snapshot.Requests) / seconds
+	}
+	s.mutex.Lock()
+	for name, counter := range s.exporters {
+		if snapshot.Exports == nil {
+			snapshot.Exports = make(map[string]ExportCount, len(s.exporters))
+		}
+		snapshot.Exports[name] = ExportCount{Published: counter.Published(), Failed: counter.Failed(), Dropped: counter.Dropped()}
+	}
+	s.mutex.Unlock()
+
+	planned, completed := atomic.LoadInt64(&s.plannedRequests), atomic.LoadInt64(&s.completedRequests)
+	if planned > 0 {
+		if completed > planned {
+			completed = planned
+		}
+		snapshot.Percent = float64(completed) * 100 / float64(planned)
+	}
+	if completed > 0 && planned > completed {
+		snapshot.ETAMS = int64(float64(elapsed.Milliseconds()) * float64(planned-completed) / float64(completed))
+	}
+	return snapshot
+}
+
+// Summary is the summary of a scan, written by -stats-json
+type Summary struct {
+	Targets  int64  `json:"targets"`
+	Requests uint64 `json:"requests"`
+	// ClusteredRequests are the requests saved by clustering the templates
+	ClusteredRequests uint64 `json:"clustered_requests"`
+	// CachedRequests are the requests whose response was reused from the
+	// cache of the project
+	CachedRequests uint64 `json:"cached_requests,omitempty"`
+	// SharedRequests are the requests whose response was the one of the
+	// same baseline request of -tech-detect
+	SharedRequests uint64 `json:"shared_requests,omitempty"`
+	DurationMS     int64  `json:"duration_ms"`
+	// Retries are the runs of the templates on the targets run again at the
+	// end of the scan after failing with a network error, SucceededRetries
+	// the ones which did not fail again.
+	Retries          uint64 `json:"retries,omitempty"`
+	SucceededRetries uint64 `json:"succeeded_retries,omitempty"`
+	// RateLimitPerHost is the maximum number of requests per second to each
+	// host if limited, RateLimitedRequests the number of requests delayed to
+	// stay below it and RateLimitWaitMS their total wait.
+	RateLimitPerHost    int64  `json:"rate_limit_per_host,omitempty"`
+	RateLimitedRequests uint64 `json:"rate_limited_requests,omitempty"`
+	RateLimitWaitMS     int64  `json:"rate_limit_wait_ms,omitempty"`
+	// Backoffs and Recoveries are the adjustments of the adaptive mode
+	Backoffs   uint64 `json:"backoffs,omitempty"`
+	Recoveries uint64 `json:"recoveries,omitempty"`
+	Findings   uint64 `json:"findings"`
+	// Severities are the numbers of findings by severity
+	Severities map[string]uint64 `json:"severities"`
+	// TopTemplates are the templates with the most findings, the most first
+	TopTemplates []Count `json:"top_templates"`
+	ErroredHosts uint64  `json:"errored_hosts"`
+	SkippedHosts uint64  `json:"skipped_hosts"`
+	// ExcludedTargets are the targets of the input not scanned and
+	// ExclusionRules their numbers by exclusion rule, the most first.
+	ExcludedTargets uint64  `json:"excluded_targets,omitempty"`
+	ExclusionRules  []Count `json:"exclusion_rules,omitempty"`
+	// DuplicateTargets are the targets of the input not scanned as they
+	// are near duplicates of a previous one.
+	DuplicateTargets uint64 `json:"duplicate_targets,omitempty"`
+	// TechHosts are the technologies detected by -tech-detect by host and
+	// port, sorted by host and port, and TechSkippedTemplates the templates
+	// skipped as the ones of technologies not detected.
+	TechHosts            []TechHost `json:"tech_hosts,omitempty"`
+	TechSkippedTemplates uint64     `json:"tech_skipped_templates,omitempty"`
+	// ErrorReasons are the numbers of errors by reason, the most first
+	ErrorReasons []Count    `json:"error_reasons"`
+	DeadHosts    []DeadHost `json:"dead_hosts"`
+	// PreflightFailed are the hosts and ports failing the liveness
+	// preflight, sorted by host and port.
+	PreflightFailed []DeadHost       `json:"preflight_failed,omitempty"`
+	FailedTemplates []FailedTemplate `json:"failed_templates"`
+	// TimedOut are the template and target pairs abandoned after the
+	// template timeout, sorted by template and target.
+	TimedOut    []TimedOutTemplate `json:"timed_out"`
+	Interrupted bool               `json:"interrupted,omitempty"`
+	// Truncated is true if the scan stopped after its maximum duration or
+	// by the control api
+	Truncated bool `json:"truncated,omitempty"`
+}
+
+// Summary returns the summary of the scan so far with the top templates
+// by number of findings, all of them if top is 0. Its totals are the ones
+// of a snapshot, so they agree with the ones written by -stats.
+func (s *Stats) Summary(top int, interrupted bool) *Summary {
+	snapshot := s.Snapshot()
+	summary := &Summary{
+		Targets:             snapshot.HostsTotal,
+		Requests:            snapshot.Requests,
+		ClusteredRequests:   atomic.LoadUint64(&s.clustered),
+		CachedRequests:      snapshot.CachedRequests,
+		SharedRequests:      atomic.LoadUint64(&s.shared),
+		DurationMS:          snapshot.ElapsedMS,
+		Retries:             atomic.LoadUint64(&s.retried),
+		SucceededRetries:    atomic.LoadUint64(&s.retrySucceeded),
+		RateLimitPerHost:    snapshot.RateLimitPerHost,
+		RateLimitedRequests: snapshot.RateLimited,
+		RateLimitWaitMS:     snapshot.RateLimitWaitMS,
+		Backoffs:            snapshot.Backoffs,
+		Recoveries:          snapshot.Recoveries,
+		Findings:            snapshot.Matched,
+		Severities:          make(map[string]uint64),
+		TopTemplates:        counts(&s.templates),
+		ErroredHosts:        atomic.LoadUint64(&s.erroredCount),
+		SkippedHosts:        atomic.LoadUint64(&s.skippedCount),
+		ExcludedTargets:     atomic.LoadUint64(&s.excludedCount),
+		DuplicateTargets:    atomic.LoadUint64(&s.duplicates),
+		ErrorReasons:        counts(&s.reasons),
+		DeadHosts:           []DeadHost{},
+		FailedTemplates:     []FailedTemplate{},
+		TimedOut:            []TimedOutTemplate{},
+		Interrupted:         interrupted,
+		Truncated:           atomic.LoadUint32(&s.truncated) == 1,
+	}
+	if summary.ExcludedTargets > 0 {
+		summary.ExclusionRules = counts(&s.excluded)
+	}
+	for _, severity := range counts(&s.severities) {
+		summary.Severities[severity.Name] = severity.Count
+	}
+	if top > 0 && len(summary.TopTemplates) > top {
+		summary.TopTemplates = summary.TopTemplates[:top]
+	}
+
+	s.dead.Range(func(key, value interface{}) bool {
+		summary.DeadHosts = append(summary.DeadHosts, DeadHost{Host: key.(string), Error: value.(string)})
+		return true
+	})
+	sort.Slice(summary.DeadHosts, func(i, j int) bool {
+		return summary.DeadHosts[i].Host < summary.DeadHosts[j].Host
+	})
+	s.preflight.Range(func(key, value interface{}) bool {
+		summary.PreflightFailed = append(summary.PreflightFailed, DeadHost{Host: key.(string), Error: value.(string)})
+		return true
+	})
+	sort.Slice(summary.PreflightFailed, func(i, j int) bool {
+		return summary.PreflightFailed[i].Host < summary.PreflightFailed[j].Host
+	})
+	summary.TechSkippedTemplates = atomic.LoadUint64(&s.techSkippedCount)
+	techHosts := make(map[string]*TechHost)
+	s.technologies.Range(func(key, value interface{}) bool {
+		techHosts[key.(string)] = &TechHost{Host: key.(string), Technologies: value.([]string)}
+		return true
+	})
+	for _, skipped := range counts(&s.techSkipped) {
+		host, ok := techHosts[skipped.Name]
+		if !ok {
+			host = &TechHost{Host: skipped.Name, Technologies: []string{}}
+			techHosts[skipped.Name] = host
+		}
+		host.SkippedTemplates = skipped.Count
+	}
+	for _, host := range techHosts {
+		summary.TechHosts = append(summary.TechHosts, *host)
+	}
+	sort.Slice(summary.TechHosts, func(i, j int) bool {
+		return summary.TechHosts[i].Host < summary.TechHosts[j].Host
+	})
+
+	s.mutex.Lock()
+	for template, reason := range s.failed {
+		summary.FailedTemplates = append(summary.FailedTemplates, FailedTemplate{Template: template, Reason: reason})
+	}
+	summary.TimedOut = append(summary.TimedOut, s.timedOut...)
+	s.mutex.Unlock()
+	sort.Slice(summary.FailedTemplates, func(i, j int) bool {
+		return summary.FailedTemplates[i].Template < summary.FailedTemplates[j].Template
+	})
+	sort.Slice(summary.TimedOut, func(i, j int) bool {
+		if summary.TimedOut[i].Template != summary.TimedOut[j].Template {
+			return summary.TimedOut[i].Template < summary.TimedOut[j].Template
+		}
+		return summary.TimedOut[i].Target < summary.TimedOut[j].Target
+	})
+	return summary
+}
+
+// counts returns the counts of a map of counters, the largest first
+func counts(counters *sync.Map) []Count {
+	result := []Count{}
+	counters.Range(func(key, value interface{}) bool {
+		result = append(result, Count{Name: key.(string), Count: atomic.LoadUint64(value.(*uint64))})
+		return true
+	})
+	sort.Slice(result, func(i, j int) bool {
+		if result[i].Count != result[j].Count {
+			return result[i].Count > result[j].Count
+		}
+		return result[i].Name < result[j].Name
+	})
+	return result
+}
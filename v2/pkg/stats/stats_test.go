@@ -0,0 +1,244 @@
+package stats
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"net/url"
+	"strings"
+	"sync"
+	"syscall"
+	"testing"
+	"time"
+
+	jsoniter "github.com/json-iterator/go"
+	pkgerrors "github.com/pkg/errors"
+	"github.com/stretchr/testify/require"
+)
+
+func TestStats(t *testing.T) {
+	s := New(3)
+	var wg sync.WaitGroup
+	for i := 0; i < 50; i++ {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			s.Request()
+			if i%5 == 0 {
+				s.Finding("git-config", "Medium")
+			}
+			if i%10 == 0 {
+				s.Finding("cve-2021-1234", "critical")
+			}
+		}(i)
+	}
+	wg.Wait()
+	s.Finding("tech-detect", "")
+
+	refused := pkgerrors.Wrap(&url.Error{Op: "Get", URL: "http://a.example.com", Err: syscall.ECONNREFUSED}, "Could not do request")
+	s.HostError("http://a.example.com", refused)
+	s.HostError("http://a.example.com", refused)
+	s.HostError("http://b.example.com", errors.New("invalid dns port for b.example.com:x: x"))
+	s.HostError("http://b.example.com", nil)
+	s.HostSkipped("c.example.com")
+	s.HostSkipped("c.example.com")
+	s.TemplateFailed("broken", "could not compile matcher")
+	s.TemplateFailed("broken", "another reason")
+	s.HostDead("d.example.com", refused)
+	s.RequestsClustered(4)
+	s.RequestsClustered(0)
+	s.RequestCached()
+	s.RequestCached()
+	s.RequestShared()
+	s.TechDetected("f.example.com:443", []string{"PHP", "WordPress"})
+	s.TechDetected("f.example.com:443", []string{"Other"})
+	s.TechSkipped("f.example.com:443")
+	s.TechSkipped("f.example.com:443")
+	s.TechSkipped("a.example.com:80")
+	s.TargetsExcluded("10.0.0.0/24", 3)
+	s.TargetsExcluded("*.internal.example.com", 1)
+	s.TargetsExcluded("10.0.0.0/24", 1)
+	s.TargetsExcluded("unused", 0)
+	s.TargetsDuplicated(2)
+	s.TargetsDuplicated(0)
+	s.RunRetried(true)
+	s.RunRetried(false)
+	s.RunRetried(true)
+	s.SetRateLimitPerHost(5)
+	s.RequestDelayed(200 * time.Millisecond)
+	s.RequestDelayed(300 * time.Millisecond)
+	s.SetConcurrency(50)
+	s.ConcurrencyAdjusted(true, 25, 1)
+	s.ConcurrencyAdjusted(true, 12, 2)
+	s.ConcurrencyAdjusted(false, 13, 1)
+	s.HostDead("d.example.com", errors.New("another error"))
+	s.HostPreflightFailed("e.example.com:8443", refused)
+	s.HostPreflightFailed("e.example.com:443", errors.New("i/o timeout"))
+	s.HostPreflightFailed("e.example.com:8443", errors.New("another error"))
+	s.TemplateTimedOut("slow", "http://b.example.com")
+	s.TemplateTimedOut("slow", "http://a.example.com")
+	s.ScanTruncated()
+
+	summary := s.Summary(2, true)
+	require.Equal(t, int64(3), summary.Targets, "Could not keep the targets")
+	require.Equal(t, uint64(50), summary.Requests, "Could not count the requests")
+	require.Equal(t, uint64(4), summary.ClusteredRequests, "Could not count the clustered requests")
+	require.Equal(t, uint64(2), summary.CachedRequests, "Could not count the cached requests")
+	require.Equal(t, uint64(1), summary.SharedRequests, "Could not count the shared requests")
+	require.Equal(t, []TechHost{{Host: "a.example.com:80", Technologies: []string{}, SkippedTemplates: 1}, {Host: "f.example.com:443", Technologies: []string{"PHP", "WordPress"}, SkippedTemplates: 2}}, summary.TechHosts, "Could not keep the technologies of the hosts")
+	require.Equal(t, uint64(3), summary.TechSkippedTemplates, "Could not count the templates skipped by the technologies")
+	require.Equal(t, uint64(5), summary.ExcludedTargets, "Could not count the excluded targets")
+	require.Equal(t, []Count{{"10.0.0.0/24", 4}, {"*.internal.example.com", 1}}, summary.ExclusionRules, "Could not count the excluded targets by rule")
+	require.Equal(t, uint64(2), summary.DuplicateTargets, "Could not count the duplicate targets")
+	require.Equal(t, uint64(3), summary.Retries, "Could not count the retried runs")
+	require.Equal(t, uint64(2), summary.SucceededRetries, "Could not count the succeeded retries")
+	require.Equal(t, int64(5), summary.RateLimitPerHost, "Could not keep the rate limit")
+	require.Equal(t, uint64(2), summary.RateLimitedRequests, "Could not count the delayed requests")
+	require.Equal(t, int64(500), summary.RateLimitWaitMS, "Could not sum the waits of the delayed requests")
+	require.Equal(t, uint64(2), summary.Backoffs, "Could not count the backoffs")
+	require.Equal(t, uint64(1), summary.Recoveries, "Could not count the recoveries")
+	require.Equal(t, uint64(16), summary.Findings, "Could not count the findings")
+	require.Equal(t, map[string]uint64{"medium": 10, "critical": 5, "unknown": 1}, summary.Severities, "Could not count the findings by severity")
+	require.Equal(t, []Count{{Name: "git-config", Count: 10}, {Name: "cve-2021-1234", Count: 5}}, summary.TopTemplates, "Could not keep the top templates")
+	require.Equal(t, uint64(2), summary.ErroredHosts, "Could not count the errored hosts once")
+	require.Equal(t, uint64(1), summary.SkippedHosts, "Could not count the skipped hosts once")
+	require.Equal(t, []Count{{Name: "connection refused", Count: 2}, {Name: "invalid dns port for b.example.com:x: x", Count: 1}}, summary.ErrorReasons, "Could not count the error reasons")
+	require.Equal(t, []FailedTemplate{{Template: "broken", Reason: "could not compile matcher"}}, summary.FailedTemplates, "Could not keep the first reason of the failed template")
+	require.Equal(t, []DeadHost{{Host: "d.example.com", Error: "connection refused"}}, summary.DeadHosts, "Could not keep the triggering error of the dead host")
+	require.Equal(t, []DeadHost{{Host: "e.example.com:443", Error: "timeout"}, {Host: "e.example.com:8443", Error: "connection refused"}}, summary.PreflightFailed, "Could not keep the hosts and ports failing the preflight")
+	require.Equal(t, []TimedOutTemplate{{Template: "slow", Target: "http://a.example.com"}, {Template: "slow", Target: "http://b.example.com"}}, summary.TimedOut, "Could not keep the timed out templates")
+	require.True(t, summary.Interrupted, "Could not flag the interrupted scan")
+	require.True(t, summary.Truncated, "Could not flag the truncated scan")
+
+	snapshot := s.Snapshot()
+	require.Equal(t, int64(13), snapshot.Concurrency, "Could not keep the adjusted concurrency")
+	require.Equal(t, int64(1), snapshot.ThrottledHosts, "Could not keep the throttled hosts")
+
+	var nilStats *Stats
+	nilStats.Request()
+	nilStats.Finding("git-config", "medium")
+	nilStats.HostError("a.example.com", refused)
+}
+
+// exportCounter is an exporter with fixed counters
+type exportCounter struct {
+	published, failed, dropped uint64
+}
+
+func (c *exportCounter) Published() uint64 { return c.published }
+func (c *exportCounter) Failed() uint64    { return c.failed }
+func (c *exportCounter) Dropped() uint64   { return c.dropped }
+
+func TestSnapshot(t *testing.T) {
+	s := New(2)
+	s.SetTemplates(2, 3)
+	s.PlanRequests(8)
+	for _, target := range []string{"a.example.com", "b.example.com", "a.example.com", "a.example.com", "b.example.com"} {
+		s.StepCompleted(target)
+	}
+	s.TemplateCompleted()
+	for i := 0; i < 3; i++ {
+		s.Request()
+	}
+	s.RequestDone()
+	s.CompleteRequests(3)
+	s.CompleteRequests(1)
+	s.Finding("git-config", "medium")
+	s.HostError("b.example.com", errors.New("connection refused"))
+	s.HostError("b.example.com", errors.New("connection refused"))
+
+	snapshot := s.Snapshot()
+	require.Equal(t, uint64(1), snapshot.HostsCompleted, "Could not complete the host with all its steps")
+	require.Equal(t, int64(2), snapshot.HostsTotal, "Could not keep the hosts")
+	require.Equal(t, uint64(1), snapshot.TemplatesCompleted, "Could not count the completed templates")
+	require.Equal(t, int64(2), snapshot.TemplatesTotal, "Could not keep the templates")
+	require.Equal(t, uint64(3), snapshot.Requests, "Could not count the requests")
+	require.Equal(t, int64(2), snapshot.RequestsInFlight, "Could not count the requests in flight")
+	require.Equal(t, uint64(1), snapshot.Matched, "Could not count the findings")
+	require.Equal(t, uint64(2), snapshot.Errored, "Could not count the errors")
+	require.Equal(t, float64(50), snapshot.Percent, "Could not compute the completion of the skipped and sent requests")
+	require.Equal(t, snapshot.ElapsedMS, snapshot.ETAMS, "Could not compute the time left at the average rate")
+	require.False(t, snapshot.Paused, "Could not start the scan running")
+	s.SetPaused(true)
+	require.True(t, s.Snapshot().Paused, "Could not flag the paused scan")
+	s.SetPaused(false)
+	require.False(t, s.Snapshot().Paused, "Could not flag the resumed scan")
+	require.Nil(t, snapshot.Exports, "Could not omit the exports without exporters")
+	s.AddExporter("kafka", &exportCounter{published: 3, failed: 1})
+	require.Equal(t, map[string]ExportCount{"kafka": {Published: 3, Failed: 1}}, s.Snapshot().Exports, "Could not count the results of the exporter")
+
+	summary := s.Summary(0, false)
+	require.Equal(t, snapshot.Requests, summary.Requests, "Could not agree on the requests")
+	require.Equal(t, snapshot.Matched, summary.Findings, "Could not agree on the findings")
+
+	s.CompleteRequests(10)
+	snapshot = s.Snapshot()
+	require.Equal(t, float64(100), snapshot.Percent, "Could not cap the completion")
+	require.Zero(t, snapshot.ETAMS, "Could not omit the time left of a completed scan")
+
+	var nilStats *Stats
+	nilStats.SetTemplates(1, 1)
+	nilStats.PlanRequests(1)
+	nilStats.StepCompleted("a.example.com")
+	nilStats.TemplateCompleted()
+	nilStats.AddTarget()
+	nilStats.RequestDone()
+}
+
+func TestStreamedTargets(t *testing.T) {
+	s := New(0)
+	s.SetTemplates(1, 2)
+	for _, target := range []string{"a.example.com", "b.example.com"} {
+		s.AddTarget()
+		s.StepCompleted(target)
+		s.StepCompleted(target)
+	}
+	// the steps of a target streamed again are counted again
+	s.AddTarget()
+	s.StepCompleted("a.example.com")
+	s.StepCompleted("a.example.com")
+
+	snapshot := s.Snapshot()
+	require.Equal(t, int64(3), snapshot.HostsTotal, "Could not count the streamed targets")
+	require.Equal(t, uint64(3), snapshot.HostsCompleted, "Could not complete the streamed targets")
+}
+
+func TestStream(t *testing.T) {
+	s := New(1)
+	s.Request()
+	s.Finding("git-config", "medium")
+
+	var buffer bytes.Buffer
+	stream := NewStream(s, &buffer, time.Hour)
+	stream.Start()
+	stream.Stop()
+	stream.Stop()
+
+	lines := strings.Split(strings.TrimSuffix(buffer.String(), "\n"), "\n")
+	require.Len(t, lines, 1, "Could not write the last snapshot once")
+	snapshot := &Snapshot{}
+	require.Nil(t, jsoniter.Unmarshal([]byte(lines[0]), snapshot), "Could not parse the snapshot")
+	require.Equal(t, uint64(1), snapshot.Requests, "Could not write the requests")
+	require.Equal(t, uint64(1), snapshot.Matched, "Could not write the findings")
+	require.True(t, snapshot.RPS > 0, "Could not compute the requests per second")
+
+	buffer.Reset()
+	NewStream(s, &buffer, time.Hour).Stop()
+	require.NotEmpty(t, buffer.String(), "Could not write the last snapshot of a stream not started")
+}
+
+func TestReason(t *testing.T) {
+	tests := []struct {
+		err    error
+		reason string
+	}{
+		{err: pkgerrors.Wrap(&url.Error{Op: "Get", URL: "https://example.com", Err: errors.New("net/http: TLS handshake timeout")}, "Could not do request"), reason: "timeout"},
+		{err: fmt.Errorf("dial tcp: lookup nx.example.com: no such host"), reason: "no such host"},
+		{err: &url.Error{Op: "Get", URL: "https://example.com", Err: errors.New("x509: certificate signed by unknown authority")}, reason: "tls error"},
+		{err: &url.Error{Op: "Get", URL: "https://example.com/a", Err: errors.New("stopped after 10 redirects")}, reason: "stopped after 10 redirects"},
+		{err: pkgerrors.Wrap(errors.New("unexpected status 500"), "could not do baseline request"), reason: "unexpected status 500"},
+	}
+	for _, test := range tests {
+		require.Equal(t, test.reason, Reason(test.err), "Could not find the reason of %s", test.err)
+	}
+}
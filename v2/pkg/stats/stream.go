@@ -0,0 +1,103 @@
+package stats
+
+import (
+	"io"
+	"sync"
+	"time"
+
+	jsoniter "github.com/json-iterator/go"
+	"github.com/projectdiscovery/gologger"
+)
+
+// Stream writes a snapshot of the stats of a scan as a json line at an
+// interval, written by -stats. Each line is written with a single write so
+// the lines are never interleaved with the other outputs of the writer.
+type Stream struct {
+	stats    *Stats
+	writer   io.Writer
+	interval time.Duration
+
+	mutex sync.Mutex
+	// requests and last are the requests and the time of the previous
+	// snapshot, to compute the current number of requests per second.
+	requests uint64
+	last     time.Time
+	failed   bool
+	running  bool
+
+	stop chan struct{}
+	done chan struct{}
+	once sync.Once
+}
+
+// NewStream returns a stream of the stats writing a snapshot to a writer
+// at an interval.
+func NewStream(stats *Stats, writer io.Writer, interval time.Duration) *Stream {
+	return &Stream{
+		stats:    stats,
+		writer:   writer,
+		interval: interval,
+		last:     stats.start,
+		stop:     make(chan struct{}),
+		done:     make(chan struct{}),
+	}
+}
+
+// Start starts writing the snapshots until the stream is stopped
+func (s *Stream) Start() {
+	s.mutex.Lock()
+	s.running = true
+	s.mutex.Unlock()
+	go func() {
+		defer close(s.done)
+		ticker := time.NewTicker(s.interval)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-ticker.C:
+				s.write()
+			case <-s.stop:
+				return
+			}
+		}
+	}()
+}
+
+// Stop stops the stream, writing a last snapshot even if it was not
+// started. It can be called more than once, the last snapshot being
+// written once.
+func (s *Stream) Stop() {
+	s.once.Do(func() {
+		s.mutex.Lock()
+		running := s.running
+		s.mutex.Unlock()
+		close(s.stop)
+		if running {
+			<-s.done
+		}
+		s.write()
+	})
+}
+
+// write writes a snapshot with the number of requests per second since the
+// previous one.
+func (s *Stream) write() {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	now := time.Now()
+	snapshot := s.stats.Snapshot()
+	if seconds := now.Sub(s.last).Seconds(); seconds > 0 {
+		snapshot.RPS = float64(snapshot.Requests-s.requests) / seconds
+	}
+	s.requests, s.last = snapshot.Requests, now
+
+	data, err := jsoniter.Marshal(snapshot)
+	if err != nil {
+		return
+	}
+	if _, err := s.writer.Write(append(data, '\n')); err != nil && !s.failed {
+		s.failed = true
+		gologger.Warningf("Could not write the stats: %s\n", err)
+	}
+}
@@ -0,0 +1,3 @@
+// Package syslog sends each result of a scan to a syslog collector as a
+// RFC5424 message, over udp, tcp or tls, for the SIEM ingestion.
+package syslog
@@ -0,0 +1,255 @@
+package syslog
+
+import (
+	"crypto/tls"
+	"crypto/x509"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"net"
+	"net/url"
+	"os"
+	"strconv"
+	"sync/atomic"
+	"time"
+
+	"github.com/projectdiscovery/gologger"
+	"github.com/projectdiscovery/nuclei/v2/pkg/export"
+)
+
+// Exporter sends the results of a scan to a syslog collector from a bounded
+// queue, so that a dead collector can't stall the scan.
+//
+// The results are dropped and counted when the queue is full. A broken tcp
+// or tls connection is reconnected at once, the results failing while the
+// collector can't be reached being dropped until the next attempt, whose
+// delay doubles up to maxBackoff.
+type Exporter struct {
+	network string
+	address string
+	tls     *tls.Config
+	// structuredData writes the results as the structured data of the
+	// messages instead of their body.
+	structuredData bool
+	header         *header
+	queue          *export.Queue
+
+	// conn and the reconnection delays are only used by the worker
+	conn net.Conn
+	// backoff is the delay before the next connection attempt, doubling from
+	// minBackoff up to maxBackoff while the collector can't be reached.
+	backoff    time.Duration
+	minBackoff time.Duration
+	retryAt    time.Time
+
+	sent   uint64
+	failed uint64
+}
+
+// maxBackoff is the longest delay before reconnecting to the collector
+const maxBackoff = 30 * time.Second
+
+// writeTimeout is the timeout of the connections and writes to the collector
+const writeTimeout = 10 * time.Second
+
+// New creates an exporter sending the results to the collector of an url
+// like udp://host:514, tcp://host:514 or tls://host:6514. The options are
+// the query parameters of the url:
+//
+//	format       body to write the json result as the message body, the
+//	             default, or sd to write it as the structured data.
+//	facility     the syslog facility, 16 (local0) by default.
+//	queue-size   the number of results waiting to be sent, 1000 by default.
+//	ca           the certificate file of the collector authority for tls.
+//	cert, key    the client certificate and key files for tls.
+//	skip-verify  true to skip the verification of the collector certificate.
+func New(rawURL string) (*Exporter, error) {
+	parsed, err := url.Parse(rawURL)
+	if err != nil {
+		return nil, fmt.Errorf("invalid syslog url %s: %s", rawURL, err)
+	}
+	if parsed.Scheme != "udp" && parsed.Scheme != "tcp" && parsed.Scheme != "tls" {
+		return nil, fmt.Errorf("invalid syslog url %s, it should be like udp://host:514, tcp://host:514 or tls://host:6514", rawURL)
+	}
+	address := parsed.Host
+	if parsed.Port() == "" {
+		port := "514"
+		if parsed.Scheme == "tls" {
+			port = "6514"
+		}
+		address = net.JoinHostPort(parsed.Hostname(), port)
+	}
+	if parsed.Hostname() == "" {
+		return nil, fmt.Errorf("invalid syslog url %s, no host given", rawURL)
+	}
+
+	query := parsed.Query()
+	e := &Exporter{
+		network:    parsed.Scheme,
+		address:    address,
+		header:     &header{facility: 16, hostname: "-", procID: strconv.Itoa(os.Getpid())},
+		minBackoff: time.Second,
+	}
+	if hostname, err := os.Hostname(); err == nil {
+		e.header.hostname = headerField(hostname, 255)
+	}
+	switch format := query.Get("format"); format {
+	case "", "body":
+	case "sd":
+		e.structuredData = true
+	default:
+		return nil, fmt.Errorf("invalid syslog format %s, it should be body or sd", format)
+	}
+	if value := query.Get("facility"); value != "" {
+		facility, err := strconv.Atoi(value)
+		if err != nil || facility < 0 || facility > 23 {
+			return nil, fmt.Errorf("invalid syslog facility %s, it should be from 0 to 23", value)
+		}
+		e.header.facility = facility
+	}
+	queueSize := 1000
+	if value := query.Get("queue-size"); value != "" {
+		queueSize, err = strconv.Atoi(value)
+		if err != nil || queueSize < 1 {
+			return nil, fmt.Errorf("invalid syslog queue size %s, it should be 1 or more", value)
+		}
+	}
+	if e.network == "tls" {
+		if e.tls, err = tlsConfig(parsed.Hostname(), query); err != nil {
+			return nil, err
+		}
+	} else if query.Get("ca") != "" || query.Get("cert") != "" {
+		return nil, errors.New("syslog certificates given without tls")
+	}
+	e.backoff = e.minBackoff
+	e.queue = export.NewQueue(queueSize, 1, time.Second, e.send)
+	return e, nil
+}
+
+// tlsConfig returns the tls config of the query parameters of a collector
+func tlsConfig(serverName string, query url.Values) (*tls.Config, error) {
+	config := &tls.Config{ServerName: serverName, InsecureSkipVerify: query.Get("skip-verify") == "true"}
+	if ca := query.Get("ca"); ca != "" {
+		data, err := ioutil.ReadFile(ca)
+		if err != nil {
+			return nil, fmt.Errorf("could not read syslog ca: %s", err)
+		}
+		config.RootCAs = x509.NewCertPool()
+		if !config.RootCAs.AppendCertsFromPEM(data) {
+			return nil, fmt.Errorf("no certificates found in syslog ca %s", ca)
+		}
+	}
+	cert, key := query.Get("cert"), query.Get("key")
+	if (cert == "") != (key == "") {
+		return nil, errors.New("both the syslog client certificate and key should be given")
+	}
+	if cert != "" {
+		certificate, err := tls.LoadX509KeyPair(cert, key)
+		if err != nil {
+			return nil, fmt.Errorf("could not load syslog client certificate: %s", err)
+		}
+		config.Certificates = []tls.Certificate{certificate}
+	}
+	return config, nil
+}
+
+// IncludeRR returns false as the messages don't have the raw requests and
+// responses, which are too large for most collectors.
+func (e *Exporter) IncludeRR() bool {
+	return false
+}
+
+// Export queues a json result to send, dropping it if the queue is full
+func (e *Exporter) Export(data []byte) {
+	e.queue.Push(data)
+}
+
+// Close sends the queued results and closes the connection
+func (e *Exporter) Close() {
+	e.queue.Close()
+	if e.conn != nil {
+		e.conn.Close()
+		e.conn = nil
+	}
+}
+
+// Sent returns the number of results sent to the collector
+func (e *Exporter) Sent() uint64 {
+	return atomic.LoadUint64(&e.sent)
+}
+
+// Queued returns the number of results waiting in the queue
+func (e *Exporter) Queued() int {
+	return e.queue.Len()
+}
+
+// Dropped returns the number of results dropped as the queue was full
+func (e *Exporter) Dropped() uint64 {
+	return e.queue.Dropped()
+}
+
+// Failed returns the number of results dropped as the collector could not
+// be reached.
+func (e *Exporter) Failed() uint64 {
+	return atomic.LoadUint64(&e.failed)
+}
+
+// send sends the results of a batch, reconnecting once on a broken
+// connection.
+func (e *Exporter) send(batch [][]byte) {
+	for _, data := range batch {
+		message := e.header.format(data, e.structuredData, time.Now())
+		err := e.write(message)
+		if err != nil && e.conn == nil && time.Now().After(e.retryAt) {
+			err = e.write(message)
+		}
+		if err != nil {
+			atomic.AddUint64(&e.failed, 1)
+			gologger.Warningf("Could not send result to syslog %s: %s\n", e.address, err)
+			continue
+		}
+		atomic.AddUint64(&e.sent, 1)
+	}
+}
+
+// write writes a message, connecting to the collector if needed. The
+// connection is closed on failure to reconnect on the next write.
+func (e *Exporter) write(message []byte) error {
+	if e.conn == nil {
+		if time.Now().Before(e.retryAt) {
+			return errors.New("collector unreachable, waiting to reconnect")
+		}
+		conn, err := e.dial()
+		if err != nil {
+			e.retryAt = time.Now().Add(e.backoff)
+			if e.backoff *= 2; e.backoff > maxBackoff {
+				e.backoff = maxBackoff
+			}
+			return err
+		}
+		e.conn = conn
+	}
+
+	// the tcp and tls messages are framed with their length, RFC6587
+	frame := message
+	if e.network != "udp" {
+		frame = append([]byte(strconv.Itoa(len(message))+" "), message...)
+	}
+	e.conn.SetWriteDeadline(time.Now().Add(writeTimeout))
+	if _, err := e.conn.Write(frame); err != nil {
+		e.conn.Close()
+		e.conn = nil
+		return err
+	}
+	e.backoff = e.minBackoff
+	return nil
+}
+
+// dial connects to the collector
+func (e *Exporter) dial() (net.Conn, error) {
+	dialer := &net.Dialer{Timeout: writeTimeout}
+	if e.network == "tls" {
+		return tls.DialWithDialer(dialer, "tcp", e.address, e.tls)
+	}
+	return dialer.Dial(e.network, e.address)
+}
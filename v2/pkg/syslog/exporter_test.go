@@ -0,0 +1,208 @@
+package syslog
+
+import (
+	"bufio"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/pem"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"math/big"
+	"net"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestFormat(t *testing.T) {
+	h := &header{facility: 16, hostname: "scanner", procID: "42"}
+	at := time.Date(2021, 10, 5, 12, 0, 0, 0, time.UTC)
+	data := []byte(`{"template":"cve-2021-41773","severity":"critical","matched":"https://example.com/a]b"}`)
+
+	require.Equal(t, `<130>1 2021-10-05T12:00:00.000000Z scanner nuclei 42 cve-2021-41773 - `+string(data), string(h.format(data, false, at)), "Could not format the message body")
+	require.Equal(t, `<130>1 2021-10-05T12:00:00.000000Z scanner nuclei 42 cve-2021-41773 [nuclei@32473 result="{\"template\":\"cve-2021-41773\",\"severity\":\"critical\",\"matched\":\"https://example.com/a\]b\"}"] [cve-2021-41773] https://example.com/a]b`, string(h.format(data, true, at)), "Could not format the structured data")
+
+	message := string(h.format([]byte(`{"template":"a template with spaces and a very long name","severity":"unknown"}`), false, at))
+	require.True(t, strings.HasPrefix(message, "<134>1 2021-10-05T12:00:00.000000Z scanner nuclei 42 a_template_with_spaces_and_a_ver - "), "Could not map the unknown severity and sanitize the msgid: %s", message)
+}
+
+func TestNew(t *testing.T) {
+	exporter, err := New("tcp://localhost")
+	require.Nil(t, err, "Could not create exporter")
+	require.Equal(t, "localhost:514", exporter.address, "Could not use the default port")
+	exporter.Close()
+
+	for _, rawURL := range []string{"http://localhost:514", "udp://:514", "udp://localhost?format=cef", "udp://localhost?facility=24", "tcp://localhost?cert=client.crt&key=client.key"} {
+		_, err := New(rawURL)
+		require.NotNil(t, err, "Could not reject %s", rawURL)
+	}
+}
+
+// readFrames reads the octet counted messages of a connection
+func readFrames(conn net.Conn, messages chan<- string) {
+	reader := bufio.NewReader(conn)
+	for {
+		length, err := reader.ReadString(' ')
+		if err != nil {
+			return
+		}
+		size, err := strconv.Atoi(strings.TrimSpace(length))
+		if err != nil {
+			return
+		}
+		message := make([]byte, size)
+		if _, err := io.ReadFull(reader, message); err != nil {
+			return
+		}
+		messages <- string(message)
+	}
+}
+
+func TestExporterTCP(t *testing.T) {
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	require.Nil(t, err, "Could not listen")
+	defer listener.Close()
+
+	messages := make(chan string, 10)
+	go func() {
+		// the first connection is broken after the first message
+		conn, err := listener.Accept()
+		if err != nil {
+			return
+		}
+		readFrames(&limitedConn{Conn: conn}, messages)
+		conn.Close()
+		conn, err = listener.Accept()
+		if err != nil {
+			return
+		}
+		defer conn.Close()
+		readFrames(conn, messages)
+	}()
+
+	exporter, err := New("tcp://" + listener.Addr().String())
+	require.Nil(t, err, "Could not create exporter")
+	exporter.Export([]byte(`{"template":"first"}`))
+	require.Contains(t, <-messages, " first - ", "Could not send the first result")
+
+	// the writes after the connection is broken fail and reconnect
+	for i := 0; i < 5; i++ {
+		time.Sleep(20 * time.Millisecond)
+		exporter.Export([]byte(fmt.Sprintf(`{"template":"next-%d"}`, i)))
+	}
+	exporter.Close()
+	select {
+	case message := <-messages:
+		require.Contains(t, message, " next-", "Could not send the results after reconnecting")
+	case <-time.After(time.Second):
+		require.Fail(t, "Could not reconnect to the collector")
+	}
+}
+
+// limitedConn is a connection read by the collector until its first message
+type limitedConn struct {
+	net.Conn
+	read bool
+}
+
+func (c *limitedConn) Read(b []byte) (int, error) {
+	if c.read {
+		return 0, io.EOF
+	}
+	c.read = true
+	return c.Conn.Read(b)
+}
+
+func TestExporterUnreachable(t *testing.T) {
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	require.Nil(t, err, "Could not listen")
+	address := listener.Addr().String()
+	listener.Close()
+
+	exporter, err := New("tcp://" + address)
+	require.Nil(t, err, "Could not create exporter")
+	start := time.Now()
+	for i := 0; i < 20; i++ {
+		exporter.Export([]byte(`{"template":"lost"}`))
+	}
+	exporter.Close()
+	require.True(t, time.Since(start) < 2*time.Second, "Could not give up on the dead collector")
+	require.Equal(t, uint64(20), exporter.Failed()+exporter.Dropped(), "Could not count the results which weren't sent")
+}
+
+func TestExporterUDP(t *testing.T) {
+	conn, err := net.ListenPacket("udp", "127.0.0.1:0")
+	require.Nil(t, err, "Could not listen")
+	defer conn.Close()
+
+	exporter, err := New("udp://" + conn.LocalAddr().String() + "?format=sd&facility=1")
+	require.Nil(t, err, "Could not create exporter")
+	exporter.Export([]byte(`{"template":"udp","severity":"high","matched":"https://example.com"}`))
+	exporter.Close()
+
+	buffer := make([]byte, 4096)
+	conn.SetReadDeadline(time.Now().Add(time.Second))
+	n, _, err := conn.ReadFrom(buffer)
+	require.Nil(t, err, "Could not receive the message")
+	require.True(t, strings.HasPrefix(string(buffer[:n]), "<11>1 "), "Could not set the priority of the message")
+	require.True(t, strings.HasSuffix(string(buffer[:n]), "] [udp] https://example.com"), "Could not write the structured data")
+	require.Equal(t, uint64(1), exporter.Sent(), "Could not count the sent result")
+}
+
+func TestExporterTLS(t *testing.T) {
+	directory, err := ioutil.TempDir("", "syslog-*")
+	require.Nil(t, err, "Could not create temporary directory")
+	defer os.RemoveAll(directory)
+
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	require.Nil(t, err, "Could not generate key")
+	template := &x509.Certificate{
+		SerialNumber:          big.NewInt(1),
+		Subject:               pkix.Name{CommonName: "collector"},
+		IPAddresses:           []net.IP{net.ParseIP("127.0.0.1")},
+		NotBefore:             time.Now().Add(-time.Hour),
+		NotAfter:              time.Now().Add(time.Hour),
+		IsCA:                  true,
+		BasicConstraintsValid: true,
+		KeyUsage:              x509.KeyUsageCertSign | x509.KeyUsageDigitalSignature,
+		ExtKeyUsage:           []x509.ExtKeyUsage{x509.ExtKeyUsageServerAuth},
+	}
+	der, err := x509.CreateCertificate(rand.Reader, template, template, &key.PublicKey, key)
+	require.Nil(t, err, "Could not create certificate")
+	ca := filepath.Join(directory, "ca.pem")
+	require.Nil(t, ioutil.WriteFile(ca, pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: der}), 0644), "Could not write certificate")
+
+	listener, err := tls.Listen("tcp", "127.0.0.1:0", &tls.Config{Certificates: []tls.Certificate{{Certificate: [][]byte{der}, PrivateKey: key}}})
+	require.Nil(t, err, "Could not listen")
+	defer listener.Close()
+	messages := make(chan string, 1)
+	go func() {
+		conn, err := listener.Accept()
+		if err != nil {
+			return
+		}
+		defer conn.Close()
+		readFrames(conn, messages)
+	}()
+
+	exporter, err := New("tls://" + listener.Addr().String() + "?ca=" + ca)
+	require.Nil(t, err, "Could not create exporter")
+	exporter.Export([]byte(`{"template":"tls"}`))
+	select {
+	case message := <-messages:
+		require.Contains(t, message, " tls - ", "Could not send the result over tls")
+	case <-time.After(5 * time.Second):
+		require.Fail(t, "Could not send the result over tls")
+	}
+	exporter.Close()
+}
@@ -0,0 +1,80 @@
+package syslog
+
+import (
+	"fmt"
+	"strings"
+	"time"
+
+	jsoniter "github.com/json-iterator/go"
+)
+
+// sdID is the id of the structured data of the messages, using the private
+// enterprise number reserved for the documentation.
+const sdID = "nuclei@32473"
+
+// severities are the syslog severities of the severities of the templates
+var severities = map[string]int{
+	"critical": 2,
+	"high":     3,
+	"medium":   4,
+	"low":      5,
+	"info":     6,
+}
+
+// result is the part of a json result written to the messages
+type result struct {
+	Template string `json:"template"`
+	Severity string `json:"severity"`
+	Matched  string `json:"matched"`
+}
+
+// header is the part of the messages common to the results
+type header struct {
+	facility int
+	hostname string
+	procID   string
+}
+
+// format returns the RFC5424 message of a json result, the result being the
+// structured data of the message or its body.
+func (h *header) format(data []byte, structuredData bool, t time.Time) []byte {
+	r := &result{}
+	jsoniter.Unmarshal(data, r)
+	severity, ok := severities[strings.ToLower(r.Severity)]
+	if !ok {
+		severity = severities["info"]
+	}
+
+	builder := &strings.Builder{}
+	fmt.Fprintf(builder, "<%d>1 %s %s nuclei %s %s ", h.facility*8+severity, t.UTC().Format("2006-01-02T15:04:05.000000Z07:00"), h.hostname, h.procID, headerField(r.Template, 32))
+	if structuredData {
+		fmt.Fprintf(builder, "[%s result=\"%s\"] [%s] %s", sdID, escapeParam(string(data)), r.Template, r.Matched)
+	} else {
+		builder.WriteString("- ")
+		builder.Write(data)
+	}
+	return []byte(builder.String())
+}
+
+// headerField returns a value as a header field of at most a length, the
+// characters other than the printable ascii ones being replaced.
+func headerField(value string, length int) string {
+	if value == "" {
+		return "-"
+	}
+	field := []byte(value)
+	for i, c := range field {
+		if c < 33 || c > 126 {
+			field[i] = '_'
+		}
+	}
+	if len(field) > length {
+		field = field[:length]
+	}
+	return string(field)
+}
+
+// escapeParam escapes the characters of a structured data parameter value
+func escapeParam(value string) string {
+	return strings.NewReplacer(`\`, `\\`, `"`, `\"`, `]`, `\]`).Replace(value)
+}
@@ -0,0 +1,6 @@
+// Package techdetect detects the technologies of the hosts from the
+// headers, the cookies and the body of their baseline responses, with
+// signatures in the style of Wappalyzer, and maps them to the tags of the
+// templates, filtering out the templates of the technologies a host does
+// not run.
+package techdetect
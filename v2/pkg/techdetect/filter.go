@@ -0,0 +1,85 @@
+package techdetect
+
+import (
+	"fmt"
+	"strings"
+)
+
+// Mode is how the templates are filtered by the technologies detected
+type Mode int
+
+const (
+	// Off runs all the templates
+	Off Mode = iota
+	// Loose runs the templates of the technologies of a host detected, and
+	// all of them on the hosts where nothing was detected.
+	Loose
+	// Strict runs the templates of the technologies of a host detected
+	// only, even on the hosts where nothing was detected.
+	Strict
+)
+
+// ParseMode returns the mode of a name, off, loose or strict
+func ParseMode(name string) (Mode, error) {
+	switch strings.ToLower(strings.TrimSpace(name)) {
+	case "", "off":
+		return Off, nil
+	case "loose":
+		return Loose, nil
+	case "strict":
+		return Strict, nil
+	}
+	return Off, fmt.Errorf("invalid tech detect mode %s, it should be loose, strict or off", name)
+}
+
+// Filter selects the templates to run on a host by the technologies
+// detected on it. The templates without a tag of the technologies of the
+// mapping, i.e the untagged ones, and the ones with a tag always run are
+// run on all the hosts.
+type Filter struct {
+	mapping *Mapping
+	mode    Mode
+}
+
+// NewFilter creates the filter of a mapping, nil if the mode is off
+func NewFilter(mapping *Mapping, mode Mode) *Filter {
+	if mode == Off {
+		return nil
+	}
+	return &Filter{mapping: mapping, mode: mode}
+}
+
+// Filters returns true if a template with the lowercased tags is filtered
+// by the technologies detected, it being run on all the hosts otherwise.
+func (f *Filter) Filters(tags []string) bool {
+	if f == nil {
+		return false
+	}
+	filtered := false
+	for _, tag := range tags {
+		if _, ok := f.mapping.alwaysRun[tag]; ok {
+			return false
+		}
+		if _, ok := f.mapping.tags[tag]; ok {
+			filtered = true
+		}
+	}
+	return filtered
+}
+
+// Runs returns true if a template with the lowercased tags runs on a host
+// with the technologies detected.
+func (f *Filter) Runs(tags []string, detection *Detection) bool {
+	if !f.Filters(tags) {
+		return true
+	}
+	if detection.Unsure() {
+		return f.mode == Loose
+	}
+	for _, tag := range tags {
+		if _, ok := detection.tags[tag]; ok {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,44 @@
+package techdetect
+
+import (
+	"net/http"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestParseMode(t *testing.T) {
+	for name, expected := range map[string]Mode{"": Off, "off": Off, "Loose": Loose, "strict": Strict} {
+		mode, err := ParseMode(name)
+		require.Nil(t, err, "Could not parse mode %s", name)
+		require.Equal(t, expected, mode, "Could not parse mode %s", name)
+	}
+	_, err := ParseMode("fuzzy")
+	require.NotNil(t, err, "Could not reject invalid mode")
+	require.Nil(t, NewFilter(DefaultMapping(), Off), "Could not disable the filter")
+}
+
+func TestFilter(t *testing.T) {
+	mapping := DefaultMapping()
+	header := make(http.Header)
+	header.Set("X-Jenkins", "2.303")
+	jenkins := mapping.Detect([]*Response{{Header: header}})
+	nothing := mapping.Detect([]*Response{{Header: make(http.Header)}})
+
+	for _, mode := range []Mode{Loose, Strict} {
+		filter := NewFilter(mapping, mode)
+		require.True(t, filter.Runs(nil, jenkins), "Could not run the untagged templates")
+		require.True(t, filter.Runs([]string{"cve", "rce"}, jenkins), "Could not run the templates of no technology")
+		require.True(t, filter.Runs([]string{"wordpress", "tech"}, jenkins), "Could not run the templates always run")
+		require.True(t, filter.Runs([]string{"cve", "jenkins"}, jenkins), "Could not run the templates of the technologies detected")
+		require.False(t, filter.Runs([]string{"cve", "wordpress"}, jenkins), "Could not skip the templates of the technologies not detected")
+		require.True(t, filter.Runs([]string{"php"}, nothing), "Could not ignore the tags of the reported technologies")
+	}
+	require.True(t, NewFilter(mapping, Loose).Runs([]string{"wordpress"}, nothing), "Could not run the templates when unsure in loose mode")
+	require.True(t, NewFilter(mapping, Loose).Runs([]string{"wordpress"}, nil), "Could not run the templates without baseline in loose mode")
+	require.False(t, NewFilter(mapping, Strict).Runs([]string{"wordpress"}, nothing), "Could not skip the templates when unsure in strict mode")
+	require.False(t, NewFilter(mapping, Strict).Runs([]string{"wordpress"}, nil), "Could not skip the templates without baseline in strict mode")
+
+	var filter *Filter
+	require.False(t, filter.Filters([]string{"wordpress"}), "Could not filter nothing without a filter")
+}
@@ -0,0 +1,287 @@
+package techdetect
+
+import (
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"regexp"
+	"sort"
+	"strings"
+
+	"gopkg.in/yaml.v2"
+)
+
+// Technology is a technology detected by its signatures, any of them
+// matching a baseline response detecting it. The signatures are case
+// insensitive regexes.
+type Technology struct {
+	// Name is the name of the technology, i.e WordPress
+	Name string `yaml:"name"`
+	// Tags are the tags of the templates of the technology, its lowercased
+	// name if not set. An empty list only reports the technology, as for
+	// the ones too often hidden to filter the templates by.
+	Tags []string `yaml:"tags"`
+	// Headers are the regexes of the values of the headers by name, an
+	// empty regex matching any value.
+	Headers map[string]string `yaml:"headers,omitempty"`
+	// Cookies are the regexes of the values of the cookies by regex of
+	// their name, an empty regex matching any value.
+	Cookies map[string]string `yaml:"cookies,omitempty"`
+	// Body are the regexes of the body
+	Body []string `yaml:"body,omitempty"`
+	// Implies are the names of the technologies detected along with it,
+	// i.e PHP for WordPress.
+	Implies []string `yaml:"implies,omitempty"`
+
+	headers []*pairRegex
+	cookies []*pairRegex
+	body    []*regexp.Regexp
+}
+
+// pairRegex matches a name and a value, a nil regex matching any
+type pairRegex struct {
+	name   string
+	names  *regexp.Regexp
+	values *regexp.Regexp
+}
+
+// Mapping are the technologies detected and the tags of their templates
+type Mapping struct {
+	// Defaults adds the built-in technologies missing from the mapping,
+	// true by default, the technologies of the mapping replacing the
+	// built-in ones of the same name.
+	Defaults *bool `yaml:"defaults,omitempty"`
+	// Technologies are the technologies detected
+	Technologies []*Technology `yaml:"technologies,omitempty"`
+	// AlwaysRun are the tags of the templates always run whatever the
+	// technologies detected, tech and generic by default.
+	AlwaysRun []string `yaml:"always-run,omitempty"`
+
+	// tags are the tags of the technologies and alwaysRun the tags of the
+	// templates always run.
+	tags      map[string]struct{}
+	alwaysRun map[string]struct{}
+	byName    map[string]*Technology
+}
+
+// defaultAlwaysRun are the tags of the templates always run by default,
+// the detection templates and the ones of any stack.
+var defaultAlwaysRun = []string{"tech", "generic"}
+
+// DefaultMapping returns the mapping of the built-in technologies
+func DefaultMapping() *Mapping {
+	mapping := &Mapping{}
+	if err := mapping.compile(); err != nil {
+		panic(err)
+	}
+	return mapping
+}
+
+// LoadMapping loads a mapping from a yaml file, along with the built-in
+// technologies unless disabled.
+func LoadMapping(file string) (*Mapping, error) {
+	data, err := ioutil.ReadFile(file)
+	if err != nil {
+		return nil, fmt.Errorf("could not read tech mapping: %s", err)
+	}
+	mapping := &Mapping{}
+	if err := yaml.UnmarshalStrict(data, mapping); err != nil {
+		return nil, fmt.Errorf("could not parse tech mapping %s: %s", file, err)
+	}
+	if err := mapping.compile(); err != nil {
+		return nil, fmt.Errorf("invalid tech mapping %s: %s", file, err)
+	}
+	return mapping, nil
+}
+
+// compile merges the built-in technologies into the mapping and compiles
+// the signatures of the technologies.
+func (m *Mapping) compile() error {
+	m.byName = make(map[string]*Technology)
+	for _, technology := range m.Technologies {
+		if technology == nil || strings.TrimSpace(technology.Name) == "" {
+			return errors.New("technology without a name")
+		}
+		key := strings.ToLower(strings.TrimSpace(technology.Name))
+		if _, ok := m.byName[key]; ok {
+			return fmt.Errorf("duplicate technology %s", technology.Name)
+		}
+		m.byName[key] = technology
+	}
+	if m.Defaults == nil || *m.Defaults {
+		for _, technology := range builtinTechnologies() {
+			key := strings.ToLower(technology.Name)
+			if _, ok := m.byName[key]; !ok {
+				m.byName[key] = technology
+				m.Technologies = append(m.Technologies, technology)
+			}
+		}
+	}
+	if len(m.Technologies) == 0 {
+		return errors.New("no technologies")
+	}
+
+	m.tags = make(map[string]struct{})
+	for _, technology := range m.Technologies {
+		if err := technology.compile(); err != nil {
+			return fmt.Errorf("could not compile technology %s: %s", technology.Name, err)
+		}
+		for _, implied := range technology.Implies {
+			if _, ok := m.byName[strings.ToLower(strings.TrimSpace(implied))]; !ok {
+				return fmt.Errorf("technology %s implies unknown technology %s", technology.Name, implied)
+			}
+		}
+		for _, tag := range technology.Tags {
+			m.tags[tag] = struct{}{}
+		}
+	}
+
+	if m.AlwaysRun == nil {
+		m.AlwaysRun = defaultAlwaysRun
+	}
+	m.alwaysRun = make(map[string]struct{}, len(m.AlwaysRun))
+	for _, tag := range m.AlwaysRun {
+		if tag = strings.ToLower(strings.TrimSpace(tag)); tag != "" {
+			m.alwaysRun[tag] = struct{}{}
+		}
+	}
+	return nil
+}
+
+// compile compiles the signatures of a technology, lowercasing its tags
+func (t *Technology) compile() error {
+	if t.Tags == nil {
+		t.Tags = []string{strings.ToLower(strings.TrimSpace(t.Name))}
+	}
+	tags := make([]string, 0, len(t.Tags))
+	for _, tag := range t.Tags {
+		if tag = strings.ToLower(strings.TrimSpace(tag)); tag != "" {
+			tags = append(tags, tag)
+		}
+	}
+	t.Tags = tags
+	if len(t.Headers)+len(t.Cookies)+len(t.Body) == 0 {
+		return errors.New("no signatures")
+	}
+
+	var err error
+	t.headers, t.cookies, t.body = nil, nil, nil
+	for name, value := range t.Headers {
+		pair := &pairRegex{name: http.CanonicalHeaderKey(strings.TrimSpace(name))}
+		if pair.values, err = compileRegex(value); err != nil {
+			return err
+		}
+		t.headers = append(t.headers, pair)
+	}
+	for name, value := range t.Cookies {
+		pair := &pairRegex{}
+		if pair.names, err = compileRegex(name); err != nil {
+			return err
+		}
+		if pair.values, err = compileRegex(value); err != nil {
+			return err
+		}
+		t.cookies = append(t.cookies, pair)
+	}
+	for _, value := range t.Body {
+		if value == "" {
+			return errors.New("empty body regex")
+		}
+		compiled, err := compileRegex(value)
+		if err != nil {
+			return err
+		}
+		t.body = append(t.body, compiled)
+	}
+	return nil
+}
+
+// compileRegex compiles a case insensitive regex, nil if empty
+func compileRegex(value string) (*regexp.Regexp, error) {
+	if value == "" {
+		return nil, nil
+	}
+	return regexp.Compile("(?i)" + value)
+}
+
+// Response is a baseline response of a host
+type Response struct {
+	Header http.Header
+	Body   string
+}
+
+// Detection are the technologies detected on a host. A nil detection is
+// the one of a host without a baseline response.
+type Detection struct {
+	// Technologies are the names of the technologies, sorted
+	Technologies []string
+
+	tags map[string]struct{}
+}
+
+// Unsure returns true if nothing was detected on a host, which may hide
+// its technologies or have no baseline response.
+func (d *Detection) Unsure() bool {
+	return d == nil || len(d.Technologies) == 0
+}
+
+// Detect returns the technologies of a host detected by the signatures
+// from its baseline responses, nil without any.
+func (m *Mapping) Detect(responses []*Response) *Detection {
+	if len(responses) == 0 {
+		return nil
+	}
+	detection := &Detection{tags: make(map[string]struct{})}
+	detected := make(map[string]struct{})
+	var add func(technology *Technology)
+	add = func(technology *Technology) {
+		if _, ok := detected[technology.Name]; ok {
+			return
+		}
+		detected[technology.Name] = struct{}{}
+		detection.Technologies = append(detection.Technologies, technology.Name)
+		for _, tag := range technology.Tags {
+			detection.tags[tag] = struct{}{}
+		}
+		for _, implied := range technology.Implies {
+			add(m.byName[strings.ToLower(strings.TrimSpace(implied))])
+		}
+	}
+	for _, technology := range m.Technologies {
+		for _, response := range responses {
+			if technology.matches(response) {
+				add(technology)
+				break
+			}
+		}
+	}
+	sort.Strings(detection.Technologies)
+	return detection
+}
+
+// matches returns true if a signature of the technology matches a response
+func (t *Technology) matches(response *Response) bool {
+	for _, header := range t.headers {
+		for _, value := range response.Header.Values(header.name) {
+			if header.values == nil || header.values.MatchString(value) {
+				return true
+			}
+		}
+	}
+	if len(t.cookies) > 0 {
+		for _, cookie := range (&http.Response{Header: response.Header}).Cookies() {
+			for _, pair := range t.cookies {
+				if (pair.names == nil || pair.names.MatchString(cookie.Name)) && (pair.values == nil || pair.values.MatchString(cookie.Value)) {
+					return true
+				}
+			}
+		}
+	}
+	for _, body := range t.body {
+		if body.MatchString(response.Body) {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,71 @@
+package techdetect
+
+import (
+	"io/ioutil"
+	"net/http"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func writeMapping(t *testing.T, directory, content string) string {
+	file := filepath.Join(directory, "tech.yaml")
+	require.Nil(t, ioutil.WriteFile(file, []byte(content), 0644), "Could not write mapping")
+	return file
+}
+
+func TestDetect(t *testing.T) {
+	mapping := DefaultMapping()
+	require.Nil(t, mapping.Detect(nil), "Could not return no detection without responses")
+
+	header := make(http.Header)
+	header.Set("Server", "nginx/1.18.0")
+	header.Add("Set-Cookie", "wordpress_test_cookie=WP+Cookie+check; path=/")
+	detection := mapping.Detect([]*Response{{Header: header}, {Header: make(http.Header), Body: `<link rel="stylesheet" href="/wp-content/themes/twentytwenty/style.css">`}})
+	require.Equal(t, []string{"Nginx", "PHP", "WordPress"}, detection.Technologies, "Could not detect the technologies and the implied ones")
+	require.False(t, detection.Unsure(), "Could not be sure of the detection")
+
+	detection = mapping.Detect([]*Response{{Header: make(http.Header), Body: "<html>hello</html>"}})
+	require.Empty(t, detection.Technologies, "Could not detect nothing")
+	require.True(t, detection.Unsure(), "Could not be unsure of an empty detection")
+}
+
+func TestLoadMapping(t *testing.T) {
+	directory, err := ioutil.TempDir("", "techdetect-*")
+	require.Nil(t, err, "Could not create temporary directory")
+	defer os.RemoveAll(directory)
+
+	mapping, err := LoadMapping(writeMapping(t, directory, "technologies:\n  - name: Acme CMS\n    headers:\n      X-Generator: '^acme'\n  - name: WordPress\n    tags: [wp]\n    body: ['wp-json']\n  - name: Internal\n    tags: []\n    cookies:\n      '^internal_': ''\nalways-run: [cve]\n"))
+	require.Nil(t, err, "Could not load mapping")
+	require.Equal(t, map[string]struct{}{"cve": {}}, mapping.alwaysRun, "Could not set the tags always run")
+	require.Equal(t, []string{"acme cms"}, mapping.byName["acme cms"].Tags, "Could not default the tags to the name")
+	require.Empty(t, mapping.byName["internal"].Tags, "Could not keep the technology only reported")
+	require.Equal(t, []string{"wp"}, mapping.byName["wordpress"].Tags, "Could not replace the built-in technology")
+	require.NotNil(t, mapping.byName["drupal"], "Could not add the built-in technologies")
+
+	header := make(http.Header)
+	header.Set("X-Generator", "ACME 2.1")
+	header.Add("Set-Cookie", "internal_id=1")
+	detection := mapping.Detect([]*Response{{Header: header, Body: "/wp-content/"}})
+	require.Equal(t, []string{"Acme CMS", "Internal"}, detection.Technologies, "Could not detect with the signatures of the mapping")
+
+	mapping, err = LoadMapping(writeMapping(t, directory, "defaults: false\ntechnologies:\n  - name: Acme CMS\n    body: ['acme']\n"))
+	require.Nil(t, err, "Could not load mapping")
+	require.Len(t, mapping.Technologies, 1, "Could not disable the built-in technologies")
+	require.Equal(t, defaultAlwaysRun, mapping.AlwaysRun, "Could not set the default tags always run")
+
+	for _, invalid := range []string{
+		"defaults: false\n",
+		"technologies:\n  - tags: [acme]\n    body: ['acme']\n",
+		"technologies:\n  - name: Acme\n",
+		"technologies:\n  - name: Acme\n    body: ['(acme']\n",
+		"technologies:\n  - name: Acme\n    body: ['acme']\n    implies: [Unknown]\n",
+		"technologies:\n  - name: Acme\n    body: ['acme']\n  - name: acme\n    body: ['acme']\n",
+		"technologies:\n  - name: Acme\n    regex: ['acme']\n",
+	} {
+		_, err = LoadMapping(writeMapping(t, directory, invalid))
+		require.NotNil(t, err, "Could not reject invalid mapping %q", invalid)
+	}
+}
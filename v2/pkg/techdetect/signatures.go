@@ -0,0 +1,155 @@
+package techdetect
+
+// builtinTechnologies returns the built-in technologies. Their tags are the
+// ones of the templates of the product only, the tags shared by several
+// products such as apache or atlassian filtering out the templates of the
+// products which aren't detected. The languages are only reported, being
+// too often hidden by the hosts.
+func builtinTechnologies() []*Technology {
+	return []*Technology{
+		{
+			Name:    "WordPress",
+			Tags:    []string{"wordpress", "wp-plugin", "wp-theme"},
+			Headers: map[string]string{"Link": `rel="https://api\.w\.org/"`, "X-Pingback": `/xmlrpc\.php`},
+			Cookies: map[string]string{`^wordpress_`: "", `^wp-settings-`: ""},
+			Body:    []string{`/wp-(?:content|includes)/`, `<meta name="generator" content="WordPress`},
+			Implies: []string{"PHP"},
+		},
+		{
+			Name:    "Drupal",
+			Headers: map[string]string{"X-Generator": `Drupal`, "X-Drupal-Cache": "", "X-Drupal-Dynamic-Cache": ""},
+			Body:    []string{`Drupal\.settings`, `/sites/(?:default|all)/(?:themes|modules)/`, `<meta name="generator" content="Drupal`},
+			Implies: []string{"PHP"},
+		},
+		{
+			Name:    "Joomla",
+			Headers: map[string]string{"X-Content-Encoded-By": `Joomla`},
+			Body:    []string{`<meta name="generator" content="Joomla`, `/media/jui/`},
+			Implies: []string{"PHP"},
+		},
+		{
+			Name:    "Magento",
+			Headers: map[string]string{"X-Magento-Cache-Debug": "", "X-Magento-Tags": ""},
+			Body:    []string{`Mage\.Cookies`, `/static/version\d+/frontend/`, `/skin/frontend/`},
+			Implies: []string{"PHP"},
+		},
+		{
+			Name:    "phpMyAdmin",
+			Cookies: map[string]string{`^phpMyAdmin$`: "", `^pma_lang$`: ""},
+			Body:    []string{`<title>phpMyAdmin`, `pma_navigation`},
+			Implies: []string{"PHP"},
+		},
+		{
+			Name:    "Laravel",
+			Cookies: map[string]string{`^laravel_session$`: ""},
+			Implies: []string{"PHP"},
+		},
+		{
+			Name:    "Jenkins",
+			Headers: map[string]string{"X-Jenkins": "", "X-Hudson": ""},
+			Body:    []string{`<title>[^<]*Jenkins`},
+			Implies: []string{"Java"},
+		},
+		{
+			Name:    "GitLab",
+			Cookies: map[string]string{`^_gitlab_session$`: ""},
+			Body:    []string{`<meta content="GitLab"`, `gon\.gitlab_url`},
+		},
+		{
+			Name: "Grafana",
+			Body: []string{`window\.grafanaBootData`, `<title>Grafana</title>`},
+		},
+		{
+			Name:    "Kibana",
+			Headers: map[string]string{"Kbn-Name": "", "Kbn-Version": ""},
+			Body:    []string{`kbn-injected-metadata`},
+		},
+		{
+			Name:    "Confluence",
+			Headers: map[string]string{"X-Confluence-Request-Time": ""},
+			Body:    []string{`com-atlassian-confluence`, `ajs-confluence-base-url`},
+			Implies: []string{"Java"},
+		},
+		{
+			Name:    "Jira",
+			Body:    []string{`ajs-jira-base-url`, `jira\.webresources`},
+			Implies: []string{"Java"},
+		},
+		{
+			Name:    "Tomcat",
+			Headers: map[string]string{"Server": `Apache-Coyote`},
+			Body:    []string{`<title>Apache Tomcat`},
+			Implies: []string{"Java"},
+		},
+		{
+			Name:    "JBoss",
+			Tags:    []string{"jboss", "wildfly"},
+			Headers: map[string]string{"X-Powered-By": `JBoss|WildFly|Undertow`},
+			Implies: []string{"Java"},
+		},
+		{
+			Name:    "WebLogic",
+			Body:    []string{`Oracle WebLogic Server`, `WebLogic Server Administration Console`},
+			Implies: []string{"Java"},
+		},
+		{
+			Name:    "Spring Boot",
+			Tags:    []string{"springboot", "spring"},
+			Headers: map[string]string{"X-Application-Context": ""},
+			Body:    []string{`Whitelabel Error Page`},
+			Implies: []string{"Java"},
+		},
+		{
+			Name:    "SharePoint",
+			Headers: map[string]string{"MicrosoftSharePointTeamServices": "", "SPRequestGuid": ""},
+			Implies: []string{"ASP.NET"},
+		},
+		{
+			Name:    "IIS",
+			Headers: map[string]string{"Server": `Microsoft-IIS`},
+		},
+		{
+			Name:    "Nginx",
+			Headers: map[string]string{"Server": `^nginx`},
+		},
+		{
+			Name:    "Express",
+			Headers: map[string]string{"X-Powered-By": `^Express$`},
+			Implies: []string{"Node.js"},
+		},
+		{
+			Name:    "Django",
+			Body:    []string{`name=['"]csrfmiddlewaretoken['"]`},
+			Implies: []string{"Python"},
+		},
+		{
+			Name:    "ASP.NET",
+			Tags:    []string{},
+			Headers: map[string]string{"X-AspNet-Version": "", "X-Powered-By": `ASP\.NET`},
+			Cookies: map[string]string{`^ASP\.NET_SessionId$`: ""},
+			Body:    []string{`name="__VIEWSTATE"`},
+		},
+		{
+			Name:    "PHP",
+			Tags:    []string{},
+			Headers: map[string]string{"X-Powered-By": `PHP`},
+			Cookies: map[string]string{`^PHPSESSID$`: ""},
+		},
+		{
+			Name:    "Java",
+			Tags:    []string{},
+			Headers: map[string]string{"X-Powered-By": `Servlet|JSP`},
+			Cookies: map[string]string{`^JSESSIONID$`: ""},
+		},
+		{
+			Name:    "Python",
+			Tags:    []string{},
+			Headers: map[string]string{"Server": `Python|gunicorn|Werkzeug`},
+		},
+		{
+			Name:    "Node.js",
+			Tags:    []string{},
+			Headers: map[string]string{"X-Powered-By": `Next\.js|Nuxt`},
+		},
+	}
+}
@@ -0,0 +1,66 @@
+package templates
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+)
+
+var (
+	// cveRegex matches the cve ids of the classifications, i.e CVE-2020-5902
+	cveRegex = regexp.MustCompile(`^CVE-\d{4}-\d{4,}$`)
+	// cweRegex matches the cwe ids of the classifications, i.e CWE-79
+	cweRegex = regexp.MustCompile(`^CWE-\d+$`)
+	// cveIDRegex matches the template ids named after a cve
+	cveIDRegex = regexp.MustCompile(`^cve-\d{4}-\d{4,}$`)
+)
+
+// Classification contains the identifiers and the score of the
+// vulnerability detected by a template, written along with its results.
+type Classification struct {
+	// CVEID contains the CVE ids of the vulnerability, i.e CVE-2020-5902
+	CVEID []string `yaml:"cve-id,omitempty" json:"cve-id,omitempty"`
+	// CWEID contains the CWE ids of the weakness, i.e CWE-22
+	CWEID []string `yaml:"cwe-id,omitempty" json:"cwe-id,omitempty"`
+	// CVSSMetrics is the CVSS vector of the vulnerability
+	CVSSMetrics string `yaml:"cvss-metrics,omitempty" json:"cvss-metrics,omitempty"`
+	// CVSSScore is the CVSS score of the vulnerability, from 0 to 10
+	CVSSScore float64 `yaml:"cvss-score,omitempty" json:"cvss-score,omitempty"`
+}
+
+// validate returns an error for the malformed ids and the scores out of range
+func (c *Classification) validate() error {
+	for _, id := range c.CVEID {
+		if !cveRegex.MatchString(id) {
+			return fmt.Errorf("classification: invalid cve-id %s, cve ids are like CVE-2020-5902", id)
+		}
+	}
+	for _, id := range c.CWEID {
+		if !cweRegex.MatchString(id) {
+			return fmt.Errorf("classification: invalid cwe-id %s, cwe ids are like CWE-79", id)
+		}
+	}
+	if c.CVSSScore < 0 || c.CVSSScore > 10 {
+		return fmt.Errorf("classification: cvss-score %v is not between 0 and 10", c.CVSSScore)
+	}
+	return nil
+}
+
+// lintClassification returns the problem of a template whose id is a cve
+// without the cve in the ids of its classification.
+func (t *Template) lintClassification() []error {
+	if !cveIDRegex.MatchString(t.ID) {
+		return nil
+	}
+	cve := strings.ToUpper(t.ID)
+	classification := t.Info.Classification
+	if classification == nil || len(classification.CVEID) == 0 {
+		return []error{fmt.Errorf("id %s looks like a cve but the template has no classification cve-id", t.ID)}
+	}
+	for _, id := range classification.CVEID {
+		if id == cve {
+			return nil
+		}
+	}
+	return []error{fmt.Errorf("id %s looks like a cve but the classification cve-id is %s", t.ID, strings.Join(classification.CVEID, ","))}
+}
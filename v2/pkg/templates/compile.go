@@ -1,112 +1,444 @@
 package templates
 
 import (
+	"bytes"
 	"errors"
 	"fmt"
-	"os"
+	"io/ioutil"
 	"strings"
 
+	"github.com/projectdiscovery/nuclei/v2/pkg/extractors"
 	"github.com/projectdiscovery/nuclei/v2/pkg/generators"
 	"github.com/projectdiscovery/nuclei/v2/pkg/matchers"
+	"github.com/projectdiscovery/nuclei/v2/pkg/network"
+	"github.com/projectdiscovery/nuclei/v2/pkg/ssl"
+	"github.com/projectdiscovery/nuclei/v2/pkg/variables"
 	"gopkg.in/yaml.v2"
 )
 
+// strict makes the parsing of the templates fail on unknown fields
+var strict bool
+
+// SetStrict sets whether the parsing of the templates fails on unknown fields,
+// i.e misspelled fields which would otherwise be silently ignored.
+func SetStrict(value bool) {
+	strict = value
+}
+
 // Parse parses a yaml request template file
 func Parse(file string) (*Template, error) {
+	data, err := ioutil.ReadFile(file)
+	if err != nil {
+		return nil, err
+	}
+	if err := CheckSignature(file, data); err != nil {
+		return nil, err
+	}
+	return ParseData(file, data)
+}
+
+// ParseData parses the yaml of a request template generated from a file,
+// i.e the login requests of an auth config, without checking its signature.
+// The imports and the environment variables are resolved from the file.
+func ParseData(file string, data []byte) (*Template, error) {
 	template := &Template{}
 
-	f, err := os.Open(file)
+	data, resolved, err := resolveImports(file, data)
+	if err != nil {
+		return nil, err
+	}
+	var secrets []string
+	if envRegex.Match(data) {
+		resolved = true
+		lookup, err := lookupEnvironment(file)
+		if err != nil {
+			return nil, err
+		}
+		if data, secrets, err = expandEnvironment(file, data, lookup); err != nil {
+			return nil, err
+		}
+	}
+
+	data, deprecated, err := upgradeSyntax(data)
 	if err != nil {
 		return nil, err
 	}
+	if err := checkDeprecations(file, deprecated); err != nil {
+		return nil, err
+	}
+	resolved = resolved || len(deprecated) > 0
 
-	err = yaml.NewDecoder(f).Decode(template)
+	decoder := yaml.NewDecoder(bytes.NewReader(data))
+	decoder.SetStrict(strict)
+	err = decoder.Decode(template)
 	if err != nil {
+		if resolved {
+			return nil, fmt.Errorf("in the resolved template: %s", err)
+		}
 		return nil, err
 	}
-	defer f.Close()
+
+	template.secrets = secrets
+
+	if err = template.compile(); err != nil {
+		return nil, err
+	}
+	return template, nil
+}
+
+// compile validates the template and compiles its requests
+func (t *Template) compile() error {
+	var err error
 
 	// If no requests, and it is also not a workflow, return error.
-	if len(template.BulkRequestsHTTP)+len(template.RequestsDNS) <= 0 {
-		return nil, errors.New("No requests defined")
+	if len(t.BulkRequestsHTTP)+len(t.RequestsDNS)+len(t.RequestsHeadless)+len(t.RequestsNetwork)+len(t.RequestsFile)+len(t.RequestsWebsocket)+len(t.RequestsSSL) <= 0 {
+		return errors.New("No requests defined")
+	}
+	if len(t.RequestsHeadless) > 0 && len(t.BulkRequestsHTTP)+len(t.RequestsDNS) > 0 {
+		return errors.New("headless requests can't be combined with dns or http requests")
+	}
+	if len(t.RequestsNetwork) > 0 && len(t.BulkRequestsHTTP)+len(t.RequestsDNS)+len(t.RequestsHeadless) > 0 {
+		return errors.New("network requests can't be combined with dns, http or headless requests")
+	}
+	if len(t.RequestsFile) > 0 && len(t.BulkRequestsHTTP)+len(t.RequestsDNS)+len(t.RequestsHeadless)+len(t.RequestsNetwork) > 0 {
+		return errors.New("file requests can't be combined with dns, http, headless or network requests")
+	}
+	if len(t.RequestsWebsocket) > 0 && len(t.BulkRequestsHTTP)+len(t.RequestsDNS)+len(t.RequestsHeadless)+len(t.RequestsNetwork)+len(t.RequestsFile) > 0 {
+		return errors.New("websocket requests can't be combined with dns, http, headless, network or file requests")
+	}
+	if len(t.RequestsSSL) > 0 && len(t.BulkRequestsHTTP)+len(t.RequestsDNS)+len(t.RequestsHeadless)+len(t.RequestsNetwork)+len(t.RequestsFile)+len(t.RequestsWebsocket) > 0 {
+		return errors.New("ssl requests can't be combined with dns, http, headless, network, file or websocket requests")
+	}
+
+	switch t.ProtocolsCondition {
+	case "", "or", "and":
+	default:
+		return fmt.Errorf("invalid protocols-condition %s", t.ProtocolsCondition)
+	}
+	if t.ProtocolsCondition != "" && !t.HasMultipleProtocols() {
+		return errors.New("protocols-condition requires both dns and http requests")
+	}
+	if t.Threads < 0 {
+		return fmt.Errorf("invalid threads %d", t.Threads)
+	}
+
+	if t.Info.Classification != nil {
+		if err := t.Info.Classification.validate(); err != nil {
+			return err
+		}
+	}
+
+	// Compile the variables, sorting them by the variables they reference
+	if len(t.Variables) > 0 {
+		t.variables, err = variables.New(t.Variables)
+		if err != nil {
+			return err
+		}
 	}
 
 	// Compile the matchers and the extractors for http requests
-	for _, request := range template.BulkRequestsHTTP {
-		// Get the condition between the matchers
-		condition, ok := matchers.ConditionTypes[request.MatchersCondition]
-		if !ok {
-			request.SetMatchersCondition(matchers.ORCondition)
-		} else {
-			request.SetMatchersCondition(condition)
+	for index, request := range t.BulkRequestsHTTP {
+		if err = httpOperators.compile(index, request, request.MatchersCondition, request.Matchers, request.Extractors); err != nil {
+			return err
+		}
+		for i, matcher := range request.Matchers {
+			if matcher.Type == "similarity" && !request.Baseline {
+				return fmt.Errorf("could not compile matcher %d: similarity matchers require baseline: true", i)
+			}
 		}
 
-		// Set the attack type - used only in raw requests
-		attack, ok := generators.AttackTypes[request.AttackType]
-		if !ok {
-			request.SetAttackType(generators.Sniper)
-		} else {
-			request.SetAttackType(attack)
+		if err = request.CompileRunIf(); err != nil {
+			return err
+		}
+
+		switch request.InternalAbort {
+		case "", "target", "iteration":
+		default:
+			return fmt.Errorf("unknown internal-abort specified: %s", request.InternalAbort)
+		}
+
+		// the iterations would multiply the requests of each payload values
+		if request.IterateAll && len(request.Payloads) > 0 {
+			return fmt.Errorf("request %d: iterate-all can't be used with payloads", index)
+		}
+		if request.MaxIterations < 0 {
+			return fmt.Errorf("request %d: invalid max-iterations %d", index, request.MaxIterations)
+		}
+		if request.Timeout < 0 || request.Retries < 0 {
+			return fmt.Errorf("request %d: invalid timeout %d or retries %d", index, request.Timeout, request.Retries)
 		}
 
+		// Set the attack type - used only in raw requests
+		request.SetAttackType(attackType(request.AttackType))
+
 		// Validate the payloads if any
-		for name, payload := range request.Payloads {
-			switch payload.(type) {
-			case string:
-				v := payload.(string)
-				// check if it's a multiline string list
-				if len(strings.Split(v, "\n")) <= 1 {
-					// check if it's a worldlist file
-					if !generators.FileExists(v) {
-						return nil, fmt.Errorf("The %s file for payload %s does not exist or does not contain enough elements", v, name)
-					}
-				}
-			case []string, []interface{}:
-				if len(payload.([]interface{})) <= 0 {
-					return nil, fmt.Errorf("The payload %s does not contain enough elements", name)
-				}
-			default:
-				return nil, fmt.Errorf("The payload %s has invalid type", name)
+		if err = validatePayloads(request.Payloads); err != nil {
+			return err
+		}
+		for i, rule := range request.Fuzzing {
+			if err = validatePayloads(rule.Payloads); err != nil {
+				return fmt.Errorf("request %d: fuzzing rule %d: %s", index, i, err)
+			}
+			if err = rule.Compile(); err != nil {
+				return fmt.Errorf("request %d: fuzzing rule %d: %s", index, i, err)
+			}
+		}
+		if err = request.ValidateFuzzing(); err != nil {
+			return fmt.Errorf("request %d: %s", index, err)
+		}
+
+		request.InitGenerator()
+	}
+
+	// Compile the steps, the matchers and the extractors for headless requests
+	for index, request := range t.RequestsHeadless {
+		if len(request.Steps) == 0 {
+			return fmt.Errorf("request %d has no steps", index)
+		}
+		for i, step := range request.Steps {
+			if err = step.Validate(); err != nil {
+				return fmt.Errorf("request %d: step %d: %s", index, i, err)
 			}
 		}
+		if request.Timeout < 0 {
+			return fmt.Errorf("request %d: invalid timeout %d", index, request.Timeout)
+		}
+		if err = headlessOperators.compile(index, request, request.MatchersCondition, request.Matchers, request.Extractors); err != nil {
+			return err
+		}
+	}
 
-		for _, matcher := range request.Matchers {
-			if err = matcher.CompileMatchers(); err != nil {
-				return nil, err
+	// Compile the inputs, the matchers and the extractors for network requests
+	for index, request := range t.RequestsNetwork {
+		if len(request.Host) == 0 {
+			return fmt.Errorf("request %d has no host", index)
+		}
+		switch request.Protocol {
+		case "", network.TCPProtocol:
+		case network.UDPProtocol:
+			if request.TLS {
+				return fmt.Errorf("request %d: tls can't be used with udp", index)
 			}
+		default:
+			return fmt.Errorf("request %d: unknown protocol %s (supported: tcp, udp)", index, request.Protocol)
+		}
+		for i, input := range request.Inputs {
+			if err = input.Validate(); err != nil {
+				return fmt.Errorf("request %d: input %d: %s", index, i, err)
+			}
+		}
+		if request.ReadSize < 0 || request.Timeout < 0 {
+			return fmt.Errorf("request %d: invalid read-size %d or timeout %d", index, request.ReadSize, request.Timeout)
 		}
 
-		for _, extractor := range request.Extractors {
-			if err := extractor.CompileExtractors(); err != nil {
-				return nil, err
+		request.SetAttackType(attackType(request.AttackType))
+		if err = validatePayloads(request.Payloads); err != nil {
+			return err
+		}
+		if err = networkOperators.compile(index, request, request.MatchersCondition, request.Matchers, request.Extractors); err != nil {
+			return err
+		}
+		request.InitPayloads()
+	}
+
+	// Compile the matchers and the extractors for file requests
+	for index, request := range t.RequestsFile {
+		if request.MaxSize < 0 {
+			return fmt.Errorf("request %d: invalid max-size %d", index, request.MaxSize)
+		}
+		if err = fileOperators.compile(index, request, request.MatchersCondition, request.Matchers, request.Extractors); err != nil {
+			return err
+		}
+	}
+
+	// Compile the messages, the matchers and the extractors for websocket requests
+	for index, request := range t.RequestsWebsocket {
+		if len(request.Path) == 0 {
+			return fmt.Errorf("request %d has no path", index)
+		}
+		for i, message := range request.Messages {
+			if err = message.Validate(); err != nil {
+				return fmt.Errorf("request %d: message %d: %s", index, i, err)
 			}
 		}
+		if request.ReadDuration < 0 || request.ReadCount < 0 || request.Timeout < 0 {
+			return fmt.Errorf("request %d: invalid read-duration %d, read-count %d or timeout %d", index, request.ReadDuration, request.ReadCount, request.Timeout)
+		}
 
-		request.InitGenerator()
+		request.SetAttackType(attackType(request.AttackType))
+		if err = validatePayloads(request.Payloads); err != nil {
+			return err
+		}
+		if err = websocketOperators.compile(index, request, request.MatchersCondition, request.Matchers, request.Extractors); err != nil {
+			return err
+		}
+		request.InitPayloads()
+	}
+
+	// Compile the options, the matchers and the extractors for ssl requests
+	for index, request := range t.RequestsSSL {
+		if len(request.Host) == 0 {
+			return fmt.Errorf("request %d has no host", index)
+		}
+		options := &ssl.Options{MinVersion: request.MinVersion, MaxVersion: request.MaxVersion, Ciphers: request.Ciphers, StartTLS: request.StartTLS}
+		if err = options.Validate(); err != nil {
+			return fmt.Errorf("request %d: %s", index, err)
+		}
+		if request.Timeout < 0 {
+			return fmt.Errorf("request %d: invalid timeout %d", index, request.Timeout)
+		}
+
+		request.SetAttackType(attackType(request.AttackType))
+		if err = validatePayloads(request.Payloads); err != nil {
+			return err
+		}
+		if err = sslOperators.compile(index, request, request.MatchersCondition, request.Matchers, request.Extractors); err != nil {
+			return err
+		}
+		request.InitPayloads()
 	}
 
 	// Compile the matchers and the extractors for dns requests
-	for _, request := range template.RequestsDNS {
-		// Get the condition between the matchers
-		condition, ok := matchers.ConditionTypes[request.MatchersCondition]
-		if !ok {
-			request.SetMatchersCondition(matchers.ORCondition)
-		} else {
-			request.SetMatchersCondition(condition)
-		}
-
-		for _, matcher := range request.Matchers {
-			if err = matcher.CompileMatchers(); err != nil {
-				return nil, err
-			}
+	for index, request := range t.RequestsDNS {
+		if err = request.ValidateType(); err != nil {
+			return err
+		}
+		if err = dnsOperators.compile(index, request, request.MatchersCondition, request.Matchers, request.Extractors); err != nil {
+			return err
 		}
+	}
 
-		for _, extractor := range request.Extractors {
-			if err := extractor.CompileExtractors(); err != nil {
-				return nil, err
-			}
+	return nil
+}
+
+// operatorRules are the matchers and the extractors supported by the
+// requests of a protocol.
+type operatorRules struct {
+	protocol string
+	// internal allows the internal matchers, only supported by http requests
+	internal bool
+	// similarity allows the similarity matchers, only supported by http
+	// requests, if all the types of matchers are supported.
+	similarity bool
+	// interactsh allows the matchers of the interactions with interactsh
+	interactsh bool
+	// matcherTypes are the types of the matchers supported, all if empty
+	matcherTypes []string
+	// unsupportedExtractors are the types of the extractors not supported
+	unsupportedExtractors []string
+	// bodyOnly only supports the matchers and the extractors of the body
+	bodyOnly bool
+}
+
+var (
+	httpOperators      = &operatorRules{protocol: "http", internal: true, similarity: true, interactsh: true}
+	dnsOperators       = &operatorRules{protocol: "dns", interactsh: true}
+	headlessOperators  = &operatorRules{protocol: "headless", matcherTypes: []string{"word", "regex", "binary", "size", "dsl", "xpath"}}
+	networkOperators   = &operatorRules{protocol: "network", matcherTypes: []string{"word", "regex", "binary", "size", "dsl"}, unsupportedExtractors: []string{"xpath"}}
+	fileOperators      = &operatorRules{protocol: "file", matcherTypes: []string{"word", "regex", "binary", "size", "dsl"}, unsupportedExtractors: []string{"kval"}, bodyOnly: true}
+	websocketOperators = &operatorRules{protocol: "websocket", matcherTypes: []string{"status", "word", "regex", "binary", "size", "dsl", "json"}, unsupportedExtractors: []string{"xpath"}}
+	sslOperators       = &operatorRules{protocol: "ssl", matcherTypes: []string{"word", "regex", "size", "dsl"}, unsupportedExtractors: []string{"json", "xpath"}}
+)
+
+// conditionRequest is a request with a condition between its matchers
+type conditionRequest interface {
+	GetMatchersCondition() matchers.ConditionType
+	SetMatchersCondition(condition matchers.ConditionType)
+}
+
+// compile sets the condition between the matchers of a request and
+// compiles its matchers and extractors, checking the protocol supports
+// them.
+func (rules *operatorRules) compile(index int, request conditionRequest, condition string, requestMatchers []*matchers.Matcher, requestExtractors []*extractors.Extractor) error {
+	// Requests without matchers nor extractors can't have any result
+	if len(requestMatchers) == 0 && len(requestExtractors) == 0 {
+		return fmt.Errorf("request %d has neither matchers nor extractors", index)
+	}
+
+	// Get the condition between the matchers
+	conditionType, ok := matchers.ConditionTypes[condition]
+	if !ok {
+		conditionType = matchers.ORCondition
+	}
+	request.SetMatchersCondition(conditionType)
+
+	for i, matcher := range requestMatchers {
+		if err := matcher.CompileMatchers(); err != nil {
+			return fmt.Errorf("could not compile matcher %d: %s", i, err)
+		}
+		if matcher.Internal && !rules.internal {
+			return fmt.Errorf("could not compile matcher %d: internal matchers are only supported by http requests", i)
+		}
+		if len(rules.matcherTypes) > 0 && !contains(rules.matcherTypes, matcher.Type) {
+			return fmt.Errorf("could not compile matcher %d: %s matchers are not supported by %s requests", i, matcher.Type, rules.protocol)
+		}
+		if matcher.Type == "similarity" && !rules.similarity && len(rules.matcherTypes) == 0 {
+			return fmt.Errorf("could not compile matcher %d: similarity matchers are only supported by http requests", i)
+		}
+		if rules.bodyOnly && matcher.GetPart() != matchers.BodyPart {
+			return fmt.Errorf("could not compile matcher %d: the %s part is not supported by %s requests", i, matcher.Part, rules.protocol)
+		}
+		if matcher.UsesInteractsh() && !rules.interactsh {
+			return fmt.Errorf("could not compile matcher %d: interactsh matchers are only supported by http requests", i)
 		}
 	}
+	if err := matchers.ValidateNegative(requestMatchers, request.GetMatchersCondition()); err != nil {
+		return err
+	}
 
-	return template, nil
+	for i, extractor := range requestExtractors {
+		if err := extractor.CompileExtractors(); err != nil {
+			return fmt.Errorf("could not compile extractor %d: %s", i, err)
+		}
+		if contains(rules.unsupportedExtractors, extractor.Type) {
+			return fmt.Errorf("could not compile extractor %d: %s extractors are not supported by %s requests", i, extractor.Type, rules.protocol)
+		}
+		if rules.bodyOnly && extractor.GetPart() != extractors.BodyPart {
+			return fmt.Errorf("could not compile extractor %d: the %s part is not supported by %s requests", i, extractor.Part, rules.protocol)
+		}
+	}
+	return nil
+}
+
+// attackType returns the attack type of a request, sniper if not specified
+func attackType(name string) generators.Type {
+	if attack, ok := generators.AttackTypes[name]; ok {
+		return attack
+	}
+	return generators.Sniper
+}
+
+// contains returns true if a value is in a list
+func contains(values []string, value string) bool {
+	for _, v := range values {
+		if v == value {
+			return true
+		}
+	}
+	return false
+}
+
+// validatePayloads returns an error if a payload is neither a wordlist file,
+// nor a multiline list of values nor a list of values.
+func validatePayloads(payloads map[string]interface{}) error {
+	for name, payload := range payloads {
+		switch payload.(type) {
+		case string:
+			v := payload.(string)
+			// check if it's a multiline string list
+			if len(strings.Split(v, "\n")) <= 1 {
+				// check if it's a worldlist file
+				if !generators.FileExists(v) {
+					return fmt.Errorf("The %s file for payload %s does not exist or does not contain enough elements", v, name)
+				}
+			}
+		case []string, []interface{}:
+			if len(payload.([]interface{})) <= 0 {
+				return fmt.Errorf("The payload %s does not contain enough elements", name)
+			}
+		default:
+			return fmt.Errorf("The payload %s has invalid type", name)
+		}
+	}
+	return nil
 }
@@ -0,0 +1,113 @@
+package templates
+
+import (
+	"fmt"
+	"os"
+	"regexp"
+	"sync"
+
+	"github.com/projectdiscovery/gologger"
+	"github.com/projectdiscovery/nuclei/v2/pkg/redact"
+	"gopkg.in/yaml.v2"
+)
+
+// envRegex matches the references to the environment variables of the templates
+var envRegex = regexp.MustCompile(`{{\s*env\("([A-Za-z_][A-Za-z0-9_]*)"\)\s*}}`)
+
+var (
+	// allowEnv allows the templates to reference environment variables
+	allowEnv bool
+	// allowMissingEnv replaces the missing environment variables with empty values
+	allowMissingEnv bool
+	// warnedEnv are the missing environment variables of templates already warned about
+	warnedEnv sync.Map
+)
+
+// SetEnvironment sets whether the references to environment variables of
+// the templates, i.e {{env("API_KEY")}}, are expanded when loading them,
+// the templates referencing them failing to load otherwise. The missing
+// variables fail the templates too unless allowMissing is true.
+func SetEnvironment(allow, allowMissing bool) {
+	allowEnv = allow
+	allowMissingEnv = allowMissing
+}
+
+// expandEnvironment replaces the references to the environment variables
+// in the string values of the yaml of a template, returning the values
+// of the variables along with the expanded yaml.
+func expandEnvironment(file string, data []byte, lookup func(name string) (string, bool)) ([]byte, []string, error) {
+	if !envRegex.Match(data) {
+		return data, nil, nil
+	}
+
+	var document yaml.MapSlice
+	if err := yaml.Unmarshal(data, &document); err != nil {
+		return nil, nil, err
+	}
+	var secrets []string
+	var missing error
+	replace := func(value string) string {
+		return envRegex.ReplaceAllStringFunc(value, func(match string) string {
+			name := envRegex.FindStringSubmatch(match)[1]
+			secret, ok := lookup(name)
+			if !ok && missing == nil {
+				missing = fmt.Errorf("%s: environment variable %s is not set", file, name)
+			}
+			if secret != "" {
+				secrets = append(secrets, secret)
+			}
+			return secret
+		})
+	}
+	expanded := replaceStrings(document, replace)
+	if missing != nil {
+		return nil, nil, missing
+	}
+	result, err := yaml.Marshal(expanded)
+	return result, secrets, err
+}
+
+// lookupEnvironment returns the value of an environment variable for a template
+func lookupEnvironment(file string) (func(name string) (string, bool), error) {
+	if !allowEnv {
+		return nil, fmt.Errorf("%s: environment variables are not allowed, use -allow-env-vars to expand them", file)
+	}
+	return func(name string) (string, bool) {
+		value, ok := os.LookupEnv(name)
+		if !ok && allowMissingEnv {
+			if _, warned := warnedEnv.LoadOrStore(file+":"+name, struct{}{}); !warned {
+				gologger.Warningf("Environment variable %s of template %s is not set, using an empty value\n", name, file)
+			}
+			return "", true
+		}
+		return value, ok
+	}, nil
+}
+
+// replaceStrings replaces the string values of a yaml value, leaving the keys as is
+func replaceStrings(value interface{}, replace func(string) string) interface{} {
+	switch v := value.(type) {
+	case string:
+		return replace(v)
+	case yaml.MapSlice:
+		items := make(yaml.MapSlice, 0, len(v))
+		for _, item := range v {
+			items = append(items, yaml.MapItem{Key: item.Key, Value: replaceStrings(item.Value, replace)})
+		}
+		return items
+	case []interface{}:
+		list := make([]interface{}, 0, len(v))
+		for _, item := range v {
+			list = append(list, replaceStrings(item, replace))
+		}
+		return list
+	}
+	return value
+}
+
+// Redact replaces the values of the environment variables expanded in the
+// template, in their raw and json escaped forms, not to write them to the
+// output.
+func (t *Template) Redact(value string) string {
+	return redact.Secrets(value, t.secrets)
+}
@@ -0,0 +1,52 @@
+package templates
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestEnvironment(t *testing.T) {
+	directory := writeFiles(t, map[string]string{"template.yaml": `
+id: env
+info:
+  name: env
+  author: test
+requests:
+  - method: GET
+    path:
+      - "{{BaseURL}}/api"
+    headers:
+      X-Api-Key: '{{env("NUCLEI_TEST_API_KEY")}}'
+    matchers:
+      - type: status
+        status:
+          - 200
+`})
+	defer os.RemoveAll(directory)
+	defer SetEnvironment(false, false)
+	file := filepath.Join(directory, "template.yaml")
+
+	_, err := Parse(file)
+	require.EqualError(t, err, file+": environment variables are not allowed, use -allow-env-vars to expand them", "Could not reject the environment variables")
+
+	SetEnvironment(true, false)
+	_, err = Parse(file)
+	require.EqualError(t, err, file+": environment variable NUCLEI_TEST_API_KEY is not set", "Could not reject the missing variable")
+
+	os.Setenv("NUCLEI_TEST_API_KEY", "s3cr\"t")
+	defer os.Unsetenv("NUCLEI_TEST_API_KEY")
+	template, err := Parse(file)
+	require.Nil(t, err, "Could not parse template with environment variables")
+	require.Equal(t, "s3cr\"t", template.BulkRequestsHTTP[0].Headers["X-Api-Key"], "Could not expand the environment variable")
+	require.Equal(t, `{"key":"[REDACTED:6 bytes]"} [REDACTED:6 bytes]`, template.Redact(`{"key":"s3cr\"t"} s3cr"t`), "Could not redact the environment variable")
+
+	os.Unsetenv("NUCLEI_TEST_API_KEY")
+	SetEnvironment(true, true)
+	template, err = Parse(file)
+	require.Nil(t, err, "Could not parse template with missing environment variables")
+	require.Equal(t, "", template.BulkRequestsHTTP[0].Headers["X-Api-Key"], "Could not use an empty value for the missing variable")
+	require.Empty(t, Validate(file), "Could not validate template with environment variables")
+}
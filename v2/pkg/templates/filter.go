@@ -0,0 +1,71 @@
+package templates
+
+import "strings"
+
+// Filter selects the templates to run by the fields of their info.
+//
+// The values are compared case insensitively and a template is selected
+// if it has one of the values of each include filter and none of the
+// excluded tags, templates missing a filtered field being excluded.
+type Filter struct {
+	severities  map[string]struct{}
+	tags        map[string]struct{}
+	excludeTags map[string]struct{}
+	authors     map[string]struct{}
+}
+
+// NewFilter creates a filter from the comma separated lists of values,
+// returning nil if all the lists are empty.
+func NewFilter(severities, tags, excludeTags, authors string) *Filter {
+	filter := &Filter{
+		severities:  splitList(severities),
+		tags:        splitList(tags),
+		excludeTags: splitList(excludeTags),
+		authors:     splitList(authors),
+	}
+	if len(filter.severities)+len(filter.tags)+len(filter.excludeTags)+len(filter.authors) == 0 {
+		return nil
+	}
+	return filter
+}
+
+// Match returns true if a template is selected, or else the name of the
+// filter the template was filtered out by.
+func (f *Filter) Match(info *Info) (bool, string) {
+	if len(f.severities) > 0 && !containsAny(f.severities, splitList(info.Severity)) {
+		return false, "severity"
+	}
+	if len(f.authors) > 0 && !containsAny(f.authors, splitList(info.Author)) {
+		return false, "author"
+	}
+	tags := splitList(info.Tags)
+	if len(f.tags) > 0 && !containsAny(f.tags, tags) {
+		return false, "tags"
+	}
+	if containsAny(f.excludeTags, tags) {
+		return false, "exclude-tags"
+	}
+	return true, ""
+}
+
+// splitList returns the set of the lowercased values of a comma separated list
+func splitList(list string) map[string]struct{} {
+	values := make(map[string]struct{})
+	for _, value := range strings.Split(list, ",") {
+		value = strings.ToLower(strings.TrimSpace(value))
+		if value != "" {
+			values[value] = struct{}{}
+		}
+	}
+	return values
+}
+
+// containsAny returns true if a set contains any of the values
+func containsAny(set, values map[string]struct{}) bool {
+	for value := range values {
+		if _, ok := set[value]; ok {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,31 @@
+package templates
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func requireFiltered(t *testing.T, filter *Filter, info *Info, expected string) {
+	matched, reason := filter.Match(info)
+	require.Equal(t, expected == "", matched, "Could not get filter result for %+v", info)
+	require.Equal(t, expected, reason, "Could not get filter reason for %+v", info)
+}
+
+func TestFilter(t *testing.T) {
+	require.Nil(t, NewFilter("", " , ", "", ""), "Could not get nil filter for empty lists")
+
+	filter := NewFilter("Critical,high", "cve, RCE", "dos,fuzz", "")
+	requireFiltered(t, filter, &Info{Severity: "HIGH", Tags: "cve,wordpress"}, "")
+	requireFiltered(t, filter, &Info{Severity: "critical", Tags: "rce"}, "")
+	requireFiltered(t, filter, &Info{Severity: "medium", Tags: "cve"}, "severity")
+	requireFiltered(t, filter, &Info{Tags: "cve"}, "severity")
+	requireFiltered(t, filter, &Info{Severity: "high", Tags: "panel"}, "tags")
+	requireFiltered(t, filter, &Info{Severity: "high"}, "tags")
+	requireFiltered(t, filter, &Info{Severity: "high", Tags: "cve,DoS"}, "exclude-tags")
+
+	filter = NewFilter("", "", "intrusive", "pdteam")
+	requireFiltered(t, filter, &Info{Author: "geeknik, PDTeam"}, "")
+	requireFiltered(t, filter, &Info{Author: "geeknik"}, "author")
+	requireFiltered(t, filter, &Info{Author: "pdteam", Tags: "intrusive"}, "exclude-tags")
+}
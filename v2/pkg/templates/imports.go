@@ -0,0 +1,229 @@
+package templates
+
+import (
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"path/filepath"
+	"strings"
+
+	"gopkg.in/yaml.v2"
+)
+
+// maxImportDepth is the maximum depth of the imports of imported files
+const maxImportDepth = 8
+
+// definition is a named block of an imported file
+type definition struct {
+	value interface{}
+	file  string
+}
+
+// resolveImports returns the yaml of a template with the references to the
+// definitions of its imported files replaced by them, or the yaml as is
+// if the template has no imports.
+//
+// The imported files are resolved relative to the importing file, and
+// contain named blocks referenced with "import: name", the blocks of
+// lists being spliced in the lists referencing them and the blocks of
+// mappings merged with the keys of the mappings referencing them:
+//
+//	imports:
+//	  - common/sql-errors.yaml
+//	requests:
+//	  - matchers:
+//	      - import: sql-errors
+func resolveImports(file string, data []byte) ([]byte, bool, error) {
+	var document yaml.MapSlice
+	if err := yaml.Unmarshal(data, &document); err != nil {
+		return nil, false, err
+	}
+	index := -1
+	for i, item := range document {
+		if item.Key == "imports" {
+			index = i
+		}
+	}
+	if index == -1 {
+		return data, false, nil
+	}
+
+	definitions := make(map[string]*definition)
+	if err := loadImports(file, document[index].Value, definitions, []string{file}); err != nil {
+		return nil, true, err
+	}
+	document = append(document[:index:index], document[index+1:]...)
+
+	expanded, err := expand(document, definitions, nil)
+	if err != nil {
+		return nil, true, fmt.Errorf("%s: %s", file, err)
+	}
+	resolved, err := yaml.Marshal(expanded)
+	return resolved, true, err
+}
+
+// loadImports loads the definitions of the files imported by a file, along
+// with the ones of their own imports, the stack being the importing files.
+func loadImports(file string, imports interface{}, definitions map[string]*definition, stack []string) error {
+	list, ok := imports.([]interface{})
+	if !ok {
+		return fmt.Errorf("%s: imports must be a list of files", file)
+	}
+	if len(stack) > maxImportDepth {
+		return fmt.Errorf("%s: imports are nested deeper than %d files", file, maxImportDepth)
+	}
+
+	for _, item := range list {
+		name, ok := item.(string)
+		if !ok || name == "" {
+			return fmt.Errorf("%s: imports must be a list of files", file)
+		}
+		path := name
+		if !filepath.IsAbs(path) {
+			path = filepath.Join(filepath.Dir(file), name)
+		}
+		for _, importing := range stack {
+			if importing == path {
+				return fmt.Errorf("%s: import cycle: %s -> %s", file, strings.Join(stack, " -> "), path)
+			}
+		}
+
+		data, err := ioutil.ReadFile(path)
+		if err != nil {
+			return fmt.Errorf("%s: could not import %s: %s", file, name, err)
+		}
+		if err := CheckSignature(path, data); err != nil {
+			return fmt.Errorf("%s: could not import %s: %s", file, name, err)
+		}
+		var document yaml.MapSlice
+		if err := yaml.Unmarshal(data, &document); err != nil {
+			return fmt.Errorf("%s: could not parse imported file %s: %s", file, path, err)
+		}
+		for _, item := range document {
+			key, _ := item.Key.(string)
+			if key == "imports" {
+				if err := loadImports(path, item.Value, definitions, append(stack[:len(stack):len(stack)], path)); err != nil {
+					return err
+				}
+				continue
+			}
+			// the signature of the imported file isn't a definition
+			if key == "digest" {
+				continue
+			}
+			if previous, ok := definitions[key]; ok {
+				return fmt.Errorf("%s: definition %s is defined by both %s and %s", file, key, previous.file, path)
+			}
+			definitions[key] = &definition{value: item.Value, file: path}
+		}
+	}
+	return nil
+}
+
+// expand replaces the references to the definitions of a value, the
+// expanding names being the definitions whose references are expanded.
+func expand(value interface{}, definitions map[string]*definition, expanding []string) (interface{}, error) {
+	switch v := value.(type) {
+	case yaml.MapSlice:
+		var name string
+		var rest yaml.MapSlice
+		for _, item := range v {
+			if item.Key == "import" {
+				name, _ = item.Value.(string)
+				if name == "" {
+					return nil, errors.New("import must be the name of a definition")
+				}
+				continue
+			}
+			rest = append(rest, item)
+		}
+		if name == "" {
+			return expandItems(v, definitions, expanding)
+		}
+
+		imported, err := resolveDefinition(name, definitions, expanding)
+		if err != nil {
+			return nil, err
+		}
+		if len(rest) == 0 {
+			return imported, nil
+		}
+		// the keys of the referencing mapping take precedence
+		importedItems, ok := imported.(yaml.MapSlice)
+		if !ok {
+			return nil, fmt.Errorf("definition %s of %s is not a mapping", name, definitions[name].file)
+		}
+		items, err := expandItems(rest, definitions, expanding)
+		if err != nil {
+			return nil, err
+		}
+		merged := make(yaml.MapSlice, 0, len(importedItems)+len(items))
+		for _, item := range importedItems {
+			if !hasKey(items, item.Key) {
+				merged = append(merged, item)
+			}
+		}
+		return append(merged, items...), nil
+	case []interface{}:
+		list := make([]interface{}, 0, len(v))
+		for _, item := range v {
+			expanded, err := expand(item, definitions, expanding)
+			if err != nil {
+				return nil, err
+			}
+			// the definitions of lists are spliced in the referencing lists
+			if items, ok := expanded.([]interface{}); ok && isReference(item) {
+				list = append(list, items...)
+				continue
+			}
+			list = append(list, expanded)
+		}
+		return list, nil
+	}
+	return value, nil
+}
+
+func expandItems(items yaml.MapSlice, definitions map[string]*definition, expanding []string) (yaml.MapSlice, error) {
+	expanded := make(yaml.MapSlice, 0, len(items))
+	for _, item := range items {
+		value, err := expand(item.Value, definitions, expanding)
+		if err != nil {
+			return nil, err
+		}
+		expanded = append(expanded, yaml.MapItem{Key: item.Key, Value: value})
+	}
+	return expanded, nil
+}
+
+// resolveDefinition returns the expanded value of a definition
+func resolveDefinition(name string, definitions map[string]*definition, expanding []string) (interface{}, error) {
+	imported, ok := definitions[name]
+	if !ok {
+		return nil, fmt.Errorf("unknown definition %s", name)
+	}
+	for _, current := range expanding {
+		if current == name {
+			return nil, fmt.Errorf("definition %s references itself: %s -> %s", name, strings.Join(expanding, " -> "), name)
+		}
+	}
+	value, err := expand(imported.value, definitions, append(expanding[:len(expanding):len(expanding)], name))
+	if err != nil {
+		return nil, fmt.Errorf("%s: %s", imported.file, err)
+	}
+	return value, nil
+}
+
+// isReference returns true if a value is a mapping only referencing a definition
+func isReference(value interface{}) bool {
+	items, ok := value.(yaml.MapSlice)
+	return ok && len(items) == 1 && items[0].Key == "import"
+}
+
+func hasKey(items yaml.MapSlice, key interface{}) bool {
+	for _, item := range items {
+		if item.Key == key {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,101 @@
+package templates
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func writeFiles(t *testing.T, files map[string]string) string {
+	directory, err := ioutil.TempDir("", "imports-")
+	require.Nil(t, err, "Could not create directory")
+	for name, content := range files {
+		path := filepath.Join(directory, name)
+		require.Nil(t, os.MkdirAll(filepath.Dir(path), os.ModePerm), "Could not create directory")
+		require.Nil(t, ioutil.WriteFile(path, []byte(content), 0644), "Could not write file")
+	}
+	return directory
+}
+
+const importingTemplate = `
+id: imports
+info:
+  name: imports
+  author: test
+imports:
+  - common/sql.yaml
+requests:
+  - method: GET
+    path:
+      - "{{BaseURL}}/?id=1'"
+    headers:
+      import: headers
+      Accept: text/html
+    matchers:
+      - import: sql-errors
+      - type: status
+        status:
+          - 500
+`
+
+func TestImports(t *testing.T) {
+	directory := writeFiles(t, map[string]string{
+		"template.yaml": importingTemplate,
+		"common/sql.yaml": `
+imports:
+  - headers.yaml
+sql-errors:
+  - type: word
+    words:
+      - "SQL syntax"
+  - type: word
+    words:
+      - "ORA-01756"
+`,
+		"common/headers.yaml": `
+headers:
+  User-Agent: scanner
+  Accept: "*/*"
+`,
+	})
+	defer os.RemoveAll(directory)
+
+	template, err := Parse(filepath.Join(directory, "template.yaml"))
+	require.Nil(t, err, "Could not parse template with imports")
+	request := template.BulkRequestsHTTP[0]
+	require.Len(t, request.Matchers, 3, "Could not splice the imported matchers")
+	require.Equal(t, []string{"SQL syntax"}, request.Matchers[0].Words, "Could not import the matchers in order")
+	require.Equal(t, map[string]string{"User-Agent": "scanner", "Accept": "text/html"}, request.Headers, "Could not merge the imported headers")
+}
+
+func TestImportErrors(t *testing.T) {
+	directory := writeFiles(t, map[string]string{
+		"template.yaml": importingTemplate,
+		"common/sql.yaml": `
+imports:
+  - ../common/sql.yaml
+`,
+	})
+	defer os.RemoveAll(directory)
+	template := filepath.Join(directory, "template.yaml")
+	sql := filepath.Join(directory, "common", "sql.yaml")
+
+	_, err := Parse(template)
+	require.EqualError(t, err, sql+": import cycle: "+template+" -> "+sql+" -> "+sql, "Could not detect the import cycle")
+
+	require.Nil(t, ioutil.WriteFile(sql, []byte("sql-errors: []\n"), 0644), "Could not write file")
+	_, err = Parse(template)
+	require.EqualError(t, err, template+": unknown definition headers", "Could not detect the unknown definition")
+
+	require.Nil(t, os.Remove(sql), "Could not remove file")
+	_, err = Parse(template)
+	require.Contains(t, err.Error(), template+": could not import common/sql.yaml: ", "Could not name the importing and the missing files")
+
+	require.Nil(t, ioutil.WriteFile(sql, []byte("sql-errors:\n  - type: word\n    word: [error]\nheaders: {}\n"), 0644), "Could not write file")
+	problems := Validate(template)
+	require.NotEmpty(t, problems, "Could not validate the imported definitions strictly")
+	require.Contains(t, problems[0].Error(), "in the resolved template: ", "Could not validate the imported definitions strictly")
+}
@@ -0,0 +1,215 @@
+package templates
+
+import (
+	"fmt"
+	"strings"
+	"sync"
+
+	"github.com/projectdiscovery/gologger"
+	"gopkg.in/yaml.v2"
+)
+
+// deprecation is a field or a value of an older template syntax, mapped to
+// its current representation when parsing the templates.
+type deprecation struct {
+	// scope is the mapping having the field, empty for the top level of
+	// the template, info, requests, dns, matchers or extractors otherwise
+	scope string
+	field string
+	// value is the deprecated value of the field, if only the value is
+	value string
+	// replacement is the current field, or value if the value is
+	// deprecated, the fields of info being moved to it from the top level
+	replacement string
+	// list is set for the fields formerly being lists, their items being
+	// joined with commas
+	list bool
+}
+
+// deprecations are the known deprecated fields and values of the templates
+var deprecations = []deprecation{
+	{scope: "", field: "name", replacement: "info.name"},
+	{scope: "", field: "author", replacement: "info.author"},
+	{scope: "", field: "severity", replacement: "info.severity"},
+	{scope: "", field: "description", replacement: "info.description"},
+	{scope: "", field: "tags", replacement: "info.tags"},
+	{scope: "info", field: "author", list: true},
+	{scope: "info", field: "tags", list: true},
+	{scope: "requests", field: "follow-redirects", replacement: "redirects"},
+	{scope: "requests", field: "max-redirect", replacement: "max-redirects"},
+	{scope: "requests", field: "attack-type", replacement: "attack"},
+	{scope: "matchers", field: "negate", replacement: "negative"},
+	{scope: "matchers", field: "part", value: "response", replacement: "all"},
+	{scope: "matchers", field: "part", value: "headers", replacement: "header"},
+	{scope: "extractors", field: "part", value: "response", replacement: "all"},
+	{scope: "extractors", field: "part", value: "headers", replacement: "header"},
+}
+
+// scopes are the mappings nested in each scope, by field
+var scopes = map[string]map[string]string{
+	"":         {"info": "info", "requests": "requests", "dns": "dns"},
+	"requests": {"matchers": "matchers", "extractors": "extractors"},
+	"dns":      {"matchers": "matchers", "extractors": "extractors"},
+}
+
+var (
+	// strictSyntax makes the templates using a deprecated syntax fail to load
+	strictSyntax bool
+	// warnedDeprecations are the files already warned about
+	warnedDeprecations sync.Map
+)
+
+// SetStrictSyntax sets whether the templates using a deprecated syntax fail
+// to load instead of being loaded with a warning.
+func SetStrictSyntax(value bool) {
+	strictSyntax = value
+}
+
+// String returns the deprecated syntax along with its replacement
+func (d *deprecation) String() string {
+	switch {
+	case d.list:
+		return fmt.Sprintf("%s as a list (use a comma separated string)", d.field)
+	case d.value != "":
+		return fmt.Sprintf("%s: %s (use %s: %s)", d.field, d.value, d.field, d.replacement)
+	}
+	return fmt.Sprintf("%s (use %s)", d.field, d.replacement)
+}
+
+// matches returns true if the deprecation applies to a field of a scope
+func (d *deprecation) matches(scope, field string, value interface{}) bool {
+	if d.scope != scope || d.field != field {
+		return false
+	}
+	if d.list {
+		_, ok := value.([]interface{})
+		return ok
+	}
+	return d.value == "" || d.value == value
+}
+
+// upgradeSyntax maps the deprecated syntax of the yaml of a template to
+// the current one, returning the deprecations found along with the yaml,
+// as is if the template has none.
+func upgradeSyntax(data []byte) ([]byte, []string, error) {
+	var document yaml.MapSlice
+	if err := yaml.Unmarshal(data, &document); err != nil {
+		return nil, nil, err
+	}
+	var found []string
+	upgraded, err := upgradeMapping("", document, &found)
+	if err != nil || len(found) == 0 {
+		return data, nil, err
+	}
+	result, err := yaml.Marshal(upgraded)
+	return result, found, err
+}
+
+// upgradeMapping maps the deprecated syntax of a mapping of a scope and of
+// its nested mappings, appending the deprecations found.
+func upgradeMapping(scope string, items yaml.MapSlice, found *[]string) (yaml.MapSlice, error) {
+	result := make(yaml.MapSlice, 0, len(items))
+	var moved yaml.MapSlice
+	for _, item := range items {
+		field, _ := item.Key.(string)
+		var current *deprecation
+		for i := range deprecations {
+			if deprecations[i].matches(scope, field, item.Value) {
+				current = &deprecations[i]
+				break
+			}
+		}
+		if current == nil {
+			result = append(result, item)
+			continue
+		}
+
+		*found = append(*found, current.String())
+		switch {
+		case current.list:
+			var values []string
+			for _, value := range item.Value.([]interface{}) {
+				values = append(values, fmt.Sprint(value))
+			}
+			item.Value = strings.Join(values, ",")
+		case current.value != "":
+			item.Value = current.replacement
+		case strings.HasPrefix(current.replacement, "info."):
+			moved = append(moved, yaml.MapItem{Key: strings.TrimPrefix(current.replacement, "info."), Value: item.Value})
+			continue
+		default:
+			if hasKey(items, current.replacement) {
+				return nil, fmt.Errorf("deprecated %s is used along with %s", field, current.replacement)
+			}
+			item.Key = current.replacement
+		}
+		result = append(result, item)
+	}
+	if len(moved) > 0 {
+		result = moveToInfo(result, moved)
+	}
+
+	for i, item := range result {
+		field, _ := item.Key.(string)
+		nested, ok := scopes[scope][field]
+		if !ok {
+			continue
+		}
+		switch value := item.Value.(type) {
+		case yaml.MapSlice:
+			upgraded, err := upgradeMapping(nested, value, found)
+			if err != nil {
+				return nil, err
+			}
+			result[i].Value = upgraded
+		case []interface{}:
+			list := make([]interface{}, 0, len(value))
+			for _, element := range value {
+				if mapping, ok := element.(yaml.MapSlice); ok {
+					upgraded, err := upgradeMapping(nested, mapping, found)
+					if err != nil {
+						return nil, err
+					}
+					element = upgraded
+				}
+				list = append(list, element)
+			}
+			result[i].Value = list
+		}
+	}
+	return result, nil
+}
+
+// moveToInfo moves the fields of the top level of a template to its info,
+// the fields already in info taking precedence.
+func moveToInfo(items, moved yaml.MapSlice) yaml.MapSlice {
+	for i, item := range items {
+		if item.Key != "info" {
+			continue
+		}
+		info, _ := item.Value.(yaml.MapSlice)
+		for _, field := range moved {
+			if !hasKey(info, field.Key) {
+				info = append(info, field)
+			}
+		}
+		items[i].Value = info
+		return items
+	}
+	return append(items, yaml.MapItem{Key: "info", Value: moved})
+}
+
+// checkDeprecations returns an error for the deprecated syntax of a file in
+// strict mode, warning about it once per file otherwise.
+func checkDeprecations(file string, found []string) error {
+	if len(found) == 0 {
+		return nil
+	}
+	if strictSyntax {
+		return fmt.Errorf("%s: deprecated syntax: %s", file, strings.Join(found, ", "))
+	}
+	if _, warned := warnedDeprecations.LoadOrStore(file, struct{}{}); !warned {
+		gologger.Labelf("Template %s uses deprecated syntax: %s\n", file, strings.Join(found, ", "))
+	}
+	return nil
+}
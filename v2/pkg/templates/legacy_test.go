@@ -0,0 +1,206 @@
+package templates
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+	"gopkg.in/yaml.v2"
+)
+
+func TestUpgradeSyntax(t *testing.T) {
+	tests := []struct {
+		name       string
+		legacy     string
+		modern     string
+		deprecated []string
+	}{
+		{
+			name: "info at the top level",
+			legacy: `
+id: legacy
+name: legacy
+author: test
+severity: high
+info:
+  description: legacy info
+`,
+			modern: `
+id: legacy
+info:
+  name: legacy
+  author: test
+  severity: high
+  description: legacy info
+`,
+			deprecated: []string{"name (use info.name)", "author (use info.author)", "severity (use info.severity)"},
+		},
+		{
+			name: "lists of authors and tags",
+			legacy: `
+id: legacy
+tags: [cve, apache]
+info:
+  name: legacy
+  author: [alice, bob]
+`,
+			modern: `
+id: legacy
+info:
+  name: legacy
+  author: alice,bob
+  tags: cve,apache
+`,
+			deprecated: []string{"tags (use info.tags)", "author as a list (use a comma separated string)", "tags as a list (use a comma separated string)"},
+		},
+		{
+			name: "renamed request fields",
+			legacy: `
+id: legacy
+info:
+  name: legacy
+  author: test
+requests:
+  - method: GET
+    path:
+      - "{{BaseURL}}"
+    follow-redirects: true
+    max-redirect: 3
+    matchers:
+      - type: word
+        negate: true
+        words:
+          - "error"
+`,
+			modern: `
+id: legacy
+info:
+  name: legacy
+  author: test
+requests:
+  - method: GET
+    path:
+      - "{{BaseURL}}"
+    redirects: true
+    max-redirects: 3
+    matchers:
+      - type: word
+        negative: true
+        words:
+          - "error"
+`,
+			deprecated: []string{"follow-redirects (use redirects)", "max-redirect (use max-redirects)", "negate (use negative)"},
+		},
+		{
+			name: "renamed parts",
+			legacy: `
+id: legacy
+info:
+  name: legacy
+  author: test
+dns:
+  - name: "{{FQDN}}"
+    type: A
+    matchers:
+      - type: word
+        part: response
+        words:
+          - "IN"
+requests:
+  - method: GET
+    path:
+      - "{{BaseURL}}"
+    extractors:
+      - type: regex
+        part: headers
+        regex:
+          - "Server: .*"
+`,
+			modern: `
+id: legacy
+info:
+  name: legacy
+  author: test
+dns:
+  - name: "{{FQDN}}"
+    type: A
+    matchers:
+      - type: word
+        part: all
+        words:
+          - "IN"
+requests:
+  - method: GET
+    path:
+      - "{{BaseURL}}"
+    extractors:
+      - type: regex
+        part: header
+        regex:
+          - "Server: .*"
+`,
+			deprecated: []string{"part: response (use part: all)", "part: headers (use part: header)"},
+		},
+		{
+			name: "current syntax",
+			legacy: `
+id: current
+info:
+  name: current
+  author: test
+  tags: cve
+`,
+			modern: `
+id: current
+info:
+  name: current
+  author: test
+  tags: cve
+`,
+		},
+	}
+
+	for _, test := range tests {
+		upgraded, deprecated, err := upgradeSyntax([]byte(test.legacy))
+		require.Nil(t, err, "Could not upgrade %s", test.name)
+		require.Equal(t, test.deprecated, deprecated, "Could not find the deprecations of %s", test.name)
+
+		got, expected := &Template{}, &Template{}
+		require.Nil(t, yaml.UnmarshalStrict(upgraded, got), "Could not parse the upgraded %s", test.name)
+		require.Nil(t, yaml.UnmarshalStrict([]byte(test.modern), expected), "Could not parse the modern %s", test.name)
+		require.Equal(t, expected, got, "Could not upgrade %s to the modern syntax", test.name)
+	}
+
+	_, _, err := upgradeSyntax([]byte("id: legacy\nrequests:\n  - follow-redirects: true\n    redirects: false\n"))
+	require.EqualError(t, err, "deprecated follow-redirects is used along with redirects", "Could not reject the conflicting fields")
+}
+
+func TestStrictSyntax(t *testing.T) {
+	directory := writeFiles(t, map[string]string{"template.yaml": `
+id: legacy
+info:
+  name: legacy
+  author: test
+requests:
+  - method: GET
+    path:
+      - "{{BaseURL}}"
+    follow-redirects: true
+    matchers:
+      - type: status
+        status:
+          - 200
+`})
+	defer os.RemoveAll(directory)
+	file := filepath.Join(directory, "template.yaml")
+
+	template, err := Parse(file)
+	require.Nil(t, err, "Could not parse the deprecated syntax")
+	require.True(t, template.BulkRequestsHTTP[0].Redirects, "Could not map the deprecated field")
+
+	SetStrictSyntax(true)
+	defer SetStrictSyntax(false)
+	_, err = Parse(file)
+	require.EqualError(t, err, file+": deprecated syntax: follow-redirects (use redirects)", "Could not reject the deprecated syntax")
+}
@@ -0,0 +1,64 @@
+package templates
+
+import (
+	"fmt"
+	"io/ioutil"
+	"sync"
+
+	"github.com/projectdiscovery/gologger"
+	"github.com/projectdiscovery/nuclei/v2/pkg/signature"
+)
+
+// The modes of the verification of the signatures of the templates
+const (
+	// SignatureIgnore doesn't verify the signatures
+	SignatureIgnore = "ignore"
+	// SignatureWarn warns about the unsigned or invalid templates and loads them
+	SignatureWarn = "warn"
+	// SignatureEnforce refuses to load the unsigned or invalid templates
+	SignatureEnforce = "enforce"
+)
+
+var (
+	// signatureMode is the mode of the verification of the signatures
+	signatureMode = SignatureIgnore
+	// trustedKeys are the keys verifying the signatures
+	trustedKeys *signature.Keys
+	// warnedSignatures are the files already warned about
+	warnedSignatures sync.Map
+)
+
+// SetSignature sets the mode of the verification of the signatures of the
+// templates, workflows and imported files against the trusted keys.
+func SetSignature(mode string, keys *signature.Keys) {
+	signatureMode = mode
+	trustedKeys = keys
+}
+
+// CheckSignature verifies the signature of a file being loaded, returning
+// an error if it isn't signed by a trusted key in enforce mode.
+func CheckSignature(file string, data []byte) error {
+	if signatureMode != SignatureWarn && signatureMode != SignatureEnforce {
+		return nil
+	}
+	_, err := trustedKeys.Verify(data)
+	if err == nil {
+		return nil
+	}
+	if signatureMode == SignatureEnforce {
+		return fmt.Errorf("%s: %s", file, err)
+	}
+	if _, warned := warnedSignatures.LoadOrStore(file, struct{}{}); !warned {
+		gologger.Labelf("Template %s: %s\n", file, err)
+	}
+	return nil
+}
+
+// VerifySignature returns the id of the trusted key a file is signed with
+func VerifySignature(file string) (string, error) {
+	data, err := ioutil.ReadFile(file)
+	if err != nil {
+		return "", err
+	}
+	return trustedKeys.Verify(data)
+}
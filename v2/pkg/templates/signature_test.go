@@ -0,0 +1,62 @@
+package templates
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/projectdiscovery/nuclei/v2/pkg/signature"
+	"github.com/stretchr/testify/require"
+)
+
+func TestSignatureEnforce(t *testing.T) {
+	directory := writeFiles(t, map[string]string{
+		"template.yaml": importingTemplate,
+		"common/sql.yaml": `
+sql-errors:
+  - type: word
+    words:
+      - "SQL syntax"
+headers:
+  User-Agent: scanner
+`,
+	})
+	defer os.RemoveAll(directory)
+
+	keyFile := filepath.Join(directory, "key")
+	require.Nil(t, signature.GenerateKey(keyFile), "Could not generate key")
+	key, err := signature.LoadPrivateKey(keyFile)
+	require.Nil(t, err, "Could not load private key")
+	keys, err := signature.LoadPublicKeys(keyFile + ".pub")
+	require.Nil(t, err, "Could not load public key")
+	SetSignature(SignatureEnforce, keys)
+	defer SetSignature(SignatureIgnore, nil)
+
+	sign := func(name string) {
+		path := filepath.Join(directory, name)
+		data, err := ioutil.ReadFile(path)
+		require.Nil(t, err, "Could not read file")
+		require.Nil(t, ioutil.WriteFile(path, signature.Sign(data, key), 0644), "Could not write file")
+	}
+	file := filepath.Join(directory, "template.yaml")
+	sql := filepath.Join(directory, "common", "sql.yaml")
+
+	_, err = Parse(file)
+	require.EqualError(t, err, file+": template is not signed", "Could not reject unsigned template")
+
+	sign("template.yaml")
+	_, err = Parse(file)
+	require.EqualError(t, err, file+": could not import common/sql.yaml: "+sql+": template is not signed", "Could not reject unsigned import")
+
+	sign("common/sql.yaml")
+	template, err := Parse(file)
+	require.Nil(t, err, "Could not parse signed template")
+	require.Equal(t, map[string]string{"User-Agent": "scanner", "Accept": "text/html"}, template.BulkRequestsHTTP[0].Headers, "Could not import signed definitions")
+
+	data, err := ioutil.ReadFile(file)
+	require.Nil(t, err, "Could not read file")
+	require.Nil(t, ioutil.WriteFile(file, append([]byte("# comment\n"), data...), 0644), "Could not write file")
+	_, err = Parse(file)
+	require.NotNil(t, err, "Could not reject modified template")
+}
@@ -1,7 +1,17 @@
 package templates
 
 import (
+	"errors"
+	"fmt"
+	"regexp"
+	"strings"
+
+	"github.com/projectdiscovery/nuclei/v2/pkg/extractors"
+	"github.com/projectdiscovery/nuclei/v2/pkg/generators"
+	"github.com/projectdiscovery/nuclei/v2/pkg/matchers"
+	"github.com/projectdiscovery/nuclei/v2/pkg/regexguard"
 	"github.com/projectdiscovery/nuclei/v2/pkg/requests"
+	"github.com/projectdiscovery/nuclei/v2/pkg/variables"
 )
 
 // Template is a request template parsed from a yaml file
@@ -10,10 +20,49 @@ type Template struct {
 	ID string `yaml:"id"`
 	// Info contains information about the template
 	Info Info `yaml:"info"`
+	// Variables contains the values reused by the requests of the template,
+	// literals or dsl expressions between {{}} evaluated once per target.
+	// They are available as placeholders of the requests and in the dsl
+	// matchers and extractors.
+	Variables map[string]string `yaml:"variables,omitempty"`
+	// variables are the compiled variables of the template
+	variables *variables.Variables
+	// secrets are the values of the environment variables expanded in the template
+	secrets []string
 	// BulkRequestsHTTP contains the http request to make in the template
 	BulkRequestsHTTP []*requests.BulkHTTPRequest `yaml:"requests,omitempty"`
 	// RequestsDNS contains the dns request to make in the template
 	RequestsDNS []*requests.DNSRequest `yaml:"dns,omitempty"`
+	// RequestsHeadless contains the steps to run in a headless browser in
+	// the template, only executed with -headless.
+	RequestsHeadless []*requests.HeadlessRequest `yaml:"headless,omitempty"`
+	// RequestsNetwork contains the steps to send over raw tcp or udp
+	// connections in the template
+	RequestsNetwork []*requests.NetworkRequest `yaml:"network,omitempty"`
+	// RequestsFile contains the matchers and the extractors to run over the
+	// local files of the targets in the template
+	RequestsFile []*requests.FileRequest `yaml:"file,omitempty"`
+	// RequestsWebsocket contains the messages to send over upgraded
+	// websocket connections in the template
+	RequestsWebsocket []*requests.WebsocketRequest `yaml:"websocket,omitempty"`
+	// RequestsSSL contains the tls handshakes to run with the servers in the
+	// template
+	RequestsSSL []*requests.SSLRequest `yaml:"ssl,omitempty"`
+	// ProtocolsCondition is the condition between the dns and the http
+	// requests of a template having both, "and" reporting only the http
+	// results of the targets matched by the dns requests. The results of
+	// each protocol are independent by default.
+	ProtocolsCondition string `yaml:"protocols-condition,omitempty"`
+	// Threads is the number of targets the template runs towards
+	// concurrently, within the global concurrency.
+	Threads int `yaml:"threads,omitempty"`
+	// NoOverride keeps the timeout, retries and threads of the template
+	// whatever the values given on the command line, for the templates
+	// relying on them like the time-based ones.
+	NoOverride bool `yaml:"no-override,omitempty"`
+	// Digest is the signature of the template by a trusted key, written
+	// by -sign-templates and verified with -template-signature.
+	Digest string `yaml:"digest,omitempty"`
 }
 
 // Info contains information about the request template
@@ -26,9 +75,59 @@ type Info struct {
 	Severity string `yaml:"severity,omitempty"`
 	// Description optionally describes the template.
 	Description string `yaml:"description,omitempty"`
+	// Tags optionally contains the comma separated tags of the template
+	Tags string `yaml:"tags,omitempty"`
+	// Reference optionally contains the urls documenting what the template
+	// detects, a single url or a list of them.
+	Reference StringSlice `yaml:"reference,omitempty"`
+	// Classification optionally contains the cve, cwe and cvss of the template
+	Classification *Classification `yaml:"classification,omitempty"`
+}
+
+// StringSlice is a list of strings which can be given as a single string
+type StringSlice []string
+
+// UnmarshalYAML unmarshals a list of strings or a single string
+func (s *StringSlice) UnmarshalYAML(unmarshal func(interface{}) error) error {
+	var values []string
+	if err := unmarshal(&values); err == nil {
+		*s = values
+		return nil
+	}
+	var value string
+	if err := unmarshal(&value); err != nil {
+		return err
+	}
+	if value != "" {
+		*s = []string{value}
+	}
+	return nil
+}
+
+// TagList returns the lowercased tags of the comma separated list
+func (i *Info) TagList() []string {
+	var tags []string
+	for _, tag := range strings.Split(i.Tags, ",") {
+		if tag = strings.ToLower(strings.TrimSpace(tag)); tag != "" {
+			tags = append(tags, tag)
+		}
+	}
+	return tags
+}
+
+// HasMultipleProtocols returns true if the template has both dns and http
+// requests, the dns requests of a target being executed before the http ones.
+func (t *Template) HasMultipleProtocols() bool {
+	return len(t.RequestsDNS) > 0 && len(t.BulkRequestsHTTP) > 0
+}
+
+// RequiresAllProtocols returns true if the http requests of the template
+// are only executed towards the targets matched by the dns requests.
+func (t *Template) RequiresAllProtocols() bool {
+	return t.ProtocolsCondition == "and"
 }
 
-func (t* Template) GetHTTPRequestCount() int64 {
+func (t *Template) GetHTTPRequestCount() int64 {
 	var count int64 = 0
 	for _, request := range t.BulkRequestsHTTP {
 		count += request.GetRequestCount()
@@ -42,4 +141,124 @@ func (t *Template) GetDNSRequestCount() int64 {
 		count += request.GetRequestCount()
 	}
 	return count
-}
\ No newline at end of file
+}
+
+func (t *Template) GetHeadlessRequestCount() int64 {
+	var count int64 = 0
+	for _, request := range t.RequestsHeadless {
+		count += request.GetRequestCount()
+	}
+	return count
+}
+
+func (t *Template) GetNetworkRequestCount() int64 {
+	var count int64 = 0
+	for _, request := range t.RequestsNetwork {
+		count += request.GetRequestCount()
+	}
+	return count
+}
+
+func (t *Template) GetFileRequestCount() int64 {
+	var count int64 = 0
+	for _, request := range t.RequestsFile {
+		count += request.GetRequestCount()
+	}
+	return count
+}
+
+func (t *Template) GetWebsocketRequestCount() int64 {
+	var count int64 = 0
+	for _, request := range t.RequestsWebsocket {
+		count += request.GetRequestCount()
+	}
+	return count
+}
+
+func (t *Template) GetSSLRequestCount() int64 {
+	var count int64 = 0
+	for _, request := range t.RequestsSSL {
+		count += request.GetRequestCount()
+	}
+	return count
+}
+
+// EvaluateVariables returns the values of the variables of the template for
+// the values of a target, i.e its Hostname.
+func (t *Template) EvaluateVariables(values map[string]interface{}) (map[string]interface{}, error) {
+	if t.variables == nil {
+		return nil, nil
+	}
+	return t.variables.Evaluate(values)
+}
+
+// HasRandomVariables returns true if a variable of the template calls the
+// dsl functions returning a different value on each evaluation.
+func (t *Template) HasRandomVariables() bool {
+	for _, value := range t.Variables {
+		if generators.HasRandomValues(value) {
+			return true
+		}
+	}
+	return false
+}
+
+// UsesInteractsh returns true if an http request of the template uses the
+// interactsh server, with {{interactsh-url}} or the interactsh matchers.
+func (t *Template) UsesInteractsh() bool {
+	for _, request := range t.BulkRequestsHTTP {
+		if request.UsesInteractsh() {
+			return true
+		}
+	}
+	return false
+}
+
+// SetRegexGuard sets the guard limiting the length of the inputs of the
+// regexes of the matchers and the extractors of all the requests.
+func (t *Template) SetRegexGuard(guard *regexguard.Guard) {
+	set := func(requestMatchers []*matchers.Matcher, requestExtractors []*extractors.Extractor) {
+		for _, matcher := range requestMatchers {
+			matcher.SetGuard(guard)
+		}
+		for _, extractor := range requestExtractors {
+			extractor.SetGuard(guard)
+		}
+	}
+	for _, request := range t.BulkRequestsHTTP {
+		set(request.Matchers, request.Extractors)
+	}
+	for _, request := range t.RequestsDNS {
+		set(request.Matchers, request.Extractors)
+	}
+	for _, request := range t.RequestsHeadless {
+		set(request.Matchers, request.Extractors)
+	}
+	for _, request := range t.RequestsNetwork {
+		set(request.Matchers, request.Extractors)
+	}
+	for _, request := range t.RequestsFile {
+		set(request.Matchers, request.Extractors)
+	}
+	for _, request := range t.RequestsWebsocket {
+		set(request.Matchers, request.Extractors)
+	}
+	for _, request := range t.RequestsSSL {
+		set(request.Matchers, request.Extractors)
+	}
+}
+
+// idRegex matches the valid ids, lowercase words separated by dashes
+var idRegex = regexp.MustCompile(`^[a-z0-9]+(-[a-z0-9]+)*$`)
+
+// ValidateID returns an error if an id isn't made of lowercase words
+// separated by dashes, i.e cve-2020-5902.
+func ValidateID(id string) error {
+	if id == "" {
+		return errors.New("no id specified")
+	}
+	if !idRegex.MatchString(id) {
+		return fmt.Errorf("invalid id %q, ids are lowercase words separated by dashes", id)
+	}
+	return nil
+}
@@ -0,0 +1,146 @@
+package templates
+
+import (
+	"errors"
+	"fmt"
+	"io/ioutil"
+
+	"github.com/projectdiscovery/nuclei/v2/pkg/extractors"
+	"github.com/projectdiscovery/nuclei/v2/pkg/matchers"
+	"github.com/projectdiscovery/nuclei/v2/pkg/requests"
+	"gopkg.in/yaml.v2"
+)
+
+// Validate parses a template with strict decoding and returns all the
+// problems found, the ones found by the yaml decoder being prefixed with
+// their line, i.e "line 12: field word not found in type matchers.Matcher".
+func Validate(file string) []error {
+	data, err := ioutil.ReadFile(file)
+	if err != nil {
+		return []error{err}
+	}
+
+	// the lines of the templates with imports or environment variables are the
+	// ones of the resolved yaml
+	data, resolved, err := resolveImports(file, data)
+	if err != nil {
+		return []error{err}
+	}
+	// the environment variables are validated as empty values
+	if envRegex.Match(data) {
+		resolved = true
+		if data, _, err = expandEnvironment(file, data, func(string) (string, bool) { return "", true }); err != nil {
+			return []error{err}
+		}
+	}
+
+	// the deprecated syntax is reported, the template being validated as upgraded
+	var problems []error
+	data, deprecated, err := upgradeSyntax(data)
+	if err != nil {
+		return []error{err}
+	}
+	for _, syntax := range deprecated {
+		problems = append(problems, fmt.Errorf("deprecated syntax: %s", syntax))
+	}
+	resolved = resolved || len(deprecated) > 0
+
+	template := &Template{}
+	if err := yaml.UnmarshalStrict(data, template); err != nil {
+		typeErr, ok := err.(*yaml.TypeError)
+		if !ok {
+			return []error{err}
+		}
+		// the other fields are decoded along with the unknown ones
+		for _, message := range typeErr.Errors {
+			if resolved {
+				message = "in the resolved template: " + message
+			}
+			problems = append(problems, errors.New(message))
+		}
+	}
+
+	problems = append(problems, template.lint()...)
+	if err := template.compile(); err != nil {
+		problems = append(problems, err)
+	}
+	return problems
+}
+
+// lint returns the problems of a template not preventing it from compiling
+func (t *Template) lint() []error {
+	problems := t.lintClassification()
+	for i, request := range t.BulkRequestsHTTP {
+		problems = append(problems, lintCondition(i, request.MatchersCondition)...)
+		for j, raw := range request.Raw {
+			if err := requests.ValidateRawRequest(raw); err != nil {
+				problems = append(problems, fmt.Errorf("request %d: raw request %d: %s", i, j, err))
+			}
+		}
+		problems = append(problems, duplicateNames(i, request.Matchers, request.Extractors)...)
+	}
+	for i, request := range t.RequestsDNS {
+		problems = append(problems, lintCondition(i, request.MatchersCondition)...)
+		problems = append(problems, duplicateNames(i, request.Matchers, request.Extractors)...)
+	}
+	for i, request := range t.RequestsHeadless {
+		problems = append(problems, lintCondition(i, request.MatchersCondition)...)
+		problems = append(problems, duplicateNames(i, request.Matchers, request.Extractors)...)
+	}
+	for i, request := range t.RequestsNetwork {
+		problems = append(problems, lintCondition(i, request.MatchersCondition)...)
+		problems = append(problems, duplicateNames(i, request.Matchers, request.Extractors)...)
+	}
+	for i, request := range t.RequestsFile {
+		problems = append(problems, lintCondition(i, request.MatchersCondition)...)
+		problems = append(problems, duplicateNames(i, request.Matchers, request.Extractors)...)
+	}
+	for i, request := range t.RequestsWebsocket {
+		problems = append(problems, lintCondition(i, request.MatchersCondition)...)
+		problems = append(problems, duplicateNames(i, request.Matchers, request.Extractors)...)
+	}
+	for i, request := range t.RequestsSSL {
+		problems = append(problems, lintCondition(i, request.MatchersCondition)...)
+		problems = append(problems, duplicateNames(i, request.Matchers, request.Extractors)...)
+	}
+	return problems
+}
+
+// lintCondition returns the problem of an unknown matchers condition, which
+// would otherwise be the default or condition.
+func lintCondition(request int, condition string) []error {
+	if _, ok := matchers.ConditionTypes[condition]; condition != "" && !ok {
+		return []error{fmt.Errorf("request %d: unknown matchers-condition specified: %s", request, condition)}
+	}
+	return nil
+}
+
+// duplicateNames returns the names used by several matchers or extractors of a request
+func duplicateNames(request int, requestMatchers []*matchers.Matcher, requestExtractors []*extractors.Extractor) []error {
+	var problems []error
+
+	matcherNames := make(map[string]int)
+	for i, matcher := range requestMatchers {
+		if matcher.Name == "" {
+			continue
+		}
+		if first, ok := matcherNames[matcher.Name]; ok {
+			problems = append(problems, fmt.Errorf("request %d: matcher %d has the name %s of matcher %d", request, i, matcher.Name, first))
+			continue
+		}
+		matcherNames[matcher.Name] = i
+	}
+
+	extractorNames := make(map[string]int)
+	for i, extractor := range requestExtractors {
+		if extractor.Name == "" {
+			continue
+		}
+		if first, ok := extractorNames[extractor.Name]; ok {
+			problems = append(problems, fmt.Errorf("request %d: extractor %d has the name %s of extractor %d", request, i, extractor.Name, first))
+			continue
+		}
+		extractorNames[extractor.Name] = i
+	}
+	return problems
+}
@@ -0,0 +1,521 @@
+package templates
+
+import (
+	"fmt"
+	"io/ioutil"
+	"os"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+	"gopkg.in/yaml.v2"
+)
+
+func validate(t *testing.T, content string) []string {
+	f, err := ioutil.TempFile("", "template-*.yaml")
+	require.Nil(t, err, "Could not create template file")
+	defer os.Remove(f.Name())
+
+	_, err = f.WriteString(content)
+	f.Close()
+	require.Nil(t, err, "Could not write template file")
+
+	var problems []string
+	for _, problem := range Validate(f.Name()) {
+		problems = append(problems, problem.Error())
+	}
+	return problems
+}
+
+func TestValidate(t *testing.T) {
+	problems := validate(t, `
+id: invalid
+info:
+  name: invalid
+  author: test
+requests:
+  - method: GET
+    matchers-condition: AND
+    raw:
+      - |
+        GET HTTP/1.1
+        Host: {{Hostname}}
+      - |
+        {{method}} /admin HTTP/1.1
+        Host: {{Hostname}}
+    matchers:
+      - type: word
+        name: admin
+        word:
+          - "admin"
+      - type: status
+        name: admin
+        status:
+          - 200
+    extractors:
+      - type: regex
+        regex:
+          - "([a-"
+`)
+	require.Equal(t, []string{
+		"line 19: field word not found in type matchers.Matcher",
+		"request 0: unknown matchers-condition specified: AND",
+		`request 0: raw request 0: malformed request line: "GET HTTP/1.1"`,
+		"request 0: matcher 1 has the name admin of matcher 0",
+		"could not compile extractor 0: could not compile regex: ([a-",
+	}, problems, "Could not get the problems of the template")
+
+	problems = validate(t, `
+id: valid
+info:
+  name: valid
+  author: test
+requests:
+  - raw:
+      - |
+        POST /login HTTP/1.1
+        Host: {{Hostname}}
+    matchers:
+      - type: status
+        status:
+          - 200
+`)
+	require.Empty(t, problems, "Could not validate correct template")
+}
+
+func TestValidateHeadless(t *testing.T) {
+	problems := validate(t, `
+id: dom-xss
+info:
+  name: dom xss
+  author: test
+headless:
+  - steps:
+      - action: navigate
+        args:
+          url: "{{BaseURL}}/#<img src=x onerror=alert(document.domain)>"
+      - action: fill
+        args:
+          selector: "#search"
+      - action: hover
+    matchers:
+      - type: status
+        status:
+          - 200
+`)
+	require.Equal(t, []string{"request 0: step 1: fill action without value arg"}, problems, "Could not get the problems of the steps")
+
+	problems = validate(t, `
+id: dom-xss
+info:
+  name: dom xss
+  author: test
+headless:
+  - steps:
+      - action: navigate
+        args:
+          url: "{{BaseURL}}/#<img src=x onerror=alert(document.domain)>"
+      - action: sleep
+        args:
+          duration: 500ms
+    matchers:
+      - type: status
+        status:
+          - 200
+`)
+	require.Equal(t, []string{"could not compile matcher 0: status matchers are not supported by headless requests"}, problems, "Could not reject the http matchers")
+
+	problems = validate(t, `
+id: dom-xss
+info:
+  name: dom xss
+  author: test
+headless:
+  - timeout: 20
+    steps:
+      - action: navigate
+        args:
+          url: "{{BaseURL}}/#<img src=x onerror=alert(document.domain)>"
+      - action: script
+        name: polluted
+        args:
+          code: "Object.prototype.polluted === true"
+    matchers:
+      - type: word
+        part: dialog
+        words:
+          - "alert: "
+      - type: dsl
+        dsl:
+          - 'polluted == "true"'
+`)
+	require.Empty(t, problems, "Could not validate correct headless template")
+}
+
+func TestValidateNetwork(t *testing.T) {
+	problems := validate(t, `
+id: redis-unauth
+info:
+  name: redis unauth
+  author: test
+network:
+  - host:
+      - "{{Host}}:6379"
+    protocol: udp
+    tls: true
+    inputs:
+      - data: "PING\r\n"
+    matchers:
+      - type: word
+        words:
+          - "+PONG"
+`)
+	require.Equal(t, []string{"request 0: tls can't be used with udp"}, problems, "Could not reject tls over udp")
+
+	problems = validate(t, `
+id: rmi-detect
+info:
+  name: rmi detect
+  author: test
+network:
+  - host:
+      - "{{Hostname}}"
+    inputs:
+      - data: "4a524d49000"
+        type: hex
+    matchers:
+      - type: binary
+        binary:
+          - "4e00"
+`)
+	require.Equal(t, []string{"request 0: input 0: invalid hex data 4a524d49000"}, problems, "Could not reject the hex data")
+
+	problems = validate(t, `
+id: redis-unauth
+info:
+  name: redis unauth
+  author: test
+network:
+  - host:
+      - "{{Host}}:6379"
+    inputs:
+      - data: "PING\r\n"
+    matchers:
+      - type: xpath
+        xpath:
+          - "//title"
+`)
+	require.Equal(t, []string{"could not compile matcher 0: xpath matchers are not supported by network requests"}, problems, "Could not reject the html matchers")
+
+	problems = validate(t, `
+id: redis-unauth
+info:
+  name: redis unauth
+  author: test
+network:
+  - host:
+      - "{{Host}}:6379"
+      - "tls-{{Host}}:6380"
+    read-size: 2048
+    payloads:
+      command:
+        - INFO
+        - CONFIG GET dir
+    inputs:
+      - data: "{{command}}\r\n"
+        name: answer
+        timeout: 3
+    matchers-condition: and
+    matchers:
+      - type: word
+        words:
+          - "redis_version"
+      - type: word
+        part: error
+        negative: true
+        words:
+          - "refused"
+    extractors:
+      - type: regex
+        group: 1
+        regex:
+          - "redis_version:([0-9.]+)"
+`)
+	require.Empty(t, problems, "Could not validate correct network template")
+}
+
+func TestValidateID(t *testing.T) {
+	require.Nil(t, ValidateID("cve-2020-5902"), "Could not validate correct id")
+	require.EqualError(t, ValidateID(""), "no id specified", "Could not get missing id error")
+	for _, id := range []string{"CVE-2020-5902", "git config", "git--config", "-git"} {
+		require.NotNil(t, ValidateID(id), "Could not get error for invalid id %s", id)
+	}
+}
+
+func TestValidateClassification(t *testing.T) {
+	template := `
+id: %s
+info:
+  name: classified
+  author: test
+  classification:
+%s
+requests:
+  - path:
+      - "{{BaseURL}}"
+    matchers:
+      - type: status
+        status:
+          - 200
+`
+	problems := validate(t, fmt.Sprintf(template, "cve-2020-5902", `    cve-id:
+      - CVE-2020-5902
+    cwe-id:
+      - CWE-22
+    cvss-metrics: CVSS:3.1/AV:N/AC:L/PR:N/UI:N/S:U/C:H/I:H/A:H
+    cvss-score: 9.8`))
+	require.Empty(t, problems, "Could not validate correct classification")
+
+	problems = validate(t, fmt.Sprintf(template, "cve-2020-5902", "    cve-id:\n      - CVE-2020-1234"))
+	require.Equal(t, []string{"id cve-2020-5902 looks like a cve but the classification cve-id is CVE-2020-1234"}, problems, "Could not get mismatched cve problem")
+
+	problems = validate(t, fmt.Sprintf(template, "cve-2020-5902", "    cwe-id:\n      - CWE-22"))
+	require.Equal(t, []string{"id cve-2020-5902 looks like a cve but the template has no classification cve-id"}, problems, "Could not get missing cve problem")
+
+	problems = validate(t, fmt.Sprintf(template, "classified", "    cve-id:\n      - cve-2020-5902"))
+	require.Equal(t, []string{"classification: invalid cve-id cve-2020-5902, cve ids are like CVE-2020-5902"}, problems, "Could not get invalid cve problem")
+
+	problems = validate(t, fmt.Sprintf(template, "classified", "    cvss-score: 10.5"))
+	require.Equal(t, []string{"classification: cvss-score 10.5 is not between 0 and 10"}, problems, "Could not get invalid score problem")
+}
+
+func TestInfoReference(t *testing.T) {
+	var info Info
+	require.Nil(t, yaml.UnmarshalStrict([]byte("name: referenced\nreference: https://example.com/advisory\n"), &info), "Could not parse a single reference")
+	require.Equal(t, StringSlice{"https://example.com/advisory"}, info.Reference, "Could not get the single reference")
+
+	info = Info{}
+	require.Nil(t, yaml.UnmarshalStrict([]byte("name: referenced\nreference:\n  - https://example.com/a\n  - https://example.com/b\n"), &info), "Could not parse a list of references")
+	require.Equal(t, StringSlice{"https://example.com/a", "https://example.com/b"}, info.Reference, "Could not get the list of references")
+}
+
+func TestValidateFile(t *testing.T) {
+	problems := validate(t, `
+id: aws-keys
+info:
+  name: aws keys
+  author: test
+file:
+  - extensions:
+      - go
+      - yml
+    max-size: -1
+    matchers:
+      - type: regex
+        regex:
+          - "AKIA[0-9A-Z]{16}"
+`)
+	require.Equal(t, []string{"request 0: invalid max-size -1"}, problems, "Could not reject the max size")
+
+	problems = validate(t, `
+id: aws-keys
+info:
+  name: aws keys
+  author: test
+file:
+  - matchers:
+      - type: word
+        part: header
+        words:
+          - "AKIA"
+`)
+	require.Equal(t, []string{"could not compile matcher 0: the header part is not supported by file requests"}, problems, "Could not reject the header part")
+
+	problems = validate(t, `
+id: aws-keys
+info:
+  name: aws keys
+  author: test
+file:
+  - extensions:
+      - go
+    archives: true
+    matchers:
+      - type: regex
+        regex:
+          - "AKIA[0-9A-Z]{16}"
+    extractors:
+      - type: kval
+        kval:
+          - key
+`)
+	require.Equal(t, []string{"could not compile extractor 0: kval extractors are not supported by file requests"}, problems, "Could not reject the kval extractors")
+}
+
+func TestValidateWebsocket(t *testing.T) {
+	problems := validate(t, `
+id: cswsh
+info:
+  name: cross-site websocket hijacking
+  author: test
+websocket:
+  - path:
+      - "{{BaseURL}}/socket"
+    headers:
+      Origin: https://evil.example.com
+    messages:
+      - data: "0a0"
+        type: binary
+    matchers:
+      - type: status
+        status:
+          - 101
+`)
+	require.Equal(t, []string{"request 0: message 0: invalid hex data 0a0"}, problems, "Could not reject the hex data")
+
+	problems = validate(t, `
+id: cswsh
+info:
+  name: cross-site websocket hijacking
+  author: test
+websocket:
+  - path:
+      - "{{BaseURL}}/socket"
+    read-count: -1
+    matchers:
+      - type: status
+        status:
+          - 101
+`)
+	require.Equal(t, []string{"request 0: invalid read-duration 0, read-count -1 or timeout 0"}, problems, "Could not reject the read count")
+
+	problems = validate(t, `
+id: cswsh
+info:
+  name: cross-site websocket hijacking
+  author: test
+websocket:
+  - path:
+      - "{{BaseURL}}/socket"
+    matchers:
+      - type: xpath
+        xpath:
+          - "//title"
+`)
+	require.Equal(t, []string{"could not compile matcher 0: xpath matchers are not supported by websocket requests"}, problems, "Could not reject the html matchers")
+
+	problems = validate(t, `
+id: cswsh
+info:
+  name: cross-site websocket hijacking
+  author: test
+requests:
+  - path:
+      - "{{BaseURL}}"
+    matchers:
+      - type: status
+        status:
+          - 200
+websocket:
+  - path:
+      - "{{BaseURL}}/socket"
+    matchers:
+      - type: status
+        status:
+          - 101
+`)
+	require.Equal(t, []string{"websocket requests can't be combined with dns, http, headless, network or file requests"}, problems, "Could not reject the combined requests")
+}
+
+func TestValidateSSL(t *testing.T) {
+	problems := validate(t, `
+id: tls10
+info:
+  name: tls 1.0 enabled
+  author: test
+ssl:
+  - host:
+      - "{{Hostname}}"
+    min-version: "{{version}}"
+    max-version: "{{version}}"
+    payloads:
+      version:
+        - tls10
+        - tls11
+    matchers:
+      - type: dsl
+        dsl:
+          - "version != ''"
+`)
+	require.Empty(t, problems, "Could not validate the versions of the payloads")
+
+	problems = validate(t, `
+id: tls10
+info:
+  name: tls 1.0 enabled
+  author: test
+ssl:
+  - host:
+      - "{{Hostname}}"
+    min-version: ssl30
+    matchers:
+      - type: word
+        part: version
+        words:
+          - ssl30
+`)
+	require.Equal(t, []string{"request 0: unknown min-version ssl30 (supported: tls10, tls11, tls12, tls13)"}, problems, "Could not reject the version")
+
+	problems = validate(t, `
+id: smtp-tls
+info:
+  name: smtp starttls
+  author: test
+ssl:
+  - host:
+      - "{{Host}}:587"
+    starttls: ftp
+    matchers:
+      - type: word
+        part: error
+        words:
+          - handshake
+`)
+	require.Equal(t, []string{"request 0: unknown starttls protocol ftp (supported: smtp, imap, pop3)"}, problems, "Could not reject the starttls protocol")
+
+	problems = validate(t, `
+id: self-signed
+info:
+  name: self-signed certificate
+  author: test
+ssl:
+  - host:
+      - "{{Hostname}}"
+    extractors:
+      - type: json
+        json:
+          - subject
+`)
+	require.Equal(t, []string{"could not compile extractor 0: json extractors are not supported by ssl requests"}, problems, "Could not reject the json extractors")
+
+	problems = validate(t, `
+id: self-signed
+info:
+  name: self-signed certificate
+  author: test
+network:
+  - host:
+      - "{{Hostname}}"
+    matchers:
+      - type: word
+        words:
+          - SSH
+ssl:
+  - host:
+      - "{{Hostname}}"
+    matchers:
+      - type: dsl
+        dsl:
+          - self_signed
+`)
+	require.Equal(t, []string{"ssl requests can't be combined with dns, http, headless, network, file or websocket requests"}, problems, "Could not reject the combined requests")
+}
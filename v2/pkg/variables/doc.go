@@ -0,0 +1,3 @@
+// Package variables compiles and evaluates the variables of the templates,
+// values reused across the requests of a template evaluated once per target.
+package variables
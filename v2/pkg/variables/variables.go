@@ -0,0 +1,160 @@
+package variables
+
+import (
+	"fmt"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/Knetic/govaluate"
+	"github.com/projectdiscovery/nuclei/v2/pkg/generators"
+)
+
+// nameRegex matches the valid variable names, usable in the expressions
+var nameRegex = regexp.MustCompile(`^[A-Za-z_][A-Za-z0-9_]*$`)
+
+// expressionRegex matches the dsl expressions between {{}} of the values
+var expressionRegex = regexp.MustCompile(`\{\{(.+?)}}`)
+
+// Variables are the compiled variables of a template.
+//
+// The value of a variable is a literal in which the dsl expressions between
+// {{}} are replaced with their result, referencing the values of the target
+// and the other variables.
+type Variables struct {
+	// names are the names of the variables, each after the ones it references
+	names []string
+	// values are the compiled values by variable name
+	values map[string]*value
+}
+
+// value is the value of a variable, literal parts surrounding expressions
+type value struct {
+	literals    []string
+	expressions []*govaluate.EvaluableExpression
+}
+
+// visit states of the variables while sorting them
+const (
+	unvisited = iota
+	visiting
+	visited
+)
+
+// New compiles the variables of a template, returning an error naming the
+// variable if one of its expressions is invalid or if it references itself.
+func New(variables map[string]string) (*Variables, error) {
+	compiled := &Variables{values: make(map[string]*value, len(variables))}
+
+	names := make([]string, 0, len(variables))
+	for name, raw := range variables {
+		if !nameRegex.MatchString(name) {
+			return nil, fmt.Errorf("invalid variable name %s", name)
+		}
+		value, err := compile(raw)
+		if err != nil {
+			return nil, fmt.Errorf("could not compile variable %s: %s", name, err)
+		}
+		compiled.values[name] = value
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	states := make(map[string]int, len(names))
+	for _, name := range names {
+		if err := compiled.visit(name, states, nil); err != nil {
+			return nil, err
+		}
+	}
+	return compiled, nil
+}
+
+// visit appends a variable to the names after the variables it references,
+// path being the variables referencing it.
+func (v *Variables) visit(name string, states map[string]int, path []string) error {
+	switch states[name] {
+	case visited:
+		return nil
+	case visiting:
+		for i, previous := range path {
+			if previous == name {
+				cycle := append(append([]string{}, path[i:]...), name)
+				return fmt.Errorf("variable %s references itself: %s", name, strings.Join(cycle, " -> "))
+			}
+		}
+	}
+
+	states[name] = visiting
+	for _, expression := range v.values[name].expressions {
+		for _, reference := range expression.Vars() {
+			if _, ok := v.values[reference]; !ok {
+				continue
+			}
+			if err := v.visit(reference, states, append(path, name)); err != nil {
+				return err
+			}
+		}
+	}
+	states[name] = visited
+	v.names = append(v.names, name)
+	return nil
+}
+
+// compile compiles the expressions of the value of a variable
+func compile(raw string) (*value, error) {
+	compiled := &value{}
+
+	var last int
+	for _, match := range expressionRegex.FindAllStringSubmatchIndex(raw, -1) {
+		expression := raw[match[2]:match[3]]
+		if err := generators.ValidateExpression(expression); err != nil {
+			return nil, err
+		}
+		evaluable, err := govaluate.NewEvaluableExpressionWithFunctions(expression, generators.HelperFunctions())
+		if err != nil {
+			return nil, err
+		}
+		compiled.literals = append(compiled.literals, raw[last:match[0]])
+		compiled.expressions = append(compiled.expressions, evaluable)
+		last = match[1]
+	}
+	compiled.literals = append(compiled.literals, raw[last:])
+	return compiled, nil
+}
+
+// Evaluate returns the values of the variables for a target, the values
+// are the ones of the target the expressions can reference, i.e Hostname.
+func (v *Variables) Evaluate(values map[string]interface{}) (map[string]interface{}, error) {
+	parameters := generators.CopyMap(values)
+	results := make(map[string]interface{}, len(v.names))
+	for _, name := range v.names {
+		result, err := v.values[name].evaluate(parameters)
+		if err != nil {
+			return nil, fmt.Errorf("could not evaluate variable %s: %s", name, err)
+		}
+		parameters[name] = result
+		results[name] = result
+	}
+	return results, nil
+}
+
+// evaluate replaces the expressions of a value with their result
+func (v *value) evaluate(parameters map[string]interface{}) (string, error) {
+	builder := &strings.Builder{}
+	for i, expression := range v.expressions {
+		builder.WriteString(v.literals[i])
+		result, err := expression.Evaluate(parameters)
+		if err != nil {
+			return "", err
+		}
+		switch r := result.(type) {
+		case float64:
+			builder.WriteString(strconv.FormatFloat(r, 'f', -1, 64))
+		default:
+			builder.WriteString(fmt.Sprint(r))
+		}
+	}
+	builder.WriteString(v.literals[len(v.literals)-1])
+	return builder.String(), nil
+}
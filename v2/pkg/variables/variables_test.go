@@ -0,0 +1,49 @@
+package variables
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestEvaluate(t *testing.T) {
+	variables, err := New(map[string]string{
+		"auth":     `{{base64(username + ":" + password)}}`,
+		"username": "admin",
+		"password": "{{username}}-{{len(Hostname)}}",
+		"prefix":   "/api/v1",
+	})
+	require.Nil(t, err, "Could not compile variables")
+
+	values, err := variables.Evaluate(map[string]interface{}{"Hostname": "example.com"})
+	require.Nil(t, err, "Could not evaluate variables")
+	require.Equal(t, map[string]interface{}{
+		"auth":     "YWRtaW46YWRtaW4tMTE=",
+		"username": "admin",
+		"password": "admin-11",
+		"prefix":   "/api/v1",
+	}, values, "Could not get variables values")
+
+	_, err = variables.Evaluate(nil)
+	require.EqualError(t, err, "could not evaluate variable password: No parameter 'Hostname' found.", "Could not get missing value error")
+}
+
+func TestCycle(t *testing.T) {
+	_, err := New(map[string]string{"a": "{{b}}", "b": "{{c + a}}", "c": "value"})
+	require.EqualError(t, err, "variable a references itself: a -> b -> a", "Could not detect cycle")
+
+	_, err = New(map[string]string{"a": "{{a}}"})
+	require.EqualError(t, err, "variable a references itself: a -> a", "Could not detect self reference")
+}
+
+func TestInvalidVariables(t *testing.T) {
+	_, err := New(map[string]string{"marker": "{{md5(}}"})
+	require.NotNil(t, err, "Could not get invalid expression error")
+	require.Contains(t, err.Error(), "could not compile variable marker", "Could not get variable name in error")
+
+	_, err = New(map[string]string{"marker": `{{starts_with("value")}}`})
+	require.EqualError(t, err, "could not compile variable marker: starts_with expects 2 arguments, got 1", "Could not validate expression")
+
+	_, err = New(map[string]string{"api-key": "value"})
+	require.EqualError(t, err, "invalid variable name api-key", "Could not validate variable name")
+}
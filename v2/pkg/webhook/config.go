@@ -0,0 +1,105 @@
+package webhook
+
+import (
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"net/url"
+	"strings"
+	"text/template"
+	"time"
+
+	jsoniter "github.com/json-iterator/go"
+	"gopkg.in/yaml.v2"
+)
+
+// Config is the configuration of the exporter, read from a yaml file
+type Config struct {
+	// URL is the url of the webhook the results are posted to
+	URL string `yaml:"url"`
+	// Headers are added to the requests, i.e an authorization header
+	Headers map[string]string `yaml:"headers,omitempty"`
+	// Template is the go template of the payload, executed with the fields
+	// of the json result by name, i.e {{.template}} or {{.severity}}. The
+	// json function quotes a value, i.e {"text": {{json .matched}}}. The
+	// json result is posted as is by default.
+	Template string `yaml:"template,omitempty"`
+	// ContentType is the content type of the payload, application/json by default
+	ContentType string `yaml:"content-type,omitempty"`
+	// Severities are the severities of the results to post, all by default
+	Severities []string `yaml:"severities,omitempty"`
+	// Timeout is the timeout of the requests, 10s by default
+	Timeout time.Duration `yaml:"timeout,omitempty"`
+	// Retries is the number of retries of the results failing with a 429 or
+	// 5xx status or a timeout, 3 by default. The results still failing are
+	// logged and dropped.
+	Retries *int `yaml:"retries,omitempty"`
+	// QueueSize is the number of results waiting to be posted beyond which
+	// the results are dropped, 1000 by default.
+	QueueSize int `yaml:"queue-size,omitempty"`
+	// SkipVerify skips the verification of the certificate of the webhook
+	SkipVerify bool `yaml:"skip-verify,omitempty"`
+	// IncludeRR adds the raw requests and responses to the json results
+	IncludeRR bool `yaml:"include-rr,omitempty"`
+
+	payload *template.Template
+}
+
+// LoadConfig reads and validates the configuration of a yaml file
+func LoadConfig(file string) (*Config, error) {
+	data, err := ioutil.ReadFile(file)
+	if err != nil {
+		return nil, fmt.Errorf("could not read webhook config: %s", err)
+	}
+	config := &Config{}
+	if err := yaml.UnmarshalStrict(data, config); err != nil {
+		return nil, fmt.Errorf("could not parse webhook config %s: %s", file, err)
+	}
+	if err := config.validate(); err != nil {
+		return nil, fmt.Errorf("invalid webhook config %s: %s", file, err)
+	}
+	return config, nil
+}
+
+// validate validates the configuration, setting the defaults
+func (c *Config) validate() error {
+	if c.URL == "" {
+		return errors.New("no url given")
+	}
+	if parsed, err := url.Parse(c.URL); err != nil || (parsed.Scheme != "http" && parsed.Scheme != "https") || parsed.Host == "" {
+		return fmt.Errorf("invalid url %s, it should be like https://hooks.example.com/nuclei", c.URL)
+	}
+	if c.Template != "" {
+		payload, err := template.New("payload").Funcs(template.FuncMap{"json": quote}).Option("missingkey=zero").Parse(c.Template)
+		if err != nil {
+			return fmt.Errorf("could not parse template: %s", err)
+		}
+		c.payload = payload
+	}
+	if c.ContentType == "" {
+		c.ContentType = "application/json"
+	}
+	for i, severity := range c.Severities {
+		c.Severities[i] = strings.ToLower(strings.TrimSpace(severity))
+	}
+	if c.Timeout < 0 || c.QueueSize < 0 || (c.Retries != nil && *c.Retries < 0) {
+		return errors.New("the timeout, queue size and retries should be 0 or more")
+	}
+	if c.Timeout == 0 {
+		c.Timeout = 10 * time.Second
+	}
+	if c.QueueSize == 0 {
+		c.QueueSize = 1000
+	}
+	if c.Retries == nil {
+		retries := 3
+		c.Retries = &retries
+	}
+	return nil
+}
+
+// quote returns the json encoding of a value, to embed it in a json payload
+func quote(value interface{}) (string, error) {
+	data, err := jsoniter.Marshal(value)
+	return string(data), err
+}
@@ -0,0 +1,3 @@
+// Package webhook posts each result of a scan to a webhook, such as a Slack
+// or Teams incoming webhook or an internal api, as the results are found.
+package webhook
@@ -0,0 +1,192 @@
+package webhook
+
+import (
+	"bytes"
+	"crypto/tls"
+	"errors"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net"
+	"net/http"
+	"strings"
+	"sync/atomic"
+	"time"
+
+	jsoniter "github.com/json-iterator/go"
+	"github.com/projectdiscovery/gologger"
+	"github.com/projectdiscovery/nuclei/v2/pkg/export"
+)
+
+// Exporter posts the results of a scan one by one from a bounded queue, so
+// that the scan is never blocked by the webhook.
+//
+// The results are dropped and counted when the queue is full. The requests
+// failing with a 429 or 5xx status or a timeout are retried with an
+// exponential backoff and the results still failing are logged and dropped.
+type Exporter struct {
+	config     *Config
+	client     *http.Client
+	queue      *export.Queue
+	severities map[string]struct{}
+	// backoff is the delay before the first retry, doubled for each retry
+	backoff time.Duration
+
+	sent   uint64
+	failed uint64
+}
+
+// New creates an exporter posting the results to the webhook of a config
+func New(config *Config) *Exporter {
+	transport := http.DefaultTransport.(*http.Transport).Clone()
+	transport.TLSClientConfig = &tls.Config{InsecureSkipVerify: config.SkipVerify}
+	e := &Exporter{
+		config:  config,
+		client:  &http.Client{Transport: transport, Timeout: config.Timeout},
+		backoff: time.Second,
+	}
+	if len(config.Severities) > 0 {
+		e.severities = make(map[string]struct{}, len(config.Severities))
+		for _, severity := range config.Severities {
+			e.severities[severity] = struct{}{}
+		}
+	}
+	e.queue = export.NewQueue(config.QueueSize, 1, time.Second, e.send)
+	return e
+}
+
+// Check sends a HEAD request to the webhook, returning an error if it can't
+// be reached or if it rejects the credentials. Any other status is accepted
+// as most webhooks only handle the requests posting a payload.
+func (e *Exporter) Check() error {
+	req, err := http.NewRequest(http.MethodHead, e.config.URL, nil)
+	if err != nil {
+		return err
+	}
+	e.setHeaders(req)
+	resp, err := e.client.Do(req)
+	if err != nil {
+		return fmt.Errorf("could not reach webhook: %s", err)
+	}
+	resp.Body.Close()
+	if resp.StatusCode == http.StatusUnauthorized || resp.StatusCode == http.StatusForbidden {
+		return fmt.Errorf("webhook rejected the credentials with status %d", resp.StatusCode)
+	}
+	return nil
+}
+
+// IncludeRR returns true if the results include the raw requests and responses
+func (e *Exporter) IncludeRR() bool {
+	return e.config.IncludeRR
+}
+
+// Export queues a json result to post if its severity is selected, dropping
+// it if the queue is full.
+func (e *Exporter) Export(data []byte) {
+	if e.severities != nil {
+		severity := strings.ToLower(jsoniter.Get(data, "severity").ToString())
+		if _, ok := e.severities[severity]; !ok {
+			return
+		}
+	}
+	e.queue.Push(data)
+}
+
+// Close posts the queued results, waiting for the retries to complete
+func (e *Exporter) Close() {
+	e.queue.Close()
+}
+
+// Sent returns the number of results posted to the webhook
+func (e *Exporter) Sent() uint64 {
+	return atomic.LoadUint64(&e.sent)
+}
+
+// Queued returns the number of results waiting in the queue
+func (e *Exporter) Queued() int {
+	return e.queue.Len()
+}
+
+// Dropped returns the number of results dropped as the queue was full
+func (e *Exporter) Dropped() uint64 {
+	return e.queue.Dropped()
+}
+
+// Failed returns the number of results dropped as they failed to be posted
+func (e *Exporter) Failed() uint64 {
+	return atomic.LoadUint64(&e.failed)
+}
+
+// send posts the results of a batch, retrying them with a backoff
+func (e *Exporter) send(batch [][]byte) {
+	for _, data := range batch {
+		payload, err := e.payload(data)
+		if err != nil {
+			atomic.AddUint64(&e.failed, 1)
+			gologger.Errorf("Could not build webhook payload: %s\n", err)
+			continue
+		}
+		err = export.Retry(*e.config.Retries, e.backoff, func() (bool, error) {
+			return e.post(payload)
+		})
+		if err != nil {
+			atomic.AddUint64(&e.failed, 1)
+			gologger.Errorf("Could not post result to webhook, dropping it: %s\n", err)
+			continue
+		}
+		atomic.AddUint64(&e.sent, 1)
+	}
+}
+
+// payload returns the payload of a json result, executing the template if any
+func (e *Exporter) payload(data []byte) ([]byte, error) {
+	if e.config.payload == nil {
+		return data, nil
+	}
+	var fields map[string]interface{}
+	if err := jsoniter.Unmarshal(data, &fields); err != nil {
+		return nil, err
+	}
+	buffer := &bytes.Buffer{}
+	if err := e.config.payload.Execute(buffer, fields); err != nil {
+		return nil, err
+	}
+	return buffer.Bytes(), nil
+}
+
+// post posts a payload, returning true along with the error if it should be
+// retried.
+func (e *Exporter) post(payload []byte) (bool, error) {
+	req, err := http.NewRequest(http.MethodPost, e.config.URL, bytes.NewReader(payload))
+	if err != nil {
+		return false, err
+	}
+	req.Header.Set("Content-Type", e.config.ContentType)
+	e.setHeaders(req)
+
+	resp, err := e.client.Do(req)
+	if err != nil {
+		var netErr net.Error
+		return errors.As(err, &netErr) && netErr.Timeout(), err
+	}
+	defer resp.Body.Close()
+	data, _ := ioutil.ReadAll(io.LimitReader(resp.Body, 4096))
+	if resp.StatusCode == http.StatusTooManyRequests || resp.StatusCode >= 500 {
+		return true, fmt.Errorf("status %d", resp.StatusCode)
+	}
+	if resp.StatusCode >= 300 {
+		return false, fmt.Errorf("status %d: %s", resp.StatusCode, bytes.TrimSpace(data))
+	}
+	return false, nil
+}
+
+// setHeaders sets the custom headers of the config on a request
+func (e *Exporter) setHeaders(req *http.Request) {
+	for name, value := range e.config.Headers {
+		if strings.EqualFold(name, "Host") {
+			req.Host = value
+			continue
+		}
+		req.Header.Set(name, value)
+	}
+}
@@ -0,0 +1,101 @@
+package webhook
+
+import (
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+func writeConfig(t *testing.T, directory, content string) string {
+	file := filepath.Join(directory, "webhook.yaml")
+	require.Nil(t, ioutil.WriteFile(file, []byte(content), 0644), "Could not write config")
+	return file
+}
+
+func TestLoadConfig(t *testing.T) {
+	directory, err := ioutil.TempDir("", "webhook-*")
+	require.Nil(t, err, "Could not create temporary directory")
+	defer os.RemoveAll(directory)
+
+	config, err := LoadConfig(writeConfig(t, directory, "url: https://hooks.example.com/nuclei\nseverities: [High, critical]\ntimeout: 2s\n"))
+	require.Nil(t, err, "Could not load config")
+	require.Equal(t, []string{"high", "critical"}, config.Severities, "Could not normalize the severities")
+	require.Equal(t, 2*time.Second, config.Timeout, "Could not parse the timeout")
+	require.Equal(t, "application/json", config.ContentType, "Could not set the default content type")
+	require.Equal(t, 3, *config.Retries, "Could not set the default retries")
+
+	_, err = LoadConfig(writeConfig(t, directory, "url: hooks.example.com\n"))
+	require.NotNil(t, err, "Could not reject an url without scheme")
+	_, err = LoadConfig(writeConfig(t, directory, "url: https://hooks.example.com\ntemplate: '{{.severity'\n"))
+	require.NotNil(t, err, "Could not reject an invalid template")
+	_, err = LoadConfig(writeConfig(t, directory, "url: https://hooks.example.com\nheader: {}\n"))
+	require.NotNil(t, err, "Could not reject an unknown field")
+}
+
+func TestExporter(t *testing.T) {
+	directory, err := ioutil.TempDir("", "webhook-*")
+	require.Nil(t, err, "Could not create temporary directory")
+	defer os.RemoveAll(directory)
+
+	var mutex sync.Mutex
+	var requests int
+	var payloads []string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		mutex.Lock()
+		defer mutex.Unlock()
+		if r.Header.Get("Authorization") != "Bearer token" {
+			w.WriteHeader(http.StatusUnauthorized)
+			return
+		}
+		if r.Method == http.MethodHead {
+			w.WriteHeader(http.StatusMethodNotAllowed)
+			return
+		}
+		requests++
+		// the first request fails with a server error and is retried
+		if requests == 1 {
+			w.WriteHeader(http.StatusBadGateway)
+			return
+		}
+		data, _ := ioutil.ReadAll(r.Body)
+		if string(data) == `{"text":"[high] rejected"}` {
+			w.WriteHeader(http.StatusBadRequest)
+			return
+		}
+		payloads = append(payloads, string(data))
+	}))
+	defer server.Close()
+
+	config, err := LoadConfig(writeConfig(t, directory, fmt.Sprintf(`url: %s
+headers:
+  Authorization: Bearer token
+severities: [high]
+template: '{"text":{{json (printf "[%%s] %%s" .severity .template)}}}'
+`, server.URL)))
+	require.Nil(t, err, "Could not load config")
+	exporter := New(config)
+	exporter.backoff = time.Millisecond
+	require.Nil(t, exporter.Check(), "Could not check the webhook")
+
+	exporter.Export([]byte(`{"template":"first","severity":"high"}`))
+	exporter.Export([]byte(`{"template":"filtered","severity":"info"}`))
+	exporter.Export([]byte(`{"template":"rejected","severity":"high"}`))
+	exporter.Export([]byte(`{"template":"\"quoted\"","severity":"high"}`))
+	exporter.Close()
+	exporter.Export([]byte(`{"template":"closed","severity":"high"}`))
+
+	require.Equal(t, uint64(2), exporter.Sent(), "Could not post the results")
+	require.Equal(t, uint64(1), exporter.Failed(), "Could not count the rejected result")
+	require.Equal(t, []string{`{"text":"[high] first"}`, `{"text":"[high] \"quoted\""}`}, payloads, "Could not post the templated payloads")
+
+	config.Headers = nil
+	require.NotNil(t, New(config).Check(), "Could not fail the check with rejected credentials")
+}
@@ -0,0 +1,6 @@
+// Package websocket runs the websocket requests of the templates, upgrading
+// a connection to a ws or wss url with custom headers, sending the messages
+// of the requests then reading the messages of the server for a duration or
+// a count, recording the upgrade response along with the kind of the error
+// of the connection if any, i.e a refused upgrade.
+package websocket
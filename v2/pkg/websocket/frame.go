@@ -0,0 +1,118 @@
+package websocket
+
+import (
+	"bufio"
+	"crypto/rand"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+)
+
+// The opcodes of the frames, https://tools.ietf.org/html/rfc6455#section-5.2
+const (
+	continuationFrame = 0x0
+	textFrame         = 0x1
+	binaryFrame       = 0x2
+	closeFrame        = 0x8
+	pingFrame         = 0x9
+	pongFrame         = 0xa
+)
+
+// MaxMessageSize is the maximum size of a message read, the larger ones
+// failing the read.
+const MaxMessageSize = 1 << 20
+
+// errMessageTooLarge is the error of a message larger than MaxMessageSize
+var errMessageTooLarge = errors.New("message too large")
+
+// frame is a frame read from a connection
+type frame struct {
+	fin     bool
+	opcode  byte
+	payload []byte
+}
+
+// readFrame reads a frame, unmasking its payload if the server masked it
+func readFrame(reader *bufio.Reader) (*frame, error) {
+	header := make([]byte, 2)
+	if _, err := io.ReadFull(reader, header); err != nil {
+		return nil, err
+	}
+	f := &frame{fin: header[0]&0x80 != 0, opcode: header[0] & 0x0f}
+
+	length := uint64(header[1] & 0x7f)
+	switch length {
+	case 126:
+		extended := make([]byte, 2)
+		if _, err := io.ReadFull(reader, extended); err != nil {
+			return nil, err
+		}
+		length = uint64(binary.BigEndian.Uint16(extended))
+	case 127:
+		extended := make([]byte, 8)
+		if _, err := io.ReadFull(reader, extended); err != nil {
+			return nil, err
+		}
+		length = binary.BigEndian.Uint64(extended)
+	}
+	if length > MaxMessageSize {
+		return nil, errMessageTooLarge
+	}
+
+	var mask []byte
+	if header[1]&0x80 != 0 {
+		mask = make([]byte, 4)
+		if _, err := io.ReadFull(reader, mask); err != nil {
+			return nil, err
+		}
+	}
+	f.payload = make([]byte, length)
+	if _, err := io.ReadFull(reader, f.payload); err != nil {
+		return nil, err
+	}
+	if mask != nil {
+		maskBytes(mask, f.payload)
+	}
+	return f, nil
+}
+
+// writeFrame writes a final frame, masking its payload as required from
+// the clients.
+func writeFrame(writer io.Writer, opcode byte, payload []byte) error {
+	if len(payload) > MaxMessageSize {
+		return errMessageTooLarge
+	}
+	frame := make([]byte, 0, 14+len(payload))
+	frame = append(frame, 0x80|opcode)
+	switch length := len(payload); {
+	case length < 126:
+		frame = append(frame, 0x80|byte(length))
+	case length <= 0xffff:
+		frame = append(frame, 0x80|126, byte(length>>8), byte(length))
+	default:
+		extended := make([]byte, 8)
+		binary.BigEndian.PutUint64(extended, uint64(length))
+		frame = append(frame, 0x80|127)
+		frame = append(frame, extended...)
+	}
+
+	mask := make([]byte, 4)
+	if _, err := rand.Read(mask); err != nil {
+		return fmt.Errorf("could not generate mask: %w", err)
+	}
+	frame = append(frame, mask...)
+	start := len(frame)
+	frame = append(frame, payload...)
+	maskBytes(mask, frame[start:])
+
+	_, err := writer.Write(frame)
+	return err
+}
+
+// maskBytes masks or unmasks a payload in place
+func maskBytes(mask, payload []byte) {
+	for i := range payload {
+		payload[i] ^= mask[i%4]
+	}
+}
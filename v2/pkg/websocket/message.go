@@ -0,0 +1,72 @@
+package websocket
+
+import (
+	"encoding/hex"
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// The types of the messages sent
+const (
+	// TextMessage sends the data as is in a text frame
+	TextMessage = "text"
+	// BinaryMessage sends the bytes of the hex encoded data in a binary
+	// frame, the spaces between the bytes being ignored.
+	BinaryMessage = "binary"
+)
+
+// Message is a message of a websocket request sent once the connection is
+// upgraded.
+type Message struct {
+	// Data is the data sent
+	Data string `yaml:"data"`
+	// Type is the type of the message, text (default) or binary
+	Type string `yaml:"type,omitempty"`
+}
+
+// Validate returns an error if the type of the message is unknown or its
+// hex data is malformed, the data with placeholders being decoded once they
+// are replaced.
+func (m *Message) Validate() error {
+	switch m.Type {
+	case "", TextMessage:
+	case BinaryMessage:
+		if !strings.Contains(m.Data, "{{") {
+			if _, err := m.Bytes(); err != nil {
+				return err
+			}
+		}
+	default:
+		return fmt.Errorf("unknown message type %s (supported: text, binary)", m.Type)
+	}
+	return nil
+}
+
+// Bytes returns the bytes sent by the message
+func (m *Message) Bytes() ([]byte, error) {
+	if m.Type != BinaryMessage {
+		return []byte(m.Data), nil
+	}
+	data, err := hex.DecodeString(strings.Join(strings.Fields(m.Data), ""))
+	if err != nil {
+		return nil, fmt.Errorf("invalid hex data %s", m.Data)
+	}
+	return data, nil
+}
+
+// WithData returns a copy of the message sending other data, i.e once its
+// placeholders are replaced.
+func (m *Message) WithData(data string) *Message {
+	message := *m
+	message.Data = data
+	return &message
+}
+
+// String returns the data sent by the message, quoted or hex encoded
+func (m *Message) String() string {
+	if m.Type == BinaryMessage {
+		return "binary " + m.Data
+	}
+	return strconv.Quote(m.Data)
+}
@@ -0,0 +1,402 @@
+package websocket
+
+import (
+	"bufio"
+	"context"
+	"crypto/rand"
+	"crypto/sha1"
+	"crypto/tls"
+	"encoding/base64"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"net"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+	"unicode"
+
+	"github.com/projectdiscovery/nuclei/v2/pkg/network"
+	"golang.org/x/net/proxy"
+)
+
+// HandshakeError is the kind of the error of an upgrade refused by the
+// server, the status and the headers of its response being recorded. The
+// other errors are of the kinds of the network errors, i.e refused or
+// timeout.
+const HandshakeError = "handshake"
+
+// DefaultReadDuration is the default time the messages of the server are
+// read for.
+const DefaultReadDuration = 2 * time.Second
+
+// acceptGUID is the guid hashed with the key of an upgrade request into the
+// accept key of its response.
+const acceptGUID = "258EAFA5-E914-47DA-95CA-C5AB0DC85B11"
+
+// errUpgradeRefused is the error of an upgrade refused by the server
+var errUpgradeRefused = errors.New("upgrade refused")
+
+// schemes are the websocket schemes of the urls, the http urls being
+// upgraded over the same connection.
+var schemes = map[string]string{
+	"ws":    "ws",
+	"wss":   "wss",
+	"http":  "ws",
+	"https": "wss",
+}
+
+// defaultPorts are the ports of the websocket schemes
+var defaultPorts = map[string]string{
+	"ws":  "80",
+	"wss": "443",
+}
+
+// Options are the options of the connections of a request
+type Options struct {
+	// Headers are the headers of the upgrade requests, i.e Origin or Cookie
+	Headers map[string]string
+	// Timeout is the time the connection, the upgrade and each message sent
+	// can take.
+	Timeout time.Duration
+	// ReadDuration is the time the messages of the server are read for once
+	// the messages are sent, 2 seconds if 0.
+	ReadDuration time.Duration
+	// ReadCount stops the read once this number of messages are read, all
+	// the messages of the read duration being read if 0.
+	ReadCount int
+	// ProxyURL is the url of a http proxy the connections are tunnelled
+	// through, i.e http://127.0.0.1:8080.
+	ProxyURL string
+	// ProxySocksURL is the url of a socks5 proxy the connections are made
+	// through, taking precedence over the http proxy.
+	ProxySocksURL string
+}
+
+// Response is what a request read from an upgraded connection
+type Response struct {
+	// URL is the websocket url the connection was upgraded on
+	URL string
+	// StatusCode is the status of the upgrade response, 101 for an upgraded
+	// connection or 0 without response.
+	StatusCode int
+	// Headers are the headers of the upgrade response
+	Headers http.Header
+	// Upgrade is the status line and the headers of the upgrade response
+	Upgrade string
+	// Messages are the data of the text and binary messages read, in order
+	Messages []string
+	// Error is the kind of the error of the connection if any, i.e
+	// handshake or refused.
+	Error string
+}
+
+// String returns the messages read, one per line
+func (r *Response) String() string {
+	return strings.Join(r.Messages, "\n")
+}
+
+// Dump returns the upgrade response and the messages read, hex dumped
+// unless printable.
+func (r *Response) Dump() string {
+	data := r.String()
+	for _, c := range data {
+		if c == unicode.ReplacementChar || (!unicode.IsPrint(c) && !unicode.IsSpace(c)) {
+			data = hex.Dump([]byte(data))
+			break
+		}
+	}
+	return r.Upgrade + data
+}
+
+// Run upgrades a connection to a websocket url, the http and https urls
+// being upgraded as ws and wss ones, and sends the messages in order before
+// reading the messages of the server until the read duration elapses, the
+// read count is reached or the server closes the connection. The errors of
+// the connection are *network.Error, the response recording their kind
+// along with the upgrade response and the messages read until then. The
+// certificates of the wss urls are not verified.
+func Run(ctx context.Context, URL string, messages []*Message, options *Options) (*Response, error) {
+	resp := &Response{URL: URL}
+	target, err := url.Parse(URL)
+	if err != nil {
+		return resp, fmt.Errorf("invalid url %s: %s", URL, err)
+	}
+	scheme, ok := schemes[strings.ToLower(target.Scheme)]
+	if !ok || target.Host == "" {
+		return resp, fmt.Errorf("invalid url %s, it should be a ws, wss, http or https url", URL)
+	}
+	target.Scheme = scheme
+	resp.URL = target.String()
+
+	conn, err := dial(ctx, target, options)
+	if err != nil {
+		return resp, resp.fail(ctx, fmt.Errorf("could not connect: %w", err))
+	}
+	defer conn.Close()
+
+	// the reads and the writes in progress fail once the context is done
+	stop := make(chan struct{})
+	defer close(stop)
+	go func() {
+		select {
+		case <-ctx.Done():
+			conn.Close()
+		case <-stop:
+		}
+	}()
+
+	if target.Scheme == "wss" {
+		tlsConn := tls.Client(conn, &tls.Config{
+			Renegotiation:      tls.RenegotiateOnceAsClient,
+			InsecureSkipVerify: true,
+			ServerName:         target.Hostname(),
+		})
+		tlsConn.SetDeadline(deadline(options.Timeout))
+		if err := tlsConn.Handshake(); err != nil {
+			return resp, resp.fail(ctx, fmt.Errorf("could not handshake: %w", err))
+		}
+		conn = tlsConn
+	}
+
+	reader := bufio.NewReader(conn)
+	if err := resp.upgrade(conn, reader, target, options); err != nil {
+		return resp, resp.fail(ctx, err)
+	}
+
+	for i, message := range messages {
+		data, err := message.Bytes()
+		if err != nil {
+			return resp, fmt.Errorf("message %d: %s", i+1, err)
+		}
+		opcode := byte(textFrame)
+		if message.Type == BinaryMessage {
+			opcode = binaryFrame
+		}
+		conn.SetWriteDeadline(deadline(options.Timeout))
+		if err := writeFrame(conn, opcode, data); err != nil {
+			return resp, resp.fail(ctx, fmt.Errorf("message %d: %w", i+1, err))
+		}
+	}
+
+	closed, err := resp.read(conn, reader, options)
+	if err != nil {
+		return resp, resp.fail(ctx, fmt.Errorf("could not read messages: %w", err))
+	}
+	if !closed {
+		conn.SetWriteDeadline(deadline(options.Timeout))
+		writeFrame(conn, closeFrame, []byte{0x03, 0xe8})
+	}
+	return resp, nil
+}
+
+// upgrade sends the upgrade request of a url, recording the upgrade
+// response. An upgrade refused by the server is an errUpgradeRefused.
+func (r *Response) upgrade(conn net.Conn, reader *bufio.Reader, target *url.URL, options *Options) error {
+	key := make([]byte, 16)
+	if _, err := rand.Read(key); err != nil {
+		return fmt.Errorf("could not generate key: %w", err)
+	}
+	encodedKey := base64.StdEncoding.EncodeToString(key)
+
+	req := &http.Request{
+		Method: http.MethodGet,
+		URL:    &url.URL{Path: target.Path, RawPath: target.RawPath, RawQuery: target.RawQuery},
+		Host:   target.Host,
+		Header: make(http.Header),
+	}
+	for name, value := range options.Headers {
+		if strings.EqualFold(name, "Host") {
+			req.Host = value
+			continue
+		}
+		req.Header.Set(name, value)
+	}
+	req.Header.Set("Upgrade", "websocket")
+	req.Header.Set("Connection", "Upgrade")
+	req.Header.Set("Sec-WebSocket-Key", encodedKey)
+	req.Header.Set("Sec-WebSocket-Version", "13")
+
+	conn.SetDeadline(deadline(options.Timeout))
+	if err := req.Write(conn); err != nil {
+		return fmt.Errorf("could not send upgrade: %w", err)
+	}
+	upgradeResp, err := http.ReadResponse(reader, req)
+	if err != nil {
+		return fmt.Errorf("could not read upgrade: %w", err)
+	}
+	r.StatusCode = upgradeResp.StatusCode
+	r.Headers = upgradeResp.Header
+	builder := &strings.Builder{}
+	fmt.Fprintf(builder, "HTTP/%d.%d %s\r\n", upgradeResp.ProtoMajor, upgradeResp.ProtoMinor, upgradeResp.Status)
+	upgradeResp.Header.Write(builder)
+	builder.WriteString("\r\n")
+	r.Upgrade = builder.String()
+
+	if upgradeResp.StatusCode != http.StatusSwitchingProtocols {
+		return fmt.Errorf("%w with status %d", errUpgradeRefused, upgradeResp.StatusCode)
+	}
+	if !strings.EqualFold(upgradeResp.Header.Get("Upgrade"), "websocket") {
+		return fmt.Errorf("%w with upgrade %q", errUpgradeRefused, upgradeResp.Header.Get("Upgrade"))
+	}
+	hash := sha1.Sum([]byte(encodedKey + acceptGUID))
+	if upgradeResp.Header.Get("Sec-WebSocket-Accept") != base64.StdEncoding.EncodeToString(hash[:]) {
+		return fmt.Errorf("%w with an invalid accept key", errUpgradeRefused)
+	}
+	return nil
+}
+
+// read reads the messages of the server until the read duration elapses,
+// the read count is reached or the server closes the connection, answering
+// its pings. It returns true if the server closed the connection.
+func (r *Response) read(conn net.Conn, reader *bufio.Reader, options *Options) (bool, error) {
+	duration := options.ReadDuration
+	if duration <= 0 {
+		duration = DefaultReadDuration
+	}
+	conn.SetDeadline(time.Now().Add(duration))
+
+	var fragments []byte
+	for options.ReadCount <= 0 || len(r.Messages) < options.ReadCount {
+		f, err := readFrame(reader)
+		if err != nil {
+			// the messages are read until the end of the read duration
+			var netErr net.Error
+			if errors.As(err, &netErr) && netErr.Timeout() {
+				return false, nil
+			}
+			return false, err
+		}
+		switch f.opcode {
+		case textFrame, binaryFrame:
+			if !f.fin {
+				fragments = f.payload
+				continue
+			}
+			r.Messages = append(r.Messages, string(f.payload))
+		case continuationFrame:
+			fragments = append(fragments, f.payload...)
+			if len(fragments) > MaxMessageSize {
+				return false, errMessageTooLarge
+			}
+			if f.fin {
+				r.Messages = append(r.Messages, string(fragments))
+				fragments = nil
+			}
+		case pingFrame:
+			if err := writeFrame(conn, pongFrame, f.payload); err != nil {
+				return false, err
+			}
+		case closeFrame:
+			// the close is echoed with the status code of the server
+			if len(f.payload) > 2 {
+				f.payload = f.payload[:2]
+			}
+			writeFrame(conn, closeFrame, f.payload)
+			return true, nil
+		}
+	}
+	return false, nil
+}
+
+// dial connects to the address of a websocket url, through the proxy of
+// the options if any.
+func dial(ctx context.Context, target *url.URL, options *Options) (net.Conn, error) {
+	address := target.Host
+	if target.Port() == "" {
+		address = net.JoinHostPort(target.Hostname(), defaultPorts[target.Scheme])
+	}
+	dialer := &net.Dialer{Timeout: options.Timeout}
+
+	switch {
+	case options.ProxySocksURL != "":
+		socksURL, err := url.Parse(options.ProxySocksURL)
+		if err != nil {
+			return nil, fmt.Errorf("invalid socks proxy %s: %s", options.ProxySocksURL, err)
+		}
+		var proxyAuth *proxy.Auth
+		if socksURL.User != nil {
+			proxyAuth = &proxy.Auth{User: socksURL.User.Username()}
+			proxyAuth.Password, _ = socksURL.User.Password()
+		}
+		socksDialer, err := proxy.SOCKS5("tcp", socksURL.Host, proxyAuth, dialer)
+		if err != nil {
+			return nil, err
+		}
+		if contextDialer, ok := socksDialer.(proxy.ContextDialer); ok {
+			return contextDialer.DialContext(ctx, "tcp", address)
+		}
+		return socksDialer.Dial("tcp", address)
+	case options.ProxyURL != "":
+		proxyURL, err := url.Parse(options.ProxyURL)
+		if err != nil {
+			return nil, fmt.Errorf("invalid proxy %s: %s", options.ProxyURL, err)
+		}
+		proxyAddress := proxyURL.Host
+		if proxyURL.Port() == "" {
+			proxyAddress = net.JoinHostPort(proxyURL.Hostname(), "80")
+		}
+		conn, err := dialer.DialContext(ctx, "tcp", proxyAddress)
+		if err != nil {
+			return nil, err
+		}
+		if err := tunnel(conn, proxyURL, address, options.Timeout); err != nil {
+			conn.Close()
+			return nil, err
+		}
+		return conn, nil
+	}
+	return dialer.DialContext(ctx, "tcp", address)
+}
+
+// tunnel asks a http proxy to tunnel a connection to an address
+func tunnel(conn net.Conn, proxyURL *url.URL, address string, timeout time.Duration) error {
+	req := &http.Request{
+		Method: http.MethodConnect,
+		URL:    &url.URL{Opaque: address},
+		Host:   address,
+		Header: make(http.Header),
+	}
+	if proxyURL.User != nil {
+		password, _ := proxyURL.User.Password()
+		credentials := base64.StdEncoding.EncodeToString([]byte(proxyURL.User.Username() + ":" + password))
+		req.Header.Set("Proxy-Authorization", "Basic "+credentials)
+	}
+
+	conn.SetDeadline(deadline(timeout))
+	if err := req.Write(conn); err != nil {
+		return err
+	}
+	// the proxy sends nothing past its response before the upgrade
+	resp, err := http.ReadResponse(bufio.NewReader(conn), req)
+	if err != nil {
+		return err
+	}
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return fmt.Errorf("proxy refused the tunnel with status %d", resp.StatusCode)
+	}
+	return nil
+}
+
+// deadline returns the deadline of an operation, none for a zero timeout
+func deadline(timeout time.Duration) time.Time {
+	if timeout <= 0 {
+		return time.Time{}
+	}
+	return time.Now().Add(timeout)
+}
+
+// fail records the kind of an error of the connection, returning the error
+// of the context instead once it is done.
+func (r *Response) fail(ctx context.Context, err error) error {
+	if ctxErr := ctx.Err(); ctxErr != nil {
+		return ctxErr
+	}
+	r.Error = network.ErrorKind(err)
+	if errors.Is(err, errUpgradeRefused) {
+		r.Error = HandshakeError
+	}
+	return &network.Error{Kind: r.Error, Err: err}
+}
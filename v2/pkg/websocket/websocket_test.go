@@ -0,0 +1,162 @@
+package websocket
+
+import (
+	"bufio"
+	"context"
+	"crypto/sha1"
+	"encoding/base64"
+	"io"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/projectdiscovery/nuclei/v2/pkg/network"
+	"github.com/stretchr/testify/require"
+)
+
+// writeServerFrame writes an unmasked frame as a server does
+func writeServerFrame(writer io.Writer, fin bool, opcode byte, payload []byte) {
+	header := []byte{opcode, byte(len(payload))}
+	if fin {
+		header[0] |= 0x80
+	}
+	writer.Write(append(header, payload...))
+}
+
+// newChatServer returns a server upgrading the connections from the
+// allowed origin only, answering each message with a fragmented echo after
+// a ping, and closing the connection on "bye".
+func newChatServer(t *testing.T) *httptest.Server {
+	return httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("Origin") != "https://chat.example.com" {
+			w.Header().Set("X-Reason", "origin")
+			w.WriteHeader(http.StatusForbidden)
+			return
+		}
+		conn, rw, err := w.(http.Hijacker).Hijack()
+		require.Nil(t, err, "Could not hijack the connection")
+		defer conn.Close()
+
+		hash := sha1.Sum([]byte(r.Header.Get("Sec-WebSocket-Key") + acceptGUID))
+		rw.WriteString("HTTP/1.1 101 Switching Protocols\r\nUpgrade: websocket\r\nConnection: Upgrade\r\n")
+		rw.WriteString("Sec-WebSocket-Accept: " + base64.StdEncoding.EncodeToString(hash[:]) + "\r\nX-Session: 42\r\n\r\n")
+		rw.Flush()
+		for {
+			f, err := readFrame(rw.Reader)
+			if err != nil {
+				return
+			}
+			switch f.opcode {
+			case pongFrame, closeFrame:
+				continue
+			}
+			if string(f.payload) == "bye" {
+				writeServerFrame(conn, true, closeFrame, []byte{0x03, 0xe8})
+				return
+			}
+			writeServerFrame(conn, true, pingFrame, nil)
+			writeServerFrame(conn, false, textFrame, []byte("echo: "))
+			writeServerFrame(conn, true, continuationFrame, f.payload)
+		}
+	}))
+}
+
+// newTunnelProxy returns a listener tunnelling the CONNECT requests
+func newTunnelProxy(t *testing.T) net.Listener {
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	require.Nil(t, err, "Could not listen")
+	go func() {
+		for {
+			conn, err := listener.Accept()
+			if err != nil {
+				return
+			}
+			go func(conn net.Conn) {
+				defer conn.Close()
+				req, err := http.ReadRequest(bufio.NewReader(conn))
+				if err != nil || req.Method != http.MethodConnect {
+					return
+				}
+				target, err := net.Dial("tcp", req.Host)
+				if err != nil {
+					conn.Write([]byte("HTTP/1.1 502 Bad Gateway\r\n\r\n"))
+					return
+				}
+				defer target.Close()
+				conn.Write([]byte("HTTP/1.1 200 Connection established\r\n\r\n"))
+				go io.Copy(target, conn)
+				io.Copy(conn, target)
+			}(conn)
+		}
+	}()
+	return listener
+}
+
+func TestRun(t *testing.T) {
+	server := newChatServer(t)
+	defer server.Close()
+
+	messages := []*Message{{Data: "hello"}, {Data: "77 6f 72 6c 64", Type: BinaryMessage}}
+	for _, message := range messages {
+		require.Nil(t, message.Validate(), "Could not validate the message")
+	}
+	options := &Options{Headers: map[string]string{"Origin": "https://chat.example.com"}, Timeout: time.Second, ReadCount: 2}
+	resp, err := Run(context.Background(), server.URL+"/chat", messages, options)
+	require.Nil(t, err, "Could not run the request")
+	require.Equal(t, "ws://"+strings.TrimPrefix(server.URL, "http://")+"/chat", resp.URL, "Could not upgrade the http url")
+	require.Equal(t, http.StatusSwitchingProtocols, resp.StatusCode, "Could not upgrade the connection")
+	require.Equal(t, "42", resp.Headers.Get("X-Session"), "Could not record the upgrade headers")
+	require.Contains(t, resp.Upgrade, "HTTP/1.1 101 Switching Protocols\r\n", "Could not record the upgrade response")
+	require.Equal(t, []string{"echo: hello", "echo: world"}, resp.Messages, "Could not read the fragmented messages")
+	require.Empty(t, resp.Error, "Could not run without error")
+
+	// the server closing the connection stops the read without error
+	start := time.Now()
+	options.ReadCount = 0
+	resp, err = Run(context.Background(), server.URL, []*Message{{Data: "bye"}}, options)
+	require.Nil(t, err, "Could not close the connection")
+	require.Empty(t, resp.Messages, "Could not read no message")
+	require.True(t, time.Since(start) < DefaultReadDuration, "Could not stop the read on close")
+
+	proxy := newTunnelProxy(t)
+	defer proxy.Close()
+	options.ProxyURL = "http://" + proxy.Addr().String()
+	options.ReadCount = 1
+	resp, err = Run(context.Background(), server.URL, []*Message{{Data: "proxied"}}, options)
+	require.Nil(t, err, "Could not tunnel the connection")
+	require.Equal(t, []string{"echo: proxied"}, resp.Messages, "Could not read through the proxy")
+}
+
+func TestRunErrors(t *testing.T) {
+	server := newChatServer(t)
+	defer server.Close()
+
+	resp, err := Run(context.Background(), server.URL, nil, &Options{Timeout: time.Second})
+	require.NotNil(t, err, "Could not refuse the upgrade")
+	require.IsType(t, &network.Error{}, err, "Could not return a connection error")
+	require.Equal(t, HandshakeError, resp.Error, "Could not record the refused upgrade")
+	require.Equal(t, http.StatusForbidden, resp.StatusCode, "Could not record the status of the upgrade")
+	require.Equal(t, "origin", resp.Headers.Get("X-Reason"), "Could not record the headers of the upgrade")
+
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	require.Nil(t, err, "Could not listen")
+	address := listener.Addr().String()
+	listener.Close()
+	resp, err = Run(context.Background(), "ws://"+address, nil, &Options{Timeout: time.Second})
+	require.NotNil(t, err, "Could not fail the closed port")
+	require.Equal(t, network.RefusedError, resp.Error, "Could not record the refused connection")
+
+	_, err = Run(context.Background(), "ftp://"+address, nil, &Options{})
+	require.NotNil(t, err, "Could not reject the scheme")
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	_, err = Run(ctx, server.URL, nil, &Options{})
+	require.Equal(t, context.Canceled, err, "Could not return the error of the context")
+
+	require.NotNil(t, (&Message{Data: "zz", Type: BinaryMessage}).Validate(), "Could not reject the malformed hex")
+	require.NotNil(t, (&Message{Type: "json"}).Validate(), "Could not reject the unknown type")
+}
@@ -2,8 +2,14 @@ package workflows
 
 import (
 	"errors"
+	"fmt"
+	"io/ioutil"
 	"os"
+	"sort"
+	"strings"
 
+	"github.com/karrick/godirwalk"
+	"github.com/projectdiscovery/nuclei/v2/pkg/templates"
 	"gopkg.in/yaml.v2"
 )
 
@@ -11,22 +17,146 @@ import (
 func Parse(file string) (*Workflow, error) {
 	workflow := &Workflow{}
 
-	f, err := os.Open(file)
+	data, err := ioutil.ReadFile(file)
 	if err != nil {
 		return nil, err
 	}
-	defer f.Close()
+	if err := templates.CheckSignature(file, data); err != nil {
+		return nil, err
+	}
 
-	err = yaml.NewDecoder(f).Decode(workflow)
+	err = yaml.Unmarshal(data, workflow)
 	if err != nil {
 		return nil, err
 	}
 
-	if workflow.Logic == "" {
-		return nil, errors.New("No logic provided")
+	if problems := workflow.check(); len(problems) > 0 {
+		return nil, problems[0]
 	}
 
 	workflow.path = file
 
 	return workflow, nil
 }
+
+// Validate parses a workflow with strict decoding and returns the problems
+// found, the ones found by the yaml decoder being prefixed with their line.
+func Validate(file string) []error {
+	data, err := ioutil.ReadFile(file)
+	if err != nil {
+		return []error{err}
+	}
+
+	var problems []error
+	workflow := &Workflow{}
+	if err := yaml.UnmarshalStrict(data, workflow); err != nil {
+		typeErr, ok := err.(*yaml.TypeError)
+		if !ok {
+			return []error{err}
+		}
+		for _, message := range typeErr.Errors {
+			problems = append(problems, errors.New(message))
+		}
+	}
+	return append(problems, workflow.check()...)
+}
+
+// check returns the problems of the logic or the workflow templates of a workflow
+func (w *Workflow) check() []error {
+	if w.Logic == "" && len(w.Workflows) == 0 {
+		return []error{errors.New("No logic provided")}
+	}
+	if w.Logic != "" && len(w.Workflows) > 0 {
+		return []error{errors.New("logic and workflows can't be used together")}
+	}
+	return checkTemplates("workflows", w.Workflows)
+}
+
+func checkTemplates(prefix string, workflowTemplates []*WorkflowTemplate) []error {
+	var problems []error
+	for i, workflowTemplate := range workflowTemplates {
+		path := fmt.Sprintf("%s[%d]", prefix, i)
+		if workflowTemplate.Template == "" {
+			problems = append(problems, fmt.Errorf("%s: no template specified", path))
+		}
+		if err := workflowTemplate.compileCondition(); err != nil {
+			problems = append(problems, fmt.Errorf("%s: could not compile condition: %s", path, err))
+		}
+		for j, matcher := range workflowTemplate.Matchers {
+			matcherPath := fmt.Sprintf("%s.matchers[%d]", path, j)
+			if matcher.Name == "" {
+				problems = append(problems, fmt.Errorf("%s: no name specified", matcherPath))
+			}
+			problems = append(problems, checkTemplates(matcherPath+".subtemplates", matcher.Subtemplates)...)
+		}
+		problems = append(problems, checkTemplates(path+".subtemplates", workflowTemplate.Subtemplates)...)
+	}
+	return problems
+}
+
+// Load parses the templates of the workflow templates, the paths being
+// resolved by a function, and returns an error if any is missing or invalid.
+func (w *Workflow) Load(resolve func(path string) (string, error)) error {
+	return loadTemplates(w.Workflows, resolve)
+}
+
+func loadTemplates(workflowTemplates []*WorkflowTemplate, resolve func(path string) (string, error)) error {
+	for _, workflowTemplate := range workflowTemplates {
+		if err := workflowTemplate.load(resolve); err != nil {
+			return fmt.Errorf("could not load template %s: %s", workflowTemplate.Template, err)
+		}
+		for _, matcher := range workflowTemplate.Matchers {
+			if err := loadTemplates(matcher.Subtemplates, resolve); err != nil {
+				return err
+			}
+		}
+		if err := loadTemplates(workflowTemplate.Subtemplates, resolve); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// load parses the template file or the templates of the directory of a workflow template
+func (t *WorkflowTemplate) load(resolve func(path string) (string, error)) error {
+	path, err := resolve(t.Template)
+	if err != nil {
+		return err
+	}
+	info, err := os.Stat(path)
+	if err != nil {
+		return err
+	}
+
+	paths := []string{path}
+	if info.IsDir() {
+		paths = nil
+		err := godirwalk.Walk(path, &godirwalk.Options{
+			Callback: func(path string, d *godirwalk.Dirent) error {
+				if !d.IsDir() && strings.HasSuffix(path, ".yaml") {
+					paths = append(paths, path)
+				}
+				return nil
+			},
+			Unsorted: true,
+		})
+		if err != nil {
+			return err
+		}
+		if len(paths) == 0 {
+			return errors.New("no templates found in the directory")
+		}
+		sort.Strings(paths)
+	}
+
+	t.Templates, t.Paths = nil, nil
+	for _, path := range paths {
+		template, err := templates.Parse(path)
+		if err != nil {
+			return fmt.Errorf("%s: %s", path, err)
+		}
+		t.Templates = append(t.Templates, template)
+		t.Paths = append(t.Paths, path)
+	}
+	return nil
+}
@@ -0,0 +1,75 @@
+package workflows
+
+import (
+	"fmt"
+
+	"github.com/Knetic/govaluate"
+	"github.com/projectdiscovery/nuclei/v2/pkg/generators"
+)
+
+// compileCondition compiles the condition of a workflow template if any
+func (t *WorkflowTemplate) compileCondition() error {
+	t.condition = nil
+	if t.Condition == "" {
+		return nil
+	}
+	if err := generators.ValidateExpression(t.Condition); err != nil {
+		return err
+	}
+	compiled, err := govaluate.NewEvaluableExpressionWithFunctions(t.Condition, generators.HelperFunctions())
+	if err != nil {
+		return err
+	}
+	t.condition = compiled
+	return nil
+}
+
+// EvaluateCondition returns true if the workflow template has no condition
+// or if its condition holds for any combination of the values extracted by
+// the named extractors of the parent template. The name of the first
+// variable of the condition without values is returned along with false
+// if there is one, the condition being skipped.
+func (t *WorkflowTemplate) EvaluateCondition(values map[string][]string) (bool, string, error) {
+	if t.condition == nil {
+		return true, "", nil
+	}
+
+	var names []string
+	seen := make(map[string]struct{})
+	for _, name := range t.condition.Vars() {
+		if _, ok := seen[name]; ok {
+			continue
+		}
+		seen[name] = struct{}{}
+		if len(values[name]) == 0 {
+			return false, name, nil
+		}
+		names = append(names, name)
+	}
+
+	parameters := make(map[string]interface{}, len(names))
+	var evaluate func(index int) (bool, error)
+	evaluate = func(index int) (bool, error) {
+		if index == len(names) {
+			result, err := t.condition.Evaluate(parameters)
+			if err != nil {
+				return false, err
+			}
+			holds, ok := result.(bool)
+			if !ok {
+				return false, fmt.Errorf("condition %s is not a boolean expression", t.Condition)
+			}
+			return holds, nil
+		}
+		for _, value := range values[names[index]] {
+			parameters[names[index]] = value
+			holds, err := evaluate(index + 1)
+			if err != nil || holds {
+				return holds, err
+			}
+		}
+		return false, nil
+	}
+	holds, err := evaluate(0)
+	return holds, "", err
+}
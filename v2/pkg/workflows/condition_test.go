@@ -0,0 +1,55 @@
+package workflows
+
+import (
+	"io/ioutil"
+	"os"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestCondition(t *testing.T) {
+	workflowTemplate := &WorkflowTemplate{Template: "cves/", Condition: `compare_versions(version, ">=2.4.49", "<2.4.51")`}
+	require.Nil(t, workflowTemplate.compileCondition(), "Could not compile condition")
+
+	holds, missing, err := workflowTemplate.EvaluateCondition(map[string][]string{"version": {"2.4.46", "2.4.50"}})
+	require.Nil(t, err, "Could not evaluate condition")
+	require.True(t, holds, "Could not match any of the extracted versions")
+	require.Empty(t, missing, "Could not use the extracted versions")
+
+	holds, _, err = workflowTemplate.EvaluateCondition(map[string][]string{"version": {"2.4.46", "2.4.51"}})
+	require.Nil(t, err, "Could not evaluate condition")
+	require.False(t, holds, "Could not skip the versions out of the range")
+
+	holds, missing, err = workflowTemplate.EvaluateCondition(nil)
+	require.Nil(t, err, "Could not evaluate condition")
+	require.False(t, holds, "Could not skip the condition without values")
+	require.Equal(t, "version", missing, "Could not return the missing extractor")
+
+	holds, _, err = (&WorkflowTemplate{}).EvaluateCondition(nil)
+	require.Nil(t, err, "Could not evaluate missing condition")
+	require.True(t, holds, "Could not run the templates without condition")
+}
+
+func TestConditionSyntax(t *testing.T) {
+	f, err := ioutil.TempFile("", "workflow-*.yaml")
+	require.Nil(t, err, "Could not create workflow file")
+	defer os.Remove(f.Name())
+	_, err = f.WriteString(`
+id: condition
+info:
+  name: condition
+  author: test
+workflows:
+  - template: technologies/apache-detect.yaml
+    subtemplates:
+      - template: cves/
+        condition: 'compare_versions(version, ">=2.4.49"'
+`)
+	f.Close()
+	require.Nil(t, err, "Could not write workflow file")
+
+	_, err = Parse(f.Name())
+	require.NotNil(t, err, "Could not fail the workflow with an invalid condition")
+	require.Contains(t, err.Error(), "workflows[0].subtemplates[0]: could not compile condition", "Could not locate the invalid condition")
+}
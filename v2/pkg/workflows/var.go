@@ -1,6 +1,7 @@
 package workflows
 
 import (
+	"context"
 	"sync"
 
 	tengo "github.com/d5/tengo/v2"
@@ -9,22 +10,30 @@ import (
 	"github.com/projectdiscovery/nuclei/v2/pkg/atomicboolean"
 	"github.com/projectdiscovery/nuclei/v2/pkg/executer"
 	"github.com/projectdiscovery/nuclei/v2/pkg/generators"
+	"github.com/projectdiscovery/nuclei/v2/pkg/requests"
+	"github.com/projectdiscovery/nuclei/v2/pkg/templates"
 )
 
 // NucleiVar within the scripting engine
 type NucleiVar struct {
 	tengo.ObjectImpl
+	// Context abandons the requests of the calls once done if not nil
+	Context      context.Context
 	Templates    []*Template
 	URL          string
 	InternalVars map[string]interface{}
+	// GotResults is true if any call of the variable got results
+	GotResults atomicboolean.AtomBool
 	sync.RWMutex
 }
 
-// Template contains HTTPOptions and DNSOptions for a single template
+// Template is a single template of the logic of a workflow, creating the
+// executers of its http or dns requests on each call.
 type Template struct {
-	HTTPOptions *executer.HTTPOptions
-	DNSOptions  *executer.DNSOptions
-	Progress    *progress.Progress
+	Template        *templates.Template
+	NewHTTPExecuter func(request *requests.BulkHTTPRequest) (*executer.HTTPExecuter, error)
+	NewDNSExecuter  func(request *requests.DNSRequest) (*executer.DNSExecuter, error)
+	Progress        *progress.Progress
 }
 
 // TypeName of the variable
@@ -53,30 +62,39 @@ func (n *NucleiVar) Call(args ...tengo.Object) (ret tengo.Object, err error) {
 		externalVars = iterableToMap(args[1])
 	}
 
+	ctx := n.Context
+	if ctx == nil {
+		ctx = context.Background()
+	}
 	var gotResult atomicboolean.AtomBool
 	for _, template := range n.Templates {
+		// the templates are not run once the context is done
+		if ctx.Err() != nil {
+			break
+		}
 		p := template.Progress
-		if template.HTTPOptions != nil {
+		if template.NewHTTPExecuter != nil {
 			if p != nil {
-				p.AddToTotal(template.HTTPOptions.Template.GetHTTPRequestCount())
+				p.AddToTotal(template.Template.GetHTTPRequestCount())
 			}
-			for _, request := range template.HTTPOptions.Template.BulkRequestsHTTP {
+			for _, request := range template.Template.BulkRequestsHTTP {
 				// apply externally supplied payloads if any
 				request.Headers = generators.MergeMapsWithStrings(request.Headers, headers)
 				// apply externally supplied payloads if any
 				request.Payloads = generators.MergeMaps(request.Payloads, externalVars)
-				template.HTTPOptions.BulkHttpRequest = request
-				httpExecuter, err := executer.NewHTTPExecuter(template.HTTPOptions)
+				httpExecuter, err := template.NewHTTPExecuter(request)
 				if err != nil {
 					if p != nil {
 						p.Drop(request.GetRequestCount())
 					}
-					gologger.Warningf("Could not compile request for template '%s': %s\n", template.HTTPOptions.Template.ID, err)
+					gologger.Warningf("Could not compile request for template '%s': %s\n", template.Template.ID, err)
 					continue
 				}
-				result := httpExecuter.ExecuteHTTP(p, n.URL)
+				result := httpExecuter.ExecuteHTTPWithContext(ctx, p, n.URL, nil)
 				if result.Error != nil {
-					gologger.Warningf("Could not send request for template '%s': %s\n", template.HTTPOptions.Template.ID, result.Error)
+					if ctx.Err() == nil {
+						gologger.Warningf("Could not send request for template '%s': %s\n", template.Template.ID, result.Error)
+					}
 					continue
 				}
 
@@ -87,16 +105,24 @@ func (n *NucleiVar) Call(args ...tengo.Object) (ret tengo.Object, err error) {
 			}
 		}
 
-		if template.DNSOptions != nil {
+		if template.NewDNSExecuter != nil {
 			if p != nil {
-				p.AddToTotal(template.DNSOptions.Template.GetDNSRequestCount())
+				p.AddToTotal(template.Template.GetDNSRequestCount())
 			}
-			for _, request := range template.DNSOptions.Template.RequestsDNS {
-				template.DNSOptions.DNSRequest = request
-				dnsExecuter := executer.NewDNSExecuter(template.DNSOptions)
-				result := dnsExecuter.ExecuteDNS(p, n.URL)
+			for _, request := range template.Template.RequestsDNS {
+				dnsExecuter, err := template.NewDNSExecuter(request)
+				if err != nil {
+					if p != nil {
+						p.Drop(request.GetRequestCount())
+					}
+					gologger.Warningf("Could not compile request for template '%s': %s\n", template.Template.ID, err)
+					continue
+				}
+				result := dnsExecuter.ExecuteDNSWithContext(ctx, p, n.URL, nil)
 				if result.Error != nil {
-					gologger.Warningf("Could not compile request for template '%s': %s\n", template.HTTPOptions.Template.ID, result.Error)
+					if ctx.Err() == nil {
+						gologger.Warningf("Could not send request for template '%s': %s\n", template.Template.ID, result.Error)
+					}
 					continue
 				}
 
@@ -108,6 +134,7 @@ func (n *NucleiVar) Call(args ...tengo.Object) (ret tengo.Object, err error) {
 		}
 	}
 
+	n.GotResults.Or(gotResult.Get())
 	if gotResult.Get() {
 		return tengo.TrueValue, nil
 	}
@@ -1,5 +1,11 @@
 package workflows
 
+import (
+	"github.com/Knetic/govaluate"
+	"github.com/projectdiscovery/nuclei/v2/pkg/regexguard"
+	"github.com/projectdiscovery/nuclei/v2/pkg/templates"
+)
+
 // Workflow is a workflow to execute with chained requests, etc.
 type Workflow struct {
 	// ID is the unique id for the template
@@ -12,7 +18,60 @@ type Workflow struct {
 	Variables map[string]string `yaml:"variables"`
 	// Logic contains the workflow pseudo-code
 	Logic string `yaml:"logic"`
-	path  string
+	// Workflows are the templates to run, along with the templates to run
+	// on their matches, replacing the pseudo-code.
+	Workflows []*WorkflowTemplate `yaml:"workflows"`
+	// Digest is the signature of the workflow by a trusted key
+	Digest string `yaml:"digest,omitempty"`
+	path   string
+}
+
+// WorkflowTemplate is a template of a workflow with the templates to run
+// on the target when it matches.
+type WorkflowTemplate struct {
+	// Template is the path of the template file or directory to run
+	Template string `yaml:"template"`
+	// Condition is a dsl expression on the values of the named extractors
+	// of the parent template, the template only running if it holds for
+	// any of them, i.e compare_versions(version, ">=2.4.49", "<2.4.51").
+	Condition string `yaml:"condition,omitempty"`
+	// condition is the compiled condition
+	condition *govaluate.EvaluableExpression
+	// Matchers run subtemplates depending on the names of the matched matchers
+	Matchers []*Matcher `yaml:"matchers,omitempty"`
+	// Subtemplates are run when the template matches, with its named extracted values
+	Subtemplates []*WorkflowTemplate `yaml:"subtemplates,omitempty"`
+
+	// Templates are the templates of the path, loaded by Load
+	Templates []*templates.Template `yaml:"-"`
+	// Paths are the files of the templates
+	Paths []string `yaml:"-"`
+}
+
+// Matcher runs subtemplates when a matcher of a workflow template matches
+type Matcher struct {
+	// Name is the name of the matcher
+	Name string `yaml:"name"`
+	// Subtemplates are run when the matcher matches
+	Subtemplates []*WorkflowTemplate `yaml:"subtemplates"`
+}
+
+// SetRegexGuard sets the guard limiting the length of the inputs of the
+// regexes of the templates loaded by Load.
+func (w *Workflow) SetRegexGuard(guard *regexguard.Guard) {
+	setRegexGuard(w.Workflows, guard)
+}
+
+func setRegexGuard(workflowTemplates []*WorkflowTemplate, guard *regexguard.Guard) {
+	for _, workflowTemplate := range workflowTemplates {
+		for _, template := range workflowTemplate.Templates {
+			template.SetRegexGuard(guard)
+		}
+		for _, matcher := range workflowTemplate.Matchers {
+			setRegexGuard(matcher.Subtemplates, guard)
+		}
+		setRegexGuard(workflowTemplate.Subtemplates, guard)
+	}
 }
 
 // GetPath of the workflow
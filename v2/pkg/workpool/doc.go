@@ -0,0 +1,4 @@
+// Package workpool runs the units of work of a scan, a template on a
+// target, on a fixed number of workers instead of a goroutine each, with
+// at most a number of units in flight towards each host.
+package workpool
@@ -0,0 +1,158 @@
+package workpool
+
+import "sync"
+
+// unit is a unit of work submitted to a pool along with its key
+type unit struct {
+	key string
+	run func()
+}
+
+// Pool runs the units of work submitted to it on a fixed number of
+// workers, with at most a number of units in flight for each key. The units
+// over the limit of their key wait in the queue without holding a worker,
+// and Submit blocks once the queue is full.
+type Pool struct {
+	perKey int
+	// slots are the units submitted and not yet taken by a worker
+	slots chan struct{}
+
+	mutex sync.Mutex
+	cond  *sync.Cond
+	// ready are the units a worker can take from head, a ring as large as
+	// the queue, pending the units waiting for a unit of their key to
+	// complete and inFlight the numbers of units taken or ready by key,
+	// only counted with a limit by key.
+	ready    []unit
+	head     int
+	count    int
+	pending  map[string][]unit
+	inFlight map[string]int
+	// paused stops the workers from taking the ready units, the units
+	// running completing, until the pool is resumed or closed.
+	paused bool
+	closed bool
+	wg     sync.WaitGroup
+}
+
+// New returns a pool of a number of workers running at most perKey units
+// of each key at once, without limit by key if 0. At most workers units
+// are queued at once besides the ones running, at least one worker being
+// started.
+func New(workers, perKey int) *Pool {
+	if workers < 1 {
+		workers = 1
+	}
+	p := &Pool{
+		perKey:   perKey,
+		slots:    make(chan struct{}, workers),
+		ready:    make([]unit, workers),
+		pending:  make(map[string][]unit),
+		inFlight: make(map[string]int),
+	}
+	p.cond = sync.NewCond(&p.mutex)
+	p.wg.Add(workers)
+	for i := 0; i < workers; i++ {
+		go p.work()
+	}
+	return p
+}
+
+// Submit queues a unit of work with its key, blocking while the queue is
+// full. It must not be called once the pool is closed.
+func (p *Pool) Submit(key string, run func()) {
+	p.slots <- struct{}{}
+
+	p.mutex.Lock()
+	defer p.mutex.Unlock()
+	if p.perKey > 0 {
+		if p.inFlight[key] >= p.perKey {
+			p.pending[key] = append(p.pending[key], unit{key: key, run: run})
+			return
+		}
+		p.inFlight[key]++
+	}
+	p.push(unit{key: key, run: run})
+}
+
+// Pause stops the workers from taking the next units once the ones they
+// run complete, Submit blocking once the queue is full.
+func (p *Pool) Pause() {
+	p.mutex.Lock()
+	p.paused = true
+	p.mutex.Unlock()
+}
+
+// Resume lets the workers take the ready units again
+func (p *Pool) Resume() {
+	p.mutex.Lock()
+	p.paused = false
+	p.cond.Broadcast()
+	p.mutex.Unlock()
+}
+
+// Close waits for the queued units to run and stops the workers, the
+// units of a paused pool being run too.
+func (p *Pool) Close() {
+	p.mutex.Lock()
+	p.closed = true
+	p.cond.Broadcast()
+	p.mutex.Unlock()
+	p.wg.Wait()
+}
+
+// work runs the ready units until the pool is closed
+func (p *Pool) work() {
+	defer p.wg.Done()
+	for {
+		p.mutex.Lock()
+		for (p.count == 0 || p.paused) && !p.closed {
+			p.cond.Wait()
+		}
+		if p.count == 0 {
+			p.mutex.Unlock()
+			return
+		}
+		next := p.ready[p.head]
+		p.ready[p.head] = unit{}
+		p.head = (p.head + 1) % len(p.ready)
+		p.count--
+		p.mutex.Unlock()
+		<-p.slots
+
+		next.run()
+		p.complete(next.key)
+	}
+}
+
+// complete makes the next pending unit of a key ready once a unit of the
+// key completed, if any.
+func (p *Pool) complete(key string) {
+	if p.perKey == 0 {
+		return
+	}
+	p.mutex.Lock()
+	defer p.mutex.Unlock()
+	queued := p.pending[key]
+	if len(queued) == 0 {
+		if p.inFlight[key]--; p.inFlight[key] == 0 {
+			delete(p.inFlight, key)
+		}
+		return
+	}
+	p.push(queued[0])
+	if len(queued) == 1 {
+		delete(p.pending, key)
+	} else {
+		queued[0] = unit{}
+		p.pending[key] = queued[1:]
+	}
+}
+
+// push makes a unit ready and wakes a worker up, the ring having room for
+// all the units holding a slot.
+func (p *Pool) push(next unit) {
+	p.ready[(p.head+p.count)%len(p.ready)] = next
+	p.count++
+	p.cond.Signal()
+}
@@ -0,0 +1,213 @@
+package workpool
+
+import (
+	"fmt"
+	"runtime"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+// maxCounter keeps the maximum of a counter
+type maxCounter struct {
+	mutex   sync.Mutex
+	current int
+	max     int
+}
+
+func (c *maxCounter) add(delta int) {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+	c.current += delta
+	if c.current > c.max {
+		c.max = c.current
+	}
+}
+
+func TestPool(t *testing.T) {
+	pool := New(4, 0)
+	var running maxCounter
+	var ran int64
+	for i := 0; i < 100; i++ {
+		pool.Submit(fmt.Sprintf("host%d", i), func() {
+			running.add(1)
+			time.Sleep(time.Millisecond)
+			running.add(-1)
+			atomic.AddInt64(&ran, 1)
+		})
+	}
+	pool.Close()
+	require.Equal(t, int64(100), ran, "Could not run all the units")
+	require.True(t, running.max <= 4, "Could not bound the units running at once")
+}
+
+func TestPoolPerKey(t *testing.T) {
+	pool := New(8, 2)
+	hosts := map[string]*maxCounter{"a": {}, "b": {}}
+	var ran int64
+	for i := 0; i < 40; i++ {
+		host := "a"
+		if i%4 == 0 {
+			host = "b"
+		}
+		counter := hosts[host]
+		pool.Submit(host, func() {
+			counter.add(1)
+			time.Sleep(time.Millisecond)
+			counter.add(-1)
+			atomic.AddInt64(&ran, 1)
+		})
+	}
+	pool.Close()
+	require.Equal(t, int64(40), ran, "Could not run the pending units")
+	require.Equal(t, 2, hosts["a"].max, "Could not bound the units in flight of a key")
+	require.Equal(t, 2, hosts["b"].max, "Could not run the other keys alongside")
+	require.Empty(t, pool.pending, "Could not remove the keys without pending units")
+	require.Empty(t, pool.inFlight, "Could not remove the keys without units in flight")
+}
+
+func TestPoolPause(t *testing.T) {
+	pool := New(2, 0)
+	var ran int64
+	pool.Pause()
+	for i := 0; i < 2; i++ {
+		pool.Submit("host", func() {
+			atomic.AddInt64(&ran, 1)
+		})
+	}
+	time.Sleep(20 * time.Millisecond)
+	require.Equal(t, int64(0), atomic.LoadInt64(&ran), "Could not hold the units of a paused pool")
+
+	pool.Resume()
+	pool.Submit("host", func() {
+		atomic.AddInt64(&ran, 1)
+	})
+	pool.Pause()
+	pool.Close()
+	require.Equal(t, int64(3), ran, "Could not run the units once resumed or closed")
+}
+
+// The benchmarks run a synthetic scan of 500 templates on 10k hosts with
+// 50 concurrent runs, a run only counting itself, and report the peak
+// number of goroutines along with the runs per second.
+const (
+	benchmarkHosts       = 10000
+	benchmarkTemplates   = 500
+	benchmarkConcurrency = 50
+)
+
+// sampleGoroutines returns a function stopping the sampling of the number
+// of goroutines and returning its peak.
+func sampleGoroutines() func() int {
+	var peak int64
+	done := make(chan struct{})
+	stopped := make(chan struct{})
+	go func() {
+		defer close(stopped)
+		ticker := time.NewTicker(time.Millisecond)
+		defer ticker.Stop()
+		for {
+			if current := int64(runtime.NumGoroutine()); current > atomic.LoadInt64(&peak) {
+				atomic.StoreInt64(&peak, current)
+			}
+			select {
+			case <-done:
+				return
+			case <-ticker.C:
+			}
+		}
+	}()
+	return func() int {
+		close(done)
+		<-stopped
+		return int(atomic.LoadInt64(&peak))
+	}
+}
+
+func reportRuns(b *testing.B, start time.Time, runs int64, peak int) {
+	b.ReportMetric(float64(runs)/time.Since(start).Seconds(), "runs/s")
+	b.ReportMetric(float64(peak), "peak-goroutines")
+}
+
+// BenchmarkGoroutines is the previous model, each template enqueuing its
+// runs from its own goroutine and each run having a goroutine bounded by a
+// global semaphore.
+func BenchmarkGoroutines(b *testing.B) {
+	b.ReportAllocs()
+	stop := sampleGoroutines()
+	start := time.Now()
+	var runs int64
+	for i := 0; i < b.N; i++ {
+		limiter := make(chan struct{}, benchmarkConcurrency)
+		var templates sync.WaitGroup
+		for template := 0; template < benchmarkTemplates; template++ {
+			templates.Add(1)
+			go func() {
+				defer templates.Done()
+				var wg sync.WaitGroup
+				for host := 0; host < benchmarkHosts; host++ {
+					limiter <- struct{}{}
+					wg.Add(1)
+					go func() {
+						defer wg.Done()
+						atomic.AddInt64(&runs, 1)
+						<-limiter
+					}()
+				}
+				wg.Wait()
+			}()
+		}
+		templates.Wait()
+	}
+	reportRuns(b, start, runs, stop())
+}
+
+// BenchmarkPool is the pool, the templates being enqueued by as many
+// goroutines as workers.
+func BenchmarkPool(b *testing.B) {
+	b.ReportAllocs()
+	stop := sampleGoroutines()
+	start := time.Now()
+	var runs int64
+	for i := 0; i < b.N; i++ {
+		pool := New(benchmarkConcurrency, 0)
+		templates := make(chan int)
+		var feeders sync.WaitGroup
+		for feeder := 0; feeder < benchmarkConcurrency; feeder++ {
+			feeders.Add(1)
+			go func() {
+				defer feeders.Done()
+				for range templates {
+					var wg sync.WaitGroup
+					for host := 0; host < benchmarkHosts; host++ {
+						wg.Add(1)
+						pool.Submit(hostKeys[host], func() {
+							atomic.AddInt64(&runs, 1)
+							wg.Done()
+						})
+					}
+					wg.Wait()
+				}
+			}()
+		}
+		for template := 0; template < benchmarkTemplates; template++ {
+			templates <- template
+		}
+		close(templates)
+		feeders.Wait()
+		pool.Close()
+	}
+	reportRuns(b, start, runs, stop())
+}
+
+// hostKeys are the keys of the hosts of the benchmarks
+var hostKeys = func() []string {
+	keys := make([]string, benchmarkHosts)
+	for i := range keys {
+		keys[i] = fmt.Sprintf("host%d.example.com", i)
+	}
+	return keys
+}()
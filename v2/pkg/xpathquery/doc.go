@@ -0,0 +1,3 @@
+// Package xpathquery evaluates XPath expressions against html and xml
+// documents, shared by the xpath matchers and extractors.
+package xpathquery
@@ -0,0 +1,63 @@
+package xpathquery
+
+import (
+	"strings"
+
+	"github.com/antchfx/htmlquery"
+	"github.com/antchfx/xmlquery"
+	"github.com/antchfx/xpath"
+)
+
+// Document is a parsed html or xml document
+type Document struct {
+	// selectValues returns the values of the nodes selected by an expression
+	selectValues func(expr *xpath.Expr, attribute string) []string
+}
+
+// Parse parses the corpus as xml if it has an xml declaration, and as html
+// otherwise. The html parser is lenient with malformed markup.
+func Parse(corpus string) (*Document, error) {
+	if strings.HasPrefix(strings.TrimSpace(corpus), "<?xml") {
+		doc, err := xmlquery.Parse(strings.NewReader(corpus))
+		if err != nil {
+			return nil, err
+		}
+		return &Document{selectValues: func(expr *xpath.Expr, attribute string) []string {
+			var values []string
+			for _, node := range xmlquery.QuerySelectorAll(doc, expr) {
+				if attribute != "" {
+					if value := node.SelectAttr(attribute); value != "" {
+						values = append(values, value)
+					}
+					continue
+				}
+				values = append(values, node.InnerText())
+			}
+			return values
+		}}, nil
+	}
+
+	doc, err := htmlquery.Parse(strings.NewReader(corpus))
+	if err != nil {
+		return nil, err
+	}
+	return &Document{selectValues: func(expr *xpath.Expr, attribute string) []string {
+		var values []string
+		for _, node := range htmlquery.QuerySelectorAll(doc, expr) {
+			if attribute != "" {
+				if value := htmlquery.SelectAttr(node, attribute); value != "" {
+					values = append(values, value)
+				}
+				continue
+			}
+			values = append(values, htmlquery.InnerText(node))
+		}
+		return values
+	}}, nil
+}
+
+// Select returns the values of the nodes selected by an expression, the
+// value of the attribute if an attribute is specified, the inner text otherwise.
+func (d *Document) Select(expr *xpath.Expr, attribute string) []string {
+	return d.selectValues(expr, attribute)
+}
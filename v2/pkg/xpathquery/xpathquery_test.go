@@ -0,0 +1,24 @@
+package xpathquery
+
+import (
+	"testing"
+
+	"github.com/antchfx/xpath"
+	"github.com/stretchr/testify/require"
+)
+
+func TestSelect(t *testing.T) {
+	document, err := Parse(`<html><form action="/login"><input name="csrf" value="t0k3n"><input name="user"></form><p> Powered by </p>`)
+	require.Nil(t, err, "Could not parse html document")
+	require.Equal(t, []string{"t0k3n"}, document.Select(xpath.MustCompile("//input[@name='csrf']"), "value"), "Could not select attribute")
+	require.Equal(t, []string{"/login"}, document.Select(xpath.MustCompile("//form"), "action"), "Could not select attribute")
+	require.Equal(t, []string{" Powered by "}, document.Select(xpath.MustCompile("//p"), ""), "Could not select inner text")
+	require.Nil(t, document.Select(xpath.MustCompile("//input[@name='user']"), "value"), "Could select missing attribute")
+
+	document, err = Parse(`<?xml version="1.0"?><users><user role="admin"><name>root</name></user></users>`)
+	require.Nil(t, err, "Could not parse xml document")
+	require.Equal(t, []string{"root"}, document.Select(xpath.MustCompile("//user[@role='admin']/name"), ""), "Could not select xml node")
+
+	_, err = Parse(`<?xml version="1.0"?><users><user>`)
+	require.NotNil(t, err, "Could parse invalid xml document")
+}